@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"digisocialblock/core/api"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+)
+
+// generate returns the formatted Go source for the client types and methods
+// described by spec.
+func generate(spec []byte, pkg string) ([]byte, error) {
+	var doc api.OpenAPIDocument
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+
+	var body bytes.Buffer
+	needJSON := false
+
+	names := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writeStruct(&body, name, doc.Components.Schemas[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		methods := make([]string, 0, len(doc.Paths[path]))
+		for method := range doc.Paths[path] {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			raw, err := writeOperation(&body, &doc, strings.ToUpper(method), path, doc.Paths[path][method])
+			if err != nil {
+				return nil, err
+			}
+			needJSON = needJSON || raw
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by apigen from core/api/openapi.json. DO NOT EDIT.\n\npackage %s\n\nimport (\n\t\"context\"\n", pkg)
+	if needJSON {
+		out.WriteString("\t\"encoding/json\"\n")
+	}
+	out.WriteString(")\n")
+	out.Write(body.Bytes())
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code does not parse: %w", err)
+	}
+	return src, nil
+}
+
+func writeStruct(buf *bytes.Buffer, name string, schema *api.Schema) error {
+	if schema.Type != "object" {
+		return fmt.Errorf("schema %s: only object schemas can be components", name)
+	}
+	fmt.Fprintf(buf, "\n%s", comment(name, schema.Description))
+	fmt.Fprintf(buf, "type %s struct {\n", name)
+	required := make(map[string]bool, len(schema.Required))
+	for _, prop := range schema.Required {
+		required[prop] = true
+	}
+	// Required fields first, in document order, then optional ones by name.
+	props := append([]string(nil), schema.Required...)
+	var optional []string
+	for prop := range schema.Properties {
+		if !required[prop] {
+			optional = append(optional, prop)
+		}
+	}
+	sort.Strings(optional)
+	props = append(props, optional...)
+	for _, prop := range props {
+		ps, ok := schema.Properties[prop]
+		if !ok {
+			return fmt.Errorf("schema %s: required property %s is not defined", name, prop)
+		}
+		typ, err := goType(ps)
+		if err != nil {
+			return fmt.Errorf("schema %s.%s: %w", name, prop, err)
+		}
+		tag := prop
+		if !required[prop] {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(buf, "\t%s %s `json:\"%s\"`", goName(prop), typ, tag)
+		if ps.Description != "" {
+			fmt.Fprintf(buf, " // %s", ps.Description)
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("}\n")
+	return nil
+}
+
+// writeOperation writes the Client method for one operation and reports
+// whether it returns raw JSON.
+func writeOperation(buf *bytes.Buffer, doc *api.OpenAPIDocument, method, path string, op *api.Operation) (bool, error) {
+	if op.OperationID == "" {
+		return false, fmt.Errorf("%s %s has no operationId", method, path)
+	}
+	in := "nil"
+	params := "ctx context.Context"
+	if op.RequestBody != nil {
+		media, ok := op.RequestBody.Content["application/json"]
+		if !ok || api.SchemaName(media.Schema) == "" {
+			return false, fmt.Errorf("%s: request body must reference a component schema", op.OperationID)
+		}
+		params += ", body *" + api.SchemaName(media.Schema)
+		in = "body"
+	}
+
+	result, raw := "", false
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if !strings.HasPrefix(code, "2") {
+			continue
+		}
+		media, ok := op.Responses[code].Content["application/json"]
+		if !ok {
+			continue
+		}
+		if name := api.SchemaName(media.Schema); name != "" {
+			result = name
+		} else {
+			raw = true
+		}
+		break
+	}
+
+	fmt.Fprintf(buf, "\n%s//\n//\t%s %s\n", comment(op.OperationID, op.Summary), method, path)
+	switch {
+	case result != "":
+		fmt.Fprintf(buf, "func (c *Client) %s(%s) (*%s, error) {\n\tvar out %s\n", op.OperationID, params, result, result)
+		fmt.Fprintf(buf, "\tif err := c.do(ctx, %q, %q, %s, &out); err != nil {\n\t\treturn nil, err\n\t}\n\treturn &out, nil\n}\n", method, path, in)
+	case raw:
+		fmt.Fprintf(buf, "func (c *Client) %s(%s) (json.RawMessage, error) {\n\tvar out json.RawMessage\n", op.OperationID, params)
+		fmt.Fprintf(buf, "\tif err := c.do(ctx, %q, %q, %s, &out); err != nil {\n\t\treturn nil, err\n\t}\n\treturn out, nil\n}\n", method, path, in)
+	default:
+		fmt.Fprintf(buf, "func (c *Client) %s(%s) error {\n\treturn c.do(ctx, %q, %q, %s, nil)\n}\n", op.OperationID, params, method, path, in)
+	}
+	return raw, nil
+}
+
+func goType(s *api.Schema) (string, error) {
+	if name := api.SchemaName(s); name != "" {
+		return name, nil
+	}
+	switch s.Type {
+	case "string":
+		if s.Format == "byte" {
+			return "[]byte", nil
+		}
+		return "string", nil
+	case "integer":
+		if s.Format == "uint64" {
+			return "uint64", nil
+		}
+		return "int64", nil
+	case "boolean":
+		return "bool", nil
+	case "array":
+		if s.Items == nil {
+			return "", fmt.Errorf("array without items")
+		}
+		elem, err := goType(s.Items)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + elem, nil
+	case "object":
+		return "map[string]interface{}", nil
+	}
+	return "", fmt.Errorf("unsupported schema type %q", s.Type)
+}
+
+// goName converts a JSON property name to an exported Go field name,
+// upper-casing a trailing "Id" as Go style requires.
+func goName(prop string) string {
+	if prop == "id" {
+		return "ID"
+	}
+	name := strings.ToUpper(prop[:1]) + prop[1:]
+	if strings.HasSuffix(name, "Id") {
+		name = strings.TrimSuffix(name, "Id") + "ID"
+	}
+	return name
+}
+
+func comment(name, text string) string {
+	if text == "" {
+		return fmt.Sprintf("// %s is generated from the OpenAPI document.\n", name)
+	}
+	return fmt.Sprintf("// %s: %s\n", name, text)
+}
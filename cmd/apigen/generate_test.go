@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestGeneratedClientIsCurrent fails when core/api/openapi.json changed
+// without regenerating the client.
+func TestGeneratedClientIsCurrent(t *testing.T) {
+	spec, err := os.ReadFile("../../core/api/openapi.json")
+	if err != nil {
+		t.Fatalf("failed to read spec: %v", err)
+	}
+	want, err := generate(spec, "client")
+	if err != nil {
+		t.Fatalf("generate() error = %v", err)
+	}
+	got, err := os.ReadFile("../../core/api/client/client_gen.go")
+	if err != nil {
+		t.Fatalf("failed to read generated client: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("core/api/client/client_gen.go is stale; run go generate ./core/api/client")
+	}
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	tests := []struct {
+		name, spec, want string
+	}{
+		{"bad JSON", `{`, "failed to parse"},
+		{"missing operationId", `{"paths":{"/x":{"get":{"responses":{}}}}}`, "no operationId"},
+		{"non-object component", `{"components":{"schemas":{"S":{"type":"string"}}}}`, "only object schemas"},
+		{"unsupported type", `{"components":{"schemas":{"S":{"type":"object","properties":{"f":{"type":"number"}}}}}}`, "unsupported schema type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := generate([]byte(tt.spec), "client")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("generate() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+// Command apigen generates the typed API client in core/api/client from the
+// OpenAPI document in core/api/openapi.json. It is run through go generate:
+//
+//	go generate ./core/api/client
+//
+// The output holds one struct per component schema and one Client method per
+// operation; the transport those methods call lives in core/api/client/client.go.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	specPath := flag.String("spec", "", "OpenAPI document to generate from (required)")
+	outPath := flag.String("out", "", "Go file to write (required)")
+	pkg := flag.String("package", "client", "package name of the generated file")
+	flag.Parse()
+
+	if *specPath == "" || *outPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	spec, err := os.ReadFile(*specPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	src, err := generate(spec, *pkg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*outPath, src, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// benchResults maps "package/BenchmarkName" to every sample recorded for one metric.
+type benchResults map[string][]float64
+
+// parseBenchOutput reads `go test -bench` output and collects the samples of
+// metric (e.g. "ns/op") for each benchmark. Results from -count runs are
+// accumulated, and the GOMAXPROCS suffix ("-8") is stripped from names so runs
+// on different machines can be compared.
+func parseBenchOutput(r io.Reader, metric string) (benchResults, error) {
+	results := make(benchResults)
+	pkg, pending := "", ""
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "pkg: ") {
+			pkg = strings.TrimSpace(strings.TrimPrefix(line, "pkg: "))
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 0 && strings.HasPrefix(fields[0], "Benchmark") {
+			pending = stripProcsSuffix(fields[0])
+			fields = fields[1:]
+		}
+		// Output printed by the code under test can separate a benchmark's
+		// name from its result, so a result line is attributed to the most
+		// recent name.
+		if pending == "" || len(fields) < 3 {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[0]); err != nil {
+			continue // Not a result line
+		}
+		name := pending
+		pending = ""
+		if pkg != "" {
+			name = pkg + "/" + name
+		}
+		// After the iteration count, fields come in (value, unit) pairs.
+		for i := 1; i+1 < len(fields); i += 2 {
+			if fields[i+1] != metric {
+				continue
+			}
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value %q for %s: %w", metric, fields[i], name, err)
+			}
+			results[name] = append(results[name], value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read benchmark output: %w", err)
+	}
+	return results, nil
+}
+
+// stripProcsSuffix removes the trailing "-N" GOMAXPROCS marker from a benchmark name.
+func stripProcsSuffix(name string) string {
+	i := strings.LastIndexByte(name, '-')
+	if i < 0 {
+		return name
+	}
+	if _, err := strconv.Atoi(name[i+1:]); err != nil {
+		return name
+	}
+	return name[:i]
+}
+
+// median returns the median of samples, which is less sensitive than the mean
+// to a single noisy run.
+func median(samples []float64) float64 {
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// comparison is the result for one benchmark present in both runs.
+type comparison struct {
+	Name       string
+	Base, New  float64
+	DeltaPct   float64
+	Regression bool
+}
+
+// compareResults compares the median of every benchmark found in both runs.
+// A benchmark regresses when it got slower (larger) by more than thresholdPct percent.
+// Benchmarks found in only one run are returned in missing.
+func compareResults(base, current benchResults, thresholdPct float64) (comparisons []comparison, missing []string) {
+	for name, baseSamples := range base {
+		newSamples, ok := current[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		c := comparison{Name: name, Base: median(baseSamples), New: median(newSamples)}
+		if c.Base > 0 {
+			c.DeltaPct = (c.New - c.Base) / c.Base * 100
+		}
+		c.Regression = c.DeltaPct > thresholdPct
+		comparisons = append(comparisons, c)
+	}
+	for name := range current {
+		if _, ok := base[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	sort.Slice(comparisons, func(i, j int) bool { return comparisons[i].Name < comparisons[j].Name })
+	sort.Strings(missing)
+	return comparisons, missing
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const baseOutput = `goos: linux
+goarch: amd64
+pkg: digisocialblock/core/ledger
+BenchmarkMerkleRoot/hashes=100-8   	   50000	     20000 ns/op	    9000 B/op	     120 allocs/op
+BenchmarkMerkleRoot/hashes=100-8   	   50000	     22000 ns/op	    9000 B/op	     120 allocs/op
+BenchmarkMerkleRoot/hashes=100-8   	   50000	     21000 ns/op	    9000 B/op	     120 allocs/op
+BenchmarkNewBlock/txs=1-8          	  300000	      4000 ns/op
+BenchmarkRemoved-8                 	  300000	      1000 ns/op
+PASS
+ok  	digisocialblock/core/ledger	3.2s
+`
+
+const newOutput = `pkg: digisocialblock/core/ledger
+BenchmarkMerkleRoot/hashes=100-4   	   50000	     25000 ns/op	    9000 B/op	     120 allocs/op
+BenchmarkNewBlock/txs=1-4          	Block added: 1
+Hash: 00ab
+  300000	      4100 ns/op
+BenchmarkAdded-4                   	  300000	      1000 ns/op
+`
+
+func TestParseBenchOutput(t *testing.T) {
+	results, err := parseBenchOutput(strings.NewReader(baseOutput), "ns/op")
+	if err != nil {
+		t.Fatalf("parseBenchOutput() error = %v", err)
+	}
+	samples := results["digisocialblock/core/ledger/BenchmarkMerkleRoot/hashes=100"]
+	if len(samples) != 3 || median(samples) != 21000 {
+		t.Errorf("MerkleRoot samples = %v, want 3 samples with median 21000", samples)
+	}
+	allocs, _ := parseBenchOutput(strings.NewReader(baseOutput), "allocs/op")
+	if got := allocs["digisocialblock/core/ledger/BenchmarkMerkleRoot/hashes=100"]; len(got) != 3 || got[0] != 120 {
+		t.Errorf("allocs/op samples = %v, want 3 samples of 120", got)
+	}
+}
+
+func TestCompareResults_FlagsRegressionsAboveThreshold(t *testing.T) {
+	base, _ := parseBenchOutput(strings.NewReader(baseOutput), "ns/op")
+	current, _ := parseBenchOutput(strings.NewReader(newOutput), "ns/op")
+
+	comparisons, missing := compareResults(base, current, 10)
+	if len(comparisons) != 2 {
+		t.Fatalf("compareResults() returned %d comparisons, want 2", len(comparisons))
+	}
+	// MerkleRoot: 21000 -> 25000 (+19%) regresses; NewBlock: 4000 -> 4100 (+2.5%) does not.
+	if !comparisons[0].Regression || comparisons[1].Regression {
+		t.Errorf("regressions = [%v %v], want [true false]", comparisons[0].Regression, comparisons[1].Regression)
+	}
+	if len(missing) != 2 {
+		t.Errorf("missing = %v, want the added and removed benchmarks", missing)
+	}
+
+	if comparisons, _ := compareResults(base, current, 25); comparisons[0].Regression {
+		t.Error("a 19% slowdown was flagged with a 25% threshold")
+	}
+}
@@ -0,0 +1,76 @@
+// Command benchcheck compares two sets of `go test -bench` results and exits
+// with a non-zero status when any benchmark regressed by more than a threshold.
+//
+// Typical use:
+//
+//	go test -run '^$' -bench . -count 5 ./core/... > base.txt
+//	# ... make changes ...
+//	go test -run '^$' -bench . -count 5 ./core/... > new.txt
+//	go run ./cmd/benchcheck -base base.txt -new new.txt -threshold 10
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	basePath := flag.String("base", "", "benchmark output to compare against (required)")
+	newPath := flag.String("new", "", "benchmark output of the change under test (required)")
+	threshold := flag.Float64("threshold", 10, "maximum allowed slowdown in percent")
+	metric := flag.String("metric", "ns/op", "benchmark metric to compare (e.g. ns/op, B/op, allocs/op)")
+	flag.Parse()
+
+	if *basePath == "" || *newPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	base, err := readResults(*basePath, *metric)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	current, err := readResults(*newPath, *metric)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	comparisons, missing := compareResults(base, current, *threshold)
+	regressions := 0
+	fmt.Printf("%-70s %14s %14s %9s\n", "benchmark", "base "+*metric, "new "+*metric, "delta")
+	for _, c := range comparisons {
+		marker := ""
+		if c.Regression {
+			marker = "  REGRESSION"
+			regressions++
+		}
+		fmt.Printf("%-70s %14.1f %14.1f %+8.1f%%%s\n", c.Name, c.Base, c.New, c.DeltaPct, marker)
+	}
+	for _, name := range missing {
+		fmt.Printf("%-70s only present in one run, skipped\n", name)
+	}
+
+	if regressions > 0 {
+		fmt.Fprintf(os.Stderr, "%d benchmark(s) regressed by more than %.1f%%\n", regressions, *threshold)
+		os.Exit(1)
+	}
+}
+
+// readResults parses the benchmark output file at path.
+func readResults(path, metric string) (benchResults, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	results, err := parseBenchOutput(f, metric)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no %s results found in %s", metric, path)
+	}
+	return results, nil
+}
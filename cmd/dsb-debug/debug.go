@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bytes"
+	"digisocialblock/core/ledger"
+	_ "digisocialblock/core/social" // Registers the social payload decoders
+	_ "digisocialblock/core/user"   // Registers the profile payload decoder
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// errUsage reports bad command-line arguments; the caller exits with status 2.
+var errUsage = errors.New("invalid arguments")
+
+// errFound reports that verify found a problem or diff found a difference.
+var errFound = errors.New("problems found")
+
+// chainLog is a block log read into memory without modifying it.
+type chainLog struct {
+	path    string
+	records []ledger.BlockLogRecord
+}
+
+// loadLog reads every record of the block log at path.
+func loadLog(path string) (*chainLog, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open block log: %w", err)
+	}
+	defer f.Close()
+	chain := &chainLog{path: path}
+	err = ledger.ScanBlockLog(f, func(rec ledger.BlockLogRecord) error {
+		chain.records = append(chain.records, rec)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return chain, nil
+}
+
+// byHeight returns the record at position height. Records are positional: a
+// log whose recorded heights disagree with their positions fails verify.
+func (l *chainLog) byHeight(height int64) (ledger.BlockLogRecord, error) {
+	if height < 0 || height >= int64(len(l.records)) {
+		return ledger.BlockLogRecord{}, fmt.Errorf("%s has no block at height %d (%d records)", l.path, height, len(l.records))
+	}
+	return l.records[height], nil
+}
+
+// byHash returns the position of the first decodable block with the given hash.
+func (l *chainLog) byHash(hash string) (int64, bool) {
+	for i, rec := range l.records {
+		if rec.Block != nil && rec.Block.Hash == hash {
+			return int64(i), true
+		}
+	}
+	return 0, false
+}
+
+// newFlagSet returns a flag set that reports errors instead of exiting, with
+// its usage written to stderr.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("dsb-debug "+name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+func writeJSON(out io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	_, err = out.Write(append(data, '\n'))
+	return err
+}
+
+// blockDump is the output of the block command.
+type blockDump struct {
+	Height int64         `json:"height"`
+	Offset int64         `json:"offset"`
+	Block  *ledger.Block `json:"block"`
+}
+
+func runBlock(args []string, out io.Writer) error {
+	fs := newFlagSet("block")
+	store := fs.String("store", "", "block log to read (required)")
+	height := fs.Int64("height", -1, "height of the block to dump")
+	hash := fs.String("hash", "", "hash of the block to dump")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *store == "" || (*height < 0) == (*hash == "") {
+		fmt.Fprintln(os.Stderr, "block needs -store and exactly one of -height and -hash")
+		return errUsage
+	}
+	chain, err := loadLog(*store)
+	if err != nil {
+		return err
+	}
+	if *hash != "" {
+		h, ok := chain.byHash(*hash)
+		if !ok {
+			return fmt.Errorf("%s has no block with hash %s", *store, *hash)
+		}
+		*height = h
+	}
+	rec, err := chain.byHeight(*height)
+	if err != nil {
+		return err
+	}
+	if rec.Err != nil {
+		return fmt.Errorf("record at height %d (offset %d): %w", *height, rec.Offset, rec.Err)
+	}
+	return writeJSON(out, blockDump{Height: *height, Offset: rec.Offset, Block: rec.Block})
+}
+
+// txDump is the output of the tx command for a transaction in a block log.
+// A payload given on the command line is dumped as a ledger.DecodedPayload alone.
+type txDump struct {
+	Height      int64                  `json:"height"`
+	Position    int                    `json:"position"` // Index of the transaction within its block
+	Transaction *ledger.Transaction    `json:"transaction"`
+	Payload     *ledger.DecodedPayload `json:"payload"`
+}
+
+func runTx(args []string, out io.Writer) error {
+	fs := newFlagSet("tx")
+	store := fs.String("store", "", "block log to search for -id")
+	id := fs.String("id", "", "ID of the transaction to dump")
+	txType := fs.String("type", "", "transaction type of -payload, e.g. PostCreated")
+	payload := fs.String("payload", "", "base64 payload to decode, as printed in block dumps")
+	payloadHex := fs.String("payload-hex", "", "hex payload to decode")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	switch {
+	case *id != "" && *store != "" && *txType == "" && *payload == "" && *payloadHex == "":
+		return dumpTx(*store, *id, out)
+	case *id == "" && *store == "" && *txType != "" && (*payload == "") != (*payloadHex == ""):
+		var data []byte
+		var err error
+		if *payload != "" {
+			data, err = base64.StdEncoding.DecodeString(*payload)
+		} else {
+			data, err = hex.DecodeString(*payloadHex)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to decode payload argument: %w", err)
+		}
+		return writeJSON(out, ledger.InspectPayload(ledger.TransactionType(*txType), data))
+	}
+	fmt.Fprintln(os.Stderr, "tx needs either -store and -id, or -type and one of -payload and -payload-hex")
+	return errUsage
+}
+
+func dumpTx(store, id string, out io.Writer) error {
+	chain, err := loadLog(store)
+	if err != nil {
+		return err
+	}
+	for height, rec := range chain.records {
+		if rec.Block == nil {
+			continue
+		}
+		for i, tx := range rec.Block.Transactions {
+			if tx != nil && tx.ID == id {
+				return writeJSON(out, txDump{Height: int64(height), Position: i, Transaction: tx, Payload: ledger.InspectPayload(tx.Type, tx.Payload)})
+			}
+		}
+	}
+	return fmt.Errorf("%s has no transaction with ID %s", store, id)
+}
+
+// verifyBlock recomputes the Merkle root and hash of the block recorded in
+// rec at position height and checks it against the record, its own fields,
+// the previous block and its transactions. It returns the recomputed values
+// and a description of every problem found.
+func verifyBlock(height int64, rec ledger.BlockLogRecord, prev *ledger.Block) (merkleRoot, hash string, problems []string) {
+	if rec.Err != nil {
+		return "", "", []string{rec.Err.Error()}
+	}
+	b := rec.Block
+	merkleRoot = ledger.MerkleRoot(ledger.GetTransactionHashes(b.Transactions))
+	hash = ledger.HashBlockContent(b.Index, b.Timestamp, b.PrevBlockHash, merkleRoot)
+
+	if rec.Height != height || b.Index != height {
+		problems = append(problems, fmt.Sprintf("at position %d but recorded height is %d and block index %d", height, rec.Height, b.Index))
+	}
+	if hash != b.Hash {
+		problems = append(problems, fmt.Sprintf("block hash %s does not match recomputed %s", b.Hash, hash))
+	}
+	if rec.Hash != b.Hash {
+		problems = append(problems, fmt.Sprintf("log index hash %s does not match block hash %s", rec.Hash, b.Hash))
+	}
+	if height == 0 && b.PrevBlockHash != "0" {
+		problems = append(problems, fmt.Sprintf("genesis previous hash is %q, want \"0\"", b.PrevBlockHash))
+	}
+	if prev != nil {
+		if b.PrevBlockHash != prev.Hash {
+			problems = append(problems, fmt.Sprintf("previous hash %s does not match block %d hash %s", b.PrevBlockHash, prev.Index, prev.Hash))
+		}
+		if b.Timestamp <= prev.Timestamp && prev.Index > 0 {
+			problems = append(problems, fmt.Sprintf("timestamp %d is not after block %d timestamp %d", b.Timestamp, prev.Index, prev.Timestamp))
+		}
+	}
+	for i, tx := range b.Transactions {
+		if tx == nil {
+			problems = append(problems, fmt.Sprintf("transaction %d is null", i))
+			continue
+		}
+		if err := tx.IsValid(); err != nil {
+			problems = append(problems, fmt.Sprintf("transaction %d (%s): %v", i, tx.ID, err))
+			continue
+		}
+		if ok, err := tx.VerifySignature(); !ok {
+			problems = append(problems, fmt.Sprintf("transaction %d (%s): invalid signature: %v", i, tx.ID, err))
+		}
+	}
+	return merkleRoot, hash, problems
+}
+
+func runVerify(args []string, out io.Writer) error {
+	fs := newFlagSet("verify")
+	store := fs.String("store", "", "block log to verify (required)")
+	only := fs.Int64("height", -1, "verify only the block at this height; default all")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *store == "" {
+		fmt.Fprintln(os.Stderr, "verify needs -store")
+		return errUsage
+	}
+	chain, err := loadLog(*store)
+	if err != nil {
+		return err
+	}
+	from, to := int64(0), int64(len(chain.records))-1
+	if *only >= 0 {
+		if _, err := chain.byHeight(*only); err != nil {
+			return err
+		}
+		from, to = *only, *only
+	}
+
+	failed := 0
+	for height := from; height <= to; height++ {
+		var prev *ledger.Block
+		if height > 0 {
+			prev = chain.records[height-1].Block
+		}
+		merkleRoot, hash, problems := verifyBlock(height, chain.records[height], prev)
+		if len(problems) == 0 {
+			fmt.Fprintf(out, "height %d: ok merkle=%s hash=%s\n", height, merkleRoot, hash)
+			continue
+		}
+		failed++
+		fmt.Fprintf(out, "height %d: FAIL (offset %d)\n", height, chain.records[height].Offset)
+		for _, p := range problems {
+			fmt.Fprintf(out, "  %s\n", p)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d blocks failed verification", errFound, failed, to-from+1)
+	}
+	return nil
+}
+
+func runDiff(args []string, out io.Writer) error {
+	fs := newFlagSet("diff")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "diff needs two block logs")
+		return errUsage
+	}
+	a, err := loadLog(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	b, err := loadLog(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	common := len(a.records)
+	if len(b.records) < common {
+		common = len(b.records)
+	}
+	for height := 0; height < common; height++ {
+		ra, rb := a.records[height], b.records[height]
+		if ra.Block != nil && rb.Block != nil && ra.Block.Hash == rb.Block.Hash {
+			continue
+		}
+		fmt.Fprintf(out, "logs diverge at height %d\n", height)
+		describeDivergence(out, a.path, ra)
+		describeDivergence(out, b.path, rb)
+		if ra.Block != nil && rb.Block != nil {
+			diffTransactions(out, a.path, ra.Block, b.path, rb.Block)
+		}
+		return fmt.Errorf("%w: logs differ", errFound)
+	}
+	if len(a.records) == len(b.records) {
+		fmt.Fprintf(out, "logs are identical: %d blocks\n", common)
+		return nil
+	}
+	longer := a
+	if len(b.records) > len(a.records) {
+		longer = b
+	}
+	fmt.Fprintf(out, "logs agree on %d blocks; %s has %d more\n", common, longer.path, len(longer.records)-common)
+	return fmt.Errorf("%w: logs differ", errFound)
+}
+
+func describeDivergence(out io.Writer, path string, rec ledger.BlockLogRecord) {
+	if rec.Err != nil {
+		fmt.Fprintf(out, "  %s: unreadable record at offset %d: %v\n", path, rec.Offset, rec.Err)
+		return
+	}
+	fmt.Fprintf(out, "  %s: hash=%s prev=%s timestamp=%d txs=%d\n", path, rec.Block.Hash, rec.Block.PrevBlockHash, rec.Block.Timestamp, len(rec.Block.Transactions))
+}
+
+// diffTransactions lists the transactions in only one of two blocks at the
+// same height, or notes that they hold the same transactions in a different
+// order or with different content.
+func diffTransactions(out io.Writer, pathA string, a *ledger.Block, pathB string, b *ledger.Block) {
+	onlyA, onlyB := txIDsNotIn(a, b), txIDsNotIn(b, a)
+	for _, id := range onlyA {
+		fmt.Fprintf(out, "  only in %s: tx %s\n", pathA, id)
+	}
+	for _, id := range onlyB {
+		fmt.Fprintf(out, "  only in %s: tx %s\n", pathB, id)
+	}
+	if len(onlyA) == 0 && len(onlyB) == 0 {
+		same := len(a.Transactions) == len(b.Transactions)
+		for i := 0; same && i < len(a.Transactions); i++ {
+			same = txEqual(a.Transactions[i], b.Transactions[i])
+		}
+		if same {
+			fmt.Fprintln(out, "  same transactions; the blocks differ in their header")
+		} else {
+			fmt.Fprintln(out, "  same transaction IDs, in a different order or with different signatures, stamps or chain IDs")
+		}
+	}
+}
+
+func txIDsNotIn(a, b *ledger.Block) []string {
+	inB := make(map[string]bool, len(b.Transactions))
+	for _, tx := range b.Transactions {
+		if tx != nil {
+			inB[tx.ID] = true
+		}
+	}
+	var ids []string
+	for _, tx := range a.Transactions {
+		if tx != nil && !inB[tx.ID] {
+			ids = append(ids, tx.ID)
+		}
+	}
+	return ids
+}
+
+func txEqual(a, b *ledger.Transaction) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if len(a.CoSignatures) != len(b.CoSignatures) {
+		return false
+	}
+	for i, cs := range a.CoSignatures {
+		if cs.PublicKey != b.CoSignatures[i].PublicKey || !bytes.Equal(cs.Signature, b.CoSignatures[i].Signature) {
+			return false
+		}
+	}
+	return a.ID == b.ID && bytes.Equal(a.Signature, b.Signature) && a.Stamp == b.Stamp && a.ChainID == b.ChainID && a.SigVersion == b.SigVersion && a.Delegate == b.Delegate
+}
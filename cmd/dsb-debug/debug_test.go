@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil/fixture"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// debugTestLog writes blocks to a new block log and returns its path.
+func debugTestLog(t *testing.T, name string, blocks []*ledger.Block) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	store, err := ledger.OpenFileBlockStore(path, ledger.FileBlockStoreOptions{})
+	if err != nil {
+		t.Fatalf("OpenFileBlockStore() error = %v", err)
+	}
+	defer store.Close()
+	for _, block := range blocks {
+		if err := store.AppendBlock(block); err != nil {
+			t.Fatalf("AppendBlock() error = %v", err)
+		}
+	}
+	return path
+}
+
+// debugTestChain returns the blocks of a valid chain with a post in block 1
+// and a follow in block 2.
+func debugTestChain(t *testing.T) []*ledger.Block {
+	t.Helper()
+	alice, bob := fixture.Wallet(t), fixture.Wallet(t)
+	post := fixture.PostTx(t, alice, "cid-1", "go")
+	return fixture.Chain(t, []*ledger.Transaction{post}, []*ledger.Transaction{fixture.FollowTx(t, bob, alice.Address)}).Blocks
+}
+
+func debugRun(t *testing.T, cmd func([]string, io.Writer) error, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	err := cmd(args, &out)
+	return out.String(), err
+}
+
+func TestBlockAndTx_DumpAndDecode(t *testing.T) {
+	blocks := debugTestChain(t)
+	path := debugTestLog(t, "chain.log", blocks)
+
+	out, err := debugRun(t, runBlock, "-store", path, "-hash", blocks[1].Hash)
+	if err != nil {
+		t.Fatalf("block error = %v", err)
+	}
+	var dump blockDump
+	if err := json.Unmarshal([]byte(out), &dump); err != nil || dump.Height != 1 || dump.Block.Hash != blocks[1].Hash {
+		t.Fatalf("block dump = %+v, %v; want block 1", dump, err)
+	}
+
+	post := blocks[1].Transactions[0]
+	out, err = debugRun(t, runTx, "-store", path, "-id", post.ID)
+	if err != nil {
+		t.Fatalf("tx error = %v", err)
+	}
+	if !strings.Contains(out, `"format": "cbor"`) || !strings.Contains(out, `"contentCID": "cid-1"`) || strings.Contains(out, `"invalid"`) {
+		t.Errorf("tx dump does not decode the post payload:\n%s", out)
+	}
+
+	out, err = debugRun(t, runTx, "-type", string(ledger.PostCreated), "-payload-hex", "7b7d")
+	if err != nil {
+		t.Fatalf("tx -payload-hex error = %v", err)
+	}
+	if !strings.Contains(out, `"format": "json"`) || !strings.Contains(out, `"invalid"`) {
+		t.Errorf("decoding an empty post should report it invalid:\n%s", out)
+	}
+
+	if _, err := debugRun(t, runBlock, "-store", path); !errors.Is(err, errUsage) {
+		t.Errorf("block without -height or -hash error = %v, want errUsage", err)
+	}
+}
+
+func TestVerify_ReportsTamperedBlock(t *testing.T) {
+	blocks := debugTestChain(t)
+	out, err := debugRun(t, runVerify, "-store", debugTestLog(t, "good.log", blocks))
+	if err != nil || strings.Count(out, ": ok") != len(blocks) {
+		t.Fatalf("verify of a valid log = %v:\n%s", err, out)
+	}
+
+	tampered := *blocks[1]
+	tampered.Timestamp++
+	out, err = debugRun(t, runVerify, "-store", debugTestLog(t, "bad.log", []*ledger.Block{blocks[0], &tampered, blocks[2]}))
+	if !errors.Is(err, errFound) {
+		t.Fatalf("verify of a tampered log error = %v, want errFound", err)
+	}
+	// The tampered block keeps its recorded hash, so the next block still links to it.
+	if !strings.Contains(out, "height 1: FAIL") || !strings.Contains(out, "does not match recomputed") || !strings.Contains(out, "height 2: ok") {
+		t.Errorf("verify output does not report the hash mismatch of block 1 alone:\n%s", out)
+	}
+}
+
+func TestDiff_FindsDivergence(t *testing.T) {
+	blocks := debugTestChain(t)
+	full := debugTestLog(t, "full.log", blocks)
+
+	if out, err := debugRun(t, runDiff, full, debugTestLog(t, "copy.log", blocks)); err != nil || !strings.Contains(out, "identical: 3 blocks") {
+		t.Errorf("diff of identical logs = %v:\n%s", err, out)
+	}
+	out, err := debugRun(t, runDiff, debugTestLog(t, "short.log", blocks[:2]), full)
+	if !errors.Is(err, errFound) || !strings.Contains(out, "agree on 2 blocks") {
+		t.Errorf("diff of a prefix = %v:\n%s", err, out)
+	}
+
+	other := debugTestChain(t)
+	out, err = debugRun(t, runDiff, full, debugTestLog(t, "other.log", other))
+	if !errors.Is(err, errFound) || !strings.Contains(out, "diverge at height 1") || !strings.Contains(out, "only in") {
+		t.Errorf("diff of different chains = %v:\n%s", err, out)
+	}
+}
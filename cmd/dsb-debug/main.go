@@ -0,0 +1,44 @@
+// Command dsb-debug inspects block logs when investigating consensus or
+// corruption issues. Logs are read with ledger.ScanBlockLog and never
+// modified, so it is safe to point at a running node's log or at a log that
+// a node refuses to open.
+//
+//	dsb-debug block  -store blocks.log -height 42       dump a block as JSON
+//	dsb-debug block  -store blocks.log -hash 3fa9...
+//	dsb-debug tx     -store blocks.log -id 9c1e...      dump a transaction with its payload decoded
+//	dsb-debug tx     -type PostCreated -payload AaN...  decode a payload (base64, as in block dumps)
+//	dsb-debug verify -store blocks.log [-height 42]     recompute Merkle roots, hashes and links
+//	dsb-debug diff   a.log b.log                        find where two logs diverge
+//
+// verify and diff exit with status 1 when they find a problem or a difference.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// commands maps each subcommand to its implementation. A command parses its
+// own flags from args and writes its report to out.
+var commands = map[string]func(args []string, out io.Writer) error{
+	"block":  runBlock,
+	"tx":     runTx,
+	"verify": runVerify,
+	"diff":   runDiff,
+}
+
+func main() {
+	if len(os.Args) < 2 || commands[os.Args[1]] == nil {
+		fmt.Fprintln(os.Stderr, "usage: dsb-debug block|tx|verify|diff [flags]; run a subcommand with -h for its flags")
+		os.Exit(2)
+	}
+	if err := commands[os.Args[1]](os.Args[2:], os.Stdout); err != nil {
+		if errors.Is(err, errUsage) {
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
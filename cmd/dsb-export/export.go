@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bufio"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"digisocialblock/core/user"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// schemaVersion is stamped on every record and on the cursor. Bump it when a
+// field changes meaning or is removed; adding fields does not require a bump.
+const schemaVersion = 1
+
+// Record kinds. Every block is followed by one "tx" record per transaction,
+// each of which is followed by the "event" records derived from it.
+const (
+	kindBlock = "block"
+	kindTx    = "tx"
+	kindEvent = "event"
+)
+
+// Derived social event types.
+const (
+	eventPostCreated      = "post_created"
+	eventGroupPostCreated = "group_post_created"
+	eventProfileUpdated   = "profile_updated"
+	eventCommentAdded     = "comment_added"
+	eventCommentEdited    = "comment_edited"
+	eventCommentDeleted   = "comment_deleted"
+	eventLiked            = "liked"
+	eventFollowed         = "followed"
+)
+
+// record is one line of the export. Exactly one of Block, Tx and Event is set,
+// matching Kind. Height and BlockHash are repeated on every record so each
+// line can be loaded on its own.
+type record struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	Kind          string       `json:"kind"`
+	Height        int64        `json:"height"`
+	BlockHash     string       `json:"blockHash"`
+	Block         *blockRecord `json:"block,omitempty"`
+	Tx            *txRecord    `json:"tx,omitempty"`
+	Event         *eventRecord `json:"event,omitempty"`
+}
+
+type blockRecord struct {
+	Timestamp     int64  `json:"timestamp"`
+	PrevBlockHash string `json:"prevBlockHash"`
+	TxCount       int    `json:"txCount"`
+}
+
+// txRecord is a transaction with its payload decoded. Payload holds the
+// decoded payload as JSON whatever its wire format; payloads that cannot be
+// decoded are exported base64-encoded in PayloadRaw with the reason in
+// PayloadError, so one bad transaction never stops an export.
+type txRecord struct {
+	ID            string          `json:"id"`
+	Position      int             `json:"position"` // Index of the transaction within its block
+	Timestamp     int64           `json:"timestamp"`
+	Sender        string          `json:"sender"`
+	Type          string          `json:"type"`
+	ChainID       string          `json:"chainId,omitempty"`
+	PayloadFormat string          `json:"payloadFormat,omitempty"` // "json" or "cbor"
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	PayloadRaw    []byte          `json:"payloadRaw,omitempty"`
+	PayloadError  string          `json:"payloadError,omitempty"`
+}
+
+// eventRecord is a social event derived from a transaction.
+type eventRecord struct {
+	Type       string   `json:"type"`
+	Actor      string   `json:"actor"`
+	TxID       string   `json:"txId"`
+	Timestamp  int64    `json:"timestamp"`
+	ContentCID string   `json:"contentCID,omitempty"`
+	GroupID    string   `json:"groupId,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+}
+
+// cursor records how far an export got. NextHeight is the first block not
+// yet exported and LastHash the hash of the block before it, which is checked
+// on resume so that an export never silently continues onto a different chain.
+type cursor struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	NextHeight    int64  `json:"nextHeight"`
+	LastHash      string `json:"lastHash,omitempty"`
+}
+
+// loadCursor reads the cursor at path. A missing file is a fresh export.
+func loadCursor(path string) (cursor, error) {
+	c := cursor{SchemaVersion: schemaVersion}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, fmt.Errorf("failed to read cursor %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("failed to decode cursor %s: %w", path, err)
+	}
+	if c.SchemaVersion != schemaVersion {
+		return c, fmt.Errorf("cursor %s has schema version %d, this exporter writes %d; start a fresh export", path, c.SchemaVersion, schemaVersion)
+	}
+	return c, nil
+}
+
+// saveCursor atomically replaces the cursor at path.
+func saveCursor(path string, c cursor) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cursor %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write cursor %s: %w", path, err)
+	}
+	return nil
+}
+
+// blockSource is the part of ledger.FileBlockStore the exporter reads.
+type blockSource interface {
+	BlockCount() int64
+	BlockByHeight(height int64) (*ledger.Block, error)
+}
+
+// exporter streams blocks from a blockSource as JSONL.
+type exporter struct {
+	blocks blockSource
+	out    *bufio.Writer
+	// checkpoint is called with the cursor after every checkpointEvery blocks
+	// and once at the end, after the output has been flushed.
+	checkpoint      func(cursor) error
+	checkpointEvery int64
+}
+
+// run exports blocks from c.NextHeight up to the current tip and returns the
+// final cursor. Records after the last checkpoint may be written again if an
+// export is interrupted and resumed, so loaders should deduplicate on
+// (kind, blockHash, tx.id / event.type + event.txId).
+func (e *exporter) run(c cursor) (cursor, error) {
+	if c.NextHeight > 0 {
+		prev, err := e.blocks.BlockByHeight(c.NextHeight - 1)
+		if err != nil {
+			return c, fmt.Errorf("cursor refers to block %d: %w", c.NextHeight-1, err)
+		}
+		if prev.Hash != c.LastHash {
+			return c, fmt.Errorf("block %d has hash %s but the cursor expects %s; the chain was replaced since the last export", c.NextHeight-1, prev.Hash, c.LastHash)
+		}
+	}
+	tip := e.blocks.BlockCount()
+	for height := c.NextHeight; height < tip; height++ {
+		block, err := e.blocks.BlockByHeight(height)
+		if err != nil {
+			return c, err
+		}
+		if err := e.writeBlock(height, block); err != nil {
+			return c, err
+		}
+		c.NextHeight, c.LastHash = height+1, block.Hash
+		if e.checkpointEvery > 0 && c.NextHeight%e.checkpointEvery == 0 {
+			if err := e.commit(c); err != nil {
+				return c, err
+			}
+		}
+	}
+	return c, e.commit(c)
+}
+
+// commit flushes the output and then records c, so the cursor never points
+// past data that has not been written.
+func (e *exporter) commit(c cursor) error {
+	if err := e.out.Flush(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	if e.checkpoint == nil {
+		return nil
+	}
+	return e.checkpoint(c)
+}
+
+// writeBlock writes the records for one block.
+func (e *exporter) writeBlock(height int64, block *ledger.Block) error {
+	base := record{SchemaVersion: schemaVersion, Height: height, BlockHash: block.Hash}
+
+	rec := base
+	rec.Kind = kindBlock
+	rec.Block = &blockRecord{Timestamp: block.Timestamp, PrevBlockHash: block.PrevBlockHash, TxCount: len(block.Transactions)}
+	if err := e.write(rec); err != nil {
+		return err
+	}
+	for i, tx := range block.Transactions {
+		if tx == nil {
+			continue
+		}
+		txRec, decoded := decodeTx(i, tx)
+		rec := base
+		rec.Kind = kindTx
+		rec.Tx = txRec
+		if err := e.write(rec); err != nil {
+			return err
+		}
+		for _, ev := range deriveEvents(tx, decoded) {
+			rec := base
+			rec.Kind = kindEvent
+			rec.Event = ev
+			if err := e.write(rec); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *exporter) write(rec record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s record at height %d: %w", rec.Kind, rec.Height, err)
+	}
+	line = append(line, '\n')
+	if _, err := e.out.Write(line); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+// decodeTx builds the tx record and returns the decoded payload value (a
+// struct from the ledger's payload decoder registry, such as *social.Post,
+// or nil) for deriveEvents.
+func decodeTx(position int, tx *ledger.Transaction) (*txRecord, interface{}) {
+	rec := &txRecord{
+		ID:        tx.ID,
+		Position:  position,
+		Timestamp: tx.Timestamp,
+		Sender:    tx.SenderPublicKey,
+		Type:      string(tx.Type),
+		ChainID:   tx.ChainID,
+	}
+	if len(tx.Payload) == 0 {
+		return rec, nil
+	}
+	format, err := ledger.DetectPayloadFormat(tx.Payload)
+	if err != nil {
+		rec.PayloadRaw, rec.PayloadError = tx.Payload, err.Error()
+		return rec, nil
+	}
+	rec.PayloadFormat = "json"
+	if format == ledger.PayloadFormatCBOR {
+		rec.PayloadFormat = "cbor"
+	}
+
+	decoded, err := ledger.DecodeTypedPayload(tx.Type, tx.Payload)
+	if errors.Is(err, ledger.ErrNoPayloadDecoder) {
+		// No registered payload type: pass JSON through as is. CBOR needs a
+		// target type to decode, so it is exported raw.
+		if format == ledger.PayloadFormatJSON && json.Valid(tx.Payload) {
+			rec.Payload = json.RawMessage(tx.Payload)
+		} else {
+			rec.PayloadRaw = tx.Payload
+		}
+		return rec, nil
+	}
+	if err == nil {
+		// Registered decoders do not validate; export only what the ledger
+		// would accept as decoded.
+		err = tx.ValidatePayload()
+	}
+	if err != nil {
+		rec.PayloadRaw, rec.PayloadError = tx.Payload, err.Error()
+		return rec, nil
+	}
+	if rec.Payload, err = json.Marshal(decoded); err != nil {
+		rec.PayloadRaw, rec.PayloadError = tx.Payload, err.Error()
+		return rec, nil
+	}
+	return rec, decoded
+}
+
+// deriveEvents returns the social events a transaction represents. Post and
+// profile events need a decoded payload; interactions are derived from the
+// transaction type alone.
+func deriveEvents(tx *ledger.Transaction, decoded interface{}) []*eventRecord {
+	ev := &eventRecord{Actor: tx.SenderPublicKey, TxID: tx.ID, Timestamp: tx.Timestamp}
+	switch tx.Type {
+	case ledger.PostCreated:
+		post, ok := decoded.(*social.Post)
+		if !ok {
+			return nil
+		}
+		ev.Type, ev.ContentCID, ev.Tags = eventPostCreated, post.ContentCID, post.Tags
+		if post.GroupID != "" {
+			ev.Type, ev.GroupID = eventGroupPostCreated, post.GroupID
+		}
+	case ledger.ProfileUpdate:
+		if _, ok := decoded.(*user.Profile); !ok {
+			return nil
+		}
+		ev.Type = eventProfileUpdated
+	case ledger.CommentAdded:
+		ev.Type = eventCommentAdded
+	case ledger.CommentEdited:
+		ev.Type = eventCommentEdited
+	case ledger.CommentDeleted:
+		ev.Type = eventCommentDeleted
+	case ledger.Like:
+		ev.Type = eventLiked
+	case ledger.UserFollowed:
+		ev.Type = eventFollowed
+	default:
+		return nil
+	}
+	return []*eventRecord{ev}
+}
+
+// openOutput opens the export destination. Resumed exports append to an
+// existing file; "-" is standard output.
+func openOutput(path string, resume bool) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if resume {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output %s: %w", path, err)
+	}
+	return f, nil
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
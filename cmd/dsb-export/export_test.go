@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"digisocialblock/core/user"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const exportTestSender = "04aa"
+
+// exportTestBlocks is an in-memory blockSource.
+type exportTestBlocks []*ledger.Block
+
+func (b exportTestBlocks) BlockCount() int64 { return int64(len(b)) }
+
+func (b exportTestBlocks) BlockByHeight(height int64) (*ledger.Block, error) {
+	if height < 0 || height >= int64(len(b)) {
+		return nil, fmt.Errorf("block %d not found", height)
+	}
+	return b[height], nil
+}
+
+func exportTestTx(t *testing.T, txType ledger.TransactionType, payload []byte) *ledger.Transaction {
+	t.Helper()
+	tx, err := ledger.NewTransaction(exportTestSender, txType, payload)
+	if err != nil {
+		t.Fatalf("NewTransaction() error = %v", err)
+	}
+	return tx
+}
+
+func exportTestChain(t *testing.T, txsPerBlock ...[]*ledger.Transaction) exportTestBlocks {
+	t.Helper()
+	var blocks exportTestBlocks
+	prev := ""
+	for i, txs := range txsPerBlock {
+		block, err := ledger.NewBlock(int64(i), prev, txs)
+		if err != nil {
+			t.Fatalf("NewBlock() error = %v", err)
+		}
+		blocks = append(blocks, block)
+		prev = block.Hash
+	}
+	return blocks
+}
+
+func exportRecords(t *testing.T, data []byte) []record {
+	t.Helper()
+	var records []record
+	for _, line := range bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n")) {
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			t.Fatalf("line %q is not a JSON record: %v", line, err)
+		}
+		if rec.SchemaVersion != schemaVersion {
+			t.Fatalf("record schemaVersion = %d, want %d", rec.SchemaVersion, schemaVersion)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestExporter_DecodesPayloadsAndDerivesEvents(t *testing.T) {
+	jsonPost, _ := social.NewPost(exportTestSender, "bafyjson", "Hello", []string{"go"}).ToPayload(ledger.PayloadFormatJSON)
+	groupPost := social.NewPost(exportTestSender, "bafygroup", "", nil)
+	groupPost.GroupID, groupPost.KeyEpoch = "g1", 1
+	cborPost, err := groupPost.ToPayload(ledger.PayloadFormatCBOR)
+	if err != nil {
+		t.Fatalf("ToPayload(CBOR) error = %v", err)
+	}
+	profile, _ := user.NewProfile(exportTestSender, "Alice", "").ToPayload(ledger.PayloadFormatJSON)
+	chain := exportTestChain(t,
+		nil,
+		[]*ledger.Transaction{
+			exportTestTx(t, ledger.PostCreated, jsonPost),
+			exportTestTx(t, ledger.PostCreated, cborPost),
+			exportTestTx(t, ledger.ProfileUpdate, profile),
+			exportTestTx(t, ledger.Like, []byte(`{"target": "abc"}`)),
+			exportTestTx(t, ledger.PostCreated, []byte{0x7f, 0x00}),
+		},
+	)
+
+	var out bytes.Buffer
+	exp := &exporter{blocks: chain, out: bufio.NewWriter(&out)}
+	end, err := exp.run(cursor{SchemaVersion: schemaVersion})
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if end.NextHeight != 2 || end.LastHash != chain[1].Hash {
+		t.Errorf("run() cursor = %+v, want next height 2 at %s", end, chain[1].Hash)
+	}
+
+	var kinds []string
+	var txs []*txRecord
+	var events []*eventRecord
+	for _, rec := range exportRecords(t, out.Bytes()) {
+		kinds = append(kinds, rec.Kind)
+		if rec.Tx != nil {
+			txs = append(txs, rec.Tx)
+		}
+		if rec.Event != nil {
+			events = append(events, rec.Event)
+		}
+	}
+	wantKinds := "block block tx event tx event tx event tx event tx"
+	if got := strings.Join(kinds, " "); got != wantKinds {
+		t.Errorf("record kinds = %q, want %q", got, wantKinds)
+	}
+	if len(txs) != 5 || len(events) != 4 {
+		t.Fatalf("got %d tx and %d event records, want 5 and 4", len(txs), len(events))
+	}
+
+	var post social.Post
+	if err := json.Unmarshal(txs[1].Payload, &post); err != nil || txs[1].PayloadFormat != "cbor" || post.ContentCID != "bafygroup" {
+		t.Errorf("CBOR post exported as format %q payload %s (%v), want decoded JSON", txs[1].PayloadFormat, txs[1].Payload, err)
+	}
+	if string(txs[3].Payload) != `{"target":"abc"}` {
+		t.Errorf("untyped JSON payload = %s, want it passed through", txs[3].Payload)
+	}
+	if txs[4].PayloadError == "" || !bytes.Equal(txs[4].PayloadRaw, []byte{0x7f, 0x00}) {
+		t.Errorf("undecodable payload exported as %+v, want raw bytes and an error", txs[4])
+	}
+
+	wantEvents := []string{eventPostCreated, eventGroupPostCreated, eventProfileUpdated, eventLiked}
+	for i, ev := range events {
+		if ev.Type != wantEvents[i] || ev.Actor != exportTestSender || ev.TxID != txs[i].ID {
+			t.Errorf("event %d = %+v, want %s by %s for %s", i, ev, wantEvents[i], exportTestSender, txs[i].ID)
+		}
+	}
+	if events[0].ContentCID != "bafyjson" || len(events[0].Tags) != 1 || events[1].GroupID != "g1" {
+		t.Errorf("post events = %+v, %+v; want content CID, tags and group ID carried over", events[0], events[1])
+	}
+}
+
+func TestExporter_ResumesFromCursor(t *testing.T) {
+	cursorPath := filepath.Join(t.TempDir(), "export.cursor")
+	chain := exportTestChain(t, nil, nil, nil, nil)
+
+	var first bytes.Buffer
+	var saves int
+	exp := &exporter{
+		blocks:          chain[:3],
+		out:             bufio.NewWriter(&first),
+		checkpoint:      func(c cursor) error { saves++; return saveCursor(cursorPath, c) },
+		checkpointEvery: 2,
+	}
+	if _, err := exp.run(cursor{SchemaVersion: schemaVersion}); err != nil {
+		t.Fatalf("first run() error = %v", err)
+	}
+	if saves != 2 {
+		t.Errorf("cursor saved %d times, want 2 (after block 1 and at the end)", saves)
+	}
+
+	start, err := loadCursor(cursorPath)
+	if err != nil || start.NextHeight != 3 {
+		t.Fatalf("loadCursor() = %+v, %v; want next height 3", start, err)
+	}
+	var second bytes.Buffer
+	exp = &exporter{blocks: chain, out: bufio.NewWriter(&second)}
+	if _, err := exp.run(start); err != nil {
+		t.Fatalf("resumed run() error = %v", err)
+	}
+	records := exportRecords(t, second.Bytes())
+	if len(records) != 1 || records[0].Height != 3 {
+		t.Errorf("resumed run exported %+v, want only block 3", records)
+	}
+
+	other := exportTestChain(t, nil, []*ledger.Transaction{exportTestTx(t, ledger.Like, nil)}, nil, nil)
+	exp = &exporter{blocks: other, out: bufio.NewWriter(&second)}
+	if _, err := exp.run(start); err == nil || !strings.Contains(err.Error(), "chain was replaced") {
+		t.Errorf("run() on a different chain error = %v, want a cursor mismatch", err)
+	}
+}
+
+func TestLoadCursor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.cursor")
+	c, err := loadCursor(path)
+	if err != nil || c.NextHeight != 0 {
+		t.Fatalf("loadCursor(missing) = %+v, %v; want a fresh cursor", c, err)
+	}
+	if err := saveCursor(path, cursor{SchemaVersion: schemaVersion + 1, NextHeight: 5}); err != nil {
+		t.Fatalf("saveCursor() error = %v", err)
+	}
+	if _, err := loadCursor(path); err == nil {
+		t.Error("loadCursor() accepted a cursor from another schema version")
+	}
+}
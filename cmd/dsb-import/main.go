@@ -0,0 +1,215 @@
+// Command dsb-import migrates a Twitter or Mastodon export archive to the
+// chain. Each post's text and media are published to DDS and the post is
+// re-signed with the user's wallet as a PostCreated transaction whose post
+// records the original publication time (see migrate.Importer).
+//
+// Transactions are either submitted to a node (-api) or written as JSON
+// Lines (-out) for later submission. Progress is recorded in -progress, so an
+// interrupted import is resumed by running the same command again.
+//
+//	go run ./cmd/dsb-import -archive twitter.zip -wallet wallet.json \
+//	    -dds-dir ./chunks -api http://localhost:8080 -progress twitter.progress
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"digisocialblock/core/api/client"
+	"digisocialblock/core/content"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/migrate"
+	"digisocialblock/pkg/dds/chunking"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+)
+
+func main() {
+	archivePath := flag.String("archive", "", "export archive: a .zip file or an unpacked directory (required)")
+	walletPath := flag.String("wallet", "", "wallet file to sign the imported posts with (required)")
+	ddsDir := flag.String("dds-dir", "", "directory the content chunks are written to (required)")
+	apiURL := flag.String("api", "", "node API to submit transactions to")
+	outPath := flag.String("out", "", "JSONL file to write transactions to instead of submitting them")
+	chainID := flag.String("chain-id", "", "chain ID for signing API requests")
+	progressPath := flag.String("progress", "", "progress log for resuming (default: <archive>.progress)")
+	rate := flag.Float64("rate", migrate.DefaultMaxPostsPerSecond, "maximum posts submitted per second")
+	flag.Parse()
+
+	if *archivePath == "" || *walletPath == "" || *ddsDir == "" || (*apiURL == "") == (*outPath == "") {
+		fmt.Fprintln(os.Stderr, "-archive, -wallet, -dds-dir and exactly one of -api and -out are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *progressPath == "" {
+		*progressPath = filepath.Clean(*archivePath) + ".progress"
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, *archivePath, *walletPath, *ddsDir, *apiURL, *outPath, *chainID, *progressPath, *rate); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, archivePath, walletPath, ddsDir, apiURL, outPath, chainID, progressPath string, rate float64) error {
+	archive, closeArchive, err := openArchive(archivePath)
+	if err != nil {
+		return err
+	}
+	defer closeArchive()
+	posts, err := migrate.ReadArchive(archive)
+	if err != nil {
+		return fmt.Errorf("failed to read archive %s: %w", archivePath, err)
+	}
+
+	wallet, err := identity.LoadWalletFromFile(walletPath)
+	if err != nil {
+		return err
+	}
+	defer wallet.Close()
+
+	if err := os.MkdirAll(ddsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create DDS directory: %w", err)
+	}
+	publisher, err := content.NewContentPublisher(fixedSizeChunker{size: chunkSize}, dirStorage(ddsDir), noopOriginator{})
+	if err != nil {
+		return err
+	}
+
+	var sink migrate.TransactionSink
+	if apiURL != "" {
+		c, err := client.New(apiURL, client.WithSigner(wallet, chainID))
+		if err != nil {
+			return err
+		}
+		sink = apiSink{c}
+	} else {
+		f, err := os.OpenFile(outPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open output %s: %w", outPath, err)
+		}
+		defer f.Close()
+		sink = fileSink{f}
+	}
+
+	progress, err := migrate.OpenProgress(progressPath)
+	if err != nil {
+		return err
+	}
+	defer progress.Close()
+	importer, err := migrate.NewImporter(publisher, wallet, sink, progress, migrate.ImporterOptions{MaxPostsPerSecond: rate})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "importing %d posts (%d already imported)\n", len(posts), progress.Len())
+	stats, err := importer.Import(ctx, posts, archive)
+	for _, w := range stats.Warnings {
+		fmt.Fprintln(os.Stderr, "warning:", w)
+	}
+	fmt.Fprintf(os.Stderr, "imported %d posts, skipped %d\n", stats.Imported, stats.Skipped)
+	return err
+}
+
+// openArchive opens a .zip archive or an unpacked archive directory.
+func openArchive(path string) (fs.FS, func() error, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	if info.IsDir() {
+		return os.DirFS(path), func() error { return nil }, nil
+	}
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open archive %s: %w", path, err)
+	}
+	return zr, zr.Close, nil
+}
+
+// apiSink submits transactions to a node.
+type apiSink struct{ c *client.Client }
+
+func (s apiSink) Submit(ctx context.Context, tx *ledger.Transaction) error {
+	_, err := s.c.SubmitTransaction(ctx, client.FromLedger(tx))
+	return err
+}
+
+// fileSink appends transactions to a JSONL file.
+type fileSink struct{ f *os.File }
+
+func (s fileSink) Submit(_ context.Context, tx *ledger.Transaction) error {
+	line, err := json.Marshal(tx)
+	if err != nil {
+		return err
+	}
+	if _, err := s.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+// --- Local DDS publishing ---
+
+// chunkSize is the chunk size used for imported content.
+const chunkSize = 256 << 10
+
+// fixedSizeChunker splits content into fixed-size chunks addressed by their
+// SHA-256, with the manifest addressed by the hash of its chunk CIDs.
+type fixedSizeChunker struct{ size int }
+
+func (c fixedSizeChunker) ChunkData(r io.Reader) (*chunking.ContentManifestV1, []chunking.DataChunk, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read content: %w", err)
+	}
+	manifest := &chunking.ContentManifestV1{Version: 1, TotalSize: int64(len(data)), EncryptionMethod: "none"}
+	var chunks []chunking.DataChunk
+	var cids bytes.Buffer
+	for off := 0; off < len(data); off += c.size {
+		end := off + c.size
+		if end > len(data) {
+			end = len(data)
+		}
+		sum := sha256.Sum256(data[off:end])
+		cid := hex.EncodeToString(sum[:])
+		chunks = append(chunks, chunking.DataChunk{ChunkCID: cid, Data: data[off:end], Size: int64(end - off)})
+		manifest.Chunks = append(manifest.Chunks, chunking.ChunkInfo{ChunkCID: cid, Size: int64(end - off)})
+		cids.WriteString(cid)
+	}
+	sum := sha256.Sum256(cids.Bytes())
+	manifest.ManifestCID = hex.EncodeToString(sum[:])
+	return manifest, chunks, nil
+}
+
+// dirStorage stores each chunk as a file named by its CID, ready to be seeded
+// by a DDS node.
+type dirStorage string
+
+func (d dirStorage) StoreChunk(chunkID string, data []byte) error {
+	return os.WriteFile(filepath.Join(string(d), chunkID), data, 0644)
+}
+
+func (d dirStorage) RetrieveChunk(chunkID string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(string(d), chunkID))
+}
+
+func (d dirStorage) ChunkExists(chunkID string) bool {
+	_, err := os.Stat(filepath.Join(string(d), chunkID))
+	return err == nil
+}
+
+// noopOriginator leaves advertising to the DDS node that seeds the chunks.
+type noopOriginator struct{}
+
+func (noopOriginator) AdvertiseManifest(*chunking.ContentManifestV1) error { return nil }
@@ -0,0 +1,479 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"digisocialblock/core/api/client"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/mobile"
+	"digisocialblock/core/social"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// config is a load run, as set by the command-line flags.
+type config struct {
+	Node        string
+	ChainID     string
+	Rate        float64 // Transactions per second
+	PublishRate float64 // Content publishes per second
+	PublishSize int
+	DDSDir      string // Empty stores published chunks in memory
+	Duration    time.Duration
+	Workers     int
+	Senders     int
+	Poll        time.Duration // Zero disables inclusion tracking
+	Drain       time.Duration
+}
+
+func (cfg config) validate() error {
+	switch {
+	case cfg.Rate < 0 || cfg.PublishRate < 0:
+		return fmt.Errorf("rates cannot be negative")
+	case cfg.Rate == 0 && cfg.PublishRate == 0:
+		return fmt.Errorf("-rate or -publish-rate must be positive")
+	case cfg.PublishRate > 0 && cfg.PublishSize <= 0:
+		return fmt.Errorf("-publish-size must be positive")
+	case cfg.Duration <= 0 || cfg.Workers <= 0 || cfg.Senders <= 0:
+		return fmt.Errorf("-duration, -workers and -senders must be positive")
+	}
+	return nil
+}
+
+type jobKind int
+
+const (
+	jobTx jobKind = iota
+	jobPublish
+)
+
+// latencies collects the durations of one kind of operation.
+type latencies struct {
+	mu     sync.Mutex
+	values []time.Duration
+	errors int
+}
+
+func (l *latencies) record(d time.Duration, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err != nil {
+		l.errors++
+		return
+	}
+	l.values = append(l.values, d)
+}
+
+// Summary reports the successful operations of one kind and how many failed.
+// Durations are in nanoseconds in JSON.
+type Summary struct {
+	Count  int           `json:"count"`
+	Errors int           `json:"errors"`
+	P50    time.Duration `json:"p50"`
+	P90    time.Duration `json:"p90"`
+	P99    time.Duration `json:"p99"`
+	Max    time.Duration `json:"max"`
+}
+
+func (l *latencies) summary() Summary {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sorted := append([]time.Duration(nil), l.values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	s := Summary{Count: len(sorted), Errors: l.errors}
+	if len(sorted) > 0 {
+		s.P50, s.P90, s.P99 = percentile(sorted, 50), percentile(sorted, 90), percentile(sorted, 99)
+		s.Max = sorted[len(sorted)-1]
+	}
+	return s
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted, which must
+// not be empty.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Report is the outcome of a load run.
+type Report struct {
+	Node     string        `json:"node"`
+	Elapsed  time.Duration `json:"elapsed"`
+	Workers  int           `json:"workers"`
+	Submit   Summary       `json:"submit"`   // Transactions for made-up CIDs
+	Publish  Summary       `json:"publish"`  // Chunking and storing published content
+	Posted   Summary       `json:"posted"`   // Transactions for published content
+	Included *Summary      `json:"included"` // Admission to block inclusion; nil without tracking
+	// NotIncluded counts admitted transactions still pending when the drain
+	// period ran out.
+	NotIncluded int `json:"notIncluded"`
+	// Skipped counts jobs not started because every worker was busy.
+	Skipped int64 `json:"skipped"`
+	// ErrorCodes counts failed submissions by the errorCode of the node's
+	// answer, or by "http <status>" or "transport" when it had none.
+	ErrorCodes map[string]int `json:"errorCodes,omitempty"`
+}
+
+func (r *Report) write(w io.Writer) {
+	fmt.Fprintf(w, "%s: %s with %d workers\n\n", r.Node, r.Elapsed.Round(time.Millisecond), r.Workers)
+	fmt.Fprintf(w, "%-10s %8s %8s %10s %10s %10s %10s\n", "", "count", "errors", "p50", "p90", "p99", "max")
+	rows := []struct {
+		name string
+		s    *Summary
+	}{{"submit", &r.Submit}, {"publish", &r.Publish}, {"posted", &r.Posted}, {"included", r.Included}}
+	for _, row := range rows {
+		if row.s == nil || (row.s != &r.Submit && row.s.Count+row.s.Errors == 0) {
+			continue // Only the kinds of job that ran
+		}
+		s := row.s
+		fmt.Fprintf(w, "%-10s %8d %8d %10s %10s %10s %10s\n", row.name, s.Count, s.Errors,
+			roundLatency(s.P50), roundLatency(s.P90), roundLatency(s.P99), roundLatency(s.Max))
+	}
+	fmt.Fprintf(w, "\nthroughput: %.1f tx/s admitted\n", float64(r.Submit.Count+r.Posted.Count)/r.Elapsed.Seconds())
+	if r.Included != nil {
+		fmt.Fprintf(w, "not included after drain: %d\n", r.NotIncluded)
+	}
+	fmt.Fprintf(w, "skipped (all workers busy): %d\n", r.Skipped)
+	codes := make([]string, 0, len(r.ErrorCodes))
+	for code := range r.ErrorCodes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "  %-16s %d\n", code, r.ErrorCodes[code])
+	}
+}
+
+func roundLatency(d time.Duration) time.Duration {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond)
+	}
+	return d.Round(100 * time.Microsecond)
+}
+
+// loadgen runs the jobs of one load run.
+type loadgen struct {
+	cfg     config
+	node    *client.Client
+	wallets []*identity.Wallet
+	content *mobile.Content // Nil without publishes
+	tracker *inclusionTracker
+
+	submit, publish, posted latencies
+	seq                     uint64 // Atomic; numbers jobs
+	skipped                 int64  // Atomic
+	runID                   string // Distinguishes this run's CIDs from earlier runs'
+
+	mu         sync.Mutex
+	errorCodes map[string]int
+}
+
+// run generates load as configured and reports the outcome. Cancelling ctx
+// stops the load early; the report covers what ran.
+func run(ctx context.Context, cfg config) (*Report, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	node, err := client.New(cfg.Node)
+	if err != nil {
+		return nil, err
+	}
+	lg := &loadgen{cfg: cfg, node: node, runID: time.Now().UTC().Format("20060102T150405"), errorCodes: make(map[string]int)}
+	for i := 0; i < cfg.Senders; i++ {
+		wallet, err := identity.NewWallet()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create sender wallet: %w", err)
+		}
+		defer wallet.Close()
+		lg.wallets = append(lg.wallets, wallet)
+	}
+	if cfg.PublishRate > 0 {
+		var store mobile.ChunkStore = newMemStore()
+		if cfg.DDSDir != "" {
+			if store, err = mobile.NewDirStore(cfg.DDSDir); err != nil {
+				return nil, err
+			}
+		}
+		if lg.content, err = mobile.NewContent(store); err != nil {
+			return nil, err
+		}
+	}
+
+	trackCtx, stopTracking := context.WithCancel(ctx)
+	defer stopTracking()
+	trackDone := make(chan error, 1)
+	if cfg.Poll > 0 {
+		lg.tracker = newInclusionTracker(node)
+		go func() { trackDone <- lg.tracker.run(trackCtx, cfg.Poll) }()
+	}
+
+	start := time.Now()
+	jobs := make(chan jobKind)
+	var workers sync.WaitGroup
+	for i := 0; i < cfg.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for kind := range jobs {
+				lg.do(ctx, kind)
+			}
+		}()
+	}
+	loadCtx, stopLoad := context.WithTimeout(ctx, cfg.Duration)
+	defer stopLoad()
+	var pacers sync.WaitGroup
+	for kind, rate := range map[jobKind]float64{jobTx: cfg.Rate, jobPublish: cfg.PublishRate} {
+		if rate > 0 {
+			pacers.Add(1)
+			go func(kind jobKind, rate float64) {
+				defer pacers.Done()
+				lg.pace(loadCtx, jobs, kind, rate)
+			}(kind, rate)
+		}
+	}
+	pacers.Wait()
+	close(jobs)
+	workers.Wait()
+	elapsed := time.Since(start)
+
+	rep := &Report{Node: cfg.Node, Elapsed: elapsed, Workers: cfg.Workers, Skipped: atomic.LoadInt64(&lg.skipped)}
+	if lg.tracker != nil {
+		lg.tracker.drain(ctx, cfg.Drain)
+		stopTracking()
+		if err := <-trackDone; err != nil {
+			return nil, err
+		}
+		included := lg.tracker.delays.summary()
+		rep.Included, rep.NotIncluded = &included, lg.tracker.pendingCount()
+	}
+	rep.Submit, rep.Publish, rep.Posted = lg.submit.summary(), lg.publish.summary(), lg.posted.summary()
+	if len(lg.errorCodes) > 0 {
+		rep.ErrorCodes = lg.errorCodes
+	}
+	return rep, nil
+}
+
+// pace offers a job of kind to the workers rate times a second until ctx is
+// done. A job no worker is free to take is skipped.
+func (lg *loadgen) pace(ctx context.Context, jobs chan<- jobKind, kind jobKind, rate float64) {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			select {
+			case jobs <- kind:
+			default:
+				atomic.AddInt64(&lg.skipped, 1)
+			}
+		}
+	}
+}
+
+// do runs one job. Requests use the run's parent context, so jobs in flight
+// when the load period ends still complete.
+func (lg *loadgen) do(ctx context.Context, kind jobKind) {
+	n := atomic.AddUint64(&lg.seq, 1)
+	wallet := lg.wallets[n%uint64(len(lg.wallets))]
+	contentCID := fmt.Sprintf("loadgen-%s-%d", lg.runID, n)
+	stats := &lg.submit
+	if kind == jobPublish {
+		data := make([]byte, lg.cfg.PublishSize)
+		rand.Read(data)
+		start := time.Now()
+		cid, err := lg.content.PublishMedia(data)
+		lg.publish.record(time.Since(start), err)
+		if err != nil {
+			lg.countError(err)
+			return
+		}
+		contentCID, stats = cid, &lg.posted
+	}
+
+	tx, err := postTx(wallet, lg.cfg.ChainID, contentCID)
+	if err != nil {
+		stats.record(0, err)
+		lg.countError(err)
+		return
+	}
+	start := time.Now()
+	_, err = lg.node.SubmitTransaction(ctx, client.FromLedger(tx))
+	stats.record(time.Since(start), err)
+	if err != nil {
+		lg.countError(err)
+		return
+	}
+	if lg.tracker != nil {
+		lg.tracker.add(tx.ID, time.Now())
+	}
+}
+
+// postTx returns a PostCreated transaction by wallet for contentCID, signed
+// for chainID.
+func postTx(wallet *identity.Wallet, chainID, contentCID string) (*ledger.Transaction, error) {
+	payload, err := social.NewPost(wallet.Address, contentCID, "load test", nil).ToPayload(ledger.PayloadFormatCBOR)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := ledger.NewTransaction(wallet.Address, ledger.PostCreated, payload)
+	if err != nil {
+		return nil, err
+	}
+	tx.ChainID = chainID
+	if err := wallet.SignTransaction(tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (lg *loadgen) countError(err error) {
+	code := "transport"
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) {
+		code = apiErr.ErrorCode
+		if code == "" {
+			code = fmt.Sprintf("http %d", apiErr.StatusCode)
+		}
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		code = "timeout"
+	}
+	lg.mu.Lock()
+	lg.errorCodes[code]++
+	lg.mu.Unlock()
+}
+
+// inclusionTracker polls the node for admitted transactions until they appear
+// in a block.
+type inclusionTracker struct {
+	node   *client.Client
+	delays latencies
+
+	mu      sync.Mutex
+	pending map[string]time.Time // Admission time by transaction ID
+}
+
+func newInclusionTracker(node *client.Client) *inclusionTracker {
+	return &inclusionTracker{node: node, pending: make(map[string]time.Time)}
+}
+
+func (it *inclusionTracker) add(txID string, admitted time.Time) {
+	it.mu.Lock()
+	it.pending[txID] = admitted
+	it.mu.Unlock()
+}
+
+func (it *inclusionTracker) pendingCount() int {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	return len(it.pending)
+}
+
+// run polls every interval until ctx is done. It fails if the node cannot be
+// queried, e.g. because it does not serve GraphQL.
+func (it *inclusionTracker) run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := it.poll(ctx); err != nil && ctx.Err() == nil {
+				return fmt.Errorf("inclusion tracking failed: %w", err)
+			}
+		}
+	}
+}
+
+const postQuery = `query($id: ID!) { post(id: $id) { blockIndex } }`
+
+// poll looks up every pending transaction once.
+func (it *inclusionTracker) poll(ctx context.Context) error {
+	it.mu.Lock()
+	ids := make([]string, 0, len(it.pending))
+	for id := range it.pending {
+		ids = append(ids, id)
+	}
+	it.mu.Unlock()
+
+	for _, id := range ids {
+		resp, err := it.node.GraphQL(ctx, &client.GraphQLRequest{Query: postQuery, Variables: map[string]interface{}{"id": id}})
+		if err != nil {
+			return err
+		}
+		if len(resp.Errors) > 0 {
+			return fmt.Errorf("post(%s): %s", id, resp.Errors[0].Message)
+		}
+		if resp.Data["post"] == nil {
+			continue
+		}
+		now := time.Now()
+		it.mu.Lock()
+		admitted := it.pending[id]
+		delete(it.pending, id)
+		it.mu.Unlock()
+		it.delays.record(now.Sub(admitted), nil)
+	}
+	return nil
+}
+
+// drain waits up to timeout for the pending transactions to be included.
+func (it *inclusionTracker) drain(ctx context.Context, timeout time.Duration) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	check := time.NewTicker(10 * time.Millisecond)
+	defer check.Stop()
+	for it.pendingCount() > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline.C:
+			return
+		case <-check.C:
+		}
+	}
+}
+
+// memStore is an in-memory mobile.ChunkStore, for publishing without
+// touching the disk.
+type memStore struct {
+	mu     sync.RWMutex
+	chunks map[string][]byte
+}
+
+func newMemStore() *memStore { return &memStore{chunks: make(map[string][]byte)} }
+
+func (m *memStore) Put(cid string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.chunks[cid] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *memStore) Get(cid string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.chunks[cid]
+	if !ok {
+		return nil, fmt.Errorf("chunk %s not found", cid)
+	}
+	return data, nil
+}
+
+func (m *memStore) Has(cid string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.chunks[cid]
+	return ok
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"digisocialblock/core/api"
+	"digisocialblock/core/graphql"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// loadgenTestNode admits signed transactions and includes everything
+// admitted so far each time mine is called.
+type loadgenTestNode struct {
+	mu       sync.Mutex
+	pending  []*ledger.Transaction
+	included map[string]*ledger.Transaction
+	reject   error
+}
+
+func (n *loadgenTestNode) Add(tx *ledger.Transaction) error {
+	if ok, err := tx.VerifySignature(); !ok {
+		return err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.reject != nil {
+		return n.reject
+	}
+	n.pending = append(n.pending, tx)
+	return nil
+}
+
+func (n *loadgenTestNode) mine() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, tx := range n.pending {
+		n.included[tx.ID] = tx
+	}
+	n.pending = nil
+}
+
+func (n *loadgenTestNode) GetPost(txID string) (social.FeedEntry, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	tx, ok := n.included[txID]
+	if !ok {
+		return social.FeedEntry{}, false
+	}
+	return social.FeedEntry{TxID: tx.ID, AuthorPublicKey: tx.SenderPublicKey, BlockIndex: 1}, true
+}
+
+func (n *loadgenTestNode) GetUserFeed(string, int) []social.FeedEntry { return nil }
+func (n *loadgenTestNode) GetGlobalFeed(int) []social.FeedEntry       { return nil }
+func (n *loadgenTestNode) GetComment(string) (social.CommentEntry, bool) {
+	return social.CommentEntry{}, false
+}
+func (n *loadgenTestNode) GetCommentHistory(string) []social.CommentRevision { return nil }
+func (n *loadgenTestNode) GetComments(string) []social.CommentEntry          { return nil }
+func (n *loadgenTestNode) GetReplies(string) []social.CommentEntry           { return nil }
+func (n *loadgenTestNode) GetFollowers(string) []social.FollowEdge           { return nil }
+func (n *loadgenTestNode) GetFollowing(string) []social.FollowEdge           { return nil }
+
+// loadgenTestServer serves node's API, mining a block every 20ms until the
+// test ends.
+func loadgenTestServer(t *testing.T, node *loadgenTestNode) string {
+	t.Helper()
+	schema, err := graphql.NewSocialSchema(node, node, nil)
+	if err != nil {
+		t.Fatalf("NewSocialSchema() error = %v", err)
+	}
+	s, err := api.NewServer(node, api.ServerOptions{GraphQL: graphql.Handler(schema)})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				node.mine()
+			}
+		}
+	}()
+	return srv.URL
+}
+
+func loadgenTestConfig(nodeURL string) config {
+	return config{
+		Node: nodeURL, ChainID: ledger.DefaultChainID,
+		Rate: 200, PublishRate: 20, PublishSize: 4096,
+		Duration: 300 * time.Millisecond, Workers: 4, Senders: 2,
+		Poll: 10 * time.Millisecond, Drain: 5 * time.Second,
+	}
+}
+
+func TestRun_ReportsLatencyAndInclusion(t *testing.T) {
+	node := &loadgenTestNode{included: make(map[string]*ledger.Transaction)}
+	rep, err := run(context.Background(), loadgenTestConfig(loadgenTestServer(t, node)))
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	if rep.Submit.Count == 0 || rep.Publish.Count == 0 || rep.Posted.Count == 0 {
+		t.Fatalf("report = %+v, want transactions, publishes and their posts", rep)
+	}
+	if rep.Submit.Errors+rep.Publish.Errors+rep.Posted.Errors != 0 || len(rep.ErrorCodes) != 0 {
+		t.Errorf("errors in report = %+v", rep)
+	}
+	if s := rep.Submit; s.P50 > s.P90 || s.P90 > s.P99 || s.P99 > s.Max || s.P50 <= 0 {
+		t.Errorf("submit percentiles not ordered: %+v", s)
+	}
+	if rep.Included == nil || rep.Included.Count != rep.Submit.Count+rep.Posted.Count || rep.NotIncluded != 0 {
+		t.Errorf("included = %+v with %d not included, want all %d admitted", rep.Included, rep.NotIncluded, rep.Submit.Count+rep.Posted.Count)
+	}
+
+	var out bytes.Buffer
+	rep.write(&out)
+	for _, want := range []string{"submit", "publish", "posted", "included", "tx/s admitted"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report does not mention %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRun_CountsRejectionsByErrorCode(t *testing.T) {
+	node := &loadgenTestNode{included: make(map[string]*ledger.Transaction), reject: ledger.ErrInsufficientStamp}
+	cfg := loadgenTestConfig(loadgenTestServer(t, node))
+	cfg.PublishRate, cfg.Poll = 0, 0
+	rep, err := run(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if rep.Submit.Count != 0 || rep.Submit.Errors == 0 || rep.ErrorCodes["DSB-LEDGER-007"] != rep.Submit.Errors {
+		t.Errorf("report = %+v, want every submission rejected with DSB-LEDGER-007", rep)
+	}
+	if rep.Included != nil {
+		t.Errorf("included = %+v without tracking", rep.Included)
+	}
+}
+
+func TestPercentile_NearestRank(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i + 1)
+	}
+	for _, tt := range []struct{ p, want int }{{50, 50}, {90, 90}, {99, 99}, {100, 100}, {0, 1}} {
+		if got := percentile(sorted, tt.p); got != time.Duration(tt.want) {
+			t.Errorf("percentile(1..100, %d) = %d, want %d", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile([]time.Duration{7}, 99); got != 7 {
+		t.Errorf("percentile of one value = %d, want 7", got)
+	}
+}
@@ -0,0 +1,74 @@
+// Command dsb-loadgen puts a node under a steady transaction load and reports
+// how it copes, to quantify the effect of performance work. Run it before and
+// after a change against the same node setup and compare the reports.
+//
+// Load is open-loop: jobs are started at the configured rates whether or not
+// earlier ones have finished, and a job that finds every worker busy is
+// counted as skipped rather than queued, so a slow node shows up as latency
+// and skips instead of as a lower offered rate.
+//
+// Two kinds of job run side by side:
+//
+//   - tx: a signed PostCreated transaction for a made-up CID, submitted to
+//     POST /v1/transactions.
+//   - publish: -publish-size random bytes chunked and stored as DDS content
+//     (in memory, or in -dds-dir), then posted like a tx.
+//
+// Submit latency is the time until the node admits the transaction to its
+// mempool. With -poll set, every admitted transaction is then looked up
+// through the node's GraphQL post(id) field until it appears in a block; the
+// inclusion delay is measured from admission, to within the poll interval.
+//
+//	go run ./cmd/dsb-loadgen -node http://localhost:8080 -rate 200 -duration 1m \
+//	    -publish-rate 5 -publish-size 65536 -poll 250ms
+package main
+
+import (
+	"context"
+	"digisocialblock/core/ledger"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+func main() {
+	var cfg config
+	flag.StringVar(&cfg.Node, "node", "", "base URL of the node API (required)")
+	flag.StringVar(&cfg.ChainID, "chain-id", ledger.DefaultChainID, "chain ID to sign transactions for")
+	flag.Float64Var(&cfg.Rate, "rate", 50, "transactions to submit per second")
+	flag.Float64Var(&cfg.PublishRate, "publish-rate", 0, "content publishes per second")
+	flag.IntVar(&cfg.PublishSize, "publish-size", 16<<10, "bytes of content per publish")
+	flag.StringVar(&cfg.DDSDir, "dds-dir", "", "directory to store published chunks in (default: memory)")
+	flag.DurationVar(&cfg.Duration, "duration", 30*time.Second, "how long to generate load")
+	flag.IntVar(&cfg.Workers, "workers", 16, "jobs in flight at most")
+	flag.IntVar(&cfg.Senders, "senders", 8, "wallets to spread transactions over")
+	flag.DurationVar(&cfg.Poll, "poll", 0, "interval to poll for block inclusion; 0 disables tracking")
+	flag.DurationVar(&cfg.Drain, "drain", 30*time.Second, "how long to wait for pending transactions after the load stops")
+	asJSON := flag.Bool("json", false, "write the report as JSON")
+	flag.Parse()
+
+	if cfg.Node == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	rep, err := run(ctx, cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if !*asJSON {
+		rep.write(os.Stdout)
+		return
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(rep); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
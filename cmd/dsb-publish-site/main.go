@@ -0,0 +1,86 @@
+// Command dsb-publish-site renders one author's public timeline as a static
+// HTML site for self-hosting: paged indexes, a permalink page per post at
+// posts/<transaction ID>.html, the post media and an Atom feed.
+//
+// Posts are read from a block log and count only if the author signed them;
+// group posts are left out. Content is read from a DDS chunk directory (files
+// named by CID, manifests included, as written by mobile.DirStore) and every
+// chunk is verified against its CID. Posts whose content is missing or fails
+// verification are skipped and reported, or fail the run with -strict.
+//
+// Opening a block log truncates a torn tail, so render from a stopped node or
+// from a copy of a running node's log.
+//
+//	go run ./cmd/dsb-publish-site -store blocks.log -dds-dir ./chunks \
+//	    -address 04ab... -out ./site -base-url https://alice.example.org
+package main
+
+import (
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/mobile"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	storePath := flag.String("store", "", "block log to read posts from (required)")
+	ddsDir := flag.String("dds-dir", "", "DDS chunk directory to read content from (required)")
+	address := flag.String("address", "", "address of the author to publish (required)")
+	outDir := flag.String("out", "site", "directory to write the site to")
+	title := flag.String("title", "", "site title (default: the author's display name)")
+	baseURL := flag.String("base-url", "", "public URL of the site, for absolute links in the Atom feed")
+	perPage := flag.Int("per-page", 50, "posts per index page and in the feed")
+	strict := flag.Bool("strict", false, "fail if any post's content cannot be retrieved and verified")
+	flag.Parse()
+
+	if *storePath == "" || *ddsDir == "" || *address == "" || *perPage <= 0 {
+		fmt.Fprintln(os.Stderr, "-store, -dds-dir and -address are required, and -per-page must be positive")
+		flag.Usage()
+		os.Exit(2)
+	}
+	cfg := siteConfig{Address: *address, Title: *title, BaseURL: *baseURL, PerPage: *perPage, Strict: *strict}
+	if err := run(*storePath, *ddsDir, *outDir, cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(storePath, ddsDir, outDir string, cfg siteConfig) error {
+	if _, err := os.Stat(storePath); err != nil {
+		return fmt.Errorf("block log %s: %w", storePath, err)
+	}
+	if info, err := os.Stat(ddsDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("DDS directory %s is not a directory", ddsDir)
+	}
+	store, err := ledger.OpenFileBlockStore(storePath, ledger.FileBlockStoreOptions{})
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	bc, err := ledger.NewBlockchainWithStore(store)
+	if err != nil {
+		return err
+	}
+	chunks, err := mobile.NewDirStore(ddsDir)
+	if err != nil {
+		return err
+	}
+	src, err := mobile.NewContent(chunks)
+	if err != nil {
+		return err
+	}
+
+	s, err := buildSite(bc, src, cfg)
+	if err != nil {
+		return err
+	}
+	if err := s.write(outDir); err != nil {
+		return err
+	}
+	for _, reason := range s.skipped {
+		fmt.Fprintln(os.Stderr, "skipped:", reason)
+	}
+	fmt.Fprintf(os.Stderr, "published %d posts to %s (%d skipped)\n", len(s.posts), outDir, len(s.skipped))
+	return nil
+}
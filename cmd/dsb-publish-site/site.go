@@ -0,0 +1,390 @@
+package main
+
+import (
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"digisocialblock/core/user"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// contentSource retrieves verified post content; mobile.Content implements it
+// over a chunk directory.
+type contentSource interface {
+	RetrieveText(manifestCID string) (string, error)
+	RetrieveMedia(manifestCID string) ([]byte, error)
+}
+
+// siteConfig controls what is rendered.
+type siteConfig struct {
+	Address string
+	Title   string // Defaults to the author's display name
+	BaseURL string // Absolute site URL for feed links; empty gives relative links
+	PerPage int
+	Strict  bool // Fail on content that cannot be retrieved instead of skipping the post
+}
+
+// sitePost is a post ready to render.
+type sitePost struct {
+	TxID       string
+	BlockIndex int64
+	Time       time.Time
+	Title      string
+	Tags       []string
+	Text       string
+	WebURL     string // For posts whose content lives on the web
+	Media      []siteMedia
+	Prev, Next string // Tx IDs of the older and newer posts
+}
+
+type siteMedia struct {
+	Path  string // Relative to the site root
+	Image bool
+}
+
+// site is an author's rendered timeline.
+type site struct {
+	cfg     siteConfig
+	author  user.Profile
+	posts   []*sitePost       // Newest first
+	media   map[string][]byte // Site path -> data
+	skipped []string          // Why posts were left out
+}
+
+// buildSite collects cfg.Address's public posts from the chain and retrieves
+// their content. Posts count only if the author signed the transaction.
+func buildSite(bc *ledger.Blockchain, src contentSource, cfg siteConfig) (*site, error) {
+	latest := bc.GetLatestBlock()
+	if latest == nil {
+		return nil, fmt.Errorf("blockchain has no blocks")
+	}
+	s := &site{cfg: cfg, author: user.Profile{OwnerPublicKey: cfg.Address}, media: map[string][]byte{}}
+	for i := int64(0); i <= latest.Index; i++ {
+		block := bc.GetBlockByIndex(i)
+		if block == nil {
+			return nil, fmt.Errorf("block %d missing", i)
+		}
+		for _, tx := range block.Transactions {
+			if tx == nil || tx.SenderPublicKey != cfg.Address {
+				continue
+			}
+			switch tx.Type {
+			case ledger.ProfileUpdate:
+				if p, err := user.ProfileFromPayload(tx.Payload); err == nil && p.OwnerPublicKey == cfg.Address {
+					s.author = *p
+				}
+			case ledger.PostCreated:
+				post, err := social.PostFromPayload(tx.Payload)
+				if err != nil || post.AuthorPublicKey != cfg.Address || post.GroupID != "" {
+					continue
+				}
+				sp, err := s.retrievePost(src, tx.ID, block.Index, post)
+				if err != nil {
+					if cfg.Strict {
+						return nil, err
+					}
+					s.skipped = append(s.skipped, err.Error())
+					continue
+				}
+				s.posts = append([]*sitePost{sp}, s.posts...)
+			}
+		}
+	}
+	for i, p := range s.posts {
+		if i > 0 {
+			p.Next = s.posts[i-1].TxID
+		}
+		if i+1 < len(s.posts) {
+			p.Prev = s.posts[i+1].TxID
+		}
+	}
+	return s, nil
+}
+
+// retrievePost fetches and verifies the post's text and media.
+func (s *site) retrievePost(src contentSource, txID string, blockIndex int64, post *social.Post) (*sitePost, error) {
+	if !validTxID(txID) {
+		return nil, fmt.Errorf("post %q: transaction ID is not usable as a file name", txID)
+	}
+	sp := &sitePost{
+		TxID:       txID,
+		BlockIndex: blockIndex,
+		Time:       time.Unix(0, post.Timestamp).UTC(),
+		Title:      post.Title,
+		Tags:       post.Tags,
+	}
+	if post.ContentCID != "" {
+		text, err := src.RetrieveText(post.ContentCID)
+		if err != nil {
+			return nil, fmt.Errorf("post %s: content %s: %w", txID, post.ContentCID, err)
+		}
+		sp.Text = text
+	} else if post.WebSource != nil {
+		sp.WebURL = post.WebSource.URL
+	}
+	for _, cid := range post.Media {
+		data, err := src.RetrieveMedia(cid)
+		if err != nil {
+			return nil, fmt.Errorf("post %s: media %s: %w", txID, cid, err)
+		}
+		if cid != filepath.Base(cid) || strings.HasPrefix(cid, ".") {
+			return nil, fmt.Errorf("post %s: media CID %q is not usable as a file name", txID, cid)
+		}
+		path := "media/" + cid
+		s.media[path] = data
+		sp.Media = append(sp.Media, siteMedia{Path: path, Image: strings.HasPrefix(http.DetectContentType(data), "image/")})
+	}
+	return sp, nil
+}
+
+func validTxID(id string) bool {
+	if id == "" || len(id) > social.MaxTxIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// title returns the site title.
+func (s *site) title() string {
+	switch {
+	case s.cfg.Title != "":
+		return s.cfg.Title
+	case s.author.DisplayName != "":
+		return s.author.DisplayName
+	}
+	return s.cfg.Address
+}
+
+// write renders the site into dir: paged indexes (index.html, page-2.html,
+// ...), a permalink page per post under posts/, media under media/, an Atom
+// feed and a stylesheet. Existing files with the same names are replaced.
+func (s *site) write(dir string) error {
+	for _, sub := range []string{"posts", "media"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", sub, err)
+		}
+	}
+	files := map[string][]byte{"style.css": []byte(siteCSS)}
+	for path, data := range s.media {
+		files[path] = data
+	}
+
+	perPage := s.cfg.PerPage
+	pages := (len(s.posts) + perPage - 1) / perPage
+	if pages == 0 {
+		pages = 1
+	}
+	for page := 1; page <= pages; page++ {
+		end := page * perPage
+		if end > len(s.posts) {
+			end = len(s.posts)
+		}
+		data := map[string]interface{}{
+			"Site":  s,
+			"Root":  "",
+			"Posts": s.posts[(page-1)*perPage : end],
+			"Page":  page,
+			"Pages": pages,
+		}
+		if page > 1 {
+			data["Newer"] = pageFile(page - 1)
+		}
+		if page < pages {
+			data["Older"] = pageFile(page + 1)
+		}
+		if err := s.render(files, pageFile(page), "index", data); err != nil {
+			return err
+		}
+	}
+	for _, p := range s.posts {
+		if err := s.render(files, "posts/"+p.TxID+".html", "post", map[string]interface{}{"Site": s, "Root": "../", "Post": p}); err != nil {
+			return err
+		}
+	}
+	feed, err := s.atom()
+	if err != nil {
+		return err
+	}
+	files["atom.xml"] = feed
+
+	for path, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(path)), data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func pageFile(page int) string {
+	if page == 1 {
+		return "index.html"
+	}
+	return fmt.Sprintf("page-%d.html", page)
+}
+
+func (s *site) render(files map[string][]byte, path, name string, data interface{}) error {
+	var b strings.Builder
+	if err := siteTemplates.ExecuteTemplate(&b, name, data); err != nil {
+		return fmt.Errorf("failed to render %s: %w", path, err)
+	}
+	files[path] = []byte(b.String())
+	return nil
+}
+
+// atomFeed is the subset of RFC 4287 the site publishes.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  string      `xml:"author>name"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Content string   `xml:"content"`
+}
+
+// atom renders the feed of the newest posts.
+func (s *site) atom() ([]byte, error) {
+	base := strings.TrimSuffix(s.cfg.BaseURL, "/")
+	if base != "" {
+		base += "/"
+	}
+	feed := atomFeed{
+		ID:      "dsb:" + s.cfg.Address,
+		Title:   s.title(),
+		Updated: time.Unix(0, 0).UTC().Format(time.RFC3339),
+		Author:  s.title(),
+		Links:   []atomLink{{Href: base + "index.html"}, {Href: base + "atom.xml", Rel: "self"}},
+	}
+	for i, p := range s.posts {
+		if i == s.cfg.PerPage {
+			break
+		}
+		if i == 0 {
+			feed.Updated = p.Time.Format(time.RFC3339)
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:      "dsb:tx:" + p.TxID,
+			Title:   postTitle(p),
+			Updated: p.Time.Format(time.RFC3339),
+			Link:    atomLink{Href: base + "posts/" + p.TxID + ".html"},
+			Content: p.Text,
+		})
+	}
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode feed: %w", err)
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// postTitle is the title shown for a post: its own, or the start of its text.
+func postTitle(p *sitePost) string {
+	if p.Title != "" {
+		return p.Title
+	}
+	text := strings.Join(strings.Fields(p.Text), " ")
+	if r := []rune(text); len(r) > 60 {
+		return string(r[:60]) + "…"
+	}
+	if text == "" {
+		return "Post " + p.TxID
+	}
+	return text
+}
+
+var siteTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"date":  func(t time.Time) string { return t.Format("2006-01-02 15:04 UTC") },
+	"iso":   func(t time.Time) string { return t.Format(time.RFC3339) },
+	"title": postTitle,
+}).Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.}}</title>
+{{end}}
+
+{{define "header"}}<header>
+<h1><a href="{{.Root}}index.html">{{.Site.Title}}</a></h1>
+{{with .Site.Author.Bio}}<p class="bio">{{.}}</p>{{end}}
+<p class="address">{{.Site.Address}}</p>
+</header>
+{{end}}
+
+{{define "index"}}{{template "head" .Site.Title}}<link rel="stylesheet" href="style.css">
+<link rel="alternate" type="application/atom+xml" href="atom.xml">
+</head>
+<body>
+{{template "header" .}}
+<main>
+{{range .Posts}}<article>
+<h2><a href="posts/{{.TxID}}.html">{{title .}}</a></h2>
+<p class="meta"><time datetime="{{iso .Time}}">{{date .Time}}</time>{{range .Tags}} <span class="tag">#{{.}}</span>{{end}}</p>
+</article>
+{{else}}<p>No posts yet.</p>
+{{end}}</main>
+<nav>{{with .Newer}}<a href="{{.}}">Newer</a>{{end}} Page {{.Page}} of {{.Pages}} {{with .Older}}<a href="{{.}}">Older</a>{{end}}</nav>
+</body>
+</html>
+{{end}}
+
+{{define "post"}}{{template "head" (title .Post)}}<link rel="stylesheet" href="../style.css">
+<link rel="alternate" type="application/atom+xml" href="../atom.xml">
+</head>
+<body>
+{{template "header" .}}
+<main>
+{{with .Post}}<article>
+{{with .Title}}<h2>{{.}}</h2>{{end}}
+<p class="meta"><time datetime="{{iso .Time}}">{{date .Time}}</time>{{range .Tags}} <span class="tag">#{{.}}</span>{{end}}</p>
+{{if .Text}}<div class="text">{{.Text}}</div>{{end}}
+{{with .WebURL}}<p class="web">Content on the web: <a href="{{.}}" rel="nofollow noopener">{{.}}</a></p>{{end}}
+{{range .Media}}{{if .Image}}<img src="../{{.Path}}" alt="">{{else}}<p><a href="../{{.Path}}">Attachment</a></p>{{end}}
+{{end}}<p class="permalink">Transaction <code>{{.TxID}}</code> in block {{.BlockIndex}}</p>
+</article>
+<nav>{{with .Next}}<a href="{{.}}.html">Newer</a>{{end}} {{with .Prev}}<a href="{{.}}.html">Older</a>{{end}}</nav>
+{{end}}</main>
+</body>
+</html>
+{{end}}
+`))
+
+// Title, Address and Author are used by the templates.
+func (s *site) Title() string        { return s.title() }
+func (s *site) Address() string      { return s.cfg.Address }
+func (s *site) Author() user.Profile { return s.author }
+
+const siteCSS = `body { max-width: 40rem; margin: 2rem auto; padding: 0 1rem; font: 16px/1.5 system-ui, sans-serif; color: #222; }
+a { color: #0b5cad; }
+header { border-bottom: 1px solid #ddd; margin-bottom: 1.5rem; }
+.address, .meta, .permalink { color: #666; font-size: 0.85rem; word-break: break-all; }
+.tag { margin-left: 0.3rem; }
+.text { white-space: pre-wrap; }
+img { max-width: 100%; }
+nav { margin: 2rem 0; color: #666; }
+`
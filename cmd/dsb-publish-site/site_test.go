@@ -0,0 +1,109 @@
+package main
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/mobile"
+	"digisocialblock/core/social"
+	"digisocialblock/core/user"
+	"digisocialblock/internal/testutil/fixture"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildSite_WritesVerifiedTimeline(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	bob, _ := identity.NewWallet()
+	chunks, _ := mobile.NewDirStore(t.TempDir())
+	src, _ := mobile.NewContent(chunks)
+	publish := func(text string) string {
+		cid, err := src.PublishText(text)
+		if err != nil {
+			t.Fatalf("PublishText() error = %v", err)
+		}
+		return cid
+	}
+	png, err := src.PublishMedia([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"))
+	if err != nil {
+		t.Fatalf("PublishMedia() error = %v", err)
+	}
+
+	first := social.NewPost(alice.Address, publish("first <b>post</b>"), "Hello", []string{"intro"})
+	first.Timestamp = 1700000000000000000
+	second := social.NewPost(alice.Address, publish("second post"), "", nil)
+	second.Timestamp = 1700000060000000000
+	second.Media = []string{png}
+	missing := social.NewPost(alice.Address, "not-in-dds", "", nil)
+	forged := social.NewPost(alice.Address, publish("not by alice"), "", nil)
+	group := social.NewPost(alice.Address, publish("secret"), "", nil)
+	group.GroupID, group.KeyEpoch = "club", 1
+
+	profile := user.NewProfile(alice.Address, "Alice", "Writes things.")
+	txs := []*ledger.Transaction{
+		fixture.TxFormat(t, alice, ledger.ProfileUpdate, ledger.PayloadFormatJSON, profile),
+		fixture.TxFormat(t, alice, ledger.PostCreated, ledger.PayloadFormatJSON, first),
+		fixture.TxFormat(t, alice, ledger.PostCreated, ledger.PayloadFormatJSON, second),
+		fixture.TxFormat(t, alice, ledger.PostCreated, ledger.PayloadFormatJSON, missing),
+		fixture.TxFormat(t, bob, ledger.PostCreated, ledger.PayloadFormatJSON, forged),
+		fixture.TxFormat(t, alice, ledger.PostCreated, ledger.PayloadFormatJSON, group),
+	}
+	bc, _ := ledger.NewBlockchain()
+	if _, err := bc.AddBlock(txs); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+
+	cfg := siteConfig{Address: alice.Address, BaseURL: "https://alice.example.org/", PerPage: 1}
+	s, err := buildSite(bc, src, cfg)
+	if err != nil {
+		t.Fatalf("buildSite() error = %v", err)
+	}
+	if len(s.posts) != 2 || len(s.skipped) != 1 || !strings.Contains(s.skipped[0], "not-in-dds") {
+		t.Fatalf("buildSite() = %d posts, skipped %v; want alice's two retrievable posts", len(s.posts), s.skipped)
+	}
+	cfg.Strict = true
+	if _, err := buildSite(bc, src, cfg); err == nil {
+		t.Error("buildSite() with Strict expected error for missing content, got nil")
+	}
+
+	dir := t.TempDir()
+	if err := s.write(dir); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("reading %s: %v", name, err)
+		}
+		return string(data)
+	}
+
+	firstPage := read("posts/" + txs[1].ID + ".html")
+	for _, want := range []string{"<title>Hello</title>", "first &lt;b&gt;post&lt;/b&gt;", "Writes things.", `href="` + txs[2].ID + `.html">Newer`} {
+		if !strings.Contains(firstPage, want) {
+			t.Errorf("first post page lacks %q:\n%s", want, firstPage)
+		}
+	}
+	if secondPage := read("posts/" + txs[2].ID + ".html"); !strings.Contains(secondPage, `<img src="../media/`+png+`"`) {
+		t.Errorf("second post page does not show its image:\n%s", secondPage)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "media", png)); err != nil {
+		t.Errorf("media file not written: %v", err)
+	}
+	if index := read("index.html"); !strings.Contains(index, "<h1><a href=\"index.html\">Alice</a></h1>") || !strings.Contains(index, `href="page-2.html">Older`) {
+		t.Errorf("index page lacks the title or paging:\n%s", index)
+	}
+	if page2 := read("page-2.html"); !strings.Contains(page2, "posts/"+txs[1].ID+".html") {
+		t.Errorf("page 2 does not list the oldest post:\n%s", page2)
+	}
+
+	var feed atomFeed
+	if err := xml.Unmarshal([]byte(read("atom.xml")), &feed); err != nil {
+		t.Fatalf("atom.xml does not parse: %v", err)
+	}
+	if len(feed.Entries) != 1 || feed.Entries[0].Link.Href != "https://alice.example.org/posts/"+txs[2].ID+".html" {
+		t.Errorf("feed entries = %+v, want the newest post with an absolute link", feed.Entries)
+	}
+}
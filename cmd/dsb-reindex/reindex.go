@@ -0,0 +1,209 @@
+package main
+
+import (
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// options configure a rebuild.
+type options struct {
+	chainID     string
+	allocations map[string]uint64 // Genesis balances; see ledger.Blockchain.SetGenesisAllocations
+	workers     int               // Goroutines verifying signatures
+	batch       int64             // Blocks appended between index syncs
+	progress    io.Writer
+}
+
+// syncer is a chain index that can catch up with a chain.
+type syncer interface {
+	Sync(bc *ledger.Blockchain) (int, error)
+}
+
+// namedIndex is an index being rebuilt, named for progress reports.
+type namedIndex struct {
+	name  string
+	index syncer
+}
+
+// deferredFeedStore keeps the feed index in memory while it is rebuilt, so the
+// file is written once at the end rather than after every batch, and a failed
+// rebuild leaves the old file in place.
+type deferredFeedStore struct {
+	state *social.FeedIndexState
+}
+
+func (d *deferredFeedStore) LoadFeedIndex() (*social.FeedIndexState, error) { return nil, nil }
+
+func (d *deferredFeedStore) SaveFeedIndex(state *social.FeedIndexState) error {
+	d.state = state
+	return nil
+}
+
+// run rebuilds the indexes from the block log at storePath and, if feedPath is
+// set, replaces the feed index file with the result.
+func run(storePath, feedPath string, opts options) error {
+	blocks, err := readBlocks(storePath)
+	if err != nil {
+		return err
+	}
+	feedStore := &deferredFeedStore{}
+	feed, err := social.NewFeedService(feedStore)
+	if err != nil {
+		return err
+	}
+	indexes := []namedIndex{
+		{"feed", feed},
+		{"graph", social.NewGraphIndex()},
+		{"tags", social.NewTrendingIndex()},
+		{"lists", social.NewListIndex()},
+		{"bookmarks", social.NewBookmarkIndex()},
+		{"engagement", social.NewEngagementIndex()},
+	}
+	if err := rebuild(blocks, indexes, opts); err != nil {
+		return err
+	}
+	if feedPath == "" {
+		return nil
+	}
+	if feedStore.state == nil {
+		return fmt.Errorf("feed index was not rebuilt")
+	}
+	file, err := social.NewFileFeedIndexStore(feedPath)
+	if err != nil {
+		return err
+	}
+	if err := file.SaveFeedIndex(feedStore.state); err != nil {
+		return err
+	}
+	fmt.Fprintf(opts.progress, "wrote feed index %s\n", feedPath)
+	return nil
+}
+
+// readBlocks returns every block of the log at path, refusing a log with any
+// undecodable record; dsb-debug verify can locate the damage.
+func readBlocks(path string) ([]*ledger.Block, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("block log %s: %w", path, err)
+	}
+	defer f.Close()
+	var blocks []*ledger.Block
+	err = ledger.ScanBlockLog(f, func(rec ledger.BlockLogRecord) error {
+		if rec.Err != nil {
+			return fmt.Errorf("block log %s is damaged at offset %d: %w", path, rec.Offset, rec.Err)
+		}
+		blocks = append(blocks, rec.Block)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("block log %s is empty", path)
+	}
+	return blocks, nil
+}
+
+// rebuild replays blocks into a fresh chain, a batch at a time, syncing every
+// index after each batch. The stored genesis block must be the standard one.
+func rebuild(blocks []*ledger.Block, indexes []namedIndex, opts options) error {
+	bc, err := ledger.NewBlockchain()
+	if err != nil {
+		return err
+	}
+	if blocks[0].Hash != bc.GetLatestBlock().Hash {
+		return fmt.Errorf("stored genesis block %s does not match this node's genesis %s", blocks[0].Hash, bc.GetLatestBlock().Hash)
+	}
+	bc.SetChainID(opts.chainID)
+	if err := bc.SetGenesisAllocations(opts.allocations); err != nil {
+		return err
+	}
+	sigCache, err := ledger.NewSignatureCache(ledger.DefaultSignatureCacheSize)
+	if err != nil {
+		return err
+	}
+	bc.SetSignatureCache(sigCache)
+
+	start := time.Now()
+	total := int64(len(blocks)) - 1 // The genesis block is already in place.
+	for from := int64(1); ; from += opts.batch {
+		to := from + opts.batch - 1
+		if to > total {
+			to = total
+		}
+		if from <= to {
+			batch := blocks[from : to+1]
+			verifySignatures(sigCache, batch, opts.workers)
+			for _, block := range batch {
+				if err := bc.AppendBlock(block); err != nil {
+					return fmt.Errorf("failed to replay block %d: %w", block.Index, err)
+				}
+			}
+		}
+		if err := syncAll(bc, indexes); err != nil {
+			return err
+		}
+		if total > 0 {
+			fmt.Fprintf(opts.progress, "reindex: %d/%d blocks (%d%%) in %s\n", to, total, 100*to/total, time.Since(start).Round(time.Millisecond))
+		}
+		if to >= total {
+			break
+		}
+	}
+	fmt.Fprintf(opts.progress, "reindex: rebuilt %d indexes from %d blocks\n", len(indexes), total+1)
+	return nil
+}
+
+// verifySignatures verifies the signatures of the transactions in blocks with
+// the given number of workers, so AppendBlock finds them in sc. Invalid
+// signatures are not cached; AppendBlock reports them.
+func verifySignatures(sc *ledger.SignatureCache, blocks []*ledger.Block, workers int) {
+	txs := make(chan *ledger.Transaction)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for tx := range txs {
+				sc.Verify(tx)
+			}
+		}()
+	}
+	for _, block := range blocks {
+		for _, tx := range block.Transactions {
+			if tx != nil {
+				txs <- tx
+			}
+		}
+	}
+	close(txs)
+	wg.Wait()
+}
+
+// syncAll syncs every index with bc concurrently. The indexes are independent
+// and each is safe for concurrent use with the chain.
+func syncAll(bc *ledger.Blockchain, indexes []namedIndex) error {
+	errs := make([]error, len(indexes))
+	var wg sync.WaitGroup
+	for i, idx := range indexes {
+		wg.Add(1)
+		go func(i int, idx namedIndex) {
+			defer wg.Done()
+			if _, err := idx.index.Sync(bc); err != nil {
+				errs[i] = fmt.Errorf("failed to rebuild the %s index: %w", idx.name, err)
+			}
+		}(i, idx)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// Command dsb-scenario runs end-to-end scenario scripts against an in-process
+// node: an in-memory DDS and a fresh chain per script. A script is a YAML file
+// listing steps that create wallets, publish content and profiles, post,
+// follow, add blocks and assert on the result, so new end-to-end coverage is a
+// new script rather than a new main package. The bundled scripts are in
+// scenarios/ and run as part of this package's tests.
+//
+//	dsb-scenario cmd/dsb-scenario/scenarios            run every *.yaml in a directory
+//	dsb-scenario -v scenarios/content_posting.yaml     also list each step as it passes
+//
+// A script has a name, an optional chunk_size for the DDS and its steps; each
+// step names its action and that action's fields:
+//
+//	name: Follow and post
+//	chunk_size: 128
+//	steps:
+//	  - action: create_wallet
+//	    name: alice
+//	  - action: post
+//	    wallet: alice
+//	    as: hello
+//	    text: Hello, world
+//	  - action: add_block
+//	  - action: assert
+//	    check: post
+//	    name: hello
+//	    in_block: 1
+//
+// Actions:
+//
+//	create_wallet    name
+//	publish          as, text                      store text on DDS without a transaction
+//	post             wallet, as, text | content    sign a PostCreated transaction; content names
+//	                 [title, tags]                 earlier published content
+//	follow           wallet, followee, [as]        sign a UserFollowed transaction
+//	publish_profile  wallet, as, [display_name,    create the wallet's profile, or update and
+//	                 bio, picture, header]         republish it at the next version
+//	add_block        -                             put every transaction signed so far in a block
+//	assert           check, ...                    see below
+//
+// Checks:
+//
+//	chain_valid  -                               the whole chain validates
+//	content      name, [equals]                  content retrieves as published, or as equals
+//	post         name, in_block, [author,        the transaction is a post in that block with
+//	             content, title, tags]           this metadata; title is compared even if unset
+//	profile      name, [display_name, bio,       the profile retrieves exactly as published
+//	             version]
+//	distinct     names                           contents and profiles all have different CIDs
+//	followers    wallet, [count, includes]       the graph index has these followers
+//	feed         wallet, count                   the feed index has this many posts by wallet
+//
+// Unknown fields are errors. The exit status is 1 if any script fails.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	verbose := flag.Bool("v", false, "list each step as it passes")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: dsb-scenario [-v] script.yaml|dir ...")
+		os.Exit(2)
+	}
+	paths, err := scriptPaths(flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	log := io.Discard
+	if *verbose {
+		log = os.Stdout
+	}
+	failed := 0
+	for _, path := range paths {
+		if err := runFile(path, log); err != nil {
+			fmt.Printf("FAIL %s: %v\n", path, err)
+			failed++
+			continue
+		}
+		fmt.Printf("ok   %s\n", path)
+	}
+	if failed > 0 {
+		fmt.Printf("%d of %d scenarios failed\n", failed, len(paths))
+		os.Exit(1)
+	}
+}
+
+// scriptPaths expands directories in args to the *.yaml files in them.
+func scriptPaths(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(arg, "*.yaml"))
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no *.yaml scripts in %s", arg)
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+func runFile(path string, log io.Writer) error {
+	sc, err := loadScenario(path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(log, "%s (%s)\n", sc.Name, path)
+	return runScenario(sc, log)
+}
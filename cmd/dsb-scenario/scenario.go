@@ -0,0 +1,717 @@
+package main
+
+import (
+	"digisocialblock/core/content"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"digisocialblock/core/user"
+	"digisocialblock/internal/testutil"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scenario is a parsed scenario script.
+type scenario struct {
+	Name      string
+	ChunkSize int
+	Steps     []*fields
+}
+
+func loadScenario(path string) (*scenario, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := parseScenario(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return sc, nil
+}
+
+func parseScenario(src string) (*scenario, error) {
+	doc, err := parseYAML(src)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("script must be a mapping with name and steps")
+	}
+	top := newFields(m)
+	sc := &scenario{Name: top.require("name"), ChunkSize: testutil.DefaultChunkSize}
+	top.string("description")
+	if n, ok := top.int("chunk_size"); ok {
+		sc.ChunkSize = n
+	}
+	steps, _ := top.m["steps"].([]interface{})
+	top.used["steps"] = true
+	if err := top.done(); err != nil {
+		return nil, err
+	}
+	if sc.ChunkSize <= 0 {
+		return nil, fmt.Errorf("chunk_size must be positive")
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("steps must be a non-empty sequence")
+	}
+	for i, raw := range steps {
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("step %d: must be a mapping with an action", i+1)
+		}
+		f := newFields(m)
+		action := f.require("action")
+		if f.err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, f.err)
+		}
+		if actions[action] == nil {
+			return nil, fmt.Errorf("step %d: unknown action %q", i+1, action)
+		}
+		sc.Steps = append(sc.Steps, f)
+	}
+	return sc, nil
+}
+
+// fields reads a step's fields, remembering which were read so that done
+// can reject misspelt or unsupported ones. The first error is kept and the
+// accessors return zero values after it.
+type fields struct {
+	m    map[string]interface{}
+	used map[string]bool
+	err  error
+}
+
+func newFields(m map[string]interface{}) *fields {
+	return &fields{m: m, used: make(map[string]bool)}
+}
+
+func (f *fields) has(key string) bool {
+	_, ok := f.m[key]
+	return ok
+}
+
+// string returns the scalar key, or "" if it is not set.
+func (f *fields) string(key string) string {
+	f.used[key] = true
+	v, ok := f.m[key]
+	if !ok || f.err != nil {
+		return ""
+	}
+	s, ok := v.(string)
+	if !ok {
+		f.err = fmt.Errorf("%s must be a scalar", key)
+	}
+	return s
+}
+
+func (f *fields) require(key string) string {
+	if !f.has(key) && f.err == nil {
+		f.err = fmt.Errorf("%s is required", key)
+	}
+	return f.string(key)
+}
+
+// int returns the integer key and whether it is set.
+func (f *fields) int(key string) (int, bool) {
+	if !f.has(key) {
+		return 0, false
+	}
+	s := f.string(key)
+	if f.err != nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		f.err = fmt.Errorf("%s must be an integer, got %q", key, s)
+		return 0, false
+	}
+	return n, true
+}
+
+// strings returns the sequence of scalars key and whether it is set.
+func (f *fields) strings(key string) ([]string, bool) {
+	f.used[key] = true
+	v, ok := f.m[key]
+	if !ok || f.err != nil {
+		return nil, false
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		f.err = fmt.Errorf("%s must be a sequence", key)
+		return nil, false
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			f.err = fmt.Errorf("%s must be a sequence of scalars", key)
+			return nil, false
+		}
+		out = append(out, s)
+	}
+	return out, true
+}
+
+// done returns the first error, or names the fields that were never read.
+func (f *fields) done() error {
+	if f.err != nil {
+		return f.err
+	}
+	var unknown []string
+	for key := range f.m {
+		if !f.used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown field(s) %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// actions maps each step action to its implementation. An action reads its
+// fields from f and must leave every field it accepts marked as read.
+var actions = map[string]func(r *runner, f *fields) error{
+	"create_wallet":   (*runner).createWallet,
+	"publish":         (*runner).publish,
+	"post":            (*runner).post,
+	"follow":          (*runner).follow,
+	"publish_profile": (*runner).publishProfile,
+	"add_block":       (*runner).addBlock,
+	"assert":          (*runner).assert,
+}
+
+// checks maps each kind of assert step, selected by its check field, to its
+// implementation.
+var checks = map[string]func(r *runner, f *fields) error{
+	"chain_valid": (*runner).checkChainValid,
+	"content":     (*runner).checkContent,
+	"post":        (*runner).checkPost,
+	"profile":     (*runner).checkProfile,
+	"distinct":    (*runner).checkDistinct,
+	"followers":   (*runner).checkFollowers,
+	"feed":        (*runner).checkFeed,
+}
+
+// runner holds a scenario's node: an in-memory DDS and chain, and the named
+// things steps have created. Transactions are held in pending until an
+// add_block step puts them in a block.
+type runner struct {
+	bc        *ledger.Blockchain
+	publisher *content.ContentPublisher
+	retriever *content.ContentRetriever
+	posts     *social.PostManager
+	profiles  *user.ProfileManager
+
+	wallets   map[string]*identity.Wallet
+	cids      map[string]string // Published content and profiles by name
+	texts     map[string]string // Published text by name
+	txs       map[string]*ledger.Transaction
+	snapshots map[string]user.Profile // Profiles as published, by name
+	current   map[string]*user.Profile
+	pending   []*ledger.Transaction
+}
+
+func newRunner(chunkSize int) (*runner, error) {
+	dds := testutil.NewDDS(chunkSize)
+	r := &runner{
+		wallets:   make(map[string]*identity.Wallet),
+		cids:      make(map[string]string),
+		texts:     make(map[string]string),
+		txs:       make(map[string]*ledger.Transaction),
+		snapshots: make(map[string]user.Profile),
+		current:   make(map[string]*user.Profile),
+	}
+	var err error
+	if r.publisher, err = content.NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator); err != nil {
+		return nil, err
+	}
+	if r.retriever, err = content.NewContentRetriever(dds.Manifests, dds.Storage); err != nil {
+		return nil, err
+	}
+	if r.posts, err = social.NewPostManager(r.publisher); err != nil {
+		return nil, err
+	}
+	if r.profiles, err = user.NewProfileManager(r.publisher, r.retriever); err != nil {
+		return nil, err
+	}
+	if r.bc, err = ledger.NewBlockchain(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// runScenario runs sc's steps in order against a fresh node, writing one
+// line per step to log, and stops at the first step that fails.
+func runScenario(sc *scenario, log io.Writer) error {
+	r, err := newRunner(sc.ChunkSize)
+	if err != nil {
+		return fmt.Errorf("failed to set up node: %w", err)
+	}
+	for i, f := range sc.Steps {
+		action := f.string("action")
+		err := actions[action](r, f)
+		if err == nil {
+			err = f.done()
+		}
+		if err != nil {
+			return fmt.Errorf("step %d (%s): %w", i+1, action, err)
+		}
+		fmt.Fprintf(log, "  ok  step %d %s\n", i+1, action)
+	}
+	return nil
+}
+
+// define reports an error if the new kind called name has no name or one
+// that is taken.
+func define(kind, name string, taken bool) error {
+	if name == "" {
+		return fmt.Errorf("%s needs a name", kind)
+	}
+	if taken {
+		return fmt.Errorf("%s %q is already defined", kind, name)
+	}
+	return nil
+}
+
+func (r *runner) wallet(name string) (*identity.Wallet, error) {
+	w, ok := r.wallets[name]
+	if !ok {
+		return nil, fmt.Errorf("no wallet %q", name)
+	}
+	return w, nil
+}
+
+func (r *runner) tx(name string) (*ledger.Transaction, error) {
+	tx, ok := r.txs[name]
+	if !ok {
+		return nil, fmt.Errorf("no transaction %q", name)
+	}
+	return tx, nil
+}
+
+func (r *runner) cid(name string) (string, error) {
+	cid, ok := r.cids[name]
+	if !ok {
+		return "", fmt.Errorf("no published content or profile %q", name)
+	}
+	return cid, nil
+}
+
+func (r *runner) createWallet(f *fields) error {
+	name := f.require("name")
+	if err := f.done(); err != nil {
+		return err
+	}
+	if err := define("wallet", name, r.wallets[name] != nil); err != nil {
+		return err
+	}
+	w, err := identity.NewWallet()
+	if err != nil {
+		return err
+	}
+	r.wallets[name] = w
+	return nil
+}
+
+// publish stores text on DDS as the content name, without a transaction.
+func (r *runner) publish(f *fields) error {
+	name, text := f.require("as"), f.require("text")
+	if err := f.done(); err != nil {
+		return err
+	}
+	if err := define("content", name, r.cids[name] != ""); err != nil {
+		return err
+	}
+	cid, err := r.publisher.PublishTextPostToDDS(text)
+	if err != nil {
+		return err
+	}
+	r.cids[name], r.texts[name] = cid, text
+	return nil
+}
+
+// post creates the PostCreated transaction name. With text, the text is
+// published through the post manager and becomes the content name too; with
+// content, the post references earlier published content.
+func (r *runner) post(f *fields) error {
+	name, walletName := f.require("as"), f.require("wallet")
+	text, ref := f.string("text"), f.string("content")
+	title := f.string("title")
+	tags, _ := f.strings("tags")
+	if err := f.done(); err != nil {
+		return err
+	}
+	if err := define("transaction", name, r.txs[name] != nil); err != nil {
+		return err
+	}
+	if (text == "") == (ref == "") {
+		return fmt.Errorf("exactly one of text and content is required")
+	}
+	w, err := r.wallet(walletName)
+	if err != nil {
+		return err
+	}
+
+	var tx *ledger.Transaction
+	if text != "" {
+		if err := define("content", name, r.cids[name] != ""); err != nil {
+			return err
+		}
+		if tx, err = r.posts.CreatePost(w, text, title, tags); err != nil {
+			return err
+		}
+		post, err := social.PostFromPayload(tx.Payload)
+		if err != nil {
+			return err
+		}
+		r.cids[name], r.texts[name] = post.ContentCID, text
+	} else {
+		cid, err := r.cid(ref)
+		if err != nil {
+			return err
+		}
+		payload, err := social.NewPost(w.Address, cid, title, tags).ToPayload(ledger.PayloadFormatJSON)
+		if err != nil {
+			return err
+		}
+		if tx, err = signedTx(w, ledger.PostCreated, payload); err != nil {
+			return err
+		}
+	}
+	r.txs[name] = tx
+	r.pending = append(r.pending, tx)
+	return nil
+}
+
+func (r *runner) follow(f *fields) error {
+	name, walletName, followeeName := f.string("as"), f.require("wallet"), f.require("followee")
+	if err := f.done(); err != nil {
+		return err
+	}
+	if name != "" && r.txs[name] != nil {
+		return fmt.Errorf("transaction %q is already defined", name)
+	}
+	w, err := r.wallet(walletName)
+	if err != nil {
+		return err
+	}
+	followee, err := r.wallet(followeeName)
+	if err != nil {
+		return err
+	}
+	payload, err := (&social.Follow{FolloweePublicKey: followee.Address, Timestamp: time.Now().UnixNano()}).ToPayload(ledger.PayloadFormatJSON)
+	if err != nil {
+		return err
+	}
+	tx, err := signedTx(w, ledger.UserFollowed, payload)
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		r.txs[name] = tx
+	}
+	r.pending = append(r.pending, tx)
+	return nil
+}
+
+func signedTx(w *identity.Wallet, txType ledger.TransactionType, payload []byte) (*ledger.Transaction, error) {
+	tx, err := ledger.NewTransaction(w.Address, txType, payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.SignTransaction(tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// publishProfile publishes the wallet's profile to DDS as name. The first
+// publish creates the profile; later ones update the fields given, which
+// must change something, and so bump its version.
+func (r *runner) publishProfile(f *fields) error {
+	name, walletName := f.require("as"), f.require("wallet")
+	displayName, bio := f.string("display_name"), f.string("bio")
+	picture, header := f.string("picture"), f.string("header")
+	if err := f.done(); err != nil {
+		return err
+	}
+	if err := define("profile", name, r.cids[name] != ""); err != nil {
+		return err
+	}
+	w, err := r.wallet(walletName)
+	if err != nil {
+		return err
+	}
+
+	p := r.current[walletName]
+	if p == nil {
+		if displayName == "" {
+			return fmt.Errorf("display_name is required for a wallet's first profile")
+		}
+		p = user.NewProfile(w.Address, displayName, bio)
+		p.ProfilePictureCID, p.HeaderImageCID = picture, header
+		r.current[walletName] = p
+	} else if !p.Update(displayName, bio, picture, header) {
+		return fmt.Errorf("update changes nothing in %s's profile", walletName)
+	}
+	cid, err := r.profiles.PublishProfile(p)
+	if err != nil {
+		return err
+	}
+	r.cids[name], r.snapshots[name] = cid, *p
+	return nil
+}
+
+// addBlock puts the pending transactions in a new block.
+func (r *runner) addBlock(f *fields) error {
+	if err := f.done(); err != nil {
+		return err
+	}
+	if _, err := r.bc.AddBlock(r.pending); err != nil {
+		return err
+	}
+	r.pending = nil
+	return nil
+}
+
+func (r *runner) assert(f *fields) error {
+	check := f.require("check")
+	if f.err != nil {
+		return f.err
+	}
+	if checks[check] == nil {
+		return fmt.Errorf("unknown check %q", check)
+	}
+	return checks[check](r, f)
+}
+
+func (r *runner) checkChainValid(f *fields) error {
+	if err := f.done(); err != nil {
+		return err
+	}
+	if ok, err := r.bc.IsChainValid(); !ok {
+		return fmt.Errorf("chain is not valid: %v", err)
+	}
+	return nil
+}
+
+// checkContent retrieves the content name and compares it with the text it
+// was published with, or with equals if given.
+func (r *runner) checkContent(f *fields) error {
+	name := f.require("name")
+	want, override := f.string("equals"), f.has("equals")
+	if err := f.done(); err != nil {
+		return err
+	}
+	cid, err := r.cid(name)
+	if err != nil {
+		return err
+	}
+	if !override {
+		want = r.texts[name]
+	}
+	got, err := r.retriever.RetrieveAndVerifyTextPost(cid)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve %s (%s): %w", name, cid, err)
+	}
+	if got != want {
+		return fmt.Errorf("%s retrieved as %q, want %q", name, got, want)
+	}
+	return nil
+}
+
+// checkPost finds the post transaction name in block in_block and compares
+// its decoded metadata with the fields given.
+func (r *runner) checkPost(f *fields) error {
+	name := f.require("name")
+	index, _ := f.int("in_block")
+	author, ref, title := f.string("author"), f.string("content"), f.string("title")
+	tags, checkTags := f.strings("tags")
+	if err := f.done(); err != nil {
+		return err
+	}
+	tx, err := r.tx(name)
+	if err != nil {
+		return err
+	}
+	block := r.bc.GetBlockByIndex(int64(index))
+	if block == nil {
+		return fmt.Errorf("no block %d", index)
+	}
+	var onChain *ledger.Transaction
+	for _, candidate := range block.Transactions {
+		if candidate.ID == tx.ID {
+			onChain = candidate
+		}
+	}
+	if onChain == nil {
+		return fmt.Errorf("%s (%s) is not in block %d", name, tx.ID, index)
+	}
+	if onChain.Type != ledger.PostCreated {
+		return fmt.Errorf("%s has type %s, want %s", name, onChain.Type, ledger.PostCreated)
+	}
+	post, err := social.PostFromPayload(onChain.Payload)
+	if err != nil {
+		return err
+	}
+	if author != "" {
+		w, err := r.wallet(author)
+		if err != nil {
+			return err
+		}
+		if post.AuthorPublicKey != w.Address {
+			return fmt.Errorf("%s author = %s, want %s (%s)", name, post.AuthorPublicKey, author, w.Address)
+		}
+	}
+	if ref != "" {
+		cid, err := r.cid(ref)
+		if err != nil {
+			return err
+		}
+		if post.ContentCID != cid {
+			return fmt.Errorf("%s content = %s, want %s (%s)", name, post.ContentCID, ref, cid)
+		}
+	}
+	if post.Title != title {
+		return fmt.Errorf("%s title = %q, want %q", name, post.Title, title)
+	}
+	if checkTags && !reflect.DeepEqual(post.Tags, tags) {
+		return fmt.Errorf("%s tags = %q, want %q", name, post.Tags, tags)
+	}
+	return nil
+}
+
+// checkProfile retrieves the profile name, compares it with what was
+// published and with the fields given.
+func (r *runner) checkProfile(f *fields) error {
+	name := f.require("name")
+	displayName, bio := f.string("display_name"), f.string("bio")
+	version, checkVersion := f.int("version")
+	if err := f.done(); err != nil {
+		return err
+	}
+	cid, err := r.cid(name)
+	if err != nil {
+		return err
+	}
+	want, ok := r.snapshots[name]
+	if !ok {
+		return fmt.Errorf("%s is not a profile", name)
+	}
+	got, err := r.profiles.RetrieveProfile(cid)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve %s (%s): %w", name, cid, err)
+	}
+	if !reflect.DeepEqual(*got, want) {
+		return fmt.Errorf("%s retrieved as %+v, published %+v", name, *got, want)
+	}
+	if displayName != "" && got.DisplayName != displayName {
+		return fmt.Errorf("%s display name = %q, want %q", name, got.DisplayName, displayName)
+	}
+	if bio != "" && got.Bio != bio {
+		return fmt.Errorf("%s bio = %q, want %q", name, got.Bio, bio)
+	}
+	if checkVersion && got.Version != version {
+		return fmt.Errorf("%s version = %d, want %d", name, got.Version, version)
+	}
+	return nil
+}
+
+// checkDistinct requires the named contents and profiles to have different
+// CIDs.
+func (r *runner) checkDistinct(f *fields) error {
+	names, _ := f.strings("names")
+	if err := f.done(); err != nil {
+		return err
+	}
+	if len(names) < 2 {
+		return fmt.Errorf("names must list at least two contents or profiles")
+	}
+	seen := make(map[string]string)
+	for _, name := range names {
+		cid, err := r.cid(name)
+		if err != nil {
+			return err
+		}
+		if other, ok := seen[cid]; ok {
+			return fmt.Errorf("%s and %s have the same CID %s", other, name, cid)
+		}
+		seen[cid] = name
+	}
+	return nil
+}
+
+// checkFollowers syncs a graph index with the chain and checks the wallet's
+// followers.
+func (r *runner) checkFollowers(f *fields) error {
+	walletName := f.require("wallet")
+	count, checkCount := f.int("count")
+	includes, _ := f.strings("includes")
+	if err := f.done(); err != nil {
+		return err
+	}
+	w, err := r.wallet(walletName)
+	if err != nil {
+		return err
+	}
+	graph := social.NewGraphIndex()
+	if _, err := graph.Sync(r.bc); err != nil {
+		return err
+	}
+	followers := make(map[string]bool)
+	edges := graph.GetFollowers(w.Address)
+	for _, edge := range edges {
+		followers[edge.Follower] = true
+	}
+	if checkCount && len(edges) != count {
+		return fmt.Errorf("%s has %d followers, want %d", walletName, len(edges), count)
+	}
+	for _, name := range includes {
+		follower, err := r.wallet(name)
+		if err != nil {
+			return err
+		}
+		if !followers[follower.Address] {
+			return fmt.Errorf("%s is not followed by %s", walletName, name)
+		}
+	}
+	return nil
+}
+
+// checkFeed syncs a feed index with the chain and counts the wallet's
+// posts.
+func (r *runner) checkFeed(f *fields) error {
+	walletName := f.require("wallet")
+	count, _ := f.int("count")
+	if f.err == nil && !f.has("count") {
+		return fmt.Errorf("count is required")
+	}
+	if err := f.done(); err != nil {
+		return err
+	}
+	w, err := r.wallet(walletName)
+	if err != nil {
+		return err
+	}
+	feed, err := social.NewFeedService(nil)
+	if err != nil {
+		return err
+	}
+	if _, err := feed.Sync(r.bc); err != nil {
+		return err
+	}
+	if got := len(feed.GetUserFeed(w.Address, 0)); got != count {
+		return fmt.Errorf("%s's feed has %d posts, want %d", walletName, got, count)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestBundledScenarios runs every script in scenarios/, so the end-to-end
+// coverage they describe runs with the unit tests.
+func TestBundledScenarios(t *testing.T) {
+	paths, err := scriptPaths([]string{"scenarios"})
+	if err != nil {
+		t.Fatalf("scriptPaths() error = %v", err)
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			var log strings.Builder
+			if err := runFile(path, &log); err != nil {
+				t.Fatalf("%v\n%s", err, log.String())
+			}
+		})
+	}
+}
+
+// scenarioTestScript wraps steps, indented as a steps sequence, in a script.
+func scenarioTestScript(steps string) string {
+	return "name: test\nchunk_size: 16\nsteps:\n" + steps
+}
+
+func TestRunScenario_ReportsFailingStep(t *testing.T) {
+	for _, tt := range []struct{ name, steps, want string }{
+		{"content mismatch", `
+  - action: publish
+    as: note
+    text: hello
+  - action: assert
+    check: content
+    name: note
+    equals: goodbye
+`, `step 2 (assert): note retrieved as "hello", want "goodbye"`},
+		{"post not in block", `
+  - action: create_wallet
+    name: alice
+  - action: post
+    wallet: alice
+    as: p
+    text: hello
+  - action: assert
+    check: post
+    name: p
+    in_block: 0
+`, "step 3 (assert): p ("},
+		{"unknown wallet", `
+  - action: follow
+    wallet: alice
+    followee: bob
+`, `step 1 (follow): no wallet "alice"`},
+		{"redefined name", `
+  - action: create_wallet
+    name: alice
+  - action: create_wallet
+    name: alice
+`, `step 2 (create_wallet): wallet "alice" is already defined`},
+		{"unknown field", `
+  - action: add_block
+    height: 1
+`, "step 1 (add_block): unknown field(s) height"},
+		{"no-op profile update", `
+  - action: create_wallet
+    name: alice
+  - action: publish_profile
+    wallet: alice
+    as: v1
+    display_name: Alice
+  - action: publish_profile
+    wallet: alice
+    as: v2
+    display_name: Alice
+`, "step 3 (publish_profile): update changes nothing"},
+		{"wrong follower count", `
+  - action: create_wallet
+    name: alice
+  - action: assert
+    check: followers
+    wallet: alice
+    count: 1
+`, "alice has 0 followers, want 1"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := parseScenario(scenarioTestScript(tt.steps))
+			if err != nil {
+				t.Fatalf("parseScenario() error = %v", err)
+			}
+			err = runScenario(sc, io.Discard)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("runScenario() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseScenario_RejectsMalformedScripts(t *testing.T) {
+	for _, tt := range []struct{ name, src, want string }{
+		{"no name", "steps:\n  - action: add_block\n", "name is required"},
+		{"no steps", "name: x\n", "non-empty sequence"},
+		{"unknown top-level field", "name: x\nsetup: y\nsteps:\n  - action: add_block\n", "unknown field(s) setup"},
+		{"bad chunk size", "name: x\nchunk_size: big\nsteps:\n  - action: add_block\n", "chunk_size must be an integer"},
+		{"unknown action", scenarioTestScript("  - action: mine\n"), `step 1: unknown action "mine"`},
+		{"step without action", scenarioTestScript("  - name: alice\n"), "step 1: action is required"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseScenario(tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseScenario() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseYAML parses a scenario script. Mappings decode to
+// map[string]interface{}, sequences to []interface{} and scalars to their
+// source text as a string; typing is left to the reader, so "007" stays a
+// string and a step's fields are checked in one place (see fields). Keys
+// without a value and duplicate keys are errors rather than being misread.
+func parseYAML(src string) (interface{}, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(src), &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("script is empty")
+	}
+	return yamlValue(doc.Content[0])
+}
+
+// yamlValue converts a parsed YAML node as parseYAML describes.
+func yamlValue(n *yaml.Node) (interface{}, error) {
+	switch n.Kind {
+	case yaml.AliasNode:
+		return yamlValue(n.Alias)
+	case yaml.ScalarNode:
+		if n.Tag == "!!null" {
+			return nil, fmt.Errorf("line %d: has no value", n.Line)
+		}
+		return n.Value, nil
+	case yaml.SequenceNode:
+		seq := make([]interface{}, 0, len(n.Content))
+		for _, item := range n.Content {
+			v, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+		}
+		return seq, nil
+	case yaml.MappingNode:
+		m := make(map[string]interface{}, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: keys must be scalars", key.Line)
+			}
+			if _, dup := m[key.Value]; dup {
+				return nil, fmt.Errorf("line %d: duplicate key %q", key.Line, key.Value)
+			}
+			if value.Kind == yaml.ScalarNode && value.Tag == "!!null" {
+				return nil, fmt.Errorf("line %d: %s has no value", key.Line, key.Value)
+			}
+			v, err := yamlValue(value)
+			if err != nil {
+				return nil, err
+			}
+			m[key.Value] = v
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("line %d: unsupported YAML node", n.Line)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	src := `# A comment
+name: "quoted: # not a comment"
+plain: it's fine # trailing comment
+single: 'it''s'
+empty_list: []
+tags: [a, "b, c", 'd', it's]
+steps:
+  - action: one
+    nested:
+      key: value
+  -
+    action: two
+  - scalar item
+list_at_key_indent:
+- x
+- y
+text: |
+  first line
+
+    indented line
+next: after
+`
+	got, err := parseYAML(src)
+	if err != nil {
+		t.Fatalf("parseYAML() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"name":       "quoted: # not a comment",
+		"plain":      "it's fine",
+		"single":     "it's",
+		"empty_list": []interface{}{},
+		"tags":       []interface{}{"a", "b, c", "d", "it's"},
+		"steps": []interface{}{
+			map[string]interface{}{"action": "one", "nested": map[string]interface{}{"key": "value"}},
+			map[string]interface{}{"action": "two"},
+			"scalar item",
+		},
+		"list_at_key_indent": []interface{}{"x", "y"},
+		"text":               "first line\n\n  indented line\n",
+		"next":               "after",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseYAML() =\n%#v\nwant\n%#v", got, want)
+	}
+}
+
+func TestParseYAML_RejectsMalformedScripts(t *testing.T) {
+	for _, tt := range []struct{ name, src, want string }{
+		{"empty", "# nothing\n", "empty"},
+		{"tab", "a:\n\tb: c\n", "line 2"},
+		{"duplicate", "a: 1\na: 2\n", "duplicate key"},
+		{"missing value", "a:\nb: c\n", "has no value"},
+		{"null item", "- a\n- ~\n", "has no value"},
+		{"bad indentation", "a: b\n  c: d\n", "line 2"},
+		{"unterminated quote", "a: 'b\n", "end of stream"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseYAML(tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseYAML(%q) error = %v, want %q", tt.src, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Command dsb-sqlmirror mirrors posts, profiles, follows and reactions from a
+// node's block log into a SQLite database (see package sqlmirror for the
+// schema). Each run mirrors the blocks added since the previous one; run it
+// from cron or after the node stops to keep the database current.
+//
+// Opening a block log truncates a torn tail, so mirror from a stopped node or
+// from a copy of a running node's log.
+//
+//	go run ./cmd/dsb-sqlmirror -store blocks.log -db social.db
+//	sqlite3 social.db "SELECT tag, COUNT(*) FROM post_tags GROUP BY tag ORDER BY 2 DESC LIMIT 10"
+package main
+
+import (
+	"context"
+	"database/sql"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/sqlmirror"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	_ "modernc.org/sqlite"
+)
+
+func main() {
+	storePath := flag.String("store", "", "block log to mirror (required)")
+	dbPath := flag.String("db", "", "SQLite database to create or update (required)")
+	rebuild := flag.Bool("rebuild", false, "empty the database and mirror the whole chain again")
+	flag.Parse()
+
+	if *storePath == "" || *dbPath == "" {
+		fmt.Fprintln(os.Stderr, "-store and -db are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(context.Background(), *storePath, *dbPath, *rebuild); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, sqlmirror.ErrMirrorDiverged) {
+			fmt.Fprintln(os.Stderr, "the chain was replaced since the last run; rerun with -rebuild")
+		}
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, storePath, dbPath string, rebuild bool) error {
+	if _, err := os.Stat(storePath); err != nil {
+		return fmt.Errorf("block log %s: %w", storePath, err)
+	}
+	store, err := ledger.OpenFileBlockStore(storePath, ledger.FileBlockStoreOptions{})
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	bc, err := ledger.NewBlockchainWithStore(store)
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database %s: %w", dbPath, err)
+	}
+	defer db.Close()
+	mirror, err := sqlmirror.NewMirror(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	update := mirror.Sync
+	if rebuild {
+		update = mirror.Rebuild
+	}
+	n, err := update(ctx, bc)
+	if err != nil {
+		return err
+	}
+	height, _, err := mirror.HighWaterMark(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "mirrored %d blocks; database is at height %d\n", n, height)
+	return nil
+}
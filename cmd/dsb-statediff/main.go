@@ -0,0 +1,69 @@
+// Command dsb-statediff compares the state of two nodes to find where they
+// part ways, when debugging consensus or indexing bugs. Each side is a node's
+// block log or the URL of its API:
+//
+//	dsb-statediff a/blocks.log b/blocks.log
+//	dsb-statediff -a-feed-index a/feed.json -b-feed-index b/feed.json a/blocks.log b/blocks.log
+//	dsb-statediff http://node-a:8080 http://node-b:8080
+//	dsb-statediff a/blocks.log http://node-b:8080
+//
+// It compares the chain tips and reports the first block at which the chains
+// diverge, with the first transaction that differs when both sides are logs.
+// A node's API serves only block headers, so against a node the comparison
+// stops at the chain. Block logs are replayed into a fresh chain, as
+// dsb-reindex does, and if both chains end at the same block their state and
+// index contents are compared too: account balances, the feed, follows,
+// comments and governance proposals. Each section is summarised by a root, a
+// hash of its sorted contents, and the first entry that differs is printed.
+// A feed index file, as persisted by a node, is loaded instead of rebuilding
+// the feed and synced up to the tip, as the node would on start-up; the file
+// is not modified.
+//
+// It exits with status 1 when the sides differ, and 2 on bad arguments.
+package main
+
+import (
+	"digisocialblock/core/ledger"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+func main() {
+	feedA := flag.String("a-feed-index", "", "feed index file of the first side, which must be a block log")
+	feedB := flag.String("b-feed-index", "", "feed index file of the second side, which must be a block log")
+	chainID := flag.String("chain-id", ledger.DefaultChainID, "chain ID the transactions are signed for")
+	allocationsPath := flag.String("allocations", "", "JSON file of genesis balances by address, if the chain has transfers")
+	timeout := flag.Duration("timeout", time.Minute, "time allowed for reading headers from a node")
+	flag.Parse()
+
+	if flag.NArg() != 2 || *timeout <= 0 {
+		fmt.Fprintln(os.Stderr, "usage: dsb-statediff [flags] A B, where A and B are block logs or node API URLs")
+		flag.Usage()
+		os.Exit(2)
+	}
+	opts := options{chainID: *chainID, timeout: *timeout}
+	if *allocationsPath != "" {
+		data, err := os.ReadFile(*allocationsPath)
+		if err == nil {
+			err = json.Unmarshal(data, &opts.allocations)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read allocations %s: %v\n", *allocationsPath, err)
+			os.Exit(1)
+		}
+	}
+	a := side{location: flag.Arg(0), feedIndex: *feedA}
+	b := side{location: flag.Arg(1), feedIndex: *feedB}
+	if err := run(a, b, opts, os.Stdout); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
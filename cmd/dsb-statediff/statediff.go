@@ -0,0 +1,473 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"digisocialblock/core/api"
+	"digisocialblock/core/api/client"
+	"digisocialblock/core/governance"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/lightclient"
+	"digisocialblock/core/migrations"
+	"digisocialblock/core/social"
+	_ "digisocialblock/core/user" // Registers the profile payload validator
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// errUsage reports bad command-line arguments; the caller exits with status 2.
+var errUsage = errors.New("invalid arguments")
+
+// errFound reports that the sides differ.
+var errFound = errors.New("the sides differ")
+
+// options configure how block logs are replayed and nodes are read.
+type options struct {
+	chainID     string
+	allocations map[string]uint64 // Genesis balances; see ledger.Blockchain.SetGenesisAllocations
+	timeout     time.Duration     // For reading headers from nodes
+}
+
+// side is one of the two nodes compared.
+type side struct {
+	location  string // Block log path or API URL
+	feedIndex string // Persisted feed index to load instead of rebuilding the feed
+}
+
+// isNode reports whether the side is a node's API rather than a block log.
+func (s side) isNode() bool {
+	return strings.HasPrefix(s.location, "http://") || strings.HasPrefix(s.location, "https://")
+}
+
+// view is what was read of one side.
+type view struct {
+	name      string
+	headers   []ledger.BlockHeader
+	blocks    []*ledger.Block // Nil for a node
+	replayErr error           // Why a block log stopped replaying, if it did
+	state     snapshot        // Nil for a node, or a block log that did not replay
+}
+
+// snapshot is the state and index contents of a chain at its tip: for each
+// section, its entries encoded as JSON, by key.
+type snapshot map[string]map[string]string
+
+// Snapshot sections, in report order.
+const (
+	sectionBalances  = "balances"  // Key is the address
+	sectionFeed      = "feed"      // Key is the post transaction ID
+	sectionFollows   = "follows"   // Key is the follower; the value its follow edges
+	sectionComments  = "comments"  // Key is the post transaction ID; the value its comments
+	sectionProposals = "proposals" // Key is the proposal transaction ID
+)
+
+var sections = []string{sectionBalances, sectionFeed, sectionFollows, sectionComments, sectionProposals}
+
+func (s snapshot) add(section, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s entry %s: %w", section, key, err)
+	}
+	if s[section] == nil {
+		s[section] = make(map[string]string)
+	}
+	s[section][key] = string(data)
+	return nil
+}
+
+// root returns the SHA-256 of the section's entries in key order, so two
+// sections with the same contents have the same root.
+func (s snapshot) root(section string) string {
+	entries := s[section]
+	h := sha256.New()
+	for _, key := range sortedKeys(entries) {
+		fmt.Fprintf(h, "%s\x00%s\n", key, entries[key])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func sortedKeys(entries map[string]string) []string {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// run compares sides a and b, writing the report to out. It returns an error
+// wrapping errFound if they differ.
+func run(a, b side, opts options, out io.Writer) error {
+	for _, s := range []side{a, b} {
+		if s.isNode() && s.feedIndex != "" {
+			return fmt.Errorf("%w: %s is a node; a feed index can only be given with a block log", errUsage, s.location)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	defer cancel()
+	va, err := read(ctx, a, opts)
+	if err != nil {
+		return err
+	}
+	vb, err := read(ctx, b, opts)
+	if err != nil {
+		return err
+	}
+
+	differ := compareChains(out, va, vb)
+	switch {
+	case va.replayErr != nil || vb.replayErr != nil:
+		for _, v := range []*view{va, vb} {
+			if v.replayErr != nil {
+				fmt.Fprintf(out, "%s: %v\n", v.name, v.replayErr)
+			}
+		}
+		fmt.Fprintln(out, "state not compared: a block log does not replay")
+		differ = true
+	case va.state == nil || vb.state == nil:
+		fmt.Fprintln(out, "state not compared: nodes serve only block headers")
+	case differ:
+		fmt.Fprintln(out, "state not compared: the chains differ")
+	default:
+		differ = compareState(out, va, vb)
+	}
+	if differ {
+		return fmt.Errorf("%w: %s and %s", errFound, va.name, vb.name)
+	}
+	return nil
+}
+
+// read reads one side: a node's headers, or a block log replayed into a chain.
+func read(ctx context.Context, s side, opts options) (*view, error) {
+	if s.isNode() {
+		return readNode(ctx, s.location)
+	}
+	return readLog(s, opts)
+}
+
+// readNode reads every block header from the node whose API is at location.
+func readNode(ctx context.Context, location string) (*view, error) {
+	c, err := client.New(location)
+	if err != nil {
+		return nil, err
+	}
+	source := lightclient.NewAPISource(c)
+	v := &view{name: location}
+	for {
+		headers, err := source.Headers(ctx, int64(len(v.headers)), api.MaxHeadersPerRequest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read headers from %s: %w", location, err)
+		}
+		if len(headers) == 0 {
+			break
+		}
+		v.headers = append(v.headers, headers...)
+	}
+	if len(v.headers) == 0 {
+		return nil, fmt.Errorf("%s serves no block headers", location)
+	}
+	return v, nil
+}
+
+// readLog reads the block log of s and replays it into a fresh chain, taking
+// a snapshot of the chain's state and indexes at its tip. A block that does
+// not replay is recorded in the view rather than returned as an error: it may
+// be the divergence being looked for.
+func readLog(s side, opts options) (*view, error) {
+	blocks, err := readBlocks(s.location)
+	if err != nil {
+		return nil, err
+	}
+	v := &view{name: s.location, blocks: blocks, headers: make([]ledger.BlockHeader, len(blocks))}
+	for i, block := range blocks {
+		v.headers[i] = block.Header()
+	}
+
+	bc, err := ledger.NewBlockchain()
+	if err != nil {
+		return nil, err
+	}
+	if blocks[0].Hash != bc.GetLatestBlock().Hash {
+		return nil, fmt.Errorf("%s: stored genesis block %s does not match this node's genesis %s", s.location, blocks[0].Hash, bc.GetLatestBlock().Hash)
+	}
+	bc.SetChainID(opts.chainID)
+	if err := bc.SetGenesisAllocations(opts.allocations); err != nil {
+		return nil, err
+	}
+	for _, block := range blocks[1:] {
+		if err := bc.AppendBlock(block); err != nil {
+			v.replayErr = fmt.Errorf("block %d does not replay: %w", block.Index, err)
+			return v, nil
+		}
+	}
+	if v.state, err = takeSnapshot(bc, s.feedIndex); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.location, err)
+	}
+	return v, nil
+}
+
+// readBlocks returns every block of the log at path, refusing a log with any
+// undecodable record; dsb-debug verify can locate the damage.
+func readBlocks(path string) ([]*ledger.Block, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("block log %s: %w", path, err)
+	}
+	defer f.Close()
+	var blocks []*ledger.Block
+	err = ledger.ScanBlockLog(f, func(rec ledger.BlockLogRecord) error {
+		if rec.Err != nil {
+			return fmt.Errorf("block log %s is damaged at offset %d: %w", path, rec.Offset, rec.Err)
+		}
+		blocks = append(blocks, rec.Block)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("block log %s is empty", path)
+	}
+	return blocks, nil
+}
+
+// readOnlyFeedStore loads the feed index file a node persisted at its path
+// and discards saves, so syncing the index leaves the file as it was.
+type readOnlyFeedStore string
+
+func (path readOnlyFeedStore) LoadFeedIndex() (*social.FeedIndexState, error) {
+	version, err := migrations.ReadVersion(string(path), social.FeedIndexSchema)
+	if err != nil {
+		return nil, err
+	}
+	if version != social.FeedIndexSchema.Current {
+		return nil, fmt.Errorf("feed index %s is version %d, want %d; a node migrates it on start-up", path, version, social.FeedIndexSchema.Current)
+	}
+	data, err := os.ReadFile(string(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed index %s: %w", path, err)
+	}
+	var state social.FeedIndexState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode feed index %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+func (readOnlyFeedStore) SaveFeedIndex(*social.FeedIndexState) error { return nil }
+
+// takeSnapshot builds the indexes of bc, loading the feed from the feed index
+// file at feedIndex if set, and returns a snapshot of them and the balances.
+func takeSnapshot(bc *ledger.Blockchain, feedIndex string) (snapshot, error) {
+	var store social.FeedIndexStore
+	if feedIndex != "" {
+		store = readOnlyFeedStore(feedIndex)
+	}
+	feed, err := social.NewFeedService(store)
+	if err != nil {
+		return nil, err
+	}
+	graph, proposals := social.NewGraphIndex(), governance.NewIndex()
+	if _, err := feed.Sync(bc); err != nil {
+		return nil, fmt.Errorf("failed to sync the feed index: %w", err)
+	}
+	if _, err := graph.Sync(bc); err != nil {
+		return nil, fmt.Errorf("failed to build the graph index: %w", err)
+	}
+	if _, err := proposals.Sync(bc); err != nil {
+		return nil, fmt.Errorf("failed to build the governance index: %w", err)
+	}
+	balances, err := bc.Balances()
+	if err != nil {
+		return nil, err
+	}
+
+	s := snapshot{}
+	for address, balance := range balances {
+		if err := s.add(sectionBalances, address, balance); err != nil {
+			return nil, err
+		}
+	}
+	for _, post := range feed.GetGlobalFeed(0) {
+		if err := s.add(sectionFeed, post.TxID, post); err != nil {
+			return nil, err
+		}
+		if comments := graph.GetComments(post.TxID); len(comments) > 0 {
+			if err := s.add(sectionComments, post.TxID, comments); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, address := range senders(bc) {
+		if follows := graph.GetFollowing(address); len(follows) > 0 {
+			if err := s.add(sectionFollows, address, follows); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, status := range []governance.ProposalStatus{governance.ProposalPending, governance.ProposalActive, governance.ProposalClosed} {
+		for _, e := range proposals.GetProposals(status) {
+			if err := s.add(sectionProposals, e.TxID, e); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+// senders returns every address that sent a transaction on bc.
+func senders(bc *ledger.Blockchain) []string {
+	seen := make(map[string]bool)
+	var addresses []string
+	for i := int64(0); i <= bc.GetLatestBlock().Index; i++ {
+		for _, tx := range bc.GetBlockByIndex(i).Transactions {
+			if tx != nil && !seen[tx.SenderPublicKey] {
+				seen[tx.SenderPublicKey] = true
+				addresses = append(addresses, tx.SenderPublicKey)
+			}
+		}
+	}
+	return addresses
+}
+
+// compareChains reports the tips of both sides and the first block at which
+// their chains diverge, and returns whether they differ.
+func compareChains(out io.Writer, a, b *view) bool {
+	for _, v := range []*view{a, b} {
+		tip := v.headers[len(v.headers)-1]
+		fmt.Fprintf(out, "%s: tip at height %d, hash %s\n", v.name, tip.Index, tip.Hash)
+	}
+	common := len(a.headers)
+	if len(b.headers) < common {
+		common = len(b.headers)
+	}
+	for height := 0; height < common; height++ {
+		ha, hb := a.headers[height], b.headers[height]
+		if ha.Hash == hb.Hash {
+			continue
+		}
+		fmt.Fprintf(out, "chains diverge at height %d\n", height)
+		for _, v := range []*view{a, b} {
+			h := v.headers[height]
+			fmt.Fprintf(out, "  %s: hash=%s prev=%s timestamp=%d merkle=%s\n", v.name, h.Hash, h.PrevBlockHash, h.Timestamp, h.MerkleRoot)
+		}
+		switch {
+		case a.blocks != nil && b.blocks != nil:
+			diffTransactions(out, a.name, a.blocks[height], b.name, b.blocks[height])
+		case ha.MerkleRoot == hb.MerkleRoot:
+			fmt.Fprintln(out, "  same transactions; the blocks differ in their header")
+		default:
+			fmt.Fprintln(out, "  the blocks hold different transactions; compare block logs to find the first")
+		}
+		return true
+	}
+	if len(a.headers) == len(b.headers) {
+		fmt.Fprintf(out, "chains are identical: %d blocks\n", common)
+		return false
+	}
+	longer := a
+	if len(b.headers) > len(a.headers) {
+		longer = b
+	}
+	fmt.Fprintf(out, "chains agree on %d blocks; %s has %d more\n", common, longer.name, len(longer.headers)-common)
+	return true
+}
+
+// diffTransactions reports the first position at which two blocks at the
+// same height hold different transactions.
+func diffTransactions(out io.Writer, nameA string, a *ledger.Block, nameB string, b *ledger.Block) {
+	n := len(a.Transactions)
+	if len(b.Transactions) > n {
+		n = len(b.Transactions)
+	}
+	for i := 0; i < n; i++ {
+		ta, tb := transactionAt(a, i), transactionAt(b, i)
+		if encodeTransaction(ta) == encodeTransaction(tb) {
+			continue
+		}
+		fmt.Fprintf(out, "  first differing transaction at position %d\n", i)
+		describeTransaction(out, nameA, ta)
+		describeTransaction(out, nameB, tb)
+		if ta != nil && tb != nil && ta.ID == tb.ID {
+			fmt.Fprintln(out, "  same transaction ID, with different signatures, stamps or chain IDs")
+		}
+		return
+	}
+	fmt.Fprintln(out, "  same transactions; the blocks differ in their header")
+}
+
+func transactionAt(b *ledger.Block, i int) *ledger.Transaction {
+	if i < len(b.Transactions) {
+		return b.Transactions[i]
+	}
+	return nil
+}
+
+func encodeTransaction(tx *ledger.Transaction) string {
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return fmt.Sprintf("unencodable: %v", err)
+	}
+	return string(data)
+}
+
+func describeTransaction(out io.Writer, name string, tx *ledger.Transaction) {
+	if tx == nil {
+		fmt.Fprintf(out, "    %s: none\n", name)
+		return
+	}
+	fmt.Fprintf(out, "    %s: tx %s type %s from %s\n", name, tx.ID, tx.Type, tx.SenderPublicKey)
+}
+
+// compareState reports the root of each snapshot section, with the first
+// entry that differs between the sides, and returns whether any differ.
+func compareState(out io.Writer, a, b *view) bool {
+	differ := false
+	for _, section := range sections {
+		ra, rb := a.state.root(section), b.state.root(section)
+		if ra == rb {
+			fmt.Fprintf(out, "%s: %d entries, root %s\n", section, len(a.state[section]), ra)
+			continue
+		}
+		differ = true
+		fmt.Fprintf(out, "%s: roots differ\n  %s: %d entries, root %s\n  %s: %d entries, root %s\n",
+			section, a.name, len(a.state[section]), ra, b.name, len(b.state[section]), rb)
+		key, va, vb := firstDifference(a.state[section], b.state[section])
+		fmt.Fprintf(out, "  first difference at %s\n    %s: %s\n    %s: %s\n", key, a.name, va, b.name, vb)
+	}
+	return differ
+}
+
+// firstDifference returns the first key, in sorted order, whose entry differs
+// between a and b, with both entries; a missing entry is "none".
+func firstDifference(a, b map[string]string) (key, va, vb string) {
+	keys := sortedKeys(a)
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		ea, okA := a[k]
+		eb, okB := b[k]
+		if okA == okB && ea == eb {
+			continue
+		}
+		if !okA {
+			ea = "none"
+		}
+		if !okB {
+			eb = "none"
+		}
+		return k, ea, eb
+	}
+	return "", "", ""
+}
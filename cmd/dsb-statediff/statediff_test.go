@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"digisocialblock/core/api"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"digisocialblock/internal/testutil/fixture"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// statediffTestLog writes the blocks of bc to a new block log and returns its
+// path.
+func statediffTestLog(t *testing.T, name string, bc *ledger.Blockchain) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	store, err := ledger.OpenFileBlockStore(path, ledger.FileBlockStoreOptions{})
+	if err != nil {
+		t.Fatalf("OpenFileBlockStore() error = %v", err)
+	}
+	defer store.Close()
+	for _, block := range bc.Blocks {
+		if err := store.AppendBlock(block); err != nil {
+			t.Fatalf("AppendBlock() error = %v", err)
+		}
+	}
+	return path
+}
+
+func statediffRun(t *testing.T, a, b side) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	err := run(a, b, options{chainID: ledger.DefaultChainID, timeout: time.Minute}, &out)
+	return out.String(), err
+}
+
+func TestRun_IdenticalAndDivergedLogs(t *testing.T) {
+	alice, bob := fixture.Wallet(t), fixture.Wallet(t)
+	post := fixture.PostTx(t, alice, "cid-1", "go")
+	bc := fixture.Chain(t, []*ledger.Transaction{post}, []*ledger.Transaction{fixture.FollowTx(t, bob, alice.Address)})
+	path := statediffTestLog(t, "a.log", bc)
+
+	out, err := statediffRun(t, side{location: path}, side{location: statediffTestLog(t, "copy.log", bc)})
+	if err != nil || !strings.Contains(out, "chains are identical: 3 blocks") || !strings.Contains(out, "follows: 1 entries") {
+		t.Fatalf("run() of identical logs = %v\n%s", err, out)
+	}
+
+	// A second chain shares block 1 and then records a different follow.
+	other, err := ledger.NewBlockchain()
+	if err != nil {
+		t.Fatalf("NewBlockchain() error = %v", err)
+	}
+	if err := other.AppendBlock(bc.Blocks[1]); err != nil {
+		t.Fatalf("AppendBlock() error = %v", err)
+	}
+	follow := fixture.FollowTx(t, alice, bob.Address)
+	if _, err := other.AddBlock([]*ledger.Transaction{follow}); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+	out, err = statediffRun(t, side{location: path}, side{location: statediffTestLog(t, "b.log", other)})
+	if !errors.Is(err, errFound) {
+		t.Fatalf("run() of diverged logs error = %v, want errFound", err)
+	}
+	for _, want := range []string{"chains diverge at height 2", "first differing transaction at position 0", "tx " + follow.ID, "state not compared: the chains differ"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("run() output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRun_StaleFeedIndexFile(t *testing.T) {
+	alice := fixture.Wallet(t)
+	post := fixture.PostTx(t, alice, "cid-1", "go")
+	bc := fixture.Chain(t, []*ledger.Transaction{post})
+	path := statediffTestLog(t, "chain.log", bc)
+
+	// A feed index that recorded block 1 but lost its post.
+	feedPath := filepath.Join(t.TempDir(), "feed.json")
+	store, err := social.NewFileFeedIndexStore(feedPath)
+	if err != nil {
+		t.Fatalf("NewFileFeedIndexStore() error = %v", err)
+	}
+	block := bc.GetLatestBlock()
+	if err := store.SaveFeedIndex(&social.FeedIndexState{HighWaterIndex: block.Index, HighWaterHash: block.Hash}); err != nil {
+		t.Fatalf("SaveFeedIndex() error = %v", err)
+	}
+	before, _ := os.ReadFile(feedPath)
+
+	out, err := statediffRun(t, side{location: path, feedIndex: feedPath}, side{location: path})
+	if !errors.Is(err, errFound) {
+		t.Fatalf("run() error = %v, want errFound", err)
+	}
+	if !strings.Contains(out, "feed: roots differ") || !strings.Contains(out, "first difference at "+post.ID) {
+		t.Errorf("run() output does not name the missing post:\n%s", out)
+	}
+	var state social.FeedIndexState
+	if after, _ := os.ReadFile(feedPath); !bytes.Equal(after, before) || json.Unmarshal(after, &state) != nil {
+		t.Error("run() modified the feed index file")
+	}
+}
+
+func TestRun_NodeAgainstLog(t *testing.T) {
+	alice := fixture.Wallet(t)
+	bc := fixture.Chain(t, []*ledger.Transaction{fixture.PostTx(t, alice, "cid-1", "go")})
+	s, err := api.NewServer(ledger.NewMempool(nil), api.ServerOptions{Chain: bc})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	path := statediffTestLog(t, "chain.log", bc)
+
+	out, err := statediffRun(t, side{location: srv.URL}, side{location: path})
+	if err != nil || !strings.Contains(out, "chains are identical: 2 blocks") || !strings.Contains(out, "nodes serve only block headers") {
+		t.Fatalf("run() = %v\n%s", err, out)
+	}
+	if _, err := statediffRun(t, side{location: srv.URL, feedIndex: "feed.json"}, side{location: path}); !errors.Is(err, errUsage) {
+		t.Errorf("run() with a feed index for a node error = %v, want errUsage", err)
+	}
+}
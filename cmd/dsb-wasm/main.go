@@ -0,0 +1,158 @@
+//go:build js && wasm
+
+// Command dsb-wasm exposes clientkit to JavaScript so a browser app can hold
+// its own key, sign transactions locally and submit them to a node with
+// POST /v1/transactions. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o dsb.wasm ./cmd/dsb-wasm
+//
+// and load it with the wasm_exec.js shipped in $(go env GOROOT)/misc/wasm.
+// The functions are installed on globalThis.dsb. Each returns a JSON string
+// on success and throws an Error on failure:
+//
+//	dsb.generateKey()                                    -> {"privateKey": hex, "address": hex}
+//	dsb.identicon(address)                               -> {"color": "#rrggbb", "svg": SVG document}
+//	dsb.postPayload(address, contentCID, title, tagsJSON) -> payload (base64, as in a transaction)
+//	dsb.signProfile(privateKeyHex, profileJSON, chainID) -> signed profile
+//	dsb.signTransaction(privateKeyHex, type, payloadB64, chainID) -> signed transaction
+package main
+
+import (
+	"crypto/ecdsa"
+	"digisocialblock/core/clientkit"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"syscall/js"
+)
+
+func main() {
+	api := js.Global().Get("Object").New()
+	api.Set("generateKey", export(generateKey))
+	api.Set("identicon", export(identicon))
+	api.Set("postPayload", export(postPayload))
+	api.Set("signProfile", export(signProfile))
+	api.Set("signTransaction", export(signTransaction))
+	js.Global().Set("dsb", api)
+	select {} // Keep the functions callable for the lifetime of the page.
+}
+
+// export wraps fn as a JavaScript function that takes string arguments,
+// returns fn's result as a JSON string and throws fn's error.
+func export(fn func(args []string) (interface{}, error)) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		strArgs := make([]string, len(args))
+		for i, arg := range args {
+			strArgs[i] = arg.String()
+		}
+		result, err := fn(strArgs)
+		if err == nil {
+			var data []byte
+			if data, err = json.Marshal(result); err == nil {
+				return string(data)
+			}
+		}
+		panic(js.Global().Get("Error").New(err.Error()))
+	})
+}
+
+func wantArgs(args []string, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("expected %d arguments, got %d", n, len(args))
+	}
+	return nil
+}
+
+func parsePrivateKey(keyHex string) (*ecdsa.PrivateKey, error) {
+	der, err := hex.DecodeString(keyHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode private key hex: %w", err)
+	}
+	return clientkit.ParsePrivateKey(der)
+}
+
+func generateKey(args []string) (interface{}, error) {
+	priv, err := clientkit.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	der, err := clientkit.MarshalPrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	address, err := clientkit.Address(&priv.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"privateKey": hex.EncodeToString(der), "address": address}, nil
+}
+
+func identicon(args []string) (interface{}, error) {
+	if err := wantArgs(args, 1); err != nil {
+		return nil, err
+	}
+	icon := clientkit.NewIdenticon(args[0])
+	return map[string]string{"color": icon.Color, "svg": icon.SVG()}, nil
+}
+
+func postPayload(args []string) (interface{}, error) {
+	if err := wantArgs(args, 4); err != nil {
+		return nil, err
+	}
+	var tags []string
+	if args[3] != "" {
+		if err := json.Unmarshal([]byte(args[3]), &tags); err != nil {
+			return nil, fmt.Errorf("failed to parse tags: %w", err)
+		}
+	}
+	payload, err := clientkit.EncodePayload(clientkit.PayloadFormatJSON, clientkit.NewPost(args[0], args[1], args[2], tags))
+	if err != nil {
+		return nil, err
+	}
+	return payload, nil // []byte marshals as base64, the encoding Transaction.Payload uses.
+}
+
+func signProfile(args []string) (interface{}, error) {
+	if err := wantArgs(args, 3); err != nil {
+		return nil, err
+	}
+	priv, err := parsePrivateKey(args[0])
+	if err != nil {
+		return nil, err
+	}
+	var profile clientkit.Profile
+	if err := json.Unmarshal([]byte(args[1]), &profile); err != nil {
+		return nil, fmt.Errorf("failed to parse profile: %w", err)
+	}
+	if err := profile.Sign(priv, args[2]); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func signTransaction(args []string) (interface{}, error) {
+	if err := wantArgs(args, 4); err != nil {
+		return nil, err
+	}
+	priv, err := parsePrivateKey(args[0])
+	if err != nil {
+		return nil, err
+	}
+	payload, err := base64.StdEncoding.DecodeString(args[2])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode payload base64: %w", err)
+	}
+	address, err := clientkit.Address(&priv.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := clientkit.NewTransaction(address, args[1], payload, args[3])
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Sign(priv); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
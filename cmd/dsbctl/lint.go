@@ -0,0 +1,124 @@
+package main
+
+import (
+	"digisocialblock/core/content"
+	"digisocialblock/core/lint"
+	"digisocialblock/core/mobile"
+	"digisocialblock/pkg/dds/chunking"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// errUsage reports bad command-line arguments; the caller exits with status 2.
+var errUsage = errors.New("invalid arguments")
+
+// errFound reports that lint found problems.
+var errFound = errors.New("problems found")
+
+// stdin is read by lint when the file is "-".
+var stdin io.Reader = os.Stdin
+
+// linters maps each -type to its lint function.
+var linters = map[string]func([]byte, lint.Options) *lint.Report{
+	"post":    lint.Post,
+	"profile": lint.Profile,
+}
+
+func runLint(args []string, out io.Writer) error {
+	fs := newFlagSet("lint")
+	kind := fs.String("type", "", "payload type, post or profile (default: detected from the payload's fields)")
+	ddsDir := fs.String("dds-dir", "", "DDS chunk directory to resolve attachment CIDs in (as written by mobile.DirStore)")
+	strict := fs.Bool("strict", false, "fail on warnings as well as errors")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() != 1 || (*kind != "" && linters[*kind] == nil) {
+		fmt.Fprintln(os.Stderr, "usage: dsbctl lint [-type post|profile] [-dds-dir dir] [-strict] file|-")
+		return errUsage
+	}
+
+	var data []byte
+	var err error
+	if path := fs.Arg(0); path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read payload: %w", err)
+	}
+	if *kind == "" {
+		if *kind, err = detectType(data); err != nil {
+			return err
+		}
+	}
+	var opts lint.Options
+	if *ddsDir != "" {
+		if info, err := os.Stat(*ddsDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("DDS directory %s is not a directory", *ddsDir)
+		}
+		store, err := mobile.NewDirStore(*ddsDir)
+		if err != nil {
+			return err
+		}
+		opts.Manifests = dirManifests{store}
+	}
+
+	report := linters[*kind](data, opts)
+	if err := writeJSON(out, report); err != nil {
+		return err
+	}
+	if !report.OK() || (*strict && len(report.Findings) > 0) {
+		return errFound
+	}
+	return nil
+}
+
+// detectType tells a post from a profile by the field naming its author.
+func detectType(data []byte) (string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", fmt.Errorf("payload is not a JSON object: %w", err)
+	}
+	_, post := fields["authorPublicKey"]
+	_, profile := fields["ownerPublicKey"]
+	switch {
+	case post && !profile:
+		return "post", nil
+	case profile && !post:
+		return "profile", nil
+	}
+	return "", fmt.Errorf("cannot tell whether the payload is a post or a profile; use -type")
+}
+
+// dirManifests fetches manifests stored in a DirStore under their CIDs.
+type dirManifests struct{ store *mobile.DirStore }
+
+func (d dirManifests) FetchManifest(manifestCID string) (*chunking.ContentManifestV1, error) {
+	data, err := d.store.Get(manifestCID)
+	if err != nil {
+		return nil, fmt.Errorf("manifest not found: %w", err)
+	}
+	return content.DecodeManifest(data)
+}
+
+// newFlagSet returns a flag set that reports errors instead of exiting, with
+// its usage written to stderr.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("dsbctl "+name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+func writeJSON(out io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	_, err = out.Write(append(data, '\n'))
+	return err
+}
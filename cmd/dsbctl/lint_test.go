@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"digisocialblock/core/lint"
+	"digisocialblock/core/mobile"
+	"digisocialblock/core/social"
+	"digisocialblock/core/user"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// lintTestFile writes data to a new file and returns its path.
+func lintTestFile(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "payload.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func lintRun(t *testing.T, args ...string) (*lint.Report, error) {
+	t.Helper()
+	var out bytes.Buffer
+	err := runLint(args, &out)
+	if errors.Is(err, errUsage) {
+		return nil, err
+	}
+	var report lint.Report
+	if jerr := json.Unmarshal(out.Bytes(), &report); jerr != nil {
+		t.Fatalf("lint output %q: %v", out.String(), jerr)
+	}
+	return &report, err
+}
+
+func TestLint_PostWithAttachments(t *testing.T) {
+	dir := t.TempDir()
+	chunks, _ := mobile.NewDirStore(dir)
+	src, _ := mobile.NewContent(chunks)
+	cid, err := src.PublishText("hello")
+	if err != nil {
+		t.Fatalf("PublishText() error = %v", err)
+	}
+	post := social.NewPost("alice", cid, "", []string{"go"})
+	data, _ := post.ToJSON()
+	path := lintTestFile(t, data)
+
+	if report, err := lintRun(t, "-dds-dir", dir, "-strict", path); err != nil || report.Type != "PostCreated" || len(report.Findings) != 0 {
+		t.Errorf("lint of a clean post = %+v, %v", report, err)
+	}
+
+	post.Media = []string{"not-in-dds"}
+	data, _ = post.ToJSON()
+	path = lintTestFile(t, data)
+	if report, err := lintRun(t, "-dds-dir", dir, path); err != nil || len(report.Findings) != 1 || report.Findings[0].Field != "media[0]" {
+		t.Errorf("lint of a post with a missing attachment = %+v, %v; want one warning", report, err)
+	}
+	if _, err := lintRun(t, "-dds-dir", dir, "-strict", path); !errors.Is(err, errFound) {
+		t.Errorf("lint -strict error = %v, want errFound for a warning", err)
+	}
+}
+
+func TestLint_ProfileFromStdin(t *testing.T) {
+	data, _ := user.NewProfile("alice", "Alice", "").ToJSON()
+	stdin = bytes.NewReader(data)
+	defer func() { stdin = os.Stdin }()
+	if report, err := lintRun(t, "-"); err != nil || report.Type != "ProfileUpdate" {
+		t.Errorf("lint of a profile = %+v, %v", report, err)
+	}
+
+	path := lintTestFile(t, []byte(`{"ownerPublicKey":"alice","displayName":"Alice"}`))
+	if report, err := lintRun(t, path); !errors.Is(err, errFound) || report.OK() {
+		t.Errorf("lint of an invalid profile = %+v, %v; want errFound", report, err)
+	}
+}
+
+func TestLint_Usage(t *testing.T) {
+	path := lintTestFile(t, []byte(`{"version":1}`))
+	for _, args := range [][]string{{}, {"-type", "comment", path}, {"a", "b"}} {
+		if _, err := lintRun(t, args...); !errors.Is(err, errUsage) {
+			t.Errorf("lint %v error = %v, want errUsage", args, err)
+		}
+	}
+	var out bytes.Buffer
+	if err := runLint([]string{path}, &out); err == nil || !strings.Contains(err.Error(), "-type") {
+		t.Errorf("lint of an unrecognisable payload error = %v, want a hint to use -type", err)
+	}
+}
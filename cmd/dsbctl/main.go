@@ -0,0 +1,38 @@
+// Command dsbctl is a client toolbox for preparing transactions before they
+// are signed and submitted.
+//
+//	dsbctl lint post.json                             lint a Post or Profile (type detected from its fields)
+//	dsbctl lint -type profile profile.json
+//	dsbctl lint -dds-dir ./chunks post.json           also check that attachment CIDs resolve
+//	dsbctl lint -strict - < post.json                 fail on warnings as well as errors
+//
+// lint writes a JSON report (see package lint) and exits with status 1 when
+// the payload has errors, or warnings with -strict.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// commands maps each subcommand to its implementation. A command parses its
+// own flags from args and writes its report to out.
+var commands = map[string]func(args []string, out io.Writer) error{
+	"lint": runLint,
+}
+
+func main() {
+	if len(os.Args) < 2 || commands[os.Args[1]] == nil {
+		fmt.Fprintln(os.Stderr, "usage: dsbctl lint [flags]; run a subcommand with -h for its flags")
+		os.Exit(2)
+	}
+	if err := commands[os.Args[1]](os.Args[2:], os.Stdout); err != nil {
+		if errors.Is(err, errUsage) {
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,266 @@
+package activitypub
+
+import (
+	"crypto/rsa"
+	"digisocialblock/core/publicnet"
+	"digisocialblock/core/social"
+	"digisocialblock/core/user"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Paths served by the bridge.
+const (
+	ActorPathPrefix = "/ap/actors/"
+	WebFingerPath   = "/.well-known/webfinger"
+)
+
+// MaxOutboxItems bounds the number of posts listed in an outbox.
+const MaxOutboxItems = 50
+
+// ErrProfileNotFound is returned by a ProfileSource for an address with no profile.
+var ErrProfileNotFound = errors.New("profile not found")
+
+// FeedSource lists the posts indexed for an author; social.FeedService implements it.
+type FeedSource interface {
+	GetUserFeed(authorPublicKey string, limit int) []social.FeedEntry
+}
+
+// ProfileSource returns the current profile of an address, or ErrProfileNotFound.
+type ProfileSource interface {
+	GetProfile(address string) (*user.Profile, error)
+}
+
+// ContentSource fetches post text from DDS; content.ContentRetriever implements it.
+type ContentSource interface {
+	RetrieveAndVerifyTextPost(manifestCID string) (string, error)
+}
+
+// BridgeOptions configures a Bridge.
+type BridgeOptions struct {
+	BaseURL    string // Public origin of the bridge, e.g. "https://dsb.example"; required
+	GatewayURL string // Optional content gateway prefix, e.g. "https://dsb.example/content/"
+	// SigningKey signs outgoing deliveries (Accept activities). Fediverse
+	// servers only verify RSA HTTP signatures, so the bridge holds one RSA key
+	// and presents it as every actor's key. Without it, follows are not accepted.
+	SigningKey *rsa.PrivateKey
+	// HTTPClient fetches remote actors and delivers activities. Nil means a
+	// publicnet client with a 10s timeout, which only connects to public
+	// addresses; a client given here is used as is.
+	HTTPClient *http.Client
+}
+
+// Bridge exports public posts and profiles to the Fediverse.
+type Bridge struct {
+	baseURL    string
+	host       string
+	gatewayURL string
+	feed       FeedSource
+	profiles   ProfileSource
+	content    ContentSource
+	signingKey *rsa.PrivateKey
+	client     *http.Client
+	now        func() time.Time
+
+	mu        sync.RWMutex
+	followers map[string]map[string]string // Local address -> remote actor ID -> remote inbox
+}
+
+// NewBridge creates a Bridge serving posts from feed, profiles from profiles
+// and post text from content.
+func NewBridge(feed FeedSource, profiles ProfileSource, content ContentSource, opts BridgeOptions) (*Bridge, error) {
+	if feed == nil || profiles == nil || content == nil {
+		return nil, fmt.Errorf("feed, profile and content sources cannot be nil")
+	}
+	base, err := url.Parse(opts.BaseURL)
+	if err != nil || base.Host == "" || (base.Scheme != "https" && base.Scheme != "http") {
+		return nil, fmt.Errorf("invalid bridge base URL %q", opts.BaseURL)
+	}
+	client := opts.HTTPClient
+	if client == nil {
+		client = publicnet.NewClient(publicnet.Options{Timeout: 10 * time.Second})
+	}
+	return &Bridge{
+		baseURL:    strings.TrimSuffix(opts.BaseURL, "/"),
+		host:       base.Host,
+		gatewayURL: opts.GatewayURL,
+		feed:       feed,
+		profiles:   profiles,
+		content:    content,
+		signingKey: opts.SigningKey,
+		client:     client,
+		now:        time.Now,
+		followers:  make(map[string]map[string]string),
+	}, nil
+}
+
+// ServeHTTP implements http.Handler for the WebFinger and actor endpoints:
+//
+//	GET  /.well-known/webfinger?resource=acct:<address>@<host>
+//	GET  /ap/actors/<address>
+//	GET  /ap/actors/<address>/outbox
+//	GET  /ap/actors/<address>/notes/<txID>
+//	POST /ap/actors/<address>/inbox
+func (b *Bridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == WebFingerPath {
+		b.handleWebFinger(w, r)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, ActorPathPrefix) {
+		http.NotFound(w, r)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, ActorPathPrefix), "/")
+	address := parts[0]
+	switch {
+	case len(parts) == 1:
+		b.handleActor(w, r, address)
+	case len(parts) == 2 && parts[1] == "outbox":
+		b.handleOutbox(w, r, address)
+	case len(parts) == 2 && parts[1] == "inbox":
+		b.handleInbox(w, r, address)
+	case len(parts) == 3 && parts[1] == "notes":
+		b.handleNote(w, r, address, parts[2])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// WebFingerResponse is a JRD document as returned by the WebFinger endpoint.
+type WebFingerResponse struct {
+	Subject string          `json:"subject"`
+	Links   []WebFingerLink `json:"links"`
+}
+
+// WebFingerLink is one link of a WebFinger response.
+type WebFingerLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type"`
+	Href string `json:"href"`
+}
+
+func (b *Bridge) handleWebFinger(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	resource := r.URL.Query().Get("resource")
+	acct := strings.TrimPrefix(resource, "acct:")
+	at := strings.LastIndex(acct, "@")
+	if acct == resource || at < 0 || !strings.EqualFold(acct[at+1:], b.host) {
+		http.Error(w, "unknown resource", http.StatusNotFound)
+		return
+	}
+	address := acct[:at]
+	if !b.knownActor(address) {
+		http.Error(w, "unknown resource", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, "application/jrd+json", WebFingerResponse{
+		Subject: resource,
+		Links:   []WebFingerLink{{Rel: "self", Type: ContentType, Href: b.actorURL(address)}},
+	})
+}
+
+func (b *Bridge) handleActor(w http.ResponseWriter, r *http.Request, address string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	profile, err := b.profiles.GetProfile(address)
+	if err != nil && !errors.Is(err, ErrProfileNotFound) {
+		log.Printf("activitypub: failed to load profile for %s: %v", address, err)
+		http.Error(w, "profile unavailable", http.StatusBadGateway)
+		return
+	}
+	if profile == nil && len(b.feed.GetUserFeed(address, 1)) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	actor, err := b.ProfileToActor(address, profile)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, ContentType, actor)
+}
+
+func (b *Bridge) handleOutbox(w http.ResponseWriter, r *http.Request, address string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !b.knownActor(address) {
+		http.NotFound(w, r)
+		return
+	}
+	outbox := OrderedCollection{
+		Context:      ContextActivityStreams,
+		ID:           b.actorURL(address) + "/outbox",
+		Type:         "OrderedCollection",
+		OrderedItems: []interface{}{},
+	}
+	for _, entry := range b.feed.GetUserFeed(address, 0) {
+		if entry.GroupID != "" {
+			continue
+		}
+		outbox.TotalItems++
+		if len(outbox.OrderedItems) == MaxOutboxItems {
+			continue
+		}
+		text, err := b.content.RetrieveAndVerifyTextPost(entry.ContentCID)
+		if err != nil {
+			// Content that cannot be fetched or verified is left out rather
+			// than failing the whole outbox.
+			log.Printf("activitypub: skipping post %s: %v", entry.TxID, err)
+			continue
+		}
+		outbox.OrderedItems = append(outbox.OrderedItems, createActivity(b.PostToNote(entry, text)))
+	}
+	writeJSON(w, ContentType, outbox)
+}
+
+func (b *Bridge) handleNote(w http.ResponseWriter, r *http.Request, address, txID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	for _, entry := range b.feed.GetUserFeed(address, 0) {
+		if entry.TxID != txID || entry.GroupID != "" {
+			continue
+		}
+		text, err := b.content.RetrieveAndVerifyTextPost(entry.ContentCID)
+		if err != nil {
+			log.Printf("activitypub: failed to retrieve post %s: %v", txID, err)
+			http.Error(w, "content unavailable", http.StatusBadGateway)
+			return
+		}
+		note := b.PostToNote(entry, text)
+		note.Context = ContextActivityStreams
+		writeJSON(w, ContentType, note)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// knownActor reports whether address has a profile or at least one post.
+func (b *Bridge) knownActor(address string) bool {
+	if profile, err := b.profiles.GetProfile(address); err == nil && profile != nil {
+		return true
+	}
+	return len(b.feed.GetUserFeed(address, 1)) > 0
+}
+
+func writeJSON(w http.ResponseWriter, contentType string, v interface{}) {
+	w.Header().Set("Content-Type", contentType)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("activitypub: failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package activitypub
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/social"
+	"digisocialblock/core/user"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeFeed struct {
+	entries []social.FeedEntry // Oldest first
+}
+
+func (f *fakeFeed) GetUserFeed(author string, limit int) []social.FeedEntry {
+	var out []social.FeedEntry
+	for i := len(f.entries) - 1; i >= 0; i-- {
+		if f.entries[i].AuthorPublicKey == author && (limit <= 0 || len(out) < limit) {
+			out = append(out, f.entries[i])
+		}
+	}
+	return out
+}
+
+type fakeProfiles map[string]*user.Profile
+
+func (f fakeProfiles) GetProfile(address string) (*user.Profile, error) {
+	if p, ok := f[address]; ok {
+		return p, nil
+	}
+	return nil, ErrProfileNotFound
+}
+
+type fakeContent map[string]string
+
+func (f fakeContent) RetrieveAndVerifyTextPost(cid string) (string, error) {
+	if text, ok := f[cid]; ok {
+		return text, nil
+	}
+	return "", fmt.Errorf("content %s not found", cid)
+}
+
+// newTestBridge returns a bridge with one author who has a profile, two public
+// posts and one group post.
+func newTestBridge(t *testing.T) (*Bridge, string) {
+	t.Helper()
+	wallet, err := identity.NewWallet()
+	if err != nil {
+		t.Fatalf("NewWallet() error = %v", err)
+	}
+	addr := wallet.Address
+	feed := &fakeFeed{entries: []social.FeedEntry{
+		{TxID: "tx1", AuthorPublicKey: addr, ContentCID: "cid1", Timestamp: 1700000000000000000, Title: "First"},
+		{TxID: "tx2", AuthorPublicKey: addr, ContentCID: "cid2", Timestamp: 1700000001000000000, Tags: []string{"go"}},
+		{TxID: "tx3", AuthorPublicKey: addr, ContentCID: "cid3", Timestamp: 1700000002000000000, GroupID: "club"},
+	}}
+	profiles := fakeProfiles{addr: user.NewProfile(addr, "Alice", "Hello <world>")}
+	content := fakeContent{"cid1": "first post", "cid2": "line one\nline two", "cid3": "secret"}
+	b, err := NewBridge(feed, profiles, content, BridgeOptions{BaseURL: "https://dsb.example", GatewayURL: "https://dsb.example/content/"})
+	if err != nil {
+		t.Fatalf("NewBridge() error = %v", err)
+	}
+	return b, addr
+}
+
+func get(t *testing.T, h http.Handler, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestNewBridge_Validation(t *testing.T) {
+	for _, base := range []string{"", "dsb.example", "ftp://dsb.example"} {
+		if _, err := NewBridge(&fakeFeed{}, fakeProfiles{}, fakeContent{}, BridgeOptions{BaseURL: base}); err == nil {
+			t.Errorf("NewBridge(BaseURL=%q): expected error, got nil", base)
+		}
+	}
+	if _, err := NewBridge(nil, fakeProfiles{}, fakeContent{}, BridgeOptions{BaseURL: "https://dsb.example"}); err == nil {
+		t.Error("NewBridge() with nil feed: expected error, got nil")
+	}
+}
+
+func TestBridge_WebFinger(t *testing.T) {
+	b, addr := newTestBridge(t)
+	rec := get(t, b, WebFingerPath+"?resource=acct:"+addr+"@dsb.example")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("WebFinger status = %d, want 200", rec.Code)
+	}
+	var jrd WebFingerResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &jrd); err != nil {
+		t.Fatalf("WebFinger body is not JSON: %v", err)
+	}
+	if len(jrd.Links) != 1 || jrd.Links[0].Href != "https://dsb.example/ap/actors/"+addr || jrd.Links[0].Type != ContentType {
+		t.Errorf("WebFinger links = %+v", jrd.Links)
+	}
+
+	for _, resource := range []string{
+		"acct:" + addr + "@other.example", // Wrong host
+		"acct:unknown@dsb.example",        // Unknown address
+		addr + "@dsb.example",             // Missing acct: scheme
+	} {
+		if rec := get(t, b, WebFingerPath+"?resource="+resource); rec.Code != http.StatusNotFound {
+			t.Errorf("WebFinger(%s) status = %d, want 404", resource, rec.Code)
+		}
+	}
+}
+
+func TestBridge_Actor(t *testing.T) {
+	b, addr := newTestBridge(t)
+	rec := get(t, b, ActorPathPrefix+addr)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("actor status = %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, ContentType)
+	}
+	var actor Actor
+	if err := json.Unmarshal(rec.Body.Bytes(), &actor); err != nil {
+		t.Fatalf("actor body is not JSON: %v", err)
+	}
+	if actor.Type != "Person" || actor.Name != "Alice" || actor.PreferredUsername != addr {
+		t.Errorf("actor = %+v", actor)
+	}
+	if actor.Summary != "<p>Hello &lt;world&gt;</p>" {
+		t.Errorf("actor summary = %q, want escaped HTML", actor.Summary)
+	}
+	if actor.Outbox != actor.ID+"/outbox" || actor.Inbox != actor.ID+"/inbox" {
+		t.Errorf("actor endpoints = %q, %q", actor.Inbox, actor.Outbox)
+	}
+	if actor.PublicKey == nil || !strings.Contains(actor.PublicKey.PublicKeyPEM, "BEGIN PUBLIC KEY") {
+		t.Errorf("actor public key = %+v", actor.PublicKey)
+	}
+
+	if rec := get(t, b, ActorPathPrefix+"unknown"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown actor status = %d, want 404", rec.Code)
+	}
+}
+
+func TestBridge_Outbox(t *testing.T) {
+	b, addr := newTestBridge(t)
+	rec := get(t, b, ActorPathPrefix+addr+"/outbox")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("outbox status = %d, want 200", rec.Code)
+	}
+	var outbox struct {
+		TotalItems   int `json:"totalItems"`
+		OrderedItems []struct {
+			Type   string `json:"type"`
+			Object Note   `json:"object"`
+		} `json:"orderedItems"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &outbox); err != nil {
+		t.Fatalf("outbox body is not JSON: %v", err)
+	}
+	// The group post is never exported.
+	if outbox.TotalItems != 2 || len(outbox.OrderedItems) != 2 {
+		t.Fatalf("outbox has %d/%d items, want 2/2", outbox.TotalItems, len(outbox.OrderedItems))
+	}
+	newest := outbox.OrderedItems[0]
+	if newest.Type != "Create" || newest.Object.Content != "<p>line one<br>line two</p>" {
+		t.Errorf("newest outbox item = %+v", newest)
+	}
+	if len(newest.Object.Tag) != 1 || newest.Object.Tag[0].Name != "#go" {
+		t.Errorf("newest note tags = %+v", newest.Object.Tag)
+	}
+	if newest.Object.URL != "https://dsb.example/content/cid2" {
+		t.Errorf("newest note URL = %q", newest.Object.URL)
+	}
+	if newest.Object.Published != "2023-11-14T22:13:21Z" {
+		t.Errorf("newest note published = %q", newest.Object.Published)
+	}
+	if strings.Contains(rec.Body.String(), "secret") {
+		t.Error("outbox leaks group post content")
+	}
+}
+
+func TestBridge_Note(t *testing.T) {
+	b, addr := newTestBridge(t)
+	rec := get(t, b, ActorPathPrefix+addr+"/notes/tx1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("note status = %d, want 200", rec.Code)
+	}
+	var note Note
+	if err := json.Unmarshal(rec.Body.Bytes(), &note); err != nil {
+		t.Fatalf("note body is not JSON: %v", err)
+	}
+	if note.ID != "https://dsb.example/ap/actors/"+addr+"/notes/tx1" || note.Name != "First" || note.Content != "<p>first post</p>" {
+		t.Errorf("note = %+v", note)
+	}
+	for _, txID := range []string{"tx3", "missing"} {
+		if rec := get(t, b, ActorPathPrefix+addr+"/notes/"+txID); rec.Code != http.StatusNotFound {
+			t.Errorf("note %s status = %d, want 404", txID, rec.Code)
+		}
+	}
+}
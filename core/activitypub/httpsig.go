@@ -0,0 +1,181 @@
+package activitypub
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MaxSignatureAge bounds the clock skew accepted on signed requests.
+const MaxSignatureAge = 5 * time.Minute
+
+// ErrBadSignature is returned when an incoming request's HTTP signature is
+// missing or does not verify.
+var ErrBadSignature = errors.New("invalid HTTP signature")
+
+// signedHeaders are the headers covered by the signatures the bridge makes.
+var signedHeaders = []string{"(request-target)", "host", "date", "digest"}
+
+// signRequest adds Date, Digest and an rsa-sha256 Signature header (the
+// draft-cavage HTTP Signatures scheme used across the Fediverse) to req.
+func signRequest(req *http.Request, key *rsa.PrivateKey, keyID string, body []byte, now time.Time) error {
+	req.Header.Set("Date", now.UTC().Format(http.TimeFormat))
+	req.Header.Set("Digest", bodyDigest(body))
+	req.Header.Set("Host", req.URL.Host)
+	signingString, err := buildSigningString(req, signedHeaders)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256([]byte(signingString))
+	sig, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(signedHeaders, " "), base64.StdEncoding.EncodeToString(sig)))
+	return nil
+}
+
+// signatureParams is a parsed Signature header.
+type signatureParams struct {
+	keyID     string
+	headers   []string
+	signature []byte
+}
+
+func parseSignatureHeader(header string) (*signatureParams, error) {
+	params := &signatureParams{headers: []string{"date"}}
+	for _, field := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: malformed parameter %q", ErrBadSignature, field)
+		}
+		v = strings.Trim(v, `"`)
+		switch k {
+		case "keyId":
+			params.keyID = v
+		case "headers":
+			params.headers = strings.Fields(strings.ToLower(v))
+		case "algorithm":
+			if v != "rsa-sha256" && v != "hs2019" {
+				return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrBadSignature, v)
+			}
+		case "signature":
+			sig, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, fmt.Errorf("%w: signature is not base64", ErrBadSignature)
+			}
+			params.signature = sig
+		}
+	}
+	if params.keyID == "" || len(params.signature) == 0 {
+		return nil, fmt.Errorf("%w: keyId and signature are required", ErrBadSignature)
+	}
+	return params, nil
+}
+
+// verifyRequest checks the HTTP signature of an incoming request with body.
+// The signature must cover (request-target), host, date and, for requests
+// with a body, digest. lookupKey resolves the keyId to a public key.
+func verifyRequest(req *http.Request, body []byte, now time.Time, lookupKey func(keyID string) (*rsa.PublicKey, error)) (keyID string, err error) {
+	header := req.Header.Get("Signature")
+	if header == "" {
+		return "", fmt.Errorf("%w: missing Signature header", ErrBadSignature)
+	}
+	params, err := parseSignatureHeader(header)
+	if err != nil {
+		return "", err
+	}
+	covered := make(map[string]bool, len(params.headers))
+	for _, h := range params.headers {
+		covered[h] = true
+	}
+	for _, h := range signedHeaders {
+		if !covered[h] && (h != "digest" || len(body) > 0) {
+			return "", fmt.Errorf("%w: signature does not cover %s", ErrBadSignature, h)
+		}
+	}
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid Date header", ErrBadSignature)
+	}
+	if skew := now.Sub(date); skew > MaxSignatureAge || skew < -MaxSignatureAge {
+		return "", fmt.Errorf("%w: Date is outside the allowed window", ErrBadSignature)
+	}
+	if len(body) > 0 && req.Header.Get("Digest") != bodyDigest(body) {
+		return "", fmt.Errorf("%w: Digest does not match the body", ErrBadSignature)
+	}
+	signingString, err := buildSigningString(req, params.headers)
+	if err != nil {
+		return "", err
+	}
+	pub, err := lookupKey(params.keyID)
+	if err != nil {
+		return "", fmt.Errorf("%w: cannot resolve key %s: %v", ErrBadSignature, params.keyID, err)
+	}
+	hash := sha256.Sum256([]byte(signingString))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], params.signature); err != nil {
+		return "", fmt.Errorf("%w: signature does not verify", ErrBadSignature)
+	}
+	return params.keyID, nil
+}
+
+func buildSigningString(req *http.Request, headers []string) (string, error) {
+	var buf bytes.Buffer
+	for i, h := range headers {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		switch h {
+		case "(request-target)":
+			fmt.Fprintf(&buf, "(request-target): %s %s", strings.ToLower(req.Method), req.URL.RequestURI())
+		case "host":
+			host := req.Host
+			if host == "" {
+				host = req.URL.Host
+			}
+			fmt.Fprintf(&buf, "host: %s", host)
+		default:
+			v := req.Header.Get(h)
+			if v == "" {
+				return "", fmt.Errorf("%w: signed header %s is missing", ErrBadSignature, h)
+			}
+			fmt.Fprintf(&buf, "%s: %s", h, v)
+		}
+	}
+	return buf.String(), nil
+}
+
+func bodyDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// parseRSAPublicKeyPEM parses a PKIX or PKCS#1 RSA public key in PEM form.
+func parseRSAPublicKeyPEM(data string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, fmt.Errorf("public key is not PEM")
+	}
+	if pub, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return pub, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is not RSA")
+	}
+	return pub, nil
+}
@@ -0,0 +1,72 @@
+package activitypub
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	return key
+}
+
+func TestSignAndVerifyRequest(t *testing.T) {
+	key := testRSAKey(t)
+	now := time.Now()
+	body := []byte(`{"type":"Follow"}`)
+	lookup := func(keyID string) (*rsa.PublicKey, error) { return &key.PublicKey, nil }
+
+	newSigned := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "https://dsb.example/ap/actors/a/inbox", bytes.NewReader(body))
+		if err := signRequest(req, key, "https://remote.example/users/bob#main-key", body, now); err != nil {
+			t.Fatalf("signRequest() error = %v", err)
+		}
+		return req
+	}
+
+	keyID, err := verifyRequest(newSigned(), body, now, lookup)
+	if err != nil {
+		t.Fatalf("verifyRequest() error = %v", err)
+	}
+	if keyID != "https://remote.example/users/bob#main-key" {
+		t.Errorf("verifyRequest() keyID = %q", keyID)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*http.Request) ([]byte, time.Time)
+	}{
+		{"tampered body", func(r *http.Request) ([]byte, time.Time) { return []byte(`{"type":"Undo"}`), now }},
+		{"stale date", func(r *http.Request) ([]byte, time.Time) { return body, now.Add(MaxSignatureAge + time.Minute) }},
+		{"missing signature", func(r *http.Request) ([]byte, time.Time) { r.Header.Del("Signature"); return body, now }},
+		{"different path", func(r *http.Request) ([]byte, time.Time) { r.URL.Path = "/ap/actors/b/inbox"; return body, now }},
+		{"digest not covered", func(r *http.Request) ([]byte, time.Time) {
+			r.Header.Set("Signature", `keyId="k",algorithm="rsa-sha256",headers="(request-target) host date",signature="AAAA"`)
+			return body, now
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newSigned()
+			b, at := tt.mutate(req)
+			if _, err := verifyRequest(req, b, at, lookup); !errors.Is(err, ErrBadSignature) {
+				t.Errorf("verifyRequest() error = %v, want ErrBadSignature", err)
+			}
+		})
+	}
+
+	other := testRSAKey(t)
+	if _, err := verifyRequest(newSigned(), body, now, func(string) (*rsa.PublicKey, error) { return &other.PublicKey, nil }); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("verifyRequest() with the wrong key error = %v, want ErrBadSignature", err)
+	}
+}
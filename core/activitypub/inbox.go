@@ -0,0 +1,229 @@
+package activitypub
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// MaxInboxBodySize bounds the size of activities accepted by the inbox.
+const MaxInboxBodySize = 256 << 10
+
+// MaxFollowersPerActor bounds the followers recorded for one local actor.
+const MaxFollowersPerActor = 10000
+
+// inboxActivity is the subset of an incoming activity the inbox looks at.
+// Object is either an ID string or an embedded object.
+type inboxActivity struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	Actor  string          `json:"actor"`
+	Object json.RawMessage `json:"object"`
+}
+
+// objectID returns the ID of an activity's object, whether it is given as a
+// string or embedded, and the embedded object's type if any.
+func objectID(raw json.RawMessage) (id, typ string) {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s, ""
+	}
+	var obj struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(raw, &obj) == nil {
+		return obj.ID, obj.Type
+	}
+	return "", ""
+}
+
+// handleInbox processes Follow and Undo(Follow) activities addressed to a
+// local actor. Every request must carry a valid HTTP signature from the
+// activity's actor. Other activity types are acknowledged and ignored.
+func (b *Bridge) handleInbox(w http.ResponseWriter, r *http.Request, address string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if b.signingKey == nil {
+		http.Error(w, "inbox is not enabled on this bridge", http.StatusNotImplemented)
+		return
+	}
+	if !b.knownActor(address) {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxInboxBodySize))
+	if err != nil {
+		http.Error(w, "activity too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	var activity inboxActivity
+	if err := json.Unmarshal(body, &activity); err != nil || activity.Actor == "" {
+		http.Error(w, "malformed activity", http.StatusBadRequest)
+		return
+	}
+
+	// The signing key must belong to the activity's actor; fetching the actor
+	// also gives us its inbox for the Accept. The actor is only fetched from
+	// the host named by the key, over https.
+	var remote *Actor
+	_, err = verifyRequest(r, body, b.now(), func(keyID string) (*rsa.PublicKey, error) {
+		actorURL, err := remoteURL(activity.Actor)
+		if err != nil {
+			return nil, err
+		}
+		keyURL, err := remoteURL(keyID)
+		if err != nil {
+			return nil, err
+		}
+		if keyURL.Host != actorURL.Host {
+			return nil, fmt.Errorf("key %s is not hosted with actor %s", keyID, activity.Actor)
+		}
+		if keyOwner := strings.SplitN(keyID, "#", 2)[0]; keyOwner != activity.Actor {
+			return nil, fmt.Errorf("key does not belong to %s", activity.Actor)
+		}
+		remote, err = b.fetchActor(address, activity.Actor)
+		if err != nil {
+			return nil, err
+		}
+		if remote.PublicKey == nil || remote.PublicKey.ID != keyID || remote.PublicKey.Owner != activity.Actor {
+			return nil, fmt.Errorf("actor does not publish key %s", keyID)
+		}
+		return parseRSAPublicKeyPEM(remote.PublicKey.PublicKeyPEM)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	target, innerType := objectID(activity.Object)
+	switch {
+	case activity.Type == "Follow" && target == b.actorURL(address):
+		if err := b.addFollower(address, activity.Actor, remote.Inbox); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err := b.deliverAccept(address, remote.Inbox, body); err != nil {
+			log.Printf("activitypub: failed to deliver Accept to %s: %v", remote.Inbox, err)
+		}
+	case activity.Type == "Undo" && innerType == "Follow":
+		b.removeFollower(address, activity.Actor)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// fetchActor retrieves a remote actor document. The fetch is signed as the
+// local actor so that servers requiring authorized fetch answer it.
+func (b *Bridge) fetchActor(address, actorID string) (*Actor, error) {
+	req, err := http.NewRequest(http.MethodGet, actorID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid actor ID: %w", err)
+	}
+	req.Header.Set("Accept", ContentType)
+	if err := signRequest(req, b.signingKey, b.actorURL(address)+"#main-key", nil, b.now()); err != nil {
+		return nil, err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch actor %s: %w", actorID, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching actor %s returned %s", actorID, resp.Status)
+	}
+	var actor Actor
+	if err := json.NewDecoder(io.LimitReader(resp.Body, MaxInboxBodySize)).Decode(&actor); err != nil {
+		return nil, fmt.Errorf("failed to decode actor %s: %w", actorID, err)
+	}
+	if actor.ID != actorID || actor.Inbox == "" {
+		return nil, fmt.Errorf("actor document for %s is inconsistent", actorID)
+	}
+	if _, err := remoteURL(actor.Inbox); err != nil {
+		return nil, fmt.Errorf("actor %s has an unusable inbox: %w", actorID, err)
+	}
+	return &actor, nil
+}
+
+// remoteURL parses a URL supplied by a remote server, which the bridge will
+// connect to, and checks that it is an absolute https URL.
+func remoteURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an absolute https URL", rawURL)
+	}
+	return u, nil
+}
+
+// deliverAccept sends an Accept for the follow request to the follower's inbox.
+func (b *Bridge) deliverAccept(address, inbox string, followJSON []byte) error {
+	accept := Activity{
+		Context: ContextActivityStreams,
+		ID:      b.actorURL(address) + "/accepts/" + fmt.Sprint(b.now().UnixNano()),
+		Type:    "Accept",
+		Actor:   b.actorURL(address),
+		Object:  json.RawMessage(followJSON),
+	}
+	body, err := json.Marshal(accept)
+	if err != nil {
+		return fmt.Errorf("failed to encode Accept: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, inbox, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid inbox URL: %w", err)
+	}
+	req.Header.Set("Content-Type", ContentType)
+	if err := signRequest(req, b.signingKey, b.actorURL(address)+"#main-key", body, b.now()); err != nil {
+		return err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("delivery failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("inbox returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (b *Bridge) addFollower(address, actorID, inbox string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	followers := b.followers[address]
+	if followers == nil {
+		followers = make(map[string]string)
+		b.followers[address] = followers
+	}
+	if _, ok := followers[actorID]; !ok && len(followers) >= MaxFollowersPerActor {
+		return fmt.Errorf("follower limit reached")
+	}
+	followers[actorID] = inbox
+	return nil
+}
+
+func (b *Bridge) removeFollower(address, actorID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.followers[address], actorID)
+}
+
+// Followers returns the remote actor IDs following address, sorted.
+func (b *Bridge) Followers(address string) []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	ids := make([]string, 0, len(b.followers[address]))
+	for id := range b.followers[address] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
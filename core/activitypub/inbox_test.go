@@ -0,0 +1,181 @@
+package activitypub
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// remoteServer is a minimal Fediverse server with one actor, bob, that
+// records the activities delivered to bob's inbox after verifying them.
+type remoteServer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	actorFetches atomic.Int32
+
+	mu        sync.Mutex
+	delivered []Activity
+}
+
+func newRemoteServer(t *testing.T, bridgeKey *rsa.PublicKey) *remoteServer {
+	t.Helper()
+	rs := &remoteServer{key: testRSAKey(t)}
+	rs.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/bob":
+			rs.actorFetches.Add(1)
+			keyPEM, _ := publicKeyPEM(&rs.key.PublicKey)
+			writeJSON(w, ContentType, Actor{
+				ID:        rs.actorID(),
+				Type:      "Person",
+				Inbox:     rs.URL + "/users/bob/inbox",
+				Outbox:    rs.URL + "/users/bob/outbox",
+				PublicKey: &PublicKey{ID: rs.actorID() + "#main-key", Owner: rs.actorID(), PublicKeyPEM: keyPEM},
+			})
+		case "/users/bob/inbox":
+			body, _ := io.ReadAll(r.Body)
+			if _, err := verifyRequest(r, body, time.Now(), func(string) (*rsa.PublicKey, error) { return bridgeKey, nil }); err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			var activity Activity
+			json.Unmarshal(body, &activity)
+			rs.mu.Lock()
+			rs.delivered = append(rs.delivered, activity)
+			rs.mu.Unlock()
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(rs.Close)
+	return rs
+}
+
+func (rs *remoteServer) actorID() string { return rs.URL + "/users/bob" }
+
+// post sends activity to inbox, signed with bob's key.
+func (rs *remoteServer) post(t *testing.T, h http.Handler, inbox string, activity interface{}) int {
+	t.Helper()
+	body, _ := json.Marshal(activity)
+	req := httptest.NewRequest(http.MethodPost, inbox, bytes.NewReader(body))
+	if err := signRequest(req, rs.key, rs.actorID()+"#main-key", body, time.Now()); err != nil {
+		t.Fatalf("signRequest() error = %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestBridge_InboxFollow(t *testing.T) {
+	b, addr := newTestBridge(t)
+	b.signingKey = testRSAKey(t)
+	remote := newRemoteServer(t, &b.signingKey.PublicKey)
+	b.client = remote.Client()
+	inbox := ActorPathPrefix + addr + "/inbox"
+	follow := map[string]string{
+		"id":     remote.actorID() + "/follows/1",
+		"type":   "Follow",
+		"actor":  remote.actorID(),
+		"object": b.actorURL(addr),
+	}
+
+	if code := remote.post(t, b, inbox, follow); code != http.StatusAccepted {
+		t.Fatalf("Follow status = %d, want 202", code)
+	}
+	if got := b.Followers(addr); len(got) != 1 || got[0] != remote.actorID() {
+		t.Fatalf("Followers() = %v, want [%s]", got, remote.actorID())
+	}
+	remote.mu.Lock()
+	delivered := remote.delivered
+	remote.mu.Unlock()
+	if len(delivered) != 1 || delivered[0].Type != "Accept" || delivered[0].Actor != b.actorURL(addr) {
+		t.Fatalf("delivered = %+v, want one Accept from the local actor", delivered)
+	}
+
+	undo := map[string]interface{}{
+		"id":     remote.actorID() + "/undo/1",
+		"type":   "Undo",
+		"actor":  remote.actorID(),
+		"object": follow,
+	}
+	if code := remote.post(t, b, inbox, undo); code != http.StatusAccepted {
+		t.Fatalf("Undo status = %d, want 202", code)
+	}
+	if got := b.Followers(addr); len(got) != 0 {
+		t.Errorf("Followers() after Undo = %v, want none", got)
+	}
+}
+
+func TestBridge_InboxRejectsForgedActor(t *testing.T) {
+	b, addr := newTestBridge(t)
+	b.signingKey = testRSAKey(t)
+	remote := newRemoteServer(t, &b.signingKey.PublicKey)
+	b.client = remote.Client()
+
+	// Signed by bob but claiming to come from another actor.
+	follow := map[string]string{
+		"type":   "Follow",
+		"actor":  remote.URL + "/users/mallory",
+		"object": b.actorURL(addr),
+	}
+	if code := remote.post(t, b, ActorPathPrefix+addr+"/inbox", follow); code != http.StatusUnauthorized {
+		t.Errorf("forged Follow status = %d, want 401", code)
+	}
+
+	// Unsigned requests are rejected outright.
+	body, _ := json.Marshal(follow)
+	rec := httptest.NewRecorder()
+	b.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, ActorPathPrefix+addr+"/inbox", bytes.NewReader(body)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("unsigned Follow status = %d, want 401", rec.Code)
+	}
+	if got := b.Followers(addr); len(got) != 0 {
+		t.Errorf("Followers() = %v, want none", got)
+	}
+}
+
+func TestBridge_InboxFetchesOnlyHTTPSActorsFromTheKeyHost(t *testing.T) {
+	b, addr := newTestBridge(t)
+	b.signingKey = testRSAKey(t)
+	remote := newRemoteServer(t, &b.signingKey.PublicKey)
+	b.client = remote.Client()
+	plainActor := strings.Replace(remote.actorID(), "https://", "http://", 1)
+
+	for _, tc := range []struct{ name, actor, keyID string }{
+		{"http actor", plainActor, plainActor + "#main-key"},
+		{"key on another host", remote.actorID(), "https://elsewhere.example/users/bob#main-key"},
+	} {
+		body, _ := json.Marshal(map[string]string{"type": "Follow", "actor": tc.actor, "object": b.actorURL(addr)})
+		req := httptest.NewRequest(http.MethodPost, ActorPathPrefix+addr+"/inbox", bytes.NewReader(body))
+		if err := signRequest(req, remote.key, tc.keyID, body, time.Now()); err != nil {
+			t.Fatalf("signRequest() error = %v", err)
+		}
+		rec := httptest.NewRecorder()
+		b.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: Follow status = %d, want 401", tc.name, rec.Code)
+		}
+	}
+	if n := remote.actorFetches.Load(); n != 0 {
+		t.Errorf("remote actor was fetched %d times, want 0", n)
+	}
+}
+
+func TestBridge_InboxDisabledWithoutKey(t *testing.T) {
+	b, addr := newTestBridge(t)
+	rec := httptest.NewRecorder()
+	b.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, ActorPathPrefix+addr+"/inbox", bytes.NewReader([]byte(`{}`))))
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("inbox without signing key status = %d, want 501", rec.Code)
+	}
+}
@@ -0,0 +1,213 @@
+// Package activitypub renders public Digisocialblock posts and profiles as
+// ActivityStreams objects and serves them over the actor, outbox and WebFinger
+// endpoints that Fediverse servers use to discover, follow and read accounts.
+//
+// The bridge is read-only with respect to the chain: the inbox only records
+// remote followers (answering each Follow with a signed Accept) and nothing a
+// remote server sends is ever written to the ledger. Group-only posts are
+// never exported.
+package activitypub
+
+import (
+	"crypto/x509"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/social"
+	"digisocialblock/core/user"
+	"encoding/pem"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+// ActivityStreams constants.
+const (
+	ContextActivityStreams = "https://www.w3.org/ns/activitystreams"
+	ContextSecurity        = "https://w3id.org/security/v1"
+	PublicAudience         = "https://www.w3.org/ns/activitystreams#Public"
+	ContentType            = "application/activity+json"
+)
+
+// Image is an ActivityStreams Image, used for avatars and headers.
+type Image struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// PublicKey is the actor key block remote servers use to verify the actor.
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPEM string `json:"publicKeyPem"`
+}
+
+// Actor is an ActivityStreams Person representing one wallet address.
+type Actor struct {
+	Context           []string   `json:"@context,omitempty"`
+	ID                string     `json:"id"`
+	Type              string     `json:"type"`
+	PreferredUsername string     `json:"preferredUsername"`
+	Name              string     `json:"name,omitempty"`
+	Summary           string     `json:"summary,omitempty"`
+	Inbox             string     `json:"inbox"`
+	Outbox            string     `json:"outbox"`
+	Icon              *Image     `json:"icon,omitempty"`
+	Image             *Image     `json:"image,omitempty"`
+	PublicKey         *PublicKey `json:"publicKey,omitempty"`
+}
+
+// Tag is an ActivityStreams Hashtag.
+type Tag struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// Note is an ActivityStreams Note representing one post.
+type Note struct {
+	Context      string   `json:"@context,omitempty"`
+	ID           string   `json:"id"`
+	Type         string   `json:"type"`
+	AttributedTo string   `json:"attributedTo"`
+	Name         string   `json:"name,omitempty"`
+	Content      string   `json:"content"`
+	Published    string   `json:"published"`
+	To           []string `json:"to"`
+	Tag          []Tag    `json:"tag,omitempty"`
+	URL          string   `json:"url,omitempty"` // Gateway URL of the raw content, when configured
+}
+
+// Activity is an ActivityStreams activity; the bridge only emits Create.
+type Activity struct {
+	Context   string      `json:"@context,omitempty"`
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	Actor     string      `json:"actor"`
+	Published string      `json:"published,omitempty"`
+	To        []string    `json:"to,omitempty"`
+	Object    interface{} `json:"object"`
+}
+
+// OrderedCollection is an ActivityStreams OrderedCollection, used for outboxes.
+type OrderedCollection struct {
+	Context      string        `json:"@context"`
+	ID           string        `json:"id"`
+	Type         string        `json:"type"`
+	TotalItems   int           `json:"totalItems"`
+	OrderedItems []interface{} `json:"orderedItems"`
+}
+
+// actorURL returns the ID of the actor for address.
+func (b *Bridge) actorURL(address string) string {
+	return b.baseURL + ActorPathPrefix + address
+}
+
+// noteURL returns the ID of the Note for the post created by txID.
+func (b *Bridge) noteURL(address, txID string) string {
+	return b.actorURL(address) + "/notes/" + txID
+}
+
+// ProfileToActor renders the actor for address. profile may be nil for an
+// address that has posts but has never published a profile.
+func (b *Bridge) ProfileToActor(address string, profile *user.Profile) (*Actor, error) {
+	id := b.actorURL(address)
+	actor := &Actor{
+		Context:           []string{ContextActivityStreams, ContextSecurity},
+		ID:                id,
+		Type:              "Person",
+		PreferredUsername: address,
+		Inbox:             id + "/inbox",
+		Outbox:            id + "/outbox",
+	}
+	if profile != nil {
+		actor.Name = profile.DisplayName
+		actor.Summary = textToHTML(profile.Bio)
+		if profile.ProfilePictureCID != "" && b.gatewayURL != "" {
+			actor.Icon = &Image{Type: "Image", URL: b.gatewayURL + profile.ProfilePictureCID}
+		}
+		if profile.HeaderImageCID != "" && b.gatewayURL != "" {
+			actor.Image = &Image{Type: "Image", URL: b.gatewayURL + profile.HeaderImageCID}
+		}
+	}
+	// Remote servers verify deliveries against the actor key, so when the
+	// bridge signs deliveries the actor presents the bridge key; otherwise it
+	// presents the wallet key for reference.
+	var keyPEM string
+	var err error
+	if b.signingKey != nil {
+		keyPEM, err = publicKeyPEM(&b.signingKey.PublicKey)
+	} else {
+		keyPEM, err = addressToPEM(address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	actor.PublicKey = &PublicKey{ID: id + "#main-key", Owner: id, PublicKeyPEM: keyPEM}
+	return actor, nil
+}
+
+// PostToNote renders a public post with its text content as a Note.
+func (b *Bridge) PostToNote(entry social.FeedEntry, text string) *Note {
+	note := &Note{
+		ID:           b.noteURL(entry.AuthorPublicKey, entry.TxID),
+		Type:         "Note",
+		AttributedTo: b.actorURL(entry.AuthorPublicKey),
+		Name:         entry.Title,
+		Content:      textToHTML(text),
+		Published:    formatTime(entry.Timestamp),
+		To:           []string{PublicAudience},
+	}
+	for _, tag := range entry.Tags {
+		note.Tag = append(note.Tag, Tag{Type: "Hashtag", Name: "#" + tag})
+	}
+	if b.gatewayURL != "" {
+		note.URL = b.gatewayURL + entry.ContentCID
+	}
+	return note
+}
+
+// createActivity wraps note in the Create activity that appears in an outbox.
+func createActivity(note *Note) *Activity {
+	return &Activity{
+		ID:        note.ID + "/activity",
+		Type:      "Create",
+		Actor:     note.AttributedTo,
+		Published: note.Published,
+		To:        note.To,
+		Object:    note,
+	}
+}
+
+// textToHTML escapes plain text for use as ActivityStreams content, keeping
+// paragraph and line breaks.
+func textToHTML(text string) string {
+	if text == "" {
+		return ""
+	}
+	paragraphs := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n")
+	for i, p := range paragraphs {
+		paragraphs[i] = "<p>" + strings.ReplaceAll(html.EscapeString(p), "\n", "<br>") + "</p>"
+	}
+	return strings.Join(paragraphs, "")
+}
+
+func formatTime(unixNano int64) string {
+	return time.Unix(0, unixNano).UTC().Format(time.RFC3339)
+}
+
+// addressToPEM returns the PKIX PEM encoding of the public key behind address.
+func addressToPEM(address string) (string, error) {
+	pub, err := identity.AddressToPublicKey(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid address %s: %w", address, err)
+	}
+	return publicKeyPEM(pub)
+}
+
+// publicKeyPEM returns the PKIX PEM encoding of pub.
+func publicKeyPEM(pub interface{}) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode public key: %w", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
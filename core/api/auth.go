@@ -0,0 +1,180 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Request authentication headers. A client signs
+//
+//	<METHOD>\n<path>\n<unix timestamp>\n<nonce>\n<hex SHA-256 of body>
+//
+// as an identity message (Wallet.SignMessage) for the node's chain ID. The
+// nonce is chosen at random for every request, and the node accepts each
+// address's nonce only once, so a captured request cannot be sent again.
+const (
+	HeaderAuthAddress   = "X-DSB-Address"
+	HeaderAuthTimestamp = "X-DSB-Timestamp"
+	HeaderAuthNonce     = "X-DSB-Nonce"     // 1 to MaxAuthNonceLength characters
+	HeaderAuthSignature = "X-DSB-Signature" // Base64 (standard encoding)
+)
+
+// DefaultMaxClockSkew bounds how far a signed request's timestamp may be from
+// the node's clock.
+const DefaultMaxClockSkew = 5 * time.Minute
+
+// MaxAuthNonceLength bounds the length of a request nonce.
+const MaxAuthNonceLength = 64
+
+// requestSigningMessage builds the message covered by a request signature.
+func requestSigningMessage(method, path string, timestamp int64, nonce string, body []byte) []byte {
+	sum := sha256.Sum256(body)
+	return []byte(fmt.Sprintf("%s\n%s\n%d\n%s\n%s", method, path, timestamp, nonce, hex.EncodeToString(sum[:])))
+}
+
+// SignRequest adds authentication headers to req for body, which must be the
+// exact request body. It is the client-side counterpart of the server's check.
+func SignRequest(req *http.Request, wallet *identity.Wallet, chainID string, body []byte) error {
+	if wallet == nil {
+		return fmt.Errorf("wallet cannot be nil")
+	}
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return fmt.Errorf("failed to generate request nonce: %w", err)
+	}
+	ts := time.Now().Unix()
+	nonceHex := hex.EncodeToString(nonce[:])
+	sig, err := wallet.SignMessage(chainID, requestSigningMessage(req.Method, req.URL.Path, ts, nonceHex, body))
+	if err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+	req.Header.Set(HeaderAuthAddress, wallet.Address)
+	req.Header.Set(HeaderAuthTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderAuthNonce, nonceHex)
+	req.Header.Set(HeaderAuthSignature, base64.StdEncoding.EncodeToString(sig))
+	return nil
+}
+
+// authenticate verifies a request's signature headers and returns the address
+// the request acts for, or "" if the request carries no authentication
+// headers. That is the signer's address or, for a request carrying a
+// capability token, the token's issuer; the token is returned too so the
+// caller can check its scopes.
+func (s *Server) authenticate(r *http.Request, body []byte) (string, *CapabilityToken, error) {
+	address := r.Header.Get(HeaderAuthAddress)
+	if address == "" {
+		return "", nil, nil
+	}
+	var token *CapabilityToken
+	if encoded := r.Header.Get(HeaderCapability); encoded != "" {
+		var err error
+		if token, err = s.verifyCapability(encoded, address); err != nil {
+			return "", nil, err
+		}
+	}
+	ts, err := strconv.ParseInt(r.Header.Get(HeaderAuthTimestamp), 10, 64)
+	if err != nil {
+		return "", nil, fmt.Errorf("missing or malformed %s header", HeaderAuthTimestamp)
+	}
+	skew := s.now().Sub(time.Unix(ts, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > s.opts.MaxClockSkew {
+		return "", nil, fmt.Errorf("request timestamp is %s from server time, limit %s", skew.Round(time.Second), s.opts.MaxClockSkew)
+	}
+	nonce := r.Header.Get(HeaderAuthNonce)
+	if nonce == "" || len(nonce) > MaxAuthNonceLength {
+		return "", nil, fmt.Errorf("missing or malformed %s header", HeaderAuthNonce)
+	}
+	sig, err := base64.StdEncoding.DecodeString(r.Header.Get(HeaderAuthSignature))
+	if err != nil || len(sig) == 0 {
+		return "", nil, fmt.Errorf("missing or malformed %s header", HeaderAuthSignature)
+	}
+	msg := requestSigningMessage(r.Method, r.URL.Path, ts, nonce, body)
+	if err := identity.VerifyMessage(address, s.opts.ChainID, msg, sig); err != nil {
+		return "", nil, fmt.Errorf("request signature is invalid: %w", err)
+	}
+	// Only verified requests are recorded, so nobody can use up another
+	// address's nonces.
+	if err := s.replays.add(address, nonce, time.Unix(ts, 0).Add(s.opts.MaxClockSkew), s.now()); err != nil {
+		return "", nil, err
+	}
+	if token != nil {
+		return token.Issuer, token, nil
+	}
+	return address, nil, nil
+}
+
+// verifyCapability decodes and verifies the capability token of a request
+// signed by signer, which must be the token's holder.
+func (s *Server) verifyCapability(encoded, signer string) (*CapabilityToken, error) {
+	token, err := DecodeCapabilityToken(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if !ledger.ConstantTimeEqual(signer, token.Holder) {
+		return nil, fmt.Errorf("request is not signed by the capability holder")
+	}
+	if err := token.Verify(s.opts.ChainID, s.now(), s.opts.MaxCapabilityTTL); err != nil {
+		return nil, err
+	}
+	if s.opts.Revocations != nil && s.opts.Revocations.IsRevoked(token.ID) {
+		return nil, fmt.Errorf("capability %s has been revoked", token.ID)
+	}
+	return token, nil
+}
+
+// replayCache remembers the nonces of the signed requests a Server accepted
+// while their timestamps are within the clock skew window. Entries are keyed
+// on the signer's address and nonce rather than on the signature, because an
+// ECDSA signature can be rewritten into a second valid one without the key.
+// It is safe for concurrent use.
+type replayCache struct {
+	mu      sync.Mutex
+	seen    map[string]time.Time // Address + "\n" + nonce -> when the timestamp leaves the window
+	maxKeys int                  // Expired entries are pruned once this many are tracked
+}
+
+func newReplayCache() *replayCache {
+	return &replayCache{seen: make(map[string]time.Time), maxKeys: 100000}
+}
+
+// add records the nonce of a request signed by address, which is replayable
+// until expires. It fails if the nonce was already used, or if the cache is
+// full of entries that have not expired.
+func (c *replayCache) add(address, nonce string, expires, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := address + "\n" + nonce
+	if prev, ok := c.seen[key]; ok && now.Before(prev) {
+		return fmt.Errorf("request nonce has already been used")
+	}
+	if len(c.seen) >= c.maxKeys {
+		c.pruneLocked(now)
+		if len(c.seen) >= c.maxKeys {
+			return fmt.Errorf("too many recent signed requests, try again later")
+		}
+	}
+	c.seen[key] = expires
+	return nil
+}
+
+// pruneLocked drops entries whose requests could no longer pass the clock
+// skew check.
+func (c *replayCache) pruneLocked(now time.Time) {
+	for key, expires := range c.seen {
+		if !now.Before(expires) {
+			delete(c.seen, key)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"crypto/rand"
+	"digisocialblock/core/identity"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HeaderCapability carries a CapabilityToken, as unpadded base64url JSON
+// (see CapabilityToken.Encode), on a request signed by the token's holder.
+const HeaderCapability = "X-DSB-Capability"
+
+// Capability scopes.
+const (
+	ScopeSubmitTransactions = "transactions:submit" // POST /v1/transactions, limited by CapabilityToken.TxTypes
+	ScopeDiagnostics        = "debug:read"          // The diagnostics endpoints, for node admins
+)
+
+// DefaultMaxCapabilityTTL bounds the lifetime of the capability tokens a
+// server accepts.
+const DefaultMaxCapabilityTTL = 24 * time.Hour
+
+// maxCapabilityHeaderSize bounds an encoded capability token.
+const maxCapabilityHeaderSize = 4 << 10
+
+// CapabilityToken is a grant, signed by a wallet (the issuer), that lets
+// another key (the holder) make API requests on the wallet's behalf. A web
+// client generates a session key, has the user's wallet mint a token for it
+// with MintCapabilityToken, and signs its requests with the session key and
+// SignRequestWithCapability. The server then treats each request as the
+// issuer's, within the token's scopes and lifetime; the wallet's key never
+// leaves the wallet. Tokens are revoked by ID (see ServerOptions.Revocations).
+type CapabilityToken struct {
+	ID         string   `json:"id"`     // Random; names the token for revocation
+	Issuer     string   `json:"issuer"` // Wallet the requests act for
+	Holder     string   `json:"holder"` // Key that signs the requests
+	Scopes     []string `json:"scopes"`
+	TxTypes    []string `json:"txTypes,omitempty"` // Transaction types ScopeSubmitTransactions covers; empty means any
+	NotBefore  int64    `json:"notBefore"`         // Unix seconds
+	Expires    int64    `json:"expires"`           // Unix seconds
+	Signature  []byte   `json:"signature,omitempty"`
+	SigVersion int      `json:"sigVersion,omitempty"`
+}
+
+// CapabilityGrant describes the token MintCapabilityToken signs.
+type CapabilityGrant struct {
+	Holder  string        // Address of the session key
+	Scopes  []string      // At least one
+	TxTypes []string      // Optional; see CapabilityToken.TxTypes
+	TTL     time.Duration // Positive; servers refuse more than their MaxCapabilityTTL
+}
+
+// MintCapabilityToken signs a token granting grant for issuer on chainID,
+// valid from now.
+func MintCapabilityToken(issuer *identity.Wallet, chainID string, grant CapabilityGrant) (*CapabilityToken, error) {
+	if issuer == nil {
+		return nil, fmt.Errorf("wallet cannot be nil")
+	}
+	if grant.Holder == "" || grant.Holder == issuer.Address {
+		return nil, fmt.Errorf("capability holder must be a key other than the issuer")
+	}
+	if len(grant.Scopes) == 0 {
+		return nil, fmt.Errorf("capability grants no scopes")
+	}
+	if grant.TTL <= 0 {
+		return nil, fmt.Errorf("capability TTL must be positive")
+	}
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate capability ID: %w", err)
+	}
+	now := time.Now()
+	t := &CapabilityToken{
+		ID:        hex.EncodeToString(id[:]),
+		Issuer:    issuer.Address,
+		Holder:    grant.Holder,
+		Scopes:    append([]string(nil), grant.Scopes...),
+		TxTypes:   append([]string(nil), grant.TxTypes...),
+		NotBefore: now.Unix(),
+		Expires:   now.Add(grant.TTL).Unix(),
+	}
+	data, err := t.signedBytes()
+	if err != nil {
+		return nil, err
+	}
+	if t.Signature, err = issuer.SignInDomain(identity.DomainCapability, chainID, data); err != nil {
+		return nil, fmt.Errorf("failed to sign capability: %w", err)
+	}
+	t.SigVersion = identity.CurrentSignatureVersion
+	return t, nil
+}
+
+// signedBytes returns the bytes the issuer's signature covers: the compact
+// JSON encoding of the token without its signature fields.
+func (t *CapabilityToken) signedBytes() ([]byte, error) {
+	unsigned := *t
+	unsigned.Signature = nil
+	unsigned.SigVersion = 0
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal capability for signing: %w", err)
+	}
+	return data, nil
+}
+
+// Verify checks the issuer's signature for chainID and that the token is
+// within its lifetime at now, which may be at most maxTTL.
+func (t *CapabilityToken) Verify(chainID string, now time.Time, maxTTL time.Duration) error {
+	if t.ID == "" || t.Issuer == "" || t.Holder == "" {
+		return fmt.Errorf("capability is missing its ID, issuer or holder")
+	}
+	if t.Expires-t.NotBefore > int64(maxTTL/time.Second) {
+		return fmt.Errorf("capability lifetime exceeds %s", maxTTL)
+	}
+	if now.Unix() < t.NotBefore || now.Unix() >= t.Expires {
+		return fmt.Errorf("capability is not valid at %s", now.UTC().Format(time.RFC3339))
+	}
+	publicKey, err := identity.AddressToPublicKey(t.Issuer)
+	if err != nil {
+		return fmt.Errorf("failed to parse capability issuer: %w", err)
+	}
+	data, err := t.signedBytes()
+	if err != nil {
+		return err
+	}
+	if err := identity.VerifyInDomain(publicKey, identity.DomainCapability, t.SigVersion, chainID, data, t.Signature); err != nil {
+		return fmt.Errorf("capability signature is invalid: %w", err)
+	}
+	return nil
+}
+
+// Allows reports whether the token grants scope.
+func (t *CapabilityToken) Allows(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsTransaction reports whether the token lets its holder submit
+// transactions of txType.
+func (t *CapabilityToken) AllowsTransaction(txType string) bool {
+	if !t.Allows(ScopeSubmitTransactions) {
+		return false
+	}
+	if len(t.TxTypes) == 0 {
+		return true
+	}
+	for _, allowed := range t.TxTypes {
+		if allowed == txType {
+			return true
+		}
+	}
+	return false
+}
+
+// Encode returns the token in the form HeaderCapability carries.
+func (t *CapabilityToken) Encode() (string, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode capability: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCapabilityToken parses a token encoded by Encode. It does not verify
+// it.
+func DecodeCapabilityToken(s string) (*CapabilityToken, error) {
+	if len(s) > maxCapabilityHeaderSize {
+		return nil, fmt.Errorf("capability exceeds %d bytes", maxCapabilityHeaderSize)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("malformed capability: %w", err)
+	}
+	var t CapabilityToken
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("malformed capability: %w", err)
+	}
+	return &t, nil
+}
+
+// SignRequestWithCapability signs req with holder, the key token was granted
+// to, and attaches token, so the request acts for the token's issuer (see
+// SignRequest).
+func SignRequestWithCapability(req *http.Request, holder *identity.Wallet, chainID string, body []byte, token *CapabilityToken) error {
+	if token == nil {
+		return fmt.Errorf("capability cannot be nil")
+	}
+	if holder == nil || holder.Address != token.Holder {
+		return fmt.Errorf("request must be signed by the capability holder %s", token.Holder)
+	}
+	encoded, err := token.Encode()
+	if err != nil {
+		return err
+	}
+	if err := SignRequest(req, holder, chainID, body); err != nil {
+		return err
+	}
+	req.Header.Set(HeaderCapability, encoded)
+	return nil
+}
+
+// CapabilityRevocations reports capability tokens revoked before they
+// expired. RevocationList implements it.
+type CapabilityRevocations interface {
+	IsRevoked(tokenID string) bool
+}
+
+// RevocationList is an in-memory set of revoked capability token IDs. Each
+// is forgotten once the token it names would have expired anyway. A
+// RevocationList is safe for concurrent use.
+type RevocationList struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time // Token ID -> expiry
+}
+
+// NewRevocationList creates an empty RevocationList.
+func NewRevocationList() *RevocationList {
+	return &RevocationList{revoked: make(map[string]time.Time)}
+}
+
+// Revoke revokes token.
+func (l *RevocationList) Revoke(token *CapabilityToken) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	for id, expires := range l.revoked {
+		if !now.Before(expires) {
+			delete(l.revoked, id)
+		}
+	}
+	l.revoked[token.ID] = time.Unix(token.Expires, 0)
+}
+
+// IsRevoked reports whether the token with tokenID has been revoked.
+func (l *RevocationList) IsRevoked(tokenID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.revoked[tokenID]
+	return ok
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"digisocialblock/core/identity"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// capabilityTestMint mints a token from issuer to holder for "test-chain".
+func capabilityTestMint(t *testing.T, issuer, holder *identity.Wallet, ttl time.Duration, scopes []string, txTypes ...string) *CapabilityToken {
+	t.Helper()
+	token, err := MintCapabilityToken(issuer, "test-chain", CapabilityGrant{Holder: holder.Address, Scopes: scopes, TxTypes: txTypes, TTL: ttl})
+	if err != nil {
+		t.Fatalf("MintCapabilityToken() error = %v", err)
+	}
+	return token
+}
+
+func TestServer_CapabilityTokens(t *testing.T) {
+	user, _ := identity.NewWallet()
+	defer user.Close()
+	session, _ := identity.NewWallet()
+	defer session.Close()
+	revocations := NewRevocationList()
+	s, _ := NewServer(&recordingSubmitter{}, ServerOptions{ChainID: "test-chain", RequireAuth: true, MaxCapabilityTTL: 2 * time.Hour, Revocations: revocations})
+
+	body := signedTxBody(t, user) // A Like by the user
+	withToken := func(token *CapabilityToken) func(*http.Request) {
+		return func(r *http.Request) {
+			if err := SignRequestWithCapability(r, session, "test-chain", body, token); err != nil {
+				t.Fatalf("SignRequestWithCapability() error = %v", err)
+			}
+		}
+	}
+	likes := capabilityTestMint(t, user, session, time.Hour, []string{ScopeSubmitTransactions}, "Like")
+	if rec, _ := submit(s, body, withToken(likes)); rec.Code != http.StatusAccepted {
+		t.Fatalf("request with a capability = %d (%s), want 202", rec.Code, rec.Body.String())
+	}
+
+	for name, tt := range map[string]struct {
+		token *CapabilityToken
+		want  int
+	}{
+		"other type":  {capabilityTestMint(t, user, session, time.Hour, []string{ScopeSubmitTransactions}, "PostCreated"), http.StatusForbidden},
+		"other scope": {capabilityTestMint(t, user, session, time.Hour, []string{ScopeDiagnostics}), http.StatusForbidden},
+		"too long":    {capabilityTestMint(t, user, session, 3*time.Hour, []string{ScopeSubmitTransactions}), http.StatusUnauthorized},
+	} {
+		if rec, apiErr := submit(s, body, withToken(tt.token)); rec.Code != tt.want {
+			t.Errorf("%s: request = %d %+v, want %d", name, rec.Code, apiErr, tt.want)
+		}
+	}
+
+	// A request acts for the token's issuer, which must be the sender.
+	reversed := capabilityTestMint(t, session, user, time.Hour, []string{ScopeSubmitTransactions})
+	asSession := func(r *http.Request) {
+		if err := SignRequestWithCapability(r, user, "test-chain", body, reversed); err != nil {
+			t.Fatalf("SignRequestWithCapability() error = %v", err)
+		}
+	}
+	if rec, _ := submit(s, body, asSession); rec.Code != http.StatusForbidden {
+		t.Errorf("token issued by a non-sender = %d, want 403", rec.Code)
+	}
+
+	// A token presented by a key other than its holder is refused.
+	forged := func(r *http.Request) {
+		SignRequest(r, user, "test-chain", body)
+		encoded, _ := likes.Encode()
+		r.Header.Set(HeaderCapability, encoded)
+	}
+	if rec, _ := submit(s, body, forged); rec.Code != http.StatusUnauthorized {
+		t.Errorf("token presented by another key = %d, want 401", rec.Code)
+	}
+
+	s.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if rec, _ := submit(s, body, withToken(likes)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expired token = %d, want 401", rec.Code)
+	}
+	s.now = time.Now
+	revocations.Revoke(likes)
+	if rec, _ := submit(s, body, withToken(likes)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("revoked token = %d, want 401", rec.Code)
+	}
+}
+
+func TestDiagnostics_CapabilityNeedsScope(t *testing.T) {
+	admin, _ := identity.NewWallet()
+	defer admin.Close()
+	session, _ := identity.NewWallet()
+	defer session.Close()
+	s, _ := NewServer(&recordingSubmitter{}, ServerOptions{ChainID: "test-chain", Admins: []string{admin.Address}})
+
+	get := func(token *CapabilityToken) int {
+		req := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+		if err := SignRequestWithCapability(req, session, "test-chain", nil, token); err != nil {
+			t.Fatalf("SignRequestWithCapability() error = %v", err)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := get(capabilityTestMint(t, admin, session, time.Hour, []string{ScopeSubmitTransactions})); code != http.StatusForbidden {
+		t.Errorf("admin token without the diagnostics scope = %d, want 403", code)
+	}
+	if code := get(capabilityTestMint(t, admin, session, time.Hour, []string{ScopeDiagnostics})); code != http.StatusOK {
+		t.Errorf("admin token with the diagnostics scope = %d, want 200", code)
+	}
+}
@@ -0,0 +1,179 @@
+// Package client is a typed Go client for the node API. The request and
+// response types and the operation methods in client_gen.go are generated
+// from core/api/openapi.json by cmd/apigen; this file holds the transport
+// they share.
+package client
+
+//go:generate go run ../../../cmd/apigen -spec ../openapi.json -out client_gen.go
+
+import (
+	"bytes"
+	"context"
+	"digisocialblock/core/api"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxResponseSize bounds the response bodies the client reads.
+const maxResponseSize = 4 << 20
+
+// Error is returned for any non-2xx response. It carries the API error body
+// when the server sent one.
+type Error struct {
+	StatusCode int
+	APIError
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("api request failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("api request failed with status %d (%s): %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Client calls a node's API.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	wallet     *identity.Wallet // Signs requests when set
+	chainID    string
+	capability *api.CapabilityToken // Attached to signed requests when set
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient makes the client send requests with hc.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithSigner makes the client sign every request with wallet for chainID
+// (see api.SignRequest).
+func WithSigner(wallet *identity.Wallet, chainID string) Option {
+	return func(c *Client) { c.wallet, c.chainID = wallet, chainID }
+}
+
+// WithCapability makes the client act for token's issuer: it signs every
+// request with holder, the session key the token was granted to, and attaches
+// the token (see api.SignRequestWithCapability).
+func WithCapability(holder *identity.Wallet, chainID string, token *api.CapabilityToken) Option {
+	return func(c *Client) { c.wallet, c.chainID, c.capability = holder, chainID, token }
+}
+
+// New creates a Client for the node at baseURL, e.g. "http://localhost:8080".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid API base URL %q", baseURL)
+	}
+	c := &Client{baseURL: strings.TrimSuffix(baseURL, "/"), httpClient: &http.Client{Timeout: 30 * time.Second}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// do sends in (if non-nil) as a JSON body and decodes a 2xx JSON response into
+// out (if non-nil).
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.capability != nil {
+		if err := api.SignRequestWithCapability(req, c.wallet, c.chainID, body, c.capability); err != nil {
+			return err
+		}
+	} else if c.wallet != nil {
+		if err := api.SignRequest(req, c.wallet, c.chainID, body); err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		var errBody ErrorBody
+		if json.Unmarshal(data, &errBody) == nil {
+			apiErr.APIError = errBody.Error
+		}
+		return apiErr
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// FromLedger converts a ledger transaction to its API representation.
+func FromLedger(tx *ledger.Transaction) *Transaction {
+	var coSignatures []CoSignature
+	for _, cs := range tx.CoSignatures {
+		coSignatures = append(coSignatures, CoSignature{PublicKey: cs.PublicKey, Signature: cs.Signature})
+	}
+	return &Transaction{
+		ID:              tx.ID,
+		Timestamp:       tx.Timestamp,
+		SenderPublicKey: tx.SenderPublicKey,
+		Type:            string(tx.Type),
+		Payload:         tx.Payload,
+		Signature:       tx.Signature,
+		ChainID:         tx.ChainID,
+		CoSignatures:    coSignatures,
+		SigVersion:      int64(tx.SigVersion),
+		Stamp:           tx.Stamp,
+		Delegate:        tx.Delegate,
+	}
+}
+
+// ToLedger converts an API transaction, such as the one in a
+// TransactionProof, to a ledger transaction.
+func ToLedger(tx *Transaction) *ledger.Transaction {
+	var coSignatures []ledger.CoSignature
+	for _, cs := range tx.CoSignatures {
+		coSignatures = append(coSignatures, ledger.CoSignature{PublicKey: cs.PublicKey, Signature: cs.Signature})
+	}
+	return &ledger.Transaction{
+		ID:              tx.ID,
+		Timestamp:       tx.Timestamp,
+		SenderPublicKey: tx.SenderPublicKey,
+		Type:            ledger.TransactionType(tx.Type),
+		Payload:         tx.Payload,
+		Signature:       tx.Signature,
+		ChainID:         tx.ChainID,
+		CoSignatures:    coSignatures,
+		SigVersion:      int(tx.SigVersion),
+		Stamp:           tx.Stamp,
+		Delegate:        tx.Delegate,
+	}
+}
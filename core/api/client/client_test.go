@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"digisocialblock/core/api"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil/fixture"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type recordingSubmitter struct {
+	added []*ledger.Transaction
+}
+
+func (rs *recordingSubmitter) Add(tx *ledger.Transaction) error {
+	if err := tx.IsValid(); err != nil {
+		return err
+	}
+	rs.added = append(rs.added, tx)
+	return nil
+}
+
+func newTestNode(t *testing.T, opts api.ServerOptions) (*httptest.Server, *recordingSubmitter) {
+	t.Helper()
+	sub := &recordingSubmitter{}
+	opts.ValidateRequests = true
+	s, err := api.NewServer(sub, opts)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return srv, sub
+}
+
+func signedTx(t *testing.T, wallet *identity.Wallet) *ledger.Transaction {
+	t.Helper()
+	return fixture.RawTx(t, wallet, ledger.Like, []byte(`{"postCID":"p"}`))
+}
+
+func TestClient_SubmitTransaction(t *testing.T) {
+	wallet, _ := identity.NewWallet()
+	defer wallet.Close()
+	srv, sub := newTestNode(t, api.ServerOptions{ChainID: ledger.DefaultChainID, RequireAuth: true})
+
+	c, err := New(srv.URL, WithSigner(wallet, ledger.DefaultChainID))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tx := signedTx(t, wallet)
+	resp, err := c.SubmitTransaction(context.Background(), FromLedger(tx))
+	if err != nil {
+		t.Fatalf("SubmitTransaction() error = %v", err)
+	}
+	if resp.TxID != tx.ID || len(sub.added) != 1 || sub.added[0].ID != tx.ID {
+		t.Errorf("SubmitTransaction() = %+v, submitter got %d txs", resp, len(sub.added))
+	}
+
+	// Without a signer the node rejects the request, and the API error is surfaced.
+	unsigned, _ := New(srv.URL)
+	_, err = unsigned.SubmitTransaction(context.Background(), FromLedger(signedTx(t, wallet)))
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Code != api.CodeUnauthenticated {
+		t.Errorf("unsigned SubmitTransaction() error = %v, want 401 unauthenticated", err)
+	}
+}
+
+func TestClient_GetOpenAPI(t *testing.T) {
+	srv, _ := newTestNode(t, api.ServerOptions{})
+	c, _ := New(srv.URL)
+	doc, err := c.GetOpenAPI(context.Background())
+	if err != nil {
+		t.Fatalf("GetOpenAPI() error = %v", err)
+	}
+	var parsed api.OpenAPIDocument
+	if err := json.Unmarshal(doc, &parsed); err != nil || parsed.OpenAPI == "" {
+		t.Errorf("GetOpenAPI() returned an unparseable document: %v", err)
+	}
+}
+
+func TestNew_InvalidURL(t *testing.T) {
+	for _, u := range []string{"", "localhost:8080", "ftp://node"} {
+		if _, err := New(u); err == nil {
+			t.Errorf("New(%q): expected error, got nil", u)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"digisocialblock/core/ledger"
+	"expvar"
+	"fmt"
+	"net/http"
+	httppprof "net/http/pprof"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+)
+
+// Diagnostics endpoints, served only to ServerOptions.Admins. They are not
+// part of the public API and are absent from OpenAPISpec. A request must be
+// signed (see SignRequest) over an empty body.
+//
+//	GET /debug/vars              expvar: runtime memstats, cmdline and the node's
+//	                             ServerOptions.Diagnostics under "dsb"
+//	GET /debug/pprof/...         net/http/pprof profiles
+//	GET /debug/dump/goroutines   full stacks of every goroutine, as text
+//	GET /debug/dump/heap         heap profile taken after a GC, for go tool pprof
+const diagnosticsPrefix = "/debug/"
+
+// HitRateVar publishes the hits, misses and hit rate of a cache with a
+// Stats method such as ledger.SignatureCache.Stats.
+func HitRateVar(stats func() (hits, misses uint64)) expvar.Var {
+	return expvar.Func(func() interface{} {
+		hits, misses := stats()
+		rate := 0.0
+		if hits+misses > 0 {
+			rate = float64(hits) / float64(hits+misses)
+		}
+		return map[string]interface{}{"hits": hits, "misses": misses, "hitRate": rate}
+	})
+}
+
+// diagnosticsHandler serves the diagnostics endpoints; requireAdmin guards it.
+func (s *Server) diagnosticsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/vars", s.handleVars)
+	mux.HandleFunc("/debug/pprof/", httppprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", httppprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", httppprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", httppprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", httppprof.Trace)
+	mux.HandleFunc("/debug/dump/", s.handleDump)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, CodeNotFound, "no such endpoint", 0)
+	})
+	return mux
+}
+
+// requireAdmin passes only signed requests from one of ServerOptions.Admins
+// to next.
+func (s *Server) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		address := r.Header.Get(HeaderAuthAddress)
+		if address == "" {
+			writeError(w, http.StatusUnauthorized, CodeUnauthenticated, "diagnostics require a signed request", 0)
+			return
+		}
+		if !s.allow(w, s.clientLimiter, "addr:"+address, "client") {
+			return
+		}
+		actor, token, err := s.authenticate(r, nil)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, CodeUnauthenticated, err.Error(), 0)
+			return
+		}
+		admin := false
+		for _, a := range s.opts.Admins {
+			if ledger.ConstantTimeEqual(actor, a) {
+				admin = true
+			}
+		}
+		if !admin || (token != nil && !token.Allows(ScopeDiagnostics)) {
+			writeError(w, http.StatusForbidden, CodeForbidden, "diagnostics are restricted to node admins", 0)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleVars writes the published expvar variables, as expvar.Handler does,
+// followed by "dsb": the server's Diagnostics and its goroutine count.
+func (s *Server) handleVars(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	fmt.Fprintf(w, "{\n")
+	expvar.Do(func(kv expvar.KeyValue) {
+		fmt.Fprintf(w, "%q: %s,\n", kv.Key, kv.Value)
+	})
+	dsb := new(expvar.Map)
+	dsb.Set("goroutines", expvar.Func(func() interface{} { return runtime.NumGoroutine() }))
+	for name, v := range s.opts.Diagnostics {
+		dsb.Set(name, v)
+	}
+	fmt.Fprintf(w, "%q: %s\n}\n", "dsb", dsb)
+}
+
+// handleDump writes a goroutine or heap dump as a download named after the
+// node's clock, so dumps taken during one incident sort in order.
+func (s *Server) handleDump(w http.ResponseWriter, r *http.Request) {
+	var profile, filename, contentType string
+	debug := 0
+	stamp := s.now().UTC().Format("20060102T150405Z")
+	switch strings.TrimPrefix(r.URL.Path, "/debug/dump/") {
+	case "goroutines":
+		profile, debug = "goroutine", 2
+		filename, contentType = "goroutines-"+stamp+".txt", "text/plain; charset=utf-8"
+	case "heap":
+		runtime.GC() // Report live objects rather than garbage since the last cycle.
+		profile = "heap"
+		filename, contentType = "heap-"+stamp+".pb.gz", "application/octet-stream"
+	default:
+		writeError(w, http.StatusNotFound, CodeNotFound, "dump must be goroutines or heap", 0)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if err := pprof.Lookup(profile).WriteTo(w, debug); err != nil {
+		// Headers are already out; all that is left is to cut the response short.
+		panic(http.ErrAbortHandler)
+	}
+}
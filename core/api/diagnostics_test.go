@@ -0,0 +1,99 @@
+package api
+
+import (
+	"digisocialblock/core/identity"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// diagnosticsGet requests path from s, signed by wallet unless it is nil.
+func diagnosticsGet(t *testing.T, s *Server, path string, wallet *identity.Wallet) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if wallet != nil {
+		if err := SignRequest(req, wallet, "test-chain", nil); err != nil {
+			t.Fatalf("SignRequest() error = %v", err)
+		}
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDiagnostics_RestrictedToAdmins(t *testing.T) {
+	admin, _ := identity.NewWallet()
+	defer admin.Close()
+	other, _ := identity.NewWallet()
+	defer other.Close()
+	s, err := NewServer(&recordingSubmitter{}, ServerOptions{ChainID: "test-chain", Admins: []string{admin.Address}, ValidateRequests: true})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	if rec := diagnosticsGet(t, s, "/debug/vars", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("unsigned request = %d, want 401", rec.Code)
+	}
+	if rec := diagnosticsGet(t, s, "/debug/pprof/", other); rec.Code != http.StatusForbidden {
+		t.Errorf("non-admin request = %d, want 403", rec.Code)
+	}
+	if rec := diagnosticsGet(t, s, "/debug/pprof/", admin); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine") {
+		t.Errorf("admin pprof index = %d:\n%s", rec.Code, rec.Body.String())
+	}
+	// Validation still applies to the public API.
+	if rec := diagnosticsGet(t, s, "/v1/nothing", admin); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown API path = %d, want 404", rec.Code)
+	}
+
+	plain, _ := NewServer(&recordingSubmitter{}, ServerOptions{ChainID: "test-chain"})
+	if rec := diagnosticsGet(t, plain, "/debug/vars", admin); rec.Code != http.StatusNotFound {
+		t.Errorf("diagnostics without admins = %d, want 404", rec.Code)
+	}
+}
+
+func TestDiagnostics_VarsAndDumps(t *testing.T) {
+	admin, _ := identity.NewWallet()
+	defer admin.Close()
+	size := new(expvar.Int)
+	size.Set(7)
+	s, _ := NewServer(&recordingSubmitter{}, ServerOptions{
+		ChainID: "test-chain",
+		Admins:  []string{admin.Address},
+		Diagnostics: map[string]expvar.Var{
+			"mempool.size": size,
+			"sigcache":     HitRateVar(func() (uint64, uint64) { return 3, 1 }),
+		},
+	})
+
+	rec := diagnosticsGet(t, s, "/debug/vars", admin)
+	var vars struct {
+		Memstats json.RawMessage `json:"memstats"`
+		DSB      struct {
+			Goroutines  int `json:"goroutines"`
+			MempoolSize int `json:"mempool.size"`
+			Sigcache    struct {
+				HitRate float64 `json:"hitRate"`
+			} `json:"sigcache"`
+		} `json:"dsb"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil {
+		t.Fatalf("/debug/vars is not JSON: %v\n%s", err, rec.Body.String())
+	}
+	if len(vars.Memstats) == 0 || vars.DSB.Goroutines == 0 || vars.DSB.MempoolSize != 7 || vars.DSB.Sigcache.HitRate != 0.75 {
+		t.Errorf("/debug/vars = %+v", vars)
+	}
+
+	rec = diagnosticsGet(t, s, "/debug/dump/goroutines", admin)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine ") || !strings.Contains(rec.Header().Get("Content-Disposition"), "goroutines-") {
+		t.Errorf("goroutine dump = %d %q", rec.Code, rec.Header().Get("Content-Disposition"))
+	}
+	if rec := diagnosticsGet(t, s, "/debug/dump/heap", admin); rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+		t.Errorf("heap dump = %d with %d bytes", rec.Code, rec.Body.Len())
+	}
+	if rec := diagnosticsGet(t, s, "/debug/dump/threads", admin); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown dump = %d, want 404", rec.Code)
+	}
+}
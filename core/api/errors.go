@@ -0,0 +1,90 @@
+package api
+
+import (
+	"digisocialblock/core/errcode"
+	"digisocialblock/core/ledger"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Error codes returned in APIError.Code. Clients should branch on the code,
+// not on the message text.
+const (
+	CodeBadRequest         = "bad_request"
+	CodeUnauthenticated    = "unauthenticated"
+	CodeForbidden          = "forbidden"
+	CodeRateLimited        = "rate_limited"
+	CodeInvalidTransaction = "invalid_transaction"
+	CodeMethodNotAllowed   = "method_not_allowed"
+	CodeNotFound           = "not_found"
+)
+
+// apiErrorCodes gives each API error code its registered code (see package
+// errcode), reported in APIError.ErrorCode when no more specific code applies.
+var apiErrorCodes = map[string]errcode.Code{
+	CodeBadRequest:         errcode.Register("DSB-API-001", http.StatusBadRequest, "request is malformed"),
+	CodeUnauthenticated:    errcode.Register("DSB-API-002", http.StatusUnauthorized, "request signature is missing or invalid"),
+	CodeForbidden:          errcode.Register("DSB-API-003", http.StatusForbidden, "authenticated address may not make this request"),
+	CodeRateLimited:        errcode.Register("DSB-API-004", http.StatusTooManyRequests, "rate limit exceeded"),
+	CodeInvalidTransaction: errcode.Register("DSB-API-005", http.StatusBadRequest, "transaction was rejected"),
+	CodeMethodNotAllowed:   errcode.Register("DSB-API-006", http.StatusMethodNotAllowed, "method not allowed"),
+	CodeNotFound:           errcode.Register("DSB-API-007", http.StatusNotFound, "no such endpoint"),
+}
+
+// APIError is the JSON error body returned by every endpoint:
+//
+//	{"error": {"code": "rate_limited", "errorCode": "DSB-API-004", "message": "...", "retryAfterSeconds": 3}}
+//
+// Code is the broad category. ErrorCode is the registered code of the
+// specific failure, e.g. DSB-LEDGER-001 for a transaction with an invalid
+// signature; errcode.Lookup describes it. TxID names the rejected
+// transaction, for errors with Code invalid_transaction.
+type APIError struct {
+	Code              string `json:"code"`
+	ErrorCode         string `json:"errorCode,omitempty"`
+	Message           string `json:"message"`
+	TxID              string `json:"txId,omitempty"`
+	RetryAfterSeconds int    `json:"retryAfterSeconds,omitempty"`
+}
+
+type errorBody struct {
+	Error APIError `json:"error"`
+}
+
+// writeError writes err with the given HTTP status. A positive retryAfter also
+// sets the Retry-After header, rounded up to whole seconds.
+func writeError(w http.ResponseWriter, status int, code, message string, retryAfter time.Duration) {
+	apiErr := APIError{Code: code, ErrorCode: string(apiErrorCodes[code]), Message: message}
+	if retryAfter > 0 {
+		secs := int((retryAfter + time.Second - 1) / time.Second)
+		apiErr.RetryAfterSeconds = secs
+		w.Header().Set("Retry-After", strconv.Itoa(secs))
+	}
+	writeJSON(w, status, errorBody{Error: apiErr})
+}
+
+// writeTransactionError answers a transaction the submitter rejected with
+// err. If err carries a registered code, that code and its HTTP status are
+// used; otherwise the status is 400. If err is a ledger.Rejection, the
+// rejected transaction's ID is reported with it.
+func writeTransactionError(w http.ResponseWriter, err error) {
+	status, errorCode := http.StatusBadRequest, apiErrorCodes[CodeInvalidTransaction]
+	if code, ok := errcode.Of(err); ok {
+		if info, ok := errcode.Lookup(code); ok {
+			status, errorCode = info.HTTPStatus, code
+		}
+	}
+	apiErr := APIError{Code: CodeInvalidTransaction, ErrorCode: string(errorCode), Message: err.Error()}
+	if r, ok := ledger.AsRejection(err); ok {
+		apiErr.TxID = r.TxID
+	}
+	writeJSON(w, status, errorBody{Error: apiErr})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"digisocialblock/core/clientkit"
+	"digisocialblock/core/identity"
+	"fmt"
+	"net/http"
+)
+
+// MaxIdenticonPNGSize bounds the PNG a GetIdenticon call renders.
+const MaxIdenticonPNGSize = 512
+
+// GetIdenticonRequest is the body of a GetIdenticon call.
+type GetIdenticonRequest struct {
+	Address string `json:"address"`
+	PNGSize int    `json:"pngSize,omitempty"` // If positive, a PNG this many pixels square is rendered too
+}
+
+// IdenticonResponse is the body of a successful GetIdenticon call: the
+// default avatar and accent color of an address (see clientkit.Identicon).
+type IdenticonResponse struct {
+	Address string `json:"address"`
+	Color   string `json:"color"`
+	SVG     string `json:"svg"`
+	PNG     []byte `json:"png,omitempty"`
+}
+
+// handleGetIdenticon is the GetIdenticon endpoint: POST /v1/identicons. It
+// serves clients that cannot derive identicons themselves; the result
+// depends on the address alone, so clients may cache it indefinitely.
+func (s *Server) handleGetIdenticon(w http.ResponseWriter, r *http.Request) {
+	var req GetIdenticonRequest
+	if !s.readChainRequest(w, r, &req) {
+		return
+	}
+	if _, err := identity.AddressToPublicKey(req.Address); err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("invalid address: %v", err), 0)
+		return
+	}
+	if req.PNGSize < 0 || req.PNGSize > MaxIdenticonPNGSize {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("pngSize must be between 0 and %d", MaxIdenticonPNGSize), 0)
+		return
+	}
+	icon := clientkit.NewIdenticon(req.Address)
+	resp := IdenticonResponse{Address: req.Address, Color: icon.Color, SVG: icon.SVG()}
+	if req.PNGSize > 0 {
+		resp.PNG, _ = icon.PNG(req.PNGSize) // Cannot fail for a size in range
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
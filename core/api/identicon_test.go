@@ -0,0 +1,34 @@
+package api
+
+import (
+	"digisocialblock/core/clientkit"
+	"digisocialblock/internal/testutil/fixture"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServer_GetIdenticon(t *testing.T) {
+	address := fixture.Wallet(t).Address
+	s, _ := NewServer(&recordingSubmitter{}, ServerOptions{ValidateRequests: true})
+
+	rec := proofsTestPost(s, "/v1/identicons", `{"address":"`+address+`","pngSize":40}`)
+	var resp IdenticonResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	want := clientkit.NewIdenticon(address)
+	if rec.Code != http.StatusOK || resp.Color != want.Color || resp.SVG != want.SVG() || len(resp.PNG) == 0 {
+		t.Fatalf("GetIdenticon = %d %s, want the address's identicon", rec.Code, rec.Body.String())
+	}
+
+	for _, body := range []string{`{"address":"not-an-address"}`, `{"address":"` + address + `","pngSize":9999}`} {
+		if rec := proofsTestPost(s, "/v1/identicons", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("GetIdenticon(%s) = %d, want 400", body, rec.Code)
+		}
+	}
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/identicons", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /v1/identicons = %d, want 405", rec.Code)
+	}
+}
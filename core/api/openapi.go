@@ -0,0 +1,253 @@
+package api
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// OpenAPISpec is the OpenAPI 3 document describing the API (openapi.json).
+// It is the source of truth for the generated client in core/api/client and
+// for ValidateRequests.
+//
+//go:embed openapi.json
+var OpenAPISpec []byte
+
+// OpenAPIDocument is the subset of an OpenAPI 3 document the node uses.
+type OpenAPIDocument struct {
+	OpenAPI    string              `json:"openapi"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components OpenAPIComponents   `json:"components"`
+}
+
+// PathItem maps lower-case HTTP methods to operations.
+type PathItem map[string]*Operation
+
+// Operation is one API operation.
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Parameters  []Parameter          `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter is an operation parameter.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody describes an operation's request body.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes an operation response, or references a shared one.
+type Response struct {
+	Ref         string               `json:"$ref"`
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+// MediaType holds the schema of one content type.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// OpenAPIComponents holds the reusable schemas and responses.
+type OpenAPIComponents struct {
+	Schemas   map[string]*Schema   `json:"schemas"`
+	Responses map[string]*Response `json:"responses"`
+}
+
+// Schema is the subset of JSON Schema the API uses.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Description          string             `json:"description"`
+	Nullable             bool               `json:"nullable"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Minimum              *float64           `json:"minimum"`
+}
+
+// LoadOpenAPI parses OpenAPISpec.
+func LoadOpenAPI() (*OpenAPIDocument, error) {
+	var doc OpenAPIDocument
+	if err := json.Unmarshal(OpenAPISpec, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	return &doc, nil
+}
+
+// ResolveSchema follows a local "#/components/schemas/<name>" reference.
+func (d *OpenAPIDocument) ResolveSchema(s *Schema) (*Schema, error) {
+	if s == nil || s.Ref == "" {
+		return s, nil
+	}
+	name := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+	target, ok := d.Components.Schemas[name]
+	if name == s.Ref || !ok {
+		return nil, fmt.Errorf("unresolvable schema reference %q", s.Ref)
+	}
+	return target, nil
+}
+
+// SchemaName returns the component name a schema reference points to.
+func SchemaName(s *Schema) string {
+	if s == nil {
+		return ""
+	}
+	return strings.TrimPrefix(s.Ref, "#/components/schemas/")
+}
+
+// ValidateRequests returns middleware that rejects requests the document does
+// not allow: unknown paths (404) and methods (405), and JSON request bodies
+// that do not match the operation's schema (400). Valid requests reach next
+// with their body intact. Bodies are read in full before next runs, so mount
+// it behind any limits that must apply before the body is read.
+func (d *OpenAPIDocument) ValidateRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		item, ok := d.Paths[r.URL.Path]
+		if !ok {
+			writeError(w, http.StatusNotFound, CodeNotFound, "no such endpoint", 0)
+			return
+		}
+		op, ok := item[strings.ToLower(r.Method)]
+		if !ok {
+			allowed := make([]string, 0, len(item))
+			for method := range item {
+				allowed = append(allowed, strings.ToUpper(method))
+			}
+			sort.Strings(allowed)
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "use "+strings.Join(allowed, " or "), 0)
+			return
+		}
+		if op.RequestBody == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		media, ok := op.RequestBody.Content["application/json"]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "application/json") {
+			writeError(w, http.StatusUnsupportedMediaType, CodeBadRequest, "request body must be application/json", 0)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSubmitBodySize))
+		if err != nil {
+			writeError(w, http.StatusRequestEntityTooLarge, CodeBadRequest, "request body too large", 0)
+			return
+		}
+		var value interface{}
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&value); err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("request body is not valid JSON: %v", err), 0)
+			return
+		}
+		if err := d.validate(media.Schema, value, "body"); err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error(), 0)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validate checks value against schema; path names the value in errors.
+func (d *OpenAPIDocument) validate(schema *Schema, value interface{}, path string) error {
+	schema, err := d.ResolveSchema(schema)
+	if err != nil || schema == nil {
+		return err
+	}
+	if value == nil {
+		if schema.Nullable {
+			return nil
+		}
+		return fmt.Errorf("%s must not be null", path)
+	}
+	switch schema.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object", path)
+		}
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s.%s is required", path, name)
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names) // Deterministic error for bodies with several problems
+		for _, name := range names {
+			v := obj[name]
+			prop, ok := schema.Properties[name]
+			if !ok {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					return fmt.Errorf("%s.%s is not a known field", path, name)
+				}
+				continue
+			}
+			if err := d.validate(prop, v, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an array", path)
+		}
+		for i, v := range arr {
+			if err := d.validate(schema.Items, v, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", path)
+		}
+		if schema.Format == "byte" {
+			if _, err := base64.StdEncoding.DecodeString(s); err != nil {
+				return fmt.Errorf("%s must be base64", path)
+			}
+		}
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s must be an integer", path)
+		}
+		f, err := n.Float64()
+		if err != nil || strings.ContainsAny(n.String(), ".eE") {
+			return fmt.Errorf("%s must be an integer", path)
+		}
+		if schema.Minimum != nil && f < *schema.Minimum {
+			return fmt.Errorf("%s must be at least %v", path, *schema.Minimum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be a boolean", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"digisocialblock/core/identity"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoadOpenAPI_RefsResolve(t *testing.T) {
+	doc, err := LoadOpenAPI()
+	if err != nil {
+		t.Fatalf("LoadOpenAPI() error = %v", err)
+	}
+	var check func(where string, s *Schema)
+	check = func(where string, s *Schema) {
+		if s == nil {
+			return
+		}
+		if _, err := doc.ResolveSchema(s); err != nil {
+			t.Errorf("%s: %v", where, err)
+		}
+		for name, prop := range s.Properties {
+			check(where+"."+name, prop)
+		}
+		check(where+"[]", s.Items)
+	}
+	for name, s := range doc.Components.Schemas {
+		check(name, s)
+	}
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			if op.OperationID == "" {
+				t.Errorf("%s %s has no operationId", method, path)
+			}
+			if op.RequestBody != nil {
+				for ct, media := range op.RequestBody.Content {
+					check(path+" request "+ct, media.Schema)
+				}
+			}
+		}
+	}
+	// Every route the server handles is documented.
+	for _, path := range []string{"/v1/transactions", "/v1/openapi.json", "/v1/graphql", "/v1/headers", "/v1/proofs", "/v1/identicons"} {
+		if _, ok := doc.Paths[path]; !ok {
+			t.Errorf("path %s is not in the OpenAPI document", path)
+		}
+	}
+}
+
+func TestServer_ValidateRequests(t *testing.T) {
+	wallet, _ := identity.NewWallet()
+	defer wallet.Close()
+	sub := &recordingSubmitter{}
+	s, err := NewServer(sub, ServerOptions{ValidateRequests: true})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	if rec, apiErr := submit(s, signedTxBody(t, wallet), nil); rec.Code != http.StatusAccepted {
+		t.Fatalf("valid transaction: status = %d (%+v), want 202", rec.Code, apiErr)
+	}
+
+	valid := string(signedTxBody(t, wallet))
+	tests := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{"unknown field", strings.Replace(valid, `{`, `{"admin":true,`, 1), "body.admin is not a known field"},
+		{"missing field", strings.Replace(valid, `"type":`, `"kind":`, 1), "body.type is required"},
+		{"wrong type", strings.Replace(valid, `"timestamp":`, `"timestamp":"1",`+`"x":`, 1), "body.timestamp must be an integer"},
+		{"fractional integer", strings.Replace(valid, `"timestamp":`, `"timestamp":1.5,"x":`, 1), "body.timestamp must be an integer"},
+		{"not base64", strings.Replace(valid, `"signature":"`, `"signature":"!!`, 1), "body.signature must be base64"},
+		{"not an object", `[]`, "body must be an object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, apiErr := submit(s, []byte(tt.body), nil)
+			if rec.Code != http.StatusBadRequest || apiErr.Code != CodeBadRequest {
+				t.Fatalf("status = %d, code = %q; want 400 bad_request", rec.Code, apiErr.Code)
+			}
+			if !strings.Contains(apiErr.Message, tt.message) {
+				t.Errorf("message = %q, want it to contain %q", apiErr.Message, tt.message)
+			}
+		})
+	}
+	if len(sub.added) != 1 {
+		t.Errorf("submitter received %d transactions, want 1", len(sub.added))
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown path status = %d, want 404", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/v1/transactions", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "POST" {
+		t.Errorf("DELETE status = %d, Allow = %q; want 405 with Allow: POST", rec.Code, rec.Header().Get("Allow"))
+	}
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != string(OpenAPISpec) {
+		t.Errorf("GET /v1/openapi.json status = %d, want 200 with the document", rec.Code)
+	}
+}
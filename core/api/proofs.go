@@ -0,0 +1,109 @@
+package api
+
+import (
+	"digisocialblock/core/ledger"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// MaxHeadersPerRequest bounds the headers one GetHeaders call returns.
+const MaxHeadersPerRequest = 512
+
+// maxProofRequestSize bounds a GetHeaders or GetProof request body.
+const maxProofRequestSize = 4 << 10
+
+// ChainReader serves the block headers and inclusion proofs light clients
+// verify transactions with (see package lightclient); *ledger.Blockchain
+// implements it.
+type ChainReader interface {
+	Headers(from int64, limit int) []ledger.BlockHeader
+	ProveTransaction(txID string) (*ledger.Transaction, *ledger.MerkleProof, bool)
+}
+
+// GetHeadersRequest is the body of a GetHeaders call.
+type GetHeadersRequest struct {
+	From  int64 `json:"from"`
+	Limit int   `json:"limit,omitempty"` // Defaults to, and is capped at, MaxHeadersPerRequest
+}
+
+// HeadersResponse is the body of a successful GetHeaders call. Headers is
+// empty once From is past the chain's tip.
+type HeadersResponse struct {
+	Headers []ledger.BlockHeader `json:"headers"`
+}
+
+// GetProofRequest is the body of a GetProof call.
+type GetProofRequest struct {
+	TxID string `json:"txId"`
+}
+
+// TransactionProof is the body of a successful GetProof call: a transaction
+// and the proof that it is in a block.
+type TransactionProof struct {
+	Transaction *ledger.Transaction `json:"transaction"`
+	ledger.MerkleProof
+}
+
+// handleGetHeaders is the GetHeaders endpoint: POST /v1/headers.
+func (s *Server) handleGetHeaders(w http.ResponseWriter, r *http.Request) {
+	var req GetHeadersRequest
+	if !s.readChainRequest(w, r, &req) {
+		return
+	}
+	if req.From < 0 || req.Limit < 0 {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "from and limit cannot be negative", 0)
+		return
+	}
+	if req.Limit == 0 || req.Limit > MaxHeadersPerRequest {
+		req.Limit = MaxHeadersPerRequest
+	}
+	headers := s.opts.Chain.Headers(req.From, req.Limit)
+	if headers == nil {
+		headers = []ledger.BlockHeader{}
+	}
+	writeJSON(w, http.StatusOK, HeadersResponse{Headers: headers})
+}
+
+// handleGetProof is the GetProof endpoint: POST /v1/proofs.
+func (s *Server) handleGetProof(w http.ResponseWriter, r *http.Request) {
+	var req GetProofRequest
+	if !s.readChainRequest(w, r, &req) {
+		return
+	}
+	if req.TxID == "" {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "txId is required", 0)
+		return
+	}
+	tx, proof, ok := s.opts.Chain.ProveTransaction(req.TxID)
+	if !ok {
+		writeError(w, http.StatusNotFound, CodeNotFound, fmt.Sprintf("transaction %s is not in a block", req.TxID), 0)
+		return
+	}
+	writeJSON(w, http.StatusOK, TransactionProof{Transaction: tx, MerkleProof: *proof})
+}
+
+// readChainRequest checks the method and client limit of a chain read, or
+// another read-only call such as GetIdenticon, and decodes its body into v,
+// writing the error response if any check fails.
+func (s *Server) readChainRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "use POST", 0)
+		return false
+	}
+	if !s.allow(w, s.clientLimiter, "ip:"+clientIP(r), "client") {
+		return false
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxProofRequestSize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, CodeBadRequest, "request body too large", 0)
+		return false
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("invalid request JSON: %v", err), 0)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"bytes"
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil/fixture"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// proofsTestPost posts body to path on s.
+func proofsTestPost(s *Server, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader([]byte(body)))
+	req.RemoteAddr = "192.0.2.1:5000"
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServer_HeadersAndProofs(t *testing.T) {
+	wallet := fixture.Wallet(t)
+	tx := fixture.PostTx(t, wallet, "cid-post")
+	bc := fixture.Chain(t, []*ledger.Transaction{tx})
+	s, _ := NewServer(&recordingSubmitter{}, ServerOptions{Chain: bc, ValidateRequests: true})
+
+	rec := proofsTestPost(s, "/v1/headers", `{"from":1}`)
+	var headers HeadersResponse
+	json.Unmarshal(rec.Body.Bytes(), &headers)
+	if rec.Code != http.StatusOK || len(headers.Headers) != 1 || headers.Headers[0].Index != 1 {
+		t.Fatalf("GetHeaders = %d %s, want block 1's header", rec.Code, rec.Body.String())
+	}
+	if rec := proofsTestPost(s, "/v1/headers", `{"from":9}`); rec.Code != http.StatusOK || !bytes.Contains(rec.Body.Bytes(), []byte(`"headers":[]`)) {
+		t.Errorf("GetHeaders past the tip = %d %s, want no headers", rec.Code, rec.Body.String())
+	}
+
+	rec = proofsTestPost(s, "/v1/proofs", `{"txId":"`+tx.ID+`"}`)
+	var proof TransactionProof
+	json.Unmarshal(rec.Body.Bytes(), &proof)
+	if rec.Code != http.StatusOK || proof.Transaction == nil || proof.Transaction.ID != tx.ID {
+		t.Fatalf("GetProof = %d %s, want the transaction", rec.Code, rec.Body.String())
+	}
+	if err := proof.MerkleProof.Verify(&headers.Headers[0]); err != nil {
+		t.Errorf("proof Verify() error = %v", err)
+	}
+	if rec := proofsTestPost(s, "/v1/proofs", `{"txId":"unknown"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("GetProof of an unknown transaction = %d, want 404", rec.Code)
+	}
+
+	// Without a chain the endpoints are not served.
+	s, _ = NewServer(&recordingSubmitter{}, ServerOptions{})
+	if rec := proofsTestPost(s, "/v1/headers", `{"from":0}`); rec.Code != http.StatusNotFound {
+		t.Errorf("GetHeaders without a chain = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_BurstAndRefill(t *testing.T) {
+	rl, err := NewRateLimiter(2, 3) // 2/s, burst 3
+	if err != nil {
+		t.Fatalf("NewRateLimiter() error = %v", err)
+	}
+	now := time.Unix(1000, 0)
+	rl.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := rl.Allow("a"); !ok {
+			t.Fatalf("request %d within burst was refused", i)
+		}
+	}
+	ok, wait := rl.Allow("a")
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("Allow() after burst = %v, %v; want false, 500ms", ok, wait)
+	}
+	if ok, _ := rl.Allow("b"); !ok {
+		t.Error("keys must be limited independently")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := rl.Allow("a"); !ok {
+		t.Error("Allow() after refill interval was refused")
+	}
+	if ok, _ := rl.Allow("a"); ok {
+		t.Error("only one token should have been refilled")
+	}
+}
+
+func TestRateLimiter_PrunesIdleKeys(t *testing.T) {
+	rl, _ := NewRateLimiter(1, 1)
+	now := time.Unix(1000, 0)
+	rl.now = func() time.Time { return now }
+	rl.maxKeys = 2
+	rl.Allow("a")
+	rl.Allow("b")
+	now = now.Add(time.Minute)
+	rl.Allow("c")
+	if len(rl.buckets) != 1 {
+		t.Errorf("tracked %d keys after pruning, want 1", len(rl.buckets))
+	}
+}
+
+func TestNewRateLimiter_InvalidArgs(t *testing.T) {
+	if _, err := NewRateLimiter(0, 1); err == nil {
+		t.Error("NewRateLimiter(0, 1) should fail")
+	}
+	if _, err := NewRateLimiter(1, 0); err == nil {
+		t.Error("NewRateLimiter(1, 0) should fail")
+	}
+}
@@ -0,0 +1,289 @@
+// Package api exposes a node's services over HTTP.
+package api
+
+import (
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/telemetry"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer traces API requests; see package telemetry.
+var tracer = telemetry.Tracer("core/api")
+
+// maxSubmitBodySize bounds a SubmitTransaction request body. Payloads are
+// limited to a few KiB by the ledger, so this leaves ample room for the envelope.
+const maxSubmitBodySize = 256 << 10
+
+// TxSubmitter accepts transactions for inclusion in a block; *ledger.Mempool
+// implements it.
+type TxSubmitter interface {
+	Add(tx *ledger.Transaction) error
+}
+
+// ServerOptions configures a Server. Zero rates disable the corresponding limit.
+type ServerOptions struct {
+	ChainID string // Chain ID request signatures are bound to
+
+	// RequireAuth rejects unsigned requests. Signed requests are always
+	// verified, and must come from the transaction's sender.
+	RequireAuth  bool
+	MaxClockSkew time.Duration // Defaults to DefaultMaxClockSkew
+
+	// A signed request carrying a capability token (see HeaderCapability)
+	// acts for the token's issuer, within the token's scopes. Tokens living
+	// longer than MaxCapabilityTTL (default DefaultMaxCapabilityTTL) or listed
+	// in Revocations are refused.
+	MaxCapabilityTTL time.Duration
+	Revocations      CapabilityRevocations
+
+	// Per-client limits apply to the remote IP and, for signed requests, to
+	// the authenticated address as well. Per-address limits apply to the
+	// transaction sender once its signature has been verified.
+	ClientRate   float64 // Requests per second
+	ClientBurst  int
+	AddressRate  float64
+	AddressBurst int
+
+	// ValidateRequests checks every request against OpenAPISpec before it
+	// reaches a handler (see OpenAPIDocument.ValidateRequests).
+	ValidateRequests bool
+
+	// Chain, if set, serves POST /v1/headers and POST /v1/proofs for light
+	// clients (see proofs.go). Requests count against the client limit of
+	// the remote IP.
+	Chain ChainReader
+
+	// GraphQL, if set, serves POST /v1/graphql (see graphql.Handler).
+	// Queries count against the client limit of the remote IP.
+	GraphQL http.Handler
+
+	// Admins are the addresses allowed to use the diagnostics endpoints under
+	// /debug/ (see diagnostics.go). With none, they are not served.
+	Admins []string
+	// Diagnostics are subsystem internals published at /debug/vars, e.g.
+	// "mempool.size" or "sigcache" (see HitRateVar).
+	Diagnostics map[string]expvar.Var
+}
+
+// Server serves the node API.
+type Server struct {
+	submitter      TxSubmitter
+	opts           ServerOptions
+	clientLimiter  *RateLimiter // Nil when unlimited
+	addressLimiter *RateLimiter
+	handler        http.Handler
+	replays        *replayCache
+	now            func() time.Time
+}
+
+// NewServer creates a Server submitting accepted transactions to submitter.
+func NewServer(submitter TxSubmitter, opts ServerOptions) (*Server, error) {
+	if submitter == nil {
+		return nil, fmt.Errorf("transaction submitter cannot be nil")
+	}
+	if opts.MaxClockSkew <= 0 {
+		opts.MaxClockSkew = DefaultMaxClockSkew
+	}
+	if opts.MaxCapabilityTTL <= 0 {
+		opts.MaxCapabilityTTL = DefaultMaxCapabilityTTL
+	}
+	s := &Server{submitter: submitter, opts: opts, replays: newReplayCache(), now: time.Now}
+	var err error
+	if opts.ClientRate > 0 {
+		if s.clientLimiter, err = NewRateLimiter(opts.ClientRate, opts.ClientBurst); err != nil {
+			return nil, fmt.Errorf("invalid client rate limit: %w", err)
+		}
+	}
+	if opts.AddressRate > 0 {
+		if s.addressLimiter, err = NewRateLimiter(opts.AddressRate, opts.AddressBurst); err != nil {
+			return nil, fmt.Errorf("invalid address rate limit: %w", err)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/transactions", s.handleSubmitTransaction)
+	mux.HandleFunc("/v1/openapi.json", handleOpenAPI)
+	mux.HandleFunc("/v1/identicons", s.handleGetIdenticon)
+	if opts.Chain != nil {
+		mux.HandleFunc("/v1/headers", s.handleGetHeaders)
+		mux.HandleFunc("/v1/proofs", s.handleGetProof)
+	}
+	if opts.GraphQL != nil {
+		mux.HandleFunc("/v1/graphql", func(w http.ResponseWriter, r *http.Request) {
+			if s.allow(w, s.clientLimiter, "ip:"+clientIP(r), "client") {
+				opts.GraphQL.ServeHTTP(w, r)
+			}
+		})
+	}
+	s.handler = mux
+	if opts.ValidateRequests {
+		doc, err := LoadOpenAPI()
+		if err != nil {
+			return nil, err
+		}
+		s.handler = doc.ValidateRequests(mux)
+	}
+	if len(opts.Admins) > 0 {
+		// Diagnostics bypass request validation: they are not in the spec.
+		root := http.NewServeMux()
+		root.Handle("/", s.handler)
+		root.Handle(diagnosticsPrefix, s.requireAdmin(s.diagnosticsHandler()))
+		s.handler = root
+	}
+	return s, nil
+}
+
+// ServeHTTP implements http.Handler. Each request is an "api.Request" server
+// span, continuing the caller's trace if the request carries one; handlers
+// see the span in the request context.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := telemetry.ExtractHTTP(r.Context(), r.Header)
+	ctx, span := tracer.Start(ctx, "api.Request", trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("http.request.method", r.Method), attribute.String("url.path", r.URL.Path)))
+	defer span.End()
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	s.handler.ServeHTTP(rec, r.WithContext(ctx))
+	span.SetAttributes(attribute.Int("http.response.status_code", rec.status))
+	if rec.status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(rec.status))
+	}
+}
+
+// statusRecorder records the status code a handler writes.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// handleOpenAPI serves OpenAPISpec.
+func handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "use GET", 0)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(OpenAPISpec)
+}
+
+// SubmitTransactionResponse is the body of a successful SubmitTransaction call.
+type SubmitTransactionResponse struct {
+	TxID string `json:"txId"`
+}
+
+// handleSubmitTransaction is the SubmitTransaction endpoint:
+// POST /v1/transactions with a JSON ledger.Transaction body.
+// Checks run cheapest first, so floods are turned away before any
+// signature verification. Only the IP limit applies to unverified input;
+// the address and sender limits are keyed on identities already verified,
+// so nobody can spend another client's quota.
+func (s *Server) handleSubmitTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "use POST", 0)
+		return
+	}
+	// Every client is limited by IP before the body is even read.
+	address := r.Header.Get(HeaderAuthAddress)
+	if address == "" && s.opts.RequireAuth {
+		writeError(w, http.StatusUnauthorized, CodeUnauthenticated, "request must be signed", 0)
+		return
+	}
+	if !s.allow(w, s.clientLimiter, "ip:"+clientIP(r), "client") {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSubmitBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, CodeBadRequest, "request body too large", 0)
+		} else {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "failed to read request body", 0)
+		}
+		return
+	}
+	var actor string
+	var token *CapabilityToken
+	if address != "" {
+		if actor, token, err = s.authenticate(r, body); err != nil {
+			writeError(w, http.StatusUnauthorized, CodeUnauthenticated, err.Error(), 0)
+			return
+		}
+		if !s.allow(w, s.clientLimiter, "addr:"+actor, "client") {
+			return
+		}
+	}
+
+	var tx ledger.Transaction
+	if err := ledger.DecodePayloadJSON(body, &tx); err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("invalid transaction JSON: %v", err), 0)
+		return
+	}
+	if actor != "" && !ledger.ConstantTimeEqual(actor, tx.SenderPublicKey) {
+		writeError(w, http.StatusForbidden, CodeForbidden, "authenticated address is not the transaction sender", 0)
+		return
+	}
+	if token != nil && !token.AllowsTransaction(string(tx.Type)) {
+		writeError(w, http.StatusForbidden, CodeForbidden, fmt.Sprintf("capability does not cover submitting %s transactions", tx.Type), 0)
+		return
+	}
+	// An unsigned request's sender is only known once the transaction
+	// signature verifies; a signed request's sender is the actor.
+	if actor == "" && s.addressLimiter != nil {
+		valid, err := tx.VerifySignature()
+		if err == nil && !valid {
+			err = fmt.Errorf("%w: transaction %s", ledger.ErrInvalidSignature, tx.ID)
+		}
+		if err != nil {
+			writeTransactionError(w, err)
+			return
+		}
+	}
+	if !s.allow(w, s.addressLimiter, tx.SenderPublicKey, "sender") {
+		return
+	}
+
+	if err := s.submitter.Add(&tx); err != nil {
+		writeTransactionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, SubmitTransactionResponse{TxID: tx.ID})
+}
+
+// allow applies limiter to key, writing a 429 with Retry-After if the key is
+// over its limit. A nil limiter allows everything.
+func (s *Server) allow(w http.ResponseWriter, limiter *RateLimiter, key, scope string) bool {
+	if limiter == nil {
+		return true
+	}
+	ok, retryAfter := limiter.Allow(key)
+	if !ok {
+		writeError(w, http.StatusTooManyRequests, CodeRateLimited, fmt.Sprintf("%s rate limit exceeded", scope), retryAfter)
+	}
+	return ok
+}
+
+// clientIP returns the remote IP without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,350 @@
+package api
+
+import (
+	"bytes"
+	"digisocialblock/core/errcode"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil/fixture"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// testSpans records the spans ended by the package's tests. Tracers bind to
+// the first global provider, so TestMain installs it once and tracing tests
+// Reset it before they run.
+var testSpans = tracetest.NewInMemoryExporter()
+
+func TestMain(m *testing.M) {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(testSpans)))
+	os.Exit(m.Run())
+}
+
+type recordingSubmitter struct {
+	added []*ledger.Transaction
+	err   error
+}
+
+func (rs *recordingSubmitter) Add(tx *ledger.Transaction) error {
+	if rs.err != nil {
+		return rs.err
+	}
+	rs.added = append(rs.added, tx)
+	return nil
+}
+
+func signedTxBody(t *testing.T, wallet *identity.Wallet) []byte {
+	t.Helper()
+	body, _ := json.Marshal(fixture.RawTx(t, wallet, ledger.Like, []byte(`{"postCID":"p"}`)))
+	return body
+}
+
+func submit(s *Server, body []byte, sign func(*http.Request)) (*httptest.ResponseRecorder, APIError) {
+	req := httptest.NewRequest(http.MethodPost, "/v1/transactions", bytes.NewReader(body))
+	req.RemoteAddr = "192.0.2.1:5000"
+	if sign != nil {
+		sign(req)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	var eb errorBody
+	json.Unmarshal(rec.Body.Bytes(), &eb)
+	return rec, eb.Error
+}
+
+func TestServer_SubmitTransaction(t *testing.T) {
+	wallet, _ := identity.NewWallet()
+	defer wallet.Close()
+	sub := &recordingSubmitter{}
+	s, err := NewServer(sub, ServerOptions{})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	rec, _ := submit(s, signedTxBody(t, wallet), nil)
+	if rec.Code != http.StatusAccepted || len(sub.added) != 1 {
+		t.Fatalf("submit = %d (%s), want 202", rec.Code, rec.Body.String())
+	}
+	var resp SubmitTransactionResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.TxID != sub.added[0].ID {
+		t.Errorf("response txId = %q, want %q", resp.TxID, sub.added[0].ID)
+	}
+
+	if rec, apiErr := submit(s, []byte(`{"id":"x","bogus":1}`), nil); rec.Code != http.StatusBadRequest || apiErr.Code != CodeBadRequest {
+		t.Errorf("malformed body = %d %+v, want 400 bad_request", rec.Code, apiErr)
+	}
+	sub.err = errors.New("mempool is full")
+	if rec, apiErr := submit(s, signedTxBody(t, wallet), nil); rec.Code != http.StatusBadRequest || apiErr.Code != CodeInvalidTransaction || apiErr.ErrorCode != "DSB-API-005" {
+		t.Errorf("rejected tx = %d %+v, want 400 invalid_transaction DSB-API-005", rec.Code, apiErr)
+	}
+}
+
+func TestServer_RejectionsCarryErrorCodes(t *testing.T) {
+	wallet, _ := identity.NewWallet()
+	defer wallet.Close()
+	sub := &recordingSubmitter{}
+	s, err := NewServer(sub, ServerOptions{})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	tests := []struct {
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{fmt.Errorf("%w: transaction abc", ledger.ErrInvalidSignature), http.StatusBadRequest, "DSB-LEDGER-001"},
+		{fmt.Errorf("%w: transaction abc is already in the mempool", ledger.ErrDuplicateTransaction), http.StatusConflict, "DSB-LEDGER-008"},
+		{ledger.ErrInsufficientStamp, http.StatusForbidden, "DSB-LEDGER-007"},
+		{fmt.Errorf("invalid post payload: %w", ledger.ErrPayloadTooLarge), http.StatusRequestEntityTooLarge, "DSB-LEDGER-004"},
+	}
+	for _, tt := range tests {
+		sub.err = tt.err
+		rec, apiErr := submit(s, signedTxBody(t, wallet), nil)
+		if rec.Code != tt.wantStatus || apiErr.ErrorCode != tt.wantCode || apiErr.Code != CodeInvalidTransaction {
+			t.Errorf("submit rejected with %q = %d %+v, want %d %s", tt.err, rec.Code, apiErr, tt.wantStatus, tt.wantCode)
+		}
+		if info, ok := errcode.Lookup(errcode.Code(apiErr.ErrorCode)); !ok || info.HTTPStatus != rec.Code {
+			t.Errorf("errorCode %s is not registered with status %d", apiErr.ErrorCode, rec.Code)
+		}
+	}
+
+	if rec, apiErr := submit(s, []byte(`{`), nil); rec.Code != http.StatusBadRequest || apiErr.ErrorCode != "DSB-API-001" {
+		t.Errorf("malformed body = %d %+v, want errorCode DSB-API-001", rec.Code, apiErr)
+	}
+}
+
+func TestServer_RejectionsNameTheTransaction(t *testing.T) {
+	wallet, _ := identity.NewWallet()
+	defer wallet.Close()
+	s, err := NewServer(ledger.NewMempool(nil), ServerOptions{})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	body := signedTxBody(t, wallet)
+	var tx ledger.Transaction
+	json.Unmarshal(body, &tx)
+
+	if rec, _ := submit(s, body, nil); rec.Code != http.StatusAccepted {
+		t.Fatalf("first submit = %d, want 202", rec.Code)
+	}
+	rec, apiErr := submit(s, body, nil)
+	if rec.Code != http.StatusConflict || apiErr.ErrorCode != "DSB-LEDGER-008" || apiErr.TxID != tx.ID {
+		t.Errorf("duplicate submit = %d %+v, want DSB-LEDGER-008 naming %s", rec.Code, apiErr, tx.ID)
+	}
+}
+
+func TestServer_RateLimitsWithRetryAfter(t *testing.T) {
+	wallet, _ := identity.NewWallet()
+	defer wallet.Close()
+	s, _ := NewServer(&recordingSubmitter{}, ServerOptions{ClientRate: 100, ClientBurst: 100, AddressRate: 0.5, AddressBurst: 2})
+
+	for i := 0; i < 2; i++ {
+		if rec, _ := submit(s, signedTxBody(t, wallet), nil); rec.Code != http.StatusAccepted {
+			t.Fatalf("submission %d = %d, want 202", i, rec.Code)
+		}
+	}
+	rec, apiErr := submit(s, signedTxBody(t, wallet), nil)
+	if rec.Code != http.StatusTooManyRequests || apiErr.Code != CodeRateLimited {
+		t.Fatalf("third submission = %d %+v, want 429 rate_limited", rec.Code, apiErr)
+	}
+	if rec.Header().Get("Retry-After") != "2" || apiErr.RetryAfterSeconds != 2 {
+		t.Errorf("Retry-After = %q / %d, want 2", rec.Header().Get("Retry-After"), apiErr.RetryAfterSeconds)
+	}
+
+	// Another sender from the same client is unaffected by the per-address limit.
+	other, _ := identity.NewWallet()
+	defer other.Close()
+	if rec, _ := submit(s, signedTxBody(t, other), nil); rec.Code != http.StatusAccepted {
+		t.Errorf("other sender = %d, want 202", rec.Code)
+	}
+}
+
+func TestServer_SignedRequestsCountAgainstIPLimit(t *testing.T) {
+	s, _ := NewServer(&recordingSubmitter{}, ServerOptions{ClientRate: 0.5, ClientBurst: 2})
+
+	// A fresh address on every request does not escape the IP limit.
+	for i := 0; i < 3; i++ {
+		wallet, _ := identity.NewWallet()
+		defer wallet.Close()
+		body := signedTxBody(t, wallet)
+		rec, apiErr := submit(s, body, func(r *http.Request) {
+			if err := SignRequest(r, wallet, "", body); err != nil {
+				t.Fatalf("SignRequest() error = %v", err)
+			}
+		})
+		if i < 2 && rec.Code != http.StatusAccepted {
+			t.Fatalf("signed submission %d = %d (%s), want 202", i, rec.Code, rec.Body.String())
+		}
+		if i == 2 && (rec.Code != http.StatusTooManyRequests || apiErr.Code != CodeRateLimited) {
+			t.Errorf("third signed submission from one IP = %d %+v, want 429 rate_limited", rec.Code, apiErr)
+		}
+	}
+}
+
+func TestServer_UnverifiedSenderDoesNotSpendQuota(t *testing.T) {
+	victim, _ := identity.NewWallet()
+	defer victim.Close()
+	s, _ := NewServer(&recordingSubmitter{}, ServerOptions{AddressRate: 0.5, AddressBurst: 1})
+
+	forged := fixture.RawTx(t, victim, ledger.Like, []byte(`{"postCID":"p"}`))
+	forged.Signature = []byte("junk")
+	junk, _ := json.Marshal(forged)
+	for i := 0; i < 3; i++ {
+		if rec, apiErr := submit(s, junk, nil); rec.Code != http.StatusBadRequest || apiErr.ErrorCode != "DSB-LEDGER-001" {
+			t.Fatalf("forged submission %d = %d %+v, want 400 DSB-LEDGER-001", i, rec.Code, apiErr)
+		}
+	}
+	if rec, _ := submit(s, signedTxBody(t, victim), nil); rec.Code != http.StatusAccepted {
+		t.Errorf("victim's own submission after forgeries = %d, want 202", rec.Code)
+	}
+}
+
+func TestServer_Authentication(t *testing.T) {
+	wallet, _ := identity.NewWallet()
+	defer wallet.Close()
+	other, _ := identity.NewWallet()
+	defer other.Close()
+	s, _ := NewServer(&recordingSubmitter{}, ServerOptions{ChainID: "test-chain", RequireAuth: true})
+
+	body := signedTxBody(t, wallet)
+	if rec, apiErr := submit(s, body, nil); rec.Code != http.StatusUnauthorized || apiErr.Code != CodeUnauthenticated {
+		t.Errorf("unsigned request = %d %+v, want 401", rec.Code, apiErr)
+	}
+	signWith := func(w *identity.Wallet, chainID string, signed []byte) func(*http.Request) {
+		return func(r *http.Request) {
+			if err := SignRequest(r, w, chainID, signed); err != nil {
+				t.Fatalf("SignRequest() error = %v", err)
+			}
+		}
+	}
+	if rec, _ := submit(s, body, signWith(wallet, "test-chain", body)); rec.Code != http.StatusAccepted {
+		t.Errorf("signed request = %d (%s), want 202", rec.Code, rec.Body.String())
+	}
+	if rec, _ := submit(s, body, signWith(wallet, "other-chain", body)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("request signed for another chain = %d, want 401", rec.Code)
+	}
+	if rec, _ := submit(s, body, signWith(wallet, "test-chain", []byte("different body"))); rec.Code != http.StatusUnauthorized {
+		t.Errorf("signature over a different body = %d, want 401", rec.Code)
+	}
+	if rec, apiErr := submit(s, body, signWith(other, "test-chain", body)); rec.Code != http.StatusForbidden {
+		t.Errorf("request signed by a non-sender = %d %+v, want 403", rec.Code, apiErr)
+	}
+
+	// A captured request cannot be sent again, nor signed without a nonce.
+	var captured http.Header
+	submit(s, body, func(r *http.Request) {
+		signWith(wallet, "test-chain", body)(r)
+		captured = r.Header.Clone()
+	})
+	replay := func(del string) func(*http.Request) {
+		return func(r *http.Request) {
+			r.Header = captured.Clone()
+			r.Header.Del(del)
+		}
+	}
+	if rec, apiErr := submit(s, body, replay("")); rec.Code != http.StatusUnauthorized || !strings.Contains(apiErr.Message, "nonce") {
+		t.Errorf("replayed request = %d %+v, want 401 for the used nonce", rec.Code, apiErr)
+	}
+	if rec, _ := submit(s, body, replay(HeaderAuthNonce)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("request without a nonce = %d, want 401", rec.Code)
+	}
+}
+
+func TestReplayCache_ForgetsExpiredNonces(t *testing.T) {
+	c := newReplayCache()
+	c.maxKeys = 2
+	now := time.Unix(1000, 0)
+	expires := now.Add(DefaultMaxClockSkew)
+	if err := c.add("a", "n1", expires, now); err != nil {
+		t.Fatalf("add() error = %v", err)
+	}
+	if err := c.add("a", "n1", expires, now); err == nil {
+		t.Error("add() of a used nonce: expected error, got nil")
+	}
+	if err := c.add("b", "n1", expires, now); err != nil {
+		t.Errorf("add() of another address's nonce error = %v", err)
+	}
+	if err := c.add("a", "n2", expires, now); err == nil {
+		t.Error("add() to a full cache: expected error, got nil")
+	}
+
+	now = expires
+	if err := c.add("a", "n2", now.Add(DefaultMaxClockSkew), now); err != nil {
+		t.Errorf("add() after the entries expired error = %v", err)
+	}
+	if len(c.seen) != 1 {
+		t.Errorf("tracked %d nonces after pruning, want 1", len(c.seen))
+	}
+}
+
+func TestServer_GraphQL(t *testing.T) {
+	graphQL := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{}}`))
+	})
+	query := func(s *Server) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/graphql", bytes.NewReader([]byte(`{"query":"{ posts { totalCount } }"}`)))
+		req.RemoteAddr = "192.0.2.1:5000"
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	disabled, _ := NewServer(&recordingSubmitter{}, ServerOptions{})
+	if rec := query(disabled); rec.Code != http.StatusNotFound {
+		t.Errorf("without GraphQL: status = %d, want 404", rec.Code)
+	}
+
+	s, err := NewServer(&recordingSubmitter{}, ServerOptions{GraphQL: graphQL, ClientRate: 0.5, ClientBurst: 1, ValidateRequests: true})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	if rec := query(s); rec.Code != http.StatusOK || rec.Body.String() != `{"data":{}}` {
+		t.Errorf("first query = %d %s, want the GraphQL handler's response", rec.Code, rec.Body)
+	}
+	if rec := query(s); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("second query = %d, want 429 from the client limit", rec.Code)
+	}
+}
+
+func TestServer_ContinuesCallerTrace(t *testing.T) {
+	testSpans.Reset()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator()) })
+
+	s, _ := NewServer(&recordingSubmitter{}, ServerOptions{})
+	req := httptest.NewRequest(http.MethodGet, "/v1/transactions", nil)
+	req.Header.Set("Traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	s.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := testSpans.GetSpans().Snapshots()
+	if len(spans) != 1 || spans[0].Name() != "api.Request" {
+		t.Fatalf("ended spans = %v, want one api.Request", spans)
+	}
+	span := spans[0]
+	if span.SpanContext().TraceID().String() != "0af7651916cd43dd8448eb211c80319c" || span.Parent().SpanID().String() != "b7ad6b7169203331" {
+		t.Errorf("span trace %s parent %s, want the caller's trace", span.SpanContext().TraceID(), span.Parent().SpanID())
+	}
+	want := attribute.Int("http.response.status_code", http.StatusMethodNotAllowed)
+	found := false
+	for _, kv := range span.Attributes() {
+		found = found || kv == want
+	}
+	if !found {
+		t.Errorf("span attributes = %v, want %v", span.Attributes(), want)
+	}
+}
@@ -0,0 +1,406 @@
+// Package archive runs a node in archival mode: it mirrors every piece of
+// content any transaction on the chain references (see
+// ledger.RegisterCIDResolver) into the node's local storage, so community
+// members can run nodes that keep all content available however little it is
+// read. A PinPolicy narrows it to the content of chosen accounts, e.g. the
+// node operator's own and that of the accounts they follow, so an ordinary
+// node keeps what matters to it through garbage collection. Content is pinned
+// once referenced, and each round re-fetches from the
+// network any chunk missing locally, whether never mirrored or lost since;
+// Completeness reports how much of the chain's content is held.
+package archive
+
+import (
+	"context"
+	"crypto/sha256"
+	"digisocialblock/core/content"
+	"digisocialblock/core/ledger"
+	"digisocialblock/pkg/dds/chunking"
+	"encoding/hex"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Defaults applied when Options leaves them zero.
+const (
+	DefaultInterval      = 10 * time.Minute
+	DefaultMaxPerRound   = 256 // Manifests mirrored per round
+	maxManifestsReported = 64  // Incomplete manifests listed by Completeness
+)
+
+// ErrDiverged is returned by Sync when the block recorded as the high-water
+// mark no longer matches the chain.
+var ErrDiverged = errors.New("archive high-water mark does not match the chain")
+
+// Options configures an Archiver. The zero value is valid.
+type Options struct {
+	Interval    time.Duration // Between rounds of Run; defaults to DefaultInterval
+	MaxPerRound int           // Incomplete manifests mirrored per round; defaults to DefaultMaxPerRound
+
+	// Pin selects the content archived; the zero value archives everything.
+	Pin PinPolicy
+
+	// Advertiser, if set, re-advertises each manifest once all its chunks are
+	// held, so peers can fetch it from this node.
+	Advertiser content.OriginatorAdvertiser
+}
+
+// Completeness summarizes how much of the content referenced on the chain
+// the node holds.
+type Completeness struct {
+	Height           int64     `json:"height"`           // Last block synced; -1 before the first
+	Manifests        int       `json:"manifests"`        // Content CIDs referenced on the chain
+	Complete         int       `json:"complete"`         // Of which every chunk is held
+	MissingManifests int       `json:"missingManifests"` // Of which the manifest could not be fetched yet
+	Chunks           int       `json:"chunks"`           // Distinct chunks of the resolved manifests
+	MissingChunks    int       `json:"missingChunks"`    // Of which not held
+	Bytes            int64     `json:"bytes"`            // Held bytes of those chunks
+	Incomplete       []string  `json:"incomplete,omitempty"`
+	LastRound        time.Time `json:"lastRound,omitempty"`
+	LastError        string    `json:"lastError,omitempty"`
+}
+
+// Ratio returns the fraction of referenced content held in full, 1 when the
+// chain references none.
+func (c Completeness) Ratio() float64 {
+	if c.Manifests == 0 {
+		return 1
+	}
+	return float64(c.Complete) / float64(c.Manifests)
+}
+
+// RoundReport summarizes a Round.
+type RoundReport struct {
+	Blocks    int   // Blocks synced
+	New       int   // Content CIDs newly referenced
+	Mirrored  int   // Manifests that became complete
+	Chunks    int   // Chunks fetched and stored
+	Bytes     int64 // Bytes of those chunks
+	Remaining int   // Manifests still incomplete
+}
+
+// entry is one piece of referenced content.
+type entry struct {
+	manifest   *chunking.ContentManifestV1 // Nil until fetched
+	complete   bool                        // Every chunk held as of the last check
+	advertised bool
+}
+
+// Archiver mirrors the content referenced on a chain. It implements
+// content.PinSet and invariant.PinSet with the content it archives, so a
+// garbage collector sharing its storage never evicts it. It is safe for
+// concurrent use if its storages and fetcher are; rounds run one at a time.
+type Archiver struct {
+	bc        *ledger.Blockchain
+	local     content.DDSStorage
+	network   content.DDSChunkRetriever
+	manifests content.DDSManifestFetcher
+	opts      Options
+
+	roundMu sync.Mutex // Held for a whole round
+
+	mu             sync.Mutex
+	highWaterIndex int64
+	highWaterHash  string
+	entries        map[string]*entry
+	authors        map[string]bool // Senders whose content is archived, as of the last sync; nil for all
+	order          []string        // Manifest CIDs in the order first referenced
+	lastRound      time.Time
+	lastErr        error
+	now            func() time.Time
+}
+
+// New creates an Archiver that mirrors the content referenced on bc into
+// local, fetching manifests with manifests and missing chunks from network.
+func New(bc *ledger.Blockchain, local content.DDSStorage, network content.DDSChunkRetriever, manifests content.DDSManifestFetcher, opts Options) (*Archiver, error) {
+	if bc == nil {
+		return nil, fmt.Errorf("blockchain cannot be nil")
+	}
+	if local == nil || network == nil {
+		return nil, fmt.Errorf("local storage and network cannot be nil")
+	}
+	if manifests == nil {
+		return nil, fmt.Errorf("manifest fetcher cannot be nil")
+	}
+	if err := opts.Pin.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.MaxPerRound <= 0 {
+		opts.MaxPerRound = DefaultMaxPerRound
+	}
+	return &Archiver{
+		bc:             bc,
+		local:          local,
+		network:        network,
+		manifests:      manifests,
+		opts:           opts,
+		highWaterIndex: -1,
+		entries:        make(map[string]*entry),
+		now:            time.Now,
+	}, nil
+}
+
+// Run runs a round every Interval until ctx is done.
+func (a *Archiver) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := a.Round(ctx); err != nil {
+				log.Printf("Archive: round failed: %v\n", err)
+			}
+		}
+	}
+}
+
+// Round syncs the chain and mirrors up to MaxPerRound incomplete manifests.
+// Content that cannot be mirrored yet is reported in the returned error and
+// retried next round.
+func (a *Archiver) Round(ctx context.Context) (RoundReport, error) {
+	a.roundMu.Lock()
+	defer a.roundMu.Unlock()
+	var report RoundReport
+	var err error
+	report.Blocks, report.New, err = a.sync()
+	if errors.Is(err, ErrDiverged) {
+		// The chain was reorganized below the high-water mark. Pins are kept:
+		// content once referenced stays archived.
+		log.Printf("Archive: %v; resyncing\n", err)
+		report.Blocks, report.New, err = a.resync()
+	}
+	if err == nil {
+		err = a.mirror(ctx, &report)
+	}
+	a.mu.Lock()
+	a.lastRound, a.lastErr = a.now(), err
+	a.mu.Unlock()
+	return report, err
+}
+
+// sync records the CIDs referenced by the blocks above the high-water mark.
+// When the pin policy selects accounts it did not last time, such as one
+// just followed, it records those of every block, since their earlier
+// content was skipped.
+func (a *Archiver) sync() (blocks, added int, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	authors := a.opts.Pin.authors()
+	widened := false
+	for author := range authors {
+		widened = widened || !a.authors[author]
+	}
+	a.authors = authors
+	if widened && a.highWaterIndex >= 0 {
+		a.highWaterIndex, a.highWaterHash = -1, ""
+	}
+	if a.highWaterIndex >= 0 {
+		block := a.bc.GetBlockByIndex(a.highWaterIndex)
+		if block == nil || block.Hash != a.highWaterHash {
+			return 0, 0, fmt.Errorf("%w (block %d)", ErrDiverged, a.highWaterIndex)
+		}
+	}
+	return a.processFromLocked(a.highWaterIndex + 1)
+}
+
+// resync records the CIDs referenced by every block, on top of those
+// recorded already.
+func (a *Archiver) resync() (blocks, added int, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.highWaterIndex, a.highWaterHash = -1, ""
+	return a.processFromLocked(0)
+}
+
+// processFromLocked records the CIDs referenced by blocks from index start to
+// the tip. The caller must hold a.mu.
+func (a *Archiver) processFromLocked(start int64) (blocks, added int, err error) {
+	latest := a.bc.GetLatestBlock()
+	if latest == nil {
+		return 0, 0, fmt.Errorf("blockchain has no blocks")
+	}
+	for i := start; i <= latest.Index; i++ {
+		block := a.bc.GetBlockByIndex(i)
+		if block == nil {
+			return blocks, added, fmt.Errorf("block %d missing while syncing archive", i)
+		}
+		for _, tx := range block.Transactions {
+			if tx == nil || (a.authors != nil && !a.authors[tx.SenderPublicKey]) {
+				continue
+			}
+			cids, err := tx.ReferencedCIDs()
+			if err != nil {
+				continue // Accepted under older rules; nothing to mirror
+			}
+			for _, cid := range cids {
+				if _, ok := a.entries[cid]; !ok {
+					a.entries[cid] = &entry{}
+					a.order = append(a.order, cid)
+					added++
+				}
+			}
+		}
+		a.highWaterIndex, a.highWaterHash = block.Index, block.Hash
+		blocks++
+	}
+	return blocks, added, nil
+}
+
+// mirror fetches the missing manifests and chunks of up to MaxPerRound
+// incomplete entries.
+func (a *Archiver) mirror(ctx context.Context, report *RoundReport) error {
+	a.mu.Lock()
+	var todo []string
+	for _, cid := range a.order {
+		if !a.entries[cid].complete {
+			todo = append(todo, cid)
+		}
+	}
+	a.mu.Unlock()
+	if len(todo) > a.opts.MaxPerRound {
+		report.Remaining = len(todo) - a.opts.MaxPerRound
+		todo = todo[:a.opts.MaxPerRound]
+	}
+
+	var errs []error
+	for _, cid := range todo {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		chunks, bytes, err := a.mirrorOne(cid)
+		report.Chunks += chunks
+		report.Bytes += bytes
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", cid, err))
+			report.Remaining++
+			continue
+		}
+		report.Mirrored++
+	}
+	return errors.Join(errs...)
+}
+
+// mirrorOne stores every missing chunk of the content at manifestCID and
+// marks it complete, returning the chunks and bytes it stored.
+func (a *Archiver) mirrorOne(manifestCID string) (int, int64, error) {
+	a.mu.Lock()
+	e := a.entries[manifestCID]
+	manifest := e.manifest
+	a.mu.Unlock()
+	if manifest == nil {
+		var err error
+		if manifest, err = a.manifests.FetchManifest(manifestCID); err != nil {
+			return 0, 0, fmt.Errorf("failed to fetch manifest: %w", err)
+		}
+		a.mu.Lock()
+		e.manifest = manifest
+		a.mu.Unlock()
+	}
+
+	stored, bytes := 0, int64(0)
+	for _, chunk := range manifest.Chunks {
+		if a.local.ChunkExists(chunk.ChunkCID) {
+			continue
+		}
+		data, err := a.network.RetrieveChunk(chunk.ChunkCID)
+		if err != nil {
+			return stored, bytes, fmt.Errorf("failed to retrieve chunk %s: %w", chunk.ChunkCID, err)
+		}
+		if err := verifyChunk(chunk, data); err != nil {
+			return stored, bytes, err
+		}
+		if err := a.local.StoreChunk(chunk.ChunkCID, data); err != nil {
+			return stored, bytes, fmt.Errorf("failed to store chunk %s: %w", chunk.ChunkCID, err)
+		}
+		stored++
+		bytes += int64(len(data))
+	}
+
+	a.mu.Lock()
+	e.complete = true
+	advertise := !e.advertised && a.opts.Advertiser != nil
+	e.advertised = e.advertised || advertise
+	a.mu.Unlock()
+	if advertise {
+		if err := a.opts.Advertiser.AdvertiseManifest(manifest); err != nil {
+			log.Printf("Archive: failed to advertise %s: %v\n", manifestCID, err)
+		}
+	}
+	return stored, bytes, nil
+}
+
+// verifyChunk checks that data is the chunk the manifest lists.
+func verifyChunk(chunk chunking.ChunkInfo, data []byte) error {
+	sum := sha256.Sum256(data)
+	if cid := hex.EncodeToString(sum[:]); !ledger.ConstantTimeEqual(cid, chunk.ChunkCID) {
+		return fmt.Errorf("chunk %s from the network hashes to %s", chunk.ChunkCID, cid)
+	}
+	if int64(len(data)) != chunk.Size {
+		return fmt.Errorf("chunk %s from the network is %d bytes, manifest says %d", chunk.ChunkCID, len(data), chunk.Size)
+	}
+	return nil
+}
+
+// Pinned returns the manifest CIDs of the content the archiver archives.
+func (a *Archiver) Pinned() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.order...)
+}
+
+// Completeness checks local storage for every chunk of the archived content
+// and reports how much is held. Content found to have lost chunks is
+// mirrored again by the next round.
+func (a *Archiver) Completeness() Completeness {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	c := Completeness{Height: a.highWaterIndex, Manifests: len(a.order), LastRound: a.lastRound}
+	if a.lastErr != nil {
+		c.LastError = a.lastErr.Error()
+	}
+	seen := make(map[string]bool)
+	for _, cid := range a.order {
+		e := a.entries[cid]
+		if e.manifest == nil {
+			c.MissingManifests++
+			e.complete = false
+		} else {
+			e.complete = true
+			for _, chunk := range e.manifest.Chunks {
+				held := a.local.ChunkExists(chunk.ChunkCID)
+				e.complete = e.complete && held
+				if seen[chunk.ChunkCID] {
+					continue
+				}
+				seen[chunk.ChunkCID] = true
+				c.Chunks++
+				if held {
+					c.Bytes += chunk.Size
+				} else {
+					c.MissingChunks++
+				}
+			}
+		}
+		if e.complete {
+			c.Complete++
+		} else if len(c.Incomplete) < maxManifestsReported {
+			c.Incomplete = append(c.Incomplete, cid)
+		}
+	}
+	return c
+}
+
+// Var publishes Completeness, e.g. as an entry of
+// api.ServerOptions.Diagnostics.
+func (a *Archiver) Var() expvar.Var {
+	return expvar.Func(func() interface{} { return a.Completeness() })
+}
@@ -0,0 +1,98 @@
+package archive
+
+import (
+	"context"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"digisocialblock/core/user"
+	"digisocialblock/internal/testutil"
+	"digisocialblock/internal/testutil/fixture"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// archiveTestPublish stores text on network in 8-byte chunks and returns its
+// manifest CID.
+func archiveTestPublish(network *testutil.DDS, text string) string {
+	manifest, chunks := testutil.Chunk([]byte(text), 8)
+	for _, c := range chunks {
+		network.Storage.Put(c.ChunkCID, c.Data)
+	}
+	network.Manifests.Add(manifest.ManifestCID, manifest)
+	return manifest.ManifestCID
+}
+
+// archiveTestChunk returns the CID of chunk i of text as archiveTestPublish
+// stores it.
+func archiveTestChunk(text string, i int) string {
+	_, chunks := testutil.Chunk([]byte(text), 8)
+	return chunks[i].ChunkCID
+}
+
+func TestArchiver_MirrorsEveryReferencedCID(t *testing.T) {
+	network, local := testutil.NewDDS(8), testutil.NewStorage()
+	post := archiveTestPublish(network, "a post on the chain")
+	media := archiveTestPublish(network, "its attached image")
+	avatar := archiveTestPublish(network, "a profile picture")
+
+	wallet := fixture.Wallet(t)
+	p := social.NewPost(wallet.Address, post, "title", nil)
+	p.Media = []string{media}
+	profile := &user.Profile{OwnerPublicKey: wallet.Address, DisplayName: "Alice", ProfilePictureCID: avatar, Timestamp: 1, Version: 1}
+	bc := fixture.Chain(t, []*ledger.Transaction{fixture.Tx(t, wallet, ledger.PostCreated, p)}, []*ledger.Transaction{fixture.Tx(t, wallet, ledger.ProfileUpdate, profile)})
+
+	a, err := New(bc, local, network.Storage, network.Manifests, Options{Advertiser: network.Originator})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	network.Storage.FailRetrieve(archiveTestChunk("a profile picture", 1), errors.New("peer offline"))
+	report, err := a.Round(context.Background())
+	if err == nil || report.Blocks != 3 || report.New != 3 || report.Mirrored != 2 || report.Remaining != 1 {
+		t.Fatalf("Round() with a chunk unavailable = %+v, %v; want 2 of 3 mirrored and an error", report, err)
+	}
+	if got := a.Pinned(); !reflect.DeepEqual(got, []string{post, media, avatar}) {
+		t.Errorf("Pinned() = %v, want every referenced CID", got)
+	}
+	c := a.Completeness()
+	if c.Manifests != 3 || c.Complete != 2 || c.MissingChunks == 0 || !reflect.DeepEqual(c.Incomplete, []string{avatar}) || c.LastError == "" {
+		t.Errorf("Completeness() = %+v, want the avatar incomplete", c)
+	}
+
+	network.Storage.FailRetrieve(archiveTestChunk("a profile picture", 1), nil)
+	if report, err := a.Round(context.Background()); err != nil || report.Mirrored != 1 || report.Remaining != 0 {
+		t.Fatalf("Round() once the chunk is back = %+v, %v; want the avatar mirrored", report, err)
+	}
+	if c := a.Completeness(); c.Complete != 3 || c.MissingChunks != 0 || c.Ratio() != 1 {
+		t.Errorf("Completeness() = %+v, want everything held", c)
+	}
+	if got := network.Originator.Advertised(); len(got) != 3 {
+		t.Errorf("advertised %v, want each manifest once", got)
+	}
+
+	// A chunk lost locally is noticed and mirrored again.
+	lost := archiveTestChunk("a post on the chain", 1)
+	local.Delete(lost)
+	if c := a.Completeness(); c.Complete != 2 || c.MissingChunks != 1 {
+		t.Errorf("Completeness() after losing a chunk = %+v, want one manifest incomplete", c)
+	}
+	if report, err := a.Round(context.Background()); err != nil || report.Chunks != 1 || !local.ChunkExists(lost) {
+		t.Errorf("Round() after losing a chunk = %+v, %v; want it re-fetched", report, err)
+	}
+}
+
+func TestArchiver_RefusesCorruptChunks(t *testing.T) {
+	network, local := testutil.NewDDS(8), testutil.NewStorage()
+	cid := archiveTestPublish(network, "content served corrupted")
+	manifest, _ := network.Manifests.FetchManifest(cid)
+	network.Storage.Put(manifest.Chunks[0].ChunkCID, []byte("tampered"))
+	bc := fixture.Chain(t, []*ledger.Transaction{fixture.PostTx(t, fixture.Wallet(t), cid)})
+
+	a, _ := New(bc, local, network.Storage, network.Manifests, Options{})
+	if _, err := a.Round(context.Background()); err == nil {
+		t.Fatal("Round() with a corrupt chunk: expected error, got nil")
+	}
+	if local.ChunkExists(manifest.Chunks[0].ChunkCID) {
+		t.Error("corrupt chunk was stored")
+	}
+}
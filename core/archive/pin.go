@@ -0,0 +1,84 @@
+package archive
+
+import (
+	"digisocialblock/core/social"
+	"fmt"
+)
+
+// PinScope selects whose content an Archiver pins.
+type PinScope int
+
+const (
+	PinEverything PinScope = iota // Content referenced by any transaction
+	PinOwn                        // Content referenced by the policy's accounts
+	PinFollowed                   // Also content referenced by the accounts they follow
+)
+
+// String returns the scope's name.
+func (s PinScope) String() string {
+	switch s {
+	case PinEverything:
+		return "everything"
+	case PinOwn:
+		return "own"
+	case PinFollowed:
+		return "followed"
+	}
+	return fmt.Sprintf("PinScope(%d)", int(s))
+}
+
+// FollowGraph lists the accounts an account follows. *social.GraphIndex
+// implements it.
+type FollowGraph interface {
+	GetFollowing(address string) []social.FollowEdge
+}
+
+// PinPolicy selects the content an Archiver pins by the account whose
+// transaction references it: a post's content and media, a profile's
+// picture, and whatever else ledger.RegisterCIDResolver covers. The zero
+// value pins everything.
+//
+// Under PinFollowed the follow graph is read at every round, so content of an
+// account followed later, including what it published before, is pinned from
+// then on. Content stays pinned after an unfollow, as all archived content
+// does.
+type PinPolicy struct {
+	Scope    PinScope
+	Accounts []string    // The node operator's accounts; required unless Scope is PinEverything
+	Follows  FollowGraph // Required for PinFollowed; sync it with the chain before each round
+}
+
+// Validate checks that the policy names the accounts and follow graph its
+// scope needs.
+func (p PinPolicy) Validate() error {
+	switch p.Scope {
+	case PinEverything:
+		return nil
+	case PinOwn, PinFollowed:
+		if len(p.Accounts) == 0 {
+			return fmt.Errorf("invalid pin policy: scope %s needs accounts", p.Scope)
+		}
+		if p.Scope == PinFollowed && p.Follows == nil {
+			return fmt.Errorf("invalid pin policy: scope %s needs a follow graph", p.Scope)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid pin policy: unknown scope %s", p.Scope)
+}
+
+// authors returns the senders whose content the policy pins, nil for all.
+func (p PinPolicy) authors() map[string]bool {
+	if p.Scope == PinEverything {
+		return nil
+	}
+	authors := make(map[string]bool)
+	for _, account := range p.Accounts {
+		authors[account] = true
+		if p.Scope == PinFollowed {
+			for _, edge := range p.Follows.GetFollowing(account) {
+				authors[edge.Followee] = true
+			}
+		}
+	}
+	return authors
+}
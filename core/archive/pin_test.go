@@ -0,0 +1,57 @@
+package archive
+
+import (
+	"context"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"digisocialblock/internal/testutil"
+	"digisocialblock/internal/testutil/fixture"
+	"reflect"
+	"testing"
+)
+
+func TestArchiver_PinPolicy(t *testing.T) {
+	network, local := testutil.NewDDS(8), testutil.NewStorage()
+	me, friend, stranger := fixture.Wallet(t), fixture.Wallet(t), fixture.Wallet(t)
+	mine := archiveTestPublish(network, "my own post")
+	friends := archiveTestPublish(network, "a friend's older post")
+	strangers := archiveTestPublish(network, "a stranger's post")
+	bc := fixture.Chain(t, []*ledger.Transaction{
+		fixture.PostTx(t, me, mine),
+		fixture.PostTx(t, friend, friends),
+		fixture.PostTx(t, stranger, strangers),
+	})
+	graph := social.NewGraphIndex()
+
+	if _, err := New(bc, local, network.Storage, network.Manifests, Options{Pin: PinPolicy{Scope: PinFollowed, Accounts: []string{me.Address}}}); err == nil {
+		t.Error("New() accepted PinFollowed without a follow graph")
+	}
+	a, err := New(bc, local, network.Storage, network.Manifests, Options{Pin: PinPolicy{Scope: PinFollowed, Accounts: []string{me.Address}, Follows: graph}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := a.Round(context.Background()); err != nil {
+		t.Fatalf("Round() error = %v", err)
+	}
+	if got := a.Pinned(); !reflect.DeepEqual(got, []string{mine}) {
+		t.Errorf("Pinned() following nobody = %v, want only own content", got)
+	}
+
+	// Following an account pins what it published before the follow.
+	if _, err := bc.AddBlock([]*ledger.Transaction{fixture.FollowTx(t, me, friend.Address)}); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+	if _, err := graph.Sync(bc); err != nil {
+		t.Fatalf("GraphIndex.Sync() error = %v", err)
+	}
+	report, err := a.Round(context.Background())
+	if err != nil || report.New != 1 || report.Mirrored != 1 {
+		t.Fatalf("Round() after a follow = %+v, %v; want the friend's post mirrored", report, err)
+	}
+	if got := a.Pinned(); !reflect.DeepEqual(got, []string{mine, friends}) {
+		t.Errorf("Pinned() = %v, want own and followed content", got)
+	}
+	if local.ChunkExists(archiveTestChunk("a stranger's post", 0)) {
+		t.Error("a stranger's content was mirrored")
+	}
+}
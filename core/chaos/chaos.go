@@ -0,0 +1,214 @@
+// Package chaos injects faults into the DDS storage, manifest and network
+// interfaces so resilience paths (retries, fallbacks, integrity checks and
+// repair) are exercised rather than assumed. An Injector decides, call by
+// call, whether to fail, delay or corrupt; its wrappers sit in front of a
+// content.DDSStorage, content.DDSManifestFetcher, content.OriginatorAdvertiser
+// or the http.RoundTripper used to talk to peers.
+//
+// Tests seed the Injector for repeatable runs. A development network can read
+// a fault spec from a flag or environment variable with ParseFaults and
+// change it at runtime with SetFaults.
+package chaos
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInjected is the error returned by calls the Injector fails.
+var ErrInjected = errors.New("chaos: injected fault")
+
+// Faults configures the faults an Injector injects. Each rate is the
+// probability, from 0 to 1, that a call is affected.
+type Faults struct {
+	// ErrorRate fails calls with ErrInjected.
+	ErrorRate float64
+	// SlowRate delays calls by Latency before they run.
+	SlowRate float64
+	Latency  time.Duration
+	// CorruptRate flips one bit of the data a call returns (or of the
+	// manifest it fetches), so integrity checks have something to catch.
+	CorruptRate float64
+}
+
+// Validate checks that the rates are probabilities and the latency is not
+// negative.
+func (f Faults) Validate() error {
+	rates := []struct {
+		name string
+		rate float64
+	}{{"error", f.ErrorRate}, {"slow", f.SlowRate}, {"corrupt", f.CorruptRate}}
+	for _, r := range rates {
+		if r.rate < 0 || r.rate > 1 {
+			return fmt.Errorf("%s rate must be between 0 and 1, got %v", r.name, r.rate)
+		}
+	}
+	if f.Latency < 0 {
+		return fmt.Errorf("latency cannot be negative, got %v", f.Latency)
+	}
+	return nil
+}
+
+// ParseFaults parses a comma-separated fault spec such as
+// "error=0.05,slow=0.1:2s,corrupt=0.01": the error rate, the slow rate and
+// its latency, and the corruption rate. Omitted faults are off; an empty spec
+// disables injection.
+func ParseFaults(spec string) (Faults, error) {
+	var f Faults
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return Faults{}, fmt.Errorf("fault %q is not name=value", field)
+		}
+		var latency string
+		if name == "slow" {
+			value, latency, ok = strings.Cut(value, ":")
+			if !ok {
+				return Faults{}, fmt.Errorf("slow fault %q needs a latency, e.g. slow=0.1:2s", field)
+			}
+		}
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return Faults{}, fmt.Errorf("invalid rate in fault %q: %w", field, err)
+		}
+		switch name {
+		case "error":
+			f.ErrorRate = rate
+		case "slow":
+			f.SlowRate = rate
+			if f.Latency, err = time.ParseDuration(latency); err != nil {
+				return Faults{}, fmt.Errorf("invalid latency in fault %q: %w", field, err)
+			}
+		case "corrupt":
+			f.CorruptRate = rate
+		default:
+			return Faults{}, fmt.Errorf("unknown fault %q", name)
+		}
+	}
+	if err := f.Validate(); err != nil {
+		return Faults{}, err
+	}
+	return f, nil
+}
+
+// Options configures an Injector.
+type Options struct {
+	Faults Faults
+	// Seed seeds the fault decisions. Calls made in the same order with the
+	// same seed get the same faults.
+	Seed int64
+	// Sleep waits out injected latency. Nil means time.Sleep; tests pass a
+	// recorder so they do not wait.
+	Sleep func(time.Duration)
+}
+
+// Stats counts the calls an Injector has seen and the faults it injected.
+type Stats struct {
+	Calls       int
+	Errors      int
+	Delays      int
+	Corruptions int
+}
+
+// Injector decides which calls fail, slow down or return corrupted data. It
+// is safe for concurrent use, though concurrent callers make the sequence of
+// decisions depend on scheduling.
+type Injector struct {
+	sleep func(time.Duration)
+
+	mu     sync.Mutex
+	rng    *rand.Rand
+	faults Faults
+	stats  Stats
+}
+
+// NewInjector creates an Injector.
+func NewInjector(opts Options) (*Injector, error) {
+	if err := opts.Faults.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid faults: %w", err)
+	}
+	if opts.Sleep == nil {
+		opts.Sleep = time.Sleep
+	}
+	return &Injector{
+		sleep:  opts.Sleep,
+		rng:    rand.New(rand.NewSource(opts.Seed)),
+		faults: opts.Faults,
+	}, nil
+}
+
+// SetFaults replaces the faults injected from the next call on.
+func (in *Injector) SetFaults(f Faults) error {
+	if err := f.Validate(); err != nil {
+		return fmt.Errorf("invalid faults: %w", err)
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.faults = f
+	return nil
+}
+
+// Stats returns the counters so far.
+func (in *Injector) Stats() Stats {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.stats
+}
+
+// decision is what the Injector does to one call.
+type decision struct {
+	in      *Injector
+	corrupt bool  // Whether returned data is corrupted
+	pick    int64 // Chooses the bit to flip
+}
+
+// decide draws the faults for a call to op, sleeps out any delay and returns
+// the decision. The generator is drawn the same number of times for every
+// call, so changing one rate does not shift the other decisions.
+func (in *Injector) decide(op string) (decision, error) {
+	in.mu.Lock()
+	f := in.faults
+	fail := in.rng.Float64() < f.ErrorRate
+	slow := in.rng.Float64() < f.SlowRate && f.Latency > 0
+	d := decision{in: in, corrupt: in.rng.Float64() < f.CorruptRate, pick: in.rng.Int63()}
+	in.stats.Calls++
+	if slow {
+		in.stats.Delays++
+	}
+	if fail {
+		in.stats.Errors++
+	}
+	in.mu.Unlock()
+
+	if slow {
+		in.sleep(f.Latency)
+	}
+	if fail {
+		return decision{}, fmt.Errorf("%w in %s", ErrInjected, op)
+	}
+	return d, nil
+}
+
+// corrupted returns data, or a copy of it with one bit flipped if the call
+// is corrupted.
+func (d decision) corrupted(data []byte) []byte {
+	if !d.corrupt || len(data) == 0 {
+		return data
+	}
+	out := append([]byte(nil), data...)
+	bit := d.pick % int64(len(out)*8)
+	out[bit/8] ^= 1 << uint(bit%8)
+	d.in.mu.Lock()
+	d.in.stats.Corruptions++
+	d.in.mu.Unlock()
+	return out
+}
@@ -0,0 +1,76 @@
+package chaos
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFaults(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Faults
+		wantErr bool
+	}{
+		{spec: "", want: Faults{}},
+		{spec: "error=0.05, slow=0.1:2s,corrupt=1", want: Faults{ErrorRate: 0.05, SlowRate: 0.1, Latency: 2 * time.Second, CorruptRate: 1}},
+		{spec: "error=2", wantErr: true},
+		{spec: "slow=0.1", wantErr: true},
+		{spec: "slow=0.1:soon", wantErr: true},
+		{spec: "drop=0.1", wantErr: true},
+		{spec: "error", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFaults(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFaults(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseFaults(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestInjector_SeededDecisions(t *testing.T) {
+	run := func() ([]bool, Stats, []time.Duration) {
+		var slept []time.Duration
+		in, err := NewInjector(Options{
+			Faults: Faults{ErrorRate: 0.3, SlowRate: 0.5, Latency: time.Second},
+			Seed:   7,
+			Sleep:  func(d time.Duration) { slept = append(slept, d) },
+		})
+		if err != nil {
+			t.Fatalf("NewInjector() error = %v", err)
+		}
+		var failed []bool
+		for i := 0; i < 50; i++ {
+			_, err := in.decide("op")
+			if err != nil && !errors.Is(err, ErrInjected) {
+				t.Fatalf("decide() error = %v, want ErrInjected", err)
+			}
+			failed = append(failed, err != nil)
+		}
+		return failed, in.Stats(), slept
+	}
+	failed1, stats1, slept1 := run()
+	failed2, stats2, _ := run()
+	if !reflect.DeepEqual(failed1, failed2) || stats1 != stats2 {
+		t.Error("the same seed gave different decisions")
+	}
+	if stats1.Calls != 50 || stats1.Errors == 0 || stats1.Errors == 50 || stats1.Delays != len(slept1) || stats1.Delays == 0 {
+		t.Errorf("Stats() = %+v with %d sleeps, want some errors and one sleep per delay", stats1, len(slept1))
+	}
+
+	in, _ := NewInjector(Options{Faults: Faults{ErrorRate: 1}})
+	if err := in.SetFaults(Faults{}); err != nil {
+		t.Fatalf("SetFaults() error = %v", err)
+	}
+	if _, err := in.decide("op"); err != nil {
+		t.Errorf("decide() after clearing faults error = %v", err)
+	}
+	if err := in.SetFaults(Faults{Latency: -time.Second}); err == nil {
+		t.Error("SetFaults() with negative latency: expected error, got nil")
+	}
+}
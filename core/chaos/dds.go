@@ -0,0 +1,97 @@
+package chaos
+
+import (
+	"digisocialblock/core/content"
+	"digisocialblock/pkg/dds/chunking"
+)
+
+// Storage wraps a content.DDSStorage. StoreChunk and RetrieveChunk may fail
+// or be delayed, RetrieveChunk may return corrupted bytes, and ChunkExists
+// reports a chunk missing when the call is failed.
+type Storage struct {
+	next content.DDSStorage
+	in   *Injector
+}
+
+// Storage returns next wrapped with in's faults.
+func (in *Injector) Storage(next content.DDSStorage) *Storage {
+	return &Storage{next: next, in: in}
+}
+
+// StoreChunk stores the chunk unless the call is failed. Stored bytes are
+// never corrupted; corruption is injected on the way back out.
+func (s *Storage) StoreChunk(chunkID string, data []byte) error {
+	if _, err := s.in.decide("StoreChunk"); err != nil {
+		return err
+	}
+	return s.next.StoreChunk(chunkID, data)
+}
+
+// RetrieveChunk returns the chunk, possibly delayed, failed or corrupted.
+func (s *Storage) RetrieveChunk(chunkID string) ([]byte, error) {
+	d, err := s.in.decide("RetrieveChunk")
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.next.RetrieveChunk(chunkID)
+	if err != nil {
+		return nil, err
+	}
+	return d.corrupted(data), nil
+}
+
+// ChunkExists reports whether the chunk exists; a failed call reports false.
+func (s *Storage) ChunkExists(chunkID string) bool {
+	if _, err := s.in.decide("ChunkExists"); err != nil {
+		return false
+	}
+	return s.next.ChunkExists(chunkID)
+}
+
+// ManifestFetcher wraps a content.DDSManifestFetcher. A corrupted fetch
+// returns a copy of the manifest with one bit of a chunk CID flipped.
+type ManifestFetcher struct {
+	next content.DDSManifestFetcher
+	in   *Injector
+}
+
+// ManifestFetcher returns next wrapped with in's faults.
+func (in *Injector) ManifestFetcher(next content.DDSManifestFetcher) *ManifestFetcher {
+	return &ManifestFetcher{next: next, in: in}
+}
+
+// FetchManifest fetches the manifest, possibly delayed, failed or corrupted.
+func (f *ManifestFetcher) FetchManifest(manifestCID string) (*chunking.ContentManifestV1, error) {
+	d, err := f.in.decide("FetchManifest")
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := f.next.FetchManifest(manifestCID)
+	if err != nil || manifest == nil || !d.corrupt || len(manifest.Chunks) == 0 {
+		return manifest, err
+	}
+	damaged := *manifest
+	damaged.Chunks = append([]chunking.ChunkInfo(nil), manifest.Chunks...)
+	damaged.Chunks[0].ChunkCID = string(d.corrupted([]byte(damaged.Chunks[0].ChunkCID)))
+	return &damaged, nil
+}
+
+// Advertiser wraps a content.OriginatorAdvertiser, so announcing content to
+// peers may fail or be delayed.
+type Advertiser struct {
+	next content.OriginatorAdvertiser
+	in   *Injector
+}
+
+// Advertiser returns next wrapped with in's faults.
+func (in *Injector) Advertiser(next content.OriginatorAdvertiser) *Advertiser {
+	return &Advertiser{next: next, in: in}
+}
+
+// AdvertiseManifest advertises the manifest unless the call is failed.
+func (a *Advertiser) AdvertiseManifest(manifest *chunking.ContentManifestV1) error {
+	if _, err := a.in.decide("AdvertiseManifest"); err != nil {
+		return err
+	}
+	return a.next.AdvertiseManifest(manifest)
+}
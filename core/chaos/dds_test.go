@@ -0,0 +1,83 @@
+package chaos
+
+import (
+	"digisocialblock/core/content"
+	"digisocialblock/internal/testutil"
+	"digisocialblock/pkg/dds/chunking"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// newDDSTestStore stores text as two chunks and returns the store with the
+// text's manifest.
+func newDDSTestStore(t *testing.T, text string) (*testutil.DDS, *chunking.ContentManifestV1) {
+	t.Helper()
+	dds := testutil.NewDDS((len(text) + 1) / 2)
+	manifest, chunks, err := dds.Chunker.ChunkData(strings.NewReader(text))
+	if err != nil || len(chunks) != 2 {
+		t.Fatalf("ChunkData() = %d chunks, %v; want 2", len(chunks), err)
+	}
+	for _, chunk := range chunks {
+		dds.Storage.StoreChunk(chunk.ChunkCID, chunk.Data)
+	}
+	return dds, manifest
+}
+
+func TestWrappers_ExerciseRetrieverChecks(t *testing.T) {
+	const text = "resilience paths only work if they run"
+	tests := []struct {
+		name      string
+		storage   Faults
+		manifests Faults
+		wantErr   string
+	}{
+		{name: "no faults"},
+		{name: "corrupt chunks", storage: Faults{CorruptRate: 1}, wantErr: "integrity check failed"},
+		{name: "corrupt manifest", manifests: Faults{CorruptRate: 1}, wantErr: "not found"},
+		{name: "failing storage", storage: Faults{ErrorRate: 1}, wantErr: "not found in storage"},
+		{name: "failing fetcher", manifests: Faults{ErrorRate: 1}, wantErr: ErrInjected.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, manifest := newDDSTestStore(t, text)
+			chunkFaults, _ := NewInjector(Options{Faults: tt.storage})
+			manifestFaults, _ := NewInjector(Options{Faults: tt.manifests})
+			r, err := content.NewContentRetriever(manifestFaults.ManifestFetcher(store.Manifests), chunkFaults.Storage(store.Storage))
+			if err != nil {
+				t.Fatalf("NewContentRetriever() error = %v", err)
+			}
+			got, err := r.RetrieveAndVerifyTextPost(manifest.ManifestCID)
+			if tt.wantErr == "" {
+				if err != nil || got != text {
+					t.Errorf("RetrieveAndVerifyTextPost() = %q, %v; want the text", got, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("RetrieveAndVerifyTextPost() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Corruption is injected on the way out; the stored bytes stay intact.
+	store, manifest := newDDSTestStore(t, text)
+	in, _ := NewInjector(Options{Faults: Faults{CorruptRate: 1}})
+	wrapped := in.Storage(store.Storage)
+	cid := manifest.Chunks[0].ChunkCID
+	intact, _ := store.Storage.RetrieveChunk(cid)
+	if damaged, _ := wrapped.RetrieveChunk(cid); string(damaged) == string(intact) {
+		t.Error("RetrieveChunk() returned intact data at corrupt rate 1")
+	}
+	if in.Stats().Corruptions != 1 {
+		t.Errorf("Stats().Corruptions = %d, want 1", in.Stats().Corruptions)
+	}
+
+	failing, _ := NewInjector(Options{Faults: Faults{ErrorRate: 1}})
+	if err := failing.Storage(store.Storage).StoreChunk("x", []byte("x")); !errors.Is(err, ErrInjected) || store.Storage.ChunkExists("x") {
+		t.Errorf("StoreChunk() error = %v, want ErrInjected and nothing stored", err)
+	}
+	if err := failing.Advertiser(&content.SimplePlaceholderOriginator{}).AdvertiseManifest(manifest); !errors.Is(err, ErrInjected) {
+		t.Errorf("AdvertiseManifest() error = %v, want ErrInjected", err)
+	}
+}
@@ -0,0 +1,47 @@
+package chaos
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Transport wraps the http.RoundTripper used to reach peers (gateways, API
+// nodes, web hosts). A failed request returns ErrInjected without reaching
+// the network; a corrupted one has a bit of its response body flipped.
+type Transport struct {
+	next http.RoundTripper
+	in   *Injector
+}
+
+// Transport returns next wrapped with in's faults. A nil next means
+// http.DefaultTransport.
+func (in *Injector) Transport(next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{next: next, in: in}
+}
+
+// RoundTrip sends the request, possibly delayed, failed or corrupted.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	d, err := t.in.decide(req.Method + " " + req.URL.Host)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || !d.corrupt {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response to corrupt it: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(d.corrupted(body)))
+	return resp, nil
+}
@@ -0,0 +1,40 @@
+package chaos
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	const body = "peer response body"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+
+	get := func(f Faults) (string, error) {
+		in, _ := NewInjector(Options{Faults: f})
+		client := &http.Client{Transport: in.Transport(srv.Client().Transport)}
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		return string(data), err
+	}
+
+	if got, err := get(Faults{}); err != nil || got != body {
+		t.Errorf("GET without faults = %q, %v; want the body", got, err)
+	}
+	if _, err := get(Faults{ErrorRate: 1}); !errors.Is(err, ErrInjected) {
+		t.Errorf("GET at error rate 1 error = %v, want ErrInjected", err)
+	}
+	got, err := get(Faults{CorruptRate: 1})
+	if err != nil || len(got) != len(body) || got == body {
+		t.Errorf("GET at corrupt rate 1 = %q, %v; want the body with a bit flipped", got, err)
+	}
+}
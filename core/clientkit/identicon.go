@@ -0,0 +1,136 @@
+package clientkit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strings"
+)
+
+// IdenticonGrid is the number of cells along each side of an identicon.
+const IdenticonGrid = 5
+
+// MaxIdenticonPNGSize bounds the edge, in pixels, of an identicon PNG.
+const MaxIdenticonPNGSize = 1024
+
+// IdenticonBackground is the color of an identicon's empty cells.
+const IdenticonBackground = "#f0f0f0"
+
+// Identicon is the default avatar of an account with no ProfilePictureCID:
+// a grid of cells, mirrored left to right, filled in the account's accent
+// color. It is derived from the address alone, so every client draws the same
+// avatar and accent color for an account:
+//
+//   - h is the SHA-256 of the lower-cased address.
+//   - The accent color has hue (h[0]<<8 | h[1]) mod 360 degrees, saturation
+//     45 + h[2] mod 30 percent and lightness 40 + h[3] mod 20 percent, as in
+//     CSS hsl(), rounded to 8-bit RGB.
+//   - Cell (row, col) for col < 3 is filled if bit row*3+col of h[4:6] is
+//     set, counting from the least significant bit of h[4]; column col >= 3
+//     mirrors column 4-col.
+type Identicon struct {
+	Color string                             // Accent color, as "#rrggbb"
+	Cells [IdenticonGrid][IdenticonGrid]bool // [row][col]; true where filled with Color
+}
+
+// NewIdenticon derives the identicon of address.
+func NewIdenticon(address string) *Identicon {
+	h := sha256.Sum256([]byte(strings.ToLower(address)))
+	hue := float64((int(h[0])<<8 | int(h[1])) % 360)
+	r, g, b := hslToRGB(hue, float64(45+int(h[2])%30)/100, float64(40+int(h[3])%20)/100)
+	icon := &Identicon{Color: fmt.Sprintf("#%02x%02x%02x", r, g, b)}
+	half := (IdenticonGrid + 1) / 2
+	for row := 0; row < IdenticonGrid; row++ {
+		for col := 0; col < half; col++ {
+			bit := row*half + col
+			filled := h[4+bit/8]>>(bit%8)&1 == 1
+			icon.Cells[row][col] = filled
+			icon.Cells[row][IdenticonGrid-1-col] = filled
+		}
+	}
+	return icon
+}
+
+// hslToRGB converts a color from HSL, with h in degrees and s and l in
+// [0, 1], to 8-bit RGB.
+func hslToRGB(h, s, l float64) (uint8, uint8, uint8) {
+	c := (1 - math.Abs(2*l-1)) * s
+	hp := h / 60
+	x := c * (1 - math.Abs(math.Mod(hp, 2)-1))
+	var r, g, b float64
+	switch int(hp) {
+	case 0:
+		r, g = c, x
+	case 1:
+		r, g = x, c
+	case 2:
+		g, b = c, x
+	case 3:
+		g, b = x, c
+	case 4:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	m := l - c/2
+	to8 := func(v float64) uint8 { return uint8((v+m)*255 + 0.5) }
+	return to8(r), to8(g), to8(b)
+}
+
+// SVG renders the identicon as a scalable SVG document, one unit per cell.
+func (i *Identicon) SVG() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, IdenticonGrid, IdenticonGrid)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="%s"/>`, IdenticonGrid, IdenticonGrid, IdenticonBackground)
+	for row := range i.Cells {
+		for col, filled := range i.Cells[row] {
+			if filled {
+				fmt.Fprintf(&b, `<rect x="%d" y="%d" width="1" height="1" fill="%s"/>`, col, row, i.Color)
+			}
+		}
+	}
+	b.WriteString("</svg>")
+	return b.String()
+}
+
+// PNG renders the identicon as a size by size pixel PNG image. Sizes that
+// are a multiple of IdenticonGrid give cells of equal size.
+func (i *Identicon) PNG(size int) ([]byte, error) {
+	if size <= 0 || size > MaxIdenticonPNGSize {
+		return nil, fmt.Errorf("identicon size %d out of range 1-%d", size, MaxIdenticonPNGSize)
+	}
+	fg, err := parseHexColor(i.Color)
+	if err != nil {
+		return nil, err
+	}
+	bg, _ := parseHexColor(IdenticonBackground)
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{bg, fg})
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if i.Cells[y*IdenticonGrid/size][x*IdenticonGrid/size] {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode identicon PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// parseHexColor parses a "#rrggbb" color.
+func parseHexColor(s string) (color.RGBA, error) {
+	var r, g, b uint8
+	if len(s) != 7 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	if _, err := fmt.Sscanf(s, "#%02x%02x%02x", &r, &g, &b); err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q: %w", s, err)
+	}
+	return color.RGBA{R: r, G: g, B: b, A: 0xff}, nil
+}
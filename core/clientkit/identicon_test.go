@@ -0,0 +1,56 @@
+package clientkit
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestNewIdenticon_IsDeterministicAndSymmetric(t *testing.T) {
+	a, b := NewIdenticon("04abcdef"), NewIdenticon("04ABCDEF")
+	if *a != *b {
+		t.Errorf("NewIdenticon() differs by address case: %+v, %+v", a, b)
+	}
+	if other := NewIdenticon("04abcdee"); *other == *a {
+		t.Error("NewIdenticon() of different addresses is identical")
+	}
+	// Golden values: changing them changes every client's default avatars.
+	if a.Color != "#baae21" || a.Cells[0] != [IdenticonGrid]bool{false, true, true, true, false} || a.Cells[4] != [IdenticonGrid]bool{true, false, false, false, true} {
+		t.Errorf("NewIdenticon() = %+v, want the golden color and cells", a)
+	}
+	for row := range a.Cells {
+		for col := range a.Cells[row] {
+			if a.Cells[row][col] != a.Cells[row][IdenticonGrid-1-col] {
+				t.Fatalf("Cells row %d is not mirrored: %v", row, a.Cells[row])
+			}
+		}
+	}
+}
+
+func TestIdenticon_Render(t *testing.T) {
+	icon := NewIdenticon("04abcdef")
+	svg := icon.SVG()
+	if !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, icon.Color) {
+		t.Errorf("SVG() = %s, want an SVG in the accent color", svg)
+	}
+	data, err := icon.PNG(50)
+	if err != nil {
+		t.Fatalf("PNG() error = %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil || img.Bounds().Dx() != 50 {
+		t.Fatalf("PNG() decodes to %v, %v; want a 50px image", img, err)
+	}
+	for row := 0; row < IdenticonGrid; row++ {
+		for col := 0; col < IdenticonGrid; col++ {
+			_, _, b, _ := img.At(col*10+5, row*10+5).RGBA()
+			if filled := b>>8 != 0xf0; filled != icon.Cells[row][col] {
+				t.Errorf("PNG cell (%d, %d) filled = %v, want %v", row, col, filled, icon.Cells[row][col])
+			}
+		}
+	}
+	if _, err := icon.PNG(MaxIdenticonPNGSize + 1); err == nil {
+		t.Error("PNG() of an oversized image succeeded")
+	}
+}
@@ -0,0 +1,109 @@
+// Package clientkit is the dependency-light subset of the core packages that a
+// client needs to act as a user without running a node: key handling,
+// domain-separated signing, transaction construction and post and profile
+// payload serialization.
+//
+// It imports only the standard library and core/codec, so it builds for
+// GOOS=js GOARCH=wasm (see cmd/dsb-wasm) and other constrained targets. The
+// identity, ledger, social and user packages build on the same definitions, so
+// what a client signs here is exactly what a node verifies.
+package clientkit
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+)
+
+// GenerateKey generates a new P-256 private key.
+func GenerateKey() (*ecdsa.PrivateKey, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ECDSA key pair: %w", err)
+	}
+	return privateKey, nil
+}
+
+// MarshalPrivateKey serializes a private key using PKCS#8 encoding.
+func MarshalPrivateKey(priv *ecdsa.PrivateKey) ([]byte, error) {
+	if priv == nil {
+		return nil, fmt.Errorf("private key is nil")
+	}
+	derBytes, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key to PKCS#8: %w", err)
+	}
+	return derBytes, nil
+}
+
+// ParsePrivateKey parses a PKCS#8 encoded ECDSA private key.
+func ParsePrivateKey(derBytes []byte) (*ecdsa.PrivateKey, error) {
+	if len(derBytes) == 0 {
+		return nil, fmt.Errorf("private key bytes are empty")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(derBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PKCS#8 private key: %w", err)
+	}
+	ecdsaPrivKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("parsed key is not an ECDSA private key")
+	}
+	return ecdsaPrivKey, nil
+}
+
+// MarshalPublicKey serializes a public key using PKIX (SubjectPublicKeyInfo) encoding.
+func MarshalPublicKey(pub *ecdsa.PublicKey) ([]byte, error) {
+	if pub == nil {
+		return nil, fmt.Errorf("public key is nil")
+	}
+	derBytes, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key to PKIX: %w", err)
+	}
+	return derBytes, nil
+}
+
+// ParsePublicKey parses a PKIX encoded ECDSA public key.
+func ParsePublicKey(derBytes []byte) (*ecdsa.PublicKey, error) {
+	if len(derBytes) == 0 {
+		return nil, fmt.Errorf("public key bytes are empty")
+	}
+	key, err := x509.ParsePKIXPublicKey(derBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PKIX public key: %w", err)
+	}
+	ecdsaPubKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("parsed key is not an ECDSA public key")
+	}
+	return ecdsaPubKey, nil
+}
+
+// Address returns the address of a public key: the hex encoding of its PKIX
+// form. Addresses identify senders in Transaction.SenderPublicKey.
+func Address(publicKey *ecdsa.PublicKey) (string, error) {
+	if publicKey == nil {
+		return "", fmt.Errorf("public key is nil")
+	}
+	pubKeyBytes, err := MarshalPublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert public key to bytes for address: %w", err)
+	}
+	return hex.EncodeToString(pubKeyBytes), nil
+}
+
+// ParseAddress returns the public key an address encodes.
+func ParseAddress(addressHex string) (*ecdsa.PublicKey, error) {
+	if addressHex == "" {
+		return nil, fmt.Errorf("address string is empty")
+	}
+	pubKeyBytes, err := hex.DecodeString(addressHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode hex string for public key address: %w", err)
+	}
+	return ParsePublicKey(pubKeyBytes)
+}
@@ -0,0 +1,78 @@
+package clientkit
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAddress_RoundTrip(t *testing.T) {
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	address, err := Address(&priv.PublicKey)
+	if err != nil {
+		t.Fatalf("Address() error = %v", err)
+	}
+	pub, err := ParseAddress(address)
+	if err != nil {
+		t.Fatalf("ParseAddress() error = %v", err)
+	}
+	if !pub.Equal(&priv.PublicKey) {
+		t.Error("ParseAddress() returned a different public key")
+	}
+
+	der, err := MarshalPrivateKey(priv)
+	if err != nil {
+		t.Fatalf("MarshalPrivateKey() error = %v", err)
+	}
+	parsed, err := ParsePrivateKey(der)
+	if err != nil {
+		t.Fatalf("ParsePrivateKey() error = %v", err)
+	}
+	if !parsed.Equal(priv) {
+		t.Error("ParsePrivateKey() returned a different private key")
+	}
+
+	for _, bad := range []string{"", "zz", "0011"} {
+		if _, err := ParseAddress(bad); err == nil {
+			t.Errorf("ParseAddress(%q): expected error, got nil", bad)
+		}
+	}
+}
+
+// TestImports_StayLight keeps the package buildable for js/wasm: it may only
+// import the standard library and core/codec.
+func TestImports_StayLight(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := parser.ParseFile(fset, name, src, parser.ImportsOnly)
+		if err != nil {
+			t.Fatalf("parsing %s: %v", name, err)
+		}
+		for _, imp := range f.Imports {
+			path, _ := strconv.Unquote(imp.Path.Value)
+			firstElem := strings.SplitN(path, "/", 2)[0]
+			if path == "digisocialblock/core/codec" || !strings.Contains(firstElem, ".") && firstElem != "digisocialblock" {
+				continue
+			}
+			t.Errorf("%s imports %s; clientkit may only depend on the standard library and core/codec", name, path)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package clientkit
+
+import (
+	"crypto/ecdsa"
+	"digisocialblock/core/codec"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// PayloadFormat selects how a structured transaction payload is encoded.
+type PayloadFormat byte
+
+const (
+	// PayloadFormatJSON is the original format: a bare JSON object with no prefix.
+	PayloadFormatJSON PayloadFormat = 0
+	// PayloadFormatCBOR is deterministic CBOR (see package codec) prefixed with
+	// PayloadVersionCBOR. It is typically 30-50% smaller than JSON.
+	PayloadFormatCBOR PayloadFormat = 1
+)
+
+// PayloadVersionCBOR is the version byte that prefixes CBOR payloads. A JSON
+// payload always starts with '{' or whitespace, so the first byte of a payload
+// unambiguously identifies its format.
+const PayloadVersionCBOR byte = 0x01
+
+// EncodePayload encodes v in the given format.
+func EncodePayload(format PayloadFormat, v interface{}) ([]byte, error) {
+	switch format {
+	case PayloadFormatJSON:
+		return json.Marshal(v)
+	case PayloadFormatCBOR:
+		body, err := codec.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode CBOR payload: %w", err)
+		}
+		return append([]byte{PayloadVersionCBOR}, body...), nil
+	default:
+		return nil, fmt.Errorf("unknown payload format %d", format)
+	}
+}
+
+// Post is the PostCreated payload. It mirrors social.Post field for field;
+// nodes validate it with social.ValidatePostPayload.
+type Post struct {
+	AuthorPublicKey string      `json:"authorPublicKey"`
+	ContentCID      string      `json:"contentCID"`
+	Timestamp       int64       `json:"timestamp"`
+	Version         int         `json:"version"`
+	Title           string      `json:"title,omitempty"`
+	Tags            []string    `json:"tags,omitempty"`
+	GroupID         string      `json:"groupId,omitempty"`
+	KeyEpoch        int         `json:"keyEpoch,omitempty"`
+	Media           []string    `json:"media,omitempty"`
+	Origin          *PostOrigin `json:"origin,omitempty"`
+	WebSource       *WebSource  `json:"webSource,omitempty"`
+	CoAuthors       []string    `json:"coAuthors,omitempty"` // Each must co-sign (see Transaction.CoSign)
+	ContentWarning  string      `json:"contentWarning,omitempty"`
+	Sensitive       bool        `json:"sensitive,omitempty"`
+	Invite          string      `json:"invite,omitempty"` // Redeemed by a new author's first post on an invite-only chain
+}
+
+// PostOrigin mirrors social.PostOrigin.
+type PostOrigin struct {
+	Source    string `json:"source"`
+	URL       string `json:"url,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// WebSource mirrors social.WebSource.
+type WebSource struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// NewPost creates a first-version post by author for content already
+// published to DDS under contentCID.
+func NewPost(authorPublicKey, contentCID, title string, tags []string) *Post {
+	return &Post{
+		AuthorPublicKey: authorPublicKey,
+		ContentCID:      contentCID,
+		Timestamp:       time.Now().UnixNano(),
+		Version:         1,
+		Title:           title,
+		Tags:            tags,
+	}
+}
+
+// Profile is the ProfileUpdate payload. It mirrors user.Profile field for
+// field; nodes validate it with user.ValidateProfilePayload.
+type Profile struct {
+	OwnerPublicKey    string `json:"ownerPublicKey"`
+	DisplayName       string `json:"displayName"`
+	Bio               string `json:"bio,omitempty"`
+	ProfilePictureCID string `json:"profilePictureCID,omitempty"`
+	HeaderImageCID    string `json:"headerImageCID,omitempty"`
+	Timestamp         int64  `json:"timestamp"`
+	Version           int    `json:"version"`
+	BaseTxID          string `json:"baseTxId,omitempty"`
+	Invite            string `json:"invite,omitempty"`
+	Signature         []byte `json:"signature,omitempty"`
+	SigVersion        int    `json:"sigVersion,omitempty"`
+}
+
+// SignedBytes returns the bytes a profile signature covers: the
+// compact JSON encoding of the profile without its signature fields.
+func (p *Profile) SignedBytes() ([]byte, error) {
+	unsigned := *p
+	unsigned.Signature = nil
+	unsigned.SigVersion = 0
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal profile for signing: %w", err)
+	}
+	return data, nil
+}
+
+// Sign signs the profile for chainID with the owner's private key.
+func (p *Profile) Sign(privateKey *ecdsa.PrivateKey, chainID string) error {
+	if privateKey == nil {
+		return fmt.Errorf("private key is nil")
+	}
+	address, err := Address(&privateKey.PublicKey)
+	if err != nil {
+		return err
+	}
+	if address != p.OwnerPublicKey {
+		return fmt.Errorf("key address %s does not own profile of %s", address, p.OwnerPublicKey)
+	}
+	data, err := p.SignedBytes()
+	if err != nil {
+		return err
+	}
+	signature, err := Sign(privateKey, DomainProfile, chainID, data)
+	if err != nil {
+		return fmt.Errorf("failed to sign profile: %w", err)
+	}
+	p.Signature, p.SigVersion = signature, CurrentSignatureVersion
+	return nil
+}
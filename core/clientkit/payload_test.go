@@ -0,0 +1,42 @@
+package clientkit
+
+import "testing"
+
+func TestEncodePayload(t *testing.T) {
+	post := NewPost("author", "cid", "title", []string{"go"})
+	jsonPayload, err := EncodePayload(PayloadFormatJSON, post)
+	if err != nil {
+		t.Fatalf("EncodePayload(JSON) error = %v", err)
+	}
+	if jsonPayload[0] != '{' {
+		t.Errorf("JSON payload starts with %q, want '{'", jsonPayload[0])
+	}
+	cborPayload, err := EncodePayload(PayloadFormatCBOR, post)
+	if err != nil {
+		t.Fatalf("EncodePayload(CBOR) error = %v", err)
+	}
+	if cborPayload[0] != PayloadVersionCBOR {
+		t.Errorf("CBOR payload starts with 0x%02x, want 0x%02x", cborPayload[0], PayloadVersionCBOR)
+	}
+	if _, err := EncodePayload(PayloadFormat(9), post); err == nil {
+		t.Error("EncodePayload() with an unknown format: expected error, got nil")
+	}
+}
+
+func TestProfile_Sign(t *testing.T) {
+	priv, _ := GenerateKey()
+	address, _ := Address(&priv.PublicKey)
+	profile := &Profile{OwnerPublicKey: address, DisplayName: "Name", Timestamp: 1, Version: 1}
+	if err := profile.Sign(priv, DefaultChainID); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	data, _ := profile.SignedBytes()
+	if err := Verify(&priv.PublicKey, DomainProfile, profile.SigVersion, DefaultChainID, data, profile.Signature); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	other, _ := GenerateKey()
+	if err := profile.Sign(other, DefaultChainID); err == nil {
+		t.Error("Sign() with a non-owner key: expected error, got nil")
+	}
+}
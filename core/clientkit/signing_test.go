@@ -0,0 +1,28 @@
+package clientkit
+
+import "testing"
+
+func TestSignAndVerify(t *testing.T) {
+	priv, _ := GenerateKey()
+	data := []byte("signed data")
+	signature, err := Sign(priv, DomainMessage, DefaultChainID, data)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if err := Verify(&priv.PublicKey, DomainMessage, CurrentSignatureVersion, DefaultChainID, data, signature); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	other, _ := GenerateKey()
+	for name, check := range map[string]error{
+		"domain":  Verify(&priv.PublicKey, DomainTransaction, CurrentSignatureVersion, DefaultChainID, data, signature),
+		"chain":   Verify(&priv.PublicKey, DomainMessage, CurrentSignatureVersion, "dsb-testnet", data, signature),
+		"data":    Verify(&priv.PublicKey, DomainMessage, CurrentSignatureVersion, DefaultChainID, []byte("other"), signature),
+		"key":     Verify(&other.PublicKey, DomainMessage, CurrentSignatureVersion, DefaultChainID, data, signature),
+		"version": Verify(&priv.PublicKey, DomainMessage, 0, DefaultChainID, data, signature),
+	} {
+		if check == nil {
+			t.Errorf("Verify() with a different %s: expected error, got nil", name)
+		}
+	}
+}
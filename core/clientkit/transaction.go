@@ -0,0 +1,164 @@
+package clientkit
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultChainID identifies the main Digisocialblock chain.
+const DefaultChainID = "dsb-mainnet"
+
+// Transaction types a client can construct.
+const (
+	TypePostCreated   = "PostCreated"
+	TypeProfileUpdate = "ProfileUpdate"
+)
+
+// Transaction is the wire form of a ledger transaction; its JSON encoding is
+// the one nodes accept (see ledger.Transaction and the node API).
+type Transaction struct {
+	ID              string        `json:"id"`
+	Timestamp       int64         `json:"timestamp"`
+	SenderPublicKey string        `json:"senderPublicKey"`
+	Type            string        `json:"type"`
+	Payload         []byte        `json:"payload"`
+	Signature       []byte        `json:"signature"`
+	ChainID         string        `json:"chainId,omitempty"`
+	SigVersion      int           `json:"sigVersion,omitempty"`
+	Stamp           uint64        `json:"stamp,omitempty"`
+	CoSignatures    []CoSignature `json:"coSignatures,omitempty"`
+	Delegate        string        `json:"delegate,omitempty"`
+}
+
+// CoSignature is a co-signer's signature of a transaction; it mirrors
+// ledger.CoSignature.
+type CoSignature struct {
+	PublicKey string `json:"publicKey"`
+	Signature []byte `json:"signature"`
+}
+
+// TransactionIDInput is the canonical string a transaction ID is the hash of:
+// the timestamp, sender, type and hex payload joined by "|".
+func TransactionIDInput(timestamp int64, senderPublicKey, txType string, payload []byte) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d", timestamp))
+	sb.WriteString("|")
+	sb.WriteString(senderPublicKey)
+	sb.WriteString("|")
+	sb.WriteString(txType)
+	sb.WriteString("|")
+	sb.WriteString(hex.EncodeToString(payload))
+	return sb.String()
+}
+
+// TransactionID returns the ID of a transaction with the given content.
+func TransactionID(timestamp int64, senderPublicKey, txType string, payload []byte) string {
+	sum := sha256.Sum256([]byte(TransactionIDInput(timestamp, senderPublicKey, txType, payload)))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewTransaction creates an unsigned transaction stamped with the current
+// time, for chainID (DefaultChainID if empty).
+func NewTransaction(senderPublicKey, txType string, payload []byte, chainID string) (*Transaction, error) {
+	if senderPublicKey == "" {
+		return nil, fmt.Errorf("sender public key cannot be empty")
+	}
+	if txType == "" {
+		return nil, fmt.Errorf("transaction type cannot be empty")
+	}
+	if chainID == "" {
+		chainID = DefaultChainID
+	}
+	tx := &Transaction{
+		Timestamp:       time.Now().UnixNano(),
+		SenderPublicKey: senderPublicKey,
+		Type:            txType,
+		Payload:         payload,
+		ChainID:         chainID,
+	}
+	tx.ID = TransactionID(tx.Timestamp, tx.SenderPublicKey, tx.Type, tx.Payload)
+	return tx, nil
+}
+
+// Sign signs the transaction with privateKey, which must belong to the sender.
+func (tx *Transaction) Sign(privateKey *ecdsa.PrivateKey) error {
+	if tx.ID == "" {
+		return fmt.Errorf("transaction ID is empty, cannot sign")
+	}
+	if privateKey == nil {
+		return fmt.Errorf("private key is nil, cannot sign")
+	}
+	address, err := Address(&privateKey.PublicKey)
+	if err != nil {
+		return err
+	}
+	if address != tx.SenderPublicKey {
+		return fmt.Errorf("transaction SenderPublicKey %s does not match signing key address %s", tx.SenderPublicKey, address)
+	}
+	signature, err := Sign(privateKey, DomainTransaction, tx.ChainID, []byte(tx.ID))
+	if err != nil {
+		return err
+	}
+	tx.Signature, tx.SigVersion = signature, CurrentSignatureVersion
+	return nil
+}
+
+// SignAsDelegate signs the transaction with privateKey, an app key the
+// sender has authorized to sign transactions of this type with a KeyDelegated
+// transaction, and records the key's address as the Delegate. Nodes reject
+// the transaction if the delegation does not cover it.
+func (tx *Transaction) SignAsDelegate(privateKey *ecdsa.PrivateKey) error {
+	if tx.ID == "" {
+		return fmt.Errorf("transaction ID is empty, cannot sign")
+	}
+	if privateKey == nil {
+		return fmt.Errorf("private key is nil, cannot sign")
+	}
+	address, err := Address(&privateKey.PublicKey)
+	if err != nil {
+		return err
+	}
+	if address == tx.SenderPublicKey {
+		return fmt.Errorf("signing key belongs to the sender, not a delegate")
+	}
+	signature, err := Sign(privateKey, DomainTransaction, tx.ChainID, []byte(tx.ID))
+	if err != nil {
+		return err
+	}
+	tx.Signature, tx.SigVersion, tx.Delegate = signature, CurrentSignatureVersion, address
+	return nil
+}
+
+// CoSign adds a co-signature by privateKey, replacing any earlier one by the
+// same key. Nodes reject a transaction unless it carries exactly one
+// co-signature from each co-signer its payload requires, such as the
+// co-authors of a post. Co-signers may sign before or after the sender.
+func (tx *Transaction) CoSign(privateKey *ecdsa.PrivateKey) error {
+	if tx.ID == "" {
+		return fmt.Errorf("transaction ID is empty, cannot co-sign")
+	}
+	if privateKey == nil {
+		return fmt.Errorf("private key is nil, cannot co-sign")
+	}
+	address, err := Address(&privateKey.PublicKey)
+	if err != nil {
+		return err
+	}
+	signature, err := Sign(privateKey, DomainTransaction, tx.ChainID, []byte(tx.ID))
+	if err != nil {
+		return err
+	}
+	tx.SigVersion = CurrentSignatureVersion
+	for i := range tx.CoSignatures {
+		if tx.CoSignatures[i].PublicKey == address {
+			tx.CoSignatures[i].Signature = signature
+			return nil
+		}
+	}
+	tx.CoSignatures = append(tx.CoSignatures, CoSignature{PublicKey: address, Signature: signature})
+	return nil
+}
@@ -0,0 +1,62 @@
+package clientkit
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTransaction_Sign(t *testing.T) {
+	priv, _ := GenerateKey()
+	address, _ := Address(&priv.PublicKey)
+	tx, err := NewTransaction(address, TypePostCreated, []byte(`{"k":"v"}`), "")
+	if err != nil {
+		t.Fatalf("NewTransaction() error = %v", err)
+	}
+	if tx.ChainID != DefaultChainID {
+		t.Errorf("ChainID = %q, want %q", tx.ChainID, DefaultChainID)
+	}
+	if tx.ID != TransactionID(tx.Timestamp, tx.SenderPublicKey, tx.Type, tx.Payload) {
+		t.Error("ID does not match the transaction content")
+	}
+	if err := tx.Sign(priv); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if tx.SigVersion != CurrentSignatureVersion {
+		t.Errorf("SigVersion = %d, want %d", tx.SigVersion, CurrentSignatureVersion)
+	}
+	if err := Verify(&priv.PublicKey, DomainTransaction, tx.SigVersion, tx.ChainID, []byte(tx.ID), tx.Signature); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if _, err := json.Marshal(tx); err != nil {
+		t.Errorf("json.Marshal() error = %v", err)
+	}
+
+	other, _ := GenerateKey()
+	if err := tx.Sign(other); err == nil {
+		t.Error("Sign() with a key that is not the sender's: expected error, got nil")
+	}
+	if _, err := NewTransaction("", TypePostCreated, nil, ""); err == nil {
+		t.Error("NewTransaction() with no sender: expected error, got nil")
+	}
+}
+
+func TestTransaction_SignAsDelegate(t *testing.T) {
+	owner, _ := GenerateKey()
+	ownerAddress, _ := Address(&owner.PublicKey)
+	app, _ := GenerateKey()
+	appAddress, _ := Address(&app.PublicKey)
+	tx, _ := NewTransaction(ownerAddress, TypePostCreated, []byte(`{"k":"v"}`), "")
+
+	if err := tx.SignAsDelegate(app); err != nil {
+		t.Fatalf("SignAsDelegate() error = %v", err)
+	}
+	if tx.Delegate != appAddress || tx.SenderPublicKey != ownerAddress {
+		t.Errorf("Delegate = %q, sender %q; want the app key signing for the owner", tx.Delegate, tx.SenderPublicKey)
+	}
+	if err := Verify(&app.PublicKey, DomainTransaction, tx.SigVersion, tx.ChainID, []byte(tx.ID), tx.Signature); err != nil {
+		t.Errorf("Verify() with the app key error = %v", err)
+	}
+	if err := tx.SignAsDelegate(owner); err == nil {
+		t.Error("SignAsDelegate() with the sender's key: expected error, got nil")
+	}
+}
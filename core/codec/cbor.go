@@ -0,0 +1,545 @@
+// Package codec implements the deterministic CBOR encoding (RFC 8949 section
+// 4.2.1, "core deterministic encoding") used for compact on-chain payloads and
+// manifests.
+//
+// Structs are encoded as maps keyed by their JSON field names, honouring
+// `json:"name,omitempty"` and `json:"-"`, so a type has the same field names in
+// JSON and CBOR. Only the types the ledger needs are supported: booleans,
+// integers, strings, byte slices, slices, arrays, string-keyed maps, structs
+// and pointers. Floats, tags and indefinite-length items are not.
+//
+// Decoding is strict: every item must already be in deterministic form (shortest
+// integer encodings, map keys in canonical order, no duplicates), struct maps
+// must contain exactly the fields Marshal would write (no unknown fields, no
+// missing required ones, no empty omitempty ones), trailing bytes are rejected
+// and nesting depth is bounded. Each value therefore has exactly one accepted
+// encoding, which keeps hashes over encoded data stable.
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrMalformed is returned for input that is not valid deterministic CBOR for
+// the target type. It carries no error code, so that clientkit, which builds on
+// this package, stays free of the registry; the ledger attaches
+// DSB-CODEC-001 to it (see ledger.CodeMalformedCBOR).
+var ErrMalformed = errors.New("malformed CBOR")
+
+// MaxDepth bounds the nesting of arrays and maps accepted by Unmarshal.
+const MaxDepth = 16
+
+// CBOR major types.
+const (
+	majorUint   = 0
+	majorNegInt = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+)
+
+// Simple values.
+const (
+	simpleFalse = 0xf4
+	simpleTrue  = 0xf5
+	simpleNull  = 0xf6
+)
+
+// Marshal returns the deterministic CBOR encoding of v.
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeValue(&buf, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes deterministic CBOR data into v, which must be a non-nil pointer.
+func Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("codec: Unmarshal target must be a non-nil pointer, got %T", v)
+	}
+	d := &decoder{data: data}
+	if err := d.decodeValue(rv.Elem(), 0); err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return fmt.Errorf("%w: %d trailing bytes", ErrMalformed, len(d.data)-d.pos)
+	}
+	return nil
+}
+
+// --- Encoding ---
+
+func writeHead(buf *bytes.Buffer, major byte, n uint64) {
+	m := major << 5
+	switch {
+	case n < 24:
+		buf.WriteByte(m | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(m | 24)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(m | 25)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		buf.WriteByte(m | 26)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	default:
+		buf.WriteByte(m | 27)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+func encodeValue(buf *bytes.Buffer, v reflect.Value) error {
+	if !v.IsValid() {
+		buf.WriteByte(simpleNull)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteByte(simpleNull)
+			return nil
+		}
+		return encodeValue(buf, v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			buf.WriteByte(simpleTrue)
+		} else {
+			buf.WriteByte(simpleFalse)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n := v.Int(); n >= 0 {
+			writeHead(buf, majorUint, uint64(n))
+		} else {
+			writeHead(buf, majorNegInt, uint64(-(n + 1)))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		writeHead(buf, majorUint, v.Uint())
+	case reflect.String:
+		s := v.String()
+		if !utf8.ValidString(s) {
+			return fmt.Errorf("codec: string is not valid UTF-8")
+		}
+		writeHead(buf, majorText, uint64(len(s)))
+		buf.WriteString(s)
+	case reflect.Slice:
+		if v.IsNil() {
+			buf.WriteByte(simpleNull)
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			writeHead(buf, majorBytes, uint64(v.Len()))
+			buf.Write(v.Bytes())
+			return nil
+		}
+		return encodeArray(buf, v)
+	case reflect.Array:
+		return encodeArray(buf, v)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("codec: unsupported map key type %s", v.Type().Key())
+		}
+		if v.IsNil() {
+			buf.WriteByte(simpleNull)
+			return nil
+		}
+		entries := make([]mapEntry, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			entries = append(entries, mapEntry{key: iter.Key().String(), value: iter.Value()})
+		}
+		return encodeMap(buf, entries)
+	case reflect.Struct:
+		fields := cachedFields(v.Type())
+		entries := make([]mapEntry, 0, len(fields))
+		for _, f := range fields {
+			fv := v.Field(f.index)
+			if f.omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+			entries = append(entries, mapEntry{key: f.name, value: fv})
+		}
+		return encodeMap(buf, entries)
+	default:
+		return fmt.Errorf("codec: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func encodeArray(buf *bytes.Buffer, v reflect.Value) error {
+	writeHead(buf, majorArray, uint64(v.Len()))
+	for i := 0; i < v.Len(); i++ {
+		if err := encodeValue(buf, v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type mapEntry struct {
+	key   string
+	value reflect.Value
+}
+
+// encodeMap writes entries with keys in canonical order: bytewise order of
+// their encodings, which for text keys is shorter first, then lexicographic.
+func encodeMap(buf *bytes.Buffer, entries []mapEntry) error {
+	sort.Slice(entries, func(i, j int) bool { return keyLess(entries[i].key, entries[j].key) })
+	writeHead(buf, majorMap, uint64(len(entries)))
+	for _, e := range entries {
+		if err := encodeValue(buf, reflect.ValueOf(e.key)); err != nil {
+			return err
+		}
+		if err := encodeValue(buf, e.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func keyLess(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+// isEmptyValue mirrors encoding/json's omitempty rules.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// --- Struct field metadata ---
+
+type fieldInfo struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // reflect.Type -> []fieldInfo
+
+func cachedFields(t reflect.Type) []fieldInfo {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]fieldInfo)
+	}
+	var fields []fieldInfo
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" { // Unexported
+			continue
+		}
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, fieldInfo{name: name, index: i, omitEmpty: strings.Contains(","+opts+",", ",omitempty,")})
+	}
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// --- Decoding ---
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w at offset %d: %s", ErrMalformed, d.pos, fmt.Sprintf(format, args...))
+}
+
+// readHead reads an item head, rejecting indefinite lengths and non-shortest
+// argument encodings.
+func (d *decoder) readHead() (major byte, n uint64, err error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, d.errorf("unexpected end of input")
+	}
+	ib := d.data[d.pos]
+	d.pos++
+	major, info := ib>>5, ib&0x1f
+	if major == majorSimple {
+		return major, uint64(ib), nil
+	}
+	var size int
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, d.errorf("indefinite-length or reserved item")
+	}
+	if len(d.data)-d.pos < size {
+		return 0, 0, d.errorf("unexpected end of input")
+	}
+	raw := d.data[d.pos : d.pos+size]
+	d.pos += size
+	switch size {
+	case 1:
+		n = uint64(raw[0])
+	case 2:
+		n = uint64(binary.BigEndian.Uint16(raw))
+	case 4:
+		n = uint64(binary.BigEndian.Uint32(raw))
+	case 8:
+		n = binary.BigEndian.Uint64(raw)
+	}
+	minimal := (size == 1 && n >= 24) || (size == 2 && n > math.MaxUint8) ||
+		(size == 4 && n > math.MaxUint16) || (size == 8 && n > math.MaxUint32)
+	if !minimal {
+		return 0, 0, d.errorf("non-shortest integer encoding")
+	}
+	return major, n, nil
+}
+
+// readLength validates that n items of at least one byte each can fit in the
+// remaining input, so hostile lengths cannot trigger huge allocations.
+func (d *decoder) readLength(n uint64) (int, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return 0, d.errorf("length %d exceeds remaining input", n)
+	}
+	return int(n), nil
+}
+
+func (d *decoder) decodeValue(v reflect.Value, depth int) error {
+	if depth > MaxDepth {
+		return d.errorf("nesting deeper than %d", MaxDepth)
+	}
+	start := d.pos
+	major, n, err := d.readHead()
+	if err != nil {
+		return err
+	}
+
+	if major == majorSimple {
+		switch n {
+		case simpleNull:
+			switch v.Kind() {
+			case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+				v.Set(reflect.Zero(v.Type()))
+				return nil
+			}
+			return d.errorf("cannot decode null into %s", v.Type())
+		case simpleFalse, simpleTrue:
+			if v.Kind() != reflect.Bool {
+				return d.errorf("cannot decode boolean into %s", v.Type())
+			}
+			v.SetBool(n == simpleTrue)
+			return nil
+		default:
+			return d.errorf("unsupported simple value or float 0x%02x", n)
+		}
+	}
+
+	if v.Kind() == reflect.Ptr {
+		d.pos = start
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decodeValue(v.Elem(), depth)
+	}
+
+	switch major {
+	case majorUint, majorNegInt:
+		return d.decodeInt(v, major, n)
+	case majorBytes:
+		length, err := d.readLength(n)
+		if err != nil {
+			return err
+		}
+		if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Uint8 {
+			return d.errorf("cannot decode byte string into %s", v.Type())
+		}
+		v.SetBytes(append([]byte{}, d.data[d.pos:d.pos+length]...))
+		d.pos += length
+		return nil
+	case majorText:
+		s, err := d.readText(n)
+		if err != nil {
+			return err
+		}
+		if v.Kind() != reflect.String {
+			return d.errorf("cannot decode text string into %s", v.Type())
+		}
+		v.SetString(s)
+		return nil
+	case majorArray:
+		length, err := d.readLength(n)
+		if err != nil {
+			return err
+		}
+		switch v.Kind() {
+		case reflect.Slice:
+			v.Set(reflect.MakeSlice(v.Type(), length, length))
+		case reflect.Array:
+			if length != v.Len() {
+				return d.errorf("array of %d items for %s", length, v.Type())
+			}
+		default:
+			return d.errorf("cannot decode array into %s", v.Type())
+		}
+		for i := 0; i < length; i++ {
+			if err := d.decodeValue(v.Index(i), depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case majorMap:
+		return d.decodeMap(v, n, depth)
+	case majorTag:
+		return d.errorf("tags are not supported")
+	}
+	return d.errorf("unexpected major type %d", major)
+}
+
+func (d *decoder) decodeInt(v reflect.Value, major byte, n uint64) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n > math.MaxInt64 {
+			return d.errorf("integer overflows %s", v.Type())
+		}
+		i := int64(n)
+		if major == majorNegInt {
+			i = -1 - i
+		}
+		if v.OverflowInt(i) {
+			return d.errorf("integer overflows %s", v.Type())
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if major == majorNegInt || v.OverflowUint(n) {
+			return d.errorf("integer out of range for %s", v.Type())
+		}
+		v.SetUint(n)
+	default:
+		return d.errorf("cannot decode integer into %s", v.Type())
+	}
+	return nil
+}
+
+func (d *decoder) readText(n uint64) (string, error) {
+	length, err := d.readLength(n)
+	if err != nil {
+		return "", err
+	}
+	raw := d.data[d.pos : d.pos+length]
+	if !utf8.Valid(raw) {
+		return "", d.errorf("text string is not valid UTF-8")
+	}
+	d.pos += length
+	return string(raw), nil
+}
+
+func (d *decoder) decodeMap(v reflect.Value, n uint64, depth int) error {
+	length, err := d.readLength(n)
+	if err != nil {
+		return err
+	}
+	var fields map[string]fieldInfo
+	switch v.Kind() {
+	case reflect.Struct:
+		fields = make(map[string]fieldInfo)
+		for _, f := range cachedFields(v.Type()) {
+			fields[f.name] = f
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return d.errorf("cannot decode map into %s", v.Type())
+		}
+		v.Set(reflect.MakeMapWithSize(v.Type(), length))
+	default:
+		return d.errorf("cannot decode map into %s", v.Type())
+	}
+
+	prevKey := ""
+	required := 0 // Non-omitempty struct fields seen
+	for i := 0; i < length; i++ {
+		major, kn, err := d.readHead()
+		if err != nil {
+			return err
+		}
+		if major != majorText {
+			return d.errorf("map key is not a text string")
+		}
+		key, err := d.readText(kn)
+		if err != nil {
+			return err
+		}
+		if i > 0 && !keyLess(prevKey, key) {
+			return d.errorf("map key %q is duplicate or out of canonical order", key)
+		}
+		prevKey = key
+
+		if v.Kind() == reflect.Map {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := d.decodeValue(elem, depth+1); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+			continue
+		}
+		f, ok := fields[key]
+		if !ok {
+			return d.errorf("unknown field %q for %s", key, v.Type())
+		}
+		fv := v.Field(f.index)
+		if err := d.decodeValue(fv, depth+1); err != nil {
+			return err
+		}
+		if f.omitEmpty && isEmptyValue(fv) {
+			return d.errorf("empty value for omitempty field %q", key)
+		}
+		if !f.omitEmpty {
+			required++
+		}
+	}
+	if v.Kind() == reflect.Struct {
+		for _, f := range fields {
+			if !f.omitEmpty {
+				required--
+			}
+		}
+		if required != 0 {
+			return d.errorf("missing required fields for %s", v.Type())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package codec
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMarshal_RFC8949Vectors(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{0, "00"},
+		{23, "17"},
+		{24, "1818"},
+		{255, "18ff"},
+		{256, "190100"},
+		{65536, "1a00010000"},
+		{uint64(1) << 32, "1b0000000100000000"},
+		{-1, "20"},
+		{-1000, "3903e7"},
+		{false, "f4"},
+		{true, "f5"},
+		{"", "60"},
+		{"IETF", "6449455446"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{[]int{1, 2, 3}, "83010203"},
+		{map[string]int{"b": 2, "a": 1, "aa": 3}, "a3616101616202626161" + "03"},
+	}
+	for _, tt := range tests {
+		got, err := Marshal(tt.value)
+		if err != nil {
+			t.Errorf("Marshal(%v) error = %v", tt.value, err)
+			continue
+		}
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("Marshal(%v) = %x, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+type inner struct {
+	CID  string `json:"cid"`
+	Size int64  `json:"size"`
+}
+
+type sample struct {
+	Name     string            `json:"name"`
+	Count    int               `json:"count"`
+	Negative int64             `json:"negative"`
+	Flag     bool              `json:"flag,omitempty"`
+	Data     []byte            `json:"data,omitempty"`
+	Tags     []string          `json:"tags,omitempty"`
+	Items    []inner           `json:"items"`
+	Ptr      *inner            `json:"ptr,omitempty"`
+	Extra    map[string]string `json:"extra,omitempty"`
+	Skipped  string            `json:"-"`
+	hidden   int
+}
+
+func TestMarshalUnmarshal_RoundTrip(t *testing.T) {
+	in := sample{
+		Name:     "héllo",
+		Count:    1 << 40,
+		Negative: -42,
+		Flag:     true,
+		Data:     []byte{0, 1, 2},
+		Tags:     []string{"x", "y"},
+		Items:    []inner{{CID: "a", Size: 1}, {CID: "b", Size: 300}},
+		Ptr:      &inner{CID: "p"},
+		Extra:    map[string]string{"k": "v"},
+	}
+	data, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var out sample
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+
+	// Encoding is deterministic and omits empty fields.
+	again, _ := Marshal(out)
+	if string(again) != string(data) {
+		t.Error("re-encoding a decoded value changed its bytes")
+	}
+	minimal, _ := Marshal(sample{Name: "n"})
+	if hex.EncodeToString(minimal) != "a4"+"646e616d65616e"+"65636f756e7400"+"656974656d73f6"+"686e6567617469766500" {
+		t.Errorf("Marshal(minimal) = %x", minimal)
+	}
+}
+
+func TestUnmarshal_Strictness(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+	}{
+		{"non-shortest integer", "a165636f756e741801"},
+		{"indefinite-length array", "9f01ff"},
+		{"unknown field", "a1636269671801"},
+		{"missing required fields", "a0"},
+		{"duplicate key", "a2646e616d6561616e616d656162"},
+		{"keys out of order", "a2656974656d73f6646e616d656161"},
+		{"trailing bytes", "a0" + "00"},
+		{"truncated", "6449"},
+		{"tag", "c001"},
+		{"float", "f93c00"},
+		{"invalid UTF-8", "a1646e616d6561ff"},
+		{"huge length", "5b00000000ffffffff"},
+		{"type mismatch", "a1646e616d6501"},
+		{"null for a non-nullable field", "a1646e616d65f6"},
+		{"explicit empty omitempty field", "a164666c6167f4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+			var s sample
+			if err := Unmarshal(data, &s); !errors.Is(err, ErrMalformed) {
+				t.Errorf("Unmarshal(%s) error = %v, want ErrMalformed", tt.hex, err)
+			}
+		})
+	}
+
+	deep := make([]byte, 0, MaxDepth+2)
+	for i := 0; i <= MaxDepth+1; i++ {
+		deep = append(deep, 0x81)
+	}
+	deep = append(deep, 0x00)
+	var v interface{} = &[]interface{}{}
+	if err := Unmarshal(deep, v); err == nil {
+		t.Error("Unmarshal() accepted input nested deeper than MaxDepth")
+	}
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	seed, _ := Marshal(sample{Name: "n", Items: []inner{{CID: "c", Size: 1}}})
+	f.Add(seed)
+	f.Add([]byte{0x9f, 0x01, 0xff})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var s sample
+		if err := Unmarshal(data, &s); err != nil {
+			return
+		}
+		// Accepted input is deterministic, so it must re-encode to the same bytes.
+		again, err := Marshal(s)
+		if err != nil {
+			t.Fatalf("Marshal() of accepted value error = %v", err)
+		}
+		if string(again) != string(data) {
+			t.Fatalf("accepted non-canonical encoding %x (canonical %x)", data, again)
+		}
+	})
+}
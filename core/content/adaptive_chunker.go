@@ -0,0 +1,140 @@
+package content
+
+import (
+	"bytes"
+	"digisocialblock/pkg/dds/chunking"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// ChunkSizeTier maps content up to MaxContentSize bytes to a chunk size.
+type ChunkSizeTier struct {
+	MaxContentSize int64 // Inclusive upper bound of the tier
+	ChunkSize      int
+}
+
+// ChunkSizePolicy selects a chunk size from the total size of the content.
+// Content larger than every tier uses LargestChunkSize.
+type ChunkSizePolicy struct {
+	Tiers            []ChunkSizeTier
+	LargestChunkSize int
+}
+
+// DefaultChunkSizePolicy keeps typical text posts in a single chunk while
+// limiting the number of chunks (and manifest entries) for large media.
+func DefaultChunkSizePolicy() ChunkSizePolicy {
+	return ChunkSizePolicy{
+		Tiers: []ChunkSizeTier{
+			{MaxContentSize: 256 << 10, ChunkSize: 256 << 10}, // <= 256 KiB: single chunk
+			{MaxContentSize: 16 << 20, ChunkSize: 1 << 20},    // <= 16 MiB: 1 MiB chunks
+			{MaxContentSize: 256 << 20, ChunkSize: 4 << 20},   // <= 256 MiB: 4 MiB chunks
+		},
+		LargestChunkSize: 8 << 20,
+	}
+}
+
+// Validate checks that tiers are ordered by size and all chunk sizes are positive.
+func (p ChunkSizePolicy) Validate() error {
+	if p.LargestChunkSize <= 0 {
+		return fmt.Errorf("largest chunk size must be positive, got %d", p.LargestChunkSize)
+	}
+	for i, tier := range p.Tiers {
+		if tier.ChunkSize <= 0 {
+			return fmt.Errorf("tier %d has non-positive chunk size %d", i, tier.ChunkSize)
+		}
+		if i > 0 && tier.MaxContentSize <= p.Tiers[i-1].MaxContentSize {
+			return fmt.Errorf("tier %d max content size %d is not greater than tier %d", i, tier.MaxContentSize, i-1)
+		}
+	}
+	return nil
+}
+
+// ChunkSizeFor returns the chunk size to use for content of the given size.
+func (p ChunkSizePolicy) ChunkSizeFor(contentSize int64) int {
+	i := sort.Search(len(p.Tiers), func(i int) bool { return p.Tiers[i].MaxContentSize >= contentSize })
+	if i < len(p.Tiers) {
+		return p.Tiers[i].ChunkSize
+	}
+	return p.LargestChunkSize
+}
+
+// ChunkerFactory builds a DDSChunker that splits content into chunks of chunkSize bytes.
+type ChunkerFactory func(chunkSize int) (DDSChunker, error)
+
+// AdaptiveChunker is a DDSChunker that picks the chunk size for each piece of
+// content from a ChunkSizePolicy and delegates the actual chunking (and CID
+// generation) to a chunker created for that size.
+//
+// Identical content always falls into the same tier and therefore produces the
+// same chunks and manifest CID, so storage-level deduplication is unaffected.
+// The selected size is recorded in the manifest as the size of the first chunk
+// (see ManifestChunkSize), since every chunk but the last has that size.
+type AdaptiveChunker struct {
+	policy     ChunkSizePolicy
+	newChunker ChunkerFactory
+	mu         sync.Mutex
+	chunkers   map[int]DDSChunker // Cached per chunk size
+}
+
+// NewAdaptiveChunker creates an AdaptiveChunker.
+func NewAdaptiveChunker(policy ChunkSizePolicy, factory ChunkerFactory) (*AdaptiveChunker, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid chunk size policy: %w", err)
+	}
+	if factory == nil {
+		return nil, fmt.Errorf("chunker factory cannot be nil")
+	}
+	return &AdaptiveChunker{
+		policy:     policy,
+		newChunker: factory,
+		chunkers:   make(map[int]DDSChunker),
+	}, nil
+}
+
+// ChunkData reads all of data, selects a chunk size for its length and chunks it.
+func (ac *AdaptiveChunker) ChunkData(data io.Reader) (*chunking.ContentManifestV1, []chunking.DataChunk, error) {
+	// The total size must be known before a tier can be chosen.
+	// Chunk data returned by the delegate may alias this buffer, so it is not pooled.
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(data); err != nil {
+		return nil, nil, fmt.Errorf("adaptive chunker failed to read data: %w", err)
+	}
+
+	chunker, err := ac.chunkerFor(ac.policy.ChunkSizeFor(int64(buf.Len())))
+	if err != nil {
+		return nil, nil, err
+	}
+	return chunker.ChunkData(bytes.NewReader(buf.Bytes()))
+}
+
+// ChunkSizeFor returns the chunk size the policy selects for content of the
+// given size, making an AdaptiveChunker a ChunkSizer.
+func (ac *AdaptiveChunker) ChunkSizeFor(contentSize int64) int {
+	return ac.policy.ChunkSizeFor(contentSize)
+}
+
+// chunkerFor returns the cached delegate chunker for chunkSize, creating it on first use.
+func (ac *AdaptiveChunker) chunkerFor(chunkSize int) (DDSChunker, error) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	if chunker, ok := ac.chunkers[chunkSize]; ok {
+		return chunker, nil
+	}
+	chunker, err := ac.newChunker(chunkSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chunker for chunk size %d: %w", chunkSize, err)
+	}
+	ac.chunkers[chunkSize] = chunker
+	return chunker, nil
+}
+
+// ManifestChunkSize returns the chunk size a manifest was produced with,
+// or 0 for a manifest without chunks.
+func ManifestChunkSize(manifest *chunking.ContentManifestV1) int64 {
+	if manifest == nil || len(manifest.Chunks) == 0 {
+		return 0
+	}
+	return manifest.Chunks[0].Size
+}
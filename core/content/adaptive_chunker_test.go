@@ -0,0 +1,83 @@
+package content
+
+import (
+	"digisocialblock/internal/testutil"
+	"strings"
+	"testing"
+)
+
+func testTierPolicy() ChunkSizePolicy {
+	return ChunkSizePolicy{
+		Tiers: []ChunkSizeTier{
+			{MaxContentSize: 100, ChunkSize: 100},
+			{MaxContentSize: 1000, ChunkSize: 64},
+		},
+		LargestChunkSize: 512,
+	}
+}
+
+func TestChunkSizePolicy_ChunkSizeFor(t *testing.T) {
+	p := testTierPolicy()
+	cases := map[int64]int{0: 100, 100: 100, 101: 64, 1000: 64, 1001: 512, 1 << 20: 512}
+	for size, want := range cases {
+		if got := p.ChunkSizeFor(size); got != want {
+			t.Errorf("ChunkSizeFor(%d) = %d, want %d", size, got, want)
+		}
+	}
+	if err := DefaultChunkSizePolicy().Validate(); err != nil {
+		t.Errorf("DefaultChunkSizePolicy().Validate() error = %v", err)
+	}
+
+	bad := ChunkSizePolicy{Tiers: []ChunkSizeTier{{MaxContentSize: 10, ChunkSize: 5}, {MaxContentSize: 10, ChunkSize: 5}}, LargestChunkSize: 5}
+	if err := bad.Validate(); err == nil {
+		t.Error("Validate() with unordered tiers: expected error, got nil")
+	}
+}
+
+func TestAdaptiveChunker_DedupAndRetrievalAcrossTiers(t *testing.T) {
+	dds := testutil.NewDDS(0)
+	adaptive, err := NewAdaptiveChunker(testTierPolicy(), func(chunkSize int) (DDSChunker, error) {
+		return &testutil.Chunker{ChunkSize: chunkSize, Manifests: dds.Manifests}, nil
+	})
+	if err != nil {
+		t.Fatalf("NewAdaptiveChunker() error = %v", err)
+	}
+	storage := dds.Storage
+	publisher, _ := NewContentPublisher(adaptive, storage, dds.Originator)
+	retriever, _ := NewContentRetriever(dds.Manifests, storage)
+
+	tests := []struct {
+		name          string
+		body          string
+		wantChunkSize int64
+		wantChunks    int
+	}{
+		{"small tier", strings.Repeat("s", 80), 80, 1},
+		{"medium tier", strings.Repeat("m", 600), 64, 10},
+		{"largest tier", strings.Repeat("l", 3000), 512, 6},
+	}
+	for _, tt := range tests {
+		cid, err := publisher.PublishTextPostToDDS(tt.body)
+		if err != nil {
+			t.Fatalf("%s: PublishTextPostToDDS() error = %v", tt.name, err)
+		}
+		manifest, _ := dds.Manifests.FetchManifest(cid)
+		if got := ManifestChunkSize(manifest); got != tt.wantChunkSize || len(manifest.Chunks) != tt.wantChunks {
+			t.Errorf("%s: manifest has %d chunks of %d bytes, want %d of %d", tt.name, len(manifest.Chunks), got, tt.wantChunks, tt.wantChunkSize)
+		}
+
+		distinct := storage.Len()
+		again, _ := publisher.PublishTextPostToDDS(tt.body)
+		if again != cid {
+			t.Errorf("%s: republishing produced CID %s, want %s", tt.name, again, cid)
+		}
+		if storage.Len() != distinct {
+			t.Errorf("%s: republishing added %d new distinct chunks", tt.name, storage.Len()-distinct)
+		}
+
+		got, err := retriever.RetrieveAndVerifyTextPost(cid)
+		if err != nil || got != tt.body {
+			t.Errorf("%s: retrieval returned %d bytes, %v; want the original %d bytes", tt.name, len(got), err, len(tt.body))
+		}
+	}
+}
@@ -0,0 +1,540 @@
+package content
+
+import (
+	"context"
+	"crypto/sha256"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/pkg/dds/chunking"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Storage attestation limits and defaults.
+const (
+	MaxAttestationProofs     = 16  // Manifests one attestation covers
+	MaxAttestedCIDLength     = 128 // Bytes
+	AttestationSliceSize     = 64  // Bytes of a chunk a proof hashes
+	DefaultAttestationWindow = 64  // Blocks an attestation may trail its challenge block
+	DefaultAttestorInterval  = time.Hour
+)
+
+// ErrAttestationDiverged is returned by AttestationIndex.Sync when the block
+// recorded as the high-water mark no longer matches the chain.
+var ErrAttestationDiverged = errors.New("attestation index high-water mark does not match the chain")
+
+func init() {
+	ledger.RegisterPayloadValidator(ledger.StorageAttested, ValidateAttestationPayload)
+	ledger.RegisterPayloadDecoder(ledger.StorageAttested, ledger.DecodeInto(func() interface{} { return &AttestationPayload{} }))
+}
+
+// AttestationPayload is the StorageAttested payload, by which a storage node
+// commits on chain that it still holds the content of some manifests. The
+// node answers a challenge derived from a recent block's hash, which it could
+// not know before that block was made: for each manifest, the hash of a slice
+// of one chunk that the challenge selects (see NewStorageChallenge). Anyone
+// holding the chunk can check the answer, so attestations build a record of
+// which nodes keep which content, for reputation or incentives.
+type AttestationPayload struct {
+	Node           string         `json:"node"`           // Attesting node; must be the sender
+	ChallengeBlock int64          `json:"challengeBlock"` // Index of the block whose hash is the challenge
+	ChallengeHash  string         `json:"challengeHash"`  // Hash of that block
+	Proofs         []StorageProof `json:"proofs"`
+	Timestamp      int64          `json:"timestamp"` // UnixNano
+}
+
+// StorageProof answers the challenge for one manifest.
+type StorageProof struct {
+	ManifestCID string `json:"manifestCID"`
+	ChunkCID    string `json:"chunkCID"` // Chunk the challenge selects
+	Offset      int64  `json:"offset"`   // Of the slice in the chunk
+	Length      int    `json:"length"`   // Of the slice
+	Digest      string `json:"digest"`   // Hex SHA-256 of the challenge seed and the slice
+}
+
+// Validate checks that required fields are set and all fields are within limits.
+func (p *AttestationPayload) Validate() error {
+	if p.Node == "" {
+		return fmt.Errorf("empty Node")
+	}
+	if p.ChallengeBlock < 0 || p.ChallengeHash == "" {
+		return fmt.Errorf("missing challenge block")
+	}
+	if p.Timestamp == 0 {
+		return fmt.Errorf("zero timestamp")
+	}
+	if len(p.Proofs) == 0 || len(p.Proofs) > MaxAttestationProofs {
+		return fmt.Errorf("%d proofs, want 1 to %d", len(p.Proofs), MaxAttestationProofs)
+	}
+	seen := make(map[string]bool, len(p.Proofs))
+	for _, proof := range p.Proofs {
+		switch {
+		case proof.ManifestCID == "" || len(proof.ManifestCID) > MaxAttestedCIDLength:
+			return fmt.Errorf("ManifestCID is %d bytes, want 1 to %d", len(proof.ManifestCID), MaxAttestedCIDLength)
+		case seen[proof.ManifestCID]:
+			return fmt.Errorf("manifest %s proved twice", proof.ManifestCID)
+		case proof.ChunkCID == "" || len(proof.ChunkCID) > MaxAttestedCIDLength:
+			return fmt.Errorf("ChunkCID is %d bytes, want 1 to %d", len(proof.ChunkCID), MaxAttestedCIDLength)
+		case proof.Offset < 0 || proof.Length <= 0 || proof.Length > AttestationSliceSize:
+			return fmt.Errorf("slice at %d of %d bytes, want at most %d", proof.Offset, proof.Length, AttestationSliceSize)
+		case !isHexSHA256(proof.Digest):
+			return fmt.Errorf("digest of manifest %s is not a hex SHA-256", proof.ManifestCID)
+		}
+		seen[proof.ManifestCID] = true
+	}
+	return nil
+}
+
+// isHexSHA256 reports whether s is a lowercase hex SHA-256 digest.
+func isHexSHA256(s string) bool {
+	if len(s) != 2*sha256.Size {
+		return false
+	}
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// ToPayload serializes the attestation as a StorageAttested transaction
+// payload in the given format.
+func (p *AttestationPayload) ToPayload(format ledger.PayloadFormat) ([]byte, error) {
+	payload, err := ledger.EncodePayload(format, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode attestation payload: %w", err)
+	}
+	return payload, nil
+}
+
+// AttestationFromPayload decodes a StorageAttested payload in either payload
+// format. The result must pass Validate.
+func AttestationFromPayload(payload []byte) (*AttestationPayload, error) {
+	var p AttestationPayload
+	if err := ledger.DecodePayload(payload, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode attestation payload: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("decoded attestation is invalid: %w", err)
+	}
+	return &p, nil
+}
+
+// ValidateAttestationPayload is the ledger schema validator for
+// StorageAttested payloads.
+func ValidateAttestationPayload(payload []byte) error {
+	_, err := AttestationFromPayload(payload)
+	return err
+}
+
+// StorageChallenge is the slice of a manifest's content a node must hash to
+// attest that it holds the content.
+type StorageChallenge struct {
+	Seed     [sha256.Size]byte
+	ChunkCID string
+	Offset   int64
+	Length   int
+}
+
+// NewStorageChallenge derives the challenge for node to prove it holds the
+// content of manifest, from challengeHash, the hash of a block. Each node
+// gets a different slice, so nodes cannot answer for one another.
+func NewStorageChallenge(challengeHash, node string, manifest *chunking.ContentManifestV1) (StorageChallenge, error) {
+	if manifest == nil || len(manifest.Chunks) == 0 {
+		return StorageChallenge{}, fmt.Errorf("manifest has no chunks to challenge")
+	}
+	c := StorageChallenge{Seed: sha256.Sum256([]byte("dsb-storage-challenge|" + challengeHash + "|" + node + "|" + manifest.ManifestCID))}
+	chunk := manifest.Chunks[binary.BigEndian.Uint64(c.Seed[0:8])%uint64(len(manifest.Chunks))]
+	if chunk.Size <= 0 {
+		return StorageChallenge{}, fmt.Errorf("manifest lists empty chunk %s", chunk.ChunkCID)
+	}
+	c.ChunkCID = chunk.ChunkCID
+	c.Length = AttestationSliceSize
+	if chunk.Size < AttestationSliceSize {
+		c.Length = int(chunk.Size)
+	}
+	c.Offset = int64(binary.BigEndian.Uint64(c.Seed[8:16]) % uint64(chunk.Size-int64(c.Length)+1))
+	return c, nil
+}
+
+// digest returns the answer to the challenge for the chunk holding data.
+func (c StorageChallenge) digest(data []byte) (string, error) {
+	if int64(len(data)) < c.Offset+int64(c.Length) {
+		return "", fmt.Errorf("chunk %s is %d bytes, challenge reads to %d", c.ChunkCID, len(data), c.Offset+int64(c.Length))
+	}
+	h := sha256.New()
+	h.Write(c.Seed[:])
+	h.Write(data[c.Offset : c.Offset+int64(c.Length)])
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ProveStorage answers the challenge for node and manifest from the chunk
+// in chunks.
+func ProveStorage(challengeHash, node string, manifest *chunking.ContentManifestV1, chunks DDSChunkRetriever) (StorageProof, error) {
+	c, err := NewStorageChallenge(challengeHash, node, manifest)
+	if err != nil {
+		return StorageProof{}, err
+	}
+	data, err := chunks.RetrieveChunk(c.ChunkCID)
+	if err != nil {
+		return StorageProof{}, fmt.Errorf("failed to read chunk %s: %w", c.ChunkCID, err)
+	}
+	digest, err := c.digest(data)
+	if err != nil {
+		return StorageProof{}, err
+	}
+	return StorageProof{ManifestCID: manifest.ManifestCID, ChunkCID: c.ChunkCID, Offset: c.Offset, Length: c.Length, Digest: digest}, nil
+}
+
+// Check checks that the proof answers the challenge for node and manifest,
+// without checking its digest.
+func (p *StorageProof) Check(challengeHash, node string, manifest *chunking.ContentManifestV1) (StorageChallenge, error) {
+	if manifest == nil || p.ManifestCID != manifest.ManifestCID {
+		return StorageChallenge{}, fmt.Errorf("proof is not for manifest %s", p.ManifestCID)
+	}
+	c, err := NewStorageChallenge(challengeHash, node, manifest)
+	if err != nil {
+		return StorageChallenge{}, err
+	}
+	if p.ChunkCID != c.ChunkCID || p.Offset != c.Offset || p.Length != c.Length {
+		return StorageChallenge{}, fmt.Errorf("proof of manifest %s answers another challenge", p.ManifestCID)
+	}
+	return c, nil
+}
+
+// Verify checks that the proof answers the challenge for node and manifest,
+// given chunkData, the chunk the challenge selects.
+func (p *StorageProof) Verify(challengeHash, node string, manifest *chunking.ContentManifestV1, chunkData []byte) error {
+	c, err := p.Check(challengeHash, node, manifest)
+	if err != nil {
+		return err
+	}
+	digest, err := c.digest(chunkData)
+	if err != nil {
+		return err
+	}
+	if digest != p.Digest {
+		return fmt.Errorf("proof of manifest %s has the wrong digest", p.ManifestCID)
+	}
+	return nil
+}
+
+// NewAttestationTransaction creates an unsigned StorageAttested transaction
+// by which node attests that it holds the content of manifests, answering the
+// challenge of block, stamped by clock (SystemClock if nil).
+func NewAttestationTransaction(clock ledger.Clock, node string, block *ledger.Block, manifests []*chunking.ContentManifestV1, chunks DDSChunkRetriever) (*ledger.Transaction, error) {
+	if clock == nil {
+		clock = ledger.SystemClock
+	}
+	if block == nil {
+		return nil, fmt.Errorf("challenge block cannot be nil")
+	}
+	p := &AttestationPayload{Node: node, ChallengeBlock: block.Index, ChallengeHash: block.Hash, Timestamp: clock.Now().UnixNano()}
+	for _, manifest := range manifests {
+		proof, err := ProveStorage(block.Hash, node, manifest, chunks)
+		if err != nil {
+			return nil, fmt.Errorf("cannot attest %s: %w", manifest.ManifestCID, err)
+		}
+		p.Proofs = append(p.Proofs, proof)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid attestation: %w", err)
+	}
+	payload, err := p.ToPayload(ledger.PayloadFormatCBOR)
+	if err != nil {
+		return nil, err
+	}
+	return ledger.NewTransactionWithClock(clock, node, ledger.StorageAttested, payload)
+}
+
+// TxSubmitter accepts transactions for inclusion in a block; *ledger.Mempool
+// implements it.
+type TxSubmitter interface {
+	Add(tx *ledger.Transaction) error
+}
+
+// AttestorOptions configures an Attestor. The zero value is valid.
+type AttestorOptions struct {
+	Interval time.Duration // Between rounds of Run; defaults to DefaultAttestorInterval
+	PerRound int           // Manifests attested per round; defaults to, and is capped at, MaxAttestationProofs
+}
+
+// Attestor periodically attests that a node holds its pinned content. Each
+// round it attests the next PerRound pinned manifests, taking turns through
+// the pin set, against the chain's latest block. It is safe for concurrent
+// use; rounds run one at a time.
+type Attestor struct {
+	wallet    *identity.Wallet
+	bc        *ledger.Blockchain
+	pins      PinSet
+	manifests DDSManifestFetcher
+	chunks    DDSChunkRetriever
+	submitter TxSubmitter
+	opts      AttestorOptions
+
+	mu     sync.Mutex
+	cursor int // Position in the pin set of the next manifest attested
+}
+
+// NewAttestor creates an Attestor signing with wallet and submitting to
+// submitter, attesting the content in pins as held in chunks.
+func NewAttestor(wallet *identity.Wallet, bc *ledger.Blockchain, pins PinSet, manifests DDSManifestFetcher, chunks DDSChunkRetriever, submitter TxSubmitter, opts AttestorOptions) (*Attestor, error) {
+	if wallet == nil || bc == nil || pins == nil || manifests == nil || chunks == nil || submitter == nil {
+		return nil, errors.New("wallet, blockchain, pins, manifests, chunks and submitter are all required")
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultAttestorInterval
+	}
+	if opts.PerRound <= 0 || opts.PerRound > MaxAttestationProofs {
+		opts.PerRound = MaxAttestationProofs
+	}
+	return &Attestor{wallet: wallet, bc: bc, pins: pins, manifests: manifests, chunks: chunks, submitter: submitter, opts: opts}, nil
+}
+
+// Run runs a round every Interval until ctx is done.
+func (a *Attestor) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := a.Round(); err != nil {
+				log.Printf("Attestor: round failed: %v\n", err)
+			}
+		}
+	}
+}
+
+// Round submits an attestation of the next pinned manifests and returns it,
+// or nil if nothing is pinned. Manifests that cannot be proved, because the
+// node no longer holds them, are skipped and reported in the error alongside
+// the attestation of the rest.
+func (a *Attestor) Round() (*ledger.Transaction, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	pinned := a.pins.Pinned()
+	if len(pinned) == 0 {
+		return nil, nil
+	}
+	block := a.bc.GetLatestBlock()
+	if block == nil {
+		return nil, fmt.Errorf("blockchain has no blocks")
+	}
+
+	var manifests []*chunking.ContentManifestV1
+	var errs []error
+	n := a.opts.PerRound
+	if n > len(pinned) {
+		n = len(pinned)
+	}
+	for i := 0; i < n; i++ {
+		cid := pinned[(a.cursor+i)%len(pinned)]
+		manifest, err := a.manifests.FetchManifest(cid)
+		if err == nil {
+			_, err = ProveStorage(block.Hash, a.wallet.Address, manifest, a.chunks)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", cid, err))
+			continue
+		}
+		manifests = append(manifests, manifest)
+	}
+	a.cursor = (a.cursor + n) % len(pinned)
+	if len(manifests) == 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	tx, err := NewAttestationTransaction(nil, a.wallet.Address, block, manifests, a.chunks)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.wallet.SignTransaction(tx); err != nil {
+		return nil, fmt.Errorf("failed to sign attestation: %w", err)
+	}
+	if err := a.submitter.Add(tx); err != nil {
+		return nil, fmt.Errorf("failed to submit attestation: %w", err)
+	}
+	return tx, errors.Join(errs...)
+}
+
+// NodeRecord is a storage node's attestation history.
+type NodeRecord struct {
+	Attestations int   // Accepted attestations
+	Rejected     int   // Attestations with a stale or unknown challenge, or by another sender
+	Proofs       int   // Proofs in accepted attestations that answer their challenge
+	Verified     int   // Of which the digest was checked against the chunk
+	Failed       int   // Proofs answering the wrong challenge or with a wrong digest
+	LastBlock    int64 // Block of the latest accepted attestation
+}
+
+// AttestationIndex records the storage attestations on a chain: which nodes
+// attested to holding which content, and how many of their proofs checked
+// out. An attestation is accepted if its node is its sender and its challenge
+// block is on the chain at most Window blocks before the attestation's own.
+// With a manifest fetcher each proof is checked to answer its challenge, and
+// with chunk storage its digest is verified if the node holds the chunk too.
+// An AttestationIndex is safe for concurrent use.
+type AttestationIndex struct {
+	manifests DDSManifestFetcher // May be nil
+	chunks    DDSChunkRetriever  // May be nil
+	window    int64
+
+	mu       sync.RWMutex
+	follower ledger.ChainFollower
+	nodes    map[string]*NodeRecord
+	holders  map[string]map[string]int64 // Manifest CID -> node -> block of latest proof
+}
+
+// NewAttestationIndex creates an empty AttestationIndex accepting challenges
+// up to window blocks old (DefaultAttestationWindow if zero or less).
+// manifests and chunks may be nil. Call Sync to populate it.
+func NewAttestationIndex(manifests DDSManifestFetcher, chunks DDSChunkRetriever, window int64) *AttestationIndex {
+	if window <= 0 {
+		window = DefaultAttestationWindow
+	}
+	x := &AttestationIndex{manifests: manifests, chunks: chunks, window: window}
+	x.reset()
+	return x
+}
+
+// reset clears the index. The caller must hold x.mu (or own x exclusively).
+func (x *AttestationIndex) reset() {
+	x.follower = ledger.NewChainFollower()
+	x.nodes = make(map[string]*NodeRecord)
+	x.holders = make(map[string]map[string]int64)
+}
+
+// HighWaterMark reports the last block whose attestations have been
+// checked.
+func (x *AttestationIndex) HighWaterMark() (int64, string) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.follower.HighWaterMark()
+}
+
+// Sync checks the attestations in blocks added since the last call against
+// bc and returns how many blocks it read, or ErrAttestationDiverged if the
+// chain was replaced.
+func (x *AttestationIndex) Sync(bc *ledger.Blockchain) (int, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.follower.Sync(bc, "attestation index", ErrAttestationDiverged, func(block *ledger.Block) { x.indexBlock(bc, block) })
+}
+
+// Rebuild forgets every attestation and rechecks the chain from genesis.
+func (x *AttestationIndex) Rebuild(bc *ledger.Blockchain) (int, error) {
+	if bc == nil {
+		return 0, fmt.Errorf("blockchain cannot be nil")
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.reset()
+	return x.follower.Sync(bc, "attestation index", ErrAttestationDiverged, func(block *ledger.Block) { x.indexBlock(bc, block) })
+}
+
+// indexBlock records the block's storage attestations, checking their
+// challenges against bc. The caller must hold x.mu.
+func (x *AttestationIndex) indexBlock(bc *ledger.Blockchain, block *ledger.Block) {
+	for _, tx := range block.Transactions {
+		if tx != nil && tx.Type == ledger.StorageAttested {
+			x.indexAttestation(bc, block.Index, tx)
+		}
+	}
+}
+
+// indexAttestation records tx, a StorageAttested transaction in block index.
+func (x *AttestationIndex) indexAttestation(bc *ledger.Blockchain, index int64, tx *ledger.Transaction) {
+	p, err := AttestationFromPayload(tx.Payload)
+	if err != nil {
+		return
+	}
+	record := x.nodes[tx.SenderPublicKey]
+	if record == nil {
+		record = &NodeRecord{LastBlock: -1}
+		x.nodes[tx.SenderPublicKey] = record
+	}
+	challenge := bc.GetBlockByIndex(p.ChallengeBlock)
+	if p.Node != tx.SenderPublicKey || p.ChallengeBlock >= index || index-p.ChallengeBlock > x.window || challenge == nil || challenge.Hash != p.ChallengeHash {
+		record.Rejected++
+		return
+	}
+	record.Attestations++
+	record.LastBlock = index
+	for i := range p.Proofs {
+		proof := &p.Proofs[i]
+		verified, err := x.checkProof(p, proof)
+		if err != nil {
+			record.Failed++
+			continue
+		}
+		record.Proofs++
+		if verified {
+			record.Verified++
+		}
+		if x.holders[proof.ManifestCID] == nil {
+			x.holders[proof.ManifestCID] = make(map[string]int64)
+		}
+		x.holders[proof.ManifestCID][p.Node] = index
+	}
+}
+
+// checkProof checks proof as far as the index can, reporting whether its
+// digest was verified.
+func (x *AttestationIndex) checkProof(p *AttestationPayload, proof *StorageProof) (bool, error) {
+	if x.manifests == nil {
+		return false, nil
+	}
+	manifest, err := x.manifests.FetchManifest(proof.ManifestCID)
+	if err != nil {
+		return false, nil // Unknown here; nothing to hold against the node
+	}
+	if _, err := proof.Check(p.ChallengeHash, p.Node, manifest); err != nil {
+		return false, err
+	}
+	if x.chunks == nil || !x.chunks.ChunkExists(proof.ChunkCID) {
+		return false, nil
+	}
+	data, err := x.chunks.RetrieveChunk(proof.ChunkCID)
+	if err != nil {
+		return false, nil
+	}
+	if err := proof.Verify(p.ChallengeHash, p.Node, manifest, data); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Node returns node's attestation record.
+func (x *AttestationIndex) Node(node string) (NodeRecord, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	record, ok := x.nodes[node]
+	if !ok {
+		return NodeRecord{}, false
+	}
+	return *record, true
+}
+
+// Holders returns the nodes that attested to holding the content at
+// manifestCID in block since or later, sorted.
+func (x *AttestationIndex) Holders(manifestCID string, since int64) []string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	var nodes []string
+	for node, block := range x.holders[manifestCID] {
+		if block >= since {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Strings(nodes)
+	return nodes
+}
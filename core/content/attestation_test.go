@@ -0,0 +1,198 @@
+package content
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil"
+	"digisocialblock/pkg/dds/chunking"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// attestationTestPublish stores text on network in 8-byte chunks and returns
+// its manifest.
+func attestationTestPublish(network *testutil.DDS, text string) *chunking.ContentManifestV1 {
+	manifest, chunks := testutil.Chunk([]byte(text), 8)
+	for _, c := range chunks {
+		network.Storage.Put(c.ChunkCID, c.Data)
+	}
+	network.Manifests.Add(manifest.ManifestCID, manifest)
+	return manifest
+}
+
+func TestStorageProof_VerifiesOnlyTheChallengedSlice(t *testing.T) {
+	manifest, chunks := testutil.Chunk([]byte(strings.Repeat("stored content ", 20)), 64)
+	store := testutil.NewStorage()
+	for _, c := range chunks {
+		store.Put(c.ChunkCID, c.Data)
+	}
+
+	proof, err := ProveStorage("blockhash", "node", manifest, store)
+	if err != nil {
+		t.Fatalf("ProveStorage() error = %v", err)
+	}
+	data, _ := store.RetrieveChunk(proof.ChunkCID)
+	if err := proof.Verify("blockhash", "node", manifest, data); err != nil {
+		t.Errorf("Verify() of an honest proof error = %v", err)
+	}
+	if err := proof.Verify("otherhash", "node", manifest, data); err == nil {
+		t.Error("Verify() against another block's challenge succeeded")
+	}
+	if err := proof.Verify("blockhash", "other node", manifest, data); err == nil {
+		t.Error("Verify() for another node succeeded")
+	}
+	forged := append([]byte(nil), data...)
+	forged[proof.Offset] ^= 0xff
+	if err := proof.Verify("blockhash", "node", manifest, forged); err == nil {
+		t.Error("Verify() against altered chunk data succeeded")
+	}
+
+	store.Delete(proof.ChunkCID)
+	if _, err := ProveStorage("blockhash", "node", manifest, store); err == nil {
+		t.Error("ProveStorage() without the challenged chunk succeeded")
+	}
+}
+
+func TestAttestationPayload_Validate(t *testing.T) {
+	proof := StorageProof{ManifestCID: "m", ChunkCID: "c", Length: 8, Digest: strings.Repeat("ab", 32)}
+	valid := AttestationPayload{Node: "n", ChallengeBlock: 1, ChallengeHash: "h", Proofs: []StorageProof{proof}, Timestamp: 1}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() of a valid attestation error = %v", err)
+	}
+	tests := map[string]func(p *AttestationPayload){
+		"no node":          func(p *AttestationPayload) { p.Node = "" },
+		"no challenge":     func(p *AttestationPayload) { p.ChallengeHash = "" },
+		"no proofs":        func(p *AttestationPayload) { p.Proofs = nil },
+		"duplicate":        func(p *AttestationPayload) { p.Proofs = []StorageProof{proof, proof} },
+		"oversized slice":  func(p *AttestationPayload) { p.Proofs[0].Length = AttestationSliceSize + 1 },
+		"malformed digest": func(p *AttestationPayload) { p.Proofs[0].Digest = strings.Repeat("AB", 32) },
+	}
+	for name, mutate := range tests {
+		p := valid
+		p.Proofs = []StorageProof{proof}
+		mutate(&p)
+		if err := p.Validate(); err == nil {
+			t.Errorf("Validate() with %s succeeded", name)
+		}
+	}
+}
+
+// attestationTestSubmitter collects submitted transactions.
+type attestationTestSubmitter []*ledger.Transaction
+
+func (s *attestationTestSubmitter) Add(tx *ledger.Transaction) error {
+	*s = append(*s, tx)
+	return nil
+}
+
+func TestAttestor_AttestsPinsInTurnAndIndexRecordsThem(t *testing.T) {
+	network := testutil.NewDDS(8)
+	texts := []string{"first pinned manifest", "second pinned manifest", "third pinned manifest"}
+	var pins retentionTestPins
+	for _, text := range texts {
+		pins = append(pins, attestationTestPublish(network, text).ManifestCID)
+	}
+	wallet, _ := identity.NewWallet()
+	bc, err := ledger.NewBlockchain()
+	if err != nil {
+		t.Fatalf("NewBlockchain() error = %v", err)
+	}
+	var submitted attestationTestSubmitter
+	a, err := NewAttestor(wallet, bc, pins, network.Manifests, network.Storage, &submitted, AttestorOptions{PerRound: 2})
+	if err != nil {
+		t.Fatalf("NewAttestor() error = %v", err)
+	}
+
+	tx, err := a.Round()
+	if err != nil || tx == nil {
+		t.Fatalf("Round() = %v, %v; want an attestation", tx, err)
+	}
+	if _, err := bc.AddBlock([]*ledger.Transaction{tx}); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+	tx, err = a.Round()
+	if err != nil || tx == nil {
+		t.Fatalf("second Round() = %v, %v; want an attestation", tx, err)
+	}
+	p, _ := AttestationFromPayload(tx.Payload)
+	if len(p.Proofs) != 2 || p.Proofs[0].ManifestCID != pins[2] || p.Proofs[1].ManifestCID != pins[0] || p.ChallengeBlock != 1 {
+		t.Fatalf("second attestation = %+v, want the next two pins against block 1", p)
+	}
+	if _, err := bc.AddBlock([]*ledger.Transaction{tx}); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+
+	x := NewAttestationIndex(network.Manifests, network.Storage, 0)
+	if n, err := x.Sync(bc); err != nil || n != 3 {
+		t.Fatalf("Sync() = %d, %v; want 3 blocks", n, err)
+	}
+	record, ok := x.Node(wallet.Address)
+	if !ok || record.Attestations != 2 || record.Proofs != 4 || record.Verified != 4 || record.Failed != 0 || record.LastBlock != 2 {
+		t.Errorf("Node() = %+v, %v; want 2 attestations of 4 verified proofs", record, ok)
+	}
+	if got := x.Holders(pins[0], 0); !reflect.DeepEqual(got, []string{wallet.Address}) {
+		t.Errorf("Holders() = %v, want the attesting node", got)
+	}
+	if got := x.Holders(pins[1], 2); len(got) != 0 {
+		t.Errorf("Holders() since block 2 = %v, want none", got)
+	}
+}
+
+func TestAttestationIndex_RejectsStaleAndForgedAttestations(t *testing.T) {
+	network := testutil.NewDDS(8)
+	text := "content the node threw away"
+	manifest := attestationTestPublish(network, text)
+	_, chunks := testutil.Chunk([]byte(text), 8)
+	wallet, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+	genesis := bc.GetLatestBlock()
+
+	sign := func(tx *ledger.Transaction) *ledger.Transaction {
+		if err := wallet.SignTransaction(tx); err != nil {
+			t.Fatalf("SignTransaction() error = %v", err)
+		}
+		return tx
+	}
+	// A forged digest, made without the chunk.
+	forged := testutil.NewStorage()
+	for _, c := range chunks {
+		forged.Put(c.ChunkCID, make([]byte, len(c.Data)))
+	}
+	tx, err := NewAttestationTransaction(nil, wallet.Address, genesis, []*chunking.ContentManifestV1{manifest}, forged)
+	if err != nil {
+		t.Fatalf("NewAttestationTransaction() error = %v", err)
+	}
+	if _, err := bc.AddBlock([]*ledger.Transaction{sign(tx)}); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+	// An honest one whose challenge is older than the window allows.
+	if _, err := bc.AddBlock(nil); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+	tx, _ = NewAttestationTransaction(nil, wallet.Address, genesis, []*chunking.ContentManifestV1{manifest}, network.Storage)
+	if _, err := bc.AddBlock([]*ledger.Transaction{sign(tx)}); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+
+	x := NewAttestationIndex(network.Manifests, network.Storage, 2)
+	if _, err := x.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	record, _ := x.Node(wallet.Address)
+	if record.Attestations != 1 || record.Failed != 1 || record.Proofs != 0 || record.Rejected != 1 {
+		t.Errorf("Node() = %+v, want the forged proof failed and the stale attestation rejected", record)
+	}
+	if got := x.Holders(manifest.ManifestCID, 0); len(got) != 0 {
+		t.Errorf("Holders() = %v, want none", got)
+	}
+
+	// Without a way to check proofs, the index takes them on trust.
+	trusting := NewAttestationIndex(nil, nil, 2)
+	if _, err := trusting.Rebuild(bc); err != nil {
+		t.Fatalf("Rebuild() error = %v", err)
+	}
+	if record, _ := trusting.Node(wallet.Address); record.Proofs != 1 || record.Verified != 0 {
+		t.Errorf("Node() without manifests = %+v, want one unverified proof", record)
+	}
+}
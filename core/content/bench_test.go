@@ -0,0 +1,82 @@
+package content
+
+import (
+	"digisocialblock/internal/testutil"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+// benchContentSizes are representative content sizes: a short text post,
+// a long-form article and a small media file.
+var benchContentSizes = []int{1 << 10, 256 << 10, 4 << 20}
+
+const benchChunkSize = 256 << 10
+
+// quietLogs silences the publish/retrieve progress logging for the duration of a test or benchmark.
+func quietLogs(tb testing.TB) {
+	tb.Helper()
+	previous := log.Writer()
+	log.SetOutput(io.Discard)
+	tb.Cleanup(func() { log.SetOutput(previous) })
+}
+
+func BenchmarkChunkData(b *testing.B) {
+	for _, size := range benchContentSizes {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			body := strings.Repeat("c", size)
+			chunker := testutil.NewChunker(benchChunkSize)
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := chunker.ChunkData(strings.NewReader(body)); err != nil {
+					b.Fatalf("ChunkData() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkPublishTextPost(b *testing.B) {
+	quietLogs(b)
+	for _, size := range benchContentSizes {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			body := strings.Repeat("p", size)
+			dds := testutil.NewDDS(benchChunkSize)
+			publisher, _ := NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := publisher.PublishTextPostToDDS(body); err != nil {
+					b.Fatalf("PublishTextPostToDDS() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRetrieveTextPost(b *testing.B) {
+	quietLogs(b)
+	for _, size := range benchContentSizes {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			body := strings.Repeat("r", size)
+			dds := testutil.NewDDS(benchChunkSize)
+			publisher, _ := NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+			cid, err := publisher.PublishTextPostToDDS(body)
+			if err != nil {
+				b.Fatalf("PublishTextPostToDDS() error = %v", err)
+			}
+			retriever, _ := NewContentRetriever(dds.Manifests, dds.Storage)
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := retriever.RetrieveAndVerifyTextPost(cid); err != nil {
+					b.Fatalf("RetrieveAndVerifyTextPost() error = %v", err)
+				}
+			}
+		})
+	}
+}
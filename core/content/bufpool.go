@@ -0,0 +1,33 @@
+package content
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBufferSize caps the capacity of buffers returned to the pool so that a
+// single large media retrieval does not pin a huge allocation for the process lifetime.
+const maxPooledBufferSize = 4 << 20 // 4 MiB
+
+// bufferPool is shared by the publish and retrieve paths (and by storage
+// implementations that want to reuse scratch space) to avoid allocating a new
+// buffer for every chunk or manifest operation.
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// GetBuffer returns an empty buffer from the shared content buffer pool.
+// Callers must return it with PutBuffer once they no longer reference its bytes.
+func GetBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// PutBuffer resets buf and returns it to the shared pool.
+// Buffers that have grown beyond maxPooledBufferSize are dropped instead.
+func PutBuffer(buf *bytes.Buffer) {
+	if buf == nil || buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
@@ -0,0 +1,32 @@
+package content
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBufferPool_ReturnsResetBuffers(t *testing.T) {
+	buf := GetBuffer()
+	buf.WriteString("leftover data")
+	PutBuffer(buf)
+
+	for i := 0; i < 4; i++ {
+		b := GetBuffer()
+		if b.Len() != 0 {
+			t.Fatalf("GetBuffer() returned a buffer with %d stale bytes", b.Len())
+		}
+		PutBuffer(b)
+	}
+}
+
+func TestPutBuffer_DropsOversizedBuffers(t *testing.T) {
+	big := bytes.NewBuffer(make([]byte, 0, maxPooledBufferSize+1))
+	PutBuffer(big) // Must not panic or retain the buffer
+	PutBuffer(nil)
+
+	b := GetBuffer()
+	if b.Cap() > maxPooledBufferSize {
+		t.Errorf("GetBuffer() returned an oversized buffer (cap %d)", b.Cap())
+	}
+	PutBuffer(b)
+}
@@ -0,0 +1,164 @@
+package content
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrCIDBlocked is returned when the node's CID policy refuses to store or
+// serve a CID.
+var ErrCIDBlocked = errors.New("CID is blocked by node policy")
+
+// CIDRule is one entry of a CID policy file. Line is kept so every decision
+// can be traced back to the file an operator audits.
+type CIDRule struct {
+	CID    string
+	Reason string
+	Line   int
+}
+
+// CIDPolicy decides which CIDs a node is willing to store and serve, e.g. to
+// honour takedown lists. It is loaded from a local, line-oriented policy file:
+//
+//	# comments and blank lines are ignored
+//	deny  <cid>  [reason...]
+//	allow <cid>  [reason...]
+//
+// A denied CID is always refused. If the file has any allow entries the policy
+// is an allowlist and every CID not listed is refused as well. A CIDPolicy is
+// safe for concurrent use; Reload swaps in the file's current contents.
+type CIDPolicy struct {
+	path string
+
+	mu    sync.RWMutex
+	allow map[string]CIDRule
+	deny  map[string]CIDRule
+}
+
+// LoadCIDPolicy reads the policy file at path.
+func LoadCIDPolicy(path string) (*CIDPolicy, error) {
+	if path == "" {
+		return nil, fmt.Errorf("CID policy path cannot be empty")
+	}
+	p := &CIDPolicy{path: path}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Reload re-reads the policy file. If the file cannot be read or parsed the
+// previous rules stay in effect, so a bad edit never opens or closes the node
+// by accident.
+func (p *CIDPolicy) Reload() error {
+	f, err := os.Open(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to open CID policy %s: %w", p.path, err)
+	}
+	defer f.Close()
+
+	allow, deny, err := parseCIDPolicy(bufio.NewScanner(f))
+	if err != nil {
+		return fmt.Errorf("failed to parse CID policy %s: %w", p.path, err)
+	}
+
+	p.mu.Lock()
+	p.allow, p.deny = allow, deny
+	p.mu.Unlock()
+	log.Printf("CIDPolicy: loaded %s (%d allow, %d deny rules)\n", p.path, len(allow), len(deny))
+	return nil
+}
+
+func parseCIDPolicy(sc *bufio.Scanner) (allow, deny map[string]CIDRule, err error) {
+	allow = make(map[string]CIDRule)
+	deny = make(map[string]CIDRule)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, nil, fmt.Errorf("line %d: expected \"<allow|deny> <cid> [reason]\"", lineNo)
+		}
+		rule := CIDRule{CID: fields[1], Reason: strings.Join(fields[2:], " "), Line: lineNo}
+		switch fields[0] {
+		case "allow":
+			allow[rule.CID] = rule
+		case "deny":
+			deny[rule.CID] = rule
+		default:
+			return nil, nil, fmt.Errorf("line %d: unknown action %q", lineNo, fields[0])
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, err
+	}
+	return allow, deny, nil
+}
+
+// Check returns nil if cid may be stored and served, or an error wrapping
+// ErrCIDBlocked that names the rule responsible. A nil policy allows everything.
+func (p *CIDPolicy) Check(cid string) error {
+	if p == nil {
+		return nil
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if rule, ok := p.deny[cid]; ok {
+		return fmt.Errorf("%w: %s denied by %s:%d (%s)", ErrCIDBlocked, cid, p.path, rule.Line, rule.Reason)
+	}
+	if len(p.allow) > 0 {
+		if _, ok := p.allow[cid]; !ok {
+			return fmt.Errorf("%w: %s not in allowlist %s", ErrCIDBlocked, cid, p.path)
+		}
+	}
+	return nil
+}
+
+// PolicyStorage wraps a DDSStorage and enforces a CIDPolicy on every chunk
+// stored or retrieved through it. Use it as the storage of a ContentPublisher
+// and the chunk retriever of a ContentRetriever so both paths honour the policy.
+type PolicyStorage struct {
+	store  DDSStorage
+	policy *CIDPolicy
+}
+
+// NewPolicyStorage creates a PolicyStorage.
+func NewPolicyStorage(store DDSStorage, policy *CIDPolicy) (*PolicyStorage, error) {
+	if store == nil {
+		return nil, fmt.Errorf("storage cannot be nil")
+	}
+	if policy == nil {
+		return nil, fmt.Errorf("CID policy cannot be nil")
+	}
+	return &PolicyStorage{store: store, policy: policy}, nil
+}
+
+// StoreChunk stores the chunk unless its CID is blocked.
+func (ps *PolicyStorage) StoreChunk(chunkID string, data []byte) error {
+	if err := ps.policy.Check(chunkID); err != nil {
+		log.Printf("PolicyStorage: refused to store chunk: %v\n", err)
+		return err
+	}
+	return ps.store.StoreChunk(chunkID, data)
+}
+
+// RetrieveChunk returns the chunk unless its CID is blocked.
+func (ps *PolicyStorage) RetrieveChunk(chunkID string) ([]byte, error) {
+	if err := ps.policy.Check(chunkID); err != nil {
+		log.Printf("PolicyStorage: refused to serve chunk: %v\n", err)
+		return nil, err
+	}
+	return ps.store.RetrieveChunk(chunkID)
+}
+
+// ChunkExists reports blocked chunks as absent.
+func (ps *PolicyStorage) ChunkExists(chunkID string) bool {
+	return ps.policy.Check(chunkID) == nil && ps.store.ChunkExists(chunkID)
+}
@@ -0,0 +1,109 @@
+package content
+
+import (
+	"digisocialblock/internal/testutil"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePolicyFile(t *testing.T, path, body string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatalf("failed to write policy file: %v", err)
+	}
+}
+
+func TestCIDPolicy_DenyAllowAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cids.policy")
+	writePolicyFile(t, path, "# takedowns\n\ndeny bad-cid DMCA notice 42\n")
+
+	policy, err := LoadCIDPolicy(path)
+	if err != nil {
+		t.Fatalf("LoadCIDPolicy() error = %v", err)
+	}
+	err = policy.Check("bad-cid")
+	if !errors.Is(err, ErrCIDBlocked) {
+		t.Fatalf("Check(bad-cid) error = %v, want ErrCIDBlocked", err)
+	}
+	if !strings.Contains(err.Error(), ":3") || !strings.Contains(err.Error(), "DMCA notice 42") {
+		t.Errorf("Check(bad-cid) error %q should cite the rule's line and reason", err)
+	}
+	if err := policy.Check("good-cid"); err != nil {
+		t.Errorf("Check(good-cid) error = %v, want nil without an allowlist", err)
+	}
+
+	// Switching to an allowlist refuses everything not listed.
+	writePolicyFile(t, path, "allow good-cid\ndeny bad-cid\n")
+	if err := policy.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if err := policy.Check("good-cid"); err != nil {
+		t.Errorf("Check(good-cid) after reload error = %v", err)
+	}
+	if err := policy.Check("other-cid"); !errors.Is(err, ErrCIDBlocked) {
+		t.Errorf("Check(other-cid) error = %v, want ErrCIDBlocked under an allowlist", err)
+	}
+
+	// A broken edit keeps the previous rules.
+	writePolicyFile(t, path, "block good-cid\n")
+	if err := policy.Reload(); err == nil {
+		t.Fatal("Reload() with an unknown action should fail")
+	}
+	if err := policy.Check("good-cid"); err != nil {
+		t.Errorf("Check(good-cid) after failed reload error = %v, want previous rules kept", err)
+	}
+}
+
+func TestLoadCIDPolicy_Errors(t *testing.T) {
+	if _, err := LoadCIDPolicy(""); err == nil {
+		t.Error("LoadCIDPolicy(\"\") should fail")
+	}
+	if _, err := LoadCIDPolicy(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("LoadCIDPolicy() of a missing file should fail")
+	}
+	path := filepath.Join(t.TempDir(), "cids.policy")
+	writePolicyFile(t, path, "deny\n")
+	if _, err := LoadCIDPolicy(path); err == nil {
+		t.Error("LoadCIDPolicy() of a rule without a CID should fail")
+	}
+}
+
+func TestPolicyStorage_EnforcesPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cids.policy")
+	writePolicyFile(t, path, "deny blocked\n")
+	policy, _ := LoadCIDPolicy(path)
+
+	inner := testutil.NewStorage()
+	inner.StoreChunk("blocked", []byte("stored before the takedown"))
+	store, err := NewPolicyStorage(inner, policy)
+	if err != nil {
+		t.Fatalf("NewPolicyStorage() error = %v", err)
+	}
+
+	if err := store.StoreChunk("blocked", []byte("x")); !errors.Is(err, ErrCIDBlocked) {
+		t.Errorf("StoreChunk(blocked) error = %v, want ErrCIDBlocked", err)
+	}
+	if _, err := store.RetrieveChunk("blocked"); !errors.Is(err, ErrCIDBlocked) {
+		t.Errorf("RetrieveChunk(blocked) error = %v, want ErrCIDBlocked", err)
+	}
+	if store.ChunkExists("blocked") {
+		t.Error("ChunkExists(blocked) = true, want blocked chunks reported absent")
+	}
+
+	if err := store.StoreChunk("fine", []byte("data")); err != nil {
+		t.Fatalf("StoreChunk(fine) error = %v", err)
+	}
+	if data, err := store.RetrieveChunk("fine"); err != nil || string(data) != "data" {
+		t.Errorf("RetrieveChunk(fine) = %q, %v", data, err)
+	}
+
+	if _, err := NewPolicyStorage(nil, policy); err == nil {
+		t.Error("NewPolicyStorage(nil store) should fail")
+	}
+	if _, err := NewPolicyStorage(inner, nil); err == nil {
+		t.Error("NewPolicyStorage(nil policy) should fail")
+	}
+}
@@ -0,0 +1,370 @@
+package content
+
+import (
+	"context"
+	"crypto/sha256"
+	"digisocialblock/core/ledger"
+	"encoding/hex"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for zero GatewayFallbackOptions fields.
+const (
+	DefaultGatewayTimeout        = 10 * time.Second
+	DefaultGatewayHealthInterval = time.Minute
+	DefaultGatewayUnhealthyAfter = 3
+	DefaultMaxGatewayChunkSize   = 4 << 20 // 4 MiB
+)
+
+// ErrNoGateway is returned by GatewayFallback.RetrieveChunk when a chunk is
+// not available from the network and no gateway could serve it either.
+var ErrNoGateway = errors.New("chunk unavailable from the network and every gateway")
+
+// GatewayEndpoint is a trusted HTTP gateway in a GatewayFallback's address
+// book.
+type GatewayEndpoint struct {
+	Name string // For stats and logs; defaults to the URL's host
+	URL  string // Base URL, e.g. "https://gateway.example"
+	// Rate limits requests to the gateway, in requests per second, with
+	// bursts of up to Burst (default 1). Zero leaves the gateway unlimited.
+	Rate  float64
+	Burst int
+}
+
+// GatewayFallbackOptions configures a GatewayFallback.
+type GatewayFallbackOptions struct {
+	Gateways       []GatewayEndpoint // Tried in order; at least one is required
+	HTTPClient     *http.Client      // Defaults to one with DefaultGatewayTimeout
+	HealthInterval time.Duration     // Between health checks in Run; defaults to DefaultGatewayHealthInterval
+	// UnhealthyAfter is the number of consecutive failed requests after which
+	// a gateway is skipped until it passes a health check. Defaults to
+	// DefaultGatewayUnhealthyAfter.
+	UnhealthyAfter int
+	MaxChunkSize   int64 // Largest chunk accepted from a gateway; defaults to DefaultMaxGatewayChunkSize
+}
+
+// GatewayStatus is the state of one gateway in the address book.
+type GatewayStatus struct {
+	Name        string    `json:"name"`
+	URL         string    `json:"url"`
+	Healthy     bool      `json:"healthy"`
+	Served      int64     `json:"served"`      // Chunks served
+	Failures    int64     `json:"failures"`    // Failed requests, including refused or corrupt chunks
+	RateLimited int64     `json:"rateLimited"` // Requests skipped by the gateway's rate limit
+	LastError   string    `json:"lastError,omitempty"`
+	LastChecked time.Time `json:"lastChecked,omitempty"` // Of the latest health check
+}
+
+// GatewayFallbackStats counts how often retrieval falls back to gateways.
+type GatewayFallbackStats struct {
+	Requests  int64           `json:"requests"`  // Chunks requested
+	Fallbacks int64           `json:"fallbacks"` // Of which the network could not serve
+	Recovered int64           `json:"recovered"` // Of which a gateway then served
+	Gateways  []GatewayStatus `json:"gateways"`
+}
+
+// gateway is a GatewayEndpoint with its health and rate limit state.
+type gateway struct {
+	GatewayStatus
+	base        string // URL without a trailing slash
+	rate, burst float64
+	tokens      float64
+	last        time.Time // Of the latest token refill
+	consecutive int       // Failed requests since the last success
+}
+
+// GatewayFallback is a DDSChunkRetriever that retrieves chunks from the DDS
+// network and, when that fails, from an ordered address book of trusted HTTP
+// gateways (see GatewayChunkPathPrefix). Chunks from gateways are checked
+// against their CIDs like any other, so a gateway can withhold content but
+// not alter it. Gateways that keep failing are skipped until Run or
+// CheckHealth finds them healthy again, and each may be rate limited.
+// Manifests are still fetched from the network. A GatewayFallback is safe
+// for concurrent use.
+type GatewayFallback struct {
+	network DDSChunkRetriever
+	opts    GatewayFallbackOptions
+
+	mu        sync.Mutex
+	clock     ledger.Clock
+	gateways  []*gateway
+	requests  int64
+	fallbacks int64
+	recovered int64
+}
+
+// NewGatewayFallback creates a GatewayFallback in front of network.
+func NewGatewayFallback(network DDSChunkRetriever, opts GatewayFallbackOptions) (*GatewayFallback, error) {
+	if network == nil {
+		return nil, errors.New("network chunk retriever cannot be nil")
+	}
+	if len(opts.Gateways) == 0 {
+		return nil, errors.New("at least one gateway is required")
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: DefaultGatewayTimeout}
+	}
+	if opts.HealthInterval <= 0 {
+		opts.HealthInterval = DefaultGatewayHealthInterval
+	}
+	if opts.UnhealthyAfter <= 0 {
+		opts.UnhealthyAfter = DefaultGatewayUnhealthyAfter
+	}
+	if opts.MaxChunkSize <= 0 {
+		opts.MaxChunkSize = DefaultMaxGatewayChunkSize
+	}
+	f := &GatewayFallback{network: network, opts: opts, clock: ledger.SystemClock}
+	for i, e := range opts.Gateways {
+		u, err := url.Parse(e.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("gateway %d: invalid URL %q", i, e.URL)
+		}
+		if e.Rate < 0 {
+			return nil, fmt.Errorf("gateway %s: negative rate", e.URL)
+		}
+		if e.Name == "" {
+			e.Name = u.Host
+		}
+		burst := float64(e.Burst)
+		if burst < 1 {
+			burst = 1
+		}
+		f.gateways = append(f.gateways, &gateway{
+			GatewayStatus: GatewayStatus{Name: e.Name, URL: e.URL, Healthy: true},
+			base:          strings.TrimSuffix(e.URL, "/"),
+			rate:          e.Rate,
+			burst:         burst,
+			tokens:        burst,
+		})
+	}
+	return f, nil
+}
+
+// SetClock sets the clock that refills rate limits and stamps health checks.
+// A nil clock restores ledger.SystemClock.
+func (f *GatewayFallback) SetClock(clock ledger.Clock) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if clock == nil {
+		clock = ledger.SystemClock
+	}
+	f.clock = clock
+	now := clock.Now()
+	for _, g := range f.gateways {
+		g.last = now
+	}
+}
+
+// ChunkExists reports whether the network holds the chunk or a healthy
+// gateway might serve it; RetrieveChunk finds out which.
+func (f *GatewayFallback) ChunkExists(chunkCID string) bool {
+	if f.network.ChunkExists(chunkCID) {
+		return true
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, g := range f.gateways {
+		if g.Healthy {
+			return true
+		}
+	}
+	return false
+}
+
+// RetrieveChunk retrieves a chunk from the network or, failing that, from
+// the first healthy gateway within its rate limit that serves it intact.
+func (f *GatewayFallback) RetrieveChunk(chunkCID string) ([]byte, error) {
+	f.mu.Lock()
+	f.requests++
+	f.mu.Unlock()
+	var netErr error
+	if f.network.ChunkExists(chunkCID) {
+		data, err := f.network.RetrieveChunk(chunkCID)
+		if err == nil {
+			return data, nil
+		}
+		netErr = err
+	} else {
+		netErr = errors.New("not found on the network")
+	}
+
+	f.mu.Lock()
+	f.fallbacks++
+	f.mu.Unlock()
+	errs := []error{fmt.Errorf("network: %w", netErr)}
+	for _, g := range f.gateways {
+		if !f.take(g) {
+			continue
+		}
+		data, err := f.fetch(context.Background(), g, chunkCID)
+		f.report(g, err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", g.Name, err))
+			continue
+		}
+		f.mu.Lock()
+		f.recovered++
+		g.Served++
+		f.mu.Unlock()
+		return data, nil
+	}
+	return nil, fmt.Errorf("%w: %s: %v", ErrNoGateway, chunkCID, errors.Join(errs...))
+}
+
+// take reports whether g is healthy and takes a token from its rate limit.
+func (f *GatewayFallback) take(g *gateway) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !g.Healthy {
+		return false
+	}
+	if g.rate == 0 {
+		return true
+	}
+	now := f.clock.Now()
+	g.tokens += now.Sub(g.last).Seconds() * g.rate
+	if g.tokens > g.burst {
+		g.tokens = g.burst
+	}
+	g.last = now
+	if g.tokens < 1 {
+		g.RateLimited++
+		return false
+	}
+	g.tokens--
+	return true
+}
+
+// report records the outcome of a request to g, marking it unhealthy after
+// UnhealthyAfter consecutive failures.
+func (f *GatewayFallback) report(g *gateway, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		g.consecutive = 0
+		return
+	}
+	g.Failures++
+	g.LastError = err.Error()
+	g.consecutive++
+	if g.Healthy && g.consecutive >= f.opts.UnhealthyAfter {
+		g.Healthy = false
+		log.Printf("GatewayFallback: gateway %s marked unhealthy after %d failures: %v\n", g.Name, g.consecutive, err)
+	}
+}
+
+// fetch retrieves chunkCID from g and checks it against the CID.
+func (f *GatewayFallback) fetch(ctx context.Context, g *gateway, chunkCID string) ([]byte, error) {
+	if chunkCID == "" || strings.ContainsAny(chunkCID, "/?#") {
+		return nil, fmt.Errorf("invalid chunk CID %q", chunkCID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.base+GatewayChunkPathPrefix+chunkCID, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > f.opts.MaxChunkSize {
+		return nil, fmt.Errorf("chunk is %d bytes, limit %d", resp.ContentLength, f.opts.MaxChunkSize)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.opts.MaxChunkSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk: %w", err)
+	}
+	if int64(len(data)) > f.opts.MaxChunkSize {
+		return nil, fmt.Errorf("chunk exceeds %d bytes", f.opts.MaxChunkSize)
+	}
+	sum := sha256.Sum256(data)
+	if !cidsEqual(hex.EncodeToString(sum[:]), chunkCID) {
+		return nil, fmt.Errorf("served data does not match chunk %s", chunkCID)
+	}
+	return data, nil
+}
+
+// Run checks the health of every gateway every HealthInterval until ctx is
+// done.
+func (f *GatewayFallback) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.opts.HealthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			f.CheckHealth(ctx)
+		}
+	}
+}
+
+// CheckHealth probes every gateway's GatewayHealthPath and returns how many
+// are healthy. A gateway answering 200 is healthy again; any other answer
+// takes it out of rotation.
+func (f *GatewayFallback) CheckHealth(ctx context.Context) int {
+	healthy := 0
+	for _, g := range f.gateways {
+		err := f.probe(ctx, g)
+		f.mu.Lock()
+		g.LastChecked = f.clock.Now()
+		if err != nil {
+			g.LastError = err.Error()
+			if g.Healthy {
+				log.Printf("GatewayFallback: gateway %s failed its health check: %v\n", g.Name, err)
+			}
+			g.Healthy = false
+		} else {
+			g.Healthy = true
+			g.consecutive = 0
+			healthy++
+		}
+		f.mu.Unlock()
+	}
+	return healthy
+}
+
+func (f *GatewayFallback) probe(ctx context.Context, g *gateway) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.base+GatewayHealthPath, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := f.opts.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Stats returns a snapshot of the fallback counters and the address book.
+func (f *GatewayFallback) Stats() GatewayFallbackStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := GatewayFallbackStats{Requests: f.requests, Fallbacks: f.fallbacks, Recovered: f.recovered}
+	for _, g := range f.gateways {
+		s.Gateways = append(s.Gateways, g.GatewayStatus)
+	}
+	return s
+}
+
+// Var returns the fallback stats as an expvar.Var, for publishing with
+// expvar.Publish.
+func (f *GatewayFallback) Var() expvar.Var {
+	return expvar.Func(func() interface{} { return f.Stats() })
+}
@@ -0,0 +1,139 @@
+package content
+
+import (
+	"context"
+	"digisocialblock/internal/testutil"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fallbackTestGateway serves the chunks of a published body like a Gateway,
+// unless broken, in which case it answers 500 to everything.
+type fallbackTestGateway struct {
+	*httptest.Server
+	broken atomic.Bool
+	hits   atomic.Int64
+}
+
+func newFallbackTestGateway(t *testing.T, retriever *ContentRetriever) *fallbackTestGateway {
+	t.Helper()
+	gw, err := NewGateway(retriever, nil)
+	if err != nil {
+		t.Fatalf("NewGateway() error = %v", err)
+	}
+	g := &fallbackTestGateway{}
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.hits.Add(1)
+		if g.broken.Load() {
+			http.Error(w, "down", http.StatusInternalServerError)
+			return
+		}
+		gw.ServeHTTP(w, r)
+	}))
+	t.Cleanup(g.Close)
+	return g
+}
+
+func TestGatewayFallback_RetrievesFromGatewaysWhenTheNetworkFails(t *testing.T) {
+	body := strings.Repeat("fallback content ", 20)
+	origin, _, cid := publishForStream(t, body, 0)
+	broken := newFallbackTestGateway(t, origin)
+	broken.broken.Store(true)
+	good := newFallbackTestGateway(t, origin)
+
+	network := testutil.NewStorage() // Holds none of the chunks
+	f, err := NewGatewayFallback(network, GatewayFallbackOptions{
+		Gateways:       []GatewayEndpoint{{Name: "broken", URL: broken.URL}, {Name: "good", URL: good.URL + "/"}},
+		UnhealthyAfter: 2,
+	})
+	if err != nil {
+		t.Fatalf("NewGatewayFallback() error = %v", err)
+	}
+	retriever, _ := NewContentRetriever(origin.manifestFetcher, f)
+	got, err := retriever.RetrieveAndVerifyTextPost(cid)
+	if err != nil || got != body {
+		t.Fatalf("RetrieveAndVerifyTextPost() = %d bytes, %v; want the body via the good gateway", len(got), err)
+	}
+
+	manifest, _ := origin.manifestFetcher.FetchManifest(cid)
+	n := int64(len(manifest.Chunks))
+	s := f.Stats()
+	if s.Requests != n || s.Fallbacks != n || s.Recovered != n {
+		t.Errorf("Stats() = %+v, want all %d chunks recovered from gateways", s, n)
+	}
+	if b := s.Gateways[0]; b.Healthy || b.Failures != 2 || b.Served != 0 {
+		t.Errorf("broken gateway = %+v, want it out of rotation after 2 failures", b)
+	}
+	if hits := broken.hits.Load(); hits != 2 {
+		t.Errorf("broken gateway was asked %d times, want 2", hits)
+	}
+	if g := s.Gateways[1]; !g.Healthy || g.Served != n || g.Name != "good" {
+		t.Errorf("good gateway = %+v, want every chunk served", g)
+	}
+
+	// The network is preferred once it has the chunk.
+	chunk := manifest.Chunks[0].ChunkCID
+	data, _ := origin.chunkRetriever.RetrieveChunk(chunk)
+	network.Put(chunk, data)
+	if _, err := f.RetrieveChunk(chunk); err != nil || f.Stats().Fallbacks != n {
+		t.Errorf("RetrieveChunk() held by the network = %v with %d fallbacks, want no fallback", err, f.Stats().Fallbacks)
+	}
+
+	broken.broken.Store(false)
+	if n := f.CheckHealth(context.Background()); n != 2 || !f.Stats().Gateways[0].Healthy {
+		t.Errorf("CheckHealth() = %d, want the repaired gateway back in rotation", n)
+	}
+	good.broken.Store(true)
+	if n := f.CheckHealth(context.Background()); n != 1 || f.Stats().Gateways[1].Healthy {
+		t.Errorf("CheckHealth() = %d, want the failed gateway taken out", n)
+	}
+}
+
+func TestGatewayFallback_RefusesAlteredChunksAndHonoursRateLimits(t *testing.T) {
+	body := strings.Repeat("rate limited ", 20)
+	origin, _, cid := publishForStream(t, body, 0)
+	manifest, _ := origin.manifestFetcher.FetchManifest(cid)
+	chunks := manifest.Chunks
+
+	tampering := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not the chunk you asked for"))
+	}))
+	defer tampering.Close()
+	good := newFallbackTestGateway(t, origin)
+
+	f, _ := NewGatewayFallback(testutil.NewStorage(), GatewayFallbackOptions{
+		Gateways: []GatewayEndpoint{{URL: tampering.URL}, {URL: good.URL, Rate: 1, Burst: 2}},
+	})
+	clock := testutil.NewClock(0)
+	f.SetClock(clock)
+
+	for i := 0; i < 2; i++ {
+		if _, err := f.RetrieveChunk(chunks[i].ChunkCID); err != nil {
+			t.Fatalf("RetrieveChunk(%d) error = %v", i, err)
+		}
+	}
+	if _, err := f.RetrieveChunk(chunks[2].ChunkCID); !errors.Is(err, ErrNoGateway) {
+		t.Fatalf("RetrieveChunk() beyond the burst error = %v, want ErrNoGateway", err)
+	}
+	s := f.Stats()
+	if s.Gateways[0].Failures != 3 || s.Gateways[0].Served != 0 || !strings.Contains(s.Gateways[0].LastError, "does not match") {
+		t.Errorf("tampering gateway = %+v, want every chunk refused", s.Gateways[0])
+	}
+	if s.Gateways[1].RateLimited != 1 || s.Gateways[1].Served != 2 {
+		t.Errorf("rate-limited gateway = %+v, want 2 served and 1 limited", s.Gateways[1])
+	}
+
+	// The tampering gateway is out of rotation by now, and the limit refills.
+	clock.Advance(time.Second)
+	if _, err := f.RetrieveChunk(chunks[2].ChunkCID); err != nil {
+		t.Errorf("RetrieveChunk() once the limit refilled error = %v", err)
+	}
+	if s := f.Stats().Gateways[0]; s.Healthy || s.Failures != 3 {
+		t.Errorf("tampering gateway = %+v, want it skipped after 3 failures", s)
+	}
+}
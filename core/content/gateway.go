@@ -0,0 +1,162 @@
+package content
+
+import (
+	"digisocialblock/core/telemetry"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Gateway URL paths.
+const (
+	// GatewayPathPrefix is the URL prefix under which the gateway serves
+	// content: GET /content/<manifestCID>.
+	GatewayPathPrefix = "/content/"
+	// GatewayChunkPathPrefix is the URL prefix under which the gateway serves
+	// single chunks, which clients can verify against their CIDs: GET
+	// /chunks/<chunkCID>. GatewayFallback fetches from here.
+	GatewayChunkPathPrefix = "/chunks/"
+	// GatewayHealthPath answers 200 while the gateway is serving.
+	GatewayHealthPath = "/healthz"
+)
+
+// Gateway serves DDS content over HTTP. Every request is checked against the
+// node's CID policy for the manifest and each of its chunks before any bytes are
+// written, so a blocked CID yields a clean 451 rather than a truncated body.
+// Responses carry the content's labels, if the retriever has a label source
+// (see HeaderContentWarning and HeaderSensitive).
+type Gateway struct {
+	retriever *ContentRetriever
+	policy    *CIDPolicy // May be nil to serve everything
+	readAhead ReadAheadOptions
+}
+
+// NewGateway creates a Gateway. policy may be nil.
+func NewGateway(retriever *ContentRetriever, policy *CIDPolicy) (*Gateway, error) {
+	if retriever == nil {
+		return nil, errors.New("content retriever cannot be nil")
+	}
+	return &Gateway{
+		retriever: retriever,
+		policy:    policy,
+		readAhead: ReadAheadOptions{Window: 4},
+	}, nil
+}
+
+// ServeHTTP implements http.Handler. Each request is a "content.Gateway"
+// server span, continuing the caller's trace if the request carries one.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := telemetry.ExtractHTTP(r.Context(), r.Header)
+	_, span := tracer.Start(ctx, "content.Gateway", trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("http.request.method", r.Method), attribute.String("url.path", r.URL.Path)))
+	defer span.End()
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.URL.Path == GatewayHealthPath {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, "ok\n")
+		return
+	}
+	if chunkCID := strings.TrimPrefix(r.URL.Path, GatewayChunkPathPrefix); chunkCID != r.URL.Path {
+		g.serveChunk(w, r, chunkCID)
+		return
+	}
+	manifestCID := strings.TrimPrefix(r.URL.Path, GatewayPathPrefix)
+	if manifestCID == r.URL.Path || manifestCID == "" || strings.Contains(manifestCID, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if err := g.policy.Check(manifestCID); err != nil {
+		g.refuse(w, err)
+		return
+	}
+
+	// A HEAD request only needs the manifest, and is how clients read the
+	// content labels without downloading the content.
+	readAhead := g.readAhead
+	if r.Method == http.MethodHead {
+		readAhead = ReadAheadOptions{}
+	}
+	stream, err := g.retriever.OpenStream(manifestCID, readAhead)
+	if err != nil {
+		if errors.Is(err, ErrCIDBlocked) {
+			g.refuse(w, err)
+			return
+		}
+		log.Printf("Gateway: failed to open %s: %v\n", manifestCID, err)
+		http.Error(w, "content not found", http.StatusNotFound)
+		return
+	}
+	defer stream.Close()
+
+	manifest := stream.Manifest()
+	for _, chunk := range manifest.Chunks {
+		if err := g.policy.Check(chunk.ChunkCID); err != nil {
+			g.refuse(w, err)
+			return
+		}
+	}
+
+	span.SetAttributes(attribute.String("dds.manifest_cid", manifestCID), attribute.Int64("dds.size", manifest.TotalSize))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(manifest.TotalSize, 10))
+	w.Header().Set("ETag", strconv.Quote(manifestCID)) // Content addressed, so the CID is a strong ETag
+	if labels, ok := g.retriever.Labels(manifestCID); ok {
+		if labels.ContentWarning != "" {
+			w.Header().Set(HeaderContentWarning, mime.QEncoding.Encode("utf-8", labels.ContentWarning))
+		}
+		if labels.Sensitive {
+			w.Header().Set(HeaderSensitive, "true")
+		}
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(w, stream); err != nil {
+		log.Printf("Gateway: error streaming %s: %v\n", manifestCID, err)
+		span.RecordError(err)
+	}
+}
+
+// serveChunk serves the chunk chunkCID from the retriever's chunk source,
+// after checking it against the CID policy.
+func (g *Gateway) serveChunk(w http.ResponseWriter, r *http.Request, chunkCID string) {
+	if chunkCID == "" || strings.Contains(chunkCID, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if err := g.policy.Check(chunkCID); err != nil {
+		g.refuse(w, err)
+		return
+	}
+	data, err := g.retriever.chunkRetriever.RetrieveChunk(chunkCID)
+	if err != nil {
+		http.Error(w, "chunk not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("ETag", strconv.Quote(chunkCID))
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(data)
+}
+
+// refuse answers a policy refusal. The rule details are logged for the
+// operator but not disclosed to the client.
+func (g *Gateway) refuse(w http.ResponseWriter, err error) {
+	log.Printf("Gateway: refused request: %v\n", err)
+	http.Error(w, "unavailable by node policy", http.StatusUnavailableForLegalReasons)
+}
@@ -0,0 +1,91 @@
+package content
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGateway_ServesContentAndHonoursPolicy(t *testing.T) {
+	body := strings.Repeat("gateway content ", 20)
+	retriever, _, cid := publishForStream(t, body, 0)
+
+	path := filepath.Join(t.TempDir(), "cids.policy")
+	writePolicyFile(t, path, "# empty policy\n")
+	policy, _ := LoadCIDPolicy(path)
+	gw, err := NewGateway(retriever, policy)
+	if err != nil {
+		t.Fatalf("NewGateway() error = %v", err)
+	}
+
+	get := func(method, url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(method, url, nil))
+		return rec
+	}
+
+	rec := get(http.MethodGet, GatewayPathPrefix+cid)
+	if rec.Code != http.StatusOK || rec.Body.String() != body {
+		t.Fatalf("GET %s = %d, %d bytes; want 200 with the content", cid, rec.Code, rec.Body.Len())
+	}
+	if got := rec.Header().Get("Content-Length"); got != "320" {
+		t.Errorf("Content-Length = %q, want 320", got)
+	}
+	if rec := get(http.MethodGet, GatewayPathPrefix+"unknown"); rec.Code != http.StatusNotFound {
+		t.Errorf("GET unknown = %d, want 404", rec.Code)
+	}
+	if rec := get(http.MethodPost, GatewayPathPrefix+cid); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST = %d, want 405", rec.Code)
+	}
+
+	// Blocking the manifest refuses the request outright.
+	writePolicyFile(t, path, "deny "+cid+" takedown\n")
+	policy.Reload()
+	if rec := get(http.MethodGet, GatewayPathPrefix+cid); rec.Code != http.StatusUnavailableForLegalReasons {
+		t.Errorf("GET blocked manifest = %d, want 451", rec.Code)
+	}
+
+	// Blocking a single chunk refuses before any content is written.
+	manifest, _ := retriever.manifestFetcher.FetchManifest(cid)
+	writePolicyFile(t, path, "deny "+manifest.Chunks[2].ChunkCID+"\n")
+	policy.Reload()
+	rec = get(http.MethodGet, GatewayPathPrefix+cid)
+	if rec.Code != http.StatusUnavailableForLegalReasons || strings.Contains(rec.Body.String(), "gateway content") {
+		t.Errorf("GET with blocked chunk = %d %q, want 451 with no content", rec.Code, rec.Body.String())
+	}
+}
+
+// gatewayTestLabels is a LabelSource over a fixed map.
+type gatewayTestLabels map[string]ContentLabels
+
+func (l gatewayTestLabels) ContentLabels(cid string) (ContentLabels, bool) {
+	labels, ok := l[cid]
+	return labels, ok
+}
+
+func TestGateway_ReturnsContentLabels(t *testing.T) {
+	retriever, _, cid := publishForStream(t, strings.Repeat("labelled ", 10), 0)
+	retriever.SetLabelSource(gatewayTestLabels{cid: {ContentWarning: "Spoilers ahead", Sensitive: true}})
+	gw, _ := NewGateway(retriever, nil)
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, GatewayPathPrefix+cid, nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Fatalf("HEAD = %d with %d bytes, want 200 with no body", rec.Code, rec.Body.Len())
+	}
+	if got := rec.Header().Get(HeaderContentWarning); got != "Spoilers ahead" {
+		t.Errorf("%s = %q, want the warning", HeaderContentWarning, got)
+	}
+	if got := rec.Header().Get(HeaderSensitive); got != "true" {
+		t.Errorf("%s = %q, want true", HeaderSensitive, got)
+	}
+
+	retriever.SetLabelSource(gatewayTestLabels{cid: {ContentWarning: "Spoilers für alle"}})
+	rec = httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, GatewayPathPrefix+cid, nil))
+	if got := rec.Header().Get(HeaderContentWarning); got != "=?utf-8?q?Spoilers_f=C3=BCr_alle?=" || rec.Header().Get(HeaderSensitive) != "" {
+		t.Errorf("labels = %q, %q; want an encoded warning and no sensitive flag", got, rec.Header().Get(HeaderSensitive))
+	}
+}
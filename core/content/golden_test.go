@@ -0,0 +1,49 @@
+package content
+
+import (
+	"digisocialblock/internal/testutil/golden"
+	"digisocialblock/pkg/dds/chunking"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func goldenManifest() *chunking.ContentManifestV1 {
+	return &chunking.ContentManifestV1{
+		Version:          1,
+		ManifestCID:      "bafy-manifest",
+		TotalSize:        300,
+		EncryptionMethod: "none",
+		Chunks: []chunking.ChunkInfo{
+			{ChunkCID: strings.Repeat("0a", 32), Size: 200},
+			{ChunkCID: strings.Repeat("0b", 32), Size: 100},
+		},
+	}
+}
+
+func TestGolden_Manifest(t *testing.T) {
+	manifest := goldenManifest()
+	for _, tc := range []struct {
+		name string
+		enc  ManifestEncoding
+	}{
+		{"manifest_json.hex", ManifestEncodingJSON},
+		{"manifest_cbor.hex", ManifestEncodingCBOR},
+	} {
+		data, err := EncodeManifest(manifest, tc.enc)
+		if err != nil {
+			t.Fatalf("EncodeManifest(%s) error = %v", tc.name, err)
+		}
+		golden.AssertBinary(t, tc.name, data)
+		decoded, err := DecodeManifest(data)
+		if err != nil || !reflect.DeepEqual(decoded, manifest) {
+			t.Errorf("DecodeManifest(%s) = %+v, %v; want %+v", tc.name, decoded, err, manifest)
+		}
+	}
+
+	canonical, err := CanonicalManifestBytes(manifest)
+	if err != nil {
+		t.Fatalf("CanonicalManifestBytes() error = %v", err)
+	}
+	golden.Assert(t, "manifest.canonical.json", append(canonical, '\n'))
+}
@@ -0,0 +1,173 @@
+package content
+
+import (
+	"crypto/sha256"
+	"digisocialblock/pkg/dds/chunking"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// ErrIntegrity is returned by IntegrityStorage for a write whose data does
+// not match the CID it is stored under.
+var ErrIntegrity = errors.New("data does not match its CID")
+
+// IntegrityOptions configures an IntegrityStorage.
+type IntegrityOptions struct {
+	// Manifests lets manifests be stored under their manifest CID alongside
+	// chunks. A write that is not addressed by its hash must then decode as
+	// a well-formed manifest (see DecodeManifest) naming the CID it is
+	// stored under. Without it, only chunks can be stored.
+	Manifests bool
+	// ManifestCID, if set, computes the CID a manifest must be stored under
+	// from its contents, for chunkers whose manifest CIDs are derived rather
+	// than assigned. Nil trusts the CID the manifest names.
+	ManifestCID func(manifest *chunking.ContentManifestV1) string
+}
+
+// IntegrityStats counts the writes an IntegrityStorage let through or refused.
+type IntegrityStats struct {
+	Stored       int64  `json:"stored"`
+	Rejected     int64  `json:"rejected"`
+	LastRejected string `json:"lastRejected,omitempty"` // CID of the latest refused write
+}
+
+// IntegrityStorage wraps a DDSStorage and refuses writes whose data does not
+// match their CID: a chunk must be stored under the SHA-256 of its data, and
+// a manifest, if allowed, must be well formed and name its own CID. Put it in
+// front of the local store that peers replicate into and publishers write to,
+// so a misbehaving peer or a buggy publisher cannot poison it with data that
+// every later retrieval would have to reject. Reads pass through unchanged.
+// An IntegrityStorage is safe for concurrent use if its store is.
+type IntegrityStorage struct {
+	store DDSStorage
+	opts  IntegrityOptions
+
+	mu    sync.Mutex
+	stats IntegrityStats
+}
+
+// NewIntegrityStorage creates an IntegrityStorage in front of store.
+func NewIntegrityStorage(store DDSStorage, opts IntegrityOptions) (*IntegrityStorage, error) {
+	if store == nil {
+		return nil, errors.New("storage cannot be nil")
+	}
+	return &IntegrityStorage{store: store, opts: opts}, nil
+}
+
+// StoreChunk stores the data if it matches chunkID.
+func (is *IntegrityStorage) StoreChunk(chunkID string, data []byte) error {
+	if err := is.check(chunkID, data); err != nil {
+		return err
+	}
+	return is.record(is.store.StoreChunk(chunkID, data))
+}
+
+// StoreChunkNoCopy stores the data if it matches chunkID, handing the buffer
+// to the wrapped store if it is a ZeroCopyStorage and copying it otherwise.
+func (is *IntegrityStorage) StoreChunkNoCopy(chunkID string, data []byte) error {
+	if err := is.check(chunkID, data); err != nil {
+		return err
+	}
+	if zc, ok := is.store.(ZeroCopyStorage); ok {
+		return is.record(zc.StoreChunkNoCopy(chunkID, data))
+	}
+	return is.record(is.store.StoreChunk(chunkID, data))
+}
+
+// RetrieveChunk returns the chunk from the wrapped store.
+func (is *IntegrityStorage) RetrieveChunk(chunkID string) ([]byte, error) {
+	return is.store.RetrieveChunk(chunkID)
+}
+
+// ChunkExists reports whether the wrapped store holds the chunk.
+func (is *IntegrityStorage) ChunkExists(chunkID string) bool {
+	return is.store.ChunkExists(chunkID)
+}
+
+// Stats returns a snapshot of the write counters.
+func (is *IntegrityStorage) Stats() IntegrityStats {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+	return is.stats
+}
+
+// check verifies data against chunkID, counting and logging a refusal.
+func (is *IntegrityStorage) check(chunkID string, data []byte) error {
+	err := is.verify(chunkID, data)
+	if err == nil {
+		return nil
+	}
+	is.mu.Lock()
+	is.stats.Rejected++
+	is.stats.LastRejected = chunkID
+	is.mu.Unlock()
+	log.Printf("IntegrityStorage: refused to store %s: %v\n", chunkID, err)
+	return err
+}
+
+func (is *IntegrityStorage) record(err error) error {
+	if err == nil {
+		is.mu.Lock()
+		is.stats.Stored++
+		is.mu.Unlock()
+	}
+	return err
+}
+
+func (is *IntegrityStorage) verify(chunkID string, data []byte) error {
+	if chunkID == "" {
+		return fmt.Errorf("%w: empty CID", ErrIntegrity)
+	}
+	sum := sha256.Sum256(data)
+	calculated := hex.EncodeToString(sum[:])
+	if cidsEqual(calculated, chunkID) {
+		return nil
+	}
+	if !is.opts.Manifests {
+		return fmt.Errorf("%w: chunk %s hashes to %s", ErrIntegrity, chunkID, calculated)
+	}
+	manifest, err := DecodeManifest(data)
+	if err != nil {
+		return fmt.Errorf("%w: %s hashes to %s and is not a manifest: %v", ErrIntegrity, chunkID, calculated, err)
+	}
+	if err := ValidateManifest(manifest); err != nil {
+		return fmt.Errorf("%w: manifest %s: %v", ErrIntegrity, chunkID, err)
+	}
+	want := manifest.ManifestCID
+	if is.opts.ManifestCID != nil {
+		want = is.opts.ManifestCID(manifest)
+	}
+	if !cidsEqual(manifest.ManifestCID, chunkID) || !cidsEqual(want, chunkID) {
+		return fmt.Errorf("%w: manifest stored under %s names CID %s and derives %s", ErrIntegrity, chunkID, manifest.ManifestCID, want)
+	}
+	return nil
+}
+
+// ValidateManifest checks that a manifest is internally consistent: it names
+// its CID, every chunk has a CID and a positive size, and the sizes add up to
+// TotalSize.
+func ValidateManifest(manifest *chunking.ContentManifestV1) error {
+	if manifest == nil {
+		return errors.New("manifest cannot be nil")
+	}
+	if manifest.ManifestCID == "" {
+		return errors.New("manifest has no CID")
+	}
+	var total int64
+	for i, chunk := range manifest.Chunks {
+		if chunk.ChunkCID == "" {
+			return fmt.Errorf("chunk %d has no CID", i)
+		}
+		if chunk.Size <= 0 {
+			return fmt.Errorf("chunk %d (%s) has size %d", i, chunk.ChunkCID, chunk.Size)
+		}
+		total += chunk.Size
+	}
+	if total != manifest.TotalSize {
+		return fmt.Errorf("chunk sizes add up to %d, but the total size is %d", total, manifest.TotalSize)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package content
+
+import (
+	"digisocialblock/internal/testutil"
+	"digisocialblock/pkg/dds/chunking"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestIntegrityStorage_RefusesMismatchedChunks(t *testing.T) {
+	dds := testutil.NewDDS(4)
+	store, err := NewIntegrityStorage(dds.Storage, IntegrityOptions{})
+	if err != nil {
+		t.Fatalf("NewIntegrityStorage() error = %v", err)
+	}
+	publisher, err := NewContentPublisher(dds.Chunker, store, dds.Originator)
+	if err != nil {
+		t.Fatalf("NewContentPublisher() error = %v", err)
+	}
+	publisher.EnableZeroCopy(true)
+	if _, err := publisher.PublishTextPostToDDS("hello, world"); err != nil {
+		t.Fatalf("PublishTextPostToDDS() through IntegrityStorage error = %v", err)
+	}
+	if s := store.Stats(); s.Stored != 3 || s.Rejected != 0 || dds.Storage.NoCopyStores() != 3 {
+		t.Fatalf("Stats() = %+v with %d zero-copy stores, want 3 chunks stored without copying", s, dds.Storage.NoCopyStores())
+	}
+
+	_, chunks := testutil.Chunk([]byte("good"), 4)
+	if err := store.StoreChunk(chunks[0].ChunkCID, []byte("evil")); !errors.Is(err, ErrIntegrity) {
+		t.Errorf("StoreChunk() of altered data error = %v, want ErrIntegrity", err)
+	}
+	if store.ChunkExists(chunks[0].ChunkCID) || store.Stats().LastRejected != chunks[0].ChunkCID {
+		t.Errorf("altered chunk was stored, or Stats() = %+v does not name it", store.Stats())
+	}
+}
+
+func TestIntegrityStorage_Manifests(t *testing.T) {
+	manifest, _ := testutil.Chunk([]byte("hello, world"), 4)
+	encoded, err := EncodeManifest(manifest, ManifestEncodingCBOR)
+	if err != nil {
+		t.Fatalf("EncodeManifest() error = %v", err)
+	}
+	chunksOnly, _ := NewIntegrityStorage(testutil.NewStorage(), IntegrityOptions{})
+	if err := chunksOnly.StoreChunk(manifest.ManifestCID, encoded); !errors.Is(err, ErrIntegrity) {
+		t.Errorf("StoreChunk() of a manifest without Manifests error = %v, want ErrIntegrity", err)
+	}
+
+	store, _ := NewIntegrityStorage(testutil.NewStorage(), IntegrityOptions{Manifests: true})
+	if err := store.StoreChunk(manifest.ManifestCID, encoded); err != nil {
+		t.Errorf("StoreChunk() of a manifest under its CID error = %v", err)
+	}
+	if err := store.StoreChunk("other-cid", encoded); !errors.Is(err, ErrIntegrity) {
+		t.Errorf("StoreChunk() of a manifest under another CID error = %v, want ErrIntegrity", err)
+	}
+	broken := *manifest
+	broken.TotalSize++
+	if data, _ := EncodeManifest(&broken, ManifestEncodingJSON); !errors.Is(store.StoreChunk(broken.ManifestCID, data), ErrIntegrity) {
+		t.Error("StoreChunk() of a manifest whose sizes do not add up succeeded")
+	}
+
+	derived, _ := NewIntegrityStorage(testutil.NewStorage(), IntegrityOptions{Manifests: true, ManifestCID: func(m *chunking.ContentManifestV1) string {
+		return strings.TrimPrefix(m.ManifestCID, "test_manifest_")
+	}})
+	if err := derived.StoreChunk(manifest.ManifestCID, encoded); !errors.Is(err, ErrIntegrity) {
+		t.Errorf("StoreChunk() of a manifest whose CID does not derive error = %v, want ErrIntegrity", err)
+	}
+}
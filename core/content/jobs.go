@@ -0,0 +1,520 @@
+package content
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultJobRetention is the number of finished jobs kept for status queries
+// when AsyncOptions leaves Retention zero.
+const DefaultJobRetention = 100
+
+// ErrJobNotFound is returned for a job ID that is not queued, running, or
+// among the finished jobs still retained.
+var ErrJobNotFound = errors.New("publish job not found")
+
+// JobID identifies an asynchronous publication.
+type JobID string
+
+// JobState is the stage an asynchronous publication has reached.
+type JobState string
+
+// Job states, in the order a job goes through them.
+const (
+	JobQueued      JobState = "queued"      // Spooled, waiting for RunJobs
+	JobChunking    JobState = "chunking"    // Being chunked
+	JobStoring     JobState = "storing"     // ChunksDone of Chunks stored or already held
+	JobAdvertising JobState = "advertising" // Stored; being signed and advertised
+	JobDone        JobState = "done"        // Published under ManifestCID
+	JobFailed      JobState = "failed"      // Gave up; see Error
+)
+
+// JobStatus is a snapshot of an asynchronous publication.
+type JobStatus struct {
+	ID          JobID     `json:"id"`
+	State       JobState  `json:"state"`
+	ChunksDone  int       `json:"chunksDone"`
+	Chunks      int       `json:"chunks"` // Known once chunked
+	ManifestCID string    `json:"manifestCID,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Queued      time.Time `json:"queued"`
+	Updated     time.Time `json:"updated"`
+}
+
+// Finished reports whether the job is done or failed.
+func (s JobStatus) Finished() bool {
+	return s.State == JobDone || s.State == JobFailed
+}
+
+// publishProgress is told of each stage of a publication as it is reached,
+// and of each chunk stored.
+type publishProgress func(state JobState, done, total int)
+
+func (p publishProgress) report(state JobState, done, total int) {
+	if p != nil {
+		p(state, done, total)
+	}
+}
+
+// JobStore persists asynchronous publications between restarts: the job
+// statuses, and the content of each job until it finishes.
+type JobStore interface {
+	// LoadJobs returns the saved jobs, or nil if nothing has been saved yet.
+	LoadJobs() ([]JobStatus, error)
+	SaveJobs(jobs []JobStatus) error
+	// PutJobContent spools all of content for the job. On error nothing is
+	// kept.
+	PutJobContent(id JobID, content io.Reader) error
+	OpenJobContent(id JobID) (io.ReadCloser, error)
+	DeleteJobContent(id JobID) error
+}
+
+// FileJobStore is a JobStore keeping the jobs in jobs.json in a directory,
+// and the content of each job in a file beside it. Content may be private
+// until it is published, so files are written with mode 0600.
+type FileJobStore struct {
+	dir string
+}
+
+// NewFileJobStore creates a FileJobStore in dir, creating dir if needed.
+func NewFileJobStore(dir string) (*FileJobStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("job store directory cannot be empty")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create job store directory %s: %w", dir, err)
+	}
+	return &FileJobStore{dir: dir}, nil
+}
+
+// LoadJobs reads jobs.json. A missing file is not an error.
+func (fs *FileJobStore) LoadJobs() ([]JobStatus, error) {
+	path := filepath.Join(fs.dir, "jobs.json")
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jobs %s: %w", path, err)
+	}
+	var jobs []JobStatus
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to decode jobs %s: %w", path, err)
+	}
+	return jobs, nil
+}
+
+// SaveJobs atomically replaces jobs.json.
+func (fs *FileJobStore) SaveJobs(jobs []JobStatus) error {
+	data, err := json.Marshal(jobs)
+	if err != nil {
+		return fmt.Errorf("failed to encode jobs: %w", err)
+	}
+	path := filepath.Join(fs.dir, "jobs.json")
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		return fmt.Errorf("failed to write jobs %s.tmp: %w", path, err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to replace jobs %s: %w", path, err)
+	}
+	return nil
+}
+
+// PutJobContent writes content to a temporary file and renames it into
+// place once complete.
+func (fs *FileJobStore) PutJobContent(id JobID, content io.Reader) error {
+	path := fs.contentPath(id)
+	f, err := os.OpenFile(path+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create job content %s.tmp: %w", path, err)
+	}
+	_, err = io.Copy(f, content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return fmt.Errorf("failed to spool job content %s: %w", path, err)
+	}
+	return nil
+}
+
+// OpenJobContent opens the content file of the job.
+func (fs *FileJobStore) OpenJobContent(id JobID) (io.ReadCloser, error) {
+	return os.Open(fs.contentPath(id))
+}
+
+// DeleteJobContent removes the content file of the job. A missing file is
+// not an error.
+func (fs *FileJobStore) DeleteJobContent(id JobID) error {
+	if err := os.Remove(fs.contentPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (fs *FileJobStore) contentPath(id JobID) string {
+	return filepath.Join(fs.dir, string(id)+".content")
+}
+
+// memoryJobStore keeps job content in memory and does not save jobs, for
+// publishers whose AsyncOptions has no Store.
+type memoryJobStore struct {
+	mu      sync.Mutex
+	content map[JobID][]byte
+}
+
+func (ms *memoryJobStore) LoadJobs() ([]JobStatus, error) { return nil, nil }
+
+func (ms *memoryJobStore) SaveJobs([]JobStatus) error { return nil }
+
+func (ms *memoryJobStore) PutJobContent(id JobID, content io.Reader) error {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.content[id] = data
+	return nil
+}
+
+func (ms *memoryJobStore) OpenJobContent(id JobID) (io.ReadCloser, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	data, ok := ms.content[id]
+	if !ok {
+		return nil, fmt.Errorf("no content for job %s", id)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (ms *memoryJobStore) DeleteJobContent(id JobID) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.content, id)
+	return nil
+}
+
+// AsyncOptions configures asynchronous publishing.
+type AsyncOptions struct {
+	// Store persists jobs and their content. Nil keeps them in memory only,
+	// so queued jobs are lost on restart.
+	Store JobStore
+	// Retention is the number of finished jobs kept for status queries; the
+	// oldest are forgotten first.
+	Retention int
+}
+
+// jobQueue holds the asynchronous publications of a ContentPublisher.
+type jobQueue struct {
+	store     JobStore
+	retention int
+
+	mu       sync.Mutex
+	jobs     []*JobStatus // In queue order
+	watchers map[JobID][]chan JobStatus
+	wake     chan struct{}
+}
+
+// EnableAsyncPublish enables PublishAsync and loads the jobs saved in
+// opts.Store. Jobs a restart interrupted are queued again; publication is
+// idempotent, so they store only the chunks still missing. Jobs run only
+// while RunJobs does.
+func (cp *ContentPublisher) EnableAsyncPublish(opts AsyncOptions) error {
+	q := &jobQueue{
+		store:     opts.Store,
+		retention: opts.Retention,
+		watchers:  make(map[JobID][]chan JobStatus),
+		wake:      make(chan struct{}, 1),
+	}
+	if q.store == nil {
+		q.store = &memoryJobStore{content: make(map[JobID][]byte)}
+	}
+	if q.retention <= 0 {
+		q.retention = DefaultJobRetention
+	}
+	saved, err := q.store.LoadJobs()
+	if err != nil {
+		return err
+	}
+	for i := range saved {
+		job := &saved[i]
+		if !job.Finished() && job.State != JobQueued {
+			job.State, job.ChunksDone, job.Chunks = JobQueued, 0, 0
+		}
+		q.jobs = append(q.jobs, job)
+	}
+	cp.jobs = q
+	return nil
+}
+
+// PublishAsync spools the content read from reader, queues it for RunJobs to
+// publish, and returns the job's ID once the content is spooled, so a caller
+// uploading large media need not wait for it to be chunked, stored and
+// advertised. Content over the publisher's size limit is refused while it
+// is spooled. Follow the job with JobStatus or WatchJob.
+func (cp *ContentPublisher) PublishAsync(reader io.Reader) (JobID, error) {
+	q := cp.jobs
+	if q == nil {
+		return "", errors.New("asynchronous publishing is not enabled")
+	}
+	reader, limited, err := cp.limits.limitReader(reader, cp.chunker)
+	if err != nil {
+		return "", err
+	}
+	id := JobID("job-" + randomHex(8))
+	if err := q.store.PutJobContent(id, reader); err != nil {
+		if limited != nil && limited.err != nil {
+			return "", limited.err
+		}
+		return "", fmt.Errorf("failed to spool content for job %s: %w", id, err)
+	}
+
+	now := time.Now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.jobs = append(q.jobs, &JobStatus{ID: id, State: JobQueued, Queued: now, Updated: now})
+	if err := q.saveLocked(); err != nil {
+		q.jobs = q.jobs[:len(q.jobs)-1]
+		q.store.DeleteJobContent(id)
+		return "", err
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return id, nil
+}
+
+// JobStatus returns the status of a job.
+func (cp *ContentPublisher) JobStatus(id JobID) (JobStatus, error) {
+	q := cp.jobs
+	if q == nil {
+		return JobStatus{}, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := q.findLocked(id)
+	if job == nil {
+		return JobStatus{}, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return *job, nil
+}
+
+// WatchJob returns a channel receiving the job's current status and then
+// each change, closed once the job finishes or stop is called. A slow
+// receiver misses intermediate statuses but always gets the latest one,
+// and the last status before the channel closes is the final one.
+func (cp *ContentPublisher) WatchJob(id JobID) (updates <-chan JobStatus, stop func(), err error) {
+	q := cp.jobs
+	if q == nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := q.findLocked(id)
+	if job == nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	ch := make(chan JobStatus, 1)
+	ch <- *job
+	if job.Finished() {
+		close(ch)
+		return ch, func() {}, nil
+	}
+	q.watchers[id] = append(q.watchers[id], ch)
+	stop = func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		watchers := q.watchers[id]
+		for i, w := range watchers {
+			if w == ch {
+				q.watchers[id] = append(watchers[:i:i], watchers[i+1:]...)
+				close(ch)
+				return
+			}
+		}
+	}
+	return ch, stop, nil
+}
+
+// RunJobs publishes queued jobs, one at a time in queue order, until ctx is
+// done. A job whose publication fails is failed, not retried; one cut short
+// by ctx is queued again.
+func (cp *ContentPublisher) RunJobs(ctx context.Context) error {
+	q := cp.jobs
+	if q == nil {
+		return errors.New("asynchronous publishing is not enabled")
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		id, ok := q.next()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-q.wake:
+			}
+			continue
+		}
+		cp.runJob(ctx, id)
+	}
+}
+
+// runJob publishes the content of a queued job.
+func (cp *ContentPublisher) runJob(ctx context.Context, id JobID) {
+	q := cp.jobs
+	content, err := q.store.OpenJobContent(id)
+	if err != nil {
+		q.finish(id, "", fmt.Errorf("failed to open job content: %w", err))
+		return
+	}
+	result, err := cp.publish(ctx, content, func(state JobState, done, total int) {
+		q.update(id, state, done, total)
+	})
+	content.Close()
+	if err != nil && ctx.Err() != nil {
+		q.update(id, JobQueued, 0, 0)
+		return
+	}
+	q.finish(id, result.ManifestCID, err)
+}
+
+// next returns the first queued job.
+func (q *jobQueue) next() (JobID, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, job := range q.jobs {
+		if job.State == JobQueued {
+			return job.ID, true
+		}
+	}
+	return "", false
+}
+
+// update records the progress of a running job, saving the jobs when it
+// reaches a new stage.
+func (q *jobQueue) update(id JobID, state JobState, done, total int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := q.findLocked(id)
+	if job == nil {
+		return
+	}
+	changed := job.State != state
+	job.State, job.ChunksDone, job.Chunks, job.Updated = state, done, total, time.Now()
+	if changed {
+		q.saveOrLogLocked()
+	}
+	q.notifyLocked(job)
+}
+
+// finish marks a job done, or failed with err, drops its content, and
+// forgets the oldest finished jobs beyond the retention.
+func (q *jobQueue) finish(id JobID, manifestCID string, err error) {
+	if deleteErr := q.store.DeleteJobContent(id); deleteErr != nil {
+		log.Printf("ContentPublisher: failed to delete content of job %s: %v\n", id, deleteErr)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := q.findLocked(id)
+	if job == nil {
+		return
+	}
+	job.State, job.ManifestCID, job.Updated = JobDone, manifestCID, time.Now()
+	if err != nil {
+		job.State, job.Error = JobFailed, err.Error()
+	}
+	finished := 0
+	for _, j := range q.jobs {
+		if j.Finished() {
+			finished++
+		}
+	}
+	kept := q.jobs[:0]
+	for _, j := range q.jobs {
+		if j.Finished() && finished > q.retention {
+			finished--
+			continue
+		}
+		kept = append(kept, j)
+	}
+	q.jobs = kept
+	q.saveOrLogLocked()
+	q.notifyLocked(job)
+}
+
+// findLocked returns the job with the given ID. The caller must hold q.mu.
+func (q *jobQueue) findLocked(id JobID) *JobStatus {
+	for _, job := range q.jobs {
+		if job.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// saveLocked persists the jobs. The caller must hold q.mu.
+func (q *jobQueue) saveLocked() error {
+	jobs := make([]JobStatus, len(q.jobs))
+	for i, job := range q.jobs {
+		jobs[i] = *job
+	}
+	return q.store.SaveJobs(jobs)
+}
+
+// saveOrLogLocked persists the jobs, logging a failure: the job carries on,
+// and a restart at worst repeats it. The caller must hold q.mu.
+func (q *jobQueue) saveOrLogLocked() {
+	if err := q.saveLocked(); err != nil {
+		log.Printf("ContentPublisher: failed to save publish jobs: %v\n", err)
+	}
+}
+
+// notifyLocked sends the job's status to its watchers, replacing any status
+// they have not received yet, and closes their channels once it finishes.
+// The caller must hold q.mu.
+func (q *jobQueue) notifyLocked(job *JobStatus) {
+	for _, ch := range q.watchers[job.ID] {
+		select {
+		case ch <- *job:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- *job
+		}
+		if job.Finished() {
+			close(ch)
+		}
+	}
+	if job.Finished() {
+		delete(q.watchers, job.ID)
+	}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,140 @@
+package content
+
+import (
+	"context"
+	"digisocialblock/internal/testutil"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// jobsTestWait returns the final status of a job, failing the test if it
+// does not finish in time.
+func jobsTestWait(t *testing.T, cp *ContentPublisher, id JobID) []JobStatus {
+	t.Helper()
+	updates, stop, err := cp.WatchJob(id)
+	if err != nil {
+		t.Fatalf("WatchJob() error = %v", err)
+	}
+	defer stop()
+	var seen []JobStatus
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case status, ok := <-updates:
+			if !ok {
+				return seen
+			}
+			seen = append(seen, status)
+		case <-timeout:
+			t.Fatalf("job %s did not finish; saw %+v", id, seen)
+		}
+	}
+}
+
+func TestContentPublisher_PublishAsync(t *testing.T) {
+	dds := testutil.NewDDS(4)
+	publisher, err := NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	if err != nil {
+		t.Fatalf("NewContentPublisher() error = %v", err)
+	}
+	if _, err := publisher.PublishAsync(strings.NewReader("hello")); err == nil {
+		t.Fatal("PublishAsync() before EnableAsyncPublish succeeded")
+	}
+	if err := publisher.EnableAsyncPublish(AsyncOptions{Retention: 1}); err != nil {
+		t.Fatalf("EnableAsyncPublish() error = %v", err)
+	}
+	if err := publisher.SetLimits(PublishLimits{MaxTotalSize: 64}); err != nil {
+		t.Fatalf("SetLimits() error = %v", err)
+	}
+
+	id, err := publisher.PublishAsync(strings.NewReader("hello, world"))
+	if err != nil {
+		t.Fatalf("PublishAsync() error = %v", err)
+	}
+	if status, err := publisher.JobStatus(id); err != nil || status.State != JobQueued {
+		t.Fatalf("JobStatus() before RunJobs = %+v, %v; want queued", status, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go publisher.RunJobs(ctx)
+
+	seen := jobsTestWait(t, publisher, id)
+	want, _ := testutil.Chunk([]byte("hello, world"), 4)
+	final := seen[len(seen)-1]
+	if final.State != JobDone || final.ManifestCID != want.ManifestCID || final.ChunksDone != 3 || final.Chunks != 3 {
+		t.Fatalf("final status = %+v, want done under %s with 3 of 3 chunks", final, want.ManifestCID)
+	}
+	if dds.Storage.Len() != 3 || len(dds.Originator.Advertised()) != 1 {
+		t.Errorf("job stored %d chunks and advertised %d times, want 3 and once", dds.Storage.Len(), len(dds.Originator.Advertised()))
+	}
+	for i := 1; i < len(seen); i++ {
+		if seen[i].ChunksDone < seen[i-1].ChunksDone && seen[i].State == seen[i-1].State {
+			t.Errorf("progress went backwards: %+v after %+v", seen[i], seen[i-1])
+		}
+	}
+
+	// A failed publication fails the job, and only the latest finished job is retained.
+	dds.Storage.FailStores(errors.New("disk full"))
+	failing, err := publisher.PublishAsync(strings.NewReader("something new"))
+	if err != nil {
+		t.Fatalf("PublishAsync() error = %v", err)
+	}
+	if seen := jobsTestWait(t, publisher, failing); seen[len(seen)-1].State != JobFailed || !strings.Contains(seen[len(seen)-1].Error, "disk full") {
+		t.Errorf("final status of a failing job = %+v, want failed with the storage error", seen[len(seen)-1])
+	}
+	if _, err := publisher.JobStatus(id); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("JobStatus() of a job beyond the retention error = %v, want ErrJobNotFound", err)
+	}
+
+	if _, err := publisher.PublishAsync(strings.NewReader(strings.Repeat("x", 65))); !errors.Is(err, ErrContentTooLarge) {
+		t.Errorf("PublishAsync() of 65 bytes error = %v, want ErrContentTooLarge", err)
+	}
+}
+
+func TestContentPublisher_PublishAsyncResumesAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileJobStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileJobStore() error = %v", err)
+	}
+	// A job that was storing when the node stopped, with one chunk stored.
+	interrupted := JobStatus{ID: "job-1", State: JobStoring, ChunksDone: 1, Chunks: 3, Queued: time.Now()}
+	if err := store.SaveJobs([]JobStatus{interrupted}); err != nil {
+		t.Fatalf("SaveJobs() error = %v", err)
+	}
+	if err := store.PutJobContent("job-1", strings.NewReader("hello, world")); err != nil {
+		t.Fatalf("PutJobContent() error = %v", err)
+	}
+	dds := testutil.NewDDS(4)
+	_, chunks := testutil.Chunk([]byte("hello, world"), 4)
+	dds.Storage.Put(chunks[0].ChunkCID, chunks[0].Data)
+
+	publisher, _ := NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	if err := publisher.EnableAsyncPublish(AsyncOptions{Store: store}); err != nil {
+		t.Fatalf("EnableAsyncPublish() error = %v", err)
+	}
+	if status, err := publisher.JobStatus("job-1"); err != nil || status.State != JobQueued {
+		t.Fatalf("JobStatus() of an interrupted job = %+v, %v; want queued again", status, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go publisher.RunJobs(ctx)
+	if seen := jobsTestWait(t, publisher, "job-1"); seen[len(seen)-1].State != JobDone {
+		t.Fatalf("resumed job ended %+v, want done", seen[len(seen)-1])
+	}
+	if dds.Storage.Stores() != 2 {
+		t.Errorf("resumed job made %d stores, want the 2 missing chunks", dds.Storage.Stores())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "job-1.content")); !os.IsNotExist(err) {
+		t.Errorf("content of a finished job was kept: %v", err)
+	}
+
+	saved, err := store.LoadJobs()
+	if err != nil || len(saved) != 1 || saved[0].State != JobDone {
+		t.Errorf("LoadJobs() = %+v, %v; want the job saved as done", saved, err)
+	}
+}
@@ -0,0 +1,39 @@
+package content
+
+// Response headers in which the gateway returns a manifest's content labels.
+// The warning is RFC 2047 encoded if it is not plain ASCII.
+const (
+	HeaderContentWarning = "X-DSB-Content-Warning"
+	HeaderSensitive      = "X-DSB-Sensitive"
+)
+
+// ContentLabels are the content warning and sensitive-media flag that a post
+// attaches to its content. They live in the post metadata on chain, so a
+// client can decide whether to show content before fetching any of it.
+type ContentLabels struct {
+	ContentWarning string `json:"contentWarning,omitempty"`
+	Sensitive      bool   `json:"sensitive,omitempty"`
+}
+
+// LabelSource looks up the labels of content by manifest CID.
+// social.FeedService implements it.
+type LabelSource interface {
+	ContentLabels(manifestCID string) (ContentLabels, bool)
+}
+
+// SetLabelSource makes the retriever (and any Gateway serving from it) report
+// content labels from src. It must be called before the retriever is shared
+// between goroutines; nil disables labels.
+func (cr *ContentRetriever) SetLabelSource(src LabelSource) {
+	cr.labels = src
+}
+
+// Labels returns the labels of manifestCID without fetching its manifest or
+// chunks. ok is false if there is no label source or it has no labels for
+// the CID.
+func (cr *ContentRetriever) Labels(manifestCID string) (labels ContentLabels, ok bool) {
+	if cr.labels == nil {
+		return ContentLabels{}, false
+	}
+	return cr.labels.ContentLabels(manifestCID)
+}
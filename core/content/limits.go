@@ -0,0 +1,152 @@
+package content
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Errors returned for content that exceeds the publisher's PublishLimits.
+var (
+	ErrContentTooLarge       = errors.New("content exceeds the maximum size")
+	ErrTooManyChunks         = errors.New("content exceeds the maximum number of chunks")
+	ErrContentTypeNotAllowed = errors.New("content type is not allowed")
+)
+
+// PublishLimits bounds what a publisher accepts, so a node does not take
+// uploads it cannot store or propagate. Zero values impose no limit.
+type PublishLimits struct {
+	MaxTotalSize int64 // Bytes
+	MaxChunks    int
+	// AllowedMediaTypes lists the media types PublishMediaToDDS accepts, as
+	// sniffed from the data by http.DetectContentType, e.g. "image/png", or
+	// "image/*" for every image type. Empty allows every type. Text posts
+	// and streams are not sniffed.
+	AllowedMediaTypes []string
+}
+
+// ChunkSizer is an optional extension of DDSChunker for chunkers that know
+// the chunk size they will use for content of a given size, letting the
+// publisher enforce MaxChunks before chunking. For other chunkers MaxChunks
+// is enforced after chunking, but still before anything is stored.
+type ChunkSizer interface {
+	ChunkSizeFor(contentSize int64) int
+}
+
+// Validate checks that no limit is negative and every media type is of the
+// form "type/subtype" or "type/*".
+func (l PublishLimits) Validate() error {
+	if l.MaxTotalSize < 0 {
+		return fmt.Errorf("max total size must not be negative, got %d", l.MaxTotalSize)
+	}
+	if l.MaxChunks < 0 {
+		return fmt.Errorf("max chunks must not be negative, got %d", l.MaxChunks)
+	}
+	for _, mediaType := range l.AllowedMediaTypes {
+		typ, sub, ok := strings.Cut(mediaType, "/")
+		if !ok || typ == "" || sub == "" || typ == "*" || strings.ContainsAny(mediaType, " ;") {
+			return fmt.Errorf("invalid media type %q", mediaType)
+		}
+	}
+	return nil
+}
+
+// SetLimits sets the limits every later publication is checked against,
+// including staged ones. The zero PublishLimits removes them.
+func (cp *ContentPublisher) SetLimits(limits PublishLimits) error {
+	if err := limits.Validate(); err != nil {
+		return fmt.Errorf("invalid publish limits: %w", err)
+	}
+	limits.AllowedMediaTypes = append([]string(nil), limits.AllowedMediaTypes...)
+	cp.limits = limits
+	return nil
+}
+
+// Limits returns the limits set by SetLimits.
+func (cp *ContentPublisher) Limits() PublishLimits {
+	return cp.limits
+}
+
+// checkMediaType returns ErrContentTypeNotAllowed unless the sniffed type of
+// data is allowed.
+func (l PublishLimits) checkMediaType(data []byte) error {
+	if len(l.AllowedMediaTypes) == 0 {
+		return nil
+	}
+	detected, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	for _, allowed := range l.AllowedMediaTypes {
+		if allowed == detected || (strings.HasSuffix(allowed, "/*") && strings.HasPrefix(detected, strings.TrimSuffix(allowed, "*"))) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s (allowed: %s)", ErrContentTypeNotAllowed, detected, strings.Join(l.AllowedMediaTypes, ", "))
+}
+
+// checkSize returns ErrContentTooLarge or ErrTooManyChunks if content of size
+// bytes, chunked by chunker, would exceed the limits.
+func (l PublishLimits) checkSize(size int64, chunker DDSChunker) error {
+	if l.MaxTotalSize > 0 && size > l.MaxTotalSize {
+		return fmt.Errorf("%w: %d bytes, the maximum is %d", ErrContentTooLarge, size, l.MaxTotalSize)
+	}
+	if sizer, ok := chunker.(ChunkSizer); ok && l.MaxChunks > 0 {
+		if chunkSize := int64(sizer.ChunkSizeFor(size)); chunkSize > 0 {
+			if chunks := (size + chunkSize - 1) / chunkSize; chunks > int64(l.MaxChunks) {
+				return fmt.Errorf("%w: %d bytes in %d-byte chunks is %d chunks, the maximum is %d", ErrTooManyChunks, size, chunkSize, chunks, l.MaxChunks)
+			}
+		}
+	}
+	return nil
+}
+
+// checkChunks returns ErrTooManyChunks if a chunked publication has more
+// chunks than the limit.
+func (l PublishLimits) checkChunks(chunks int) error {
+	if l.MaxChunks > 0 && chunks > l.MaxChunks {
+		return fmt.Errorf("%w: %d chunks, the maximum is %d", ErrTooManyChunks, chunks, l.MaxChunks)
+	}
+	return nil
+}
+
+// limitReader wraps reader so content is checked before it is chunked: a
+// reader that reports its length (bytes.Reader, strings.Reader) is checked
+// up front, and any other reader fails once it yields more than
+// MaxTotalSize bytes, so the chunker never buffers an oversized upload.
+func (l PublishLimits) limitReader(reader io.Reader, chunker DDSChunker) (io.Reader, *sizeLimitedReader, error) {
+	if sized, ok := reader.(interface{ Len() int }); ok {
+		return reader, nil, l.checkSize(int64(sized.Len()), chunker)
+	}
+	if l.MaxTotalSize <= 0 {
+		return reader, nil, nil
+	}
+	limited := &sizeLimitedReader{reader: reader, remaining: l.MaxTotalSize, max: l.MaxTotalSize}
+	return limited, limited, nil
+}
+
+// sizeLimitedReader fails with ErrContentTooLarge once more than max bytes
+// have been read, and remembers that it did, since a chunker may not wrap
+// the errors of its reader.
+type sizeLimitedReader struct {
+	reader    io.Reader
+	remaining int64
+	max       int64
+	err       error
+}
+
+func (r *sizeLimitedReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	// Read one byte past the limit to tell content of exactly max bytes from larger content.
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.reader.Read(p)
+	if int64(n) > r.remaining {
+		r.err = fmt.Errorf("%w: more than %d bytes", ErrContentTooLarge, r.max)
+		return 0, r.err
+	}
+	r.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,68 @@
+package content
+
+import (
+	"context"
+	"digisocialblock/internal/testutil"
+	"digisocialblock/pkg/dds/chunking"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// limitsTestChunker hides the ChunkSizer method of a testutil.Chunker, so
+// chunk counts are only known after chunking.
+type limitsTestChunker struct{ chunker *testutil.Chunker }
+
+func (c limitsTestChunker) ChunkData(data io.Reader) (*chunking.ContentManifestV1, []chunking.DataChunk, error) {
+	return c.chunker.ChunkData(data)
+}
+
+func TestContentPublisher_Limits(t *testing.T) {
+	dds := testutil.NewDDS(4)
+	publisher, err := NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	if err != nil {
+		t.Fatalf("NewContentPublisher() error = %v", err)
+	}
+	if err := publisher.SetLimits(PublishLimits{MaxTotalSize: 16, MaxChunks: 3, AllowedMediaTypes: []string{"image/*", "text/plain"}}); err != nil {
+		t.Fatalf("SetLimits() error = %v", err)
+	}
+
+	if _, err := publisher.PublishTextPostToDDS("twelve bytes"); err != nil {
+		t.Fatalf("PublishTextPostToDDS() within the limits error = %v", err)
+	}
+	stores := dds.Storage.Stores()
+	if _, err := publisher.PublishTextPostToDDS("seventeen bytes!!"); !errors.Is(err, ErrContentTooLarge) {
+		t.Errorf("PublishTextPostToDDS() of 17 bytes error = %v, want ErrContentTooLarge", err)
+	}
+	if _, err := publisher.PublishTextPostToDDS("thirteen byte"); !errors.Is(err, ErrTooManyChunks) {
+		t.Errorf("PublishTextPostToDDS() of 4 chunks error = %v, want ErrTooManyChunks", err)
+	}
+	if _, err := publisher.PublishMediaToDDS([]byte("%PDF-1.7")); !errors.Is(err, ErrContentTypeNotAllowed) || !strings.Contains(err.Error(), "application/pdf") {
+		t.Errorf("PublishMediaToDDS() of a PDF error = %v, want ErrContentTypeNotAllowed naming it", err)
+	}
+	if _, err := publisher.PublishMediaToDDS([]byte("\x89PNG\r\n\x1a\n")); err != nil {
+		t.Errorf("PublishMediaToDDS() of a PNG error = %v", err)
+	}
+
+	// A stream of unknown length is cut off at the limit, whatever the chunker.
+	if _, err := publisher.PublishStream(context.Background(), io.LimitReader(strings.NewReader(strings.Repeat("x", 100)), 100)); !errors.Is(err, ErrContentTooLarge) {
+		t.Errorf("PublishStream() of 100 bytes error = %v, want ErrContentTooLarge", err)
+	}
+	opaque, _ := NewContentPublisher(limitsTestChunker{dds.Chunker}, dds.Storage, dds.Originator)
+	if err := opaque.SetLimits(PublishLimits{MaxChunks: 1}); err != nil {
+		t.Fatalf("SetLimits() error = %v", err)
+	}
+	if _, err := opaque.PublishTextPostToDDS("ten bytes!"); !errors.Is(err, ErrTooManyChunks) {
+		t.Errorf("PublishTextPostToDDS() of 3 chunks from a chunker without ChunkSizeFor error = %v, want ErrTooManyChunks", err)
+	}
+	if got := dds.Storage.Stores(); got != stores+2 {
+		t.Errorf("refused content was stored: %d stores, want %d for the PNG alone", got, stores+2)
+	}
+
+	for _, bad := range []PublishLimits{{MaxTotalSize: -1}, {MaxChunks: -1}, {AllowedMediaTypes: []string{"image"}}, {AllowedMediaTypes: []string{"*/*"}}} {
+		if err := publisher.SetLimits(bad); err == nil {
+			t.Errorf("SetLimits(%+v) succeeded", bad)
+		}
+	}
+}
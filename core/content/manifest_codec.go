@@ -0,0 +1,74 @@
+package content
+
+import (
+	"bytes"
+	"digisocialblock/core/codec"
+	"digisocialblock/pkg/dds/chunking"
+	"encoding/json"
+	"fmt"
+)
+
+// ManifestEncoding selects how a manifest is serialized for storage and transfer.
+type ManifestEncoding int
+
+const (
+	// ManifestEncodingJSON is the original, unprefixed JSON form. The gateway
+	// and external APIs always use it.
+	ManifestEncodingJSON ManifestEncoding = iota
+	// ManifestEncodingCBOR is deterministic CBOR prefixed with ManifestVersionCBOR.
+	ManifestEncodingCBOR
+)
+
+// ManifestVersionCBOR is the version byte that prefixes CBOR manifests. JSON
+// manifests start with '{' (or whitespace), so the first byte identifies the encoding.
+const ManifestVersionCBOR byte = 0x01
+
+// EncodeManifest serializes manifest with the given encoding.
+func EncodeManifest(manifest *chunking.ContentManifestV1, enc ManifestEncoding) ([]byte, error) {
+	if manifest == nil {
+		return nil, fmt.Errorf("manifest cannot be nil")
+	}
+	switch enc {
+	case ManifestEncodingJSON:
+		data, err := json.Marshal(manifest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode manifest %s as JSON: %w", manifest.ManifestCID, err)
+		}
+		return data, nil
+	case ManifestEncodingCBOR:
+		body, err := codec.Marshal(manifest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode manifest %s as CBOR: %w", manifest.ManifestCID, err)
+		}
+		return append([]byte{ManifestVersionCBOR}, body...), nil
+	default:
+		return nil, fmt.Errorf("unknown manifest encoding %d", enc)
+	}
+}
+
+// DecodeManifest deserializes a manifest in either encoding, detected from its
+// first byte. Unknown fields are rejected in both.
+func DecodeManifest(data []byte) (*chunking.ContentManifestV1, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("manifest data is empty")
+	}
+	var manifest chunking.ContentManifestV1
+	switch data[0] {
+	case ManifestVersionCBOR:
+		if err := codec.Unmarshal(data[1:], &manifest); err != nil {
+			return nil, fmt.Errorf("failed to decode CBOR manifest: %w", err)
+		}
+	case '{', ' ', '\t', '\n', '\r':
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&manifest); err != nil {
+			return nil, fmt.Errorf("failed to decode JSON manifest: %w", err)
+		}
+		if dec.More() {
+			return nil, fmt.Errorf("failed to decode JSON manifest: trailing data")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported manifest version byte 0x%02x", data[0])
+	}
+	return &manifest, nil
+}
@@ -0,0 +1,56 @@
+package content
+
+import (
+	"digisocialblock/pkg/dds/chunking"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeDecodeManifest(t *testing.T) {
+	manifest := &chunking.ContentManifestV1{
+		Version:          1,
+		ManifestCID:      "manifest_cid",
+		TotalSize:        300,
+		EncryptionMethod: "none",
+		Chunks: []chunking.ChunkInfo{
+			{ChunkCID: "chunk_cid_1", Size: 200},
+			{ChunkCID: "chunk_cid_2", Size: 100},
+		},
+	}
+	jsonData, err := EncodeManifest(manifest, ManifestEncodingJSON)
+	if err != nil {
+		t.Fatalf("EncodeManifest(JSON) error = %v", err)
+	}
+	cborData, err := EncodeManifest(manifest, ManifestEncodingCBOR)
+	if err != nil {
+		t.Fatalf("EncodeManifest(CBOR) error = %v", err)
+	}
+	if cborData[0] != ManifestVersionCBOR {
+		t.Errorf("CBOR manifest starts with 0x%02x, want 0x%02x", cborData[0], ManifestVersionCBOR)
+	}
+	if len(cborData) >= len(jsonData) {
+		t.Errorf("CBOR manifest is %d bytes, JSON %d; want CBOR smaller", len(cborData), len(jsonData))
+	}
+	for name, data := range map[string][]byte{"JSON": jsonData, "CBOR": cborData} {
+		got, err := DecodeManifest(data)
+		if err != nil {
+			t.Fatalf("DecodeManifest(%s) error = %v", name, err)
+		}
+		if !reflect.DeepEqual(got, manifest) {
+			t.Errorf("DecodeManifest(%s) = %+v, want %+v", name, got, manifest)
+		}
+	}
+
+	unknownField, _ := json.Marshal(map[string]interface{}{"version": 1, "extra": true})
+	for name, data := range map[string][]byte{
+		"empty":                nil,
+		"unknown version byte": {0x07, 0xa0},
+		"JSON unknown field":   unknownField,
+		"CBOR trailing byte":   append(append([]byte(nil), cborData...), 0x00),
+	} {
+		if _, err := DecodeManifest(data); err == nil {
+			t.Errorf("DecodeManifest(%s): expected error, got nil", name)
+		}
+	}
+}
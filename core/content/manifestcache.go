@@ -0,0 +1,251 @@
+package content
+
+import (
+	"container/list"
+	"digisocialblock/core/ledger"
+	"digisocialblock/pkg/dds/chunking"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Defaults for zero ManifestCacheOptions fields.
+const (
+	DefaultManifestCacheTTL         = 10 * time.Minute
+	DefaultManifestCacheNegativeTTL = 30 * time.Second
+	DefaultManifestCacheEntries     = 4096
+)
+
+// ManifestCacheOptions configures a ManifestCache.
+type ManifestCacheOptions struct {
+	TTL         time.Duration // How long a fetched manifest is served; defaults to DefaultManifestCacheTTL
+	NegativeTTL time.Duration // How long a failed fetch is remembered; defaults to DefaultManifestCacheNegativeTTL
+	MaxEntries  int           // Entries kept, least recently used evicted first; defaults to DefaultManifestCacheEntries
+	// NotFound reports whether a fetch error means the manifest does not
+	// exist, and so may be cached for NegativeTTL. Other errors are returned
+	// without being cached. Nil treats every error as not found.
+	NotFound func(error) bool
+}
+
+// ManifestCacheStats counts a ManifestCache's lookups.
+type ManifestCacheStats struct {
+	Entries       int   `json:"entries"`
+	Hits          int64 `json:"hits"`          // Manifests served from the cache
+	NegativeHits  int64 `json:"negativeHits"`  // Failures served from the cache
+	Misses        int64 `json:"misses"`        // Lookups passed to the fetcher
+	Expired       int64 `json:"expired"`       // Of the misses, entries that had outlived their TTL
+	Evictions     int64 `json:"evictions"`     // Entries dropped to stay within MaxEntries
+	Invalidations int64 `json:"invalidations"` // Entries dropped by Invalidate or Purge
+}
+
+// manifestCacheEntry is a cached manifest, or the error fetching it failed
+// with.
+type manifestCacheEntry struct {
+	cid      string
+	manifest *chunking.ContentManifestV1
+	err      error
+	expires  time.Time
+}
+
+// ManifestCache is a DDSManifestFetcher that remembers the manifests another
+// fetcher returns, so rendering a busy feed does not look the same manifest
+// up on the network for every post that shows it. Manifests are content
+// addressed and so never go stale, but each is only kept for a TTL to bound
+// how long a manifest retracted by its publisher keeps being served.
+// Manifests that could not be found are remembered for a shorter NegativeTTL,
+// so a missing CID does not cost a network lookup on every render either.
+// Entries can be dropped early with Invalidate or Purge, and OnInvalidate
+// hooks are told about them. Callers get their own copy of each manifest. A
+// ManifestCache is safe for concurrent use.
+type ManifestCache struct {
+	fetcher DDSManifestFetcher
+	opts    ManifestCacheOptions
+
+	mu      sync.Mutex
+	clock   ledger.Clock
+	entries map[string]*list.Element
+	order   *list.List // Front is most recently used
+	hooks   []func(manifestCID string)
+	stats   ManifestCacheStats
+	gen     uint64 // Bumped by Invalidate and Purge; a fetch begun before is not cached
+}
+
+// NewManifestCache creates a ManifestCache in front of fetcher.
+func NewManifestCache(fetcher DDSManifestFetcher, opts ManifestCacheOptions) (*ManifestCache, error) {
+	if fetcher == nil {
+		return nil, errors.New("manifest fetcher cannot be nil")
+	}
+	if opts.TTL < 0 || opts.NegativeTTL < 0 || opts.MaxEntries < 0 {
+		return nil, fmt.Errorf("manifest cache options cannot be negative")
+	}
+	if opts.TTL == 0 {
+		opts.TTL = DefaultManifestCacheTTL
+	}
+	if opts.NegativeTTL == 0 {
+		opts.NegativeTTL = DefaultManifestCacheNegativeTTL
+	}
+	if opts.MaxEntries == 0 {
+		opts.MaxEntries = DefaultManifestCacheEntries
+	}
+	return &ManifestCache{
+		fetcher: fetcher,
+		opts:    opts,
+		clock:   ledger.SystemClock,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}, nil
+}
+
+// SetClock sets the clock entries expire by. A nil clock restores
+// ledger.SystemClock.
+func (c *ManifestCache) SetClock(clock ledger.Clock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if clock == nil {
+		clock = ledger.SystemClock
+	}
+	c.clock = clock
+}
+
+// OnInvalidate registers hook to be called with the CID of every entry
+// dropped by Invalidate or Purge, for example to clear rendered content built
+// from it. Hooks run after the cache lock is released.
+func (c *ManifestCache) OnInvalidate(hook func(manifestCID string)) {
+	if hook == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hooks = append(c.hooks, hook)
+}
+
+// FetchManifest returns the cached manifest or failure for manifestCID, or
+// fetches it and caches the result.
+func (c *ManifestCache) FetchManifest(manifestCID string) (*chunking.ContentManifestV1, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[manifestCID]; ok {
+		e := elem.Value.(*manifestCacheEntry)
+		if c.clock.Now().Before(e.expires) {
+			c.order.MoveToFront(elem)
+			if e.err != nil {
+				c.stats.NegativeHits++
+				c.mu.Unlock()
+				return nil, e.err
+			}
+			c.stats.Hits++
+			c.mu.Unlock()
+			return copyManifest(e.manifest), nil
+		}
+		c.remove(elem)
+		c.stats.Expired++
+	}
+	c.stats.Misses++
+	gen := c.gen
+	c.mu.Unlock()
+
+	manifest, err := c.fetcher.FetchManifest(manifestCID)
+	if err == nil && manifest == nil {
+		err = fmt.Errorf("manifest CID %s: fetcher returned no manifest", manifestCID)
+	}
+	if err != nil {
+		if c.opts.NotFound == nil || c.opts.NotFound(err) {
+			c.store(&manifestCacheEntry{cid: manifestCID, err: err}, c.opts.NegativeTTL, gen)
+		}
+		return nil, err
+	}
+	c.store(&manifestCacheEntry{cid: manifestCID, manifest: copyManifest(manifest)}, c.opts.TTL, gen)
+	return manifest, nil
+}
+
+// store caches e for ttl, replacing any entry for the same CID and evicting
+// the least recently used entries beyond MaxEntries. The result of a fetch
+// begun at generation gen is dropped if an invalidation has happened since:
+// it may predate the change the invalidation is for.
+func (c *ManifestCache) store(e *manifestCacheEntry, ttl time.Duration, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen != gen {
+		return
+	}
+	e.expires = c.clock.Now().Add(ttl)
+	if elem, ok := c.entries[e.cid]; ok {
+		c.remove(elem)
+	}
+	c.entries[e.cid] = c.order.PushFront(e)
+	for c.order.Len() > c.opts.MaxEntries {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+// remove drops elem from the cache. c.mu must be held.
+func (c *ManifestCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*manifestCacheEntry).cid)
+}
+
+// Invalidate drops any entry for manifestCID, so the next fetch goes to the
+// network, and reports whether there was one. Fetches already under way are
+// not cached. Hooks are called either way.
+func (c *ManifestCache) Invalidate(manifestCID string) bool {
+	c.mu.Lock()
+	c.gen++
+	elem, ok := c.entries[manifestCID]
+	if ok {
+		c.remove(elem)
+		c.stats.Invalidations++
+	}
+	hooks := c.hooks
+	c.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook(manifestCID)
+	}
+	return ok
+}
+
+// Purge drops every entry and returns how many there were.
+func (c *ManifestCache) Purge() int {
+	c.mu.Lock()
+	var cids []string
+	for cid := range c.entries {
+		cids = append(cids, cid)
+	}
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+	c.gen++
+	c.stats.Invalidations += int64(len(cids))
+	hooks := c.hooks
+	c.mu.Unlock()
+
+	for _, cid := range cids {
+		for _, hook := range hooks {
+			hook(cid)
+		}
+	}
+	return len(cids)
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *ManifestCache) Stats() ManifestCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Entries = c.order.Len()
+	return s
+}
+
+// Var returns the cache stats as an expvar.Var, for publishing with
+// expvar.Publish.
+func (c *ManifestCache) Var() expvar.Var {
+	return expvar.Func(func() interface{} { return c.Stats() })
+}
+
+// copyManifest returns a copy of m that shares none of its chunk list.
+func copyManifest(m *chunking.ContentManifestV1) *chunking.ContentManifestV1 {
+	c := *m
+	c.Chunks = append([]chunking.ChunkInfo(nil), m.Chunks...)
+	return &c
+}
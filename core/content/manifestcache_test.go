@@ -0,0 +1,187 @@
+package content
+
+import (
+	"digisocialblock/internal/testutil"
+	"digisocialblock/pkg/dds/chunking"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// manifestCacheTestFetcher counts the fetches that reach a
+// testutil.ManifestFetcher.
+type manifestCacheTestFetcher struct {
+	*testutil.ManifestFetcher
+	fetches atomic.Int64
+}
+
+func (f *manifestCacheTestFetcher) FetchManifest(manifestCID string) (*chunking.ContentManifestV1, error) {
+	f.fetches.Add(1)
+	return f.ManifestFetcher.FetchManifest(manifestCID)
+}
+
+func manifestCacheTestSetup(t *testing.T, opts ManifestCacheOptions) (*ManifestCache, *manifestCacheTestFetcher, *testutil.Clock) {
+	t.Helper()
+	fetcher := &manifestCacheTestFetcher{ManifestFetcher: testutil.NewManifestFetcher()}
+	fetcher.Add("cid-a", &chunking.ContentManifestV1{Version: 1, TotalSize: 3, Chunks: []chunking.ChunkInfo{{ChunkCID: "chunk-a", Size: 3}}})
+	cache, err := NewManifestCache(fetcher, opts)
+	if err != nil {
+		t.Fatalf("NewManifestCache() error = %v", err)
+	}
+	clock := testutil.NewClock(0)
+	cache.SetClock(clock)
+	return cache, fetcher, clock
+}
+
+func TestManifestCache_ServesCopiesUntilTheTTLExpires(t *testing.T) {
+	cache, fetcher, clock := manifestCacheTestSetup(t, ManifestCacheOptions{TTL: time.Minute})
+
+	first, err := cache.FetchManifest("cid-a")
+	if err != nil {
+		t.Fatalf("FetchManifest() error = %v", err)
+	}
+	first.Chunks[0].ChunkCID = "tampered"
+	second, err := cache.FetchManifest("cid-a")
+	if err != nil {
+		t.Fatalf("FetchManifest() error = %v", err)
+	}
+	if second.Chunks[0].ChunkCID != "chunk-a" {
+		t.Errorf("cached manifest was changed through a returned copy: %+v", second.Chunks)
+	}
+	if n := fetcher.fetches.Load(); n != 1 {
+		t.Errorf("fetches = %d, want 1", n)
+	}
+
+	clock.Advance(time.Minute)
+	if _, err := cache.FetchManifest("cid-a"); err != nil {
+		t.Fatalf("FetchManifest() after TTL error = %v", err)
+	}
+	if n := fetcher.fetches.Load(); n != 2 {
+		t.Errorf("fetches after TTL = %d, want 2", n)
+	}
+	s := cache.Stats()
+	if s.Hits != 1 || s.Misses != 2 || s.Expired != 1 || s.Entries != 1 {
+		t.Errorf("Stats() = %+v", s)
+	}
+}
+
+func TestManifestCache_CachesNotFoundBriefly(t *testing.T) {
+	errTransient := errors.New("network unreachable")
+	cache, fetcher, clock := manifestCacheTestSetup(t, ManifestCacheOptions{
+		NegativeTTL: 10 * time.Second,
+		NotFound:    func(err error) bool { return strings.Contains(err.Error(), "not found") },
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.FetchManifest("cid-missing"); err == nil {
+			t.Fatal("FetchManifest() of a missing CID succeeded")
+		}
+	}
+	if n := fetcher.fetches.Load(); n != 1 {
+		t.Errorf("fetches of a missing CID = %d, want 1", n)
+	}
+	fetcher.Add("cid-missing", &chunking.ContentManifestV1{Version: 1})
+	clock.Advance(10 * time.Second)
+	if _, err := cache.FetchManifest("cid-missing"); err != nil {
+		t.Errorf("FetchManifest() after the negative TTL error = %v", err)
+	}
+
+	// Errors that do not mean not found are not remembered.
+	fetcher.Fail(errTransient)
+	cache.Invalidate("cid-a")
+	if _, err := cache.FetchManifest("cid-a"); !errors.Is(err, errTransient) {
+		t.Fatalf("FetchManifest() error = %v, want %v", err, errTransient)
+	}
+	fetcher.Fail(nil)
+	if _, err := cache.FetchManifest("cid-a"); err != nil {
+		t.Errorf("FetchManifest() after a transient failure error = %v", err)
+	}
+	if s := cache.Stats(); s.NegativeHits != 2 {
+		t.Errorf("NegativeHits = %d, want 2", s.NegativeHits)
+	}
+}
+
+func TestManifestCache_InvalidationAndEviction(t *testing.T) {
+	cache, fetcher, _ := manifestCacheTestSetup(t, ManifestCacheOptions{MaxEntries: 2})
+	fetcher.Add("cid-b", &chunking.ContentManifestV1{Version: 1})
+	fetcher.Add("cid-c", &chunking.ContentManifestV1{Version: 1})
+	var invalidated []string
+	cache.OnInvalidate(func(cid string) { invalidated = append(invalidated, cid) })
+
+	for _, cid := range []string{"cid-a", "cid-b", "cid-a", "cid-c"} {
+		if _, err := cache.FetchManifest(cid); err != nil {
+			t.Fatalf("FetchManifest(%s) error = %v", cid, err)
+		}
+	}
+	// cid-b was the least recently used when cid-c arrived.
+	if s := cache.Stats(); s.Entries != 2 || s.Evictions != 1 {
+		t.Errorf("Stats() = %+v, want 2 entries after 1 eviction", s)
+	}
+	before := fetcher.fetches.Load()
+	cache.FetchManifest("cid-a")
+	if fetcher.fetches.Load() != before {
+		t.Error("cid-a was evicted instead of cid-b")
+	}
+
+	if !cache.Invalidate("cid-a") {
+		t.Error("Invalidate() of a cached CID = false")
+	}
+	if cache.Invalidate("cid-a") {
+		t.Error("Invalidate() of an uncached CID = true")
+	}
+	cache.FetchManifest("cid-a")
+	if fetcher.fetches.Load() != before+1 {
+		t.Error("FetchManifest() after Invalidate did not refetch")
+	}
+	if n := cache.Purge(); n != 2 {
+		t.Errorf("Purge() = %d, want 2", n)
+	}
+	if len(invalidated) != 4 || cache.Stats().Entries != 0 {
+		t.Errorf("hooks saw %v, entries = %d", invalidated, cache.Stats().Entries)
+	}
+}
+
+// manifestCacheTestGate is a fetcher whose fetches signal started and then
+// wait for release.
+type manifestCacheTestGate struct {
+	*manifestCacheTestFetcher
+	started, release chan struct{}
+}
+
+func (g *manifestCacheTestGate) FetchManifest(manifestCID string) (*chunking.ContentManifestV1, error) {
+	g.started <- struct{}{}
+	<-g.release
+	return g.manifestCacheTestFetcher.FetchManifest(manifestCID)
+}
+
+func TestManifestCache_InvalidationDuringFetchIsNotLost(t *testing.T) {
+	fetcher := &manifestCacheTestFetcher{ManifestFetcher: testutil.NewManifestFetcher()}
+	fetcher.Add("cid-a", &chunking.ContentManifestV1{Version: 1})
+	gate := &manifestCacheTestGate{manifestCacheTestFetcher: fetcher, started: make(chan struct{}), release: make(chan struct{})}
+	cache, _ := NewManifestCache(gate, ManifestCacheOptions{})
+
+	// A fetch that began before the invalidation may have read the old
+	// manifest, so its result is returned but not cached.
+	done := make(chan error)
+	go func() {
+		_, err := cache.FetchManifest("cid-a")
+		done <- err
+	}()
+	<-gate.started
+	cache.Invalidate("cid-a")
+	close(gate.release)
+	if err := <-done; err != nil {
+		t.Fatalf("FetchManifest() error = %v", err)
+	}
+	if s := cache.Stats(); s.Entries != 0 {
+		t.Errorf("Stats() = %+v, want the fetch overlapping an invalidation uncached", s)
+	}
+
+	go func() { <-gate.started }()
+	cache.FetchManifest("cid-a")
+	if _, err := cache.FetchManifest("cid-a"); err != nil || fetcher.fetches.Load() != 2 {
+		t.Errorf("later fetches = %v with %d reaching the network, want the second cached", err, fetcher.fetches.Load())
+	}
+}
@@ -0,0 +1,230 @@
+package content
+
+import (
+	"container/list"
+	"digisocialblock/pkg/dds/chunking"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// MirrorOptions configures a Mirror. The zero value mirrors all content read,
+// without limit, and advertises none of it.
+type MirrorOptions struct {
+	// MaxBytes caps the bytes of mirrored chunks kept; the least recently
+	// read content is evicted first. Content larger than the cap is never
+	// mirrored. Zero means no limit.
+	MaxBytes int64
+	// Exclude lists manifest CIDs never mirrored.
+	Exclude []string
+	// Skip, if set, is asked before mirroring each piece of content, e.g. to
+	// consult a moderation blocklist; content it returns true for is not
+	// mirrored.
+	Skip func(manifestCID string) bool
+	// Advertiser, if set, advertises content once every chunk has been read
+	// and mirrored, registering the node as a provider of it.
+	Advertiser OriginatorAdvertiser
+}
+
+// MirrorStats summarizes a Mirror's contents and activity.
+type MirrorStats struct {
+	Manifests  int   `json:"manifests"`  // Content with chunks held by the mirror
+	Chunks     int   `json:"chunks"`     // Chunks held by the mirror
+	Bytes      int64 `json:"bytes"`      // Size of those chunks
+	Stored     int64 `json:"stored"`     // Chunks stored since creation
+	Failures   int64 `json:"failures"`   // Chunks that could not be stored
+	Evictions  int64 `json:"evictions"`  // Content evicted to stay within MaxBytes, or by Evict
+	Advertised int64 `json:"advertised"` // Content advertised
+}
+
+// mirrorEntry is content some of whose chunks the mirror stored.
+type mirrorEntry struct {
+	cid        string
+	chunks     map[string]bool // Mirror-owned chunks the content uses
+	advertised bool
+}
+
+// Mirror persists content read through a ContentRetriever into local storage
+// (see ContentRetriever.SetMirror), so content many users read spreads to the
+// nodes they read it from. Every chunk is verified before it is mirrored.
+// Only chunks the storage did not already hold are the mirror's, counted
+// against MaxBytes and deleted on eviction; content the node publishes or
+// pins itself is never touched. Evicted content is not withdrawn from the
+// network, whose advertisements lapse on their own. A Mirror is safe for
+// concurrent use if its storage is.
+type Mirror struct {
+	local   HotStorage
+	opts    MirrorOptions
+	exclude map[string]bool
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List       // Of *mirrorEntry; front is most recently read
+	sizes   map[string]int64 // Mirror-owned chunk CID -> size
+	refs    map[string]int   // Mirror-owned chunk CID -> entries using it
+	bytes   int64
+	stats   MirrorStats
+}
+
+// NewMirror creates a Mirror that stores chunks in local.
+func NewMirror(local HotStorage, opts MirrorOptions) (*Mirror, error) {
+	if local == nil {
+		return nil, errors.New("local storage cannot be nil")
+	}
+	if opts.MaxBytes < 0 {
+		return nil, fmt.Errorf("mirror byte cap cannot be negative")
+	}
+	m := &Mirror{
+		local:   local,
+		opts:    opts,
+		exclude: make(map[string]bool),
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		sizes:   make(map[string]int64),
+		refs:    make(map[string]int),
+	}
+	for _, cid := range opts.Exclude {
+		m.exclude[cid] = true
+	}
+	return m, nil
+}
+
+// SetMirror makes the retriever mirror the content it reads into m: every
+// chunk read by RetrieveAndVerifyTextPost or through a ContentStream (and so
+// a Gateway) once verified. It must be called before the retriever is shared
+// between goroutines; nil disables mirroring.
+func (cr *ContentRetriever) SetMirror(m *Mirror) {
+	cr.mirror = m
+}
+
+// admits reports whether the options allow mirroring manifest's content.
+func (m *Mirror) admits(manifest *chunking.ContentManifestV1) bool {
+	if m.exclude[manifest.ManifestCID] {
+		return false
+	}
+	if m.opts.MaxBytes > 0 && manifest.TotalSize > m.opts.MaxBytes {
+		return false
+	}
+	return m.opts.Skip == nil || !m.opts.Skip(manifest.ManifestCID)
+}
+
+// storeChunk mirrors a verified chunk of manifest's content. A nil Mirror
+// does nothing.
+func (m *Mirror) storeChunk(manifest *chunking.ContentManifestV1, chunk chunking.ChunkInfo, data []byte) {
+	if m == nil || !m.admits(manifest) {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	elem, ok := m.entries[manifest.ManifestCID]
+	if ok {
+		m.order.MoveToFront(elem)
+		if elem.Value.(*mirrorEntry).chunks[chunk.ChunkCID] {
+			return
+		}
+	}
+	if _, owned := m.refs[chunk.ChunkCID]; !owned {
+		if m.local.ChunkExists(chunk.ChunkCID) {
+			return // Held for another reason; not the mirror's to count or evict
+		}
+		if err := m.local.StoreChunk(chunk.ChunkCID, data); err != nil {
+			log.Printf("Mirror: failed to store chunk %s: %v\n", chunk.ChunkCID, err)
+			m.stats.Failures++
+			return
+		}
+		m.sizes[chunk.ChunkCID] = int64(len(data))
+		m.bytes += int64(len(data))
+		m.stats.Stored++
+	}
+	if !ok {
+		elem = m.order.PushFront(&mirrorEntry{cid: manifest.ManifestCID, chunks: make(map[string]bool)})
+		m.entries[manifest.ManifestCID] = elem
+	}
+	e := elem.Value.(*mirrorEntry)
+	m.refs[chunk.ChunkCID]++
+	e.chunks[chunk.ChunkCID] = true
+	m.evictLocked(elem)
+}
+
+// complete records that every chunk of manifest's content was read and
+// verified, and advertises it if it was mirrored and an Advertiser is set. A
+// nil Mirror does nothing.
+func (m *Mirror) complete(manifest *chunking.ContentManifestV1) {
+	if m == nil || m.opts.Advertiser == nil {
+		return
+	}
+	m.mu.Lock()
+	elem, ok := m.entries[manifest.ManifestCID]
+	advertise := ok && !elem.Value.(*mirrorEntry).advertised
+	if advertise {
+		elem.Value.(*mirrorEntry).advertised = true
+		m.stats.Advertised++
+	}
+	m.mu.Unlock()
+	if advertise {
+		if err := m.opts.Advertiser.AdvertiseManifest(manifest); err != nil {
+			log.Printf("Mirror: failed to advertise %s: %v\n", manifest.ManifestCID, err)
+		}
+	}
+}
+
+// evictLocked evicts the least recently read content other than keep until
+// the mirror is within MaxBytes. The caller must hold m.mu.
+func (m *Mirror) evictLocked(keep *list.Element) {
+	for m.opts.MaxBytes > 0 && m.bytes > m.opts.MaxBytes {
+		back := m.order.Back()
+		if back == nil || back == keep {
+			return
+		}
+		m.removeLocked(back)
+	}
+}
+
+// removeLocked drops an entry, deleting the chunks no other entry uses. The
+// caller must hold m.mu.
+func (m *Mirror) removeLocked(elem *list.Element) {
+	e := m.order.Remove(elem).(*mirrorEntry)
+	delete(m.entries, e.cid)
+	m.stats.Evictions++
+	for cid := range e.chunks {
+		if m.refs[cid]--; m.refs[cid] > 0 {
+			continue
+		}
+		if err := m.local.DeleteChunk(cid); err != nil {
+			log.Printf("Mirror: failed to delete chunk %s: %v\n", cid, err)
+		}
+		m.bytes -= m.sizes[cid]
+		delete(m.refs, cid)
+		delete(m.sizes, cid)
+	}
+}
+
+// Evict deletes the mirrored chunks of manifestCID that no other mirrored
+// content uses, e.g. once the content is added to a blocklist, and reports
+// whether any were held.
+func (m *Mirror) Evict(manifestCID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	elem, ok := m.entries[manifestCID]
+	if ok {
+		m.removeLocked(elem)
+	}
+	return ok
+}
+
+// Stats returns the mirror's current contents and counters.
+func (m *Mirror) Stats() MirrorStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.stats
+	s.Manifests, s.Chunks, s.Bytes = m.order.Len(), len(m.refs), m.bytes
+	return s
+}
+
+// Var returns the mirror stats as an expvar.Var, for publishing with
+// expvar.Publish.
+func (m *Mirror) Var() expvar.Var {
+	return expvar.Func(func() interface{} { return m.Stats() })
+}
@@ -0,0 +1,73 @@
+package content
+
+import (
+	"digisocialblock/internal/testutil"
+	"io"
+	"testing"
+)
+
+// mirrorTestPublish stores text on network in 8-byte chunks and returns its
+// manifest CID and chunk CIDs.
+func mirrorTestPublish(network *testutil.DDS, text string) (string, []string) {
+	manifest, chunks := testutil.Chunk([]byte(text), 8)
+	var cids []string
+	for _, c := range chunks {
+		network.Storage.Put(c.ChunkCID, c.Data)
+		cids = append(cids, c.ChunkCID)
+	}
+	network.Manifests.Add(manifest.ManifestCID, manifest)
+	return manifest.ManifestCID, cids
+}
+
+func TestMirror_MirrorsReadsWithinLimits(t *testing.T) {
+	network, local := testutil.NewDDS(8), testutil.NewStorage()
+	first, firstChunks := mirrorTestPublish(network, "first post, read")
+	second, _ := mirrorTestPublish(network, "second one, read")
+	third, _ := mirrorTestPublish(network, "a third post....")
+	excluded, excludedChunks := mirrorTestPublish(network, "never mirrored")
+	huge, _ := mirrorTestPublish(network, "content too large to fit the mirror at all")
+
+	mirror, err := NewMirror(tieringHotStorage{local}, MirrorOptions{MaxBytes: 32, Exclude: []string{excluded}, Advertiser: network.Originator})
+	if err != nil {
+		t.Fatalf("NewMirror() error = %v", err)
+	}
+	retriever, _ := NewContentRetriever(network.Manifests, network.Storage)
+	retriever.SetMirror(mirror)
+
+	if _, err := retriever.RetrieveAndVerifyTextPost(first); err != nil {
+		t.Fatalf("RetrieveAndVerifyTextPost() error = %v", err)
+	}
+	for _, cid := range []string{excluded, huge} {
+		if _, err := retriever.RetrieveAndVerifyTextPost(cid); err != nil {
+			t.Fatalf("RetrieveAndVerifyTextPost() error = %v", err)
+		}
+	}
+	if !local.ChunkExists(firstChunks[1]) || local.ChunkExists(excludedChunks[0]) {
+		t.Error("read content not mirrored, or excluded content mirrored")
+	}
+	stream, _ := retriever.OpenStream(second, ReadAheadOptions{Window: 1})
+	if _, err := io.ReadAll(stream); err != nil {
+		t.Fatalf("reading stream error = %v", err)
+	}
+	stream.Close()
+	if s := mirror.Stats(); s.Manifests != 2 || s.Bytes != 32 || s.Advertised != 2 {
+		t.Errorf("Stats() = %+v, want two pieces of content mirrored and advertised", s)
+	}
+	if got := network.Originator.Advertised(); len(got) != 2 || got[0] != first || got[1] != second {
+		t.Errorf("Advertised() = %v, want [%s %s]", got, first, second)
+	}
+
+	// The cap evicts the least recently read content.
+	if _, err := retriever.RetrieveAndVerifyTextPost(third); err != nil {
+		t.Fatalf("RetrieveAndVerifyTextPost() error = %v", err)
+	}
+	if s := mirror.Stats(); s.Manifests != 2 || s.Bytes != 32 || s.Evictions != 1 || local.ChunkExists(firstChunks[0]) {
+		t.Errorf("Stats() = %+v after a third read, want the first content evicted", s)
+	}
+	if !mirror.Evict(second) || mirror.Evict(first) {
+		t.Error("Evict() did not report exactly the mirrored content")
+	}
+	if local.Len() != 2 {
+		t.Errorf("local storage holds %d chunks, want only the third content's", local.Len())
+	}
+}
@@ -0,0 +1,53 @@
+package content
+
+import (
+	"bytes"
+	"digisocialblock/internal/testutil"
+	"io"
+	"testing"
+	"testing/quick"
+)
+
+// TestProperty_PublishRetrieveRoundTrip checks that any non-empty content
+// published with any chunk size is retrieved byte for byte, whole and as a
+// stream. Empty media is rejected before chunking.
+func TestProperty_PublishRetrieveRoundTrip(t *testing.T) {
+	quietLogs(t)
+	property := func(data []byte, chunkSize uint8, window uint8, zeroCopy bool) bool {
+		if len(data) == 0 {
+			return true
+		}
+		// testing/quick generates at most 50 bytes, so keep chunks small
+		// enough that most inputs span several.
+		dds := testutil.NewDDS(1 + int(chunkSize%16))
+		publisher, _ := NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+		publisher.EnableZeroCopy(zeroCopy)
+		cid, err := publisher.PublishMediaToDDS(data)
+		if err != nil {
+			t.Logf("PublishMediaToDDS(%d bytes) error = %v", len(data), err)
+			return false
+		}
+		retriever, _ := NewContentRetriever(dds.Manifests, dds.Storage)
+
+		text, err := retriever.RetrieveAndVerifyTextPost(cid)
+		if err != nil || text != string(data) {
+			t.Logf("RetrieveAndVerifyTextPost() = %d bytes, %v; want %d bytes", len(text), err, len(data))
+			return false
+		}
+		stream, err := retriever.OpenStream(cid, ReadAheadOptions{Window: int(window % 4)})
+		if err != nil {
+			t.Logf("OpenStream() error = %v", err)
+			return false
+		}
+		defer stream.Close()
+		streamed, err := io.ReadAll(stream)
+		if err != nil || !bytes.Equal(streamed, data) {
+			t.Logf("streamed %d bytes, %v; want %d bytes", len(streamed), err, len(data))
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 200}); err != nil {
+		t.Error(err)
+	}
+}
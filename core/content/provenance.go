@@ -0,0 +1,150 @@
+package content
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/pkg/dds/chunking"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrNoProvenance is returned when a manifest has no publisher signature.
+var ErrNoProvenance = errors.New("manifest has no publisher signature")
+
+// ManifestExtPublisherSig names the manifest extension holding the publisher signature.
+const ManifestExtPublisherSig = "publisher-sig"
+
+// ManifestExtensionID returns the DDS chunk ID under which the named extension
+// of a manifest is stored. Extensions sit next to the manifest rather than in
+// it, so adding one never changes the manifest or its CID.
+func ManifestExtensionID(manifestCID, name string) string {
+	return manifestCID + ".ext." + name
+}
+
+// ManifestSignature is a publisher's signature over a manifest. It proves
+// which wallet published the content, in addition to the integrity the
+// manifest's hashes already provide.
+type ManifestSignature struct {
+	ManifestCID string `json:"manifestCID"`
+	Publisher   string `json:"publisher"` // Publisher wallet address
+	SigVersion  int    `json:"sigVersion"`
+	Signature   []byte `json:"signature"`
+}
+
+// canonicalManifest fixes the field order and set of the signed manifest data,
+// so the signed bytes depend only on the manifest's contents.
+type canonicalManifest struct {
+	Version          int                  `json:"version"`
+	ManifestCID      string               `json:"manifestCID"`
+	TotalSize        int64                `json:"totalSize"`
+	EncryptionMethod string               `json:"encryptionMethod"`
+	Chunks           []chunking.ChunkInfo `json:"chunks"`
+}
+
+// CanonicalManifestBytes returns the deterministic encoding of manifest that
+// publishers sign.
+func CanonicalManifestBytes(manifest *chunking.ContentManifestV1) ([]byte, error) {
+	if manifest == nil {
+		return nil, fmt.Errorf("manifest cannot be nil")
+	}
+	return json.Marshal(canonicalManifest{
+		Version:          manifest.Version,
+		ManifestCID:      manifest.ManifestCID,
+		TotalSize:        manifest.TotalSize,
+		EncryptionMethod: manifest.EncryptionMethod,
+		Chunks:           manifest.Chunks,
+	})
+}
+
+// SignManifest signs manifest with wallet in the manifest signing domain.
+func SignManifest(wallet *identity.Wallet, manifest *chunking.ContentManifestV1) (*ManifestSignature, error) {
+	if wallet == nil {
+		return nil, fmt.Errorf("wallet cannot be nil")
+	}
+	data, err := CanonicalManifestBytes(manifest)
+	if err != nil {
+		return nil, err
+	}
+	// Manifests live off-chain, so the signature is not bound to a chain ID.
+	sig, err := wallet.SignInDomain(identity.DomainManifest, "", data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign manifest %s: %w", manifest.ManifestCID, err)
+	}
+	return &ManifestSignature{
+		ManifestCID: manifest.ManifestCID,
+		Publisher:   wallet.Address,
+		SigVersion:  identity.CurrentSignatureVersion,
+		Signature:   sig,
+	}, nil
+}
+
+// VerifyManifestSignature checks that sig is a valid publisher signature over manifest.
+func VerifyManifestSignature(manifest *chunking.ContentManifestV1, sig *ManifestSignature) error {
+	if sig == nil {
+		return ErrNoProvenance
+	}
+	if manifest == nil || !cidsEqual(sig.ManifestCID, manifest.ManifestCID) {
+		return fmt.Errorf("publisher signature is for a different manifest")
+	}
+	pub, err := identity.AddressToPublicKey(sig.Publisher)
+	if err != nil {
+		return fmt.Errorf("invalid publisher address: %w", err)
+	}
+	data, err := CanonicalManifestBytes(manifest)
+	if err != nil {
+		return err
+	}
+	if err := identity.VerifyInDomain(pub, identity.DomainManifest, sig.SigVersion, "", data, sig.Signature); err != nil {
+		return fmt.Errorf("invalid publisher signature on manifest %s: %w", manifest.ManifestCID, err)
+	}
+	return nil
+}
+
+// EnableManifestSigning makes the publisher sign every manifest it publishes
+// with wallet and store the signature as the manifest's publisher-sig
+// extension. Passing nil disables signing.
+func (cp *ContentPublisher) EnableManifestSigning(wallet *identity.Wallet) {
+	cp.signer = wallet
+}
+
+func (cp *ContentPublisher) storeManifestSignature(manifest *chunking.ContentManifestV1) error {
+	sig, err := SignManifest(cp.signer, manifest)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(sig)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest signature: %w", err)
+	}
+	if err := cp.storage.StoreChunk(ManifestExtensionID(manifest.ManifestCID, ManifestExtPublisherSig), data); err != nil {
+		return fmt.Errorf("failed to store signature for manifest %s: %w", manifest.ManifestCID, err)
+	}
+	return nil
+}
+
+// VerifyProvenance fetches the manifest and its publisher signature and
+// returns the verified publisher address. It returns ErrNoProvenance for
+// unsigned content, which callers should treat as "publisher unknown" rather
+// than as a failure.
+func (cr *ContentRetriever) VerifyProvenance(manifestCID string) (string, error) {
+	manifest, err := cr.manifestFetcher.FetchManifest(manifestCID)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch manifest %s: %w", manifestCID, err)
+	}
+	extID := ManifestExtensionID(manifestCID, ManifestExtPublisherSig)
+	if !cr.chunkRetriever.ChunkExists(extID) {
+		return "", ErrNoProvenance
+	}
+	data, err := cr.chunkRetriever.RetrieveChunk(extID)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve signature for manifest %s: %w", manifestCID, err)
+	}
+	var sig ManifestSignature
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return "", fmt.Errorf("failed to decode signature for manifest %s: %w", manifestCID, err)
+	}
+	if err := VerifyManifestSignature(manifest, &sig); err != nil {
+		return "", err
+	}
+	return sig.Publisher, nil
+}
@@ -2,14 +2,24 @@ package content
 
 import (
 	"bytes"
+	"context"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/telemetry"
 	"digisocialblock/pkg/dds/chunking" // Assuming this path for your DDS packages
-	"digisocialblock/pkg/dds/storage"   // Assuming this path
 	// "digisocialblock/pkg/dds/originator" // Will be conceptual for now
 	"fmt"
 	"io"
 	"log" // For logging conceptual originator call
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracer traces publication and retrieval; see package telemetry.
+var tracer = telemetry.Tracer("core/content")
+
 // DDSStorage defines the interface for storing chunks.
 // This should match the interface provided by your pkg/dds/storage package.
 type DDSStorage interface {
@@ -18,6 +28,13 @@ type DDSStorage interface {
 	ChunkExists(chunkID string) bool
 }
 
+// ZeroCopyStorage is an optional extension of DDSStorage for trusted in-process
+// storages that can take ownership of a chunk's buffer instead of cloning it.
+// It is only used when the publisher's zero-copy mode is enabled.
+type ZeroCopyStorage interface {
+	StoreChunkNoCopy(chunkID string, data []byte) error
+}
+
 // DDSChunker defines the interface for chunking data.
 // This should match the interface provided by your pkg/dds/chunking package.
 type DDSChunker interface {
@@ -44,12 +61,42 @@ func (spo *SimplePlaceholderOriginator) AdvertiseManifest(manifest *chunking.Con
 
 
 // ContentPublisher service for publishing content to DDS.
+// It is safe for concurrent use if its chunker, storage and originator are;
+// configure it (EnableZeroCopy, EnableManifestSigning, EnableAsyncPublish)
+// before sharing it.
 type ContentPublisher struct {
 	chunker   DDSChunker
 	storage   DDSStorage
 	originator OriginatorAdvertiser // Conceptual for now
+	zeroCopy   bool                 // Hand chunk buffers to ZeroCopyStorage without cloning
+	signer     *identity.Wallet     // Optional; signs published manifests (see EnableManifestSigning)
+	network    ChunkLocator         // Optional; chunks it holds are not stored again (see EnableNetworkDedup)
+	limits     PublishLimits        // Checked before chunking; see SetLimits
+	jobs       *jobQueue            // Optional; see EnableAsyncPublish
+
+	stagedMu sync.Mutex
+	staged   map[string]*chunking.ContentManifestV1 // Stored but not yet advertised; see Stage
+}
+
+// ChunkLocator reports whether a chunk is already held somewhere, e.g. by the
+// DDS network. Every DDSStorage and DDSChunkRetriever is one.
+type ChunkLocator interface {
+	ChunkExists(chunkCID string) bool
 }
 
+// PublishResult describes a publication. Publishing is idempotent: chunks
+// already held are skipped, so publishing the same content again, or
+// retrying a publication that failed part way, stores only what is missing.
+type PublishResult struct {
+	ManifestCID string
+	Chunks      int // In the manifest
+	Stored      int // Written by this publication
+	Existing    int // Already held locally or on the network, so skipped
+}
+
+// AlreadyPublished reports whether every chunk was already held.
+func (r PublishResult) AlreadyPublished() bool { return r.Stored == 0 }
+
 // NewContentPublisher creates a new ContentPublisher.
 func NewContentPublisher(chunker DDSChunker, store DDSStorage, originator OriginatorAdvertiser) (*ContentPublisher, error) {
 	if chunker == nil {
@@ -68,50 +115,193 @@ func NewContentPublisher(chunker DDSChunker, store DDSStorage, originator Origin
 	}, nil
 }
 
+// EnableZeroCopy switches the publisher into zero-copy mode.
+//
+// UNSAFE: in this mode chunk buffers produced by the chunker are handed to a
+// storage implementing ZeroCopyStorage without being cloned, so the storage
+// shares memory with the chunker's output. Only enable this for trusted
+// in-process chunker/storage pairs that never mutate chunk data after it has
+// been produced. Storages that do not implement ZeroCopyStorage are unaffected.
+func (cp *ContentPublisher) EnableZeroCopy(enabled bool) {
+	cp.zeroCopy = enabled
+}
+
+// EnableNetworkDedup makes the publisher skip chunks network already holds,
+// in addition to those in its own storage. A nil network disables it.
+func (cp *ContentPublisher) EnableNetworkDedup(network ChunkLocator) {
+	cp.network = network
+}
+
+// Fingerprint chunks the content read from reader without storing anything
+// and returns its manifest, so the manifest CID is known before any upload.
+func (cp *ContentPublisher) Fingerprint(reader io.Reader) (*chunking.ContentManifestV1, error) {
+	manifest, _, err := cp.chunker.ChunkData(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to chunk data: %w", err)
+	}
+	if manifest == nil || manifest.ManifestCID == "" {
+		return nil, fmt.Errorf("chunking produced an invalid or empty manifest CID")
+	}
+	return manifest, nil
+}
+
+// PublishStream publishes the content read from reader and reports which
+// chunks it stored. It is idempotent across retries: the manifest is
+// computed first, and only chunks not already held are stored.
+func (cp *ContentPublisher) PublishStream(ctx context.Context, reader io.Reader) (PublishResult, error) {
+	return cp.publish(ctx, reader, nil)
+}
+
 // PublishTextPostToDDS chunks a text post, stores its chunks,
 // conceptually advertises it, and returns the manifest CID.
 func (cp *ContentPublisher) PublishTextPostToDDS(text string) (string, error) {
+	return cp.PublishTextPostToDDSContext(context.Background(), text)
+}
+
+// PublishTextPostToDDSContext is PublishTextPostToDDS as part of the trace
+// in ctx.
+func (cp *ContentPublisher) PublishTextPostToDDSContext(ctx context.Context, text string) (string, error) {
 	if text == "" {
 		return "", fmt.Errorf("cannot publish empty text content")
 	}
+	// strings.NewReader reads the string in place, avoiding a string->[]byte copy.
+	result, err := cp.publish(ctx, strings.NewReader(text), nil)
+	return result.ManifestCID, err
+}
+
+// PublishMediaToDDS publishes a media file (an image, video, etc.) the same
+// way as a text post and returns its manifest CID.
+func (cp *ContentPublisher) PublishMediaToDDS(data []byte) (string, error) {
+	return cp.PublishMediaToDDSContext(context.Background(), data)
+}
+
+// PublishMediaToDDSContext is PublishMediaToDDS as part of the trace in ctx.
+func (cp *ContentPublisher) PublishMediaToDDSContext(ctx context.Context, data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", fmt.Errorf("cannot publish empty media content")
+	}
+	if err := cp.limits.checkMediaType(data); err != nil {
+		return "", err
+	}
+	result, err := cp.publish(ctx, bytes.NewReader(data), nil)
+	return result.ManifestCID, err
+}
+
+// publish chunks the content read from reader, stores the chunks not
+// already held, and conceptually advertises it. Each stage is a child span of
+// a "content.Publish" span. progress, if set, is told of each stage.
+func (cp *ContentPublisher) publish(ctx context.Context, reader io.Reader, progress publishProgress) (result PublishResult, err error) {
+	ctx, span := tracer.Start(ctx, "content.Publish")
+	defer func() { telemetry.End(span, err) }()
 
-	// 1. Chunk the data
-	reader := bytes.NewReader([]byte(text))
+	manifest, result, err := cp.chunkAndStore(ctx, span, reader, progress)
+	if err != nil {
+		return result, err
+	}
+	// Sign before advertising, so the manifest is never discoverable without its provenance.
+	if cp.signer != nil {
+		if err := cp.storeManifestSignature(manifest); err != nil {
+			return result, err
+		}
+	}
+	progress.report(JobAdvertising, result.Chunks, result.Chunks)
+	if err := cp.advertise(ctx, manifest); err != nil {
+		// This is conceptual, so error handling might be just logging for now.
+		// In a real system, failure to advertise might be critical.
+		log.Printf("ContentPublisher: Warning - %v\n", err)
+		// Depending on requirements, might not return an error for advertisement failure if local storage succeeded.
+	}
+	return result, nil
+}
+
+// chunkAndStore chunks the content read from reader and stores the chunks not
+// already held, as children of span, telling progress, if set, of each stage.
+func (cp *ContentPublisher) chunkAndStore(ctx context.Context, span trace.Span, reader io.Reader, progress publishProgress) (*chunking.ContentManifestV1, PublishResult, error) {
+	// 1. Chunk the data, refusing content over the limits before it is buffered
+	reader, limited, err := cp.limits.limitReader(reader, cp.chunker)
+	if err != nil {
+		return nil, PublishResult{}, err
+	}
+	progress.report(JobChunking, 0, 0)
+	_, chunkSpan := tracer.Start(ctx, "content.Chunk")
 	manifest, dataChunks, err := cp.chunker.ChunkData(reader)
+	telemetry.End(chunkSpan, err)
+	if limited != nil && limited.err != nil {
+		return nil, PublishResult{}, limited.err
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to chunk data: %w", err)
+		return nil, PublishResult{}, fmt.Errorf("failed to chunk data: %w", err)
+	}
+	if err := cp.limits.checkChunks(len(dataChunks)); err != nil {
+		return nil, PublishResult{}, err
 	}
 	if manifest == nil || manifest.ManifestCID == "" {
-		return "", fmt.Errorf("chunking produced an invalid or empty manifest CID")
+		return nil, PublishResult{}, fmt.Errorf("chunking produced an invalid or empty manifest CID")
 	}
+	result := PublishResult{ManifestCID: manifest.ManifestCID, Chunks: len(dataChunks)}
+	span.SetAttributes(
+		attribute.String("dds.manifest_cid", manifest.ManifestCID),
+		attribute.Int64("dds.size", manifest.TotalSize),
+		attribute.Int("dds.chunks", len(dataChunks)),
+	)
 
 	fmt.Printf("ContentPublisher: Content chunked. Manifest CID: %s, Number of chunks: %d\n", manifest.ManifestCID, len(dataChunks))
 
-	// 2. Store the chunks
-	for _, chunk := range dataChunks {
-		// Check if chunk already exists to avoid re-storing (optional, storage might handle this)
-		// if cp.storage.ChunkExists(chunk.ChunkCID) {
-		//  fmt.Printf("ContentPublisher: Chunk %s already exists, skipping store.\n", chunk.ChunkCID)
-		//  continue
-		// }
-		err := cp.storage.StoreChunk(chunk.ChunkCID, chunk.Data)
-		if err != nil {
-			// TODO: Add rollback logic for partially stored chunks if a later chunk fails?
-			// For now, fail fast.
-			return "", fmt.Errorf("failed to store chunk %s: %w", chunk.ChunkCID, err)
-		}
-		// fmt.Printf("ContentPublisher: Stored chunk %s\n", chunk.ChunkCID)
+	// 2. Store the chunks not already held
+	result.Stored, err = cp.storeChunks(ctx, dataChunks, progress)
+	result.Existing = len(dataChunks) - result.Stored
+	if err != nil {
+		return nil, result, err
 	}
-	fmt.Printf("ContentPublisher: All %d chunks stored successfully.\n", len(dataChunks))
+	fmt.Printf("ContentPublisher: All %d chunks stored successfully (%d already held).\n", len(dataChunks), result.Existing)
+	return manifest, result, nil
+}
 
+// advertise (conceptually) advertises the manifest via the originator under
+// a "content.Advertise" span.
+func (cp *ContentPublisher) advertise(ctx context.Context, manifest *chunking.ContentManifestV1) error {
 	// 3. (Conceptual) Advertise content via Originator
-	if err := cp.originator.AdvertiseManifest(manifest); err != nil {
-		// This is conceptual, so error handling might be just logging for now.
-		// In a real system, failure to advertise might be critical.
-		log.Printf("ContentPublisher: Warning - failed to advertise manifest %s: %v\n", manifest.ManifestCID, err)
-		// Depending on requirements, might not return an error for advertisement failure if local storage succeeded.
+	_, advertiseSpan := tracer.Start(ctx, "content.Advertise")
+	advertiseErr := cp.originator.AdvertiseManifest(manifest)
+	telemetry.End(advertiseSpan, advertiseErr)
+	if advertiseErr != nil {
+		return fmt.Errorf("failed to advertise manifest %s: %w", manifest.ManifestCID, advertiseErr)
 	}
+	return nil
+}
 
+// storeChunks stores the chunks of one publication that are not already
+// held, under a "content.StoreChunks" span, and returns how many it stored.
+// Chunks stored before a failure stay stored, so a retry resumes after them.
+// progress, if set, is told of each chunk stored or skipped.
+func (cp *ContentPublisher) storeChunks(ctx context.Context, dataChunks []chunking.DataChunk, progress publishProgress) (stored int, err error) {
+	_, span := tracer.Start(ctx, "content.StoreChunks", trace.WithAttributes(attribute.Int("dds.chunks", len(dataChunks))))
+	defer func() {
+		span.SetAttributes(attribute.Int("dds.chunks_stored", stored))
+		telemetry.End(span, err)
+	}()
 
-	return manifest.ManifestCID, nil
+	zeroCopyStore, useZeroCopy := cp.storage.(ZeroCopyStorage)
+	useZeroCopy = useZeroCopy && cp.zeroCopy
+	progress.report(JobStoring, 0, len(dataChunks))
+	for i, chunk := range dataChunks {
+		// Chunks are content addressed, so one held under its CID is this chunk.
+		if cp.storage.ChunkExists(chunk.ChunkCID) || (cp.network != nil && cp.network.ChunkExists(chunk.ChunkCID)) {
+			progress.report(JobStoring, i+1, len(dataChunks))
+			continue
+		}
+		var err error
+		if useZeroCopy {
+			err = zeroCopyStore.StoreChunkNoCopy(chunk.ChunkCID, chunk.Data)
+		} else {
+			err = cp.storage.StoreChunk(chunk.ChunkCID, chunk.Data)
+		}
+		if err != nil {
+			// Fail fast; the chunks stored so far are kept for a retry to resume from.
+			return stored, fmt.Errorf("failed to store chunk %s: %w", chunk.ChunkCID, err)
+		}
+		stored++
+		progress.report(JobStoring, i+1, len(dataChunks))
+	}
+	return stored, nil
 }
@@ -2,264 +2,124 @@ package content
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
-	"digisocialblock/pkg/dds/chunking" // Assuming this path
+	"digisocialblock/internal/testutil"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"io"
+	"os"
+	"strings"
 	"sync"
 	"testing"
-)
-
-// --- Mock DDS Implementations for Testing ---
-
-// MockTestChunker is a mock for DDSChunker interface used in tests.
-type MockTestChunker struct {
-	ChunkSize      int
-	SimulateError  bool
-	ExpectedChunks int // Optional: for verifying number of chunks
-}
-
-func (mtc *MockTestChunker) ChunkData(data io.Reader) (*chunking.ContentManifestV1, []chunking.DataChunk, error) {
-	if mtc.SimulateError {
-		return nil, nil, fmt.Errorf("mock chunker simulated error")
-	}
-
-	var allData []byte
-	buf := make([]byte, 256)
-	for {
-		n, err := data.Read(buf)
-		if n > 0 {
-			allData = append(allData, buf[:n]...)
-		}
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, nil, fmt.Errorf("mock chunker failed to read data: %w", err)
-		}
-	}
-	if len(allData) == 0 {
-		return &chunking.ContentManifestV1{ManifestCID: "empty_data_manifest_cid"}, []chunking.DataChunk{}, nil
-	}
-
-
-	manifest := &chunking.ContentManifestV1{
-		Version:   1,
-		TotalSize: int64(len(allData)),
-	}
-	var dataChunks []chunking.DataChunk
-	chunkSize := mtc.ChunkSize
-	if chunkSize == 0 {
-		chunkSize = 1024 // Default
-	}
-
-	for i := 0; i < len(allData); i += chunkSize {
-		end := i + chunkSize
-		if end > len(allData) {
-			end = len(allData)
-		}
-		chunkData := allData[i:end]
-		hash := sha256.Sum256(chunkData)
-		chunkCID := hex.EncodeToString(hash[:])
-		dc := chunking.DataChunk{ChunkCID: chunkCID, Data: chunkData, Size: int64(len(chunkData))}
-		dataChunks = append(dataChunks, dc)
-		manifest.Chunks = append(manifest.Chunks, chunking.ChunkInfo{ChunkCID: chunkCID, Size: int64(len(chunkData))})
-	}
-
-	if mtc.ExpectedChunks > 0 && len(dataChunks) != mtc.ExpectedChunks {
-		return nil, nil, fmt.Errorf("mock chunker expected %d chunks, got %d", mtc.ExpectedChunks, len(dataChunks))
-	}
-
-	var cidBuffer bytes.Buffer
-	for _, ci := range manifest.Chunks {
-		cidBuffer.WriteString(ci.ChunkCID)
-	}
-	manifestHash := sha256.Sum256(cidBuffer.Bytes())
-	manifest.ManifestCID = "test_manifest_" + hex.EncodeToString(manifestHash[:]) // Prefix for clarity in tests
-	manifest.EncryptionMethod = "none"
-
-	return manifest, dataChunks, nil
-}
-
-// MockTestStorage is a mock for DDSStorage interface used in tests.
-type MockTestStorage struct {
-	mu            sync.Mutex
-	chunks        map[string][]byte
-	SimulateError bool
-	StoreCount    int
-}
-
-func NewMockTestStorage() *MockTestStorage {
-	return &MockTestStorage{chunks: make(map[string][]byte)}
-}
-
-func (mts *MockTestStorage) StoreChunk(chunkID string, data []byte) error {
-	mts.mu.Lock()
-	defer mts.mu.Unlock()
-	if mts.SimulateError {
-		return fmt.Errorf("mock storage simulated store error")
-	}
-	if chunkID == "" {
-		return fmt.Errorf("chunkID cannot be empty")
-	}
-	mts.chunks[chunkID] = bytes.Clone(data)
-	mts.StoreCount++
-	return nil
-}
-
-func (mts *MockTestStorage) RetrieveChunk(chunkID string) ([]byte, error) {
-	mts.mu.Lock()
-	defer mts.mu.Unlock()
-	data, ok := mts.chunks[chunkID]
-	if !ok {
-		return nil, fmt.Errorf("chunk %s not found", chunkID)
-	}
-	return bytes.Clone(data), nil
-}
-
-func (mts *MockTestStorage) ChunkExists(chunkID string) bool {
-	mts.mu.Lock()
-	defer mts.mu.Unlock()
-	_, ok := mts.chunks[chunkID]
-	return ok
-}
-
-// MockTestOriginator is a mock for OriginatorAdvertiser.
-type MockTestOriginator struct {
-	SimulateError   bool
-	AdvertisedCID   string
-	AdvertiseCount  int
-}
-func (mto *MockTestOriginator) AdvertiseManifest(manifest *chunking.ContentManifestV1) error {
-	mto.AdvertiseCount++
-	if mto.SimulateError {
-		return fmt.Errorf("mock originator simulated advertise error")
-	}
-	if manifest != nil {
-		mto.AdvertisedCID = manifest.ManifestCID
-	}
-	return nil
-}
 
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
 
 func TestContentPublisher_PublishTextPostToDDS(t *testing.T) {
-	mockChunker := &MockTestChunker{ChunkSize: 64} // Small chunk size for easy testing
-	mockStorage := NewMockTestStorage()
-	mockOriginator := &MockTestOriginator{}
-
-	publisher, err := NewContentPublisher(mockChunker, mockStorage, mockOriginator)
-	if err != nil {
-		t.Fatalf("NewContentPublisher() error = %v", err)
-	}
-
 	tests := []struct {
-		name          string
-		text          string
-		chunkerError  bool
-		storageError  bool
+		name            string
+		text            string
+		chunkerError    bool
+		storageError    bool
 		originatorError bool
-		wantErr       bool
-		expectedChunks int
+		wantErr         bool
+		expectedChunks  int
 	}{
 		{
-			name: "valid text post",
-			text: "This is a test post to be published to DDS.",
-			expectedChunks: 1, // (45 bytes / 64 chunksize)
-			wantErr: false,
+			name:           "valid text post",
+			text:           "This is a test post to be published to DDS.",
+			expectedChunks: 1, // (43 bytes / 64 chunksize)
+			wantErr:        false,
 		},
 		{
-			name: "longer text post (multiple chunks)",
-			text: "This is a much longer test post that should definitely be split into multiple distinct chunks by our mock chunker setup with a small chunk size.",
-			expectedChunks: 2, // (139 bytes / 64 chunksize)
-			wantErr: false,
+			name:           "longer text post (multiple chunks)",
+			text:           "This is a much longer test post that should definitely be split into multiple distinct chunks by our mock chunker setup with a small chunk size.",
+			expectedChunks: 3, // (144 bytes / 64 chunksize)
+			wantErr:        false,
 		},
 		{
-			name: "empty text post",
-			text: "",
+			name:    "empty text post",
+			text:    "",
 			wantErr: true,
 		},
 		{
-			name: "chunker error",
-			text: "Some text",
+			name:         "chunker error",
+			text:         "Some text",
 			chunkerError: true,
-			wantErr: true,
+			wantErr:      true,
 		},
 		{
-			name: "storage error",
-			text: "Some text that chunks fine",
-			storageError: true,
+			name:           "storage error",
+			text:           "Some text that chunks fine",
+			storageError:   true,
 			expectedChunks: 1,
-			wantErr: true,
+			wantErr:        true,
 		},
 		{
-			name: "originator error (should still succeed if storage works)",
-			text: "Text with originator error",
+			name:            "originator error (should still succeed if storage works)",
+			text:            "Text with originator error",
 			originatorError: true,
-			expectedChunks: 1,
-			wantErr: false, // PublishTextPostToDDS currently only logs originator error
+			expectedChunks:  1,
+			wantErr:         false, // PublishTextPostToDDS currently only logs originator error
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockChunker.SimulateError = tt.chunkerError
-			mockStorage.SimulateError = tt.storageError
-			mockStorage.StoreCount = 0 // Reset store count for each test
-			mockOriginator.SimulateError = tt.originatorError
-			mockOriginator.AdvertiseCount = 0
-			mockOriginator.AdvertisedCID = ""
-			mockChunker.ExpectedChunks = tt.expectedChunks
-
+			dds := testutil.NewDDS(64) // Small chunk size for easy testing
+			if tt.chunkerError {
+				dds.Chunker.Err = fmt.Errorf("simulated chunker error")
+			}
+			if tt.storageError {
+				dds.Storage.FailStores(fmt.Errorf("simulated store error"))
+			}
+			if tt.originatorError {
+				dds.Originator.Fail(fmt.Errorf("simulated advertise error"))
+			}
+			publisher, err := NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+			if err != nil {
+				t.Fatalf("NewContentPublisher() error = %v", err)
+			}
 
 			cid, err := publisher.PublishTextPostToDDS(tt.text)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("PublishTextPostToDDS() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !tt.wantErr {
-				if cid == "" {
-					t.Errorf("PublishTextPostToDDS() returned empty CID for successful case")
-				}
-				if mockStorage.StoreCount != tt.expectedChunks && tt.text != "" {
-					t.Errorf("Expected %d chunks to be stored, got %d", tt.expectedChunks, mockStorage.StoreCount)
-				}
-				if mockOriginator.AdvertiseCount != 1 && tt.text != "" {
-					t.Errorf("Expected AdvertiseManifest to be called once, called %d times", mockOriginator.AdvertiseCount)
-				}
-				if mockOriginator.AdvertisedCID != cid && tt.text != "" {
-					t.Errorf("Originator advertised CID %s, but publisher returned %s", mockOriginator.AdvertisedCID, cid)
-				}
+			if tt.wantErr {
+				return
+			}
+			if cid == "" {
+				t.Errorf("PublishTextPostToDDS() returned empty CID for successful case")
+			}
+			if dds.Storage.Stores() != tt.expectedChunks {
+				t.Errorf("Expected %d chunks to be stored, got %d", tt.expectedChunks, dds.Storage.Stores())
+			}
+			if advertised := dds.Originator.Advertised(); len(advertised) != 1 || advertised[0] != cid {
+				t.Errorf("Originator advertised %v, want exactly the returned CID %s", advertised, cid)
+			}
 
-				// Verify chunks were stored (simple check for existence of manifest's chunks)
-				if tt.text != "" {
-					manifestReader := bytes.NewReader([]byte(tt.text))
-					manifest, dataChunks, _ := mockChunker.ChunkData(manifestReader) // Re-chunk to get expected CIDs
-					if manifest != nil {
-						for _, chunkInfo := range manifest.Chunks {
-							if !mockStorage.ChunkExists(chunkInfo.ChunkCID) {
-								t.Errorf("Expected chunk %s to be in mock storage, but it was not", chunkInfo.ChunkCID)
-							}
-						}
-						// Check if the returned CID matches the manifest CID from re-chunking
-						if cid != manifest.ManifestCID {
-							t.Errorf("Returned CID %s does not match re-chunked manifest CID %s", cid, manifest.ManifestCID)
-						}
-					}
-					if len(dataChunks) != mockStorage.StoreCount {
-						t.Errorf("Number of data chunks (%d) from re-chunking does not match stored count (%d)", len(dataChunks), mockStorage.StoreCount)
-					}
+			// Re-chunk to get the expected CIDs.
+			manifest, _ := testutil.Chunk([]byte(tt.text), 64)
+			for _, chunkInfo := range manifest.Chunks {
+				if !dds.Storage.ChunkExists(chunkInfo.ChunkCID) {
+					t.Errorf("Expected chunk %s to be in storage, but it was not", chunkInfo.ChunkCID)
 				}
-
+			}
+			if cid != manifest.ManifestCID {
+				t.Errorf("Returned CID %s does not match re-chunked manifest CID %s", cid, manifest.ManifestCID)
 			}
 		})
 	}
 }
 
 func TestNewContentPublisher_NilArgs(t *testing.T) {
-	mockChunker := &MockTestChunker{}
-	mockStorage := NewMockTestStorage()
-	mockOriginator := &MockTestOriginator{}
+	dds := testutil.NewDDS(0)
+	mockChunker, mockStorage, mockOriginator := dds.Chunker, dds.Storage, dds.Originator
 
 	_, err := NewContentPublisher(nil, mockStorage, mockOriginator)
 	if err == nil {
@@ -274,3 +134,211 @@ func TestNewContentPublisher_NilArgs(t *testing.T) {
 		t.Error("NewContentPublisher with nil originator: expected error, got nil")
 	}
 }
+
+func TestContentPublisher_ZeroCopyMode(t *testing.T) {
+	dds := testutil.NewDDS(16)
+	store := dds.Storage
+	publisher, err := NewContentPublisher(dds.Chunker, store, dds.Originator)
+	if err != nil {
+		t.Fatalf("NewContentPublisher() error = %v", err)
+	}
+
+	if _, err := publisher.PublishTextPostToDDS("copying publish path"); err != nil {
+		t.Fatalf("PublishTextPostToDDS() error = %v", err)
+	}
+	if store.NoCopyStores() != 0 {
+		t.Errorf("StoreChunkNoCopy called %d times with zero-copy disabled, want 0", store.NoCopyStores())
+	}
+
+	publisher.EnableZeroCopy(true)
+	before := store.Stores()
+	if _, err := publisher.PublishTextPostToDDS("zero-copy publish path"); err != nil {
+		t.Fatalf("PublishTextPostToDDS() error = %v", err)
+	}
+	if stored := store.Stores() - before; store.NoCopyStores() != stored {
+		t.Errorf("StoreChunkNoCopy called %d times, want one per stored chunk (%d)", store.NoCopyStores(), stored)
+	}
+}
+
+// publisherTestFlakyStorage fails every store after the first allowed ones.
+type publisherTestFlakyStorage struct {
+	*testutil.Storage
+	allowed int
+}
+
+func (s *publisherTestFlakyStorage) StoreChunk(chunkID string, data []byte) error {
+	if s.allowed == 0 {
+		return errors.New("connection reset")
+	}
+	s.allowed--
+	return s.Storage.StoreChunk(chunkID, data)
+}
+
+func TestContentPublisher_PublishStreamResumesAndIsIdempotent(t *testing.T) {
+	dds := testutil.NewDDS(16)
+	store := &publisherTestFlakyStorage{Storage: dds.Storage, allowed: 2}
+	publisher, _ := NewContentPublisher(dds.Chunker, store, dds.Originator)
+	body := strings.Repeat("resumable upload ", 4) // 68 bytes: 5 chunks
+
+	manifest, err := publisher.Fingerprint(strings.NewReader(body))
+	if err != nil || dds.Storage.Len() != 0 {
+		t.Fatalf("Fingerprint() = %v, %v with %d chunks stored; want a manifest and nothing stored", manifest, err, dds.Storage.Len())
+	}
+	result, err := publisher.PublishStream(context.Background(), strings.NewReader(body))
+	if err == nil || result.Stored != 2 || result.ManifestCID != manifest.ManifestCID {
+		t.Fatalf("PublishStream() with a failing store = %+v, %v; want 2 chunks stored and an error", result, err)
+	}
+
+	store.allowed = 100
+	result, err = publisher.PublishStream(context.Background(), strings.NewReader(body))
+	if err != nil || result.Chunks != 5 || result.Stored != 3 || result.Existing != 2 {
+		t.Fatalf("retried PublishStream() = %+v, %v; want only the 3 missing chunks stored", result, err)
+	}
+	result, err = publisher.PublishStream(context.Background(), strings.NewReader(body))
+	if err != nil || !result.AlreadyPublished() || result.ManifestCID != manifest.ManifestCID {
+		t.Errorf("repeated PublishStream() = %+v, %v; want nothing stored", result, err)
+	}
+
+	// Chunks the network holds are not stored locally either.
+	network := testutil.NewStorage()
+	other, _ := testutil.Chunk([]byte("held elsewhere"), 16)
+	for _, c := range other.Chunks {
+		network.Put(c.ChunkCID, nil)
+	}
+	publisher.EnableNetworkDedup(network)
+	before := dds.Storage.Stores()
+	if result, err := publisher.PublishStream(context.Background(), strings.NewReader("held elsewhere")); err != nil || !result.AlreadyPublished() || dds.Storage.Stores() != before {
+		t.Errorf("PublishStream() of content on the network = %+v, %v; want nothing stored", result, err)
+	}
+}
+
+func TestContentPublisher_PublishMediaToDDS(t *testing.T) {
+	dds := testutil.NewDDS(4)
+	store := dds.Storage
+	publisher, err := NewContentPublisher(dds.Chunker, store, dds.Originator)
+	if err != nil {
+		t.Fatalf("NewContentPublisher() error = %v", err)
+	}
+	media := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff, 0x10}
+	cid, err := publisher.PublishMediaToDDS(media)
+	if err != nil || cid == "" {
+		t.Fatalf("PublishMediaToDDS() = %q, %v; want a manifest CID", cid, err)
+	}
+	var stored []byte
+	for _, chunk := range [][]byte{media[:4], media[4:]} {
+		hash := sha256.Sum256(chunk)
+		data, err := store.RetrieveChunk(hex.EncodeToString(hash[:]))
+		if err != nil {
+			t.Fatalf("chunk of media not stored: %v", err)
+		}
+		stored = append(stored, data...)
+	}
+	if !bytes.Equal(stored, media) {
+		t.Errorf("stored media = %x, want %x", stored, media)
+	}
+	if _, err := publisher.PublishMediaToDDS(nil); err == nil {
+		t.Error("PublishMediaToDDS(nil): expected error, got nil")
+	}
+}
+
+// publisherTestSpans records the spans ended by the package's tests. Tracers
+// bind to the first global provider, so TestMain installs it once and tracing
+// tests Reset it before they run.
+var publisherTestSpans = tracetest.NewInMemoryExporter()
+
+func TestMain(m *testing.M) {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(publisherTestSpans)))
+	os.Exit(m.Run())
+}
+
+// publisherTestTrace returns the names of the ended spans in the trace of
+// root, each with its parent's name, in the order they ended.
+func publisherTestTrace(root trace.Span) map[string]string {
+	names := make(map[trace.SpanID]string)
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range publisherTestSpans.GetSpans().Snapshots() {
+		if span.SpanContext().TraceID() == root.SpanContext().TraceID() {
+			names[span.SpanContext().SpanID()] = span.Name()
+			spans = append(spans, span)
+		}
+	}
+	names[root.SpanContext().SpanID()] = "root"
+	parents := make(map[string]string)
+	for _, span := range spans {
+		parents[span.Name()] = names[span.Parent().SpanID()]
+	}
+	return parents
+}
+
+func TestContentPublisher_PublishTracesStages(t *testing.T) {
+	dds := testutil.NewDDS(4)
+	dds.Originator.Fail(fmt.Errorf("simulated advertise error"))
+	publisher, _ := NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	publisherTestSpans.Reset()
+	ctx, root := otel.Tracer("test").Start(context.Background(), "root")
+	if _, err := publisher.PublishTextPostToDDSContext(ctx, "traced post"); err != nil {
+		t.Fatalf("PublishTextPostToDDSContext() error = %v", err)
+	}
+	root.End()
+
+	want := map[string]string{
+		"content.Publish":     "root",
+		"content.Chunk":       "content.Publish",
+		"content.StoreChunks": "content.Publish",
+		"content.Advertise":   "content.Publish",
+		"root":                "",
+	}
+	got := publisherTestTrace(root)
+	if len(got) != len(want) {
+		t.Fatalf("trace spans = %v, want %v", got, want)
+	}
+	for name, parent := range want {
+		if got[name] != parent {
+			t.Errorf("span %s has parent %q, want %q", name, got[name], parent)
+		}
+	}
+	for _, span := range publisherTestSpans.GetSpans().Snapshots() {
+		if span.Name() == "content.Advertise" && span.SpanContext().TraceID() == root.SpanContext().TraceID() && len(span.Events()) == 0 {
+			t.Error("content.Advertise span did not record the advertise error")
+		}
+	}
+}
+
+func TestContentPublisher_ConcurrentPublishAndRetrieve(t *testing.T) {
+	dds := testutil.NewDDS(8)
+	dds.Originator.Manifests = dds.Manifests
+	publisher, _ := NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	publisher.EnableZeroCopy(true)
+	retriever, _ := NewContentRetriever(dds.Manifests, dds.Storage)
+
+	workers, perWorker := 8, 25
+	if testing.Short() {
+		perWorker = 5
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				// Workers overlap in the chunks they store, so the same CIDs are
+				// written and read from several goroutines.
+				text := fmt.Sprintf("shared prefix! post %d", (w+i)%5)
+				cid, err := publisher.PublishTextPostToDDS(text)
+				if err != nil {
+					t.Errorf("PublishTextPostToDDS() error = %v", err)
+					return
+				}
+				got, err := retriever.RetrieveAndVerifyTextPost(cid)
+				if err != nil || got != text {
+					t.Errorf("RetrieveAndVerifyTextPost(%s) = %q, %v; want %q", cid, got, err, text)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	if got := len(dds.Originator.Advertised()); got != workers*perWorker {
+		t.Errorf("advertised %d manifests, want %d", got, workers*perWorker)
+	}
+}
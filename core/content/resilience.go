@@ -0,0 +1,381 @@
+package content
+
+import (
+	"digisocialblock/core/ledger"
+	"digisocialblock/pkg/dds/chunking"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Defaults for zero ResilienceOptions fields.
+const (
+	DefaultRetryAttempts    = 3
+	DefaultRetryBaseDelay   = 100 * time.Millisecond
+	DefaultRetryMaxDelay    = 2 * time.Second
+	DefaultFailureThreshold = 5
+	DefaultCircuitCooldown  = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned, without calling the backend, by calls to a
+// backend whose circuit is open.
+var ErrCircuitOpen = errors.New("backend circuit is open")
+
+// ResilienceOptions configures a ResilientStorage or ResilientManifestFetcher.
+type ResilienceOptions struct {
+	Name string // Of the backend, for stats and logs; required
+
+	// Attempts is how many times a call is tried before its error is
+	// returned. Defaults to DefaultRetryAttempts; 1 disables retries.
+	Attempts int
+	// BaseDelay is the delay before the first retry; it doubles with each
+	// further retry up to MaxDelay. Each delay is jittered down by up to
+	// half, so callers that failed together do not retry together.
+	// They default to DefaultRetryBaseDelay and DefaultRetryMaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// FailureThreshold is the number of consecutive failed attempts after
+	// which the circuit opens and calls fail at once with ErrCircuitOpen.
+	// Defaults to DefaultFailureThreshold.
+	FailureThreshold int
+	// Cooldown is how long the circuit stays open before one call is let
+	// through to probe the backend: if it succeeds the circuit closes, and
+	// if it fails the circuit opens again. Defaults to DefaultCircuitCooldown.
+	Cooldown time.Duration
+
+	// Permanent reports whether an error is the backend's answer rather
+	// than a failure of the backend, such as a chunk that does not exist.
+	// Such errors are returned at once and count as successes for the
+	// circuit. Nil treats every error as a failure.
+	Permanent func(error) bool
+	// Sleep waits between attempts. Nil means time.Sleep; tests pass a
+	// recorder so they do not wait.
+	Sleep func(time.Duration)
+}
+
+// CircuitState is the state of a backend's circuit breaker.
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // Calls reach the backend
+	CircuitOpen     CircuitState = "open"      // Calls fail with ErrCircuitOpen
+	CircuitHalfOpen CircuitState = "half-open" // One call is probing the backend; others fail
+)
+
+// ResilienceStats counts the calls to one backend and the state of its
+// circuit.
+type ResilienceStats struct {
+	Backend   string       `json:"backend"`
+	State     CircuitState `json:"state"`
+	Calls     int64        `json:"calls"`
+	Failures  int64        `json:"failures"` // Failed attempts, retried or not
+	Retries   int64        `json:"retries"`
+	Rejected  int64        `json:"rejected"` // Calls failed with ErrCircuitOpen
+	Opened    int64        `json:"opened"`   // Times the circuit opened
+	LastError string       `json:"lastError,omitempty"`
+	OpenUntil time.Time    `json:"openUntil,omitempty"` // While open, when a probe is next let through
+}
+
+// resilience retries calls to one backend and breaks its circuit.
+type resilience struct {
+	opts ResilienceOptions
+
+	mu          sync.Mutex
+	clock       ledger.Clock
+	rng         *rand.Rand
+	stats       ResilienceStats
+	consecutive int // Failed attempts since the last success
+}
+
+func newResilience(opts ResilienceOptions) (*resilience, error) {
+	if opts.Name == "" {
+		return nil, errors.New("backend name is required")
+	}
+	if opts.Attempts < 0 || opts.BaseDelay < 0 || opts.MaxDelay < 0 || opts.FailureThreshold < 0 || opts.Cooldown < 0 {
+		return nil, fmt.Errorf("resilience options for %s cannot be negative", opts.Name)
+	}
+	if opts.Attempts == 0 {
+		opts.Attempts = DefaultRetryAttempts
+	}
+	if opts.BaseDelay == 0 {
+		opts.BaseDelay = DefaultRetryBaseDelay
+	}
+	if opts.MaxDelay == 0 {
+		opts.MaxDelay = DefaultRetryMaxDelay
+	}
+	if opts.FailureThreshold == 0 {
+		opts.FailureThreshold = DefaultFailureThreshold
+	}
+	if opts.Cooldown == 0 {
+		opts.Cooldown = DefaultCircuitCooldown
+	}
+	if opts.Sleep == nil {
+		opts.Sleep = time.Sleep
+	}
+	return &resilience{
+		opts:  opts,
+		clock: ledger.SystemClock,
+		rng:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		stats: ResilienceStats{Backend: opts.Name, State: CircuitClosed},
+	}, nil
+}
+
+func (r *resilience) setClock(clock ledger.Clock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if clock == nil {
+		clock = ledger.SystemClock
+	}
+	r.clock = clock
+}
+
+// do calls call, which performs op on the backend, retrying it with backoff
+// while it fails and the circuit lets it through.
+func (r *resilience) do(op string, call func() error) error {
+	r.mu.Lock()
+	r.stats.Calls++
+	r.mu.Unlock()
+	var err error
+	for attempt := 1; ; attempt++ {
+		if !r.admit() {
+			if err != nil {
+				return fmt.Errorf("%w: %s: %s: %v", ErrCircuitOpen, r.opts.Name, op, err)
+			}
+			return fmt.Errorf("%w: %s: %s", ErrCircuitOpen, r.opts.Name, op)
+		}
+		err = call()
+		if err == nil || (r.opts.Permanent != nil && r.opts.Permanent(err)) {
+			r.succeed()
+			return err
+		}
+		r.fail(err)
+		if attempt >= r.opts.Attempts {
+			return fmt.Errorf("%s: %s failed after %d attempts: %w", r.opts.Name, op, attempt, err)
+		}
+		r.mu.Lock()
+		r.stats.Retries++
+		delay := r.backoffLocked(attempt)
+		r.mu.Unlock()
+		r.opts.Sleep(delay)
+	}
+}
+
+// backoffLocked returns the delay after the given failed attempt. The caller
+// must hold r.mu.
+func (r *resilience) backoffLocked(attempt int) time.Duration {
+	delay := r.opts.BaseDelay
+	for i := 1; i < attempt && delay < r.opts.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > r.opts.MaxDelay {
+		delay = r.opts.MaxDelay
+	}
+	return delay/2 + time.Duration(r.rng.Int63n(int64(delay/2)+1))
+}
+
+// admit reports whether a call may reach the backend, moving an open circuit
+// whose cooldown has passed to half-open with the caller as its probe.
+func (r *resilience) admit() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch r.stats.State {
+	case CircuitOpen:
+		if r.clock.Now().Before(r.stats.OpenUntil) {
+			r.stats.Rejected++
+			return false
+		}
+		r.stats.State = CircuitHalfOpen
+		r.stats.OpenUntil = time.Time{}
+		return true
+	case CircuitHalfOpen:
+		r.stats.Rejected++
+		return false
+	}
+	return true
+}
+
+// available reports whether the circuit would let a call through, without
+// claiming the probe of a half-open circuit.
+func (r *resilience) available() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch r.stats.State {
+	case CircuitOpen:
+		return !r.clock.Now().Before(r.stats.OpenUntil)
+	case CircuitHalfOpen:
+		return false
+	}
+	return true
+}
+
+func (r *resilience) succeed() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.consecutive = 0
+	if r.stats.State != CircuitClosed {
+		r.stats.State = CircuitClosed
+		log.Printf("Resilience: circuit for %s closed\n", r.opts.Name)
+	}
+}
+
+// fail records a failed attempt, opening the circuit if it was probing or
+// has now failed FailureThreshold times in a row.
+func (r *resilience) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.consecutive++
+	r.stats.Failures++
+	r.stats.LastError = err.Error()
+	if r.stats.State == CircuitHalfOpen || (r.stats.State == CircuitClosed && r.consecutive >= r.opts.FailureThreshold) {
+		r.stats.State = CircuitOpen
+		r.stats.OpenUntil = r.clock.Now().Add(r.opts.Cooldown)
+		r.stats.Opened++
+		log.Printf("Resilience: circuit for %s opened for %v after %d failures: %v\n", r.opts.Name, r.opts.Cooldown, r.consecutive, err)
+	}
+}
+
+func (r *resilience) snapshot() ResilienceStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// ResilientBackend is a backend wrapped with retries and a circuit breaker.
+type ResilientBackend interface {
+	ResilienceStats() ResilienceStats
+}
+
+// ResilienceVar returns an expvar.Var with the number of backends whose
+// circuit is not closed, as "openCircuits", and the stats of every backend,
+// for publishing with expvar.Publish or as api.ServerOptions.Diagnostics.
+func ResilienceVar(backends ...ResilientBackend) expvar.Var {
+	return expvar.Func(func() interface{} {
+		open := 0
+		stats := make([]ResilienceStats, len(backends))
+		for i, b := range backends {
+			stats[i] = b.ResilienceStats()
+			if stats[i].State != CircuitClosed {
+				open++
+			}
+		}
+		return map[string]interface{}{"openCircuits": open, "backends": stats}
+	})
+}
+
+// ResilientStorage is a DDSStorage in front of a storage backend reached over
+// the network. Failed calls are retried with exponential backoff and jitter,
+// and a backend that keeps failing has its circuit opened, so calls fail at
+// once with ErrCircuitOpen instead of each waiting out the retries, until a
+// probe finds it working again. Give each remote backend its own
+// ResilientStorage: one flaky gateway then does not stall retrieval from the
+// others, such as the network behind a GatewayFallback. Chunks are content
+// addressed, so retrying a store is safe. A ResilientStorage is safe for
+// concurrent use if its backend is.
+type ResilientStorage struct {
+	store DDSStorage
+	r     *resilience
+}
+
+// NewResilientStorage creates a ResilientStorage in front of store.
+func NewResilientStorage(store DDSStorage, opts ResilienceOptions) (*ResilientStorage, error) {
+	if store == nil {
+		return nil, errors.New("storage cannot be nil")
+	}
+	r, err := newResilience(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &ResilientStorage{store: store, r: r}, nil
+}
+
+// SetClock sets the clock circuit cooldowns are timed by. A nil clock
+// restores ledger.SystemClock.
+func (rs *ResilientStorage) SetClock(clock ledger.Clock) {
+	rs.r.setClock(clock)
+}
+
+// StoreChunk stores the chunk in the backend.
+func (rs *ResilientStorage) StoreChunk(chunkID string, data []byte) error {
+	return rs.r.do("store chunk "+chunkID, func() error {
+		return rs.store.StoreChunk(chunkID, data)
+	})
+}
+
+// RetrieveChunk retrieves the chunk from the backend.
+func (rs *ResilientStorage) RetrieveChunk(chunkID string) ([]byte, error) {
+	var data []byte
+	err := rs.r.do("retrieve chunk "+chunkID, func() error {
+		var err error
+		data, err = rs.store.RetrieveChunk(chunkID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ChunkExists asks the backend whether it holds the chunk, and reports it
+// absent while the circuit is open. The answer does not tell failures apart,
+// so it is neither retried nor counted against the circuit.
+func (rs *ResilientStorage) ChunkExists(chunkID string) bool {
+	return rs.r.available() && rs.store.ChunkExists(chunkID)
+}
+
+// ResilienceStats returns a snapshot of the backend's counters and circuit.
+func (rs *ResilientStorage) ResilienceStats() ResilienceStats {
+	return rs.r.snapshot()
+}
+
+// ResilientManifestFetcher is a DDSManifestFetcher in front of a fetcher
+// reached over the network, with the retries and circuit breaker of a
+// ResilientStorage. Put a ManifestCache in front of it, so cached manifests
+// are served while the circuit is open. A ResilientManifestFetcher is safe
+// for concurrent use if its fetcher is.
+type ResilientManifestFetcher struct {
+	fetcher DDSManifestFetcher
+	r       *resilience
+}
+
+// NewResilientManifestFetcher creates a ResilientManifestFetcher in front of
+// fetcher.
+func NewResilientManifestFetcher(fetcher DDSManifestFetcher, opts ResilienceOptions) (*ResilientManifestFetcher, error) {
+	if fetcher == nil {
+		return nil, errors.New("manifest fetcher cannot be nil")
+	}
+	r, err := newResilience(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &ResilientManifestFetcher{fetcher: fetcher, r: r}, nil
+}
+
+// SetClock sets the clock circuit cooldowns are timed by. A nil clock
+// restores ledger.SystemClock.
+func (rf *ResilientManifestFetcher) SetClock(clock ledger.Clock) {
+	rf.r.setClock(clock)
+}
+
+// FetchManifest fetches the manifest from the backend.
+func (rf *ResilientManifestFetcher) FetchManifest(manifestCID string) (*chunking.ContentManifestV1, error) {
+	var manifest *chunking.ContentManifestV1
+	err := rf.r.do("fetch manifest "+manifestCID, func() error {
+		var err error
+		manifest, err = rf.fetcher.FetchManifest(manifestCID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// ResilienceStats returns a snapshot of the backend's counters and circuit.
+func (rf *ResilientManifestFetcher) ResilienceStats() ResilienceStats {
+	return rf.r.snapshot()
+}
@@ -0,0 +1,145 @@
+package content
+
+import (
+	"digisocialblock/internal/testutil"
+	"digisocialblock/pkg/dds/chunking"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// resilienceTestStorage is a testutil.Storage whose next fails retrievals
+// fail, like a flaky remote backend, counting the retrievals that reach it.
+type resilienceTestStorage struct {
+	*testutil.Storage
+	fails atomic.Int64
+	calls atomic.Int64
+}
+
+func (s *resilienceTestStorage) RetrieveChunk(chunkID string) ([]byte, error) {
+	s.calls.Add(1)
+	if s.fails.Add(-1) >= 0 {
+		return nil, errors.New("gateway timeout")
+	}
+	return s.Storage.RetrieveChunk(chunkID)
+}
+
+func resilienceTestSetup(t *testing.T, opts ResilienceOptions) (*ResilientStorage, *resilienceTestStorage, *[]time.Duration, *testutil.Clock) {
+	t.Helper()
+	backend := &resilienceTestStorage{Storage: testutil.NewStorage()}
+	backend.Put("chunk-a", []byte("abc"))
+	var sleeps []time.Duration
+	opts.Name = "gateway-1"
+	opts.Sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	rs, err := NewResilientStorage(backend, opts)
+	if err != nil {
+		t.Fatalf("NewResilientStorage() error = %v", err)
+	}
+	clock := testutil.NewClock(0)
+	rs.SetClock(clock)
+	return rs, backend, &sleeps, clock
+}
+
+func TestResilientStorage_RetriesWithBackoff(t *testing.T) {
+	errMissing := errors.New("chunk not held")
+	rs, backend, sleeps, _ := resilienceTestSetup(t, ResilienceOptions{
+		Attempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: 150 * time.Millisecond,
+		Permanent: func(err error) bool { return errors.Is(err, errMissing) },
+	})
+
+	backend.fails.Store(2)
+	data, err := rs.RetrieveChunk("chunk-a")
+	if err != nil || string(data) != "abc" {
+		t.Fatalf("RetrieveChunk() = %q, %v; want the chunk on the third attempt", data, err)
+	}
+	if len(*sleeps) != 2 || (*sleeps)[0] < 50*time.Millisecond || (*sleeps)[0] > 100*time.Millisecond || (*sleeps)[1] < 75*time.Millisecond || (*sleeps)[1] > 150*time.Millisecond {
+		t.Errorf("backoff delays = %v, want jittered 100ms then 150ms, the cap", *sleeps)
+	}
+	if s := rs.ResilienceStats(); s.Calls != 1 || s.Failures != 2 || s.Retries != 2 || s.State != CircuitClosed {
+		t.Errorf("ResilienceStats() = %+v, want one call retried twice", s)
+	}
+
+	// Running out of attempts returns the backend's error.
+	backend.fails.Store(3)
+	if _, err := rs.RetrieveChunk("chunk-a"); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("RetrieveChunk() after 3 failures error = %v, want the backend's error", err)
+	}
+
+	// A permanent error is the backend's answer: it is not retried.
+	backend.FailRetrieve("chunk-b", errMissing)
+	calls := backend.calls.Load()
+	if _, err := rs.RetrieveChunk("chunk-b"); !errors.Is(err, errMissing) || backend.calls.Load() != calls+1 {
+		t.Errorf("RetrieveChunk() of a missing chunk error = %v after %d calls, want errMissing after one", err, backend.calls.Load()-calls)
+	}
+}
+
+func TestResilientStorage_CircuitOpensAndProbes(t *testing.T) {
+	rs, backend, _, clock := resilienceTestSetup(t, ResilienceOptions{Attempts: 1, FailureThreshold: 2, Cooldown: time.Minute})
+	other, _ := NewResilientStorage(testutil.NewStorage(), ResilienceOptions{Name: "gateway-2"})
+	openCircuits := func() int {
+		var v struct {
+			OpenCircuits int `json:"openCircuits"`
+		}
+		if err := json.Unmarshal([]byte(ResilienceVar(rs, other).String()), &v); err != nil {
+			t.Fatalf("ResilienceVar() is not JSON: %v", err)
+		}
+		return v.OpenCircuits
+	}
+
+	backend.fails.Store(100)
+	for i := 0; i < 2; i++ {
+		if _, err := rs.RetrieveChunk("chunk-a"); err == nil {
+			t.Fatal("RetrieveChunk() from a failing backend succeeded")
+		}
+	}
+	if _, err := rs.RetrieveChunk("chunk-a"); !errors.Is(err, ErrCircuitOpen) || backend.calls.Load() != 2 {
+		t.Fatalf("RetrieveChunk() with the circuit open error = %v after %d backend calls, want ErrCircuitOpen without a call", err, backend.calls.Load())
+	}
+	if rs.ChunkExists("chunk-a") || openCircuits() != 1 {
+		t.Errorf("with the circuit open, ChunkExists() = true or %d circuits open, want false and 1", openCircuits())
+	}
+
+	// After the cooldown one call probes the backend; a failure reopens it.
+	clock.Advance(time.Minute)
+	if _, err := rs.RetrieveChunk("chunk-a"); err == nil || errors.Is(err, ErrCircuitOpen) || backend.calls.Load() != 3 {
+		t.Fatalf("probe error = %v after %d backend calls, want the backend's failure", err, backend.calls.Load())
+	}
+	if s := rs.ResilienceStats(); s.State != CircuitOpen || s.Opened != 2 || s.Rejected != 1 {
+		t.Errorf("ResilienceStats() after a failed probe = %+v, want reopened", s)
+	}
+
+	clock.Advance(time.Minute)
+	backend.fails.Store(0)
+	if data, err := rs.RetrieveChunk("chunk-a"); err != nil || string(data) != "abc" {
+		t.Fatalf("probe = %q, %v; want the chunk", data, err)
+	}
+	if s := rs.ResilienceStats(); s.State != CircuitClosed || openCircuits() != 0 {
+		t.Errorf("ResilienceStats() after a successful probe = %+v, want closed", s)
+	}
+}
+
+func TestResilientManifestFetcher_Retries(t *testing.T) {
+	fetcher := testutil.NewManifestFetcher()
+	fetcher.Add("cid-a", &chunking.ContentManifestV1{Version: 1, TotalSize: 3, Chunks: []chunking.ChunkInfo{{ChunkCID: "chunk-a", Size: 3}}})
+	rf, err := NewResilientManifestFetcher(fetcher, ResilienceOptions{Name: "peer", Attempts: 2, Sleep: func(time.Duration) {}})
+	if err != nil {
+		t.Fatalf("NewResilientManifestFetcher() error = %v", err)
+	}
+	errDown := errors.New("peer unreachable")
+	fetcher.Fail(errDown)
+	if _, err := rf.FetchManifest("cid-a"); !errors.Is(err, errDown) {
+		t.Errorf("FetchManifest() error = %v, want errDown", err)
+	}
+	fetcher.Fail(nil)
+	if m, err := rf.FetchManifest("cid-a"); err != nil || m.TotalSize != 3 {
+		t.Errorf("FetchManifest() = %+v, %v", m, err)
+	}
+	if s := rf.ResilienceStats(); s.Calls != 2 || s.Failures != 2 || s.Retries != 1 {
+		t.Errorf("ResilienceStats() = %+v, want two calls, the first tried twice", s)
+	}
+	if _, err := NewResilientManifestFetcher(fetcher, ResilienceOptions{}); err == nil {
+		t.Error("NewResilientManifestFetcher() without a backend name succeeded")
+	}
+}
@@ -0,0 +1,262 @@
+package content
+
+import (
+	"context"
+	"crypto/sha256"
+	"digisocialblock/core/migrations"
+	"digisocialblock/pkg/dds/chunking"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DownloadState is the persisted progress of a download: the chunks of the
+// manifest already verified and held in local storage.
+type DownloadState struct {
+	ManifestCID string `json:"manifestCID"`
+	Verified    []bool `json:"verified"` // By position in the manifest
+}
+
+// DownloadStateStore persists DownloadState between restarts.
+type DownloadStateStore interface {
+	// LoadDownload returns the saved state, or (nil, nil) if there is none.
+	LoadDownload(manifestCID string) (*DownloadState, error)
+	SaveDownload(state *DownloadState) error
+	DeleteDownload(manifestCID string) error
+}
+
+// DownloadStateSchema versions the FileDownloadStateStore layout. Saved state
+// only saves work, so a migration may simply remove it.
+var DownloadStateSchema = &migrations.Schema{Name: "content.downloads", Current: 1}
+
+// FileDownloadStateStore is a DownloadStateStore keeping each download's
+// state in a JSON file in a directory.
+type FileDownloadStateStore struct {
+	dir string
+}
+
+// NewFileDownloadStateStore creates a FileDownloadStateStore in dir, creating
+// the directory if needed. A store in an older layout is migrated first (see
+// DownloadStateSchema); one in a newer layout is refused.
+func NewFileDownloadStateStore(dir string) (*FileDownloadStateStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("download state directory cannot be empty")
+	}
+	dir = filepath.Clean(dir)
+	if err := os.MkdirAll(filepath.Dir(dir), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create download state directory %s: %w", dir, err)
+	}
+	if err := migrations.Prepare(dir, DownloadStateSchema); err != nil {
+		return nil, fmt.Errorf("failed to open download state: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create download state directory %s: %w", dir, err)
+	}
+	return &FileDownloadStateStore{dir: dir}, nil
+}
+
+func (s *FileDownloadStateStore) path(manifestCID string) (string, error) {
+	if manifestCID == "" || strings.ContainsAny(manifestCID, `/\.`) {
+		return "", fmt.Errorf("invalid manifest CID %q", manifestCID)
+	}
+	return filepath.Join(s.dir, manifestCID+".json"), nil
+}
+
+// LoadDownload reads the state of the download of manifestCID. A missing
+// file is not an error.
+func (s *FileDownloadStateStore) LoadDownload(manifestCID string) (*DownloadState, error) {
+	path, err := s.path(manifestCID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read download state %s: %w", path, err)
+	}
+	var state DownloadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode download state %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+// SaveDownload atomically replaces the state of a download.
+func (s *FileDownloadStateStore) SaveDownload(state *DownloadState) error {
+	path, err := s.path(state.ManifestCID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode download state: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write download state %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace download state %s: %w", path, err)
+	}
+	return nil
+}
+
+// DeleteDownload removes the state of a download. A missing file is not an
+// error.
+func (s *FileDownloadStateStore) DeleteDownload(manifestCID string) error {
+	path, err := s.path(manifestCID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove download state %s: %w", path, err)
+	}
+	return nil
+}
+
+// DownloadProgress reports how far a download has got.
+type DownloadProgress struct {
+	ManifestCID string
+	Chunks      int   // In the manifest
+	Verified    int   // Held locally and verified
+	Resumed     int   // Of which were already held when this attempt started
+	Bytes       int64 // Size of the verified chunks
+	TotalBytes  int64
+}
+
+// Complete reports whether every chunk is held.
+func (p DownloadProgress) Complete() bool { return p.Verified == p.Chunks }
+
+// Downloader downloads content into local storage chunk by chunk, recording
+// each verified chunk in a DownloadStateStore, so that a download cut short
+// by a dropped connection or a restart resumes where it stopped. Chunks
+// recorded as verified are trusted if local storage still holds them; chunks
+// found locally without a record are verified before being counted. Once a
+// download completes its state is deleted, and the content can be read from
+// local storage (e.g. by a ContentRetriever over it).
+//
+// A Downloader is safe for concurrent use; concurrent downloads of the same
+// manifest run one at a time.
+type Downloader struct {
+	retriever *ContentRetriever
+	local     DDSStorage
+	states    DownloadStateStore
+
+	mu     sync.Mutex
+	active map[string]*sync.Mutex // Manifest CID -> lock held while downloading it
+}
+
+// NewDownloader creates a Downloader fetching with retriever into local and
+// recording progress in states.
+func NewDownloader(retriever *ContentRetriever, local DDSStorage, states DownloadStateStore) (*Downloader, error) {
+	if retriever == nil || local == nil || states == nil {
+		return nil, errors.New("retriever, local storage and state store are all required")
+	}
+	return &Downloader{retriever: retriever, local: local, states: states, active: make(map[string]*sync.Mutex)}, nil
+}
+
+// lock serializes downloads of manifestCID.
+func (d *Downloader) lock(manifestCID string) func() {
+	d.mu.Lock()
+	l, ok := d.active[manifestCID]
+	if !ok {
+		l = &sync.Mutex{}
+		d.active[manifestCID] = l
+	}
+	d.mu.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
+// Download fetches every chunk of manifestCID not yet held locally. On error
+// or cancellation the progress so far is saved and returned with the error;
+// calling Download again resumes from there.
+func (d *Downloader) Download(ctx context.Context, manifestCID string) (DownloadProgress, error) {
+	if manifestCID == "" {
+		return DownloadProgress{}, fmt.Errorf("manifest CID cannot be empty")
+	}
+	defer d.lock(manifestCID)()
+
+	manifest, err := d.retriever.manifestFetcher.FetchManifest(manifestCID)
+	if err != nil {
+		return DownloadProgress{}, fmt.Errorf("failed to fetch manifest %s: %w", manifestCID, err)
+	}
+	if manifest == nil {
+		return DownloadProgress{}, fmt.Errorf("fetched manifest is nil for CID %s", manifestCID)
+	}
+	state, err := d.states.LoadDownload(manifestCID)
+	if err != nil {
+		return DownloadProgress{}, err
+	}
+	if state == nil || len(state.Verified) != len(manifest.Chunks) {
+		state = &DownloadState{ManifestCID: manifestCID, Verified: make([]bool, len(manifest.Chunks))}
+	}
+
+	progress := DownloadProgress{ManifestCID: manifestCID, Chunks: len(manifest.Chunks), TotalBytes: manifest.TotalSize}
+	for i, chunk := range manifest.Chunks {
+		state.Verified[i] = d.held(chunk, state.Verified[i])
+		if state.Verified[i] {
+			progress.Verified++
+			progress.Resumed++
+			progress.Bytes += chunk.Size
+		}
+	}
+
+	for i, chunk := range manifest.Chunks {
+		if state.Verified[i] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return progress, d.suspend(state, err)
+		}
+		data, err := d.retriever.retrieveVerifiedChunk(chunk)
+		if err == nil {
+			err = d.local.StoreChunk(chunk.ChunkCID, data)
+		}
+		if err != nil {
+			return progress, d.suspend(state, fmt.Errorf("chunk %d of %s: %w", i, manifestCID, err))
+		}
+		state.Verified[i] = true
+		progress.Verified++
+		progress.Bytes += chunk.Size
+		if err := d.states.SaveDownload(state); err != nil {
+			return progress, err
+		}
+	}
+	if err := d.states.DeleteDownload(manifestCID); err != nil {
+		return progress, err
+	}
+	return progress, nil
+}
+
+// held reports whether local storage holds chunk intact. A chunk recorded as
+// verified is only checked for presence.
+func (d *Downloader) held(chunk chunking.ChunkInfo, recorded bool) bool {
+	if !d.local.ChunkExists(chunk.ChunkCID) {
+		return false
+	}
+	if recorded {
+		return true
+	}
+	data, err := d.local.RetrieveChunk(chunk.ChunkCID)
+	if err != nil || int64(len(data)) != chunk.Size {
+		return false
+	}
+	sum := sha256.Sum256(data)
+	return cidsEqual(hex.EncodeToString(sum[:]), chunk.ChunkCID)
+}
+
+// suspend saves state and returns cause, or the save error alongside it.
+func (d *Downloader) suspend(state *DownloadState, cause error) error {
+	if err := d.states.SaveDownload(state); err != nil {
+		return errors.Join(cause, err)
+	}
+	return cause
+}
@@ -0,0 +1,213 @@
+package content
+
+import (
+	"context"
+	"digisocialblock/core/ledger"
+	"digisocialblock/pkg/dds/chunking"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PinSet lists the manifest CIDs of content the node has pinned. It has the
+// shape of invariant.PinSet, so one pin set serves both.
+type PinSet interface {
+	Pinned() []string
+}
+
+// RetentionPolicy bounds the third-party content a node keeps because it
+// relayed it rather than pinned it. Zero fields impose no limit. Under a byte
+// cap the newest content that fits is kept.
+type RetentionPolicy struct {
+	MaxAge            time.Duration // Evict content published longer ago than this
+	MaxBytes          int64         // Keep at most this many bytes of unpinned content
+	MaxBytesPerAuthor int64         // Keep at most this many bytes of any one author's unpinned content
+}
+
+// Validate checks that no limit is negative.
+func (p RetentionPolicy) Validate() error {
+	if p.MaxAge < 0 || p.MaxBytes < 0 || p.MaxBytesPerAuthor < 0 {
+		return fmt.Errorf("invalid retention policy: negative limit in %+v", p)
+	}
+	return nil
+}
+
+// GCReport summarizes a Collect pass.
+type GCReport struct {
+	Evicted int   // Manifests whose content was evicted
+	Chunks  int   // Chunks deleted
+	Bytes   int64 // Bytes deleted
+}
+
+// GarbageCollector enforces a RetentionPolicy on a node's local chunk
+// storage. It is safe for concurrent use if its storage, manifest fetcher
+// and pin set are; passes run one at a time.
+type GarbageCollector struct {
+	store     HotStorage
+	manifests DDSManifestFetcher
+	pins      PinSet
+	policy    RetentionPolicy
+
+	mu    sync.Mutex // Held for a whole pass
+	clock ledger.Clock
+}
+
+// NewGarbageCollector creates a GarbageCollector that deletes chunks from
+// store, never those of content in pins. A nil pin set pins nothing.
+func NewGarbageCollector(store HotStorage, manifests DDSManifestFetcher, pins PinSet, policy RetentionPolicy) (*GarbageCollector, error) {
+	if store == nil {
+		return nil, errors.New("storage cannot be nil")
+	}
+	if manifests == nil {
+		return nil, errors.New("manifest fetcher cannot be nil")
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &GarbageCollector{store: store, manifests: manifests, pins: pins, policy: policy, clock: ledger.SystemClock}, nil
+}
+
+// SetClock sets the clock that content ages are measured at. A nil clock
+// restores ledger.SystemClock.
+func (gc *GarbageCollector) SetClock(clock ledger.Clock) {
+	if clock == nil {
+		clock = ledger.SystemClock
+	}
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.clock = clock
+}
+
+// retained is unpinned content the node holds, with the stored bytes of its
+// chunks that are not pinned. A chunk shared by several posts counts toward
+// each of them.
+type retained struct {
+	TieringCandidate
+	chunks []chunking.ChunkInfo
+	bytes  int64
+}
+
+// Collect runs a GC pass over content, the published content the node knows
+// of (see social.FeedService.TieringCandidates). Content that is unpinned and
+// too old, or over a byte cap, is evicted by deleting its chunks, except those
+// shared with pinned or retained content. Content whose manifest cannot be
+// fetched is reported in the returned error and left alone; the pass is
+// aborted without deleting anything if the pinned content cannot be resolved,
+// since its chunks could not be protected.
+func (gc *GarbageCollector) Collect(ctx context.Context, content []TieringCandidate) (GCReport, error) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	var report GCReport
+
+	protected := make(map[string]bool)
+	pinned := make(map[string]bool)
+	if gc.pins != nil {
+		for _, cid := range gc.pins.Pinned() {
+			manifest, err := gc.fetch(cid)
+			if err != nil {
+				return report, fmt.Errorf("cannot resolve pinned content, nothing collected: %w", err)
+			}
+			pinned[cid] = true
+			for _, chunk := range manifest.Chunks {
+				protected[chunk.ChunkCID] = true
+			}
+		}
+	}
+
+	var held []*retained
+	var errs []error
+	seen := make(map[string]bool)
+	for _, c := range content {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if pinned[c.ManifestCID] || seen[c.ManifestCID] {
+			continue
+		}
+		seen[c.ManifestCID] = true
+		manifest, err := gc.fetch(c.ManifestCID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		r := &retained{TieringCandidate: c}
+		for _, chunk := range manifest.Chunks {
+			if !protected[chunk.ChunkCID] && gc.store.ChunkExists(chunk.ChunkCID) {
+				r.chunks = append(r.chunks, chunk)
+				r.bytes += chunk.Size
+			}
+		}
+		if len(r.chunks) > 0 {
+			held = append(held, r)
+		}
+	}
+
+	evict := gc.selectEvictions(held)
+	keep := make(map[string]bool)
+	for _, r := range held {
+		if !evict[r] {
+			for _, chunk := range r.chunks {
+				keep[chunk.ChunkCID] = true
+			}
+		}
+	}
+	deleted := make(map[string]bool)
+	for _, r := range held {
+		if !evict[r] {
+			continue
+		}
+		report.Evicted++
+		for _, chunk := range r.chunks {
+			if keep[chunk.ChunkCID] || deleted[chunk.ChunkCID] {
+				continue
+			}
+			if err := gc.store.DeleteChunk(chunk.ChunkCID); err != nil {
+				errs = append(errs, fmt.Errorf("failed to delete chunk %s of %s: %w", chunk.ChunkCID, r.ManifestCID, err))
+				continue
+			}
+			deleted[chunk.ChunkCID] = true
+			report.Chunks++
+			report.Bytes += chunk.Size
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+// fetch returns the manifest of cid.
+func (gc *GarbageCollector) fetch(cid string) (*chunking.ContentManifestV1, error) {
+	manifest, err := gc.manifests.FetchManifest(cid)
+	if err == nil && manifest == nil {
+		err = errors.New("not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("manifest %s: %w", cid, err)
+	}
+	return manifest, nil
+}
+
+// selectEvictions returns the content the policy evicts: everything older
+// than MaxAge, and whatever does not fit under the byte caps once newer
+// content of the same author, and newer content overall, has been kept.
+// The caller must hold gc.mu.
+func (gc *GarbageCollector) selectEvictions(held []*retained) map[*retained]bool {
+	now := gc.clock.Now()
+	sort.SliceStable(held, func(i, j int) bool { return held[i].Published.After(held[j].Published) })
+	evict := make(map[*retained]bool)
+	authorBytes := make(map[string]int64)
+	var total int64
+	for _, r := range held {
+		switch {
+		case gc.policy.MaxAge > 0 && now.Sub(r.Published) > gc.policy.MaxAge:
+		case gc.policy.MaxBytesPerAuthor > 0 && authorBytes[r.Author]+r.bytes > gc.policy.MaxBytesPerAuthor:
+		case gc.policy.MaxBytes > 0 && total+r.bytes > gc.policy.MaxBytes:
+		default:
+			authorBytes[r.Author] += r.bytes
+			total += r.bytes
+			continue
+		}
+		evict[r] = true
+	}
+	return evict
+}
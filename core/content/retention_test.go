@@ -0,0 +1,93 @@
+package content
+
+import (
+	"context"
+	"digisocialblock/internal/testutil"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// retentionTestPins is a fixed PinSet.
+type retentionTestPins []string
+
+func (p retentionTestPins) Pinned() []string { return p }
+
+// retentionTestBody returns 20 bytes of content: a 16-byte chunk holding head
+// and a 4-byte one holding tail.
+func retentionTestBody(head, tail string) string {
+	return fmt.Sprintf("%-16.16s%-4.4s", head, tail)
+}
+
+func TestGarbageCollector_EnforcesRetentionPolicy(t *testing.T) {
+	store, manifests := testutil.NewStorage(), testutil.NewManifestFetcher()
+	publisher, _ := NewContentPublisher(&testutil.Chunker{ChunkSize: 16, Manifests: manifests}, store, &testutil.Originator{})
+	publish := func(text string) string {
+		cid, err := publisher.PublishTextPostToDDS(text)
+		if err != nil {
+			t.Fatalf("PublishTextPostToDDS() error = %v", err)
+		}
+		return cid
+	}
+	day := func(n int) time.Time { return testutil.ClockStart.Add(time.Duration(n) * 24 * time.Hour) }
+	pinned := publish(retentionTestBody("alice pinned", "pin"))
+	content := []TieringCandidate{
+		{ManifestCID: pinned, Author: "alice", Published: day(-100)},
+		{ManifestCID: publish(retentionTestBody("shared", "old")), Author: "alice", Published: day(-40)}, // Too old
+		{ManifestCID: publish(retentionTestBody("bob three", "b3")), Author: "bob", Published: day(10)},
+		{ManifestCID: publish(retentionTestBody("bob two", "b2")), Author: "bob", Published: day(9)},
+		{ManifestCID: publish(retentionTestBody("shared", "new")), Author: "carol", Published: day(8)},
+		{ManifestCID: publish(retentionTestBody("bob one", "b1")), Author: "bob", Published: day(7)}, // Over bob's cap
+		{ManifestCID: publish(retentionTestBody("dave", "d1")), Author: "dave", Published: day(6)},   // Over the node's cap
+	}
+	before := store.Len()
+
+	gc, err := NewGarbageCollector(tieringHotStorage{store}, manifests, retentionTestPins{pinned}, RetentionPolicy{
+		MaxAge:            30 * 24 * time.Hour,
+		MaxBytes:          70,
+		MaxBytesPerAuthor: 45,
+	})
+	if err != nil {
+		t.Fatalf("NewGarbageCollector() error = %v", err)
+	}
+	clock := testutil.NewClock(0)
+	clock.Set(day(12))
+	gc.SetClock(clock)
+	report, err := gc.Collect(context.Background(), content)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	// The old post keeps the chunk it shares with carol's; the other two lose both.
+	if report != (GCReport{Evicted: 3, Chunks: 5, Bytes: 4 + 20 + 20}) || store.Len() != before-5 {
+		t.Errorf("Collect() = %+v, %d of %d chunks left; want 3 evicted and 5 chunks deleted", report, store.Len(), before)
+	}
+	retriever, _ := NewContentRetriever(manifests, store)
+	for i, c := range content {
+		_, err := retriever.RetrieveAndVerifyTextPost(c.ManifestCID)
+		if evicted := i == 1 || i == 5 || i == 6; evicted != (err != nil) {
+			t.Errorf("content %d: RetrieveAndVerifyTextPost() error = %v, evicted %v", i, err, evicted)
+		}
+	}
+
+	// A second pass finds nothing more to do.
+	if report, err := gc.Collect(context.Background(), content); err != nil || report.Chunks != 0 {
+		t.Errorf("second Collect() = %+v, %v; want nothing deleted", report, err)
+	}
+}
+
+func TestGarbageCollector_UnresolvablePinAbortsPass(t *testing.T) {
+	store, manifests := testutil.NewStorage(), testutil.NewManifestFetcher()
+	manifest, chunks := testutil.Chunk([]byte("relayed content"), 16)
+	manifests.Add(manifest.ManifestCID, manifest)
+	store.Put(chunks[0].ChunkCID, chunks[0].Data)
+	gc, _ := NewGarbageCollector(tieringHotStorage{store}, manifests, retentionTestPins{"lost-pin"}, RetentionPolicy{MaxBytes: 1})
+
+	_, err := gc.Collect(context.Background(), []TieringCandidate{{ManifestCID: manifest.ManifestCID, Author: "alice"}})
+	if err == nil || !strings.Contains(err.Error(), "lost-pin") || store.Len() != 1 {
+		t.Errorf("Collect() with an unresolvable pin error = %v, %d chunks left; want it aborted", err, store.Len())
+	}
+	if _, err := NewGarbageCollector(tieringHotStorage{store}, manifests, nil, RetentionPolicy{MaxAge: -1}); err == nil {
+		t.Error("NewGarbageCollector() with a negative MaxAge: expected error, got nil")
+	}
+}
@@ -1,14 +1,18 @@
 package content
 
 import (
-	"bytes"
+	"context"
 	"crypto/sha256"
+	"crypto/subtle"
+	"digisocialblock/core/telemetry"
 	"digisocialblock/pkg/dds/chunking" // Assuming this path
 	"encoding/hex"
 	"fmt"
-	"io"
 	"log"
 	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // DDSManifestFetcher defines the interface for fetching a content manifest.
@@ -27,9 +31,12 @@ type DDSChunkRetriever interface {
 }
 
 // ContentRetriever service for retrieving and reassembling content from DDS.
+// It is safe for concurrent use if its fetcher and chunk retriever are.
 type ContentRetriever struct {
 	manifestFetcher DDSManifestFetcher
 	chunkRetriever  DDSChunkRetriever
+	labels          LabelSource // Optional; see SetLabelSource
+	mirror          *Mirror     // Optional; see SetMirror
 }
 
 // NewContentRetriever creates a new ContentRetriever.
@@ -49,13 +56,26 @@ func NewContentRetriever(fetcher DDSManifestFetcher, retriever DDSChunkRetriever
 // RetrieveAndVerifyTextPost fetches a manifest by its CID, retrieves all chunks,
 // verifies their integrity, reassembles them, and verifies the overall content.
 func (cr *ContentRetriever) RetrieveAndVerifyTextPost(manifestCID string) (string, error) {
+	return cr.RetrieveAndVerifyTextPostContext(context.Background(), manifestCID)
+}
+
+// RetrieveAndVerifyTextPostContext is RetrieveAndVerifyTextPost as part of
+// the trace in ctx. It records a "content.Retrieve" span with children
+// "content.FetchManifest" and "content.RetrieveChunks".
+func (cr *ContentRetriever) RetrieveAndVerifyTextPostContext(ctx context.Context, manifestCID string) (text string, err error) {
+	ctx, span := tracer.Start(ctx, "content.Retrieve")
+	defer func() { telemetry.End(span, err) }()
+	span.SetAttributes(attribute.String("dds.manifest_cid", manifestCID))
+
 	if manifestCID == "" {
 		return "", fmt.Errorf("manifest CID cannot be empty")
 	}
 
 	// 1. Fetch the manifest
 	log.Printf("ContentRetriever: Fetching manifest for CID: %s\n", manifestCID)
+	_, fetchSpan := tracer.Start(ctx, "content.FetchManifest")
 	manifest, err := cr.manifestFetcher.FetchManifest(manifestCID)
+	telemetry.End(fetchSpan, err)
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch manifest %s: %w", manifestCID, err)
 	}
@@ -79,39 +99,29 @@ func (cr *ContentRetriever) RetrieveAndVerifyTextPost(manifestCID string) (strin
 		manifest.ManifestCID, manifest.TotalSize, len(manifest.Chunks))
 
 	// 2. Retrieve and verify each chunk
-	var reassembledData bytes.Buffer
+	// strings.Builder lets the final string share the reassembled bytes instead of copying them.
+	// It is not grown to TotalSize up front: the manifest is not verified yet.
+	var reassembledData strings.Builder
 	retrievedChunkCIDs := make([]string, len(manifest.Chunks))
+	span.SetAttributes(attribute.Int64("dds.size", manifest.TotalSize), attribute.Int("dds.chunks", len(manifest.Chunks)))
 
+	_, chunksSpan := tracer.Start(ctx, "content.RetrieveChunks")
 	for i, chunkInfo := range manifest.Chunks {
 		log.Printf("ContentRetriever: Retrieving chunk %d/%d: CID %s (Expected size: %d)\n",
 			i+1, len(manifest.Chunks), chunkInfo.ChunkCID, chunkInfo.Size)
 
-		if !cr.chunkRetriever.ChunkExists(chunkInfo.ChunkCID) {
-			return "", fmt.Errorf("chunk %s listed in manifest not found in storage/network", chunkInfo.ChunkCID)
-		}
-
-		chunkData, err := cr.chunkRetriever.RetrieveChunk(chunkInfo.ChunkCID)
+		chunkData, err := cr.retrieveVerifiedChunk(chunkInfo)
 		if err != nil {
-			return "", fmt.Errorf("failed to retrieve chunk %s: %w", chunkInfo.ChunkCID, err)
-		}
-
-		// Verify chunk integrity: re-hash data and compare with ChunkCID
-		hashBytes := sha256.Sum256(chunkData)
-		calculatedChunkCID := hex.EncodeToString(hashBytes[:])
-		if calculatedChunkCID != chunkInfo.ChunkCID {
-			return "", fmt.Errorf("integrity check failed for chunk %s: expected CID %s, calculated CID %s",
-				chunkInfo.ChunkCID, chunkInfo.ChunkCID, calculatedChunkCID)
-		}
-		// Verify chunk size (optional, but good for consistency)
-		if int64(len(chunkData)) != chunkInfo.Size {
-			return "", fmt.Errorf("size mismatch for chunk %s: manifest says %d, actual %d",
-				chunkInfo.ChunkCID, chunkInfo.Size, len(chunkData))
+			telemetry.End(chunksSpan, err)
+			return "", err
 		}
 
+		cr.mirror.storeChunk(manifest, chunkInfo, chunkData)
 		reassembledData.Write(chunkData)
 		retrievedChunkCIDs[i] = chunkInfo.ChunkCID // Store for overall manifest CID verification
 		// log.Printf("ContentRetriever: Chunk %s retrieved and verified.\n", chunkInfo.ChunkCID)
 	}
+	chunksSpan.End()
 
 	// 3. Verify overall content integrity
 	//    a. Check total size
@@ -124,7 +134,8 @@ func (cr *ContentRetriever) RetrieveAndVerifyTextPost(manifestCID string) (strin
 	//       (assuming manifest CID was derived from sorted list of chunk CIDs)
 	//       The order of chunks in manifest.Chunks should be the assembly order.
 	//       Our mock chunker's manifest CID is based on concatenating chunk CIDs in order.
-	var cidBuffer bytes.Buffer
+	cidBuffer := GetBuffer()
+	defer PutBuffer(cidBuffer)
 	sort.Strings(retrievedChunkCIDs) // Ensure sorted order if manifest CID was based on sorted CIDs
 	for _, cid := range retrievedChunkCIDs { // Use the CIDs of the chunks as they were retrieved/verified
 		cidBuffer.WriteString(cid)
@@ -149,5 +160,39 @@ func (cr *ContentRetriever) RetrieveAndVerifyTextPost(manifestCID string) (strin
 
 
 	log.Printf("ContentRetriever: All chunks retrieved, reassembled. Total size verified.\n")
+	cr.mirror.complete(manifest)
 	return reassembledData.String(), nil
 }
+
+// cidsEqual compares content hashes in constant time. It is a variable only
+// so tests can assert the verification path goes through it.
+var cidsEqual = func(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// retrieveVerifiedChunk retrieves a single chunk and checks it against the
+// CID and size recorded in the manifest.
+func (cr *ContentRetriever) retrieveVerifiedChunk(chunkInfo chunking.ChunkInfo) ([]byte, error) {
+	if !cr.chunkRetriever.ChunkExists(chunkInfo.ChunkCID) {
+		return nil, fmt.Errorf("chunk %s listed in manifest not found in storage/network", chunkInfo.ChunkCID)
+	}
+
+	chunkData, err := cr.chunkRetriever.RetrieveChunk(chunkInfo.ChunkCID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve chunk %s: %w", chunkInfo.ChunkCID, err)
+	}
+
+	// Verify chunk integrity: re-hash data and compare with ChunkCID
+	hashBytes := sha256.Sum256(chunkData)
+	calculatedChunkCID := hex.EncodeToString(hashBytes[:])
+	if !cidsEqual(calculatedChunkCID, chunkInfo.ChunkCID) {
+		return nil, fmt.Errorf("integrity check failed for chunk %s: expected CID %s, calculated CID %s",
+			chunkInfo.ChunkCID, chunkInfo.ChunkCID, calculatedChunkCID)
+	}
+	// Verify chunk size (optional, but good for consistency)
+	if int64(len(chunkData)) != chunkInfo.Size {
+		return nil, fmt.Errorf("size mismatch for chunk %s: manifest says %d, actual %d",
+			chunkInfo.ChunkCID, chunkInfo.Size, len(chunkData))
+	}
+	return chunkData, nil
+}
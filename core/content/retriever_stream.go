@@ -0,0 +1,209 @@
+package content
+
+import (
+	"digisocialblock/pkg/dds/chunking" // Assuming this path
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+var errStreamClosed = errors.New("content stream is closed")
+
+// ReadAheadOptions configures chunk prefetching for a ContentStream.
+type ReadAheadOptions struct {
+	// Window is the number of chunks fetched ahead of the chunk the consumer is
+	// currently reading. Zero disables read-ahead: each chunk is fetched when
+	// the consumer reaches it.
+	Window int
+	// MaxBufferedBytes caps the total size of prefetched chunks waiting to be
+	// read. Zero means the window alone bounds memory. A chunk larger than the
+	// cap is still fetched once nothing else is buffered, so a stream can never stall.
+	MaxBufferedBytes int64
+}
+
+// ContentStream is an io.ReadCloser over content retrieved chunk by chunk.
+// Every chunk is verified against its manifest entry before it is returned.
+type ContentStream struct {
+	cr       *ContentRetriever
+	manifest *chunking.ContentManifestV1
+	opts     ReadAheadOptions
+	current  []byte // Unread remainder of the chunk being consumed
+	next     int    // Next chunk to fetch when read-ahead is disabled
+	read     int    // Chunks handed to the consumer
+
+	// Read-ahead state, guarded by mu.
+	mu       sync.Mutex
+	cond     *sync.Cond
+	ready    [][]byte // Prefetched chunks in manifest order
+	buffered int64    // Total size of ready
+	fetchErr error    // First error hit by the prefetcher
+	fetched  int      // Number of chunks the prefetcher has produced
+	closed   bool
+	done     chan struct{}
+}
+
+// OpenStream fetches the manifest for manifestCID and returns a stream of its
+// content. With a non-zero read-ahead window a background goroutine keeps up to
+// opts.Window verified chunks ready while the consumer reads the current one,
+// which hides retrieval latency for sequential consumers such as media playback.
+// The caller must Close the stream to stop the prefetcher.
+func (cr *ContentRetriever) OpenStream(manifestCID string, opts ReadAheadOptions) (*ContentStream, error) {
+	if manifestCID == "" {
+		return nil, fmt.Errorf("manifest CID cannot be empty")
+	}
+	if opts.Window < 0 {
+		return nil, fmt.Errorf("read-ahead window cannot be negative")
+	}
+	if opts.MaxBufferedBytes < 0 {
+		return nil, fmt.Errorf("read-ahead buffer limit cannot be negative")
+	}
+	manifest, err := cr.manifestFetcher.FetchManifest(manifestCID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest %s: %w", manifestCID, err)
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("fetched manifest is nil for CID %s", manifestCID)
+	}
+	if len(manifest.Chunks) == 0 && manifest.TotalSize > 0 {
+		return nil, fmt.Errorf("manifest %s lists non-zero total size but has no chunks", manifestCID)
+	}
+
+	s := &ContentStream{cr: cr, manifest: manifest, opts: opts}
+	s.cond = sync.NewCond(&s.mu)
+	if opts.Window > 0 && len(manifest.Chunks) > 0 {
+		s.done = make(chan struct{})
+		go s.prefetch()
+	}
+	return s, nil
+}
+
+// Manifest returns the manifest the stream is reading.
+func (s *ContentStream) Manifest() *chunking.ContentManifestV1 {
+	return s.manifest
+}
+
+// prefetch fetches chunks in order, waiting whenever the window or the byte
+// budget is full.
+func (s *ContentStream) prefetch() {
+	defer close(s.done)
+	for _, chunkInfo := range s.manifest.Chunks {
+		s.mu.Lock()
+		for !s.closed && !s.hasRoomLocked(chunkInfo.Size) {
+			s.cond.Wait()
+		}
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+		s.mu.Unlock()
+
+		data, err := s.cr.retrieveVerifiedChunk(chunkInfo)
+
+		s.mu.Lock()
+		if err != nil {
+			s.fetchErr = err
+		} else {
+			s.ready = append(s.ready, data)
+			s.buffered += int64(len(data))
+			s.fetched++
+		}
+		s.cond.Broadcast()
+		s.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// hasRoomLocked reports whether a chunk of size bytes fits in the read-ahead
+// window. The caller must hold s.mu.
+func (s *ContentStream) hasRoomLocked(size int64) bool {
+	if len(s.ready) >= s.opts.Window {
+		return false
+	}
+	if s.opts.MaxBufferedBytes > 0 && len(s.ready) > 0 && s.buffered+size > s.opts.MaxBufferedBytes {
+		return false
+	}
+	return true
+}
+
+// nextChunk returns the next chunk in manifest order, or io.EOF after the last one.
+func (s *ContentStream) nextChunk() ([]byte, error) {
+	if s.done == nil {
+		if s.closed {
+			return nil, errStreamClosed
+		}
+		if s.next >= len(s.manifest.Chunks) {
+			return nil, io.EOF
+		}
+		data, err := s.cr.retrieveVerifiedChunk(s.manifest.Chunks[s.next])
+		if err != nil {
+			return nil, err
+		}
+		s.next++
+		return data, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.ready) == 0 && s.fetchErr == nil && !s.closed && s.fetched < len(s.manifest.Chunks) {
+		s.cond.Wait()
+	}
+	switch {
+	case s.closed:
+		return nil, errStreamClosed
+	case len(s.ready) > 0:
+		data := s.ready[0]
+		s.ready[0] = nil
+		s.ready = s.ready[1:]
+		s.buffered -= int64(len(data))
+		s.cond.Broadcast() // Wake the prefetcher: room is available again
+		return data, nil
+	case s.fetchErr != nil:
+		return nil, s.fetchErr
+	default:
+		return nil, io.EOF
+	}
+}
+
+// Read implements io.Reader.
+func (s *ContentStream) Read(p []byte) (int, error) {
+	for len(s.current) == 0 {
+		data, err := s.nextChunk()
+		if err == io.EOF {
+			s.cr.mirror.complete(s.manifest)
+		}
+		if err != nil {
+			return 0, err
+		}
+		s.cr.mirror.storeChunk(s.manifest, s.manifest.Chunks[s.read], data)
+		s.read++
+		s.current = data
+	}
+	n := copy(p, s.current)
+	s.current = s.current[n:]
+	return n, nil
+}
+
+// Close stops the prefetcher and releases buffered chunks.
+func (s *ContentStream) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.ready = nil
+	s.buffered = 0
+	s.cond.Broadcast()
+	s.mu.Unlock()
+	if s.done != nil {
+		<-s.done
+	}
+	s.current = nil
+	return nil
+}
+
+// Buffered returns the number and total size of prefetched chunks waiting to be read.
+func (s *ContentStream) Buffered() (int, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.ready), s.buffered
+}
@@ -0,0 +1,122 @@
+package content
+
+import (
+	"digisocialblock/internal/testutil"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingChunkRetriever wraps testutil.Storage, counting retrievals and
+// optionally slowing them down to simulate network latency.
+type countingChunkRetriever struct {
+	*testutil.Storage
+	delay      time.Duration
+	mu         sync.Mutex
+	retrievals int
+}
+
+func (cc *countingChunkRetriever) RetrieveChunk(chunkCID string) ([]byte, error) {
+	time.Sleep(cc.delay)
+	cc.mu.Lock()
+	cc.retrievals++
+	cc.mu.Unlock()
+	return cc.Storage.RetrieveChunk(chunkCID)
+}
+
+func (cc *countingChunkRetriever) Retrievals() int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.retrievals
+}
+
+// publishForStream publishes body with 64-byte chunks and returns a retriever over it.
+func publishForStream(t *testing.T, body string, delay time.Duration) (*ContentRetriever, *countingChunkRetriever, string) {
+	t.Helper()
+	dds := testutil.NewDDS(64)
+	storage := &countingChunkRetriever{Storage: dds.Storage, delay: delay}
+	publisher, _ := NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	cid, err := publisher.PublishTextPostToDDS(body)
+	if err != nil {
+		t.Fatalf("PublishTextPostToDDS() error = %v", err)
+	}
+	retriever, _ := NewContentRetriever(dds.Manifests, storage)
+	return retriever, storage, cid
+}
+
+// waitFor polls cond until it holds or the deadline passes.
+func waitFor(t *testing.T, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return cond()
+}
+
+func TestContentStream_ReadAllWithAndWithoutReadAhead(t *testing.T) {
+	body := strings.Repeat("0123456789abcdef", 40) // 640 bytes -> 10 chunks
+	retriever, _, cid := publishForStream(t, body, 0)
+
+	for _, opts := range []ReadAheadOptions{{}, {Window: 3}, {Window: 4, MaxBufferedBytes: 100}} {
+		stream, err := retriever.OpenStream(cid, opts)
+		if err != nil {
+			t.Fatalf("OpenStream(%+v) error = %v", opts, err)
+		}
+		got, err := io.ReadAll(stream)
+		stream.Close()
+		if err != nil || string(got) != body {
+			t.Errorf("OpenStream(%+v): read %d bytes, %v; want the original %d bytes", opts, len(got), err, len(body))
+		}
+	}
+}
+
+func TestContentStream_ReadAheadIsBoundedByWindowAndBytes(t *testing.T) {
+	body := strings.Repeat("x", 64*12)
+	retriever, storage, cid := publishForStream(t, body, time.Millisecond)
+
+	stream, err := retriever.OpenStream(cid, ReadAheadOptions{Window: 8, MaxBufferedBytes: 150})
+	if err != nil {
+		t.Fatalf("OpenStream() error = %v", err)
+	}
+	defer stream.Close()
+
+	// Consume the first chunk, then let the prefetcher run ahead while we "play" it.
+	if _, err := io.ReadFull(stream, make([]byte, 64)); err != nil {
+		t.Fatalf("ReadFull() error = %v", err)
+	}
+	if !waitFor(t, func() bool { n, _ := stream.Buffered(); return n == 2 }) {
+		n, size := stream.Buffered()
+		t.Fatalf("Buffered() = %d chunks (%d bytes), want 2 chunks prefetched within the 150-byte cap", n, size)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if n, size := stream.Buffered(); n != 2 || size > 150 {
+		t.Errorf("prefetcher exceeded the byte cap: %d chunks, %d bytes buffered", n, size)
+	}
+	if got := storage.Retrievals(); got != 3 {
+		t.Errorf("chunks retrieved = %d, want 3 (current + 2 ahead)", got)
+	}
+}
+
+func TestContentStream_CloseStopsPrefetcher(t *testing.T) {
+	retriever, storage, cid := publishForStream(t, strings.Repeat("y", 64*20), time.Millisecond)
+
+	stream, err := retriever.OpenStream(cid, ReadAheadOptions{Window: 2})
+	if err != nil {
+		t.Fatalf("OpenStream() error = %v", err)
+	}
+	stream.Close()
+	retrievals := storage.Retrievals()
+	time.Sleep(10 * time.Millisecond)
+	if got := storage.Retrievals(); got != retrievals || got > 3 {
+		t.Errorf("retrievals after Close went from %d to %d, want prefetching to stop", retrievals, got)
+	}
+	if _, err := stream.Read(make([]byte, 1)); err == nil {
+		t.Error("Read() after Close: expected error, got nil")
+	}
+}
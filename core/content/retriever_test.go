@@ -2,112 +2,19 @@ package content
 
 import (
 	"bytes"
-	"crypto/sha256"
-	"digisocialblock/pkg/dds/chunking" // Assuming this path
-	"encoding/hex"
+	"context"
+	"digisocialblock/internal/testutil"
+	"digisocialblock/pkg/dds/chunking"
 	"fmt"
-	"io"
 	"strings"
-	"sync"
 	"testing"
-)
-
-// --- Mock Implementations for Retriever Tests ---
-
-// MockTestManifestFetcher allows controlling the manifest returned for a CID.
-type MockTestManifestFetcher struct {
-	mu         sync.Mutex
-	manifests  map[string]*chunking.ContentManifestV1
-	SimulateError bool
-	ErrorToReturn error
-}
-
-func NewMockTestManifestFetcher() *MockTestManifestFetcher {
-	return &MockTestManifestFetcher{manifests: make(map[string]*chunking.ContentManifestV1)}
-}
-
-func (mf *MockTestManifestFetcher) AddManifest(cid string, manifest *chunking.ContentManifestV1) {
-	mf.mu.Lock()
-	defer mf.mu.Unlock()
-	mf.manifests[cid] = manifest
-}
-
-func (mf *MockTestManifestFetcher) FetchManifest(manifestCID string) (*chunking.ContentManifestV1, error) {
-	mf.mu.Lock()
-	defer mf.mu.Unlock()
-	if mf.SimulateError {
-		return nil, mf.ErrorToReturn
-	}
-	m, ok := mf.manifests[manifestCID]
-	if !ok {
-		return nil, fmt.Errorf("test manifest fetcher: manifest %s not found", manifestCID)
-	}
-	// Return a copy
-	mCopy := *m
-	mCopy.Chunks = make([]chunking.ChunkInfo, len(m.Chunks))
-	copy(mCopy.Chunks, m.Chunks)
-	return &mCopy, nil
-}
-
-// MockTestChunkRetriever allows controlling chunks returned for a CID.
-// Reuses MockTestStorage from publisher_test.go as it fits the DDSChunkRetriever interface.
-// For clarity, we can alias it or use it directly. Let's use MockTestStorage.
-// type MockTestChunkRetriever MockTestStorage (if methods match directly)
-// For this test, let's define one specific to retriever tests for more control over errors.
-
-type ControlledMockChunkRetriever struct {
-	mu            sync.Mutex
-	chunks        map[string][]byte
-	SimulateErrorOnCID string
-	ErrorToReturn      error
-	CorruptChunkCID    string // If set, data for this CID will be altered
-}
-
-func NewControlledMockChunkRetriever() *ControlledMockChunkRetriever {
-	return &ControlledMockChunkRetriever{chunks: make(map[string][]byte)}
-}
-
-func (cr *ControlledMockChunkRetriever) AddChunk(cid string, data []byte) {
-	cr.mu.Lock()
-	defer cr.mu.Unlock()
-	cr.chunks[cid] = data
-}
-
-func (cr *ControlledMockChunkRetriever) RetrieveChunk(chunkCID string) ([]byte, error) {
-	cr.mu.Lock()
-	defer cr.mu.Unlock()
-	if cr.SimulateErrorOnCID == chunkCID {
-		return nil, cr.ErrorToReturn
-	}
-	data, ok := cr.chunks[chunkCID]
-	if !ok {
-		return nil, fmt.Errorf("test chunk retriever: chunk %s not found", chunkCID)
-	}
-	if cr.CorruptChunkCID == chunkCID {
-		corruptedData := bytes.Clone(data)
-		if len(corruptedData) > 0 {
-			corruptedData[0] = corruptedData[0] ^ 0xff // Flip a bit
-		} else { // Handle empty chunk case if it needs specific corruption
-			corruptedData = []byte("corrupted_empty_chunk_data")
-		}
-		return corruptedData, nil
-	}
-	return bytes.Clone(data), nil
-}
-
-func (cr *ControlledMockChunkRetriever) ChunkExists(chunkCID string) bool {
-	cr.mu.Lock()
-	defer cr.mu.Unlock()
-	_, ok := cr.chunks[chunkCID]
-	return ok
-}
 
+	"go.opentelemetry.io/otel"
+)
 
 // --- Helper to create sample manifest and chunks for tests ---
 func createSampleContentAndManifest(text string, chunkSize int) (string, *chunking.ContentManifestV1, map[string][]byte) {
-	mockChunker := &MockTestChunker{ChunkSize: chunkSize} // Using the one from publisher_test for consistency
-	reader := strings.NewReader(text)
-	manifest, dataChunks, _ := mockChunker.ChunkData(reader)
+	manifest, dataChunks := testutil.Chunk([]byte(text), chunkSize)
 
 	storedChunks := make(map[string][]byte)
 	for _, dc := range dataChunks {
@@ -126,19 +33,19 @@ func TestContentRetriever_RetrieveAndVerifyTextPost(t *testing.T) {
 	tests := []struct {
 		name                string
 		manifestCIDToFetch  string
-		setupFetcher        func(*MockTestManifestFetcher)
-		setupChunkRetriever func(*ControlledMockChunkRetriever)
+		setupFetcher        func(*testutil.ManifestFetcher)
+		setupChunkRetriever func(*testutil.Storage)
 		wantErrMsgContains  string // Substring of expected error, empty if no error
 	}{
 		{
 			name:               "successful retrieval",
 			manifestCIDToFetch: expectedManifestCID,
-			setupFetcher: func(mf *MockTestManifestFetcher) {
-				mf.AddManifest(expectedManifestCID, expectedManifest)
+			setupFetcher: func(mf *testutil.ManifestFetcher) {
+				mf.Add(expectedManifestCID, expectedManifest)
 			},
-			setupChunkRetriever: func(cr *ControlledMockChunkRetriever) {
+			setupChunkRetriever: func(cr *testutil.Storage) {
 				for cid, data := range expectedChunksMap {
-					cr.AddChunk(cid, data)
+					cr.Put(cid, data)
 				}
 			},
 			wantErrMsgContains: "",
@@ -146,88 +53,93 @@ func TestContentRetriever_RetrieveAndVerifyTextPost(t *testing.T) {
 		{
 			name:               "manifest fetch error",
 			manifestCIDToFetch: "nonexistent_cid",
-			setupFetcher: func(mf *MockTestManifestFetcher) {
-				mf.SimulateError = true
-				mf.ErrorToReturn = fmt.Errorf("simulated manifest fetch network error")
+			setupFetcher: func(mf *testutil.ManifestFetcher) {
+				mf.Fail(fmt.Errorf("simulated manifest fetch network error"))
 			},
-			setupChunkRetriever: func(cr *ControlledMockChunkRetriever) {},
+			setupChunkRetriever: func(cr *testutil.Storage) {},
 			wantErrMsgContains:  "simulated manifest fetch network error",
 		},
 		{
 			name:               "manifest not found",
 			manifestCIDToFetch: "cid_that_fetcher_does_not_have",
-			setupFetcher:       func(mf *MockTestManifestFetcher) { /* No manifest added */ },
-			setupChunkRetriever: func(cr *ControlledMockChunkRetriever) {},
+			setupFetcher:       func(mf *testutil.ManifestFetcher) { /* No manifest added */ },
+			setupChunkRetriever: func(cr *testutil.Storage) {},
 			wantErrMsgContains:  "manifest CID cid_that_fetcher_does_not_have not found",
 		},
 		{
 			name:               "chunk retrieval error",
 			manifestCIDToFetch: expectedManifestCID,
-			setupFetcher: func(mf *MockTestManifestFetcher) {
-				mf.AddManifest(expectedManifestCID, expectedManifest)
+			setupFetcher: func(mf *testutil.ManifestFetcher) {
+				mf.Add(expectedManifestCID, expectedManifest)
 			},
-			setupChunkRetriever: func(cr *ControlledMockChunkRetriever) {
-				// Store all but one chunk
-				i := 0
+			setupChunkRetriever: func(cr *testutil.Storage) {
+				// Store every chunk but fail retrieving the last
 				for cid, data := range expectedChunksMap {
-					if i < len(expectedChunksMap)-1 { // Skip last chunk
-						cr.AddChunk(cid, data)
-					}
-					i++
-				}
-				if len(expectedManifest.Chunks) > 0 {
-					lastChunkCID := expectedManifest.Chunks[len(expectedManifest.Chunks)-1].ChunkCID
-					cr.SimulateErrorOnCID = lastChunkCID
-					cr.ErrorToReturn = fmt.Errorf("simulated chunk retrieve error")
+					cr.Put(cid, data)
 				}
+				lastChunkCID := expectedManifest.Chunks[len(expectedManifest.Chunks)-1].ChunkCID
+				cr.FailRetrieve(lastChunkCID, fmt.Errorf("simulated chunk retrieve error"))
 			},
 			wantErrMsgContains: "simulated chunk retrieve error",
 		},
 		{
 			name:               "chunk not found in storage",
 			manifestCIDToFetch: expectedManifestCID,
-			setupFetcher: func(mf *MockTestManifestFetcher) {
-				mf.AddManifest(expectedManifestCID, expectedManifest)
+			setupFetcher: func(mf *testutil.ManifestFetcher) {
+				mf.Add(expectedManifestCID, expectedManifest)
 			},
-			setupChunkRetriever: func(cr *ControlledMockChunkRetriever) {
-				// Store all but one chunk, and don't simulate error, just let it be missing
-				i := 0
+			setupChunkRetriever: func(cr *testutil.Storage) {
+				// Store all but the last chunk, and don't simulate error, just let it be missing
 				for cid, data := range expectedChunksMap {
-					if i < len(expectedChunksMap)-1 {
-						cr.AddChunk(cid, data)
-					}
-					i++
+					cr.Put(cid, data)
 				}
+				cr.Delete(expectedManifest.Chunks[len(expectedManifest.Chunks)-1].ChunkCID)
 			},
 			wantErrMsgContains: "not found in storage", // Error from ChunkExists being false
 		},
 		{
 			name:               "chunk integrity verification failure (corrupted chunk)",
 			manifestCIDToFetch: expectedManifestCID,
-			setupFetcher: func(mf *MockTestManifestFetcher) {
-				mf.AddManifest(expectedManifestCID, expectedManifest)
+			setupFetcher: func(mf *testutil.ManifestFetcher) {
+				mf.Add(expectedManifestCID, expectedManifest)
 			},
-			setupChunkRetriever: func(cr *ControlledMockChunkRetriever) {
+			setupChunkRetriever: func(cr *testutil.Storage) {
 				for cid, data := range expectedChunksMap {
-					cr.AddChunk(cid, data)
-				}
-				if len(expectedManifest.Chunks) > 0 {
-					cr.CorruptChunkCID = expectedManifest.Chunks[0].ChunkCID
+					cr.Put(cid, data)
 				}
+				first := expectedManifest.Chunks[0].ChunkCID
+				corrupted := bytes.Clone(expectedChunksMap[first])
+				corrupted[0] ^= 0xff // Flip a bit
+				cr.Put(first, corrupted)
 			},
 			wantErrMsgContains: "integrity check failed for chunk",
 		},
 		{
 			name:               "total size mismatch",
 			manifestCIDToFetch: expectedManifestCID,
-			setupFetcher: func(mf *MockTestManifestFetcher) {
+			setupFetcher: func(mf *testutil.ManifestFetcher) {
 				corruptedManifest := *expectedManifest // copy
 				corruptedManifest.TotalSize = expectedManifest.TotalSize + 10
-				mf.AddManifest(expectedManifestCID, &corruptedManifest)
+				mf.Add(expectedManifestCID, &corruptedManifest)
 			},
-			setupChunkRetriever: func(cr *ControlledMockChunkRetriever) {
+			setupChunkRetriever: func(cr *testutil.Storage) {
 				for cid, data := range expectedChunksMap {
-					cr.AddChunk(cid, data)
+					cr.Put(cid, data)
+				}
+			},
+			wantErrMsgContains: "reassembled content size mismatch",
+		},
+		{
+			name:               "total size beyond memory",
+			manifestCIDToFetch: expectedManifestCID,
+			setupFetcher: func(mf *testutil.ManifestFetcher) {
+				corruptedManifest := *expectedManifest // copy
+				corruptedManifest.TotalSize = 1 << 50
+				mf.Add(expectedManifestCID, &corruptedManifest)
+			},
+			setupChunkRetriever: func(cr *testutil.Storage) {
+				for cid, data := range expectedChunksMap {
+					cr.Put(cid, data)
 				}
 			},
 			wantErrMsgContains: "reassembled content size mismatch",
@@ -235,24 +147,18 @@ func TestContentRetriever_RetrieveAndVerifyTextPost(t *testing.T) {
 		{
 			name:               "empty manifest CID",
 			manifestCIDToFetch: "",
-			setupFetcher:       func(mf *MockTestManifestFetcher) {},
-			setupChunkRetriever: func(cr *ControlledMockChunkRetriever) {},
+			setupFetcher:       func(mf *testutil.ManifestFetcher) {},
+			setupChunkRetriever: func(cr *testutil.Storage) {},
 			wantErrMsgContains:  "manifest CID cannot be empty",
 		},
 		{
             name: "successful retrieval of empty content",
-            manifestCIDToFetch: "empty_content_manifest_cid_v1", // From MockChunker logic
-            setupFetcher: func(mf *MockTestManifestFetcher) {
-				// MockChunker used in createSampleContentAndManifest would store this if "" was passed
-				// For this test, we explicitly add an empty manifest.
-                emptyManifest := &chunking.ContentManifestV1{
-                    ManifestCID: "empty_content_manifest_cid_v1",
-                    TotalSize: 0,
-                    Chunks: []chunking.ChunkInfo{},
-                }
-                mf.AddManifest("empty_content_manifest_cid_v1", emptyManifest)
+            manifestCIDToFetch: testutil.EmptyManifestCID,
+            setupFetcher: func(mf *testutil.ManifestFetcher) {
+				emptyManifest, _ := testutil.Chunk(nil, chunkSize)
+				mf.Add(testutil.EmptyManifestCID, emptyManifest)
             },
-            setupChunkRetriever: func(cr *ControlledMockChunkRetriever) {},
+            setupChunkRetriever: func(cr *testutil.Storage) {},
             wantErrMsgContains:  "", // Expect no error, empty string result
         },
 
@@ -260,8 +166,8 @@ func TestContentRetriever_RetrieveAndVerifyTextPost(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockFetcher := NewMockTestManifestFetcher()
-			mockRetriever := NewControlledMockChunkRetriever()
+			mockFetcher := testutil.NewManifestFetcher()
+			mockRetriever := testutil.NewStorage()
 
 			tt.setupFetcher(mockFetcher)
 			tt.setupChunkRetriever(mockRetriever)
@@ -284,7 +190,7 @@ func TestContentRetriever_RetrieveAndVerifyTextPost(t *testing.T) {
 					t.Errorf("RetrieveAndVerifyTextPost() unexpected error = %v", err)
 				}
 				expectedText := sampleText
-				if tt.manifestCIDToFetch == "empty_content_manifest_cid_v1" {
+				if tt.manifestCIDToFetch == testutil.EmptyManifestCID {
 					expectedText = ""
 				}
 				if retrievedText != expectedText {
@@ -294,3 +200,35 @@ func TestContentRetriever_RetrieveAndVerifyTextPost(t *testing.T) {
 		})
 	}
 }
+
+func TestContentRetriever_RetrieveTracesStages(t *testing.T) {
+	manifestCID, manifest, chunks := createSampleContentAndManifest("traced retrieval spanning several chunks", 8)
+	fetcher := testutil.NewManifestFetcher()
+	fetcher.Add(manifestCID, manifest)
+	chunkRetriever := testutil.NewStorage()
+	for cid, data := range chunks {
+		chunkRetriever.Put(cid, data)
+	}
+	retriever, _ := NewContentRetriever(fetcher, chunkRetriever)
+	publisherTestSpans.Reset()
+
+	ctx, root := otel.Tracer("test").Start(context.Background(), "root")
+	if _, err := retriever.RetrieveAndVerifyTextPostContext(ctx, manifestCID); err != nil {
+		t.Fatalf("RetrieveAndVerifyTextPostContext() error = %v", err)
+	}
+	if _, err := retriever.RetrieveAndVerifyTextPostContext(ctx, "missing"); err == nil {
+		t.Fatal("RetrieveAndVerifyTextPostContext(missing): expected error, got nil")
+	}
+	root.End()
+
+	got := publisherTestTrace(root)
+	for name, parent := range map[string]string{
+		"content.Retrieve":       "root",
+		"content.FetchManifest":  "content.Retrieve",
+		"content.RetrieveChunks": "content.Retrieve",
+	} {
+		if got[name] != parent {
+			t.Errorf("span %s has parent %q, want %q", name, got[name], parent)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package content
+
+import (
+	"context"
+	"digisocialblock/core/telemetry"
+	"digisocialblock/pkg/dds/chunking"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ErrNotStaged is returned by Commit for a manifest CID that Stage has not
+// staged, or that has since been committed or discarded.
+var ErrNotStaged = errors.New("content is not staged")
+
+// Stage is the first phase of a two-phase publication: it chunks the content
+// read from reader and stores the chunks not already held, like
+// PublishStream, but neither signs nor advertises the manifest. The result's
+// ManifestCID is provisional: the content can be retrieved from the local
+// storage to preview it, but no peer learns of it until Commit. Staging the
+// same content twice stages it once. It records a "content.Stage" span.
+func (cp *ContentPublisher) Stage(ctx context.Context, reader io.Reader) (result PublishResult, err error) {
+	ctx, span := tracer.Start(ctx, "content.Stage")
+	defer func() { telemetry.End(span, err) }()
+
+	manifest, result, err := cp.chunkAndStore(ctx, span, reader, nil)
+	if err != nil {
+		return result, err
+	}
+	cp.stagedMu.Lock()
+	defer cp.stagedMu.Unlock()
+	if cp.staged == nil {
+		cp.staged = make(map[string]*chunking.ContentManifestV1)
+	}
+	cp.staged[manifest.ManifestCID] = manifest
+	return result, nil
+}
+
+// Commit is the second phase of a two-phase publication: it signs, if
+// manifest signing is enabled, and advertises the staged manifest, making
+// the content public. Unlike the one-phase publish methods, it fails if the
+// manifest cannot be advertised, and the content then stays staged so Commit
+// can be retried. It records a "content.Commit" span.
+func (cp *ContentPublisher) Commit(ctx context.Context, manifestCID string) (err error) {
+	ctx, span := tracer.Start(ctx, "content.Commit")
+	defer func() { telemetry.End(span, err) }()
+
+	cp.stagedMu.Lock()
+	manifest := cp.staged[manifestCID]
+	cp.stagedMu.Unlock()
+	if manifest == nil {
+		return fmt.Errorf("%w: %s", ErrNotStaged, manifestCID)
+	}
+	if cp.signer != nil {
+		if err := cp.storeManifestSignature(manifest); err != nil {
+			return err
+		}
+	}
+	if err := cp.advertise(ctx, manifest); err != nil {
+		return err
+	}
+	cp.stagedMu.Lock()
+	delete(cp.staged, manifestCID)
+	cp.stagedMu.Unlock()
+	return nil
+}
+
+// Discard forgets staged content without advertising it, and reports whether
+// it was staged. Its chunks stay in the local storage, which has no way to
+// delete them, but are never advertised; content staged again later is
+// stored without copying them again.
+func (cp *ContentPublisher) Discard(manifestCID string) bool {
+	cp.stagedMu.Lock()
+	defer cp.stagedMu.Unlock()
+	_, ok := cp.staged[manifestCID]
+	delete(cp.staged, manifestCID)
+	return ok
+}
+
+// Staged returns the manifest CIDs of the content staged and neither
+// committed nor discarded, sorted.
+func (cp *ContentPublisher) Staged() []string {
+	cp.stagedMu.Lock()
+	defer cp.stagedMu.Unlock()
+	cids := make([]string, 0, len(cp.staged))
+	for cid := range cp.staged {
+		cids = append(cids, cid)
+	}
+	sort.Strings(cids)
+	return cids
+}
@@ -0,0 +1,59 @@
+package content
+
+import (
+	"context"
+	"digisocialblock/internal/testutil"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestContentPublisher_StageAndCommit(t *testing.T) {
+	dds := testutil.NewDDS(8)
+	publisher, _ := NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	retriever, _ := NewContentRetriever(dds.Manifests, dds.Storage)
+	ctx := context.Background()
+
+	result, err := publisher.Stage(ctx, strings.NewReader("draft for review"))
+	if err != nil || result.Stored == 0 {
+		t.Fatalf("Stage() = %+v, %v; want the chunks stored", result, err)
+	}
+	cid := result.ManifestCID
+	if len(dds.Originator.Advertised()) != 0 {
+		t.Fatalf("Stage() advertised %v", dds.Originator.Advertised())
+	}
+	if text, err := retriever.RetrieveAndVerifyTextPost(cid); err != nil || text != "draft for review" {
+		t.Errorf("preview of staged content = %q, %v", text, err)
+	}
+	if staged := publisher.Staged(); len(staged) != 1 || staged[0] != cid {
+		t.Errorf("Staged() = %v, want [%s]", staged, cid)
+	}
+
+	// A failed advertisement leaves the content staged for a retry.
+	dds.Originator.Fail(fmt.Errorf("network down"))
+	if err := publisher.Commit(ctx, cid); err == nil {
+		t.Error("Commit() succeeded though the advertisement failed")
+	}
+	dds.Originator.Fail(nil)
+	if err := publisher.Commit(ctx, cid); err != nil {
+		t.Fatalf("retried Commit() error = %v", err)
+	}
+	if advertised := dds.Originator.Advertised(); len(advertised) != 2 || advertised[1] != cid {
+		t.Errorf("Advertised() = %v, want the staged CID", advertised)
+	}
+	if err := publisher.Commit(ctx, cid); !errors.Is(err, ErrNotStaged) {
+		t.Errorf("second Commit() error = %v, want ErrNotStaged", err)
+	}
+
+	discarded, _ := publisher.Stage(ctx, strings.NewReader("never mind"))
+	if !publisher.Discard(discarded.ManifestCID) || publisher.Discard(discarded.ManifestCID) {
+		t.Error("Discard() did not report the staged content exactly once")
+	}
+	if err := publisher.Commit(ctx, discarded.ManifestCID); !errors.Is(err, ErrNotStaged) {
+		t.Errorf("Commit() after Discard() error = %v, want ErrNotStaged", err)
+	}
+	if len(dds.Originator.Advertised()) != 2 || len(publisher.Staged()) != 0 {
+		t.Errorf("discarded content was advertised or is still staged")
+	}
+}
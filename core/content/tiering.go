@@ -0,0 +1,284 @@
+package content
+
+import (
+	"context"
+	"digisocialblock/core/ledger"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Default TieringPolicy thresholds.
+const (
+	DefaultColdAfter = 90 * 24 * time.Hour // Post age before its chunks may go cold
+	DefaultIdleFor   = 30 * 24 * time.Hour // Time without reads before a chunk may go cold
+)
+
+// HotStorage is the local storage of a TieredStorage. Chunks are deleted from
+// it once they have been copied to the cold tier.
+type HotStorage interface {
+	DDSStorage
+	DeleteChunk(chunkID string) error
+}
+
+// TieringPolicy decides which posts' chunks TieredStorage.Archive moves to the
+// cold tier: those of posts at least ColdAfter old whose chunks have not been
+// stored or read for IdleFor.
+type TieringPolicy struct {
+	// ColdAfter is the post age after which its chunks may go cold. Zero
+	// means DefaultColdAfter.
+	ColdAfter time.Duration
+	// IdleFor is how long a chunk must go without being stored or read before
+	// it may go cold. Zero means DefaultIdleFor.
+	IdleFor time.Duration
+	// Authors overrides ColdAfter for the posts of the given authors, keyed by
+	// public key. A negative duration keeps an author's posts hot.
+	Authors map[string]time.Duration
+}
+
+// coldAfter returns how old a post by author must be before it may go cold,
+// and false if the author's posts stay hot.
+func (p TieringPolicy) coldAfter(author string) (time.Duration, bool) {
+	d, ok := p.Authors[author]
+	if !ok {
+		d = p.ColdAfter
+	}
+	return d, d >= 0
+}
+
+// TieringCandidate is published content that TieredStorage.Archive may move
+// to the cold tier. social.FeedService.TieringCandidates lists the content of
+// every indexed post.
+type TieringCandidate struct {
+	ManifestCID string
+	Author      string // Public key of the post's author
+	Published   time.Time
+}
+
+// TieringReport summarizes an Archive run.
+type TieringReport struct {
+	Archived int   // Manifests whose chunks are all cold now
+	Chunks   int   // Chunks moved
+	Bytes    int64 // Bytes moved
+}
+
+// TieredStorage is a DDSStorage that keeps chunks on a hot local storage and
+// moves the chunks of old, rarely read posts to a cold one (e.g. an object
+// store) when Archive is called. New chunks are stored hot; reads try the hot
+// tier and fall back to the cold one, so a ContentRetriever or Gateway using
+// it as its chunk retriever serves archived posts unchanged, if more slowly.
+//
+// Chunk reads are tracked in memory, so after a restart every chunk counts as
+// read when the TieredStorage was created. TieredStorage is safe for
+// concurrent use if its storages and manifest fetcher are.
+type TieredStorage struct {
+	hot       HotStorage
+	cold      DDSStorage
+	manifests DDSManifestFetcher
+	policy    TieringPolicy
+
+	mu         sync.Mutex
+	clock      ledger.Clock
+	since      time.Time            // When tracking started; the last access of chunks not seen since
+	lastAccess map[string]time.Time // Chunk CID -> last store or read
+}
+
+// NewTieredStorage creates a TieredStorage over hot and cold, which looks up
+// the chunks of candidate posts with manifests.
+func NewTieredStorage(hot HotStorage, cold DDSStorage, manifests DDSManifestFetcher, policy TieringPolicy) (*TieredStorage, error) {
+	if hot == nil {
+		return nil, errors.New("hot storage cannot be nil")
+	}
+	if cold == nil {
+		return nil, errors.New("cold storage cannot be nil")
+	}
+	if manifests == nil {
+		return nil, errors.New("manifest fetcher cannot be nil")
+	}
+	if policy.ColdAfter < 0 || policy.IdleFor < 0 {
+		return nil, fmt.Errorf("invalid tiering policy: ColdAfter %s, IdleFor %s", policy.ColdAfter, policy.IdleFor)
+	}
+	if policy.ColdAfter == 0 {
+		policy.ColdAfter = DefaultColdAfter
+	}
+	if policy.IdleFor == 0 {
+		policy.IdleFor = DefaultIdleFor
+	}
+	authors := make(map[string]time.Duration, len(policy.Authors))
+	for author, d := range policy.Authors {
+		authors[author] = d
+	}
+	policy.Authors = authors
+	return &TieredStorage{
+		hot:        hot,
+		cold:       cold,
+		manifests:  manifests,
+		policy:     policy,
+		clock:      ledger.SystemClock,
+		since:      ledger.SystemClock.Now(),
+		lastAccess: make(map[string]time.Time),
+	}, nil
+}
+
+// SetClock sets the clock that times post ages and chunk reads, and restarts
+// the idle time of chunks not accessed yet. A nil clock restores
+// ledger.SystemClock.
+func (ts *TieredStorage) SetClock(clock ledger.Clock) {
+	if clock == nil {
+		clock = ledger.SystemClock
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.clock = clock
+	ts.since = clock.Now()
+}
+
+// touch records an access to chunkID.
+func (ts *TieredStorage) touch(chunkID string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.lastAccess[chunkID] = ts.clock.Now()
+}
+
+// StoreChunk stores the chunk on the hot tier.
+func (ts *TieredStorage) StoreChunk(chunkID string, data []byte) error {
+	if err := ts.hot.StoreChunk(chunkID, data); err != nil {
+		return err
+	}
+	ts.touch(chunkID)
+	return nil
+}
+
+// RetrieveChunk returns the chunk from the hot tier, or from the cold tier if
+// it is not hot.
+func (ts *TieredStorage) RetrieveChunk(chunkID string) ([]byte, error) {
+	data, err := ts.hot.RetrieveChunk(chunkID)
+	if err != nil {
+		if ts.hot.ChunkExists(chunkID) {
+			return nil, err
+		}
+		if data, err = ts.cold.RetrieveChunk(chunkID); err != nil {
+			return nil, err
+		}
+	}
+	ts.touch(chunkID)
+	return data, nil
+}
+
+// ChunkExists reports whether the chunk is on either tier.
+func (ts *TieredStorage) ChunkExists(chunkID string) bool {
+	return ts.hot.ChunkExists(chunkID) || ts.cold.ChunkExists(chunkID)
+}
+
+// Archive moves to the cold tier the hot chunks of every candidate the policy
+// says is due. A chunk shared with a candidate that is not due stays hot.
+// Each chunk is copied to the cold tier before it is deleted from the hot
+// one, so an interrupted run loses nothing and can simply be repeated.
+// Candidates whose manifest cannot be fetched, and chunks that fail to move,
+// are reported in the returned error; the rest are still archived.
+func (ts *TieredStorage) Archive(ctx context.Context, candidates []TieringCandidate) (TieringReport, error) {
+	var report TieringReport
+	var errs []error
+	type due struct {
+		manifestCID string
+		chunks      []string
+	}
+	var archive []due
+	keep := make(map[string]bool) // Chunks of candidates that are not due
+	for _, c := range candidates {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		manifest, err := ts.manifests.FetchManifest(c.ManifestCID)
+		if err == nil && manifest == nil {
+			err = errors.New("manifest not found")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("manifest %s: %w", c.ManifestCID, err))
+			continue
+		}
+		chunks := make([]string, len(manifest.Chunks))
+		for i, chunk := range manifest.Chunks {
+			chunks[i] = chunk.ChunkCID
+		}
+		if ts.isDue(c, chunks) {
+			archive = append(archive, due{c.ManifestCID, chunks})
+		} else {
+			for _, chunkID := range chunks {
+				keep[chunkID] = true
+			}
+		}
+	}
+
+	moved := make(map[string]bool)
+	for _, d := range archive {
+		complete := true
+		for _, chunkID := range d.chunks {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+			if keep[chunkID] {
+				complete = false
+				continue
+			}
+			if moved[chunkID] || !ts.hot.ChunkExists(chunkID) {
+				continue
+			}
+			n, err := ts.moveChunk(chunkID)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("manifest %s: %w", d.manifestCID, err))
+				complete = false
+				continue
+			}
+			moved[chunkID] = true
+			report.Chunks++
+			report.Bytes += int64(n)
+		}
+		if complete {
+			report.Archived++
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+// isDue reports whether the policy moves c, whose manifest lists chunks, to
+// the cold tier now.
+func (ts *TieredStorage) isDue(c TieringCandidate, chunks []string) bool {
+	coldAfter, ok := ts.policy.coldAfter(c.Author)
+	if !ok {
+		return false
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	now := ts.clock.Now()
+	if now.Sub(c.Published) < coldAfter {
+		return false
+	}
+	for _, chunkID := range chunks {
+		last, ok := ts.lastAccess[chunkID]
+		if !ok {
+			last = ts.since
+		}
+		if now.Sub(last) < ts.policy.IdleFor {
+			return false
+		}
+	}
+	return true
+}
+
+// moveChunk copies a chunk from the hot tier to the cold one, then deletes it
+// from the hot tier, and returns its size.
+func (ts *TieredStorage) moveChunk(chunkID string) (int, error) {
+	data, err := ts.hot.RetrieveChunk(chunkID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read chunk %s: %w", chunkID, err)
+	}
+	if err := ts.cold.StoreChunk(chunkID, data); err != nil {
+		return 0, fmt.Errorf("failed to store chunk %s cold: %w", chunkID, err)
+	}
+	if err := ts.hot.DeleteChunk(chunkID); err != nil {
+		return 0, fmt.Errorf("failed to delete chunk %s after archiving it: %w", chunkID, err)
+	}
+	return len(data), nil
+}
@@ -0,0 +1,113 @@
+package content
+
+import (
+	"context"
+	"digisocialblock/internal/testutil"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tieringHotStorage adds DeleteChunk to a testutil.Storage.
+type tieringHotStorage struct {
+	*testutil.Storage
+}
+
+func (s tieringHotStorage) DeleteChunk(chunkID string) error {
+	s.Delete(chunkID)
+	return nil
+}
+
+// tieringTestStorage returns a TieredStorage on clock with hot and cold
+// in-memory tiers, and a publisher and retriever using it.
+func tieringTestStorage(t *testing.T, clock *testutil.Clock, policy TieringPolicy) (*TieredStorage, *testutil.Storage, *testutil.Storage, *ContentPublisher, *ContentRetriever) {
+	t.Helper()
+	hot, cold, manifests := testutil.NewStorage(), testutil.NewStorage(), testutil.NewManifestFetcher()
+	ts, err := NewTieredStorage(tieringHotStorage{hot}, cold, manifests, policy)
+	if err != nil {
+		t.Fatalf("NewTieredStorage() error = %v", err)
+	}
+	ts.SetClock(clock)
+	publisher, _ := NewContentPublisher(&testutil.Chunker{ChunkSize: 16, Manifests: manifests}, ts, &testutil.Originator{})
+	retriever, _ := NewContentRetriever(manifests, ts)
+	return ts, hot, cold, publisher, retriever
+}
+
+func TestTieredStorage_ArchivesOldIdlePostsPerAuthorPolicy(t *testing.T) {
+	clock := testutil.NewClock(0)
+	ts, hot, cold, publisher, retriever := tieringTestStorage(t, clock, TieringPolicy{Authors: map[string]time.Duration{"bob": -1}})
+	publish := func(text string) string {
+		cid, err := publisher.PublishTextPostToDDS(text)
+		if err != nil {
+			t.Fatalf("PublishTextPostToDDS() error = %v", err)
+		}
+		return cid
+	}
+	start := clock.Peek()
+	const shared = "shared-16-bytes!"
+	archived := publish("an old post that nobody reads any more")
+	partly := publish(shared + "and an old ending!")
+	pinned := publish("bob keeps every post of his hot")
+	reread := publish("an old post read again recently")
+	// A post stored long ago but published on the chain only recently, with
+	// a chunk in common with an old one.
+	recent := publish(shared + "with a new ending")
+	clock.Advance(80 * 24 * time.Hour)
+	retriever.RetrieveAndVerifyTextPost(reread)
+	clock.Advance(20 * 24 * time.Hour)
+
+	hotBefore := hot.Len()
+	report, err := ts.Archive(context.Background(), []TieringCandidate{
+		{ManifestCID: archived, Author: "alice", Published: start},
+		{ManifestCID: partly, Author: "alice", Published: start},
+		{ManifestCID: pinned, Author: "bob", Published: start},
+		{ManifestCID: reread, Author: "alice", Published: start},
+		{ManifestCID: recent, Author: "alice", Published: start.Add(95 * 24 * time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+	// Three chunks of the archived post, and the two of the other old post
+	// that the recent one does not share.
+	if report.Archived != 1 || report.Chunks != 5 || cold.Len() != 5 || hot.Len() != hotBefore-5 {
+		t.Errorf("Archive() = %+v with %d hot and %d cold chunks; want 1 manifest and 5 chunks moved", report, hot.Len(), cold.Len())
+	}
+	for _, cid := range []string{archived, partly, pinned, reread, recent} {
+		if _, err := retriever.RetrieveAndVerifyTextPost(cid); err != nil {
+			t.Errorf("RetrieveAndVerifyTextPost(%s) after archiving error = %v", cid, err)
+		}
+	}
+}
+
+func TestTieredStorage_FailedMoveKeepsChunkHot(t *testing.T) {
+	clock := testutil.NewClock(0)
+	ts, hot, cold, publisher, retriever := tieringTestStorage(t, clock, TieringPolicy{ColdAfter: time.Hour, IdleFor: time.Hour})
+	cid, _ := publisher.PublishTextPostToDDS("a post the cold tier refuses")
+	clock.Advance(2 * time.Hour)
+	cold.FailStores(errors.New("bucket unavailable"))
+
+	report, err := ts.Archive(context.Background(), []TieringCandidate{
+		{ManifestCID: cid, Author: "alice", Published: clock.Peek().Add(-2 * time.Hour)},
+		{ManifestCID: "missing", Author: "alice"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "bucket unavailable") || !strings.Contains(err.Error(), "manifest missing") {
+		t.Errorf("Archive() error = %v, want the failed store and the missing manifest", err)
+	}
+	if report.Archived != 0 || report.Chunks != 0 || hot.Len() != 2 {
+		t.Errorf("Archive() = %+v with %d hot chunks, want nothing moved", report, hot.Len())
+	}
+	if _, err := retriever.RetrieveAndVerifyTextPost(cid); err != nil {
+		t.Errorf("RetrieveAndVerifyTextPost() after a failed move error = %v", err)
+	}
+}
+
+func TestNewTieredStorage_RejectsInvalidArgs(t *testing.T) {
+	hot, cold, manifests := tieringHotStorage{testutil.NewStorage()}, testutil.NewStorage(), testutil.NewManifestFetcher()
+	if _, err := NewTieredStorage(hot, nil, manifests, TieringPolicy{}); err == nil {
+		t.Error("NewTieredStorage() with no cold storage: expected error, got nil")
+	}
+	if _, err := NewTieredStorage(hot, cold, manifests, TieringPolicy{IdleFor: -time.Hour}); err == nil {
+		t.Error("NewTieredStorage() with a negative IdleFor: expected error, got nil")
+	}
+}
@@ -0,0 +1,170 @@
+package content
+
+import (
+	"context"
+	"crypto/sha256"
+	"digisocialblock/core/publicnet"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultWebMirrorMaxSize bounds the size of web content a WebMirror fetches.
+	DefaultWebMirrorMaxSize = 16 << 20
+	// DefaultWebMirrorTimeout bounds a whole fetch by the default client,
+	// redirects included.
+	DefaultWebMirrorTimeout = 30 * time.Second
+	// MaxWebMirrorRedirects is the number of redirects the default client
+	// follows before giving up.
+	MaxWebMirrorRedirects = 5
+)
+
+var (
+	// ErrWebContentMismatch is returned when fetched web content does not hash
+	// to the SHA-256 the post pinned it to.
+	ErrWebContentMismatch = errors.New("web content does not match its hash")
+	// ErrNonPublicAddress is returned when the default client is asked to
+	// connect to an address that is not on the public internet.
+	ErrNonPublicAddress = publicnet.ErrNonPublicAddress
+)
+
+// WebMirrorOptions configures a WebMirror.
+type WebMirrorOptions struct {
+	// Client fetches web content. Nil means NewWebMirrorClient(); a client
+	// given here is used as is, without its address and redirect checks.
+	Client *http.Client
+	// MaxSize is the largest response body accepted, in bytes. Zero means
+	// DefaultWebMirrorMaxSize.
+	MaxSize int64
+}
+
+// WebMirror bridges HTTPS content into DDS. Posts can reference web content
+// by URL and SHA-256 (see social.WebSource); the mirror fetches it once,
+// verifies the hash and publishes it to DDS, and serves later requests for
+// the same hash from DDS. The hash-to-manifest index is kept in memory.
+type WebMirror struct {
+	publisher *ContentPublisher
+	retriever *ContentRetriever
+	client    *http.Client
+	maxSize   int64
+
+	mu        sync.Mutex
+	manifests map[string]string // SHA-256 hex -> manifest CID
+}
+
+// NewWebMirror creates a WebMirror that caches into publisher's storage and
+// reads the cache back through retriever.
+func NewWebMirror(publisher *ContentPublisher, retriever *ContentRetriever, opts WebMirrorOptions) (*WebMirror, error) {
+	if publisher == nil {
+		return nil, errors.New("content publisher cannot be nil")
+	}
+	if retriever == nil {
+		return nil, errors.New("content retriever cannot be nil")
+	}
+	if opts.MaxSize < 0 {
+		return nil, fmt.Errorf("invalid web mirror max size %d", opts.MaxSize)
+	}
+	if opts.Client == nil {
+		opts.Client = NewWebMirrorClient()
+	}
+	if opts.MaxSize == 0 {
+		opts.MaxSize = DefaultWebMirrorMaxSize
+	}
+	return &WebMirror{
+		publisher: publisher,
+		retriever: retriever,
+		client:    opts.Client,
+		maxSize:   opts.MaxSize,
+		manifests: make(map[string]string),
+	}, nil
+}
+
+// Fetch returns the content at rawURL, which must hash to sha256Hex. Content
+// already mirrored is read from DDS; otherwise it is downloaded over HTTPS,
+// verified and published to DDS first.
+func (m *WebMirror) Fetch(ctx context.Context, rawURL, sha256Hex string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("web mirror only fetches absolute https URLs, got %q", rawURL)
+	}
+	sha256Hex = strings.ToLower(sha256Hex)
+	if manifestCID, ok := m.ManifestCID(sha256Hex); ok {
+		text, err := m.retriever.RetrieveAndVerifyTextPostContext(ctx, manifestCID)
+		if err == nil && cidsEqual(hashHex([]byte(text)), sha256Hex) {
+			return []byte(text), nil
+		}
+		// The cached copy is gone or damaged; fall through and re-mirror it.
+	}
+
+	data, err := m.download(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	if !cidsEqual(hashHex(data), sha256Hex) {
+		return nil, fmt.Errorf("%w: %s", ErrWebContentMismatch, rawURL)
+	}
+	manifestCID, err := m.publisher.PublishMediaToDDSContext(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mirror %s into DDS: %w", rawURL, err)
+	}
+	m.mu.Lock()
+	m.manifests[sha256Hex] = manifestCID
+	m.mu.Unlock()
+	return data, nil
+}
+
+// ManifestCID returns the DDS manifest CID of mirrored content with the given
+// SHA-256, if it has been mirrored.
+func (m *WebMirror) ManifestCID(sha256Hex string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	manifestCID, ok := m.manifests[strings.ToLower(sha256Hex)]
+	return manifestCID, ok
+}
+
+func (m *WebMirror) download(ctx context.Context, u *url.URL) ([]byte, error) {
+	rawURL := u.String()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request for %s: %w", rawURL, err)
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", rawURL, resp.Status)
+	}
+	if resp.ContentLength > m.maxSize {
+		return nil, fmt.Errorf("web content at %s exceeds %d bytes", rawURL, m.maxSize)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, m.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", rawURL, err)
+	}
+	if int64(len(data)) > m.maxSize {
+		return nil, fmt.Errorf("web content at %s exceeds %d bytes", rawURL, m.maxSize)
+	}
+	return data, nil
+}
+
+// NewWebMirrorClient returns the client a WebMirror uses by default. URLs in
+// posts are chosen by their authors, so it is a publicnet client: it connects
+// only to public addresses, follows at most MaxWebMirrorRedirects redirects,
+// all to https URLs, and gives up after DefaultWebMirrorTimeout.
+func NewWebMirrorClient() *http.Client {
+	return publicnet.NewClient(publicnet.Options{Timeout: DefaultWebMirrorTimeout, MaxRedirects: MaxWebMirrorRedirects})
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
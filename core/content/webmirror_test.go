@@ -0,0 +1,88 @@
+package content
+
+import (
+	"context"
+	"crypto/sha256"
+	"digisocialblock/internal/testutil"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWebMirror_FetchVerifiesAndCaches(t *testing.T) {
+	body := strings.Repeat("web content ", 100)
+	sum := sha256.Sum256([]byte(body))
+	hash := hex.EncodeToString(sum[:])
+	var hits atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	dds := testutil.NewDDS(256)
+	store := dds.Storage
+	publisher, _ := NewContentPublisher(dds.Chunker, store, dds.Originator)
+	retriever, _ := NewContentRetriever(dds.Manifests, store)
+	mirror, err := NewWebMirror(publisher, retriever, WebMirrorOptions{Client: srv.Client(), MaxSize: 4096})
+	if err != nil {
+		t.Fatalf("NewWebMirror() error = %v", err)
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		data, err := mirror.Fetch(ctx, srv.URL+"/page", strings.ToUpper(hash))
+		if err != nil {
+			t.Fatalf("Fetch() #%d error = %v", i+1, err)
+		}
+		if string(data) != body {
+			t.Errorf("Fetch() #%d returned %d bytes, want the served %d", i+1, len(data), len(body))
+		}
+	}
+	if hits.Load() != 1 {
+		t.Errorf("server was hit %d times, want 1 (second fetch served from DDS)", hits.Load())
+	}
+	if _, ok := mirror.ManifestCID(hash); !ok {
+		t.Error("ManifestCID() found no mirrored copy")
+	}
+
+	// A lost chunk is re-mirrored from the web.
+	store.Clear()
+	if _, err := mirror.Fetch(ctx, srv.URL+"/page", hash); err != nil || hits.Load() != 2 {
+		t.Errorf("Fetch() after losing the cache = %v with %d hits, want a re-fetch", err, hits.Load())
+	}
+
+	if _, err := mirror.Fetch(ctx, srv.URL+"/page", strings.Repeat("0", 64)); !errors.Is(err, ErrWebContentMismatch) {
+		t.Errorf("Fetch() with the wrong hash error = %v, want ErrWebContentMismatch", err)
+	}
+	if _, err := mirror.Fetch(ctx, strings.Replace(srv.URL, "https://", "http://", 1), hash); err == nil {
+		t.Error("Fetch() of an http URL: expected error, got nil")
+	}
+	small, _ := NewWebMirror(publisher, retriever, WebMirrorOptions{Client: srv.Client(), MaxSize: 100})
+	if _, err := small.Fetch(ctx, srv.URL+"/page", strings.Repeat("1", 64)); err == nil || errors.Is(err, ErrWebContentMismatch) {
+		t.Errorf("Fetch() of oversized content error = %v, want a size error", err)
+	}
+}
+
+func TestWebMirror_DefaultClientRefusesNonPublicAddresses(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	dds := testutil.NewDDS(0)
+	publisher, _ := NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	retriever, _ := NewContentRetriever(dds.Manifests, dds.Storage)
+	mirror, _ := NewWebMirror(publisher, retriever, WebMirrorOptions{})
+	if _, err := mirror.Fetch(context.Background(), srv.URL+"/page", strings.Repeat("0", 64)); !errors.Is(err, ErrNonPublicAddress) {
+		t.Errorf("Fetch() from a loopback server error = %v, want ErrNonPublicAddress", err)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("loopback server was hit %d times, want 0", hits.Load())
+	}
+}
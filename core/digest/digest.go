@@ -0,0 +1,261 @@
+// Package digest compiles periodic email summaries for users of a node: new
+// posts from the accounts they follow and the notifications they have not
+// seen yet (new followers, comments on their posts, replies to their
+// comments). Digests are rendered as plain-text mail and handed to a Mailer;
+// how often each user gets one is configured per user (see Digester).
+//
+// "New" and "unread" are measured in chain position, not in claimed
+// timestamps: a digest covers the blocks added since the previous digest.
+package digest
+
+import (
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"digisocialblock/core/user"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultMaxItems bounds the posts and the notifications listed in one digest.
+const DefaultMaxItems = 50
+
+// PostSource looks up indexed posts; social.FeedService implements it.
+type PostSource interface {
+	GetPost(txID string) (social.FeedEntry, bool)
+	GetUserFeed(authorPublicKey string, limit int) []social.FeedEntry
+}
+
+// GraphSource looks up comments and follows; social.GraphIndex implements it.
+type GraphSource interface {
+	GetComment(txID string) (social.CommentEntry, bool)
+	GetFollowers(address string) []social.FollowEdge
+	GetFollowing(address string) []social.FollowEdge
+}
+
+// BlockSource reads the chain; *ledger.Blockchain implements it.
+type BlockSource interface {
+	GetBlockByIndex(index int64) *ledger.Block
+	GetLatestBlock() *ledger.Block
+}
+
+// ProfileSource returns the current profile of an address, for display
+// names. A nil profile or an error shows the address instead.
+type ProfileSource interface {
+	GetProfile(address string) (*user.Profile, error)
+}
+
+// NotificationKind says what a notification is about.
+type NotificationKind string
+
+const (
+	NotifyFollow  NotificationKind = "follow"  // Someone followed the user
+	NotifyComment NotificationKind = "comment" // Someone commented on the user's post
+	NotifyReply   NotificationKind = "reply"   // Someone replied to the user's comment
+)
+
+// Notification is one unread notification.
+type Notification struct {
+	Kind       NotificationKind `json:"kind"`
+	From       string           `json:"from"`
+	FromName   string           `json:"fromName,omitempty"`
+	TxID       string           `json:"txId"`
+	PostTxID   string           `json:"postTxId,omitempty"` // For comments and replies
+	BlockIndex int64            `json:"blockIndex"`
+}
+
+// DigestPost is a new post by a followed account.
+type DigestPost struct {
+	social.FeedEntry
+	AuthorName string `json:"authorName,omitempty"`
+}
+
+// Digest is the summary for one user of blocks FromBlock to ToBlock.
+type Digest struct {
+	Address       string         `json:"address"`
+	FromBlock     int64          `json:"fromBlock"`
+	ToBlock       int64          `json:"toBlock"`
+	Posts         []DigestPost   `json:"posts"`         // Newest first
+	Notifications []Notification `json:"notifications"` // Newest first
+	MorePosts     int            `json:"morePosts"`     // New posts left out beyond the limit
+	MoreNotifs    int            `json:"moreNotifications"`
+}
+
+// Empty reports whether there is nothing to tell the user.
+func (dg *Digest) Empty() bool {
+	return len(dg.Posts) == 0 && len(dg.Notifications) == 0
+}
+
+// compiler gathers digests from the node's indexes.
+type compiler struct {
+	posts    PostSource
+	graph    GraphSource
+	blocks   BlockSource
+	profiles ProfileSource // Optional
+	maxItems int
+}
+
+// compile builds the digest of blocks after sinceBlock up to toBlock for
+// address.
+func (c *compiler) compile(address string, sinceBlock, toBlock int64) (*Digest, error) {
+	dg := &Digest{Address: address, FromBlock: sinceBlock + 1, ToBlock: toBlock}
+	inRange := func(index int64) bool { return index > sinceBlock && index <= toBlock }
+
+	for _, follow := range c.graph.GetFollowing(address) {
+		for _, entry := range c.posts.GetUserFeed(follow.Followee, 0) { // Newest first
+			if entry.BlockIndex <= sinceBlock {
+				break
+			}
+			if inRange(entry.BlockIndex) && entry.GroupID == "" {
+				dg.Posts = append(dg.Posts, DigestPost{FeedEntry: entry})
+			}
+		}
+	}
+	sort.SliceStable(dg.Posts, func(i, j int) bool { return dg.Posts[i].BlockIndex > dg.Posts[j].BlockIndex })
+
+	for _, follow := range c.graph.GetFollowers(address) {
+		if inRange(follow.BlockIndex) {
+			dg.Notifications = append(dg.Notifications, Notification{
+				Kind: NotifyFollow, From: follow.Follower, TxID: follow.TxID, BlockIndex: follow.BlockIndex,
+			})
+		}
+	}
+	for i := sinceBlock + 1; i <= toBlock; i++ {
+		block := c.blocks.GetBlockByIndex(i)
+		if block == nil {
+			return nil, fmt.Errorf("block %d missing while compiling digest", i)
+		}
+		for _, tx := range block.Transactions {
+			if tx == nil || tx.Type != ledger.CommentAdded {
+				continue
+			}
+			if n, ok := c.commentNotification(address, tx.ID); ok {
+				dg.Notifications = append(dg.Notifications, n)
+			}
+		}
+	}
+	sort.SliceStable(dg.Notifications, func(i, j int) bool {
+		return dg.Notifications[i].BlockIndex > dg.Notifications[j].BlockIndex
+	})
+
+	if len(dg.Posts) > c.maxItems {
+		dg.MorePosts = len(dg.Posts) - c.maxItems
+		dg.Posts = dg.Posts[:c.maxItems]
+	}
+	if len(dg.Notifications) > c.maxItems {
+		dg.MoreNotifs = len(dg.Notifications) - c.maxItems
+		dg.Notifications = dg.Notifications[:c.maxItems]
+	}
+	for i := range dg.Posts {
+		dg.Posts[i].AuthorName = c.name(dg.Posts[i].AuthorPublicKey)
+	}
+	for i := range dg.Notifications {
+		dg.Notifications[i].FromName = c.name(dg.Notifications[i].From)
+	}
+	return dg, nil
+}
+
+// commentNotification returns the notification address gets for the
+// comment txID, if any. Only comments the graph index accepted count, and
+// nobody is notified of their own comments.
+func (c *compiler) commentNotification(address, txID string) (Notification, bool) {
+	comment, ok := c.graph.GetComment(txID)
+	if !ok || comment.AuthorPublicKey == address {
+		return Notification{}, false
+	}
+	n := Notification{From: comment.AuthorPublicKey, TxID: txID, PostTxID: comment.PostTxID, BlockIndex: comment.BlockIndex}
+	if comment.ParentTxID != "" {
+		if parent, ok := c.graph.GetComment(comment.ParentTxID); ok && parent.AuthorPublicKey == address {
+			n.Kind = NotifyReply
+			return n, true
+		}
+	}
+	if post, ok := c.posts.GetPost(comment.PostTxID); ok && post.AuthorPublicKey == address {
+		n.Kind = NotifyComment
+		return n, true
+	}
+	return Notification{}, false
+}
+
+// name returns the display name of address, or "" if it has none.
+func (c *compiler) name(address string) string {
+	if c.profiles == nil {
+		return ""
+	}
+	if p, err := c.profiles.GetProfile(address); err == nil && p != nil {
+		return p.DisplayName
+	}
+	return ""
+}
+
+// who renders a person for the mail body.
+func who(address, name string) string {
+	short := address
+	if len(short) > 16 {
+		short = short[:16] + "…"
+	}
+	if name == "" {
+		return short
+	}
+	return name + " (" + short + ")"
+}
+
+// Message renders the digest as a plain-text mail to the given recipient.
+// linkBase, if set, is prefixed to post transaction IDs to link to them (for
+// example "https://node.example.org/posts/").
+func (dg *Digest) Message(to, linkBase string) Message {
+	var b strings.Builder
+	subject := fmt.Sprintf("Your digest: %s, %s",
+		plural(len(dg.Posts)+dg.MorePosts, "new post"), plural(len(dg.Notifications)+dg.MoreNotifs, "notification"))
+
+	if len(dg.Notifications) > 0 {
+		b.WriteString("Notifications\n\n")
+		for _, n := range dg.Notifications {
+			from := who(n.From, n.FromName)
+			switch n.Kind {
+			case NotifyFollow:
+				fmt.Fprintf(&b, "- %s followed you\n", from)
+			case NotifyComment:
+				fmt.Fprintf(&b, "- %s commented on your post%s\n", from, link(linkBase, n.PostTxID))
+			case NotifyReply:
+				fmt.Fprintf(&b, "- %s replied to your comment%s\n", from, link(linkBase, n.PostTxID))
+			}
+		}
+		if dg.MoreNotifs > 0 {
+			fmt.Fprintf(&b, "- and %s\n", plural(dg.MoreNotifs, "more notification"))
+		}
+		b.WriteString("\n")
+	}
+	if len(dg.Posts) > 0 {
+		b.WriteString("New posts from people you follow\n\n")
+		for _, p := range dg.Posts {
+			title := p.Title
+			if title == "" {
+				title = "(untitled)"
+			}
+			fmt.Fprintf(&b, "- %s: %s, %s%s\n", who(p.AuthorPublicKey, p.AuthorName), title,
+				time.Unix(0, p.Timestamp).UTC().Format("Jan 2 15:04 UTC"), link(linkBase, p.TxID))
+		}
+		if dg.MorePosts > 0 {
+			fmt.Fprintf(&b, "- and %s\n", plural(dg.MorePosts, "more post"))
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "This digest covers blocks %d to %d.\n", dg.FromBlock, dg.ToBlock)
+	return Message{To: to, Subject: subject, Text: b.String()}
+}
+
+func link(base, txID string) string {
+	if base == "" || txID == "" {
+		return ""
+	}
+	return "\n  " + base + txID
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
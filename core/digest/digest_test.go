@@ -0,0 +1,147 @@
+package digest
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"digisocialblock/core/user"
+	"digisocialblock/internal/testutil/fixture"
+	"strings"
+	"testing"
+)
+
+type digestTestProfiles map[string]string
+
+func (p digestTestProfiles) GetProfile(address string) (*user.Profile, error) {
+	name, ok := p[address]
+	if !ok {
+		return nil, nil
+	}
+	return user.NewProfile(address, name, ""), nil
+}
+
+// digestTestNode is a chain with alice following bob, and the indexes over it.
+type digestTestNode struct {
+	alice, bob, carol *identity.Wallet
+	bc                *ledger.Blockchain
+	feed              *social.FeedService
+	graph             *social.GraphIndex
+	alicePost         *ledger.Transaction
+}
+
+func newDigestTestNode(t *testing.T) *digestTestNode {
+	t.Helper()
+	n := &digestTestNode{}
+	n.alice, _ = identity.NewWallet()
+	n.bob, _ = identity.NewWallet()
+	n.carol, _ = identity.NewWallet()
+	n.bc, _ = ledger.NewBlockchain()
+	n.feed, _ = social.NewFeedService(nil)
+	n.graph = social.NewGraphIndex()
+	n.alicePost = fixture.TxFormat(t, n.alice, ledger.PostCreated, ledger.PayloadFormatJSON, social.NewPost(n.alice.Address, "cid-alice", "Mine", nil))
+	n.add(t,
+		fixture.TxFormat(t, n.alice, ledger.UserFollowed, ledger.PayloadFormatJSON, &social.Follow{FolloweePublicKey: n.bob.Address, Timestamp: 1}),
+		fixture.TxFormat(t, n.bob, ledger.PostCreated, ledger.PayloadFormatJSON, social.NewPost(n.bob.Address, "cid-old", "Old", nil)),
+		n.alicePost,
+	)
+	return n
+}
+
+// add appends a block and syncs the indexes.
+func (n *digestTestNode) add(t *testing.T, txs ...*ledger.Transaction) {
+	t.Helper()
+	if _, err := n.bc.AddBlock(txs); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+	if _, err := n.feed.Sync(n.bc); err != nil {
+		t.Fatalf("feed Sync() error = %v", err)
+	}
+	if _, err := n.graph.Sync(n.bc); err != nil {
+		t.Fatalf("graph Sync() error = %v", err)
+	}
+}
+
+func (n *digestTestNode) comment(t *testing.T, wallet *identity.Wallet, parent string) *ledger.Transaction {
+	t.Helper()
+	c := &social.Comment{AuthorPublicKey: wallet.Address, PostTxID: n.alicePost.ID, ParentTxID: parent, ContentCID: "cid-comment", Timestamp: 1}
+	return fixture.TxFormat(t, wallet, ledger.CommentAdded, ledger.PayloadFormatJSON, c)
+}
+
+func TestCompile_CollectsPostsAndNotifications(t *testing.T) {
+	n := newDigestTestNode(t)
+	since := n.bc.GetLatestBlock().Index
+
+	bobPost := fixture.TxFormat(t, n.bob, ledger.PostCreated, ledger.PayloadFormatJSON, social.NewPost(n.bob.Address, "cid-new", "News", nil))
+	group := social.NewPost(n.bob.Address, "cid-secret", "", nil)
+	group.GroupID, group.KeyEpoch = "club", 1
+	carolFollow := fixture.TxFormat(t, n.carol, ledger.UserFollowed, ledger.PayloadFormatJSON, &social.Follow{FolloweePublicKey: n.alice.Address, Timestamp: 2})
+	carolComment := n.comment(t, n.carol, "")
+	aliceComment := n.comment(t, n.alice, "")
+	n.add(t, bobPost, fixture.TxFormat(t, n.bob, ledger.PostCreated, ledger.PayloadFormatJSON, group), carolFollow, carolComment, aliceComment,
+		fixture.TxFormat(t, n.carol, ledger.PostCreated, ledger.PayloadFormatJSON, social.NewPost(n.carol.Address, "cid-carol", "", nil)))
+	carolReply := n.comment(t, n.carol, aliceComment.ID)
+	n.add(t, carolReply)
+
+	c := &compiler{posts: n.feed, graph: n.graph, blocks: n.bc, profiles: digestTestProfiles{n.carol.Address: "Carol"}, maxItems: DefaultMaxItems}
+	dg, err := c.compile(n.alice.Address, since, n.bc.GetLatestBlock().Index)
+	if err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+	if len(dg.Posts) != 1 || dg.Posts[0].TxID != bobPost.ID {
+		t.Errorf("Posts = %+v, want only bob's new public post", dg.Posts)
+	}
+	want := []struct {
+		kind NotificationKind
+		txID string
+	}{{NotifyReply, carolReply.ID}, {NotifyFollow, carolFollow.ID}, {NotifyComment, carolComment.ID}}
+	if len(dg.Notifications) != len(want) {
+		t.Fatalf("Notifications = %+v, want %d", dg.Notifications, len(want))
+	}
+	for i, w := range want {
+		got := dg.Notifications[i]
+		if got.Kind != w.kind || got.TxID != w.txID || got.From != n.carol.Address || got.FromName != "Carol" {
+			t.Errorf("Notifications[%d] = %+v, want %s %s from Carol", i, got, w.kind, w.txID)
+		}
+	}
+
+	c.maxItems = 1
+	dg, _ = c.compile(n.alice.Address, since, n.bc.GetLatestBlock().Index)
+	if len(dg.Notifications) != 1 || dg.MoreNotifs != 2 || dg.MorePosts != 0 {
+		t.Errorf("limited digest = %d notifications, %d more, %d more posts; want 1, 2, 0",
+			len(dg.Notifications), dg.MoreNotifs, dg.MorePosts)
+	}
+
+	if dg, _ := c.compile(n.bob.Address, n.bc.GetLatestBlock().Index, n.bc.GetLatestBlock().Index); !dg.Empty() {
+		t.Errorf("digest of no blocks = %+v, want empty", dg)
+	}
+}
+
+func TestDigest_Message(t *testing.T) {
+	dg := &Digest{
+		FromBlock: 3, ToBlock: 5,
+		Posts: []DigestPost{{FeedEntry: social.FeedEntry{TxID: "tx-post", AuthorPublicKey: "04bbbbbbbbbbbbbbbbbbbbbb", Timestamp: 1700000000000000000}, AuthorName: "Bob"}},
+		Notifications: []Notification{
+			{Kind: NotifyFollow, From: "04cc"},
+			{Kind: NotifyComment, From: "04dd", PostTxID: "tx-mine"},
+		},
+		MorePosts: 2,
+	}
+	msg := dg.Message("alice@example.org", "https://node.example.org/posts/")
+	if msg.To != "alice@example.org" || msg.Subject != "Your digest: 3 new posts, 2 notifications" {
+		t.Errorf("Message() To, Subject = %q, %q", msg.To, msg.Subject)
+	}
+	for _, want := range []string{
+		"- 04cc followed you\n",
+		"- 04dd commented on your post\n  https://node.example.org/posts/tx-mine\n",
+		"- Bob (04bbbbbbbbbbbbbb…): (untitled), Nov 14 22:13 UTC\n  https://node.example.org/posts/tx-post\n",
+		"- and 2 more posts\n",
+		"covers blocks 3 to 5",
+	} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("Message() text lacks %q:\n%s", want, msg.Text)
+		}
+	}
+	if strings.Contains(dg.Message("a@example.org", "").Text, "https://") {
+		t.Error("Message() without linkBase contains links")
+	}
+}
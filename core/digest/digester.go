@@ -0,0 +1,304 @@
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Frequency is how often a user gets a digest.
+type Frequency string
+
+const (
+	Daily  Frequency = "daily"
+	Weekly Frequency = "weekly"
+)
+
+func (f Frequency) interval() (time.Duration, error) {
+	switch f {
+	case Daily:
+		return 24 * time.Hour, nil
+	case Weekly:
+		return 7 * 24 * time.Hour, nil
+	}
+	return 0, fmt.Errorf("unknown digest frequency %q", f)
+}
+
+// ErrNotSubscribed is returned for an address without digest settings.
+var ErrNotSubscribed = errors.New("address is not subscribed to digests")
+
+// Subscriber is one user's digest settings and delivery state.
+type Subscriber struct {
+	Address   string    `json:"address"`
+	Email     string    `json:"email"`
+	Frequency Frequency `json:"frequency"`
+	Paused    bool      `json:"paused,omitempty"`
+
+	LastBlock  int64 `json:"lastBlock"`  // Last block covered by a digest
+	LastSentAt int64 `json:"lastSentAt"` // UnixNano; when the last digest was due
+}
+
+// SubscriberStore persists subscribers between restarts.
+type SubscriberStore interface {
+	// LoadSubscribers returns the saved subscribers, or nil if nothing has
+	// been saved yet.
+	LoadSubscribers() ([]Subscriber, error)
+	SaveSubscribers(subs []Subscriber) error
+}
+
+// FileSubscriberStore is a SubscriberStore backed by a single JSON file. It
+// holds email addresses, so it is written with mode 0600.
+type FileSubscriberStore struct {
+	path string
+}
+
+// NewFileSubscriberStore creates a FileSubscriberStore writing to path.
+func NewFileSubscriberStore(path string) (*FileSubscriberStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("subscriber store path cannot be empty")
+	}
+	return &FileSubscriberStore{path: path}, nil
+}
+
+// LoadSubscribers reads the subscriber file. A missing file is not an error.
+func (fs *FileSubscriberStore) LoadSubscribers() ([]Subscriber, error) {
+	data, err := os.ReadFile(fs.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subscribers %s: %w", fs.path, err)
+	}
+	var subs []Subscriber
+	if err := json.Unmarshal(data, &subs); err != nil {
+		return nil, fmt.Errorf("failed to decode subscribers %s: %w", fs.path, err)
+	}
+	return subs, nil
+}
+
+// SaveSubscribers atomically replaces the subscriber file.
+func (fs *FileSubscriberStore) SaveSubscribers(subs []Subscriber) error {
+	data, err := json.MarshalIndent(subs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode subscribers: %w", err)
+	}
+	tmp := fs.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write subscribers %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, fs.path); err != nil {
+		return fmt.Errorf("failed to replace subscribers %s: %w", fs.path, err)
+	}
+	return nil
+}
+
+// Options configures a Digester.
+type Options struct {
+	Posts    PostSource    // Required
+	Graph    GraphSource   // Required
+	Blocks   BlockSource   // Required
+	Profiles ProfileSource // Optional, for display names
+	Mailer   Mailer        // Required
+	Store    SubscriberStore
+	// LinkBase is prefixed to transaction IDs to link to posts from the mail.
+	LinkBase string
+	// MaxItems bounds the posts and the notifications per digest. Zero means
+	// DefaultMaxItems.
+	MaxItems int
+}
+
+// Digester keeps the node's digest subscribers and sends their digests when
+// due. Call SendDue periodically, e.g. every few minutes.
+type Digester struct {
+	compiler
+	mailer   Mailer
+	store    SubscriberStore // Optional
+	linkBase string
+
+	mu   sync.Mutex
+	subs map[string]*Subscriber
+}
+
+// NewDigester creates a Digester and loads any subscribers saved in
+// opts.Store.
+func NewDigester(opts Options) (*Digester, error) {
+	if opts.Posts == nil || opts.Graph == nil || opts.Blocks == nil || opts.Mailer == nil {
+		return nil, fmt.Errorf("posts, graph, blocks and mailer are required")
+	}
+	d := &Digester{
+		compiler: compiler{posts: opts.Posts, graph: opts.Graph, blocks: opts.Blocks, profiles: opts.Profiles, maxItems: opts.MaxItems},
+		mailer:   opts.Mailer,
+		store:    opts.Store,
+		linkBase: opts.LinkBase,
+		subs:     make(map[string]*Subscriber),
+	}
+	if d.maxItems <= 0 {
+		d.maxItems = DefaultMaxItems
+	}
+	if opts.Store != nil {
+		subs, err := opts.Store.LoadSubscribers()
+		if err != nil {
+			return nil, err
+		}
+		for i := range subs {
+			d.subs[subs[i].Address] = &subs[i]
+		}
+	}
+	return d, nil
+}
+
+// Subscribe sets address's digest email and frequency. A new subscriber's
+// first digest covers only blocks added from now on, and is due one interval
+// after now. Existing subscribers keep their delivery state.
+func (d *Digester) Subscribe(address, email string, freq Frequency, now time.Time) error {
+	if address == "" {
+		return fmt.Errorf("address cannot be empty")
+	}
+	if _, err := mail.ParseAddress(email); err != nil {
+		return fmt.Errorf("invalid email address %q: %w", email, err)
+	}
+	if _, err := freq.interval(); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sub, ok := d.subs[address]
+	if !ok {
+		latest := d.blocks.GetLatestBlock()
+		if latest == nil {
+			return fmt.Errorf("blockchain has no blocks")
+		}
+		sub = &Subscriber{Address: address, LastBlock: latest.Index, LastSentAt: now.UnixNano()}
+		d.subs[address] = sub
+	}
+	sub.Email, sub.Frequency, sub.Paused = email, freq, false
+	return d.save()
+}
+
+// SetPaused pauses or resumes address's digests. A resumed digest covers
+// everything since the last one that was sent.
+func (d *Digester) SetPaused(address string, paused bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sub, ok := d.subs[address]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotSubscribed, address)
+	}
+	sub.Paused = paused
+	return d.save()
+}
+
+// Unsubscribe removes address's settings.
+func (d *Digester) Unsubscribe(address string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.subs[address]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotSubscribed, address)
+	}
+	delete(d.subs, address)
+	return d.save()
+}
+
+// Subscriber returns address's settings and delivery state.
+func (d *Digester) Subscriber(address string) (Subscriber, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sub, ok := d.subs[address]
+	if !ok {
+		return Subscriber{}, false
+	}
+	return *sub, true
+}
+
+// Preview compiles address's next digest without sending it or recording
+// anything.
+func (d *Digester) Preview(address string) (*Digest, error) {
+	sub, ok := d.Subscriber(address)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotSubscribed, address)
+	}
+	latest := d.blocks.GetLatestBlock()
+	if latest == nil {
+		return nil, fmt.Errorf("blockchain has no blocks")
+	}
+	return d.compile(address, sub.LastBlock, latest.Index)
+}
+
+// SendDue sends every digest that is due at now and returns the number
+// sent. Digests with nothing in them are not mailed but still count as
+// delivered, so the next one starts from here. A subscriber whose digest
+// fails to send is retried on the next call; the errors are joined.
+func (d *Digester) SendDue(ctx context.Context, now time.Time) (int, error) {
+	latest := d.blocks.GetLatestBlock()
+	if latest == nil {
+		return 0, fmt.Errorf("blockchain has no blocks")
+	}
+	var due []Subscriber
+	d.mu.Lock()
+	for _, sub := range d.subs {
+		interval, err := sub.Frequency.interval()
+		if err == nil && !sub.Paused && now.Sub(time.Unix(0, sub.LastSentAt)) >= interval {
+			due = append(due, *sub)
+		}
+	}
+	d.mu.Unlock()
+	sort.Slice(due, func(i, j int) bool { return due[i].Address < due[j].Address })
+
+	sent := 0
+	var errs []error
+	for _, sub := range due {
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+		dg, err := d.compile(sub.Address, sub.LastBlock, latest.Index)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("digest for %s: %w", sub.Address, err))
+			continue
+		}
+		if !dg.Empty() {
+			if err := d.mailer.Send(ctx, dg.Message(sub.Email, d.linkBase)); err != nil {
+				errs = append(errs, fmt.Errorf("digest for %s: %w", sub.Address, err))
+				continue
+			}
+			sent++
+		}
+		if err := d.delivered(sub.Address, latest.Index, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// delivered records that address's digest up to block was sent at now.
+func (d *Digester) delivered(address string, block int64, now time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sub, ok := d.subs[address]
+	if !ok {
+		return nil // Unsubscribed while sending
+	}
+	sub.LastBlock, sub.LastSentAt = block, now.UnixNano()
+	return d.save()
+}
+
+// save persists the subscribers. The caller must hold d.mu.
+func (d *Digester) save() error {
+	if d.store == nil {
+		return nil
+	}
+	subs := make([]Subscriber, 0, len(d.subs))
+	for _, sub := range d.subs {
+		subs = append(subs, *sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].Address < subs[j].Address })
+	if err := d.store.SaveSubscribers(subs); err != nil {
+		return fmt.Errorf("failed to persist digest subscribers: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package digest
+
+import (
+	"context"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"digisocialblock/internal/testutil/fixture"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type digesterTestMailer struct {
+	sent []Message
+	err  error
+}
+
+func (m *digesterTestMailer) Send(ctx context.Context, msg Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestDigester_SendsDueDigests(t *testing.T) {
+	n := newDigestTestNode(t)
+	mailer := &digesterTestMailer{}
+	store, _ := NewFileSubscriberStore(filepath.Join(t.TempDir(), "digests.json"))
+	opts := Options{Posts: n.feed, Graph: n.graph, Blocks: n.bc, Mailer: mailer, Store: store}
+	d, err := NewDigester(opts)
+	if err != nil {
+		t.Fatalf("NewDigester() error = %v", err)
+	}
+	start := time.Unix(1700000000, 0)
+	if err := d.Subscribe(n.alice.Address, "not an address", Daily, start); err == nil {
+		t.Error("Subscribe() with a bad email expected error, got nil")
+	}
+	if err := d.Subscribe(n.alice.Address, "alice@example.org", "hourly", start); err == nil {
+		t.Error("Subscribe() with an unknown frequency expected error, got nil")
+	}
+	if err := d.Subscribe(n.alice.Address, "alice@example.org", Daily, start); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	n.add(t, fixture.TxFormat(t, n.bob, ledger.PostCreated, ledger.PayloadFormatJSON, social.NewPost(n.bob.Address, "cid-new", "News", nil)))
+	if sent, err := d.SendDue(context.Background(), start.Add(time.Hour)); err != nil || sent != 0 {
+		t.Fatalf("SendDue() before a day = %d, %v; want nothing sent", sent, err)
+	}
+	if dg, err := d.Preview(n.alice.Address); err != nil || len(dg.Posts) != 1 {
+		t.Fatalf("Preview() = %+v, %v; want bob's new post only", dg, err)
+	}
+
+	mailer.err = errors.New("relay down")
+	day := start.Add(24 * time.Hour)
+	if sent, err := d.SendDue(context.Background(), day); err == nil || sent != 0 {
+		t.Fatalf("SendDue() with a failing mailer = %d, %v; want an error", sent, err)
+	}
+	mailer.err = nil
+	if sent, err := d.SendDue(context.Background(), day); err != nil || sent != 1 {
+		t.Fatalf("SendDue() retry = %d, %v; want 1 sent", sent, err)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].To != "alice@example.org" {
+		t.Fatalf("sent = %+v, want one digest to alice", mailer.sent)
+	}
+
+	// State survives a restart: nothing new, so the next day sends nothing
+	// but still advances.
+	d, err = NewDigester(opts)
+	if err != nil {
+		t.Fatalf("NewDigester() reload error = %v", err)
+	}
+	sub, ok := d.Subscriber(n.alice.Address)
+	if !ok || sub.LastBlock != n.bc.GetLatestBlock().Index || sub.LastSentAt != day.UnixNano() {
+		t.Fatalf("reloaded subscriber = %+v, %v", sub, ok)
+	}
+	if sent, err := d.SendDue(context.Background(), day.Add(24*time.Hour)); err != nil || sent != 0 {
+		t.Errorf("SendDue() with nothing new = %d, %v; want 0", sent, err)
+	}
+	if sub, _ := d.Subscriber(n.alice.Address); sub.LastSentAt != day.Add(24*time.Hour).UnixNano() {
+		t.Errorf("empty digest did not advance LastSentAt: %+v", sub)
+	}
+
+	n.add(t, fixture.TxFormat(t, n.bob, ledger.PostCreated, ledger.PayloadFormatJSON, social.NewPost(n.bob.Address, "cid-later", "", nil)))
+	if err := d.SetPaused(n.alice.Address, true); err != nil {
+		t.Fatalf("SetPaused() error = %v", err)
+	}
+	if sent, _ := d.SendDue(context.Background(), day.Add(72*time.Hour)); sent != 0 {
+		t.Errorf("SendDue() while paused sent %d", sent)
+	}
+	if err := d.Unsubscribe(n.alice.Address); err != nil {
+		t.Fatalf("Unsubscribe() error = %v", err)
+	}
+	if err := d.Unsubscribe(n.alice.Address); !errors.Is(err, ErrNotSubscribed) {
+		t.Errorf("second Unsubscribe() error = %v, want ErrNotSubscribed", err)
+	}
+}
@@ -0,0 +1,79 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Message is a plain-text mail.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+// Mailer sends digests. Nodes plug in their own delivery (an HTTP mail API,
+// a queue) or use SMTPMailer.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPMailer sends mail through an SMTP relay.
+type SMTPMailer struct {
+	Addr string    // Relay host:port
+	From string    // Sender address, e.g. "Digests <digest@node.example.org>"
+	Auth smtp.Auth // Optional, e.g. smtp.PlainAuth
+	// Now returns the Date header time. Nil uses time.Now.
+	Now func() time.Time
+}
+
+// Send implements Mailer. smtp.SendMail cannot be cancelled, so ctx is only
+// checked before sending.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address %q: %w", m.From, err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address %q: %w", msg.To, err)
+	}
+	data, err := m.format(from, to, msg)
+	if err != nil {
+		return err
+	}
+	if err := smtp.SendMail(m.Addr, m.Auth, from.Address, []string{to.Address}, data); err != nil {
+		return fmt.Errorf("failed to send digest to %s: %w", to.Address, err)
+	}
+	return nil
+}
+
+// format renders the message in RFC 5322 form with a UTF-8 text body.
+func (m *SMTPMailer) format(from, to *mail.Address, msg Message) ([]byte, error) {
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, fmt.Errorf("subject cannot contain line breaks")
+	}
+	now := time.Now
+	if m.Now != nil {
+		now = m.Now
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Text, "\r\n", "\n"), "\n", "\r\n"))
+	return b.Bytes(), nil
+}
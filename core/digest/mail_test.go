@@ -0,0 +1,53 @@
+package digest
+
+import (
+	"context"
+	"mime"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSMTPMailer_Format(t *testing.T) {
+	m := &SMTPMailer{From: "Digests <digest@node.example.org>", Now: func() time.Time { return time.Unix(1700000000, 0).UTC() }}
+	from, _ := mail.ParseAddress(m.From)
+	to, _ := mail.ParseAddress("alice@example.org")
+	data, err := m.format(from, to, Message{Subject: "Your digest: 1 new post ✓", Text: "line one\nline two\n"})
+	if err != nil {
+		t.Fatalf("format() error = %v", err)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("formatted message does not parse: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != "Your digest: 1 new post ✓" {
+		t.Errorf("Subject = %q, %v", subject, err)
+	}
+	if got := parsed.Header.Get("Date"); got != "Tue, 14 Nov 2023 22:13:20 +0000" {
+		t.Errorf("Date = %q", got)
+	}
+	if got := parsed.Header.Get("To"); got != "<alice@example.org>" {
+		t.Errorf("To = %q", got)
+	}
+	if !strings.HasSuffix(string(data), "\r\n\r\nline one\r\nline two\r\n") {
+		t.Errorf("body is not CRLF-terminated text:\n%q", data)
+	}
+
+	if _, err := m.format(from, to, Message{Subject: "hi\r\nBcc: eve@example.org"}); err == nil {
+		t.Error("format() with a line break in the subject expected error, got nil")
+	}
+}
+
+func TestSMTPMailer_SendRejectsBadAddresses(t *testing.T) {
+	m := &SMTPMailer{Addr: "127.0.0.1:1", From: "digest@node.example.org"}
+	if err := m.Send(context.Background(), Message{To: "not an address"}); err == nil || !strings.Contains(err.Error(), "recipient") {
+		t.Errorf("Send() to a bad address error = %v, want invalid recipient", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.Send(ctx, Message{To: "alice@example.org"}); err != context.Canceled {
+		t.Errorf("Send() with cancelled context error = %v, want context.Canceled", err)
+	}
+}
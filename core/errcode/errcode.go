@@ -0,0 +1,117 @@
+// Package errcode is the registry of stable, machine-readable error codes
+// shared by every package. A code names a class of failure, such as
+// DSB-LEDGER-001 for an invalid transaction signature, and travels with the
+// error through fmt.Errorf("%w") wrapping, so callers and the API server can
+// branch on it instead of matching message text.
+//
+// Packages register their codes at init and attach them either by declaring
+// coded sentinel errors with New or by wrapping an error with Wrap:
+//
+//	var CodeInvalidSignature = errcode.Register("DSB-LEDGER-001", http.StatusBadRequest, "transaction signature is invalid")
+//	var ErrInvalidSignature = errcode.New(CodeInvalidSignature, "transaction signature is invalid")
+//
+// Codes are never reused or renumbered once released; a retired code stays
+// registered so old clients can still look it up.
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// Code identifies a class of error: "DSB-", the owning package in capitals
+// and a three-digit number, e.g. "DSB-LEDGER-001".
+type Code string
+
+// Info describes a registered code.
+type Info struct {
+	Code Code `json:"code"`
+	// HTTPStatus is the status the API answers with when a request fails
+	// with this code.
+	HTTPStatus  int    `json:"httpStatus"`
+	Description string `json:"description"`
+}
+
+var codeFormat = regexp.MustCompile(`^DSB-[A-Z]+-[0-9]{3}$`)
+
+var registry = struct {
+	mu    sync.RWMutex
+	codes map[Code]Info
+}{codes: make(map[Code]Info)}
+
+// Register adds code to the registry and returns it. It panics if the code
+// is malformed or already registered, since both are programming errors
+// that must not reach a release.
+func Register(code string, httpStatus int, description string) Code {
+	if !codeFormat.MatchString(code) {
+		panic(fmt.Sprintf("errcode: malformed code %q", code))
+	}
+	if httpStatus < 400 || httpStatus > 599 {
+		panic(fmt.Sprintf("errcode: %s has HTTP status %d, want 4xx or 5xx", code, httpStatus))
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if _, ok := registry.codes[Code(code)]; ok {
+		panic(fmt.Sprintf("errcode: %s registered twice", code))
+	}
+	registry.codes[Code(code)] = Info{Code: Code(code), HTTPStatus: httpStatus, Description: description}
+	return Code(code)
+}
+
+// Lookup returns the registration of code.
+func Lookup(code Code) (Info, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	info, ok := registry.codes[code]
+	return info, ok
+}
+
+// All returns every registered code, sorted by code.
+func All() []Info {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	infos := make([]Info, 0, len(registry.codes))
+	for _, info := range registry.codes {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Code < infos[j].Code })
+	return infos
+}
+
+// Error is an error carrying a code. Its message is that of the error it
+// wraps, so attaching a code never changes what is logged.
+type Error struct {
+	Code Code
+	Err  error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// New returns a coded error with the given message, for declaring sentinel
+// errors that callers test for with errors.Is.
+func New(code Code, message string) error {
+	return &Error{Code: code, Err: errors.New(message)}
+}
+
+// Wrap attaches code to err. A nil err stays nil.
+func Wrap(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Of returns the code of the outermost coded error in err's chain, so a
+// caller can give a more specific code by wrapping an already coded error.
+func Of(err error) (Code, bool) {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Code, true
+	}
+	return "", false
+}
@@ -0,0 +1,83 @@
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// The tests' codes are registered once per test binary, like the package
+// variables they stand in for, so the tests can be run more than once.
+var (
+	testCode     = Register("DSB-TEST-001", http.StatusBadRequest, "test code")
+	testConflict = Register("DSB-TEST-010", http.StatusConflict, "test conflict")
+	testSpecific = Register("DSB-TEST-011", http.StatusBadRequest, "more specific")
+	_            = Register("DSB-TEST-021", http.StatusNotFound, "b")
+	_            = Register("DSB-TEST-020", http.StatusGone, "a")
+)
+
+func TestRegister_RejectsBadCodes(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       string
+		httpStatus int
+	}{
+		{"malformed", "LEDGER-1", http.StatusBadRequest},
+		{"lowercase", "DSB-test-002", http.StatusBadRequest},
+		{"not an error status", "DSB-TEST-003", http.StatusOK},
+		{"duplicate", string(testCode), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register(%q, %d) did not panic", tt.code, tt.httpStatus)
+				}
+			}()
+			Register(tt.code, tt.httpStatus, "bad")
+		})
+	}
+	if _, ok := Lookup("DSB-TEST-003"); ok {
+		t.Error("a rejected code was registered")
+	}
+}
+
+func TestOf_FindsCodeThroughWrapping(t *testing.T) {
+	code := testConflict
+	sentinel := New(code, "conflict")
+	wrapped := fmt.Errorf("adding tx abc: %w", sentinel)
+
+	if !errors.Is(wrapped, sentinel) {
+		t.Error("errors.Is does not find the sentinel through wrapping")
+	}
+	if got, ok := Of(wrapped); !ok || got != code {
+		t.Errorf("Of() = %q, %v; want %q", got, ok, code)
+	}
+	if wrapped.Error() != "adding tx abc: conflict" {
+		t.Errorf("message = %q; the code must not change it", wrapped.Error())
+	}
+
+	outer := testSpecific
+	if got, _ := Of(Wrap(outer, wrapped)); got != outer {
+		t.Errorf("Of() = %q, want the outermost code %q", got, outer)
+	}
+	if _, ok := Of(errors.New("plain")); ok {
+		t.Error("Of() found a code on an uncoded error")
+	}
+	if Wrap(code, nil) != nil {
+		t.Error("Wrap(code, nil) != nil")
+	}
+}
+
+func TestAll_SortedWithStatus(t *testing.T) {
+	all := All()
+	for i := 1; i < len(all); i++ {
+		if all[i-1].Code >= all[i].Code {
+			t.Fatalf("All() not sorted: %s before %s", all[i-1].Code, all[i].Code)
+		}
+	}
+	if info, ok := Lookup("DSB-TEST-020"); !ok || info.HTTPStatus != http.StatusGone || info.Description != "a" {
+		t.Errorf("Lookup() = %+v, %v", info, ok)
+	}
+}
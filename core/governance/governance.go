@@ -0,0 +1,270 @@
+// Package governance implements on-chain proposals and voting, for community
+// decisions such as changes to network parameters. An account proposes with
+// a ProposalCreated transaction whose full text is content on DDS, naming the
+// window of block heights in which votes count; accounts vote with VoteCast
+// transactions, and an Index tallies the votes from the chain:
+//
+//	tx, _ := governance.NewProposalTransaction(nil, wallet, &governance.Proposal{
+//		Title:       "Raise the post payload limit",
+//		MetadataCID: manifestCID,
+//		StartHeight: tip + 10,
+//		EndHeight:   tip + 10 + 17280,
+//		Weighting:   governance.WeightByStake,
+//	})
+//
+// A proposal may carry changes to the protocol parameters (see
+// ledger.Params) and the height they take effect at. The chain does not act
+// on a proposal that passes by itself: nodes add the activations of passed
+// proposals, from Index.Activations, to the parameter schedule they set with
+// Blockchain.SetParamSchedule.
+package governance
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"fmt"
+	"unicode/utf8"
+)
+
+func init() {
+	ledger.RegisterPayloadValidator(ledger.ProposalCreated, ValidateProposalPayload)
+	ledger.RegisterPayloadValidator(ledger.VoteCast, ValidateVotePayload)
+	ledger.RegisterPayloadDecoder(ledger.ProposalCreated, ledger.DecodeInto(func() interface{} { return &Proposal{} }))
+	ledger.RegisterPayloadDecoder(ledger.VoteCast, ledger.DecodeInto(func() interface{} { return &Vote{} }))
+	ledger.RegisterCIDResolver(ledger.ProposalCreated, proposalCIDs)
+}
+
+// Limits on proposals and votes.
+const (
+	MaxTitleLength      = 140    // Characters
+	MaxCIDLength        = 128    // Bytes
+	MaxTxIDLength       = 128    // Bytes
+	MaxVotingWindow     = 100000 // Blocks from StartHeight to EndHeight
+	MaxParameterChanges = 16
+)
+
+// Weighting is how a proposal's votes are weighed.
+type Weighting string
+
+const (
+	WeightByAccount Weighting = "account" // One vote per account
+	WeightByStake   Weighting = "stake"   // The voter's balance when voting opens, before the StartHeight block
+)
+
+// Choice is a vote's answer to a proposal.
+type Choice string
+
+const (
+	ChoiceYes     Choice = "yes"
+	ChoiceNo      Choice = "no"
+	ChoiceAbstain Choice = "abstain"
+)
+
+// Proposal is the ProposalCreated payload. The proposal is identified by the
+// ProposalCreated transaction ID, and votes count only in blocks from
+// StartHeight to EndHeight, which must come after the block that records it.
+// Parameter changes take effect from ActivationHeight, after voting ends.
+type Proposal struct {
+	ProposerPublicKey string               `json:"proposerPublicKey"`
+	Title             string               `json:"title"`
+	MetadataCID       string               `json:"metadataCID"`                // Manifest of the full proposal text on DDS
+	Changes           []ledger.ParamChange `json:"changes,omitempty"`          // Protocol parameters the proposal would set, if any
+	ActivationHeight  int64                `json:"activationHeight,omitempty"` // First block under Changes; set exactly when Changes is
+	StartHeight       int64                `json:"startHeight"`                // First block in which votes count
+	EndHeight         int64                `json:"endHeight"`                  // Last block in which votes count
+	Weighting         Weighting            `json:"weighting"`
+	Timestamp         int64                `json:"timestamp"`
+}
+
+// Validate checks that required fields are set and within limits.
+func (p *Proposal) Validate() error {
+	if p.ProposerPublicKey == "" {
+		return fmt.Errorf("empty ProposerPublicKey")
+	}
+	if n := utf8.RuneCountInString(p.Title); n == 0 || n > MaxTitleLength {
+		return fmt.Errorf("title is %d characters, want 1 to %d", n, MaxTitleLength)
+	}
+	if p.MetadataCID == "" || len(p.MetadataCID) > MaxCIDLength {
+		return fmt.Errorf("MetadataCID is %d bytes, want 1 to %d", len(p.MetadataCID), MaxCIDLength)
+	}
+	if len(p.Changes) > MaxParameterChanges {
+		return fmt.Errorf("%d parameter changes, limit %d", len(p.Changes), MaxParameterChanges)
+	}
+	seen := make(map[string]bool, len(p.Changes))
+	for _, c := range p.Changes {
+		if err := ledger.ValidateParamChange(c.Name, c.Value); err != nil {
+			return err
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("parameter %s changed twice", c.Name)
+		}
+		seen[c.Name] = true
+	}
+	if p.StartHeight <= 0 || p.EndHeight < p.StartHeight {
+		return fmt.Errorf("voting window %d-%d is empty", p.StartHeight, p.EndHeight)
+	}
+	switch {
+	case len(p.Changes) > 0 && p.ActivationHeight <= p.EndHeight:
+		return fmt.Errorf("changes activate at height %d, before voting ends at %d", p.ActivationHeight, p.EndHeight)
+	case len(p.Changes) == 0 && p.ActivationHeight != 0:
+		return fmt.Errorf("activation height %d without changes", p.ActivationHeight)
+	}
+	if p.EndHeight-p.StartHeight >= MaxVotingWindow {
+		return fmt.Errorf("voting window %d-%d is longer than %d blocks", p.StartHeight, p.EndHeight, MaxVotingWindow)
+	}
+	if p.Weighting != WeightByAccount && p.Weighting != WeightByStake {
+		return fmt.Errorf("unknown weighting %q", p.Weighting)
+	}
+	if p.Timestamp == 0 {
+		return fmt.Errorf("zero timestamp")
+	}
+	return nil
+}
+
+// ToPayload serializes the Proposal as a ProposalCreated transaction payload
+// in the given format.
+func (p *Proposal) ToPayload(format ledger.PayloadFormat) ([]byte, error) {
+	payload, err := ledger.EncodePayload(format, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode proposal payload: %w", err)
+	}
+	return payload, nil
+}
+
+// ProposalFromPayload deserializes a ProposalCreated payload in either
+// payload format. The result must pass Validate.
+func ProposalFromPayload(payload []byte) (*Proposal, error) {
+	var p Proposal
+	if err := ledger.DecodePayload(payload, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode proposal payload: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("decoded proposal is invalid: %w", err)
+	}
+	return &p, nil
+}
+
+// ValidateProposalPayload is the ledger schema validator for ProposalCreated payloads.
+func ValidateProposalPayload(payload []byte) error {
+	_, err := ProposalFromPayload(payload)
+	return err
+}
+
+// proposalCIDs is the ledger CID resolver for ProposalCreated payloads.
+func proposalCIDs(payload []byte) ([]string, error) {
+	p, err := ProposalFromPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	return []string{p.MetadataCID}, nil
+}
+
+// Vote is the VoteCast payload. An account may vote again while voting is
+// open; its vote with the latest Timestamp counts.
+type Vote struct {
+	VoterPublicKey string `json:"voterPublicKey"`
+	ProposalTxID   string `json:"proposalTxId"` // ProposalCreated transaction
+	Choice         Choice `json:"choice"`
+	Timestamp      int64  `json:"timestamp"`
+}
+
+// Validate checks that required fields are set and within limits.
+func (v *Vote) Validate() error {
+	if v.VoterPublicKey == "" {
+		return fmt.Errorf("empty VoterPublicKey")
+	}
+	if v.ProposalTxID == "" || len(v.ProposalTxID) > MaxTxIDLength {
+		return fmt.Errorf("ProposalTxID is %d bytes, want 1 to %d", len(v.ProposalTxID), MaxTxIDLength)
+	}
+	switch v.Choice {
+	case ChoiceYes, ChoiceNo, ChoiceAbstain:
+	default:
+		return fmt.Errorf("unknown choice %q", v.Choice)
+	}
+	if v.Timestamp == 0 {
+		return fmt.Errorf("zero timestamp")
+	}
+	return nil
+}
+
+// ToPayload serializes the Vote as a VoteCast transaction payload in the
+// given format.
+func (v *Vote) ToPayload(format ledger.PayloadFormat) ([]byte, error) {
+	payload, err := ledger.EncodePayload(format, v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode vote payload: %w", err)
+	}
+	return payload, nil
+}
+
+// VoteFromPayload deserializes a VoteCast payload in either payload format.
+// The result must pass Validate.
+func VoteFromPayload(payload []byte) (*Vote, error) {
+	var v Vote
+	if err := ledger.DecodePayload(payload, &v); err != nil {
+		return nil, fmt.Errorf("failed to decode vote payload: %w", err)
+	}
+	if err := v.Validate(); err != nil {
+		return nil, fmt.Errorf("decoded vote is invalid: %w", err)
+	}
+	return &v, nil
+}
+
+// ValidateVotePayload is the ledger schema validator for VoteCast payloads.
+func ValidateVotePayload(payload []byte) error {
+	_, err := VoteFromPayload(payload)
+	return err
+}
+
+// NewProposalTransaction returns a signed ProposalCreated transaction for p,
+// proposed by wallet and stamped by clock (SystemClock if nil). The proposer
+// and timestamp of p are set from them. The caller submits it.
+func NewProposalTransaction(clock ledger.Clock, wallet *identity.Wallet, p *Proposal) (*ledger.Transaction, error) {
+	if wallet == nil || p == nil {
+		return nil, fmt.Errorf("wallet and proposal are both required")
+	}
+	if clock == nil {
+		clock = ledger.SystemClock
+	}
+	p.ProposerPublicKey, p.Timestamp = wallet.Address, clock.Now().UnixNano()
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid proposal: %w", err)
+	}
+	payload, err := p.ToPayload(ledger.PayloadFormatCBOR)
+	if err != nil {
+		return nil, err
+	}
+	return signedTransaction(clock, wallet, ledger.ProposalCreated, payload)
+}
+
+// NewVoteTransaction returns a signed VoteCast transaction by which wallet
+// answers the proposal created by proposalTxID with choice, stamped by clock
+// (SystemClock if nil). The caller submits it.
+func NewVoteTransaction(clock ledger.Clock, wallet *identity.Wallet, proposalTxID string, choice Choice) (*ledger.Transaction, error) {
+	if wallet == nil {
+		return nil, fmt.Errorf("wallet cannot be nil")
+	}
+	if clock == nil {
+		clock = ledger.SystemClock
+	}
+	v := &Vote{VoterPublicKey: wallet.Address, ProposalTxID: proposalTxID, Choice: choice, Timestamp: clock.Now().UnixNano()}
+	if err := v.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid vote: %w", err)
+	}
+	payload, err := v.ToPayload(ledger.PayloadFormatCBOR)
+	if err != nil {
+		return nil, err
+	}
+	return signedTransaction(clock, wallet, ledger.VoteCast, payload)
+}
+
+func signedTransaction(clock ledger.Clock, wallet *identity.Wallet, txType ledger.TransactionType, payload []byte) (*ledger.Transaction, error) {
+	tx, err := ledger.NewTransactionWithClock(clock, wallet.Address, txType, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s transaction: %w", txType, err)
+	}
+	if err := wallet.SignTransaction(tx); err != nil {
+		return nil, fmt.Errorf("failed to sign %s transaction: %w", txType, err)
+	}
+	return tx, nil
+}
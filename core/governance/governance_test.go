@@ -0,0 +1,68 @@
+package governance
+
+import (
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil/fixture"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProposal_PayloadValidation(t *testing.T) {
+	valid := func() Proposal {
+		return Proposal{ProposerPublicKey: "alice", Title: "Raise limits", MetadataCID: "cid", StartHeight: 5, EndHeight: 10, Weighting: WeightByAccount, Timestamp: 1}
+	}
+	for name, mutate := range map[string]func(p *Proposal){
+		"no proposer":       func(p *Proposal) { p.ProposerPublicKey = "" },
+		"long title":        func(p *Proposal) { p.Title = strings.Repeat("t", MaxTitleLength+1) },
+		"no metadata":       func(p *Proposal) { p.MetadataCID = "" },
+		"empty window":      func(p *Proposal) { p.EndHeight = 4 },
+		"long window":       func(p *Proposal) { p.EndHeight = p.StartHeight + MaxVotingWindow },
+		"unknown weighting": func(p *Proposal) { p.Weighting = "quadratic" },
+		"unknown parameter": func(p *Proposal) {
+			p.Changes, p.ActivationHeight = []ledger.ParamChange{{Name: "blockReward", Value: "1"}}, 20
+		},
+		"repeated change": func(p *Proposal) {
+			p.Changes, p.ActivationHeight = []ledger.ParamChange{{Name: "maxBlockBytes", Value: "1"}, {Name: "maxBlockBytes", Value: "2"}}, 20
+		},
+		"early activation": func(p *Proposal) {
+			p.Changes, p.ActivationHeight = []ledger.ParamChange{{Name: "maxBlockBytes", Value: "1"}}, 10
+		},
+		"stray activation": func(p *Proposal) { p.ActivationHeight = 20 },
+		"no instant":       func(p *Proposal) { p.Timestamp = 0 },
+	} {
+		p := valid()
+		mutate(&p)
+		if err := p.Validate(); err == nil {
+			t.Errorf("%s: Validate() expected error, got nil", name)
+		}
+	}
+	if err := (&Vote{VoterPublicKey: "bob", ProposalTxID: "p", Choice: "maybe", Timestamp: 1}).Validate(); err == nil {
+		t.Error("Vote with an unknown choice: Validate() expected error, got nil")
+	}
+}
+
+func TestNewProposalTransaction_SignsAndReferencesMetadata(t *testing.T) {
+	wallet := fixture.Wallet(t)
+	p := &Proposal{Title: "Raise limits", MetadataCID: "bafy-proposal", Changes: []ledger.ParamChange{{Name: "maxBlockBytes", Value: "2097152"}}, ActivationHeight: 20, StartHeight: 5, EndHeight: 10, Weighting: WeightByStake}
+	tx, err := NewProposalTransaction(nil, wallet, p)
+	if err != nil {
+		t.Fatalf("NewProposalTransaction() error = %v", err)
+	}
+	if ok, err := tx.VerifySignature(); !ok || err != nil {
+		t.Fatalf("VerifySignature() = %v, %v", ok, err)
+	}
+	decoded, err := ProposalFromPayload(tx.Payload)
+	if err != nil || decoded.ProposerPublicKey != wallet.Address || !reflect.DeepEqual(decoded.Changes, p.Changes) {
+		t.Errorf("ProposalFromPayload() = %+v, %v; want the proposal by the wallet", decoded, err)
+	}
+	if cids, err := tx.ReferencedCIDs(); err != nil || !reflect.DeepEqual(cids, []string{"bafy-proposal"}) {
+		t.Errorf("ReferencedCIDs() = %v, %v; want the metadata CID", cids, err)
+	}
+	if _, err := NewVoteTransaction(nil, wallet, tx.ID, ChoiceYes); err != nil {
+		t.Errorf("NewVoteTransaction() error = %v", err)
+	}
+	if err := (&ledger.Transaction{Type: ledger.VoteCast, Payload: []byte(`{}`)}).ValidatePayload(); err == nil {
+		t.Error("ledger accepted an empty VoteCast payload")
+	}
+}
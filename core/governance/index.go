@@ -0,0 +1,334 @@
+package governance
+
+import (
+	"digisocialblock/core/ledger"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrIndexDiverged is returned by Index.Sync when the block recorded as
+// processed last is no longer on the chain. Call Rebuild to recover.
+var ErrIndexDiverged = errors.New("governance index high-water mark does not match the chain")
+
+// Errors returned by Index.ValidateVote. Votes failing them are not counted.
+var (
+	ErrProposalNotFound = errors.New("proposal not found")
+	ErrVotingClosed     = errors.New("proposal is not open for voting")
+)
+
+// ProposalStatus is where a proposal is in its voting window, as of the
+// block after an Index's high-water mark.
+type ProposalStatus int
+
+const (
+	ProposalPending ProposalStatus = iota // Voting has not opened
+	ProposalActive                        // Votes in the next block count
+	ProposalClosed                        // Voting has ended; the tally is final
+)
+
+// String returns the status's name.
+func (s ProposalStatus) String() string {
+	switch s {
+	case ProposalPending:
+		return "pending"
+	case ProposalActive:
+		return "active"
+	case ProposalClosed:
+		return "closed"
+	}
+	return fmt.Sprintf("ProposalStatus(%d)", int(s))
+}
+
+// Tally is the weighed votes on a proposal.
+type Tally struct {
+	Yes     uint64 `json:"yes"`
+	No      uint64 `json:"no"`
+	Abstain uint64 `json:"abstain"`
+	Voters  int    `json:"voters"` // Accounts whose vote counts, whatever its weight
+}
+
+// Passed reports whether more weight voted yes than no.
+func (t Tally) Passed() bool {
+	return t.Yes > t.No
+}
+
+// ProposalEntry is a proposal recorded in the index, with its votes so far.
+type ProposalEntry struct {
+	TxID       string         `json:"txId"`
+	BlockIndex int64          `json:"blockIndex"`
+	Proposal   Proposal       `json:"proposal"`
+	Status     ProposalStatus `json:"status"`
+	Tally      Tally          `json:"tally"`
+}
+
+// proposalState is an indexed proposal and the votes cast on it.
+type proposalState struct {
+	txID       string
+	blockIndex int64
+	proposal   Proposal
+	stakes     map[string]uint64 // Balances when voting opened; nil until then or if weighed by account
+	votes      map[string]Vote   // Voter -> counted vote, the one with the latest Timestamp
+}
+
+// Index indexes proposals and tallies their votes. It is kept in memory and
+// updated incrementally like the social indexes.
+//
+// A proposal is indexed only if its proposer is the transaction sender and
+// its voting window opens after the block that records it. A vote counts
+// only if its voter is the sender and its block is in the proposal's voting
+// window; a later vote by the same account replaces an earlier one. To weigh
+// votes by stake, the index follows balances from the chain's genesis
+// allocations through every Transfer, and keeps a copy of them for each
+// stake-weighted proposal when its voting opens. An Index is safe for
+// concurrent use.
+type Index struct {
+	mu        sync.RWMutex
+	follower  ledger.ChainFollower
+	balances  map[string]uint64
+	proposals map[string]*proposalState
+	order     []string           // Proposal tx IDs, in chain order
+	opening   map[int64][]string // StartHeight -> stake-weighted proposals opening there
+}
+
+// NewIndex creates an empty Index. Call Sync to populate it.
+func NewIndex() *Index {
+	x := &Index{}
+	x.reset()
+	return x
+}
+
+// reset clears the index. The caller must hold x.mu (or own x exclusively).
+func (x *Index) reset() {
+	x.follower = ledger.NewChainFollower()
+	x.balances = nil
+	x.proposals = make(map[string]*proposalState)
+	x.order = nil
+	x.opening = make(map[int64][]string)
+}
+
+// HighWaterMark reports the last block whose proposals and votes have been
+// tallied.
+func (x *Index) HighWaterMark() (int64, string) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.follower.HighWaterMark()
+}
+
+// Sync tallies the proposals and votes in blocks added since the last call.
+// ErrIndexDiverged is returned if the chain no longer holds the last block
+// tallied.
+func (x *Index) Sync(bc *ledger.Blockchain) (int, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.follow(bc)
+}
+
+// Rebuild starts the tallies over from genesis, e.g. after the chain's
+// genesis allocations are set.
+func (x *Index) Rebuild(bc *ledger.Blockchain) (int, error) {
+	if bc == nil {
+		return 0, fmt.Errorf("blockchain cannot be nil")
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.reset()
+	return x.follow(bc)
+}
+
+// follow indexes the blocks above the high-water mark, starting the balances
+// from the chain's genesis allocations if the index has none yet. The caller
+// must hold x.mu.
+func (x *Index) follow(bc *ledger.Blockchain) (int, error) {
+	return x.follower.Sync(bc, "governance index", ErrIndexDiverged, func(block *ledger.Block) {
+		if x.balances == nil {
+			x.balances = bc.GenesisAllocations()
+		}
+		x.indexBlock(block)
+	})
+}
+
+// indexBlock opens voting on the proposals whose window starts at the block,
+// then adds its proposals, votes and transfers to the index.
+func (x *Index) indexBlock(block *ledger.Block) {
+	for _, id := range x.opening[block.Index] {
+		stakes := make(map[string]uint64, len(x.balances))
+		for address, b := range x.balances {
+			stakes[address] = b
+		}
+		x.proposals[id].stakes = stakes
+	}
+	delete(x.opening, block.Index)
+
+	for _, tx := range block.Transactions {
+		if tx == nil {
+			continue
+		}
+		switch tx.Type {
+		case ledger.ProposalCreated:
+			p, err := ProposalFromPayload(tx.Payload)
+			if err != nil || p.ProposerPublicKey != tx.SenderPublicKey || p.StartHeight <= block.Index {
+				continue
+			}
+			if _, dup := x.proposals[tx.ID]; dup {
+				continue
+			}
+			x.proposals[tx.ID] = &proposalState{txID: tx.ID, blockIndex: block.Index, proposal: *p, votes: make(map[string]Vote)}
+			x.order = append(x.order, tx.ID)
+			if p.Weighting == WeightByStake {
+				x.opening[p.StartHeight] = append(x.opening[p.StartHeight], tx.ID)
+			}
+		case ledger.VoteCast:
+			v, err := x.voteLocked(tx, block.Index)
+			if err != nil {
+				continue
+			}
+			// A vote replaces only an older one, so an earlier vote
+			// included late cannot undo a change of mind.
+			votes := x.proposals[v.ProposalTxID].votes
+			if counted, ok := votes[v.VoterPublicKey]; ok && v.Timestamp <= counted.Timestamp {
+				continue
+			}
+			votes[v.VoterPublicKey] = *v
+		case ledger.Transfer:
+			// The chain has checked the block, so transfers never overdraw.
+			t, err := ledger.TransferFromPayload(tx.Payload)
+			if err != nil {
+				continue
+			}
+			x.balances[tx.SenderPublicKey] -= t.Amount
+			x.balances[t.Recipient] += t.Amount
+		}
+	}
+}
+
+// ValidateVote checks a VoteCast transaction against the indexed proposal it
+// answers, as if it were in the block after the high-water mark: the
+// proposal must exist and be open for voting then. Clients can use it to
+// reject a vote before submitting it; the index applies the same rules.
+func (x *Index) ValidateVote(tx *ledger.Transaction) error {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	_, err := x.voteLocked(tx, x.follower.HighWaterIndex+1)
+	return err
+}
+
+// voteLocked validates a vote in the block at height and returns it. The
+// caller must hold x.mu.
+func (x *Index) voteLocked(tx *ledger.Transaction, height int64) (*Vote, error) {
+	if tx.Type != ledger.VoteCast {
+		return nil, fmt.Errorf("transaction type %s is not a vote", tx.Type)
+	}
+	v, err := VoteFromPayload(tx.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if v.VoterPublicKey != tx.SenderPublicKey {
+		return nil, fmt.Errorf("vote by %s sent by %s", v.VoterPublicKey, tx.SenderPublicKey)
+	}
+	s, ok := x.proposals[v.ProposalTxID]
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("%w: %s", ErrProposalNotFound, v.ProposalTxID)
+	case height < s.proposal.StartHeight || height > s.proposal.EndHeight:
+		return nil, fmt.Errorf("%w: proposal %s takes votes in blocks %d-%d, not %d", ErrVotingClosed, v.ProposalTxID, s.proposal.StartHeight, s.proposal.EndHeight, height)
+	}
+	return v, nil
+}
+
+// entryLocked returns the proposal with its status and tally. The caller must
+// hold x.mu.
+func (x *Index) entryLocked(s *proposalState) ProposalEntry {
+	e := ProposalEntry{TxID: s.txID, BlockIndex: s.blockIndex, Proposal: s.proposal}
+	e.Proposal.Changes = append([]ledger.ParamChange(nil), s.proposal.Changes...)
+	switch next := x.follower.HighWaterIndex + 1; {
+	case next < s.proposal.StartHeight:
+		e.Status = ProposalPending
+	case next > s.proposal.EndHeight:
+		e.Status = ProposalClosed
+	default:
+		e.Status = ProposalActive
+	}
+	for voter, v := range s.votes {
+		weight := uint64(1)
+		if s.proposal.Weighting == WeightByStake {
+			weight = s.stakes[voter]
+		}
+		switch v.Choice {
+		case ChoiceYes:
+			e.Tally.Yes += weight
+		case ChoiceNo:
+			e.Tally.No += weight
+		case ChoiceAbstain:
+			e.Tally.Abstain += weight
+		}
+		e.Tally.Voters++
+	}
+	return e
+}
+
+// GetProposal returns the proposal created by transaction txID.
+func (x *Index) GetProposal(txID string) (ProposalEntry, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	s, ok := x.proposals[txID]
+	if !ok {
+		return ProposalEntry{}, false
+	}
+	return x.entryLocked(s), true
+}
+
+// GetProposals returns the proposals with the given status, in the order the
+// chain recorded them: ProposalActive for those open for voting,
+// ProposalClosed for past ones with their final tally.
+func (x *Index) GetProposals(status ProposalStatus) []ProposalEntry {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	entries := []ProposalEntry{}
+	for _, id := range x.order {
+		if e := x.entryLocked(x.proposals[id]); e.Status == status {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// GetVote returns the counted choice of voter on the proposal created by
+// proposalTxID.
+func (x *Index) GetVote(proposalTxID, voter string) (Choice, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	s, ok := x.proposals[proposalTxID]
+	if !ok {
+		return "", false
+	}
+	v, ok := s.votes[voter]
+	return v.Choice, ok
+}
+
+// Activations returns the parameter changes of the proposals that have
+// closed and passed, in the order the chain recorded them, each with the
+// proposal's transaction ID as its source. Nodes add them to the schedule
+// they set with Blockchain.SetParamSchedule; since an activation height is
+// after its proposal's voting ends, the result is final before the changes
+// take effect.
+func (x *Index) Activations() []ledger.ParamActivation {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	activations := []ledger.ParamActivation{}
+	for _, id := range x.order {
+		s := x.proposals[id]
+		if len(s.proposal.Changes) == 0 {
+			continue
+		}
+		if e := x.entryLocked(s); e.Status != ProposalClosed || !e.Tally.Passed() {
+			continue
+		}
+		activations = append(activations, ledger.ParamActivation{
+			Height:  s.proposal.ActivationHeight,
+			Changes: append([]ledger.ParamChange(nil), s.proposal.Changes...),
+			Source:  s.txID,
+		})
+	}
+	return activations
+}
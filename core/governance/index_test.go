@@ -0,0 +1,190 @@
+package governance
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil"
+	"digisocialblock/internal/testutil/fixture"
+	"errors"
+	"testing"
+	"time"
+)
+
+func indexTestPropose(t *testing.T, wallet *identity.Wallet, start, end int64, weighting Weighting) *ledger.Transaction {
+	t.Helper()
+	tx, err := NewProposalTransaction(nil, wallet, &Proposal{Title: "proposal", MetadataCID: "cid", StartHeight: start, EndHeight: end, Weighting: weighting})
+	if err != nil {
+		t.Fatalf("NewProposalTransaction() error = %v", err)
+	}
+	return tx
+}
+
+func indexTestVote(t *testing.T, wallet *identity.Wallet, proposal *ledger.Transaction, choice Choice) *ledger.Transaction {
+	t.Helper()
+	tx, err := NewVoteTransaction(nil, wallet, proposal.ID, choice)
+	if err != nil {
+		t.Fatalf("NewVoteTransaction() error = %v", err)
+	}
+	return tx
+}
+
+func indexTestTransfer(t *testing.T, from *identity.Wallet, to string, amount uint64) *ledger.Transaction {
+	t.Helper()
+	tx, err := ledger.NewTransferTransaction(nil, from.Address, to, amount, "", "")
+	if err != nil {
+		t.Fatalf("NewTransferTransaction() error = %v", err)
+	}
+	if err := from.SignTransaction(tx); err != nil {
+		t.Fatalf("SignTransaction() error = %v", err)
+	}
+	return tx
+}
+
+func indexTestAdd(t *testing.T, bc *ledger.Blockchain, x *Index, txs ...*ledger.Transaction) {
+	t.Helper()
+	if _, err := bc.AddBlock(txs); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+	if _, err := x.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+}
+
+func TestIndex_VotingWindowsAndWeighting(t *testing.T) {
+	alice, bob, carol := fixture.Wallet(t), fixture.Wallet(t), fixture.Wallet(t)
+	bc := fixture.Chain(t)
+	if err := bc.SetGenesisAllocations(map[string]uint64{alice.Address: 100, bob.Address: 10}); err != nil {
+		t.Fatalf("SetGenesisAllocations() error = %v", err)
+	}
+	x := NewIndex()
+
+	stake := indexTestPropose(t, alice, 3, 4, WeightByStake)
+	account := indexTestPropose(t, alice, 3, 3, WeightByAccount)
+	late := indexTestPropose(t, bob, 1, 5, WeightByAccount)
+	indexTestAdd(t, bc, x, stake, account, late)
+	if _, ok := x.GetProposal(late.ID); ok {
+		t.Error("a proposal whose voting opened before it was recorded was indexed")
+	}
+	if got := x.GetProposals(ProposalPending); len(got) != 2 || got[0].TxID != stake.ID {
+		t.Fatalf("GetProposals(pending) = %+v, want both proposals", got)
+	}
+
+	// Votes before the window do not count; balances move before it opens.
+	indexTestAdd(t, bc, x, indexTestVote(t, bob, stake, ChoiceYes), indexTestTransfer(t, alice, carol.Address, 50))
+	if got := x.GetProposals(ProposalActive); len(got) != 2 || got[0].Tally.Voters != 0 {
+		t.Fatalf("GetProposals(active) = %+v, want both proposals without votes", got)
+	}
+	if err := x.ValidateVote(indexTestVote(t, bob, stake, ChoiceNo)); err != nil {
+		t.Errorf("ValidateVote() for the next block error = %v", err)
+	}
+	if err := x.ValidateVote(indexTestVote(t, bob, late, ChoiceNo)); !errors.Is(err, ErrProposalNotFound) {
+		t.Errorf("ValidateVote() for an unindexed proposal error = %v, want ErrProposalNotFound", err)
+	}
+
+	// Stakes are fixed when voting opens, so carol's transfer to bob in the
+	// opening block gives bob no more weight.
+	indexTestAdd(t, bc, x,
+		indexTestVote(t, alice, stake, ChoiceYes), indexTestVote(t, bob, stake, ChoiceNo), indexTestVote(t, carol, stake, ChoiceNo),
+		indexTestTransfer(t, carol, bob.Address, 50),
+		indexTestVote(t, alice, account, ChoiceYes), indexTestVote(t, bob, account, ChoiceYes), indexTestVote(t, carol, account, ChoiceNo),
+	)
+	if got := x.GetProposals(ProposalActive); len(got) != 1 || got[0].TxID != stake.ID || got[0].Tally.Passed() {
+		t.Errorf("GetProposals(active) = %+v, want the stake proposal, not passing", got)
+	}
+
+	// A changed vote replaces the earlier one; votes after the window do not count.
+	indexTestAdd(t, bc, x, indexTestVote(t, bob, stake, ChoiceYes), indexTestVote(t, carol, account, ChoiceYes))
+	closed := x.GetProposals(ProposalClosed)
+	if len(closed) != 2 {
+		t.Fatalf("GetProposals(closed) = %+v, want both proposals", closed)
+	}
+	if got, want := closed[0].Tally, (Tally{Yes: 60, No: 50, Voters: 3}); got != want || !got.Passed() {
+		t.Errorf("stake-weighted Tally = %+v, want %+v", got, want)
+	}
+	if got, want := closed[1].Tally, (Tally{Yes: 2, No: 1, Voters: 3}); got != want {
+		t.Errorf("account-weighted Tally = %+v, want %+v", got, want)
+	}
+	if choice, ok := x.GetVote(account.ID, carol.Address); !ok || choice != ChoiceNo {
+		t.Errorf("GetVote() = %q, %v; want carol's vote in the window", choice, ok)
+	}
+	if err := x.ValidateVote(indexTestVote(t, alice, stake, ChoiceNo)); !errors.Is(err, ErrVotingClosed) {
+		t.Errorf("ValidateVote() after the window error = %v, want ErrVotingClosed", err)
+	}
+
+	rebuilt := NewIndex()
+	if _, err := rebuilt.Rebuild(bc); err != nil {
+		t.Fatalf("Rebuild() error = %v", err)
+	}
+	if got, _ := rebuilt.GetProposal(stake.ID); got.Tally != closed[0].Tally {
+		t.Errorf("rebuilt Tally = %+v, want %+v", got.Tally, closed[0].Tally)
+	}
+}
+
+func TestIndex_ActivationsOfPassedProposals(t *testing.T) {
+	alice, bob := fixture.Wallet(t), fixture.Wallet(t)
+	bc := fixture.Chain(t)
+	x := NewIndex()
+
+	propose := func(title string) *ledger.Transaction {
+		tx, err := NewProposalTransaction(nil, alice, &Proposal{
+			Title: title, MetadataCID: "cid", Weighting: WeightByAccount,
+			Changes: []ledger.ParamChange{{Name: ledger.ParamMaxBlockTransactions, Value: "2"}}, StartHeight: 2, EndHeight: 3, ActivationHeight: 5,
+		})
+		if err != nil {
+			t.Fatalf("NewProposalTransaction() error = %v", err)
+		}
+		return tx
+	}
+	passes, fails := propose("passes"), propose("fails")
+	indexTestAdd(t, bc, x, passes, fails, indexTestPropose(t, alice, 2, 2, WeightByAccount))
+	indexTestAdd(t, bc, x, indexTestVote(t, alice, passes, ChoiceYes), indexTestVote(t, bob, passes, ChoiceYes), indexTestVote(t, alice, fails, ChoiceNo))
+	if got := x.Activations(); len(got) != 0 {
+		t.Fatalf("Activations() while voting is open = %+v, want none", got)
+	}
+
+	indexTestAdd(t, bc, x)
+	got := x.Activations()
+	if len(got) != 1 || got[0].Source != passes.ID || got[0].Height != 5 {
+		t.Fatalf("Activations() = %+v, want the passed proposal's at height 5", got)
+	}
+	if err := bc.SetParamSchedule(ledger.ParamSchedule{Activations: got}); err != nil {
+		t.Fatalf("SetParamSchedule() error = %v", err)
+	}
+	if p := bc.ParamsAt(5); p.MaxBlockTransactions != 2 {
+		t.Errorf("ParamsAt(5) = %+v, want the passed change", p)
+	}
+}
+
+func TestIndex_OlderVoteDoesNotReplaceNewer(t *testing.T) {
+	alice, bob := fixture.Wallet(t), fixture.Wallet(t)
+	bc := fixture.Chain(t)
+	x := NewIndex()
+	clock := testutil.NewClock(time.Second)
+	vote := func(wallet *identity.Wallet, proposal *ledger.Transaction, choice Choice) *ledger.Transaction {
+		tx, err := NewVoteTransaction(clock, wallet, proposal.ID, choice)
+		if err != nil {
+			t.Fatalf("NewVoteTransaction() error = %v", err)
+		}
+		return tx
+	}
+
+	proposal := indexTestPropose(t, alice, 2, 4, WeightByAccount)
+	indexTestAdd(t, bc, x, proposal)
+	aliceYes, aliceAbstain, aliceNo := vote(alice, proposal, ChoiceYes), vote(alice, proposal, ChoiceAbstain), vote(alice, proposal, ChoiceNo)
+	bobYes, bobNo := vote(bob, proposal, ChoiceYes), vote(bob, proposal, ChoiceNo)
+
+	// Alice changes her vote; replaying her first vote is refused by the
+	// chain, and a vote she signed in between but sent late does not count.
+	// Bob's first vote follows his second in the same block.
+	indexTestAdd(t, bc, x, aliceYes, bobNo, bobYes)
+	indexTestAdd(t, bc, x, aliceNo)
+	if _, err := bc.AddBlock([]*ledger.Transaction{aliceYes}); !errors.Is(err, ledger.ErrDuplicateTransaction) {
+		t.Errorf("AddBlock() replaying alice's first vote error = %v, want ErrDuplicateTransaction", err)
+	}
+	indexTestAdd(t, bc, x, aliceAbstain)
+	for voter, want := range map[string]Choice{alice.Address: ChoiceNo, bob.Address: ChoiceNo} {
+		if got, ok := x.GetVote(proposal.ID, voter); !ok || got != want {
+			t.Errorf("GetVote() = %q, %v; want the newest vote %q", got, ok, want)
+		}
+	}
+}
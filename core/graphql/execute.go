@@ -0,0 +1,431 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// MaxQueryLength bounds the size of a query document, in bytes.
+const MaxQueryLength = 16 << 10
+
+// Location is a line and column (both 1-based) in a query document.
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Error is a GraphQL error as reported in a response.
+type Error struct {
+	Message   string        `json:"message"`
+	Locations []Location    `json:"locations,omitempty"`
+	Path      []interface{} `json:"path,omitempty"` // Response keys and list indices
+}
+
+func (e *Error) Error() string {
+	if len(e.Locations) > 0 {
+		return fmt.Sprintf("%s (line %d, column %d)", e.Message, e.Locations[0].Line, e.Locations[0].Column)
+	}
+	return e.Message
+}
+
+// Request is a GraphQL request.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response is a GraphQL response. Data is nil if the request failed before
+// execution (syntax, validation or variable errors); otherwise it holds the
+// result, with null fields where resolvers failed.
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Execute validates and runs a request. Resolver errors are reported
+// alongside the partial result.
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	if len(req.Query) > MaxQueryLength {
+		return errorResponse(&Error{Message: fmt.Sprintf("query is %d bytes, limit %d", len(req.Query), MaxQueryLength)})
+	}
+	doc, err := parse(req.Query)
+	if err != nil {
+		return errorResponse(err.(*Error))
+	}
+	op, gqlErr := selectOperation(doc, req.OperationName)
+	if gqlErr != nil {
+		return errorResponse(gqlErr)
+	}
+	vars, gqlErr := coerceVariables(op, req.Variables)
+	if gqlErr != nil {
+		return errorResponse(gqlErr)
+	}
+	v := &validator{schema: s, vars: op.vars, maxDepth: s.MaxDepth}
+	if v.maxDepth <= 0 {
+		v.maxDepth = DefaultMaxDepth
+	}
+	v.selections(s.query, op.selections, 1)
+	if len(v.errors) > 0 {
+		return &Response{Errors: v.errors}
+	}
+	e := &executor{ctx: ctx, schema: s, vars: vars, maxFields: s.MaxFields}
+	if e.maxFields <= 0 {
+		e.maxFields = DefaultMaxFields
+	}
+	// A nil result still encodes as "data": null, marking the request as executed.
+	data, _ := e.selectFields(s.query, nil, op.selections, nil)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+func errorResponse(err *Error) *Response {
+	return &Response{Errors: []*Error{err}}
+}
+
+func selectOperation(doc *document, name string) (*operation, *Error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, &Error{Message: "operationName is required for a document with several operations"}
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, &Error{Message: fmt.Sprintf("unknown operation %q", name)}
+}
+
+// coerceVariables checks supplied variables against the operation's
+// definitions and applies defaults.
+func coerceVariables(op *operation, supplied map[string]interface{}) (map[string]interface{}, *Error) {
+	vars := make(map[string]interface{}, len(op.vars))
+	defined := make(map[string]bool, len(op.vars))
+	for _, def := range op.vars {
+		if defined[def.name] {
+			return nil, &Error{Message: fmt.Sprintf("variable $%s is defined twice", def.name), Locations: []Location{def.loc}}
+		}
+		defined[def.name] = true
+		raw, ok := supplied[def.name]
+		if !ok && def.hasDefault {
+			raw, ok = def.defaultVal, true
+		}
+		if !ok || raw == nil {
+			if def.typ.nonNull {
+				return nil, &Error{Message: fmt.Sprintf("variable $%s of type %s is required", def.name, def.typ), Locations: []Location{def.loc}}
+			}
+			if ok {
+				vars[def.name] = nil
+			}
+			continue
+		}
+		val, err := coerceScalar(def.typ.name, raw)
+		if err != nil {
+			return nil, &Error{Message: fmt.Sprintf("variable $%s: %v", def.name, err), Locations: []Location{def.loc}}
+		}
+		vars[def.name] = val
+	}
+	for name := range supplied {
+		if !defined[name] {
+			return nil, &Error{Message: fmt.Sprintf("variable $%s is not defined by the operation", name)}
+		}
+	}
+	return vars, nil
+}
+
+// coerceScalar converts a literal or JSON variable value to the Go type
+// resolvers receive for a built-in scalar.
+func coerceScalar(typ string, raw interface{}) (interface{}, error) {
+	switch typ {
+	case TypeInt:
+		var n float64
+		switch v := raw.(type) {
+		case int64:
+			n = float64(v)
+		case float64:
+			n = v
+		case json.Number:
+			f, err := v.Float64()
+			if err != nil {
+				return nil, fmt.Errorf("%q is not an Int", v)
+			}
+			n = f
+		default:
+			return nil, fmt.Errorf("expected an Int, got %T", raw)
+		}
+		if n != math.Trunc(n) || n < math.MinInt32 || n > math.MaxInt32 {
+			return nil, fmt.Errorf("%v is not a 32-bit Int", raw)
+		}
+		return int(n), nil
+	case TypeID, TypeString:
+		if s, ok := raw.(string); ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("expected a %s, got %T", typ, raw)
+	case TypeBoolean:
+		if b, ok := raw.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("expected a Boolean, got %T", raw)
+	}
+	return nil, fmt.Errorf("arguments of type %s are not supported", typ)
+}
+
+// validator checks an operation against the schema before execution.
+type validator struct {
+	schema   *Schema
+	vars     []*varDef
+	maxDepth int
+	errors   []*Error
+}
+
+func (v *validator) fail(loc Location, format string, args ...interface{}) {
+	v.errors = append(v.errors, &Error{Message: fmt.Sprintf(format, args...), Locations: []Location{loc}})
+}
+
+func (v *validator) selections(obj *Object, sels []*selection, depth int) {
+	if depth > v.maxDepth {
+		v.fail(sels[0].loc, "query is nested more than %d levels deep", v.maxDepth)
+		return
+	}
+	seen := make(map[string]bool, len(sels))
+	for _, sel := range sels {
+		if seen[sel.alias] {
+			v.fail(sel.loc, "response key %q is selected twice; use an alias", sel.alias)
+			continue
+		}
+		seen[sel.alias] = true
+		if sel.name == "__typename" {
+			if len(sel.args) > 0 || sel.selections != nil {
+				v.fail(sel.loc, "__typename takes no arguments or selections")
+			}
+			continue
+		}
+		field := obj.Fields[sel.name]
+		if field == nil {
+			v.fail(sel.loc, "type %s has no field %q", obj.Name, sel.name)
+			continue
+		}
+		v.arguments(obj, sel, field)
+		if child := v.schema.objects[field.typ.name]; child != nil {
+			if sel.selections == nil {
+				v.fail(sel.loc, "field %q of type %s needs a selection set", sel.name, field.typ)
+				continue
+			}
+			v.selections(child, sel.selections, depth+1)
+		} else if sel.selections != nil {
+			v.fail(sel.loc, "field %q of scalar type %s cannot have a selection set", sel.name, field.typ)
+		}
+	}
+}
+
+func (v *validator) arguments(obj *Object, sel *selection, field *Field) {
+	given := make(map[string]bool, len(sel.args))
+	for _, arg := range sel.args {
+		typ, ok := field.args[arg.name]
+		if !ok {
+			v.fail(arg.loc, "field %s.%s has no argument %q", obj.Name, sel.name, arg.name)
+			continue
+		}
+		if given[arg.name] {
+			v.fail(arg.loc, "argument %q is given twice", arg.name)
+			continue
+		}
+		given[arg.name] = true
+		switch val := arg.value.(type) {
+		case variableRef:
+			def := v.varDef(string(val))
+			if def == nil {
+				v.fail(arg.loc, "variable $%s is not defined", val)
+			} else if def.typ.name != typ.name || (typ.nonNull && !def.typ.nonNull && !def.hasDefault) {
+				v.fail(arg.loc, "variable $%s of type %s cannot be used as argument %q of type %s", val, def.typ, arg.name, typ)
+			}
+		case nil:
+			if typ.nonNull {
+				v.fail(arg.loc, "argument %q of type %s cannot be null", arg.name, typ)
+			}
+		default:
+			if _, err := coerceScalar(typ.name, val); err != nil {
+				v.fail(arg.loc, "argument %q: %v", arg.name, err)
+			}
+		}
+	}
+	for name, typ := range field.args {
+		if typ.nonNull && !given[name] {
+			v.fail(sel.loc, "field %s.%s requires argument %q", obj.Name, sel.name, name)
+		}
+	}
+}
+
+func (v *validator) varDef(name string) *varDef {
+	for _, def := range v.vars {
+		if def.name == name {
+			return def
+		}
+	}
+	return nil
+}
+
+// executor runs a validated operation.
+type executor struct {
+	ctx       context.Context
+	schema    *Schema
+	vars      map[string]interface{}
+	maxFields int
+	resolved  int
+	errors    []*Error
+}
+
+func (e *executor) fail(sel *selection, path []interface{}, msg string) {
+	e.errors = append(e.errors, &Error{
+		Message:   msg,
+		Locations: []Location{sel.loc},
+		Path:      append([]interface{}(nil), path...),
+	})
+}
+
+// selectFields resolves sels on an object. It returns false if a non-null
+// field came back null, in which case the object itself becomes null.
+func (e *executor) selectFields(obj *Object, source interface{}, sels []*selection, path []interface{}) (*orderedMap, bool) {
+	result := &orderedMap{}
+	for _, sel := range sels {
+		fieldPath := append(path[:len(path):len(path)], sel.alias)
+		if sel.name == "__typename" {
+			result.set(sel.alias, obj.Name)
+			continue
+		}
+		field := obj.Fields[sel.name]
+		if e.resolved++; e.resolved > e.maxFields {
+			if e.resolved == e.maxFields+1 {
+				e.fail(sel, fieldPath, fmt.Sprintf("query resolves more than %d fields", e.maxFields))
+			}
+			return nil, false
+		}
+		value, err := e.resolve(field, sel, source)
+		if err != nil {
+			e.fail(sel, fieldPath, err.Error())
+			value = nil
+		}
+		completed, ok := e.complete(field.typ, value, sel, fieldPath, err != nil)
+		if !ok {
+			return nil, false
+		}
+		result.set(sel.alias, completed)
+	}
+	return result, true
+}
+
+func (e *executor) resolve(field *Field, sel *selection, source interface{}) (interface{}, error) {
+	if err := e.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if field.Resolve == nil {
+		m, _ := source.(map[string]interface{})
+		return m[sel.name], nil
+	}
+	args := make(map[string]interface{}, len(sel.args))
+	for _, arg := range sel.args {
+		value := arg.value
+		if ref, ok := value.(variableRef); ok {
+			var present bool
+			if value, present = e.vars[string(ref)]; !present {
+				continue // An omitted variable leaves the argument unset.
+			}
+		} else if value != nil {
+			value, _ = coerceScalar(field.args[arg.name].name, value) // Checked by the validator.
+		}
+		args[arg.name] = value
+	}
+	return field.Resolve(ResolveParams{Context: e.ctx, Source: source, Args: args})
+}
+
+// complete shapes a resolved value to its field type. It returns false if
+// the value is null but the type is non-null; reported says whether an error
+// has already been recorded for the field.
+func (e *executor) complete(t typeRef, value interface{}, sel *selection, path []interface{}, reported bool) (interface{}, bool) {
+	if isNull(value) {
+		if t.nonNull {
+			if !reported {
+				e.fail(sel, path, fmt.Sprintf("non-null field %q resolved to null", sel.name))
+			}
+			return nil, false
+		}
+		return nil, true
+	}
+	if t.list {
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice {
+			e.fail(sel, path, fmt.Sprintf("field %q resolved to %T, want a list", sel.name, value))
+			return nil, !t.nonNull
+		}
+		elem := typeRef{name: t.name, nonNull: t.elemNonNull}
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			item, ok := e.complete(elem, rv.Index(i).Interface(), sel, append(path[:len(path):len(path)], i), false)
+			if !ok {
+				return nil, !t.nonNull
+			}
+			items[i] = item
+		}
+		return items, true
+	}
+	if obj := e.schema.objects[t.name]; obj != nil {
+		m, ok := e.selectFields(obj, value, sel.selections, path)
+		if !ok {
+			return nil, !t.nonNull
+		}
+		return m, true
+	}
+	return value, true
+}
+
+// isNull reports whether v is nil or a nil pointer or map. Nil slices are
+// empty lists, not null.
+func isNull(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// orderedMap is a JSON object that keeps the order of the selections that
+// produced it.
+type orderedMap struct {
+	keys   []string
+	values []interface{}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	m.keys = append(m.keys, key)
+	m.values = append(m.values, value)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		b.Write(k)
+		b.WriteByte(':')
+		v, err := json.Marshal(m.values[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", key, err)
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
@@ -0,0 +1,150 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// executeTestSchema is a small schema of numbered items whose resolvers echo
+// their arguments, fail on request or return null for a non-null field.
+func executeTestSchema(t *testing.T) *Schema {
+	t.Helper()
+	item := &Object{Name: "Item", Fields: map[string]*Field{
+		"n": {Type: "Int!", Resolve: func(p ResolveParams) (interface{}, error) { return p.Source.(int), nil }},
+		"next": {Type: "Item", Resolve: func(p ResolveParams) (interface{}, error) {
+			return p.Source.(int) + 1, nil
+		}},
+		"fail": {Type: TypeString, Resolve: func(p ResolveParams) (interface{}, error) {
+			return nil, errors.New("boom")
+		}},
+		"missing": {Type: "String!", Resolve: func(p ResolveParams) (interface{}, error) { return nil, nil }},
+	}}
+	query := &Object{Name: "Query", Fields: map[string]*Field{
+		"item": {Type: "Item", Args: map[string]string{"n": "Int!"}, Resolve: func(p ResolveParams) (interface{}, error) {
+			return p.Int("n", 0), nil
+		}},
+		"items": {Type: "[Item!]!", Args: map[string]string{"count": TypeInt}, Resolve: func(p ResolveParams) (interface{}, error) {
+			var items []int
+			for i := 0; i < p.Int("count", 2); i++ {
+				items = append(items, i)
+			}
+			return items, nil
+		}},
+		"echo": {Type: TypeString, Args: map[string]string{"s": TypeString}, Resolve: func(p ResolveParams) (interface{}, error) {
+			if v, ok := p.Args["s"]; ok && v == nil {
+				return "explicit null", nil
+			}
+			return p.String("s"), nil
+		}},
+	}}
+	schema, err := NewSchema(query, []*Object{item}, nil)
+	if err != nil {
+		t.Fatalf("NewSchema() error = %v", err)
+	}
+	return schema
+}
+
+// executeTestRun executes query and returns the JSON-encoded response.
+func executeTestRun(t *testing.T, schema *Schema, query string, vars map[string]interface{}) string {
+	t.Helper()
+	resp := schema.Execute(context.Background(), Request{Query: query, Variables: vars})
+	out, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("json.Marshal(response) error = %v", err)
+	}
+	return string(out)
+}
+
+func TestExecute(t *testing.T) {
+	schema := executeTestSchema(t)
+	tests := []struct {
+		name  string
+		query string
+		vars  map[string]interface{}
+		want  string
+	}{
+		{"nested and ordered", `{ b: item(n: 1) { next { n } n __typename } a: echo(s: "x") }`, nil,
+			`{"data":{"b":{"next":{"n":2},"n":1,"__typename":"Item"},"a":"x"}}`},
+		{"lists", `{ items(count: 3) { n } }`, nil, `{"data":{"items":[{"n":0},{"n":1},{"n":2}]}}`},
+		{"variables and defaults", `query Q($n: Int!, $c: Int = 1) { item(n: $n) { n } items(count: $c) { n } }`,
+			map[string]interface{}{"n": json.Number("7")}, `{"data":{"item":{"n":7},"items":[{"n":0}]}}`},
+		{"omitted and null arguments", `query ($s: String) { unset: echo(s: $s) null: echo(s: null) }`, nil,
+			`{"data":{"unset":"","null":"explicit null"}}`},
+		{"resolver error", `{ item(n: 1) { n fail } }`, nil,
+			`{"data":{"item":{"n":1,"fail":null}},"errors":[{"message":"boom","locations":[{"line":1,"column":18}],"path":["item","fail"]}]}`},
+		{"null propagates to nullable parent", `{ item(n: 1) { missing } echo(s: "ok") }`, nil,
+			`{"data":{"item":null,"echo":"ok"},"errors":[{"message":"non-null field \"missing\" resolved to null","locations":[{"line":1,"column":16}],"path":["item","missing"]}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := executeTestRun(t, schema, tt.query, tt.vars); got != tt.want {
+				t.Errorf("Execute() =\n  %s\nwant\n  %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecute_RequestErrors(t *testing.T) {
+	schema := executeTestSchema(t)
+	tests := []struct {
+		name  string
+		query string
+		vars  map[string]interface{}
+		want  string // Substring of the first error
+	}{
+		{"syntax", `{ item(`, nil, "syntax error"},
+		{"unknown field", `{ nope }`, nil, `no field "nope"`},
+		{"unknown argument", `{ echo(t: "x") }`, nil, `no argument "t"`},
+		{"missing argument", `{ item { n } }`, nil, `requires argument "n"`},
+		{"wrong argument type", `{ echo(s: 1) }`, nil, "expected a String"},
+		{"null for non-null argument", `{ item(n: null) { n } }`, nil, "cannot be null"},
+		{"missing selection", `{ item(n: 1) }`, nil, "needs a selection set"},
+		{"scalar selection", `{ echo { n } }`, nil, "cannot have a selection set"},
+		{"duplicate key", `{ echo echo }`, nil, "selected twice"},
+		{"undefined variable", `{ item(n: $n) { n } }`, nil, "$n is not defined"},
+		{"nullable variable for non-null argument", `query ($n: Int) { item(n: $n) { n } }`, nil, "cannot be used"},
+		{"missing required variable", `query ($n: Int!) { item(n: $n) { n } }`, nil, "is required"},
+		{"bad variable", `query ($n: Int!) { item(n: $n) { n } }`, map[string]interface{}{"n": 1.5}, "not a 32-bit Int"},
+		{"unknown variable", `{ echo }`, map[string]interface{}{"x": 1}, "not defined by the operation"},
+		{"several operations", `query A { echo } query B { echo }`, nil, "operationName is required"},
+		{"too deep", `{ item(n: 0) {` + strings.Repeat(` next {`, DefaultMaxDepth) + ` n` + strings.Repeat(` }`, DefaultMaxDepth+1) + ` }`, nil, "levels deep"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := schema.Execute(context.Background(), Request{Query: tt.query, Variables: tt.vars})
+			if resp.Data != nil {
+				t.Errorf("Execute() data = %v, want none for a request error", resp.Data)
+			}
+			if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, tt.want) {
+				t.Errorf("Execute() errors = %v, want one containing %q", resp.Errors, tt.want)
+			}
+		})
+	}
+
+	resp := schema.Execute(context.Background(), Request{Query: `query A { a: echo } query B { b: echo }`, OperationName: "B"})
+	if out, _ := json.Marshal(resp); string(out) != `{"data":{"b":""}}` {
+		t.Errorf("Execute(operationName B) = %s", out)
+	}
+}
+
+func TestExecute_LimitsAndCancellation(t *testing.T) {
+	schema := executeTestSchema(t)
+	schema.MaxFields = 10
+	resp := schema.Execute(context.Background(), Request{Query: `{ items(count: 20) { n } }`})
+	if len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "more than 10 fields") {
+		t.Errorf("Execute() errors = %v, want the field limit", resp.Errors)
+	}
+	if out, _ := json.Marshal(resp.Data); string(out) != "null" {
+		t.Errorf("Execute() data = %s, want null", out)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	resp = schema.Execute(ctx, Request{Query: `{ echo(s: "x") }`})
+	if len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "canceled") {
+		t.Errorf("Execute() with a canceled context errors = %v", resp.Errors)
+	}
+}
@@ -0,0 +1,53 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// maxRequestBodySize bounds a POSTed request: the query plus its variables.
+const maxRequestBodySize = MaxQueryLength + 16<<10
+
+// Handler serves a schema over HTTP: POST a JSON Request, get a JSON
+// Response. Requests that fail before execution get a 400 status, executed
+// ones 200 with any errors in the body.
+func Handler(schema *Schema) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeResponse(w, http.StatusMethodNotAllowed, errorResponse(&Error{Message: "use POST"}))
+			return
+		}
+		// Numbers are kept exact, so Int variables are checked against their literal value.
+		var req Request
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+		dec.UseNumber()
+		err := dec.Decode(&req)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeResponse(w, http.StatusRequestEntityTooLarge, errorResponse(&Error{Message: "request body too large"}))
+			return
+		}
+		if err != nil {
+			writeResponse(w, http.StatusBadRequest, errorResponse(&Error{Message: "request body is not a GraphQL request object"}))
+			return
+		}
+		if req.Query == "" {
+			writeResponse(w, http.StatusBadRequest, errorResponse(&Error{Message: "query is required"}))
+			return
+		}
+		resp := schema.Execute(r.Context(), req)
+		status := http.StatusOK
+		if resp.Data == nil && len(resp.Errors) > 0 {
+			status = http.StatusBadRequest
+		}
+		writeResponse(w, status, resp)
+	})
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp *Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
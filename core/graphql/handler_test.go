@@ -0,0 +1,42 @@
+package graphql
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	h := Handler(executeTestSchema(t))
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+		want   string // Substring of the response body
+	}{
+		{"post", http.MethodPost, `{"query":"query ($n: Int!) { item(n: $n) { n } }","variables":{"n":3}}`,
+			http.StatusOK, `{"data":{"item":{"n":3}}}`},
+		{"resolver error", http.MethodPost, `{"query":"{ item(n: 1) { fail } }"}`, http.StatusOK, `"message":"boom"`},
+		{"validation error", http.MethodPost, `{"query":"{ nope }"}`, http.StatusBadRequest, `no field`},
+		{"no query", http.MethodPost, `{}`, http.StatusBadRequest, "query is required"},
+		{"not JSON", http.MethodPost, `query`, http.StatusBadRequest, "not a GraphQL request"},
+		{"bad variables", http.MethodPost, `{"query":"{ echo }","variables":[]}`, http.StatusBadRequest, "not a GraphQL request"},
+		{"too large", http.MethodPost, `{"query":"` + strings.Repeat(" ", maxRequestBodySize) + `"}`, http.StatusRequestEntityTooLarge, "too large"},
+		{"wrong method", http.MethodGet, "", http.StatusMethodNotAllowed, "use POST"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(tt.method, "/v1/graphql", strings.NewReader(tt.body)))
+			if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("%s = %d %s, want %d with %q", tt.method, rec.Code, rec.Body, tt.status, tt.want)
+			}
+			if !json.Valid(rec.Body.Bytes()) || rec.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("response is not JSON: %s", rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,379 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The parser covers the query subset of GraphQL used by front ends: named or
+// anonymous queries, variables, aliases, arguments and nested selections.
+// Fragments, directives, list and object values, and mutations are rejected
+// with a syntax error.
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind tokenKind
+	text string // Punctuator, name, number, or the decoded string value
+	loc  Location
+}
+
+// lex splits src into tokens, dropping whitespace, commas and comments.
+func lex(src string) ([]token, error) {
+	var toks []token
+	line, lineStart := 1, 0
+	for i := 0; i < len(src); {
+		c := src[i]
+		loc := Location{Line: line, Column: i - lineStart + 1}
+		switch {
+		case c == '\n':
+			i++
+			line, lineStart = line+1, i
+		case c == ' ' || c == '\t' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "..."):
+			toks = append(toks, token{tokPunct, "...", loc})
+			i += 3
+		case strings.IndexByte("!$():=@[]{}|&", c) >= 0:
+			toks = append(toks, token{tokPunct, string(c), loc})
+			i++
+		case c == '_' || isLetter(c):
+			j := i + 1
+			for j < len(src) && (src[j] == '_' || isLetter(src[j]) || isDigit(src[j])) {
+				j++
+			}
+			toks = append(toks, token{tokName, src[i:j], loc})
+			i = j
+		case c == '-' || isDigit(c):
+			j := i + 1
+			kind := tokInt
+			for j < len(src) && (isDigit(src[j]) || strings.IndexByte(".eE+-", src[j]) >= 0) {
+				if !isDigit(src[j]) {
+					kind = tokFloat
+				}
+				j++
+			}
+			toks = append(toks, token{kind, src[i:j], loc})
+			i = j
+		case c == '"':
+			if strings.HasPrefix(src[i:], `"""`) {
+				return nil, syntaxError(loc, "block strings are not supported")
+			}
+			j := i + 1
+			for j < len(src) && src[j] != '"' && src[j] != '\n' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) || src[j] != '"' {
+				return nil, syntaxError(loc, "unterminated string")
+			}
+			// GraphQL string escapes are a subset of JSON's.
+			var s string
+			if err := json.Unmarshal([]byte(src[i:j+1]), &s); err != nil {
+				return nil, syntaxError(loc, "invalid string escape")
+			}
+			toks = append(toks, token{tokString, s, loc})
+			i = j + 1
+		default:
+			return nil, syntaxError(loc, fmt.Sprintf("unexpected character %q", c))
+		}
+	}
+	return append(toks, token{kind: tokEOF, loc: Location{Line: line, Column: len(src) - lineStart + 1}}), nil
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+func syntaxError(loc Location, msg string) *Error {
+	return &Error{Message: "syntax error: " + msg, Locations: []Location{loc}}
+}
+
+type document struct {
+	operations []*operation
+}
+
+type operation struct {
+	name       string
+	vars       []*varDef
+	selections []*selection
+	loc        Location
+}
+
+type varDef struct {
+	name       string
+	typ        typeRef
+	defaultVal interface{} // Literal value; nil if absent or null
+	hasDefault bool
+	loc        Location
+}
+
+type selection struct {
+	alias      string // Response key; the field name if no alias was given
+	name       string
+	args       []*argument
+	selections []*selection
+	loc        Location
+}
+
+type argument struct {
+	name  string
+	value interface{} // int64, string, bool, nil or variableRef
+	loc   Location
+}
+
+// variableRef is an argument value naming a variable.
+type variableRef string
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+// parse parses a request document.
+func parse(src string) (*document, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	doc := &document{}
+	for p.peek().kind != tokEOF {
+		op, err := p.operation()
+		if err != nil {
+			return nil, err
+		}
+		doc.operations = append(doc.operations, op)
+	}
+	if len(doc.operations) == 0 {
+		return nil, syntaxError(p.peek().loc, "document contains no operations")
+	}
+	return doc, nil
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) advance() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// skip consumes the punctuator punct if it is next.
+func (p *parser) skip(punct string) bool {
+	if t := p.peek(); t.kind == tokPunct && t.text == punct {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.skip(punct) {
+		return p.unexpected("expected " + strconv.Quote(punct))
+	}
+	return nil
+}
+
+func (p *parser) name() (string, error) {
+	t := p.peek()
+	if t.kind != tokName {
+		return "", p.unexpected("expected a name")
+	}
+	p.pos++
+	return t.text, nil
+}
+
+func (p *parser) unexpected(want string) *Error {
+	t := p.peek()
+	if t.kind == tokEOF {
+		return syntaxError(t.loc, want+", got end of document")
+	}
+	return syntaxError(t.loc, fmt.Sprintf("%s, got %q", want, t.text))
+}
+
+func (p *parser) operation() (*operation, error) {
+	op := &operation{loc: p.peek().loc}
+	if t := p.peek(); t.kind == tokName {
+		switch t.text {
+		case "query":
+			p.pos++
+		case "mutation", "subscription":
+			return nil, syntaxError(t.loc, t.text+" operations are not supported")
+		case "fragment":
+			return nil, syntaxError(t.loc, "fragments are not supported")
+		default:
+			return nil, p.unexpected(`expected "query" or "{"`)
+		}
+		if p.peek().kind == tokName {
+			op.name = p.advance().text
+		}
+		if p.skip("(") {
+			for !p.skip(")") {
+				v, err := p.varDef()
+				if err != nil {
+					return nil, err
+				}
+				op.vars = append(op.vars, v)
+			}
+		}
+	}
+	if p.peek().kind == tokPunct && p.peek().text == "@" {
+		return nil, syntaxError(p.peek().loc, "directives are not supported")
+	}
+	sels, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.selections = sels
+	return op, nil
+}
+
+func (p *parser) varDef() (*varDef, error) {
+	v := &varDef{loc: p.peek().loc}
+	if err := p.expect("$"); err != nil {
+		return nil, err
+	}
+	var err error
+	if v.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	if p.peek().kind == tokPunct && p.peek().text == "[" {
+		return nil, syntaxError(p.peek().loc, "list variables are not supported")
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	v.typ = typeRef{name: name, nonNull: p.skip("!")}
+	if p.skip("=") {
+		if v.defaultVal, err = p.value(true); err != nil {
+			return nil, err
+		}
+		v.hasDefault = true
+	}
+	return v, nil
+}
+
+func (p *parser) selectionSet() ([]*selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sels []*selection
+	for !p.skip("}") {
+		if t := p.peek(); t.kind == tokPunct && t.text == "..." {
+			return nil, syntaxError(t.loc, "fragments are not supported")
+		}
+		sel, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	if len(sels) == 0 {
+		return nil, syntaxError(p.toks[p.pos-1].loc, "empty selection set")
+	}
+	return sels, nil
+}
+
+func (p *parser) field() (*selection, error) {
+	sel := &selection{loc: p.peek().loc}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	sel.alias, sel.name = name, name
+	if p.skip(":") {
+		if sel.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.skip("(") {
+		for !p.skip(")") {
+			arg := &argument{loc: p.peek().loc}
+			if arg.name, err = p.name(); err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if arg.value, err = p.value(false); err != nil {
+				return nil, err
+			}
+			sel.args = append(sel.args, arg)
+		}
+	}
+	if t := p.peek(); t.kind == tokPunct && t.text == "@" {
+		return nil, syntaxError(t.loc, "directives are not supported")
+	}
+	if t := p.peek(); t.kind == tokPunct && t.text == "{" {
+		if sel.selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return sel, nil
+}
+
+// value parses an argument value. constant values cannot reference variables.
+func (p *parser) value(constant bool) (interface{}, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokInt:
+		p.pos++
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, syntaxError(t.loc, fmt.Sprintf("invalid integer %q", t.text))
+		}
+		return n, nil
+	case tokFloat:
+		return nil, syntaxError(t.loc, "float values are not supported")
+	case tokString:
+		p.pos++
+		return t.text, nil
+	case tokName:
+		p.pos++
+		switch t.text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return nil, syntaxError(t.loc, "enum values are not supported")
+	case tokPunct:
+		if t.text == "$" && !constant {
+			p.pos++
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			return variableRef(name), nil
+		}
+		if t.text == "[" || t.text == "{" {
+			return nil, syntaxError(t.loc, "list and object values are not supported")
+		}
+	}
+	return nil, p.unexpected("expected a value")
+}
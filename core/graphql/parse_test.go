@@ -0,0 +1,64 @@
+package graphql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := parse(`
+		# Comments, commas and aliases are fine.
+		query Feed($n: Int = 5, $tag: String!) {
+			latest: posts(first: $n, tag: $tag, after: "c1") { nodes { id } }
+			author(address: "a", extra: -3, flag: true, none: null) { address }
+		}`)
+	if err != nil {
+		t.Fatalf("parse() error = %v", err)
+	}
+	op := doc.operations[0]
+	if op.name != "Feed" || len(op.vars) != 2 || op.vars[0].defaultVal != int64(5) || !op.vars[1].typ.nonNull {
+		t.Fatalf("operation = %+v, want Feed with $n = 5 and $tag: String!", op)
+	}
+	posts := op.selections[0]
+	if posts.alias != "latest" || posts.name != "posts" || posts.args[0].value != variableRef("n") || posts.args[2].value != "c1" {
+		t.Errorf("posts selection = %+v", posts)
+	}
+	if posts.selections[0].selections[0].name != "id" {
+		t.Errorf("nested selection = %+v, want id", posts.selections[0].selections[0])
+	}
+	args := op.selections[1].args
+	if args[1].value != int64(-3) || args[2].value != true || args[3].value != nil {
+		t.Errorf("literal arguments = %v %v %v", args[1].value, args[2].value, args[3].value)
+	}
+	if op.selections[1].loc != (Location{Line: 5, Column: 4}) {
+		t.Errorf("author location = %+v, want 5:4", op.selections[1].loc)
+	}
+}
+
+func TestParse_RejectsUnsupportedSyntax(t *testing.T) {
+	tests := map[string]string{
+		"empty":        ``,
+		"mutation":     `mutation { post }`,
+		"fragment":     `{ post { ...F } }`,
+		"directive":    `{ post @skip(if: true) }`,
+		"float":        `{ posts(first: 1.5) { totalCount } }`,
+		"list value":   `{ posts(first: [1]) { totalCount } }`,
+		"enum value":   `{ posts(first: ONE) { totalCount } }`,
+		"unterminated": `{ author(address: "x) { address } }`,
+		"unclosed":     `{ author(address: "x") { address }`,
+		"empty set":    `{ }`,
+		"bad char":     `{ post % }`,
+		"const var":    `query ($a: Int = $b) { post }`,
+	}
+	for name, src := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := parse(src)
+			if err == nil {
+				t.Fatal("parse() expected error, got nil")
+			}
+			if !strings.HasPrefix(err.Error(), "syntax error") {
+				t.Errorf("parse() error = %v, want a syntax error", err)
+			}
+		})
+	}
+}
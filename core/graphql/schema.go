@@ -0,0 +1,251 @@
+// Package graphql is a small GraphQL query engine and the schema that exposes
+// the social graph (posts, authors, comment trees and follows) through it.
+//
+// The engine implements the query subset of GraphQL that front ends use in
+// practice (see parse.go) and keeps the repository free of third-party
+// dependencies. Schemas are built from Objects whose fields carry resolver
+// functions; requests are validated against the schema before any resolver
+// runs, and bounded in depth and in the number of fields resolved.
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Built-in scalar type names.
+const (
+	TypeID      = "ID"
+	TypeString  = "String"
+	TypeInt     = "Int"
+	TypeBoolean = "Boolean"
+)
+
+// Execution limits applied when a Schema leaves them zero.
+const (
+	DefaultMaxDepth  = 12
+	DefaultMaxFields = 10000
+)
+
+// ResolveFunc resolves a field. Source is the value of the object the field
+// belongs to (nil for Query fields); Args holds the coerced arguments that
+// were supplied. An Int argument is an int, ID and String arguments are
+// strings and a Boolean argument is a bool; an argument given as null is
+// present with a nil value.
+type ResolveFunc func(p ResolveParams) (interface{}, error)
+
+// ResolveParams are passed to a ResolveFunc.
+type ResolveParams struct {
+	Context context.Context
+	Source  interface{}
+	Args    map[string]interface{}
+}
+
+// Int returns the Int argument name, or def if it was not supplied or null.
+func (p ResolveParams) Int(name string, def int) int {
+	if n, ok := p.Args[name].(int); ok {
+		return n
+	}
+	return def
+}
+
+// String returns the ID or String argument name, or "" if it was not supplied
+// or null.
+func (p ResolveParams) String(name string) string {
+	s, _ := p.Args[name].(string)
+	return s
+}
+
+// Field is a field of an Object.
+type Field struct {
+	// Type is the field's type in GraphQL notation, e.g. "Post", "String!" or
+	// "[Comment!]!". Only one level of list is supported.
+	Type        string
+	Args        map[string]string // Argument name -> type, e.g. "Int" or "ID!"
+	Description string
+	// Resolve computes the field's value. Nil reads the field name from a
+	// map[string]interface{} source.
+	Resolve ResolveFunc
+
+	typ  typeRef
+	args map[string]typeRef
+}
+
+// Object is a GraphQL object type.
+type Object struct {
+	Name        string
+	Description string
+	Fields      map[string]*Field
+}
+
+// Schema is an executable GraphQL schema.
+type Schema struct {
+	query   *Object
+	objects map[string]*Object
+	scalars map[string]bool
+
+	// MaxDepth bounds how deeply selections may nest. Zero means DefaultMaxDepth.
+	MaxDepth int
+	// MaxFields bounds the number of fields resolved by one request. Zero
+	// means DefaultMaxFields.
+	MaxFields int
+}
+
+// NewSchema builds a schema with the given Query type. objects lists every
+// other object type reachable from it; scalars lists custom scalar type
+// names, whose values resolvers return ready to encode as JSON. Arguments
+// must have built-in scalar types.
+func NewSchema(query *Object, objects []*Object, scalars []string) (*Schema, error) {
+	if query == nil {
+		return nil, fmt.Errorf("query type cannot be nil")
+	}
+	s := &Schema{
+		query:   query,
+		objects: map[string]*Object{query.Name: query},
+		scalars: map[string]bool{TypeID: true, TypeString: true, TypeInt: true, TypeBoolean: true},
+	}
+	for _, name := range scalars {
+		s.scalars[name] = true
+	}
+	for _, obj := range objects {
+		if obj == nil || obj.Name == "" {
+			return nil, fmt.Errorf("object types must be named")
+		}
+		if _, dup := s.objects[obj.Name]; dup || s.scalars[obj.Name] {
+			return nil, fmt.Errorf("type %s is defined twice", obj.Name)
+		}
+		s.objects[obj.Name] = obj
+	}
+	for _, obj := range s.objects {
+		if len(obj.Fields) == 0 {
+			return nil, fmt.Errorf("type %s has no fields", obj.Name)
+		}
+		for name, f := range obj.Fields {
+			var err error
+			if f.typ, err = parseTypeRef(f.Type); err != nil {
+				return nil, fmt.Errorf("field %s.%s: %w", obj.Name, name, err)
+			}
+			if !s.isType(f.typ.name) {
+				return nil, fmt.Errorf("field %s.%s has unknown type %s", obj.Name, name, f.typ.name)
+			}
+			f.args = make(map[string]typeRef, len(f.Args))
+			for arg, typ := range f.Args {
+				ref, err := parseTypeRef(typ)
+				if err != nil || ref.list || !builtinScalar(ref.name) {
+					return nil, fmt.Errorf("argument %s.%s(%s) must be a built-in scalar, got %q", obj.Name, name, arg, typ)
+				}
+				f.args[arg] = ref
+			}
+		}
+	}
+	return s, nil
+}
+
+func builtinScalar(name string) bool {
+	return name == TypeID || name == TypeString || name == TypeInt || name == TypeBoolean
+}
+
+func (s *Schema) isType(name string) bool {
+	return s.scalars[name] || s.objects[name] != nil
+}
+
+// SDL renders the schema in the GraphQL schema definition language, for
+// publishing to front-end tooling. Types and fields are sorted by name.
+func (s *Schema) SDL() string {
+	var b strings.Builder
+	var scalars []string
+	for name := range s.scalars {
+		if !builtinScalar(name) {
+			scalars = append(scalars, name)
+		}
+	}
+	sort.Strings(scalars)
+	for _, name := range scalars {
+		fmt.Fprintf(&b, "scalar %s\n\n", name)
+	}
+	var names []string
+	for name := range s.objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		obj := s.objects[name]
+		writeDescription(&b, "", obj.Description)
+		fmt.Fprintf(&b, "type %s {\n", name)
+		var fields []string
+		for f := range obj.Fields {
+			fields = append(fields, f)
+		}
+		sort.Strings(fields)
+		for _, fname := range fields {
+			f := obj.Fields[fname]
+			writeDescription(&b, "  ", f.Description)
+			b.WriteString("  " + fname)
+			if len(f.Args) > 0 {
+				var args []string
+				for arg := range f.Args {
+					args = append(args, arg)
+				}
+				sort.Strings(args)
+				for i, arg := range args {
+					args[i] = arg + ": " + f.Args[arg]
+				}
+				b.WriteString("(" + strings.Join(args, ", ") + ")")
+			}
+			b.WriteString(": " + f.Type + "\n")
+		}
+		b.WriteString("}\n\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func writeDescription(b *strings.Builder, indent, desc string) {
+	if desc != "" {
+		fmt.Fprintf(b, "%s%q\n", indent, desc)
+	}
+}
+
+// typeRef is a parsed field, argument or variable type.
+type typeRef struct {
+	name        string
+	nonNull     bool
+	list        bool
+	elemNonNull bool // For lists: whether elements are non-null
+}
+
+func parseTypeRef(s string) (typeRef, error) {
+	var t typeRef
+	if strings.HasSuffix(s, "!") {
+		t.nonNull = true
+		s = s[:len(s)-1]
+	}
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		t.list = true
+		s = s[1 : len(s)-1]
+		if strings.HasSuffix(s, "!") {
+			t.elemNonNull = true
+			s = s[:len(s)-1]
+		}
+	}
+	if s == "" || strings.ContainsAny(s, "[]! ") {
+		return typeRef{}, fmt.Errorf("invalid type %q", s)
+	}
+	t.name = s
+	return t, nil
+}
+
+func (t typeRef) String() string {
+	s := t.name
+	if t.list {
+		if t.elemNonNull {
+			s += "!"
+		}
+		s = "[" + s + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
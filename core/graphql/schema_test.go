@@ -0,0 +1,67 @@
+package graphql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewSchema_RejectsBadTypes(t *testing.T) {
+	resolve := func(p ResolveParams) (interface{}, error) { return nil, nil }
+	tests := map[string]*Field{
+		"unknown type":       {Type: "Nope", Resolve: resolve},
+		"nested list":        {Type: "[[Int]]", Resolve: resolve},
+		"object argument":    {Type: TypeInt, Args: map[string]string{"q": "Query"}, Resolve: resolve},
+		"list argument":      {Type: TypeInt, Args: map[string]string{"q": "[Int]"}, Resolve: resolve},
+		"malformed argument": {Type: TypeInt, Args: map[string]string{"q": "Int!!"}, Resolve: resolve},
+		"custom scalar arg":  {Type: TypeInt, Args: map[string]string{"q": "Time"}, Resolve: resolve},
+	}
+	for name, field := range tests {
+		t.Run(name, func(t *testing.T) {
+			query := &Object{Name: "Query", Fields: map[string]*Field{"f": field}}
+			if _, err := NewSchema(query, nil, nil); err == nil {
+				t.Error("NewSchema() expected error, got nil")
+			}
+		})
+	}
+	if _, err := NewSchema(nil, nil, nil); err == nil {
+		t.Error("NewSchema(nil) expected error, got nil")
+	}
+	query := &Object{Name: "Query", Fields: map[string]*Field{"f": {Type: TypeInt}}}
+	if _, err := NewSchema(query, []*Object{{Name: "Query", Fields: query.Fields}}, nil); err == nil {
+		t.Error("NewSchema() with a duplicate type expected error, got nil")
+	}
+}
+
+func TestSchema_SDL(t *testing.T) {
+	thing := &Object{Name: "Thing", Description: "A thing.", Fields: map[string]*Field{
+		"id":   {Type: "ID!"},
+		"tags": {Type: "[String!]!", Description: "Sorted tags."},
+		"seen": {Type: "Time"},
+	}}
+	query := &Object{Name: "Query", Fields: map[string]*Field{
+		"thing": {Type: "Thing", Args: map[string]string{"id": "ID!", "at": TypeInt}},
+	}}
+	schema, err := NewSchema(query, []*Object{thing}, []string{"Time"})
+	if err != nil {
+		t.Fatalf("NewSchema() error = %v", err)
+	}
+	want := strings.Join([]string{
+		`scalar Time`,
+		``,
+		`type Query {`,
+		`  thing(at: Int, id: ID!): Thing`,
+		`}`,
+		``,
+		`"A thing."`,
+		`type Thing {`,
+		`  id: ID!`,
+		`  seen: Time`,
+		`  "Sorted tags."`,
+		`  tags: [String!]!`,
+		`}`,
+		``,
+	}, "\n")
+	if got := schema.SDL(); got != want {
+		t.Errorf("SDL() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,469 @@
+package graphql
+
+import (
+	"digisocialblock/core/social"
+	"digisocialblock/core/user"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Page sizes for connection fields.
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Limits of the trendingTags field.
+const (
+	DefaultTrendingTags = 10
+	MaxTrendingTags     = 50
+)
+
+// PostSource looks up indexed posts; social.FeedService implements it.
+type PostSource interface {
+	GetPost(txID string) (social.FeedEntry, bool)
+	GetUserFeed(authorPublicKey string, limit int) []social.FeedEntry
+	GetGlobalFeed(limit int) []social.FeedEntry
+}
+
+// GraphSource looks up comments and follows; social.GraphIndex implements it.
+type GraphSource interface {
+	GetComment(txID string) (social.CommentEntry, bool)
+	GetCommentHistory(txID string) []social.CommentRevision
+	GetComments(postTxID string) []social.CommentEntry
+	GetReplies(commentTxID string) []social.CommentEntry
+	GetFollowers(address string) []social.FollowEdge
+	GetFollowing(address string) []social.FollowEdge
+}
+
+// TrendingSource scores tags by recent activity; social.TrendingIndex
+// implements it.
+type TrendingSource interface {
+	GetTrendingTags(window time.Duration) []social.TrendingTag
+}
+
+// ProfileSource returns the current profile of an address. A nil profile or
+// an error leaves the author's profile fields null.
+type ProfileSource interface {
+	GetProfile(address string) (*user.Profile, error)
+}
+
+// author is the source value of an Author object.
+type author struct{ address string }
+
+type socialResolvers struct {
+	posts    PostSource
+	graph    GraphSource
+	profiles ProfileSource  // Optional
+	trending TrendingSource // Optional
+}
+
+// NewSocialSchema builds the social graph schema:
+//
+//	post(id), comment(id)     a post or comment by transaction ID
+//	posts(first, after, author, tag, language)
+//	author(address)           an address with its profile, posts and follows
+//
+// List fields are cursor-paginated connections ({nodes, pageInfo, totalCount});
+// pass pageInfo.endCursor as after to fetch the next page. Post lists are
+// pinned to a block height while paging, and report the posts that landed
+// since in newItems. Only public posts are served: group posts are left out
+// of lists and resolve to null. profiles may be nil.
+func NewSocialSchema(posts PostSource, graph GraphSource, profiles ProfileSource) (*Schema, error) {
+	return NewSocialSchemaWithTrending(posts, graph, profiles, nil)
+}
+
+// NewSocialSchemaWithTrending is NewSocialSchema with the discovery field
+//
+//	trendingTags(windowHours, first)  tags by recent activity, each with its posts
+//
+// served from trending. A nil trending leaves the field out.
+func NewSocialSchemaWithTrending(posts PostSource, graph GraphSource, profiles ProfileSource, trending TrendingSource) (*Schema, error) {
+	if posts == nil {
+		return nil, fmt.Errorf("post source cannot be nil")
+	}
+	if graph == nil {
+		return nil, fmt.Errorf("graph source cannot be nil")
+	}
+	r := &socialResolvers{posts: posts, graph: graph, profiles: profiles, trending: trending}
+	page := map[string]string{"first": TypeInt, "after": TypeString}
+	withTag := map[string]string{"first": TypeInt, "after": TypeString, "tag": TypeString, "language": TypeString}
+
+	query := &Object{Name: "Query", Fields: map[string]*Field{
+		"post":    {Type: "Post", Args: map[string]string{"id": "ID!"}, Resolve: r.post},
+		"comment": {Type: "Comment", Args: map[string]string{"id": "ID!"}, Resolve: r.comment},
+		"posts": {Type: "PostConnection!", Resolve: r.allPosts, Description: "Public posts, newest first.",
+			Args: map[string]string{"first": TypeInt, "after": TypeString, "author": TypeID, "tag": TypeString, "language": TypeString}},
+		"author": {Type: "Author!", Args: map[string]string{"address": "ID!"}, Resolve: r.author},
+	}}
+	post := &Object{Name: "Post", Fields: map[string]*Field{
+		"id":             {Type: "ID!", Resolve: postField(func(e social.FeedEntry) interface{} { return e.TxID })},
+		"author":         {Type: "Author!", Resolve: postField(func(e social.FeedEntry) interface{} { return author{e.AuthorPublicKey} })},
+		"coAuthors":      {Type: "[Author!]!", Resolve: postField(coAuthors), Description: "Co-authors who co-signed the post."},
+		"contentCID":     {Type: TypeString, Resolve: postField(func(e social.FeedEntry) interface{} { return optional(e.ContentCID) })},
+		"title":          {Type: TypeString, Resolve: postField(func(e social.FeedEntry) interface{} { return optional(e.Title) })},
+		"tags":           {Type: "[String!]!", Resolve: postField(func(e social.FeedEntry) interface{} { return e.Tags })},
+		"contentWarning": {Type: TypeString, Resolve: postField(func(e social.FeedEntry) interface{} { return optional(e.ContentWarning) })},
+		"sensitive":      {Type: "Boolean!", Resolve: postField(func(e social.FeedEntry) interface{} { return e.Sensitive })},
+		"language":       {Type: TypeString, Resolve: postField(func(e social.FeedEntry) interface{} { return optional(e.Language) }), Description: "BCP 47 language tag, if known."},
+		"timestamp":      {Type: "Time!", Resolve: postField(func(e social.FeedEntry) interface{} { return formatTime(e.Timestamp) })},
+		"blockIndex":     {Type: "Int!", Resolve: postField(func(e social.FeedEntry) interface{} { return e.BlockIndex })},
+		"comments":       {Type: "CommentConnection!", Args: page, Resolve: r.postComments, Description: "Top-level comments, oldest first."},
+	}}
+	comment := &Object{Name: "Comment", Fields: map[string]*Field{
+		"id":         {Type: "ID!", Resolve: commentField(func(c social.CommentEntry) interface{} { return c.TxID })},
+		"author":     {Type: "Author!", Resolve: commentField(func(c social.CommentEntry) interface{} { return author{c.AuthorPublicKey} })},
+		"contentCID": {Type: "String!", Resolve: commentField(func(c social.CommentEntry) interface{} { return c.ContentCID }), Description: "Latest version; empty once deleted."},
+		"timestamp":  {Type: "Time!", Resolve: commentField(func(c social.CommentEntry) interface{} { return formatTime(c.Timestamp) })},
+		"blockIndex": {Type: "Int!", Resolve: commentField(func(c social.CommentEntry) interface{} { return c.BlockIndex })},
+		"version":    {Type: "Int!", Resolve: commentField(func(c social.CommentEntry) interface{} { return c.Version })},
+		"editedAt": {Type: "Time", Resolve: commentField(func(c social.CommentEntry) interface{} {
+			if c.EditedAt == 0 {
+				return nil
+			}
+			return formatTime(c.EditedAt)
+		})},
+		"deleted": {Type: "Boolean!", Resolve: commentField(func(c social.CommentEntry) interface{} { return c.Deleted })},
+		"history": {Type: "[CommentRevision!]!", Resolve: r.commentHistory, Description: "Every version, oldest first; empty once deleted."},
+		"post":    {Type: "Post", Resolve: r.commentPost},
+		"parent":  {Type: "Comment", Resolve: r.commentParent, Description: "The comment this replies to; null for top-level comments."},
+		"replies": {Type: "CommentConnection!", Args: page, Resolve: r.commentReplies, Description: "Direct replies, oldest first."},
+	}}
+	authorObj := &Object{Name: "Author", Fields: map[string]*Field{
+		"address":     {Type: "ID!", Resolve: func(p ResolveParams) (interface{}, error) { return p.Source.(author).address, nil }},
+		"displayName": {Type: TypeString, Resolve: r.profileField(func(pr *user.Profile) string { return pr.DisplayName })},
+		"bio":         {Type: TypeString, Resolve: r.profileField(func(pr *user.Profile) string { return pr.Bio })},
+		"posts":       {Type: "PostConnection!", Args: withTag, Resolve: r.authorPosts, Description: "Public posts, newest first."},
+		"followers":   {Type: "FollowConnection!", Args: page, Resolve: r.followers, Description: "Follows of this author, oldest first."},
+		"following":   {Type: "FollowConnection!", Args: page, Resolve: r.following, Description: "Follows made by this author, oldest first."},
+	}}
+	follow := &Object{Name: "Follow", Fields: map[string]*Field{
+		"id":        {Type: "ID!", Resolve: followField(func(f social.FollowEdge) interface{} { return f.TxID })},
+		"follower":  {Type: "Author!", Resolve: followField(func(f social.FollowEdge) interface{} { return author{f.Follower} })},
+		"followee":  {Type: "Author!", Resolve: followField(func(f social.FollowEdge) interface{} { return author{f.Followee} })},
+		"timestamp": {Type: "Time!", Resolve: followField(func(f social.FollowEdge) interface{} { return formatTime(f.Timestamp) })},
+	}}
+	revision := &Object{Name: "CommentRevision", Fields: map[string]*Field{
+		"id":         {Type: "ID!", Resolve: revisionField(func(r social.CommentRevision) interface{} { return r.TxID })},
+		"version":    {Type: "Int!", Resolve: revisionField(func(r social.CommentRevision) interface{} { return r.Version })},
+		"contentCID": {Type: "String!", Resolve: revisionField(func(r social.CommentRevision) interface{} { return r.ContentCID })},
+		"timestamp":  {Type: "Time!", Resolve: revisionField(func(r social.CommentRevision) interface{} { return formatTime(r.Timestamp) })},
+		"blockIndex": {Type: "Int!", Resolve: revisionField(func(r social.CommentRevision) interface{} { return r.BlockIndex })},
+	}}
+	pageInfo := &Object{Name: "PageInfo", Fields: map[string]*Field{
+		"endCursor":   {Type: TypeString, Description: "Pass as after to fetch the next page."},
+		"hasNextPage": {Type: "Boolean!"},
+	}}
+	objects := []*Object{post, comment, revision, authorObj, follow, pageInfo}
+	scalars := []string{"Time"}
+	if trending != nil {
+		query.Fields["trendingTags"] = &Field{Type: "[TrendingTag!]!", Resolve: r.trendingTags,
+			Args:        map[string]string{"windowHours": TypeInt, "first": TypeInt},
+			Description: "Tags by time-decayed activity over the last windowHours (default 24), highest score first. For discovery."}
+		objects = append(objects, &Object{Name: "TrendingTag", Fields: map[string]*Field{
+			"tag":       {Type: "String!", Resolve: trendingField(func(t social.TrendingTag) interface{} { return t.Tag })},
+			"score":     {Type: "Float!", Resolve: trendingField(func(t social.TrendingTag) interface{} { return t.Score })},
+			"postCount": {Type: "Int!", Resolve: trendingField(func(t social.TrendingTag) interface{} { return t.Posts }), Description: "New posts with the tag in the window."},
+			"posts":     {Type: "PostConnection!", Args: page, Resolve: r.trendingPosts, Description: "Public posts with the tag, newest first."},
+		}})
+		scalars = append(scalars, "Float")
+	}
+	for _, node := range []string{"Post", "Comment", "Follow"} {
+		conn := &Object{Name: node + "Connection", Fields: map[string]*Field{
+			"nodes":      {Type: "[" + node + "!]!"},
+			"pageInfo":   {Type: "PageInfo!"},
+			"totalCount": {Type: "Int!"},
+		}}
+		if node == "Post" {
+			conn.Fields["snapshot"] = &Field{Type: "Int!", Description: "Block height the list is pinned to while paging with after."}
+			conn.Fields["newItems"] = &Field{Type: "Int!", Description: "Posts in blocks above the snapshot; reload without after to see them."}
+		}
+		objects = append(objects, conn)
+	}
+	return NewSchema(query, objects, scalars)
+}
+
+func postField(get func(social.FeedEntry) interface{}) ResolveFunc {
+	return func(p ResolveParams) (interface{}, error) { return get(p.Source.(social.FeedEntry)), nil }
+}
+
+// coAuthors returns a post's co-authors as Author sources.
+func coAuthors(e social.FeedEntry) interface{} {
+	authors := make([]author, len(e.CoAuthors))
+	for i, address := range e.CoAuthors {
+		authors[i] = author{address}
+	}
+	return authors
+}
+
+func commentField(get func(social.CommentEntry) interface{}) ResolveFunc {
+	return func(p ResolveParams) (interface{}, error) { return get(p.Source.(social.CommentEntry)), nil }
+}
+
+func revisionField(get func(social.CommentRevision) interface{}) ResolveFunc {
+	return func(p ResolveParams) (interface{}, error) { return get(p.Source.(social.CommentRevision)), nil }
+}
+
+func trendingField(get func(social.TrendingTag) interface{}) ResolveFunc {
+	return func(p ResolveParams) (interface{}, error) { return get(p.Source.(social.TrendingTag)), nil }
+}
+
+func followField(get func(social.FollowEdge) interface{}) ResolveFunc {
+	return func(p ResolveParams) (interface{}, error) { return get(p.Source.(social.FollowEdge)), nil }
+}
+
+func (r *socialResolvers) post(p ResolveParams) (interface{}, error) {
+	return r.publicPost(p.String("id")), nil
+}
+
+// publicPost returns the post, or nil if it is unknown or a group post.
+func (r *socialResolvers) publicPost(txID string) interface{} {
+	entry, ok := r.posts.GetPost(txID)
+	if !ok || entry.GroupID != "" {
+		return nil
+	}
+	return entry
+}
+
+func (r *socialResolvers) comment(p ResolveParams) (interface{}, error) {
+	if c, ok := r.graph.GetComment(p.String("id")); ok {
+		return c, nil
+	}
+	return nil, nil
+}
+
+func (r *socialResolvers) allPosts(p ResolveParams) (interface{}, error) {
+	if address := p.String("author"); address != "" {
+		return r.postConnection(p, r.posts.GetUserFeed(address, 0), p.String("tag"))
+	}
+	return r.postConnection(p, r.posts.GetGlobalFeed(0), p.String("tag"))
+}
+
+func (r *socialResolvers) author(p ResolveParams) (interface{}, error) {
+	return author{p.String("address")}, nil
+}
+
+func (r *socialResolvers) authorPosts(p ResolveParams) (interface{}, error) {
+	return r.postConnection(p, r.posts.GetUserFeed(p.Source.(author).address, 0), p.String("tag"))
+}
+
+// postConnection pages through the public posts in entries that carry tag,
+// if one is given, and whose language falls under the language argument, if
+// there is one (see social.LanguageMatches). The list is pinned to a snapshot: the first page to the
+// newest post's block, and later pages to the block their cursor carries, so
+// posts in newer blocks do not change totalCount or hasNextPage while a
+// client pages. They are counted in newItems instead.
+func (r *socialResolvers) postConnection(p ResolveParams, entries []social.FeedEntry, tag string) (interface{}, error) {
+	snapshot, pinned := postCursorSnapshot(p.String("after"))
+	language := p.String("language")
+	public := make([]social.FeedEntry, 0, len(entries))
+	newItems := 0
+	for _, e := range entries {
+		if e.GroupID != "" || (tag != "" && !hasTag(e.Tags, tag)) {
+			continue
+		}
+		if language != "" && !social.LanguageMatches(e.Language, []string{language}) {
+			continue
+		}
+		if pinned && e.BlockIndex > snapshot {
+			newItems++
+			continue
+		}
+		public = append(public, e)
+		if !pinned && e.BlockIndex > snapshot {
+			snapshot = e.BlockIndex
+		}
+	}
+	id := func(i int) string { return public[i].TxID + "@" + strconv.FormatInt(snapshot, 10) }
+	start, end, err := pageBounds(p, len(public), id)
+	if err != nil {
+		return nil, err
+	}
+	conn := connection(public[start:end], end, len(public), id)
+	conn["snapshot"] = snapshot
+	conn["newItems"] = newItems
+	return conn, nil
+}
+
+// postCursorSnapshot returns the snapshot height a post cursor carries.
+// Malformed cursors are left for pageBounds to reject.
+func postCursorSnapshot(after string) (int64, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(after)
+	if after == "" || err != nil {
+		return 0, false
+	}
+	_, height, ok := strings.Cut(string(raw), "@")
+	snapshot, err := strconv.ParseInt(height, 10, 64)
+	if !ok || err != nil {
+		return 0, false
+	}
+	return snapshot, true
+}
+
+func (r *socialResolvers) trendingTags(p ResolveParams) (interface{}, error) {
+	hours := p.Int("windowHours", int(social.DefaultTrendingWindow/time.Hour))
+	if hours < 1 || time.Duration(hours)*time.Hour > social.MaxTrendingWindow {
+		return nil, fmt.Errorf("windowHours must be between 1 and %d", int(social.MaxTrendingWindow/time.Hour))
+	}
+	first := p.Int("first", DefaultTrendingTags)
+	if first < 0 || first > MaxTrendingTags {
+		return nil, fmt.Errorf("first must be between 0 and %d", MaxTrendingTags)
+	}
+	tags := r.trending.GetTrendingTags(time.Duration(hours) * time.Hour)
+	if len(tags) > first {
+		tags = tags[:first]
+	}
+	if tags == nil {
+		tags = []social.TrendingTag{}
+	}
+	return tags, nil
+}
+
+func (r *socialResolvers) trendingPosts(p ResolveParams) (interface{}, error) {
+	return r.postConnection(p, r.posts.GetGlobalFeed(0), p.Source.(social.TrendingTag).Tag)
+}
+
+func (r *socialResolvers) postComments(p ResolveParams) (interface{}, error) {
+	return commentConnection(p, r.graph.GetComments(p.Source.(social.FeedEntry).TxID))
+}
+
+func (r *socialResolvers) commentReplies(p ResolveParams) (interface{}, error) {
+	return commentConnection(p, r.graph.GetReplies(p.Source.(social.CommentEntry).TxID))
+}
+
+func commentConnection(p ResolveParams, comments []social.CommentEntry) (interface{}, error) {
+	id := func(i int) string { return comments[i].TxID }
+	start, end, err := pageBounds(p, len(comments), id)
+	if err != nil {
+		return nil, err
+	}
+	return connection(comments[start:end], end, len(comments), id), nil
+}
+
+func (r *socialResolvers) commentHistory(p ResolveParams) (interface{}, error) {
+	history := r.graph.GetCommentHistory(p.Source.(social.CommentEntry).TxID)
+	if history == nil {
+		history = []social.CommentRevision{}
+	}
+	return history, nil
+}
+
+func (r *socialResolvers) commentPost(p ResolveParams) (interface{}, error) {
+	return r.publicPost(p.Source.(social.CommentEntry).PostTxID), nil
+}
+
+func (r *socialResolvers) commentParent(p ResolveParams) (interface{}, error) {
+	parentTxID := p.Source.(social.CommentEntry).ParentTxID
+	if parentTxID == "" {
+		return nil, nil
+	}
+	if parent, ok := r.graph.GetComment(parentTxID); ok {
+		return parent, nil
+	}
+	return nil, nil
+}
+
+func (r *socialResolvers) followers(p ResolveParams) (interface{}, error) {
+	return followConnection(p, r.graph.GetFollowers(p.Source.(author).address))
+}
+
+func (r *socialResolvers) following(p ResolveParams) (interface{}, error) {
+	return followConnection(p, r.graph.GetFollowing(p.Source.(author).address))
+}
+
+func followConnection(p ResolveParams, edges []social.FollowEdge) (interface{}, error) {
+	id := func(i int) string { return edges[i].TxID }
+	start, end, err := pageBounds(p, len(edges), id)
+	if err != nil {
+		return nil, err
+	}
+	return connection(edges[start:end], end, len(edges), id), nil
+}
+
+func (r *socialResolvers) profileField(get func(*user.Profile) string) ResolveFunc {
+	return func(p ResolveParams) (interface{}, error) {
+		if r.profiles == nil {
+			return nil, nil
+		}
+		profile, err := r.profiles.GetProfile(p.Source.(author).address)
+		if err != nil || profile == nil {
+			return nil, nil
+		}
+		return optional(get(profile)), nil
+	}
+}
+
+// pageBounds returns the [start, end) range of a list of n items selected by
+// the first and after arguments. id returns the transaction ID of item i,
+// which cursors encode.
+func pageBounds(p ResolveParams, n int, id func(i int) string) (int, int, error) {
+	first := p.Int("first", DefaultPageSize)
+	if first < 0 || first > MaxPageSize {
+		return 0, 0, fmt.Errorf("first must be between 0 and %d", MaxPageSize)
+	}
+	start := 0
+	if after := p.String("after"); after != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(after)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid cursor %q", after)
+		}
+		start = -1
+		for i := 0; i < n; i++ {
+			if id(i) == string(raw) {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			return 0, 0, fmt.Errorf("cursor %q is not in this list", after)
+		}
+	}
+	end := start + first
+	if end > n {
+		end = n
+	}
+	return start, end, nil
+}
+
+// connection builds a connection object for the page nodes, which ends
+// before item end of a list of total items.
+func connection(nodes interface{}, end, total int, id func(i int) string) map[string]interface{} {
+	var endCursor interface{}
+	if end > 0 {
+		endCursor = base64.RawURLEncoding.EncodeToString([]byte(id(end - 1)))
+	}
+	return map[string]interface{}{
+		"nodes":      nodes,
+		"totalCount": total,
+		"pageInfo": map[string]interface{}{
+			"endCursor":   endCursor,
+			"hasNextPage": end < total,
+		},
+	}
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// optional maps "" to null.
+func optional(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// formatTime renders a UnixNano timestamp as a Time scalar (RFC 3339 in UTC).
+// GraphQL Ints are 32-bit, too small for nanosecond timestamps.
+func formatTime(unixNano int64) string {
+	return time.Unix(0, unixNano).UTC().Format(time.RFC3339Nano)
+}
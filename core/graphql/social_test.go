@@ -0,0 +1,315 @@
+package graphql
+
+import (
+	"context"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"digisocialblock/core/user"
+	"digisocialblock/internal/testutil/fixture"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type socialTestProfiles map[string]*user.Profile
+
+func (p socialTestProfiles) GetProfile(address string) (*user.Profile, error) {
+	return p[address], nil
+}
+
+// socialTestGraph is the fixture chain: alice posts three public posts and a
+// group post, bob comments on the first and alice replies, and bob and carol
+// follow alice.
+type socialTestGraph struct {
+	schema             *Schema
+	alice, bob, carol  *identity.Wallet
+	posts              []*ledger.Transaction // Public posts, oldest first
+	groupPost          *ledger.Transaction
+	comment, reply     *ledger.Transaction
+	bobFollow, cFollow *ledger.Transaction
+	chain              *ledger.Blockchain
+	feed               *social.FeedService
+}
+
+func newSocialTestGraph(t *testing.T) *socialTestGraph {
+	t.Helper()
+	g := &socialTestGraph{}
+	g.alice, _ = identity.NewWallet()
+	g.bob, _ = identity.NewWallet()
+	g.carol, _ = identity.NewWallet()
+	for i, tag := range []string{"go", "news", "go"} {
+		post := social.NewPost(g.alice.Address, "cid-post", "", []string{tag})
+		post.Timestamp = int64(i + 1)
+		g.posts = append(g.posts, fixture.Tx(t, g.alice, ledger.PostCreated, post))
+	}
+	groupPost := social.NewPost(g.alice.Address, "cid-secret", "", nil)
+	groupPost.GroupID, groupPost.KeyEpoch = "club", 1
+	g.groupPost = fixture.Tx(t, g.alice, ledger.PostCreated, groupPost)
+	g.comment = fixture.Tx(t, g.bob, ledger.CommentAdded, &social.Comment{
+		AuthorPublicKey: g.bob.Address, PostTxID: g.posts[0].ID, ContentCID: "cid-comment", Timestamp: 1700000000000000000})
+	g.bobFollow = fixture.Tx(t, g.bob, ledger.UserFollowed, &social.Follow{FolloweePublicKey: g.alice.Address, Timestamp: 1})
+	g.cFollow = fixture.Tx(t, g.carol, ledger.UserFollowed, &social.Follow{FolloweePublicKey: g.alice.Address, Timestamp: 2})
+
+	bc, _ := ledger.NewBlockchain()
+	if _, err := bc.AddBlock(append(g.posts, g.groupPost, g.comment, g.bobFollow, g.cFollow)); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+	g.reply = fixture.Tx(t, g.alice, ledger.CommentAdded, &social.Comment{
+		AuthorPublicKey: g.alice.Address, PostTxID: g.posts[0].ID, ParentTxID: g.comment.ID, ContentCID: "cid-reply", Timestamp: 2})
+	if _, err := bc.AddBlock([]*ledger.Transaction{g.reply}); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+
+	feed, _ := social.NewFeedService(nil)
+	g.chain, g.feed = bc, feed
+	graph := social.NewGraphIndex()
+	if _, err := feed.Sync(bc); err != nil {
+		t.Fatalf("FeedService.Sync() error = %v", err)
+	}
+	if _, err := graph.Sync(bc); err != nil {
+		t.Fatalf("GraphIndex.Sync() error = %v", err)
+	}
+	trending := social.NewTrendingIndex()
+	if _, err := trending.Sync(bc); err != nil {
+		t.Fatalf("TrendingIndex.Sync() error = %v", err)
+	}
+	profiles := socialTestProfiles{g.alice.Address: user.NewProfile(g.alice.Address, "Alice", "")}
+	var err error
+	if g.schema, err = NewSocialSchemaWithTrending(feed, graph, profiles, trending); err != nil {
+		t.Fatalf("NewSocialSchemaWithTrending() error = %v", err)
+	}
+	return g
+}
+
+// query runs a query that must succeed and decodes its data.
+func (g *socialTestGraph) query(t *testing.T, query string, vars map[string]interface{}, out interface{}) {
+	t.Helper()
+	resp := g.schema.Execute(context.Background(), Request{Query: query, Variables: vars})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("Execute() errors = %v", resp.Errors)
+	}
+	data, _ := json.Marshal(resp.Data)
+	if err := json.Unmarshal(data, out); err != nil {
+		t.Fatalf("decoding %s: %v", data, err)
+	}
+}
+
+func TestSocialSchema_NestedResolution(t *testing.T) {
+	g := newSocialTestGraph(t)
+	var got struct {
+		Post struct {
+			Author   struct{ DisplayName string }
+			Comments struct {
+				TotalCount int
+				Nodes      []struct {
+					ID        string
+					Timestamp string
+					Version   int
+					Deleted   bool
+					History   []struct{ ContentCID string }
+					Author    struct{ Address string }
+					Replies   struct {
+						Nodes []struct {
+							ContentCID string
+							Parent     struct{ ID string }
+							Post       struct{ ID string }
+						}
+					}
+				}
+			}
+		}
+		Author struct {
+			DisplayName *string
+			Following   struct {
+				Nodes []struct{ Followee struct{ Address string } }
+			}
+		}
+		Secret *struct{ ID string }
+	}
+	g.query(t, `query ($id: ID!, $bob: ID!, $group: ID!) {
+		post(id: $id) {
+			author { displayName }
+			comments { totalCount nodes { id timestamp version deleted history { contentCID } author { address } replies { nodes { contentCID parent { id } post { id } } } } }
+		}
+		author(address: $bob) { displayName following { nodes { followee { address } } } }
+		secret: post(id: $group) { id }
+	}`, map[string]interface{}{"id": g.posts[0].ID, "bob": g.bob.Address, "group": g.groupPost.ID}, &got)
+
+	if got.Post.Author.DisplayName != "Alice" {
+		t.Errorf("post author displayName = %q, want Alice", got.Post.Author.DisplayName)
+	}
+	comments := got.Post.Comments
+	if comments.TotalCount != 1 || len(comments.Nodes) != 1 || comments.Nodes[0].ID != g.comment.ID {
+		t.Fatalf("post comments = %+v, want bob's comment", comments)
+	}
+	if c := comments.Nodes[0]; c.Author.Address != g.bob.Address || c.Timestamp != "2023-11-14T22:13:20Z" {
+		t.Errorf("comment = %+v, want bob's at 2023-11-14T22:13:20Z", c)
+	}
+	if c := comments.Nodes[0]; c.Version != 1 || c.Deleted || len(c.History) != 1 || c.History[0].ContentCID != "cid-comment" {
+		t.Errorf("comment = %+v, want an unedited version 1 with its original revision", c)
+	}
+	replies := comments.Nodes[0].Replies.Nodes
+	if len(replies) != 1 || replies[0].ContentCID != "cid-reply" || replies[0].Parent.ID != g.comment.ID || replies[0].Post.ID != g.posts[0].ID {
+		t.Errorf("replies = %+v, want alice's reply linked to its parent and post", replies)
+	}
+	if got.Author.DisplayName != nil {
+		t.Errorf("bob's displayName = %q, want null without a profile", *got.Author.DisplayName)
+	}
+	if f := got.Author.Following.Nodes; len(f) != 1 || f[0].Followee.Address != g.alice.Address {
+		t.Errorf("bob following = %+v, want alice", f)
+	}
+	if got.Secret != nil {
+		t.Errorf("group post resolved to %+v, want null", got.Secret)
+	}
+}
+
+func TestSocialSchema_CursorPagination(t *testing.T) {
+	g := newSocialTestGraph(t)
+	type page struct {
+		Posts struct {
+			TotalCount int
+			Nodes      []struct{ ID string }
+			PageInfo   struct {
+				EndCursor   *string
+				HasNextPage bool
+			}
+		}
+	}
+	const q = `query ($after: String, $tag: String) {
+		posts(first: 2, after: $after, tag: $tag) { totalCount nodes { id } pageInfo { endCursor hasNextPage } }
+	}`
+
+	var first, second page
+	g.query(t, q, nil, &first)
+	if first.Posts.TotalCount != 3 || len(first.Posts.Nodes) != 2 || !first.Posts.PageInfo.HasNextPage {
+		t.Fatalf("first page = %+v, want 2 of 3 public posts", first.Posts)
+	}
+	if first.Posts.Nodes[0].ID != g.posts[2].ID {
+		t.Errorf("first post = %s, want the newest", first.Posts.Nodes[0].ID)
+	}
+	g.query(t, q, map[string]interface{}{"after": *first.Posts.PageInfo.EndCursor}, &second)
+	if len(second.Posts.Nodes) != 1 || second.Posts.Nodes[0].ID != g.posts[0].ID || second.Posts.PageInfo.HasNextPage {
+		t.Errorf("second page = %+v, want the oldest post and no next page", second.Posts)
+	}
+
+	var tagged page
+	g.query(t, q, map[string]interface{}{"tag": "go"}, &tagged)
+	if tagged.Posts.TotalCount != 2 || tagged.Posts.Nodes[1].ID != g.posts[0].ID {
+		t.Errorf("posts tagged go = %+v, want the two go posts", tagged.Posts)
+	}
+
+	var followers struct {
+		Author struct {
+			Followers struct {
+				Nodes    []struct{ Follower struct{ Address string } }
+				PageInfo struct{ HasNextPage bool }
+			}
+		}
+	}
+	g.query(t, `query ($a: ID!) { author(address: $a) { followers(first: 1) { nodes { follower { address } } pageInfo { hasNextPage } } } }`,
+		map[string]interface{}{"a": g.alice.Address}, &followers)
+	if f := followers.Author.Followers; len(f.Nodes) != 1 || f.Nodes[0].Follower.Address != g.bob.Address || !f.PageInfo.HasNextPage {
+		t.Errorf("alice's first follower page = %+v, want bob with more to come", f)
+	}
+
+	for _, bad := range []string{
+		`{ posts(first: 101) { totalCount } }`,
+		`{ posts(after: "!!") { totalCount } }`,
+		`{ posts(after: "bm9wZQ") { totalCount } }`, // "nope"
+	} {
+		resp := g.schema.Execute(context.Background(), Request{Query: bad})
+		if len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "first") && !strings.Contains(resp.Errors[0].Message, "cursor") {
+			t.Errorf("Execute(%s) errors = %v, want a paging error", bad, resp.Errors)
+		}
+	}
+}
+
+func TestSocialSchema_PostPagesStayOnTheirSnapshot(t *testing.T) {
+	g := newSocialTestGraph(t)
+	type page struct {
+		Posts struct {
+			TotalCount int
+			Snapshot   int64
+			NewItems   int
+			Nodes      []struct{ ID string }
+			PageInfo   struct{ EndCursor *string }
+		}
+	}
+	const q = `query ($after: String) { posts(first: 2, after: $after) { totalCount snapshot newItems nodes { id } pageInfo { endCursor } } }`
+
+	var first, second page
+	g.query(t, q, nil, &first)
+	if first.Posts.Snapshot != 1 || first.Posts.NewItems != 0 {
+		t.Fatalf("first page = %+v, want it pinned to block 1", first.Posts)
+	}
+	newer := fixture.Tx(t, g.alice, ledger.PostCreated, social.NewPost(g.alice.Address, "cid-new", "", nil))
+	if _, err := g.chain.AddBlock([]*ledger.Transaction{newer}); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+	if _, err := g.feed.Sync(g.chain); err != nil {
+		t.Fatalf("FeedService.Sync() error = %v", err)
+	}
+
+	g.query(t, q, map[string]interface{}{"after": *first.Posts.PageInfo.EndCursor}, &second)
+	if p := second.Posts; p.Snapshot != 1 || p.TotalCount != 3 || p.NewItems != 1 || len(p.Nodes) != 1 || p.Nodes[0].ID != g.posts[0].ID {
+		t.Errorf("second page = %+v, want the oldest post with 1 new item signalled", p)
+	}
+	var reloaded page
+	g.query(t, q, nil, &reloaded)
+	if p := reloaded.Posts; p.Snapshot != 3 || p.TotalCount != 4 || p.Nodes[0].ID != newer.ID {
+		t.Errorf("reloaded first page = %+v, want the new post first", p)
+	}
+}
+
+func TestSocialSchema_PostsFilterByLanguage(t *testing.T) {
+	g := newSocialTestGraph(t)
+	var txs []*ledger.Transaction
+	for _, language := range []string{"en-GB", "pt"} {
+		post := social.NewPost(g.alice.Address, "cid-"+language, "", nil)
+		post.Language = language
+		txs = append(txs, fixture.Tx(t, g.alice, ledger.PostCreated, post))
+	}
+	if _, err := g.chain.AddBlock(txs); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+	if _, err := g.feed.Sync(g.chain); err != nil {
+		t.Fatalf("FeedService.Sync() error = %v", err)
+	}
+
+	var got struct {
+		Posts struct {
+			TotalCount int
+			Nodes      []struct{ ID, Language string }
+		}
+	}
+	g.query(t, `{ posts(language: "en") { totalCount nodes { id language } } }`, nil, &got)
+	if p := got.Posts; p.TotalCount != 1 || p.Nodes[0].ID != txs[0].ID || p.Nodes[0].Language != "en-GB" {
+		t.Errorf("posts(language: en) = %+v, want only the en-GB post", p)
+	}
+}
+
+func TestSocialSchema_TrendingTags(t *testing.T) {
+	g := newSocialTestGraph(t)
+	var got struct {
+		TrendingTags []struct {
+			Tag       string
+			Score     float64
+			PostCount int
+			Posts     struct{ Nodes []struct{ ID string } }
+		}
+	}
+	g.query(t, `{ trendingTags(first: 5) { tag score postCount posts(first: 1) { nodes { id } } } }`, nil, &got)
+	tags := got.TrendingTags
+	if len(tags) != 2 || tags[0].Tag != "go" || tags[0].PostCount != 2 || tags[1].Tag != "news" || tags[0].Score <= tags[1].Score {
+		t.Fatalf("trendingTags = %+v, want go (2 posts) above news", tags)
+	}
+	if nodes := tags[0].Posts.Nodes; len(nodes) != 1 || nodes[0].ID != g.posts[2].ID {
+		t.Errorf("go posts = %+v, want the newest go post", nodes)
+	}
+
+	resp := g.schema.Execute(context.Background(), Request{Query: `{ trendingTags(windowHours: 0) { tag } }`})
+	if len(resp.Errors) == 0 {
+		t.Error("trendingTags(windowHours: 0) succeeded, want an error")
+	}
+}
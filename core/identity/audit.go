@@ -0,0 +1,171 @@
+package identity
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrAuditLogTampered is returned when the audit log's hash chain is broken.
+var ErrAuditLogTampered = errors.New("signature audit log has been tampered with")
+
+// AuditEntry records one signing operation performed by a wallet.
+type AuditEntry struct {
+	Seq         uint64    `json:"seq"`
+	Time        time.Time `json:"time"`
+	Signer      string    `json:"signer"`      // Wallet address
+	Component   string    `json:"component"`   // Which part of the application asked for the signature
+	Description string    `json:"description"` // What was signed, e.g. "transaction <id> (PostCreated)"
+	Digest      string    `json:"digest"`      // Hex of the exact hash that was signed
+	PrevHash    string    `json:"prevHash"`    // Hash of the previous entry; empty for the first
+	Hash        string    `json:"hash"`        // Hash of this entry with Hash itself empty
+}
+
+// computeHash returns the chain hash of the entry.
+func (e AuditEntry) computeHash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AuditLog is an append-only, hash-chained local log of signing operations.
+// Each entry commits to its predecessor, so editing, reordering or deleting an
+// entry in the middle of the file is detected by Verify. Truncating the tail is
+// not detectable from the file alone; record Head elsewhere to guard against that.
+// An AuditLog is safe for concurrent use.
+type AuditLog struct {
+	mu       sync.Mutex
+	path     string
+	lastSeq  uint64
+	lastHash string
+}
+
+// OpenAuditLog opens (creating if needed) the audit log at path and verifies
+// its existing entries.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	if path == "" {
+		return nil, fmt.Errorf("audit log path cannot be empty")
+	}
+	al := &AuditLog{path: path}
+	entries, err := al.readAll()
+	if err != nil {
+		return nil, err
+	}
+	if n := len(entries); n > 0 {
+		al.lastSeq, al.lastHash = entries[n-1].Seq, entries[n-1].Hash
+	}
+	return al, nil
+}
+
+// Head returns the sequence number and hash of the newest entry.
+func (al *AuditLog) Head() (uint64, string) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	return al.lastSeq, al.lastHash
+}
+
+// record appends an entry for a signature over digest and syncs it to disk.
+func (al *AuditLog) record(signer, component, description string, digest []byte) error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	entry := AuditEntry{
+		Seq:         al.lastSeq + 1,
+		Time:        time.Now().UTC(),
+		Signer:      signer,
+		Component:   component,
+		Description: description,
+		Digest:      hex.EncodeToString(digest),
+		PrevHash:    al.lastHash,
+	}
+	hash, err := entry.computeHash()
+	if err != nil {
+		return fmt.Errorf("failed to hash audit entry: %w", err)
+	}
+	entry.Hash = hash
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	f, err := os.OpenFile(al.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log %s: %w", al.path, err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to append to audit log %s: %w", al.path, err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log %s: %w", al.path, err)
+	}
+	al.lastSeq, al.lastHash = entry.Seq, entry.Hash
+	return nil
+}
+
+// Verify checks the whole hash chain.
+func (al *AuditLog) Verify() error {
+	_, err := al.readAll()
+	return err
+}
+
+// Recent returns up to n of the newest entries, oldest first, after verifying
+// the chain. n <= 0 returns every entry.
+func (al *AuditLog) Recent(n int) ([]AuditEntry, error) {
+	entries, err := al.readAll()
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 && len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	return entries, nil
+}
+
+// readAll reads and verifies every entry in the log. A missing file is an empty log.
+func (al *AuditLog) readAll() ([]AuditEntry, error) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	f, err := os.Open(al.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log %s: %w", al.path, err)
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	prevHash := ""
+	sc := bufio.NewScanner(f)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		var entry AuditEntry
+		if err := json.Unmarshal(sc.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%w: line %d is not a valid entry: %v", ErrAuditLogTampered, lineNo, err)
+		}
+		hash, err := entry.computeHash()
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash audit entry on line %d: %w", lineNo, err)
+		}
+		if entry.Seq != uint64(lineNo) || !constantTimeEqual(entry.PrevHash, prevHash) || !constantTimeEqual(entry.Hash, hash) {
+			return nil, fmt.Errorf("%w: chain broken at line %d", ErrAuditLogTampered, lineNo)
+		}
+		prevHash = entry.Hash
+		entries = append(entries, entry)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log %s: %w", al.path, err)
+	}
+	return entries, nil
+}
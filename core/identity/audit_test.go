@@ -0,0 +1,101 @@
+package identity
+
+import (
+	"digisocialblock/core/ledger"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWallet_AuditLogRecordsSignatures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log, err := OpenAuditLog(path)
+	if err != nil {
+		t.Fatalf("OpenAuditLog() error = %v", err)
+	}
+	wallet, _ := NewWallet()
+	defer wallet.Close()
+	wallet.SetAuditLog(log)
+
+	if _, err := wallet.Sign([]byte("0123456789abcdef0123456789abcdef")); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	tx, _ := ledger.NewTransaction(wallet.Address, ledger.PostCreated, []byte(`{}`))
+	if err := wallet.SignTransaction(tx); err != nil {
+		t.Fatalf("SignTransaction() error = %v", err)
+	}
+	if _, err := wallet.SignMessage("test-chain", []byte("hello")); err != nil {
+		t.Fatalf("SignMessage() error = %v", err)
+	}
+
+	entries, err := log.Recent(0)
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("Recent(0) = %d entries, %v; want 3", len(entries), err)
+	}
+	wantComponents := []string{"wallet", "ledger", string(DomainMessage)}
+	for i, e := range entries {
+		if e.Component != wantComponents[i] || e.Signer != wallet.Address || e.Seq != uint64(i+1) {
+			t.Errorf("entry %d = %+v, want component %q", i, e, wantComponents[i])
+		}
+	}
+	if !strings.Contains(entries[1].Description, tx.ID) {
+		t.Errorf("transaction entry description %q should name the transaction", entries[1].Description)
+	}
+	if recent, _ := log.Recent(2); len(recent) != 2 || recent[1].Seq != 3 {
+		t.Errorf("Recent(2) = %+v, want the two newest entries", recent)
+	}
+
+	// Reopening continues the chain.
+	reopened, err := OpenAuditLog(path)
+	if err != nil {
+		t.Fatalf("OpenAuditLog() on existing log error = %v", err)
+	}
+	if seq, hash := reopened.Head(); seq != 3 || hash != entries[2].Hash {
+		t.Errorf("Head() = %d, %s; want 3, %s", seq, hash, entries[2].Hash)
+	}
+}
+
+func TestAuditLog_DetectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log, _ := OpenAuditLog(path)
+	wallet, _ := NewWallet()
+	defer wallet.Close()
+	wallet.SetAuditLog(log)
+	for i := 0; i < 3; i++ {
+		wallet.SignAs("test", "entry", []byte("0123456789abcdef0123456789abcdef"))
+	}
+	if err := log.Verify(); err != nil {
+		t.Fatalf("Verify() on an intact log error = %v", err)
+	}
+
+	original, _ := os.ReadFile(path)
+	lines := strings.SplitAfter(string(original), "\n")
+
+	tampered := map[string]string{
+		"edited entry":  strings.Replace(string(original), `"component":"test"`, `"component":"evil"`, 1),
+		"deleted entry": lines[0] + lines[2],
+		"reordered":     lines[1] + lines[0] + lines[2],
+	}
+	for name, body := range tampered {
+		os.WriteFile(path, []byte(body), 0600)
+		if err := log.Verify(); !errors.Is(err, ErrAuditLogTampered) {
+			t.Errorf("%s: Verify() error = %v, want ErrAuditLogTampered", name, err)
+		}
+		if _, err := OpenAuditLog(path); !errors.Is(err, ErrAuditLogTampered) {
+			t.Errorf("%s: OpenAuditLog() error = %v, want ErrAuditLogTampered", name, err)
+		}
+	}
+}
+
+func TestWallet_RefusesToSignWhenAuditFails(t *testing.T) {
+	dir := t.TempDir()
+	log, _ := OpenAuditLog(filepath.Join(dir, "missing-dir", "audit.log"))
+	wallet, _ := NewWallet()
+	defer wallet.Close()
+	wallet.SetAuditLog(log)
+	if _, err := wallet.Sign([]byte("0123456789abcdef0123456789abcdef")); err == nil {
+		t.Error("Sign() should fail when the audit entry cannot be written")
+	}
+}
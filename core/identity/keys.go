@@ -2,12 +2,11 @@ package identity
 
 import (
 	"crypto/ecdsa"
-	"crypto/elliptic"
 	"crypto/rand"
-	"crypto/x509"
+	"digisocialblock/core/clientkit"
 	"encoding/hex"
-	"io"
 	"fmt"
+	"io"
 )
 
 // GetRandReader returns rand.Reader, useful for mocking in tests if needed,
@@ -19,9 +18,9 @@ func GetRandReader() io.Reader {
 // GenerateECDSAKeyPair generates a new ECDSA private and public key pair
 // using the P-256 elliptic curve.
 func GenerateECDSAKeyPair() (*ecdsa.PrivateKey, *ecdsa.PublicKey, error) {
-	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	privateKey, err := clientkit.GenerateKey()
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to generate ECDSA key pair: %w", err)
+		return nil, nil, err
 	}
 	return privateKey, &privateKey.PublicKey, nil
 }
@@ -31,30 +30,12 @@ func GenerateECDSAKeyPair() (*ecdsa.PrivateKey, *ecdsa.PublicKey, error) {
 // PrivateKeyToBytes serializes an ECDSA private key to its byte representation
 // using PKCS#8 encoding.
 func PrivateKeyToBytes(priv *ecdsa.PrivateKey) ([]byte, error) {
-	if priv == nil {
-		return nil, fmt.Errorf("private key is nil")
-	}
-	derBytes, err := x509.MarshalPKCS8PrivateKey(priv)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal private key to PKCS#8: %w", err)
-	}
-	return derBytes, nil
+	return clientkit.MarshalPrivateKey(priv)
 }
 
 // BytesToPrivateKey deserializes bytes (PKCS#8 encoded) into an ECDSA private key.
 func BytesToPrivateKey(derBytes []byte) (*ecdsa.PrivateKey, error) {
-	if len(derBytes) == 0 {
-		return nil, fmt.Errorf("private key bytes are empty")
-	}
-	key, err := x509.ParsePKCS8PrivateKey(derBytes)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse PKCS#8 private key: %w", err)
-	}
-	ecdsaPrivKey, ok := key.(*ecdsa.PrivateKey)
-	if !ok {
-		return nil, fmt.Errorf("parsed key is not an ECDSA private key")
-	}
-	return ecdsaPrivKey, nil
+	return clientkit.ParsePrivateKey(derBytes)
 }
 
 // PrivateKeyToHexString converts an ECDSA private key to a hex string.
@@ -81,54 +62,22 @@ func HexStringToPrivateKey(hexStr string) (*ecdsa.PrivateKey, error) {
 // PublicKeyToBytes serializes an ECDSA public key to its byte representation
 // using PKIX encoding (SubjectPublicKeyInfo).
 func PublicKeyToBytes(pub *ecdsa.PublicKey) ([]byte, error) {
-	if pub == nil {
-		return nil, fmt.Errorf("public key is nil")
-	}
-	derBytes, err := x509.MarshalPKIXPublicKey(pub)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal public key to PKIX: %w", err)
-	}
-	return derBytes, nil
+	return clientkit.MarshalPublicKey(pub)
 }
 
 // BytesToPublicKey deserializes bytes (PKIX encoded) into an ECDSA public key.
 func BytesToPublicKey(derBytes []byte) (*ecdsa.PublicKey, error) {
-	if len(derBytes) == 0 {
-		return nil, fmt.Errorf("public key bytes are empty")
-	}
-	key, err := x509.ParsePKIXPublicKey(derBytes)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse PKIX public key: %w", err)
-	}
-	ecdsaPubKey, ok := key.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("parsed key is not an ECDSA public key")
-	}
-	return ecdsaPubKey, nil
+	return clientkit.ParsePublicKey(derBytes)
 }
 
 // PublicKeyToAddress converts an ECDSA public key to a hex-encoded string address.
 // This uses the marshaled PKIX representation of the public key.
 // For use in Transaction.SenderPublicKey.
 func PublicKeyToAddress(publicKey *ecdsa.PublicKey) (string, error) {
-	if publicKey == nil {
-		return "", fmt.Errorf("public key is nil")
-	}
-	pubKeyBytes, err := PublicKeyToBytes(publicKey)
-	if err != nil {
-		return "", fmt.Errorf("failed to convert public key to bytes for address: %w", err)
-	}
-	return hex.EncodeToString(pubKeyBytes), nil
+	return clientkit.Address(publicKey)
 }
 
 // AddressToPublicKey converts a hex-encoded string address back to an ECDSA public key.
 func AddressToPublicKey(addressHex string) (*ecdsa.PublicKey, error) {
-	if addressHex == "" {
-		return nil, fmt.Errorf("address string is empty")
-	}
-	pubKeyBytes, err := hex.DecodeString(addressHex)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode hex string for public key address: %w", err)
-	}
-	return BytesToPublicKey(pubKeyBytes)
+	return clientkit.ParseAddress(addressHex)
 }
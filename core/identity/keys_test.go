@@ -1,8 +1,8 @@
 package identity
 
 import (
-	"crypto/ecdsa"
 	"crypto/elliptic"
+	"crypto/rand"
 	"encoding/hex"
 	"reflect"
 	"testing"
@@ -0,0 +1,147 @@
+package identity
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// ErrNotARecipient is returned when a wallet opens a sealed key set that was
+// not sealed to its address.
+var ErrNotARecipient = errors.New("wallet is not a recipient of the sealed key")
+
+// SealedKey is a symmetric key encrypted to one recipient: an ephemeral P-256
+// ECDH exchange with the recipient's public key derives a wrapping key, which
+// encrypts the content key with AES-256-GCM.
+type SealedKey struct {
+	Recipient    string `json:"recipient"`    // Recipient address
+	EphemeralKey []byte `json:"ephemeralKey"` // Uncompressed ephemeral public key
+	WrappedKey   []byte `json:"wrappedKey"`   // Nonce || AES-GCM ciphertext of the content key
+}
+
+// wrappingKey derives the key that wraps a content key from an ECDH shared
+// secret, binding both public keys so the wrap is specific to this exchange.
+func wrappingKey(shared, ephemeral []byte, recipient string) []byte {
+	mac := hmac.New(sha256.New, shared)
+	mac.Write([]byte("dsb-seal-v1"))
+	mac.Write(ephemeral)
+	mac.Write([]byte(recipient))
+	return mac.Sum(nil)
+}
+
+// SealKeyForRecipients encrypts key separately to each recipient address.
+// Only holders of a recipient's private key can recover it (see OpenSealedKey).
+func SealKeyForRecipients(key []byte, recipients []string) ([]SealedKey, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("key to seal cannot be empty")
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("at least one recipient is required")
+	}
+	sealed := make([]SealedKey, 0, len(recipients))
+	for _, recipient := range recipients {
+		pub, err := AddressToPublicKey(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %s: %w", recipient, err)
+		}
+		recipientKey, err := pub.ECDH()
+		if err != nil {
+			return nil, fmt.Errorf("recipient %s key does not support ECDH: %w", recipient, err)
+		}
+		ephemeral, err := recipientKey.Curve().GenerateKey(GetRandReader())
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+		}
+		shared, err := ephemeral.ECDH(recipientKey)
+		if err != nil {
+			return nil, fmt.Errorf("ECDH with recipient %s failed: %w", recipient, err)
+		}
+		ephemeralPub := ephemeral.PublicKey().Bytes()
+		wrapped, err := aesGCMSeal(wrappingKey(shared, ephemeralPub, recipient), key)
+		zeroBytes(shared)
+		if err != nil {
+			return nil, err
+		}
+		sealed = append(sealed, SealedKey{Recipient: recipient, EphemeralKey: ephemeralPub, WrappedKey: wrapped})
+	}
+	return sealed, nil
+}
+
+// OpenSealedKey recovers the key sealed to this wallet's address. The caller
+// owns the returned key and should wipe it when done.
+func (w *Wallet) OpenSealedKey(sealed []SealedKey) ([]byte, error) {
+	if w.PrivateKey == nil {
+		return nil, fmt.Errorf("wallet has no private key to open sealed keys with")
+	}
+	for _, sk := range sealed {
+		if !constantTimeEqual(sk.Recipient, w.Address) {
+			continue
+		}
+		priv, err := w.PrivateKey.ECDH()
+		runtime.KeepAlive(w)
+		if err != nil {
+			return nil, fmt.Errorf("wallet key does not support ECDH: %w", err)
+		}
+		ephemeral, err := priv.Curve().NewPublicKey(sk.EphemeralKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ephemeral key: %w", err)
+		}
+		shared, err := priv.ECDH(ephemeral)
+		if err != nil {
+			return nil, fmt.Errorf("ECDH failed: %w", err)
+		}
+		defer zeroBytes(shared)
+		return aesGCMOpen(wrappingKey(shared, sk.EphemeralKey, sk.Recipient), sk.WrappedKey)
+	}
+	return nil, ErrNotARecipient
+}
+
+// aesGCMSeal encrypts plaintext under key with a random nonce and returns
+// nonce || ciphertext. The key is wiped afterwards.
+func aesGCMSeal(key, plaintext []byte) ([]byte, error) {
+	defer zeroBytes(key)
+	aead, err := newAESGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(GetRandReader(), nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// aesGCMOpen reverses aesGCMSeal. The key is wiped afterwards.
+func aesGCMOpen(key, sealed []byte) ([]byte, error) {
+	defer zeroBytes(key)
+	aead, err := newAESGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("sealed key is truncated")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap sealed key: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return aead, nil
+}
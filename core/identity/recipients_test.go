@@ -0,0 +1,52 @@
+package identity
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSealKeyForRecipients_RoundTrip(t *testing.T) {
+	alice, _ := NewWallet()
+	defer alice.Close()
+	bob, _ := NewWallet()
+	defer bob.Close()
+	eve, _ := NewWallet()
+	defer eve.Close()
+
+	key := bytes.Repeat([]byte{7}, 32)
+	sealed, err := SealKeyForRecipients(key, []string{alice.Address, bob.Address})
+	if err != nil {
+		t.Fatalf("SealKeyForRecipients() error = %v", err)
+	}
+	for _, w := range []*Wallet{alice, bob} {
+		got, err := w.OpenSealedKey(sealed)
+		if err != nil || !bytes.Equal(got, key) {
+			t.Errorf("OpenSealedKey() = %x, %v; want the sealed key", got, err)
+		}
+	}
+	if _, err := eve.OpenSealedKey(sealed); !errors.Is(err, ErrNotARecipient) {
+		t.Errorf("OpenSealedKey() by a non-recipient error = %v, want ErrNotARecipient", err)
+	}
+
+	// Relabelling Bob's entry for Eve must not let Eve open it.
+	forged := []SealedKey{sealed[1]}
+	forged[0].Recipient = eve.Address
+	if _, err := eve.OpenSealedKey(forged); err == nil {
+		t.Error("OpenSealedKey() of a relabelled entry should fail")
+	}
+}
+
+func TestSealKeyForRecipients_Errors(t *testing.T) {
+	w, _ := NewWallet()
+	defer w.Close()
+	if _, err := SealKeyForRecipients(nil, []string{w.Address}); err == nil {
+		t.Error("sealing an empty key should fail")
+	}
+	if _, err := SealKeyForRecipients([]byte("k"), nil); err == nil {
+		t.Error("sealing to no recipients should fail")
+	}
+	if _, err := SealKeyForRecipients([]byte("k"), []string{"not-hex"}); err == nil {
+		t.Error("sealing to an invalid address should fail")
+	}
+}
@@ -0,0 +1,87 @@
+package identity
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"digisocialblock/core/ledger"
+	"fmt"
+	"runtime"
+)
+
+// Secret key hygiene.
+//
+// Go gives no control over copies made by the garbage collector or by the
+// standard library's crypto internals, so zeroization here is best effort: it
+// shortens the time key material sits in memory (and therefore in core dumps or
+// swap) but cannot guarantee that no copy survives.
+
+// constantTimeEqual compares addresses, hashes and other encoded values in the
+// identity verification paths in constant time (see ledger.ConstantTimeEqual).
+// It is a variable only so tests can assert those paths go through it.
+var constantTimeEqual = ledger.ConstantTimeEqual
+
+// zeroBytes overwrites b with zeros.
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// zeroPrivateKey overwrites the private scalar of priv in place.
+func zeroPrivateKey(priv *ecdsa.PrivateKey) {
+	if priv == nil || priv.D == nil {
+		return
+	}
+	words := priv.D.Bits()
+	for i := range words {
+		words[i] = 0
+	}
+	priv.D.SetInt64(0)
+}
+
+// WithPrivateKeyBytes calls fn with the PKCS#8 DER encoding of the wallet's
+// private key and wipes the encoding as soon as fn returns. fn must not retain
+// the slice or copy it into memory it does not wipe itself.
+func (w *Wallet) WithPrivateKeyBytes(fn func(der []byte) error) error {
+	if w.PrivateKey == nil {
+		return fmt.Errorf("wallet has no private key")
+	}
+	der, err := PrivateKeyToBytes(w.PrivateKey)
+	runtime.KeepAlive(w)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(der)
+	return fn(der)
+}
+
+// Close wipes the wallet's private key. The wallet can still report its
+// address and verify signatures, but can no longer sign. Close is idempotent.
+func (w *Wallet) Close() error {
+	zeroPrivateKey(w.PrivateKey)
+	w.PrivateKey = nil
+	runtime.SetFinalizer(w, nil)
+	return nil
+}
+
+// DeriveKey derives a 32-byte symmetric key for purpose from the wallet's
+// private key (HMAC-SHA256 keyed by the private key encoding). Different
+// purposes yield independent keys, and none of them reveals the private key.
+// The caller owns the returned key and should wipe it when done.
+func (w *Wallet) DeriveKey(purpose string) ([]byte, error) {
+	if purpose == "" {
+		return nil, fmt.Errorf("key derivation purpose cannot be empty")
+	}
+	var key []byte
+	err := w.WithPrivateKeyBytes(func(der []byte) error {
+		mac := hmac.New(sha256.New, der)
+		mac.Write([]byte("dsb-derive-v1:" + purpose))
+		key = mac.Sum(nil)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
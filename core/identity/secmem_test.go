@@ -0,0 +1,165 @@
+package identity
+
+import (
+	"bytes"
+	"digisocialblock/core/ledger"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWallet_CloseWipesPrivateKey(t *testing.T) {
+	wallet, _ := NewWallet()
+	priv := wallet.PrivateKey
+	if err := wallet.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if priv.D.Sign() != 0 {
+		t.Error("private scalar was not wiped by Close()")
+	}
+	if _, err := wallet.Sign(bytes.Repeat([]byte{1}, 32)); err == nil {
+		t.Error("Sign() after Close(): expected error, got nil")
+	}
+	if wallet.Address == "" {
+		t.Error("Close() cleared the public address")
+	}
+	if err := wallet.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+}
+
+func TestWallet_ReachableWalletKeepsKeyAcrossCollections(t *testing.T) {
+	wallet, _ := NewWallet()
+	defer wallet.Close()
+	runtime.GC()
+	if _, err := wallet.Sign(bytes.Repeat([]byte{1}, 32)); err != nil {
+		t.Errorf("Sign() after a collection error = %v", err)
+	}
+}
+
+func TestWallet_WithPrivateKeyBytesWipesBuffer(t *testing.T) {
+	wallet, _ := NewWallet()
+	defer wallet.Close()
+	var leaked []byte
+	err := wallet.WithPrivateKeyBytes(func(der []byte) error {
+		if _, err := BytesToPrivateKey(der); err != nil {
+			return err
+		}
+		leaked = der
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithPrivateKeyBytes() error = %v", err)
+	}
+	if !bytes.Equal(leaked, make([]byte, len(leaked))) {
+		t.Error("key bytes were not wiped after the callback returned")
+	}
+}
+
+func TestWallet_SaveToFileFormatAndSilence(t *testing.T) {
+	wallet, _ := NewWallet()
+	defer wallet.Close()
+	path := filepath.Join(t.TempDir(), "wallet.json")
+
+	// SaveToFile must not print anything (it used to log the address and path).
+	stdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := wallet.SaveToFile(path)
+	w.Close()
+	os.Stdout = stdout
+	printed, _ := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("SaveToFile() error = %v", err)
+	}
+	if len(printed) > 0 {
+		t.Errorf("SaveToFile() printed %q", printed)
+	}
+
+	// The hand-written JSON still decodes as WalletData.
+	raw, _ := os.ReadFile(path)
+	var data WalletData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		t.Fatalf("saved wallet is not valid JSON: %v", err)
+	}
+	restored, err := HexStringToPrivateKey(data.PrivateKeyHex)
+	if err != nil || !restored.Equal(wallet.PrivateKey) || data.Address != wallet.Address {
+		t.Errorf("saved wallet data does not round-trip (err = %v)", err)
+	}
+
+	loaded, err := LoadWalletFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadWalletFromFile() error = %v", err)
+	}
+	defer loaded.Close()
+	if !loaded.PrivateKey.Equal(wallet.PrivateKey) {
+		t.Error("loaded private key does not match the saved wallet")
+	}
+}
+
+func TestWallet_DeriveKey(t *testing.T) {
+	wallet, _ := NewWallet()
+	defer wallet.Close()
+	other, _ := NewWallet()
+	defer other.Close()
+
+	k1, err := wallet.DeriveKey("drafts")
+	if err != nil || len(k1) != 32 {
+		t.Fatalf("DeriveKey() = %d bytes, %v; want 32 bytes", len(k1), err)
+	}
+	k2, _ := wallet.DeriveKey("drafts")
+	k3, _ := wallet.DeriveKey("other")
+	k4, _ := other.DeriveKey("drafts")
+	if !bytes.Equal(k1, k2) {
+		t.Error("DeriveKey() is not deterministic")
+	}
+	if bytes.Equal(k1, k3) || bytes.Equal(k1, k4) {
+		t.Error("DeriveKey() keys for different purposes or wallets must differ")
+	}
+	if _, err := wallet.DeriveKey(""); err == nil {
+		t.Error("DeriveKey(\"\") should fail")
+	}
+	wallet.Close()
+	if _, err := wallet.DeriveKey("drafts"); err == nil {
+		t.Error("DeriveKey() on a closed wallet should fail")
+	}
+}
+
+func TestVerificationPathsUseConstantTimeEqual(t *testing.T) {
+	calls := 0
+	original := constantTimeEqual
+	constantTimeEqual = func(a, b string) bool {
+		calls++
+		return original(a, b)
+	}
+	defer func() { constantTimeEqual = original }()
+
+	wallet, _ := NewWallet()
+	defer wallet.Close()
+	path := filepath.Join(t.TempDir(), "wallet.json")
+	wallet.SaveToFile(path)
+	audit, _ := OpenAuditLog(filepath.Join(t.TempDir(), "audit.log"))
+	wallet.SetAuditLog(audit)
+	sealed, _ := SealKeyForRecipients([]byte("key"), []string{wallet.Address})
+	tx, _ := ledger.NewTransaction(wallet.Address, ledger.PostCreated, []byte(`{}`))
+
+	paths := map[string]func(){
+		"LoadWalletFromFile":     func() { LoadWalletFromFile(path) },
+		"Wallet.SignTransaction": func() { wallet.SignTransaction(tx) },
+		"Wallet.OpenSealedKey":   func() { wallet.OpenSealedKey(sealed) },
+		"AuditLog.Verify": func() {
+			wallet.SignTransaction(tx) // Verify has nothing to compare on an empty log
+			audit.Verify()
+		},
+	}
+	for name, path := range paths {
+		calls = 0
+		path()
+		if calls == 0 {
+			t.Errorf("%s does not compare values with constantTimeEqual", name)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package identity
+
+import (
+	"crypto/ecdsa"
+	"digisocialblock/core/clientkit"
+	"fmt"
+)
+
+// SigningDomain names the context a signature is valid in (see
+// clientkit.SigningDomain).
+type SigningDomain = clientkit.SigningDomain
+
+const (
+	DomainTransaction = clientkit.DomainTransaction
+	DomainProfile     = clientkit.DomainProfile
+	DomainMessage     = clientkit.DomainMessage
+	DomainManifest    = clientkit.DomainManifest
+	DomainCapability  = clientkit.DomainCapability
+)
+
+// CurrentSignatureVersion is the domain-separated signing scheme used for new
+// signatures. Version 0 denotes legacy signatures over the bare data.
+const CurrentSignatureVersion = clientkit.CurrentSignatureVersion
+
+// DomainDigest returns the SHA-256 digest that is signed for data in the given
+// domain (see clientkit.DomainDigest).
+func DomainDigest(domain SigningDomain, version int, chainID string, data []byte) ([]byte, error) {
+	return clientkit.DomainDigest(domain, version, chainID, data)
+}
+
+// SignInDomain signs data for the given domain and chain using the current
+// signature version. It returns the ASN.1 DER encoded signature.
+func (w *Wallet) SignInDomain(domain SigningDomain, chainID string, data []byte) ([]byte, error) {
+	digest, err := DomainDigest(domain, CurrentSignatureVersion, chainID, data)
+	if err != nil {
+		return nil, err
+	}
+	return w.SignAs(string(domain), fmt.Sprintf("%s-v%d signature for chain %q", domain, CurrentSignatureVersion, chainID), digest)
+}
+
+// VerifyInDomain checks a signature made by SignInDomain (or an equivalent
+// signer) at the given signature version.
+func VerifyInDomain(publicKey *ecdsa.PublicKey, domain SigningDomain, version int, chainID string, data, signature []byte) error {
+	return clientkit.Verify(publicKey, domain, version, chainID, data, signature)
+}
+
+// SignMessage signs an arbitrary application message. Message signatures are
+// domain-separated from transaction and profile signatures.
+func (w *Wallet) SignMessage(chainID string, message []byte) ([]byte, error) {
+	return w.SignInDomain(DomainMessage, chainID, message)
+}
+
+// VerifyMessage checks a signature produced by SignMessage against the signer's address.
+func VerifyMessage(address, chainID string, message, signature []byte) error {
+	publicKey, err := AddressToPublicKey(address)
+	if err != nil {
+		return fmt.Errorf("failed to parse signer address: %w", err)
+	}
+	return VerifyInDomain(publicKey, DomainMessage, CurrentSignatureVersion, chainID, message, signature)
+}
@@ -0,0 +1,71 @@
+package identity
+
+import (
+	"bytes"
+	"digisocialblock/core/ledger"
+	"errors"
+	"testing"
+)
+
+func TestDomainDigest_SeparatesContexts(t *testing.T) {
+	data := []byte("same bytes")
+	base, err := DomainDigest(DomainTransaction, 1, "chain-a", data)
+	if err != nil {
+		t.Fatalf("DomainDigest() error = %v", err)
+	}
+	others := map[string][]byte{}
+	others["domain"], _ = DomainDigest(DomainMessage, 1, "chain-a", data)
+	others["version"], _ = DomainDigest(DomainTransaction, 2, "chain-a", data)
+	others["chain"], _ = DomainDigest(DomainTransaction, 1, "chain-b", data)
+	// Moving bytes between the chain ID and the data must not collide either.
+	others["boundary"], _ = DomainDigest(DomainTransaction, 1, "chain-asame", []byte(" bytes"))
+	for name, digest := range others {
+		if bytes.Equal(digest, base) {
+			t.Errorf("digest with different %s equals the base digest", name)
+		}
+	}
+	if _, err := DomainDigest(DomainTransaction, 0, "chain-a", data); err == nil {
+		t.Error("DomainDigest() with version 0: expected error, got nil")
+	}
+}
+
+func TestSignatures_CannotBeReusedAcrossDomains(t *testing.T) {
+	wallet, _ := NewWallet()
+	tx, _ := ledger.NewTransaction(wallet.Address, ledger.PostCreated, []byte("payload"))
+	if err := wallet.SignTransaction(tx); err != nil {
+		t.Fatalf("SignTransaction() error = %v", err)
+	}
+	if valid, err := tx.VerifySignature(); err != nil || !valid {
+		t.Fatalf("VerifySignature() = %v, %v; want true, nil", valid, err)
+	}
+
+	// A transaction signature is not a valid message signature over the ID...
+	if err := VerifyMessage(wallet.Address, ledger.DefaultChainID, []byte(tx.ID), tx.Signature); err == nil {
+		t.Error("transaction signature verified as a message signature")
+	}
+	// ...and a message signature over the ID is not a valid transaction signature.
+	msgSig, _ := wallet.SignMessage(ledger.DefaultChainID, []byte(tx.ID))
+	forged := *tx
+	forged.Signature = msgSig
+	if valid, _ := forged.VerifySignature(); valid {
+		t.Error("message signature verified as a transaction signature")
+	}
+}
+
+func TestSignTransaction_BindsChainAndVersion(t *testing.T) {
+	wallet, _ := NewWallet()
+	tx, _ := ledger.NewTransaction(wallet.Address, ledger.PostCreated, []byte("payload"))
+	wallet.SignTransaction(tx)
+
+	replayed := *tx
+	replayed.ChainID = "dsb-testnet"
+	if valid, _ := replayed.VerifySignature(); valid {
+		t.Error("signature verified after changing the chain ID")
+	}
+
+	legacy := *tx
+	legacy.SigVersion = 0
+	if _, err := legacy.VerifySignature(); !errors.Is(err, ledger.ErrLegacySignature) {
+		t.Errorf("VerifySignature() on a legacy signature error = %v, want ErrLegacySignature", err)
+	}
+}
@@ -0,0 +1,189 @@
+package identity
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// URIScheme is the scheme of the URIs that name a wallet address, as in
+// "dsb:<address>?action=follow".
+const URIScheme = "dsb"
+
+// Limits on the optional parts of an AddressURI.
+const (
+	MaxURILabelLength = 64  // Characters
+	MaxURIPostLength  = 128 // Bytes
+)
+
+// URIAction is what a client is asked to do with the address in a URI.
+type URIAction string
+
+// Actions an AddressURI can carry. The empty action just shows the address's
+// profile.
+const (
+	URIActionView   URIAction = ""
+	URIActionFollow URIAction = "follow"
+	URIActionTip    URIAction = "tip"
+)
+
+// ErrInvalidURI is returned for a string that is not a valid dsb: URI.
+var ErrInvalidURI = errors.New("invalid dsb URI")
+
+// AddressURI is a parsed dsb: URI. It is what scan-to-follow and
+// scan-to-tip QR codes carry, so every client builds and reads them the same
+// way:
+//
+//	dsb:<address>
+//	dsb:<address>?action=follow&label=Alice
+//	dsb:<address>?action=tip&amount=5&post=<content CID>
+type AddressURI struct {
+	Address string
+	Action  URIAction
+	Label   string // Suggested display name; never trusted over the profile
+	Amount  uint64 // Suggested tip; only with URIActionTip, zero lets the user choose
+	PostCID string // Post being tipped; only with URIActionTip
+}
+
+// Validate checks that the address is a valid public key and that the
+// optional fields fit the action and their limits.
+func (u *AddressURI) Validate() error {
+	if u.Address == "" {
+		return fmt.Errorf("%w: empty address", ErrInvalidURI)
+	}
+	if _, err := AddressToPublicKey(u.Address); err != nil {
+		return fmt.Errorf("%w: address: %v", ErrInvalidURI, err)
+	}
+	switch u.Action {
+	case URIActionView, URIActionFollow, URIActionTip:
+	default:
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidURI, u.Action)
+	}
+	if u.Action != URIActionTip && (u.Amount != 0 || u.PostCID != "") {
+		return fmt.Errorf("%w: amount and post are only allowed with the tip action", ErrInvalidURI)
+	}
+	if n := utf8.RuneCountInString(u.Label); n > MaxURILabelLength {
+		return fmt.Errorf("%w: label is %d characters, limit %d", ErrInvalidURI, n, MaxURILabelLength)
+	}
+	if len(u.PostCID) > MaxURIPostLength {
+		return fmt.Errorf("%w: post is %d bytes, limit %d", ErrInvalidURI, len(u.PostCID), MaxURIPostLength)
+	}
+	return nil
+}
+
+// String formats the URI. Parameters appear in a fixed order, so equal
+// AddressURIs format identically.
+func (u *AddressURI) String() string {
+	var b strings.Builder
+	b.WriteString(URIScheme)
+	b.WriteByte(':')
+	b.WriteString(strings.ToLower(u.Address))
+	sep := byte('?')
+	param := func(key, value string) {
+		b.WriteByte(sep)
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(url.QueryEscape(value))
+		sep = '&'
+	}
+	if u.Action != URIActionView {
+		param("action", string(u.Action))
+	}
+	if u.Amount != 0 {
+		param("amount", strconv.FormatUint(u.Amount, 10))
+	}
+	if u.PostCID != "" {
+		param("post", u.PostCID)
+	}
+	if u.Label != "" {
+		param("label", u.Label)
+	}
+	return b.String()
+}
+
+// ParseAddressURI parses and validates a dsb: URI. The scheme and address
+// are case-insensitive; the address is returned in lower case, as wallets
+// produce it. Unknown parameters are ignored so that later versions can add
+// them.
+func ParseAddressURI(s string) (*AddressURI, error) {
+	s = strings.TrimSpace(s)
+	scheme, rest, ok := strings.Cut(s, ":")
+	if !ok || !strings.EqualFold(scheme, URIScheme) {
+		return nil, fmt.Errorf("%w: scheme is not %s:", ErrInvalidURI, URIScheme)
+	}
+	rest = strings.TrimPrefix(rest, "//") // Tolerate dsb://<address>
+	address, rawQuery, _ := strings.Cut(rest, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURI, err)
+	}
+	for key, values := range query {
+		if len(values) > 1 {
+			return nil, fmt.Errorf("%w: parameter %s repeated", ErrInvalidURI, key)
+		}
+	}
+	u := &AddressURI{
+		Address: strings.ToLower(address),
+		Action:  URIAction(strings.ToLower(query.Get("action"))),
+		Label:   query.Get("label"),
+		PostCID: query.Get("post"),
+	}
+	if amount := query.Get("amount"); amount != "" {
+		if u.Amount, err = strconv.ParseUint(amount, 10, 64); err != nil {
+			return nil, fmt.Errorf("%w: amount %q", ErrInvalidURI, amount)
+		}
+	}
+	if err := u.Validate(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// QRPayload returns the text to encode in a QR code for u. A URI with no
+// parameters is returned in upper case, which QR encoders can pack in the
+// denser alphanumeric mode; ParseQRPayload reads either case.
+func (u *AddressURI) QRPayload() string {
+	s := u.String()
+	if !strings.Contains(s, "?") {
+		return strings.ToUpper(s)
+	}
+	return s
+}
+
+// ParseQRPayload parses the text scanned from a QR code. Besides dsb: URIs it
+// accepts a bare address, which older clients show as the QR code of a
+// profile.
+func ParseQRPayload(payload string) (*AddressURI, error) {
+	payload = strings.TrimSpace(payload)
+	if !strings.Contains(payload, ":") {
+		payload = URIScheme + ":" + payload
+	}
+	return ParseAddressURI(payload)
+}
+
+// FollowURI returns the dsb: URI asking a client to follow address.
+func FollowURI(address string) (*AddressURI, error) {
+	u := &AddressURI{Address: strings.ToLower(address), Action: URIActionFollow}
+	if err := u.Validate(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// TipURI returns the dsb: URI asking a client to tip address amount, on the
+// post with postCID if that is set. A zero amount lets the user choose.
+func TipURI(address string, amount uint64, postCID string) (*AddressURI, error) {
+	u := &AddressURI{Address: strings.ToLower(address), Action: URIActionTip, Amount: amount, PostCID: postCID}
+	if err := u.Validate(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// URI returns the dsb: URI of the wallet's address, for sharing a profile.
+func (w *Wallet) URI() *AddressURI {
+	return &AddressURI{Address: w.Address}
+}
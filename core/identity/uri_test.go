@@ -0,0 +1,67 @@
+package identity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAddressURI_RoundTrip(t *testing.T) {
+	w, _ := NewWallet()
+	defer w.Close()
+
+	follow, err := FollowURI(w.Address)
+	if err != nil {
+		t.Fatalf("FollowURI() error = %v", err)
+	}
+	follow.Label = "Alice & Bob"
+	tip, err := TipURI(w.Address, 5, "bafy-post")
+	if err != nil {
+		t.Fatalf("TipURI() error = %v", err)
+	}
+	for _, u := range []*AddressURI{w.URI(), follow, tip} {
+		got, err := ParseAddressURI(u.String())
+		if err != nil {
+			t.Fatalf("ParseAddressURI(%q) error = %v", u, err)
+		}
+		if *got != *u {
+			t.Errorf("ParseAddressURI(%q) = %+v, want %+v", u, got, u)
+		}
+		scanned, err := ParseQRPayload(u.QRPayload())
+		if err != nil || *scanned != *u {
+			t.Errorf("ParseQRPayload(%q) = %+v, %v; want %+v", u.QRPayload(), scanned, err, u)
+		}
+	}
+
+	if got := w.URI().QRPayload(); got != strings.ToUpper("dsb:"+w.Address) {
+		t.Errorf("QRPayload() = %q, want the upper-case URI", got)
+	}
+	if got := tip.String(); got != "dsb:"+w.Address+"?action=tip&amount=5&post=bafy-post" {
+		t.Errorf("String() = %q", got)
+	}
+	if got, err := ParseQRPayload(" " + w.Address + "\n"); err != nil || got.Address != w.Address {
+		t.Errorf("ParseQRPayload() of a bare address = %+v, %v", got, err)
+	}
+}
+
+func TestParseAddressURI_Errors(t *testing.T) {
+	w, _ := NewWallet()
+	defer w.Close()
+
+	for _, s := range []string{
+		"",
+		w.Address,
+		"https:" + w.Address,
+		"dsb:",
+		"dsb:not-hex",
+		"dsb:" + w.Address + "?action=burn",
+		"dsb:" + w.Address + "?action=follow&amount=5",
+		"dsb:" + w.Address + "?action=tip&amount=-1",
+		"dsb:" + w.Address + "?action=tip&action=follow",
+		"dsb:" + w.Address + "?label=" + strings.Repeat("x", MaxURILabelLength+1),
+	} {
+		if _, err := ParseAddressURI(s); !errors.Is(err, ErrInvalidURI) {
+			t.Errorf("ParseAddressURI(%q) error = %v, want ErrInvalidURI", s, err)
+		}
+	}
+}
@@ -1,18 +1,30 @@
 package identity
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/rand" // For ecdsa.Sign
 	"digisocialblock/core/ledger" // Assuming this path based on previous structure
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"os"
+	"runtime"
 	// "math/big" // Required for ecdsa.Sign Ecdsa signatures are a pair of integers (r, s).
 )
 
 // Wallet holds a user's cryptographic key pair and their public address.
+// Call Close when the wallet is no longer needed to wipe the private key;
+// wallets that are dropped without Close are wiped when garbage collected.
+// Code that uses PrivateKey directly must keep the wallet reachable until it
+// is done with the key (see runtime.KeepAlive), or the key may be wiped under
+// it. A Wallet must not be copied by value, as copies share the private key.
 type Wallet struct {
 	PrivateKey *ecdsa.PrivateKey
 	PublicKey  *ecdsa.PublicKey
 	Address    string // Derived from PublicKey, typically hex-encoded
+
+	audit *AuditLog // Optional; records every signature when set
 }
 
 // NewWallet creates a new Wallet instance, generating a new ECDSA key pair.
@@ -27,11 +39,19 @@ func NewWallet() (*Wallet, error) {
 		return nil, fmt.Errorf("failed to derive address for new wallet: %w", err)
 	}
 
-	return &Wallet{
+	return newWallet(privKey, address), nil
+}
+
+// newWallet wraps a private key in a Wallet that wipes the key when it is
+// garbage collected without having been closed.
+func newWallet(privKey *ecdsa.PrivateKey, address string) *Wallet {
+	w := &Wallet{
 		PrivateKey: privKey,
-		PublicKey:  pubKey,
+		PublicKey:  &privKey.PublicKey,
 		Address:    address,
-	}, nil
+	}
+	runtime.SetFinalizer(w, func(w *Wallet) { w.Close() })
+	return w
 }
 
 // GetAddress returns the public address of the wallet.
@@ -39,19 +59,38 @@ func (w *Wallet) GetAddress() string {
 	return w.Address
 }
 
+// SetAuditLog makes the wallet record every signing operation in log. It must
+// be called before the wallet is shared between goroutines; nil disables auditing.
+func (w *Wallet) SetAuditLog(log *AuditLog) {
+	w.audit = log
+}
+
 // Sign uses the wallet's private key to sign a hash (typically a transaction ID).
 // Returns the ASN.1 DER encoded signature.
 func (w *Wallet) Sign(dataHash []byte) ([]byte, error) {
+	return w.SignAs("wallet", "raw digest", dataHash)
+}
+
+// SignAs signs dataHash like Sign, recording in the audit log (if any) which
+// component requested the signature and what it covers. If the audit entry
+// cannot be written the wallet refuses to sign, so no signature goes unrecorded.
+func (w *Wallet) SignAs(component, description string, dataHash []byte) ([]byte, error) {
 	if w.PrivateKey == nil {
 		return nil, fmt.Errorf("wallet has no private key to sign with")
 	}
 	if len(dataHash) == 0 {
 		return nil, fmt.Errorf("cannot sign empty data hash")
 	}
+	if w.audit != nil {
+		if err := w.audit.record(w.Address, component, description, dataHash); err != nil {
+			return nil, fmt.Errorf("refusing to sign: %w", err)
+		}
+	}
 
 	// ecdsa.SignASN1 signs a hash (which should be the output of a hash function)
 	// and returns the ASN.1 DER encoded signature.
 	signature, err := ecdsa.SignASN1(rand.Reader, w.PrivateKey, dataHash)
+	runtime.KeepAlive(w) // The finalizer must not wipe the key while it is in use
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign data: %w", err)
 	}
@@ -60,7 +99,9 @@ func (w *Wallet) Sign(dataHash []byte) ([]byte, error) {
 
 // SignTransaction signs a ledger transaction using the wallet's private key.
 // It populates the transaction's Signature field.
-// The transaction's ID field (which is a hash of its content) is what gets signed.
+// The transaction is signed with the current domain-separated scheme, so the
+// signature covers the transaction ID and chain ID and is only valid as a
+// transaction signature (see ledger.Transaction.SigningDigest).
 func (w *Wallet) SignTransaction(tx *ledger.Transaction) error {
 	if tx == nil {
 		return fmt.Errorf("cannot sign a nil transaction")
@@ -69,8 +110,12 @@ func (w *Wallet) SignTransaction(tx *ledger.Transaction) error {
 		return fmt.Errorf("transaction ID is empty, cannot determine data to sign")
 	}
 
-	dataToSign := []byte(tx.ID) // The transaction ID (hash of content) is what we sign
-	signature, err := w.Sign(dataToSign)
+	tx.SigVersion = CurrentSignatureVersion
+	dataToSign, err := tx.SigningDigest()
+	if err != nil {
+		return fmt.Errorf("failed to compute signing digest for transaction %s: %w", tx.ID, err)
+	}
+	signature, err := w.SignAs("ledger", fmt.Sprintf("transaction %s (%s)", tx.ID, tx.Type), dataToSign)
 	if err != nil {
 		return fmt.Errorf("failed to sign transaction ID %s: %w", tx.ID, err)
 	}
@@ -81,13 +126,38 @@ func (w *Wallet) SignTransaction(tx *ledger.Transaction) error {
 	// For consistency, we can set it here if it's not already set, or verify it.
 	if tx.SenderPublicKey == "" {
 		tx.SenderPublicKey = w.Address
-	} else if tx.SenderPublicKey != w.Address {
+	} else if !constantTimeEqual(tx.SenderPublicKey, w.Address) {
 		return fmt.Errorf("transaction SenderPublicKey %s does not match wallet address %s", tx.SenderPublicKey, w.Address)
 	}
 
 	return nil
 }
 
+// CoSignTransaction adds the wallet's co-signature to a transaction whose
+// payload lists the wallet as a required co-signer (see
+// ledger.RegisterCoSigners). It may be called before or after the sender signs.
+func (w *Wallet) CoSignTransaction(tx *ledger.Transaction) error {
+	if tx == nil {
+		return fmt.Errorf("cannot co-sign a nil transaction")
+	}
+	if tx.ID == "" {
+		return fmt.Errorf("transaction ID is empty, cannot determine data to co-sign")
+	}
+
+	if tx.SigVersion == 0 {
+		tx.SigVersion = CurrentSignatureVersion
+	}
+	dataToSign, err := tx.SigningDigest()
+	if err != nil {
+		return fmt.Errorf("failed to compute signing digest for transaction %s: %w", tx.ID, err)
+	}
+	signature, err := w.SignAs("ledger", fmt.Sprintf("co-signature of transaction %s (%s)", tx.ID, tx.Type), dataToSign)
+	if err != nil {
+		return fmt.Errorf("failed to co-sign transaction ID %s: %w", tx.ID, err)
+	}
+	return tx.AddCoSignature(w.Address, signature)
+}
+
 // --- Persistence (Placeholder for now, as per Task 1.1.6 for Blockchain) ---
 // For Wallet, simple JSON or Gob encoding of the hex/base64 private key could be done.
 // IMPORTANT: Real wallet persistence MUST encrypt the private key.
@@ -103,64 +173,70 @@ type WalletData struct {
 // SaveToFile serializes the wallet's private key to a file.
 // NOTE: This is a placeholder and does NOT encrypt the private key.
 // In a real application, the private key MUST be encrypted.
+//
+// The encoded key only ever lives in byte buffers that are wiped before
+// SaveToFile returns, and nothing about the key or its location is logged.
 func (w *Wallet) SaveToFile(filepath string) error {
 	// TODO: Implement proper encryption for the private key before saving.
-	// For this task, we'll save it as hex for simplicity of demonstration.
-	privKeyHex, err := PrivateKeyToHexString(w.PrivateKey)
-	if err != nil {
-		return fmt.Errorf("failed to convert private key to hex for saving: %w", err)
-	}
 	pubKeyHex, err := PublicKeyToAddress(w.PublicKey) // Address is already hex of public key
 	if err != nil {
 		return fmt.Errorf("failed to convert public key to hex for saving: %w", err)
 	}
 
+	return w.WithPrivateKeyBytes(func(der []byte) error {
+		privKeyHex := make([]byte, hex.EncodedLen(len(der)))
+		defer zeroBytes(privKeyHex)
+		hex.Encode(privKeyHex, der)
 
-	data := WalletData{
-		PrivateKeyHex: privKeyHex,
-		PublicKeyHex:  pubKeyHex, // Same as w.Address if using PublicKeyToAddress
-		Address:       w.Address,
-	}
-
-	// Using JSON for simplicity. Gob would also work.
-	// jsonData, err := json.MarshalIndent(data, "", "  ")
-	// if err != nil {
-	// 	return fmt.Errorf("failed to marshal wallet data to JSON: %w", err)
-	// }
-	// return os.WriteFile(filepath, jsonData, 0600) // 0600 for private key file
+		// The JSON is assembled by hand (same layout as json.MarshalIndent of
+		// WalletData) so the key is never converted to an immutable string.
+		// Hex strings need no JSON escaping.
+		var buf bytes.Buffer
+		defer func() { zeroBytes(buf.Bytes()[:buf.Cap()]) }()
+		buf.WriteString("{\n  \"privateKeyHex\": \"")
+		buf.Write(privKeyHex)
+		buf.WriteString("\",\n  \"publicKeyHex\": \"" + pubKeyHex + "\",\n  \"address\": \"" + w.Address + "\"\n}")
 
-	// For this task, we will use JSON encoding.
-	// IMPORTANT: In a real application, the private key MUST be encrypted before saving.
-	// This implementation does not include encryption.
-	jsonData, err := json.MarshalIndent(data, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal wallet data to JSON: %w", err)
-	}
+		// 0600: the file holds an unencrypted private key.
+		if err := os.WriteFile(filepath, buf.Bytes(), 0600); err != nil {
+			return fmt.Errorf("failed to write wallet file %s: %w", filepath, err)
+		}
+		return nil
+	})
+}
 
-	// TODO: Add a comment about file permissions for private key files (e.g., 0600)
-	err = os.WriteFile(filepath, jsonData, 0600) // Restrictive permissions
-	if err != nil {
-		return fmt.Errorf("failed to write wallet file %s: %w", filepath, err)
-	}
-	fmt.Printf("Wallet for address %s saved to %s (UNENCRYPTED - FOR DEMO ONLY)\n", w.Address, filepath)
-	return nil
+// walletFileData mirrors WalletData for loading, but keeps the private key as
+// raw JSON bytes so it can be wiped after decoding.
+type walletFileData struct {
+	PrivateKeyHex json.RawMessage `json:"privateKeyHex"`
+	PublicKeyHex  string          `json:"publicKeyHex"`
+	Address       string          `json:"address"`
 }
 
 // LoadWalletFromFile loads a wallet from a JSON file.
 // NOTE: This implementation assumes the private key in the file is NOT encrypted.
+// Intermediate copies of the key material are wiped before returning.
 func LoadWalletFromFile(filepath string) (*Wallet, error) {
 	// TODO: Implement proper decryption if the private key was encrypted during save.
 	fileData, err := os.ReadFile(filepath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read wallet file %s: %w", filepath, err)
 	}
+	defer zeroBytes(fileData)
 
-	var data WalletData
+	var data walletFileData
 	if err := json.Unmarshal(fileData, &data); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal wallet data from JSON: %w", err)
 	}
+	defer zeroBytes(data.PrivateKeyHex)
 
-	privKey, err := HexStringToPrivateKey(data.PrivateKeyHex)
+	rawHex := bytes.Trim(data.PrivateKeyHex, "\"")
+	der := make([]byte, hex.DecodedLen(len(rawHex)))
+	defer zeroBytes(der)
+	if _, err := hex.Decode(der, rawHex); err != nil {
+		return nil, fmt.Errorf("failed to decode hex string for private key: %w", err)
+	}
+	privKey, err := BytesToPrivateKey(der)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert hex to private key: %w", err)
 	}
@@ -173,26 +249,20 @@ func LoadWalletFromFile(filepath string) (*Wallet, error) {
 	// Verify the loaded address matches the address derived from the reconstructed public key
 	addressFromLoadedKey, err := PublicKeyToAddress(reconstructedPubKey)
 	if err != nil {
+		zeroPrivateKey(privKey)
 		return nil, fmt.Errorf("failed to derive address from loaded public key: %w", err)
 	}
-	if data.Address != addressFromLoadedKey {
+	if !constantTimeEqual(data.Address, addressFromLoadedKey) {
+		zeroPrivateKey(privKey)
 		// Also check against PublicKeyHex if it was different
 		if data.PublicKeyHex != "" {
 			addrFromHexPub, _ := AddressToPublicKey(data.PublicKeyHex)
 			if !reconstructedPubKey.Equal(addrFromHexPub) {
-				 return nil, fmt.Errorf("loaded public key hex %s does not match private key's public key", data.PublicKeyHex)
+				return nil, fmt.Errorf("loaded public key hex %s does not match private key's public key", data.PublicKeyHex)
 			}
 		}
-		// If PublicKeyHex matched or was empty, but Address doesn't match derived, it's an issue.
-		if data.Address != addressFromLoadedKey { // Re-check after potentially verifying PublicKeyHex
-			return nil, fmt.Errorf("loaded address %s does not match address derived from private key %s", data.Address, addressFromLoadedKey)
-		}
+		return nil, fmt.Errorf("loaded address %s does not match address derived from private key %s", data.Address, addressFromLoadedKey)
 	}
 
-
-	return &Wallet{
-		PrivateKey: privKey,
-		PublicKey:  reconstructedPubKey,
-		Address:    data.Address, // Use the validated address from file
-	}, nil
+	return newWallet(privKey, data.Address), nil // Use the validated address from file
 }
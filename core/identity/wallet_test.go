@@ -1,7 +1,7 @@
 package identity
 
 import (
-	"bytes"
+	"crypto/ecdsa"
 	"digisocialblock/core/ledger" // Adjust import path as necessary
 	"os"
 	"testing"
@@ -225,7 +225,9 @@ func TestWalletPersistence(t *testing.T) {
 // Test that SignTransaction correctly updates the transaction's SenderPublicKey if it's empty.
 func TestWallet_SignTransaction_SetsSenderPublicKey(t *testing.T) {
 	wallet, _ := NewWallet()
-	tx, _ := ledger.NewTransaction("", ledger.PostCreated, []byte("payload")) // Empty SenderPublicKey initially
+	// NewTransaction requires a sender, so clear it afterwards.
+	tx, _ := ledger.NewTransaction(wallet.Address, ledger.PostCreated, []byte("payload"))
+	tx.SenderPublicKey = "" // Empty SenderPublicKey initially
 
 	err := wallet.SignTransaction(tx)
 	if err != nil {
@@ -0,0 +1,372 @@
+// Package invariant re-checks, in the background, properties a node relies on
+// but only verifies when data first arrives: that stored blocks still hash and
+// link correctly and carry valid signatures, that pinned content still matches
+// its CIDs, and that the social indexes agree with the chain. A failure means
+// memory or disk corruption, or a bug in incremental indexing, that would
+// otherwise only surface when a peer or user hits it.
+//
+// A Checker runs a round of checks every Interval. Each round re-validates the
+// most recent blocks, verifies a few pinned CIDs (rotating through the pin set
+// over successive rounds) and cross-checks the indexes against the recent
+// blocks. Violations are passed to Options.OnViolation and counted in the
+// statistics Var publishes, e.g. as api.ServerOptions.Diagnostics. To alert
+// webhook subscribers, publish them as webhook.EventInvariantViolation:
+//
+//	OnViolation: func(v invariant.Violation) {
+//		dispatcher.Publish(webhook.Event{ID: v.Subject, Type: webhook.EventInvariantViolation, Data: v})
+//	}
+package invariant
+
+import (
+	"context"
+	"digisocialblock/core/content"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"expvar"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Defaults for zero Options fields.
+const (
+	DefaultInterval     = time.Minute
+	DefaultRecentBlocks = 16
+	DefaultSpotChecks   = 4
+)
+
+// Check names one of the checks a round runs.
+type Check string
+
+const (
+	CheckBlocks     Check = "blocks"      // Subject is the block hash
+	CheckContent    Check = "content"     // Subject is the manifest CID
+	CheckFeedIndex  Check = "feed-index"  // Subject is the transaction ID, or the block hash for a diverged index
+	CheckGraphIndex Check = "graph-index" // Likewise
+)
+
+// Violation is a failed check.
+type Violation struct {
+	Check   Check     `json:"check"`
+	Subject string    `json:"subject"`
+	Detail  string    `json:"detail"`
+	At      time.Time `json:"at"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s %s: %s", v.Check, v.Subject, v.Detail)
+}
+
+// PinSet lists the manifest CIDs of content the node has pinned.
+type PinSet interface {
+	Pinned() []string
+}
+
+// Options configures a Checker. Every check but the block check is optional
+// and runs only if what it checks is set.
+type Options struct {
+	Interval     time.Duration // Between rounds; defaults to DefaultInterval
+	RecentBlocks int           // Blocks at the tip checked each round; defaults to DefaultRecentBlocks
+
+	// Pins and Content enable the content check: SpotChecks pinned CIDs
+	// (default DefaultSpotChecks) are retrieved through Content each round
+	// and every chunk verified.
+	Pins       PinSet
+	Content    *content.ContentRetriever
+	SpotChecks int
+
+	Feed  *social.FeedService
+	Graph *social.GraphIndex
+
+	// OnViolation is called for each violation found, from the goroutine
+	// running the round.
+	OnViolation func(Violation)
+}
+
+// Checker runs invariant checks against a chain and the node state derived
+// from it. It is safe for concurrent use.
+type Checker struct {
+	bc   *ledger.Blockchain
+	opts Options
+	now  func() time.Time
+
+	mu         sync.Mutex
+	pinCursor  int // Position in the pin set of the next spot check
+	rounds     int64
+	violations map[Check]int64
+	lastRound  time.Time
+	last       *Violation
+}
+
+// New creates a Checker for bc.
+func New(bc *ledger.Blockchain, opts Options) (*Checker, error) {
+	if bc == nil {
+		return nil, fmt.Errorf("blockchain cannot be nil")
+	}
+	if opts.Pins != nil && opts.Content == nil {
+		return nil, fmt.Errorf("content retriever is required to check pinned content")
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.RecentBlocks <= 0 {
+		opts.RecentBlocks = DefaultRecentBlocks
+	}
+	if opts.SpotChecks <= 0 {
+		opts.SpotChecks = DefaultSpotChecks
+	}
+	return &Checker{bc: bc, opts: opts, now: time.Now, violations: make(map[Check]int64)}, nil
+}
+
+// Run runs a round every Interval until ctx is done.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Round(ctx)
+		}
+	}
+}
+
+// Round runs every check once and returns the violations found, which have
+// also been reported to OnViolation.
+func (c *Checker) Round(ctx context.Context) []Violation {
+	var found []Violation
+	report := func(check Check, subject, format string, args ...interface{}) {
+		v := Violation{Check: check, Subject: subject, Detail: fmt.Sprintf(format, args...), At: c.now()}
+		found = append(found, v)
+		if c.opts.OnViolation != nil {
+			c.opts.OnViolation(v)
+		}
+	}
+
+	blocks := c.recentBlocks()
+	c.checkBlocks(blocks, report)
+	if c.opts.Pins != nil {
+		c.checkContent(ctx, report)
+	}
+	if c.opts.Feed != nil {
+		c.checkFeed(blocks, report)
+	}
+	if c.opts.Graph != nil {
+		c.checkGraph(blocks, report)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rounds++
+	c.lastRound = c.now()
+	for _, v := range found {
+		c.violations[v.Check]++
+		last := v
+		c.last = &last
+	}
+	return found
+}
+
+type reportFunc func(check Check, subject, format string, args ...interface{})
+
+// recentBlocks returns the last RecentBlocks blocks in chain order, preceded
+// by the block before them to check the first link against. On a short chain
+// they start at genesis.
+func (c *Checker) recentBlocks() []*ledger.Block {
+	latest := c.bc.GetLatestBlock()
+	if latest == nil {
+		return nil
+	}
+	from := latest.Index - int64(c.opts.RecentBlocks)
+	if from < 0 {
+		from = 0
+	}
+	var blocks []*ledger.Block
+	for i := from; i <= latest.Index; i++ {
+		blocks = append(blocks, c.bc.GetBlockByIndex(i))
+	}
+	return blocks
+}
+
+// checkBlocks re-validates each block after the first of blocks against its
+// predecessor, and re-verifies every signature. The chain's signature cache
+// is bypassed: a cached result is exactly what this check must not trust.
+func (c *Checker) checkBlocks(blocks []*ledger.Block, report reportFunc) {
+	for i := 1; i < len(blocks); i++ {
+		block, prev := blocks[i], blocks[i-1]
+		if block == nil || prev == nil {
+			report(CheckBlocks, "", "block %d is missing from the chain", blocks[0].Index+int64(i))
+			return
+		}
+		if err := block.IsValid(prev); err != nil {
+			report(CheckBlocks, block.Hash, "block %d: %v", block.Index, err)
+			continue
+		}
+		for _, tx := range block.Transactions {
+			if ok, err := tx.VerifySignature(); err != nil {
+				report(CheckBlocks, block.Hash, "block %d: transaction %s: %v", block.Index, tx.ID, err)
+			} else if !ok {
+				report(CheckBlocks, block.Hash, "block %d: transaction %s: signature does not verify", block.Index, tx.ID)
+			}
+		}
+	}
+}
+
+// checkContent retrieves the next SpotChecks pinned CIDs and reads them to
+// the end, which verifies every chunk against its CID.
+func (c *Checker) checkContent(ctx context.Context, report reportFunc) {
+	pinned := c.opts.Pins.Pinned()
+	if len(pinned) == 0 {
+		return
+	}
+	n := c.opts.SpotChecks
+	if n > len(pinned) {
+		n = len(pinned)
+	}
+	c.mu.Lock()
+	start := c.pinCursor % len(pinned)
+	c.pinCursor = start + n
+	c.mu.Unlock()
+
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		cid := pinned[(start+i)%len(pinned)]
+		if err := c.verifyContent(cid); err != nil {
+			report(CheckContent, cid, "%v", err)
+		}
+	}
+}
+
+func (c *Checker) verifyContent(cid string) error {
+	stream, err := c.opts.Content.OpenStream(cid, content.ReadAheadOptions{})
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	_, err = io.Copy(io.Discard, stream)
+	return err
+}
+
+// checkFeed checks that the feed index is on the chain and holds exactly the
+// posts of the recent blocks it has processed.
+func (c *Checker) checkFeed(blocks []*ledger.Block, report reportFunc) {
+	highWater, hash := c.opts.Feed.HighWaterMark()
+	if !c.onChain(CheckFeedIndex, highWater, hash, report) {
+		return
+	}
+	indexed := make(map[string]bool)
+	for _, block := range blocks {
+		if block == nil || block.Index > highWater {
+			continue
+		}
+		for _, tx := range block.Transactions {
+			if tx.Type != ledger.PostCreated {
+				continue
+			}
+			post, err := social.PostFromPayload(tx.Payload)
+			if err != nil || post.AuthorPublicKey != tx.SenderPublicKey {
+				continue // Not indexed by design
+			}
+			indexed[tx.ID] = true
+			entry, ok := c.opts.Feed.GetPost(tx.ID)
+			switch {
+			case !ok:
+				report(CheckFeedIndex, tx.ID, "post in block %d is missing from the feed index", block.Index)
+			case entry.BlockIndex != block.Index || entry.AuthorPublicKey != post.AuthorPublicKey || entry.ContentCID != post.ContentCID:
+				report(CheckFeedIndex, tx.ID, "feed index has block %d, author %s, CID %s; chain has block %d, author %s, CID %s",
+					entry.BlockIndex, entry.AuthorPublicKey, entry.ContentCID, block.Index, post.AuthorPublicKey, post.ContentCID)
+			}
+		}
+	}
+	// Entries claiming a recent block must come from it.
+	oldest := int64(-1)
+	if len(blocks) > 0 && blocks[0] != nil {
+		oldest = blocks[0].Index
+	}
+	for _, entry := range c.opts.Feed.GetGlobalFeed(0) {
+		if entry.BlockIndex < oldest {
+			break // Newest first; the rest are older still
+		}
+		if entry.BlockIndex <= highWater && !indexed[entry.TxID] {
+			report(CheckFeedIndex, entry.TxID, "feed index entry for block %d is not a post in that block", entry.BlockIndex)
+		}
+	}
+}
+
+// checkGraph checks that the graph index is on the chain and holds the
+// comments and follows of the recent blocks it has processed.
+func (c *Checker) checkGraph(blocks []*ledger.Block, report reportFunc) {
+	highWater, hash := c.opts.Graph.HighWaterMark()
+	if !c.onChain(CheckGraphIndex, highWater, hash, report) {
+		return
+	}
+	for _, block := range blocks {
+		if block == nil || block.Index > highWater {
+			continue
+		}
+		for _, tx := range block.Transactions {
+			switch tx.Type {
+			case ledger.CommentAdded:
+				comment, err := social.CommentFromPayload(tx.Payload)
+				if err != nil || comment.AuthorPublicKey != tx.SenderPublicKey {
+					continue // Not indexed by design
+				}
+				// A transaction seen twice is indexed at its first block.
+				if entry, ok := c.opts.Graph.GetComment(tx.ID); !ok || entry.BlockIndex > block.Index || entry.PostTxID != comment.PostTxID {
+					report(CheckGraphIndex, tx.ID, "comment in block %d is missing from the graph index or recorded differently", block.Index)
+				}
+			case ledger.UserFollowed:
+				follow, err := social.FollowFromPayload(tx.Payload)
+				if err != nil || follow.FolloweePublicKey == tx.SenderPublicKey {
+					continue
+				}
+				// Only the first follow of a pair is recorded.
+				found := false
+				for _, edge := range c.opts.Graph.GetFollowing(tx.SenderPublicKey) {
+					found = found || (edge.Followee == follow.FolloweePublicKey && edge.BlockIndex <= block.Index)
+				}
+				if !found {
+					report(CheckGraphIndex, tx.ID, "follow of %s in block %d is missing from the graph index", follow.FolloweePublicKey, block.Index)
+				}
+			}
+		}
+	}
+}
+
+// onChain reports whether the block an index processed last is on the chain,
+// reporting a violation if not. An index that has processed nothing passes.
+func (c *Checker) onChain(check Check, highWater int64, hash string, report reportFunc) bool {
+	if highWater < 0 {
+		return true
+	}
+	if block := c.bc.GetBlockByIndex(highWater); block == nil || block.Hash != hash {
+		report(check, hash, "index processed block %d with hash %s, which is not on the chain", highWater, hash)
+		return false
+	}
+	return true
+}
+
+// Stats are a Checker's counters.
+type Stats struct {
+	Rounds     int64           `json:"rounds"`
+	Violations map[Check]int64 `json:"violations"`
+	LastRound  time.Time       `json:"lastRound"`
+	Last       *Violation      `json:"lastViolation,omitempty"`
+}
+
+// Stats returns the checker's counters.
+func (c *Checker) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := Stats{Rounds: c.rounds, Violations: make(map[Check]int64, len(c.violations)), LastRound: c.lastRound, Last: c.last}
+	for check, n := range c.violations {
+		s.Violations[check] = n
+	}
+	return s
+}
+
+// Var publishes Stats, e.g. as an entry of api.ServerOptions.Diagnostics.
+func (c *Checker) Var() expvar.Var {
+	return expvar.Func(func() interface{} { return c.Stats() })
+}
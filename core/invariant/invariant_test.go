@@ -0,0 +1,182 @@
+package invariant
+
+import (
+	"context"
+	"digisocialblock/core/content"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"digisocialblock/internal/testutil"
+	"digisocialblock/internal/testutil/fixture"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type pinList []string
+
+func (p pinList) Pinned() []string { return p }
+
+// savedFeedIndex is a FeedIndexStore holding a fixed state.
+type savedFeedIndex struct{ state *social.FeedIndexState }
+
+func (s savedFeedIndex) LoadFeedIndex() (*social.FeedIndexState, error) { return s.state, nil }
+func (s savedFeedIndex) SaveFeedIndex(*social.FeedIndexState) error     { return nil }
+
+// invariantTestNode is a chain with posts, a comment and a follow, indexes
+// synced to it and two pinned pieces of content.
+type invariantTestNode struct {
+	bc      *ledger.Blockchain
+	feed    *social.FeedService
+	graph   *social.GraphIndex
+	storage *testutil.Storage
+	pins    pinList
+	opts    Options
+}
+
+func newInvariantTestNode(t *testing.T) *invariantTestNode {
+	t.Helper()
+	alice, bob := fixture.Wallet(t), fixture.Wallet(t)
+	post := fixture.PostTx(t, alice, "cid-1")
+	comment := fixture.Tx(t, bob, ledger.CommentAdded, &social.Comment{AuthorPublicKey: bob.Address, PostTxID: post.ID, ContentCID: "cid-c", Timestamp: time.Now().UnixNano()})
+	bc := fixture.Chain(t,
+		[]*ledger.Transaction{post},
+		[]*ledger.Transaction{comment, fixture.FollowTx(t, bob, alice.Address)},
+		[]*ledger.Transaction{fixture.PostTx(t, bob, "cid-2")},
+	)
+
+	n := &invariantTestNode{bc: bc, graph: social.NewGraphIndex(), storage: testutil.NewStorage()}
+	var err error
+	if n.feed, err = social.NewFeedService(nil); err != nil {
+		t.Fatalf("NewFeedService() error = %v", err)
+	}
+	if _, err := n.feed.Sync(bc); err != nil {
+		t.Fatalf("feed Sync() error = %v", err)
+	}
+	if _, err := n.graph.Sync(bc); err != nil {
+		t.Fatalf("graph Sync() error = %v", err)
+	}
+
+	manifests := testutil.NewManifestFetcher()
+	for _, data := range []string{"first pinned content", "second pinned content, a little longer"} {
+		manifest, chunks := testutil.Chunk([]byte(data), 8)
+		for _, chunk := range chunks {
+			n.storage.Put(chunk.ChunkCID, chunk.Data)
+		}
+		manifests.Add(manifest.ManifestCID, manifest)
+		n.pins = append(n.pins, manifest.ManifestCID)
+	}
+	retriever, err := content.NewContentRetriever(manifests, n.storage)
+	if err != nil {
+		t.Fatalf("NewContentRetriever() error = %v", err)
+	}
+	n.opts = Options{Pins: n.pins, Content: retriever, Feed: n.feed, Graph: n.graph}
+	return n
+}
+
+func (n *invariantTestNode) checker(t *testing.T) *Checker {
+	t.Helper()
+	c, err := New(n.bc, n.opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return c
+}
+
+func TestRound_HealthyNode(t *testing.T) {
+	n := newInvariantTestNode(t)
+	c := n.checker(t)
+	if found := c.Round(context.Background()); len(found) != 0 {
+		t.Fatalf("Round() on a healthy node = %v", found)
+	}
+	if s := c.Stats(); s.Rounds != 1 || len(s.Violations) != 0 || s.Last != nil {
+		t.Errorf("Stats() = %+v", s)
+	}
+}
+
+func TestRound_ReportsTamperedBlockAndContent(t *testing.T) {
+	n := newInvariantTestNode(t)
+	var alerted []Violation
+	n.opts.OnViolation = func(v Violation) { alerted = append(alerted, v) }
+	c := n.checker(t)
+
+	// The chain's signature cache already holds this transaction; the
+	// checker must verify it again.
+	block := n.bc.GetBlockByIndex(1)
+	block.Transactions[0].Signature[4] ^= 0xff
+	manifest, _ := testutil.Chunk([]byte("first pinned content"), 8)
+	n.storage.Put(manifest.Chunks[1].ChunkCID, []byte("tampered"))
+
+	found := c.Round(context.Background())
+	var checks []string
+	for _, v := range found {
+		checks = append(checks, string(v.Check)+" "+v.Subject)
+	}
+	got := strings.Join(checks, "\n")
+	if !strings.Contains(got, "blocks "+block.Hash) || !strings.Contains(got, "content "+n.pins[0]) {
+		t.Errorf("violations =\n%s\nwant the tampered signature and chunk", got)
+	}
+	if len(alerted) != len(found) {
+		t.Errorf("OnViolation called %d times for %d violations", len(alerted), len(found))
+	}
+	s := c.Stats()
+	if s.Violations[CheckBlocks] == 0 || s.Violations[CheckContent] == 0 || s.Last == nil {
+		t.Errorf("Stats() = %+v", s)
+	}
+	var published Stats
+	if err := json.Unmarshal([]byte(c.Var().String()), &published); err != nil || published.Rounds != 1 {
+		t.Errorf("Var() = %s, %v", c.Var().String(), err)
+	}
+}
+
+func TestRound_SpotChecksRotateThroughPins(t *testing.T) {
+	n := newInvariantTestNode(t)
+	n.opts.SpotChecks = 1
+	n.opts.Feed, n.opts.Graph = nil, nil
+	manifest, _ := testutil.Chunk([]byte("second pinned content, a little longer"), 8)
+	n.storage.Put(manifest.Chunks[0].ChunkCID, []byte("tampered"))
+	c := n.checker(t)
+
+	if found := c.Round(context.Background()); len(found) != 0 {
+		t.Errorf("first round checked %v, want only the intact first pin", found)
+	}
+	if found := c.Round(context.Background()); len(found) != 1 || found[0].Subject != n.pins[1] {
+		t.Errorf("second round = %v, want the tampered second pin", found)
+	}
+}
+
+func TestRound_ReportsIndexDivergence(t *testing.T) {
+	n := newInvariantTestNode(t)
+
+	// A feed index holding the newest post, missing the one in block 1 and
+	// holding a post the chain never had.
+	mark, hash := n.feed.HighWaterMark()
+	entries := n.feed.GetGlobalFeed(0)
+	phantom := entries[0]
+	phantom.TxID = "phantom"
+	state := &social.FeedIndexState{HighWaterIndex: mark, HighWaterHash: hash, Entries: []social.FeedEntry{entries[0], phantom}}
+	corrupt, err := social.NewFeedService(savedFeedIndex{state})
+	if err != nil {
+		t.Fatalf("NewFeedService() error = %v", err)
+	}
+	n.opts.Feed = corrupt
+
+	// A graph index synced to another chain.
+	other := social.NewGraphIndex()
+	if _, err := other.Sync(newInvariantTestNode(t).bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	n.opts.Graph = other
+
+	got := map[string]bool{}
+	for _, v := range n.checker(t).Round(context.Background()) {
+		got[string(v.Check)+" "+v.Subject] = true
+	}
+	missing := n.bc.GetBlockByIndex(1).Transactions[0].ID
+	_, otherHash := other.HighWaterMark()
+	for _, want := range []string{"feed-index " + missing, "feed-index phantom", "graph-index " + otherHash} {
+		if !got[want] {
+			t.Errorf("no violation %q in %v", want, got)
+		}
+	}
+}
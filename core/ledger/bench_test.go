@@ -0,0 +1,69 @@
+package ledger
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Hot-path benchmarks. Record a baseline with
+//
+//	go test -run '^$' -bench . -count 5 ./core/... > base.txt
+//
+// and compare a later run against it with cmd/benchcheck.
+
+// benchPayloadSizes are representative transaction payloads: a reaction, a
+// typical post metadata record and a large profile update.
+var benchPayloadSizes = []int{64, 1 << 10, 16 << 10}
+
+// benchTxCounts are representative numbers of transactions per block.
+var benchTxCounts = []int{1, 100, 1000}
+
+func benchTransactions(n int) []*Transaction {
+	txs := make([]*Transaction, n)
+	for i := range txs {
+		txs[i], _ = NewTransaction("bench-sender", PostCreated, []byte(fmt.Sprintf("bench payload %d", i)))
+	}
+	return txs
+}
+
+func BenchmarkHashTransactionContent(b *testing.B) {
+	for _, size := range benchPayloadSizes {
+		b.Run(fmt.Sprintf("payload=%d", size), func(b *testing.B) {
+			payload := []byte(strings.Repeat("p", size))
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				HashTransactionContent(int64(i), "bench-sender", PostCreated, payload)
+			}
+		})
+	}
+}
+
+func BenchmarkNewBlock(b *testing.B) {
+	for _, n := range benchTxCounts {
+		b.Run(fmt.Sprintf("txs=%d", n), func(b *testing.B) {
+			txs := benchTransactions(n)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := NewBlock(1, "bench-prev-hash", txs); err != nil {
+					b.Fatalf("NewBlock() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMerkleRoot(b *testing.B) {
+	for _, n := range benchTxCounts {
+		b.Run(fmt.Sprintf("hashes=%d", n), func(b *testing.B) {
+			hashes := GetTransactionHashes(benchTransactions(n))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				MerkleRoot(hashes)
+			}
+		})
+	}
+}
@@ -1,14 +1,16 @@
 package ledger
 
-import (
-	"fmt"
-	"time"
-)
+import "fmt"
 
 // NewBlock creates and returns a new block in the blockchain.
 // It takes the index, the hash of the previous block, and a list of transactions.
 // The block's own hash is calculated based on its content.
 func NewBlock(index int64, prevBlockHash string, transactions []*Transaction) (*Block, error) {
+	return NewBlockWithClock(SystemClock, index, prevBlockHash, transactions)
+}
+
+// NewBlockWithClock is NewBlock with the block stamped by clock.
+func NewBlockWithClock(clock Clock, index int64, prevBlockHash string, transactions []*Transaction) (*Block, error) {
 	if transactions == nil {
 		// Allow blocks with no transactions (e.g. genesis block might not have app-level transactions)
 		// but ensure it's an empty slice not a nil one for consistency.
@@ -17,7 +19,7 @@ func NewBlock(index int64, prevBlockHash string, transactions []*Transaction) (*
 
 	block := &Block{
 		Index:         index,
-		Timestamp:     time.Now().UnixNano(),
+		Timestamp:     clock.Now().UnixNano(),
 		Transactions:  transactions,
 		PrevBlockHash: prevBlockHash,
 		// Hash will be calculated next
@@ -39,18 +41,33 @@ func NewBlock(index int64, prevBlockHash string, transactions []*Transaction) (*
 }
 
 // IsValid checks basic validity of the block structure and its hash.
-// It does not validate individual transactions here, that's a separate concern.
+// It combines validateLink (ordering against prevBlock) and validateContent
+// (hash and transaction checks).
 func (b *Block) IsValid(prevBlock *Block) error {
+	if err := b.validateLink(prevBlock); err != nil {
+		return err
+	}
+	return b.validateContent()
+}
+
+// validateLink checks that the block correctly follows prevBlock
+// (index, previous hash and timestamp ordering).
+func (b *Block) validateLink(prevBlock *Block) error {
 	if b.Index != prevBlock.Index+1 {
 		return fmt.Errorf("invalid block index: expected %d, got %d", prevBlock.Index+1, b.Index)
 	}
-	if b.PrevBlockHash != prevBlock.Hash {
+	if !hashesEqual(b.PrevBlockHash, prevBlock.Hash) {
 		return fmt.Errorf("invalid previous block hash: expected %s, got %s", prevBlock.Hash, b.PrevBlockHash)
 	}
 	if b.Timestamp <= prevBlock.Timestamp && prevBlock.Index > 0 { // Allow genesis to have any timestamp
 		return fmt.Errorf("invalid block timestamp: block %d timestamp %d before or same as prev block %d timestamp %d", b.Index, b.Timestamp, prevBlock.Index, prevBlock.Timestamp)
 	}
+	return nil
+}
 
+// validateContent recalculates the block hash and validates the contained transactions.
+// It only depends on the block itself, so it can run concurrently for different blocks.
+func (b *Block) validateContent() error {
 	// Recalculate hash to verify integrity
 	var txHashes []string
 	if len(b.Transactions) > 0 {
@@ -59,7 +76,7 @@ func (b *Block) IsValid(prevBlock *Block) error {
 	merkleRoot := MerkleRoot(txHashes)
 	expectedHash := HashBlockContent(b.Index, b.Timestamp, b.PrevBlockHash, merkleRoot)
 
-	if b.Hash != expectedHash {
+	if !hashesEqual(b.Hash, expectedHash) {
 		return fmt.Errorf("invalid block hash: expected %s, got %s", expectedHash, b.Hash)
 	}
 
@@ -66,27 +66,25 @@ func TestNewBlock(t *testing.T) {
 }
 
 func TestBlock_IsValid(t *testing.T) {
-	// Setup: Create a valid previous block (like a genesis block)
-	genesisTx, _ := NewTransaction("genesisSender", PostCreated, []byte("genesis payload"))
-	_ = genesisTx.Sign([]byte("dummyKey"))
-	prevBlock, _ := NewBlock(0, "0", []*Transaction{genesisTx})
+	// Setup: Create a valid previous block. It is not the genesis block, whose
+	// timestamp later blocks are not checked against.
+	priv, addr := newTestKey(t)
+	prevTx := newSignedTestTx(t, priv, addr, "previous payload")
+	prevBlock, _ := NewBlock(1, "genesis_hash_placeholder", []*Transaction{prevTx})
 	// Ensure prevBlock's timestamp is definitely in the past for subsequent blocks
 	prevBlock.Timestamp = time.Now().UnixNano() - 10000
 	prevBlock.Hash = HashBlockContent(prevBlock.Index, prevBlock.Timestamp, prevBlock.PrevBlockHash, MerkleRoot(GetTransactionHashes(prevBlock.Transactions)))
 
-
 	// Create a valid current block based on prevBlock
-	validTx1, _ := NewTransaction("senderA", PostCreated, []byte("valid payload 1"))
-	_ = validTx1.Sign([]byte("dummyKey"))
-	validTx2, _ := NewTransaction("senderB", CommentAdded, []byte("valid payload 2"))
-	_ = validTx2.Sign([]byte("dummyKey"))
+	validTx1 := newSignedTestTx(t, priv, addr, "valid payload 1")
+	validTx2, _ := NewTransaction(addr, CommentAdded, []byte("valid payload 2"))
+	_ = validTx2.Sign(priv)
 
 	validBlock, _ := NewBlock(prevBlock.Index+1, prevBlock.Hash, []*Transaction{validTx1, validTx2})
 	// Ensure this block's timestamp is after prevBlock for test determinism
 	validBlock.Timestamp = prevBlock.Timestamp + 500
 	validBlock.Hash = HashBlockContent(validBlock.Index, validBlock.Timestamp, validBlock.PrevBlockHash, MerkleRoot(GetTransactionHashes(validBlock.Transactions)))
 
-
 	tests := []struct {
 		name      string
 		blockFunc func() *Block // Function to generate the block for the test case
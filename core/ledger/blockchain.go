@@ -1,15 +1,46 @@
 package ledger
 
 import (
+	"context"
+	"digisocialblock/core/telemetry"
 	"fmt"
+	"runtime"
 	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracer traces block production; see package telemetry.
+var tracer = telemetry.Tracer("core/ledger")
+
 // Blockchain represents the append-only chain of blocks.
+//
+// A Blockchain is safe for concurrent use. Blocks and transactions it returns
+// are shared with the chain, not copied, and must be treated as read-only.
+// Blocks may only be accessed directly before the chain is shared between
+// goroutines (e.g. by tests building a chain); afterwards use the accessors.
 type Blockchain struct {
-	mu     sync.Mutex // For thread-safe access to the chain
-	Blocks []*Block
+	mu       sync.Mutex // For thread-safe access to the chain
+	Blocks   []*Block   // Guarded by mu once the chain is shared
+	sigCache *SignatureCache // Verified signatures, optionally shared with a Mempool
+	// validationWorkers is the number of goroutines IsChainValid uses for per-block
+	// hash/transaction checks. Zero means runtime.GOMAXPROCS(0).
+	validationWorkers int
+	store             BlockStore        // Optional persistence; nil keeps the chain in memory only
+	chainID           string            // Transactions for other chains are rejected by AddBlock
+	clock             Clock             // Stamps blocks made by AddBlock
+	allocations       map[string]uint64 // Balances before the first block; see SetGenesisAllocations
+	balances          map[string]uint64 // Non-zero balances as of the latest block; nil until computed
+	delegations       delegationSet     // Delegations in force as of the latest block; nil until computed
+	rule              TransactionRule   // Optional; see SetTransactionRule
+	reserved          map[string]string // Folded reserved handle to owner; see ApplyGenesis
+	systemAccounts    []SystemAccount   // See ApplyGenesis
+	invitePolicy      InvitePolicy      // See SetInvitePolicy
+	invites           *inviteState      // As of the latest block, if invite-only; nil until computed
+	params            []paramEpoch      // Protocol parameters by height; see SetParamSchedule
+	txIDs             map[string]int64  // Block index of each transaction ID on the chain; nil until computed
 	// TODO: Could add a map for quick block lookup by hash:
 	// blockIndex map[string]*Block
 }
@@ -35,11 +66,113 @@ func NewBlockchain() (*Blockchain, error) {
 	genesisBlock.Hash = HashBlockContent(genesisBlock.Index, genesisBlock.Timestamp, genesisBlock.PrevBlockHash, merkleRoot)
 
 
+	sigCache, err := NewSignatureCache(DefaultSignatureCacheSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signature cache: %w", err)
+	}
+
 	return &Blockchain{
-		Blocks: []*Block{genesisBlock},
+		Blocks:   []*Block{genesisBlock},
+		sigCache: sigCache,
+		chainID:  DefaultChainID,
+		clock:    SystemClock,
 	}, nil
 }
 
+// NewBlockchainWithStore opens a Blockchain persisted in store.
+// An empty store is initialized with a fresh genesis block; otherwise the
+// stored blocks are loaded and validated. Blocks added later via AddBlock are
+// appended to the store.
+func NewBlockchainWithStore(store BlockStore) (*Blockchain, error) {
+	if store == nil {
+		return nil, fmt.Errorf("block store cannot be nil")
+	}
+	blocks, err := store.LoadBlocks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load blocks from store: %w", err)
+	}
+
+	if len(blocks) == 0 {
+		bc, err := NewBlockchain()
+		if err != nil {
+			return nil, err
+		}
+		if err := store.AppendBlock(bc.Blocks[0]); err != nil {
+			return nil, fmt.Errorf("failed to persist genesis block: %w", err)
+		}
+		if err := store.Flush(); err != nil {
+			return nil, fmt.Errorf("failed to persist genesis block: %w", err)
+		}
+		bc.store = store
+		return bc, nil
+	}
+
+	sigCache, err := NewSignatureCache(DefaultSignatureCacheSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signature cache: %w", err)
+	}
+	bc := &Blockchain{Blocks: blocks, sigCache: sigCache, chainID: DefaultChainID, clock: SystemClock}
+	if valid, err := bc.IsChainValid(); !valid {
+		return nil, fmt.Errorf("stored chain is invalid: %w", err)
+	}
+	bc.store = store
+	return bc, nil
+}
+
+// SetSignatureCache replaces the chain's signature cache.
+// Pass the cache used by the node's Mempool so signatures verified at
+// admission are not verified again in AddBlock. A nil cache disables caching.
+func (bc *Blockchain) SetSignatureCache(sc *SignatureCache) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.sigCache = sc
+}
+
+// SignatureCache returns the chain's signature cache, which may be nil.
+func (bc *Blockchain) SignatureCache() *SignatureCache {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	return bc.sigCache
+}
+
+// SetChainID sets the chain ID that transactions must be signed for to be accepted by AddBlock.
+func (bc *Blockchain) SetChainID(chainID string) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.chainID = chainID
+}
+
+// ChainID returns the chain ID transactions must be signed for.
+func (bc *Blockchain) ChainID() string {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	return bc.chainID
+}
+
+// SetClock sets the clock that stamps blocks made by AddBlock. A block must be
+// stamped later than its predecessor, so the clock must move forward between
+// blocks. A nil clock restores SystemClock.
+func (bc *Blockchain) SetClock(clock Clock) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if clock == nil {
+		clock = SystemClock
+	}
+	bc.clock = clock
+}
+
+// SetValidationWorkers sets how many goroutines IsChainValid uses to validate
+// block contents. Values <= 0 restore the default of runtime.GOMAXPROCS(0);
+// 1 validates serially.
+func (bc *Blockchain) SetValidationWorkers(workers int) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if workers < 0 {
+		workers = 0
+	}
+	bc.validationWorkers = workers
+}
+
 // GetLatestBlock returns the most recent block in the chain.
 func (bc *Blockchain) GetLatestBlock() *Block {
 	bc.mu.Lock()
@@ -51,8 +184,25 @@ func (bc *Blockchain) GetLatestBlock() *Block {
 }
 
 // AddBlock creates a new block with the given transactions and adds it to the blockchain.
-// It performs validation before adding.
+// It performs validation before adding. The transactions slice is copied, so
+// the caller may reuse it, but the transactions themselves become part of the
+// chain and must not be modified afterwards.
 func (bc *Blockchain) AddBlock(transactions []*Transaction) (*Block, error) {
+	return bc.AddBlockContext(context.Background(), transactions)
+}
+
+// AddBlockContext is AddBlock as part of the trace in ctx. It records a
+// "ledger.AddBlock" span, which includes waiting for the chain lock, with
+// children "ledger.ValidateTransactions" and "ledger.PersistBlock".
+func (bc *Blockchain) AddBlockContext(ctx context.Context, transactions []*Transaction) (block *Block, err error) {
+	ctx, span := tracer.Start(ctx, "ledger.AddBlock", trace.WithAttributes(attribute.Int("ledger.transactions", len(transactions))))
+	defer func() {
+		if block != nil {
+			span.SetAttributes(attribute.Int64("ledger.block_index", block.Index))
+		}
+		telemetry.End(span, err)
+	}()
+
 	bc.mu.Lock()
 	defer bc.mu.Unlock()
 
@@ -60,26 +210,35 @@ func (bc *Blockchain) AddBlock(transactions []*Transaction) (*Block, error) {
 		return nil, fmt.Errorf("blockchain is not initialized with a genesis block")
 	}
 	latestBlock := bc.Blocks[len(bc.Blocks)-1]
+	transactions = append([]*Transaction{}, transactions...)
 
 	// Validate transactions before adding them to a block
-	for i, tx := range transactions {
-		if err := tx.IsValid(); err != nil {
-			return nil, fmt.Errorf("invalid transaction at index %d for new block: %w", i, err)
-		}
-		// In a real system, also verify signatures here if not done before (e.g. in a mempool)
-		validSig, err := tx.VerifySignature()
-		if err != nil {
-			return nil, fmt.Errorf("error verifying signature for transaction %s: %w", tx.ID, err)
-		}
-		if !validSig {
-			return nil, fmt.Errorf("invalid signature for transaction %s", tx.ID)
-		}
+	if err := bc.validateNewTransactions(ctx, transactions); err != nil {
+		return nil, err
+	}
+	if err := paramsAt(bc.params, latestBlock.Index+1).check(transactions); err != nil {
+		return nil, err
+	}
+	changes, err := bc.checkTransfersLocked(transactions)
+	if err != nil {
+		return nil, err
 	}
 
-	newBlock, err := NewBlock(latestBlock.Index+1, latestBlock.Hash, transactions)
+	newBlock, err := NewBlockWithClock(bc.clock, latestBlock.Index+1, latestBlock.Hash, transactions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create new block: %w", err)
 	}
+	delegations, err := bc.checkDelegationsLocked(transactions, newBlock.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	invites, err := bc.checkInvitesLocked(transactions, newBlock.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRule(bc.rule, transactions, RuleEnv{Height: newBlock.Index, Time: time.Unix(0, newBlock.Timestamp)}); err != nil {
+		return nil, err
+	}
 
 	// Validate the new block against the current latest block
 	// The IsValid method on Block already checks index, prevhash, and its own hash.
@@ -88,11 +247,126 @@ func (bc *Blockchain) AddBlock(transactions []*Transaction) (*Block, error) {
 		return nil, fmt.Errorf("newly created block is invalid: %w", err)
 	}
 
-	bc.Blocks = append(bc.Blocks, newBlock)
+	if err := bc.appendLocked(ctx, newBlock); err != nil {
+		return nil, err
+	}
+	bc.commitBalances(changes)
+	bc.commitDelegations(delegations)
+	bc.commitInvites(invites)
 	fmt.Printf("Block #%d added to the blockchain.\nHash: %s\n", newBlock.Index, newBlock.Hash)
 	return newBlock, nil
 }
 
+// AppendBlock adds a block built elsewhere, typically received from a peer,
+// to the tip of the chain. The block must follow the current tip and pass the
+// same transaction checks as AddBlock. It records a "ledger.AppendBlock" span.
+// Once appended, block is shared with the chain and must not be modified.
+func (bc *Blockchain) AppendBlock(block *Block) (err error) {
+	if block == nil {
+		return fmt.Errorf("cannot append a nil block")
+	}
+	ctx, span := tracer.Start(context.Background(), "ledger.AppendBlock", trace.WithAttributes(
+		attribute.Int64("ledger.block_index", block.Index),
+		attribute.Int("ledger.transactions", len(block.Transactions)),
+	))
+	defer func() { telemetry.End(span, err) }()
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if len(bc.Blocks) == 0 {
+		return fmt.Errorf("blockchain is not initialized with a genesis block")
+	}
+	if err := block.IsValid(bc.Blocks[len(bc.Blocks)-1]); err != nil {
+		return fmt.Errorf("block %d is invalid: %w", block.Index, err)
+	}
+	if err := bc.validateNewTransactions(ctx, block.Transactions); err != nil {
+		return fmt.Errorf("block %d: %w", block.Index, err)
+	}
+	if err := paramsAt(bc.params, block.Index).check(block.Transactions); err != nil {
+		return fmt.Errorf("block %d: %w", block.Index, err)
+	}
+	changes, err := bc.checkTransfersLocked(block.Transactions)
+	if err != nil {
+		return fmt.Errorf("block %d: %w", block.Index, err)
+	}
+	delegations, err := bc.checkDelegationsLocked(block.Transactions, block.Timestamp)
+	if err != nil {
+		return fmt.Errorf("block %d: %w", block.Index, err)
+	}
+	invites, err := bc.checkInvitesLocked(block.Transactions, block.Timestamp)
+	if err != nil {
+		return fmt.Errorf("block %d: %w", block.Index, err)
+	}
+	if err := checkRule(bc.rule, block.Transactions, RuleEnv{Height: block.Index, Time: time.Unix(0, block.Timestamp)}); err != nil {
+		return fmt.Errorf("block %d: %w", block.Index, err)
+	}
+	if err := bc.appendLocked(ctx, block); err != nil {
+		return err
+	}
+	bc.commitBalances(changes)
+	bc.commitDelegations(delegations)
+	bc.commitInvites(invites)
+	return nil
+}
+
+// appendLocked persists block, if the chain has a store, and appends it. The
+// caller must hold bc.mu and have validated the block.
+func (bc *Blockchain) appendLocked(ctx context.Context, block *Block) error {
+	// Persist before exposing the block; with group commit this only buffers the write.
+	if bc.store != nil {
+		_, persistSpan := tracer.Start(ctx, "ledger.PersistBlock")
+		err := bc.store.AppendBlock(block)
+		telemetry.End(persistSpan, err)
+		if err != nil {
+			return fmt.Errorf("failed to persist block %d: %w", block.Index, err)
+		}
+	}
+	bc.Blocks = append(bc.Blocks, block)
+	bc.indexTransactionsLocked(block)
+	return nil
+}
+
+// validateNewTransactions checks the transactions of a new block under a
+// "ledger.ValidateTransactions" span. The caller must hold bc.mu.
+func (bc *Blockchain) validateNewTransactions(ctx context.Context, transactions []*Transaction) (err error) {
+	_, span := tracer.Start(ctx, "ledger.ValidateTransactions")
+	defer func() { telemetry.End(span, err) }()
+
+	for i, tx := range transactions {
+		if err := bc.validateNewTransaction(i, tx); err != nil {
+			return reject(i, tx, err)
+		}
+	}
+	return bc.checkNewTransactionIDs(transactions)
+}
+
+// validateNewTransaction checks tx, at index i of a new block, against the
+// chain: its form, chain ID, signature and handles.
+func (bc *Blockchain) validateNewTransaction(i int, tx *Transaction) error {
+	if err := tx.IsValid(); err != nil {
+		return fmt.Errorf("invalid transaction at index %d for new block: %w", i, err)
+	}
+	if tx.ChainID != bc.chainID {
+		return fmt.Errorf("%w: transaction %s is for chain %q, not %q", ErrWrongChain, tx.ID, tx.ChainID, bc.chainID)
+	}
+	// Signatures already verified at mempool admission are served from the cache.
+	var validSig bool
+	var err error
+	if bc.sigCache != nil {
+		validSig, err = bc.sigCache.Verify(tx)
+	} else {
+		validSig, err = tx.VerifySignature()
+	}
+	if err != nil {
+		return fmt.Errorf("error verifying signature for transaction %s: %w", tx.ID, err)
+	}
+	if !validSig {
+		return fmt.Errorf("%w: transaction %s", ErrInvalidSignature, tx.ID)
+	}
+	return checkHandles(bc.reserved, tx)
+}
+
 // IsChainValid checks the integrity of the entire blockchain.
 // It verifies each block against its predecessor and validates hashes.
 func (bc *Blockchain) IsChainValid() (bool, error) {
@@ -115,23 +389,116 @@ func (bc *Blockchain) IsChainValid() (bool, error) {
 	}
 	merkleRoot := MerkleRoot(txHashes)
 	expectedGenesisHash := HashBlockContent(genesis.Index, genesis.Timestamp, genesis.PrevBlockHash, merkleRoot)
-	if genesis.Hash != expectedGenesisHash {
+	if !hashesEqual(genesis.Hash, expectedGenesisHash) {
 		return false, fmt.Errorf("genesis block hash mismatch: expected %s, got %s", expectedGenesisHash, genesis.Hash)
 	}
 
 
-	// Check subsequent blocks
+	// Check subsequent blocks.
+	// Links (index, prev hash, timestamp) are checked in order first; they are cheap
+	// and inherently sequential. The expensive per-block hash and transaction checks
+	// only depend on the block itself and are spread over a worker pool.
+	linkFailure := len(bc.Blocks)
+	var linkErr error
 	for i := 1; i < len(bc.Blocks); i++ {
-		currentBlock := bc.Blocks[i]
-		previousBlock := bc.Blocks[i-1]
-
-		if err := currentBlock.IsValid(previousBlock); err != nil {
-			return false, fmt.Errorf("chain validation failed at block %d: %w", currentBlock.Index, err)
+		if err := bc.Blocks[i].validateLink(bc.Blocks[i-1]); err != nil {
+			linkFailure, linkErr = i, err
+			break
 		}
 	}
+
+	// Content checks beyond the first broken link cannot change the result.
+	last := linkFailure
+	if last == len(bc.Blocks) {
+		last = len(bc.Blocks) - 1
+	}
+	contentFailure, contentErr := bc.validateContentsParallel(1, last)
+
+	// Report the lowest failing block; within the same block the link error wins,
+	// matching the order of checks in Block.IsValid.
+	if contentErr != nil && contentFailure < linkFailure {
+		return false, fmt.Errorf("chain validation failed at block %d: %w", bc.Blocks[contentFailure].Index, contentErr)
+	}
+	if linkErr != nil {
+		return false, fmt.Errorf("chain validation failed at block %d: %w", bc.Blocks[linkFailure].Index, linkErr)
+	}
 	return true, nil
 }
 
+// validateContentsParallel runs validateContent for blocks[from..to] (inclusive)
+// using the configured number of workers. It returns the position of the lowest
+// failing block and its error, or (-1, nil) if all blocks are valid.
+// The caller must hold bc.mu.
+func (bc *Blockchain) validateContentsParallel(from, to int) (int, error) {
+	if from > to {
+		return -1, nil
+	}
+	workers := bc.validationWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if n := to - from + 1; workers > n {
+		workers = n
+	}
+
+	var (
+		wg         sync.WaitGroup
+		failMu     sync.Mutex
+		failure    = -1
+		failureErr error
+		next       = from
+		nextMu     sync.Mutex
+	)
+	// Workers claim small batches of consecutive blocks from a shared cursor and
+	// stop early once a failure below their next batch has been recorded.
+	const batchSize = 64
+	claim := func() (int, int, bool) {
+		nextMu.Lock()
+		defer nextMu.Unlock()
+		if next > to {
+			return 0, 0, false
+		}
+		start := next
+		end := start + batchSize - 1
+		if end > to {
+			end = to
+		}
+		next = end + 1
+		return start, end, true
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				start, end, ok := claim()
+				if !ok {
+					return
+				}
+				failMu.Lock()
+				stop := failure != -1 && failure < start
+				failMu.Unlock()
+				if stop {
+					return
+				}
+				for i := start; i <= end; i++ {
+					if err := bc.Blocks[i].validateContent(); err != nil {
+						failMu.Lock()
+						if failure == -1 || i < failure {
+							failure, failureErr = i, err
+						}
+						failMu.Unlock()
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return failure, failureErr
+}
+
 // GetBlockByIndex returns a block by its index. Returns nil if not found.
 func (bc *Blockchain) GetBlockByIndex(index int64) *Block {
     bc.mu.Lock()
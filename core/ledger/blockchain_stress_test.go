@@ -0,0 +1,191 @@
+package ledger
+
+import (
+	"digisocialblock/internal/testutil"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The stress tests hammer shared types from many goroutines. They check the
+// results at the end, but mostly exist to be run under -race.
+
+// stressSize returns full in normal runs and a smaller count with -short.
+func stressSize(full int) int {
+	if testing.Short() {
+		return full / 4
+	}
+	return full
+}
+
+func TestBlockchain_ConcurrentAddAndRead(t *testing.T) {
+	store, err := OpenFileBlockStore(filepath.Join(t.TempDir(), "blocks.log"), FileBlockStoreOptions{FlushInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("OpenFileBlockStore() error = %v", err)
+	}
+	defer store.Close()
+	bc, err := NewBlockchainWithStore(store)
+	if err != nil {
+		t.Fatalf("NewBlockchainWithStore() error = %v", err)
+	}
+	bc.SetClock(testutil.NewClock(time.Millisecond))
+
+	writers, perWriter := 4, stressSize(20)
+	priv, addr := newTestKey(t)
+	txs := make([][]*Transaction, writers)
+	for w := range txs {
+		for i := 0; i < perWriter; i++ {
+			txs[w] = append(txs[w], newSignedTestTx(t, priv, addr, fmt.Sprintf("writer %d tx %d", w, i)))
+		}
+	}
+
+	var writersWG, readersWG sync.WaitGroup
+	done := make(chan struct{})
+	for w := 0; w < writers; w++ {
+		writersWG.Add(1)
+		go func(w int) {
+			defer writersWG.Done()
+			batch := make([]*Transaction, 1)
+			for _, tx := range txs[w] {
+				// Reusing batch checks that AddBlock does not keep the caller's slice.
+				batch[0] = tx
+				if _, err := bc.AddBlock(batch); err != nil {
+					t.Errorf("AddBlock() error = %v", err)
+					return
+				}
+			}
+		}(w)
+	}
+	for r := 0; r < 4; r++ {
+		readersWG.Add(1)
+		go func(r int) {
+			defer readersWG.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				latest := bc.GetLatestBlock()
+				if got := bc.GetBlockByHash(latest.Hash); got != latest {
+					t.Errorf("GetBlockByHash(latest) = %v, want the latest block", got)
+					return
+				}
+				if b := bc.GetBlockByIndex(int64(i) % (latest.Index + 1)); b == nil {
+					t.Errorf("GetBlockByIndex(%d) = nil with tip %d", int64(i)%(latest.Index+1), latest.Index)
+					return
+				}
+				for _, tx := range latest.Transactions {
+					if got, _ := bc.GetTransactionByID(tx.ID); got != tx {
+						t.Errorf("GetTransactionByID(%s) = %v, want the block's transaction", tx.ID, got)
+						return
+					}
+				}
+				if r == 0 && i%16 == 0 {
+					if valid, err := bc.IsChainValid(); !valid {
+						t.Errorf("IsChainValid() = false during writes: %v", err)
+						return
+					}
+				}
+			}
+		}(r)
+	}
+	writersWG.Wait()
+	close(done)
+	readersWG.Wait()
+
+	if got, want := bc.GetLatestBlock().Index, int64(writers*perWriter); got != want {
+		t.Errorf("tip index = %d, want %d", got, want)
+	}
+	if valid, err := bc.IsChainValid(); !valid {
+		t.Errorf("IsChainValid() = false after writes: %v", err)
+	}
+	for w := range txs {
+		for _, tx := range txs[w] {
+			if _, block := bc.GetTransactionByID(tx.ID); block == nil || len(block.Transactions) != 1 || block.Transactions[0] != tx {
+				t.Fatalf("transaction %s is not alone in its block", tx.ID)
+			}
+		}
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got, want := store.BlockCount(), int64(writers*perWriter+1); got != want {
+		t.Errorf("store.BlockCount() = %d, want %d", got, want)
+	}
+}
+
+func TestMempool_ConcurrentAddAndDrain(t *testing.T) {
+	sigCache, _ := NewSignatureCache(DefaultSignatureCacheSize)
+	mp := NewMempool(sigCache)
+	bc, _ := NewBlockchain()
+	bc.SetSignatureCache(sigCache)
+	bc.SetClock(testutil.NewClock(time.Millisecond))
+
+	producers, perProducer := 4, stressSize(40)
+	priv, addr := newTestKey(t)
+	txs := make([][]*Transaction, producers)
+	for p := range txs {
+		for i := 0; i < perProducer; i++ {
+			txs[p] = append(txs[p], newSignedTestTx(t, priv, addr, fmt.Sprintf("producer %d tx %d", p, i)))
+		}
+	}
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for _, tx := range txs[p] {
+				if err := mp.Add(tx); err != nil {
+					t.Errorf("Add() error = %v", err)
+					return
+				}
+			}
+		}(p)
+	}
+
+	// Drain concurrently, the way a block producer would.
+	drained := make(chan int)
+	stop := make(chan struct{})
+	go func() {
+		included := 0
+		for {
+			select {
+			case <-stop:
+				drained <- included
+				return
+			default:
+			}
+			pending := mp.Pending()
+			if len(pending) == 0 {
+				continue
+			}
+			if _, err := bc.AddBlock(pending); err != nil {
+				t.Errorf("AddBlock() error = %v", err)
+			}
+			ids := make([]string, len(pending))
+			for i, tx := range pending {
+				ids[i] = tx.ID
+			}
+			mp.Remove(ids...)
+			included += len(pending)
+		}
+	}()
+	wg.Wait()
+	close(stop)
+	included := <-drained
+	if rest := mp.Pending(); len(rest) > 0 {
+		if _, err := bc.AddBlock(rest); err != nil {
+			t.Fatalf("AddBlock() error = %v", err)
+		}
+		included += len(rest)
+	}
+	if included != producers*perProducer {
+		t.Errorf("included %d transactions, want %d", included, producers*perProducer)
+	}
+	if valid, err := bc.IsChainValid(); !valid {
+		t.Errorf("IsChainValid() = false: %v", err)
+	}
+}
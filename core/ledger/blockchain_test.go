@@ -1,7 +1,7 @@
 package ledger
 
 import (
-	"bytes"
+	"fmt"
 	"testing"
 	"time"
 )
@@ -37,15 +37,16 @@ func TestNewBlockchain(t *testing.T) {
 
 func TestBlockchain_AddBlock(t *testing.T) {
 	bc, _ := NewBlockchain()
+	priv, addr := newTestKey(t)
 
 	// Create some valid transactions
 	tx1Payload := []byte("first transaction data")
-	tx1, _ := NewTransaction("sender1", PostCreated, tx1Payload)
-	_ = tx1.Sign([]byte("dummyPrivateKey")) // Sign with placeholder
+	tx1, _ := NewTransaction(addr, PostCreated, tx1Payload)
+	_ = tx1.Sign(priv)
 
 	tx2Payload := []byte("second transaction data")
-	tx2, _ := NewTransaction("sender2", CommentAdded, tx2Payload)
-	_ = tx2.Sign([]byte("dummyPrivateKey"))
+	tx2, _ := NewTransaction(addr, CommentAdded, tx2Payload)
+	_ = tx2.Sign(priv)
 
 	transactions := []*Transaction{tx1, tx2}
 
@@ -75,7 +76,7 @@ func TestBlockchain_AddBlock(t *testing.T) {
 	}
 
 	// Test adding a block with an invalid transaction (e.g., unsigned or bad structure)
-	invalidTx, _ := NewTransaction("sender3", PostCreated, []byte("invalid payload"))
+	invalidTx, _ := NewTransaction(addr, PostCreated, []byte("invalid payload"))
 	// Not signing invalidTx, or tampering it:
 	// invalidTx.Signature = []byte{} // or tx.ID = "" to fail IsValid()
 
@@ -90,12 +91,13 @@ func TestBlockchain_AddBlock(t *testing.T) {
 
 func TestBlockchain_IsChainValid(t *testing.T) {
 	bc, _ := NewBlockchain()
+	priv, addr := newTestKey(t)
 
 	// Add a few valid blocks
 	for i := 0; i < 3; i++ {
 		payload := []byte("Block " + string(rune(i+1)) + " transaction")
-		tx, _ := NewTransaction("sender"+string(rune(i)), PostCreated, payload)
-		_ = tx.Sign([]byte("dummyKey"))
+		tx, _ := NewTransaction(addr, PostCreated, payload)
+		_ = tx.Sign(priv)
 		_, err := bc.AddBlock([]*Transaction{tx})
 		if err != nil {
 			t.Fatalf("Failed to add valid block during test setup: %v", err)
@@ -142,7 +144,6 @@ func TestBlockchain_IsChainValid(t *testing.T) {
 
 	// --- Test case: Tamper with a transaction within a block (after block was added) ---
 	if len(bc.Blocks) > 1 && len(bc.Blocks[1].Transactions) > 0 {
-		originalTxID := bc.Blocks[1].Transactions[0].ID
 		// This kind of tampering is subtle. If Block.IsValid recalculates Merkle root and block hash,
 		// and IsChainValid calls Block.IsValid, this should be caught.
 		// Our current Block.IsValid re-calculates the hash based on its *current* transactions.
@@ -167,7 +168,6 @@ func TestBlockchain_IsChainValid(t *testing.T) {
 		bc.Blocks[1].Transactions[0].Payload = originalTxPayload
 	}
 
-
 	// --- Test case: Invalid timestamp sequence ---
 	if len(bc.Blocks) > 2 {
 		originalTimestamp := bc.Blocks[2].Timestamp
@@ -190,15 +190,15 @@ func TestBlockchain_IsChainValid(t *testing.T) {
 	}
 }
 
-
 func TestBlockchain_Getters(t *testing.T) {
 	bc, _ := NewBlockchain()
-	tx1, _ := NewTransaction("s1", PostCreated, []byte("p1"))
-	_ = tx1.Sign([]byte("key"))
+	priv, addr := newTestKey(t)
+	tx1, _ := NewTransaction(addr, PostCreated, []byte("p1"))
+	_ = tx1.Sign(priv)
 	b1, _ := bc.AddBlock([]*Transaction{tx1})
 
-	tx2, _ := NewTransaction("s2", CommentAdded, []byte("p2"))
-	_ = tx2.Sign([]byte("key"))
+	tx2, _ := NewTransaction(addr, CommentAdded, []byte("p2"))
+	_ = tx2.Sign(priv)
 	b2, _ := bc.AddBlock([]*Transaction{tx2})
 
 	// Test GetLatestBlock
@@ -246,9 +246,10 @@ func TestBlockchain_Getters(t *testing.T) {
 }
 
 // Helper to ensure transactions are actually different for some tests
-func createUniqueTransaction(index int) *Transaction {
-	tx, _ := NewTransaction(fmt.Sprintf("sender%d", index), PostCreated, []byte(fmt.Sprintf("payload%d", index)))
-	_ = tx.Sign([]byte("dummy"))
+func createUniqueTransaction(tb testing.TB, index int) *Transaction {
+	priv, addr := newTestKey(tb)
+	tx, _ := NewTransaction(addr, PostCreated, []byte(fmt.Sprintf("payload%d", index)))
+	_ = tx.Sign(priv)
 	return tx
 }
 
@@ -256,7 +257,7 @@ func TestBlockchain_AddBlock_TransactionValidation(t *testing.T) {
 	bc, _ := NewBlockchain()
 
 	// Case 1: Transaction with empty ID (should fail IsValid())
-	txEmptyID := createUniqueTransaction(1)
+	txEmptyID := createUniqueTransaction(t, 1)
 	txEmptyID.ID = ""
 	// Note: tx.Sign() would fail if ID is empty, so we are testing IsValid() check within AddBlock
 	// For this specific test to work as intended (invalid tx failing AddBlock),
@@ -266,10 +267,10 @@ func TestBlockchain_AddBlock_TransactionValidation(t *testing.T) {
 	// Our current tx.IsValid() checks ID, Timestamp, SenderPublicKey, Type.
 	// NewTransaction sets these correctly. Let's make one invalid *after* creation.
 
-	txBadTimestamp := createUniqueTransaction(2)
+	txBadTimestamp := createUniqueTransaction(t, 2)
 	txBadTimestamp.Timestamp = 0 // Invalid timestamp
 
-	txNoSender := createUniqueTransaction(3)
+	txNoSender := createUniqueTransaction(t, 3)
 	txNoSender.SenderPublicKey = ""
 	// Recalculate ID for txNoSender to be consistent with its current (bad) state for IsValid() to focus on sender.
 	// This is a bit artificial as NewTransaction would prevent this.
@@ -283,7 +284,7 @@ func TestBlockchain_AddBlock_TransactionValidation(t *testing.T) {
 	}{
 		{
 			name:    "add block with valid transaction",
-			txs:     []*Transaction{createUniqueTransaction(0)},
+			txs:     []*Transaction{createUniqueTransaction(t, 0)},
 			wantErr: false,
 		},
 		{
@@ -323,14 +324,14 @@ func TestBlockchain_AddBlock_TransactionValidation(t *testing.T) {
 
 // Helper for TestBlockchain_AddBlock_TransactionValidation
 func NewTransactionUnsigned(senderPublicKey string, txType TransactionType, payload []byte) *Transaction {
-    ts := time.Now().UnixNano()
-    tx := &Transaction{
-        Timestamp:       ts,
-        SenderPublicKey: senderPublicKey,
-        Type:            txType,
-        Payload:         payload,
-    }
-    tx.ID = HashTransactionContent(tx.Timestamp, tx.SenderPublicKey, tx.Type, tx.Payload)
-    // tx.Signature remains nil
-    return tx
+	ts := time.Now().UnixNano()
+	tx := &Transaction{
+		Timestamp:       ts,
+		SenderPublicKey: senderPublicKey,
+		Type:            txType,
+		Payload:         payload,
+	}
+	tx.ID = HashTransactionContent(tx.Timestamp, tx.SenderPublicKey, tx.Type, tx.Payload)
+	// tx.Signature remains nil
+	return tx
 }
@@ -0,0 +1,203 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// testSpans records the spans ended by the package's tests. Tracers bind to
+// the first global provider, so TestMain installs it once and tracing tests
+// Reset it before they run.
+var testSpans = tracetest.NewInMemoryExporter()
+
+func TestMain(m *testing.M) {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(testSpans)))
+	os.Exit(m.Run())
+}
+
+// buildSyntheticChain assembles a valid chain of n blocks directly, bypassing
+// AddBlock (and its signature checks and logging) so large chains are cheap to build.
+func buildSyntheticChain(tb testing.TB, n int, txPerBlock int) *Blockchain {
+	tb.Helper()
+	bc, err := NewBlockchain()
+	if err != nil {
+		tb.Fatalf("NewBlockchain() error = %v", err)
+	}
+	prev := bc.Blocks[0]
+	for i := 1; i < n; i++ {
+		txs := make([]*Transaction, 0, txPerBlock)
+		for j := 0; j < txPerBlock; j++ {
+			tx, _ := NewTransaction("synthetic-sender", PostCreated, []byte(fmt.Sprintf("block %d tx %d", i, j)))
+			txs = append(txs, tx)
+		}
+		block := &Block{
+			Index:         prev.Index + 1,
+			Timestamp:     prev.Timestamp + 1,
+			Transactions:  txs,
+			PrevBlockHash: prev.Hash,
+		}
+		block.Hash = HashBlockContent(block.Index, block.Timestamp, block.PrevBlockHash, MerkleRoot(GetTransactionHashes(txs)))
+		bc.Blocks = append(bc.Blocks, block)
+		prev = block
+	}
+	return bc
+}
+
+func TestBlockchain_IsChainValid_ParallelMatchesSerial(t *testing.T) {
+	bc := buildSyntheticChain(t, 500, 2)
+
+	for _, workers := range []int{1, 4, 0} {
+		bc.SetValidationWorkers(workers)
+		if valid, err := bc.IsChainValid(); err != nil || !valid {
+			t.Fatalf("workers=%d: IsChainValid() = %v, %v; want true, nil", workers, valid, err)
+		}
+	}
+
+	// Tamper with two blocks; every worker count must report the lower one.
+	bc.Blocks[420].Transactions[0].SenderPublicKey = ""
+	bc.Blocks[123].Hash = "tampered"
+	for _, workers := range []int{1, 4, 16} {
+		bc.SetValidationWorkers(workers)
+		valid, err := bc.IsChainValid()
+		if valid || err == nil {
+			t.Fatalf("workers=%d: IsChainValid() on tampered chain returned valid", workers)
+		}
+		if !strings.Contains(err.Error(), "at block 123:") {
+			t.Errorf("workers=%d: error = %v, want failure at block 123", workers, err)
+		}
+	}
+}
+
+func TestBlockchain_IsChainValid_BrokenLinkBeforeContentFailure(t *testing.T) {
+	bc := buildSyntheticChain(t, 200, 1)
+	bc.Blocks[150].Hash = "tampered"      // content failure
+	bc.Blocks[40].PrevBlockHash = "wrong" // link failure (also breaks block 40's own hash)
+	bc.SetValidationWorkers(8)
+
+	_, err := bc.IsChainValid()
+	if err == nil || !strings.Contains(err.Error(), "at block 40:") {
+		t.Fatalf("IsChainValid() error = %v, want failure at block 40", err)
+	}
+	if !strings.Contains(err.Error(), "invalid previous block hash") {
+		t.Errorf("IsChainValid() error = %v, want the link error reported first", err)
+	}
+}
+
+var (
+	syntheticChainOnce sync.Once
+	syntheticChain     *Blockchain
+)
+
+// BenchmarkIsChainValid_100k validates a 100k-block synthetic chain serially and
+// with one worker per CPU.
+func BenchmarkIsChainValid_100k(b *testing.B) {
+	syntheticChainOnce.Do(func() { syntheticChain = buildSyntheticChain(b, 100000, 4) })
+
+	for _, workers := range []int{1, runtime.GOMAXPROCS(0)} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			syntheticChain.SetValidationWorkers(workers)
+			for i := 0; i < b.N; i++ {
+				if valid, err := syntheticChain.IsChainValid(); err != nil || !valid {
+					b.Fatalf("IsChainValid() = %v, %v", valid, err)
+				}
+			}
+		})
+	}
+}
+
+func TestBlockchain_AddBlock_RejectsTamperedPayload(t *testing.T) {
+	priv, addr := newTestKey(t)
+	tx := newSignedTestTx(t, priv, addr, "original payload")
+	bc, _ := NewBlockchain()
+
+	// Warm the signature cache with the genuine transaction, so only the
+	// content check stands between the tampered copy and the chain.
+	if _, err := bc.SignatureCache().Verify(tx); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	tampered := *tx
+	tampered.Payload = []byte("tampered payload")
+	_, err := bc.AddBlock([]*Transaction{&tampered})
+	if !errors.Is(err, ErrTransactionIDMismatch) {
+		t.Fatalf("AddBlock() with tampered payload error = %v, want ErrTransactionIDMismatch", err)
+	}
+	if _, err := bc.AddBlock([]*Transaction{tx}); err != nil {
+		t.Errorf("AddBlock() with the genuine transaction error = %v", err)
+	}
+}
+
+func TestBlockchain_AddBlockContextTracesValidation(t *testing.T) {
+	testSpans.Reset()
+	priv, addr := newTestKey(t)
+	bc, _ := NewBlockchain()
+
+	if _, err := bc.AddBlockContext(context.Background(), []*Transaction{newSignedTestTx(t, priv, addr, "traced")}); err != nil {
+		t.Fatalf("AddBlockContext() error = %v", err)
+	}
+	unsigned, _ := NewTransaction(addr, PostCreated, []byte("unsigned"))
+	if _, err := bc.AddBlockContext(context.Background(), []*Transaction{unsigned}); err == nil {
+		t.Fatal("AddBlockContext() with an unsigned transaction: expected error, got nil")
+	}
+
+	var added, failed, validations int
+	for _, span := range testSpans.GetSpans() {
+		switch span.Name {
+		case "ledger.AddBlock":
+			if span.Status.Code == codes.Error {
+				failed++
+			} else {
+				added++
+			}
+		case "ledger.ValidateTransactions":
+			validations++
+		}
+	}
+	if added != 1 || failed != 1 || validations != 2 {
+		t.Errorf("spans: %d added, %d failed, %d validations; want 1, 1, 2", added, failed, validations)
+	}
+}
+
+func TestBlockchain_AppendBlock(t *testing.T) {
+	priv, addr := newTestKey(t)
+	producer, _ := NewBlockchain()
+	block, err := producer.AddBlock([]*Transaction{newSignedTestTx(t, priv, addr, "relayed")})
+	if err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+
+	follower, _ := NewBlockchain()
+	if err := follower.AppendBlock(block); err != nil {
+		t.Fatalf("AppendBlock() error = %v", err)
+	}
+	if got := follower.GetLatestBlock(); got.Hash != block.Hash {
+		t.Errorf("latest block = %s, want %s", got.Hash, block.Hash)
+	}
+	if err := follower.AppendBlock(block); err == nil {
+		t.Error("AppendBlock() of the same block twice: expected error, got nil")
+	}
+
+	forged := *block
+	forged.Index = 2
+	forged.PrevBlockHash = block.Hash
+	forged.Timestamp = block.Timestamp + 1
+	unsigned, _ := NewTransaction(addr, PostCreated, []byte("unsigned"))
+	forged.Transactions = []*Transaction{unsigned}
+	forged.Hash = HashBlockContent(forged.Index, forged.Timestamp, forged.PrevBlockHash, MerkleRoot(GetTransactionHashes(forged.Transactions)))
+	if err := follower.AppendBlock(&forged); err == nil {
+		t.Error("AppendBlock() with an unsigned transaction: expected error, got nil")
+	}
+	if got := follower.GetLatestBlock().Index; got != 1 {
+		t.Errorf("latest index after rejections = %d, want 1", got)
+	}
+}
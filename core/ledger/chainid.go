@@ -0,0 +1,56 @@
+package ledger
+
+import (
+	"fmt"
+)
+
+// MaxChainIDLength bounds a chain ID. Chain IDs name the directories
+// parallel chains are stored in (see package namespace), so they are short
+// and made of characters that are safe in file names.
+const MaxChainIDLength = 64
+
+// ValidateChainID checks that chainID is 1 to MaxChainIDLength lowercase
+// letters, digits, '-' and '.', starting with a letter or digit, like
+// DefaultChainID.
+func ValidateChainID(chainID string) error {
+	if chainID == "" {
+		return fmt.Errorf("chain ID cannot be empty")
+	}
+	if len(chainID) > MaxChainIDLength {
+		return fmt.Errorf("chain ID is %d bytes, limit %d", len(chainID), MaxChainIDLength)
+	}
+	for i, c := range chainID {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case (c == '-' || c == '.') && i > 0:
+		default:
+			return fmt.Errorf("chain ID %q contains %q at position %d", chainID, c, i)
+		}
+	}
+	return nil
+}
+
+// NewBlockchainForChain opens the chain with ID chainID persisted in store,
+// like NewBlockchainWithStore followed by SetChainID, but also checks that
+// every stored transaction is for chainID, so a store holding another
+// network's chain is refused rather than extended.
+func NewBlockchainForChain(store BlockStore, chainID string) (*Blockchain, error) {
+	if err := ValidateChainID(chainID); err != nil {
+		return nil, err
+	}
+	bc, err := NewBlockchainWithStore(store)
+	if err != nil {
+		return nil, err
+	}
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	for _, block := range bc.Blocks {
+		for _, tx := range block.Transactions {
+			if tx.ChainID != chainID {
+				return nil, fmt.Errorf("%w: stored block %d holds transaction %s for chain %q, not %q", ErrWrongChain, block.Index, tx.ID, tx.ChainID, chainID)
+			}
+		}
+	}
+	bc.chainID = chainID
+	return bc, nil
+}
@@ -0,0 +1,46 @@
+package ledger
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateChainID(t *testing.T) {
+	for _, id := range []string{DefaultChainID, "a", "books.community", "testnet-2"} {
+		if err := ValidateChainID(id); err != nil {
+			t.Errorf("ValidateChainID(%q) error = %v", id, err)
+		}
+	}
+	for _, id := range []string{"", "-net", ".net", "Main", "a/b", "..", string(make([]byte, MaxChainIDLength+1))} {
+		if err := ValidateChainID(id); err == nil {
+			t.Errorf("ValidateChainID(%q) expected error, got nil", id)
+		}
+	}
+}
+
+func TestNewBlockchainForChain_RefusesAnotherChainsStore(t *testing.T) {
+	priv, addr := newTestKey(t)
+	path := filepath.Join(t.TempDir(), "chain.log")
+	store, _ := OpenFileBlockStore(path, FileBlockStoreOptions{})
+	bc, err := NewBlockchainForChain(store, "testnet")
+	if err != nil {
+		t.Fatalf("NewBlockchainForChain() error = %v", err)
+	}
+	if bc.ChainID() != "testnet" {
+		t.Errorf("ChainID() = %q, want testnet", bc.ChainID())
+	}
+	tx, _ := NewTransaction(addr, PostCreated, []byte("hello"))
+	tx.ChainID = "testnet"
+	tx.Sign(priv)
+	if _, err := bc.AddBlock([]*Transaction{tx}); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+	store.Close()
+
+	store, _ = OpenFileBlockStore(path, FileBlockStoreOptions{})
+	defer store.Close()
+	if _, err := NewBlockchainForChain(store, DefaultChainID); !errors.Is(err, ErrWrongChain) {
+		t.Errorf("NewBlockchainForChain() for another chain error = %v, want ErrWrongChain", err)
+	}
+}
@@ -0,0 +1,53 @@
+package ledger
+
+import (
+	"sync"
+)
+
+// CIDResolver returns the DDS CIDs of the content a transaction with the
+// given payload references, such as a post's content and media.
+type CIDResolver func(payload []byte) ([]string, error)
+
+// cidResolvers holds the resolvers of the transaction types that reference
+// content. Like co-signer resolvers, they are registered by the packages that
+// define the payloads.
+var cidResolvers = struct {
+	mu        sync.RWMutex
+	resolvers map[TransactionType]CIDResolver
+}{resolvers: make(map[TransactionType]CIDResolver)}
+
+// RegisterCIDResolver installs the CID resolver for txType, replacing any
+// previous one. A nil resolver removes it.
+func RegisterCIDResolver(txType TransactionType, resolver CIDResolver) {
+	cidResolvers.mu.Lock()
+	defer cidResolvers.mu.Unlock()
+	if resolver == nil {
+		delete(cidResolvers.resolvers, txType)
+		return
+	}
+	cidResolvers.resolvers[txType] = resolver
+}
+
+// ReferencedCIDs returns the CIDs of the content tx references, according to
+// the resolver registered for its type, without empty or repeated ones.
+func (tx *Transaction) ReferencedCIDs() ([]string, error) {
+	cidResolvers.mu.RLock()
+	resolver := cidResolvers.resolvers[tx.Type]
+	cidResolvers.mu.RUnlock()
+	if resolver == nil {
+		return nil, nil
+	}
+	cids, err := resolver(tx.Payload)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(cids))
+	out := cids[:0:0]
+	for _, cid := range cids {
+		if cid != "" && !seen[cid] {
+			seen[cid] = true
+			out = append(out, cid)
+		}
+	}
+	return out, nil
+}
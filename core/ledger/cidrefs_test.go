@@ -0,0 +1,22 @@
+package ledger
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTransaction_ReferencedCIDs(t *testing.T) {
+	const txType TransactionType = "CIDRefsTest"
+	RegisterCIDResolver(txType, func(payload []byte) ([]string, error) {
+		return []string{string(payload), "", "media", string(payload)}, nil
+	})
+	defer RegisterCIDResolver(txType, nil)
+
+	tx := &Transaction{Type: txType, Payload: []byte("content")}
+	if got, err := tx.ReferencedCIDs(); err != nil || !reflect.DeepEqual(got, []string{"content", "media"}) {
+		t.Errorf("ReferencedCIDs() = %v, %v; want each CID once", got, err)
+	}
+	if got, err := (&Transaction{Type: Like}).ReferencedCIDs(); got != nil || err != nil {
+		t.Errorf("ReferencedCIDs() of a type without a resolver = %v, %v; want none", got, err)
+	}
+}
@@ -0,0 +1,31 @@
+package ledger
+
+import (
+	"digisocialblock/core/clientkit"
+	"encoding/json"
+	"testing"
+)
+
+func TestTransaction_VerifiesClientkitSignature(t *testing.T) {
+	priv, _ := clientkit.GenerateKey()
+	address, _ := clientkit.Address(&priv.PublicKey)
+	clientTx, _ := clientkit.NewTransaction(address, clientkit.TypePostCreated, []byte(`{"k":"v"}`), "")
+	if err := clientTx.Sign(priv); err != nil {
+		t.Fatalf("clientkit Sign() error = %v", err)
+	}
+	data, _ := json.Marshal(clientTx)
+
+	var tx Transaction
+	if err := json.Unmarshal(data, &tx); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if tx.Type != PostCreated {
+		t.Errorf("Type = %q, want %q", tx.Type, PostCreated)
+	}
+	if want := HashTransactionContent(tx.Timestamp, tx.SenderPublicKey, tx.Type, tx.Payload); tx.ID != want {
+		t.Errorf("ID = %s, want %s", tx.ID, want)
+	}
+	if valid, err := tx.VerifySignature(); err != nil || !valid {
+		t.Errorf("VerifySignature() = %v, %v; want true, nil", valid, err)
+	}
+}
@@ -0,0 +1,19 @@
+package ledger
+
+import "time"
+
+// Clock is the source of the timestamps that constructors stamp on blocks,
+// transactions and social payloads. The WithClock constructors and SetClock
+// setters take one so that tests and simulations control time instead of
+// sleeping between calls; everything else uses SystemClock.
+// sim.Clock and testutil.Clock implement it.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the Clock that reads the system time.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
@@ -0,0 +1,50 @@
+package ledger
+
+import (
+	"digisocialblock/internal/testutil"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNewTransactionWithClock(t *testing.T) {
+	clock := testutil.NewClock(0)
+	a, _ := NewTransactionWithClock(clock, "sender", PostCreated, []byte("payload"))
+	b, _ := NewTransactionWithClock(clock, "sender", PostCreated, []byte("payload"))
+	if a.Timestamp != testutil.ClockStart.UnixNano() {
+		t.Errorf("Timestamp = %d, want the clock's %d", a.Timestamp, testutil.ClockStart.UnixNano())
+	}
+	if a.ID != b.ID {
+		t.Errorf("IDs at the same time = %s and %s, want equal", a.ID, b.ID)
+	}
+
+	clock.Advance(time.Nanosecond)
+	c, _ := NewTransactionWithClock(clock, "sender", PostCreated, []byte("payload"))
+	if c.ID == a.ID {
+		t.Errorf("ID one nanosecond later = %s, want a different ID", c.ID)
+	}
+}
+
+func TestBlockchain_SetClockStampsBlocks(t *testing.T) {
+	bc, _ := NewBlockchain()
+	clock := testutil.NewClock(time.Second)
+	bc.SetClock(clock)
+	priv, addr := newTestKey(t)
+	for i := 0; i < 2; i++ {
+		tx := newSignedTestTx(t, priv, addr, fmt.Sprintf("post %d", i))
+		if _, err := bc.AddBlock([]*Transaction{tx}); err != nil {
+			t.Fatalf("AddBlock() error = %v", err)
+		}
+	}
+	for i, want := range []time.Time{testutil.ClockStart, testutil.ClockStart.Add(time.Second)} {
+		if got := bc.Blocks[i+1].Timestamp; got != want.UnixNano() {
+			t.Errorf("block %d Timestamp = %d, want %d", i+1, got, want.UnixNano())
+		}
+	}
+
+	// A clock that stands still cannot stamp a later block.
+	bc.SetClock(testutil.NewClock(0))
+	if _, err := bc.AddBlock([]*Transaction{newSignedTestTx(t, priv, addr, "late")}); err == nil {
+		t.Error("AddBlock() stamped before the tip succeeded, want an error")
+	}
+}
@@ -0,0 +1,67 @@
+package ledger
+
+import (
+	"digisocialblock/core/clientkit"
+	"digisocialblock/core/codec"
+	"digisocialblock/core/errcode"
+	"errors"
+	"fmt"
+)
+
+// PayloadFormat selects how a structured transaction payload is encoded
+// (see clientkit.PayloadFormat).
+type PayloadFormat = clientkit.PayloadFormat
+
+const (
+	// PayloadFormatJSON is the original format: a bare JSON object with no prefix.
+	PayloadFormatJSON = clientkit.PayloadFormatJSON
+	// PayloadFormatCBOR is deterministic CBOR (see package codec) prefixed with
+	// PayloadVersionCBOR. It is typically 30-50% smaller than JSON.
+	PayloadFormatCBOR = clientkit.PayloadFormatCBOR
+)
+
+// PayloadVersionCBOR is the version byte that prefixes CBOR payloads. A JSON
+// payload always starts with '{' or whitespace, so the first byte of a payload
+// unambiguously identifies its format.
+const PayloadVersionCBOR = clientkit.PayloadVersionCBOR
+
+// EncodePayload encodes v in the given format.
+func EncodePayload(format PayloadFormat, v interface{}) ([]byte, error) {
+	return clientkit.EncodePayload(format, v)
+}
+
+// DetectPayloadFormat reports the format of an encoded payload from its first byte.
+func DetectPayloadFormat(payload []byte) (PayloadFormat, error) {
+	if len(payload) == 0 {
+		return 0, fmt.Errorf("%w: empty payload", ErrMalformedJSON)
+	}
+	switch b := payload[0]; {
+	case b == PayloadVersionCBOR:
+		return PayloadFormatCBOR, nil
+	case b == '{' || b == '[' || b == ' ' || b == '\t' || b == '\n' || b == '\r':
+		return PayloadFormatJSON, nil
+	default:
+		return 0, fmt.Errorf("unsupported payload version byte 0x%02x", b)
+	}
+}
+
+// DecodePayload strictly decodes a JSON or CBOR payload into v, detecting the
+// format from the payload's first byte. Both decoders reject anything but the
+// single canonical reading of the payload (see DecodePayloadJSON and package codec).
+func DecodePayload(payload []byte, v interface{}) error {
+	format, err := DetectPayloadFormat(payload)
+	if err != nil {
+		return err
+	}
+	if format == PayloadFormatJSON {
+		return DecodePayloadJSON(payload, v)
+	}
+	if len(payload) > MaxJSONPayloadSize {
+		return errcode.Wrap(CodeMalformedCBOR, fmt.Errorf("%w: %d bytes exceeds limit of %d", codec.ErrMalformed, len(payload), MaxJSONPayloadSize))
+	}
+	err = codec.Unmarshal(payload[1:], v)
+	if errors.Is(err, codec.ErrMalformed) {
+		return errcode.Wrap(CodeMalformedCBOR, err)
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package ledger
+
+import (
+	"digisocialblock/core/codec"
+	"digisocialblock/core/errcode"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodeDecodePayload(t *testing.T) {
+	in := strictTestPayload{Name: "a", Tags: []string{"x", "y"}}
+	for _, format := range []PayloadFormat{PayloadFormatJSON, PayloadFormatCBOR} {
+		payload, err := EncodePayload(format, in)
+		if err != nil {
+			t.Fatalf("EncodePayload(%d) error = %v", format, err)
+		}
+		got, err := DetectPayloadFormat(payload)
+		if err != nil || got != format {
+			t.Fatalf("DetectPayloadFormat() = %d, %v; want %d", got, err, format)
+		}
+		var out strictTestPayload
+		if err := DecodePayload(payload, &out); err != nil {
+			t.Fatalf("DecodePayload(%d) error = %v", format, err)
+		}
+		if !reflect.DeepEqual(out, in) {
+			t.Errorf("DecodePayload(%d) = %+v, want %+v", format, out, in)
+		}
+	}
+}
+
+func TestDecodePayload_Rejects(t *testing.T) {
+	unknownField, _ := codec.Marshal(map[string]interface{}{"name": "a", "admin": true})
+	tests := []struct {
+		name    string
+		payload []byte
+	}{
+		{"empty", nil},
+		{"unknown version byte", []byte{0x02, 0xa0}},
+		{"bare CBOR without version byte", []byte{0xa1, 0x64, 'n', 'a', 'm', 'e', 0x61, 'a'}},
+		{"CBOR unknown field", append([]byte{PayloadVersionCBOR}, unknownField...)},
+		{"CBOR trailing bytes", []byte{PayloadVersionCBOR, 0xa1, 0x64, 'n', 'a', 'm', 'e', 0x61, 'a', 0x00}},
+		{"CBOR too large", append([]byte{PayloadVersionCBOR}, make([]byte, MaxJSONPayloadSize)...)},
+		{"JSON unknown field", []byte(`{"name":"a","admin":true}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v strictTestPayload
+			if err := DecodePayload(tt.payload, &v); err == nil {
+				t.Errorf("DecodePayload(%x): expected error, got nil", tt.payload)
+			}
+		})
+	}
+
+	var v strictTestPayload
+	err := DecodePayload(append([]byte{PayloadVersionCBOR}, 0xa1, 0x64, 'n', 'a', 'm', 'e', 0x78, 0x18), &v)
+	if code, _ := errcode.Of(err); !errors.Is(err, codec.ErrMalformed) || code != CodeMalformedCBOR {
+		t.Errorf("DecodePayload() on truncated CBOR error = %v, want codec.ErrMalformed coded %s", err, CodeMalformedCBOR)
+	}
+}
+
+func TestEncodePayload_CBORIsSmaller(t *testing.T) {
+	in := strictTestPayload{Name: strings.Repeat("n", 40), Tags: []string{"alpha", "beta", "gamma"}}
+	jsonPayload, _ := EncodePayload(PayloadFormatJSON, in)
+	cborPayload, _ := EncodePayload(PayloadFormatCBOR, in)
+	if len(cborPayload) >= len(jsonPayload) {
+		t.Errorf("CBOR payload is %d bytes, JSON %d; want CBOR smaller", len(cborPayload), len(jsonPayload))
+	}
+}
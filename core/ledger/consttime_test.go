@@ -0,0 +1,54 @@
+package ledger
+
+import "testing"
+
+func TestConstantTimeEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"", "", true},
+		{"abc", "abc", true},
+		{"abc", "abd", false},
+		{"abc", "abcd", false},
+	}
+	for _, tt := range tests {
+		if got := ConstantTimeEqual(tt.a, tt.b); got != tt.want {
+			t.Errorf("ConstantTimeEqual(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// spyHashesEqual replaces hashesEqual for the duration of the test and
+// returns a counter of its calls.
+func spyHashesEqual(t *testing.T) *int {
+	t.Helper()
+	calls := 0
+	original := hashesEqual
+	hashesEqual = func(a, b string) bool {
+		calls++
+		return original(a, b)
+	}
+	t.Cleanup(func() { hashesEqual = original })
+	return &calls
+}
+
+func TestVerificationPathsUseConstantTimeEqual(t *testing.T) {
+	tx, _ := NewTransaction("sender", PostCreated, []byte(`{}`))
+	bc, _ := NewBlockchain()
+	block, _ := NewBlock(1, bc.Blocks[0].Hash, []*Transaction{tx})
+
+	paths := map[string]func(){
+		"Transaction.VerifyIntegrity": func() { tx.VerifyIntegrity() },
+		"Block.validateLink":          func() { block.validateLink(bc.Blocks[0]) },
+		"Block.validateContent":       func() { block.validateContent() },
+		"Blockchain.IsChainValid":     func() { bc.IsChainValid() },
+	}
+	for name, path := range paths {
+		calls := spyHashesEqual(t)
+		path()
+		if *calls == 0 {
+			t.Errorf("%s does not compare hashes with hashesEqual", name)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package ledger
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"digisocialblock/core/clientkit"
+	"digisocialblock/core/errcode"
+	"fmt"
+	"sync"
+)
+
+// ErrInvalidCoSignatures is returned when a transaction's co-signatures are
+// not exactly one valid signature from each co-signer its payload requires.
+var ErrInvalidCoSignatures = errcode.New(CodeInvalidCoSignatures, "transaction co-signatures do not match its required co-signers")
+
+// CoSignerResolver returns the addresses, besides the sender, that must sign
+// a transaction with the given payload.
+type CoSignerResolver func(payload []byte) ([]string, error)
+
+// coSigners holds the resolvers of the transaction types that can require
+// co-signatures. Like payload validators, they are registered by the packages
+// that define the payloads.
+var coSigners = struct {
+	mu        sync.RWMutex
+	resolvers map[TransactionType]CoSignerResolver
+}{resolvers: make(map[TransactionType]CoSignerResolver)}
+
+// RegisterCoSigners installs the co-signer resolver for txType, replacing any
+// previous one. A nil resolver removes it. Transactions of types without a
+// resolver must carry no co-signatures.
+func RegisterCoSigners(txType TransactionType, resolver CoSignerResolver) {
+	coSigners.mu.Lock()
+	defer coSigners.mu.Unlock()
+	if resolver == nil {
+		delete(coSigners.resolvers, txType)
+		return
+	}
+	coSigners.resolvers[txType] = resolver
+}
+
+// RequiredCoSigners returns the addresses that must co-sign tx, according to
+// the resolver registered for its type.
+func (tx *Transaction) RequiredCoSigners() ([]string, error) {
+	coSigners.mu.RLock()
+	resolver := coSigners.resolvers[tx.Type]
+	coSigners.mu.RUnlock()
+	if resolver == nil {
+		return nil, nil
+	}
+	return resolver(tx.Payload)
+}
+
+// CoSign adds a co-signature by privateKey, which must belong to one of the
+// transaction's required co-signers. Co-signers sign the same digest as the
+// sender, so they may sign before or after the sender does.
+func (tx *Transaction) CoSign(privateKey *ecdsa.PrivateKey) error {
+	if tx.ID == "" {
+		return fmt.Errorf("transaction ID is empty, cannot co-sign")
+	}
+	if privateKey == nil {
+		return fmt.Errorf("private key is nil, cannot co-sign")
+	}
+	address, err := clientkit.Address(&privateKey.PublicKey)
+	if err != nil {
+		return err
+	}
+	if tx.SigVersion == 0 {
+		tx.SigVersion = clientkit.CurrentSignatureVersion
+	}
+	digest, err := tx.SigningDigest()
+	if err != nil {
+		return fmt.Errorf("failed to compute signing digest: %w", err)
+	}
+	signature, err := ecdsa.SignASN1(rand.Reader, privateKey, digest)
+	if err != nil {
+		return fmt.Errorf("failed to co-sign transaction: %w", err)
+	}
+	return tx.AddCoSignature(address, signature)
+}
+
+// AddCoSignature records signature as the co-signature of address, which must
+// be one of the transaction's required co-signers, replacing any earlier one.
+// The signature is checked when the transaction is verified.
+func (tx *Transaction) AddCoSignature(address string, signature []byte) error {
+	required, err := tx.RequiredCoSigners()
+	if err != nil {
+		return fmt.Errorf("failed to determine co-signers: %w", err)
+	}
+	if !containsAddress(required, address) {
+		return fmt.Errorf("%s is not a co-signer of transaction %s", address, tx.ID)
+	}
+	for i := range tx.CoSignatures {
+		if tx.CoSignatures[i].PublicKey == address {
+			tx.CoSignatures[i].Signature = signature
+			return nil
+		}
+	}
+	tx.CoSignatures = append(tx.CoSignatures, CoSignature{PublicKey: address, Signature: signature})
+	return nil
+}
+
+// verifyCoSignatures checks that tx carries exactly one valid co-signature
+// from each of its required co-signers and no others.
+func (tx *Transaction) verifyCoSignatures() error {
+	required, err := tx.RequiredCoSigners()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCoSignatures, err)
+	}
+	if len(tx.CoSignatures) != len(required) {
+		return fmt.Errorf("%w: %d co-signatures, %d co-signers", ErrInvalidCoSignatures, len(tx.CoSignatures), len(required))
+	}
+	if len(required) == 0 {
+		return nil
+	}
+	digest, err := tx.SigningDigest()
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(tx.CoSignatures))
+	for _, cs := range tx.CoSignatures {
+		if seen[cs.PublicKey] || !containsAddress(required, cs.PublicKey) {
+			return fmt.Errorf("%w: unexpected or repeated co-signer %s", ErrInvalidCoSignatures, cs.PublicKey)
+		}
+		seen[cs.PublicKey] = true
+		publicKey, err := clientkit.ParseAddress(cs.PublicKey)
+		if err != nil {
+			return fmt.Errorf("%w: failed to parse co-signer address %s: %v", ErrInvalidCoSignatures, cs.PublicKey, err)
+		}
+		if !ecdsa.VerifyASN1(publicKey, digest, cs.Signature) {
+			return fmt.Errorf("%w: signature of co-signer %s does not verify", ErrInvalidCoSignatures, cs.PublicKey)
+		}
+	}
+	return nil
+}
+
+func containsAddress(addresses []string, address string) bool {
+	for _, a := range addresses {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package ledger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTransaction_CoSignaturesRequiredByPayload(t *testing.T) {
+	const coSignType = TransactionType("CoSignTest")
+	RegisterCoSigners(coSignType, func(payload []byte) ([]string, error) {
+		if len(payload) == 0 {
+			return nil, nil
+		}
+		return strings.Split(string(payload), ","), nil
+	})
+	t.Cleanup(func() { RegisterCoSigners(coSignType, nil) })
+
+	sender, senderAddr := newTestKey(t)
+	alice, aliceAddr := newTestKey(t)
+	bob, bobAddr := newTestKey(t)
+	mallory, _ := newTestKey(t)
+	newTx := func(coSigners ...string) *Transaction {
+		tx, err := NewTransaction(senderAddr, coSignType, []byte(strings.Join(coSigners, ",")))
+		if err != nil {
+			t.Fatalf("NewTransaction() error = %v", err)
+		}
+		return tx
+	}
+
+	tx := newTx(aliceAddr, bobAddr)
+	if err := tx.CoSign(alice); err != nil { // Before the sender signs...
+		t.Fatalf("CoSign(alice) error = %v", err)
+	}
+	if err := tx.Sign(sender); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if _, err := tx.VerifySignature(); !errors.Is(err, ErrInvalidCoSignatures) {
+		t.Errorf("VerifySignature() without bob error = %v, want ErrInvalidCoSignatures", err)
+	}
+	if err := tx.CoSign(bob); err != nil { // ...and after.
+		t.Fatalf("CoSign(bob) error = %v", err)
+	}
+	if _, err := tx.VerifySignature(); err != nil {
+		t.Fatalf("VerifySignature() with all co-signatures error = %v", err)
+	}
+	if err := tx.CoSign(mallory); err == nil {
+		t.Error("CoSign() by a wallet that is not a co-signer: expected error, got nil")
+	}
+
+	sc, _ := NewSignatureCache(10)
+	sc.Add(tx)
+	tampered := *tx
+	tampered.CoSignatures = []CoSignature{tx.CoSignatures[0], {PublicKey: bobAddr, Signature: tx.CoSignatures[0].Signature}}
+	if sc.Contains(&tampered) {
+		t.Error("cache hit after tampering with a co-signature")
+	}
+	if _, err := tampered.VerifySignature(); !errors.Is(err, ErrInvalidCoSignatures) {
+		t.Errorf("VerifySignature() with a forged co-signature error = %v, want ErrInvalidCoSignatures", err)
+	}
+	tampered.CoSignatures = []CoSignature{tx.CoSignatures[0], tx.CoSignatures[0]}
+	if _, err := tampered.VerifySignature(); !errors.Is(err, ErrInvalidCoSignatures) {
+		t.Errorf("VerifySignature() with a repeated co-signer error = %v, want ErrInvalidCoSignatures", err)
+	}
+
+	solo := newTx()
+	solo.Sign(sender)
+	solo.CoSignatures = tx.CoSignatures
+	if _, err := solo.VerifySignature(); !errors.Is(err, ErrInvalidCoSignatures) {
+		t.Errorf("VerifySignature() with unrequired co-signatures error = %v, want ErrInvalidCoSignatures", err)
+	}
+}
@@ -2,10 +2,11 @@ package ledger
 
 import (
 	"crypto/sha256"
+	"crypto/subtle"
+	"digisocialblock/core/clientkit"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"sort"
 	"strings"
 )
 
@@ -16,6 +17,19 @@ func CalculateSHA256Hash(data []byte) string {
 	return hex.EncodeToString(hashBytes[:])
 }
 
+// ConstantTimeEqual reports whether a and b are equal, taking time that
+// depends only on their lengths. Use it for every comparison of hashes,
+// signatures, MACs or addresses in a verification path: even where the values
+// are public, a uniform helper means no path has to be re-audited when a
+// comparison later starts involving secret-derived data.
+func ConstantTimeEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// hashesEqual is the comparison used by the ledger's verification paths.
+// It is a variable only so tests can assert those paths go through it.
+var hashesEqual = ConstantTimeEqual
+
 // prepareDataForHashing serializes an interface into a canonical JSON string for hashing.
 // It ensures that map keys are sorted to produce a deterministic output.
 // Using JSON for simplicity; a more performant binary serialization might be used in production.
@@ -93,15 +107,7 @@ func GetTransactionHashes(transactions []*Transaction) []string {
 // This ensures that the same transaction data always produces the same hash ID.
 // It explicitly concatenates relevant fields in a fixed order.
 func GenerateDeterministicTransactionIDInput(timestamp int64, senderPublicKey string, txType TransactionType, payload []byte) string {
-    var sb strings.Builder
-    sb.WriteString(fmt.Sprintf("%d", timestamp))
-    sb.WriteString("|")
-    sb.WriteString(senderPublicKey)
-    sb.WriteString("|")
-    sb.WriteString(string(txType))
-    sb.WriteString("|")
-    sb.WriteString(hex.EncodeToString(payload)) // Ensure payload is consistently represented
-    return sb.String()
+	return clientkit.TransactionIDInput(timestamp, senderPublicKey, string(txType), payload)
 }
 
 // GenerateDeterministicBlockHeaderInput creates a canonical string representation of block header data for hashing.
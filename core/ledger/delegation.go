@@ -0,0 +1,297 @@
+package ledger
+
+import (
+	"crypto/ecdsa"
+	"digisocialblock/core/clientkit"
+	"digisocialblock/core/errcode"
+	"fmt"
+)
+
+// MaxDelegationTypes bounds the transaction types one delegation may grant.
+const MaxDelegationTypes = 16
+
+// ErrUnauthorizedDelegate is returned when a block contains a transaction
+// signed by a delegate that its sender has not authorized, at the block's
+// time, to sign transactions of its type.
+var ErrUnauthorizedDelegate = errcode.New(CodeUnauthorizedDelegate, "transaction delegate is not authorized to sign it")
+
+func init() {
+	RegisterPayloadValidator(KeyDelegated, ValidateDelegationPayload)
+	RegisterPayloadDecoder(KeyDelegated, DecodeInto(func() interface{} { return &DelegationPayload{} }))
+}
+
+// DelegationPayload authorizes the key with address Delegate to sign
+// transactions of the listed types on behalf of the KeyDelegated
+// transaction's sender, until Expires. A transaction signed this way names
+// the sender as usual and the delegate in Transaction.Delegate, so an app can
+// post for a user without holding the user's primary key.
+//
+// A delegation replaces any earlier one of the same delegate by the same
+// sender; one with no types revokes it. A delegation whose Timestamp is not
+// after that of the one in force, a revocation included, is ignored, so a
+// grant signed before a revocation cannot undo it. A delegate can never
+// delegate in turn.
+type DelegationPayload struct {
+	Delegate  string            `json:"delegate"`
+	Types     []TransactionType `json:"types,omitempty"`
+	Expires   int64             `json:"expires,omitempty"` // UnixNano; compared with the time of the block
+	Timestamp int64             `json:"timestamp"`         // UnixNano; distinguishes otherwise identical delegations
+}
+
+// Validate checks that required fields are set and all fields are within limits.
+func (p *DelegationPayload) Validate() error {
+	if p.Delegate == "" {
+		return fmt.Errorf("empty Delegate")
+	}
+	if p.Timestamp == 0 {
+		return fmt.Errorf("zero timestamp")
+	}
+	if len(p.Types) > MaxDelegationTypes {
+		return fmt.Errorf("%d types, limit %d", len(p.Types), MaxDelegationTypes)
+	}
+	seen := make(map[TransactionType]bool, len(p.Types))
+	for _, t := range p.Types {
+		switch {
+		case t == "":
+			return fmt.Errorf("empty type")
+		case t == KeyDelegated:
+			return fmt.Errorf("%s cannot be delegated", KeyDelegated)
+		case seen[t]:
+			return fmt.Errorf("type %s listed twice", t)
+		}
+		seen[t] = true
+	}
+	if len(p.Types) > 0 && p.Expires <= p.Timestamp {
+		return fmt.Errorf("delegation expires at %d, before it is made", p.Expires)
+	}
+	return nil
+}
+
+// Revokes reports whether the delegation revokes its delegate.
+func (p *DelegationPayload) Revokes() bool {
+	return len(p.Types) == 0
+}
+
+// Allows reports whether the delegation lets its delegate sign a transaction
+// of txType in a block made at time at (UnixNano).
+func (p *DelegationPayload) Allows(txType TransactionType, at int64) bool {
+	if at >= p.Expires {
+		return false
+	}
+	for _, t := range p.Types {
+		if t == txType {
+			return true
+		}
+	}
+	return false
+}
+
+// ToPayload serializes the delegation as a KeyDelegated transaction payload
+// in the given format.
+func (p *DelegationPayload) ToPayload(format PayloadFormat) ([]byte, error) {
+	payload, err := EncodePayload(format, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode delegation payload: %w", err)
+	}
+	return payload, nil
+}
+
+// DelegationFromPayload decodes a KeyDelegated payload in either payload
+// format. The result must pass Validate.
+func DelegationFromPayload(payload []byte) (*DelegationPayload, error) {
+	var p DelegationPayload
+	if err := DecodePayload(payload, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode delegation payload: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("decoded delegation is invalid: %w", err)
+	}
+	return &p, nil
+}
+
+// ValidateDelegationPayload is the schema validator for KeyDelegated payloads.
+func ValidateDelegationPayload(payload []byte) error {
+	_, err := DelegationFromPayload(payload)
+	return err
+}
+
+// NewDelegationTransaction creates an unsigned KeyDelegated transaction by
+// which sender authorizes delegate to sign transactions of types until
+// expires, stamped by clock (SystemClock if nil). No types revokes delegate.
+func NewDelegationTransaction(clock Clock, sender, delegate string, types []TransactionType, expires int64) (*Transaction, error) {
+	if clock == nil {
+		clock = SystemClock
+	}
+	if delegate == sender {
+		return nil, fmt.Errorf("cannot delegate to the sender")
+	}
+	p := &DelegationPayload{Delegate: delegate, Types: types, Expires: expires, Timestamp: clock.Now().UnixNano()}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid delegation: %w", err)
+	}
+	payload, err := p.ToPayload(PayloadFormatCBOR)
+	if err != nil {
+		return nil, err
+	}
+	return NewTransactionWithClock(clock, sender, KeyDelegated, payload)
+}
+
+// SignAsDelegate signs the transaction with privateKey, a key the sender has
+// delegated the transaction's type to, and records the key's address as the
+// transaction's Delegate.
+func (tx *Transaction) SignAsDelegate(privateKey *ecdsa.PrivateKey) error {
+	if privateKey == nil {
+		return fmt.Errorf("private key is nil, cannot sign")
+	}
+	address, err := clientkit.Address(&privateKey.PublicKey)
+	if err != nil {
+		return err
+	}
+	if address == tx.SenderPublicKey {
+		return fmt.Errorf("signing key belongs to the sender, not a delegate")
+	}
+	tx.Delegate = address
+	return tx.Sign(privateKey)
+}
+
+// delegationKey identifies the delegation of one delegate by one sender.
+type delegationKey struct {
+	owner, delegate string
+}
+
+// delegationSet holds the delegations in force, by sender and delegate.
+// Revocations are kept, so a later delegation can be checked against their
+// Timestamp.
+type delegationSet map[delegationKey]*DelegationPayload
+
+// delegationChanges holds the delegations a batch of transactions makes, on
+// top of the chain's committed ones.
+type delegationChanges map[delegationKey]*DelegationPayload
+
+// applyDelegations computes the delegations made by txs, in a block made at
+// time at, on top of delegations. It fails with ErrUnauthorizedDelegate if
+// any transaction signed by a delegate is not allowed by the delegations in
+// force when it is applied, in order, so a delegation takes effect for the
+// transactions after it in the same block. A delegation no newer than the one
+// in force is ignored.
+func applyDelegations(delegations delegationSet, txs []*Transaction, at int64) (delegationChanges, error) {
+	changes := make(delegationChanges)
+	for i, tx := range txs {
+		if tx == nil {
+			continue
+		}
+		if tx.Delegate != "" {
+			key := delegationKey{tx.SenderPublicKey, tx.Delegate}
+			grant, changed := changes[key]
+			if !changed {
+				grant = delegations[key]
+			}
+			if grant == nil || !grant.Allows(tx.Type, at) {
+				return nil, reject(i, tx, fmt.Errorf("%w: transaction %s of type %s by %s for %s", ErrUnauthorizedDelegate, tx.ID, tx.Type, tx.Delegate, tx.SenderPublicKey))
+			}
+		}
+		if tx.Type != KeyDelegated {
+			continue
+		}
+		p, err := DelegationFromPayload(tx.Payload)
+		if err != nil {
+			return nil, reject(i, tx, fmt.Errorf("%w: delegation %s: %v", ErrInvalidPayload, tx.ID, err))
+		}
+		if p.Delegate == tx.SenderPublicKey {
+			return nil, reject(i, tx, fmt.Errorf("%w: delegation %s is to its own sender", ErrInvalidPayload, tx.ID))
+		}
+		key := delegationKey{tx.SenderPublicKey, p.Delegate}
+		current, changed := changes[key]
+		if !changed {
+			current = delegations[key]
+		}
+		if current != nil && p.Timestamp <= current.Timestamp {
+			continue
+		}
+		changes[key] = p
+	}
+	return changes, nil
+}
+
+// checkDelegationsLocked returns the delegation changes of appending a block
+// with txs made at time at. The caller must hold bc.mu.
+func (bc *Blockchain) checkDelegationsLocked(txs []*Transaction, at int64) (delegationChanges, error) {
+	if bc.delegations == nil {
+		if err := bc.replayDelegationsLocked(); err != nil {
+			return nil, err
+		}
+	}
+	return applyDelegations(bc.delegations, txs, at)
+}
+
+// commitDelegations applies changes to the chain's delegations. The caller
+// must hold bc.mu and have computed changes with checkDelegationsLocked.
+func (bc *Blockchain) commitDelegations(changes delegationChanges) {
+	for key, grant := range changes {
+		bc.delegations[key] = grant
+	}
+}
+
+// replayDelegationsLocked recomputes the delegations from every block in the
+// chain. On failure the delegations are left uncomputed. The caller must hold
+// bc.mu.
+func (bc *Blockchain) replayDelegationsLocked() error {
+	bc.delegations = make(delegationSet)
+	for _, block := range bc.Blocks {
+		changes, err := applyDelegations(bc.delegations, block.Transactions, block.Timestamp)
+		if err != nil {
+			bc.delegations = nil
+			return fmt.Errorf("block %d: %w", block.Index, err)
+		}
+		bc.commitDelegations(changes)
+	}
+	return nil
+}
+
+// Delegation returns the delegation in force, as of the latest block, by
+// which owner authorized delegate. It may have expired.
+func (bc *Blockchain) Delegation(owner, delegate string) (DelegationPayload, bool) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if bc.delegations == nil && bc.replayDelegationsLocked() != nil {
+		return DelegationPayload{}, false
+	}
+	grant, ok := bc.delegations[delegationKey{owner, delegate}]
+	if !ok || grant.Revokes() {
+		return DelegationPayload{}, false
+	}
+	p := *grant
+	p.Types = append([]TransactionType(nil), grant.Types...)
+	return p, true
+}
+
+// DelegationSource looks up the delegation in force by which owner
+// authorized delegate; *Blockchain implements it.
+type DelegationSource interface {
+	Delegation(owner, delegate string) (DelegationPayload, bool)
+}
+
+// SetDelegations makes Add check transactions signed by a delegate against
+// the delegations of source, normally the chain the mempool feeds, as of the
+// mempool's clock. Until it is set, or after it is set to nil, Add refuses
+// every transaction signed by a delegate. A delegate's transaction is only
+// admitted once the delegation allowing it is in a block.
+func (mp *Mempool) SetDelegations(source DelegationSource) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.delegations = source
+}
+
+// checkDelegate refuses a transaction signed by a delegate that source does
+// not allow, at time at, to sign it for its sender.
+func checkDelegate(source DelegationSource, tx *Transaction, at int64) error {
+	if tx.Delegate == "" {
+		return nil
+	}
+	if source != nil {
+		if grant, ok := source.Delegation(tx.SenderPublicKey, tx.Delegate); ok && grant.Allows(tx.Type, at) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: transaction %s of type %s by %s for %s", ErrUnauthorizedDelegate, tx.ID, tx.Type, tx.Delegate, tx.SenderPublicKey)
+}
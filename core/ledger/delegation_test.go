@@ -0,0 +1,207 @@
+package ledger
+
+import (
+	"crypto/ecdsa"
+	"digisocialblock/internal/testutil"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// delegationTestTx returns a transaction of txType from sender, signed by the
+// delegated key priv.
+func delegationTestTx(t *testing.T, priv *ecdsa.PrivateKey, sender string, txType TransactionType) *Transaction {
+	t.Helper()
+	tx, err := NewTransaction(sender, txType, []byte("by the app"))
+	if err != nil {
+		t.Fatalf("NewTransaction() error = %v", err)
+	}
+	if err := tx.SignAsDelegate(priv); err != nil {
+		t.Fatalf("SignAsDelegate() error = %v", err)
+	}
+	return tx
+}
+
+func TestDelegationPayload_Validation(t *testing.T) {
+	for name, p := range map[string]DelegationPayload{
+		"no delegate":   {Types: []TransactionType{PostCreated}, Expires: 2, Timestamp: 1},
+		"no instant":    {Delegate: "app", Types: []TransactionType{PostCreated}, Expires: 2},
+		"expired":       {Delegate: "app", Types: []TransactionType{PostCreated}, Expires: 1, Timestamp: 1},
+		"redelegation":  {Delegate: "app", Types: []TransactionType{KeyDelegated}, Expires: 2, Timestamp: 1},
+		"repeated type": {Delegate: "app", Types: []TransactionType{Like, Like}, Expires: 2, Timestamp: 1},
+	} {
+		if err := p.Validate(); err == nil {
+			t.Errorf("%s: Validate() expected error, got nil", name)
+		}
+	}
+	if _, err := NewDelegationTransaction(nil, "alice", "alice", []TransactionType{Like}, time.Now().Add(time.Hour).UnixNano()); err == nil {
+		t.Error("NewDelegationTransaction() to the sender: expected error, got nil")
+	}
+}
+
+func TestBlockchain_DelegatesSignOnlyWhatTheyAreGranted(t *testing.T) {
+	alicePriv, alice := newTestKey(t)
+	appPriv, app := newTestKey(t)
+	path := filepath.Join(t.TempDir(), "chain.log")
+	store, _ := OpenFileBlockStore(path, FileBlockStoreOptions{FlushInterval: time.Hour})
+	bc, err := NewBlockchainWithStore(store)
+	if err != nil {
+		t.Fatalf("NewBlockchainWithStore() error = %v", err)
+	}
+	clock := testutil.NewClock(time.Second)
+	bc.SetClock(clock)
+
+	if _, err := bc.AddBlock([]*Transaction{delegationTestTx(t, appPriv, alice, PostCreated)}); !errors.Is(err, ErrUnauthorizedDelegate) {
+		t.Fatalf("AddBlock() before the delegation error = %v, want ErrUnauthorizedDelegate", err)
+	}
+
+	// The delegation covers posts signed later in the same block.
+	grant, _ := NewDelegationTransaction(clock, alice, app, []TransactionType{PostCreated, Like}, clock.Peek().Add(time.Hour).UnixNano())
+	grant.Sign(alicePriv)
+	if _, err := bc.AddBlock([]*Transaction{grant, delegationTestTx(t, appPriv, alice, PostCreated)}); err != nil {
+		t.Fatalf("AddBlock() with a delegated post error = %v", err)
+	}
+	for name, tx := range map[string]*Transaction{
+		"ungranted type": delegationTestTx(t, appPriv, alice, Transfer),
+		"redelegation":   delegationTestTx(t, appPriv, alice, KeyDelegated),
+	} {
+		if _, err := bc.AddBlock([]*Transaction{tx}); err == nil {
+			t.Errorf("AddBlock() with %s signed by the delegate: expected error, got nil", name)
+		}
+	}
+	if got, ok := bc.Delegation(alice, app); !ok || len(got.Types) != 2 {
+		t.Errorf("Delegation() = %+v, %v; want the grant", got, ok)
+	}
+	store.Close()
+
+	// Delegations are replayed from a stored chain; once expired they allow nothing.
+	store, _ = OpenFileBlockStore(path, FileBlockStoreOptions{})
+	defer store.Close()
+	restored, err := NewBlockchainWithStore(store)
+	if err != nil {
+		t.Fatalf("NewBlockchainWithStore() on existing store error = %v", err)
+	}
+	restored.SetClock(clock)
+	if _, err := restored.AddBlock([]*Transaction{delegationTestTx(t, appPriv, alice, Like)}); err != nil {
+		t.Fatalf("AddBlock() with a delegated like error = %v", err)
+	}
+	clock.Advance(time.Hour)
+	if _, err := restored.AddBlock([]*Transaction{delegationTestTx(t, appPriv, alice, Like)}); !errors.Is(err, ErrUnauthorizedDelegate) {
+		t.Errorf("AddBlock() after the delegation expired error = %v, want ErrUnauthorizedDelegate", err)
+	}
+
+	// A revoked delegate is unknown.
+	revoke, _ := NewDelegationTransaction(clock, alice, app, nil, 0)
+	revoke.Sign(alicePriv)
+	if _, err := restored.AddBlock([]*Transaction{revoke}); err != nil {
+		t.Fatalf("AddBlock() revoking the delegate error = %v", err)
+	}
+	if _, ok := restored.Delegation(alice, app); ok {
+		t.Error("Delegation() after revocation found a grant")
+	}
+}
+
+func TestTransaction_DelegateSignature(t *testing.T) {
+	_, alice := newTestKey(t)
+	appPriv, _ := newTestKey(t)
+	otherPriv, other := newTestKey(t)
+	tx := delegationTestTx(t, appPriv, alice, PostCreated)
+	if ok, err := tx.VerifySignature(); !ok || err != nil {
+		t.Fatalf("VerifySignature() = %v, %v; want the delegate's signature accepted", ok, err)
+	}
+	tx.Delegate = other
+	if ok, _ := tx.VerifySignature(); ok {
+		t.Error("VerifySignature() with another delegate named: want rejected")
+	}
+	tx.Delegate = alice
+	if err := tx.IsValid(); !errors.Is(err, ErrMalformedTransaction) {
+		t.Errorf("IsValid() with the sender as delegate error = %v, want ErrMalformedTransaction", err)
+	}
+	own, _ := NewTransaction(other, PostCreated, []byte("x"))
+	if err := own.SignAsDelegate(otherPriv); err == nil {
+		t.Error("SignAsDelegate() with the sender's key: expected error, got nil")
+	}
+}
+
+func TestMempool_ChecksDelegationAtAdmission(t *testing.T) {
+	alicePriv, alice := newTestKey(t)
+	appPriv, app := newTestKey(t)
+	attackerPriv, _ := newTestKey(t)
+	bc, _ := NewBlockchain()
+	clock := testutil.NewClock(time.Second)
+	bc.SetClock(clock)
+	mp := NewMempool(nil)
+	mp.SetClock(clock)
+
+	if err := mp.Add(delegationTestTx(t, appPriv, alice, PostCreated)); !errors.Is(err, ErrUnauthorizedDelegate) {
+		t.Errorf("Add() of a delegated post without a delegation source error = %v, want ErrUnauthorizedDelegate", err)
+	}
+	mp.SetDelegations(bc)
+	if err := mp.Add(delegationTestTx(t, attackerPriv, alice, PostCreated)); !errors.Is(err, ErrUnauthorizedDelegate) {
+		t.Errorf("Add() signed by a self-named delegate error = %v, want ErrUnauthorizedDelegate", err)
+	}
+
+	grant, _ := NewDelegationTransaction(clock, alice, app, []TransactionType{PostCreated}, clock.Peek().Add(time.Hour).UnixNano())
+	grant.Sign(alicePriv)
+	if _, err := bc.AddBlock([]*Transaction{grant}); err != nil {
+		t.Fatalf("AddBlock() with the delegation error = %v", err)
+	}
+	if err := mp.Add(delegationTestTx(t, appPriv, alice, PostCreated)); err != nil {
+		t.Errorf("Add() of a delegated post error = %v", err)
+	}
+	if err := mp.Add(delegationTestTx(t, appPriv, alice, Like)); !errors.Is(err, ErrUnauthorizedDelegate) {
+		t.Errorf("Add() of an ungranted type error = %v, want ErrUnauthorizedDelegate", err)
+	}
+	clock.Advance(time.Hour)
+	if err := mp.Add(delegationTestTx(t, appPriv, alice, PostCreated)); !errors.Is(err, ErrUnauthorizedDelegate) {
+		t.Errorf("Add() after the delegation expired error = %v, want ErrUnauthorizedDelegate", err)
+	}
+	if mp.Size() != 1 {
+		t.Errorf("Size() = %d, want only the authorized post", mp.Size())
+	}
+}
+
+func TestBlockchain_RevokedDelegationCannotBeReplayed(t *testing.T) {
+	alicePriv, alice := newTestKey(t)
+	appPriv, app := newTestKey(t)
+	bc, _ := NewBlockchain()
+	clock := testutil.NewClock(time.Second)
+	bc.SetClock(clock)
+	expires := clock.Peek().Add(time.Hour).UnixNano()
+
+	grant, _ := NewDelegationTransaction(clock, alice, app, []TransactionType{PostCreated}, expires)
+	grant.Sign(alicePriv)
+	// Signed before the revocation but not yet on chain.
+	unsent, _ := NewDelegationTransaction(clock, alice, app, []TransactionType{PostCreated, Like}, expires)
+	unsent.Sign(alicePriv)
+	revoke, _ := NewDelegationTransaction(clock, alice, app, nil, 0)
+	revoke.Sign(alicePriv)
+	if _, err := bc.AddBlock([]*Transaction{grant}); err != nil {
+		t.Fatalf("AddBlock() with the delegation error = %v", err)
+	}
+	if _, err := bc.AddBlock([]*Transaction{revoke}); err != nil {
+		t.Fatalf("AddBlock() revoking the delegate error = %v", err)
+	}
+
+	// The revoked app replays the grant it was given.
+	if _, err := bc.AddBlock([]*Transaction{grant, delegationTestTx(t, appPriv, alice, PostCreated)}); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Errorf("AddBlock() replaying the revoked grant error = %v, want ErrDuplicateTransaction", err)
+	}
+	if _, err := bc.AddBlock([]*Transaction{unsent}); err != nil {
+		t.Fatalf("AddBlock() with a grant older than the revocation error = %v", err)
+	}
+	if got, ok := bc.Delegation(alice, app); ok {
+		t.Errorf("Delegation() = %+v after replays, want the revocation kept", got)
+	}
+	if _, err := bc.AddBlock([]*Transaction{delegationTestTx(t, appPriv, alice, PostCreated)}); !errors.Is(err, ErrUnauthorizedDelegate) {
+		t.Errorf("AddBlock() signed by the revoked delegate error = %v, want ErrUnauthorizedDelegate", err)
+	}
+
+	// A grant made after the revocation takes effect again.
+	regrant, _ := NewDelegationTransaction(clock, alice, app, []TransactionType{Like}, clock.Peek().Add(time.Hour).UnixNano())
+	regrant.Sign(alicePriv)
+	if _, err := bc.AddBlock([]*Transaction{regrant, delegationTestTx(t, appPriv, alice, Like)}); err != nil {
+		t.Errorf("AddBlock() with a new grant error = %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package ledger
+
+import (
+	"digisocialblock/core/errcode"
+	"net/http"
+)
+
+// Error codes of the ledger (see package errcode). The sentinel errors below
+// and in the files that return them carry these codes.
+var (
+	CodeInvalidSignature      = errcode.Register("DSB-LEDGER-001", http.StatusBadRequest, "transaction signature is invalid")
+	CodeLegacySignature       = errcode.Register("DSB-LEDGER-002", http.StatusBadRequest, "transaction uses the legacy signature scheme")
+	CodeTransactionIDMismatch = errcode.Register("DSB-LEDGER-003", http.StatusBadRequest, "transaction ID does not match its content")
+	CodePayloadTooLarge       = errcode.Register("DSB-LEDGER-004", http.StatusRequestEntityTooLarge, "transaction payload exceeds the limit for its type")
+	CodeInvalidPayload        = errcode.Register("DSB-LEDGER-005", http.StatusUnprocessableEntity, "transaction payload does not match the schema for its type")
+	CodeMalformedJSON         = errcode.Register("DSB-LEDGER-006", http.StatusBadRequest, "JSON payload is malformed or exceeds the decoding limits")
+	CodeInsufficientStamp     = errcode.Register("DSB-LEDGER-007", http.StatusForbidden, "proof-of-work stamp is below the required difficulty")
+	CodeDuplicateTransaction  = errcode.Register("DSB-LEDGER-008", http.StatusConflict, "transaction is already pending or on chain")
+	CodeWrongChain            = errcode.Register("DSB-LEDGER-009", http.StatusBadRequest, "transaction is for a different chain")
+	CodeMalformedTransaction  = errcode.Register("DSB-LEDGER-010", http.StatusBadRequest, "transaction is missing a required field")
+	CodeInvalidCoSignatures   = errcode.Register("DSB-LEDGER-011", http.StatusBadRequest, "transaction co-signatures do not match its required co-signers")
+	CodeInsufficientBalance   = errcode.Register("DSB-LEDGER-012", http.StatusUnprocessableEntity, "transfer exceeds the sender's balance")
+	CodeUnauthorizedDelegate  = errcode.Register("DSB-LEDGER-013", http.StatusForbidden, "transaction delegate is not authorized to sign it for its sender")
+)
+
+// CodeMalformedCBOR is the code of codec.ErrMalformed, which the ledger
+// attaches when it decodes a CBOR payload. It keeps the codec's name, but
+// package codec does not register it, to stay as light as the clients that
+// import it.
+var CodeMalformedCBOR = errcode.Register("DSB-CODEC-001", http.StatusBadRequest, "CBOR is malformed or not in deterministic form")
+
+// ErrInvalidSignature is returned when a transaction signature does not
+// verify against its sender and signing digest.
+var ErrInvalidSignature = errcode.New(CodeInvalidSignature, "transaction signature is invalid")
+
+// ErrDuplicateTransaction is returned when a transaction is already in the
+// mempool or on the chain, or appears twice in a new block.
+var ErrDuplicateTransaction = errcode.New(CodeDuplicateTransaction, "transaction is already pending or on chain")
+
+// ErrWrongChain is returned when a transaction was signed for another chain.
+var ErrWrongChain = errcode.New(CodeWrongChain, "transaction is for a different chain")
+
+// ErrMalformedTransaction is returned when a transaction lacks a required
+// field or has an out-of-range one.
+var ErrMalformedTransaction = errcode.New(CodeMalformedTransaction, "transaction is malformed")
@@ -0,0 +1,59 @@
+package ledger
+
+import "fmt"
+
+// ChainFollower is the high-water mark of an index built by processing a
+// Blockchain's blocks in order: the index and hash of the last block
+// processed. An index keeps one under its own lock and passes Sync the
+// function that indexes a single block, so the walk over the chain, and the
+// divergence check that guards it, live in one place.
+type ChainFollower struct {
+	HighWaterIndex int64 // -1 until a block has been processed
+	HighWaterHash  string
+}
+
+// NewChainFollower returns a follower that has processed no block.
+func NewChainFollower() ChainFollower {
+	return ChainFollower{HighWaterIndex: -1}
+}
+
+// HighWaterMark returns the index and hash of the last block Sync processed,
+// or -1 and "" before the first.
+func (f *ChainFollower) HighWaterMark() (int64, string) {
+	return f.HighWaterIndex, f.HighWaterHash
+}
+
+// Sync calls indexBlock for every block of bc above the high-water mark, in
+// order, advancing the mark past each, and returns the number of blocks
+// processed. If the block at the high-water mark no longer matches the
+// recorded hash, nothing is processed and the error wraps diverged. A
+// follower that has processed no block processes the whole chain, which is
+// how an index rebuilds after discarding its state. name identifies the
+// index in errors.
+func (f *ChainFollower) Sync(bc *Blockchain, name string, diverged error, indexBlock func(*Block)) (int, error) {
+	if bc == nil {
+		return 0, fmt.Errorf("blockchain cannot be nil")
+	}
+	if f.HighWaterIndex >= 0 {
+		block := bc.GetBlockByIndex(f.HighWaterIndex)
+		if block == nil || block.Hash != f.HighWaterHash {
+			return 0, fmt.Errorf("%w (block %d)", diverged, f.HighWaterIndex)
+		}
+	}
+	latest := bc.GetLatestBlock()
+	if latest == nil {
+		return 0, fmt.Errorf("blockchain has no blocks")
+	}
+	processed := 0
+	for i := f.HighWaterIndex + 1; i <= latest.Index; i++ {
+		block := bc.GetBlockByIndex(i)
+		if block == nil {
+			return processed, fmt.Errorf("block %d missing while syncing %s", i, name)
+		}
+		indexBlock(block)
+		f.HighWaterIndex = block.Index
+		f.HighWaterHash = block.Hash
+		processed++
+	}
+	return processed, nil
+}
@@ -0,0 +1,46 @@
+package ledger
+
+import (
+	"errors"
+	"testing"
+)
+
+var errTestDiverged = errors.New("test index diverged")
+
+func TestChainFollower_SyncProcessesNewBlocksOnce(t *testing.T) {
+	bc := buildSyntheticChain(t, 5, 1)
+	f := NewChainFollower()
+	var seen []int64
+	index := func(block *Block) { seen = append(seen, block.Index) }
+
+	if n, err := f.Sync(bc, "test index", errTestDiverged, index); err != nil || n != 5 {
+		t.Fatalf("first Sync() = %d, %v; want 5, nil", n, err)
+	}
+	if n, err := f.Sync(bc, "test index", errTestDiverged, index); err != nil || n != 0 {
+		t.Fatalf("second Sync() = %d, %v; want 0, nil", n, err)
+	}
+	if len(seen) != 5 || seen[0] != 0 || seen[4] != 4 {
+		t.Errorf("indexed blocks %v, want 0 through 4 once each", seen)
+	}
+	if idx, hash := f.HighWaterMark(); idx != 4 || hash != bc.Blocks[4].Hash {
+		t.Errorf("HighWaterMark() = %d, %q; want 4, %q", idx, hash, bc.Blocks[4].Hash)
+	}
+}
+
+func TestChainFollower_SyncDetectsDivergence(t *testing.T) {
+	bc := buildSyntheticChain(t, 3, 1)
+	f := NewChainFollower()
+	if _, err := f.Sync(bc, "test index", errTestDiverged, func(*Block) {}); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	other := buildSyntheticChain(t, 4, 2)
+	called := false
+	n, err := f.Sync(other, "test index", errTestDiverged, func(*Block) { called = true })
+	if !errors.Is(err, errTestDiverged) || n != 0 || called {
+		t.Errorf("Sync() of another chain = %d, %v (indexed %v); want 0, errTestDiverged, nothing indexed", n, err, called)
+	}
+	if idx, _ := f.HighWaterMark(); idx != 2 {
+		t.Errorf("HighWaterMark() index after divergence = %d, want 2", idx)
+	}
+}
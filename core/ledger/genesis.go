@@ -0,0 +1,283 @@
+package ledger
+
+import (
+	"digisocialblock/core/errcode"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// MaxHandleLength bounds a reserved handle once folded (see FoldHandle).
+const MaxHandleLength = 64 // Characters
+
+// CodeReservedHandle is the error code of ErrReservedHandle.
+var CodeReservedHandle = errcode.Register("DSB-LEDGER-016", http.StatusForbidden, "transaction claims a handle reserved at genesis")
+
+// ErrReservedHandle is returned for a transaction claiming a handle that the
+// genesis configuration reserves for another account, or for nobody.
+var ErrReservedHandle = errcode.New(CodeReservedHandle, "transaction claims a handle reserved at genesis")
+
+// HandleResolver returns the handles a transaction with the given payload
+// claims, such as a profile's display name.
+type HandleResolver func(payload []byte) ([]string, error)
+
+// handleResolvers holds the resolvers of the transaction types that claim
+// handles. Like CID resolvers, they are registered by the packages that
+// define the payloads.
+var handleResolvers = struct {
+	mu        sync.RWMutex
+	resolvers map[TransactionType]HandleResolver
+}{resolvers: make(map[TransactionType]HandleResolver)}
+
+// RegisterHandleResolver installs the handle resolver for txType, replacing
+// any previous one. A nil resolver removes it.
+func RegisterHandleResolver(txType TransactionType, resolver HandleResolver) {
+	handleResolvers.mu.Lock()
+	defer handleResolvers.mu.Unlock()
+	if resolver == nil {
+		delete(handleResolvers.resolvers, txType)
+		return
+	}
+	handleResolvers.resolvers[txType] = resolver
+}
+
+// ClaimedHandles returns the handles tx claims, according to the resolver
+// registered for its type.
+func (tx *Transaction) ClaimedHandles() ([]string, error) {
+	handleResolvers.mu.RLock()
+	resolver := handleResolvers.resolvers[tx.Type]
+	handleResolvers.mu.RUnlock()
+	if resolver == nil {
+		return nil, nil
+	}
+	return resolver(tx.Payload)
+}
+
+// FoldHandle returns the form in which handles are compared: lower case, with
+// everything but letters and digits removed, so that "Moderation",
+// "moderation_" and "M.O.D.E.R.A.T.I.O.N" all claim the handle "moderation".
+func FoldHandle(handle string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, handle)
+}
+
+// SystemAccount is an account with a role in running the network, such as
+// the moderation attestor, named in the genesis configuration so that every
+// node can find it.
+type SystemAccount struct {
+	Role    string `json:"role"`             // E.g. "moderation-attestor"; unique
+	Address string `json:"address"`          // The account's address
+	Handle  string `json:"handle,omitempty"` // Reserved for this account alone
+}
+
+// GenesisConfig holds the parameters of a network that every node must agree
+// on before the first block: its chain ID, starting balances, and the
+// identities it launches with. Nodes load it from a file shipped with the
+// network (see LoadGenesisConfig) and apply it with Blockchain.ApplyGenesis.
+type GenesisConfig struct {
+	ChainID     string            `json:"chainId,omitempty"`     // Defaults to DefaultChainID
+	Allocations map[string]uint64 `json:"allocations,omitempty"` // See SetGenesisAllocations
+	// ReservedHandles may not be claimed by any account, e.g. "admin" or
+	// "support". Handles are compared folded.
+	ReservedHandles []string        `json:"reservedHandles,omitempty"`
+	SystemAccounts  []SystemAccount `json:"systemAccounts,omitempty"`
+	Invites         *InvitePolicy   `json:"invites,omitempty"` // Nil leaves the chain open to anyone
+}
+
+// ParseGenesisConfig decodes a GenesisConfig from JSON, refusing unknown
+// fields, and validates it.
+func ParseGenesisConfig(data []byte) (*GenesisConfig, error) {
+	var cfg GenesisConfig
+	if err := DecodePayloadJSON(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse genesis config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// LoadGenesisConfig reads and parses the genesis configuration at path.
+func LoadGenesisConfig(path string) (*GenesisConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read genesis config: %w", err)
+	}
+	return ParseGenesisConfig(data)
+}
+
+// Validate checks that the chain ID is valid, and that every reserved handle
+// and system account is well formed and named only once.
+func (cfg *GenesisConfig) Validate() error {
+	if cfg.ChainID != "" {
+		if err := ValidateChainID(cfg.ChainID); err != nil {
+			return fmt.Errorf("invalid genesis config: %w", err)
+		}
+	}
+	for address := range cfg.Allocations {
+		if address == "" {
+			return fmt.Errorf("invalid genesis config: allocation to an empty address")
+		}
+	}
+	if _, err := cfg.Reservations(); err != nil {
+		return err
+	}
+	if cfg.Invites != nil {
+		if err := cfg.Invites.Validate(); err != nil {
+			return fmt.Errorf("invalid genesis config: %w", err)
+		}
+	}
+	roles := make(map[string]bool, len(cfg.SystemAccounts))
+	for i, account := range cfg.SystemAccounts {
+		if account.Role == "" || account.Address == "" {
+			return fmt.Errorf("invalid genesis config: system account %d needs a role and an address", i)
+		}
+		if roles[account.Role] {
+			return fmt.Errorf("invalid genesis config: role %q is held by more than one system account", account.Role)
+		}
+		roles[account.Role] = true
+	}
+	return nil
+}
+
+// Reservations returns the handles cfg reserves, folded, mapped to the
+// address of the system account each is reserved for, or to "" for handles
+// nobody may claim. Pass it to Mempool.SetReservedHandles.
+func (cfg *GenesisConfig) Reservations() (map[string]string, error) {
+	reserved := make(map[string]string, len(cfg.ReservedHandles)+len(cfg.SystemAccounts))
+	reserve := func(handle, owner string) error {
+		folded := FoldHandle(handle)
+		if folded == "" || len([]rune(folded)) > MaxHandleLength {
+			return fmt.Errorf("invalid genesis config: handle %q must fold to 1 to %d letters and digits", handle, MaxHandleLength)
+		}
+		if _, dup := reserved[folded]; dup {
+			return fmt.Errorf("invalid genesis config: handle %q is reserved more than once", handle)
+		}
+		reserved[folded] = owner
+		return nil
+	}
+	for _, handle := range cfg.ReservedHandles {
+		if err := reserve(handle, ""); err != nil {
+			return nil, err
+		}
+	}
+	for _, account := range cfg.SystemAccounts {
+		if account.Handle != "" {
+			if err := reserve(account.Handle, account.Address); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return reserved, nil
+}
+
+// checkHandles refuses a transaction claiming a handle reserved for anyone
+// but its sender.
+func checkHandles(reserved map[string]string, tx *Transaction) error {
+	if len(reserved) == 0 {
+		return nil
+	}
+	handles, err := tx.ClaimedHandles()
+	if err != nil {
+		return fmt.Errorf("failed to resolve handles of transaction %s: %w", tx.ID, err)
+	}
+	for _, handle := range handles {
+		if owner, ok := reserved[FoldHandle(handle)]; ok && owner != tx.SenderPublicKey {
+			return fmt.Errorf("%w: transaction %s claims %q", ErrReservedHandle, tx.ID, handle)
+		}
+	}
+	return nil
+}
+
+// ApplyGenesis applies cfg to the chain: its chain ID, if set, and
+// allocations as by SetGenesisAllocations, its system accounts, its invite
+// policy as by SetInvitePolicy, and its reserved handles, which AddBlock,
+// AppendBlock and block production then refuse to let anyone else claim.
+// Like the allocations, the configuration is not stored with the chain, so a
+// node must apply it again after opening a stored chain. It fails, leaving
+// the chain unchanged, if cfg is invalid, names another chain than a stored
+// chain's transactions are for, or if the chain already breaks it.
+func (bc *Blockchain) ApplyGenesis(cfg GenesisConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	reserved, _ := cfg.Reservations()
+	chainID := cfg.ChainID
+	if chainID == "" {
+		chainID = DefaultChainID
+	}
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	for _, block := range bc.Blocks {
+		for _, tx := range block.Transactions {
+			if tx.ChainID != chainID {
+				return fmt.Errorf("%w: stored block %d holds transaction %s for chain %q, not %q", ErrWrongChain, block.Index, tx.ID, tx.ChainID, chainID)
+			}
+			if err := checkHandles(reserved, tx); err != nil {
+				return fmt.Errorf("block %d: %w", block.Index, err)
+			}
+		}
+	}
+	prevAllocations, prevAccounts, prevPolicy := bc.allocations, bc.systemAccounts, bc.invitePolicy
+	bc.allocations = make(map[string]uint64, len(cfg.Allocations))
+	for address, amount := range cfg.Allocations {
+		bc.allocations[address] = amount
+	}
+	bc.systemAccounts = append([]SystemAccount(nil), cfg.SystemAccounts...)
+	bc.invitePolicy = InvitePolicy{}
+	if cfg.Invites != nil {
+		bc.invitePolicy = *cfg.Invites
+	}
+	bc.invites = nil
+	err := bc.replayBalancesLocked()
+	if err == nil && bc.invitePolicy.Required {
+		err = bc.replayInvitesLocked()
+	}
+	if err != nil {
+		bc.allocations, bc.systemAccounts, bc.invitePolicy = prevAllocations, prevAccounts, prevPolicy
+		bc.balances, bc.invites = nil, nil
+		return err
+	}
+	bc.chainID = chainID
+	bc.reserved = reserved
+	return nil
+}
+
+// SystemAccount returns the system account holding role, if the applied
+// genesis configuration names one.
+func (bc *Blockchain) SystemAccount(role string) (SystemAccount, bool) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	for _, account := range bc.systemAccounts {
+		if account.Role == role {
+			return account, true
+		}
+	}
+	return SystemAccount{}, false
+}
+
+// HandleReservation reports whether handle is reserved at genesis and, if it
+// is reserved for a system account, that account's address.
+func (bc *Blockchain) HandleReservation(handle string) (owner string, reserved bool) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	owner, reserved = bc.reserved[FoldHandle(handle)]
+	return owner, reserved
+}
+
+// SetReservedHandles makes Add refuse, with ErrReservedHandle, transactions
+// claiming a handle in reserved (as returned by GenesisConfig.Reservations)
+// unless sent by the account it is reserved for. A nil map removes the check.
+func (mp *Mempool) SetReservedHandles(reserved map[string]string) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.reserved = reserved
+}
@@ -0,0 +1,111 @@
+package ledger
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// genesisTestType is a transaction type whose payload is the handle it claims.
+const genesisTestType = TransactionType("HandleTest")
+
+// genesisTestSetup registers the handle resolver of genesisTestType and
+// returns a config reserving "admin", with a moderation attestor holding
+// "moderation".
+func genesisTestSetup(t *testing.T, attestor string) GenesisConfig {
+	t.Helper()
+	RegisterHandleResolver(genesisTestType, func(payload []byte) ([]string, error) {
+		return []string{string(payload)}, nil
+	})
+	t.Cleanup(func() { RegisterHandleResolver(genesisTestType, nil) })
+	return GenesisConfig{
+		ReservedHandles: []string{"admin"},
+		SystemAccounts:  []SystemAccount{{Role: "moderation-attestor", Address: attestor, Handle: "Moderation"}},
+		Allocations:     map[string]uint64{attestor: 100},
+	}
+}
+
+func TestApplyGenesis_ReservesHandles(t *testing.T) {
+	attestorKey, attestor := newTestKey(t)
+	cfg := genesisTestSetup(t, attestor)
+	userKey, user := newTestKey(t)
+	claim := func(priv *ecdsa.PrivateKey, addr, handle string) *Transaction {
+		tx, _ := NewTransaction(addr, genesisTestType, []byte(handle))
+		tx.Sign(priv)
+		return tx
+	}
+
+	bc, _ := NewBlockchain()
+	if err := bc.ApplyGenesis(cfg); err != nil {
+		t.Fatalf("ApplyGenesis() error = %v", err)
+	}
+	if account, ok := bc.SystemAccount("moderation-attestor"); !ok || account.Address != attestor {
+		t.Errorf("SystemAccount() = %+v, %v; want the attestor", account, ok)
+	}
+	if owner, ok := bc.HandleReservation("MODERATION"); !ok || owner != attestor {
+		t.Errorf("HandleReservation() = %q, %v; want reserved for the attestor", owner, ok)
+	}
+	if bc.Balance(attestor) != 100 {
+		t.Errorf("Balance() = %d, want the allocation", bc.Balance(attestor))
+	}
+
+	for _, handle := range []string{"Admin", "m.o.d.e.r.a.t.i.o.n"} {
+		if _, err := bc.AddBlock([]*Transaction{claim(userKey, user, handle)}); !errors.Is(err, ErrReservedHandle) {
+			t.Errorf("AddBlock() claiming %q error = %v, want ErrReservedHandle", handle, err)
+		}
+	}
+	if _, err := bc.AddBlock([]*Transaction{claim(attestorKey, attestor, "moderation"), claim(userKey, user, "administrator")}); err != nil {
+		t.Errorf("AddBlock() of the attestor's own handle and an unreserved one error = %v", err)
+	}
+
+	mempool := NewMempool(nil)
+	reserved, _ := cfg.Reservations()
+	mempool.SetReservedHandles(reserved)
+	if err := mempool.Add(claim(userKey, user, "admin")); !errors.Is(err, ErrReservedHandle) {
+		t.Errorf("Mempool.Add() claiming a reserved handle error = %v, want ErrReservedHandle", err)
+	}
+
+	// A chain already holding a claim cannot have the handle reserved.
+	cfg.ReservedHandles = append(cfg.ReservedHandles, "administrator")
+	if err := bc.ApplyGenesis(cfg); !errors.Is(err, ErrReservedHandle) {
+		t.Errorf("ApplyGenesis() reserving a claimed handle error = %v, want ErrReservedHandle", err)
+	}
+	if _, ok := bc.HandleReservation("administrator"); ok {
+		t.Error("a failed ApplyGenesis() changed the reservations")
+	}
+}
+
+func TestLoadGenesisConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	data := `{"chainId": "testnet", "reservedHandles": ["admin"],
+		"systemAccounts": [{"role": "moderation-attestor", "address": "04ab", "handle": "moderation"}]}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadGenesisConfig(path)
+	if err != nil || cfg.ChainID != "testnet" || len(cfg.SystemAccounts) != 1 {
+		t.Fatalf("LoadGenesisConfig() = %+v, %v", cfg, err)
+	}
+	bc, _ := NewBlockchain()
+	if err := bc.ApplyGenesis(*cfg); err != nil || bc.ChainID() != "testnet" {
+		t.Errorf("ApplyGenesis() = %v, chain ID %q; want testnet", err, bc.ChainID())
+	}
+
+	for name, cfg := range map[string]GenesisConfig{
+		"bad chain ID":   {ChainID: "Test Net"},
+		"empty handle":   {ReservedHandles: []string{"--"}},
+		"folded twice":   {ReservedHandles: []string{"admin", "Admin"}},
+		"handle clash":   {ReservedHandles: []string{"admin"}, SystemAccounts: []SystemAccount{{Role: "r", Address: "a", Handle: "admin"}}},
+		"duplicate role": {SystemAccounts: []SystemAccount{{Role: "r", Address: "a"}, {Role: "r", Address: "b"}}},
+		"no address":     {SystemAccounts: []SystemAccount{{Role: "r"}}},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Validate(%s) succeeded, want an error", name)
+		}
+	}
+	if _, err := ParseGenesisConfig([]byte(`{"reservedHandle": ["admin"]}`)); err == nil {
+		t.Error("ParseGenesisConfig() accepted an unknown field")
+	}
+}
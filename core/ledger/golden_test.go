@@ -0,0 +1,126 @@
+package ledger
+
+import (
+	"digisocialblock/internal/testutil/golden"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// goldenPayload is a structured payload with a field of each kind the
+// payload codecs handle.
+type goldenPayload struct {
+	ContentCID string   `json:"contentCid"`
+	Tags       []string `json:"tags"`
+	Timestamp  int64    `json:"timestamp"`
+}
+
+// goldenTransaction returns a transaction with every field set to a fixed
+// value, so no part of its encoding depends on the clock or a random key.
+func goldenTransaction(tb testing.TB, format PayloadFormat) *Transaction {
+	tb.Helper()
+	payload, err := EncodePayload(format, goldenPayload{ContentCID: "bafy-golden", Tags: []string{"go", "dds"}, Timestamp: 1700000000})
+	if err != nil {
+		tb.Fatalf("EncodePayload() error = %v", err)
+	}
+	tx := &Transaction{
+		Timestamp:       1700000000000000000,
+		SenderPublicKey: "04" + strings.Repeat("ab", 64),
+		Type:            PostCreated,
+		Payload:         payload,
+		Signature:       []byte{0x30, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02},
+		ChainID:         DefaultChainID,
+		SigVersion:      1,
+		Stamp:           42,
+	}
+	tx.ID = HashTransactionContent(tx.Timestamp, tx.SenderPublicKey, tx.Type, tx.Payload)
+	return tx
+}
+
+func goldenJSON(tb testing.TB, v interface{}) []byte {
+	tb.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		tb.Fatalf("json.Marshal() error = %v", err)
+	}
+	return append(data, '\n')
+}
+
+func TestGolden_Transaction(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		format PayloadFormat
+	}{
+		{"json", PayloadFormatJSON},
+		{"cbor", PayloadFormatCBOR},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tx := goldenTransaction(t, tc.format)
+			golden.AssertBinary(t, "payload_"+tc.name+".hex", tx.Payload)
+			golden.Assert(t, "transaction_"+tc.name+".id-input", []byte(GenerateDeterministicTransactionIDInput(tx.Timestamp, tx.SenderPublicKey, tx.Type, tx.Payload)+"\n"))
+			golden.Assert(t, "transaction_"+tc.name+".json", goldenJSON(t, tx))
+
+			digest, err := tx.SigningDigest()
+			if err != nil {
+				t.Fatalf("SigningDigest() error = %v", err)
+			}
+			golden.AssertBinary(t, "transaction_"+tc.name+".digest", digest)
+		})
+	}
+}
+
+func TestGolden_TransactionDecodes(t *testing.T) {
+	var tx Transaction
+	if err := json.Unmarshal(golden.Read(t, "transaction_cbor.json"), &tx); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if err := tx.VerifyIntegrity(); err != nil {
+		t.Errorf("VerifyIntegrity() of the golden transaction error = %v", err)
+	}
+	var payload goldenPayload
+	if err := DecodePayload(tx.Payload, &payload); err != nil || payload.ContentCID != "bafy-golden" {
+		t.Errorf("DecodePayload() = %+v, %v", payload, err)
+	}
+}
+
+// TestGolden_SignedTransaction checks that a transaction signed when the
+// golden file was written still verifies, so signature compatibility does
+// not depend on the encodings above alone. ECDSA signatures are randomized,
+// so the file is only rewritten on -update.
+func TestGolden_SignedTransaction(t *testing.T) {
+	const name = "transaction_signed.json"
+	if golden.Updating() {
+		priv, addr := newTestKey(t)
+		golden.Write(t, name, goldenJSON(t, newSignedTestTx(t, priv, addr, `{"contentCid":"bafy-golden"}`)))
+	}
+	var tx Transaction
+	if err := json.Unmarshal(golden.Read(t, name), &tx); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if err := tx.VerifyIntegrity(); err != nil {
+		t.Errorf("VerifyIntegrity() error = %v", err)
+	}
+	if ok, err := tx.VerifySignature(); !ok || err != nil {
+		t.Errorf("VerifySignature() = %v, %v; want a valid signature", ok, err)
+	}
+}
+
+func TestGolden_Block(t *testing.T) {
+	txs := []*Transaction{goldenTransaction(t, PayloadFormatJSON), goldenTransaction(t, PayloadFormatCBOR)}
+	merkleRoot := MerkleRoot(GetTransactionHashes(txs))
+	block := &Block{
+		Index:         7,
+		Timestamp:     1700000000000000001,
+		Transactions:  txs,
+		PrevBlockHash: strings.Repeat("0f", 32),
+	}
+	block.Hash = HashBlockContent(block.Index, block.Timestamp, block.PrevBlockHash, merkleRoot)
+
+	golden.Assert(t, "block.header-input", []byte(GenerateDeterministicBlockHeaderInput(block.Index, block.Timestamp, block.PrevBlockHash, merkleRoot)+"\n"))
+	golden.Assert(t, "block.json", goldenJSON(t, block))
+	golden.Assert(t, "block_empty.json", goldenJSON(t, &Block{
+		Timestamp:    1700000000000000000,
+		Transactions: []*Transaction{},
+		Hash:         HashBlockContent(0, 1700000000000000000, "", MerkleRoot(nil)),
+	}))
+}
@@ -0,0 +1,127 @@
+package ledger
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrNoPayloadDecoder is returned by DecodeTypedPayload for transaction types
+// without a registered payload decoder.
+var ErrNoPayloadDecoder = errors.New("no payload decoder registered for transaction type")
+
+// PayloadDecoder decodes a payload of one transaction type into its payload
+// struct. It must not validate the result, so that payloads the ledger
+// rejects can still be inspected; validation is the PayloadValidator's job.
+type PayloadDecoder func(payload []byte) (interface{}, error)
+
+// payloadDecoders holds the decoders used to introspect payloads. Like the
+// validators, they are registered by the packages that define the payload
+// structs.
+var payloadDecoders = struct {
+	mu       sync.RWMutex
+	decoders map[TransactionType]PayloadDecoder
+}{decoders: make(map[TransactionType]PayloadDecoder)}
+
+// RegisterPayloadDecoder installs the payload decoder for txType, replacing
+// any previous one. A nil decoder removes it. Packages that define payload
+// structs (e.g. social for PostCreated) register theirs from init, next to
+// their validators.
+func RegisterPayloadDecoder(txType TransactionType, decoder PayloadDecoder) {
+	payloadDecoders.mu.Lock()
+	defer payloadDecoders.mu.Unlock()
+	if decoder == nil {
+		delete(payloadDecoders.decoders, txType)
+		return
+	}
+	payloadDecoders.decoders[txType] = decoder
+}
+
+// DecodeInto returns a PayloadDecoder that strictly decodes payloads of
+// either format (see DecodePayload) into a new value from newValue, e.g.
+// func() interface{} { return &social.Post{} }.
+func DecodeInto(newValue func() interface{}) PayloadDecoder {
+	return func(payload []byte) (interface{}, error) {
+		v := newValue()
+		if err := DecodePayload(payload, v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+// DecodableTypes returns the transaction types with a registered payload
+// decoder, sorted.
+func DecodableTypes() []TransactionType {
+	payloadDecoders.mu.RLock()
+	defer payloadDecoders.mu.RUnlock()
+	types := make([]TransactionType, 0, len(payloadDecoders.decoders))
+	for txType := range payloadDecoders.decoders {
+		types = append(types, txType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// DecodeTypedPayload decodes payload with the decoder registered for txType,
+// without validating it. It returns ErrNoPayloadDecoder if there is none.
+func DecodeTypedPayload(txType TransactionType, payload []byte) (interface{}, error) {
+	payloadDecoders.mu.RLock()
+	decoder := payloadDecoders.decoders[txType]
+	payloadDecoders.mu.RUnlock()
+	if decoder == nil {
+		return nil, ErrNoPayloadDecoder
+	}
+	return decoder(payload)
+}
+
+// DecodedPayload is a payload decoded for display, as produced by
+// InspectPayload. It marshals to JSON whatever the payload's wire format.
+type DecodedPayload struct {
+	Format  string      `json:"format,omitempty"` // "json" or "cbor"
+	Value   interface{} `json:"value,omitempty"`
+	RawHex  string      `json:"rawHex,omitempty"`  // Set when the payload could not be decoded
+	Error   string      `json:"error,omitempty"`   // Why the payload could not be decoded
+	Invalid string      `json:"invalid,omitempty"` // Why a decoded payload is rejected by the ledger
+}
+
+// InspectPayload decodes payload as the payload of a txType transaction for
+// explorers and tools. Types with a registered decoder are decoded into their
+// struct; other JSON payloads are passed through as is, and other CBOR ones,
+// which need a target type to decode, are left as hex. The payload is then
+// validated as the ledger would, and the reason it would be rejected, if
+// any, is reported in Invalid.
+func InspectPayload(txType TransactionType, payload []byte) *DecodedPayload {
+	d := &DecodedPayload{}
+	if len(payload) == 0 {
+		return d
+	}
+	format, err := DetectPayloadFormat(payload)
+	if err != nil {
+		d.RawHex, d.Error = hex.EncodeToString(payload), err.Error()
+		return d
+	}
+	d.Format = "json"
+	if format == PayloadFormatCBOR {
+		d.Format = "cbor"
+	}
+
+	v, err := DecodeTypedPayload(txType, payload)
+	switch {
+	case err == nil:
+		d.Value = v
+	case !errors.Is(err, ErrNoPayloadDecoder):
+		d.RawHex, d.Error = hex.EncodeToString(payload), err.Error()
+		return d
+	case format == PayloadFormatJSON && json.Valid(payload):
+		d.Value = json.RawMessage(payload)
+	default:
+		d.RawHex = hex.EncodeToString(payload)
+	}
+	if err := (&Transaction{Type: txType, Payload: payload}).ValidatePayload(); err != nil {
+		d.Invalid = err.Error()
+	}
+	return d
+}
@@ -0,0 +1,88 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// inspectTestPayload is a payload struct registered for inspectTestType.
+type inspectTestPayload struct {
+	Text string `json:"text"`
+}
+
+const inspectTestType TransactionType = "InspectTest"
+
+func registerInspectTestType(t *testing.T) {
+	t.Helper()
+	RegisterPayloadDecoder(inspectTestType, DecodeInto(func() interface{} { return &inspectTestPayload{} }))
+	RegisterPayloadValidator(inspectTestType, func(payload []byte) error {
+		var p inspectTestPayload
+		if err := DecodePayload(payload, &p); err != nil {
+			return err
+		}
+		if p.Text == "" {
+			return errors.New("empty text")
+		}
+		return nil
+	})
+	t.Cleanup(func() {
+		RegisterPayloadDecoder(inspectTestType, nil)
+		RegisterPayloadValidator(inspectTestType, nil)
+	})
+}
+
+func TestDecodeTypedPayload(t *testing.T) {
+	registerInspectTestType(t)
+	for _, format := range []PayloadFormat{PayloadFormatJSON, PayloadFormatCBOR} {
+		payload, err := EncodePayload(format, &inspectTestPayload{Text: "hi"})
+		if err != nil {
+			t.Fatalf("EncodePayload() error = %v", err)
+		}
+		v, err := DecodeTypedPayload(inspectTestType, payload)
+		if p, ok := v.(*inspectTestPayload); err != nil || !ok || p.Text != "hi" {
+			t.Errorf("DecodeTypedPayload(format %v) = %#v, %v", format, v, err)
+		}
+	}
+	if _, err := DecodeTypedPayload("Unregistered", []byte(`{}`)); !errors.Is(err, ErrNoPayloadDecoder) {
+		t.Errorf("DecodeTypedPayload() of an unregistered type error = %v, want ErrNoPayloadDecoder", err)
+	}
+	found := false
+	for _, txType := range DecodableTypes() {
+		found = found || txType == inspectTestType
+	}
+	if !found {
+		t.Errorf("DecodableTypes() = %v, want it to include %s", DecodableTypes(), inspectTestType)
+	}
+}
+
+func TestInspectPayload(t *testing.T) {
+	registerInspectTestType(t)
+	cbor, _ := EncodePayload(PayloadFormatCBOR, &inspectTestPayload{Text: "hi"})
+
+	tests := []struct {
+		name    string
+		txType  TransactionType
+		payload []byte
+		want    string
+	}{
+		{"registered CBOR", inspectTestType, cbor, `{"format":"cbor","value":{"text":"hi"}}`},
+		{"decoded but invalid", inspectTestType, []byte(`{"text":""}`), `{"format":"json","value":{"text":""},"invalid":"`},
+		{"undecodable", inspectTestType, []byte(`{"text":1}`), `{"format":"json","rawHex":"7b2274657874223a317d","error":"`},
+		{"unregistered JSON", "Unregistered", []byte(`{"any": 1}`), `{"format":"json","value":{"any":1}}`},
+		{"unregistered CBOR", "Unregistered", cbor, `{"format":"cbor","rawHex":"`},
+		{"unknown format", inspectTestType, []byte{0x7f}, `{"rawHex":"7f","error":"`},
+		{"empty", inspectTestType, nil, `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(InspectPayload(tt.txType, tt.payload))
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if len(got) < len(tt.want) || string(got[:len(tt.want)]) != tt.want {
+				t.Errorf("InspectPayload() = %s, want it to start with %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package ledger
+
+import (
+	"digisocialblock/core/errcode"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// DefaultMaxInvites is the number of invites an account may issue under an
+// InvitePolicy with a zero MaxPerAccount.
+const DefaultMaxInvites = 5
+
+// CodeInviteRequired is the error code of ErrInviteRequired.
+var CodeInviteRequired = errcode.Register("DSB-LEDGER-017", http.StatusForbidden, "transaction by a new account lacks a valid invite")
+
+// ErrInviteRequired is returned, on an invite-only chain, for a block with a
+// transaction by a new account that does not redeem a valid unused invite
+// for that account, or with an invite its issuer may not issue.
+var ErrInviteRequired = errcode.New(CodeInviteRequired, "transaction by a new account lacks a valid invite")
+
+func init() {
+	RegisterPayloadValidator(InviteIssued, ValidateInvitePayload)
+	RegisterPayloadDecoder(InviteIssued, DecodeInto(func() interface{} { return &InvitePayload{} }))
+}
+
+// InvitePayload invites the account with address Invitee to an invite-only
+// chain (see InvitePolicy). The invitee redeems it by referencing the
+// InviteIssued transaction's ID from its first transaction, which must be of
+// a type with a registered InviteResolver, such as a post or profile.
+type InvitePayload struct {
+	Invitee   string `json:"invitee"`
+	Expires   int64  `json:"expires,omitempty"` // UnixNano; compared with the time of the block. Zero never expires
+	Timestamp int64  `json:"timestamp"`         // UnixNano; distinguishes otherwise identical invites
+}
+
+// Validate checks that required fields are set.
+func (p *InvitePayload) Validate() error {
+	if p.Invitee == "" {
+		return fmt.Errorf("empty Invitee")
+	}
+	if p.Timestamp == 0 {
+		return fmt.Errorf("zero timestamp")
+	}
+	if p.Expires != 0 && p.Expires <= p.Timestamp {
+		return fmt.Errorf("invite expires at %d, before it is made", p.Expires)
+	}
+	return nil
+}
+
+// InviteFromPayload decodes an InviteIssued payload in either payload
+// format. The result must pass Validate.
+func InviteFromPayload(payload []byte) (*InvitePayload, error) {
+	var p InvitePayload
+	if err := DecodePayload(payload, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode invite payload: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("decoded invite is invalid: %w", err)
+	}
+	return &p, nil
+}
+
+// ValidateInvitePayload is the schema validator for InviteIssued payloads.
+func ValidateInvitePayload(payload []byte) error {
+	_, err := InviteFromPayload(payload)
+	return err
+}
+
+// NewInviteTransaction creates an unsigned InviteIssued transaction by which
+// inviter invites invitee until expires (zero for never), stamped by clock
+// (SystemClock if nil).
+func NewInviteTransaction(clock Clock, inviter, invitee string, expires int64) (*Transaction, error) {
+	if clock == nil {
+		clock = SystemClock
+	}
+	if invitee == inviter {
+		return nil, fmt.Errorf("cannot invite the inviter")
+	}
+	p := &InvitePayload{Invitee: invitee, Expires: expires, Timestamp: clock.Now().UnixNano()}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid invite: %w", err)
+	}
+	payload, err := EncodePayload(PayloadFormatCBOR, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode invite payload: %w", err)
+	}
+	return NewTransactionWithClock(clock, inviter, InviteIssued, payload)
+}
+
+// InviteResolver returns the ID of the InviteIssued transaction that a
+// transaction with the given payload redeems, or "" if it redeems none.
+type InviteResolver func(payload []byte) (string, error)
+
+// inviteResolvers holds the resolvers of the transaction types that can
+// redeem invites. Like handle resolvers, they are registered by the packages
+// that define the payloads.
+var inviteResolvers = struct {
+	mu        sync.RWMutex
+	resolvers map[TransactionType]InviteResolver
+}{resolvers: make(map[TransactionType]InviteResolver)}
+
+// RegisterInviteResolver installs the invite resolver for txType, replacing
+// any previous one. A nil resolver removes it.
+func RegisterInviteResolver(txType TransactionType, resolver InviteResolver) {
+	inviteResolvers.mu.Lock()
+	defer inviteResolvers.mu.Unlock()
+	if resolver == nil {
+		delete(inviteResolvers.resolvers, txType)
+		return
+	}
+	inviteResolvers.resolvers[txType] = resolver
+}
+
+// RedeemedInvite returns the ID of the invite tx redeems, according to the
+// resolver registered for its type, or "" if it redeems none.
+func (tx *Transaction) RedeemedInvite() (string, error) {
+	inviteResolvers.mu.RLock()
+	resolver := inviteResolvers.resolvers[tx.Type]
+	inviteResolvers.mu.RUnlock()
+	if resolver == nil {
+		return "", nil
+	}
+	return resolver(tx.Payload)
+}
+
+// InvitePolicy makes a chain invite-only. The zero value admits anyone.
+type InvitePolicy struct {
+	// Required refuses transactions by accounts that have not yet transacted
+	// on the chain, unless they redeem an invite for the account. Accounts
+	// with genesis allocations and system accounts need no invite.
+	Required bool `json:"required"`
+	// MaxPerAccount bounds the invites one account may ever issue. Zero
+	// means DefaultMaxInvites.
+	MaxPerAccount int `json:"maxPerAccount,omitempty"`
+}
+
+// Validate checks that MaxPerAccount is not negative.
+func (p InvitePolicy) Validate() error {
+	if p.MaxPerAccount < 0 {
+		return fmt.Errorf("invalid invite policy: negative MaxPerAccount %d", p.MaxPerAccount)
+	}
+	return nil
+}
+
+func (p InvitePolicy) maxPerAccount() int {
+	if p.MaxPerAccount == 0 {
+		return DefaultMaxInvites
+	}
+	return p.MaxPerAccount
+}
+
+// Invite is an invite issued on the chain.
+type Invite struct {
+	ID      string // Of the InviteIssued transaction
+	Inviter string
+	Invitee string
+	Expires int64 // UnixNano; zero never expires
+	Used    bool
+}
+
+// inviteState holds what an invite-only chain needs to check the next
+// block: the accounts that have transacted, every invite, and how many
+// invites each account has issued.
+type inviteState struct {
+	accounts map[string]bool
+	invites  map[string]*Invite
+	issued   map[string]int
+}
+
+func newInviteState() *inviteState {
+	return &inviteState{accounts: make(map[string]bool), invites: make(map[string]*Invite), issued: make(map[string]int)}
+}
+
+// applyInvites computes the invite state changes made by txs, in a block
+// made at time at, on top of state. It fails with ErrInviteRequired if a
+// new account's transaction does not redeem a valid unused invite for it,
+// or an account issues more invites than policy allows, and with
+// ErrDuplicateTransaction if an invite is issued twice. Transactions are
+// applied in order, so an invite may be redeemed later in the block that
+// issues it.
+func applyInvites(state *inviteState, policy InvitePolicy, txs []*Transaction, at int64) (*inviteState, error) {
+	changes := newInviteState()
+	invite := func(id string) *Invite {
+		if inv, ok := changes.invites[id]; ok {
+			return inv
+		}
+		return state.invites[id]
+	}
+	for i, tx := range txs {
+		if tx == nil {
+			continue
+		}
+		sender := tx.SenderPublicKey
+		if !state.accounts[sender] && !changes.accounts[sender] {
+			id, err := tx.RedeemedInvite()
+			if err != nil {
+				return nil, reject(i, tx, fmt.Errorf("%w: transaction %s: %v", ErrInvalidPayload, tx.ID, err))
+			}
+			inv := invite(id)
+			switch {
+			case id == "":
+				return nil, reject(i, tx, fmt.Errorf("%w: transaction %s is the first by %s and redeems no invite", ErrInviteRequired, tx.ID, sender))
+			case inv == nil:
+				return nil, reject(i, tx, fmt.Errorf("%w: transaction %s redeems unknown invite %s", ErrInviteRequired, tx.ID, id))
+			case inv.Invitee != sender:
+				return nil, reject(i, tx, fmt.Errorf("%w: transaction %s redeems invite %s issued to another account", ErrInviteRequired, tx.ID, id))
+			case inv.Used:
+				return nil, reject(i, tx, fmt.Errorf("%w: transaction %s redeems invite %s, which is already used", ErrInviteRequired, tx.ID, id))
+			case inv.Expires != 0 && at >= inv.Expires:
+				return nil, reject(i, tx, fmt.Errorf("%w: transaction %s redeems invite %s, which has expired", ErrInviteRequired, tx.ID, id))
+			}
+			used := *inv
+			used.Used = true
+			changes.invites[id] = &used
+			changes.accounts[sender] = true
+		}
+		if tx.Type != InviteIssued {
+			continue
+		}
+		p, err := InviteFromPayload(tx.Payload)
+		if err != nil {
+			return nil, reject(i, tx, fmt.Errorf("%w: invite %s: %v", ErrInvalidPayload, tx.ID, err))
+		}
+		// A replayed invite would be issued again, unused, and count again.
+		if invite(tx.ID) != nil {
+			return nil, reject(i, tx, fmt.Errorf("%w: invite %s is already issued", ErrDuplicateTransaction, tx.ID))
+		}
+		issued, ok := changes.issued[sender]
+		if !ok {
+			issued = state.issued[sender]
+		}
+		if issued >= policy.maxPerAccount() {
+			return nil, reject(i, tx, fmt.Errorf("%w: %s has already issued its %d invites", ErrInviteRequired, sender, issued))
+		}
+		changes.issued[sender] = issued + 1
+		changes.invites[tx.ID] = &Invite{ID: tx.ID, Inviter: sender, Invitee: p.Invitee, Expires: p.Expires}
+	}
+	return changes, nil
+}
+
+// checkInvitesLocked returns the invite state changes of appending a block
+// with txs made at time at, or nil if the chain is not invite-only. The
+// caller must hold bc.mu.
+func (bc *Blockchain) checkInvitesLocked(txs []*Transaction, at int64) (*inviteState, error) {
+	if !bc.invitePolicy.Required {
+		return nil, nil
+	}
+	if bc.invites == nil {
+		if err := bc.replayInvitesLocked(); err != nil {
+			return nil, err
+		}
+	}
+	return applyInvites(bc.invites, bc.invitePolicy, txs, at)
+}
+
+// commitInvites applies changes to the chain's invite state. The caller must
+// hold bc.mu and have computed changes with checkInvitesLocked.
+func (bc *Blockchain) commitInvites(changes *inviteState) {
+	if changes == nil || bc.invites == nil {
+		return
+	}
+	for account := range changes.accounts {
+		bc.invites.accounts[account] = true
+	}
+	for id, inv := range changes.invites {
+		bc.invites.invites[id] = inv
+	}
+	for account, n := range changes.issued {
+		bc.invites.issued[account] = n
+	}
+}
+
+// replayInvitesLocked recomputes the invite state from the genesis accounts
+// and every block in the chain. On failure the state is left uncomputed.
+// The caller must hold bc.mu.
+func (bc *Blockchain) replayInvitesLocked() error {
+	bc.invites = newInviteState()
+	for address := range bc.allocations {
+		bc.invites.accounts[address] = true
+	}
+	for _, account := range bc.systemAccounts {
+		bc.invites.accounts[account.Address] = true
+	}
+	for _, block := range bc.Blocks {
+		changes, err := applyInvites(bc.invites, bc.invitePolicy, block.Transactions, block.Timestamp)
+		if err != nil {
+			bc.invites = nil
+			return fmt.Errorf("block %d: %w", block.Index, err)
+		}
+		bc.commitInvites(changes)
+	}
+	return nil
+}
+
+// SetInvitePolicy makes the chain invite-only, or open again with the zero
+// policy. Like the genesis allocations, the policy decides which blocks the
+// chain accepts, so every node of a chain must apply the same one, and must
+// set it again after opening a stored chain (or apply it with the rest of
+// the GenesisConfig). It fails, keeping the previous policy, if the chain
+// already breaks the new one.
+func (bc *Blockchain) SetInvitePolicy(policy InvitePolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	previous := bc.invitePolicy
+	bc.invitePolicy = policy
+	bc.invites = nil
+	if policy.Required {
+		if err := bc.replayInvitesLocked(); err != nil {
+			bc.invitePolicy = previous
+			return err
+		}
+	}
+	return nil
+}
+
+// Invite returns the invite issued by the InviteIssued transaction id, as of
+// the latest block. Invites are only tracked on an invite-only chain.
+func (bc *Blockchain) Invite(id string) (Invite, bool) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if !bc.invitePolicy.Required || (bc.invites == nil && bc.replayInvitesLocked() != nil) {
+		return Invite{}, false
+	}
+	inv, ok := bc.invites.invites[id]
+	if !ok {
+		return Invite{}, false
+	}
+	return *inv, true
+}
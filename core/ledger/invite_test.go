@@ -0,0 +1,127 @@
+package ledger
+
+import (
+	"crypto/ecdsa"
+	"digisocialblock/internal/testutil"
+	"errors"
+	"testing"
+	"time"
+)
+
+// inviteTestType is a transaction type whose payload is the ID of the invite
+// it redeems.
+const inviteTestType = TransactionType("InviteTest")
+
+// inviteTestSetup returns an invite-only chain on a fake clock, founded by
+// the account returned with its key, which may issue maxInvites invites.
+func inviteTestSetup(t *testing.T, maxInvites int) (*Blockchain, *testutil.Clock, *ecdsa.PrivateKey, string) {
+	t.Helper()
+	RegisterInviteResolver(inviteTestType, func(payload []byte) (string, error) {
+		return string(payload), nil
+	})
+	t.Cleanup(func() { RegisterInviteResolver(inviteTestType, nil) })
+	bc, _ := NewBlockchain()
+	clock := testutil.NewClock(time.Millisecond)
+	bc.SetClock(clock)
+	key, founder := newTestKey(t)
+	err := bc.ApplyGenesis(GenesisConfig{
+		Allocations: map[string]uint64{founder: 1},
+		Invites:     &InvitePolicy{Required: true, MaxPerAccount: maxInvites},
+	})
+	if err != nil {
+		t.Fatalf("ApplyGenesis() error = %v", err)
+	}
+	return bc, clock, key, founder
+}
+
+func TestInvitePolicy_AdmitsOnlyInvitedAccounts(t *testing.T) {
+	bc, clock, founderKey, founder := inviteTestSetup(t, 2)
+	aliceKey, alice := newTestKey(t)
+	bobKey, bob := newTestKey(t)
+	invite := func(priv *ecdsa.PrivateKey, inviter, invitee string, expires int64) *Transaction {
+		tx, err := NewInviteTransaction(clock, inviter, invitee, expires)
+		if err != nil {
+			t.Fatalf("NewInviteTransaction() error = %v", err)
+		}
+		tx.Sign(priv)
+		return tx
+	}
+	redeem := func(priv *ecdsa.PrivateKey, sender, inviteID string) *Transaction {
+		tx, _ := NewTransactionWithClock(clock, sender, inviteTestType, []byte(inviteID))
+		tx.Sign(priv)
+		return tx
+	}
+
+	if _, err := bc.AddBlock([]*Transaction{redeem(bobKey, bob, "")}); !errors.Is(err, ErrInviteRequired) {
+		t.Errorf("AddBlock() of an uninvited account's transaction error = %v, want ErrInviteRequired", err)
+	}
+	forAlice := invite(founderKey, founder, alice, 0)
+	if _, err := bc.AddBlock([]*Transaction{forAlice, redeem(aliceKey, alice, forAlice.ID), redeem(aliceKey, alice, "")}); err != nil {
+		t.Fatalf("AddBlock() of an invite redeemed in the same block error = %v", err)
+	}
+	if inv, ok := bc.Invite(forAlice.ID); !ok || !inv.Used || inv.Inviter != founder || inv.Invitee != alice {
+		t.Errorf("Invite() = %+v, %v; want alice's invite used", inv, ok)
+	}
+	if _, err := bc.AddBlock([]*Transaction{redeem(bobKey, bob, forAlice.ID)}); !errors.Is(err, ErrInviteRequired) {
+		t.Errorf("AddBlock() redeeming another account's invite error = %v, want ErrInviteRequired", err)
+	}
+
+	forBob := invite(founderKey, founder, bob, clock.Now().Add(time.Second).UnixNano())
+	if _, err := bc.AddBlock([]*Transaction{forBob}); err != nil {
+		t.Fatalf("AddBlock() of an expiring invite error = %v", err)
+	}
+	clock.Advance(time.Second)
+	if _, err := bc.AddBlock([]*Transaction{redeem(bobKey, bob, forBob.ID)}); !errors.Is(err, ErrInviteRequired) {
+		t.Errorf("AddBlock() redeeming an expired invite error = %v, want ErrInviteRequired", err)
+	}
+	if _, err := bc.AddBlock([]*Transaction{invite(founderKey, founder, bob, 0)}); !errors.Is(err, ErrInviteRequired) {
+		t.Errorf("AddBlock() of a third invite error = %v, want the limit of 2 enforced", err)
+	}
+	if _, err := bc.AddBlock([]*Transaction{invite(aliceKey, alice, bob, 0)}); err != nil {
+		t.Errorf("AddBlock() of an invite by an invited account error = %v", err)
+	}
+
+	// Once an account joins uninvited, the chain breaks any invite policy.
+	if err := bc.SetInvitePolicy(InvitePolicy{}); err != nil {
+		t.Fatalf("SetInvitePolicy(open) error = %v", err)
+	}
+	if _, err := bc.AddBlock([]*Transaction{redeem(bobKey, bob, "")}); err != nil {
+		t.Fatalf("AddBlock() on an open chain error = %v", err)
+	}
+	if err := bc.SetInvitePolicy(InvitePolicy{Required: true, MaxPerAccount: 2}); err == nil {
+		t.Error("SetInvitePolicy() accepted a policy the chain breaks")
+	}
+}
+
+func TestInvitePolicy_RejectsReplayedInvites(t *testing.T) {
+	bc, clock, founderKey, founder := inviteTestSetup(t, 2)
+	aliceKey, alice := newTestKey(t)
+	forAlice, _ := NewInviteTransaction(clock, founder, alice, 0)
+	forAlice.Sign(founderKey)
+	redeem, _ := NewTransactionWithClock(clock, alice, inviteTestType, []byte(forAlice.ID))
+	redeem.Sign(aliceKey)
+	if _, err := bc.AddBlock([]*Transaction{forAlice, redeem}); err != nil {
+		t.Fatalf("AddBlock() of a redeemed invite error = %v", err)
+	}
+
+	if _, err := bc.AddBlock([]*Transaction{forAlice}); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Errorf("AddBlock() replaying a redeemed invite error = %v, want ErrDuplicateTransaction", err)
+	}
+	// The invite state refuses a replay on its own too, whether the invite is
+	// on chain or earlier in the same block.
+	at := clock.Now().UnixNano()
+	if _, err := applyInvites(bc.invites, bc.invitePolicy, []*Transaction{forAlice}, at); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Errorf("applyInvites() replaying a redeemed invite error = %v, want ErrDuplicateTransaction", err)
+	}
+	forBob, _ := NewInviteTransaction(clock, founder, "bob", 0)
+	forBob.Sign(founderKey)
+	if _, err := applyInvites(bc.invites, bc.invitePolicy, []*Transaction{forBob, forBob}, at); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Errorf("applyInvites() with an invite twice error = %v, want ErrDuplicateTransaction", err)
+	}
+	if inv, ok := bc.Invite(forAlice.ID); !ok || !inv.Used {
+		t.Errorf("Invite() = %+v, %v; want alice's invite still used", inv, ok)
+	}
+	if _, err := bc.AddBlock([]*Transaction{forBob}); err != nil {
+		t.Errorf("AddBlock() of the founder's second invite error = %v, want the replays not counted", err)
+	}
+}
@@ -0,0 +1,324 @@
+package ledger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Mempool holds validated transactions that are waiting to be included in a block.
+// Transactions are kept in arrival order. A Mempool is safe for concurrent
+// use; the transactions it holds are shared with callers and must not be
+// modified once added.
+type Mempool struct {
+	mu           sync.Mutex
+	transactions map[string]*Transaction
+	order        []string
+	sigCache     *SignatureCache // Optional; shared with the Blockchain to avoid re-verifying signatures
+	stampPolicy  StampPolicy     // Proof-of-work required for admission; zero value requires none
+	chainID      string          // Transactions for other chains are rejected; "" admits any
+	clock        Clock
+	rule         TransactionRule   // Optional; see SetTransactionRule
+	reserved     map[string]string // Reserved handles; see SetReservedHandles
+	delegations  DelegationSource  // Optional; see SetDelegations
+	onChain      TransactionIndex  // Optional; see SetTransactionIndex
+
+	// Spam classification; see SetSpamClassifier.
+	spam       SpamClassifier
+	spamPolicy SpamPolicy
+	auditor    SpamAuditor
+	senders    map[string]*mempoolSender
+	pruned     time.Time            // Of the last sweep of senders
+	held       map[string]time.Time // Delayed transactions and their release times
+	low        map[string]bool      // Deprioritized transactions
+}
+
+// mempoolSender is the admission history of one sender, for SpamFeatures.
+type mempoolSender struct {
+	admitted []time.Time // Within the policy's HistoryWindow, oldest first
+	pending  int
+}
+
+// NewMempool creates an empty Mempool.
+// sigCache may be nil, in which case every admission performs a full signature check.
+// Passing the same cache to Blockchain.SetSignatureCache lets AddBlock skip
+// verifying signatures that were already checked here.
+func NewMempool(sigCache *SignatureCache) *Mempool {
+	return &Mempool{
+		transactions: make(map[string]*Transaction),
+		sigCache:     sigCache,
+		clock:        SystemClock,
+		senders:      make(map[string]*mempoolSender),
+		held:         make(map[string]time.Time),
+		low:          make(map[string]bool),
+	}
+}
+
+// SetClock sets the clock that sender histories and spam delays are measured
+// by. A nil clock restores SystemClock.
+func (mp *Mempool) SetClock(clock Clock) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	if clock == nil {
+		clock = SystemClock
+	}
+	mp.clock = clock
+}
+
+// SetSpamClassifier makes Add classify every transaction that passes the
+// other admission checks, and act on its score as policy says: reject it
+// with ErrSpamRejected, hold it back from Pending for policy.Delay, or list
+// it in Pending after all other transactions. Every decision is passed to
+// auditor, if it is not nil. A nil classifier turns classification off;
+// transactions already delayed or deprioritized stay so.
+func (mp *Mempool) SetSpamClassifier(classifier SpamClassifier, policy SpamPolicy, auditor SpamAuditor) error {
+	if err := policy.Validate(); err != nil {
+		return fmt.Errorf("invalid spam policy: %w", err)
+	}
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.spam, mp.spamPolicy, mp.auditor = classifier, policy.withDefaults(), auditor
+	return nil
+}
+
+// SetStampPolicy sets the proof-of-work stamp required for admission.
+func (mp *Mempool) SetStampPolicy(policy StampPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return fmt.Errorf("invalid stamp policy: %w", err)
+	}
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.stampPolicy = policy
+	return nil
+}
+
+// SetChainID makes the mempool reject transactions for chains other than
+// chainID with ErrWrongChain, rather than leave them for AddBlock to refuse.
+// Nodes hosting several chains give each its own Mempool. An empty chainID
+// admits transactions for any chain.
+func (mp *Mempool) SetChainID(chainID string) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.chainID = chainID
+}
+
+// Add validates a transaction and admits it to the mempool.
+// Duplicate transaction IDs are rejected. A transaction refused for any
+// reason is reported as a *Rejection.
+func (mp *Mempool) Add(tx *Transaction) error {
+	return reject(0, tx, mp.add(tx))
+}
+
+// add validates and admits tx for Add.
+func (mp *Mempool) add(tx *Transaction) error {
+	if tx == nil {
+		return fmt.Errorf("cannot add a nil transaction to the mempool")
+	}
+	if err := tx.IsValid(); err != nil {
+		return fmt.Errorf("invalid transaction %s: %w", tx.ID, err)
+	}
+
+	// The stamp is checked before the signature: it costs one hash, so
+	// unstamped spam is turned away without an ECDSA verification.
+	mp.mu.Lock()
+	requiredBits := mp.stampPolicy.RequiredBits(tx.Type)
+	chainID, delegations, onChain, at := mp.chainID, mp.delegations, mp.onChain, mp.clock.Now().UnixNano()
+	mp.mu.Unlock()
+	if chainID != "" && tx.ChainID != chainID {
+		return fmt.Errorf("%w: transaction %s is for chain %q, not %q", ErrWrongChain, tx.ID, tx.ChainID, chainID)
+	}
+	if onChain != nil && onChain.HasTransaction(tx.ID) {
+		return fmt.Errorf("%w: transaction %s is already on chain", ErrDuplicateTransaction, tx.ID)
+	}
+	if err := tx.VerifyStamp(requiredBits); err != nil {
+		return fmt.Errorf("transaction %s rejected: %w", tx.ID, err)
+	}
+	// VerifySignature checks a delegate's signature against the delegate's
+	// key, so the delegation must be checked before it counts for anything.
+	if err := checkDelegate(delegations, tx, at); err != nil {
+		return err
+	}
+
+	var validSig bool
+	var err error
+	if mp.sigCache != nil {
+		validSig, err = mp.sigCache.Verify(tx)
+	} else {
+		validSig, err = tx.VerifySignature()
+	}
+	if err != nil {
+		return fmt.Errorf("error verifying signature for transaction %s: %w", tx.ID, err)
+	}
+	if !validSig {
+		return fmt.Errorf("%w: transaction %s", ErrInvalidSignature, tx.ID)
+	}
+
+	mp.mu.Lock()
+	if _, exists := mp.transactions[tx.ID]; exists {
+		mp.mu.Unlock()
+		return fmt.Errorf("%w: transaction %s is already in the mempool", ErrDuplicateTransaction, tx.ID)
+	}
+	classifier, policy, auditor, now := mp.spam, mp.spamPolicy, mp.auditor, mp.clock.Now()
+	rule, reserved := mp.rule, mp.reserved
+	var features SpamFeatures
+	if classifier != nil {
+		features = mp.spamFeatures(tx, now)
+	}
+	mp.mu.Unlock()
+
+	if err := checkHandles(reserved, tx); err != nil {
+		return err
+	}
+	if err := checkRule(rule, []*Transaction{tx}, RuleEnv{Height: -1, Time: now}); err != nil {
+		return err
+	}
+
+	// The classifier runs unlocked, so a slow one delays only its own caller.
+	decision := SpamDecision{Verdict: SpamAccept}
+	if classifier != nil {
+		decision = SpamDecision{
+			Time:      now,
+			TxID:      tx.ID,
+			Sender:    tx.SenderPublicKey,
+			Type:      tx.Type,
+			Features:  features,
+			SpamScore: classifier.Classify(tx, features),
+		}
+		decision.Verdict = policy.Verdict(decision.Score)
+		if decision.Verdict == SpamDelay {
+			decision.Until = now.Add(policy.Delay)
+		}
+		if auditor != nil {
+			auditor.RecordSpamDecision(decision)
+		}
+		if decision.Verdict == SpamReject {
+			return fmt.Errorf("%w: transaction %s scored %.2f: %s", ErrSpamRejected, tx.ID, decision.Score, decision.Reason)
+		}
+	}
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	if _, exists := mp.transactions[tx.ID]; exists {
+		return fmt.Errorf("%w: transaction %s is already in the mempool", ErrDuplicateTransaction, tx.ID)
+	}
+	mp.transactions[tx.ID] = tx
+	mp.order = append(mp.order, tx.ID)
+	switch decision.Verdict {
+	case SpamDelay:
+		mp.held[tx.ID] = decision.Until
+	case SpamDeprioritize:
+		mp.low[tx.ID] = true
+	}
+	if classifier != nil {
+		s := mp.senders[tx.SenderPublicKey]
+		if s == nil {
+			s = &mempoolSender{}
+			mp.senders[tx.SenderPublicKey] = s
+		}
+		s.admitted = append(s.admitted, now)
+		s.pending++
+	}
+	return nil
+}
+
+// spamFeatures computes the features of tx arriving at now, forgetting
+// history older than the policy's HistoryWindow. The caller must hold mp.mu.
+func (mp *Mempool) spamFeatures(tx *Transaction, now time.Time) SpamFeatures {
+	policy := mp.spamPolicy
+	horizon := now.Add(-policy.HistoryWindow)
+	if now.Sub(mp.pruned) >= policy.RateWindow {
+		for sender, s := range mp.senders {
+			if s.pending == 0 && (len(s.admitted) == 0 || !s.admitted[len(s.admitted)-1].After(horizon)) {
+				delete(mp.senders, sender)
+			}
+		}
+		mp.pruned = now
+	}
+
+	f := SpamFeatures{PayloadSize: len(tx.Payload), PayloadEntropy: PayloadEntropy(tx.Payload)}
+	s := mp.senders[tx.SenderPublicKey]
+	if s == nil {
+		return f
+	}
+	kept := s.admitted[:0]
+	for _, at := range s.admitted {
+		if at.After(horizon) {
+			kept = append(kept, at)
+		}
+	}
+	s.admitted = kept
+	recent := now.Add(-policy.RateWindow)
+	for _, at := range s.admitted {
+		if at.After(recent) {
+			f.SenderRecent++
+		}
+	}
+	f.SenderPending = s.pending
+	f.SenderHistory = len(s.admitted)
+	if len(s.admitted) > 0 {
+		f.SenderAge = now.Sub(s.admitted[0])
+	}
+	return f
+}
+
+// Pending returns the queued transactions in arrival order, except that
+// transactions deprioritized by the spam policy come after all others and
+// delayed ones are left out until their delay has passed.
+func (mp *Mempool) Pending() []*Transaction {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	now := mp.clock.Now()
+	pending := make([]*Transaction, 0, len(mp.order))
+	var low []*Transaction
+	for _, id := range mp.order {
+		if until, ok := mp.held[id]; ok {
+			if now.Before(until) {
+				continue
+			}
+			delete(mp.held, id)
+		}
+		if mp.low[id] {
+			low = append(low, mp.transactions[id])
+			continue
+		}
+		pending = append(pending, mp.transactions[id])
+	}
+	return append(pending, low...)
+}
+
+// Remove drops the given transactions from the mempool, typically after they
+// have been included in a block. Unknown IDs are ignored.
+func (mp *Mempool) Remove(txIDs ...string) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	removed := false
+	for _, id := range txIDs {
+		if tx, ok := mp.transactions[id]; ok {
+			delete(mp.transactions, id)
+			delete(mp.held, id)
+			delete(mp.low, id)
+			if s := mp.senders[tx.SenderPublicKey]; s != nil && s.pending > 0 {
+				s.pending--
+			}
+			removed = true
+		}
+	}
+	if !removed {
+		return
+	}
+	kept := mp.order[:0]
+	for _, id := range mp.order {
+		if _, ok := mp.transactions[id]; ok {
+			kept = append(kept, id)
+		}
+	}
+	mp.order = kept
+}
+
+// Size returns the number of transactions waiting in the mempool, including
+// delayed ones.
+func (mp *Mempool) Size() int {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	return len(mp.order)
+}
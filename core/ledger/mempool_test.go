@@ -0,0 +1,72 @@
+package ledger
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMempool_AddAndRemove(t *testing.T) {
+	priv, addr := newTestKey(t)
+	mp := NewMempool(nil)
+
+	tx1 := newSignedTestTx(t, priv, addr, "first")
+	tx2 := newSignedTestTx(t, priv, addr, "second")
+	tx3 := newSignedTestTx(t, priv, addr, "third")
+	for _, tx := range []*Transaction{tx1, tx2, tx3} {
+		if err := mp.Add(tx); err != nil {
+			t.Fatalf("Add(%s) error = %v", tx.ID, err)
+		}
+	}
+	if err := mp.Add(tx1); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Errorf("Add() of a duplicate transaction error = %v, want ErrDuplicateTransaction", err)
+	}
+	if mp.Size() != 3 {
+		t.Fatalf("Size() = %d, want 3", mp.Size())
+	}
+
+	mp.Remove(tx2.ID, "unknown-id")
+	pending := mp.Pending()
+	if len(pending) != 2 || pending[0].ID != tx1.ID || pending[1].ID != tx3.ID {
+		t.Errorf("Pending() after Remove did not preserve arrival order of remaining transactions")
+	}
+}
+
+func TestMempool_RejectsInvalidTransactions(t *testing.T) {
+	priv, addr := newTestKey(t)
+	mp := NewMempool(nil)
+
+	unsigned, _ := NewTransaction(addr, PostCreated, []byte("unsigned"))
+	if err := mp.Add(unsigned); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Add() of an unsigned transaction error = %v, want ErrInvalidSignature", err)
+	}
+
+	forged := newSignedTestTx(t, priv, addr, "forged")
+	_, otherAddr := newTestKey(t)
+	forged.SenderPublicKey = otherAddr
+	if err := mp.Add(forged); !errors.Is(err, ErrTransactionIDMismatch) {
+		t.Errorf("Add() of a transaction signed by a different key error = %v, want ErrTransactionIDMismatch", err)
+	}
+
+	if err := mp.Add(nil); err == nil {
+		t.Error("Add(nil): expected error, got nil")
+	}
+	if mp.Size() != 0 {
+		t.Errorf("Size() = %d, want 0", mp.Size())
+	}
+}
+
+func TestMempool_RejectsOtherChains(t *testing.T) {
+	priv, addr := newTestKey(t)
+	mp := NewMempool(nil)
+	mp.SetChainID("testnet")
+
+	if err := mp.Add(newSignedTestTx(t, priv, addr, "main")); !errors.Is(err, ErrWrongChain) {
+		t.Errorf("Add() of a transaction for another chain error = %v, want ErrWrongChain", err)
+	}
+	tx, _ := NewTransaction(addr, PostCreated, []byte("test"))
+	tx.ChainID = "testnet"
+	tx.Sign(priv)
+	if err := mp.Add(tx); err != nil {
+		t.Errorf("Add() of a transaction for the mempool's chain error = %v", err)
+	}
+}
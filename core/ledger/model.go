@@ -1,27 +1,49 @@
 package ledger
 
-import "time"
-
 // TransactionType defines the type of action a transaction represents.
 type TransactionType string
 
 const (
-	PostCreated   TransactionType = "PostCreated"
-	CommentAdded  TransactionType = "CommentAdded"
-	Like          TransactionType = "Like"
-	UserFollowed  TransactionType = "UserFollowed"
-	ProfileUpdate TransactionType = "ProfileUpdate"
+	PostCreated      TransactionType = "PostCreated"
+	CommentAdded     TransactionType = "CommentAdded"
+	CommentEdited    TransactionType = "CommentEdited"
+	CommentDeleted   TransactionType = "CommentDeleted"
+	Like             TransactionType = "Like"
+	UserFollowed     TransactionType = "UserFollowed"
+	ProfileUpdate    TransactionType = "ProfileUpdate"
+	EngagementReport TransactionType = "EngagementReport"
+	PostBookmarked   TransactionType = "PostBookmarked"
+	ListCreated      TransactionType = "ListCreated"
+	ListMemberAdded  TransactionType = "ListMemberAdded"
+	Transfer         TransactionType = "Transfer"
+	KeyDelegated     TransactionType = "KeyDelegated"
+	StorageAttested  TransactionType = "StorageAttested"
+	InviteIssued     TransactionType = "InviteIssued"
+	ProposalCreated  TransactionType = "ProposalCreated"
+	VoteCast         TransactionType = "VoteCast"
 	// Add other transaction types as needed
 )
 
 // Transaction represents a single action or event in the Digisocialblock system.
 type Transaction struct {
-	ID              string          `json:"id"`              // Unique identifier (hash of key transaction data)
-	Timestamp       int64           `json:"timestamp"`       // Unix timestamp of when the transaction was created
-	SenderPublicKey string          `json:"senderPublicKey"` // Public key of the user initiating the transaction
-	Type            TransactionType `json:"type"`            // Type of the transaction (e.g., "PostCreated")
-	Payload         []byte          `json:"payload"`         // Serialized data specific to the transaction type (e.g., post content CID, comment details)
-	Signature       []byte          `json:"signature"`       // Cryptographic signature of the transaction data
+	ID              string          `json:"id"`                     // Unique identifier (hash of key transaction data)
+	Timestamp       int64           `json:"timestamp"`              // Unix timestamp of when the transaction was created
+	SenderPublicKey string          `json:"senderPublicKey"`        // Public key of the user initiating the transaction
+	Type            TransactionType `json:"type"`                   // Type of the transaction (e.g., "PostCreated")
+	Payload         []byte          `json:"payload"`                // Serialized data specific to the transaction type (e.g., post content CID, comment details)
+	Signature       []byte          `json:"signature"`              // Cryptographic signature of the transaction data
+	ChainID         string          `json:"chainId,omitempty"`      // Chain the transaction is intended for; bound into the signature
+	SigVersion      int             `json:"sigVersion,omitempty"`   // Signing scheme version (0 = legacy signature over the bare ID)
+	Stamp           uint64          `json:"stamp,omitempty"`        // Optional anti-spam proof-of-work nonce bound to ID (see MintStamp)
+	CoSignatures    []CoSignature   `json:"coSignatures,omitempty"` // Signatures of the co-signers the payload requires (see RegisterCoSigners)
+	Delegate        string          `json:"delegate,omitempty"`     // Address of the delegated key that signed in the sender's place (see KeyDelegated)
+}
+
+// CoSignature is the signature of one additional signer of a transaction,
+// over the same digest as the sender's (see Transaction.SigningDigest).
+type CoSignature struct {
+	PublicKey string `json:"publicKey"` // Co-signer address
+	Signature []byte `json:"signature"`
 }
 
 // Block represents a collection of transactions, forming a unit in the blockchain.
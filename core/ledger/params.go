@@ -0,0 +1,243 @@
+package ledger
+
+import (
+	"bytes"
+	"digisocialblock/core/errcode"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Names of the protocol parameters, as used in ParamChange.
+const (
+	ParamMaxBlockBytes        = "maxBlockBytes"
+	ParamMaxBlockTransactions = "maxBlockTransactions"
+	ParamMinStampBits         = "minStampBits"
+)
+
+// CodeParamViolation is the error code of ErrParamViolation.
+var CodeParamViolation = errcode.Register("DSB-LEDGER-019", http.StatusUnprocessableEntity, "block breaks the protocol parameters in force at its height")
+
+// ErrParamViolation is returned for a block, or a transaction in it, that
+// breaks the protocol parameters in force at the block's height.
+var ErrParamViolation = errcode.New(CodeParamViolation, "block breaks the protocol parameters in force at its height")
+
+// Params are the protocol parameters a block is validated under. Unlike
+// node policy, they are a rule of the network that every node must agree on,
+// and they change only at scheduled heights (see ParamSchedule), so a block
+// stays valid under the parameters of its own height. The zero value imposes
+// no limits, and governs blocks before the first activation.
+type Params struct {
+	MaxBlockBytes        int `json:"maxBlockBytes,omitempty"`        // Of the block's transactions, encoded as JSON; 0 for no limit
+	MaxBlockTransactions int `json:"maxBlockTransactions,omitempty"` // 0 for no limit
+	// MinStampBits is the proof-of-work stamp (see MintStamp) every
+	// transaction in a block must carry: the fee of this chain, paid in work
+	// rather than balance. 0 requires none.
+	MinStampBits int `json:"minStampBits,omitempty"`
+}
+
+// Set changes the parameter called name to value, given in decimal.
+func (p *Params) Set(name, value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return fmt.Errorf("parameter %s: %q is not a non-negative integer", name, value)
+	}
+	switch name {
+	case ParamMaxBlockBytes:
+		p.MaxBlockBytes = n
+	case ParamMaxBlockTransactions:
+		p.MaxBlockTransactions = n
+	case ParamMinStampBits:
+		if n > MaxStampBits {
+			return fmt.Errorf("parameter %s: %d exceeds %d", name, n, MaxStampBits)
+		}
+		p.MinStampBits = n
+	default:
+		return fmt.Errorf("unknown protocol parameter %q", name)
+	}
+	return nil
+}
+
+// ValidateParamChange checks that name is a protocol parameter and value a
+// valid setting of it.
+func ValidateParamChange(name, value string) error {
+	var p Params
+	return p.Set(name, value)
+}
+
+// fit returns the longest prefix of txs that a block may hold under p.
+func (p Params) fit(txs []*Transaction) []*Transaction {
+	if p.MaxBlockTransactions > 0 && len(txs) > p.MaxBlockTransactions {
+		txs = txs[:p.MaxBlockTransactions]
+	}
+	if p.MaxBlockBytes > 0 {
+		size := 0
+		for i, tx := range txs {
+			if size += transactionBytes(tx); size > p.MaxBlockBytes {
+				return txs[:i]
+			}
+		}
+	}
+	return txs
+}
+
+// check checks the transactions of a block against p.
+func (p Params) check(txs []*Transaction) error {
+	if p.MaxBlockTransactions > 0 && len(txs) > p.MaxBlockTransactions {
+		return fmt.Errorf("%w: %d transactions, limit %d", ErrParamViolation, len(txs), p.MaxBlockTransactions)
+	}
+	size := 0
+	for i, tx := range txs {
+		if err := tx.VerifyStamp(p.MinStampBits); err != nil {
+			return reject(i, tx, fmt.Errorf("%w: transaction %s: %v", ErrParamViolation, tx.ID, err))
+		}
+		size += transactionBytes(tx)
+	}
+	if p.MaxBlockBytes > 0 && size > p.MaxBlockBytes {
+		return fmt.Errorf("%w: transactions take %d bytes, limit %d", ErrParamViolation, size, p.MaxBlockBytes)
+	}
+	return nil
+}
+
+// transactionBytes returns the size of tx encoded as JSON, the measure of the
+// block size limits.
+func transactionBytes(tx *Transaction) int {
+	encoded, err := json.Marshal(tx)
+	if err != nil {
+		return 0
+	}
+	return len(encoded)
+}
+
+// ParamChange sets one protocol parameter.
+type ParamChange struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ParamActivation changes protocol parameters from a block height on.
+type ParamActivation struct {
+	Height  int64         `json:"height"` // First block validated under the changes
+	Changes []ParamChange `json:"changes"`
+	Source  string        `json:"source,omitempty"` // Where the activation was decided, e.g. a governance proposal ID
+}
+
+// ParamSchedule is every scheduled change of the protocol parameters, from
+// node configuration (see LoadParamSchedule) or passed governance proposals.
+// Activations apply in height order, and those at the same height in the
+// order listed.
+type ParamSchedule struct {
+	Activations []ParamActivation `json:"activations"`
+}
+
+// ParseParamSchedule decodes a ParamSchedule from JSON. Unknown fields are
+// rejected, so a misspelt field is an error rather than silently ignored.
+func ParseParamSchedule(data []byte) (*ParamSchedule, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var s ParamSchedule
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("failed to parse parameter schedule: %w", err)
+	}
+	return &s, nil
+}
+
+// LoadParamSchedule reads and decodes the ParamSchedule at path.
+func LoadParamSchedule(path string) (*ParamSchedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parameter schedule %s: %w", path, err)
+	}
+	return ParseParamSchedule(data)
+}
+
+// paramEpoch is the parameters in force from a height on.
+type paramEpoch struct {
+	height int64
+	params Params
+}
+
+// compile returns the parameters in force from each activation height on,
+// in height order, checking every change.
+func (s ParamSchedule) compile() ([]paramEpoch, error) {
+	activations := append([]ParamActivation{}, s.Activations...)
+	sort.SliceStable(activations, func(i, j int) bool { return activations[i].Height < activations[j].Height })
+	var epochs []paramEpoch
+	var params Params
+	for _, a := range activations {
+		if a.Height <= 0 {
+			return nil, fmt.Errorf("invalid parameter schedule: activation at height %d; the genesis block has no parameters", a.Height)
+		}
+		for _, c := range a.Changes {
+			if err := params.Set(c.Name, c.Value); err != nil {
+				return nil, fmt.Errorf("invalid parameter schedule: activation at height %d: %w", a.Height, err)
+			}
+		}
+		if n := len(epochs); n > 0 && epochs[n-1].height == a.Height {
+			epochs[n-1].params = params
+		} else {
+			epochs = append(epochs, paramEpoch{height: a.Height, params: params})
+		}
+	}
+	return epochs, nil
+}
+
+// Validate checks that every activation is above the genesis block and
+// every change a valid setting of a protocol parameter.
+func (s ParamSchedule) Validate() error {
+	_, err := s.compile()
+	return err
+}
+
+// paramsAt returns the parameters in force at height under epochs.
+func paramsAt(epochs []paramEpoch, height int64) Params {
+	i := sort.Search(len(epochs), func(i int) bool { return epochs[i].height > height })
+	if i == 0 {
+		return Params{}
+	}
+	return epochs[i-1].params
+}
+
+// SetParamSchedule sets the schedule of protocol parameter changes blocks are
+// validated under. Like the genesis allocations, the schedule is a parameter
+// of the network that every node must agree on, and is not stored with the
+// chain. Activations may be added for heights above the tip as they are
+// decided; the chain's blocks are checked against the new schedule, and if
+// any breaks it the previous schedule is kept.
+func (bc *Blockchain) SetParamSchedule(schedule ParamSchedule) error {
+	epochs, err := schedule.compile()
+	if err != nil {
+		return err
+	}
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	for _, block := range bc.Blocks {
+		if block.Index == 0 {
+			continue
+		}
+		if err := paramsAt(epochs, block.Index).check(block.Transactions); err != nil {
+			return fmt.Errorf("block %d breaks the parameter schedule: %w", block.Index, err)
+		}
+	}
+	bc.params = epochs
+	return nil
+}
+
+// fitNextBlock returns the longest prefix of txs that the block after the tip
+// may hold under the parameters at its height.
+func (bc *Blockchain) fitNextBlock(txs []*Transaction) []*Transaction {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	return paramsAt(bc.params, bc.Blocks[len(bc.Blocks)-1].Index+1).fit(txs)
+}
+
+// ParamsAt returns the protocol parameters a block at height is validated
+// under.
+func (bc *Blockchain) ParamsAt(height int64) Params {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	return paramsAt(bc.params, height)
+}
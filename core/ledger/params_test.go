@@ -0,0 +1,94 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestParamSchedule_Parse(t *testing.T) {
+	s, err := ParseParamSchedule([]byte(`{"activations": [
+		{"height": 10, "changes": [{"name": "minStampBits", "value": "8"}]},
+		{"height": 5, "changes": [{"name": "maxBlockTransactions", "value": "100"}], "source": "config"}
+	]}`))
+	if err != nil {
+		t.Fatalf("ParseParamSchedule() error = %v", err)
+	}
+	epochs, err := s.compile()
+	if err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+	for height, want := range map[int64]Params{
+		4:  {},
+		5:  {MaxBlockTransactions: 100},
+		12: {MaxBlockTransactions: 100, MinStampBits: 8},
+	} {
+		if got := paramsAt(epochs, height); got != want {
+			t.Errorf("params at %d = %+v, want %+v", height, got, want)
+		}
+	}
+	for name, data := range map[string]string{
+		"unknown field":     `{"activations": [{"height": 5, "chnages": []}]}`,
+		"unknown parameter": `{"activations": [{"height": 5, "changes": [{"name": "blockReward", "value": "1"}]}]}`,
+		"bad value":         `{"activations": [{"height": 5, "changes": [{"name": "maxBlockBytes", "value": "-1"}]}]}`,
+		"genesis":           `{"activations": [{"height": 0, "changes": []}]}`,
+	} {
+		if s, err := ParseParamSchedule([]byte(data)); err == nil && s.Validate() == nil {
+			t.Errorf("%s: schedule accepted", name)
+		}
+	}
+}
+
+func TestBlockchain_ParamsActivateByHeight(t *testing.T) {
+	bc, mempool, p, _ := producerTestSetup(t, ProducerOptions{})
+	priv, addr := newTestKey(t)
+	if _, err := bc.AddBlock([]*Transaction{newSignedTestTx(t, priv, addr, "one"), newSignedTestTx(t, priv, addr, "two")}); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+	schedule := ParamSchedule{Activations: []ParamActivation{
+		{Height: 2, Changes: []ParamChange{{ParamMaxBlockTransactions, "1"}}},
+		{Height: 3, Changes: []ParamChange{{ParamMinStampBits, "4"}}},
+	}}
+	if err := bc.SetParamSchedule(schedule); err != nil {
+		t.Fatalf("SetParamSchedule() error = %v", err)
+	}
+
+	// Block 1 stays valid under the schedule; block 2 may hold one transaction.
+	if _, err := bc.AddBlock([]*Transaction{newSignedTestTx(t, priv, addr, "three"), newSignedTestTx(t, priv, addr, "four")}); !errors.Is(err, ErrParamViolation) {
+		t.Errorf("AddBlock() of two transactions at height 2 error = %v, want ErrParamViolation", err)
+	}
+	for _, payload := range []string{"three", "four"} {
+		if err := mempool.Add(newSignedTestTx(t, priv, addr, payload)); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	block, err := p.Produce(context.Background())
+	if err != nil || len(block.Transactions) != 1 || mempool.Size() != 1 || p.Stats().Dropped != 0 {
+		t.Fatalf("Produce() = %v, %v with %d left; want one transaction and the other kept", block, err, mempool.Size())
+	}
+
+	// From height 3 every transaction must carry a stamp.
+	unstamped := newSignedTestTx(t, priv, addr, "five")
+	for unstamped.VerifyStamp(4) == nil {
+		// One ID in 16 meets 4 bits without a stamp; draw another.
+		unstamped = newSignedTestTx(t, priv, addr, "five")
+	}
+	_, err = bc.AddBlock([]*Transaction{unstamped})
+	if r, ok := AsRejection(err); !ok || r.TxID != unstamped.ID || !errors.Is(err, ErrParamViolation) {
+		t.Errorf("AddBlock() of an unstamped transaction at height 3 error = %v, want a Rejection for ErrParamViolation", err)
+	}
+	if err := unstamped.MintStamp(context.Background(), 4); err != nil {
+		t.Fatalf("MintStamp() error = %v", err)
+	}
+	if _, err := bc.AddBlock([]*Transaction{unstamped}); err != nil {
+		t.Errorf("AddBlock() of a stamped transaction error = %v", err)
+	}
+
+	// A schedule the chain's blocks break is refused.
+	if err := bc.SetParamSchedule(ParamSchedule{Activations: []ParamActivation{{Height: 1, Changes: []ParamChange{{ParamMaxBlockTransactions, "1"}}}}}); !errors.Is(err, ErrParamViolation) {
+		t.Errorf("SetParamSchedule() of a schedule block 1 breaks error = %v, want ErrParamViolation", err)
+	}
+	if got := bc.ParamsAt(3); got != (Params{MaxBlockTransactions: 1, MinStampBits: 4}) {
+		t.Errorf("ParamsAt(3) = %+v after a refused schedule, want the previous one's", got)
+	}
+}
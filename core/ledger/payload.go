@@ -0,0 +1,114 @@
+package ledger
+
+import (
+	"digisocialblock/core/errcode"
+	"fmt"
+	"sync"
+)
+
+// ErrPayloadTooLarge is returned when a transaction payload exceeds the
+// maximum size for its type.
+var ErrPayloadTooLarge = errcode.New(CodePayloadTooLarge, "transaction payload too large")
+
+// ErrInvalidPayload is returned when a transaction payload does not match the
+// schema registered for its type.
+var ErrInvalidPayload = errcode.New(CodeInvalidPayload, "transaction payload does not match its schema")
+
+// DefaultMaxPayloadSize applies to transaction types without an explicit limit.
+const DefaultMaxPayloadSize = 4 << 10 // 4 KiB
+
+// defaultMaxPayloadSizes bounds the payload of each known transaction type.
+// Payloads carry metadata only; content itself lives on DDS and is referenced by CID.
+var defaultMaxPayloadSizes = map[TransactionType]int{
+	PostCreated:      8 << 10,
+	CommentAdded:     8 << 10,
+	CommentEdited:    8 << 10,
+	CommentDeleted:   1 << 10,
+	Like:             1 << 10,
+	UserFollowed:     1 << 10,
+	ProfileUpdate:    16 << 10,
+	EngagementReport: 16 << 10,
+	PostBookmarked:   1 << 10,
+	ListCreated:      2 << 10,
+	ListMemberAdded:  1 << 10,
+	Transfer:         1 << 10,
+	KeyDelegated:     1 << 10,
+	StorageAttested:  8 << 10,
+	InviteIssued:     1 << 10,
+	ProposalCreated:  8 << 10,
+	VoteCast:         1 << 10,
+}
+
+// PayloadValidator checks that a payload matches the schema of a transaction type.
+type PayloadValidator func(payload []byte) error
+
+// payloadRules holds the per-type limits and schema validators applied by
+// Transaction.IsValid, and therefore at mempool admission and block validation.
+var payloadRules = struct {
+	mu         sync.RWMutex
+	maxSizes   map[TransactionType]int
+	validators map[TransactionType]PayloadValidator
+}{
+	maxSizes:   copyPayloadSizes(defaultMaxPayloadSizes),
+	validators: make(map[TransactionType]PayloadValidator),
+}
+
+func copyPayloadSizes(sizes map[TransactionType]int) map[TransactionType]int {
+	out := make(map[TransactionType]int, len(sizes))
+	for txType, size := range sizes {
+		out[txType] = size
+	}
+	return out
+}
+
+// RegisterPayloadValidator installs the schema validator for txType, replacing
+// any previous one. Packages that define payload formats (e.g. social for
+// PostCreated) register their validators from init, so the ledger enforces
+// schemas it cannot import itself.
+func RegisterPayloadValidator(txType TransactionType, validator PayloadValidator) {
+	payloadRules.mu.Lock()
+	defer payloadRules.mu.Unlock()
+	if validator == nil {
+		delete(payloadRules.validators, txType)
+		return
+	}
+	payloadRules.validators[txType] = validator
+}
+
+// SetMaxPayloadSize overrides the payload size limit for txType.
+func SetMaxPayloadSize(txType TransactionType, maxSize int) error {
+	if maxSize <= 0 {
+		return fmt.Errorf("max payload size must be positive, got %d", maxSize)
+	}
+	payloadRules.mu.Lock()
+	defer payloadRules.mu.Unlock()
+	payloadRules.maxSizes[txType] = maxSize
+	return nil
+}
+
+// MaxPayloadSize returns the payload size limit for txType.
+func MaxPayloadSize(txType TransactionType) int {
+	payloadRules.mu.RLock()
+	defer payloadRules.mu.RUnlock()
+	if size, ok := payloadRules.maxSizes[txType]; ok {
+		return size
+	}
+	return DefaultMaxPayloadSize
+}
+
+// ValidatePayload enforces the size limit and registered schema for the
+// transaction's type.
+func (tx *Transaction) ValidatePayload() error {
+	if limit := MaxPayloadSize(tx.Type); len(tx.Payload) > limit {
+		return fmt.Errorf("%w: %s payload is %d bytes, limit %d", ErrPayloadTooLarge, tx.Type, len(tx.Payload), limit)
+	}
+	payloadRules.mu.RLock()
+	validator := payloadRules.validators[tx.Type]
+	payloadRules.mu.RUnlock()
+	if validator != nil {
+		if err := validator(tx.Payload); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidPayload, tx.Type, err)
+		}
+	}
+	return nil
+}
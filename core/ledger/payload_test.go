@@ -0,0 +1,70 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTransaction_ValidatePayload_SizeLimits(t *testing.T) {
+	like, _ := NewTransaction("sender", Like, []byte(strings.Repeat("l", MaxPayloadSize(Like)+1)))
+	if err := like.IsValid(); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("IsValid() on oversized Like error = %v, want ErrPayloadTooLarge", err)
+	}
+	custom, _ := NewTransaction("sender", TransactionType("SizeTest"), []byte(strings.Repeat("c", DefaultMaxPayloadSize)))
+	if err := custom.IsValid(); err != nil {
+		t.Errorf("IsValid() at the default limit error = %v", err)
+	}
+
+	previous := MaxPayloadSize("SizeTest")
+	t.Cleanup(func() { SetMaxPayloadSize("SizeTest", previous) })
+	if err := SetMaxPayloadSize("SizeTest", 16); err != nil {
+		t.Fatalf("SetMaxPayloadSize() error = %v", err)
+	}
+	if err := custom.IsValid(); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("IsValid() after lowering the limit error = %v, want ErrPayloadTooLarge", err)
+	}
+	if err := SetMaxPayloadSize("SizeTest", 0); err == nil {
+		t.Error("SetMaxPayloadSize(0): expected error, got nil")
+	}
+}
+
+func TestTransaction_ValidatePayload_SchemaEnforcedAtAdmissionAndBlock(t *testing.T) {
+	const schemaType = TransactionType("SchemaTest")
+	RegisterPayloadValidator(schemaType, func(payload []byte) error {
+		var v struct {
+			Name string `json:"name"`
+		}
+		if err := DecodePayloadJSON(payload, &v); err != nil {
+			return err
+		}
+		if v.Name == "" {
+			return fmt.Errorf("name is required")
+		}
+		return nil
+	})
+	defer RegisterPayloadValidator(schemaType, nil)
+
+	priv, addr := newTestKey(t)
+	newTx := func(payload string) *Transaction {
+		tx, _ := NewTransaction(addr, schemaType, []byte(payload))
+		tx.Sign(priv)
+		return tx
+	}
+
+	mempool := NewMempool(nil)
+	bc, _ := NewBlockchain()
+	for _, payload := range []string{`{"name":"x","extra":1}`, `{"name":""}`, `{"name":"x"} {}`, `not json`} {
+		tx := newTx(payload)
+		if err := mempool.Add(tx); !errors.Is(err, ErrInvalidPayload) {
+			t.Errorf("Mempool.Add(%s) error = %v, want ErrInvalidPayload", payload, err)
+		}
+		if _, err := bc.AddBlock([]*Transaction{tx}); !errors.Is(err, ErrInvalidPayload) {
+			t.Errorf("AddBlock(%s) error = %v, want ErrInvalidPayload", payload, err)
+		}
+	}
+	if err := mempool.Add(newTx(`{"name":"valid"}`)); err != nil {
+		t.Errorf("Mempool.Add() with a valid payload error = %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package ledger
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Defaults for zero ProducerOptions fields.
+const (
+	DefaultBlockInterval        = 5 * time.Second
+	DefaultMaxBlockTransactions = 1000
+)
+
+// ProducerOptions configures a Producer. The zero value is valid: a block
+// every DefaultBlockInterval when the mempool holds anything, and no empty
+// blocks.
+type ProducerOptions struct {
+	Interval        time.Duration // Target time between blocks; defaults to DefaultBlockInterval
+	MaxTransactions int           // Per block, taken in arrival order; defaults to DefaultMaxBlockTransactions
+	// Heartbeat, if positive, is the longest the chain goes without a block:
+	// once the tip is this old by the chain's clock, an empty block is
+	// produced so followers can tell the producer is alive. Zero never
+	// produces empty blocks.
+	Heartbeat time.Duration
+}
+
+// ProducerStats counts what a Producer has done.
+type ProducerStats struct {
+	Blocks       int64  // Produced, including heartbeats
+	Heartbeats   int64  // Empty blocks produced
+	Transactions int64  // Included in produced blocks
+	Skipped      int64  // Rounds with nothing to produce
+	Dropped      int64  // Transactions evicted from the mempool because no block could include them
+	LastBlock    int64  // Index of the latest block produced; -1 if none
+	LastError    string // Of the latest failed round; cleared by a successful one
+}
+
+// Producer cuts blocks from a mempool onto a chain on a schedule, in place of
+// calling AddBlock by hand. Each round it takes up to MaxTransactions pending
+// transactions; if the mempool is empty it produces nothing unless a
+// heartbeat is due. A transaction that the chain refuses, such as a transfer
+// the sender can no longer cover, is evicted from the mempool so that it does
+// not hold up the rest. The Producer should be the only writer of blocks to
+// the chain. A Producer is safe for concurrent use; rounds run one at a time.
+type Producer struct {
+	bc      *Blockchain
+	mempool *Mempool
+	opts    ProducerOptions
+
+	mu    sync.Mutex // Serializes rounds
+	smu   sync.Mutex // Guards stats
+	stats ProducerStats
+}
+
+// NewProducer creates a Producer that moves transactions from mempool to bc.
+func NewProducer(bc *Blockchain, mempool *Mempool, opts ProducerOptions) (*Producer, error) {
+	if bc == nil || mempool == nil {
+		return nil, fmt.Errorf("blockchain and mempool are both required")
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultBlockInterval
+	}
+	if opts.MaxTransactions <= 0 {
+		opts.MaxTransactions = DefaultMaxBlockTransactions
+	}
+	if opts.Heartbeat < 0 {
+		opts.Heartbeat = 0
+	}
+	return &Producer{bc: bc, mempool: mempool, opts: opts, stats: ProducerStats{LastBlock: -1}}, nil
+}
+
+// Run runs a round every Interval until ctx is done.
+func (p *Producer) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := p.Produce(ctx); err != nil {
+				log.Printf("Producer: round failed: %v\n", err)
+			}
+		}
+	}
+}
+
+// Produce runs one round and returns the block produced, or nil if there was
+// nothing to produce.
+func (p *Producer) Produce(ctx context.Context) (*Block, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pending := p.mempool.Pending()
+	if len(pending) > p.opts.MaxTransactions {
+		pending = pending[:p.opts.MaxTransactions]
+	}
+	pending = p.bc.fitNextBlock(pending)
+	if len(pending) > 0 {
+		if err := p.bc.checkCandidate(pending); err != nil {
+			var dropped []*Transaction
+			pending, dropped = p.bc.admissible(pending)
+			p.evict(dropped)
+		}
+	}
+	if len(pending) == 0 && !p.heartbeatDue() {
+		p.record(func(s *ProducerStats) { s.Skipped++ })
+		return nil, nil
+	}
+
+	block, err := p.bc.AddBlockContext(ctx, pending)
+	if err != nil {
+		p.record(func(s *ProducerStats) { s.LastError = err.Error() })
+		return nil, fmt.Errorf("failed to produce block: %w", err)
+	}
+	ids := make([]string, len(pending))
+	for i, tx := range pending {
+		ids[i] = tx.ID
+	}
+	p.mempool.Remove(ids...)
+	p.record(func(s *ProducerStats) {
+		s.Blocks++
+		if len(pending) == 0 {
+			s.Heartbeats++
+		}
+		s.Transactions += int64(len(pending))
+		s.LastBlock = block.Index
+		s.LastError = ""
+	})
+	return block, nil
+}
+
+// heartbeatDue reports whether the tip is old enough for an empty block.
+func (p *Producer) heartbeatDue() bool {
+	if p.opts.Heartbeat == 0 {
+		return false
+	}
+	p.bc.mu.Lock()
+	now := p.bc.clock.Now()
+	tip := p.bc.Blocks[len(p.bc.Blocks)-1]
+	p.bc.mu.Unlock()
+	return now.Sub(time.Unix(0, tip.Timestamp)) >= p.opts.Heartbeat
+}
+
+// evict removes transactions no block can include from the mempool.
+func (p *Producer) evict(dropped []*Transaction) {
+	if len(dropped) == 0 {
+		return
+	}
+	ids := make([]string, len(dropped))
+	for i, tx := range dropped {
+		ids[i] = tx.ID
+		log.Printf("Producer: evicting transaction %s: refused by the chain\n", tx.ID)
+	}
+	p.mempool.Remove(ids...)
+	p.record(func(s *ProducerStats) { s.Dropped += int64(len(dropped)) })
+}
+
+func (p *Producer) record(update func(s *ProducerStats)) {
+	p.smu.Lock()
+	defer p.smu.Unlock()
+	update(&p.stats)
+}
+
+// Stats returns a snapshot of the producer's counters.
+func (p *Producer) Stats() ProducerStats {
+	p.smu.Lock()
+	defer p.smu.Unlock()
+	return p.stats
+}
+
+// Var returns the producer's stats as an expvar.Var, for publishing with
+// expvar.Publish.
+func (p *Producer) Var() expvar.Var {
+	return expvar.Func(func() interface{} { return p.Stats() })
+}
+
+// checkCandidate reports whether AddBlock would accept a block of txs now,
+// without adding it.
+func (bc *Blockchain) checkCandidate(txs []*Transaction) error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	return bc.checkCandidateLocked(txs)
+}
+
+// checkCandidateLocked is checkCandidate for callers holding bc.mu.
+func (bc *Blockchain) checkCandidateLocked(txs []*Transaction) error {
+	if err := bc.validateNewTransactions(context.Background(), txs); err != nil {
+		return err
+	}
+	height := bc.Blocks[len(bc.Blocks)-1].Index + 1
+	if err := paramsAt(bc.params, height).check(txs); err != nil {
+		return err
+	}
+	if _, err := bc.checkTransfersLocked(txs); err != nil {
+		return err
+	}
+	now := bc.clock.Now()
+	if _, err := bc.checkDelegationsLocked(txs, now.UnixNano()); err != nil {
+		return err
+	}
+	if _, err := bc.checkInvitesLocked(txs, now.UnixNano()); err != nil {
+		return err
+	}
+	return checkRule(bc.rule, txs, RuleEnv{Height: height, Time: now})
+}
+
+// admissible splits txs into those a block could include, keeping each in
+// order if it is consistent with the ones kept before it, and the rest.
+func (bc *Blockchain) admissible(txs []*Transaction) (ok, refused []*Transaction) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	for _, tx := range txs {
+		if bc.checkCandidateLocked(append(ok[:len(ok):len(ok)], tx)) != nil {
+			refused = append(refused, tx)
+			continue
+		}
+		ok = append(ok, tx)
+	}
+	return ok, refused
+}
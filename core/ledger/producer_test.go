@@ -0,0 +1,106 @@
+package ledger
+
+import (
+	"context"
+	"digisocialblock/internal/testutil"
+	"testing"
+	"time"
+)
+
+// producerTestSetup returns a chain on a fake clock, its mempool and a
+// Producer for them.
+func producerTestSetup(t *testing.T, opts ProducerOptions) (*Blockchain, *Mempool, *Producer, *testutil.Clock) {
+	t.Helper()
+	bc, err := NewBlockchain()
+	if err != nil {
+		t.Fatalf("NewBlockchain() error = %v", err)
+	}
+	clock := testutil.NewClock(time.Millisecond)
+	bc.SetClock(clock)
+	mempool := NewMempool(bc.SignatureCache())
+	p, err := NewProducer(bc, mempool, opts)
+	if err != nil {
+		t.Fatalf("NewProducer() error = %v", err)
+	}
+	return bc, mempool, p, clock
+}
+
+func TestProducer_CutsBlocksFromTheMempoolAndSkipsEmptyOnes(t *testing.T) {
+	bc, mempool, p, _ := producerTestSetup(t, ProducerOptions{MaxTransactions: 2})
+	ctx := context.Background()
+
+	if block, err := p.Produce(ctx); block != nil || err != nil {
+		t.Fatalf("Produce() with an empty mempool = %v, %v; want nothing", block, err)
+	}
+	priv, addr := newTestKey(t)
+	for _, payload := range []string{"one", "two", "three"} {
+		if err := mempool.Add(newSignedTestTx(t, priv, addr, payload)); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	block, err := p.Produce(ctx)
+	if err != nil || block == nil || len(block.Transactions) != 2 || mempool.Size() != 1 {
+		t.Fatalf("Produce() = %v, %v with %d left pending; want a block of 2 and 1 left", block, err, mempool.Size())
+	}
+	if block, err := p.Produce(ctx); err != nil || block == nil || len(block.Transactions) != 1 || mempool.Size() != 0 {
+		t.Fatalf("second Produce() = %v, %v; want the last transaction", block, err)
+	}
+	if block, _ := p.Produce(ctx); block != nil {
+		t.Errorf("Produce() once drained = block %d, want nothing", block.Index)
+	}
+	want := ProducerStats{Blocks: 2, Transactions: 3, Skipped: 2, LastBlock: 2}
+	if got := p.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+	if got := bc.GetLatestBlock().Index; got != 2 {
+		t.Errorf("chain height = %d, want 2", got)
+	}
+}
+
+func TestProducer_HeartbeatProducesEmptyBlocksWhenIdle(t *testing.T) {
+	_, _, p, clock := producerTestSetup(t, ProducerOptions{Heartbeat: time.Minute})
+	ctx := context.Background()
+
+	if block, _ := p.Produce(ctx); block != nil {
+		t.Fatalf("Produce() before the heartbeat is due = block %d, want nothing", block.Index)
+	}
+	clock.Advance(time.Minute)
+	block, err := p.Produce(ctx)
+	if err != nil || block == nil || len(block.Transactions) != 0 {
+		t.Fatalf("Produce() once the heartbeat is due = %v, %v; want an empty block", block, err)
+	}
+	if block, _ := p.Produce(ctx); block != nil {
+		t.Errorf("Produce() right after a heartbeat = block %d, want nothing", block.Index)
+	}
+	if s := p.Stats(); s.Blocks != 1 || s.Heartbeats != 1 {
+		t.Errorf("Stats() = %+v, want one heartbeat", s)
+	}
+}
+
+func TestProducer_EvictsTransactionsTheChainRefuses(t *testing.T) {
+	bc, mempool, p, _ := producerTestSetup(t, ProducerOptions{})
+	alicePriv, alice := newTestKey(t)
+	_, bob := newTestKey(t)
+	if err := bc.SetGenesisAllocations(map[string]uint64{alice: 10}); err != nil {
+		t.Fatalf("SetGenesisAllocations() error = %v", err)
+	}
+	spend := transferTestTx(t, alicePriv, alice, bob, 8)
+	overdraft := transferTestTx(t, alicePriv, alice, bob, 5)
+	post := newSignedTestTx(t, alicePriv, alice, "still included")
+	for _, tx := range []*Transaction{spend, overdraft, post} {
+		if err := mempool.Add(tx); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	block, err := p.Produce(context.Background())
+	if err != nil || block == nil {
+		t.Fatalf("Produce() = %v, %v; want a block", block, err)
+	}
+	if len(block.Transactions) != 2 || block.Transactions[0].ID != spend.ID || block.Transactions[1].ID != post.ID {
+		t.Errorf("block holds %d transactions, want the spend and the post", len(block.Transactions))
+	}
+	if mempool.Size() != 0 || p.Stats().Dropped != 1 {
+		t.Errorf("mempool holds %d, %d dropped; want the overdraft evicted", mempool.Size(), p.Stats().Dropped)
+	}
+}
@@ -0,0 +1,160 @@
+package ledger
+
+import (
+	"fmt"
+)
+
+// BlockHeader is a block without its transactions: enough to check that a
+// chain of blocks links up and, with a MerkleProof, that a transaction is in
+// one of them. Light clients sync headers instead of blocks.
+type BlockHeader struct {
+	Index         int64  `json:"index"`
+	Timestamp     int64  `json:"timestamp"`
+	PrevBlockHash string `json:"prevBlockHash"`
+	MerkleRoot    string `json:"merkleRoot"` // MerkleRoot of the block's transaction IDs
+	Hash          string `json:"hash"`
+}
+
+// Header returns the block's header.
+func (b *Block) Header() BlockHeader {
+	var txHashes []string
+	if len(b.Transactions) > 0 {
+		txHashes = GetTransactionHashes(b.Transactions)
+	}
+	return BlockHeader{
+		Index:         b.Index,
+		Timestamp:     b.Timestamp,
+		PrevBlockHash: b.PrevBlockHash,
+		MerkleRoot:    MerkleRoot(txHashes),
+		Hash:          b.Hash,
+	}
+}
+
+// Verify checks that the header's hash covers its other fields.
+func (h *BlockHeader) Verify() error {
+	expected := HashBlockContent(h.Index, h.Timestamp, h.PrevBlockHash, h.MerkleRoot)
+	if !hashesEqual(h.Hash, expected) {
+		return fmt.Errorf("invalid block hash: expected %s, got %s", expected, h.Hash)
+	}
+	return nil
+}
+
+// VerifyLink checks that the header follows prev, as Block.IsValid does for
+// whole blocks.
+func (h *BlockHeader) VerifyLink(prev *BlockHeader) error {
+	if h.Index != prev.Index+1 {
+		return fmt.Errorf("invalid block index: expected %d, got %d", prev.Index+1, h.Index)
+	}
+	if !hashesEqual(h.PrevBlockHash, prev.Hash) {
+		return fmt.Errorf("invalid previous block hash: expected %s, got %s", prev.Hash, h.PrevBlockHash)
+	}
+	if h.Timestamp <= prev.Timestamp && prev.Index > 0 {
+		return fmt.Errorf("invalid block timestamp: block %d timestamp %d before or same as prev block %d timestamp %d", h.Index, h.Timestamp, prev.Index, prev.Timestamp)
+	}
+	return nil
+}
+
+// MerkleStep is one level of a MerkleProof: the hash to combine with, and
+// whether it goes on the left.
+type MerkleStep struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left,omitempty"`
+}
+
+// MerkleProof shows that the transaction TxID is in block BlockIndex: hashing
+// TxID up through Path gives the block's MerkleRoot. Levels where MerkleRoot
+// carries an odd last hash up unchanged have no step.
+type MerkleProof struct {
+	TxID       string       `json:"txId"`
+	BlockIndex int64        `json:"blockIndex"`
+	Path       []MerkleStep `json:"path"`
+}
+
+// Root returns the Merkle root the proof leads to.
+func (p *MerkleProof) Root() string {
+	hash := p.TxID
+	for _, step := range p.Path {
+		if step.Left {
+			hash = CalculateSHA256Hash([]byte(step.Hash + hash))
+		} else {
+			hash = CalculateSHA256Hash([]byte(hash + step.Hash))
+		}
+	}
+	return hash
+}
+
+// Verify checks that the proof leads to header's Merkle root.
+func (p *MerkleProof) Verify(header *BlockHeader) error {
+	if p.BlockIndex != header.Index {
+		return fmt.Errorf("proof is for block %d, not %d", p.BlockIndex, header.Index)
+	}
+	if root := p.Root(); !hashesEqual(root, header.MerkleRoot) {
+		return fmt.Errorf("proof of %s leads to Merkle root %s, not block %d's %s", p.TxID, root, header.Index, header.MerkleRoot)
+	}
+	return nil
+}
+
+// ProveInclusion returns the proof that the transaction at position i of
+// block is in it.
+func ProveInclusion(block *Block, i int) (*MerkleProof, error) {
+	if i < 0 || i >= len(block.Transactions) {
+		return nil, fmt.Errorf("block %d has no transaction %d", block.Index, i)
+	}
+	level := GetTransactionHashes(block.Transactions)
+	proof := &MerkleProof{TxID: level[i], BlockIndex: block.Index}
+	for len(level) > 1 {
+		switch {
+		case i%2 == 1:
+			proof.Path = append(proof.Path, MerkleStep{Hash: level[i-1], Left: true})
+		case i+1 < len(level):
+			proof.Path = append(proof.Path, MerkleStep{Hash: level[i+1]})
+		}
+		next := make([]string, 0, (len(level)+1)/2)
+		for j := 0; j < len(level); j += 2 {
+			if j+1 < len(level) {
+				next = append(next, CalculateSHA256Hash([]byte(level[j]+level[j+1])))
+			} else {
+				next = append(next, level[j])
+			}
+		}
+		level, i = next, i/2
+	}
+	return proof, nil
+}
+
+// Headers returns the headers of up to limit blocks starting at index from.
+func (bc *Blockchain) Headers(from int64, limit int) []BlockHeader {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if from < 0 || from >= int64(len(bc.Blocks)) || limit <= 0 {
+		return nil
+	}
+	end := from + int64(limit)
+	if end > int64(len(bc.Blocks)) {
+		end = int64(len(bc.Blocks))
+	}
+	headers := make([]BlockHeader, 0, end-from)
+	for _, block := range bc.Blocks[from:end] {
+		headers = append(headers, block.Header())
+	}
+	return headers
+}
+
+// ProveTransaction returns the transaction with txID and the proof that it is
+// in its block. ok is false if no block holds it.
+func (bc *Blockchain) ProveTransaction(txID string) (tx *Transaction, proof *MerkleProof, ok bool) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	for _, block := range bc.Blocks {
+		for i, candidate := range block.Transactions {
+			if candidate.ID == txID {
+				proof, err := ProveInclusion(block, i)
+				if err != nil {
+					return nil, nil, false
+				}
+				return candidate, proof, true
+			}
+		}
+	}
+	return nil, nil, false
+}
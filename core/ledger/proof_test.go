@@ -0,0 +1,68 @@
+package ledger
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestProveInclusion_EveryPositionAndSize(t *testing.T) {
+	priv, addr := newTestKey(t)
+	for n := 1; n <= 7; n++ {
+		var txs []*Transaction
+		for i := 0; i < n; i++ {
+			txs = append(txs, newSignedTestTx(t, priv, addr, fmt.Sprintf("tx %d of %d", i, n)))
+		}
+		block, _ := NewBlock(3, "prev", txs)
+		header := block.Header()
+		if err := header.Verify(); err != nil {
+			t.Fatalf("%d txs: Header().Verify() error = %v", n, err)
+		}
+		for i := range txs {
+			proof, err := ProveInclusion(block, i)
+			if err != nil {
+				t.Fatalf("ProveInclusion(%d of %d) error = %v", i, n, err)
+			}
+			if err := proof.Verify(&header); err != nil {
+				t.Errorf("proof of %d of %d: Verify() error = %v", i, n, err)
+			}
+			if n > 1 {
+				proof.TxID = txs[(i+1)%n].ID
+				if err := proof.Verify(&header); err == nil {
+					t.Errorf("proof of %d of %d for another transaction: expected error, got nil", i, n)
+				}
+			}
+		}
+	}
+}
+
+func TestBlockchain_HeadersAndProofs(t *testing.T) {
+	priv, addr := newTestKey(t)
+	bc, _ := NewBlockchain()
+	tx := newSignedTestTx(t, priv, addr, "proved")
+	if _, err := bc.AddBlock([]*Transaction{newSignedTestTx(t, priv, addr, "other"), tx}); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+	headers := bc.Headers(0, 10)
+	if len(headers) != 2 {
+		t.Fatalf("Headers() returned %d headers, want 2", len(headers))
+	}
+	if err := headers[1].VerifyLink(&headers[0]); err != nil {
+		t.Errorf("VerifyLink() error = %v", err)
+	}
+	tampered := headers[1]
+	tampered.MerkleRoot = headers[0].MerkleRoot
+	if err := tampered.Verify(); err == nil {
+		t.Error("Verify() of a header with another Merkle root: expected error, got nil")
+	}
+
+	got, proof, ok := bc.ProveTransaction(tx.ID)
+	if !ok || got != tx {
+		t.Fatalf("ProveTransaction() = %v, %v; want the transaction", got, ok)
+	}
+	if err := proof.Verify(&headers[1]); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if _, _, ok := bc.ProveTransaction("unknown"); ok {
+		t.Error("ProveTransaction() of an unknown transaction succeeded")
+	}
+}
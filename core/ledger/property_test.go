@@ -0,0 +1,203 @@
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// propertyConfig runs each property enough times to reach every field
+// several times over.
+var propertyConfig = &quick.Config{MaxCount: 300}
+
+// transactionFields names the fields tamperTransaction can change. Stamp is
+// deliberately absent: it is not covered by the signature (see MintStamp) and
+// is checked against a stamp policy by VerifyStamp instead.
+var transactionFields = []string{"ID", "Timestamp", "SenderPublicKey", "Type", "Payload", "Signature", "ChainID", "SigVersion"}
+
+// tamperTransaction changes one field of tx to a different value chosen by r.
+func tamperTransaction(tx *Transaction, field string, r *rand.Rand) {
+	switch field {
+	case "ID":
+		tx.ID = flipHexChar(tx.ID, r)
+	case "Timestamp":
+		tx.Timestamp += 1 + r.Int63n(1e9)
+	case "SenderPublicKey":
+		tx.SenderPublicKey = flipHexChar(tx.SenderPublicKey, r)
+	case "Type":
+		var others []TransactionType
+		for _, txType := range []TransactionType{PostCreated, CommentAdded, Like, UserFollowed, ProfileUpdate} {
+			if txType != tx.Type {
+				others = append(others, txType)
+			}
+		}
+		tx.Type = others[r.Intn(len(others))]
+	case "Payload":
+		tx.Payload = tamperBytes(tx.Payload, r)
+	case "Signature":
+		tx.Signature = tamperBytes(tx.Signature, r)
+	case "ChainID":
+		tx.ChainID += string(rune('a' + r.Intn(26)))
+	case "SigVersion":
+		tx.SigVersion += 1 + r.Intn(3)
+		if r.Intn(2) == 0 {
+			tx.SigVersion = 0
+		}
+	}
+}
+
+// tamperBytes returns a copy of b with one bit flipped, a byte appended or
+// the last byte dropped.
+func tamperBytes(b []byte, r *rand.Rand) []byte {
+	out := bytes.Clone(b)
+	switch r.Intn(3) {
+	case 0:
+		if len(out) > 0 {
+			out[r.Intn(len(out))] ^= 1 << uint(r.Intn(8))
+			return out
+		}
+		fallthrough
+	case 1:
+		return append(out, byte(r.Intn(256)))
+	default:
+		if len(out) == 0 {
+			return []byte{byte(r.Intn(256))}
+		}
+		return out[:len(out)-1]
+	}
+}
+
+// flipHexChar replaces one character of the hex string s with a different
+// hex digit.
+func flipHexChar(s string, r *rand.Rand) string {
+	const digits = "0123456789abcdef"
+	b := []byte(s)
+	i := r.Intn(len(b))
+	shift := 1 + r.Intn(len(digits)-1)
+	b[i] = digits[(strings.IndexByte(digits, b[i])+shift)%len(digits)]
+	return string(b)
+}
+
+func cloneTransaction(tx *Transaction) *Transaction {
+	c := *tx
+	c.Payload = bytes.Clone(tx.Payload)
+	c.Signature = bytes.Clone(tx.Signature)
+	return &c
+}
+
+func TestProperty_TamperedTransactionRejected(t *testing.T) {
+	bc, err := NewBlockchain()
+	if err != nil {
+		t.Fatalf("NewBlockchain() error = %v", err)
+	}
+	priv, addr := newTestKey(t)
+	original := newSignedTestTx(t, priv, addr, `{"contentCID":"cid-1"}`)
+	if err := bc.validateNewTransactions(context.Background(), []*Transaction{original}); err != nil {
+		t.Fatalf("untampered transaction rejected: %v", err)
+	}
+
+	property := func(field uint8, seed int64) bool {
+		name := transactionFields[int(field)%len(transactionFields)]
+		tx := cloneTransaction(original)
+		tamperTransaction(tx, name, rand.New(rand.NewSource(seed)))
+		if err := bc.validateNewTransactions(context.Background(), []*Transaction{tx}); err == nil {
+			t.Logf("transaction with tampered %s accepted: %+v", name, tx)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(property, propertyConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+// blockFields names the block fields tamperBlock can change. Transactions is
+// tampered by changing the list or a field its Merkle root commits to; the
+// block hash commits to transaction IDs only, so a transaction's signature
+// and stamp are checked when the block is added, not by IsChainValid.
+var blockFields = []string{"Index", "Timestamp", "PrevBlockHash", "Hash", "Transactions"}
+
+func tamperBlock(b *Block, field string, r *rand.Rand) {
+	switch field {
+	case "Index":
+		b.Index += 1 + r.Int63n(10)
+	case "Timestamp":
+		b.Timestamp += 1 + r.Int63n(1e9)
+	case "PrevBlockHash":
+		b.PrevBlockHash = flipHexChar(b.PrevBlockHash, r)
+	case "Hash":
+		b.Hash = flipHexChar(b.Hash, r)
+	case "Transactions":
+		txs := b.Transactions
+		switch op := r.Intn(4); {
+		case op == 0 || (op == 1 && len(txs) < 2):
+			b.Transactions = append(txs, cloneTransaction(txs[r.Intn(len(txs))]))
+		case op == 1:
+			i := r.Intn(len(txs) - 1)
+			txs[i], txs[i+1] = txs[i+1], txs[i]
+		case op == 2:
+			i := r.Intn(len(txs))
+			b.Transactions = append(txs[:i:i], txs[i+1:]...)
+		default:
+			contentFields := []string{"ID", "Timestamp", "SenderPublicKey", "Type", "Payload"}
+			tamperTransaction(txs[r.Intn(len(txs))], contentFields[r.Intn(len(contentFields))], r)
+		}
+	}
+}
+
+// cloneChain returns a Blockchain whose blocks and transactions are copies of
+// those in bc, so a property can tamper with it freely.
+func cloneChain(bc *Blockchain) *Blockchain {
+	blocks := make([]*Block, len(bc.Blocks))
+	for i, b := range bc.Blocks {
+		c := *b
+		c.Transactions = make([]*Transaction, len(b.Transactions))
+		for j, tx := range b.Transactions {
+			c.Transactions[j] = cloneTransaction(tx)
+		}
+		blocks[i] = &c
+	}
+	return &Blockchain{Blocks: blocks, chainID: bc.chainID}
+}
+
+func TestProperty_TamperedBlockRejected(t *testing.T) {
+	bc, err := NewBlockchain()
+	if err != nil {
+		t.Fatalf("NewBlockchain() error = %v", err)
+	}
+	priv, addr := newTestKey(t)
+	for i := 0; i < 3; i++ {
+		txs := []*Transaction{
+			newSignedTestTx(t, priv, addr, `{"contentCID":"a"}`),
+			newSignedTestTx(t, priv, addr, `{"contentCID":"b"}`),
+			newSignedTestTx(t, priv, addr, `{"contentCID":"c"}`),
+		}
+		if _, err := bc.AddBlock(txs[:i+1]); err != nil {
+			t.Fatalf("AddBlock() error = %v", err)
+		}
+	}
+	if ok, err := cloneChain(bc).IsChainValid(); !ok {
+		t.Fatalf("untampered chain invalid: %v", err)
+	}
+
+	property := func(block, field uint8, seed int64) bool {
+		chain := cloneChain(bc)
+		b := chain.Blocks[int(block)%len(chain.Blocks)]
+		name := blockFields[int(field)%len(blockFields)]
+		if name == "Transactions" && len(b.Transactions) == 0 {
+			name = "Hash" // The genesis block has no transactions to tamper with.
+		}
+		tamperBlock(b, name, rand.New(rand.NewSource(seed)))
+		if ok, _ := chain.IsChainValid(); ok {
+			t.Logf("chain with tampered %s of block %d accepted", name, b.Index)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(property, propertyConfig); err != nil {
+		t.Error(err)
+	}
+}
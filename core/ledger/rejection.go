@@ -0,0 +1,52 @@
+package ledger
+
+import (
+	"digisocialblock/core/errcode"
+	"errors"
+)
+
+// Rejection is the reason a transaction was refused: by AddBlock or
+// AppendBlock, for the transaction that made the block invalid, or by
+// Mempool.Add. It names the offending transaction so a client can fix and
+// resubmit it without parsing the message. Its Error is the message of the
+// failure it wraps, which errors.Is, errors.As and errcode.Of still see.
+//
+// Failures not caused by a single transaction, such as a block that cannot
+// be stored, are not Rejections.
+type Rejection struct {
+	Code    errcode.Code // Registered code of the failure; empty if it has none
+	TxIndex int          // Position of the transaction in the block; 0 for Mempool.Add
+	TxID    string       // ID of the transaction; empty if it has none
+	Message string       // Human-readable description of the failure
+	err     error
+}
+
+// reject wraps err, the failure of tx at index i of the transactions being
+// checked, in a Rejection. A nil err, or one that already is a Rejection,
+// is returned as is.
+func reject(i int, tx *Transaction, err error) error {
+	if _, ok := err.(*Rejection); ok || err == nil {
+		return err
+	}
+	r := &Rejection{TxIndex: i, Message: err.Error(), err: err}
+	if tx != nil {
+		r.TxID = tx.ID
+	}
+	if code, ok := errcode.Of(err); ok {
+		r.Code = code
+	}
+	return r
+}
+
+func (r *Rejection) Error() string { return r.Message }
+
+func (r *Rejection) Unwrap() error { return r.err }
+
+// AsRejection returns the Rejection in err's chain, if there is one.
+func AsRejection(err error) (*Rejection, bool) {
+	var r *Rejection
+	if errors.As(err, &r) {
+		return r, true
+	}
+	return nil, false
+}
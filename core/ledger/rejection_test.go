@@ -0,0 +1,63 @@
+package ledger
+
+import (
+	"digisocialblock/core/errcode"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestBlockchain_AddBlockRejectsWithOffendingTransaction(t *testing.T) {
+	alicePriv, alice := newTestKey(t)
+	bobPriv, bob := newTestKey(t)
+	bc, err := NewBlockchain()
+	if err != nil {
+		t.Fatalf("NewBlockchain() error = %v", err)
+	}
+	if err := bc.SetGenesisAllocations(map[string]uint64{alice: 10}); err != nil {
+		t.Fatalf("SetGenesisAllocations() error = %v", err)
+	}
+
+	forged := newSignedTestTx(t, alicePriv, alice, `{"text":"forged"}`)
+	forged.Signature = newSignedTestTx(t, bobPriv, alice, `{"text":"other"}`).Signature
+	overdraft := transferTestTx(t, bobPriv, bob, alice, 5)
+	for _, tc := range []struct {
+		name  string
+		txs   []*Transaction
+		index int
+		want  error
+		code  errcode.Code
+	}{
+		{"invalid signature", []*Transaction{newSignedTestTx(t, alicePriv, alice, `{"text":"ok"}`), forged}, 1, ErrInvalidSignature, CodeInvalidSignature},
+		{"overdraft", []*Transaction{transferTestTx(t, alicePriv, alice, bob, 1), newSignedTestTx(t, bobPriv, bob, `{"text":"ok"}`), overdraft}, 2, ErrInsufficientBalance, CodeInsufficientBalance},
+	} {
+		_, err := bc.AddBlock(tc.txs)
+		r, ok := AsRejection(err)
+		if !ok {
+			t.Fatalf("%s: AddBlock() error = %v, want a Rejection", tc.name, err)
+		}
+		if r.TxIndex != tc.index || r.TxID != tc.txs[tc.index].ID || r.Code != tc.code || !errors.Is(err, tc.want) || r.Message != err.Error() {
+			t.Errorf("%s: Rejection = %+v, want index %d, ID %s and %s", tc.name, r, tc.index, tc.txs[tc.index].ID, tc.code)
+		}
+	}
+}
+
+func TestMempool_AddRejectsWithCode(t *testing.T) {
+	priv, addr := newTestKey(t)
+	mp := NewMempool(nil)
+	tx := newSignedTestTx(t, priv, addr, `{"text":"hello"}`)
+	if err := mp.Add(tx); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	err := mp.Add(tx)
+	r, ok := AsRejection(err)
+	if !ok || r.Code != CodeDuplicateTransaction || r.TxID != tx.ID || r.TxIndex != 0 || !errors.Is(err, ErrDuplicateTransaction) {
+		t.Errorf("Add() of a duplicate error = %#v, want a Rejection with %s", err, CodeDuplicateTransaction)
+	}
+	if r, ok := AsRejection(mp.Add(nil)); !ok || r.Code != "" || r.TxID != "" {
+		t.Errorf("Add(nil) Rejection = %+v, want one without code or ID", r)
+	}
+	if _, ok := AsRejection(fmt.Errorf("block 3: %w", reject(0, tx, ErrWrongChain))); !ok {
+		t.Error("AsRejection() did not find a wrapped Rejection")
+	}
+}
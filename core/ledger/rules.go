@@ -0,0 +1,71 @@
+package ledger
+
+import (
+	"digisocialblock/core/errcode"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// CodePolicyViolation is the error code of ErrPolicyViolation.
+var CodePolicyViolation = errcode.Register("DSB-LEDGER-015", http.StatusUnprocessableEntity, "transaction violates the node's policy")
+
+// ErrPolicyViolation is returned for a transaction refused by a
+// TransactionRule.
+var ErrPolicyViolation = errcode.New(CodePolicyViolation, "transaction violates the node's policy")
+
+// RuleEnv is where a TransactionRule is applied: the block the transaction
+// would join, and that block's time. At mempool admission, which is not tied
+// to a chain, Height is -1 and Time is the mempool's clock.
+type RuleEnv struct {
+	Height int64
+	Time   time.Time
+}
+
+// TransactionRule is a check on transactions beyond the ledger's own, such
+// as the rules of package policy. CheckTransaction returns nil to allow tx,
+// and is called concurrently; it must not modify tx, and must not call back
+// into the Blockchain or Mempool applying it.
+type TransactionRule interface {
+	CheckTransaction(tx *Transaction, env RuleEnv) error
+}
+
+// TransactionRuleFunc adapts a function to a TransactionRule.
+type TransactionRuleFunc func(tx *Transaction, env RuleEnv) error
+
+// CheckTransaction calls f.
+func (f TransactionRuleFunc) CheckTransaction(tx *Transaction, env RuleEnv) error {
+	return f(tx, env)
+}
+
+// checkRule applies rule, if there is one, to each of txs.
+func checkRule(rule TransactionRule, txs []*Transaction, env RuleEnv) error {
+	if rule == nil {
+		return nil
+	}
+	for i, tx := range txs {
+		if err := rule.CheckTransaction(tx, env); err != nil {
+			return reject(i, tx, fmt.Errorf("%w: transaction %s: %v", ErrPolicyViolation, tx.ID, err))
+		}
+	}
+	return nil
+}
+
+// SetTransactionRule makes AddBlock, AppendBlock and block production refuse
+// transactions rule refuses. Unlike a Mempool's rule, this one decides which
+// blocks the chain accepts, so every node of a chain must apply the same
+// rule or they will refuse each other's blocks. A nil rule removes it.
+func (bc *Blockchain) SetTransactionRule(rule TransactionRule) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.rule = rule
+}
+
+// SetTransactionRule makes Add refuse transactions rule refuses, with
+// ErrPolicyViolation. Rules here only decide what this node relays and
+// includes in its own blocks. A nil rule removes it.
+func (mp *Mempool) SetTransactionRule(rule TransactionRule) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.rule = rule
+}
@@ -0,0 +1,51 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTransactionRule_AppliedAtAdmissionAndBlockValidation(t *testing.T) {
+	bc, mempool, p, _ := producerTestSetup(t, ProducerOptions{})
+	var envs []RuleEnv
+	noBanned := TransactionRuleFunc(func(tx *Transaction, env RuleEnv) error {
+		envs = append(envs, env)
+		if string(tx.Payload) == "banned" {
+			return errors.New("banned word")
+		}
+		return nil
+	})
+	priv, addr := newTestKey(t)
+	banned := newSignedTestTx(t, priv, addr, "banned")
+
+	mempool.SetTransactionRule(noBanned)
+	if err := mempool.Add(banned); !errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("Mempool.Add() error = %v, want ErrPolicyViolation", err)
+	}
+	if envs[0].Height != -1 {
+		t.Errorf("admission env = %+v, want height -1", envs[0])
+	}
+	mempool.SetTransactionRule(nil)
+	if err := mempool.Add(banned); err != nil {
+		t.Fatalf("Mempool.Add() without the rule error = %v", err)
+	}
+
+	bc.SetTransactionRule(noBanned)
+	if _, err := bc.AddBlock([]*Transaction{banned}); !errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("AddBlock() error = %v, want ErrPolicyViolation", err)
+	}
+	if env := envs[len(envs)-1]; env.Height != 1 || env.Time.IsZero() {
+		t.Errorf("block env = %+v, want height 1", env)
+	}
+
+	// The producer evicts what the chain's rule refuses.
+	mempool.Add(newSignedTestTx(t, priv, addr, "fine"))
+	block, err := p.Produce(context.Background())
+	if err != nil || block == nil || len(block.Transactions) != 1 || string(block.Transactions[0].Payload) != "fine" {
+		t.Fatalf("Produce() = %v, %v; want a block with only the allowed transaction", block, err)
+	}
+	if mempool.Size() != 0 {
+		t.Errorf("mempool Size() = %d after production, want the refused transaction evicted", mempool.Size())
+	}
+}
@@ -0,0 +1,140 @@
+package ledger
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// DefaultSignatureCacheSize is the number of verified signatures a Blockchain
+// remembers when no explicit cache has been configured.
+const DefaultSignatureCacheSize = 10000
+
+// SignatureCache is a bounded LRU cache of transaction signatures that have
+// already been verified successfully.
+//
+// Transactions are verified once at mempool admission and again when they are
+// included in a block via AddBlock. Sharing a SignatureCache between the two
+// lets the second ECDSA verification be skipped.
+//
+// Invalidation safety: entries are keyed by a hash over the transaction ID,
+// the sender public key, the raw signature bytes, the delegate that made them,
+// any co-signatures, and the chain ID and signature version the signature was
+// made under. Changing any of them produces a different key, so a tampered
+// transaction can never reuse a cached result. Only successful verifications
+// are cached; failures are always recomputed. Note that the cache does not
+// bind the ID to the transaction content; that remains the job of the
+// structural checks in IsValid.
+//
+// A SignatureCache is safe for concurrent use.
+type SignatureCache struct {
+	mu      sync.Mutex
+	maxSize int
+	entries map[string]*list.Element
+	order   *list.List // Front is most recently used
+	hits    uint64
+	misses  uint64
+}
+
+// NewSignatureCache creates a SignatureCache holding at most maxSize entries.
+func NewSignatureCache(maxSize int) (*SignatureCache, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("signature cache size must be positive, got %d", maxSize)
+	}
+	return &SignatureCache{
+		maxSize: maxSize,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}, nil
+}
+
+// signatureCacheKey derives the cache key for a transaction.
+// Fields are length-prefixed so that no two distinct inputs share a preimage.
+func signatureCacheKey(tx *Transaction) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "v%d:", tx.SigVersion)
+	parts := [][]byte{[]byte(tx.ID), []byte(tx.SenderPublicKey), tx.Signature, []byte(tx.ChainID), []byte(tx.Delegate)}
+	for _, cs := range tx.CoSignatures {
+		parts = append(parts, []byte(cs.PublicKey), cs.Signature)
+	}
+	for _, part := range parts {
+		fmt.Fprintf(h, "%d:", len(part))
+		h.Write(part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Contains reports whether the transaction's signature has already been verified.
+func (sc *SignatureCache) Contains(tx *Transaction) bool {
+	if tx == nil {
+		return false
+	}
+	key := signatureCacheKey(tx)
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	elem, ok := sc.entries[key]
+	if !ok {
+		sc.misses++
+		return false
+	}
+	sc.hits++
+	sc.order.MoveToFront(elem)
+	return true
+}
+
+// Add records that the transaction's signature verified successfully.
+// The least recently used entry is evicted when the cache is full.
+func (sc *SignatureCache) Add(tx *Transaction) {
+	if tx == nil {
+		return
+	}
+	key := signatureCacheKey(tx)
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if elem, ok := sc.entries[key]; ok {
+		sc.order.MoveToFront(elem)
+		return
+	}
+	sc.entries[key] = sc.order.PushFront(key)
+	for sc.order.Len() > sc.maxSize {
+		oldest := sc.order.Back()
+		sc.order.Remove(oldest)
+		delete(sc.entries, oldest.Value.(string))
+	}
+}
+
+// Verify is a caching wrapper around Transaction.VerifySignature.
+// A cache hit returns immediately; otherwise the signature is verified and,
+// if valid, remembered.
+func (sc *SignatureCache) Verify(tx *Transaction) (bool, error) {
+	if tx == nil {
+		return false, fmt.Errorf("cannot verify a nil transaction")
+	}
+	if sc.Contains(tx) {
+		return true, nil
+	}
+	valid, err := tx.VerifySignature()
+	if err != nil || !valid {
+		return valid, err
+	}
+	sc.Add(tx)
+	return true, nil
+}
+
+// Len returns the number of cached signatures.
+func (sc *SignatureCache) Len() int {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.order.Len()
+}
+
+// Stats returns the number of cache hits and misses observed so far.
+func (sc *SignatureCache) Stats() (hits, misses uint64) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.hits, sc.misses
+}
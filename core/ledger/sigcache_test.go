@@ -0,0 +1,166 @@
+package ledger
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"digisocialblock/core/clientkit"
+	"fmt"
+	"testing"
+)
+
+// newTestKey generates a P-256 key and its hex address for tests in this package.
+func newTestKey(tb testing.TB) (*ecdsa.PrivateKey, string) {
+	tb.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatalf("failed to generate key: %v", err)
+	}
+	addr, err := clientkit.Address(&priv.PublicKey)
+	if err != nil {
+		tb.Fatalf("failed to derive address: %v", err)
+	}
+	return priv, addr
+}
+
+// newSignedTestTx creates a PostCreated transaction signed by priv.
+func newSignedTestTx(tb testing.TB, priv *ecdsa.PrivateKey, addr string, payload string) *Transaction {
+	tb.Helper()
+	tx, err := NewTransaction(addr, PostCreated, []byte(payload))
+	if err != nil {
+		tb.Fatalf("NewTransaction() error = %v", err)
+	}
+	if err := tx.Sign(priv); err != nil {
+		tb.Fatalf("Sign() error = %v", err)
+	}
+	return tx
+}
+
+func TestNewSignatureCache_InvalidSize(t *testing.T) {
+	if _, err := NewSignatureCache(0); err == nil {
+		t.Error("NewSignatureCache(0): expected error, got nil")
+	}
+}
+
+func TestSignatureCache_VerifyCachesOnlyValidSignatures(t *testing.T) {
+	priv, addr := newTestKey(t)
+	sc, _ := NewSignatureCache(10)
+
+	tx := newSignedTestTx(t, priv, addr, "cached payload")
+	if ok, err := sc.Verify(tx); err != nil || !ok {
+		t.Fatalf("Verify() on valid tx = %v, %v; want true, nil", ok, err)
+	}
+	if !sc.Contains(tx) {
+		t.Error("valid signature was not cached")
+	}
+
+	bad := newSignedTestTx(t, priv, addr, "bad payload")
+	bad.Signature = append([]byte{}, tx.Signature...) // Signature over a different ID
+	if ok, _ := sc.Verify(bad); ok {
+		t.Error("Verify() accepted a signature for a different transaction ID")
+	}
+	if sc.Len() != 1 {
+		t.Errorf("cache length = %d, want 1 (failures must not be cached)", sc.Len())
+	}
+}
+
+func TestSignatureCache_TamperedFieldsMiss(t *testing.T) {
+	priv, addr := newTestKey(t)
+	_, otherAddr := newTestKey(t)
+	sc, _ := NewSignatureCache(10)
+	tx := newSignedTestTx(t, priv, addr, "payload")
+	sc.Add(tx)
+
+	tamperedID := *tx
+	tamperedID.ID = "different-id"
+	tamperedSender := *tx
+	tamperedSender.SenderPublicKey = otherAddr
+	tamperedSig := *tx
+	tamperedSig.Signature = append(append([]byte{}, tx.Signature...), 0x00)
+
+	for name, candidate := range map[string]*Transaction{
+		"id": &tamperedID, "sender": &tamperedSender, "signature": &tamperedSig,
+	} {
+		if sc.Contains(candidate) {
+			t.Errorf("cache hit after tampering with %s", name)
+		}
+	}
+}
+
+func TestSignatureCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	priv, addr := newTestKey(t)
+	sc, _ := NewSignatureCache(2)
+	tx1 := newSignedTestTx(t, priv, addr, "one")
+	tx2 := newSignedTestTx(t, priv, addr, "two")
+	tx3 := newSignedTestTx(t, priv, addr, "three")
+
+	sc.Add(tx1)
+	sc.Add(tx2)
+	sc.Contains(tx1) // tx1 becomes most recently used
+	sc.Add(tx3)      // evicts tx2
+
+	if !sc.Contains(tx1) || !sc.Contains(tx3) {
+		t.Error("expected tx1 and tx3 to remain cached")
+	}
+	if sc.Contains(tx2) {
+		t.Error("expected tx2 to be evicted")
+	}
+}
+
+func TestBlockchain_AddBlock_UsesMempoolSignatureCache(t *testing.T) {
+	priv, addr := newTestKey(t)
+	bc, err := NewBlockchain()
+	if err != nil {
+		t.Fatalf("NewBlockchain() error = %v", err)
+	}
+	mp := NewMempool(bc.SignatureCache())
+
+	tx := newSignedTestTx(t, priv, addr, "admitted via mempool")
+	if err := mp.Add(tx); err != nil {
+		t.Fatalf("Mempool.Add() error = %v", err)
+	}
+	hitsBefore, _ := bc.SignatureCache().Stats()
+	if _, err := bc.AddBlock(mp.Pending()); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+	hitsAfter, _ := bc.SignatureCache().Stats()
+	if hitsAfter != hitsBefore+1 {
+		t.Errorf("AddBlock cache hits = %d, want %d", hitsAfter-hitsBefore, 1)
+	}
+}
+
+// BenchmarkAddBlock_SignatureCache measures block import with and without
+// signatures pre-verified at mempool admission.
+func BenchmarkAddBlock_SignatureCache(b *testing.B) {
+	priv, addr := newTestKey(b)
+	const txPerBlock = 100
+
+	for _, warm := range []bool{false, true} {
+		b.Run(fmt.Sprintf("warm=%v", warm), func(b *testing.B) {
+			blocks := make([][]*Transaction, b.N)
+			for i := range blocks {
+				for j := 0; j < txPerBlock; j++ {
+					blocks[i] = append(blocks[i], newSignedTestTx(b, priv, addr, fmt.Sprintf("bench %d/%d", i, j)))
+				}
+			}
+			bc, _ := NewBlockchain()
+			if warm {
+				sc, _ := NewSignatureCache(b.N * txPerBlock)
+				for _, txs := range blocks {
+					for _, tx := range txs {
+						sc.Add(tx)
+					}
+				}
+				bc.SetSignatureCache(sc)
+			} else {
+				bc.SetSignatureCache(nil)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := bc.AddBlock(blocks[i]); err != nil {
+					b.Fatalf("AddBlock() error = %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package ledger
+
+import (
+	"digisocialblock/core/errcode"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CodeSpamRejected is the error code of ErrSpamRejected.
+var CodeSpamRejected = errcode.Register("DSB-LEDGER-014", http.StatusForbidden, "transaction was classified as spam")
+
+// ErrSpamRejected is returned by Mempool.Add for a transaction the node's
+// spam classifier and policy reject.
+var ErrSpamRejected = errcode.New(CodeSpamRejected, "transaction was classified as spam")
+
+// Defaults for zero SpamPolicy fields.
+const (
+	DefaultSpamDelay         = time.Minute
+	DefaultSpamRateWindow    = time.Minute
+	DefaultSpamHistoryWindow = 24 * time.Hour
+)
+
+// SpamFeatures describe a transaction and its sender's recent behaviour, as
+// seen by the mempool when the transaction arrives. The sender's signature
+// has been verified, so the history is really theirs.
+type SpamFeatures struct {
+	PayloadSize    int           `json:"payloadSize"`
+	PayloadEntropy float64       `json:"payloadEntropy"` // Shannon entropy in bits per byte, 0 to 8
+	SenderPending  int           `json:"senderPending"`  // The sender's transactions waiting in the mempool
+	SenderRecent   int           `json:"senderRecent"`   // Admitted from the sender within the policy's RateWindow
+	SenderHistory  int           `json:"senderHistory"`  // Admitted from the sender within the policy's HistoryWindow
+	SenderAge      time.Duration `json:"senderAge"`      // Since the sender's first admission in the history; 0 for a new sender
+}
+
+// SpamScore is a classifier's judgement of a transaction: Score runs from 0
+// (certainly not spam) to 1 (certainly spam).
+type SpamScore struct {
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason,omitempty"` // For the audit log
+}
+
+// SpamClassifier judges transactions as they enter the mempool. What happens
+// to a transaction follows from its score and the node's SpamPolicy.
+// Classify is called concurrently and should be fast; it must not modify tx.
+type SpamClassifier interface {
+	Classify(tx *Transaction, features SpamFeatures) SpamScore
+}
+
+// SpamClassifierFunc adapts a function to a SpamClassifier.
+type SpamClassifierFunc func(tx *Transaction, features SpamFeatures) SpamScore
+
+// Classify calls f.
+func (f SpamClassifierFunc) Classify(tx *Transaction, features SpamFeatures) SpamScore {
+	return f(tx, features)
+}
+
+// SpamVerdict is what the mempool does with a classified transaction.
+type SpamVerdict string
+
+// Spam verdicts, from the mildest.
+const (
+	SpamAccept       SpamVerdict = "accept"
+	SpamDeprioritize SpamVerdict = "deprioritize" // Admitted, but offered to block producers after everything else
+	SpamDelay        SpamVerdict = "delay"        // Admitted, but not offered to block producers until the delay passes
+	SpamReject       SpamVerdict = "reject"       // Refused with ErrSpamRejected
+)
+
+// SpamPolicy is a node's response to spam scores. Each threshold applies its
+// verdict to transactions scoring at or above it; the harshest applicable
+// verdict wins. A zero threshold disables its verdict, so the zero policy
+// accepts everything and only records decisions.
+type SpamPolicy struct {
+	DeprioritizeAt float64
+	DelayAt        float64
+	RejectAt       float64
+	Delay          time.Duration // Hold time for delayed transactions; defaults to DefaultSpamDelay
+	RateWindow     time.Duration // Window of SpamFeatures.SenderRecent; defaults to DefaultSpamRateWindow
+	HistoryWindow  time.Duration // Window of SpamFeatures.SenderHistory; defaults to DefaultSpamHistoryWindow
+}
+
+// Validate checks that thresholds are within 0 to 1 and durations are not
+// negative.
+func (p SpamPolicy) Validate() error {
+	for name, v := range map[string]float64{"deprioritize": p.DeprioritizeAt, "delay": p.DelayAt, "reject": p.RejectAt} {
+		if v < 0 || v > 1 || math.IsNaN(v) {
+			return fmt.Errorf("%s threshold %v is outside 0 to 1", name, v)
+		}
+	}
+	if p.Delay < 0 || p.RateWindow < 0 || p.HistoryWindow < 0 {
+		return fmt.Errorf("spam policy durations cannot be negative")
+	}
+	return nil
+}
+
+// withDefaults returns p with zero durations replaced by their defaults.
+func (p SpamPolicy) withDefaults() SpamPolicy {
+	if p.Delay == 0 {
+		p.Delay = DefaultSpamDelay
+	}
+	if p.RateWindow == 0 {
+		p.RateWindow = DefaultSpamRateWindow
+	}
+	if p.HistoryWindow == 0 {
+		p.HistoryWindow = DefaultSpamHistoryWindow
+	}
+	return p
+}
+
+// Verdict returns the policy's verdict for score.
+func (p SpamPolicy) Verdict(score float64) SpamVerdict {
+	switch {
+	case p.RejectAt > 0 && score >= p.RejectAt:
+		return SpamReject
+	case p.DelayAt > 0 && score >= p.DelayAt:
+		return SpamDelay
+	case p.DeprioritizeAt > 0 && score >= p.DeprioritizeAt:
+		return SpamDeprioritize
+	}
+	return SpamAccept
+}
+
+// SpamDecision records one classification, for audit.
+type SpamDecision struct {
+	Time     time.Time       `json:"time"`
+	TxID     string          `json:"txId"`
+	Sender   string          `json:"sender"`
+	Type     TransactionType `json:"type"`
+	Features SpamFeatures    `json:"features"`
+	SpamScore
+	Verdict SpamVerdict `json:"verdict"`
+	Until   time.Time   `json:"until,omitempty"` // When a delayed transaction is released
+}
+
+// SpamAuditor records the mempool's spam decisions. RecordSpamDecision is
+// called for every classified transaction, accepted or not, and must not
+// block for long.
+type SpamAuditor interface {
+	RecordSpamDecision(d SpamDecision)
+}
+
+// SpamAuditLog is a SpamAuditor that writes each decision to w as a line of
+// JSON. A SpamAuditLog is safe for concurrent use.
+type SpamAuditLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewSpamAuditLog returns a SpamAuditLog writing to w.
+func NewSpamAuditLog(w io.Writer) *SpamAuditLog {
+	return &SpamAuditLog{enc: json.NewEncoder(w)}
+}
+
+// RecordSpamDecision writes d. Write errors are kept for Err rather than
+// returned, so a failing log never blocks admission.
+func (l *SpamAuditLog) RecordSpamDecision(d SpamDecision) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(d); err != nil && l.err == nil {
+		l.err = fmt.Errorf("failed to write spam decision for %s: %w", d.TxID, err)
+	}
+}
+
+// Err returns the first write error, if any.
+func (l *SpamAuditLog) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// PayloadEntropy returns the Shannon entropy of payload in bits per byte:
+// 0 for a run of one byte value, 8 for uniformly random bytes.
+func PayloadEntropy(payload []byte) float64 {
+	if len(payload) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, b := range payload {
+		counts[b]++
+	}
+	entropy, n := 0.0, float64(len(payload))
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / n
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
+
+// HeuristicSpamClassifier is a simple SpamClassifier built from the features
+// alone. It scores high for senders posting faster than MaxRate, and for
+// large payloads that are near-constant filler or near-random noise, and
+// scores new senders a little higher than established ones.
+type HeuristicSpamClassifier struct {
+	MaxRecent   int           // Transactions per rate window before the rate counts against a sender; defaults to 10
+	Established time.Duration // Sender age after which the new-sender penalty ends; defaults to an hour
+}
+
+// Classify implements SpamClassifier.
+func (c HeuristicSpamClassifier) Classify(tx *Transaction, f SpamFeatures) SpamScore {
+	maxRecent, established := c.MaxRecent, c.Established
+	if maxRecent <= 0 {
+		maxRecent = 10
+	}
+	if established <= 0 {
+		established = time.Hour
+	}
+	score := SpamScore{}
+	raise := func(s float64, reason string) {
+		if s > score.Score {
+			score = SpamScore{Score: math.Min(s, 1), Reason: reason}
+		}
+	}
+	if f.SenderRecent >= maxRecent {
+		// 0.5 at the limit, rising to 1 at twice the limit.
+		raise(0.5+0.5*float64(f.SenderRecent-maxRecent)/float64(maxRecent), fmt.Sprintf("%d transactions in the rate window", f.SenderRecent))
+	}
+	if f.PayloadSize >= 256 {
+		switch {
+		case f.PayloadEntropy < 2:
+			raise(0.6, fmt.Sprintf("low-entropy payload (%.2f bits per byte)", f.PayloadEntropy))
+		case f.PayloadEntropy > 7.5:
+			raise(0.6, fmt.Sprintf("random-looking payload (%.2f bits per byte)", f.PayloadEntropy))
+		}
+	}
+	if f.SenderAge < established && score.Score > 0 {
+		raise(score.Score+0.2, score.Reason+" from a new sender")
+	}
+	return score
+}
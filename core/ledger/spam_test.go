@@ -0,0 +1,130 @@
+package ledger
+
+import (
+	"bytes"
+	"digisocialblock/internal/testutil"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// spamTestIDs returns the IDs of txs, in order.
+func spamTestIDs(txs []*Transaction) string {
+	ids := make([]string, len(txs))
+	for i, tx := range txs {
+		ids[i] = tx.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestMempool_SpamVerdicts(t *testing.T) {
+	mempool := NewMempool(nil)
+	clock := testutil.NewClock(0)
+	mempool.SetClock(clock)
+	var audit bytes.Buffer
+	scores := map[string]float64{"fine": 0, "meh": 0.4, "dodgy": 0.6, "spam": 0.9}
+	classifier := SpamClassifierFunc(func(tx *Transaction, f SpamFeatures) SpamScore {
+		return SpamScore{Score: scores[string(tx.Payload)], Reason: string(tx.Payload)}
+	})
+	policy := SpamPolicy{DeprioritizeAt: 0.3, DelayAt: 0.5, RejectAt: 0.8, Delay: time.Minute}
+	if err := mempool.SetSpamClassifier(classifier, policy, NewSpamAuditLog(&audit)); err != nil {
+		t.Fatalf("SetSpamClassifier() error = %v", err)
+	}
+
+	priv, addr := newTestKey(t)
+	txs := map[string]*Transaction{}
+	for _, payload := range []string{"meh", "dodgy", "fine", "spam"} {
+		txs[payload] = newSignedTestTx(t, priv, addr, payload)
+		err := mempool.Add(txs[payload])
+		if payload == "spam" {
+			if !errors.Is(err, ErrSpamRejected) {
+				t.Errorf("Add(spam) error = %v, want ErrSpamRejected", err)
+			}
+		} else if err != nil {
+			t.Fatalf("Add(%s) error = %v", payload, err)
+		}
+	}
+	if got, want := spamTestIDs(mempool.Pending()), spamTestIDs([]*Transaction{txs["fine"], txs["meh"]}); got != want {
+		t.Errorf("Pending() = %s, want fine then the deprioritized meh, without dodgy", got)
+	}
+	if mempool.Size() != 3 {
+		t.Errorf("Size() = %d, want 3 including the delayed transaction", mempool.Size())
+	}
+	clock.Advance(time.Minute)
+	if got, want := spamTestIDs(mempool.Pending()), spamTestIDs([]*Transaction{txs["dodgy"], txs["fine"], txs["meh"]}); got != want {
+		t.Errorf("Pending() after the delay = %s, want dodgy released in arrival order", got)
+	}
+
+	var decisions []SpamDecision
+	for _, line := range strings.Split(strings.TrimSpace(audit.String()), "\n") {
+		var d SpamDecision
+		if err := json.Unmarshal([]byte(line), &d); err != nil {
+			t.Fatalf("audit line %q: %v", line, err)
+		}
+		decisions = append(decisions, d)
+	}
+	var verdicts []string
+	for _, d := range decisions {
+		verdicts = append(verdicts, d.Reason+"="+string(d.Verdict))
+	}
+	if got := strings.Join(verdicts, " "); got != "meh=deprioritize dodgy=delay fine=accept spam=reject" {
+		t.Errorf("audited verdicts = %s", got)
+	}
+	if d := decisions[3]; d.TxID != txs["spam"].ID || d.Sender != addr || d.Features.SenderHistory != 3 || d.Features.SenderPending != 3 {
+		t.Errorf("audited rejection = %+v", d)
+	}
+}
+
+func TestHeuristicSpamClassifier(t *testing.T) {
+	mempool := NewMempool(nil)
+	clock := testutil.NewClock(time.Second)
+	mempool.SetClock(clock)
+	mempool.SetSpamClassifier(HeuristicSpamClassifier{MaxRecent: 3}, SpamPolicy{RejectAt: 0.7}, nil)
+	priv, addr := newTestKey(t)
+
+	// A new sender is refused once its rate passes the limit.
+	var err error
+	for i := 0; err == nil; i++ {
+		if i > 10 {
+			t.Fatal("a flooding sender was never refused")
+		}
+		err = mempool.Add(newSignedTestTx(t, priv, addr, strings.Repeat("x", i+1)))
+	}
+	if !errors.Is(err, ErrSpamRejected) || !strings.Contains(err.Error(), "new sender") {
+		t.Errorf("Add() error = %v, want a rate rejection of a new sender", err)
+	}
+	clock.Advance(DefaultSpamRateWindow)
+	if err := mempool.Add(newSignedTestTx(t, priv, addr, "later")); err != nil {
+		t.Errorf("Add() after the rate window error = %v", err)
+	}
+
+	noise := make([]byte, 1024)
+	rand.New(rand.NewSource(1)).Read(noise)
+	for _, tt := range []struct {
+		payload []byte
+		spam    bool
+	}{
+		{bytes.Repeat([]byte("a"), 1024), true},
+		{noise, true},
+		{[]byte(strings.Repeat("An ordinary post about the weather. ", 10)), false},
+	} {
+		f := SpamFeatures{PayloadSize: len(tt.payload), PayloadEntropy: PayloadEntropy(tt.payload), SenderAge: 24 * time.Hour}
+		if s := (HeuristicSpamClassifier{}).Classify(nil, f); (s.Score >= 0.5) != tt.spam {
+			t.Errorf("Classify(entropy %.2f) = %+v, want spam %v", f.PayloadEntropy, s, tt.spam)
+		}
+	}
+}
+
+func TestSpamPolicy_Validate(t *testing.T) {
+	for _, p := range []SpamPolicy{{RejectAt: 1.5}, {DelayAt: -0.1}, {Delay: -time.Second}} {
+		if err := p.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want an error", p)
+		}
+	}
+	if PayloadEntropy(nil) != 0 || PayloadEntropy([]byte{0, 1}) != 1 {
+		t.Error("PayloadEntropy() of trivial payloads is wrong")
+	}
+}
@@ -0,0 +1,105 @@
+package ledger
+
+import (
+	"context"
+	"crypto/sha256"
+	"digisocialblock/core/errcode"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// ErrInsufficientStamp is returned when a transaction's proof-of-work stamp
+// does not meet the difficulty required by the mempool.
+var ErrInsufficientStamp = errcode.New(CodeInsufficientStamp, "transaction proof-of-work stamp is insufficient")
+
+// MaxStampBits caps stamp difficulty. Each extra bit doubles the expected
+// minting work; 32 bits already takes billions of hashes.
+const MaxStampBits = 32
+
+// StampPolicy is a node's anti-spam requirement for transaction submission: a
+// hashcash-style stamp whose hash has at least the required number of leading
+// zero bits. It is a mempool admission rule only; blocks are not required to
+// carry stamps, so nodes may choose different policies.
+type StampPolicy struct {
+	DefaultBits int                     // Required bits for types not in PerType; 0 disables the requirement
+	PerType     map[TransactionType]int // Per-type overrides (0 exempts a type)
+}
+
+// Validate checks that all difficulties are within [0, MaxStampBits].
+func (p StampPolicy) Validate() error {
+	if p.DefaultBits < 0 || p.DefaultBits > MaxStampBits {
+		return fmt.Errorf("default stamp bits %d out of range [0, %d]", p.DefaultBits, MaxStampBits)
+	}
+	for txType, n := range p.PerType {
+		if n < 0 || n > MaxStampBits {
+			return fmt.Errorf("stamp bits %d for %s out of range [0, %d]", n, txType, MaxStampBits)
+		}
+	}
+	return nil
+}
+
+// RequiredBits returns the stamp difficulty for txType.
+func (p StampPolicy) RequiredBits(txType TransactionType) int {
+	if n, ok := p.PerType[txType]; ok {
+		return n
+	}
+	return p.DefaultBits
+}
+
+// stampWork returns the number of leading zero bits of SHA-256(ID || stamp).
+// Binding the stamp to the ID (a hash of the content and sender) means a stamp
+// cannot be reused for any other transaction.
+func stampWork(txID string, stamp uint64) int {
+	h := sha256.New()
+	h.Write([]byte(txID))
+	var nonce [8]byte
+	binary.BigEndian.PutUint64(nonce[:], stamp)
+	h.Write(nonce[:])
+	sum := h.Sum(nil)
+
+	zeros := 0
+	for i := 0; i < len(sum); i += 8 {
+		word := binary.BigEndian.Uint64(sum[i : i+8])
+		zeros += bits.LeadingZeros64(word)
+		if word != 0 {
+			break
+		}
+	}
+	return zeros
+}
+
+// VerifyStamp checks that the transaction's stamp has at least requiredBits of work.
+func (tx *Transaction) VerifyStamp(requiredBits int) error {
+	if requiredBits <= 0 {
+		return nil
+	}
+	if got := stampWork(tx.ID, tx.Stamp); got < requiredBits {
+		return fmt.Errorf("%w: %d leading zero bits, %d required", ErrInsufficientStamp, got, requiredBits)
+	}
+	return nil
+}
+
+// MintStamp searches for a stamp with at least requiredBits of work and stores
+// it in tx.Stamp. The transaction ID must be final (the stamp is bound to it),
+// but the stamp itself is not covered by the signature, so minting may happen
+// before or after signing. Expected cost is 2^requiredBits hashes; ctx bounds it.
+func (tx *Transaction) MintStamp(ctx context.Context, requiredBits int) error {
+	if tx.ID == "" {
+		return fmt.Errorf("transaction ID is empty, cannot mint stamp")
+	}
+	if requiredBits < 0 || requiredBits > MaxStampBits {
+		return fmt.Errorf("stamp bits %d out of range [0, %d]", requiredBits, MaxStampBits)
+	}
+	for stamp := uint64(0); ; stamp++ {
+		if stampWork(tx.ID, stamp) >= requiredBits {
+			tx.Stamp = stamp
+			return nil
+		}
+		if stamp%4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return fmt.Errorf("stamp minting aborted: %w", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestTransaction_MintAndVerifyStamp(t *testing.T) {
+	tx, _ := NewTransaction("sender", PostCreated, []byte("stamped"))
+	if err := tx.MintStamp(context.Background(), 12); err != nil {
+		t.Fatalf("MintStamp() error = %v", err)
+	}
+	if err := tx.VerifyStamp(12); err != nil {
+		t.Errorf("VerifyStamp() on a freshly minted stamp error = %v", err)
+	}
+
+	// The stamp is bound to the transaction ID.
+	other, _ := NewTransaction("sender", PostCreated, []byte("different"))
+	other.Stamp = tx.Stamp
+	if err := other.VerifyStamp(12); !errors.Is(err, ErrInsufficientStamp) {
+		t.Errorf("VerifyStamp() with a stamp from another transaction error = %v, want ErrInsufficientStamp", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := tx.MintStamp(ctx, MaxStampBits); !errors.Is(err, context.Canceled) {
+		t.Errorf("MintStamp() with cancelled context error = %v, want context.Canceled", err)
+	}
+}
+
+func TestMempool_StampPolicy(t *testing.T) {
+	priv, addr := newTestKey(t)
+	mempool := NewMempool(nil)
+	if err := mempool.SetStampPolicy(StampPolicy{DefaultBits: MaxStampBits + 1}); err == nil {
+		t.Fatal("SetStampPolicy() with out-of-range bits: expected error, got nil")
+	}
+	if err := mempool.SetStampPolicy(StampPolicy{DefaultBits: 10, PerType: map[TransactionType]int{ProfileUpdate: 0}}); err != nil {
+		t.Fatalf("SetStampPolicy() error = %v", err)
+	}
+
+	// Find a transaction whose zero stamp does not satisfy the policy.
+	var unstamped *Transaction
+	for i := 0; unstamped == nil; i++ {
+		tx := newSignedTestTx(t, priv, addr, fmt.Sprintf("spam %d", i))
+		if tx.VerifyStamp(10) != nil {
+			unstamped = tx
+		}
+	}
+	if err := mempool.Add(unstamped); !errors.Is(err, ErrInsufficientStamp) {
+		t.Fatalf("Add() without stamp error = %v, want ErrInsufficientStamp", err)
+	}
+	if err := unstamped.MintStamp(context.Background(), 10); err != nil {
+		t.Fatalf("MintStamp() error = %v", err)
+	}
+	if err := mempool.Add(unstamped); err != nil {
+		t.Errorf("Add() with minted stamp error = %v", err)
+	}
+
+	exempt, _ := NewTransaction(addr, ProfileUpdate, []byte("exempt"))
+	exempt.Sign(priv)
+	if err := mempool.Add(exempt); err != nil {
+		t.Errorf("Add() of an exempt type error = %v", err)
+	}
+}
@@ -0,0 +1,403 @@
+package ledger
+
+import (
+	"bufio"
+	"bytes"
+	"digisocialblock/core/migrations"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// BlockStore persists the blocks of a Blockchain.
+type BlockStore interface {
+	// AppendBlock records a block. Implementations may buffer the write;
+	// it is only guaranteed to be durable after Flush returns.
+	AppendBlock(block *Block) error
+	// LoadBlocks returns all durable blocks in chain order.
+	LoadBlocks() ([]*Block, error)
+	// Flush makes all appended blocks durable.
+	Flush() error
+	// Close flushes pending blocks and releases resources.
+	Close() error
+}
+
+// DefaultMaxBatchSize is the number of buffered blocks that triggers a flush
+// when FileBlockStoreOptions.MaxBatchSize is not set.
+const DefaultMaxBatchSize = 256
+
+// FileBlockStoreOptions configures group commit for a FileBlockStore.
+type FileBlockStoreOptions struct {
+	// FlushInterval is the longest a block may stay buffered before it is
+	// written and fsynced together with any other pending blocks.
+	// Zero disables batching: every AppendBlock is written and fsynced immediately.
+	FlushInterval time.Duration
+	// MaxBatchSize flushes early once this many blocks are pending.
+	// Zero means DefaultMaxBatchSize.
+	MaxBatchSize int
+}
+
+// storedBlockRecord is one line of the block log. The index fields (height and
+// hash) travel in the same record as the block, so a single write and fsync
+// commits both the block and its index entry.
+type storedBlockRecord struct {
+	Height int64  `json:"height"`
+	Hash   string `json:"hash"`
+	Block  *Block `json:"block"`
+}
+
+// FileBlockStore is an append-only, newline-delimited JSON block log with
+// group commit: blocks appended within FlushInterval are written with one
+// write call and made durable with one fsync.
+//
+// The in-memory index (hash -> height, height -> file offset) is only updated
+// after a batch has been fsynced, so it never refers to data that could be lost.
+// A FileBlockStore is safe for concurrent use.
+type FileBlockStore struct {
+	mu      sync.Mutex
+	file    *os.File
+	path    string
+	opts    FileBlockStoreOptions
+	pending []storedBlockRecord
+	size    int64            // Size of the durable part of the log
+	offsets []int64          // Height -> offset of the record in the log
+	heights map[string]int64 // Block hash -> height
+	syncs   int              // Number of fsyncs performed (exposed for tests and metrics)
+	lastErr error            // Sticky error from a background flush
+	closed  bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// BlockLogSchema versions the FileBlockStore log format. Add a migration
+// here whenever the record layout changes.
+var BlockLogSchema = &migrations.Schema{Name: "ledger.blocklog", Current: 1}
+
+// OpenFileBlockStore opens (or creates) the block log at path.
+// A torn record left at the end of the log by a crash is truncated away.
+// A log in an older format is migrated first (see BlockLogSchema); one in a
+// newer format is refused with an error wrapping migrations.ErrNewerFormat.
+func OpenFileBlockStore(path string, opts FileBlockStoreOptions) (*FileBlockStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("block store path cannot be empty")
+	}
+	if opts.FlushInterval < 0 {
+		return nil, fmt.Errorf("flush interval cannot be negative")
+	}
+	if opts.MaxBatchSize <= 0 {
+		opts.MaxBatchSize = DefaultMaxBatchSize
+	}
+
+	if err := migrations.Prepare(path, BlockLogSchema); err != nil {
+		return nil, fmt.Errorf("failed to open block store: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open block store %s: %w", path, err)
+	}
+	s := &FileBlockStore{
+		file:    file,
+		path:    path,
+		opts:    opts,
+		heights: make(map[string]int64),
+	}
+	if err := s.loadIndex(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if opts.FlushInterval > 0 {
+		s.stop = make(chan struct{})
+		s.done = make(chan struct{})
+		go s.flushLoop()
+	}
+	return s, nil
+}
+
+// loadIndex scans the log, rebuilds the index and truncates a torn tail.
+func (s *FileBlockStore) loadIndex() error {
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek block store: %w", err)
+	}
+	reader := bufio.NewReader(s.file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// len(line) > 0 here means a record without its newline: a torn write.
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read block store: %w", err)
+		}
+		var rec storedBlockRecord
+		if jsonErr := json.Unmarshal(line, &rec); jsonErr != nil || rec.Block == nil {
+			break // Treat an undecodable record as the torn tail
+		}
+		if rec.Height != int64(len(s.offsets)) {
+			return fmt.Errorf("block store %s is corrupt: record at offset %d has height %d, expected %d", s.path, offset, rec.Height, len(s.offsets))
+		}
+		s.offsets = append(s.offsets, offset)
+		s.heights[rec.Hash] = rec.Height
+		offset += int64(len(line))
+	}
+	if err := s.file.Truncate(offset); err != nil {
+		return fmt.Errorf("failed to truncate torn block store tail: %w", err)
+	}
+	if _, err := s.file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek block store: %w", err)
+	}
+	s.size = offset
+	return nil
+}
+
+// BlockLogRecord is one record of a block log as read by ScanBlockLog.
+type BlockLogRecord struct {
+	Offset int64  // Byte offset of the record in the log
+	Height int64  // Height recorded in the log, not checked against position
+	Hash   string // Hash recorded in the log, not recomputed
+	Block  *Block
+	Err    error // Why the record could not be decoded; Block is nil when set
+}
+
+// ScanBlockLog reads a block log written by FileBlockStore and calls fn for
+// every record in order, stopping early if fn returns an error. Unlike
+// OpenFileBlockStore it never modifies the log and does not stop at a bad
+// record: undecodable records, including a torn tail, are passed to fn with
+// Err set. It is meant for tools that inspect a log that may be corrupt.
+func ScanBlockLog(r io.Reader, fn func(BlockLogRecord) error) error {
+	reader := bufio.NewReader(r)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read block log: %w", err)
+		}
+		if len(line) == 0 {
+			return nil
+		}
+		rec := BlockLogRecord{Offset: offset}
+		if err != nil {
+			rec.Err = fmt.Errorf("torn record: %d bytes without a newline", len(line))
+		} else {
+			var stored storedBlockRecord
+			if jsonErr := json.Unmarshal(line, &stored); jsonErr != nil {
+				rec.Err = fmt.Errorf("undecodable record: %w", jsonErr)
+			} else if stored.Block == nil {
+				rec.Err = fmt.Errorf("record has no block")
+			} else {
+				rec.Height, rec.Hash, rec.Block = stored.Height, stored.Hash, stored.Block
+			}
+		}
+		if fnErr := fn(rec); fnErr != nil {
+			return fnErr
+		}
+		if err != nil {
+			return nil
+		}
+		offset += int64(len(line))
+	}
+}
+
+// flushLoop commits pending blocks every FlushInterval.
+func (s *FileBlockStore) flushLoop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			if err := s.flushLocked(); err != nil && s.lastErr == nil {
+				s.lastErr = err
+			}
+			s.mu.Unlock()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// AppendBlock buffers a block for the next group commit. With batching
+// disabled the block is committed before AppendBlock returns.
+// An error from a previous background flush is reported here. When the
+// flush AppendBlock triggers itself fails, the block is not kept, so the
+// same height can be appended again.
+func (s *FileBlockStore) AppendBlock(block *Block) error {
+	if block == nil {
+		return fmt.Errorf("cannot append a nil block")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return fmt.Errorf("block store is closed")
+	}
+	if s.lastErr != nil {
+		return fmt.Errorf("block store is unusable after a failed flush: %w", s.lastErr)
+	}
+	expected := int64(len(s.offsets) + len(s.pending))
+	if block.Index != expected {
+		return fmt.Errorf("cannot append block %d: expected height %d", block.Index, expected)
+	}
+	s.pending = append(s.pending, storedBlockRecord{Height: block.Index, Hash: block.Hash, Block: block})
+	if s.opts.FlushInterval == 0 || len(s.pending) >= s.opts.MaxBatchSize {
+		if err := s.flushLocked(); err != nil {
+			// The caller treats the block as rejected, so a later flush must not
+			// write it. Blocks appended before it stay pending for a retry.
+			s.pending = s.pending[:len(s.pending)-1]
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes all pending blocks with a single write and fsync.
+func (s *FileBlockStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lastErr != nil {
+		return s.lastErr
+	}
+	return s.flushLocked()
+}
+
+// flushLocked commits the pending batch. The caller must hold s.mu.
+func (s *FileBlockStore) flushLocked() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	var batch bytes.Buffer
+	recordOffsets := make([]int64, len(s.pending))
+	for i, rec := range s.pending {
+		recordOffsets[i] = s.size + int64(batch.Len())
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return fmt.Errorf("failed to encode block %d: %w", rec.Height, err)
+		}
+		batch.Write(data)
+		batch.WriteByte('\n')
+	}
+
+	if _, err := s.file.Write(batch.Bytes()); err != nil {
+		s.discardUncommitted()
+		return fmt.Errorf("failed to write block batch: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		s.discardUncommitted()
+		return fmt.Errorf("failed to fsync block batch: %w", err)
+	}
+	s.syncs++
+
+	// The batch is durable; publish it to the index.
+	for i, rec := range s.pending {
+		s.offsets = append(s.offsets, recordOffsets[i])
+		s.heights[rec.Hash] = rec.Height
+	}
+	s.size += int64(batch.Len())
+	s.pending = s.pending[:0]
+	return nil
+}
+
+// discardUncommitted drops whatever part of a failed batch reached the file so
+// the log ends at the last durable record. The batch stays pending for a retry.
+func (s *FileBlockStore) discardUncommitted() {
+	s.file.Truncate(s.size)
+	s.file.Seek(s.size, io.SeekStart)
+}
+
+// LoadBlocks flushes pending blocks and returns every stored block in order.
+func (s *FileBlockStore) LoadBlocks() ([]*Block, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.flushLocked(); err != nil {
+		return nil, err
+	}
+	blocks := make([]*Block, 0, len(s.offsets))
+	for height := range s.offsets {
+		block, err := s.readBlockLocked(int64(height))
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// BlockByHeight reads a single durable block using the offset index.
+func (s *FileBlockStore) BlockByHeight(height int64) (*Block, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readBlockLocked(height)
+}
+
+// HeightByHash looks up the height of a durable block by its hash.
+func (s *FileBlockStore) HeightByHash(hash string) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	height, ok := s.heights[hash]
+	return height, ok
+}
+
+// BlockCount returns the number of durable blocks, i.e. one past the highest
+// height BlockByHeight can read.
+func (s *FileBlockStore) BlockCount() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.offsets))
+}
+
+// readBlockLocked decodes the record at height. The caller must hold s.mu.
+func (s *FileBlockStore) readBlockLocked(height int64) (*Block, error) {
+	if height < 0 || height >= int64(len(s.offsets)) {
+		return nil, fmt.Errorf("block %d not found in store", height)
+	}
+	end := s.size
+	if height+1 < int64(len(s.offsets)) {
+		end = s.offsets[height+1]
+	}
+	buf := make([]byte, end-s.offsets[height])
+	if _, err := s.file.ReadAt(buf, s.offsets[height]); err != nil {
+		return nil, fmt.Errorf("failed to read block %d: %w", height, err)
+	}
+	var rec storedBlockRecord
+	if err := json.Unmarshal(buf, &rec); err != nil {
+		return nil, fmt.Errorf("failed to decode block %d: %w", height, err)
+	}
+	return rec.Block, nil
+}
+
+// SyncCount returns the number of fsyncs performed so far.
+func (s *FileBlockStore) SyncCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.syncs
+}
+
+// Close stops background flushing, commits pending blocks and closes the log.
+func (s *FileBlockStore) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flushErr := s.flushLocked()
+	closeErr := s.file.Close()
+	if flushErr != nil {
+		return flushErr
+	}
+	return closeErr
+}
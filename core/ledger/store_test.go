@@ -0,0 +1,272 @@
+package ledger
+
+import (
+	"bytes"
+	"digisocialblock/core/migrations"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileBlockStore_GroupCommit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocks.log")
+	store, err := OpenFileBlockStore(path, FileBlockStoreOptions{FlushInterval: time.Hour, MaxBatchSize: 1000})
+	if err != nil {
+		t.Fatalf("OpenFileBlockStore() error = %v", err)
+	}
+	chain := buildSyntheticChain(t, 50, 1)
+	for _, block := range chain.Blocks {
+		if err := store.AppendBlock(block); err != nil {
+			t.Fatalf("AppendBlock(%d) error = %v", block.Index, err)
+		}
+	}
+	if store.SyncCount() != 0 {
+		t.Fatalf("SyncCount() before flush = %d, want 0 (blocks should be batched)", store.SyncCount())
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if store.SyncCount() != 1 {
+		t.Errorf("SyncCount() after flush = %d, want a single fsync for the batch", store.SyncCount())
+	}
+	if height, ok := store.HeightByHash(chain.Blocks[17].Hash); !ok || height != 17 {
+		t.Errorf("HeightByHash() = %d, %v; want 17, true", height, ok)
+	}
+	if n := store.BlockCount(); n != 50 {
+		t.Errorf("BlockCount() = %d, want 50", n)
+	}
+	store.Close()
+
+	reopened, err := OpenFileBlockStore(path, FileBlockStoreOptions{})
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer reopened.Close()
+	blocks, err := reopened.LoadBlocks()
+	if err != nil || len(blocks) != 50 {
+		t.Fatalf("LoadBlocks() = %d blocks, %v; want 50", len(blocks), err)
+	}
+	if blocks[49].Hash != chain.Blocks[49].Hash {
+		t.Errorf("reloaded tip hash = %s, want %s", blocks[49].Hash, chain.Blocks[49].Hash)
+	}
+}
+
+func TestFileBlockStore_FlushIntervalAndMaxBatch(t *testing.T) {
+	chain := buildSyntheticChain(t, 10, 0)
+
+	store, _ := OpenFileBlockStore(filepath.Join(t.TempDir(), "a.log"), FileBlockStoreOptions{FlushInterval: time.Hour, MaxBatchSize: 4})
+	defer store.Close()
+	for _, block := range chain.Blocks {
+		store.AppendBlock(block)
+	}
+	if store.SyncCount() != 2 {
+		t.Errorf("SyncCount() with MaxBatchSize 4 after 10 blocks = %d, want 2", store.SyncCount())
+	}
+
+	timed, _ := OpenFileBlockStore(filepath.Join(t.TempDir(), "b.log"), FileBlockStoreOptions{FlushInterval: 10 * time.Millisecond})
+	defer timed.Close()
+	timed.AppendBlock(chain.Blocks[0])
+	deadline := time.Now().Add(2 * time.Second)
+	for timed.SyncCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if timed.SyncCount() == 0 {
+		t.Error("pending block was not flushed by the background flush interval")
+	}
+}
+
+func TestFileBlockStore_TruncatesTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocks.log")
+	store, _ := OpenFileBlockStore(path, FileBlockStoreOptions{})
+	chain := buildSyntheticChain(t, 3, 1)
+	for _, block := range chain.Blocks {
+		store.AppendBlock(block)
+	}
+	store.Close()
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"height":3,"hash":"partial`)
+	f.Close()
+
+	reopened, err := OpenFileBlockStore(path, FileBlockStoreOptions{})
+	if err != nil {
+		t.Fatalf("OpenFileBlockStore() on torn log error = %v", err)
+	}
+	defer reopened.Close()
+	blocks, _ := reopened.LoadBlocks()
+	if len(blocks) != 3 {
+		t.Fatalf("LoadBlocks() after torn write = %d blocks, want 3", len(blocks))
+	}
+	if err := reopened.AppendBlock(chain.Blocks[0]); err == nil {
+		t.Error("AppendBlock() with wrong height: expected error, got nil")
+	}
+}
+
+func TestFileBlockStore_FailedFlushDropsRejectedBlock(t *testing.T) {
+	for name, opts := range map[string]FileBlockStoreOptions{
+		"unbatched":  {},
+		"batch full": {FlushInterval: time.Hour, MaxBatchSize: 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "blocks.log")
+			store, _ := OpenFileBlockStore(path, opts)
+			chain := buildSyntheticChain(t, 2, 1)
+			if err := store.AppendBlock(chain.Blocks[0]); err != nil {
+				t.Fatalf("AppendBlock(0) error = %v", err)
+			}
+
+			// A read-only descriptor makes the next flush fail.
+			writable := store.file
+			store.file, _ = os.Open(path)
+			if err := store.AppendBlock(chain.Blocks[1]); err == nil {
+				t.Fatal("AppendBlock(1) with a failing file: expected error, got nil")
+			}
+			store.file.Close()
+			store.file = writable
+
+			if err := store.Flush(); err != nil {
+				t.Fatalf("Flush() after the failure error = %v", err)
+			}
+			if n := store.BlockCount(); n != 1 {
+				t.Fatalf("BlockCount() after the failure = %d, want only block 0 (block 1 was rejected)", n)
+			}
+			if err := store.AppendBlock(chain.Blocks[1]); err != nil {
+				t.Fatalf("AppendBlock(1) retry error = %v", err)
+			}
+			store.Close()
+
+			reopened, _ := OpenFileBlockStore(path, FileBlockStoreOptions{})
+			defer reopened.Close()
+			if blocks, err := reopened.LoadBlocks(); err != nil || len(blocks) != 2 || blocks[1].Hash != chain.Blocks[1].Hash {
+				t.Errorf("LoadBlocks() = %d blocks, %v; want blocks 0 and 1 once each", len(blocks), err)
+			}
+		})
+	}
+}
+
+func TestScanBlockLog_ReportsBadRecordsWithoutModifying(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocks.log")
+	store, _ := OpenFileBlockStore(path, FileBlockStoreOptions{})
+	chain := buildSyntheticChain(t, 3, 1)
+	store.AppendBlock(chain.Blocks[0])
+	store.Close()
+
+	// A corrupt record between two good ones, then a torn tail.
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString("not json\n")
+	f.Close()
+	good, _ := os.ReadFile(path)
+	f, _ = os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.Write(good[:bytes.IndexByte(good, '\n')+1])
+	f.WriteString(`{"height":3,"hash":"partial`)
+	f.Close()
+	before, _ := os.ReadFile(path)
+
+	f, _ = os.Open(path)
+	defer f.Close()
+	var records []BlockLogRecord
+	if err := ScanBlockLog(f, func(rec BlockLogRecord) error {
+		records = append(records, rec)
+		return nil
+	}); err != nil {
+		t.Fatalf("ScanBlockLog() error = %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("ScanBlockLog() passed %d records, want 4", len(records))
+	}
+	if records[0].Err != nil || records[0].Block.Hash != chain.Blocks[0].Hash || records[2].Err != nil {
+		t.Errorf("good records = %+v and %+v", records[0], records[2])
+	}
+	if records[1].Err == nil || records[1].Offset != int64(bytes.IndexByte(good, '\n')+1) {
+		t.Errorf("corrupt record = %+v, want an error at the second line", records[1])
+	}
+	if records[3].Err == nil || !strings.Contains(records[3].Err.Error(), "torn") {
+		t.Errorf("torn tail = %+v, want a torn record error", records[3])
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(before, after) {
+		t.Error("ScanBlockLog() modified the log")
+	}
+
+	stop := errors.New("stop")
+	f.Seek(0, io.SeekStart)
+	if err := ScanBlockLog(f, func(BlockLogRecord) error { return stop }); err != stop {
+		t.Errorf("ScanBlockLog() error = %v, want the callback's error", err)
+	}
+}
+
+func TestNewBlockchainWithStore_PersistsAcrossRestarts(t *testing.T) {
+	priv, addr := newTestKey(t)
+	path := filepath.Join(t.TempDir(), "chain.log")
+
+	store, _ := OpenFileBlockStore(path, FileBlockStoreOptions{FlushInterval: time.Hour})
+	bc, err := NewBlockchainWithStore(store)
+	if err != nil {
+		t.Fatalf("NewBlockchainWithStore() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := bc.AddBlock([]*Transaction{newSignedTestTx(t, priv, addr, fmt.Sprintf("persisted %d", i))}); err != nil {
+			t.Fatalf("AddBlock() error = %v", err)
+		}
+	}
+	tip := bc.GetLatestBlock().Hash
+	store.Close()
+
+	store, _ = OpenFileBlockStore(path, FileBlockStoreOptions{})
+	defer store.Close()
+	restored, err := NewBlockchainWithStore(store)
+	if err != nil {
+		t.Fatalf("NewBlockchainWithStore() on existing store error = %v", err)
+	}
+	if len(restored.Blocks) != 4 || restored.GetLatestBlock().Hash != tip {
+		t.Errorf("restored chain has %d blocks with tip %s, want 4 with tip %s", len(restored.Blocks), restored.GetLatestBlock().Hash, tip)
+	}
+}
+
+// BenchmarkFileBlockStore_Ingest compares per-block fsync with group commit.
+func BenchmarkFileBlockStore_Ingest(b *testing.B) {
+	chain := buildSyntheticChain(b, 1000, 4)
+	for _, opts := range []FileBlockStoreOptions{
+		{},
+		{FlushInterval: 50 * time.Millisecond, MaxBatchSize: 256},
+	} {
+		b.Run(fmt.Sprintf("interval=%s", opts.FlushInterval), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				store, err := OpenFileBlockStore(filepath.Join(b.TempDir(), "bench.log"), opts)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for _, block := range chain.Blocks {
+					if err := store.AppendBlock(block); err != nil {
+						b.Fatal(err)
+					}
+				}
+				store.Close()
+			}
+		})
+	}
+}
+
+func TestOpenFileBlockStore_RefusesNewerFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocks.log")
+	store, err := OpenFileBlockStore(path, FileBlockStoreOptions{})
+	if err != nil {
+		t.Fatalf("OpenFileBlockStore() error = %v", err)
+	}
+	store.Close()
+	if v, err := migrations.ReadVersion(path, BlockLogSchema); err != nil || v != BlockLogSchema.Current {
+		t.Fatalf("ReadVersion() = %d, %v; want %d", v, err, BlockLogSchema.Current)
+	}
+
+	newer := `{"schema":"ledger.blocklog","version":` + fmt.Sprint(BlockLogSchema.Current+1) + `}`
+	if err := os.WriteFile(migrations.VersionPath(path), []byte(newer), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenFileBlockStore(path, FileBlockStoreOptions{}); !errors.Is(err, migrations.ErrNewerFormat) {
+		t.Errorf("OpenFileBlockStore() of a newer log error = %v, want ErrNewerFormat", err)
+	}
+}
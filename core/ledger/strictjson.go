@@ -0,0 +1,119 @@
+package ledger
+
+import (
+	"bytes"
+	"digisocialblock/core/errcode"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// Limits applied by DecodePayloadJSON to attacker-controlled JSON from the chain.
+const (
+	MaxJSONPayloadSize = 64 << 10 // Upper bound regardless of per-type limits
+	MaxJSONDepth       = 16       // Maximum nesting of objects and arrays
+)
+
+// ErrMalformedJSON is returned by DecodePayloadJSON for payloads that are not
+// a single, well-formed, unambiguous JSON value within the limits.
+var ErrMalformedJSON = errcode.New(CodeMalformedJSON, "malformed JSON payload")
+
+// DecodePayloadJSON strictly decodes a JSON payload into v. Payloads are parsed
+// from untrusted chain data, so beyond the standard decoder it rejects:
+//   - payloads larger than MaxJSONPayloadSize or nested deeper than MaxJSONDepth,
+//   - invalid UTF-8 (which encoding/json would silently replace),
+//   - duplicate object keys (which encoding/json resolves last-wins),
+//   - unknown fields and trailing data after the JSON value.
+//
+// Together these make decoding deterministic: every accepted payload has
+// exactly one meaning.
+func DecodePayloadJSON(payload []byte, v interface{}) error {
+	if len(payload) > MaxJSONPayloadSize {
+		return fmt.Errorf("%w: %d bytes exceeds limit of %d", ErrMalformedJSON, len(payload), MaxJSONPayloadSize)
+	}
+	if !utf8.Valid(payload) {
+		return fmt.Errorf("%w: invalid UTF-8", ErrMalformedJSON)
+	}
+	if err := checkJSONStructure(payload); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformedJSON, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkJSONStructure walks the token stream once, enforcing the depth limit,
+// rejecting duplicate keys and requiring exactly one top-level value.
+func checkJSONStructure(payload []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+
+	// One entry per open container; objects track the keys seen so far.
+	type frame struct {
+		keys      map[string]struct{}
+		expectKey bool
+	}
+	var stack []*frame
+	values := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		var top *frame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+
+		// Inside an object, tokens alternate between keys and values.
+		if top != nil && top.keys != nil && top.expectKey {
+			if delim, ok := tok.(json.Delim); ok && delim == '}' {
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			key := tok.(string)
+			if _, dup := top.keys[key]; dup {
+				return fmt.Errorf("duplicate key %q", key)
+			}
+			top.keys[key] = struct{}{}
+			top.expectKey = false
+			continue
+		}
+		if top != nil && top.keys != nil {
+			top.expectKey = true // This token is the value; the next one is a key
+		}
+		if len(stack) == 0 {
+			values++
+			if values > 1 {
+				return fmt.Errorf("unexpected data after JSON value")
+			}
+		}
+
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			if len(stack) >= MaxJSONDepth {
+				return fmt.Errorf("nesting exceeds depth %d", MaxJSONDepth)
+			}
+			f := &frame{}
+			if tok == json.Delim('{') {
+				f.keys = make(map[string]struct{})
+				f.expectKey = true
+			}
+			stack = append(stack, f)
+		case json.Delim(']'):
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if values == 0 {
+		return fmt.Errorf("empty payload")
+	}
+	return nil
+}
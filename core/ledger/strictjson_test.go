@@ -0,0 +1,60 @@
+package ledger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type strictTestPayload struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags,omitempty"`
+	Meta *struct {
+		Note string `json:"note"`
+	} `json:"meta,omitempty"`
+}
+
+func TestDecodePayloadJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		payload   string
+		wantErr   bool
+		malformed bool // Expect ErrMalformedJSON specifically
+	}{
+		{"valid", `{"name":"a","tags":["x","y"],"meta":{"note":"n"}}`, false, false},
+		{"valid with whitespace", " {\n \"name\": \"a\"\n}\n", false, false},
+		{"unknown field", `{"name":"a","admin":true}`, true, false},
+		{"duplicate key", `{"name":"a","name":"b"}`, true, true},
+		{"duplicate nested key", `{"name":"a","meta":{"note":"x","note":"y"}}`, true, true},
+		{"same key in sibling objects", `{"name":"a","meta":{"name":"x"}}`, true, false}, // Unknown field, not a duplicate
+		{"trailing value", `{"name":"a"} {"name":"b"}`, true, true},
+		{"trailing garbage", `{"name":"a"} x`, true, true},
+		{"invalid UTF-8", "{\"name\":\"\xff\"}", true, true},
+		{"too deep", strings.Repeat("[", MaxJSONDepth+1) + strings.Repeat("]", MaxJSONDepth+1), true, true},
+		{"too large", `{"name":"` + strings.Repeat("a", MaxJSONPayloadSize) + `"}`, true, true},
+		{"empty", ``, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v strictTestPayload
+			err := DecodePayloadJSON([]byte(tt.payload), &v)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodePayloadJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.malformed && !errors.Is(err, ErrMalformedJSON) {
+				t.Errorf("DecodePayloadJSON() error = %v, want ErrMalformedJSON", err)
+			}
+		})
+	}
+}
+
+func FuzzDecodePayloadJSON(f *testing.F) {
+	f.Add([]byte(`{"name":"a","tags":["x"],"meta":{"note":"n"}}`))
+	f.Add([]byte(`{"name":"a","name":"b"}`))
+	f.Add([]byte(`[[[[{}]]]]`))
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		var v strictTestPayload
+		// Must never panic, regardless of input.
+		DecodePayloadJSON(payload, &v)
+	})
+}
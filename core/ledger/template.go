@@ -0,0 +1,124 @@
+package ledger
+
+import (
+	"digisocialblock/core/errcode"
+	"fmt"
+	"net/http"
+)
+
+// CodeStaleBlock is the error code of ErrStaleBlock.
+var CodeStaleBlock = errcode.Register("DSB-LEDGER-018", http.StatusConflict, "block does not extend the chain's tip")
+
+// ErrStaleBlock is returned by SubmitSealedBlock for a block built on a block
+// other than the chain's tip, typically from a template the chain has since
+// moved past.
+var ErrStaleBlock = errcode.New(CodeStaleBlock, "block does not extend the chain's tip")
+
+// BlockTemplate is an unsealed block: the next block's position and contents,
+// chosen by the chain, for a consensus engine or miner outside the node to
+// seal and hand back through SubmitSealedBlock. Sealing picks the block's
+// timestamp and so fixes its hash.
+type BlockTemplate struct {
+	Index         int64          `json:"index"`
+	PrevBlockHash string         `json:"prevBlockHash"`
+	MerkleRoot    string         `json:"merkleRoot"`   // MerkleRoot of the transaction IDs
+	MinTimestamp  int64          `json:"minTimestamp"` // Earliest timestamp, in Unix nanoseconds, the sealed block may have
+	Transactions  []*Transaction `json:"transactions"`
+	Bytes         int            `json:"bytes"` // JSON-encoded size of Transactions
+}
+
+// BuildBlockTemplate returns a template for the block after the chain's tip,
+// holding the pending transactions of mp, in the order Pending returns them,
+// that together take at most maxBytes encoded as JSON and fit the protocol
+// parameters at its height (see ParamsAt). A transaction is left out if it
+// does not fit or if the chain would refuse it after those already chosen,
+// like a transfer its sender can no longer cover; mp is not changed.
+//
+// External block producers take the place of a Producer: run one or the
+// other against a chain, not both.
+func (bc *Blockchain) BuildBlockTemplate(mp *Mempool, maxBytes int) (*BlockTemplate, error) {
+	if mp == nil {
+		return nil, fmt.Errorf("mempool is required")
+	}
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("block template byte limit must be positive, got %d", maxBytes)
+	}
+	pending := mp.Pending()
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if len(bc.Blocks) == 0 {
+		return nil, fmt.Errorf("blockchain is not initialized with a genesis block")
+	}
+	tip := bc.Blocks[len(bc.Blocks)-1]
+	t := &BlockTemplate{
+		Index:         tip.Index + 1,
+		PrevBlockHash: tip.Hash,
+		MinTimestamp:  tip.Timestamp + 1,
+		Transactions:  []*Transaction{},
+	}
+	params := paramsAt(bc.params, t.Index)
+	if params.MaxBlockBytes > 0 && params.MaxBlockBytes < maxBytes {
+		maxBytes = params.MaxBlockBytes
+	}
+	for _, tx := range pending {
+		if params.MaxBlockTransactions > 0 && len(t.Transactions) == params.MaxBlockTransactions {
+			break
+		}
+		size := transactionBytes(tx)
+		if t.Bytes+size > maxBytes {
+			continue
+		}
+		chosen := append(t.Transactions[:len(t.Transactions):len(t.Transactions)], tx)
+		if bc.checkCandidateLocked(chosen) != nil {
+			continue
+		}
+		t.Transactions = chosen
+		t.Bytes += size
+	}
+	var txHashes []string
+	if len(t.Transactions) > 0 {
+		txHashes = GetTransactionHashes(t.Transactions)
+	}
+	t.MerkleRoot = MerkleRoot(txHashes)
+	return t, nil
+}
+
+// Seal returns the block the template describes, stamped with timestamp in
+// Unix nanoseconds.
+func (t *BlockTemplate) Seal(timestamp int64) (*Block, error) {
+	if timestamp < t.MinTimestamp {
+		return nil, fmt.Errorf("block timestamp %d is before the template's minimum %d", timestamp, t.MinTimestamp)
+	}
+	return &Block{
+		Index:         t.Index,
+		Timestamp:     timestamp,
+		Transactions:  append([]*Transaction{}, t.Transactions...),
+		PrevBlockHash: t.PrevBlockHash,
+		Hash:          HashBlockContent(t.Index, timestamp, t.PrevBlockHash, t.MerkleRoot),
+	}, nil
+}
+
+// SubmitSealedBlock appends a block sealed from a BlockTemplate, checking it
+// as AppendBlock does, and removes its transactions from mp, which may be
+// nil. A block that no longer extends the tip fails with ErrStaleBlock; one
+// with a transaction the chain refuses fails with a Rejection naming it.
+func (bc *Blockchain) SubmitSealedBlock(mp *Mempool, block *Block) error {
+	if block == nil {
+		return fmt.Errorf("cannot submit a nil block")
+	}
+	if err := bc.AppendBlock(block); err != nil {
+		if tip := bc.GetLatestBlock(); tip != nil && !hashesEqual(block.PrevBlockHash, tip.Hash) {
+			return fmt.Errorf("%w: block %d follows %s, but the tip is block %d %s", ErrStaleBlock, block.Index, block.PrevBlockHash, tip.Index, tip.Hash)
+		}
+		return err
+	}
+	if mp != nil {
+		ids := make([]string, len(block.Transactions))
+		for i, tx := range block.Transactions {
+			ids[i] = tx.ID
+		}
+		mp.Remove(ids...)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBlockchain_BlockTemplateSealAndSubmit(t *testing.T) {
+	bc, mempool, _, clock := producerTestSetup(t, ProducerOptions{})
+	priv, addr := newTestKey(t)
+	_, bob := newTestKey(t)
+	one := newSignedTestTx(t, priv, addr, "one")
+	overdraft := transferTestTx(t, priv, addr, bob, 20)
+	large := newSignedTestTx(t, priv, addr, strings.Repeat("x", 2000))
+	two := newSignedTestTx(t, priv, addr, "two")
+	maxBytes := 0
+	for _, tx := range []*Transaction{one, overdraft, large, two} {
+		if err := mempool.Add(tx); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if tx == one || tx == two {
+			encoded, _ := json.Marshal(tx)
+			maxBytes += len(encoded)
+		}
+	}
+
+	if _, err := bc.BuildBlockTemplate(mempool, 0); err == nil {
+		t.Error("BuildBlockTemplate() accepted a zero byte limit")
+	}
+	tmpl, err := bc.BuildBlockTemplate(mempool, maxBytes)
+	if err != nil {
+		t.Fatalf("BuildBlockTemplate() error = %v", err)
+	}
+	tip := bc.GetLatestBlock()
+	if tmpl.Index != tip.Index+1 || tmpl.PrevBlockHash != tip.Hash || tmpl.Bytes != maxBytes ||
+		len(tmpl.Transactions) != 2 || tmpl.Transactions[0] != one || tmpl.Transactions[1] != two {
+		t.Fatalf("BuildBlockTemplate() = %+v, want the two posts that fit after the tip", tmpl)
+	}
+	if mempool.Size() != 4 {
+		t.Errorf("mempool holds %d transactions after building a template, want 4", mempool.Size())
+	}
+
+	if _, err := tmpl.Seal(tmpl.MinTimestamp - 1); err == nil {
+		t.Error("Seal() accepted a timestamp before the template's minimum")
+	}
+	block, err := tmpl.Seal(clock.Now().UnixNano())
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if h := block.Header(); h.MerkleRoot != tmpl.MerkleRoot || h.Verify() != nil {
+		t.Errorf("sealed block header %+v does not match the template", h)
+	}
+	if err := bc.SubmitSealedBlock(mempool, block); err != nil {
+		t.Fatalf("SubmitSealedBlock() error = %v", err)
+	}
+	if bc.GetLatestBlock() != block || mempool.Size() != 2 {
+		t.Errorf("after SubmitSealedBlock() tip = %v, mempool size %d; want the block appended and its transactions removed", bc.GetLatestBlock().Index, mempool.Size())
+	}
+
+	// A block sealed from a template the chain has moved past is stale.
+	stale, _ := tmpl.Seal(clock.Now().UnixNano())
+	if err := bc.SubmitSealedBlock(mempool, stale); !errors.Is(err, ErrStaleBlock) {
+		t.Errorf("SubmitSealedBlock() of a stale block error = %v, want ErrStaleBlock", err)
+	}
+}
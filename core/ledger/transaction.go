@@ -2,15 +2,37 @@ package ledger
 
 import (
 	"crypto/ecdsa"
-	"digisocialblock/core/identity" // Assuming this path for identity package
+	"crypto/rand"
+	"digisocialblock/core/clientkit"
+	"digisocialblock/core/errcode"
 	"fmt"
-	"time"
 )
 
+// DefaultChainID identifies the main Digisocialblock chain. It is committed to
+// by every transaction signature so signatures cannot be replayed across chains.
+const DefaultChainID = clientkit.DefaultChainID
+
+// ErrLegacySignature is returned when verifying a transaction signed with the
+// legacy (version 0) scheme, which signed the bare transaction ID and could be
+// replayed as a signature in any other context.
+var ErrLegacySignature = errcode.New(CodeLegacySignature, "legacy transaction signature is not domain-separated")
+
+// ErrTransactionIDMismatch is returned when a transaction's ID is not the hash
+// of its content, i.e. the content was changed after the ID was computed.
+var ErrTransactionIDMismatch = errcode.New(CodeTransactionIDMismatch, "transaction ID does not match its content")
+
 // NewTransaction creates a new transaction with the given parameters.
 // The ID is generated by hashing the core content (timestamp, sender, type, payload).
 // The signature is initially nil and should be set by calling Sign.
 func NewTransaction(senderPublicKey string, txType TransactionType, payload []byte) (*Transaction, error) {
+	return NewTransactionWithClock(SystemClock, senderPublicKey, txType, payload)
+}
+
+// NewTransactionWithClock is NewTransaction with the transaction stamped by
+// clock. Two transactions with the same sender, type, payload and time have
+// the same ID, so a clock under test control should not repeat a time for
+// transactions that must differ.
+func NewTransactionWithClock(clock Clock, senderPublicKey string, txType TransactionType, payload []byte) (*Transaction, error) {
 	if senderPublicKey == "" {
 		return nil, fmt.Errorf("sender public key cannot be empty")
 	}
@@ -18,13 +40,14 @@ func NewTransaction(senderPublicKey string, txType TransactionType, payload []by
 		return nil, fmt.Errorf("transaction type cannot be empty")
 	}
 
-	ts := time.Now().UnixNano()
+	ts := clock.Now().UnixNano()
 	tx := &Transaction{
 		Timestamp:       ts,
-		SenderPublicKey: senderPublicKey, // This is the hex string address from identity.PublicKeyToAddress
+		SenderPublicKey: senderPublicKey, // This is the hex string address from clientkit.Address
 		Type:            txType,
 		Payload:         payload,
 		Signature:       nil, // Signature to be added later
+		ChainID:         DefaultChainID,
 	}
 
 	// Calculate ID based on content (excluding signature and ID itself)
@@ -32,8 +55,21 @@ func NewTransaction(senderPublicKey string, txType TransactionType, payload []by
 	return tx, nil
 }
 
+// SigningDigest returns the digest a signature over this transaction must cover:
+// the transaction ID, domain-separated for transactions and bound to tx.ChainID
+// under the scheme given by tx.SigVersion.
+func (tx *Transaction) SigningDigest() ([]byte, error) {
+	if tx.ID == "" {
+		return nil, fmt.Errorf("transaction ID is empty, cannot determine data to sign")
+	}
+	if tx.SigVersion == 0 {
+		return nil, ErrLegacySignature
+	}
+	return clientkit.DomainDigest(clientkit.DomainTransaction, tx.SigVersion, tx.ChainID, []byte(tx.ID))
+}
+
 // Sign populates the Signature field of the transaction using a provided ECDSA private key.
-// The data signed is the transaction ID (which is a hash of its content).
+// The transaction is signed with the current domain-separated scheme (see SigningDigest).
 func (tx *Transaction) Sign(privateKey *ecdsa.PrivateKey) error {
 	if tx.ID == "" {
 		return fmt.Errorf("transaction ID is empty, cannot sign")
@@ -42,11 +78,15 @@ func (tx *Transaction) Sign(privateKey *ecdsa.PrivateKey) error {
 		return fmt.Errorf("private key is nil, cannot sign")
 	}
 
-	dataToSign := []byte(tx.ID) // Hash of the transaction content (its ID)
+	tx.SigVersion = clientkit.CurrentSignatureVersion
+	dataToSign, err := tx.SigningDigest()
+	if err != nil {
+		return fmt.Errorf("failed to compute signing digest: %w", err)
+	}
 
-	// ecdsa.SignASN1 signs a hash (which tx.ID is) and returns the ASN.1 DER encoded signature.
+	// ecdsa.SignASN1 signs a hash and returns the ASN.1 DER encoded signature.
 	// This is a common way to store ECDSA signatures.
-	signature, err := ecdsa.SignASN1(identity.GetRandReader(), privateKey, dataToSign)
+	signature, err := ecdsa.SignASN1(rand.Reader, privateKey, dataToSign)
 	if err != nil {
 		return fmt.Errorf("failed to sign transaction data: %w", err)
 	}
@@ -54,58 +94,87 @@ func (tx *Transaction) Sign(privateKey *ecdsa.PrivateKey) error {
 	return nil
 }
 
-// VerifySignature checks if the transaction's signature is valid against its content (ID)
-// and the sender's public key.
+// VerifySignature checks if the transaction's signature is valid against its content (ID),
+// chain ID and the sender's public key, and that it carries the co-signatures
+// its payload requires (see RegisterCoSigners). Legacy (version 0) signatures
+// are rejected with ErrLegacySignature. A transaction with a Delegate is
+// verified against the delegate's key instead; whether the delegate may sign
+// for the sender is checked by the chain (see KeyDelegated).
 func (tx *Transaction) VerifySignature() (bool, error) {
 	if tx.ID == "" {
-		return false, fmt.Errorf("transaction ID is empty, cannot verify signature")
+		return false, fmt.Errorf("%w: transaction ID is empty, cannot verify signature", ErrMalformedTransaction)
 	}
 	if tx.SenderPublicKey == "" {
-		return false, fmt.Errorf("sender public key (address) is empty, cannot verify signature")
+		return false, fmt.Errorf("%w: sender public key (address) is empty, cannot verify signature", ErrMalformedTransaction)
 	}
 	if len(tx.Signature) == 0 {
-		return false, fmt.Errorf("signature is empty, cannot verify")
+		return false, fmt.Errorf("%w: signature is empty", ErrInvalidSignature)
 	}
 
 	// Convert the hex-encoded public key string (address) back to an *ecdsa.PublicKey
-	publicKey, err := identity.AddressToPublicKey(tx.SenderPublicKey)
+	signer := tx.SenderPublicKey
+	if tx.Delegate != "" {
+		signer = tx.Delegate
+	}
+	publicKey, err := clientkit.ParseAddress(signer)
 	if err != nil {
-		return false, fmt.Errorf("failed to parse sender public key from address '%s': %w", tx.SenderPublicKey, err)
+		return false, fmt.Errorf("%w: failed to parse signer public key from address '%s': %w", ErrInvalidSignature, signer, err)
 	}
 
-	dataToVerify := []byte(tx.ID) // The data that was signed is the transaction ID
+	// The signed digest depends on the scheme the transaction declares.
+	dataToVerify, err := tx.SigningDigest()
+	if err != nil {
+		return false, err
+	}
 
 	// ecdsa.VerifyASN1 verifies an ASN.1 DER encoded signature.
 	isValid := ecdsa.VerifyASN1(publicKey, dataToVerify, tx.Signature)
 	if !isValid {
-		return false, fmt.Errorf("ECDSA signature verification failed") // Specific error for failed verification
+		return false, fmt.Errorf("%w: ECDSA signature verification failed", ErrInvalidSignature)
+	}
+	if err := tx.verifyCoSignatures(); err != nil {
+		return false, err
 	}
 	return true, nil
 }
 
 
-// IsValid performs basic validation checks on the transaction.
+// IsValid performs basic validation checks on the transaction, including the
+// payload limits and schema for its type (see ValidatePayload) and that the ID
+// is the canonical hash of the content (see VerifyIntegrity).
 // This does not include signature verification here, as that might be context-dependent
 // (e.g., you might validate structure before bothering with crypto).
 func (tx *Transaction) IsValid() error {
 	if tx.ID == "" {
-		return fmt.Errorf("transaction has empty ID")
-	}
-	// Check if calculated ID matches the current content (if ID was pre-set and content changed)
-	// This is more of an internal consistency check if the tx was mutated after ID calculation.
-	// currentContentHash := HashTransactionContent(tx.Timestamp, tx.SenderPublicKey, tx.Type, tx.Payload)
-	// if tx.ID != currentContentHash {
-	// 	return fmt.Errorf("transaction ID mismatch: recorded %s, calculated %s", tx.ID, currentContentHash)
-	// }
+		return fmt.Errorf("%w: empty ID", ErrMalformedTransaction)
+	}
 	if tx.Timestamp <= 0 {
-		return fmt.Errorf("transaction has invalid timestamp: %d", tx.Timestamp)
+		return fmt.Errorf("%w: invalid timestamp %d", ErrMalformedTransaction, tx.Timestamp)
 	}
 	if tx.SenderPublicKey == "" {
-		return fmt.Errorf("transaction has empty sender public key")
+		return fmt.Errorf("%w: empty sender public key", ErrMalformedTransaction)
 	}
 	if tx.Type == "" {
-		return fmt.Errorf("transaction has empty type")
+		return fmt.Errorf("%w: empty type", ErrMalformedTransaction)
+	}
+	if tx.Delegate == tx.SenderPublicKey {
+		return fmt.Errorf("%w: sender named as its own delegate", ErrMalformedTransaction)
 	}
 	// Payload can be empty for certain transaction types, so not checking len(tx.Payload) == 0 by default.
+	if err := tx.ValidatePayload(); err != nil {
+		return err
+	}
+	return tx.VerifyIntegrity()
+}
+
+// VerifyIntegrity recomputes the transaction ID from its content and compares
+// it with the recorded ID. Because signatures cover the ID rather than the
+// content directly, this is what binds a valid signature to the payload: a
+// tampered payload carrying the original ID and signature fails here.
+func (tx *Transaction) VerifyIntegrity() error {
+	currentContentHash := HashTransactionContent(tx.Timestamp, tx.SenderPublicKey, tx.Type, tx.Payload)
+	if !hashesEqual(tx.ID, currentContentHash) {
+		return fmt.Errorf("%w: recorded %s, calculated %s", ErrTransactionIDMismatch, tx.ID, currentContentHash)
+	}
 	return nil
 }
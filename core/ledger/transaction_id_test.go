@@ -0,0 +1,27 @@
+package ledger
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTransaction_IsValidRecomputesID(t *testing.T) {
+	priv, addr := newTestKey(t)
+	tx := newSignedTestTx(t, priv, addr, "good payload")
+	if err := tx.IsValid(); err != nil {
+		t.Fatalf("IsValid() of an untouched transaction error = %v", err)
+	}
+
+	tamperedPayload := *tx
+	tamperedPayload.Payload = []byte("evil payload")
+	tamperedTimestamp := *tx
+	tamperedTimestamp.Timestamp++
+	for name, tampered := range map[string]*Transaction{
+		"tampered payload with original ID":   &tamperedPayload,
+		"tampered timestamp with original ID": &tamperedTimestamp,
+	} {
+		if err := tampered.IsValid(); !errors.Is(err, ErrTransactionIDMismatch) {
+			t.Errorf("%s: IsValid() error = %v, want ErrTransactionIDMismatch", name, err)
+		}
+	}
+}
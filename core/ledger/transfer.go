@@ -0,0 +1,254 @@
+package ledger
+
+import (
+	"digisocialblock/core/errcode"
+	"fmt"
+	"math"
+	"unicode/utf8"
+)
+
+// Limits on Transfer payload fields.
+const (
+	MaxTransferMemoLength = 280 // Characters
+	MaxTransferRefLength  = 128 // Bytes; a post CID
+)
+
+// ErrInsufficientBalance is returned when a block would make an account's
+// balance negative.
+var ErrInsufficientBalance = errcode.New(CodeInsufficientBalance, "transfer exceeds the sender's balance")
+
+func init() {
+	RegisterPayloadValidator(Transfer, ValidateTransferPayload)
+	RegisterPayloadDecoder(Transfer, DecodeInto(func() interface{} { return &TransferPayload{} }))
+}
+
+// TransferPayload moves Amount from the transaction's sender to Recipient.
+// Balances are whole units; the ledger has no fractional amounts.
+type TransferPayload struct {
+	Recipient string `json:"recipient"`
+	Amount    uint64 `json:"amount"`
+	PostCID   string `json:"postCID,omitempty"` // Set for a tip on the post with this content CID
+	Memo      string `json:"memo,omitempty"`
+	Timestamp int64  `json:"timestamp"` // UnixNano; distinguishes otherwise identical transfers
+}
+
+// Validate checks that required fields are set and all fields are within limits.
+func (p *TransferPayload) Validate() error {
+	if p.Recipient == "" {
+		return fmt.Errorf("empty Recipient")
+	}
+	if p.Amount == 0 {
+		return fmt.Errorf("zero amount")
+	}
+	if len(p.PostCID) > MaxTransferRefLength {
+		return fmt.Errorf("PostCID is %d bytes, limit %d", len(p.PostCID), MaxTransferRefLength)
+	}
+	if n := utf8.RuneCountInString(p.Memo); n > MaxTransferMemoLength {
+		return fmt.Errorf("memo is %d characters, limit %d", n, MaxTransferMemoLength)
+	}
+	if p.Timestamp == 0 {
+		return fmt.Errorf("zero timestamp")
+	}
+	return nil
+}
+
+// ToPayload serializes the transfer as a Transfer transaction payload in the
+// given format.
+func (p *TransferPayload) ToPayload(format PayloadFormat) ([]byte, error) {
+	payload, err := EncodePayload(format, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transfer payload: %w", err)
+	}
+	return payload, nil
+}
+
+// TransferFromPayload decodes a Transfer payload in either payload format.
+// The result must pass Validate.
+func TransferFromPayload(payload []byte) (*TransferPayload, error) {
+	var p TransferPayload
+	if err := DecodePayload(payload, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode transfer payload: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("decoded transfer is invalid: %w", err)
+	}
+	return &p, nil
+}
+
+// ValidateTransferPayload is the schema validator for Transfer payloads.
+func ValidateTransferPayload(payload []byte) error {
+	_, err := TransferFromPayload(payload)
+	return err
+}
+
+// NewTransferTransaction creates an unsigned Transfer of amount from sender to
+// recipient, stamped by clock (SystemClock if nil). postCID and memo may be
+// empty.
+func NewTransferTransaction(clock Clock, sender, recipient string, amount uint64, postCID, memo string) (*Transaction, error) {
+	if clock == nil {
+		clock = SystemClock
+	}
+	if recipient == sender {
+		return nil, fmt.Errorf("cannot transfer to the sender")
+	}
+	p := &TransferPayload{Recipient: recipient, Amount: amount, PostCID: postCID, Memo: memo, Timestamp: clock.Now().UnixNano()}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid transfer: %w", err)
+	}
+	payload, err := p.ToPayload(PayloadFormatCBOR)
+	if err != nil {
+		return nil, err
+	}
+	return NewTransactionWithClock(clock, sender, Transfer, payload)
+}
+
+// balanceChanges holds the balances a batch of transactions would leave the
+// accounts it touches with, on top of the chain's committed balances.
+type balanceChanges map[string]uint64
+
+// applyTransfers computes the balances after txs on top of balances. It fails
+// with ErrInsufficientBalance if any transfer, applied in order, overdraws its
+// sender, so funds received earlier in the same batch may be spent.
+func applyTransfers(balances map[string]uint64, txs []*Transaction) (balanceChanges, error) {
+	changes := make(balanceChanges)
+	balance := func(address string) uint64 {
+		if b, ok := changes[address]; ok {
+			return b
+		}
+		return balances[address]
+	}
+	for i, tx := range txs {
+		if tx == nil || tx.Type != Transfer {
+			continue
+		}
+		p, err := TransferFromPayload(tx.Payload)
+		if err != nil {
+			return nil, reject(i, tx, fmt.Errorf("%w: transfer %s: %v", ErrInvalidPayload, tx.ID, err))
+		}
+		if p.Recipient == tx.SenderPublicKey {
+			return nil, reject(i, tx, fmt.Errorf("%w: transfer %s is to its own sender", ErrInvalidPayload, tx.ID))
+		}
+		from, to := balance(tx.SenderPublicKey), balance(p.Recipient)
+		if from < p.Amount {
+			return nil, reject(i, tx, fmt.Errorf("%w: transfer %s of %d from %s, balance %d", ErrInsufficientBalance, tx.ID, p.Amount, tx.SenderPublicKey, from))
+		}
+		if to > math.MaxUint64-p.Amount {
+			return nil, reject(i, tx, fmt.Errorf("%w: transfer %s overflows the balance of %s", ErrInvalidPayload, tx.ID, p.Recipient))
+		}
+		changes[tx.SenderPublicKey] = from - p.Amount
+		changes[p.Recipient] = to + p.Amount
+	}
+	return changes, nil
+}
+
+// balancesLocked returns the balances as of the latest block, replaying the
+// chain first if they have not been computed yet (e.g. for a chain loaded from
+// a store). The caller must hold bc.mu.
+func (bc *Blockchain) balancesLocked() (map[string]uint64, error) {
+	if bc.balances == nil {
+		if err := bc.replayBalancesLocked(); err != nil {
+			return nil, err
+		}
+	}
+	return bc.balances, nil
+}
+
+// checkTransfersLocked returns the balance changes of appending a block with
+// txs. The caller must hold bc.mu.
+func (bc *Blockchain) checkTransfersLocked(txs []*Transaction) (balanceChanges, error) {
+	balances, err := bc.balancesLocked()
+	if err != nil {
+		return nil, err
+	}
+	return applyTransfers(balances, txs)
+}
+
+// commitBalances applies changes to the chain's balances. The caller must hold
+// bc.mu and have computed changes with checkTransfersLocked.
+func (bc *Blockchain) commitBalances(changes balanceChanges) {
+	for address, b := range changes {
+		if b == 0 {
+			delete(bc.balances, address)
+		} else {
+			bc.balances[address] = b
+		}
+	}
+}
+
+// replayBalancesLocked recomputes the balances from the genesis allocations
+// and every block in the chain. On failure the balances are left uncomputed.
+// The caller must hold bc.mu.
+func (bc *Blockchain) replayBalancesLocked() error {
+	bc.balances = make(map[string]uint64, len(bc.allocations))
+	for address, amount := range bc.allocations {
+		if amount > 0 {
+			bc.balances[address] = amount
+		}
+	}
+	for _, block := range bc.Blocks {
+		changes, err := applyTransfers(bc.balances, block.Transactions)
+		if err != nil {
+			bc.balances = nil
+			return fmt.Errorf("block %d: %w", block.Index, err)
+		}
+		bc.commitBalances(changes)
+	}
+	return nil
+}
+
+// SetGenesisAllocations sets the balances accounts start with before the
+// first block, and recomputes the current balances by replaying the chain.
+// Like the chain ID, the allocations are a parameter of the network that every
+// node must agree on; they are not stored with the chain, so a node must set them
+// again after opening a stored chain. It fails, keeping the previous
+// allocations, if the chain overdraws an account under the new ones.
+func (bc *Blockchain) SetGenesisAllocations(allocations map[string]uint64) error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	previous := bc.allocations
+	bc.allocations = make(map[string]uint64, len(allocations))
+	for address, amount := range allocations {
+		bc.allocations[address] = amount
+	}
+	if err := bc.replayBalancesLocked(); err != nil {
+		bc.allocations = previous
+		return err
+	}
+	return nil
+}
+
+// GenesisAllocations returns a copy of the allocations set with
+// SetGenesisAllocations.
+func (bc *Blockchain) GenesisAllocations() map[string]uint64 {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	allocations := make(map[string]uint64, len(bc.allocations))
+	for address, amount := range bc.allocations {
+		allocations[address] = amount
+	}
+	return allocations
+}
+
+// Balance returns the balance of address as of the latest block. It is zero
+// if the chain overdraws an account under its genesis allocations.
+func (bc *Blockchain) Balance(address string) uint64 {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	balances, _ := bc.balancesLocked()
+	return balances[address]
+}
+
+// Balances returns a copy of every non-zero balance as of the latest block.
+func (bc *Blockchain) Balances() (map[string]uint64, error) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	current, err := bc.balancesLocked()
+	if err != nil {
+		return nil, err
+	}
+	balances := make(map[string]uint64, len(current))
+	for address, b := range current {
+		balances[address] = b
+	}
+	return balances, nil
+}
@@ -0,0 +1,94 @@
+package ledger
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// transferTestTx returns a transfer of amount from priv's address to to,
+// signed by priv.
+func transferTestTx(t *testing.T, priv *ecdsa.PrivateKey, from, to string, amount uint64) *Transaction {
+	t.Helper()
+	tx, err := NewTransferTransaction(nil, from, to, amount, "", "")
+	if err != nil {
+		t.Fatalf("NewTransferTransaction() error = %v", err)
+	}
+	if err := tx.Sign(priv); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	return tx
+}
+
+func TestTransferPayload_Validation(t *testing.T) {
+	for name, p := range map[string]TransferPayload{
+		"no recipient": {Amount: 1, Timestamp: 1},
+		"zero amount":  {Recipient: "bob", Timestamp: 1},
+		"no instant":   {Recipient: "bob", Amount: 1},
+		"long memo":    {Recipient: "bob", Amount: 1, Timestamp: 1, Memo: string(make([]rune, MaxTransferMemoLength+1))},
+	} {
+		if err := p.Validate(); err == nil {
+			t.Errorf("%s: Validate() expected error, got nil", name)
+		}
+	}
+	if _, err := NewTransferTransaction(nil, "alice", "alice", 1, "", ""); err == nil {
+		t.Error("NewTransferTransaction() to the sender: expected error, got nil")
+	}
+}
+
+func TestBlockchain_TransfersTrackBalancesAndRejectOverdrafts(t *testing.T) {
+	alicePriv, alice := newTestKey(t)
+	bobPriv, bob := newTestKey(t)
+	_, carol := newTestKey(t)
+	path := filepath.Join(t.TempDir(), "chain.log")
+	store, _ := OpenFileBlockStore(path, FileBlockStoreOptions{FlushInterval: time.Hour})
+	bc, err := NewBlockchainWithStore(store)
+	if err != nil {
+		t.Fatalf("NewBlockchainWithStore() error = %v", err)
+	}
+	allocations := map[string]uint64{alice: 100}
+	if err := bc.SetGenesisAllocations(allocations); err != nil {
+		t.Fatalf("SetGenesisAllocations() error = %v", err)
+	}
+
+	// Bob may spend funds received earlier in the same block.
+	if _, err := bc.AddBlock([]*Transaction{
+		transferTestTx(t, alicePriv, alice, bob, 60),
+		transferTestTx(t, bobPriv, bob, carol, 25),
+	}); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+	if _, err := bc.AddBlock([]*Transaction{transferTestTx(t, alicePriv, alice, carol, 41)}); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("AddBlock() overdrawing alice error = %v, want ErrInsufficientBalance", err)
+	}
+	want := map[string]uint64{alice: 40, bob: 35, carol: 25}
+	for address, balance := range want {
+		if got := bc.Balance(address); got != balance {
+			t.Errorf("Balance() = %d, want %d", got, balance)
+		}
+	}
+	if len(bc.Blocks) != 2 {
+		t.Errorf("chain has %d blocks, want the rejected block left out", len(bc.Blocks))
+	}
+	if err := bc.SetGenesisAllocations(nil); !errors.Is(err, ErrInsufficientBalance) || bc.Balance(alice) != 40 {
+		t.Errorf("SetGenesisAllocations(nil) error = %v, balance %d; want ErrInsufficientBalance with balances kept", err, bc.Balance(alice))
+	}
+	store.Close()
+
+	// Allocations are not stored, so a restarted node sets them again.
+	store, _ = OpenFileBlockStore(path, FileBlockStoreOptions{})
+	defer store.Close()
+	restored, err := NewBlockchainWithStore(store)
+	if err != nil {
+		t.Fatalf("NewBlockchainWithStore() on existing store error = %v", err)
+	}
+	if _, err := restored.Balances(); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("Balances() without allocations error = %v, want ErrInsufficientBalance", err)
+	}
+	restored.SetGenesisAllocations(allocations)
+	if got, err := restored.Balances(); err != nil || len(got) != 3 || got[bob] != 35 {
+		t.Errorf("restored Balances() = %v, %v; want %v", got, err, want)
+	}
+}
@@ -0,0 +1,77 @@
+package ledger
+
+import "fmt"
+
+// transactionIDsLocked returns the index of the block holding each
+// transaction on the chain, replaying the chain first if it has not been
+// indexed yet (e.g. for a chain loaded from a store). The caller must hold
+// bc.mu.
+func (bc *Blockchain) transactionIDsLocked() map[string]int64 {
+	if bc.txIDs == nil {
+		bc.txIDs = make(map[string]int64)
+		for _, block := range bc.Blocks {
+			bc.indexTransactionsLocked(block)
+		}
+	}
+	return bc.txIDs
+}
+
+// indexTransactionsLocked adds the transactions of block, just appended, to
+// the chain's transaction index, if it has been built. A transaction stays
+// indexed under the first block holding it. The caller must hold bc.mu.
+func (bc *Blockchain) indexTransactionsLocked(block *Block) {
+	if bc.txIDs == nil {
+		return
+	}
+	for _, tx := range block.Transactions {
+		if _, ok := bc.txIDs[tx.ID]; !ok {
+			bc.txIDs[tx.ID] = block.Index
+		}
+	}
+}
+
+// checkNewTransactionIDs refuses, with ErrDuplicateTransaction, a batch of
+// transactions for a new block that holds a transaction already on the chain
+// or holds one twice. Applying a signed transaction again would repeat its
+// effects, such as debiting a transfer a second time. The caller must hold
+// bc.mu.
+func (bc *Blockchain) checkNewTransactionIDs(txs []*Transaction) error {
+	onChain := bc.transactionIDsLocked()
+	seen := make(map[string]bool, len(txs))
+	for i, tx := range txs {
+		if tx == nil {
+			continue
+		}
+		if index, ok := onChain[tx.ID]; ok {
+			return reject(i, tx, fmt.Errorf("%w: transaction %s is already in block %d", ErrDuplicateTransaction, tx.ID, index))
+		}
+		if seen[tx.ID] {
+			return reject(i, tx, fmt.Errorf("%w: transaction %s appears twice in the block", ErrDuplicateTransaction, tx.ID))
+		}
+		seen[tx.ID] = true
+	}
+	return nil
+}
+
+// HasTransaction reports whether the transaction with ID txID is on the chain.
+func (bc *Blockchain) HasTransaction(txID string) bool {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	_, ok := bc.transactionIDsLocked()[txID]
+	return ok
+}
+
+// TransactionIndex reports whether a transaction is already on a chain;
+// *Blockchain implements it.
+type TransactionIndex interface {
+	HasTransaction(txID string) bool
+}
+
+// SetTransactionIndex makes Add refuse, with ErrDuplicateTransaction,
+// transactions that index already holds, normally the chain the mempool
+// feeds, so a mined transaction cannot be queued again.
+func (mp *Mempool) SetTransactionIndex(index TransactionIndex) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.onChain = index
+}
@@ -0,0 +1,121 @@
+package ledger
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// nextTestBlock returns a block of txs extending bc's tip, as another
+// producer would send it.
+func nextTestBlock(t *testing.T, bc *Blockchain, txs ...*Transaction) *Block {
+	t.Helper()
+	tip := bc.GetLatestBlock()
+	block, err := NewBlock(tip.Index+1, tip.Hash, txs)
+	if err != nil {
+		t.Fatalf("NewBlock() error = %v", err)
+	}
+	return block
+}
+
+// replayTestChain returns a chain on which alice has transferred 40 of her
+// 100 to bob, and that transfer. The chain is kept in store, if not nil.
+func replayTestChain(t *testing.T, store BlockStore) (*Blockchain, *Transaction, string) {
+	t.Helper()
+	alicePriv, alice := newTestKey(t)
+	_, bob := newTestKey(t)
+	bc, err := NewBlockchain()
+	if store != nil {
+		bc, err = NewBlockchainWithStore(store)
+	}
+	if err != nil {
+		t.Fatalf("NewBlockchain() error = %v", err)
+	}
+	bc.SetGenesisAllocations(map[string]uint64{alice: 100})
+	transfer := transferTestTx(t, alicePriv, alice, bob, 40)
+	if _, err := bc.AddBlock([]*Transaction{transfer}); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+	return bc, transfer, alice
+}
+
+func TestBlockchain_AddBlockRejectsTransactionsOnChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chain.log")
+	store, _ := OpenFileBlockStore(path, FileBlockStoreOptions{FlushInterval: time.Hour})
+	bc, transfer, alice := replayTestChain(t, store)
+
+	if _, err := bc.AddBlock([]*Transaction{transfer}); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Errorf("AddBlock() replaying a mined transfer error = %v, want ErrDuplicateTransaction", err)
+	}
+	if got := bc.Balance(alice); got != 60 {
+		t.Errorf("Balance() after the replay = %d, want 60", got)
+	}
+	if !bc.HasTransaction(transfer.ID) || bc.HasTransaction("unknown") {
+		t.Error("HasTransaction() does not report exactly the mined transfer")
+	}
+
+	store.Close()
+
+	// A chain loaded from its store indexes the blocks it loaded.
+	store, _ = OpenFileBlockStore(path, FileBlockStoreOptions{})
+	defer store.Close()
+	restored, err := NewBlockchainWithStore(store)
+	if err != nil {
+		t.Fatalf("NewBlockchainWithStore() on existing store error = %v", err)
+	}
+	if _, err := restored.AddBlock([]*Transaction{transfer}); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Errorf("AddBlock() on a reloaded chain replaying a mined transfer error = %v, want ErrDuplicateTransaction", err)
+	}
+}
+
+func TestBlockchain_AddBlockRejectsRepeatedTransactions(t *testing.T) {
+	alicePriv, alice := newTestKey(t)
+	_, bob := newTestKey(t)
+	bc, _ := NewBlockchain()
+	bc.SetGenesisAllocations(map[string]uint64{alice: 100})
+	transfer := transferTestTx(t, alicePriv, alice, bob, 40)
+
+	_, err := bc.AddBlock([]*Transaction{transfer, transfer})
+	var rejection *Rejection
+	if !errors.Is(err, ErrDuplicateTransaction) || !errors.As(err, &rejection) || rejection.TxIndex != 1 {
+		t.Errorf("AddBlock() with a transfer twice error = %v, want ErrDuplicateTransaction rejecting index 1", err)
+	}
+	if err := bc.AppendBlock(nextTestBlock(t, bc, transfer, transfer)); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Errorf("AppendBlock() with a transfer twice error = %v, want ErrDuplicateTransaction", err)
+	}
+	if got := bc.Balance(alice); got != 100 || len(bc.Blocks) != 1 {
+		t.Errorf("Balance() = %d with %d blocks, want 100 and the blocks left out", got, len(bc.Blocks))
+	}
+}
+
+func TestBlockchain_AppendBlockRejectsTransactionsOnChain(t *testing.T) {
+	bc, transfer, alice := replayTestChain(t, nil)
+
+	if err := bc.AppendBlock(nextTestBlock(t, bc, transfer)); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Errorf("AppendBlock() replaying a mined transfer error = %v, want ErrDuplicateTransaction", err)
+	}
+	if err := bc.SubmitSealedBlock(nil, nextTestBlock(t, bc, transfer)); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Errorf("SubmitSealedBlock() replaying a mined transfer error = %v, want ErrDuplicateTransaction", err)
+	}
+	if got := bc.Balance(alice); got != 60 || len(bc.Blocks) != 2 {
+		t.Errorf("Balance() = %d with %d blocks, want 60 and the replays left out", got, len(bc.Blocks))
+	}
+}
+
+func TestMempool_RejectsTransactionsOnChain(t *testing.T) {
+	bc, transfer, _ := replayTestChain(t, nil)
+	mempool := NewMempool(nil)
+	if err := mempool.Add(transfer); err != nil {
+		t.Fatalf("Add() without a transaction index error = %v", err)
+	}
+	mempool.Remove(transfer.ID)
+
+	mempool.SetTransactionIndex(bc)
+	if err := mempool.Add(transfer); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Errorf("Add() of a mined transfer error = %v, want ErrDuplicateTransaction", err)
+	}
+	if mempool.Size() != 0 {
+		t.Errorf("mempool holds %d transactions, want 0", mempool.Size())
+	}
+}
@@ -0,0 +1,396 @@
+// Package lightclient follows a chain without storing or validating its
+// blocks, for mobile apps, browsers (WASM) and other constrained clients. It
+// syncs only block headers, checking that each links to the one before, and
+// verifies the transactions it is interested in, such as posts by followed
+// authors, on demand: a full node's proof API (see api.ChainReader) supplies
+// the transaction and a Merkle proof that it is in a synced block. Content is
+// fetched from DDS gateways (see content.Gateway).
+//
+// A light client trusts the chain's first header it syncs, or the checkpoint
+// it is given, and that the blocks full nodes accepted are valid; it proves
+// that a transaction is in the chain, not that the chain's rules allowed it.
+package lightclient
+
+import (
+	"context"
+	"digisocialblock/core/api"
+	"digisocialblock/core/api/client"
+	"digisocialblock/core/content"
+	"digisocialblock/core/ledger"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxContentSize bounds the content FetchContent reads when
+// Options.MaxContentSize is zero.
+const DefaultMaxContentSize = 16 << 20
+
+// ErrNotSynced is returned when a transaction is in a block the client has no
+// header for: one after the synced tip (Sync and retry) or before its
+// checkpoint.
+var ErrNotSynced = errors.New("transaction's block is not synced")
+
+// ErrContentUnavailable is returned when no gateway served content.
+var ErrContentUnavailable = errors.New("content unavailable from every gateway")
+
+// Source serves headers and proofs; NewAPISource adapts a node API client.
+type Source interface {
+	// Headers returns up to limit headers starting at index from, and none
+	// past the tip.
+	Headers(ctx context.Context, from int64, limit int) ([]ledger.BlockHeader, error)
+	// Proof returns the transaction with txID and the proof that it is in
+	// its block.
+	Proof(ctx context.Context, txID string) (*ledger.Transaction, *ledger.MerkleProof, error)
+}
+
+// apiSource is a Source backed by a node's API.
+type apiSource struct {
+	client *client.Client
+}
+
+// NewAPISource returns a Source calling the GetHeaders and GetProof
+// endpoints of the node c is for.
+func NewAPISource(c *client.Client) Source {
+	return &apiSource{client: c}
+}
+
+func (s *apiSource) Headers(ctx context.Context, from int64, limit int) ([]ledger.BlockHeader, error) {
+	resp, err := s.client.GetHeaders(ctx, &client.GetHeadersRequest{From: from, Limit: int64(limit)})
+	if err != nil {
+		return nil, err
+	}
+	headers := make([]ledger.BlockHeader, len(resp.Headers))
+	for i, h := range resp.Headers {
+		headers[i] = ledger.BlockHeader{Index: h.Index, Timestamp: h.Timestamp, PrevBlockHash: h.PrevBlockHash, MerkleRoot: h.MerkleRoot, Hash: h.Hash}
+	}
+	return headers, nil
+}
+
+func (s *apiSource) Proof(ctx context.Context, txID string) (*ledger.Transaction, *ledger.MerkleProof, error) {
+	resp, err := s.client.GetProof(ctx, &client.GetProofRequest{TxID: txID})
+	if err != nil {
+		return nil, nil, err
+	}
+	proof := &ledger.MerkleProof{TxID: resp.TxID, BlockIndex: resp.BlockIndex}
+	for _, step := range resp.Path {
+		proof.Path = append(proof.Path, ledger.MerkleStep{Hash: step.Hash, Left: step.Left})
+	}
+	return client.ToLedger(&resp.Transaction), proof, nil
+}
+
+// HeaderStore keeps the synced headers. Apps may persist them by
+// implementing it; NewMemoryHeaderStore keeps them in memory.
+type HeaderStore interface {
+	// Append stores headers, which follow the latest stored one.
+	Append(headers []ledger.BlockHeader) error
+	// Header returns the stored header of block index.
+	Header(index int64) (ledger.BlockHeader, bool)
+	// Latest returns the last stored header.
+	Latest() (ledger.BlockHeader, bool)
+}
+
+// MemoryHeaderStore is a HeaderStore in memory. It is safe for concurrent
+// use.
+type MemoryHeaderStore struct {
+	mu      sync.Mutex
+	headers []ledger.BlockHeader // Consecutive, from the first stored
+}
+
+// NewMemoryHeaderStore creates an empty MemoryHeaderStore.
+func NewMemoryHeaderStore() *MemoryHeaderStore {
+	return &MemoryHeaderStore{}
+}
+
+// Append implements HeaderStore.
+func (m *MemoryHeaderStore) Append(headers []ledger.BlockHeader) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, h := range headers {
+		if n := len(m.headers); n > 0 && h.Index != m.headers[n-1].Index+1 {
+			return fmt.Errorf("header %d does not follow %d", h.Index, m.headers[n-1].Index)
+		}
+		m.headers = append(m.headers, h)
+	}
+	return nil
+}
+
+// Header implements HeaderStore.
+func (m *MemoryHeaderStore) Header(index int64) (ledger.BlockHeader, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.headers) == 0 {
+		return ledger.BlockHeader{}, false
+	}
+	i := index - m.headers[0].Index
+	if i < 0 || i >= int64(len(m.headers)) {
+		return ledger.BlockHeader{}, false
+	}
+	return m.headers[i], true
+}
+
+// Latest implements HeaderStore.
+func (m *MemoryHeaderStore) Latest() (ledger.BlockHeader, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.headers) == 0 {
+		return ledger.BlockHeader{}, false
+	}
+	return m.headers[len(m.headers)-1], true
+}
+
+// Options configures a Client. The zero value is valid.
+type Options struct {
+	ChainID string // Chain transactions must be signed for; defaults to ledger.DefaultChainID
+
+	// Checkpoint is a header the client trusts, e.g. one shipped with the
+	// app, so it syncs from there instead of from the source's genesis.
+	// Ignored once Store holds headers.
+	Checkpoint *ledger.BlockHeader
+	Store      HeaderStore // Defaults to a MemoryHeaderStore
+	BatchSize  int         // Headers requested at a time; defaults to api.MaxHeadersPerRequest
+
+	// Gateways are the base URLs of the DDS gateways FetchContent tries, in
+	// order, e.g. "https://gateway.example".
+	Gateways       []string
+	HTTPClient     *http.Client // Defaults to one with a 30s timeout
+	MaxContentSize int64        // Defaults to DefaultMaxContentSize
+	// VerifyContent, if set, checks content a gateway served against its
+	// manifest CID; content failing it is refused and the next gateway tried.
+	// Without it, gateways are trusted to serve the content they name.
+	VerifyContent func(manifestCID string, data []byte) error
+}
+
+// Client is a light client. It is safe for concurrent use.
+type Client struct {
+	source Source
+	opts   Options
+	syncMu sync.Mutex // Serializes Sync
+}
+
+// New creates a Client fetching headers and proofs from source.
+func New(source Source, opts Options) (*Client, error) {
+	if source == nil {
+		return nil, fmt.Errorf("source cannot be nil")
+	}
+	if opts.ChainID == "" {
+		opts.ChainID = ledger.DefaultChainID
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryHeaderStore()
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = api.MaxHeadersPerRequest
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if opts.MaxContentSize <= 0 {
+		opts.MaxContentSize = DefaultMaxContentSize
+	}
+	for _, g := range opts.Gateways {
+		if !strings.HasPrefix(g, "http://") && !strings.HasPrefix(g, "https://") {
+			return nil, fmt.Errorf("invalid gateway URL %q", g)
+		}
+	}
+	if opts.Checkpoint != nil {
+		if err := opts.Checkpoint.Verify(); err != nil {
+			return nil, fmt.Errorf("invalid checkpoint: %w", err)
+		}
+	}
+	return &Client{source: source, opts: opts}, nil
+}
+
+// Sync fetches and verifies the headers after the latest synced one, up to
+// the source's tip, and returns how many it stored. Headers verified before
+// a failure, in batches of Options.BatchSize, are kept.
+func (c *Client) Sync(ctx context.Context) (int, error) {
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+	latest, ok := c.opts.Store.Latest()
+	synced := 0
+	if !ok {
+		first, err := c.first(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if err := c.opts.Store.Append([]ledger.BlockHeader{first}); err != nil {
+			return 0, fmt.Errorf("failed to store header %d: %w", first.Index, err)
+		}
+		latest, synced = first, 1
+	}
+	for {
+		headers, err := c.source.Headers(ctx, latest.Index+1, c.opts.BatchSize)
+		if err != nil {
+			return synced, fmt.Errorf("failed to fetch headers from %d: %w", latest.Index+1, err)
+		}
+		if len(headers) == 0 {
+			return synced, nil
+		}
+		if len(headers) > c.opts.BatchSize {
+			return synced, fmt.Errorf("source returned %d headers, asked for %d", len(headers), c.opts.BatchSize)
+		}
+		prev := latest
+		for i := range headers {
+			if err := headers[i].Verify(); err != nil {
+				return synced, fmt.Errorf("header %d: %w", headers[i].Index, err)
+			}
+			if err := headers[i].VerifyLink(&prev); err != nil {
+				return synced, fmt.Errorf("header %d: %w", headers[i].Index, err)
+			}
+			prev = headers[i]
+		}
+		if err := c.opts.Store.Append(headers); err != nil {
+			return synced, fmt.Errorf("failed to store headers from %d: %w", headers[0].Index, err)
+		}
+		latest = prev
+		synced += len(headers)
+	}
+}
+
+// first returns the header to sync from: the checkpoint, or the source's
+// genesis.
+func (c *Client) first(ctx context.Context) (ledger.BlockHeader, error) {
+	if c.opts.Checkpoint != nil {
+		return *c.opts.Checkpoint, nil
+	}
+	headers, err := c.source.Headers(ctx, 0, 1)
+	if err != nil {
+		return ledger.BlockHeader{}, fmt.Errorf("failed to fetch the genesis header: %w", err)
+	}
+	if len(headers) != 1 || headers[0].Index != 0 {
+		return ledger.BlockHeader{}, fmt.Errorf("source returned no genesis header")
+	}
+	if err := headers[0].Verify(); err != nil {
+		return ledger.BlockHeader{}, fmt.Errorf("genesis header: %w", err)
+	}
+	return headers[0], nil
+}
+
+// Height returns the index of the latest synced block, or -1 before the
+// first Sync.
+func (c *Client) Height() int64 {
+	latest, ok := c.opts.Store.Latest()
+	if !ok {
+		return -1
+	}
+	return latest.Index
+}
+
+// VerifyTransaction fetches the transaction with txID and checks that it is
+// what its ID names, is signed for the client's chain, and is in a synced
+// block.
+func (c *Client) VerifyTransaction(ctx context.Context, txID string) (*ledger.Transaction, error) {
+	tx, proof, err := c.source.Proof(ctx, txID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch proof of %s: %w", txID, err)
+	}
+	if tx == nil || proof == nil || tx.ID != txID || proof.TxID != txID {
+		return nil, fmt.Errorf("source returned a proof of another transaction than %s", txID)
+	}
+	if id := ledger.HashTransactionContent(tx.Timestamp, tx.SenderPublicKey, tx.Type, tx.Payload); !ledger.ConstantTimeEqual(id, tx.ID) {
+		return nil, fmt.Errorf("transaction %s does not match its ID", txID)
+	}
+	if tx.ChainID != c.opts.ChainID {
+		return nil, fmt.Errorf("%w: transaction %s is for chain %q, not %q", ledger.ErrWrongChain, txID, tx.ChainID, c.opts.ChainID)
+	}
+	if ok, err := tx.VerifySignature(); !ok {
+		return nil, fmt.Errorf("%w: transaction %s: %v", ledger.ErrInvalidSignature, txID, err)
+	}
+	header, ok := c.opts.Store.Header(proof.BlockIndex)
+	if !ok {
+		return nil, fmt.Errorf("%w: transaction %s is in block %d, synced to %d", ErrNotSynced, txID, proof.BlockIndex, c.Height())
+	}
+	if err := proof.Verify(&header); err != nil {
+		return nil, fmt.Errorf("transaction %s: %w", txID, err)
+	}
+	return tx, nil
+}
+
+// VerifyTransactions verifies each of txIDs, as VerifyTransaction does, and
+// returns those interest accepts, in order; a nil interest accepts all. It
+// stops at the first transaction that fails verification.
+func (c *Client) VerifyTransactions(ctx context.Context, txIDs []string, interest func(*ledger.Transaction) bool) ([]*ledger.Transaction, error) {
+	var verified []*ledger.Transaction
+	for _, id := range txIDs {
+		tx, err := c.VerifyTransaction(ctx, id)
+		if err != nil {
+			return verified, err
+		}
+		if interest == nil || interest(tx) {
+			verified = append(verified, tx)
+		}
+	}
+	return verified, nil
+}
+
+// PostsFrom returns an interest for VerifyTransactions accepting posts by
+// authors, such as the ones a user follows.
+func PostsFrom(authors ...string) func(*ledger.Transaction) bool {
+	set := make(map[string]bool, len(authors))
+	for _, a := range authors {
+		set[a] = true
+	}
+	return func(tx *ledger.Transaction) bool {
+		return tx.Type == ledger.PostCreated && set[tx.SenderPublicKey]
+	}
+}
+
+// FetchContent retrieves the content published under manifestCID from the
+// first of the client's gateways that serves it.
+func (c *Client) FetchContent(ctx context.Context, manifestCID string) ([]byte, error) {
+	if manifestCID == "" || strings.ContainsAny(manifestCID, "/?#") {
+		return nil, fmt.Errorf("invalid manifest CID %q", manifestCID)
+	}
+	var errs []error
+	for _, gateway := range c.opts.Gateways {
+		data, err := c.fetchFrom(ctx, gateway, manifestCID)
+		if err == nil {
+			return data, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", gateway, err))
+	}
+	return nil, fmt.Errorf("%w: %s: %v", ErrContentUnavailable, manifestCID, errors.Join(errs...))
+}
+
+func (c *Client) fetchFrom(ctx context.Context, gateway, manifestCID string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(gateway, "/")+content.GatewayPathPrefix+manifestCID, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" && etag != strconv.Quote(manifestCID) {
+		return nil, fmt.Errorf("served %s instead", etag)
+	}
+	if resp.ContentLength > c.opts.MaxContentSize {
+		return nil, fmt.Errorf("content is %d bytes, limit %d", resp.ContentLength, c.opts.MaxContentSize)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, c.opts.MaxContentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content: %w", err)
+	}
+	if int64(len(data)) > c.opts.MaxContentSize {
+		return nil, fmt.Errorf("content exceeds %d bytes", c.opts.MaxContentSize)
+	}
+	if c.opts.VerifyContent != nil {
+		if err := c.opts.VerifyContent(manifestCID, data); err != nil {
+			return nil, fmt.Errorf("content failed verification: %w", err)
+		}
+	}
+	return data, nil
+}
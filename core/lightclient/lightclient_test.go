@@ -0,0 +1,162 @@
+package lightclient
+
+import (
+	"context"
+	"digisocialblock/core/api"
+	"digisocialblock/core/api/client"
+	"digisocialblock/core/content"
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil/fixture"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// lightclientTestNode serves bc's headers and proofs over the node API and
+// returns a Source for it.
+func lightclientTestNode(t *testing.T, bc *ledger.Blockchain) Source {
+	t.Helper()
+	s, err := api.NewServer(ledger.NewMempool(nil), api.ServerOptions{Chain: bc, ValidateRequests: true})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	c, _ := client.New(srv.URL)
+	return NewAPISource(c)
+}
+
+// tamperingSource alters what it passes on from a Source.
+type tamperingSource struct {
+	Source
+	headers func([]ledger.BlockHeader)
+	proof   func(*ledger.Transaction, *ledger.MerkleProof)
+}
+
+func (s *tamperingSource) Headers(ctx context.Context, from int64, limit int) ([]ledger.BlockHeader, error) {
+	headers, err := s.Source.Headers(ctx, from, limit)
+	if err == nil && s.headers != nil {
+		s.headers(headers)
+	}
+	return headers, err
+}
+
+func (s *tamperingSource) Proof(ctx context.Context, txID string) (*ledger.Transaction, *ledger.MerkleProof, error) {
+	tx, proof, err := s.Source.Proof(ctx, txID)
+	if err == nil && s.proof != nil {
+		s.proof(tx, proof)
+	}
+	return tx, proof, err
+}
+
+func TestClient_SyncsHeadersAndVerifiesTransactions(t *testing.T) {
+	alice, bob := fixture.Wallet(t), fixture.Wallet(t)
+	post := fixture.PostTx(t, alice, "cid-1")
+	follow := fixture.FollowTx(t, alice, bob.Address)
+	bobsPost := fixture.PostTx(t, bob, "cid-2")
+	bc := fixture.Chain(t, []*ledger.Transaction{follow, post}, []*ledger.Transaction{bobsPost})
+	source := lightclientTestNode(t, bc)
+
+	lc, err := New(source, Options{BatchSize: 1})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := lc.VerifyTransaction(context.Background(), post.ID); !errors.Is(err, ErrNotSynced) {
+		t.Errorf("VerifyTransaction() before Sync() error = %v, want ErrNotSynced", err)
+	}
+	if n, err := lc.Sync(context.Background()); n != 3 || err != nil || lc.Height() != 2 {
+		t.Fatalf("Sync() = %d, %v with height %d; want 3 headers to height 2", n, err, lc.Height())
+	}
+	if n, err := lc.Sync(context.Background()); n != 0 || err != nil {
+		t.Errorf("Sync() at the tip = %d, %v; want nothing", n, err)
+	}
+
+	got, err := lc.VerifyTransactions(context.Background(), []string{follow.ID, post.ID, bobsPost.ID}, PostsFrom(alice.Address))
+	if err != nil {
+		t.Fatalf("VerifyTransactions() error = %v", err)
+	}
+	if len(got) != 1 || got[0].ID != post.ID {
+		t.Errorf("VerifyTransactions() = %v, want alice's post", got)
+	}
+
+	// Tampered transactions and proofs are refused.
+	for name, tamper := range map[string]func(*ledger.Transaction, *ledger.MerkleProof){
+		"payload":    func(tx *ledger.Transaction, _ *ledger.MerkleProof) { tx.Payload = append(tx.Payload, ' ') },
+		"signature":  func(tx *ledger.Transaction, _ *ledger.MerkleProof) { tx.Signature[len(tx.Signature)-1] ^= 1 },
+		"proof path": func(_ *ledger.Transaction, p *ledger.MerkleProof) { p.Path = p.Path[1:] },
+		"block":      func(_ *ledger.Transaction, p *ledger.MerkleProof) { p.BlockIndex = 2 },
+	} {
+		lying, _ := New(&tamperingSource{Source: source, proof: tamper}, Options{})
+		lying.Sync(context.Background())
+		if _, err := lying.VerifyTransaction(context.Background(), post.ID); err == nil {
+			t.Errorf("VerifyTransaction() with a tampered %s: expected error, got nil", name)
+		}
+	}
+	other, _ := New(source, Options{ChainID: "testnet"})
+	other.Sync(context.Background())
+	if _, err := other.VerifyTransaction(context.Background(), post.ID); !errors.Is(err, ledger.ErrWrongChain) {
+		t.Errorf("VerifyTransaction() for another chain error = %v, want ErrWrongChain", err)
+	}
+}
+
+func TestClient_SyncRefusesBrokenChains(t *testing.T) {
+	wallet := fixture.Wallet(t)
+	bc := fixture.Chain(t, []*ledger.Transaction{fixture.PostTx(t, wallet, "a")}, []*ledger.Transaction{fixture.PostTx(t, wallet, "b")})
+	source := lightclientTestNode(t, bc)
+	headers := bc.Headers(0, 3)
+
+	forged := &tamperingSource{Source: source, headers: func(hs []ledger.BlockHeader) {
+		for i := range hs {
+			if hs[i].Index == 2 {
+				hs[i].MerkleRoot = headers[1].MerkleRoot
+			}
+		}
+	}}
+	lc, _ := New(forged, Options{})
+	if n, err := lc.Sync(context.Background()); err == nil || lc.Height() != 0 || n != 1 {
+		t.Errorf("Sync() with a forged header = %d, %v with height %d; want error storing only genesis", n, err, lc.Height())
+	}
+
+	// A checkpoint from another chain does not link up.
+	otherChain := fixture.Chain(t, []*ledger.Transaction{fixture.PostTx(t, wallet, "c")})
+	checkpoint := otherChain.Headers(1, 1)[0]
+	lc, _ = New(source, Options{Checkpoint: &checkpoint})
+	if _, err := lc.Sync(context.Background()); err == nil {
+		t.Error("Sync() from another chain's checkpoint: expected error, got nil")
+	}
+}
+
+func TestClient_FetchContentFailsOver(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "content not found", http.StatusNotFound)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != content.GatewayPathPrefix+"cid-1" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", strconv.Quote("cid-1"))
+		w.Write([]byte("hello"))
+	}))
+	defer up.Close()
+
+	lc, _ := New(&tamperingSource{}, Options{Gateways: []string{down.URL, up.URL}})
+	if data, err := lc.FetchContent(context.Background(), "cid-1"); err != nil || string(data) != "hello" {
+		t.Errorf("FetchContent() = %q, %v; want the content from the second gateway", data, err)
+	}
+	if _, err := lc.FetchContent(context.Background(), "cid-2"); !errors.Is(err, ErrContentUnavailable) {
+		t.Errorf("FetchContent() of missing content error = %v, want ErrContentUnavailable", err)
+	}
+
+	lc, _ = New(&tamperingSource{}, Options{Gateways: []string{up.URL}, MaxContentSize: 4})
+	if _, err := lc.FetchContent(context.Background(), "cid-1"); err == nil {
+		t.Error("FetchContent() of oversized content: expected error, got nil")
+	}
+	lc, _ = New(&tamperingSource{}, Options{Gateways: []string{up.URL}, VerifyContent: func(string, []byte) error { return errors.New("mismatch") }})
+	if _, err := lc.FetchContent(context.Background(), "cid-1"); !errors.Is(err, ErrContentUnavailable) {
+		t.Errorf("FetchContent() of content failing verification error = %v, want ErrContentUnavailable", err)
+	}
+}
@@ -0,0 +1,320 @@
+// Package linkpreview builds preview cards for links in posts. The card for
+// a link (its OpenGraph title, description and site name, and a thumbnail of
+// its image) is published to DDS as JSON and the post records the card's CID
+// in social.Post.PreviewCID, so readers show the preview without contacting
+// the linked site, and see the same preview on every node.
+//
+// Cards are cached per URL. The cache only holds CIDs: a cached card is read
+// back from DDS, where every chunk is verified against its CID, and is
+// fetched again if it has gone missing, fails verification or is too old.
+package linkpreview
+
+import (
+	"context"
+	"digisocialblock/core/publicnet"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Defaults for Options.
+const (
+	DefaultMaxPageSize   = 1 << 20 // Bytes of HTML read, from the start of the page
+	DefaultMaxImageSize  = 8 << 20 // Bytes
+	DefaultThumbnailSize = 400     // Pixels on the longest side
+	DefaultMaxAge        = 24 * time.Hour
+)
+
+// Limits on card text, in characters. Longer values are truncated.
+const (
+	MaxTitleLength       = 256
+	MaxDescriptionLength = 1024
+	MaxSiteNameLength    = 128
+)
+
+// ErrNoMetadata is returned for pages with neither a title nor a description.
+var ErrNoMetadata = errors.New("page has no preview metadata")
+
+// Card is a link preview, published to DDS as JSON.
+type Card struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	SiteName    string `json:"siteName,omitempty"`
+	Type        string `json:"type,omitempty"`     // og:type, e.g. "article"
+	ImageCID    string `json:"imageCID,omitempty"` // JPEG thumbnail on DDS
+	ImageWidth  int    `json:"imageWidth,omitempty"`
+	ImageHeight int    `json:"imageHeight,omitempty"`
+	FetchedAt   int64  `json:"fetchedAt"` // UnixNano
+}
+
+// DDS publishes content and reads it back verified; mobile.Content
+// implements it.
+type DDS interface {
+	PublishMedia(data []byte) (string, error)
+	RetrieveMedia(manifestCID string) ([]byte, error)
+}
+
+// Options configures a Service.
+type Options struct {
+	// Client fetches pages and images. Nil means a publicnet client, which
+	// only connects to public addresses and follows redirects to http URLs
+	// only if AllowHTTP is set; a client given here is used as is.
+	Client *http.Client
+	// AllowHTTP previews plain http links as well as https ones.
+	AllowHTTP bool
+	// MaxPageSize, MaxImageSize, ThumbnailSize and MaxAge default to the
+	// Default constants when zero. MaxAge is how long a cached card is reused.
+	MaxPageSize   int64
+	MaxImageSize  int64
+	ThumbnailSize int
+	MaxAge        time.Duration
+	// Now returns the current time. Nil uses time.Now.
+	Now func() time.Time
+}
+
+// Service fetches, publishes and caches link previews. It implements
+// social.LinkPreviewer.
+type Service struct {
+	dds  DDS
+	opts Options
+
+	mu    sync.Mutex
+	cards map[string]string // URL -> card CID
+}
+
+// NewService creates a Service publishing to dds.
+func NewService(dds DDS, opts Options) (*Service, error) {
+	if dds == nil {
+		return nil, fmt.Errorf("DDS cannot be nil")
+	}
+	if opts.MaxPageSize < 0 || opts.MaxImageSize < 0 || opts.ThumbnailSize < 0 || opts.MaxAge < 0 {
+		return nil, fmt.Errorf("link preview limits cannot be negative")
+	}
+	if opts.Client == nil {
+		opts.Client = publicnet.NewClient(publicnet.Options{AllowHTTP: opts.AllowHTTP})
+	}
+	if opts.MaxPageSize == 0 {
+		opts.MaxPageSize = DefaultMaxPageSize
+	}
+	if opts.MaxImageSize == 0 {
+		opts.MaxImageSize = DefaultMaxImageSize
+	}
+	if opts.ThumbnailSize == 0 {
+		opts.ThumbnailSize = DefaultThumbnailSize
+	}
+	if opts.MaxAge == 0 {
+		opts.MaxAge = DefaultMaxAge
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Service{dds: dds, opts: opts, cards: make(map[string]string)}, nil
+}
+
+// PreviewText previews the first link in text that the service accepts and
+// returns the card's CID, or "" if text has no such link.
+func (s *Service) PreviewText(ctx context.Context, text string) (string, error) {
+	for _, link := range FindURLs(text) {
+		if _, err := s.checkURL(link); err == nil {
+			cid, _, err := s.Preview(ctx, link)
+			return cid, err
+		}
+	}
+	return "", nil
+}
+
+// Preview returns the CID and contents of the card for rawURL, from the cache
+// if a fresh, verified card is there, and otherwise by fetching the page and
+// publishing a new card. A missing or unusable page image leaves the card
+// without a thumbnail rather than failing.
+func (s *Service) Preview(ctx context.Context, rawURL string) (string, *Card, error) {
+	u, err := s.checkURL(rawURL)
+	if err != nil {
+		return "", nil, err
+	}
+	key := u.String()
+	now := s.opts.Now()
+
+	s.mu.Lock()
+	cached, ok := s.cards[key]
+	s.mu.Unlock()
+	if ok {
+		card, err := s.Card(cached)
+		if err == nil && card.URL == key && now.Sub(time.Unix(0, card.FetchedAt)) < s.opts.MaxAge {
+			return cached, card, nil
+		}
+		// Gone, damaged or stale; fetch it again.
+	}
+
+	card, err := s.fetchCard(ctx, u)
+	if err != nil {
+		return "", nil, err
+	}
+	card.FetchedAt = now.UnixNano()
+	data, err := json.Marshal(card)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode preview card: %w", err)
+	}
+	cid, err := s.dds.PublishMedia(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to publish preview card for %s: %w", key, err)
+	}
+	s.mu.Lock()
+	s.cards[key] = cid
+	s.mu.Unlock()
+	return cid, card, nil
+}
+
+// Card reads the card published under cid from DDS.
+func (s *Service) Card(cid string) (*Card, error) {
+	data, err := s.dds.RetrieveMedia(cid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve preview card %s: %w", cid, err)
+	}
+	var card Card
+	if err := json.Unmarshal(data, &card); err != nil {
+		return nil, fmt.Errorf("failed to decode preview card %s: %w", cid, err)
+	}
+	if card.URL == "" {
+		return nil, fmt.Errorf("preview card %s has no URL", cid)
+	}
+	return &card, nil
+}
+
+// checkURL parses rawURL and checks that it may be previewed. The fragment
+// is dropped, since it does not change the page.
+func (s *Service) checkURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && !(s.opts.AllowHTTP && u.Scheme == "http")) {
+		return nil, fmt.Errorf("cannot preview %q: not an absolute https URL", rawURL)
+	}
+	u.Fragment, u.RawFragment = "", ""
+	return u, nil
+}
+
+// fetchCard fetches the page at u and builds its card, including the
+// thumbnail.
+func (s *Service) fetchCard(ctx context.Context, u *url.URL) (*Card, error) {
+	body, final, err := s.fetch(ctx, u, s.opts.MaxPageSize, false, "text/html", "application/xhtml+xml")
+	if err != nil {
+		return nil, err
+	}
+	meta := parseMeta(strings.ToValidUTF8(string(body), "�"))
+	card := &Card{
+		URL:         u.String(),
+		Title:       clean(meta.Title, MaxTitleLength),
+		Description: clean(meta.Description, MaxDescriptionLength),
+		SiteName:    clean(meta.SiteName, MaxSiteNameLength),
+		Type:        clean(meta.Type, 32),
+	}
+	if card.Title == "" && card.Description == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNoMetadata, card.URL)
+	}
+	if meta.Image == "" {
+		return card, nil
+	}
+	imageURL, err := final.Parse(meta.Image)
+	if err != nil {
+		return card, nil
+	}
+	imageURL, err = s.checkURL(imageURL.String())
+	if err != nil {
+		return card, nil
+	}
+	data, _, err := s.fetch(ctx, imageURL, s.opts.MaxImageSize, true, "image/")
+	if err != nil {
+		return card, nil
+	}
+	thumb, w, h, err := thumbnail(data, s.opts.ThumbnailSize)
+	if err != nil {
+		return card, nil
+	}
+	if card.ImageCID, err = s.dds.PublishMedia(thumb); err != nil {
+		return nil, fmt.Errorf("failed to publish thumbnail for %s: %w", card.URL, err)
+	}
+	card.ImageWidth, card.ImageHeight = w, h
+	return card, nil
+}
+
+// fetch GETs u and returns the body and the URL it was served from after
+// redirects. The response must have one of the given media types (a prefix
+// ending in "/" matches a whole family). Bodies over maxSize are an error if
+// strict, and otherwise cut off.
+func (s *Service) fetch(ctx context.Context, u *url.URL, maxSize int64, strict bool, types ...string) ([]byte, *url.URL, error) {
+	rawURL := u.String()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build request for %s: %w", rawURL, err)
+	}
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("failed to fetch %s: %s", rawURL, resp.Status)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !mediaTypeIn(mediaType, types) {
+		return nil, nil, fmt.Errorf("%s is %q, not %s", rawURL, mediaType, strings.Join(types, " or "))
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", rawURL, err)
+	}
+	if int64(len(data)) > maxSize {
+		if strict {
+			return nil, nil, fmt.Errorf("%s exceeds %d bytes", rawURL, maxSize)
+		}
+		data = data[:maxSize]
+	}
+	return data, resp.Request.URL, nil
+}
+
+func mediaTypeIn(mediaType string, types []string) bool {
+	for _, t := range types {
+		if mediaType == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// clean collapses whitespace in s and truncates it to max characters.
+func clean(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
+
+// FindURLs returns the http and https URLs in text, in order. Punctuation
+// that usually ends a sentence rather than a URL is trimmed.
+func FindURLs(text string) []string {
+	var urls []string
+	for _, word := range strings.Fields(text) {
+		start := strings.Index(word, "https://")
+		if i := strings.Index(word, "http://"); i >= 0 && (start < 0 || i < start) {
+			start = i
+		}
+		if start < 0 {
+			continue
+		}
+		link := strings.TrimRight(word[start:], ".,;:!?)]}'\"")
+		if u, err := url.Parse(link); err == nil && u.Host != "" {
+			urls = append(urls, link)
+		}
+	}
+	return urls
+}
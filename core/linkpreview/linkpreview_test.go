@@ -0,0 +1,115 @@
+package linkpreview
+
+import (
+	"bytes"
+	"context"
+	"digisocialblock/core/mobile"
+	"digisocialblock/core/publicnet"
+	"errors"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newLinkPreviewTestServer(t *testing.T, pageHits *atomic.Int32) *httptest.Server {
+	t.Helper()
+	var img bytes.Buffer
+	png.Encode(&img, image.NewGray(image.Rect(0, 0, 1200, 600)))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		pageHits.Add(1)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><meta property="og:title" content="An   article">
+<meta property="og:description" content="` + strings.Repeat("long ", 300) + `">
+<meta property="og:image" content="/card.png"></head><body>text</body></html>`))
+	})
+	mux.HandleFunc("/broken-image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<title>Broken</title><meta property="og:image" content="/page.txt">`))
+	})
+	mux.HandleFunc("/bare", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<p>no head</p>`))
+	})
+	mux.HandleFunc("/card.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(img.Bytes())
+	})
+	mux.HandleFunc("/page.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("not an image"))
+	})
+	srv := httptest.NewTLSServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestService_PreviewPublishesAndCaches(t *testing.T) {
+	var hits atomic.Int32
+	srv := newLinkPreviewTestServer(t, &hits)
+	chunks, _ := mobile.NewDirStore(t.TempDir())
+	dds, _ := mobile.NewContent(chunks)
+	now := time.Unix(1700000000, 0)
+	s, err := NewService(dds, Options{Client: srv.Client(), Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	ctx := context.Background()
+
+	cid, card, err := s.Preview(ctx, srv.URL+"/article#comments")
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+	if card.URL != srv.URL+"/article" || card.Title != "An article" || len([]rune(card.Description)) != MaxDescriptionLength {
+		t.Errorf("card = %+v, want the article without fragment, cleaned and truncated", card)
+	}
+	if card.ImageCID == "" || card.ImageWidth != DefaultThumbnailSize || card.ImageHeight != DefaultThumbnailSize/2 {
+		t.Errorf("card image = %q %dx%d, want a %dx%d thumbnail", card.ImageCID, card.ImageWidth, card.ImageHeight, DefaultThumbnailSize, DefaultThumbnailSize/2)
+	}
+	if thumb, err := dds.RetrieveMedia(card.ImageCID); err != nil || !bytes.HasPrefix(thumb, []byte("\xff\xd8")) {
+		t.Errorf("RetrieveMedia(thumbnail) = %d bytes, %v; want a JPEG", len(thumb), err)
+	}
+	if stored, err := s.Card(cid); err != nil || *stored != *card {
+		t.Errorf("Card() = %+v, %v; want the published card", stored, err)
+	}
+
+	if again, err := s.PreviewText(ctx, "see "+srv.URL+"/article."); err != nil || again != cid || hits.Load() != 1 {
+		t.Errorf("PreviewText() = %q (page hits %d), want the cached %q without refetching", again, hits.Load(), cid)
+	}
+	now = now.Add(DefaultMaxAge)
+	if _, _, err := s.Preview(ctx, srv.URL+"/article"); err != nil || hits.Load() != 2 {
+		t.Errorf("Preview() of a stale card: err %v, page hits %d; want a refetch", err, hits.Load())
+	}
+
+	if _, card, err := s.Preview(ctx, srv.URL+"/broken-image"); err != nil || card.Title != "Broken" || card.ImageCID != "" {
+		t.Errorf("Preview() with a bad image = %+v, %v; want a card without thumbnail", card, err)
+	}
+	if _, _, err := s.Preview(ctx, srv.URL+"/bare"); !errors.Is(err, ErrNoMetadata) {
+		t.Errorf("Preview() of a bare page error = %v, want ErrNoMetadata", err)
+	}
+	if _, _, err := s.Preview(ctx, "http://example.com/"); err == nil {
+		t.Error("Preview() of an http URL expected error, got nil")
+	}
+	if cid, err := s.PreviewText(ctx, "no links here, or only http://example.com"); err != nil || cid != "" {
+		t.Errorf("PreviewText() without a previewable link = %q, %v; want nothing", cid, err)
+	}
+}
+
+func TestService_DefaultClientRefusesNonPublicAddresses(t *testing.T) {
+	var hits atomic.Int32
+	srv := newLinkPreviewTestServer(t, &hits)
+	chunks, _ := mobile.NewDirStore(t.TempDir())
+	dds, _ := mobile.NewContent(chunks)
+	s, _ := NewService(dds, Options{})
+	if _, _, err := s.Preview(context.Background(), srv.URL+"/article"); !errors.Is(err, publicnet.ErrNonPublicAddress) {
+		t.Errorf("Preview() of a loopback page error = %v, want ErrNonPublicAddress", err)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("loopback server was hit %d times, want 0", hits.Load())
+	}
+}
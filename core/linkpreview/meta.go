@@ -0,0 +1,165 @@
+package linkpreview
+
+import (
+	"html"
+	"strings"
+)
+
+// pageMeta is what a page says about itself.
+type pageMeta struct {
+	Title       string
+	Description string
+	SiteName    string
+	Type        string
+	Image       string // As written in the page; may be relative
+}
+
+// parseMeta reads the OpenGraph properties from the head of an HTML page,
+// falling back to Twitter card tags, the description meta tag and the title
+// element. The first value of each property wins. It is a small scanner, not
+// an HTML parser: it only needs to find tags in the head, and stops at the
+// body.
+func parseMeta(doc string) pageMeta {
+	var meta pageMeta
+	var title string
+	props := make(map[string]string)
+	lower := asciiLower(doc)
+	for i := 0; i < len(doc); {
+		lt := strings.IndexByte(doc[i:], '<')
+		if lt < 0 {
+			break
+		}
+		i += lt
+		rest := lower[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest, "-->")
+			if end < 0 {
+				i = len(doc)
+				continue
+			}
+			i += end + len("-->")
+			continue
+		case strings.HasPrefix(rest, "</head"), hasTag(rest, "body"):
+			i = len(doc)
+			continue
+		case hasTag(rest, "script"), hasTag(rest, "style"):
+			name := "</script"
+			if hasTag(rest, "style") {
+				name = "</style"
+			}
+			end := strings.Index(rest, name)
+			if end < 0 {
+				i = len(doc)
+				continue
+			}
+			i += end + len(name)
+			continue
+		case hasTag(rest, "title"):
+			start := strings.IndexByte(rest, '>')
+			end := strings.Index(rest, "</title")
+			if start >= 0 && end > start && title == "" {
+				title = html.UnescapeString(doc[i+start+1 : i+end])
+			}
+			i++
+			continue
+		case hasTag(rest, "meta"):
+			attrs, n := parseAttrs(doc[i+len("<meta"):])
+			key := attrs["property"]
+			if key == "" {
+				key = attrs["name"]
+			}
+			key = asciiLower(key)
+			if _, seen := props[key]; key != "" && !seen {
+				props[key] = attrs["content"]
+			}
+			i += len("<meta") + n
+			continue
+		}
+		i++
+	}
+
+	meta.Title = first(props["og:title"], props["twitter:title"], title)
+	meta.Description = first(props["og:description"], props["twitter:description"], props["description"])
+	meta.SiteName = props["og:site_name"]
+	meta.Type = props["og:type"]
+	meta.Image = strings.TrimSpace(first(props["og:image:secure_url"], props["og:image"], props["og:image:url"], props["twitter:image"]))
+	return meta
+}
+
+// hasTag reports whether s starts with the opening tag name.
+func hasTag(s, name string) bool {
+	if !strings.HasPrefix(s, "<"+name) || len(s) == len(name)+1 {
+		return false
+	}
+	switch s[len(name)+1] {
+	case ' ', '\t', '\n', '\r', '\f', '/', '>':
+		return true
+	}
+	return false
+}
+
+// parseAttrs parses the attributes of a tag from just after its name up to
+// the closing '>'. It returns the attributes, with lower-case names and
+// unescaped values, and the number of bytes consumed.
+func parseAttrs(s string) (map[string]string, int) {
+	attrs := make(map[string]string)
+	i := 0
+	for i < len(s) {
+		for i < len(s) && strings.IndexByte(" \t\n\r\f/", s[i]) >= 0 {
+			i++
+		}
+		if i >= len(s) || s[i] == '>' {
+			break
+		}
+		start := i
+		for i < len(s) && strings.IndexByte(" \t\n\r\f/=>", s[i]) < 0 {
+			i++
+		}
+		name := asciiLower(s[start:i])
+		value := ""
+		if i < len(s) && s[i] == '=' {
+			i++
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				quote := s[i]
+				end := strings.IndexByte(s[i+1:], quote)
+				if end < 0 {
+					return attrs, len(s)
+				}
+				value = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start := i
+				for i < len(s) && strings.IndexByte(" \t\n\r\f>", s[i]) < 0 {
+					i++
+				}
+				value = s[start:i]
+			}
+		}
+		if _, seen := attrs[name]; !seen {
+			attrs[name] = html.UnescapeString(value)
+		}
+	}
+	return attrs, i
+}
+
+// asciiLower lower-cases ASCII letters only, so byte offsets into the result
+// are offsets into s.
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,46 @@
+package linkpreview
+
+import "testing"
+
+func TestParseMeta(t *testing.T) {
+	doc := `<!DOCTYPE html>
+<HTML><Head>
+<!-- <meta property="og:title" content="commented out"> -->
+<title>Fallback &amp; title</title>
+<script>var s = '<meta property="og:title" content="in a script">';</script>
+<meta property=og:title content="Open &quot;Graph&quot;" />
+<meta property="og:title" content="second title">
+<META NAME="description" CONTENT='Plain description'>
+<meta property="og:site_name" content="Example">
+<meta property="og:image" content="/img/card.png">
+</head>
+<body><meta property="og:description" content="in the body"></body></HTML>`
+
+	meta := parseMeta(doc)
+	want := pageMeta{
+		Title:       `Open "Graph"`,
+		Description: "Plain description",
+		SiteName:    "Example",
+		Image:       "/img/card.png",
+	}
+	if meta != want {
+		t.Errorf("parseMeta() = %+v, want %+v", meta, want)
+	}
+
+	if meta := parseMeta("<title>Only a title</title><meta name=twitter:image content=x.jpg>"); meta.Title != "Only a title" || meta.Image != "x.jpg" {
+		t.Errorf("parseMeta() of a bare page = %+v, want the title and the Twitter image", meta)
+	}
+}
+
+func TestFindURLs(t *testing.T) {
+	got := FindURLs("Read (https://example.com/a?b=1). Also http://example.org, ftp://x and https:// nothing.")
+	want := []string{"https://example.com/a?b=1", "http://example.org"}
+	if len(got) != len(want) {
+		t.Fatalf("FindURLs() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("FindURLs()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
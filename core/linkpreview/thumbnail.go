@@ -0,0 +1,91 @@
+package linkpreview
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif" // Register decoders for the formats pages commonly use
+	"image/jpeg"
+	_ "image/png"
+)
+
+// maxImagePixels bounds the decoded size of a page image, so a small file
+// cannot claim huge dimensions and exhaust memory when decoded.
+const maxImagePixels = 25000000
+
+// thumbnail decodes a PNG, JPEG or GIF image and re-encodes it as a JPEG no
+// larger than size pixels on its longest side. Re-encoding also drops
+// anything in the file besides the pixels. It returns the JPEG and its
+// dimensions.
+func thumbnail(data []byte, size int) ([]byte, int, int, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to read image header: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxImagePixels {
+		return nil, 0, 0, fmt.Errorf("image is %dx%d, limit %d pixels", cfg.Width, cfg.Height, maxImagePixels)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	w, h := cfg.Width, cfg.Height
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max1(h*size/w)
+		} else {
+			w, h = max1(w*size/h), size
+		}
+	}
+	dst := scale(src, w, h)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), w, h, nil
+}
+
+// scale resizes src to w by h by averaging the source pixels under each
+// destination pixel, and flattens transparency onto white.
+func scale(src image.Image, w, h int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := b.Min.Y+y*sh/h, b.Min.Y+max1((y+1)*sh/h)
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < w; x++ {
+			x0, x1 := b.Min.X+x*sw/w, b.Min.X+max1((x+1)*sw/w)
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA() // Alpha-premultiplied
+					white := uint64(0xffff - pa)
+					r += uint64(pr) + white
+					g += uint64(pg) + white
+					bl += uint64(pb) + white
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(bl / n >> 8)
+			dst.Pix[i+3] = 0xff
+		}
+	}
+	return dst
+}
+
+func max1(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
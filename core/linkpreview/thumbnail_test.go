@@ -0,0 +1,54 @@
+package linkpreview
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestThumbnail(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 800, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 800; x++ {
+			if x < 400 {
+				src.Set(x, y, color.NRGBA{R: 0xff, A: 0xff})
+			} // The right half stays transparent
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, src)
+
+	data, w, h, err := thumbnail(buf.Bytes(), 100)
+	if err != nil {
+		t.Fatalf("thumbnail() error = %v", err)
+	}
+	if w != 100 || h != 25 {
+		t.Errorf("thumbnail() size = %dx%d, want 100x25", w, h)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("thumbnail is not a JPEG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 100 || b.Dy() != 25 {
+		t.Errorf("JPEG bounds = %v, want 100x25", b)
+	}
+	if r, g, _, _ := img.At(10, 10).RGBA(); r>>8 < 0xe0 || g>>8 > 0x30 {
+		t.Errorf("left pixel = %v, want red", img.At(10, 10))
+	}
+	if r, g, b, _ := img.At(90, 10).RGBA(); r>>8 < 0xe0 || g>>8 < 0xe0 || b>>8 < 0xe0 {
+		t.Errorf("right pixel = %v, want transparency flattened to white", img.At(90, 10))
+	}
+
+	small := image.NewGray(image.Rect(0, 0, 10, 20))
+	buf.Reset()
+	png.Encode(&buf, small)
+	if _, w, h, err := thumbnail(buf.Bytes(), 100); err != nil || w != 10 || h != 20 {
+		t.Errorf("thumbnail() of a small image = %dx%d, %v; want it kept at 10x20", w, h, err)
+	}
+	if _, _, _, err := thumbnail([]byte("<svg/>"), 100); err == nil {
+		t.Error("thumbnail() of a non-image expected error, got nil")
+	}
+}
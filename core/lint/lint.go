@@ -0,0 +1,166 @@
+// Package lint checks Post and Profile JSON before it is signed and submitted,
+// so that authors and importers learn everything wrong with a payload at
+// once instead of one mempool rejection at a time.
+//
+// A Report lists errors, which the ledger would refuse, and warnings, which
+// it would accept but which are probably mistakes: tags that are not in
+// normal form, or attachments that cannot be fetched from DDS.
+package lint
+
+import (
+	"digisocialblock/core/content"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"digisocialblock/core/user"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Severity is how serious a Finding is.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // The ledger would refuse the payload
+	SeverityWarning Severity = "warning" // Accepted, but probably a mistake
+)
+
+// Finding is one problem with a payload. Field is the JSON path of the field
+// concerned, e.g. "tags[2]", or empty for the payload as a whole.
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Field    string   `json:"field,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// Report is the result of linting one payload.
+type Report struct {
+	Type     ledger.TransactionType `json:"type"`
+	Findings []Finding              `json:"findings"`
+	// Tags is the post's tags in normal form, set when any differ from the
+	// tags given.
+	Tags []string `json:"normalizedTags,omitempty"`
+}
+
+// OK reports whether the payload has no errors; it may have warnings.
+func (r *Report) OK() bool {
+	return r.Errors() == 0
+}
+
+// Errors returns the number of error findings.
+func (r *Report) Errors() int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Severity == SeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *Report) add(severity Severity, field, format string, args ...interface{}) {
+	r.Findings = append(r.Findings, Finding{Severity: severity, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Options configures linting.
+type Options struct {
+	// Manifests, if set, is used to check that every attachment CID resolves
+	// to a manifest. Without it attachments are not checked.
+	Manifests content.DDSManifestFetcher
+}
+
+// Post lints data as the payload of a PostCreated transaction.
+func Post(data []byte, opts Options) *Report {
+	r := &Report{Type: ledger.PostCreated, Findings: []Finding{}}
+	checkSize(r, data)
+	var p social.Post
+	if err := ledger.DecodePayloadJSON(data, &p); err != nil {
+		r.add(SeverityError, "", "invalid post JSON: %v", err)
+		return r
+	}
+	if err := p.Validate(); err != nil {
+		r.add(SeverityError, "", "invalid post: %v", err)
+	}
+
+	normalized := make([]string, 0, len(p.Tags))
+	seen := make(map[string]int, len(p.Tags))
+	changed := false
+	for i, tag := range p.Tags {
+		field := fmt.Sprintf("tags[%d]", i)
+		norm := NormalizeTag(tag)
+		switch {
+		case norm == "":
+			r.add(SeverityWarning, field, "tag %q is empty once normalized and will be dropped", tag)
+			changed = true
+			continue
+		case norm != tag:
+			r.add(SeverityWarning, field, "tag %q is not in normal form; use %q", tag, norm)
+			changed = true
+		}
+		if j, dup := seen[norm]; dup {
+			r.add(SeverityWarning, field, "tag %q duplicates tags[%d] and will be dropped", tag, j)
+			changed = true
+			continue
+		}
+		seen[norm] = i
+		normalized = append(normalized, norm)
+	}
+	if changed {
+		r.Tags = normalized
+	}
+
+	checkCID(r, opts, "contentCID", p.ContentCID)
+	checkCID(r, opts, "previewCID", p.PreviewCID)
+	for i, cid := range p.Media {
+		checkCID(r, opts, fmt.Sprintf("media[%d]", i), cid)
+	}
+	return r
+}
+
+// Profile lints data as the payload of a ProfileUpdate transaction.
+func Profile(data []byte, opts Options) *Report {
+	r := &Report{Type: ledger.ProfileUpdate, Findings: []Finding{}}
+	checkSize(r, data)
+	var p user.Profile
+	if err := ledger.DecodePayloadJSON(data, &p); err != nil {
+		r.add(SeverityError, "", "invalid profile JSON: %v", err)
+		return r
+	}
+	if err := p.Validate(); err != nil {
+		r.add(SeverityError, "", "invalid profile: %v", err)
+	}
+	if strings.TrimSpace(p.DisplayName) != p.DisplayName {
+		r.add(SeverityWarning, "displayName", "display name has leading or trailing space")
+	}
+	if len(p.Signature) > 0 {
+		r.add(SeverityWarning, "signature", "profile is already signed; any change made after linting invalidates the signature")
+	}
+	checkCID(r, opts, "profilePictureCID", p.ProfilePictureCID)
+	checkCID(r, opts, "headerImageCID", p.HeaderImageCID)
+	return r
+}
+
+// NormalizeTag returns tag in normal form: without surrounding space or a
+// leading '#', lower case, and with runs of inner space replaced by '-'.
+// Tags are matched exactly, so "#Go" and "go" otherwise name different feeds.
+func NormalizeTag(tag string) string {
+	tag = strings.TrimLeft(strings.TrimSpace(tag), "#")
+	return strings.ToLower(strings.Join(strings.FieldsFunc(tag, unicode.IsSpace), "-"))
+}
+
+// checkSize reports a payload over the ledger's limit for its type.
+func checkSize(r *Report, data []byte) {
+	if limit := ledger.MaxPayloadSize(r.Type); len(data) > limit {
+		r.add(SeverityError, "", "payload is %d bytes, limit %d for %s", len(data), limit, r.Type)
+	}
+}
+
+// checkCID reports an attachment CID that does not resolve to a manifest.
+func checkCID(r *Report, opts Options, field, cid string) {
+	if cid == "" || opts.Manifests == nil {
+		return
+	}
+	if _, err := opts.Manifests.FetchManifest(cid); err != nil {
+		r.add(SeverityWarning, field, "%s cannot be resolved: %v", cid, err)
+	}
+}
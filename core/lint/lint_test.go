@@ -0,0 +1,67 @@
+package lint
+
+import (
+	"digisocialblock/core/social"
+	"digisocialblock/core/user"
+	"digisocialblock/internal/testutil"
+	"digisocialblock/pkg/dds/chunking"
+	"strings"
+	"testing"
+)
+
+// lintTestFields returns "severity:field" for each finding of r.
+func lintTestFields(r *Report) string {
+	var fields []string
+	for _, f := range r.Findings {
+		fields = append(fields, string(f.Severity)+":"+f.Field)
+	}
+	return strings.Join(fields, " ")
+}
+
+func TestPost_TagsAndAttachments(t *testing.T) {
+	manifests := testutil.NewManifestFetcher()
+	manifests.Add("cid-content", &chunking.ContentManifestV1{ManifestCID: "cid-content"})
+	post := social.NewPost("alice", "cid-content", "title", []string{"go", "#Go", " Open  Source ", "#"})
+	post.Media = []string{"cid-missing"}
+	data, _ := post.ToJSON()
+
+	r := Post(data, Options{Manifests: manifests})
+	if !r.OK() {
+		t.Errorf("Post() found errors in a valid post: %+v", r.Findings)
+	}
+	if got, want := lintTestFields(r), "warning:tags[1] warning:tags[1] warning:tags[2] warning:tags[3] warning:media[0]"; got != want {
+		t.Errorf("Post() findings = %s, want %s", got, want)
+	}
+	if got := strings.Join(r.Tags, ","); got != "go,open-source" {
+		t.Errorf("Post() normalized tags = %s, want go,open-source", got)
+	}
+
+	if r := Post(data, Options{}); len(r.Findings) != 4 {
+		t.Errorf("Post() without manifests = %+v, want attachments unchecked", r.Findings)
+	}
+}
+
+func TestPost_Errors(t *testing.T) {
+	for name, data := range map[string]string{
+		"unknown field": `{"authorPublicKey":"a","contentCID":"c","timestamp":1,"version":1,"colour":"red"}`,
+		"invalid":       `{"authorPublicKey":"a","contentCID":"","timestamp":1,"version":1}`,
+		"oversized":     `{"authorPublicKey":"a","contentCID":"c","timestamp":1,"version":1,"title":"` + strings.Repeat("x", 8<<10) + `"}`,
+	} {
+		if r := Post([]byte(data), Options{}); r.OK() {
+			t.Errorf("Post(%s) = %+v, want an error", name, r.Findings)
+		}
+	}
+}
+
+func TestProfile(t *testing.T) {
+	p := user.NewProfile("alice", " Alice", "")
+	p.ProfilePictureCID = "cid-missing"
+	data, _ := p.ToJSON()
+	r := Profile(data, Options{Manifests: testutil.NewManifestFetcher()})
+	if !r.OK() || lintTestFields(r) != "warning:displayName warning:profilePictureCID" {
+		t.Errorf("Profile() findings = %+v", r.Findings)
+	}
+	if r := Profile([]byte(`{"ownerPublicKey":"alice"}`), Options{}); r.OK() {
+		t.Error("Profile() accepted a profile without a timestamp or version")
+	}
+}
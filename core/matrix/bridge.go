@@ -0,0 +1,312 @@
+package matrix
+
+import (
+	"context"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/social"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Mailbox is the DM transport the bridge reads from and sends through.
+type Mailbox interface {
+	// Fetch returns the envelopes sent or received by the bridged user after
+	// cursor ("" for the beginning), oldest first, and the cursor to resume
+	// from.
+	Fetch(ctx context.Context, cursor string) ([]*social.DMEnvelope, string, error)
+	// Deliver sends an envelope to its recipient.
+	Deliver(ctx context.Context, env *social.DMEnvelope) error
+}
+
+// State is what the bridge remembers between runs: the room of each
+// conversation, which DMs and Matrix events correspond to each other, and how
+// far it has read on both sides. It is persisted as a single JSON file.
+type State struct {
+	mu            sync.Mutex
+	path          string
+	Rooms         map[string]string `json:"rooms"`     // Conversation ID -> room ID
+	Peers         map[string]string `json:"peers"`     // Room ID -> peer address
+	EventByDM     map[string]string `json:"eventByDm"` // DM envelope ID -> Matrix event ID
+	DMByEvent     map[string]string `json:"dmByEvent"` // Matrix event ID -> DM envelope ID
+	SyncToken     string            `json:"syncToken,omitempty"`
+	MailboxCursor string            `json:"mailboxCursor,omitempty"`
+}
+
+// OpenState loads the bridge state at path. A missing file is a fresh state.
+func OpenState(path string) (*State, error) {
+	if path == "" {
+		return nil, fmt.Errorf("bridge state path cannot be empty")
+	}
+	s := &State{path: path}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read bridge state %s: %w", path, err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, s); err != nil {
+			return nil, fmt.Errorf("failed to decode bridge state %s: %w", path, err)
+		}
+	}
+	for _, m := range []*map[string]string{&s.Rooms, &s.Peers, &s.EventByDM, &s.DMByEvent} {
+		if *m == nil {
+			*m = map[string]string{}
+		}
+	}
+	return s, nil
+}
+
+// save atomically rewrites the state file. The caller must hold s.mu.
+func (s *State) save() error {
+	data, err := json.Marshal(s)
+	if err == nil {
+		tmp := s.path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0600); err == nil {
+			err = os.Rename(tmp, s.path)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write bridge state %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// BridgeConfig configures a Bridge.
+type BridgeConfig struct {
+	// Wallet is the bridged user's wallet, used to open incoming DMs and to
+	// seal and sign outgoing ones.
+	Wallet *identity.Wallet
+	// MatrixUser is the Matrix account that speaks for Wallet, e.g.
+	// "@alice:example.org". It is invited to every room, and only its
+	// messages are relayed as DMs.
+	MatrixUser string
+	// BotUser is the Matrix account the client is logged in as (see
+	// Client.WhoAmI).
+	BotUser string
+	// Names maps addresses to display names for room names and message
+	// prefixes. Unnamed addresses are shown abbreviated.
+	Names map[string]string
+}
+
+// Bridge relays one user's DMs to Matrix rooms and the user's Matrix replies
+// back as DMs.
+//
+// Loops are prevented on both sides: messages the bot posts carry the DM's
+// envelope ID and are never relayed back, and every relayed pair is recorded
+// in the State so a DM sent from Matrix is not posted to Matrix again when the
+// mailbox returns it.
+type Bridge struct {
+	client  *Client
+	mailbox Mailbox
+	state   *State
+	cfg     BridgeConfig
+	mu      sync.Mutex // Serialises relay passes
+}
+
+// NewBridge creates a Bridge.
+func NewBridge(client *Client, mailbox Mailbox, state *State, cfg BridgeConfig) (*Bridge, error) {
+	if client == nil || mailbox == nil || state == nil {
+		return nil, fmt.Errorf("client, mailbox and state are required")
+	}
+	if cfg.Wallet == nil {
+		return nil, fmt.Errorf("wallet cannot be nil")
+	}
+	if cfg.MatrixUser == "" || cfg.BotUser == "" {
+		return nil, fmt.Errorf("matrix user and bot user are required")
+	}
+	if cfg.MatrixUser == cfg.BotUser {
+		return nil, fmt.Errorf("matrix user cannot be the bot user")
+	}
+	return &Bridge{client: client, mailbox: mailbox, state: state, cfg: cfg}, nil
+}
+
+// label returns the display name of address.
+func (b *Bridge) label(address string) string {
+	if name := b.cfg.Names[address]; name != "" {
+		return name
+	}
+	if len(address) > 12 {
+		return address[:12] + "…"
+	}
+	return address
+}
+
+// RelayDMs posts new DMs from the mailbox to their conversation rooms,
+// creating rooms as needed, and returns the number posted.
+func (b *Bridge) RelayDMs(ctx context.Context) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state.mu.Lock()
+	cursor := b.state.MailboxCursor
+	b.state.mu.Unlock()
+
+	envs, next, err := b.mailbox.Fetch(ctx, cursor)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch DMs: %w", err)
+	}
+	self := b.cfg.Wallet.Address
+	relayed := 0
+	for _, env := range envs {
+		id := env.ID()
+		b.state.mu.Lock()
+		_, done := b.state.EventByDM[id]
+		b.state.mu.Unlock()
+		if done || (env.Sender != self && env.Recipient != self) {
+			continue
+		}
+		text, err := env.Open(b.cfg.Wallet)
+		if err != nil {
+			continue // Unreadable or forged; nothing to show
+		}
+		roomID, err := b.room(ctx, env.ConversationID(), env.Peer(self))
+		if err != nil {
+			return relayed, err
+		}
+		eventID, err := b.client.SendMessage(ctx, roomID, id, MessageContent{
+			MsgType: "m.text",
+			Body:    b.label(env.Sender) + ": " + text,
+			DMID:    id,
+		})
+		if err != nil {
+			return relayed, fmt.Errorf("failed to post DM %s to %s: %w", id, roomID, err)
+		}
+		if err := b.record(id, eventID); err != nil {
+			return relayed, err
+		}
+		relayed++
+	}
+
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+	b.state.MailboxCursor = next
+	return relayed, b.state.save()
+}
+
+// room returns the room of a conversation, creating it if needed.
+func (b *Bridge) room(ctx context.Context, conversationID, peer string) (string, error) {
+	b.state.mu.Lock()
+	roomID, ok := b.state.Rooms[conversationID]
+	b.state.mu.Unlock()
+	if ok {
+		return roomID, nil
+	}
+	roomID, err := b.client.CreateRoom(ctx, "DM with "+b.label(peer), []string{b.cfg.MatrixUser})
+	if err != nil {
+		return "", fmt.Errorf("failed to create room for conversation with %s: %w", peer, err)
+	}
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+	b.state.Rooms[conversationID] = roomID
+	b.state.Peers[roomID] = peer
+	if err := b.state.save(); err != nil {
+		return "", err
+	}
+	return roomID, nil
+}
+
+// record links a DM envelope and a Matrix event and saves the state.
+func (b *Bridge) record(dmID, eventID string) error {
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+	b.state.EventByDM[dmID] = eventID
+	b.state.DMByEvent[eventID] = dmID
+	return b.state.save()
+}
+
+// RelayMatrix syncs with the homeserver, waiting up to timeout for new
+// events, and sends the mapped user's new messages in bridged rooms as DMs.
+// It returns the number sent. The sync position only advances once every
+// message in the batch has been sent, so a failed delivery is retried on the
+// next call.
+func (b *Bridge) RelayMatrix(ctx context.Context, timeout time.Duration) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state.mu.Lock()
+	since := b.state.SyncToken
+	b.state.mu.Unlock()
+
+	resp, err := b.client.Sync(ctx, since, timeout)
+	if err != nil {
+		return 0, fmt.Errorf("matrix sync failed: %w", err)
+	}
+	sent := 0
+	for roomID, room := range resp.Rooms.Join {
+		b.state.mu.Lock()
+		peer, bridged := b.state.Peers[roomID]
+		b.state.mu.Unlock()
+		if !bridged {
+			continue
+		}
+		for _, ev := range room.Timeline.Events {
+			ok, err := b.relayEvent(ctx, peer, ev)
+			if err != nil {
+				return sent, err
+			}
+			if ok {
+				sent++
+			}
+		}
+	}
+
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+	b.state.SyncToken = resp.NextBatch
+	return sent, b.state.save()
+}
+
+// relayEvent sends ev to peer as a DM if it is a new text message by the
+// mapped user. It reports whether a DM was sent.
+func (b *Bridge) relayEvent(ctx context.Context, peer string, ev RoomEvent) (bool, error) {
+	if ev.Type != "m.room.message" || ev.Sender != b.cfg.MatrixUser {
+		return false, nil
+	}
+	b.state.mu.Lock()
+	_, done := b.state.DMByEvent[ev.EventID]
+	b.state.mu.Unlock()
+	if done {
+		return false, nil
+	}
+	var content MessageContent
+	if err := json.Unmarshal(ev.Content, &content); err != nil || content.MsgType != "m.text" || content.DMID != "" || content.Body == "" {
+		return false, nil
+	}
+	env, err := social.SealDM(b.cfg.Wallet, peer, content.Body)
+	if err != nil {
+		return false, fmt.Errorf("failed to seal Matrix event %s: %w", ev.EventID, err)
+	}
+	if err := b.mailbox.Deliver(ctx, env); err != nil {
+		return false, fmt.Errorf("failed to deliver Matrix event %s: %w", ev.EventID, err)
+	}
+	return true, b.record(env.ID(), ev.EventID)
+}
+
+// Run relays in both directions until ctx is cancelled. Matrix sync
+// long-polls for up to interval; after an error the bridge waits interval
+// before trying again. It returns ctx.Err().
+func (b *Bridge) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	for {
+		_, err := b.RelayDMs(ctx)
+		if err == nil {
+			_, err = b.RelayMatrix(ctx, interval)
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err == nil {
+			continue
+		}
+		if onError != nil {
+			onError(err)
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package matrix
+
+import (
+	"context"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/social"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// bridgeTestMailbox is an in-memory DM transport; a cursor is an index into
+// its message list.
+type bridgeTestMailbox struct {
+	mu   sync.Mutex
+	envs []*social.DMEnvelope
+}
+
+func (m *bridgeTestMailbox) Fetch(ctx context.Context, cursor string) ([]*social.DMEnvelope, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	from, _ := strconv.Atoi(cursor)
+	return append([]*social.DMEnvelope(nil), m.envs[from:]...), strconv.Itoa(len(m.envs)), nil
+}
+
+func (m *bridgeTestMailbox) Deliver(ctx context.Context, env *social.DMEnvelope) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.envs = append(m.envs, env)
+	return nil
+}
+
+func newBridgeTest(t *testing.T, hs *clientTestHomeserver, mailbox Mailbox, alice *identity.Wallet, statePath string) *Bridge {
+	t.Helper()
+	client, _ := NewClient(hs.URL, "token", nil)
+	state, err := OpenState(statePath)
+	if err != nil {
+		t.Fatalf("OpenState() error = %v", err)
+	}
+	b, err := NewBridge(client, mailbox, state, BridgeConfig{Wallet: alice, MatrixUser: "@alice:hs", BotUser: "@bot:hs"})
+	if err != nil {
+		t.Fatalf("NewBridge() error = %v", err)
+	}
+	return b
+}
+
+func TestBridge_RelaysBothWaysWithoutLoops(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	bob, _ := identity.NewWallet()
+	hs := newClientTestHomeserver(t, "@bot:hs")
+	mailbox := &bridgeTestMailbox{}
+	statePath := filepath.Join(t.TempDir(), "matrix-bridge.json")
+	b := newBridgeTest(t, hs, mailbox, alice, statePath)
+	b.cfg.Names = map[string]string{bob.Address: "Bob"}
+	ctx := context.Background()
+
+	fromBob, _ := social.SealDM(bob, alice.Address, "hello alice")
+	mailbox.Deliver(ctx, fromBob)
+	if n, err := b.RelayDMs(ctx); err != nil || n != 1 {
+		t.Fatalf("RelayDMs() = %d, %v; want 1", n, err)
+	}
+	roomID := b.state.Rooms[social.DMConversationID(alice.Address, bob.Address)]
+	if invited := hs.rooms[roomID]; len(invited) != 1 || invited[0] != "@alice:hs" {
+		t.Fatalf("room %q invites %v, want the mapped Matrix user", roomID, invited)
+	}
+	if !strings.Contains(string(hs.events[0].Content), `"body":"Bob: hello alice"`) {
+		t.Errorf("relayed message = %s, want it labelled with Bob's name", hs.events[0].Content)
+	}
+
+	hs.say(roomID, "@alice:hs", "hi bob")
+	hs.say(roomID, "@mallory:hs", "not alice") // Not the mapped user
+	if n, err := b.RelayMatrix(ctx, 0); err != nil || n != 1 {
+		t.Fatalf("RelayMatrix() = %d, %v; want only alice's message", n, err)
+	}
+	reply := mailbox.envs[1]
+	if text, err := reply.Open(bob); err != nil || text != "hi bob" || reply.Sender != alice.Address {
+		t.Errorf("delivered DM = %q, %v from %s; want alice's reply", text, err, reply.Sender)
+	}
+
+	// Neither side echoes: the reply is not posted back to Matrix, and the
+	// bot's own message is not sent back as a DM.
+	if n, _ := b.RelayDMs(ctx); n != 0 {
+		t.Errorf("RelayDMs() after reply = %d, want 0", n)
+	}
+	if n, _ := b.RelayMatrix(ctx, 0); n != 0 {
+		t.Errorf("RelayMatrix() again = %d, want 0", n)
+	}
+
+	// A restarted bridge resumes from the saved state without duplicates.
+	fromBob2, _ := social.SealDM(bob, alice.Address, "are you there?")
+	mailbox.Deliver(ctx, fromBob2)
+	restarted := newBridgeTest(t, hs, mailbox, alice, statePath)
+	if n, err := restarted.RelayDMs(ctx); err != nil || n != 1 {
+		t.Errorf("RelayDMs() after restart = %d, %v; want only the new DM", n, err)
+	}
+	if len(hs.rooms) != 1 {
+		t.Errorf("%d rooms created, want the conversation's room reused", len(hs.rooms))
+	}
+}
+
+func TestBridge_SkipsForeignAndForgedDMs(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	bob, _ := identity.NewWallet()
+	carol, _ := identity.NewWallet()
+	hs := newClientTestHomeserver(t, "@bot:hs")
+	mailbox := &bridgeTestMailbox{}
+	b := newBridgeTest(t, hs, mailbox, alice, filepath.Join(t.TempDir(), "state.json"))
+
+	notForAlice, _ := social.SealDM(bob, carol.Address, "psst")
+	forged, _ := social.SealDM(bob, alice.Address, "hi")
+	forged.Sender = carol.Address
+	mailbox.envs = append(mailbox.envs, notForAlice, forged)
+	if n, err := b.RelayDMs(context.Background()); err != nil || n != 0 || len(hs.events) != 0 {
+		t.Errorf("RelayDMs() = %d, %v with %d events; want nothing relayed", n, err, len(hs.events))
+	}
+	if _, err := NewBridge(b.client, mailbox, b.state, BridgeConfig{Wallet: alice, MatrixUser: "@bot:hs", BotUser: "@bot:hs"}); err == nil {
+		t.Error("NewBridge() with the bot as the mapped user expected error, got nil")
+	}
+}
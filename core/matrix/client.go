@@ -0,0 +1,191 @@
+// Package matrix is an optional bridge that relays a user's encrypted direct
+// messages to and from Matrix, one room per conversation, so they can be read
+// and answered from any Matrix client.
+//
+// The bridge logs in to a homeserver as a bot account, which creates the
+// rooms and posts incoming messages. Only messages typed by the Matrix
+// account mapped to the user's wallet are sealed and sent back as DMs (see
+// Bridge).
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxResponseSize bounds the homeserver responses the client decodes.
+const maxResponseSize = 16 << 20
+
+// Error is an error response from the homeserver.
+type Error struct {
+	StatusCode   int    `json:"-"`
+	Code         string `json:"errcode"`
+	Message      string `json:"error"`
+	RetryAfterMs int64  `json:"retry_after_ms,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("matrix: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Client calls the Matrix client-server API with an access token.
+type Client struct {
+	homeserver  string
+	accessToken string
+	http        *http.Client
+}
+
+// NewClient creates a client for the homeserver base URL (e.g.
+// "https://matrix.example.org"). A nil httpClient uses http.DefaultClient.
+func NewClient(homeserver, accessToken string, httpClient *http.Client) (*Client, error) {
+	u, err := url.Parse(homeserver)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, fmt.Errorf("invalid homeserver URL %q", homeserver)
+	}
+	if accessToken == "" {
+		return nil, fmt.Errorf("access token cannot be empty")
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{homeserver: strings.TrimSuffix(homeserver, "/"), accessToken: accessToken, http: httpClient}, nil
+}
+
+// WhoAmI returns the user ID the access token belongs to.
+func (c *Client) WhoAmI(ctx context.Context) (string, error) {
+	var resp struct {
+		UserID string `json:"user_id"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/_matrix/client/v3/account/whoami", nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.UserID, nil
+}
+
+// CreateRoom creates a private direct-chat room with the given name, inviting
+// the listed users, and returns its room ID.
+func (c *Client) CreateRoom(ctx context.Context, name string, invite []string) (string, error) {
+	req := map[string]interface{}{
+		"name":      name,
+		"invite":    invite,
+		"is_direct": true,
+		"preset":    "trusted_private_chat",
+	}
+	var resp struct {
+		RoomID string `json:"room_id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/_matrix/client/v3/createRoom", req, &resp); err != nil {
+		return "", err
+	}
+	return resp.RoomID, nil
+}
+
+// MessageContent is the content of an m.room.message event. DMID is set on
+// messages the bridge relays from a DM, which is how it recognises its own
+// messages when they come back through sync.
+type MessageContent struct {
+	MsgType string `json:"msgtype"`
+	Body    string `json:"body"`
+	DMID    string `json:"dsb.dm_id,omitempty"`
+}
+
+// SendMessage sends an m.room.message event and returns its event ID. The
+// homeserver treats a repeated txnID as the same send, so retrying with the
+// same txnID cannot post a message twice.
+func (c *Client) SendMessage(ctx context.Context, roomID, txnID string, content MessageContent) (string, error) {
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomID) + "/send/m.room.message/" + url.PathEscape(txnID)
+	var resp struct {
+		EventID string `json:"event_id"`
+	}
+	if err := c.do(ctx, http.MethodPut, path, content, &resp); err != nil {
+		return "", err
+	}
+	return resp.EventID, nil
+}
+
+// SyncResponse is the part of a /sync response the bridge uses.
+type SyncResponse struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]JoinedRoom `json:"join"`
+	} `json:"rooms"`
+}
+
+// JoinedRoom holds the new timeline events of a joined room.
+type JoinedRoom struct {
+	Timeline struct {
+		Events []RoomEvent `json:"events"`
+	} `json:"timeline"`
+}
+
+// RoomEvent is a room timeline event.
+type RoomEvent struct {
+	EventID        string          `json:"event_id"`
+	Sender         string          `json:"sender"`
+	Type           string          `json:"type"`
+	Content        json.RawMessage `json:"content"`
+	OriginServerTS int64           `json:"origin_server_ts"`
+}
+
+// Sync returns events since the given batch token ("" for the initial sync),
+// waiting up to timeout for new ones.
+func (c *Client) Sync(ctx context.Context, since string, timeout time.Duration) (*SyncResponse, error) {
+	q := url.Values{"timeout": {strconv.FormatInt(timeout.Milliseconds(), 10)}}
+	if since != "" {
+		q.Set("since", since)
+	}
+	var resp SyncResponse
+	if err := c.do(ctx, http.MethodGet, "/_matrix/client/v3/sync?"+q.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// do sends a request with an optional JSON body and decodes the JSON response
+// into out. Non-2xx responses are returned as *Error.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode matrix request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.homeserver+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to build matrix request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("matrix %s %s: %w", method, strings.SplitN(path, "?", 2)[0], err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to read matrix response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		mErr := &Error{StatusCode: resp.StatusCode}
+		if json.Unmarshal(data, mErr) != nil || mErr.Code == "" {
+			mErr.Code, mErr.Message = "M_UNKNOWN", resp.Status
+		}
+		return mErr
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode matrix response: %w", err)
+	}
+	return nil
+}
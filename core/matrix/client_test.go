@@ -0,0 +1,168 @@
+package matrix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// clientTestHomeserver is an in-memory homeserver implementing the endpoints
+// the bridge uses. Every room event goes into one global timeline so a sync
+// token is simply an index into it.
+type clientTestHomeserver struct {
+	*httptest.Server
+	mu      sync.Mutex
+	bot     string
+	rooms   map[string][]string // Room ID -> invited users
+	events  []clientTestEvent
+	txns    map[string]string // Transaction ID -> event ID
+	failing bool              // Answer every request with M_LIMIT_EXCEEDED
+}
+
+type clientTestEvent struct {
+	room string
+	RoomEvent
+}
+
+func newClientTestHomeserver(t *testing.T, bot string) *clientTestHomeserver {
+	t.Helper()
+	hs := &clientTestHomeserver{bot: bot, rooms: map[string][]string{}, txns: map[string]string{}}
+	hs.Server = httptest.NewServer(http.HandlerFunc(hs.serve))
+	t.Cleanup(hs.Close)
+	return hs
+}
+
+func (hs *clientTestHomeserver) serve(w http.ResponseWriter, r *http.Request) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	reply := func(status int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+	if r.Header.Get("Authorization") != "Bearer token" {
+		reply(http.StatusUnauthorized, map[string]string{"errcode": "M_UNKNOWN_TOKEN", "error": "bad token"})
+		return
+	}
+	if hs.failing {
+		reply(http.StatusTooManyRequests, map[string]interface{}{"errcode": "M_LIMIT_EXCEEDED", "error": "slow down", "retry_after_ms": 500})
+		return
+	}
+	path := r.URL.EscapedPath()
+	switch {
+	case path == "/_matrix/client/v3/account/whoami":
+		reply(http.StatusOK, map[string]string{"user_id": hs.bot})
+	case path == "/_matrix/client/v3/createRoom" && r.Method == http.MethodPost:
+		var req struct {
+			Invite []string `json:"invite"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		roomID := fmt.Sprintf("!room%d:hs", len(hs.rooms)+1)
+		hs.rooms[roomID] = req.Invite
+		reply(http.StatusOK, map[string]string{"room_id": roomID})
+	case strings.HasPrefix(path, "/_matrix/client/v3/rooms/") && r.Method == http.MethodPut:
+		parts := strings.Split(strings.TrimPrefix(path, "/_matrix/client/v3/rooms/"), "/")
+		if len(parts) != 4 || parts[1] != "send" || parts[2] != "m.room.message" {
+			reply(http.StatusNotFound, map[string]string{"errcode": "M_UNRECOGNIZED", "error": path})
+			return
+		}
+		if id, ok := hs.txns[parts[3]]; ok {
+			reply(http.StatusOK, map[string]string{"event_id": id})
+			return
+		}
+		var content json.RawMessage
+		json.NewDecoder(r.Body).Decode(&content)
+		id := hs.append(mustUnescape(parts[0]), hs.bot, content)
+		hs.txns[parts[3]] = id
+		reply(http.StatusOK, map[string]string{"event_id": id})
+	case path == "/_matrix/client/v3/sync":
+		since, _ := strconv.Atoi(r.URL.Query().Get("since"))
+		var resp SyncResponse
+		resp.NextBatch = strconv.Itoa(len(hs.events))
+		resp.Rooms.Join = map[string]JoinedRoom{}
+		for _, ev := range hs.events[since:] {
+			room := resp.Rooms.Join[ev.room]
+			room.Timeline.Events = append(room.Timeline.Events, ev.RoomEvent)
+			resp.Rooms.Join[ev.room] = room
+		}
+		reply(http.StatusOK, resp)
+	default:
+		reply(http.StatusNotFound, map[string]string{"errcode": "M_UNRECOGNIZED", "error": path})
+	}
+}
+
+// append adds an event to the timeline. The caller must hold hs.mu.
+func (hs *clientTestHomeserver) append(roomID, sender string, content json.RawMessage) string {
+	id := fmt.Sprintf("$event%d", len(hs.events)+1)
+	hs.events = append(hs.events, clientTestEvent{roomID, RoomEvent{EventID: id, Sender: sender, Type: "m.room.message", Content: content}})
+	return id
+}
+
+// say posts a text message to roomID as sender.
+func (hs *clientTestHomeserver) say(roomID, sender, body string) string {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	content, _ := json.Marshal(MessageContent{MsgType: "m.text", Body: body})
+	return hs.append(roomID, sender, content)
+}
+
+func mustUnescape(s string) string {
+	u, err := url.PathUnescape(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func TestClient_RoundTrip(t *testing.T) {
+	hs := newClientTestHomeserver(t, "@bot:hs")
+	c, err := NewClient(hs.URL+"/", "token", nil)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	ctx := context.Background()
+	if who, err := c.WhoAmI(ctx); err != nil || who != "@bot:hs" {
+		t.Errorf("WhoAmI() = %q, %v; want @bot:hs", who, err)
+	}
+	roomID, err := c.CreateRoom(ctx, "DM", []string{"@alice:hs"})
+	if err != nil || hs.rooms[roomID][0] != "@alice:hs" {
+		t.Fatalf("CreateRoom() = %q, %v; want a room inviting alice", roomID, err)
+	}
+	first, err := c.SendMessage(ctx, roomID, "txn1", MessageContent{MsgType: "m.text", Body: "hi", DMID: "dm1"})
+	if err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	if again, _ := c.SendMessage(ctx, roomID, "txn1", MessageContent{MsgType: "m.text", Body: "hi"}); again != first {
+		t.Errorf("SendMessage() retry = %s, want the same event %s", again, first)
+	}
+
+	resp, err := c.Sync(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	events := resp.Rooms.Join[roomID].Timeline.Events
+	var content MessageContent
+	if len(events) != 1 || json.Unmarshal(events[0].Content, &content) != nil || content.DMID != "dm1" {
+		t.Errorf("Sync() events = %+v, want the message carrying its DM ID", events)
+	}
+	if resp, _ := c.Sync(ctx, resp.NextBatch, 0); len(resp.Rooms.Join) != 0 {
+		t.Errorf("Sync(next) = %+v, want nothing new", resp.Rooms.Join)
+	}
+
+	hs.failing = true
+	var mErr *Error
+	if _, err := c.WhoAmI(ctx); !errors.As(err, &mErr) || mErr.Code != "M_LIMIT_EXCEEDED" || mErr.RetryAfterMs != 500 {
+		t.Errorf("WhoAmI() error = %v, want M_LIMIT_EXCEEDED with retry_after_ms", err)
+	}
+	if _, err := NewClient("matrix.example.org", "token", nil); err == nil {
+		t.Error("NewClient() without a scheme expected error, got nil")
+	}
+}
@@ -0,0 +1,275 @@
+// Package migrate imports posts from other social networks' export archives:
+// it reads Twitter and Mastodon archives, publishes each post's text and
+// media to DDS and turns it into a PostCreated transaction that records the
+// original publication time in the post's Origin.
+package migrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Source names used in social.PostOrigin.Source.
+const (
+	SourceTwitter  = "twitter"
+	SourceMastodon = "mastodon"
+)
+
+// ErrUnknownArchive is returned when an archive is neither a Twitter nor a
+// Mastodon export.
+var ErrUnknownArchive = errors.New("not a Twitter or Mastodon archive")
+
+// LegacyPost is a post read from an archive.
+type LegacyPost struct {
+	Source    string    // SourceTwitter or SourceMastodon
+	ID        string    // ID of the post on the source network
+	URL       string    // Original URL, if the archive records one
+	Text      string    // Plain text
+	CreatedAt time.Time // Original publication time
+	Tags      []string  // Hashtags, without '#'
+	Media     []string  // Paths of attached media files within the archive
+}
+
+// Key identifies the post across imports.
+func (p *LegacyPost) Key() string {
+	return p.Source + ":" + p.ID
+}
+
+// ReadArchive detects the archive type and reads its posts, oldest first.
+// fsys is the unpacked archive, or a *zip.Reader for the archive itself.
+func ReadArchive(fsys fs.FS) ([]*LegacyPost, error) {
+	if _, err := fs.Stat(fsys, "outbox.json"); err == nil {
+		return ReadMastodonArchive(fsys)
+	}
+	if matches, _ := fs.Glob(fsys, "data/tweet*.js"); len(matches) > 0 {
+		return ReadTwitterArchive(fsys)
+	}
+	return nil, ErrUnknownArchive
+}
+
+// --- Twitter ---
+
+type twitterEntry struct {
+	Tweet twitterTweet `json:"tweet"`
+}
+
+type twitterTweet struct {
+	ID        string `json:"id_str"`
+	FullText  string `json:"full_text"`
+	CreatedAt string `json:"created_at"`
+	Entities  struct {
+		Hashtags []struct {
+			Text string `json:"text"`
+		} `json:"hashtags"`
+	} `json:"entities"`
+	ExtendedEntities struct {
+		Media []struct {
+			URL string `json:"url"`
+		} `json:"media"`
+	} `json:"extended_entities"`
+	RetweetedStatusID string `json:"retweeted_status_id_str"`
+}
+
+// twitterDataFile matches the tweet data files of old and new archive layouts
+// (tweet.js, tweets.js, tweets-part1.js, ...).
+var twitterDataFile = regexp.MustCompile(`^data/tweets?(-part\d+)?\.js$`)
+
+// ReadTwitterArchive reads the tweets in a Twitter archive. Retweets are
+// skipped: they are other people's posts.
+func ReadTwitterArchive(fsys fs.FS) ([]*LegacyPost, error) {
+	files, err := fs.Glob(fsys, "data/tweet*.js")
+	if err != nil {
+		return nil, err
+	}
+	var posts []*LegacyPost
+	read := 0
+	for _, name := range files {
+		if !twitterDataFile.MatchString(name) {
+			continue
+		}
+		read++
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		// The file is a script assigning the array to a global:
+		// window.YTD.tweets.part0 = [ ... ]
+		if data = bytes.TrimSpace(data); len(data) > 0 && data[0] != '[' {
+			if i := bytes.IndexByte(data, '='); i >= 0 {
+				data = data[i+1:]
+			}
+		}
+		var entries []twitterEntry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", name, err)
+		}
+		for _, entry := range entries {
+			post, err := twitterPost(fsys, &entry.Tweet)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			if post != nil {
+				posts = append(posts, post)
+			}
+		}
+	}
+	if read == 0 {
+		return nil, ErrUnknownArchive
+	}
+	sortPosts(posts)
+	return posts, nil
+}
+
+func twitterPost(fsys fs.FS, tw *twitterTweet) (*LegacyPost, error) {
+	if tw.RetweetedStatusID != "" || strings.HasPrefix(tw.FullText, "RT @") {
+		return nil, nil
+	}
+	created, err := time.Parse(time.RubyDate, tw.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("tweet %s has invalid created_at %q", tw.ID, tw.CreatedAt)
+	}
+	text := tw.FullText
+	for _, media := range tw.ExtendedEntities.Media {
+		text = strings.ReplaceAll(text, media.URL, "") // The t.co link to the media itself
+	}
+	post := &LegacyPost{
+		Source:    SourceTwitter,
+		ID:        tw.ID,
+		URL:       "https://twitter.com/i/web/status/" + tw.ID,
+		CreatedAt: created,
+	}
+	if len(tw.ExtendedEntities.Media) > 0 {
+		// Media files are stored as <tweet ID>-<name> in data/tweets_media
+		// (data/tweet_media in older archives).
+		for _, dir := range []string{"data/tweets_media/", "data/tweet_media/"} {
+			matches, _ := fs.Glob(fsys, dir+tw.ID+"-*")
+			post.Media = append(post.Media, matches...)
+		}
+	}
+	post.Text = strings.TrimSpace(html.UnescapeString(text))
+	for _, tag := range tw.Entities.Hashtags {
+		post.Tags = append(post.Tags, tag.Text)
+	}
+	return post, nil
+}
+
+// --- Mastodon ---
+
+// publicAudience is the ActivityStreams public collection.
+const publicAudience = "https://www.w3.org/ns/activitystreams#Public"
+
+type mastodonOutbox struct {
+	OrderedItems []mastodonActivity `json:"orderedItems"`
+}
+
+type mastodonActivity struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"` // A Note for Create, a URL for Announce
+}
+
+type mastodonNote struct {
+	ID        string   `json:"id"`
+	Type      string   `json:"type"`
+	URL       string   `json:"url"`
+	Published string   `json:"published"`
+	Content   string   `json:"content"`
+	To        []string `json:"to"`
+	CC        []string `json:"cc"`
+	Tag       []struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	} `json:"tag"`
+	Attachment []struct {
+		URL string `json:"url"`
+	} `json:"attachment"`
+}
+
+// ReadMastodonArchive reads the posts in a Mastodon archive's outbox.json.
+// Only public and unlisted posts are read; followers-only posts and direct
+// messages must not end up on a public chain. Boosts are skipped.
+func ReadMastodonArchive(fsys fs.FS) ([]*LegacyPost, error) {
+	data, err := fs.ReadFile(fsys, "outbox.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox.json: %w", err)
+	}
+	var outbox mastodonOutbox
+	if err := json.Unmarshal(data, &outbox); err != nil {
+		return nil, fmt.Errorf("failed to decode outbox.json: %w", err)
+	}
+	var posts []*LegacyPost
+	for _, activity := range outbox.OrderedItems {
+		if activity.Type != "Create" {
+			continue
+		}
+		var note mastodonNote
+		if err := json.Unmarshal(activity.Object, &note); err != nil || note.Type != "Note" {
+			continue
+		}
+		if !contains(note.To, publicAudience) && !contains(note.CC, publicAudience) {
+			continue
+		}
+		created, err := time.Parse(time.RFC3339, note.Published)
+		if err != nil {
+			return nil, fmt.Errorf("post %s has invalid published time %q", note.ID, note.Published)
+		}
+		post := &LegacyPost{
+			Source:    SourceMastodon,
+			ID:        note.ID,
+			URL:       note.URL,
+			Text:      htmlToText(note.Content),
+			CreatedAt: created,
+		}
+		for _, tag := range note.Tag {
+			if tag.Type == "Hashtag" {
+				post.Tags = append(post.Tags, strings.TrimPrefix(tag.Name, "#"))
+			}
+		}
+		for _, att := range note.Attachment {
+			// Attachment URLs are archive-relative, e.g. /media_attachments/files/...
+			name := strings.TrimPrefix(att.URL, "/")
+			if _, err := fs.Stat(fsys, name); err == nil {
+				post.Media = append(post.Media, name)
+			}
+		}
+		posts = append(posts, post)
+	}
+	sortPosts(posts)
+	return posts, nil
+}
+
+var (
+	htmlBreak = regexp.MustCompile(`(?i)<br\s*/?>`)
+	htmlPara  = regexp.MustCompile(`(?i)</p>\s*<p[^>]*>`)
+	htmlTag   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// htmlToText converts the restricted HTML Mastodon stores post content in to
+// plain text, keeping line and paragraph breaks.
+func htmlToText(s string) string {
+	s = htmlPara.ReplaceAllString(s, "\n\n")
+	s = htmlBreak.ReplaceAllString(s, "\n")
+	s = htmlTag.ReplaceAllString(s, "")
+	return strings.TrimSpace(html.UnescapeString(s))
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sortPosts orders posts oldest first, so imports replay history in order.
+func sortPosts(posts []*LegacyPost) {
+	sort.SliceStable(posts, func(i, j int) bool { return posts[i].CreatedAt.Before(posts[j].CreatedAt) })
+}
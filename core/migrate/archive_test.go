@@ -0,0 +1,114 @@
+package migrate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+const testTweets = `window.YTD.tweets.part0 = [
+  {"tweet": {
+    "id_str": "200",
+    "full_text": "Second &amp; last #golang https://t.co/pic",
+    "created_at": "Thu Oct 11 08:00:00 +0000 2018",
+    "entities": {"hashtags": [{"text": "golang"}]},
+    "extended_entities": {"media": [{"url": "https://t.co/pic", "media_url_https": "https://pbs.twimg.com/media/abc.jpg"}]}
+  }},
+  {"tweet": {
+    "id_str": "100",
+    "full_text": "First post",
+    "created_at": "Wed Oct 10 20:19:24 +0000 2018",
+    "entities": {"hashtags": []}
+  }},
+  {"tweet": {
+    "id_str": "150",
+    "full_text": "RT @someone: not mine",
+    "created_at": "Wed Oct 10 21:00:00 +0000 2018",
+    "retweeted_status_id_str": "99"
+  }}
+]`
+
+const testOutbox = `{
+  "@context": "https://www.w3.org/ns/activitystreams",
+  "type": "OrderedCollection",
+  "orderedItems": [
+    {"type": "Create", "object": {
+      "id": "https://example.social/users/alice/statuses/1", "type": "Note",
+      "url": "https://example.social/@alice/1",
+      "published": "2022-11-05T10:00:00Z",
+      "to": ["https://www.w3.org/ns/activitystreams#Public"],
+      "content": "<p>Hello <a href=\"https://example.social/tags/fediverse\">#<span>fediverse</span></a></p><p>line one<br>line &lt;two&gt;</p>",
+      "tag": [{"type": "Hashtag", "name": "#fediverse"}, {"type": "Mention", "name": "@bob"}],
+      "attachment": [{"type": "Document", "url": "/media_attachments/files/1/original/cat.png"}]
+    }},
+    {"type": "Create", "object": {
+      "id": "https://example.social/users/alice/statuses/2", "type": "Note",
+      "published": "2022-11-06T10:00:00Z",
+      "to": ["https://example.social/users/alice/followers"],
+      "content": "<p>followers only</p>"
+    }},
+    {"type": "Announce", "object": "https://other.social/notes/9"}
+  ]
+}`
+
+func TestReadTwitterArchive(t *testing.T) {
+	fsys := fstest.MapFS{
+		"data/tweets.js":                  {Data: []byte(testTweets)},
+		"data/tweets_media/200-abc.jpg":   {Data: []byte("jpeg")},
+		"data/tweets_media/999-other.jpg": {Data: []byte("jpeg")},
+		"data/tweetdeck.js":               {Data: []byte("not tweets")},
+	}
+	posts, err := ReadArchive(fsys)
+	if err != nil {
+		t.Fatalf("ReadArchive() error = %v", err)
+	}
+	want := []*LegacyPost{
+		{Source: SourceTwitter, ID: "100", URL: "https://twitter.com/i/web/status/100", Text: "First post",
+			CreatedAt: time.Date(2018, 10, 10, 20, 19, 24, 0, time.UTC)},
+		{Source: SourceTwitter, ID: "200", URL: "https://twitter.com/i/web/status/200", Text: "Second & last #golang",
+			CreatedAt: time.Date(2018, 10, 11, 8, 0, 0, 0, time.UTC), Tags: []string{"golang"}, Media: []string{"data/tweets_media/200-abc.jpg"}},
+	}
+	if len(posts) != len(want) {
+		t.Fatalf("ReadArchive() returned %d posts, want %d (retweets skipped)", len(posts), len(want))
+	}
+	for i := range want {
+		posts[i].CreatedAt = posts[i].CreatedAt.UTC()
+		if !reflect.DeepEqual(posts[i], want[i]) {
+			t.Errorf("post %d = %+v, want %+v", i, posts[i], want[i])
+		}
+	}
+}
+
+func TestReadMastodonArchive(t *testing.T) {
+	fsys := fstest.MapFS{
+		"outbox.json": {Data: []byte(testOutbox)},
+		"media_attachments/files/1/original/cat.png": {Data: []byte("png")},
+	}
+	posts, err := ReadArchive(fsys)
+	if err != nil {
+		t.Fatalf("ReadArchive() error = %v", err)
+	}
+	if len(posts) != 1 {
+		t.Fatalf("ReadArchive() returned %d posts, want only the public one", len(posts))
+	}
+	want := &LegacyPost{
+		Source:    SourceMastodon,
+		ID:        "https://example.social/users/alice/statuses/1",
+		URL:       "https://example.social/@alice/1",
+		Text:      "Hello #fediverse\n\nline one\nline <two>",
+		CreatedAt: time.Date(2022, 11, 5, 10, 0, 0, 0, time.UTC),
+		Tags:      []string{"fediverse"},
+		Media:     []string{"media_attachments/files/1/original/cat.png"},
+	}
+	if !reflect.DeepEqual(posts[0], want) {
+		t.Errorf("post = %+v, want %+v", posts[0], want)
+	}
+}
+
+func TestReadArchive_Unknown(t *testing.T) {
+	if _, err := ReadArchive(fstest.MapFS{"README.txt": {Data: []byte("hi")}}); !errors.Is(err, ErrUnknownArchive) {
+		t.Errorf("ReadArchive() error = %v, want ErrUnknownArchive", err)
+	}
+}
@@ -0,0 +1,205 @@
+package migrate
+
+import (
+	"context"
+	"digisocialblock/core/content"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"fmt"
+	"io/fs"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultMaxPostsPerSecond is the submission rate used when
+// ImporterOptions.MaxPostsPerSecond is not set. It keeps a large archive
+// import well inside a node's per-sender rate limit.
+const DefaultMaxPostsPerSecond = 1
+
+// TransactionSink receives the signed transactions an import produces, e.g.
+// by submitting them to a node.
+type TransactionSink interface {
+	Submit(ctx context.Context, tx *ledger.Transaction) error
+}
+
+// ImporterOptions configures an Importer.
+type ImporterOptions struct {
+	// MaxPostsPerSecond limits how fast transactions are handed to the sink.
+	// Zero means DefaultMaxPostsPerSecond.
+	MaxPostsPerSecond float64
+}
+
+// ImportStats summarises an Import call.
+type ImportStats struct {
+	Imported int      // Posts submitted by this call
+	Skipped  int      // Posts already imported by an earlier run, or with nothing to import
+	Warnings []string // Problems that did not stop a post from being imported
+}
+
+// Importer re-publishes archived posts as the wallet's own posts.
+//
+// Each post gets a fresh PostCreated transaction with the current time; the
+// original publication time and URL are kept in the post's Origin, so feeds
+// order imported posts by when they arrived on the chain while clients can
+// still show when they were first written.
+type Importer struct {
+	publisher *content.ContentPublisher
+	wallet    *identity.Wallet
+	sink      TransactionSink
+	progress  *Progress
+	interval  time.Duration
+	last      time.Time // When the last transaction was submitted
+}
+
+// NewImporter creates an Importer that publishes content through publisher,
+// signs with wallet, submits to sink and records each post in progress.
+func NewImporter(publisher *content.ContentPublisher, wallet *identity.Wallet, sink TransactionSink, progress *Progress, opts ImporterOptions) (*Importer, error) {
+	if publisher == nil || wallet == nil || sink == nil || progress == nil {
+		return nil, fmt.Errorf("publisher, wallet, sink and progress cannot be nil")
+	}
+	if opts.MaxPostsPerSecond < 0 {
+		return nil, fmt.Errorf("max posts per second cannot be negative")
+	}
+	if opts.MaxPostsPerSecond == 0 {
+		opts.MaxPostsPerSecond = DefaultMaxPostsPerSecond
+	}
+	return &Importer{
+		publisher: publisher,
+		wallet:    wallet,
+		sink:      sink,
+		progress:  progress,
+		interval:  time.Duration(float64(time.Second) / opts.MaxPostsPerSecond),
+	}, nil
+}
+
+// Import publishes posts in order, reading their media from archive. Posts
+// already in the progress log are skipped, so an interrupted import can be
+// run again with the same arguments. It stops at the first post that cannot
+// be imported, or when ctx is done.
+//
+// A post is recorded in the progress log only after the sink accepted it; if
+// the process dies in between, that one post is imported again on resume.
+func (im *Importer) Import(ctx context.Context, posts []*LegacyPost, archive fs.FS) (ImportStats, error) {
+	var stats ImportStats
+	for _, lp := range posts {
+		if _, ok := im.progress.Done(lp.Key()); ok {
+			stats.Skipped++
+			continue
+		}
+		tx, warnings, err := im.buildTransaction(lp, archive)
+		stats.Warnings = append(stats.Warnings, warnings...)
+		if err != nil {
+			return stats, fmt.Errorf("post %s: %w", lp.Key(), err)
+		}
+		if tx == nil {
+			stats.Skipped++
+			continue
+		}
+		if err := im.wait(ctx); err != nil {
+			return stats, err
+		}
+		if err := im.sink.Submit(ctx, tx); err != nil {
+			return stats, fmt.Errorf("failed to submit post %s: %w", lp.Key(), err)
+		}
+		im.last = time.Now()
+		if err := im.progress.record(lp.Key(), tx.ID); err != nil {
+			return stats, err
+		}
+		stats.Imported++
+	}
+	return stats, nil
+}
+
+// buildTransaction publishes the post's content and returns its signed
+// transaction, or nil if the post has neither text nor media.
+func (im *Importer) buildTransaction(lp *LegacyPost, archive fs.FS) (*ledger.Transaction, []string, error) {
+	var warnings []string
+	var mediaCIDs []string
+	for i, name := range lp.Media {
+		if i == social.MaxPostMedia {
+			warnings = append(warnings, fmt.Sprintf("post %s: dropped %d media attachments over the limit of %d", lp.Key(), len(lp.Media)-i, social.MaxPostMedia))
+			break
+		}
+		data, err := fs.ReadFile(archive, name)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("post %s: skipped media %s: %v", lp.Key(), name, err))
+			continue
+		}
+		cid, err := im.publisher.PublishMediaToDDS(data)
+		if err != nil {
+			return nil, warnings, fmt.Errorf("failed to publish media %s to DDS: %w", name, err)
+		}
+		mediaCIDs = append(mediaCIDs, cid)
+	}
+
+	// A media-only post uses its first attachment as its content.
+	var contentCID string
+	switch {
+	case lp.Text != "":
+		cid, err := im.publisher.PublishTextPostToDDS(lp.Text)
+		if err != nil {
+			return nil, warnings, fmt.Errorf("failed to publish post text to DDS: %w", err)
+		}
+		contentCID = cid
+	case len(mediaCIDs) > 0:
+		contentCID = mediaCIDs[0]
+	default:
+		return nil, append(warnings, fmt.Sprintf("post %s: skipped, it has no text or media", lp.Key())), nil
+	}
+
+	post := social.NewPost(im.wallet.Address, contentCID, "", importableTags(lp.Tags))
+	post.Media = mediaCIDs
+	post.Origin = &social.PostOrigin{Source: lp.Source, Timestamp: lp.CreatedAt.UnixNano()}
+	if len(lp.URL) <= social.MaxOriginURLLength {
+		post.Origin.URL = lp.URL
+	}
+	if err := post.Validate(); err != nil {
+		return nil, warnings, fmt.Errorf("does not map to a valid post: %w", err)
+	}
+	payload, err := post.ToJSON()
+	if err != nil {
+		return nil, warnings, fmt.Errorf("failed to serialize post metadata to JSON: %w", err)
+	}
+	tx, err := ledger.NewTransaction(im.wallet.Address, ledger.PostCreated, payload)
+	if err != nil {
+		return nil, warnings, fmt.Errorf("failed to create new ledger transaction for post: %w", err)
+	}
+	if err := im.wallet.SignTransaction(tx); err != nil {
+		return nil, warnings, fmt.Errorf("failed to sign post transaction: %w", err)
+	}
+	return tx, warnings, nil
+}
+
+// wait blocks until the next transaction may be submitted.
+func (im *Importer) wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	delay := time.Until(im.last.Add(im.interval))
+	if im.last.IsZero() || delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// importableTags keeps the hashtags that fit post tag limits.
+func importableTags(hashtags []string) []string {
+	var tags []string
+	for _, tag := range hashtags {
+		if len(tags) == social.MaxPostTags {
+			break
+		}
+		if tag != "" && utf8.RuneCountInString(tag) <= social.MaxPostTagLength {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,151 @@
+package migrate
+
+import (
+	"context"
+	"digisocialblock/core/content"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"digisocialblock/internal/testutil"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// importTestSink collects submitted transactions and can fail after a number of them.
+type importTestSink struct {
+	txs     []*ledger.Transaction
+	failAt  int
+	submits []time.Time
+}
+
+func (s *importTestSink) Submit(ctx context.Context, tx *ledger.Transaction) error {
+	if s.failAt > 0 && len(s.txs) == s.failAt {
+		return errors.New("node unavailable")
+	}
+	s.txs = append(s.txs, tx)
+	s.submits = append(s.submits, time.Now())
+	return nil
+}
+
+func newTestImporter(t *testing.T, sink TransactionSink, progressPath string, rate float64) (*Importer, *identity.Wallet) {
+	t.Helper()
+	dds := testutil.NewDDS(0)
+	publisher, _ := content.NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	wallet, err := identity.NewWallet()
+	if err != nil {
+		t.Fatalf("NewWallet() error = %v", err)
+	}
+	t.Cleanup(func() { wallet.Close() })
+	progress, err := OpenProgress(progressPath)
+	if err != nil {
+		t.Fatalf("OpenProgress() error = %v", err)
+	}
+	t.Cleanup(func() { progress.Close() })
+	im, err := NewImporter(publisher, wallet, sink, progress, ImporterOptions{MaxPostsPerSecond: rate})
+	if err != nil {
+		t.Fatalf("NewImporter() error = %v", err)
+	}
+	return im, wallet
+}
+
+func testLegacyPosts() []*LegacyPost {
+	created := time.Date(2018, 10, 10, 20, 19, 24, 0, time.UTC)
+	return []*LegacyPost{
+		{Source: SourceTwitter, ID: "1", URL: "https://twitter.com/i/web/status/1", Text: "hello", CreatedAt: created, Tags: []string{"go", ""}},
+		{Source: SourceTwitter, ID: "2", CreatedAt: created.Add(time.Hour), Media: []string{"data/tweets_media/2-a.jpg", "data/tweets_media/2-missing.jpg"}},
+		{Source: SourceTwitter, ID: "3", CreatedAt: created.Add(2 * time.Hour)},
+	}
+}
+
+func TestImporter_Import(t *testing.T) {
+	archive := fstest.MapFS{"data/tweets_media/2-a.jpg": {Data: []byte("jpeg bytes")}}
+	sink := &importTestSink{}
+	im, wallet := newTestImporter(t, sink, filepath.Join(t.TempDir(), "progress"), 1000)
+
+	before := time.Now().UnixNano()
+	stats, err := im.Import(context.Background(), testLegacyPosts(), archive)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if stats.Imported != 2 || stats.Skipped != 1 || len(stats.Warnings) != 2 {
+		t.Errorf("Import() stats = %+v, want 2 imported, 1 skipped (empty) and 2 warnings", stats)
+	}
+	if len(sink.txs) != 2 {
+		t.Fatalf("sink received %d transactions, want 2", len(sink.txs))
+	}
+
+	first, err := social.PostFromPayload(sink.txs[0].Payload)
+	if err != nil {
+		t.Fatalf("imported payload is not a valid post: %v", err)
+	}
+	if first.AuthorPublicKey != wallet.Address || sink.txs[0].SenderPublicKey != wallet.Address || len(sink.txs[0].Signature) == 0 {
+		t.Error("imported post is not authored and signed by the wallet")
+	}
+	if first.Origin == nil || first.Origin.Source != SourceTwitter || first.Origin.URL != "https://twitter.com/i/web/status/1" ||
+		first.Origin.Timestamp != testLegacyPosts()[0].CreatedAt.UnixNano() {
+		t.Errorf("post origin = %+v, want the original source, URL and time", first.Origin)
+	}
+	if first.Timestamp < before || sink.txs[0].Timestamp < before {
+		t.Error("post and transaction should carry the import time, not the original time")
+	}
+	if len(first.Tags) != 1 || first.Tags[0] != "go" {
+		t.Errorf("post tags = %v, want [go]", first.Tags)
+	}
+
+	second, _ := social.PostFromPayload(sink.txs[1].Payload)
+	if len(second.Media) != 1 || second.ContentCID != second.Media[0] {
+		t.Errorf("media-only post = %+v, want its one found attachment as content", second)
+	}
+}
+
+func TestImporter_ResumesAfterFailure(t *testing.T) {
+	progressPath := filepath.Join(t.TempDir(), "progress")
+	posts := []*LegacyPost{
+		{Source: SourceMastodon, ID: "a", Text: "one", CreatedAt: time.Unix(1, 0)},
+		{Source: SourceMastodon, ID: "b", Text: "two", CreatedAt: time.Unix(2, 0)},
+		{Source: SourceMastodon, ID: "c", Text: "three", CreatedAt: time.Unix(3, 0)},
+	}
+
+	failing := &importTestSink{failAt: 2}
+	im, _ := newTestImporter(t, failing, progressPath, 1000)
+	if stats, err := im.Import(context.Background(), posts, fstest.MapFS{}); err == nil || stats.Imported != 2 {
+		t.Fatalf("Import() = %+v, %v; want 2 imported and then an error", stats, err)
+	}
+	im.progress.Close()
+
+	sink := &importTestSink{}
+	im, _ = newTestImporter(t, sink, progressPath, 1000)
+	stats, err := im.Import(context.Background(), posts, fstest.MapFS{})
+	if err != nil {
+		t.Fatalf("resumed Import() error = %v", err)
+	}
+	if stats.Imported != 1 || stats.Skipped != 2 || len(sink.txs) != 1 {
+		t.Errorf("resumed Import() = %+v with %d submissions, want only post c imported", stats, len(sink.txs))
+	}
+}
+
+func TestImporter_RateLimit(t *testing.T) {
+	posts := []*LegacyPost{
+		{Source: SourceMastodon, ID: "a", Text: "one", CreatedAt: time.Unix(1, 0)},
+		{Source: SourceMastodon, ID: "b", Text: "two", CreatedAt: time.Unix(2, 0)},
+		{Source: SourceMastodon, ID: "c", Text: "three", CreatedAt: time.Unix(3, 0)},
+	}
+	sink := &importTestSink{}
+	im, _ := newTestImporter(t, sink, filepath.Join(t.TempDir(), "progress"), 20)
+	if _, err := im.Import(context.Background(), posts, fstest.MapFS{}); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if gap := sink.submits[2].Sub(sink.submits[0]); gap < 90*time.Millisecond {
+		t.Errorf("3 posts at 20/s were submitted within %v, want at least 100ms", gap)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	im, _ = newTestImporter(t, &importTestSink{}, filepath.Join(t.TempDir(), "progress"), 20)
+	if _, err := im.Import(ctx, posts, fstest.MapFS{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Import() with a cancelled context error = %v, want context.Canceled", err)
+	}
+}
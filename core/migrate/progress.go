@@ -0,0 +1,107 @@
+package migrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// progressRecord is one line of the progress log.
+type progressRecord struct {
+	Key  string `json:"key"`  // LegacyPost.Key
+	TxID string `json:"txId"` // Transaction the post was imported as
+}
+
+// Progress is an append-only log of imported posts, one JSON line per post,
+// fsynced after every append. It lets an interrupted import resume without
+// importing any post twice. Archives can hold tens of thousands of posts, so
+// the log is appended to rather than rewritten like the smaller JSON state
+// files elsewhere.
+type Progress struct {
+	mu   sync.Mutex
+	file *os.File
+	done map[string]string // Post key -> transaction ID
+}
+
+// OpenProgress opens (or creates) the progress log at path. A torn last line
+// left by a crash is dropped; that post is simply imported again.
+func OpenProgress(path string) (*Progress, error) {
+	if path == "" {
+		return nil, fmt.Errorf("progress log path cannot be empty")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read progress log %s: %w", path, err)
+	}
+	p := &Progress{done: make(map[string]string)}
+	valid := 0 // Length of the prefix made of complete, well-formed lines
+	for {
+		n := bytes.IndexByte(data[valid:], '\n')
+		if n < 0 {
+			break
+		}
+		var rec progressRecord
+		if json.Unmarshal(data[valid:valid+n], &rec) != nil || rec.Key == "" {
+			break
+		}
+		p.done[rec.Key] = rec.TxID
+		valid += n + 1
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open progress log %s: %w", path, err)
+	}
+	if err = file.Truncate(int64(valid)); err == nil {
+		_, err = file.Seek(int64(valid), io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to repair progress log %s: %w", path, err)
+	}
+	p.file = file
+	return p, nil
+}
+
+// Done returns the transaction a post was imported as.
+func (p *Progress) Done(key string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	txID, ok := p.done[key]
+	return txID, ok
+}
+
+// Len returns the number of imported posts.
+func (p *Progress) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.done)
+}
+
+// record durably notes that the post with key was imported as txID.
+func (p *Progress) record(key, txID string) error {
+	line, err := json.Marshal(progressRecord{Key: key, TxID: txID})
+	if err != nil {
+		return fmt.Errorf("failed to encode progress record: %w", err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write progress log: %w", err)
+	}
+	if err := p.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync progress log: %w", err)
+	}
+	p.done[key] = txID
+	return nil
+}
+
+// Close closes the log file.
+func (p *Progress) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.file.Close()
+}
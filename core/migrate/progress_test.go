@@ -0,0 +1,47 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProgress_PersistsAndDropsTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "import.progress")
+	p, err := OpenProgress(path)
+	if err != nil {
+		t.Fatalf("OpenProgress() error = %v", err)
+	}
+	if err := p.record("twitter:1", "tx1"); err != nil {
+		t.Fatalf("record() error = %v", err)
+	}
+	if err := p.record("twitter:2", "tx2"); err != nil {
+		t.Fatalf("record() error = %v", err)
+	}
+	p.Close()
+
+	// Simulate a crash in the middle of a write.
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	f.WriteString(`{"key":"twitter:3","tx`)
+	f.Close()
+
+	p, err = OpenProgress(path)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer p.Close()
+	if txID, ok := p.Done("twitter:2"); !ok || txID != "tx2" {
+		t.Errorf("Done(twitter:2) = %q, %v; want tx2, true", txID, ok)
+	}
+	if _, ok := p.Done("twitter:3"); ok || p.Len() != 2 {
+		t.Errorf("torn record was loaded; Len() = %d, want 2", p.Len())
+	}
+	if err := p.record("twitter:3", "tx3"); err != nil {
+		t.Fatalf("record() after repair error = %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	want := `{"key":"twitter:1","txId":"tx1"}` + "\n" + `{"key":"twitter:2","txId":"tx2"}` + "\n" + `{"key":"twitter:3","txId":"tx3"}` + "\n"
+	if string(data) != want {
+		t.Errorf("progress log = %q, want %q", data, want)
+	}
+}
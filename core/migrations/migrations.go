@@ -0,0 +1,174 @@
+// Package migrations versions the formats of data a node keeps on disk, such
+// as the block log, chunk stores and social indexes, so the formats can
+// evolve without bricking existing nodes.
+//
+// Each format is described by a Schema. Stores call Prepare with theirs
+// before opening their data: it records the version of new data, upgrades
+// older data by running the schema's migrations in order, and refuses data
+// written in a newer format than the running binary understands.
+//
+// The version is kept in a sidecar file next to the data (see VersionPath),
+// so the data itself needs no header and formats that predate versioning are
+// still recognized: data without a sidecar is version 1.
+package migrations
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+// ErrNewerFormat is returned by Prepare for data written in a newer version of
+// its format than the running binary supports.
+var ErrNewerFormat = errors.New("data was written by a newer version of the software")
+
+// Migration upgrades data from the previous version of its format.
+type Migration struct {
+	Version     int    // Version the data is in after the migration
+	Description string // Logged when the migration runs
+	// Apply rewrites the data at path, which is in version Version-1. A
+	// migration interrupted by a crash runs again on the next start, so Apply
+	// must replace data atomically (e.g. write a temporary file and rename it).
+	Apply func(path string) error
+}
+
+// Schema describes one on-disk format.
+type Schema struct {
+	Name       string // Identifies the format in the sidecar, e.g. "ledger.blocklog"
+	Current    int    // Version written by this binary; at least 1
+	Migrations []Migration
+}
+
+// validate checks that s has one migration for every version from 2 up to
+// Current, in order.
+func (s *Schema) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("schema name cannot be empty")
+	}
+	if s.Current < 1 {
+		return fmt.Errorf("schema %s: current version %d is not positive", s.Name, s.Current)
+	}
+	if len(s.Migrations) != s.Current-1 {
+		return fmt.Errorf("schema %s: %d migrations for current version %d, want %d", s.Name, len(s.Migrations), s.Current, s.Current-1)
+	}
+	for i, m := range s.Migrations {
+		if m.Version != i+2 {
+			return fmt.Errorf("schema %s: migration %d produces version %d, want %d", s.Name, i, m.Version, i+2)
+		}
+		if m.Apply == nil {
+			return fmt.Errorf("schema %s: migration to version %d has no Apply", s.Name, m.Version)
+		}
+	}
+	return nil
+}
+
+// versionFile is the JSON content of a sidecar.
+type versionFile struct {
+	Schema  string `json:"schema"`
+	Version int    `json:"version"`
+}
+
+// VersionPath returns the path of the sidecar recording the format version of
+// the data at path.
+func VersionPath(path string) string {
+	return path + ".version"
+}
+
+// ReadVersion returns the format version of the data at path according to
+// schema, without changing anything. It is 0 if there is no data yet, and 1
+// for data from before versioning.
+func ReadVersion(path string, schema *Schema) (int, error) {
+	v, err := readVersionFile(path)
+	if err != nil {
+		return 0, err
+	}
+	if v != nil {
+		if v.Schema != schema.Name {
+			return 0, fmt.Errorf("%s holds %s data, not %s", path, v.Schema, schema.Name)
+		}
+		return v.Version, nil
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	return 1, nil
+}
+
+// Prepare brings the data at path to schema's current version. New data is
+// recorded as the current version; older data is migrated one version at a
+// time, recording each version as it is reached, so an interrupted upgrade
+// resumes where it stopped. Data in a newer version is left alone and
+// ErrNewerFormat is returned.
+//
+// Prepare must not run concurrently with anything else using the data.
+func Prepare(path string, schema *Schema) error {
+	if err := schema.validate(); err != nil {
+		return err
+	}
+	version, err := ReadVersion(path, schema)
+	if err != nil {
+		return err
+	}
+	switch {
+	case version == 0:
+		return writeVersionFile(path, schema.Name, schema.Current)
+	case version > schema.Current:
+		return fmt.Errorf("%s at %s is version %d, but this binary supports up to version %d: %w",
+			schema.Name, path, version, schema.Current, ErrNewerFormat)
+	case version == schema.Current:
+		if _, err := os.Stat(VersionPath(path)); err != nil {
+			// Unversioned version 1 data; record it.
+			return writeVersionFile(path, schema.Name, version)
+		}
+		return nil
+	}
+	for _, m := range schema.Migrations[version-1:] {
+		log.Printf("migrations: migrating %s at %s to version %d: %s", schema.Name, path, m.Version, m.Description)
+		if err := m.Apply(path); err != nil {
+			return fmt.Errorf("failed to migrate %s at %s to version %d: %w", schema.Name, path, m.Version, err)
+		}
+		if err := writeVersionFile(path, schema.Name, m.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readVersionFile(path string) (*versionFile, error) {
+	data, err := os.ReadFile(VersionPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read format version: %w", err)
+	}
+	var v versionFile
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("failed to decode format version %s: %w", VersionPath(path), err)
+	}
+	if v.Schema == "" || v.Version < 1 {
+		return nil, fmt.Errorf("format version %s is malformed", VersionPath(path))
+	}
+	return &v, nil
+}
+
+// writeVersionFile atomically replaces the sidecar of the data at path.
+func writeVersionFile(path, schema string, version int) error {
+	data, err := json.Marshal(versionFile{Schema: schema, Version: version})
+	if err != nil {
+		return fmt.Errorf("failed to encode format version: %w", err)
+	}
+	target := VersionPath(path)
+	tmp := target + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write format version %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		return fmt.Errorf("failed to replace format version %s: %w", target, err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package migrations
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// appendMigration returns a migration to version that appends a line to the
+// data, failing instead if *fail is set.
+func appendMigration(version int, fail *bool) Migration {
+	return Migration{
+		Version:     version,
+		Description: "append a line",
+		Apply: func(path string) error {
+			if fail != nil && *fail {
+				return errors.New("disk full")
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(path, append(data, []byte("v"+string(rune('0'+version))+"\n")...), 0644)
+		},
+	}
+}
+
+func TestPrepare_NewDataIsCurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data")
+	schema := &Schema{Name: "test", Current: 3, Migrations: []Migration{appendMigration(2, nil), appendMigration(3, nil)}}
+	if v, err := ReadVersion(path, schema); err != nil || v != 0 {
+		t.Fatalf("ReadVersion() of missing data = %d, %v; want 0", v, err)
+	}
+	if err := Prepare(path, schema); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	if v, err := ReadVersion(path, schema); err != nil || v != 3 {
+		t.Errorf("ReadVersion() after Prepare = %d, %v; want 3", v, err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Prepare() created the data itself: %v", err)
+	}
+}
+
+func TestPrepare_MigratesInOrderAndResumes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, []byte("v1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fail := true
+	schema := &Schema{Name: "test", Current: 3, Migrations: []Migration{appendMigration(2, nil), appendMigration(3, &fail)}}
+
+	// Data from before versioning is version 1; the failed step is not recorded.
+	if err := Prepare(path, schema); err == nil || !strings.Contains(err.Error(), "version 3") {
+		t.Fatalf("Prepare() error = %v, want the failure migrating to version 3", err)
+	}
+	if v, _ := ReadVersion(path, schema); v != 2 {
+		t.Fatalf("version after a failed migration = %d, want 2", v)
+	}
+
+	fail = false
+	if err := Prepare(path, schema); err != nil {
+		t.Fatalf("Prepare() retry error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "v1\nv2\nv3\n" {
+		t.Errorf("data = %q, want each migration applied once, in order", data)
+	}
+	if err := Prepare(path, schema); err != nil {
+		t.Fatalf("Prepare() of current data error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "v1\nv2\nv3\n" {
+		t.Errorf("data = %q after preparing current data, want it unchanged", data)
+	}
+}
+
+func TestPrepare_RefusesNewerAndForeignData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data")
+	newer := &Schema{Name: "test", Current: 2, Migrations: []Migration{appendMigration(2, nil)}}
+	if err := Prepare(path, newer); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+
+	older := &Schema{Name: "test", Current: 1}
+	if err := Prepare(path, older); !errors.Is(err, ErrNewerFormat) {
+		t.Errorf("Prepare() with an older binary error = %v, want ErrNewerFormat", err)
+	}
+	if v, _ := ReadVersion(path, newer); v != 2 {
+		t.Errorf("version = %d after refusing, want it left at 2", v)
+	}
+	if err := Prepare(path, &Schema{Name: "other", Current: 2, Migrations: newer.Migrations}); err == nil || !strings.Contains(err.Error(), "holds test data") {
+		t.Errorf("Prepare() with another schema error = %v, want a mismatch", err)
+	}
+}
+
+func TestPrepare_RejectsInvalidSchemas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data")
+	for _, tt := range []struct {
+		name   string
+		schema *Schema
+	}{
+		{"no name", &Schema{Current: 1}},
+		{"zero version", &Schema{Name: "test"}},
+		{"missing migration", &Schema{Name: "test", Current: 3, Migrations: []Migration{appendMigration(2, nil)}}},
+		{"out of order", &Schema{Name: "test", Current: 3, Migrations: []Migration{appendMigration(3, nil), appendMigration(2, nil)}}},
+		{"no apply", &Schema{Name: "test", Current: 2, Migrations: []Migration{{Version: 2}}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Prepare(path, tt.schema); err == nil {
+				t.Error("Prepare() expected error, got nil")
+			}
+		})
+	}
+	if _, err := os.Stat(VersionPath(path)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("an invalid schema wrote a version file: %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package mobile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"digisocialblock/core/content"
+	"digisocialblock/core/migrations"
+	"digisocialblock/pkg/dds/chunking"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ChunkSize is the size of the chunks content is split into when published.
+const ChunkSize = 256 << 10
+
+// ChunkStore stores DDS chunks and manifests by CID. Apps implement it in
+// Swift or Kotlin to back it with their own storage, or use DirStore.
+type ChunkStore interface {
+	Put(cid string, data []byte) error
+	Get(cid string) ([]byte, error)
+	Has(cid string) bool
+}
+
+// Content publishes content to, and retrieves it from, a ChunkStore.
+type Content struct {
+	publisher *content.ContentPublisher
+	retriever *content.ContentRetriever
+}
+
+// NewContent creates a Content backed by store.
+func NewContent(store ChunkStore) (*Content, error) {
+	if store == nil {
+		return nil, fmt.Errorf("chunk store cannot be nil")
+	}
+	storage := chunkStorage{store}
+	publisher, err := content.NewContentPublisher(&manifestChunker{store: store}, storage, noopOriginator{})
+	if err != nil {
+		return nil, err
+	}
+	retriever, err := content.NewContentRetriever(manifestFetcher{store}, storage)
+	if err != nil {
+		return nil, err
+	}
+	return &Content{publisher: publisher, retriever: retriever}, nil
+}
+
+// PublishText publishes a text post and returns its manifest CID, which is
+// the content CID of the post.
+func (c *Content) PublishText(text string) (string, error) {
+	return c.publisher.PublishTextPostToDDS(text)
+}
+
+// PublishMedia publishes an image or other attachment and returns its
+// manifest CID.
+func (c *Content) PublishMedia(data []byte) (string, error) {
+	return c.publisher.PublishMediaToDDS(data)
+}
+
+// RetrieveText retrieves the text published under manifestCID, verifying
+// every chunk against its CID.
+func (c *Content) RetrieveText(manifestCID string) (string, error) {
+	return c.retriever.RetrieveAndVerifyTextPost(manifestCID)
+}
+
+// RetrieveMedia retrieves the attachment published under manifestCID,
+// verifying every chunk against its CID.
+func (c *Content) RetrieveMedia(manifestCID string) ([]byte, error) {
+	text, err := c.retriever.RetrieveAndVerifyTextPost(manifestCID)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(text), nil
+}
+
+// dirStoreSchema versions the DirStore layout. It is unexported because
+// gomobile cannot bind it.
+var dirStoreSchema = &migrations.Schema{Name: "mobile.dirstore", Current: 1}
+
+// DirStore is a ChunkStore that keeps each chunk in a file named by its CID.
+type DirStore struct {
+	dir string
+}
+
+// NewDirStore creates a DirStore in dir, creating the directory if needed. A
+// store in an older layout is migrated first; one in a newer layout is
+// refused.
+func NewDirStore(dir string) (*DirStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("store directory cannot be empty")
+	}
+	// The format version is recorded next to dir, and before it is created
+	// so that a new store is not mistaken for one from before versioning.
+	dir = filepath.Clean(dir)
+	if err := os.MkdirAll(filepath.Dir(dir), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create store directory %s: %w", dir, err)
+	}
+	if err := migrations.Prepare(dir, dirStoreSchema); err != nil {
+		return nil, fmt.Errorf("failed to open chunk store: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create store directory %s: %w", dir, err)
+	}
+	return &DirStore{dir: dir}, nil
+}
+
+// Put stores data under cid.
+func (d *DirStore) Put(cid string, data []byte) error {
+	path, err := d.path(cid)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Get returns the data stored under cid.
+func (d *DirStore) Get(cid string) ([]byte, error) {
+	path, err := d.path(cid)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// Has reports whether data is stored under cid.
+func (d *DirStore) Has(cid string) bool {
+	path, err := d.path(cid)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+func (d *DirStore) path(cid string) (string, error) {
+	if cid == "" || cid != filepath.Base(cid) || cid == "." || cid == ".." {
+		return "", fmt.Errorf("invalid CID %q", cid)
+	}
+	return filepath.Join(d.dir, cid), nil
+}
+
+// chunkStorage adapts a ChunkStore to content.DDSStorage.
+type chunkStorage struct{ store ChunkStore }
+
+func (s chunkStorage) StoreChunk(chunkID string, data []byte) error {
+	return s.store.Put(chunkID, data)
+}
+
+func (s chunkStorage) RetrieveChunk(chunkID string) ([]byte, error) { return s.store.Get(chunkID) }
+
+func (s chunkStorage) ChunkExists(chunkID string) bool { return s.store.Has(chunkID) }
+
+// manifestFetcher reads manifests stored by manifestChunker.
+type manifestFetcher struct{ store ChunkStore }
+
+func (f manifestFetcher) FetchManifest(manifestCID string) (*chunking.ContentManifestV1, error) {
+	data, err := f.store.Get(manifestCID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", manifestCID, err)
+	}
+	return content.DecodeManifest(data)
+}
+
+// manifestChunker splits content into ChunkSize chunks addressed by their
+// SHA-256, with the manifest addressed by the hash of its chunk CIDs. The
+// manifest itself is stored under its CID so it can be fetched by
+// RetrieveText; the publisher stores the chunks.
+type manifestChunker struct{ store ChunkStore }
+
+func (c *manifestChunker) ChunkData(r io.Reader) (*chunking.ContentManifestV1, []chunking.DataChunk, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read content: %w", err)
+	}
+	manifest := &chunking.ContentManifestV1{Version: 1, TotalSize: int64(len(data)), EncryptionMethod: "none"}
+	var chunks []chunking.DataChunk
+	var cids bytes.Buffer
+	for off := 0; off < len(data); off += ChunkSize {
+		end := off + ChunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		sum := sha256.Sum256(data[off:end])
+		cid := hex.EncodeToString(sum[:])
+		chunks = append(chunks, chunking.DataChunk{ChunkCID: cid, Data: data[off:end], Size: int64(end - off)})
+		manifest.Chunks = append(manifest.Chunks, chunking.ChunkInfo{ChunkCID: cid, Size: int64(end - off)})
+		cids.WriteString(cid)
+	}
+	sum := sha256.Sum256(cids.Bytes())
+	manifest.ManifestCID = hex.EncodeToString(sum[:])
+
+	encoded, err := content.EncodeManifest(manifest, content.ManifestEncodingJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := c.store.Put(manifest.ManifestCID, encoded); err != nil {
+		return nil, nil, fmt.Errorf("failed to store manifest %s: %w", manifest.ManifestCID, err)
+	}
+	return manifest, chunks, nil
+}
+
+// noopOriginator leaves advertising to the DDS node that seeds the store.
+type noopOriginator struct{}
+
+func (noopOriginator) AdvertiseManifest(*chunking.ContentManifestV1) error { return nil }
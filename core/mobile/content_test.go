@@ -0,0 +1,67 @@
+package mobile
+
+import (
+	"bytes"
+	"digisocialblock/core/migrations"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestContent_PublishAndRetrieve(t *testing.T) {
+	store, err := NewDirStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDirStore() error = %v", err)
+	}
+	c, err := NewContent(store)
+	if err != nil {
+		t.Fatalf("NewContent() error = %v", err)
+	}
+
+	cid, err := c.PublishText("hello from a phone")
+	if err != nil {
+		t.Fatalf("PublishText() error = %v", err)
+	}
+	if text, err := c.RetrieveText(cid); err != nil || text != "hello from a phone" {
+		t.Errorf("RetrieveText() = %q, %v; want the published text", text, err)
+	}
+
+	media := bytes.Repeat([]byte{0xff, 0xd8, 0x00}, ChunkSize) // Spans several chunks.
+	mediaCID, err := c.PublishMedia(media)
+	if err != nil {
+		t.Fatalf("PublishMedia() error = %v", err)
+	}
+	if got, err := c.RetrieveMedia(mediaCID); err != nil || !bytes.Equal(got, media) {
+		t.Errorf("RetrieveMedia() returned %d bytes, %v; want the published %d bytes", len(got), err, len(media))
+	}
+
+	if _, err := c.RetrieveText("unknown"); err == nil {
+		t.Error("RetrieveText() of an unknown CID: expected error, got nil")
+	}
+}
+
+func TestDirStore_RejectsPaths(t *testing.T) {
+	store, _ := NewDirStore(t.TempDir())
+	for _, cid := range []string{"", ".", "..", "../escape", "a/b"} {
+		if err := store.Put(cid, []byte("x")); err == nil {
+			t.Errorf("Put(%q): expected error, got nil", cid)
+		}
+	}
+}
+
+func TestNewDirStore_RecordsFormatVersion(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "app", "chunks")
+	if _, err := NewDirStore(dir); err != nil {
+		t.Fatalf("NewDirStore() error = %v", err)
+	}
+	if v, err := migrations.ReadVersion(dir, dirStoreSchema); err != nil || v != dirStoreSchema.Current {
+		t.Errorf("ReadVersion() = %d, %v; want %d", v, err, dirStoreSchema.Current)
+	}
+	if err := os.WriteFile(migrations.VersionPath(dir), []byte(`{"schema":"mobile.dirstore","version":99}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewDirStore(dir); !errors.Is(err, migrations.ErrNewerFormat) {
+		t.Errorf("NewDirStore() of a newer store error = %v, want ErrNewerFormat", err)
+	}
+}
@@ -0,0 +1,109 @@
+// Package mobile is a gomobile-bindable facade over the core packages for iOS
+// and Android apps:
+//
+//	gomobile bind -target=android ./core/mobile
+//	gomobile bind -target=ios ./core/mobile
+//
+// gomobile can only export a restricted set of types, so everything here uses
+// flat signatures: strings, integers, booleans, []byte and pointers to the
+// structs below. Lists are passed as newline-separated strings and structured
+// results as JSON. Signed transactions are returned as the JSON body a node
+// accepts on POST /v1/transactions; the app submits them over its own HTTP
+// stack.
+package mobile
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Wallet holds a user's signing key.
+type Wallet struct {
+	w *identity.Wallet
+}
+
+// NewWallet creates a wallet with a freshly generated key.
+func NewWallet() (*Wallet, error) {
+	w, err := identity.NewWallet()
+	if err != nil {
+		return nil, err
+	}
+	return &Wallet{w: w}, nil
+}
+
+// LoadWallet loads a wallet saved with Save. The file should live in the app's
+// private storage; it holds the unencrypted private key.
+func LoadWallet(path string) (*Wallet, error) {
+	w, err := identity.LoadWalletFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Wallet{w: w}, nil
+}
+
+// Save writes the wallet to path (see LoadWallet).
+func (w *Wallet) Save(path string) error {
+	return w.w.SaveToFile(path)
+}
+
+// Address returns the wallet's address, which identifies the user on chain.
+func (w *Wallet) Address() string {
+	return w.w.Address
+}
+
+// Close wipes the private key. The wallet cannot sign afterwards.
+func (w *Wallet) Close() error {
+	return w.w.Close()
+}
+
+// SignTransaction creates a transaction of txType (e.g. "PostCreated") with the
+// given payload for chainID (the main chain if empty), signs it and returns its
+// JSON encoding.
+func (w *Wallet) SignTransaction(txType string, payload []byte, chainID string) ([]byte, error) {
+	tx, err := ledger.NewTransaction(w.w.Address, ledger.TransactionType(txType), payload)
+	if err != nil {
+		return nil, err
+	}
+	if chainID != "" {
+		tx.ChainID = chainID
+	}
+	if err := w.w.SignTransaction(tx); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal transaction %s: %w", tx.ID, err)
+	}
+	return data, nil
+}
+
+// SignPost creates a PostCreated transaction for content already published
+// with Content.PublishText, signs it and returns its JSON encoding. tags is
+// newline-separated; blank lines are ignored.
+func (w *Wallet) SignPost(contentCID, title, tags, chainID string) ([]byte, error) {
+	post := social.NewPost(w.w.Address, contentCID, title, splitLines(tags))
+	if err := post.Validate(); err != nil {
+		return nil, err
+	}
+	payload, err := post.ToPayload(ledger.PayloadFormatJSON)
+	if err != nil {
+		return nil, err
+	}
+	return w.SignTransaction(string(ledger.PostCreated), payload, chainID)
+}
+
+// splitLines splits a newline-separated list, trimming each entry and dropping
+// empty ones.
+func splitLines(list string) []string {
+	var out []string
+	for _, line := range strings.Split(list, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
@@ -0,0 +1,64 @@
+package mobile
+
+import (
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWallet_SignPost(t *testing.T) {
+	wallet, err := NewWallet()
+	if err != nil {
+		t.Fatalf("NewWallet() error = %v", err)
+	}
+	defer wallet.Close()
+
+	data, err := wallet.SignPost("content_cid", "Title", "go\n\n  mobile \n", "dsb-testnet")
+	if err != nil {
+		t.Fatalf("SignPost() error = %v", err)
+	}
+	var tx ledger.Transaction
+	if err := json.Unmarshal(data, &tx); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if tx.Type != ledger.PostCreated || tx.SenderPublicKey != wallet.Address() || tx.ChainID != "dsb-testnet" {
+		t.Errorf("transaction = %s/%s/%s, want PostCreated by %s on dsb-testnet", tx.Type, tx.SenderPublicKey, tx.ChainID, wallet.Address())
+	}
+	if valid, err := tx.VerifySignature(); err != nil || !valid {
+		t.Errorf("VerifySignature() = %v, %v; want true, nil", valid, err)
+	}
+	post, err := social.PostFromPayload(tx.Payload)
+	if err != nil {
+		t.Fatalf("PostFromPayload() error = %v", err)
+	}
+	if want := []string{"go", "mobile"}; !reflect.DeepEqual(post.Tags, want) {
+		t.Errorf("Tags = %q, want %q", post.Tags, want)
+	}
+
+	if _, err := wallet.SignPost("", "Title", "", ""); err == nil {
+		t.Error("SignPost() without a content CID: expected error, got nil")
+	}
+}
+
+func TestWallet_SaveAndLoad(t *testing.T) {
+	wallet, _ := NewWallet()
+	path := filepath.Join(t.TempDir(), "wallet.json")
+	if err := wallet.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := LoadWallet(path)
+	if err != nil {
+		t.Fatalf("LoadWallet() error = %v", err)
+	}
+	if loaded.Address() != wallet.Address() {
+		t.Errorf("loaded address = %s, want %s", loaded.Address(), wallet.Address())
+	}
+
+	wallet.Close()
+	if _, err := wallet.SignTransaction(string(ledger.Like), []byte("{}"), ""); err == nil {
+		t.Error("SignTransaction() after Close: expected error, got nil")
+	}
+}
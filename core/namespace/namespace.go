@@ -0,0 +1,228 @@
+// Package namespace runs several independent chains, such as topical
+// communities or test networks, on one node. Each namespace is named by its
+// chain ID and has its own directory under the host's root, holding its block
+// log and any index files the node keeps for it, along with its own
+// Blockchain and Mempool. Transactions are signed for one chain ID (see
+// ledger.Transaction.ChainID), so a transaction from one namespace is
+// rejected by every other rather than replayed there.
+package namespace
+
+import (
+	"digisocialblock/core/ledger"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// File names inside a namespace directory.
+const (
+	chainIDFile = "CHAIN_ID"  // The chain ID, guarding against a renamed directory
+	chainFile   = "chain.log" // The ledger.FileBlockStore
+)
+
+// ErrNotFound is returned for a namespace the host has not opened.
+var ErrNotFound = errors.New("namespace not found")
+
+// ErrClosed is returned by a Host after Close.
+var ErrClosed = errors.New("namespace host is closed")
+
+// Options configures a Host. The zero value is valid.
+type Options struct {
+	// Store configures each namespace's block log.
+	Store ledger.FileBlockStoreOptions
+}
+
+// Host holds the namespaces opened on a node. A Host is safe for concurrent
+// use.
+type Host struct {
+	root     string
+	opts     Options
+	sigCache *ledger.SignatureCache // Shared by every namespace; entries are keyed by chain ID
+
+	mu         sync.Mutex
+	namespaces map[string]*Namespace
+	closed     bool
+}
+
+// NewHost creates a Host keeping its namespaces under the directory root,
+// which is created if needed.
+func NewHost(root string, opts Options) (*Host, error) {
+	if root == "" {
+		return nil, fmt.Errorf("namespace root cannot be empty")
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create namespace root: %w", err)
+	}
+	sigCache, err := ledger.NewSignatureCache(ledger.DefaultSignatureCacheSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Host{root: root, opts: opts, sigCache: sigCache, namespaces: make(map[string]*Namespace)}, nil
+}
+
+// Open opens the namespace for chainID, creating it if it does not exist.
+// Opening a namespace that is already open returns it.
+func (h *Host) Open(chainID string) (*Namespace, error) {
+	if err := ledger.ValidateChainID(chainID); err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrClosed
+	}
+	if ns, ok := h.namespaces[chainID]; ok {
+		return ns, nil
+	}
+	dir := filepath.Join(h.root, chainID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create namespace %s: %w", chainID, err)
+	}
+	if err := claimDir(dir, chainID); err != nil {
+		return nil, err
+	}
+	store, err := ledger.OpenFileBlockStore(filepath.Join(dir, chainFile), h.opts.Store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open namespace %s: %w", chainID, err)
+	}
+	bc, err := ledger.NewBlockchainForChain(store, chainID)
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to open namespace %s: %w", chainID, err)
+	}
+	bc.SetSignatureCache(h.sigCache)
+	mempool := ledger.NewMempool(h.sigCache)
+	mempool.SetChainID(chainID)
+	mempool.SetDelegations(bc)
+	mempool.SetTransactionIndex(bc)
+	ns := &Namespace{chainID: chainID, dir: dir, store: store, chain: bc, mempool: mempool}
+	h.namespaces[chainID] = ns
+	return ns, nil
+}
+
+// claimDir records chainID in dir, or checks that dir already records it.
+func claimDir(dir, chainID string) error {
+	path := filepath.Join(dir, chainIDFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := os.WriteFile(path, []byte(chainID+"\n"), 0o644); err != nil {
+			return fmt.Errorf("failed to record namespace %s: %w", chainID, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read namespace %s: %w", chainID, err)
+	}
+	if recorded := strings.TrimSpace(string(data)); recorded != chainID {
+		return fmt.Errorf("%w: directory %s holds chain %q", ledger.ErrWrongChain, dir, recorded)
+	}
+	return nil
+}
+
+// Get returns the open namespace for chainID.
+func (h *Host) Get(chainID string) (*Namespace, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ns, ok := h.namespaces[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, chainID)
+	}
+	return ns, nil
+}
+
+// Namespaces returns the chain IDs of the open namespaces, sorted.
+func (h *Host) Namespaces() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ids := make([]string, 0, len(h.namespaces))
+	for id := range h.namespaces {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Stored returns the chain IDs of the namespaces stored under the host's
+// root, open or not, sorted.
+func (h *Host) Stored() ([]string, error) {
+	entries, err := os.ReadDir(h.root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	var ids []string
+	for _, e := range entries {
+		if !e.IsDir() || ledger.ValidateChainID(e.Name()) != nil {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(h.root, e.Name(), chainIDFile)); err == nil {
+			ids = append(ids, e.Name())
+		}
+	}
+	return ids, nil
+}
+
+// Close closes every open namespace. The host cannot be used afterwards.
+func (h *Host) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	h.closed = true
+	var errs []error
+	for _, id := range sortedKeys(h.namespaces) {
+		if err := h.namespaces[id].store.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %w", id, err))
+		}
+	}
+	h.namespaces = nil
+	return errors.Join(errs...)
+}
+
+func sortedKeys(m map[string]*Namespace) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Namespace is one chain opened on a Host.
+type Namespace struct {
+	chainID string
+	dir     string
+	store   *ledger.FileBlockStore
+	chain   *ledger.Blockchain
+	mempool *ledger.Mempool
+}
+
+// ChainID returns the namespace's chain ID.
+func (ns *Namespace) ChainID() string {
+	return ns.chainID
+}
+
+// Chain returns the namespace's blockchain, which rejects transactions signed
+// for other chains.
+func (ns *Namespace) Chain() *ledger.Blockchain {
+	return ns.chain
+}
+
+// Mempool returns the namespace's mempool, which rejects transactions signed
+// for other chains.
+func (ns *Namespace) Mempool() *ledger.Mempool {
+	return ns.mempool
+}
+
+// Path returns the path of the file name in the namespace's directory, for
+// indexes and other state kept per namespace. name must be a plain file name.
+func (ns *Namespace) Path(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." || name == chainIDFile || name == chainFile {
+		return "", fmt.Errorf("invalid namespace file name %q", name)
+	}
+	return filepath.Join(ns.dir, name), nil
+}
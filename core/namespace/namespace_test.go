@@ -0,0 +1,104 @@
+package namespace
+
+import (
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil/fixture"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// namespaceTestTx returns a post signed for chainID.
+func namespaceTestTx(t *testing.T, chainID string) *ledger.Transaction {
+	t.Helper()
+	wallet := fixture.Wallet(t)
+	tx := fixture.PostTx(t, wallet, "cid-post")
+	tx.ChainID = chainID
+	if err := wallet.SignTransaction(tx); err != nil {
+		t.Fatalf("SignTransaction() error = %v", err)
+	}
+	return tx
+}
+
+func TestHost_IsolatesNamespaces(t *testing.T) {
+	root := t.TempDir()
+	host, err := NewHost(root, Options{})
+	if err != nil {
+		t.Fatalf("NewHost() error = %v", err)
+	}
+	books, err := host.Open("books")
+	if err != nil {
+		t.Fatalf("Open(books) error = %v", err)
+	}
+	testnet, _ := host.Open("testnet")
+	if again, _ := host.Open("books"); again != books {
+		t.Error("Open() of an open namespace returned a new one")
+	}
+	if _, err := host.Open("../escape"); err == nil {
+		t.Error("Open() of an invalid chain ID: expected error, got nil")
+	}
+
+	tx := namespaceTestTx(t, "books")
+	if err := books.Mempool().Add(tx); err != nil {
+		t.Fatalf("books Mempool().Add() error = %v", err)
+	}
+	if _, err := books.Chain().AddBlock([]*ledger.Transaction{tx}); err != nil {
+		t.Fatalf("books Chain().AddBlock() error = %v", err)
+	}
+	// The same signed transaction cannot be replayed on the other chain.
+	if err := testnet.Mempool().Add(tx); !errors.Is(err, ledger.ErrWrongChain) {
+		t.Errorf("testnet Mempool().Add() of a books transaction error = %v, want ErrWrongChain", err)
+	}
+	if _, err := testnet.Chain().AddBlock([]*ledger.Transaction{tx}); !errors.Is(err, ledger.ErrWrongChain) {
+		t.Errorf("testnet Chain().AddBlock() of a books transaction error = %v, want ErrWrongChain", err)
+	}
+
+	index, err := books.Path("posts.idx")
+	if err != nil || filepath.Dir(index) != filepath.Join(root, "books") {
+		t.Errorf("Path() = %q, %v; want a file in the books directory", index, err)
+	}
+	if _, err := books.Path("../testnet/chain.log"); err == nil {
+		t.Error("Path() outside the namespace: expected error, got nil")
+	}
+	if got := host.Namespaces(); !reflect.DeepEqual(got, []string{"books", "testnet"}) {
+		t.Errorf("Namespaces() = %v", got)
+	}
+	if err := host.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Each chain is restored from its own directory.
+	host, _ = NewHost(root, Options{})
+	defer host.Close()
+	if stored, _ := host.Stored(); !reflect.DeepEqual(stored, []string{"books", "testnet"}) {
+		t.Errorf("Stored() = %v", stored)
+	}
+	if _, err := host.Get("books"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() before Open() error = %v, want ErrNotFound", err)
+	}
+	books, _ = host.Open("books")
+	testnet, _ = host.Open("testnet")
+	if got := books.Chain().GetLatestBlock(); got.Index != 1 || got.Transactions[0].ID != tx.ID {
+		t.Errorf("restored books chain ends with block %d, want the post in block 1", got.Index)
+	}
+	if got := testnet.Chain().GetLatestBlock(); got.Index != 0 {
+		t.Errorf("restored testnet chain ends with block %d, want only genesis", got.Index)
+	}
+}
+
+func TestHost_RefusesMovedNamespace(t *testing.T) {
+	root := t.TempDir()
+	host, _ := NewHost(root, Options{})
+	host.Open("books")
+	host.Close()
+	if err := os.Rename(filepath.Join(root, "books"), filepath.Join(root, "music")); err != nil {
+		t.Fatal(err)
+	}
+	host, _ = NewHost(root, Options{})
+	defer host.Close()
+	if _, err := host.Open("music"); !errors.Is(err, ledger.ErrWrongChain) {
+		t.Errorf("Open() of a renamed namespace error = %v, want ErrWrongChain", err)
+	}
+}
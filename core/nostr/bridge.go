@@ -0,0 +1,246 @@
+package nostr
+
+import (
+	"digisocialblock/core/content"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"unicode/utf8"
+)
+
+var (
+	// ErrAlreadyBridged is returned for an event or post that has already
+	// crossed the bridge in either direction.
+	ErrAlreadyBridged = errors.New("already bridged")
+	// ErrForeignEvent is returned when importing an event by a pubkey other
+	// than the importer's.
+	ErrForeignEvent = errors.New("event is not by the mapped nostr key")
+	// ErrNotBridgeable is returned for events and posts the bridge does not
+	// carry: non-text-note events and group-only posts.
+	ErrNotBridgeable = errors.New("not bridgeable")
+)
+
+// BridgeLog records which Nostr events correspond to which transactions, in
+// both directions, and is persisted as a single JSON file. It is what makes
+// the bridge idempotent.
+type BridgeLog struct {
+	mu        sync.Mutex
+	path      string
+	TxByEvent map[string]string `json:"txByEvent"` // Nostr event ID -> transaction ID
+	EventByTx map[string]string `json:"eventByTx"` // Transaction ID -> Nostr event ID
+}
+
+// OpenBridgeLog loads the bridge log at path. A missing file is an empty log.
+func OpenBridgeLog(path string) (*BridgeLog, error) {
+	if path == "" {
+		return nil, fmt.Errorf("bridge log path cannot be empty")
+	}
+	l := &BridgeLog{path: path, TxByEvent: map[string]string{}, EventByTx: map[string]string{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bridge log %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("failed to decode bridge log %s: %w", path, err)
+	}
+	if l.TxByEvent == nil || l.EventByTx == nil {
+		l.TxByEvent, l.EventByTx = map[string]string{}, map[string]string{}
+	}
+	return l, nil
+}
+
+// TxForEvent returns the transaction a Nostr event was bridged to or from.
+func (l *BridgeLog) TxForEvent(eventID string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	txID, ok := l.TxByEvent[eventID]
+	return txID, ok
+}
+
+// EventForTx returns the Nostr event a transaction was bridged to or from.
+func (l *BridgeLog) EventForTx(txID string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	eventID, ok := l.EventByTx[txID]
+	return eventID, ok
+}
+
+// record adds a mapping and atomically rewrites the log file. It fails with
+// ErrAlreadyBridged if either side is already mapped.
+func (l *BridgeLog) record(eventID, txID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.TxByEvent[eventID]; ok {
+		return fmt.Errorf("nostr event %s: %w", eventID, ErrAlreadyBridged)
+	}
+	if _, ok := l.EventByTx[txID]; ok {
+		return fmt.Errorf("transaction %s: %w", txID, ErrAlreadyBridged)
+	}
+	l.TxByEvent[eventID], l.EventByTx[txID] = txID, eventID
+	data, err := json.Marshal(l)
+	if err == nil {
+		tmp := l.path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0644); err == nil {
+			err = os.Rename(tmp, l.path)
+		}
+	}
+	if err != nil {
+		delete(l.TxByEvent, eventID)
+		delete(l.EventByTx, txID)
+		return fmt.Errorf("failed to write bridge log %s: %w", l.path, err)
+	}
+	return nil
+}
+
+// Importer turns one user's Nostr text notes into PostCreated transactions
+// signed by that user's wallet. Only events by the mapped Nostr key are
+// accepted, so a user can migrate their own notes but not anyone else's.
+type Importer struct {
+	publisher   *content.ContentPublisher
+	wallet      *identity.Wallet
+	nostrPubKey string
+	log         *BridgeLog
+}
+
+// NewImporter creates an Importer that re-signs notes by nostrPubKey (hex,
+// x-only) with wallet, publishing note content through publisher.
+func NewImporter(publisher *content.ContentPublisher, wallet *identity.Wallet, nostrPubKey string, log *BridgeLog) (*Importer, error) {
+	if publisher == nil || wallet == nil || log == nil {
+		return nil, fmt.Errorf("publisher, wallet and bridge log cannot be nil")
+	}
+	if key, err := hex.DecodeString(nostrPubKey); err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("nostr pubkey must be 32 bytes of hex")
+	}
+	return &Importer{publisher: publisher, wallet: wallet, nostrPubKey: nostrPubKey, log: log}, nil
+}
+
+// Import verifies ev and returns the signed PostCreated transaction for it.
+// The post keeps the note's creation time and its hashtags ("t" tags). The
+// event is recorded in the bridge log, so importing it again, or importing
+// a note that was exported from this chain, fails with ErrAlreadyBridged.
+func (im *Importer) Import(ev *Event) (*ledger.Transaction, error) {
+	if ev == nil {
+		return nil, fmt.Errorf("event cannot be nil")
+	}
+	if ev.Kind != KindTextNote {
+		return nil, fmt.Errorf("event kind %d: %w", ev.Kind, ErrNotBridgeable)
+	}
+	if ev.PubKey != im.nostrPubKey {
+		return nil, ErrForeignEvent
+	}
+	if err := ev.Verify(); err != nil {
+		return nil, err
+	}
+	if _, ok := im.log.TxForEvent(ev.ID); ok {
+		return nil, fmt.Errorf("nostr event %s: %w", ev.ID, ErrAlreadyBridged)
+	}
+	if ev.Content == "" {
+		return nil, fmt.Errorf("nostr event %s has no content", ev.ID)
+	}
+
+	contentCID, err := im.publisher.PublishTextPostToDDS(ev.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish nostr note content to DDS: %w", err)
+	}
+	post := social.NewPost(im.wallet.Address, contentCID, subject(ev), importableTags(ev.Hashtags()))
+	post.Timestamp = ev.CreatedAt * 1e9
+	if err := post.Validate(); err != nil {
+		return nil, fmt.Errorf("nostr event %s does not map to a valid post: %w", ev.ID, err)
+	}
+	payload, err := post.ToJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize post metadata to JSON: %w", err)
+	}
+	tx, err := ledger.NewTransaction(im.wallet.Address, ledger.PostCreated, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new ledger transaction for post: %w", err)
+	}
+	if err := im.wallet.SignTransaction(tx); err != nil {
+		return nil, fmt.Errorf("failed to sign post transaction: %w", err)
+	}
+	if err := im.log.record(ev.ID, tx.ID); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// subject returns the NIP-14 subject of ev, used as the post title when it fits.
+func subject(ev *Event) string {
+	for _, tag := range ev.Tags {
+		if len(tag) >= 2 && tag[0] == "subject" && utf8.RuneCountInString(tag[1]) <= social.MaxPostTitleLength {
+			return tag[1]
+		}
+	}
+	return ""
+}
+
+// importableTags keeps the hashtags that fit post tag limits.
+func importableTags(hashtags []string) []string {
+	var tags []string
+	for _, tag := range hashtags {
+		if len(tags) == social.MaxPostTags {
+			break
+		}
+		if utf8.RuneCountInString(tag) <= social.MaxPostTagLength {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Exporter turns posts into Nostr text notes signed with a mapping key.
+type Exporter struct {
+	privateKey []byte
+	log        *BridgeLog
+}
+
+// NewExporter creates an Exporter signing with the 32-byte Nostr privateKey.
+func NewExporter(privateKey []byte, log *BridgeLog) (*Exporter, error) {
+	if log == nil {
+		return nil, fmt.Errorf("bridge log cannot be nil")
+	}
+	if _, err := PublicKey(privateKey); err != nil {
+		return nil, fmt.Errorf("invalid nostr private key: %w", err)
+	}
+	return &Exporter{privateKey: append([]byte(nil), privateKey...), log: log}, nil
+}
+
+// Export returns the signed Nostr note for the post in entry with its text
+// content. Group-only posts are never exported, and a post that was imported
+// from Nostr or has already been exported fails with ErrAlreadyBridged.
+func (ex *Exporter) Export(entry social.FeedEntry, text string) (*Event, error) {
+	if entry.GroupID != "" {
+		return nil, fmt.Errorf("group post %s: %w", entry.TxID, ErrNotBridgeable)
+	}
+	if _, ok := ex.log.EventForTx(entry.TxID); ok {
+		return nil, fmt.Errorf("transaction %s: %w", entry.TxID, ErrAlreadyBridged)
+	}
+	ev := &Event{
+		CreatedAt: entry.Timestamp / 1e9,
+		Kind:      KindTextNote,
+		Tags:      [][]string{},
+		Content:   text,
+	}
+	if entry.Title != "" {
+		ev.Tags = append(ev.Tags, []string{"subject", entry.Title})
+	}
+	for _, tag := range entry.Tags {
+		ev.Tags = append(ev.Tags, []string{"t", tag})
+	}
+	if err := ev.Sign(ex.privateKey); err != nil {
+		return nil, err
+	}
+	if err := ex.log.record(ev.ID, entry.TxID); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
@@ -0,0 +1,137 @@
+package nostr
+
+import (
+	"digisocialblock/core/content"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/social"
+	"digisocialblock/internal/testutil"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newTestImporter(t *testing.T, log *BridgeLog) (*Importer, []byte, *identity.Wallet) {
+	t.Helper()
+	dds := testutil.NewDDS(0)
+	publisher, _ := content.NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	wallet, err := identity.NewWallet()
+	if err != nil {
+		t.Fatalf("NewWallet() error = %v", err)
+	}
+	sk, _ := GeneratePrivateKey()
+	pk, _ := PublicKey(sk)
+	im, err := NewImporter(publisher, wallet, hex.EncodeToString(pk), log)
+	if err != nil {
+		t.Fatalf("NewImporter() error = %v", err)
+	}
+	return im, sk, wallet
+}
+
+func signedNote(t *testing.T, sk []byte, content string, tags ...[]string) *Event {
+	t.Helper()
+	ev := &Event{CreatedAt: 1700000000, Kind: KindTextNote, Tags: append([][]string{}, tags...), Content: content}
+	if err := ev.Sign(sk); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	return ev
+}
+
+func TestImporter_Import(t *testing.T) {
+	log, _ := OpenBridgeLog(filepath.Join(t.TempDir(), "bridge.json"))
+	im, sk, wallet := newTestImporter(t, log)
+	ev := signedNote(t, sk, "hello from nostr", []string{"t", "intro"}, []string{"subject", "Hi"})
+
+	tx, err := im.Import(ev)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if tx.SenderPublicKey != wallet.Address || len(tx.Signature) == 0 {
+		t.Errorf("Import() tx sender = %s, signed = %v", tx.SenderPublicKey, len(tx.Signature) > 0)
+	}
+	post, err := social.PostFromPayload(tx.Payload)
+	if err != nil {
+		t.Fatalf("imported payload is not a valid post: %v", err)
+	}
+	if post.Timestamp != 1700000000*1e9 || post.Title != "Hi" || !reflect.DeepEqual(post.Tags, []string{"intro"}) {
+		t.Errorf("imported post = %+v", post)
+	}
+	if txID, ok := log.TxForEvent(ev.ID); !ok || txID != tx.ID {
+		t.Errorf("TxForEvent() = %q, %v; want %q", txID, ok, tx.ID)
+	}
+
+	if _, err := im.Import(ev); !errors.Is(err, ErrAlreadyBridged) {
+		t.Errorf("second Import() error = %v, want ErrAlreadyBridged", err)
+	}
+
+	// The log survives a restart.
+	reopened, err := OpenBridgeLog(log.path)
+	if err != nil {
+		t.Fatalf("OpenBridgeLog() error = %v", err)
+	}
+	if _, ok := reopened.TxForEvent(ev.ID); !ok {
+		t.Error("reopened log lost the imported event")
+	}
+}
+
+func TestImporter_Rejects(t *testing.T) {
+	log, _ := OpenBridgeLog(filepath.Join(t.TempDir(), "bridge.json"))
+	im, sk, _ := newTestImporter(t, log)
+
+	otherSK, _ := GeneratePrivateKey()
+	if _, err := im.Import(signedNote(t, otherSK, "not mine")); !errors.Is(err, ErrForeignEvent) {
+		t.Errorf("Import() of another key's note error = %v, want ErrForeignEvent", err)
+	}
+
+	reaction := &Event{CreatedAt: 1, Kind: 7, Tags: [][]string{}, Content: "+"}
+	reaction.Sign(sk)
+	if _, err := im.Import(reaction); !errors.Is(err, ErrNotBridgeable) {
+		t.Errorf("Import() of a reaction error = %v, want ErrNotBridgeable", err)
+	}
+
+	forged := signedNote(t, sk, "original")
+	forged.Content = "forged"
+	forged.ID = forged.ComputeID()
+	if _, err := im.Import(forged); err == nil {
+		t.Error("Import() of a forged note: expected error, got nil")
+	}
+}
+
+func TestExporter_Export(t *testing.T) {
+	log, _ := OpenBridgeLog(filepath.Join(t.TempDir(), "bridge.json"))
+	sk, _ := GeneratePrivateKey()
+	ex, err := NewExporter(sk, log)
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+	entry := social.FeedEntry{TxID: "tx1", ContentCID: "cid", Timestamp: 1700000000123456789, Title: "Title", Tags: []string{"go"}}
+
+	ev, err := ex.Export(entry, "post text")
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if err := ev.Verify(); err != nil {
+		t.Fatalf("exported event does not verify: %v", err)
+	}
+	if ev.CreatedAt != 1700000000 || ev.Content != "post text" || !reflect.DeepEqual(ev.Hashtags(), []string{"go"}) {
+		t.Errorf("exported event = %+v", ev)
+	}
+
+	if _, err := ex.Export(entry, "post text"); !errors.Is(err, ErrAlreadyBridged) {
+		t.Errorf("second Export() error = %v, want ErrAlreadyBridged", err)
+	}
+	if _, err := ex.Export(social.FeedEntry{TxID: "tx2", GroupID: "club"}, "secret"); !errors.Is(err, ErrNotBridgeable) {
+		t.Errorf("Export() of a group post error = %v, want ErrNotBridgeable", err)
+	}
+
+	// A post imported from Nostr is not exported back.
+	im, isk, _ := newTestImporter(t, log)
+	tx, err := im.Import(signedNote(t, isk, "imported"))
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if _, err := ex.Export(social.FeedEntry{TxID: tx.ID, Timestamp: 1}, "imported"); !errors.Is(err, ErrAlreadyBridged) {
+		t.Errorf("Export() of an imported post error = %v, want ErrAlreadyBridged", err)
+	}
+}
@@ -0,0 +1,165 @@
+// Package nostr bridges Digisocialblock posts and Nostr text notes (NIP-01
+// kind 1 events). Import turns a user's own signed Nostr notes into
+// PostCreated transactions signed by their wallet; export turns posts into
+// Nostr notes signed with a Nostr mapping key. Both directions suppress
+// duplicates by the original event ID, so notes never bounce between networks.
+//
+// Relay transport is out of scope: events are exchanged as JSON, e.g. as
+// fetched by a relay client or dumped to a file.
+package nostr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// KindTextNote is the Nostr event kind for short text notes.
+const KindTextNote = 1
+
+// Event is a Nostr event as defined by NIP-01.
+type Event struct {
+	ID        string     `json:"id"`         // Hex SHA-256 of the serialized event
+	PubKey    string     `json:"pubkey"`     // Hex x-only public key of the author
+	CreatedAt int64      `json:"created_at"` // Unix seconds
+	Kind      int        `json:"kind"`
+	Tags      [][]string `json:"tags"`
+	Content   string     `json:"content"`
+	Sig       string     `json:"sig"` // Hex BIP-340 signature over ID
+}
+
+// ParseEvent decodes a JSON event and checks its ID and signature.
+func ParseEvent(data []byte) (*Event, error) {
+	var ev Event
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return nil, fmt.Errorf("failed to decode nostr event: %w", err)
+	}
+	if err := ev.Verify(); err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+
+// Serialize returns the canonical NIP-01 serialization the event ID is the hash of:
+// [0, pubkey, created_at, kind, tags, content].
+func (ev *Event) Serialize() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`[0,`)
+	writeString(&buf, ev.PubKey)
+	buf.WriteByte(',')
+	buf.WriteString(strconv.FormatInt(ev.CreatedAt, 10))
+	buf.WriteByte(',')
+	buf.WriteString(strconv.Itoa(ev.Kind))
+	buf.WriteString(`,[`)
+	for i, tag := range ev.Tags {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('[')
+		for j, v := range tag {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			writeString(&buf, v)
+		}
+		buf.WriteByte(']')
+	}
+	buf.WriteString(`],`)
+	writeString(&buf, ev.Content)
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
+// writeString writes s as a JSON string with exactly the escaping NIP-01
+// prescribes; in particular HTML characters and U+2028/U+2029 are not escaped,
+// unlike encoding/json.
+func writeString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// ComputeID returns the hex event ID for the event's current contents.
+func (ev *Event) ComputeID() string {
+	sum := sha256.Sum256(ev.Serialize())
+	return hex.EncodeToString(sum[:])
+}
+
+// Sign sets the event's PubKey, ID and Sig using privateKey.
+func (ev *Event) Sign(privateKey []byte) error {
+	pub, err := PublicKey(privateKey)
+	if err != nil {
+		return err
+	}
+	ev.PubKey = hex.EncodeToString(pub)
+	ev.ID = ev.ComputeID()
+	id, _ := hex.DecodeString(ev.ID)
+	sig, err := SignSchnorr(privateKey, id, nil)
+	if err != nil {
+		return fmt.Errorf("failed to sign nostr event: %w", err)
+	}
+	ev.Sig = hex.EncodeToString(sig)
+	return nil
+}
+
+// Verify checks that the event's ID matches its contents and that Sig is a
+// valid signature over it by PubKey.
+func (ev *Event) Verify() error {
+	if !utf8.ValidString(ev.Content) {
+		return fmt.Errorf("nostr event content is not valid UTF-8")
+	}
+	if ev.ComputeID() != ev.ID {
+		return fmt.Errorf("nostr event ID does not match its contents")
+	}
+	pub, err := hex.DecodeString(ev.PubKey)
+	if err != nil {
+		return fmt.Errorf("invalid nostr pubkey: %w", err)
+	}
+	id, _ := hex.DecodeString(ev.ID)
+	sig, err := hex.DecodeString(ev.Sig)
+	if err != nil {
+		return fmt.Errorf("invalid nostr signature encoding: %w", err)
+	}
+	if err := VerifySchnorr(pub, id, sig); err != nil {
+		return fmt.Errorf("nostr event %s: %w", ev.ID, err)
+	}
+	return nil
+}
+
+// Hashtags returns the values of the event's "t" tags.
+func (ev *Event) Hashtags() []string {
+	var tags []string
+	for _, tag := range ev.Tags {
+		if len(tag) >= 2 && tag[0] == "t" && tag[1] != "" {
+			tags = append(tags, tag[1])
+		}
+	}
+	return tags
+}
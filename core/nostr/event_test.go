@@ -0,0 +1,53 @@
+package nostr
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEvent_Serialize(t *testing.T) {
+	ev := &Event{
+		PubKey:    "abcd",
+		CreatedAt: 1700000000,
+		Kind:      KindTextNote,
+		Tags:      [][]string{{"t", "go"}, {"subject", "a\"b"}},
+		Content:   "<b>&</b>\n \x01",
+	}
+	want := `[0,"abcd",1700000000,1,[["t","go"],["subject","a\"b"]],"<b>&</b>\n` + " " + `\u0001"]`
+	if got := string(ev.Serialize()); got != want {
+		t.Errorf("Serialize() = %s, want %s", got, want)
+	}
+	empty := &Event{PubKey: "ab", Kind: 1}
+	if got := string(empty.Serialize()); got != `[0,"ab",0,1,[],""]` {
+		t.Errorf("Serialize() with no tags = %s", got)
+	}
+}
+
+func TestEvent_SignAndParse(t *testing.T) {
+	sk, _ := GeneratePrivateKey()
+	ev := &Event{CreatedAt: 1700000000, Kind: KindTextNote, Tags: [][]string{{"t", "nostr"}}, Content: "hello"}
+	if err := ev.Sign(sk); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	data, _ := json.Marshal(ev)
+	parsed, err := ParseEvent(data)
+	if err != nil {
+		t.Fatalf("ParseEvent() error = %v", err)
+	}
+	if parsed.ID != ev.ID || parsed.Content != "hello" {
+		t.Errorf("ParseEvent() = %+v, want %+v", parsed, ev)
+	}
+	if got := parsed.Hashtags(); len(got) != 1 || got[0] != "nostr" {
+		t.Errorf("Hashtags() = %v, want [nostr]", got)
+	}
+
+	tampered := *ev
+	tampered.Content = "goodbye"
+	if err := tampered.Verify(); err == nil {
+		t.Error("Verify() with changed content: expected error, got nil")
+	}
+	tampered.ID = tampered.ComputeID() // Recomputing the ID does not fix the signature.
+	if err := tampered.Verify(); err == nil {
+		t.Error("Verify() with changed content and ID: expected error, got nil")
+	}
+}
@@ -0,0 +1,95 @@
+package nostr
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+)
+
+// This file wraps the BIP-340 Schnorr signatures over secp256k1 that Nostr
+// uses for event signatures. The curve arithmetic is btcec's, which is
+// constant-time where secret values are involved, so the bridge's mapping
+// keys do not leak through signing time.
+
+// ErrInvalidSignature is returned when a Schnorr signature does not verify.
+var ErrInvalidSignature = errors.New("invalid schnorr signature")
+
+// GeneratePrivateKey returns a new random 32-byte secp256k1 private key.
+func GeneratePrivateKey() ([]byte, error) {
+	key, err := btcec.NewPrivateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return key.Serialize(), nil
+}
+
+// PublicKey returns the 32-byte x-only public key of a private key.
+func PublicKey(privateKey []byte) ([]byte, error) {
+	key, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return schnorr.SerializePubKey(key.PubKey()), nil
+}
+
+func parsePrivateKey(privateKey []byte) (*btcec.PrivateKey, error) {
+	if len(privateKey) != 32 {
+		return nil, fmt.Errorf("private key must be 32 bytes, got %d", len(privateKey))
+	}
+	var d btcec.ModNScalar
+	if overflow := d.SetByteSlice(privateKey); overflow || d.IsZero() {
+		return nil, fmt.Errorf("private key is out of range")
+	}
+	return btcec.PrivKeyFromScalar(&d), nil
+}
+
+// SignSchnorr signs the 32-byte message msg with privateKey. auxRand is the
+// 32 bytes of auxiliary randomness BIP-340 mixes into the nonce; nil draws it
+// from crypto/rand.
+func SignSchnorr(privateKey, msg, auxRand []byte) ([]byte, error) {
+	key, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg) != 32 {
+		return nil, fmt.Errorf("message must be 32 bytes, got %d", len(msg))
+	}
+	var aux [32]byte
+	if auxRand == nil {
+		if _, err := rand.Read(aux[:]); err != nil {
+			return nil, fmt.Errorf("failed to generate nonce randomness: %w", err)
+		}
+	} else if len(auxRand) != 32 {
+		return nil, fmt.Errorf("auxiliary randomness must be 32 bytes, got %d", len(auxRand))
+	} else {
+		copy(aux[:], auxRand)
+	}
+	sig, err := schnorr.Sign(key, msg, schnorr.CustomNonce(aux))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+	return sig.Serialize(), nil
+}
+
+// VerifySchnorr checks a BIP-340 signature over the 32-byte message msg by
+// the x-only public key pub.
+func VerifySchnorr(pub, msg, sig []byte) error {
+	if len(pub) != 32 || len(msg) != 32 || len(sig) != 64 {
+		return fmt.Errorf("%w: bad key, message or signature length", ErrInvalidSignature)
+	}
+	key, err := schnorr.ParsePubKey(pub)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	s, err := schnorr.ParseSignature(sig)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if !s.Verify(msg, key) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package nostr
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func mustDecode(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("bad hex %q: %v", s, err)
+	}
+	return b
+}
+
+// Test vectors 0 and 1 from BIP-340.
+var bip340Vectors = []struct {
+	secKey, pubKey, auxRand, msg, sig string
+}{
+	{
+		"0000000000000000000000000000000000000000000000000000000000000003",
+		"F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0",
+	},
+	{
+		"B7E151628AED2A6ABF7158809CF4F3C762E7160F38B4DA56A784D9045190CFEF",
+		"DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		"0000000000000000000000000000000000000000000000000000000000000001",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A",
+	},
+}
+
+func TestSchnorr_BIP340Vectors(t *testing.T) {
+	for i, v := range bip340Vectors {
+		sk, pk := mustDecode(t, v.secKey), mustDecode(t, v.pubKey)
+		msg, aux, want := mustDecode(t, v.msg), mustDecode(t, v.auxRand), mustDecode(t, v.sig)
+
+		gotPK, err := PublicKey(sk)
+		if err != nil || !strings.EqualFold(hex.EncodeToString(gotPK), v.pubKey) {
+			t.Errorf("vector %d: PublicKey() = %x, %v; want %s", i, gotPK, err, v.pubKey)
+		}
+		sig, err := SignSchnorr(sk, msg, aux)
+		if err != nil || !strings.EqualFold(hex.EncodeToString(sig), v.sig) {
+			t.Errorf("vector %d: SignSchnorr() = %x, %v; want %s", i, sig, err, v.sig)
+		}
+		if err := VerifySchnorr(pk, msg, want); err != nil {
+			t.Errorf("vector %d: VerifySchnorr() error = %v", i, err)
+		}
+	}
+}
+
+func TestSchnorr_RejectsTampering(t *testing.T) {
+	sk, err := GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("GeneratePrivateKey() error = %v", err)
+	}
+	pk, _ := PublicKey(sk)
+	msg := make([]byte, 32)
+	msg[0] = 1
+	sig, err := SignSchnorr(sk, msg, nil)
+	if err != nil {
+		t.Fatalf("SignSchnorr() error = %v", err)
+	}
+	if err := VerifySchnorr(pk, msg, sig); err != nil {
+		t.Fatalf("VerifySchnorr() on a fresh signature error = %v", err)
+	}
+
+	otherMsg := append([]byte(nil), msg...)
+	otherMsg[31] ^= 1
+	badSig := append([]byte(nil), sig...)
+	badSig[63] ^= 1
+	otherSK, _ := GeneratePrivateKey()
+	otherPK, _ := PublicKey(otherSK)
+	for name, args := range map[string][3][]byte{
+		"different message": {pk, otherMsg, sig},
+		"modified sig":      {pk, msg, badSig},
+		"different key":     {otherPK, msg, sig},
+		"short sig":         {pk, msg, sig[:63]},
+	} {
+		if err := VerifySchnorr(args[0], args[1], args[2]); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("VerifySchnorr(%s) error = %v, want ErrInvalidSignature", name, err)
+		}
+	}
+}
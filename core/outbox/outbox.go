@@ -0,0 +1,621 @@
+// Package outbox queues signed transactions and content publish jobs while a
+// node or app is offline, or its peers are unreachable, and delivers them
+// once connectivity returns. It is meant for mobile apps and other clients
+// that must keep working without a network.
+//
+// Entries are persisted (see Store) and delivered in the order they were
+// queued. A delivery that fails for lack of connectivity is retried with
+// exponential backoff, and ends the flush so later entries are not sent ahead
+// of it; Online makes everything due at once. A delivery the network rejects
+// for good is a conflict: a transaction that no longer applies, or content
+// that published under a different CID than the transaction referencing it
+// expected. Conflicts, and the entries depending on them, are set aside for
+// the app to Retry or Discard. A transaction the network already has is
+// treated as delivered, so a flush interrupted after sending is safe to
+// repeat.
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"digisocialblock/core/api/client"
+	"digisocialblock/core/errcode"
+	"digisocialblock/core/ledger"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Retry defaults applied when Options leaves them zero.
+const (
+	DefaultRetryBackoff = 5 * time.Second
+	DefaultMaxBackoff   = 10 * time.Minute
+)
+
+// ErrNotFound is returned for an entry ID that is not in the outbox.
+var ErrNotFound = errors.New("outbox entry not found")
+
+// Kind is the kind of work an Entry holds.
+type Kind string
+
+// Entry kinds.
+const (
+	KindTransaction Kind = "transaction" // Submit Tx
+	KindPublish     Kind = "publish"     // Publish Content
+)
+
+// Status is the state of an Entry.
+type Status string
+
+// Entry states.
+const (
+	StatusPending  Status = "pending"  // Waiting to be delivered
+	StatusConflict Status = "conflict" // Rejected by the network; waiting for Retry or Discard
+)
+
+// Entry is a queued transaction or publish job.
+type Entry struct {
+	ID     string `json:"id"` // The transaction ID, or a random ID for a publish job
+	Kind   Kind   `json:"kind"`
+	Status Status `json:"status"`
+
+	Tx          *ledger.Transaction `json:"tx,omitempty"`
+	Content     []byte              `json:"content,omitempty"`
+	ExpectedCID string              `json:"expectedCID,omitempty"` // For a publish job, the CID a queued transaction references, if any
+	CID         string              `json:"cid,omitempty"`         // For a conflicting publish job, the CID the content went out under
+	DependsOn   string              `json:"dependsOn,omitempty"`   // Entry that must be delivered first
+
+	Queued    time.Time `json:"queued"`
+	Due       time.Time `json:"due"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// Submitter submits signed transactions to the network. Errors carrying an
+// errcode code, or a *client.Error, are classified by their HTTP status: a
+// duplicate transaction counts as delivered, other 4xx responses (but 408
+// and 429) are conflicts, and anything else is retried.
+type Submitter interface {
+	SubmitTransaction(ctx context.Context, tx *ledger.Transaction) error
+}
+
+// Publisher publishes content and returns its manifest CID. Its errors are
+// classified like a Submitter's. *content.ContentPublisher implements it.
+type Publisher interface {
+	PublishMediaToDDSContext(ctx context.Context, data []byte) (string, error)
+}
+
+// ClientSubmitter submits transactions through the node API.
+type ClientSubmitter struct {
+	Client *client.Client
+}
+
+// SubmitTransaction submits tx with POST /v1/transactions.
+func (s ClientSubmitter) SubmitTransaction(ctx context.Context, tx *ledger.Transaction) error {
+	_, err := s.Client.SubmitTransaction(ctx, client.FromLedger(tx))
+	return err
+}
+
+// Store persists the outbox between restarts.
+type Store interface {
+	// LoadOutbox returns the saved entries, or nil if nothing has been saved
+	// yet.
+	LoadOutbox() ([]Entry, error)
+	SaveOutbox(entries []Entry) error
+}
+
+// FileStore is a Store backed by a single JSON file. Queued content may be
+// private until it is published, so the file is written with mode 0600.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore creates a FileStore writing to path.
+func NewFileStore(path string) (*FileStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("outbox store path cannot be empty")
+	}
+	return &FileStore{path: path}, nil
+}
+
+// LoadOutbox reads the outbox file. A missing file is not an error.
+func (fs *FileStore) LoadOutbox() ([]Entry, error) {
+	data, err := os.ReadFile(fs.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox %s: %w", fs.path, err)
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode outbox %s: %w", fs.path, err)
+	}
+	return entries, nil
+}
+
+// SaveOutbox atomically replaces the outbox file.
+func (fs *FileStore) SaveOutbox(entries []Entry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox: %w", err)
+	}
+	tmp := fs.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write outbox %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, fs.path); err != nil {
+		return fmt.Errorf("failed to replace outbox %s: %w", fs.path, err)
+	}
+	return nil
+}
+
+// Options configures an Outbox.
+type Options struct {
+	// Store persists the outbox. Nil keeps it in memory only.
+	Store Store
+	// RetryBackoff is the delay before retrying a failed delivery; it
+	// doubles with each further attempt up to MaxBackoff.
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+}
+
+// Outbox queues transactions and publish jobs and delivers them in order.
+// It is safe for concurrent use; deliveries run one at a time.
+type Outbox struct {
+	submitter    Submitter
+	publisher    Publisher
+	store        Store
+	retryBackoff time.Duration
+	maxBackoff   time.Duration
+
+	flushMu sync.Mutex // Held for a whole flush
+	mu      sync.Mutex
+	clock   ledger.Clock
+	entries []*Entry // In queue order
+	wake    chan struct{}
+}
+
+// New creates an Outbox that delivers through submitter and publisher, and
+// loads any entries saved in opts.Store. publisher may be nil if only
+// transactions are queued.
+func New(submitter Submitter, publisher Publisher, opts Options) (*Outbox, error) {
+	if submitter == nil {
+		return nil, errors.New("submitter cannot be nil")
+	}
+	o := &Outbox{
+		submitter:    submitter,
+		publisher:    publisher,
+		store:        opts.Store,
+		retryBackoff: opts.RetryBackoff,
+		maxBackoff:   opts.MaxBackoff,
+		clock:        ledger.SystemClock,
+		wake:         make(chan struct{}, 1),
+	}
+	if o.retryBackoff <= 0 {
+		o.retryBackoff = DefaultRetryBackoff
+	}
+	if o.maxBackoff <= 0 {
+		o.maxBackoff = DefaultMaxBackoff
+	}
+	if o.store != nil {
+		entries, err := o.store.LoadOutbox()
+		if err != nil {
+			return nil, err
+		}
+		for i := range entries {
+			o.entries = append(o.entries, &entries[i])
+		}
+	}
+	return o, nil
+}
+
+// SetClock sets the clock that stamps and schedules entries. A nil clock
+// restores ledger.SystemClock.
+func (o *Outbox) SetClock(clock ledger.Clock) {
+	if clock == nil {
+		clock = ledger.SystemClock
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.clock = clock
+}
+
+// EnqueueTransaction queues a signed transaction and returns its entry ID,
+// the transaction ID. If dependsOn is set, the transaction is held until that
+// entry is delivered, e.g. a post until its content is published.
+func (o *Outbox) EnqueueTransaction(tx *ledger.Transaction, dependsOn string) (string, error) {
+	if tx == nil {
+		return "", errors.New("transaction cannot be nil")
+	}
+	if ok, err := tx.VerifySignature(); !ok {
+		return "", fmt.Errorf("transaction %s is not validly signed: %w", tx.ID, err)
+	}
+	return o.enqueue(&Entry{ID: tx.ID, Kind: KindTransaction, Tx: tx, DependsOn: dependsOn})
+}
+
+// EnqueuePublish queues content to publish and returns its entry ID.
+// expectedCID, if set, is the CID the content must publish under, normally
+// because a queued transaction already references it; any other CID is a
+// conflict.
+func (o *Outbox) EnqueuePublish(data []byte, expectedCID string) (string, error) {
+	if o.publisher == nil {
+		return "", errors.New("outbox has no publisher")
+	}
+	if len(data) == 0 {
+		return "", errors.New("content cannot be empty")
+	}
+	return o.enqueue(&Entry{ID: "publish-" + randomHex(8), Kind: KindPublish, Content: append([]byte(nil), data...), ExpectedCID: expectedCID})
+}
+
+// enqueue appends e, due now, and saves the outbox.
+func (o *Outbox) enqueue(e *Entry) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if e.DependsOn != "" && o.findLocked(e.DependsOn) == nil {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, e.DependsOn)
+	}
+	if o.findLocked(e.ID) != nil {
+		return "", fmt.Errorf("entry %s is already queued", e.ID)
+	}
+	e.Status = StatusPending
+	e.Queued = o.clock.Now()
+	e.Due = e.Queued
+	o.entries = append(o.entries, e)
+	if err := o.saveLocked(); err != nil {
+		o.entries = o.entries[:len(o.entries)-1]
+		return "", err
+	}
+	o.signal()
+	return e.ID, nil
+}
+
+// findLocked returns the entry with the given ID. The caller must hold o.mu.
+func (o *Outbox) findLocked(id string) *Entry {
+	for _, e := range o.entries {
+		if e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+// saveLocked persists the entries. The caller must hold o.mu.
+func (o *Outbox) saveLocked() error {
+	if o.store == nil {
+		return nil
+	}
+	entries := make([]Entry, len(o.entries))
+	for i, e := range o.entries {
+		entries[i] = *e
+	}
+	return o.store.SaveOutbox(entries)
+}
+
+// signal wakes Run.
+func (o *Outbox) signal() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Entries returns a copy of every entry, pending and conflicting, in queue
+// order.
+func (o *Outbox) Entries() []Entry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entries := make([]Entry, len(o.entries))
+	for i, e := range o.entries {
+		entries[i] = *e
+	}
+	return entries
+}
+
+// Online reports that connectivity has returned: every pending entry is due
+// now, and Run flushes at once.
+func (o *Outbox) Online() {
+	o.mu.Lock()
+	now := o.clock.Now()
+	for _, e := range o.entries {
+		if e.Status == StatusPending {
+			e.Due = now
+		}
+	}
+	o.mu.Unlock()
+	o.signal()
+}
+
+// Retry requeues a conflicting entry, and the entries that depend on it, to
+// be delivered again, e.g. after the app has resolved the conflict.
+func (o *Outbox) Retry(id string) error {
+	return o.update(id, func(e *Entry, now time.Time) {
+		e.Status, e.Due, e.Attempts = StatusPending, now, 0
+	})
+}
+
+// Discard removes an entry, and the entries that depend on it.
+func (o *Outbox) Discard(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.findLocked(id) == nil {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	drop := o.dependentsLocked(id)
+	var kept []*Entry
+	for _, e := range o.entries {
+		if !drop[e.ID] {
+			kept = append(kept, e)
+		}
+	}
+	o.entries = kept
+	return o.saveLocked()
+}
+
+// update applies fn to the entry with the given ID and the entries that
+// depend on it, then saves the outbox and wakes Run.
+func (o *Outbox) update(id string, fn func(e *Entry, now time.Time)) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.findLocked(id) == nil {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	affected := o.dependentsLocked(id)
+	now := o.clock.Now()
+	for _, e := range o.entries {
+		if affected[e.ID] {
+			fn(e, now)
+		}
+	}
+	if err := o.saveLocked(); err != nil {
+		return err
+	}
+	o.signal()
+	return nil
+}
+
+// dependentsLocked returns id and the IDs of every entry that depends on it,
+// directly or not. The caller must hold o.mu.
+func (o *Outbox) dependentsLocked(id string) map[string]bool {
+	set := map[string]bool{id: true}
+	// Dependencies always point to earlier entries, so one pass finds them all.
+	for _, e := range o.entries {
+		if e.DependsOn != "" && set[e.DependsOn] {
+			set[e.ID] = true
+		}
+	}
+	return set
+}
+
+// Flush delivers every due entry in queue order and returns the number
+// delivered. Delivered entries are removed, and rejected ones set aside as
+// conflicts. It stops at the first delivery that fails for lack of
+// connectivity, rescheduling it with backoff, and returns that error.
+func (o *Outbox) Flush(ctx context.Context) (int, error) {
+	o.flushMu.Lock()
+	defer o.flushMu.Unlock()
+	delivered := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return delivered, err
+		}
+		e := o.nextDue()
+		if e == nil {
+			return delivered, nil
+		}
+		cid, err := o.deliver(ctx, e)
+		switch outcome := classify(err); {
+		case outcome == outcomeDelivered:
+			if err := o.complete(e.ID); err != nil {
+				return delivered, err
+			}
+			delivered++
+		case outcome == outcomeConflict:
+			if err := o.conflict(e.ID, cid, err); err != nil {
+				return delivered, err
+			}
+		default:
+			if saveErr := o.reschedule(e.ID, err); saveErr != nil {
+				return delivered, saveErr
+			}
+			return delivered, fmt.Errorf("outbox entry %s: %w", e.ID, err)
+		}
+	}
+}
+
+// nextDue returns a copy of the first pending entry if it is due, or nil.
+// Later entries wait behind it, even if due, to keep the queue order; the
+// entry it depends on, if any, is earlier and so already delivered.
+func (o *Outbox) nextDue() *Entry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, e := range o.entries {
+		if e.Status != StatusPending {
+			continue
+		}
+		if e.Due.After(o.clock.Now()) {
+			return nil
+		}
+		copied := *e
+		return &copied
+	}
+	return nil
+}
+
+// deliver makes one delivery attempt and returns the CID content was
+// published under.
+func (o *Outbox) deliver(ctx context.Context, e *Entry) (string, error) {
+	if e.Kind == KindTransaction {
+		return "", o.submitter.SubmitTransaction(ctx, e.Tx)
+	}
+	if o.publisher == nil {
+		return "", &conflictError{errors.New("outbox has no publisher")}
+	}
+	cid, err := o.publisher.PublishMediaToDDSContext(ctx, e.Content)
+	if err != nil {
+		return "", err
+	}
+	if e.ExpectedCID != "" && cid != e.ExpectedCID {
+		return cid, &conflictError{fmt.Errorf("content published as %s, but %s was expected", cid, e.ExpectedCID)}
+	}
+	return cid, nil
+}
+
+// complete removes a delivered entry.
+func (o *Outbox) complete(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i, e := range o.entries {
+		if e.ID == id {
+			o.entries = append(o.entries[:i], o.entries[i+1:]...)
+			break
+		}
+	}
+	return o.saveLocked()
+}
+
+// conflict sets aside a rejected entry and the entries depending on it. cid
+// is the CID a publish job's content went out under, if it did.
+func (o *Outbox) conflict(id, cid string, cause error) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	affected := o.dependentsLocked(id)
+	for _, e := range o.entries {
+		if !affected[e.ID] {
+			continue
+		}
+		e.Status = StatusConflict
+		if e.ID == id {
+			e.Attempts++
+			e.LastError = cause.Error()
+			e.CID = cid
+		} else {
+			e.LastError = fmt.Sprintf("depends on conflicting entry %s", id)
+		}
+	}
+	return o.saveLocked()
+}
+
+// reschedule records a failed attempt and backs the entry off.
+func (o *Outbox) reschedule(id string, cause error) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	e := o.findLocked(id)
+	if e == nil {
+		return nil // Discarded during the attempt
+	}
+	e.Attempts++
+	e.LastError = cause.Error()
+	e.Due = o.clock.Now().Add(o.backoff(e.Attempts))
+	return o.saveLocked()
+}
+
+// backoff returns the delay after the given failed attempt.
+func (o *Outbox) backoff(attempt int) time.Duration {
+	delay := o.retryBackoff
+	for i := 1; i < attempt && delay < o.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > o.maxBackoff {
+		delay = o.maxBackoff
+	}
+	return delay
+}
+
+// Run flushes the outbox until ctx is cancelled, sleeping until the next
+// entry is due, something is queued or Online is called. It returns
+// ctx.Err().
+func (o *Outbox) Run(ctx context.Context) error {
+	for {
+		o.Flush(ctx)
+		if err := o.waitNext(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// waitNext blocks until the first pending entry is due, the outbox is woken,
+// or ctx is done.
+func (o *Outbox) waitNext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	o.mu.Lock()
+	var next <-chan time.Time
+	for _, e := range o.entries {
+		if e.Status == StatusPending {
+			timer := time.NewTimer(e.Due.Sub(o.clock.Now()))
+			defer timer.Stop()
+			next = timer.C
+			break
+		}
+	}
+	o.mu.Unlock()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-o.wake:
+		return nil
+	case <-next:
+		return nil
+	}
+}
+
+// outcome is how a delivery attempt ended.
+type outcome int
+
+const (
+	outcomeDelivered outcome = iota
+	outcomeConflict
+	outcomeRetry
+)
+
+// conflictError marks an error found by the outbox itself as a conflict.
+type conflictError struct{ err error }
+
+func (e *conflictError) Error() string { return e.err.Error() }
+func (e *conflictError) Unwrap() error { return e.err }
+
+// classify maps the error of a delivery attempt to its outcome.
+func classify(err error) outcome {
+	if err == nil {
+		return outcomeDelivered
+	}
+	var ce *conflictError
+	if errors.As(err, &ce) {
+		return outcomeConflict
+	}
+	var code errcode.Code
+	var status int
+	var apiErr *client.Error
+	if c, ok := errcode.Of(err); ok {
+		code = c
+		if info, ok := errcode.Lookup(c); ok {
+			status = info.HTTPStatus
+		}
+	} else if errors.As(err, &apiErr) {
+		code, status = errcode.Code(apiErr.ErrorCode), apiErr.StatusCode
+	}
+	switch {
+	case code == ledger.CodeDuplicateTransaction:
+		return outcomeDelivered
+	case status == http.StatusRequestTimeout || status == http.StatusTooManyRequests:
+		return outcomeRetry
+	case status >= 400 && status < 500:
+		return outcomeConflict
+	}
+	return outcomeRetry
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
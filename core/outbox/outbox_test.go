@@ -0,0 +1,184 @@
+package outbox
+
+import (
+	"context"
+	"digisocialblock/core/api/client"
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil"
+	"digisocialblock/internal/testutil/fixture"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// outboxTestNetwork fakes a node: it fails everything while offline, and
+// otherwise answers with the error set for a transaction, if any.
+type outboxTestNetwork struct {
+	mu        sync.Mutex
+	offline   bool
+	errs      map[string]error // Transaction ID -> error to answer with
+	cid       string           // CID content publishes under; "" means "cid-" + the content
+	delivered []string         // Transaction IDs and published content, in order
+}
+
+func (n *outboxTestNetwork) SubmitTransaction(ctx context.Context, tx *ledger.Transaction) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.offline {
+		return errors.New("dial tcp: network is unreachable")
+	}
+	if err := n.errs[tx.ID]; err != nil {
+		return err
+	}
+	n.delivered = append(n.delivered, tx.ID)
+	return nil
+}
+
+func (n *outboxTestNetwork) PublishMediaToDDSContext(ctx context.Context, data []byte) (string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.offline {
+		return "", errors.New("dial tcp: network is unreachable")
+	}
+	n.delivered = append(n.delivered, string(data))
+	if n.cid != "" {
+		return n.cid, nil
+	}
+	return "cid-" + string(data), nil
+}
+
+func (n *outboxTestNetwork) setOffline(offline bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.offline = offline
+}
+
+func TestOutbox_QueuesWhileOfflineAndFlushesInOrder(t *testing.T) {
+	wallet := fixture.Wallet(t)
+	network := &outboxTestNetwork{offline: true}
+	store, _ := NewFileStore(filepath.Join(t.TempDir(), "outbox.json"))
+	clock := testutil.NewClock(0)
+	o, err := New(network, network, Options{Store: store, RetryBackoff: time.Minute})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	o.SetClock(clock)
+
+	publishID, err := o.EnqueuePublish([]byte("hello"), "cid-hello")
+	if err != nil {
+		t.Fatalf("EnqueuePublish() error = %v", err)
+	}
+	post := fixture.PostTx(t, wallet, "cid-hello")
+	if _, err := o.EnqueueTransaction(post, publishID); err != nil {
+		t.Fatalf("EnqueueTransaction() error = %v", err)
+	}
+	follow := fixture.FollowTx(t, wallet, "bob")
+	o.EnqueueTransaction(follow, "")
+	if _, err := o.EnqueueTransaction(follow, ""); err == nil {
+		t.Error("EnqueueTransaction() twice: expected error, got nil")
+	}
+
+	if n, err := o.Flush(context.Background()); n != 0 || err == nil {
+		t.Fatalf("Flush() offline = %d, %v; want nothing delivered and an error", n, err)
+	}
+	entries := o.Entries()
+	if len(entries) != 3 || entries[0].Attempts != 1 || !entries[0].Due.Equal(clock.Peek().Add(time.Minute)) || entries[1].Attempts != 0 {
+		t.Fatalf("Entries() after an offline flush = %+v; want the first backed off and the rest untried", entries)
+	}
+
+	// The queue survives a restart.
+	o, err = New(network, network, Options{Store: store})
+	if err != nil {
+		t.Fatalf("New() with a saved outbox error = %v", err)
+	}
+	o.SetClock(clock)
+	network.setOffline(false)
+	if n, _ := o.Flush(context.Background()); n != 0 {
+		t.Errorf("Flush() before the retry is due delivered %d entries", n)
+	}
+	o.Online()
+	if n, err := o.Flush(context.Background()); n != 3 || err != nil {
+		t.Fatalf("Flush() after Online = %d, %v; want 3 delivered", n, err)
+	}
+	if got, want := strings.Join(network.delivered, " "), "hello "+post.ID+" "+follow.ID; got != want {
+		t.Errorf("delivered %q, want %q", got, want)
+	}
+	if saved, _ := store.LoadOutbox(); len(saved) != 0 || len(o.Entries()) != 0 {
+		t.Errorf("outbox after delivery holds %d entries, saved %d; want none", len(o.Entries()), len(saved))
+	}
+}
+
+func TestOutbox_SetsAsideConflicts(t *testing.T) {
+	wallet := fixture.Wallet(t)
+	pending := fixture.PostTx(t, wallet, "cid-a")
+	rejected := fixture.PostTx(t, wallet, "cid-b")
+	dependent := fixture.FollowTx(t, wallet, "bob")
+	network := &outboxTestNetwork{errs: map[string]error{
+		pending.ID:  fmt.Errorf("%w: sent before a restart", ledger.ErrDuplicateTransaction),
+		rejected.ID: fmt.Errorf("%w: for another chain", ledger.ErrWrongChain),
+	}}
+	o, _ := New(network, network, Options{})
+	o.EnqueueTransaction(pending, "")
+	o.EnqueueTransaction(rejected, "")
+	o.EnqueueTransaction(dependent, rejected.ID)
+	mismatched, _ := o.EnqueuePublish([]byte("edited"), "cid-original")
+
+	if n, err := o.Flush(context.Background()); n != 1 || err != nil {
+		t.Fatalf("Flush() = %d, %v; want the duplicate counted as delivered", n, err)
+	}
+	entries := o.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("Entries() = %+v, want the three conflicts", entries)
+	}
+	for _, e := range entries {
+		if e.Status != StatusConflict {
+			t.Errorf("entry %s status = %s, want conflict", e.ID, e.Status)
+		}
+	}
+	if entries[1].DependsOn != rejected.ID || !strings.Contains(entries[1].LastError, rejected.ID) {
+		t.Errorf("dependent entry = %+v, want it set aside with the entry it depends on", entries[1])
+	}
+	if entries[2].ID != mismatched || entries[2].CID != "cid-edited" {
+		t.Errorf("publish entry = %+v, want the CID it went out under", entries[2])
+	}
+
+	// Once resolved, a retried conflict goes out with its dependents.
+	delete(network.errs, rejected.ID)
+	if err := o.Retry(rejected.ID); err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+	if n, _ := o.Flush(context.Background()); n != 2 {
+		t.Errorf("Flush() after Retry delivered %d, want the entry and its dependent", n)
+	}
+	if err := o.Discard(mismatched); err != nil || len(o.Entries()) != 0 {
+		t.Errorf("Discard() = %v, leaving %d entries; want an empty outbox", err, len(o.Entries()))
+	}
+	if err := o.Discard(mismatched); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Discard() of a removed entry error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want outcome
+	}{
+		{nil, outcomeDelivered},
+		{errors.New("connection refused"), outcomeRetry},
+		{fmt.Errorf("%w: again", ledger.ErrDuplicateTransaction), outcomeDelivered},
+		{ledger.ErrInvalidSignature, outcomeConflict},
+		{&client.Error{StatusCode: http.StatusConflict, APIError: client.APIError{ErrorCode: "DSB-LEDGER-008"}}, outcomeDelivered},
+		{&client.Error{StatusCode: http.StatusTooManyRequests}, outcomeRetry},
+		{&client.Error{StatusCode: http.StatusUnprocessableEntity}, outcomeConflict},
+		{&client.Error{StatusCode: http.StatusServiceUnavailable}, outcomeRetry},
+	} {
+		if got := classify(tt.err); got != tt.want {
+			t.Errorf("classify(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
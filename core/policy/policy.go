@@ -0,0 +1,399 @@
+// Package policy lets node operators declare rules about transactions in a
+// configuration file, such as how many tags a post may carry, how old an
+// account must be before it creates lists or posts in groups, or how deeply
+// comments may nest. An Engine compiles the rules into ledger
+// TransactionRules for the node's Mempool and Blockchain:
+//
+//	{"rules": [
+//	  {"type": "PostCreated", "maxTags": 5},
+//	  {"type": "PostCreated", "groupPosts": true, "minAccountAge": "72h"},
+//	  {"type": "CommentAdded", "maxCommentDepth": 6, "scope": "chain"},
+//	  {"type": "*", "maxPayloadBytes": 4096}
+//	]}
+//
+// Rules are node policy: by default they only decide what the node admits to
+// its mempool, and so relays and puts in its own blocks. Rules with the
+// "chain" scope also decide which blocks the chain accepts, and must be the
+// same on every node of the chain.
+package policy
+
+import (
+	"bytes"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrPolicyDiverged is returned by Engine.Sync when the engine's high-water
+// mark no longer matches the chain. Call Rebuild to recover.
+var ErrPolicyDiverged = errors.New("policy engine high-water mark does not match the chain")
+
+// AnyType is the Rule.Type that selects every transaction type.
+const AnyType ledger.TransactionType = "*"
+
+// Scope is where a rule is enforced.
+type Scope string
+
+// Rule scopes.
+const (
+	ScopeMempool Scope = "mempool" // Mempool admission only; the default
+	ScopeChain   Scope = "chain"   // Mempool admission and block validation
+)
+
+// Rule is one declared rule: limits that apply to transactions of Type. Zero
+// limits are not checked, and a rule must set at least one.
+type Rule struct {
+	Name       string                 `json:"name,omitempty"` // For error messages; defaults to the rule's position
+	Type       ledger.TransactionType `json:"type"`           // Or AnyType
+	Scope      Scope                  `json:"scope,omitempty"`
+	GroupPosts bool                   `json:"groupPosts,omitempty"` // Only PostCreated transactions of group posts
+
+	MaxPayloadBytes int    `json:"maxPayloadBytes,omitempty"`
+	MaxTags         int    `json:"maxTags,omitempty"`         // PostCreated
+	MaxMedia        int    `json:"maxMedia,omitempty"`        // PostCreated
+	MaxCommentDepth int    `json:"maxCommentDepth,omitempty"` // CommentAdded; top-level comments are depth 1
+	MinAccountAge   string `json:"minAccountAge,omitempty"`   // Go duration since the sender's first transaction on the chain, e.g. "72h"
+}
+
+// Config is a policy configuration file.
+type Config struct {
+	Rules []Rule `json:"rules"`
+}
+
+// ParseConfig decodes a Config from JSON. Unknown fields are rejected, so a
+// misspelt limit is an error rather than silently unenforced.
+func ParseConfig(data []byte) (*Config, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var c Config
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("failed to parse policy config: %w", err)
+	}
+	return &c, nil
+}
+
+// LoadConfig reads and decodes the Config at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy config %s: %w", path, err)
+	}
+	return ParseConfig(data)
+}
+
+// check is one compiled limit. It returns nil to allow the transaction.
+type check func(e *Engine, tx *ledger.Transaction, env ledger.RuleEnv, d *decoded) error
+
+// compiledRule is a Rule compiled into its checks.
+type compiledRule struct {
+	name   string
+	rule   Rule
+	checks []check
+}
+
+// decoded caches a transaction's payload decoded for the checks that need
+// it, so several rules decode it once.
+type decoded struct {
+	tx      *ledger.Transaction
+	post    *social.Post
+	comment *social.Comment
+	err     error
+	done    bool
+}
+
+func (d *decoded) decode() error {
+	if d.done {
+		return d.err
+	}
+	d.done = true
+	switch d.tx.Type {
+	case ledger.PostCreated:
+		d.post, d.err = social.PostFromPayload(d.tx.Payload)
+	case ledger.CommentAdded:
+		d.comment, d.err = social.CommentFromPayload(d.tx.Payload)
+	}
+	return d.err
+}
+
+// compile validates r, the rule at position i, and compiles its checks.
+func compile(i int, r Rule) (compiledRule, error) {
+	c := compiledRule{name: r.Name, rule: r}
+	if c.name == "" {
+		c.name = fmt.Sprintf("rule %d", i)
+	}
+	fail := func(format string, args ...interface{}) (compiledRule, error) {
+		return compiledRule{}, fmt.Errorf("%s: %s", c.name, fmt.Sprintf(format, args...))
+	}
+	if r.Type == "" {
+		return fail("type is required")
+	}
+	switch r.Scope {
+	case "":
+		c.rule.Scope = ScopeMempool
+	case ScopeMempool, ScopeChain:
+	default:
+		return fail("unknown scope %q", r.Scope)
+	}
+	if r.MaxPayloadBytes < 0 || r.MaxTags < 0 || r.MaxMedia < 0 || r.MaxCommentDepth < 0 {
+		return fail("limits cannot be negative")
+	}
+	onlyFor := func(field string, t ledger.TransactionType) error {
+		if r.Type != t {
+			return fmt.Errorf("%s: %s only applies to %s", c.name, field, t)
+		}
+		return nil
+	}
+	if r.GroupPosts {
+		if err := onlyFor("groupPosts", ledger.PostCreated); err != nil {
+			return compiledRule{}, err
+		}
+	}
+
+	if n := r.MaxPayloadBytes; n > 0 {
+		c.checks = append(c.checks, func(_ *Engine, tx *ledger.Transaction, _ ledger.RuleEnv, _ *decoded) error {
+			if len(tx.Payload) > n {
+				return fmt.Errorf("payload is %d bytes, limit %d", len(tx.Payload), n)
+			}
+			return nil
+		})
+	}
+	if n := r.MaxTags; n > 0 {
+		if err := onlyFor("maxTags", ledger.PostCreated); err != nil {
+			return compiledRule{}, err
+		}
+		c.checks = append(c.checks, func(_ *Engine, _ *ledger.Transaction, _ ledger.RuleEnv, d *decoded) error {
+			if len(d.post.Tags) > n {
+				return fmt.Errorf("post has %d tags, limit %d", len(d.post.Tags), n)
+			}
+			return nil
+		})
+	}
+	if n := r.MaxMedia; n > 0 {
+		if err := onlyFor("maxMedia", ledger.PostCreated); err != nil {
+			return compiledRule{}, err
+		}
+		c.checks = append(c.checks, func(_ *Engine, _ *ledger.Transaction, _ ledger.RuleEnv, d *decoded) error {
+			if len(d.post.Media) > n {
+				return fmt.Errorf("post has %d media attachments, limit %d", len(d.post.Media), n)
+			}
+			return nil
+		})
+	}
+	if n := r.MaxCommentDepth; n > 0 {
+		if err := onlyFor("maxCommentDepth", ledger.CommentAdded); err != nil {
+			return compiledRule{}, err
+		}
+		c.checks = append(c.checks, func(e *Engine, _ *ledger.Transaction, _ ledger.RuleEnv, d *decoded) error {
+			if depth := e.commentDepth(d.comment); depth > n {
+				return fmt.Errorf("comment is at depth %d, limit %d", depth, n)
+			}
+			return nil
+		})
+	}
+	if r.MinAccountAge != "" {
+		age, err := time.ParseDuration(r.MinAccountAge)
+		if err != nil || age <= 0 {
+			return fail("minAccountAge %q is not a positive duration", r.MinAccountAge)
+		}
+		c.checks = append(c.checks, func(e *Engine, tx *ledger.Transaction, env ledger.RuleEnv, _ *decoded) error {
+			if got := e.accountAge(tx.SenderPublicKey, env.Time); got < age {
+				return fmt.Errorf("account is %s old, minimum %s", got.Round(time.Second), age)
+			}
+			return nil
+		})
+	}
+	if len(c.checks) == 0 {
+		return fail("sets no limits")
+	}
+	return c, nil
+}
+
+// applies reports whether r selects tx, decoding it if r needs to look
+// inside.
+func (r *compiledRule) applies(tx *ledger.Transaction, d *decoded) bool {
+	if r.rule.Type != AnyType && r.rule.Type != tx.Type {
+		return false
+	}
+	if r.rule.GroupPosts {
+		return d.decode() == nil && d.post.GroupID != ""
+	}
+	return true
+}
+
+// Engine enforces a Config. Its account-age and comment-depth checks need to
+// know the chain: keep the engine current with Sync, typically from the loop
+// that syncs the node's other indexes. Until it has seen a sender, the
+// sender counts as a new account; until it has seen a comment, replies to it
+// count as replies to a top-level comment. An Engine is safe for concurrent
+// use.
+type Engine struct {
+	rules         []compiledRule
+	trackAccounts bool
+	trackComments bool
+
+	syncMu   sync.Mutex // Serializes Sync and Rebuild; guards follower
+	follower ledger.ChainFollower
+
+	mu        sync.RWMutex
+	firstSeen map[string]time.Time // Sender address to the time of their first block
+	depths    map[string]int       // CommentAdded transaction ID to its depth
+}
+
+// NewEngine compiles cfg. Every rule is checked, and the first invalid one
+// is reported.
+func NewEngine(cfg Config) (*Engine, error) {
+	e := &Engine{}
+	for i, r := range cfg.Rules {
+		c, err := compile(i, r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy: %w", err)
+		}
+		e.rules = append(e.rules, c)
+		e.trackAccounts = e.trackAccounts || r.MinAccountAge != ""
+		e.trackComments = e.trackComments || r.MaxCommentDepth > 0
+	}
+	e.reset()
+	return e, nil
+}
+
+// reset clears the engine. The caller must hold e.syncMu and e.mu (or own e
+// exclusively).
+func (e *Engine) reset() {
+	e.follower = ledger.NewChainFollower()
+	e.firstSeen = make(map[string]time.Time)
+	e.depths = make(map[string]int)
+}
+
+// MempoolRule returns the rule to pass to ledger.Mempool.SetTransactionRule:
+// every rule of the policy. Chain-scoped rules are included so the mempool
+// does not admit transactions no block could include.
+func (e *Engine) MempoolRule() ledger.TransactionRule {
+	return ledger.TransactionRuleFunc(func(tx *ledger.Transaction, env ledger.RuleEnv) error {
+		return e.check(tx, env, false)
+	})
+}
+
+// ChainRule returns the rule to pass to ledger.Blockchain.SetTransactionRule:
+// the chain-scoped rules only.
+func (e *Engine) ChainRule() ledger.TransactionRule {
+	return ledger.TransactionRuleFunc(func(tx *ledger.Transaction, env ledger.RuleEnv) error {
+		return e.check(tx, env, true)
+	})
+}
+
+// check applies the rules, or the chain-scoped ones if chainOnly, to tx.
+func (e *Engine) check(tx *ledger.Transaction, env ledger.RuleEnv, chainOnly bool) error {
+	d := &decoded{tx: tx}
+	for i := range e.rules {
+		r := &e.rules[i]
+		if (chainOnly && r.rule.Scope != ScopeChain) || !r.applies(tx, d) {
+			continue
+		}
+		if r.rule.Type == ledger.PostCreated || r.rule.Type == ledger.CommentAdded {
+			// The ledger's payload validator rejects what does not decode.
+			if d.decode() != nil {
+				continue
+			}
+		}
+		for _, c := range r.checks {
+			if err := c(e, tx, env, d); err != nil {
+				return fmt.Errorf("%s: %w", r.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// accountAge returns how long before now sender first appeared on the chain,
+// or zero for a sender the engine has not seen.
+func (e *Engine) accountAge(sender string, now time.Time) time.Duration {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	first, ok := e.firstSeen[sender]
+	if !ok || now.Before(first) {
+		return 0
+	}
+	return now.Sub(first)
+}
+
+// commentDepth returns the depth c would have in its comment tree.
+func (e *Engine) commentDepth(c *social.Comment) int {
+	if c.ParentTxID == "" {
+		return 1
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	parent, ok := e.depths[c.ParentTxID]
+	if !ok {
+		parent = 1
+	}
+	return parent + 1
+}
+
+// HighWaterMark reports the last block the engine's chain rules have seen.
+func (e *Engine) HighWaterMark() (int64, string) {
+	e.syncMu.Lock()
+	defer e.syncMu.Unlock()
+	return e.follower.HighWaterMark()
+}
+
+// Sync processes the blocks added to bc since the last call and returns how
+// many there were. Blocks are read without holding the engine's lock, so
+// rules applied by bc while it adds a block never wait on Sync.
+func (e *Engine) Sync(bc *ledger.Blockchain) (int, error) {
+	e.syncMu.Lock()
+	defer e.syncMu.Unlock()
+	return e.follower.Sync(bc, "policy engine", ErrPolicyDiverged, e.lockedProcessBlock)
+}
+
+// Rebuild discards what the engine knows of the chain and reprocesses it.
+func (e *Engine) Rebuild(bc *ledger.Blockchain) (int, error) {
+	if bc == nil {
+		return 0, fmt.Errorf("blockchain cannot be nil")
+	}
+	e.syncMu.Lock()
+	defer e.syncMu.Unlock()
+	e.mu.Lock()
+	e.reset()
+	e.mu.Unlock()
+	return e.follower.Sync(bc, "policy engine", ErrPolicyDiverged, e.lockedProcessBlock)
+}
+
+// lockedProcessBlock calls processBlock holding e.mu for that block only.
+func (e *Engine) lockedProcessBlock(block *ledger.Block) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.processBlock(block)
+}
+
+// processBlock records the senders and comments of block. The caller must
+// hold e.mu.
+func (e *Engine) processBlock(block *ledger.Block) {
+	at := time.Unix(0, block.Timestamp)
+	for _, tx := range block.Transactions {
+		if tx == nil {
+			continue
+		}
+		if _, ok := e.firstSeen[tx.SenderPublicKey]; e.trackAccounts && !ok {
+			e.firstSeen[tx.SenderPublicKey] = at
+		}
+		if e.trackComments && tx.Type == ledger.CommentAdded {
+			if c, err := social.CommentFromPayload(tx.Payload); err == nil {
+				depth := 1
+				if c.ParentTxID != "" {
+					if parent, ok := e.depths[c.ParentTxID]; ok {
+						depth = parent + 1
+					} else {
+						depth = 2
+					}
+				}
+				e.depths[tx.ID] = depth
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package policy
+
+import (
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"digisocialblock/internal/testutil"
+	"digisocialblock/internal/testutil/fixture"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const policyTestConfig = `{"rules": [
+	{"name": "tags", "type": "PostCreated", "maxTags": 2},
+	{"name": "young accounts", "type": "PostCreated", "groupPosts": true, "minAccountAge": "72h"},
+	{"name": "threads", "type": "CommentAdded", "maxCommentDepth": 2, "scope": "chain"},
+	{"type": "*", "maxPayloadBytes": 4096}
+]}`
+
+// policyTestSetup returns a chain and mempool on a fake clock, enforcing
+// policyTestConfig.
+func policyTestSetup(t *testing.T) (*Engine, *ledger.Blockchain, *ledger.Mempool, *testutil.Clock) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(policyTestConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	engine, err := NewEngine(*cfg)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	bc, _ := ledger.NewBlockchain()
+	clock := testutil.NewClock(time.Millisecond)
+	bc.SetClock(clock)
+	mempool := ledger.NewMempool(bc.SignatureCache())
+	mempool.SetClock(clock)
+	mempool.SetTransactionRule(engine.MempoolRule())
+	bc.SetTransactionRule(engine.ChainRule())
+	return engine, bc, mempool, clock
+}
+
+func TestEngine_MempoolRules(t *testing.T) {
+	engine, bc, mempool, clock := policyTestSetup(t)
+	alice := fixture.Wallet(t)
+
+	if err := mempool.Add(fixture.PostTx(t, alice, "cid-1", "a", "b", "c")); !errors.Is(err, ledger.ErrPolicyViolation) || !strings.Contains(err.Error(), "tags") {
+		t.Errorf("Add() of a post with 3 tags error = %v, want the tags rule", err)
+	}
+	if err := mempool.Add(fixture.PostTx(t, alice, "cid-1", "a", "b")); err != nil {
+		t.Fatalf("Add() of a post with 2 tags error = %v", err)
+	}
+
+	groupPost := func() *ledger.Transaction {
+		post := social.NewPost(alice.Address, "cid-group", "", nil)
+		post.GroupID, post.KeyEpoch = "club", 1
+		return fixture.Tx(t, alice, ledger.PostCreated, post)
+	}
+	if err := mempool.Add(groupPost()); !errors.Is(err, ledger.ErrPolicyViolation) {
+		t.Errorf("Add() of a group post by an unseen account error = %v, want a policy violation", err)
+	}
+	if _, err := bc.AddBlock(mempool.Pending()); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+	if _, err := engine.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	clock.Advance(71 * time.Hour)
+	if err := mempool.Add(groupPost()); err == nil {
+		t.Error("Add() of a group post by a 71 hour old account succeeded")
+	}
+	clock.Advance(time.Hour)
+	if err := mempool.Add(groupPost()); err != nil {
+		t.Errorf("Add() of a group post by a 72 hour old account error = %v", err)
+	}
+
+	// Mempool-scoped rules do not bind the chain.
+	if _, err := bc.AddBlock([]*ledger.Transaction{fixture.PostTx(t, alice, "cid-2", "a", "b", "c")}); err != nil {
+		t.Errorf("AddBlock() of a post breaking a mempool rule error = %v", err)
+	}
+}
+
+func TestEngine_ChainRulesLimitCommentDepth(t *testing.T) {
+	engine, bc, _, _ := policyTestSetup(t)
+	alice := fixture.Wallet(t)
+	post := fixture.PostTx(t, alice, "cid-post")
+	comment := func(parent string) *ledger.Transaction {
+		return fixture.Tx(t, alice, ledger.CommentAdded, &social.Comment{
+			AuthorPublicKey: alice.Address, PostTxID: post.ID, ParentTxID: parent, ContentCID: "cid-comment", Timestamp: 1})
+	}
+	add := func(tx *ledger.Transaction) error {
+		t.Helper()
+		if _, err := bc.AddBlock([]*ledger.Transaction{tx}); err != nil {
+			return err
+		}
+		if _, err := engine.Sync(bc); err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+		return nil
+	}
+
+	top := comment("")
+	reply := comment(top.ID)
+	for _, tx := range []*ledger.Transaction{post, top, reply} {
+		if err := add(tx); err != nil {
+			t.Fatalf("AddBlock() error = %v", err)
+		}
+	}
+	if err := add(comment(reply.ID)); !errors.Is(err, ledger.ErrPolicyViolation) || !strings.Contains(err.Error(), "depth 3") {
+		t.Errorf("AddBlock() of a depth 3 reply error = %v, want the threads rule", err)
+	}
+
+	if n, err := engine.Rebuild(bc); err != nil || n != 4 {
+		t.Errorf("Rebuild() = %d, %v; want 4 blocks", n, err)
+	}
+	if depth := engine.commentDepth(&social.Comment{ParentTxID: reply.ID}); depth != 3 {
+		t.Errorf("commentDepth() after Rebuild = %d, want 3", depth)
+	}
+}
+
+func TestNewEngine_RejectsInvalidRules(t *testing.T) {
+	if _, err := ParseConfig([]byte(`{"rules": [{"type": "PostCreated", "maxTag": 2}]}`)); err == nil {
+		t.Error("ParseConfig() accepted a misspelt limit")
+	}
+	for name, r := range map[string]Rule{
+		"no type":       {MaxTags: 1},
+		"no limits":     {Type: ledger.PostCreated},
+		"bad scope":     {Type: ledger.PostCreated, MaxTags: 1, Scope: "global"},
+		"wrong type":    {Type: ledger.CommentAdded, MaxTags: 1},
+		"bad age":       {Type: ledger.ListCreated, MinAccountAge: "three days"},
+		"negative":      {Type: ledger.PostCreated, MaxMedia: -1},
+		"group comment": {Type: ledger.CommentAdded, GroupPosts: true, MaxPayloadBytes: 10},
+	} {
+		if _, err := NewEngine(Config{Rules: []Rule{r}}); err == nil {
+			t.Errorf("NewEngine(%s) succeeded, want an error", name)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Package publicnet builds HTTP clients for fetching URLs chosen by other
+// people: links in posts, remote ActivityPub actors and inboxes. Such a
+// client must not become a way to reach the node's own network, so it
+// connects only to public unicast addresses, checked after DNS resolution so
+// a name cannot be pointed elsewhere between the check and the connection,
+// and never through a proxy.
+package publicnet
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// Defaults for Options.
+const (
+	DefaultTimeout      = 30 * time.Second
+	DefaultMaxRedirects = 5
+)
+
+// ErrNonPublicAddress is returned when a client is asked to connect to an
+// address that is not on the public internet.
+var ErrNonPublicAddress = errors.New("only public addresses may be connected to")
+
+// Options configures NewClient.
+type Options struct {
+	// Timeout bounds a whole request, redirects included. Zero means
+	// DefaultTimeout.
+	Timeout time.Duration
+	// MaxRedirects is the number of redirects followed before giving up.
+	// Zero means DefaultMaxRedirects.
+	MaxRedirects int
+	// AllowHTTP follows redirects to plain http URLs as well as https ones.
+	AllowHTTP bool
+}
+
+// NewClient returns a client that connects only to public addresses and
+// follows redirects only as opts allows.
+func NewClient(opts Options) *http.Client {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxRedirects == 0 {
+		opts.MaxRedirects = DefaultMaxRedirects
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: dialPublicOnly}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 nil, // A proxy would make the connection on our behalf, unchecked
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          16,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 10 * time.Second,
+		},
+		CheckRedirect: opts.checkRedirect,
+		Timeout:       opts.Timeout,
+	}
+}
+
+// checkRedirect is the CheckRedirect function of the clients NewClient builds.
+func (opts Options) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= opts.MaxRedirects {
+		return fmt.Errorf("stopped after %d redirects", len(via))
+	}
+	if req.URL.Scheme != "https" && !(opts.AllowHTTP && req.URL.Scheme == "http") {
+		return fmt.Errorf("refusing redirect to non-https URL %s", req.URL.Redacted())
+	}
+	return nil
+}
+
+// dialPublicOnly is the net.Dialer Control function of NewClient. It runs
+// once the address is resolved, just before each connection.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, host)
+	}
+	if !IsPublicAddr(ip) {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, ip)
+	}
+	return nil
+}
+
+// nonPublicPrefixes are the special-purpose ranges that netip.Addr's
+// predicates do not cover.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "This network"
+	netip.MustParsePrefix("100.64.0.0/10"),  // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),    // Reserved
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64, which can reach private IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"), // Local-use NAT64
+	netip.MustParsePrefix("2002::/16"),      // 6to4, which embeds an IPv4 address
+}
+
+// IsPublicAddr reports whether ip is a unicast address on the public
+// internet: not private, loopback, link-local, multicast, unspecified or
+// otherwise reserved.
+func IsPublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
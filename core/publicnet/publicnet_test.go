@@ -0,0 +1,85 @@
+package publicnet
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNewClient_RefusesNonPublicAddresses(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	if _, err := NewClient(Options{}).Get(srv.URL + "/page"); !errors.Is(err, ErrNonPublicAddress) {
+		t.Errorf("Get() from a loopback server error = %v, want ErrNonPublicAddress", err)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("loopback server was hit %d times, want 0", hits.Load())
+	}
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":        true,
+		"2606:2800:220:1::1":   true,
+		"127.0.0.1":            false,
+		"::1":                  false,
+		"10.1.2.3":             false,
+		"172.16.0.1":           false,
+		"192.168.1.1":          false,
+		"169.254.169.254":      false, // Cloud metadata
+		"fe80::1":              false,
+		"fd00::1":              false,
+		"0.0.0.0":              false,
+		"0.1.2.3":              false,
+		"100.64.0.1":           false,
+		"224.0.0.1":            false,
+		"255.255.255.255":      false,
+		"::ffff:127.0.0.1":     false,
+		"::ffff:93.184.216.34": true,
+		"64:ff9b::a01:203":     false,
+		"2002:a01:203::1":      false,
+		"ff02::1":              false,
+		"::":                   false,
+		"198.18.0.1":           false,
+		"2001:db8::1":          true, // Documentation range; unroutable, not internal
+	} {
+		if got := IsPublicAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("IsPublicAddr(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestOptions_CheckRedirect(t *testing.T) {
+	req := func(rawURL string) *http.Request {
+		r, _ := http.NewRequest(http.MethodGet, rawURL, nil)
+		return r
+	}
+	opts := Options{MaxRedirects: 3}
+	via := []*http.Request{req("https://example.com/")}
+	if err := opts.checkRedirect(req("https://example.org/"), via); err != nil {
+		t.Errorf("redirect to https error = %v, want nil", err)
+	}
+	if err := opts.checkRedirect(req("http://example.org/"), via); err == nil {
+		t.Error("redirect to http: expected error, got nil")
+	}
+	allowHTTP := Options{MaxRedirects: 3, AllowHTTP: true}
+	if err := allowHTTP.checkRedirect(req("http://example.org/"), via); err != nil {
+		t.Errorf("redirect to http with AllowHTTP error = %v, want nil", err)
+	}
+	if err := allowHTTP.checkRedirect(req("file:///etc/passwd"), via); err == nil {
+		t.Error("redirect to a file URL: expected error, got nil")
+	}
+	for len(via) < opts.MaxRedirects {
+		via = append(via, req("https://example.com/"))
+	}
+	if err := opts.checkRedirect(req("https://example.org/"), via); err == nil {
+		t.Errorf("redirect after %d redirects: expected error, got nil", len(via))
+	}
+}
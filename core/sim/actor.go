@@ -0,0 +1,128 @@
+package sim
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"digisocialblock/core/clientkit"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+)
+
+// User is a scripted actor with a signing key. Its key is derived from the
+// simulation seed and its name, so its address and the IDs of its
+// transactions are the same on every run.
+type User struct {
+	Name    string
+	Address string
+
+	sim *Simulation
+	key *ecdsa.PrivateKey
+}
+
+// AddUser creates a user named name.
+func (s *Simulation) AddUser(name string) (*User, error) {
+	if name == "" {
+		return nil, fmt.Errorf("user name cannot be empty")
+	}
+	if _, dup := s.users[name]; dup {
+		return nil, fmt.Errorf("user %q already exists", name)
+	}
+	key, err := deriveKey(s.seed, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key for user %s: %w", name, err)
+	}
+	addr, err := clientkit.Address(&key.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive address for user %s: %w", name, err)
+	}
+	u := &User{Name: name, Address: addr, sim: s, key: key}
+	s.users[name] = u
+	return u, nil
+}
+
+// Post returns an action that submits a post titled title to node n.
+func (u *User) Post(n *Node, title string) Action {
+	return Action{
+		Name: fmt.Sprintf("%s posts %q to %s", u.Name, title, n.Name),
+		Run: func() error {
+			sum := sha256.Sum256([]byte(u.Address + "\x00" + title))
+			post := social.NewPostWithClock(u.sim.clock, u.Address, hex.EncodeToString(sum[:]), title, nil)
+			payload, err := post.ToPayload(ledger.PayloadFormatJSON)
+			if err != nil {
+				return err
+			}
+			return u.submit(n, ledger.PostCreated, payload)
+		},
+	}
+}
+
+// Follow returns an action that submits u following other to node n.
+func (u *User) Follow(n *Node, other *User) Action {
+	return Action{
+		Name: fmt.Sprintf("%s follows %s via %s", u.Name, other.Name, n.Name),
+		Run: func() error {
+			follow := &social.Follow{FolloweePublicKey: other.Address, Timestamp: u.sim.clock.Now().UnixNano()}
+			payload, err := follow.ToPayload(ledger.PayloadFormatJSON)
+			if err != nil {
+				return err
+			}
+			return u.submit(n, ledger.UserFollowed, payload)
+		},
+	}
+}
+
+// NewTransaction returns a transaction of the given type signed by u and
+// stamped with the virtual time, for scripting transactions the built-in
+// actions do not cover.
+func (u *User) NewTransaction(txType ledger.TransactionType, payload []byte) (*ledger.Transaction, error) {
+	tx, err := ledger.NewTransactionWithClock(u.sim.clock, u.Address, txType, payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Sign(u.key); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (u *User) submit(n *Node, txType ledger.TransactionType, payload []byte) error {
+	tx, err := u.NewTransaction(txType, payload)
+	if err != nil {
+		return err
+	}
+	return n.Submit(tx)
+}
+
+// deriveKey derives a P-256 key from the seed and name. ecdsa.GenerateKey
+// does not promise the same key for the same random stream, so the scalar is
+// derived directly.
+func deriveKey(seed int64, name string) (*ecdsa.PrivateKey, error) {
+	var seedBytes [8]byte
+	binary.BigEndian.PutUint64(seedBytes[:], uint64(seed))
+	sum := sha256.Sum256(append(seedBytes[:], name...))
+
+	// Map the hash into [1, N-1].
+	n1 := new(big.Int).Sub(elliptic.P256().Params().N, big.NewInt(1))
+	d := new(big.Int).Mod(new(big.Int).SetBytes(sum[:]), n1)
+	d.Add(d, big.NewInt(1))
+
+	priv, err := ecdh.P256().NewPrivateKey(d.FillBytes(make([]byte, 32)))
+	if err != nil {
+		return nil, err
+	}
+	point := priv.PublicKey().Bytes() // 0x04 || X || Y
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(point[1:33]),
+			Y:     new(big.Int).SetBytes(point[33:]),
+		},
+		D: d,
+	}, nil
+}
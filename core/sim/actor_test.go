@@ -0,0 +1,33 @@
+package sim
+
+import "testing"
+
+func TestAddUser_DerivesKeysFromSeed(t *testing.T) {
+	s1, _ := New(Options{Seed: 9})
+	s2, _ := New(Options{Seed: 9})
+	other, _ := New(Options{Seed: 10})
+	alice1, err := s1.AddUser("alice")
+	if err != nil {
+		t.Fatalf("AddUser() error = %v", err)
+	}
+	alice2, _ := s2.AddUser("alice")
+	bob1, _ := s1.AddUser("bob")
+	aliceOther, _ := other.AddUser("alice")
+	if alice1.Address != alice2.Address {
+		t.Error("same seed and name gave different addresses")
+	}
+	if alice1.Address == bob1.Address || alice1.Address == aliceOther.Address {
+		t.Error("different names or seeds gave the same address")
+	}
+	if _, err := s1.AddUser("alice"); err == nil {
+		t.Error("AddUser() with a duplicate name: expected error, got nil")
+	}
+
+	tx, err := alice1.NewTransaction("Like", []byte(`{}`))
+	if err != nil {
+		t.Fatalf("NewTransaction() error = %v", err)
+	}
+	if ok, err := tx.VerifySignature(); !ok || err != nil {
+		t.Errorf("VerifySignature() = %v, %v; want a valid signature", ok, err)
+	}
+}
@@ -0,0 +1,121 @@
+package sim
+
+import (
+	"fmt"
+	"time"
+)
+
+// Link describes the delivery of messages from one node to another.
+type Link struct {
+	// Latency is the base one-way delay.
+	Latency time.Duration
+	// Jitter adds a uniformly random delay in [0, Jitter) to each message,
+	// so messages on the same link can be reordered.
+	Jitter time.Duration
+	// Loss is the probability, from 0 to 1, that a message is dropped.
+	Loss float64
+}
+
+// NetworkStats counts messages on the network.
+type NetworkStats struct {
+	Sent      int
+	Delivered int
+	Dropped   int // Lost on the link or blocked by a partition
+}
+
+// Network is the in-memory network between the nodes of a Simulation. Every
+// node can address every other node; links default to the simulation's
+// Options and can be overridden per direction.
+type Network struct {
+	sim       *Simulation
+	def       Link
+	links     map[[2]string]Link
+	partition map[string]int // Node name -> partition group; nil when healed
+	stats     NetworkStats
+}
+
+func newNetwork(s *Simulation, def Link) *Network {
+	return &Network{sim: s, def: def, links: make(map[[2]string]Link)}
+}
+
+// SetLink overrides the link from one node to another. It affects messages
+// sent after the call.
+func (n *Network) SetLink(from, to string, l Link) error {
+	if l.Latency < 0 || l.Jitter < 0 || l.Loss < 0 || l.Loss > 1 {
+		return fmt.Errorf("invalid link %+v", l)
+	}
+	n.links[[2]string{from, to}] = l
+	return nil
+}
+
+// SetDefaultLink replaces the link used between nodes without an override.
+func (n *Network) SetDefaultLink(l Link) error {
+	if l.Latency < 0 || l.Jitter < 0 || l.Loss < 0 || l.Loss > 1 {
+		return fmt.Errorf("invalid link %+v", l)
+	}
+	n.def = l
+	return nil
+}
+
+// Partition splits the network into the given groups of node names.
+// Messages between groups are dropped, as are messages to or from nodes in
+// no group. Messages already in flight are still delivered.
+func (n *Network) Partition(groups ...[]string) {
+	n.partition = make(map[string]int)
+	for i, group := range groups {
+		for _, name := range group {
+			n.partition[name] = i
+		}
+	}
+	n.sim.logf("network: partitioned into %v", groups)
+}
+
+// Heal removes any partition.
+func (n *Network) Heal() {
+	n.partition = nil
+	n.sim.logf("network: healed")
+}
+
+// Stats returns the message counters.
+func (n *Network) Stats() NetworkStats { return n.stats }
+
+func (n *Network) connected(from, to string) bool {
+	if n.partition == nil {
+		return true
+	}
+	a, okA := n.partition[from]
+	b, okB := n.partition[to]
+	return okA && okB && a == b
+}
+
+// send delivers msg from one node to another after the link's latency,
+// unless the link loses it or a partition separates the nodes.
+func (n *Network) send(from, to *Node, msg message) {
+	n.stats.Sent++
+	if !n.connected(from.Name, to.Name) {
+		n.stats.Dropped++
+		n.sim.logf("network: %s -> %s %s blocked by partition", from.Name, to.Name, msg)
+		return
+	}
+	l, ok := n.links[[2]string{from.Name, to.Name}]
+	if !ok {
+		l = n.def
+	}
+	// Draw from the generator in the same order whatever the outcome, so
+	// changing one link's loss does not shift the delays of other messages.
+	lost := n.sim.rng.Float64() < l.Loss
+	delay := l.Latency
+	if l.Jitter > 0 {
+		delay += time.Duration(n.sim.rng.Int63n(int64(l.Jitter)))
+	}
+	if lost {
+		n.stats.Dropped++
+		n.sim.logf("network: %s -> %s %s lost", from.Name, to.Name, msg)
+		return
+	}
+	n.sim.at(n.sim.clock.Now().Add(delay), func() error {
+		n.stats.Delivered++
+		to.receive(from, msg)
+		return nil
+	})
+}
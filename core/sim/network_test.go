@@ -0,0 +1,65 @@
+package sim
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNetwork_PartitionAndHeal(t *testing.T) {
+	s, _ := New(Options{Seed: 1})
+	producer, _ := s.AddNode("producer", true)
+	a, _ := s.AddNode("a", false)
+	b, _ := s.AddNode("b", false)
+	alice, _ := s.AddUser("alice")
+
+	s.Network().Partition([]string{"producer", "a"}, []string{"b"})
+	s.Schedule(0, alice.Post(a, "during partition"))
+	if err := s.RunFor(3 * time.Second); err != nil {
+		t.Fatalf("RunFor() error = %v", err)
+	}
+	if producer.Height() != 1 || a.Height() != 1 || b.Height() != 0 {
+		t.Fatalf("heights producer %d, a %d, b %d; want 1, 1, 0", producer.Height(), a.Height(), b.Height())
+	}
+	if s.Network().Stats().Dropped == 0 {
+		t.Error("Stats().Dropped = 0, want messages to b dropped")
+	}
+
+	// After healing, the next block is ahead of b, which syncs the gap.
+	s.Network().Heal()
+	s.Schedule(4*time.Second, alice.Post(a, "after heal"))
+	if err := s.RunFor(5 * time.Second); err != nil {
+		t.Fatalf("RunFor() error = %v", err)
+	}
+	if !s.Converged() || b.Height() != 2 {
+		t.Errorf("after heal: converged %v, b height %d; want all nodes at 2", s.Converged(), b.Height())
+	}
+}
+
+func TestNetwork_LinkLossAndLatency(t *testing.T) {
+	s, _ := New(Options{Seed: 1, Latency: 200 * time.Millisecond})
+	producer, _ := s.AddNode("producer", true)
+	relay, _ := s.AddNode("relay", false)
+	cut, _ := s.AddNode("cut", false)
+	alice, _ := s.AddUser("alice")
+	if err := s.Network().SetLink("producer", "cut", Link{Loss: 1}); err != nil {
+		t.Fatalf("SetLink() error = %v", err)
+	}
+	if err := s.Network().SetLink("producer", "cut", Link{Loss: 2}); err == nil {
+		t.Error("SetLink() with loss 2: expected error, got nil")
+	}
+
+	s.Schedule(0, alice.Post(producer, "hello"))
+	// The block is cut at 1s and takes one hop to relay and two to cut.
+	if err := s.RunFor(1300 * time.Millisecond); err != nil {
+		t.Fatalf("RunFor() error = %v", err)
+	}
+	if relay.Height() != 1 || cut.Height() != 0 {
+		t.Errorf("after one hop: relay %d, cut %d; want 1, 0", relay.Height(), cut.Height())
+	}
+	if err := s.RunFor(200 * time.Millisecond); err != nil {
+		t.Fatalf("RunFor() error = %v", err)
+	}
+	if cut.Height() != 1 {
+		t.Errorf("cut height = %d, want the block relayed around the lossy link", cut.Height())
+	}
+}
@@ -0,0 +1,240 @@
+package sim
+
+import (
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"fmt"
+)
+
+// DefaultMaxSyncBlocks bounds the blocks a node sends in one sync response.
+const DefaultMaxSyncBlocks = 64
+
+// Node is a simulated node. Transactions submitted to it or received from
+// peers are admitted to its mempool and gossiped to every other node; a
+// producer node cuts a block from its mempool every block interval and
+// gossips it. A node that receives a block beyond its tip asks the sender for
+// the missing blocks. Several producers race: a block that does not extend a
+// node's tip is rejected and traced, and no fork choice is made.
+//
+// The exported fields are the node's real state and may be inspected (or
+// wrapped) by tests; blocks and transactions are shared between nodes and
+// must not be modified.
+type Node struct {
+	Name     string
+	Producer bool
+	Chain    *ledger.Blockchain
+	Mempool  *ledger.Mempool
+	Graph    *social.GraphIndex
+
+	sim  *Simulation
+	seen map[string]bool // Transaction IDs in the mempool or on the chain
+}
+
+// AddNode adds a node named name. Producer nodes cut blocks.
+func (s *Simulation) AddNode(name string, producer bool) (*Node, error) {
+	if name == "" {
+		return nil, fmt.Errorf("node name cannot be empty")
+	}
+	if _, dup := s.byName[name]; dup {
+		return nil, fmt.Errorf("node %q already exists", name)
+	}
+	chain, err := ledger.NewBlockchain()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chain for node %s: %w", name, err)
+	}
+	n := &Node{
+		Name:     name,
+		Producer: producer,
+		Chain:    chain,
+		Mempool:  ledger.NewMempool(chain.SignatureCache()),
+		Graph:    social.NewGraphIndex(),
+		sim:      s,
+		seen:     make(map[string]bool),
+	}
+	n.Mempool.SetDelegations(chain)
+	n.Mempool.SetTransactionIndex(chain)
+	if _, err := n.Graph.Sync(chain); err != nil {
+		return nil, fmt.Errorf("failed to index genesis for node %s: %w", name, err)
+	}
+	s.nodes = append(s.nodes, n)
+	s.byName[name] = n
+	if producer {
+		n.scheduleProduce()
+	}
+	return n, nil
+}
+
+// Height returns the index of the node's latest block.
+func (n *Node) Height() int64 { return n.Chain.GetLatestBlock().Index }
+
+// Submit admits tx to the node's mempool and gossips it, as a client
+// submitting a transaction to this node would.
+func (n *Node) Submit(tx *ledger.Transaction) error {
+	if n.seen[tx.ID] {
+		return fmt.Errorf("transaction %s already known to %s", shortID(tx.ID), n.Name)
+	}
+	if err := n.Mempool.Add(tx); err != nil {
+		return err
+	}
+	n.seen[tx.ID] = true
+	n.sim.logf("%s: admitted tx %s from client", n.Name, shortID(tx.ID))
+	n.broadcast(txMessage{tx}, nil)
+	return nil
+}
+
+// Sync returns an action that makes the node ask peer for every block above
+// its tip.
+func (n *Node) Sync(peer *Node) Action {
+	return Action{
+		Name: fmt.Sprintf("%s syncs from %s", n.Name, peer.Name),
+		Run: func() error {
+			n.sim.net.send(n, peer, syncRequest{from: n.Height() + 1})
+			return nil
+		},
+	}
+}
+
+func (n *Node) scheduleProduce() {
+	n.sim.at(n.sim.clock.Now().Add(n.sim.blockInterval), func() error {
+		n.produce()
+		n.scheduleProduce()
+		return nil
+	})
+}
+
+// produce cuts a block from the mempool, if it holds anything. The block is
+// stamped with the virtual time, so its hash is the same on every run.
+func (n *Node) produce() {
+	pending := n.Mempool.Pending()
+	if len(pending) == 0 {
+		return
+	}
+	tip := n.Chain.GetLatestBlock()
+	ts := n.sim.clock.Now().UnixNano()
+	if ts <= tip.Timestamp {
+		ts = tip.Timestamp + 1
+	}
+	block := &ledger.Block{
+		Index:         tip.Index + 1,
+		Timestamp:     ts,
+		Transactions:  pending,
+		PrevBlockHash: tip.Hash,
+	}
+	block.Hash = ledger.HashBlockContent(block.Index, block.Timestamp, block.PrevBlockHash, ledger.MerkleRoot(ledger.GetTransactionHashes(pending)))
+	if err := n.accept(block); err != nil {
+		n.sim.logf("%s: failed to produce block %d: %v", n.Name, block.Index, err)
+		return
+	}
+	n.broadcast(blockMessage{block}, nil)
+}
+
+// accept appends block to the chain and updates the mempool and graph index.
+func (n *Node) accept(block *ledger.Block) error {
+	if err := n.Chain.AppendBlock(block); err != nil {
+		return err
+	}
+	ids := make([]string, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		ids[i] = tx.ID
+		n.seen[tx.ID] = true
+	}
+	n.Mempool.Remove(ids...)
+	if _, err := n.Graph.Sync(n.Chain); err != nil {
+		return fmt.Errorf("failed to index block %d: %w", block.Index, err)
+	}
+	n.sim.logf("%s: accepted block %d (%s) with %d txs", n.Name, block.Index, shortID(block.Hash), len(block.Transactions))
+	return nil
+}
+
+// broadcast sends msg to every other node except skip.
+func (n *Node) broadcast(msg message, skip *Node) {
+	for _, peer := range n.sim.nodes {
+		if peer != n && peer != skip {
+			n.sim.net.send(n, peer, msg)
+		}
+	}
+}
+
+func (n *Node) receive(from *Node, msg message) {
+	switch m := msg.(type) {
+	case txMessage:
+		if n.seen[m.tx.ID] {
+			return
+		}
+		if err := n.Mempool.Add(m.tx); err != nil {
+			n.sim.logf("%s: rejected tx %s from %s: %v", n.Name, shortID(m.tx.ID), from.Name, err)
+			return
+		}
+		n.seen[m.tx.ID] = true
+		n.broadcast(m, from)
+	case blockMessage:
+		height := n.Height()
+		switch {
+		case m.block.Index <= height:
+			return
+		case m.block.Index > height+1:
+			n.sim.logf("%s: block %d from %s is ahead of height %d, requesting sync", n.Name, m.block.Index, from.Name, height)
+			n.sim.net.send(n, from, syncRequest{from: height + 1})
+		default:
+			if err := n.accept(m.block); err != nil {
+				n.sim.logf("%s: rejected block %d from %s: %v", n.Name, m.block.Index, from.Name, err)
+				return
+			}
+			n.broadcast(m, from)
+		}
+	case syncRequest:
+		var blocks []*ledger.Block
+		for i := m.from; i <= n.Height() && len(blocks) < n.sim.maxSyncBlocks; i++ {
+			blocks = append(blocks, n.Chain.GetBlockByIndex(i))
+		}
+		if len(blocks) > 0 {
+			n.sim.net.send(n, from, syncResponse{blocks: blocks})
+		}
+	case syncResponse:
+		for _, block := range m.blocks {
+			if block.Index <= n.Height() {
+				continue
+			}
+			if err := n.accept(block); err != nil {
+				n.sim.logf("%s: rejected synced block %d from %s: %v", n.Name, block.Index, from.Name, err)
+				return
+			}
+		}
+		// A full response means the peer may have more.
+		if len(m.blocks) == n.sim.maxSyncBlocks {
+			n.sim.net.send(n, from, syncRequest{from: n.Height() + 1})
+		}
+	}
+}
+
+// message is a message between nodes. String describes it in the trace.
+type message interface {
+	String() string
+}
+
+type txMessage struct{ tx *ledger.Transaction }
+
+func (m txMessage) String() string { return "tx " + shortID(m.tx.ID) }
+
+type blockMessage struct{ block *ledger.Block }
+
+func (m blockMessage) String() string {
+	return fmt.Sprintf("block %d (%s)", m.block.Index, shortID(m.block.Hash))
+}
+
+type syncRequest struct{ from int64 }
+
+func (m syncRequest) String() string { return fmt.Sprintf("sync request from block %d", m.from) }
+
+type syncResponse struct{ blocks []*ledger.Block }
+
+func (m syncResponse) String() string {
+	return fmt.Sprintf("sync response of %d blocks", len(m.blocks))
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
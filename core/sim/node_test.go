@@ -0,0 +1,59 @@
+package sim
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNode_GossipsTransactionsAndIndexesFollows(t *testing.T) {
+	s, _ := New(Options{Seed: 3})
+	producer, _ := s.AddNode("producer", true)
+	a, _ := s.AddNode("a", false)
+	alice, _ := s.AddUser("alice")
+	bob, _ := s.AddUser("bob")
+
+	s.Schedule(0, bob.Follow(a, alice))
+	s.Schedule(0, alice.Post(a, "hi"))
+	if err := s.RunFor(100 * time.Millisecond); err != nil {
+		t.Fatalf("RunFor() error = %v", err)
+	}
+	if producer.Mempool.Size() != 2 {
+		t.Fatalf("producer mempool size = %d, want the gossiped transactions", producer.Mempool.Size())
+	}
+	if err := s.RunFor(2 * time.Second); err != nil {
+		t.Fatalf("RunFor() error = %v", err)
+	}
+	for _, n := range []*Node{producer, a} {
+		if n.Height() != 1 || n.Mempool.Size() != 0 {
+			t.Errorf("%s: height %d, mempool %d; want 1, 0", n.Name, n.Height(), n.Mempool.Size())
+		}
+		if followers := n.Graph.GetFollowers(alice.Address); len(followers) != 1 || followers[0].Follower != bob.Address {
+			t.Errorf("%s: followers of alice = %+v, want bob", n.Name, followers)
+		}
+	}
+
+	tx, _ := alice.NewTransaction("PostCreated", []byte("not a post"))
+	if err := a.Submit(tx); err == nil {
+		t.Error("Submit() of an invalid payload: expected error, got nil")
+	}
+}
+
+func TestNode_SyncCatchesUpInPages(t *testing.T) {
+	s, _ := New(Options{Seed: 5, MaxSyncBlocks: 2})
+	producer, _ := s.AddNode("producer", true)
+	alice, _ := s.AddUser("alice")
+	for i := 0; i < 5; i++ {
+		s.Schedule(time.Duration(i)*time.Second+500*time.Millisecond, alice.Post(producer, string(rune('a'+i))))
+	}
+	if err := s.RunFor(6 * time.Second); err != nil {
+		t.Fatalf("RunFor() error = %v", err)
+	}
+	late, _ := s.AddNode("late", false)
+	s.Schedule(7*time.Second, late.Sync(producer))
+	if err := s.RunFor(2 * time.Second); err != nil {
+		t.Fatalf("RunFor() error = %v", err)
+	}
+	if producer.Height() != 5 || !s.Converged() {
+		t.Errorf("producer height %d, late height %d; want both at 5", producer.Height(), late.Height())
+	}
+}
@@ -0,0 +1,259 @@
+// Package sim runs deterministic multi-node simulations. Nodes hold a real
+// ledger.Blockchain, ledger.Mempool and social.GraphIndex but talk over an
+// in-memory Network with configurable latency, jitter, loss and partitions,
+// and time is a virtual Clock that only moves when the simulation runs.
+// Scripted actors post, follow and trigger syncs at chosen virtual times.
+//
+// Everything that varies between runs (message latency, losses, user keys)
+// is drawn from the seed, and events run one at a time in (time, schedule
+// order), so a simulation with the same seed and script produces the same
+// Trace and the same chain on every run. That makes sync and block production
+// changes testable without sockets, sleeps or flakes:
+//
+//	s, _ := sim.New(sim.Options{Seed: 1, Loss: 0.2})
+//	producer, _ := s.AddNode("producer", true)
+//	follower, _ := s.AddNode("follower", false)
+//	alice, _ := s.AddUser("alice")
+//	s.Schedule(time.Second, alice.Post(follower, "hello"))
+//	s.Schedule(10*time.Second, follower.Sync(producer))
+//	err := s.RunFor(time.Minute)
+package sim
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// DefaultLatency is the one-way message latency of links without their own setting.
+const DefaultLatency = 50 * time.Millisecond
+
+// DefaultBlockInterval is how often producer nodes cut a block from their mempool.
+const DefaultBlockInterval = time.Second
+
+// DefaultStart is the virtual time a simulation starts at.
+var DefaultStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Options configures a Simulation.
+type Options struct {
+	// Seed seeds every random choice the simulation makes.
+	Seed int64
+	// Start is the initial virtual time. Zero means DefaultStart.
+	Start time.Time
+	// Latency, Jitter and Loss configure the default link; see Link. Zero
+	// Latency means DefaultLatency.
+	Latency time.Duration
+	Jitter  time.Duration
+	Loss    float64
+	// BlockInterval is how often producers cut a block. Zero means
+	// DefaultBlockInterval.
+	BlockInterval time.Duration
+	// MaxSyncBlocks bounds the blocks sent in one sync response. Zero means
+	// DefaultMaxSyncBlocks.
+	MaxSyncBlocks int
+}
+
+// Clock is the virtual clock of a Simulation. It is a ledger.Clock, so
+// constructors and managers that take one stamp virtual time; components
+// that take a func() time.Time option can be given Now.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// Now returns the current virtual time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Clock) set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// An Action is a scripted step, run at the virtual time it was scheduled for.
+type Action struct {
+	// Name describes the step in the trace.
+	Name string
+	Run  func() error
+}
+
+// Simulation is a set of nodes and users on a simulated network. It is not
+// safe for concurrent use; everything runs on the goroutine calling RunFor.
+type Simulation struct {
+	clock         *Clock
+	start         time.Time
+	rng           *rand.Rand
+	seed          int64
+	net           *Network
+	blockInterval time.Duration
+	maxSyncBlocks int
+
+	nodes  []*Node
+	byName map[string]*Node
+	users  map[string]*User
+
+	queue eventQueue
+	seq   uint64
+	trace []string
+}
+
+// New creates an empty simulation.
+func New(opts Options) (*Simulation, error) {
+	if opts.Latency < 0 || opts.Jitter < 0 {
+		return nil, fmt.Errorf("invalid latency %v or jitter %v", opts.Latency, opts.Jitter)
+	}
+	if opts.Loss < 0 || opts.Loss > 1 {
+		return nil, fmt.Errorf("loss must be between 0 and 1, got %v", opts.Loss)
+	}
+	if opts.BlockInterval < 0 || opts.MaxSyncBlocks < 0 {
+		return nil, fmt.Errorf("invalid block interval %v or max sync blocks %d", opts.BlockInterval, opts.MaxSyncBlocks)
+	}
+	if opts.Start.IsZero() {
+		opts.Start = DefaultStart
+	}
+	if opts.Latency == 0 {
+		opts.Latency = DefaultLatency
+	}
+	if opts.BlockInterval == 0 {
+		opts.BlockInterval = DefaultBlockInterval
+	}
+	if opts.MaxSyncBlocks == 0 {
+		opts.MaxSyncBlocks = DefaultMaxSyncBlocks
+	}
+	s := &Simulation{
+		clock:         &Clock{now: opts.Start},
+		start:         opts.Start,
+		rng:           rand.New(rand.NewSource(opts.Seed)),
+		seed:          opts.Seed,
+		blockInterval: opts.BlockInterval,
+		maxSyncBlocks: opts.MaxSyncBlocks,
+		byName:        make(map[string]*Node),
+		users:         make(map[string]*User),
+	}
+	s.net = newNetwork(s, Link{Latency: opts.Latency, Jitter: opts.Jitter, Loss: opts.Loss})
+	return s, nil
+}
+
+// Clock returns the simulation's virtual clock.
+func (s *Simulation) Clock() *Clock { return s.clock }
+
+// Now returns the current virtual time.
+func (s *Simulation) Now() time.Time { return s.clock.Now() }
+
+// Elapsed returns the virtual time since the simulation started.
+func (s *Simulation) Elapsed() time.Duration { return s.clock.Now().Sub(s.start) }
+
+// Network returns the simulated network, for changing links and partitions.
+func (s *Simulation) Network() *Network { return s.net }
+
+// Nodes returns the nodes in the order they were added.
+func (s *Simulation) Nodes() []*Node {
+	return append([]*Node(nil), s.nodes...)
+}
+
+// Node returns the node with the given name, or nil.
+func (s *Simulation) Node(name string) *Node { return s.byName[name] }
+
+// Schedule runs a at virtual time at, measured from the start of the
+// simulation. Steps scheduled in the past run at the next RunFor.
+func (s *Simulation) Schedule(at time.Duration, a Action) {
+	when := s.start.Add(at)
+	if now := s.clock.Now(); when.Before(now) {
+		when = now
+	}
+	s.at(when, func() error {
+		s.logf("script: %s", a.Name)
+		if err := a.Run(); err != nil {
+			s.logf("script: %s failed: %v", a.Name, err)
+			return fmt.Errorf("%s at %v: %w", a.Name, s.Elapsed(), err)
+		}
+		return nil
+	})
+}
+
+// RunFor runs every event due in the next d of virtual time, then leaves the
+// clock at the end of the window. It returns the errors of failed scripted
+// actions; messages nodes reject are only traced.
+func (s *Simulation) RunFor(d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("cannot run for negative duration %v", d)
+	}
+	end := s.clock.Now().Add(d)
+	var errs []error
+	for s.queue.Len() > 0 && !s.queue[0].at.After(end) {
+		ev := heap.Pop(&s.queue).(*event)
+		s.clock.set(ev.at)
+		if err := ev.fn(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.clock.set(end)
+	return errors.Join(errs...)
+}
+
+// Trace returns the log of everything that happened so far, one line per
+// event, prefixed with the virtual time since the start. Two runs with the
+// same seed and script return the same trace.
+func (s *Simulation) Trace() []string {
+	return append([]string(nil), s.trace...)
+}
+
+// Converged reports whether every node has the same chain tip.
+func (s *Simulation) Converged() bool {
+	if len(s.nodes) == 0 {
+		return true
+	}
+	for _, n := range s.nodes[1:] {
+		if n.Chain.GetLatestBlock().Hash != s.nodes[0].Chain.GetLatestBlock().Hash {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Simulation) logf(format string, args ...interface{}) {
+	s.trace = append(s.trace, fmt.Sprintf("%10.3fs ", s.Elapsed().Seconds())+fmt.Sprintf(format, args...))
+}
+
+// at schedules fn at virtual time when. Events at the same time run in the
+// order they were scheduled.
+func (s *Simulation) at(when time.Time, fn func() error) {
+	s.seq++
+	heap.Push(&s.queue, &event{at: when, seq: s.seq, fn: fn})
+}
+
+type event struct {
+	at  time.Time
+	seq uint64
+	fn  func() error
+}
+
+// eventQueue is a min-heap of events ordered by time, then scheduling order.
+type eventQueue []*event
+
+func (q eventQueue) Len() int { return len(q) }
+
+func (q eventQueue) Less(i, j int) bool {
+	if !q[i].at.Equal(q[j].at) {
+		return q[i].at.Before(q[j].at)
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *eventQueue) Push(x interface{}) { *q = append(*q, x.(*event)) }
+
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	ev := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return ev
+}
@@ -0,0 +1,87 @@
+package sim
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runSimTestScenario runs a small lossy, jittery network: users post and
+// follow through different nodes, and every node syncs from the producer at
+// the end.
+func runSimTestScenario(t *testing.T, seed int64) *Simulation {
+	t.Helper()
+	s, err := New(Options{Seed: seed, Jitter: 80 * time.Millisecond, Loss: 0.2})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	producer, _ := s.AddNode("producer", true)
+	a, _ := s.AddNode("a", false)
+	b, _ := s.AddNode("b", false)
+	alice, _ := s.AddUser("alice")
+	bob, _ := s.AddUser("bob")
+	s.Schedule(100*time.Millisecond, alice.Post(a, "first"))
+	s.Schedule(1500*time.Millisecond, bob.Follow(b, alice))
+	s.Schedule(1500*time.Millisecond, bob.Post(producer, "reply"))
+	s.Schedule(2200*time.Millisecond, alice.Post(b, "second"))
+	for i, n := range []*Node{a, b} {
+		s.Schedule(10*time.Second+time.Duration(i)*time.Second, n.Sync(producer))
+	}
+	if err := s.RunFor(15 * time.Second); err != nil {
+		t.Fatalf("RunFor() error = %v", err)
+	}
+	return s
+}
+
+func TestSimulation_SameSeedSameRun(t *testing.T) {
+	first := runSimTestScenario(t, 42)
+	second := runSimTestScenario(t, 42)
+	if !reflect.DeepEqual(first.Trace(), second.Trace()) {
+		t.Fatalf("traces differ between runs with the same seed:\n%s\n---\n%s",
+			strings.Join(first.Trace(), "\n"), strings.Join(second.Trace(), "\n"))
+	}
+	if !first.Converged() || first.Network().Stats().Dropped == 0 {
+		t.Errorf("converged %v with %d messages dropped, want convergence despite losses", first.Converged(), first.Network().Stats().Dropped)
+	}
+	for _, name := range []string{"producer", "a", "b"} {
+		if h1, h2 := first.Node(name).Chain.GetLatestBlock().Hash, second.Node(name).Chain.GetLatestBlock().Hash; h1 != h2 {
+			t.Errorf("node %s tip %s on one run, %s on the other", name, h1, h2)
+		}
+	}
+}
+
+func TestSimulation_RunForOrdersEvents(t *testing.T) {
+	s, _ := New(Options{})
+	var order []string
+	step := func(name string, err error) Action {
+		return Action{Name: name, Run: func() error {
+			order = append(order, name+"@"+s.Elapsed().String())
+			return err
+		}}
+	}
+	s.Schedule(2*time.Second, step("late", nil))
+	s.Schedule(time.Second, step("early", nil))
+	s.Schedule(time.Second, step("early-failing", errors.New("boom")))
+	s.Schedule(10*time.Second, step("after-window", nil))
+
+	err := s.RunFor(5 * time.Second)
+	if err == nil || !strings.Contains(err.Error(), "early-failing at 1s: boom") {
+		t.Errorf("RunFor() error = %v, want the failing step", err)
+	}
+	want := []string{"early@1s", "early-failing@1s", "late@2s"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("ran %v, want %v", order, want)
+	}
+	if got := s.Now(); !got.Equal(DefaultStart.Add(5 * time.Second)) {
+		t.Errorf("Now() = %v, want the end of the window", got)
+	}
+	if err := s.RunFor(5 * time.Second); err != nil || len(order) != 4 {
+		t.Errorf("second RunFor() = %v with %d steps run, want the remaining step", err, len(order))
+	}
+
+	if _, err := New(Options{Loss: 1.5}); err == nil {
+		t.Error("New() with loss 1.5: expected error, got nil")
+	}
+}
@@ -0,0 +1,436 @@
+package social
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+func init() {
+	ledger.RegisterPayloadValidator(ledger.PostBookmarked, ValidatePostBookmarkPayload)
+	ledger.RegisterPayloadDecoder(ledger.PostBookmarked, ledger.DecodeInto(func() interface{} { return &PostBookmark{} }))
+}
+
+// ErrInvalidBookmarkCursor is returned when a pagination cursor does not name
+// a saved post.
+var ErrInvalidBookmarkCursor = errors.New("bookmark cursor is not in the list")
+
+// bookmarkKeyPurpose scopes the wallet-derived key to bookmark encryption.
+const bookmarkKeyPurpose = "social-bookmarks"
+
+// bookmarkAD is bound as additional data to the encrypted bookmark file.
+var bookmarkAD = []byte("bookmarks")
+
+// Bookmark is a saved post.
+type Bookmark struct {
+	PostCID  string `json:"postCID"`
+	PostTxID string `json:"postTxId,omitempty"` // PostCreated transaction, if known
+	SavedAt  int64  `json:"savedAt"`            // UnixNano
+}
+
+// BookmarkPage is one page of bookmarks, newest first. NextCursor is passed
+// as after to fetch the next page; it is empty on the last page.
+type BookmarkPage struct {
+	Bookmarks  []Bookmark `json:"bookmarks"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+// PostBookmark is the PostBookmarked payload: a user saving a post publicly,
+// or, with Removed set, unsaving it. Bookmarks are private by default (see
+// BookmarkStore); publishing them is opt-in.
+type PostBookmark struct {
+	OwnerPublicKey string `json:"ownerPublicKey"`
+	PostCID        string `json:"postCID"`
+	PostTxID       string `json:"postTxId,omitempty"`
+	Removed        bool   `json:"removed,omitempty"`
+	Timestamp      int64  `json:"timestamp"`
+}
+
+// Validate checks that required fields are set and within limits.
+func (b *PostBookmark) Validate() error {
+	if b.OwnerPublicKey == "" {
+		return fmt.Errorf("empty OwnerPublicKey")
+	}
+	if b.PostCID == "" || len(b.PostCID) > MaxCIDLength {
+		return fmt.Errorf("PostCID is %d bytes, want 1 to %d", len(b.PostCID), MaxCIDLength)
+	}
+	if len(b.PostTxID) > MaxTxIDLength {
+		return fmt.Errorf("PostTxID is %d bytes, limit %d", len(b.PostTxID), MaxTxIDLength)
+	}
+	if b.Timestamp == 0 {
+		return fmt.Errorf("zero timestamp")
+	}
+	return nil
+}
+
+// ToPayload serializes the PostBookmark as a PostBookmarked transaction
+// payload in the given format.
+func (b *PostBookmark) ToPayload(format ledger.PayloadFormat) ([]byte, error) {
+	payload, err := ledger.EncodePayload(format, b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode bookmark payload: %w", err)
+	}
+	return payload, nil
+}
+
+// PostBookmarkFromPayload deserializes a PostBookmarked payload in either
+// payload format. The result must pass Validate.
+func PostBookmarkFromPayload(payload []byte) (*PostBookmark, error) {
+	var b PostBookmark
+	if err := ledger.DecodePayload(payload, &b); err != nil {
+		return nil, fmt.Errorf("failed to decode bookmark payload: %w", err)
+	}
+	if err := b.Validate(); err != nil {
+		return nil, fmt.Errorf("decoded bookmark is invalid: %w", err)
+	}
+	return &b, nil
+}
+
+// ValidatePostBookmarkPayload is the ledger schema validator for PostBookmarked payloads.
+func ValidatePostBookmarkPayload(payload []byte) error {
+	_, err := PostBookmarkFromPayload(payload)
+	return err
+}
+
+// NewPostBookmarkTransaction returns a signed PostBookmarked transaction
+// saving (or, if removed, unsaving) the post publicly. The caller submits it.
+func NewPostBookmarkTransaction(clock ledger.Clock, wallet *identity.Wallet, postCID, postTxID string, removed bool) (*ledger.Transaction, error) {
+	if wallet == nil {
+		return nil, fmt.Errorf("wallet cannot be nil")
+	}
+	if clock == nil {
+		clock = ledger.SystemClock
+	}
+	b := &PostBookmark{OwnerPublicKey: wallet.Address, PostCID: postCID, PostTxID: postTxID, Removed: removed, Timestamp: clock.Now().UnixNano()}
+	if err := b.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid bookmark: %w", err)
+	}
+	payload, err := b.ToPayload(ledger.PayloadFormatCBOR)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := ledger.NewTransactionWithClock(clock, wallet.Address, ledger.PostBookmarked, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bookmark transaction: %w", err)
+	}
+	if err := wallet.SignTransaction(tx); err != nil {
+		return nil, fmt.Errorf("failed to sign bookmark transaction: %w", err)
+	}
+	return tx, nil
+}
+
+// bookmarkList is an ordered set of bookmarks, oldest first, keyed by post CID.
+type bookmarkList []Bookmark
+
+// save adds b, or moves the existing bookmark of the same post to the end.
+func (l bookmarkList) save(b Bookmark) bookmarkList {
+	return append(l.remove(b.PostCID), b)
+}
+
+// remove drops the bookmark of postCID, if any.
+func (l bookmarkList) remove(postCID string) bookmarkList {
+	for i, b := range l {
+		if b.PostCID == postCID {
+			return append(l[:i:i], l[i+1:]...)
+		}
+	}
+	return l
+}
+
+func (l bookmarkList) has(postCID string) bool {
+	for _, b := range l {
+		if b.PostCID == postCID {
+			return true
+		}
+	}
+	return false
+}
+
+// page returns up to limit bookmarks, newest first, after the bookmark of the
+// post CID after. A limit <= 0 returns all of them.
+func (l bookmarkList) page(limit int, after string) (BookmarkPage, error) {
+	end := len(l) // Exclusive index of the newest bookmark on the page
+	if after != "" {
+		end = -1
+		for i, b := range l {
+			if b.PostCID == after {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return BookmarkPage{}, fmt.Errorf("%w: %s", ErrInvalidBookmarkCursor, after)
+		}
+	}
+	start := 0
+	if limit > 0 && end-limit > 0 {
+		start = end - limit
+	}
+	page := BookmarkPage{Bookmarks: make([]Bookmark, 0, end-start)}
+	for i := end - 1; i >= start; i-- {
+		page.Bookmarks = append(page.Bookmarks, l[i])
+	}
+	if start > 0 {
+		page.NextCursor = l[start].PostCID
+	}
+	return page, nil
+}
+
+// BookmarkStore keeps a user's private bookmarks in a local file encrypted
+// with AES-256-GCM under a key derived from their wallet, like DraftStore.
+// Nothing is put on chain; see NewPostBookmarkTransaction to save publicly.
+// A BookmarkStore is safe for concurrent use.
+type BookmarkStore struct {
+	mu        sync.Mutex
+	path      string
+	aead      cipher.AEAD
+	clock     ledger.Clock
+	bookmarks bookmarkList
+}
+
+// NewBookmarkStore opens the bookmark file at path for wallet, creating its
+// directory if needed. A missing file holds no bookmarks; one written under
+// another wallet cannot be opened.
+func NewBookmarkStore(path string, wallet *identity.Wallet) (*BookmarkStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("bookmark file path cannot be empty")
+	}
+	if wallet == nil {
+		return nil, fmt.Errorf("wallet cannot be nil for BookmarkStore")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create bookmark directory: %w", err)
+	}
+	key, err := wallet.DeriveKey(bookmarkKeyPurpose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive bookmark key: %w", err)
+	}
+	defer func() {
+		for i := range key {
+			key[i] = 0
+		}
+	}()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bookmark cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bookmark cipher: %w", err)
+	}
+	bs := &BookmarkStore{path: path, aead: aead, clock: ledger.SystemClock}
+	if err := bs.load(); err != nil {
+		return nil, err
+	}
+	return bs, nil
+}
+
+// SetClock sets the clock that stamps new bookmarks. A nil clock restores
+// ledger.SystemClock.
+func (bs *BookmarkStore) SetClock(clock ledger.Clock) {
+	if clock == nil {
+		clock = ledger.SystemClock
+	}
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.clock = clock
+}
+
+// Save bookmarks the post with content CID postCID; postTxID may be empty.
+// Saving a post again moves it to the top.
+func (bs *BookmarkStore) Save(postCID, postTxID string) error {
+	if postCID == "" || len(postCID) > MaxCIDLength {
+		return fmt.Errorf("post CID is %d bytes, want 1 to %d", len(postCID), MaxCIDLength)
+	}
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	updated := append(bookmarkList(nil), bs.bookmarks...).save(Bookmark{PostCID: postCID, PostTxID: postTxID, SavedAt: bs.clock.Now().UnixNano()})
+	return bs.storeLocked(updated)
+}
+
+// Remove deletes the bookmark of postCID. Removing a post that is not saved
+// is not an error.
+func (bs *BookmarkStore) Remove(postCID string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if !bs.bookmarks.has(postCID) {
+		return nil
+	}
+	return bs.storeLocked(append(bookmarkList(nil), bs.bookmarks...).remove(postCID))
+}
+
+// IsSaved reports whether the post with content CID postCID is bookmarked.
+func (bs *BookmarkStore) IsSaved(postCID string) bool {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	return bs.bookmarks.has(postCID)
+}
+
+// List returns up to limit bookmarks, most recently saved first, following
+// the cursor after ("" for the first page). A limit <= 0 returns all of them.
+func (bs *BookmarkStore) List(limit int, after string) (BookmarkPage, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	return bs.bookmarks.page(limit, after)
+}
+
+func (bs *BookmarkStore) load() error {
+	sealed, err := os.ReadFile(bs.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read bookmarks: %w", err)
+	}
+	nonceSize := bs.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return fmt.Errorf("bookmark file %s is truncated", bs.path)
+	}
+	plaintext, err := bs.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], bookmarkAD)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt bookmarks (wrong wallet or corrupted file): %w", err)
+	}
+	if err := json.Unmarshal(plaintext, &bs.bookmarks); err != nil {
+		return fmt.Errorf("failed to decode bookmarks: %w", err)
+	}
+	return nil
+}
+
+// storeLocked writes bookmarks to the file and, once written, makes them the
+// store's bookmarks. The caller must hold bs.mu.
+func (bs *BookmarkStore) storeLocked(bookmarks bookmarkList) error {
+	plaintext, err := json.Marshal(bookmarks)
+	if err != nil {
+		return fmt.Errorf("failed to encode bookmarks: %w", err)
+	}
+	nonce := make([]byte, bs.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate bookmark nonce: %w", err)
+	}
+	sealed := bs.aead.Seal(nonce, nonce, plaintext, bookmarkAD)
+
+	tmp := bs.path + ".tmp"
+	if err := os.WriteFile(tmp, sealed, 0600); err != nil {
+		return fmt.Errorf("failed to write bookmarks: %w", err)
+	}
+	if err := os.Rename(tmp, bs.path); err != nil {
+		return fmt.Errorf("failed to replace bookmarks: %w", err)
+	}
+	bs.bookmarks = bookmarks
+	return nil
+}
+
+// BookmarkIndex records the public bookmarks in the chain's PostBookmarked
+// transactions, per owner. It is kept in memory and updated incrementally
+// like the graph index. A bookmark counts only if its owner is the
+// transaction sender. A BookmarkIndex is safe for concurrent use.
+type BookmarkIndex struct {
+	mu        sync.RWMutex
+	follower  ledger.ChainFollower
+	bookmarks map[string]bookmarkList // Owner -> bookmarks, oldest first
+}
+
+// ErrBookmarkIndexDiverged is returned by BookmarkIndex.Sync when the block
+// recorded as processed last is no longer on the chain. Call Rebuild to
+// recover.
+var ErrBookmarkIndexDiverged = errors.New("bookmark index high-water mark does not match the chain")
+
+// NewBookmarkIndex creates an empty BookmarkIndex. Call Sync to populate it.
+func NewBookmarkIndex() *BookmarkIndex {
+	b := &BookmarkIndex{}
+	b.reset()
+	return b
+}
+
+// reset clears the index. The caller must hold b.mu (or own b exclusively).
+func (b *BookmarkIndex) reset() {
+	b.follower = ledger.NewChainFollower()
+	b.bookmarks = make(map[string]bookmarkList)
+}
+
+// HighWaterMark reports the last block scanned for PostBookmarked
+// transactions.
+func (b *BookmarkIndex) HighWaterMark() (int64, string) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.follower.HighWaterMark()
+}
+
+// Sync applies the PostBookmarked transactions in blocks added since the
+// last call. ErrBookmarkIndexDiverged is returned if the chain was replaced.
+func (b *BookmarkIndex) Sync(bc *ledger.Blockchain) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.follower.Sync(bc, "bookmark index", ErrBookmarkIndexDiverged, b.indexBlock)
+}
+
+// Rebuild forgets every on-chain bookmark and rescans the chain.
+func (b *BookmarkIndex) Rebuild(bc *ledger.Blockchain) (int, error) {
+	if bc == nil {
+		return 0, fmt.Errorf("blockchain cannot be nil")
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reset()
+	return b.follower.Sync(bc, "bookmark index", ErrBookmarkIndexDiverged, b.indexBlock)
+}
+
+// indexBlock records the block's bookmarks. The caller must hold b.mu.
+func (b *BookmarkIndex) indexBlock(block *ledger.Block) {
+	for _, tx := range block.Transactions {
+		if tx == nil || tx.Type != ledger.PostBookmarked {
+			continue
+		}
+		pb, err := PostBookmarkFromPayload(tx.Payload)
+		if err != nil || pb.OwnerPublicKey != tx.SenderPublicKey {
+			continue
+		}
+		if pb.Removed {
+			b.bookmarks[pb.OwnerPublicKey] = b.bookmarks[pb.OwnerPublicKey].remove(pb.PostCID)
+		} else {
+			b.bookmarks[pb.OwnerPublicKey] = b.bookmarks[pb.OwnerPublicKey].save(Bookmark{PostCID: pb.PostCID, PostTxID: pb.PostTxID, SavedAt: pb.Timestamp})
+		}
+	}
+}
+
+// IsBookmarked reports whether owner has publicly bookmarked the post with
+// content CID postCID.
+func (b *BookmarkIndex) IsBookmarked(owner, postCID string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.bookmarks[owner].has(postCID)
+}
+
+// GetBookmarks returns up to limit of owner's public bookmarks, most recently
+// saved first, following the cursor after ("" for the first page). A limit
+// <= 0 returns all of them.
+func (b *BookmarkIndex) GetBookmarks(owner string, limit int, after string) (BookmarkPage, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.bookmarks[owner].page(limit, after)
+}
+
+// SavedFeedEntry is a feed entry with the viewer's saved state.
+type SavedFeedEntry struct {
+	FeedEntry
+	Saved bool `json:"saved"`
+}
+
+// WithSavedState annotates feed entries with whether isSaved reports their
+// content CID as bookmarked, e.g. BookmarkStore.IsSaved, or a closure over
+// BookmarkIndex.IsBookmarked for a given owner.
+func WithSavedState(entries []FeedEntry, isSaved func(postCID string) bool) []SavedFeedEntry {
+	out := make([]SavedFeedEntry, len(entries))
+	for i, e := range entries {
+		out[i] = SavedFeedEntry{FeedEntry: e, Saved: e.ContentCID != "" && isSaved(e.ContentCID)}
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package social
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// bookmarkTestCIDs returns the post CIDs of a page, in order.
+func bookmarkTestCIDs(page BookmarkPage) []string {
+	var cids []string
+	for _, b := range page.Bookmarks {
+		cids = append(cids, b.PostCID)
+	}
+	return cids
+}
+
+func TestBookmarkStore_SavePaginateAndPersist(t *testing.T) {
+	wallet, _ := identity.NewWallet()
+	path := filepath.Join(t.TempDir(), "user", "bookmarks.dat")
+	bs, err := NewBookmarkStore(path, wallet)
+	if err != nil {
+		t.Fatalf("NewBookmarkStore() error = %v", err)
+	}
+	bs.SetClock(testutil.NewClock(time.Second))
+	for _, cid := range []string{"a", "b", "c", "d", "b"} { // Saving b again moves it to the top.
+		if err := bs.Save(cid, "tx-"+cid); err != nil {
+			t.Fatalf("Save(%s) error = %v", cid, err)
+		}
+	}
+	if err := bs.Remove("a"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := bs.Remove("never-saved"); err != nil {
+		t.Errorf("Remove() of an unsaved post error = %v, want nil", err)
+	}
+
+	page, err := bs.List(2, "")
+	if got := bookmarkTestCIDs(page); err != nil || len(got) != 2 || got[0] != "b" || got[1] != "d" || page.NextCursor != "d" {
+		t.Fatalf("List(2) = %v (next %q), %v; want [b d] with cursor d", got, page.NextCursor, err)
+	}
+	page, _ = bs.List(2, page.NextCursor)
+	if got := bookmarkTestCIDs(page); len(got) != 1 || got[0] != "c" || page.NextCursor != "" {
+		t.Errorf("List(2, d) = %v (next %q), want the last page [c]", got, page.NextCursor)
+	}
+	if _, err := bs.List(2, "a"); !errors.Is(err, ErrInvalidBookmarkCursor) {
+		t.Errorf("List() with a removed cursor error = %v, want ErrInvalidBookmarkCursor", err)
+	}
+
+	reopened, err := NewBookmarkStore(path, wallet)
+	if err != nil {
+		t.Fatalf("reopening NewBookmarkStore() error = %v", err)
+	}
+	if page, _ := reopened.List(0, ""); len(page.Bookmarks) != 3 || !reopened.IsSaved("c") || reopened.IsSaved("a") {
+		t.Errorf("reopened bookmarks = %v, want b, d and c", bookmarkTestCIDs(page))
+	}
+	other, _ := identity.NewWallet()
+	if _, err := NewBookmarkStore(path, other); err == nil {
+		t.Error("NewBookmarkStore() with another wallet: expected error, got nil")
+	}
+}
+
+func TestBookmarkIndex_PublicBookmarksAndSavedState(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	mallory, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+	clock := testutil.NewClock(time.Second)
+	var txs []*ledger.Transaction
+	for _, b := range []struct {
+		cid     string
+		removed bool
+	}{{"a", false}, {"b", false}, {"a", true}, {"c", false}} {
+		tx, err := NewPostBookmarkTransaction(clock, alice, b.cid, "", b.removed)
+		if err != nil {
+			t.Fatalf("NewPostBookmarkTransaction() error = %v", err)
+		}
+		txs = append(txs, tx)
+	}
+	forged, _ := (&PostBookmark{OwnerPublicKey: alice.Address, PostCID: "forged", Timestamp: 1}).ToPayload(ledger.PayloadFormatJSON)
+	txs = append(txs, graphTestTx(t, mallory, ledger.PostBookmarked, forged))
+	if _, err := bc.AddBlock(txs); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+
+	idx := NewBookmarkIndex()
+	if _, err := idx.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	page, err := idx.GetBookmarks(alice.Address, 0, "")
+	if got := bookmarkTestCIDs(page); err != nil || len(got) != 2 || got[0] != "c" || got[1] != "b" {
+		t.Errorf("GetBookmarks() = %v, %v; want [c b]", got, err)
+	}
+	if idx.IsBookmarked(alice.Address, "forged") || idx.IsBookmarked(mallory.Address, "forged") {
+		t.Error("a bookmark sent by someone other than its owner was indexed")
+	}
+
+	entries := WithSavedState([]FeedEntry{{TxID: "1", ContentCID: "b"}, {TxID: "2", ContentCID: "a"}, {TxID: "3"}},
+		func(cid string) bool { return idx.IsBookmarked(alice.Address, cid) })
+	if !entries[0].Saved || entries[1].Saved || entries[2].Saved || entries[0].TxID != "1" {
+		t.Errorf("WithSavedState() = %+v, want only b saved", entries)
+	}
+}
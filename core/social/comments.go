@@ -0,0 +1,261 @@
+package social
+
+import (
+	"digisocialblock/core/ledger"
+	"fmt"
+)
+
+func init() {
+	ledger.RegisterPayloadValidator(ledger.CommentAdded, ValidateCommentPayload)
+	ledger.RegisterPayloadValidator(ledger.CommentEdited, ValidateCommentEditPayload)
+	ledger.RegisterPayloadValidator(ledger.CommentDeleted, ValidateCommentDeletionPayload)
+	ledger.RegisterPayloadValidator(ledger.UserFollowed, ValidateFollowPayload)
+	ledger.RegisterPayloadDecoder(ledger.CommentAdded, ledger.DecodeInto(func() interface{} { return &Comment{} }))
+	ledger.RegisterPayloadDecoder(ledger.CommentEdited, ledger.DecodeInto(func() interface{} { return &CommentEdit{} }))
+	ledger.RegisterPayloadDecoder(ledger.CommentDeleted, ledger.DecodeInto(func() interface{} { return &CommentDeletion{} }))
+	ledger.RegisterPayloadDecoder(ledger.UserFollowed, ledger.DecodeInto(func() interface{} { return &Follow{} }))
+	ledger.RegisterCIDResolver(ledger.CommentAdded, commentCIDs)
+	ledger.RegisterCIDResolver(ledger.CommentEdited, commentEditCIDs)
+}
+
+// MaxTxIDLength bounds transaction IDs referenced from payloads.
+const MaxTxIDLength = 128 // Bytes
+
+// Comment is the CommentAdded payload. Comments form a tree under a post:
+// top-level comments have no ParentTxID, replies name the comment they answer.
+// Like posts, the comment text is stored on DDS and referenced by ContentCID.
+type Comment struct {
+	AuthorPublicKey string `json:"authorPublicKey"`
+	PostTxID        string `json:"postTxId"`             // PostCreated transaction commented on
+	ParentTxID      string `json:"parentTxId,omitempty"` // CommentAdded transaction replied to
+	ContentCID      string `json:"contentCID"`
+	Timestamp       int64  `json:"timestamp"`
+}
+
+// Validate checks that required fields are set and within limits.
+func (c *Comment) Validate() error {
+	if c.AuthorPublicKey == "" {
+		return fmt.Errorf("empty AuthorPublicKey")
+	}
+	if c.PostTxID == "" || len(c.PostTxID) > MaxTxIDLength {
+		return fmt.Errorf("PostTxID is %d bytes, want 1 to %d", len(c.PostTxID), MaxTxIDLength)
+	}
+	if len(c.ParentTxID) > MaxTxIDLength {
+		return fmt.Errorf("ParentTxID is %d bytes, limit %d", len(c.ParentTxID), MaxTxIDLength)
+	}
+	if c.ContentCID == "" || len(c.ContentCID) > MaxCIDLength {
+		return fmt.Errorf("ContentCID is %d bytes, want 1 to %d", len(c.ContentCID), MaxCIDLength)
+	}
+	if c.Timestamp == 0 {
+		return fmt.Errorf("zero timestamp")
+	}
+	return nil
+}
+
+// ToPayload serializes the Comment as a CommentAdded transaction payload in
+// the given format.
+func (c *Comment) ToPayload(format ledger.PayloadFormat) ([]byte, error) {
+	payload, err := ledger.EncodePayload(format, c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode comment payload: %w", err)
+	}
+	return payload, nil
+}
+
+// CommentFromPayload deserializes a CommentAdded payload in either payload
+// format. The result must pass Validate.
+func CommentFromPayload(payload []byte) (*Comment, error) {
+	var c Comment
+	if err := ledger.DecodePayload(payload, &c); err != nil {
+		return nil, fmt.Errorf("failed to decode comment payload: %w", err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("decoded comment is invalid: %w", err)
+	}
+	return &c, nil
+}
+
+// ValidateCommentPayload is the ledger schema validator for CommentAdded payloads.
+func ValidateCommentPayload(payload []byte) error {
+	_, err := CommentFromPayload(payload)
+	return err
+}
+
+// commentCIDs is the ledger CID resolver for CommentAdded: the comment's text.
+func commentCIDs(payload []byte) ([]string, error) {
+	c, err := CommentFromPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	return []string{c.ContentCID}, nil
+}
+
+// CommentEdit is the CommentEdited payload: a new version of a comment's text.
+// Only the author of the original comment may edit it, which indexes check
+// against the chain (see GraphIndex.ValidateCommentChange). The original
+// comment is version 1 and each edit must carry the next version, so edits
+// apply in order and a replayed or concurrent edit is ignored.
+type CommentEdit struct {
+	AuthorPublicKey string `json:"authorPublicKey"`
+	CommentTxID     string `json:"commentTxId"` // CommentAdded transaction edited
+	ContentCID      string `json:"contentCID"`  // New text on DDS
+	Version         int    `json:"version"`
+	Timestamp       int64  `json:"timestamp"`
+}
+
+// Validate checks that required fields are set and within limits.
+func (e *CommentEdit) Validate() error {
+	if e.AuthorPublicKey == "" {
+		return fmt.Errorf("empty AuthorPublicKey")
+	}
+	if e.CommentTxID == "" || len(e.CommentTxID) > MaxTxIDLength {
+		return fmt.Errorf("CommentTxID is %d bytes, want 1 to %d", len(e.CommentTxID), MaxTxIDLength)
+	}
+	if e.ContentCID == "" || len(e.ContentCID) > MaxCIDLength {
+		return fmt.Errorf("ContentCID is %d bytes, want 1 to %d", len(e.ContentCID), MaxCIDLength)
+	}
+	if e.Version < 2 {
+		return fmt.Errorf("edit version %d, want 2 or more", e.Version)
+	}
+	if e.Timestamp == 0 {
+		return fmt.Errorf("zero timestamp")
+	}
+	return nil
+}
+
+// ToPayload serializes the CommentEdit as a CommentEdited transaction payload
+// in the given format.
+func (e *CommentEdit) ToPayload(format ledger.PayloadFormat) ([]byte, error) {
+	payload, err := ledger.EncodePayload(format, e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode comment edit payload: %w", err)
+	}
+	return payload, nil
+}
+
+// CommentEditFromPayload deserializes a CommentEdited payload in either
+// payload format. The result must pass Validate.
+func CommentEditFromPayload(payload []byte) (*CommentEdit, error) {
+	var e CommentEdit
+	if err := ledger.DecodePayload(payload, &e); err != nil {
+		return nil, fmt.Errorf("failed to decode comment edit payload: %w", err)
+	}
+	if err := e.Validate(); err != nil {
+		return nil, fmt.Errorf("decoded comment edit is invalid: %w", err)
+	}
+	return &e, nil
+}
+
+// ValidateCommentEditPayload is the ledger schema validator for CommentEdited payloads.
+func ValidateCommentEditPayload(payload []byte) error {
+	_, err := CommentEditFromPayload(payload)
+	return err
+}
+
+// commentEditCIDs is the ledger CID resolver for CommentEdited: the new text.
+func commentEditCIDs(payload []byte) ([]string, error) {
+	e, err := CommentEditFromPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	return []string{e.ContentCID}, nil
+}
+
+// CommentDeletion is the CommentDeleted payload. Like edits, deletions are
+// only honoured from the original comment's author. The chain keeps the
+// comment; indexes replace it with a tombstone so replies keep their place.
+type CommentDeletion struct {
+	AuthorPublicKey string `json:"authorPublicKey"`
+	CommentTxID     string `json:"commentTxId"` // CommentAdded transaction deleted
+	Timestamp       int64  `json:"timestamp"`
+}
+
+// Validate checks that required fields are set and within limits.
+func (d *CommentDeletion) Validate() error {
+	if d.AuthorPublicKey == "" {
+		return fmt.Errorf("empty AuthorPublicKey")
+	}
+	if d.CommentTxID == "" || len(d.CommentTxID) > MaxTxIDLength {
+		return fmt.Errorf("CommentTxID is %d bytes, want 1 to %d", len(d.CommentTxID), MaxTxIDLength)
+	}
+	if d.Timestamp == 0 {
+		return fmt.Errorf("zero timestamp")
+	}
+	return nil
+}
+
+// ToPayload serializes the CommentDeletion as a CommentDeleted transaction
+// payload in the given format.
+func (d *CommentDeletion) ToPayload(format ledger.PayloadFormat) ([]byte, error) {
+	payload, err := ledger.EncodePayload(format, d)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode comment deletion payload: %w", err)
+	}
+	return payload, nil
+}
+
+// CommentDeletionFromPayload deserializes a CommentDeleted payload in either
+// payload format. The result must pass Validate.
+func CommentDeletionFromPayload(payload []byte) (*CommentDeletion, error) {
+	var d CommentDeletion
+	if err := ledger.DecodePayload(payload, &d); err != nil {
+		return nil, fmt.Errorf("failed to decode comment deletion payload: %w", err)
+	}
+	if err := d.Validate(); err != nil {
+		return nil, fmt.Errorf("decoded comment deletion is invalid: %w", err)
+	}
+	return &d, nil
+}
+
+// ValidateCommentDeletionPayload is the ledger schema validator for
+// CommentDeleted payloads.
+func ValidateCommentDeletionPayload(payload []byte) error {
+	_, err := CommentDeletionFromPayload(payload)
+	return err
+}
+
+// Follow is the UserFollowed payload. The follower is the transaction sender.
+type Follow struct {
+	FolloweePublicKey string `json:"followeePublicKey"`
+	Timestamp         int64  `json:"timestamp"`
+}
+
+// Validate checks that required fields are set.
+func (f *Follow) Validate() error {
+	if f.FolloweePublicKey == "" {
+		return fmt.Errorf("empty FolloweePublicKey")
+	}
+	if f.Timestamp == 0 {
+		return fmt.Errorf("zero timestamp")
+	}
+	return nil
+}
+
+// ToPayload serializes the Follow as a UserFollowed transaction payload in
+// the given format.
+func (f *Follow) ToPayload(format ledger.PayloadFormat) ([]byte, error) {
+	payload, err := ledger.EncodePayload(format, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode follow payload: %w", err)
+	}
+	return payload, nil
+}
+
+// FollowFromPayload deserializes a UserFollowed payload in either payload
+// format. The result must pass Validate.
+func FollowFromPayload(payload []byte) (*Follow, error) {
+	var f Follow
+	if err := ledger.DecodePayload(payload, &f); err != nil {
+		return nil, fmt.Errorf("failed to decode follow payload: %w", err)
+	}
+	if err := f.Validate(); err != nil {
+		return nil, fmt.Errorf("decoded follow is invalid: %w", err)
+	}
+	return &f, nil
+}
+
+// ValidateFollowPayload is the ledger schema validator for UserFollowed payloads.
+func ValidateFollowPayload(payload []byte) error {
+	_, err := FollowFromPayload(payload)
+	return err
+}
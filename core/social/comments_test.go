@@ -0,0 +1,106 @@
+package social
+
+import (
+	"digisocialblock/core/ledger"
+	"strings"
+	"testing"
+)
+
+func TestComment_PayloadRoundTripAndValidation(t *testing.T) {
+	valid := Comment{AuthorPublicKey: "author", PostTxID: "post-tx", ParentTxID: "comment-tx", ContentCID: "cid", Timestamp: 1}
+	for _, format := range []ledger.PayloadFormat{ledger.PayloadFormatJSON, ledger.PayloadFormatCBOR} {
+		payload, err := valid.ToPayload(format)
+		if err != nil {
+			t.Fatalf("ToPayload(%d) error = %v", format, err)
+		}
+		got, err := CommentFromPayload(payload)
+		if err != nil || *got != valid {
+			t.Errorf("CommentFromPayload(ToPayload(%d)) = %+v, %v; want %+v", format, got, err, valid)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(c *Comment)
+	}{
+		{"no author", func(c *Comment) { c.AuthorPublicKey = "" }},
+		{"no post", func(c *Comment) { c.PostTxID = "" }},
+		{"long parent", func(c *Comment) { c.ParentTxID = strings.Repeat("a", MaxTxIDLength+1) }},
+		{"no content", func(c *Comment) { c.ContentCID = "" }},
+		{"no timestamp", func(c *Comment) { c.Timestamp = 0 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid
+			tt.mutate(&c)
+			payload, _ := c.ToPayload(ledger.PayloadFormatJSON)
+			if err := ValidateCommentPayload(payload); err == nil {
+				t.Error("ValidateCommentPayload() expected error, got nil")
+			}
+		})
+	}
+	if err := ValidateCommentPayload([]byte(`{"authorPublicKey":"a","postTxId":"p","contentCID":"c","timestamp":1,"extra":1}`)); err == nil {
+		t.Error("ValidateCommentPayload() accepted an unknown field")
+	}
+}
+
+func TestFollow_PayloadValidation(t *testing.T) {
+	payload, _ := (&Follow{FolloweePublicKey: "followee", Timestamp: 1}).ToPayload(ledger.PayloadFormatCBOR)
+	if err := ValidateFollowPayload(payload); err != nil {
+		t.Errorf("ValidateFollowPayload() error = %v", err)
+	}
+	for _, bad := range []string{`{"timestamp":1}`, `{"followeePublicKey":"x"}`, `{"followee":"x","timestamp":1}`} {
+		if err := ValidateFollowPayload([]byte(bad)); err == nil {
+			t.Errorf("ValidateFollowPayload(%s) expected error, got nil", bad)
+		}
+	}
+}
+
+func TestPayloadDecodersRegistered(t *testing.T) {
+	comment, _ := (&Comment{AuthorPublicKey: "author", PostTxID: "post-tx", ContentCID: "cid", Timestamp: 1}).ToPayload(ledger.PayloadFormatCBOR)
+	follow, _ := (&Follow{FolloweePublicKey: "followee", Timestamp: 1}).ToPayload(ledger.PayloadFormatJSON)
+	post, _ := NewPost("author", "cid", "", nil).ToPayload(ledger.PayloadFormatCBOR)
+	for _, tt := range []struct {
+		txType  ledger.TransactionType
+		payload []byte
+		ok      func(v interface{}) bool
+	}{
+		{ledger.CommentAdded, comment, func(v interface{}) bool { c, ok := v.(*Comment); return ok && c.PostTxID == "post-tx" }},
+		{ledger.UserFollowed, follow, func(v interface{}) bool { f, ok := v.(*Follow); return ok && f.FolloweePublicKey == "followee" }},
+		{ledger.PostCreated, post, func(v interface{}) bool { p, ok := v.(*Post); return ok && p.ContentCID == "cid" }},
+	} {
+		v, err := ledger.DecodeTypedPayload(tt.txType, tt.payload)
+		if err != nil || !tt.ok(v) {
+			t.Errorf("DecodeTypedPayload(%s) = %#v, %v", tt.txType, v, err)
+		}
+	}
+}
+
+func TestCommentEditAndDeletion_PayloadValidation(t *testing.T) {
+	edit := CommentEdit{AuthorPublicKey: "author", CommentTxID: "comment-tx", ContentCID: "cid-2", Version: 2, Timestamp: 1}
+	payload, _ := edit.ToPayload(ledger.PayloadFormatCBOR)
+	if got, err := CommentEditFromPayload(payload); err != nil || *got != edit {
+		t.Errorf("CommentEditFromPayload(ToPayload()) = %+v, %v; want %+v", got, err, edit)
+	}
+	for _, bad := range []string{
+		`{"authorPublicKey":"a","commentTxId":"c","contentCID":"x","version":1,"timestamp":1}`,
+		`{"authorPublicKey":"a","commentTxId":"","contentCID":"x","version":2,"timestamp":1}`,
+		`{"authorPublicKey":"a","commentTxId":"c","contentCID":"","version":2,"timestamp":1}`,
+		`{"commentTxId":"c","contentCID":"x","version":2,"timestamp":1}`,
+	} {
+		if err := ValidateCommentEditPayload([]byte(bad)); err == nil {
+			t.Errorf("ValidateCommentEditPayload(%s) expected error, got nil", bad)
+		}
+	}
+
+	deletion := CommentDeletion{AuthorPublicKey: "author", CommentTxID: "comment-tx", Timestamp: 1}
+	payload, _ = deletion.ToPayload(ledger.PayloadFormatJSON)
+	if got, err := CommentDeletionFromPayload(payload); err != nil || *got != deletion {
+		t.Errorf("CommentDeletionFromPayload(ToPayload()) = %+v, %v; want %+v", got, err, deletion)
+	}
+	for _, bad := range []string{`{"authorPublicKey":"a","timestamp":1}`, `{"authorPublicKey":"a","commentTxId":"c"}`, `{"authorPublicKey":"a","commentTxId":"c","timestamp":1,"contentCID":"x"}`} {
+		if err := ValidateCommentDeletionPayload([]byte(bad)); err == nil {
+			t.Errorf("ValidateCommentDeletionPayload(%s) expected error, got nil", bad)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package social
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DMEncryptionMethod identifies direct-message envelopes.
+const DMEncryptionMethod = "dsb-dm-aes256gcm-v1"
+
+// MaxDMLength is the maximum length of a direct message's text, in bytes.
+const MaxDMLength = 16 * 1024
+
+// DMEnvelope is an encrypted direct message between two addresses. A fresh
+// content key is sealed to both the sender and the recipient, so either side
+// can read the conversation back, and the sender signs the envelope so the
+// recipient knows who wrote it.
+type DMEnvelope struct {
+	Method     string               `json:"method"`
+	ChainID    string               `json:"chainId"` // Chain the sender's signature is bound to
+	Sender     string               `json:"sender"`
+	Recipient  string               `json:"recipient"`
+	SentAt     int64                `json:"sentAt"`     // UnixNano
+	SealedKeys []identity.SealedKey `json:"sealedKeys"` // Content key sealed to sender and recipient
+	Nonce      []byte               `json:"nonce"`
+	Ciphertext []byte               `json:"ciphertext"`
+	Signature  []byte               `json:"signature,omitempty"` // Sender's message signature over the rest of the envelope
+}
+
+// SealDM encrypts text from wallet's owner to recipient and signs it for the
+// default chain.
+func SealDM(wallet *identity.Wallet, recipient, text string) (*DMEnvelope, error) {
+	if wallet == nil {
+		return nil, fmt.Errorf("wallet cannot be nil")
+	}
+	if recipient == "" || recipient == wallet.Address {
+		return nil, fmt.Errorf("direct message needs a recipient other than the sender")
+	}
+	if text == "" || len(text) > MaxDMLength || !utf8.ValidString(text) {
+		return nil, fmt.Errorf("direct message must be 1 to %d bytes of UTF-8", MaxDMLength)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate message key: %w", err)
+	}
+	defer wipe(key)
+	sealed, err := identity.SealKeyForRecipients(key, []string{wallet.Address, recipient})
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal message key: %w", err)
+	}
+	e := &DMEnvelope{
+		Method:     DMEncryptionMethod,
+		ChainID:    ledger.DefaultChainID,
+		Sender:     wallet.Address,
+		Recipient:  recipient,
+		SentAt:     time.Now().UnixNano(),
+		SealedKeys: sealed,
+	}
+	aead, err := newDMCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	e.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(e.Nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	e.Ciphertext = aead.Seal(nil, e.Nonce, []byte(text), e.aad())
+	if e.Signature, err = wallet.SignMessage(e.ChainID, e.signingBytes()); err != nil {
+		return nil, fmt.Errorf("failed to sign direct message: %w", err)
+	}
+	return e, nil
+}
+
+// Open verifies the sender's signature and decrypts the message with wallet,
+// which must be the sender's or the recipient's.
+func (e *DMEnvelope) Open(wallet *identity.Wallet) (string, error) {
+	if wallet == nil {
+		return "", fmt.Errorf("wallet cannot be nil")
+	}
+	if e.Method != DMEncryptionMethod {
+		return "", fmt.Errorf("unsupported direct message method %q", e.Method)
+	}
+	if err := identity.VerifyMessage(e.Sender, e.ChainID, e.signingBytes(), e.Signature); err != nil {
+		return "", fmt.Errorf("direct message signature is invalid: %w", err)
+	}
+	key, err := wallet.OpenSealedKey(e.SealedKeys)
+	if err != nil {
+		return "", fmt.Errorf("cannot open direct message: %w", err)
+	}
+	defer wipe(key)
+	aead, err := newDMCipher(key)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := aead.Open(nil, e.Nonce, e.Ciphertext, e.aad())
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt direct message: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// ID returns a stable identifier for the envelope: the hex SHA-256 of its
+// JSON encoding, signature included.
+func (e *DMEnvelope) ID() string {
+	data, _ := json.Marshal(e)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ConversationID returns the conversation the envelope belongs to (see
+// DMConversationID).
+func (e *DMEnvelope) ConversationID() string {
+	return DMConversationID(e.Sender, e.Recipient)
+}
+
+// Peer returns the other party of the conversation from self's point of view.
+func (e *DMEnvelope) Peer(self string) string {
+	if e.Sender == self {
+		return e.Recipient
+	}
+	return e.Sender
+}
+
+// signingBytes is the envelope's JSON encoding without its signature.
+func (e *DMEnvelope) signingBytes() []byte {
+	unsigned := *e
+	unsigned.Signature = nil
+	data, _ := json.Marshal(&unsigned)
+	return data
+}
+
+// aad binds the ciphertext to its sender, recipient and time.
+func (e *DMEnvelope) aad() []byte {
+	aad := make([]byte, 8, 8+len(e.Sender)+1+len(e.Recipient))
+	binary.BigEndian.PutUint64(aad, uint64(e.SentAt))
+	return append(append(append(aad, e.Sender...), 0), e.Recipient...)
+}
+
+// DMConversationID identifies the conversation between two addresses. It is
+// the same whichever order the addresses are given in.
+func DMConversationID(a, b string) string {
+	pair := []string{a, b}
+	sort.Strings(pair)
+	sum := sha256.Sum256([]byte("dsb-dm-v1\n" + strings.Join(pair, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+func newDMCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create message cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,48 @@
+package social
+
+import (
+	"digisocialblock/core/identity"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDMEnvelope_SealOpen(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	bob, _ := identity.NewWallet()
+	mallory, _ := identity.NewWallet()
+
+	env, err := SealDM(alice, bob.Address, "hi bob")
+	if err != nil {
+		t.Fatalf("SealDM() error = %v", err)
+	}
+	for _, w := range []*identity.Wallet{alice, bob} {
+		if text, err := env.Open(w); err != nil || text != "hi bob" {
+			t.Errorf("Open() as %s = %q, %v; want the text", w.Address[:8], text, err)
+		}
+	}
+	if _, err := env.Open(mallory); !errors.Is(err, identity.ErrNotARecipient) {
+		t.Errorf("Open() as outsider error = %v, want ErrNotARecipient", err)
+	}
+	if env.ConversationID() != DMConversationID(bob.Address, alice.Address) || env.Peer(bob.Address) != alice.Address {
+		t.Error("conversation ID or peer does not match the two parties")
+	}
+
+	forged := *env
+	forged.Sender = mallory.Address
+	if _, err := forged.Open(bob); err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Errorf("Open() with a forged sender error = %v, want a signature error", err)
+	}
+	if forged.ID() == env.ID() {
+		t.Error("ID() did not change with the envelope")
+	}
+
+	for _, text := range []string{"", strings.Repeat("x", MaxDMLength+1), "\xff"} {
+		if _, err := SealDM(alice, bob.Address, text); err == nil {
+			t.Errorf("SealDM(%d bytes) expected error, got nil", len(text))
+		}
+	}
+	if _, err := SealDM(alice, alice.Address, "me"); err == nil {
+		t.Error("SealDM() to self expected error, got nil")
+	}
+}
@@ -0,0 +1,252 @@
+package social
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDraftNotFound is returned when a draft ID does not exist in the store.
+var ErrDraftNotFound = errors.New("draft not found")
+
+// draftKeyPurpose scopes the wallet-derived key to draft encryption.
+const draftKeyPurpose = "social-drafts"
+
+// draftFileExt is the extension of encrypted draft files.
+const draftFileExt = ".draft"
+
+// Draft is an unpublished post. Drafts never leave the local machine: they are
+// not chunked to DDS or put on chain until published with PostManager.PublishDraft.
+type Draft struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title,omitempty"`
+	Body      string    `json:"body"`
+	Tags      []string  `json:"tags,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// DraftStore keeps drafts in a local directory, one file per draft, each
+// encrypted with AES-256-GCM under a key derived from the owner's wallet. The
+// draft ID is bound as additional data, so files cannot be swapped between IDs
+// undetected. A DraftStore is safe for concurrent use.
+type DraftStore struct {
+	mu   sync.Mutex
+	dir  string
+	aead cipher.AEAD
+}
+
+// NewDraftStore opens (creating if needed) the draft directory dir for wallet.
+// Drafts written under one wallet cannot be read with another.
+func NewDraftStore(dir string, wallet *identity.Wallet) (*DraftStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("draft directory cannot be empty")
+	}
+	if wallet == nil {
+		return nil, fmt.Errorf("wallet cannot be nil for DraftStore")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create draft directory %s: %w", dir, err)
+	}
+	key, err := wallet.DeriveKey(draftKeyPurpose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive draft key: %w", err)
+	}
+	defer func() {
+		for i := range key {
+			key[i] = 0
+		}
+	}()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create draft cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create draft cipher: %w", err)
+	}
+	return &DraftStore{dir: dir, aead: aead}, nil
+}
+
+// Create stores a new draft and returns it.
+func (ds *DraftStore) Create(title, body string, tags []string) (*Draft, error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate draft ID: %w", err)
+	}
+	now := time.Now().UTC()
+	d := &Draft{
+		ID:        hex.EncodeToString(idBytes),
+		Title:     title,
+		Body:      body,
+		Tags:      tags,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.writeLocked(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Get returns the draft with the given ID.
+func (ds *DraftStore) Get(id string) (*Draft, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.readLocked(id)
+}
+
+// Update replaces the title, body and tags of an existing draft.
+func (ds *DraftStore) Update(id, title, body string, tags []string) (*Draft, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	d, err := ds.readLocked(id)
+	if err != nil {
+		return nil, err
+	}
+	d.Title, d.Body, d.Tags = title, body, tags
+	d.UpdatedAt = time.Now().UTC()
+	if err := ds.writeLocked(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Delete removes a draft.
+func (ds *DraftStore) Delete(id string) error {
+	path, err := ds.pathFor(id)
+	if err != nil {
+		return err
+	}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrDraftNotFound, id)
+		}
+		return fmt.Errorf("failed to delete draft %s: %w", id, err)
+	}
+	return nil
+}
+
+// List returns all drafts, most recently updated first.
+func (ds *DraftStore) List() ([]*Draft, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	entries, err := os.ReadDir(ds.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list drafts in %s: %w", ds.dir, err)
+	}
+	var drafts []*Draft
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, draftFileExt) {
+			continue
+		}
+		d, err := ds.readLocked(strings.TrimSuffix(name, draftFileExt))
+		if err != nil {
+			return nil, err
+		}
+		drafts = append(drafts, d)
+	}
+	sort.Slice(drafts, func(i, j int) bool { return drafts[i].UpdatedAt.After(drafts[j].UpdatedAt) })
+	return drafts, nil
+}
+
+// pathFor maps a draft ID to its file, rejecting IDs that are not ones Create
+// would generate so an ID can never escape the draft directory.
+func (ds *DraftStore) pathFor(id string) (string, error) {
+	if raw, err := hex.DecodeString(id); err != nil || len(raw) != 16 {
+		return "", fmt.Errorf("%w: invalid draft ID %q", ErrDraftNotFound, id)
+	}
+	return filepath.Join(ds.dir, id+draftFileExt), nil
+}
+
+func (ds *DraftStore) writeLocked(d *Draft) error {
+	path, err := ds.pathFor(d.ID)
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("failed to encode draft %s: %w", d.ID, err)
+	}
+	nonce := make([]byte, ds.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate draft nonce: %w", err)
+	}
+	sealed := ds.aead.Seal(nonce, nonce, plaintext, []byte(d.ID))
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, sealed, 0600); err != nil {
+		return fmt.Errorf("failed to write draft %s: %w", d.ID, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace draft %s: %w", d.ID, err)
+	}
+	return nil
+}
+
+func (ds *DraftStore) readLocked(id string) (*Draft, error) {
+	path, err := ds.pathFor(id)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrDraftNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read draft %s: %w", id, err)
+	}
+	nonceSize := ds.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, fmt.Errorf("draft %s is truncated", id)
+	}
+	plaintext, err := ds.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt draft %s (wrong wallet or corrupted file): %w", id, err)
+	}
+	var d Draft
+	if err := json.Unmarshal(plaintext, &d); err != nil {
+		return nil, fmt.Errorf("failed to decode draft %s: %w", id, err)
+	}
+	return &d, nil
+}
+
+// PublishDraft publishes a stored draft as a post (see CreatePost) and removes
+// it from the store once the signed transaction has been created. The draft is
+// kept if publishing fails. If only the removal fails, the transaction is still
+// returned alongside the error so it is not lost.
+func (pm *PostManager) PublishDraft(wallet *identity.Wallet, drafts *DraftStore, id string) (*ledger.Transaction, error) {
+	if drafts == nil {
+		return nil, fmt.Errorf("draft store cannot be nil")
+	}
+	d, err := drafts.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := pm.CreatePost(wallet, d.Body, d.Title, d.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish draft %s: %w", id, err)
+	}
+	if err := drafts.Delete(id); err != nil {
+		return tx, fmt.Errorf("draft %s was published but could not be removed: %w", id, err)
+	}
+	return tx, nil
+}
@@ -0,0 +1,116 @@
+package social
+
+import (
+	"bytes"
+	"digisocialblock/core/content"
+	"digisocialblock/core/identity"
+	"digisocialblock/internal/testutil"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDraftStore_CRUD(t *testing.T) {
+	wallet, _ := identity.NewWallet()
+	defer wallet.Close()
+	dir := t.TempDir()
+	store, err := NewDraftStore(dir, wallet)
+	if err != nil {
+		t.Fatalf("NewDraftStore() error = %v", err)
+	}
+
+	d, err := store.Create("Title", "secret body", []string{"a"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	got, err := store.Get(d.ID)
+	if err != nil || !reflect.DeepEqual(got, d) {
+		t.Fatalf("Get() = %+v, %v; want %+v", got, err, d)
+	}
+
+	// The file on disk must not contain the plaintext.
+	raw, _ := os.ReadFile(filepath.Join(dir, d.ID+draftFileExt))
+	if bytes.Contains(raw, []byte("secret body")) {
+		t.Error("draft file contains plaintext body")
+	}
+
+	updated, err := store.Update(d.ID, "New title", "new body", nil)
+	if err != nil || updated.Body != "new body" || !updated.CreatedAt.Equal(d.CreatedAt) {
+		t.Fatalf("Update() = %+v, %v", updated, err)
+	}
+	second, _ := store.Create("", "second", nil)
+	list, err := store.List()
+	if err != nil || len(list) != 2 || list[0].ID != second.ID {
+		t.Fatalf("List() = %d drafts, %v; want 2 with the newest first", len(list), err)
+	}
+
+	if err := store.Delete(d.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(d.ID); !errors.Is(err, ErrDraftNotFound) {
+		t.Errorf("Get() after Delete error = %v, want ErrDraftNotFound", err)
+	}
+	if err := store.Delete(d.ID); !errors.Is(err, ErrDraftNotFound) {
+		t.Errorf("second Delete() error = %v, want ErrDraftNotFound", err)
+	}
+	if _, err := store.Get("../../etc/passwd"); !errors.Is(err, ErrDraftNotFound) {
+		t.Errorf("Get(path traversal) error = %v, want ErrDraftNotFound", err)
+	}
+}
+
+func TestDraftStore_OtherWalletAndSwappedFilesFail(t *testing.T) {
+	owner, _ := identity.NewWallet()
+	defer owner.Close()
+	intruder, _ := identity.NewWallet()
+	defer intruder.Close()
+	dir := t.TempDir()
+
+	store, _ := NewDraftStore(dir, owner)
+	a, _ := store.Create("", "draft a", nil)
+	b, _ := store.Create("", "draft b", nil)
+
+	other, _ := NewDraftStore(dir, intruder)
+	if _, err := other.Get(a.ID); err == nil {
+		t.Error("Get() with another wallet's key should fail")
+	}
+
+	// Copying b's ciphertext over a must not decrypt as a.
+	raw, _ := os.ReadFile(filepath.Join(dir, b.ID+draftFileExt))
+	os.WriteFile(filepath.Join(dir, a.ID+draftFileExt), raw, 0600)
+	if _, err := store.Get(a.ID); err == nil {
+		t.Error("Get() of a swapped draft file should fail")
+	}
+}
+
+func TestPostManager_PublishDraft(t *testing.T) {
+	wallet, _ := identity.NewWallet()
+	defer wallet.Close()
+	store, _ := NewDraftStore(t.TempDir(), wallet)
+	dds := testutil.NewDDS(0)
+	publisher, _ := content.NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	pm, _ := NewPostManager(publisher)
+
+	empty, _ := store.Create("Empty", "", nil)
+	if _, err := pm.PublishDraft(wallet, store, empty.ID); err == nil {
+		t.Fatal("PublishDraft() of an empty draft should fail")
+	}
+	if _, err := store.Get(empty.ID); err != nil {
+		t.Errorf("failed publish should keep the draft, Get() error = %v", err)
+	}
+
+	d, _ := store.Create("Hello", "draft body", []string{"t"})
+	tx, err := pm.PublishDraft(wallet, store, d.ID)
+	if err != nil {
+		t.Fatalf("PublishDraft() error = %v", err)
+	}
+	want, _ := testutil.Chunk([]byte("draft body"), 0)
+	post, err := PostFromJSON(tx.Payload)
+	if err != nil || post.Title != "Hello" || post.ContentCID != want.ManifestCID {
+		t.Errorf("published post = %+v, %v", post, err)
+	}
+	if _, err := store.Get(d.ID); !errors.Is(err, ErrDraftNotFound) {
+		t.Errorf("published draft should be removed, Get() error = %v", err)
+	}
+}
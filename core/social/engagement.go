@@ -0,0 +1,351 @@
+package social
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+func init() {
+	ledger.RegisterPayloadValidator(ledger.EngagementReport, ValidateEngagementReportPayload)
+	ledger.RegisterPayloadDecoder(ledger.EngagementReport, ledger.DecodeInto(func() interface{} { return &EngagementReport{} }))
+}
+
+// Limits on engagement reporting.
+const (
+	MaxEngagementReportPosts = 128
+	// MinEngagementInterval is the shortest window a report may cover. Indexes
+	// ignore reports for shorter windows, so a node cannot report more often.
+	MinEngagementInterval = time.Hour
+	// DefaultEngagementGranularity is the step view counts are reported in.
+	DefaultEngagementGranularity = 5
+)
+
+// Errors returned by EngagementAggregator.Report when there is nothing to report yet.
+var (
+	ErrEngagementReportNotDue = errors.New("engagement report window has not ended")
+	ErrNoEngagementToReport   = errors.New("no engagement to report")
+)
+
+// PostViews is the number of views of one post in an EngagementReport.
+type PostViews struct {
+	PostTxID string `json:"postTxId"`
+	Views    int64  `json:"views"`
+}
+
+// EngagementReport is the EngagementReport payload: the views a node served
+// for each post during a window. Views are counted anonymously, without who
+// viewed what, and rounded down to the reporter's granularity, so a report
+// does not reveal individual views (see EngagementAggregator).
+type EngagementReport struct {
+	ReporterPublicKey string      `json:"reporterPublicKey"`
+	WindowStart       int64       `json:"windowStart"` // UnixNano
+	WindowEnd         int64       `json:"windowEnd"`   // UnixNano
+	Posts             []PostViews `json:"posts"`       // Sorted by PostTxID
+}
+
+// Validate checks that required fields are set and within limits.
+func (r *EngagementReport) Validate() error {
+	if r.ReporterPublicKey == "" {
+		return fmt.Errorf("empty ReporterPublicKey")
+	}
+	if r.WindowStart <= 0 || r.WindowEnd <= r.WindowStart {
+		return fmt.Errorf("window [%d, %d) is empty or not positive", r.WindowStart, r.WindowEnd)
+	}
+	if len(r.Posts) == 0 || len(r.Posts) > MaxEngagementReportPosts {
+		return fmt.Errorf("report has %d posts, want 1 to %d", len(r.Posts), MaxEngagementReportPosts)
+	}
+	for i, p := range r.Posts {
+		if p.PostTxID == "" || len(p.PostTxID) > MaxTxIDLength {
+			return fmt.Errorf("post %d: PostTxID is %d bytes, want 1 to %d", i, len(p.PostTxID), MaxTxIDLength)
+		}
+		if i > 0 && p.PostTxID <= r.Posts[i-1].PostTxID {
+			return fmt.Errorf("post %d: posts are not sorted by PostTxID without duplicates", i)
+		}
+		if p.Views <= 0 {
+			return fmt.Errorf("post %d: views must be positive", i)
+		}
+	}
+	return nil
+}
+
+// ToPayload serializes the EngagementReport as an EngagementReport
+// transaction payload in the given format.
+func (r *EngagementReport) ToPayload(format ledger.PayloadFormat) ([]byte, error) {
+	payload, err := ledger.EncodePayload(format, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode engagement report payload: %w", err)
+	}
+	return payload, nil
+}
+
+// EngagementReportFromPayload deserializes an EngagementReport payload in
+// either payload format. The result must pass Validate.
+func EngagementReportFromPayload(payload []byte) (*EngagementReport, error) {
+	var r EngagementReport
+	if err := ledger.DecodePayload(payload, &r); err != nil {
+		return nil, fmt.Errorf("failed to decode engagement report payload: %w", err)
+	}
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("decoded engagement report is invalid: %w", err)
+	}
+	return &r, nil
+}
+
+// ValidateEngagementReportPayload is the ledger schema validator for EngagementReport payloads.
+func ValidateEngagementReportPayload(payload []byte) error {
+	_, err := EngagementReportFromPayload(payload)
+	return err
+}
+
+// EngagementAggregator counts post views on a node and batches them into
+// EngagementReport transactions, at most one per interval.
+//
+// Only per-post totals are kept. Counts are reported in multiples of the
+// granularity and the remainder is carried into the next window, so every
+// reported number stands for several views; posts with fewer views than the
+// granularity are not reported until they have more. When more posts are due
+// than fit in a report, the most viewed are reported and the rest carried.
+//
+// An EngagementAggregator is safe for concurrent use.
+type EngagementAggregator struct {
+	mu          sync.Mutex
+	clock       ledger.Clock
+	interval    time.Duration
+	granularity int64
+	windowStart time.Time
+	views       map[string]int64 // Post tx ID -> views not yet reported
+}
+
+// NewEngagementAggregator creates an EngagementAggregator reporting every
+// interval in steps of granularity views. The interval must be at least
+// MinEngagementInterval; a granularity <= 0 means
+// DefaultEngagementGranularity.
+func NewEngagementAggregator(interval time.Duration, granularity int) (*EngagementAggregator, error) {
+	if interval < MinEngagementInterval {
+		return nil, fmt.Errorf("engagement interval %s is shorter than %s", interval, MinEngagementInterval)
+	}
+	if granularity <= 0 {
+		granularity = DefaultEngagementGranularity
+	}
+	return &EngagementAggregator{
+		clock:       ledger.SystemClock,
+		interval:    interval,
+		granularity: int64(granularity),
+		windowStart: ledger.SystemClock.Now(),
+		views:       make(map[string]int64),
+	}, nil
+}
+
+// SetClock sets the clock that times report windows and stamps report
+// transactions, and starts a new window. A nil clock restores
+// ledger.SystemClock.
+func (a *EngagementAggregator) SetClock(clock ledger.Clock) {
+	if clock == nil {
+		clock = ledger.SystemClock
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.clock = clock
+	a.windowStart = clock.Now()
+}
+
+// RecordView counts one view of the post created by postTxID.
+func (a *EngagementAggregator) RecordView(postTxID string) {
+	if postTxID == "" || len(postTxID) > MaxTxIDLength {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.views[postTxID]++
+}
+
+// Report returns a signed EngagementReport transaction for the window that
+// has just ended and starts the next one. It returns
+// ErrEngagementReportNotDue before the interval has passed and
+// ErrNoEngagementToReport if no post has reached the granularity; in both
+// cases the counts are kept. The caller submits the transaction.
+func (a *EngagementAggregator) Report(wallet *identity.Wallet) (*ledger.Transaction, error) {
+	if wallet == nil {
+		return nil, fmt.Errorf("wallet cannot be nil")
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.clock.Now()
+	if now.Sub(a.windowStart) < a.interval {
+		return nil, ErrEngagementReportNotDue
+	}
+	var due []PostViews
+	for postTxID, views := range a.views {
+		if reported := views - views%a.granularity; reported > 0 {
+			due = append(due, PostViews{PostTxID: postTxID, Views: reported})
+		}
+	}
+	if len(due) == 0 {
+		return nil, ErrNoEngagementToReport
+	}
+	if len(due) > MaxEngagementReportPosts {
+		sort.Slice(due, func(i, j int) bool {
+			if due[i].Views != due[j].Views {
+				return due[i].Views > due[j].Views
+			}
+			return due[i].PostTxID < due[j].PostTxID
+		})
+		due = due[:MaxEngagementReportPosts]
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].PostTxID < due[j].PostTxID })
+
+	report := &EngagementReport{
+		ReporterPublicKey: wallet.Address,
+		WindowStart:       a.windowStart.UnixNano(),
+		WindowEnd:         now.UnixNano(),
+		Posts:             due,
+	}
+	payload, err := report.ToPayload(ledger.PayloadFormatCBOR)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := ledger.NewTransactionWithClock(a.clock, wallet.Address, ledger.EngagementReport, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create engagement report transaction: %w", err)
+	}
+	if err := wallet.SignTransaction(tx); err != nil {
+		return nil, fmt.Errorf("failed to sign engagement report transaction: %w", err)
+	}
+
+	for _, p := range due {
+		if a.views[p.PostTxID] -= p.Views; a.views[p.PostTxID] == 0 {
+			delete(a.views, p.PostTxID)
+		}
+	}
+	a.windowStart = now
+	return tx, nil
+}
+
+// PostEngagement is the approximate engagement with a post, summed over the
+// reports in the chain.
+type PostEngagement struct {
+	Views     int64 `json:"views"`
+	Reporters int   `json:"reporters"` // Nodes that reported views of the post
+}
+
+// EngagementIndex totals the EngagementReport transactions in the chain per
+// post. It is kept in memory and updated incrementally like the graph index.
+//
+// A report is counted only if its reporter is the transaction sender, its
+// window is at least MinEngagementInterval long and it starts no earlier than
+// the end of the sender's previous counted report. Each node can therefore
+// add views for any stretch of time only once, and at most once per interval.
+//
+// An EngagementIndex is safe for concurrent use.
+type EngagementIndex struct {
+	mu        sync.RWMutex
+	follower  ledger.ChainFollower
+	posts     map[string]PostEngagement
+	reporters map[[2]string]bool // (post tx ID, reporter) pairs already counted
+	limiter   engagementLimiter
+}
+
+// ErrEngagementIndexDiverged is returned by EngagementIndex.Sync when the
+// block recorded as processed last is no longer on the chain. Call Rebuild to
+// recover.
+var ErrEngagementIndexDiverged = errors.New("engagement index high-water mark does not match the chain")
+
+// NewEngagementIndex creates an empty EngagementIndex. Call Sync to populate it.
+func NewEngagementIndex() *EngagementIndex {
+	e := &EngagementIndex{}
+	e.reset()
+	return e
+}
+
+// reset clears the index. The caller must hold e.mu (or own e exclusively).
+func (e *EngagementIndex) reset() {
+	e.follower = ledger.NewChainFollower()
+	e.posts = make(map[string]PostEngagement)
+	e.reporters = make(map[[2]string]bool)
+	e.limiter = make(engagementLimiter)
+}
+
+// HighWaterMark reports the last block whose engagement reports have been
+// counted.
+func (e *EngagementIndex) HighWaterMark() (int64, string) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.follower.HighWaterMark()
+}
+
+// Sync counts the reports in blocks added since the last call and returns
+// how many blocks it read, or ErrEngagementIndexDiverged if the chain was
+// replaced under the index.
+func (e *EngagementIndex) Sync(bc *ledger.Blockchain) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.follower.Sync(bc, "engagement index", ErrEngagementIndexDiverged, e.indexBlock)
+}
+
+// Rebuild drops every counter and recounts the whole chain.
+func (e *EngagementIndex) Rebuild(bc *ledger.Blockchain) (int, error) {
+	if bc == nil {
+		return 0, fmt.Errorf("blockchain cannot be nil")
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.reset()
+	return e.follower.Sync(bc, "engagement index", ErrEngagementIndexDiverged, e.indexBlock)
+}
+
+// indexBlock adds the block's counted engagement reports to the totals.
+func (e *EngagementIndex) indexBlock(block *ledger.Block) {
+	for _, tx := range block.Transactions {
+		if tx == nil {
+			continue
+		}
+		r, ok := e.limiter.accept(tx)
+		if !ok {
+			continue
+		}
+		for _, p := range r.Posts {
+			total := e.posts[p.PostTxID]
+			total.Views += p.Views
+			if pair := [2]string{p.PostTxID, r.ReporterPublicKey}; !e.reporters[pair] {
+				e.reporters[pair] = true
+				total.Reporters++
+			}
+			e.posts[p.PostTxID] = total
+		}
+	}
+}
+
+// engagementLimiter applies the EngagementIndex rules for which reports
+// count, tracking the end of each reporter's last counted window.
+type engagementLimiter map[string]int64
+
+// accept returns the report carried by tx and records its window if tx is an
+// EngagementReport that counts.
+func (l engagementLimiter) accept(tx *ledger.Transaction) (*EngagementReport, bool) {
+	if tx.Type != ledger.EngagementReport {
+		return nil, false
+	}
+	r, err := EngagementReportFromPayload(tx.Payload)
+	if err != nil || r.ReporterPublicKey != tx.SenderPublicKey {
+		return nil, false
+	}
+	if time.Duration(r.WindowEnd-r.WindowStart) < MinEngagementInterval || r.WindowStart < l[r.ReporterPublicKey] {
+		return nil, false
+	}
+	l[r.ReporterPublicKey] = r.WindowEnd
+	return r, true
+}
+
+// GetEngagement returns the engagement reported for the post created by
+// postTxID; the zero value if none has been.
+func (e *EngagementIndex) GetEngagement(postTxID string) PostEngagement {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.posts[postTxID]
+}
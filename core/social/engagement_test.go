@@ -0,0 +1,132 @@
+package social
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func engagementTestReport(t *testing.T, wallet *identity.Wallet, reporter string, start, end time.Duration, posts ...PostViews) *ledger.Transaction {
+	t.Helper()
+	r := &EngagementReport{ReporterPublicKey: reporter, WindowStart: int64(time.Hour + start), WindowEnd: int64(time.Hour + end), Posts: posts}
+	payload, err := r.ToPayload(ledger.PayloadFormatCBOR)
+	if err != nil {
+		t.Fatalf("ToPayload() error = %v", err)
+	}
+	return graphTestTx(t, wallet, ledger.EngagementReport, payload)
+}
+
+func TestEngagementReport_PayloadValidation(t *testing.T) {
+	valid := EngagementReport{ReporterPublicKey: "node", WindowStart: 1, WindowEnd: 2, Posts: []PostViews{{"a", 5}, {"b", 10}}}
+	payload, _ := valid.ToPayload(ledger.PayloadFormatJSON)
+	if got, err := EngagementReportFromPayload(payload); err != nil || !reflect.DeepEqual(*got, valid) {
+		t.Errorf("EngagementReportFromPayload(ToPayload()) = %+v, %v; want %+v", got, err, valid)
+	}
+
+	tooMany := valid
+	tooMany.Posts = nil
+	for i := 0; i <= MaxEngagementReportPosts; i++ {
+		tooMany.Posts = append(tooMany.Posts, PostViews{fmt.Sprintf("post-%03d", i), 5})
+	}
+	for name, r := range map[string]EngagementReport{
+		"no reporter":  {WindowStart: 1, WindowEnd: 2, Posts: valid.Posts},
+		"empty window": {ReporterPublicKey: "node", WindowStart: 2, WindowEnd: 2, Posts: valid.Posts},
+		"no posts":     {ReporterPublicKey: "node", WindowStart: 1, WindowEnd: 2},
+		"too many":     tooMany,
+		"unsorted":     {ReporterPublicKey: "node", WindowStart: 1, WindowEnd: 2, Posts: []PostViews{{"b", 5}, {"a", 5}}},
+		"duplicate":    {ReporterPublicKey: "node", WindowStart: 1, WindowEnd: 2, Posts: []PostViews{{"a", 5}, {"a", 5}}},
+		"zero views":   {ReporterPublicKey: "node", WindowStart: 1, WindowEnd: 2, Posts: []PostViews{{"a", 0}}},
+	} {
+		if err := r.Validate(); err == nil {
+			t.Errorf("%s: Validate() expected error, got nil", name)
+		}
+	}
+}
+
+func TestEngagementAggregator_ReportsRoundedBatchesOncePerInterval(t *testing.T) {
+	if _, err := NewEngagementAggregator(time.Minute, 0); err == nil {
+		t.Error("NewEngagementAggregator() with an interval below the minimum: expected error, got nil")
+	}
+	wallet, _ := identity.NewWallet()
+	clock := testutil.NewClock(0)
+	a, _ := NewEngagementAggregator(time.Hour, 5)
+	a.SetClock(clock)
+	for post, views := range map[string]int{"a": 12, "b": 3, "c": 5} {
+		for i := 0; i < views; i++ {
+			a.RecordView(post)
+		}
+	}
+
+	if _, err := a.Report(wallet); !errors.Is(err, ErrEngagementReportNotDue) {
+		t.Fatalf("Report() before the interval error = %v, want ErrEngagementReportNotDue", err)
+	}
+	clock.Advance(time.Hour)
+	tx, err := a.Report(wallet)
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if err := tx.IsValid(); err != nil {
+		t.Fatalf("report transaction is invalid: %v", err)
+	}
+	r, _ := EngagementReportFromPayload(tx.Payload)
+	if want := []PostViews{{"a", 10}, {"c", 5}}; !reflect.DeepEqual(r.Posts, want) || r.WindowEnd-r.WindowStart != int64(time.Hour) {
+		t.Errorf("report = %+v, want %v over one hour", r, want)
+	}
+	if _, err := a.Report(wallet); !errors.Is(err, ErrEngagementReportNotDue) {
+		t.Errorf("second Report() in the same interval error = %v, want ErrEngagementReportNotDue", err)
+	}
+
+	// Remainders carry over: b reaches the granularity, a's 2 views do not.
+	a.RecordView("b")
+	a.RecordView("b")
+	clock.Advance(time.Hour)
+	tx, err = a.Report(wallet)
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if r, _ := EngagementReportFromPayload(tx.Payload); !reflect.DeepEqual(r.Posts, []PostViews{{"b", 5}}) {
+		t.Errorf("second report = %+v, want b's carried views", r.Posts)
+	}
+	clock.Advance(time.Hour)
+	if _, err := a.Report(wallet); !errors.Is(err, ErrNoEngagementToReport) {
+		t.Errorf("Report() with only remainders error = %v, want ErrNoEngagementToReport", err)
+	}
+}
+
+func TestEngagementIndex_TotalsRateLimitedReports(t *testing.T) {
+	node1, _ := identity.NewWallet()
+	node2, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+	bc.AddBlock([]*ledger.Transaction{
+		engagementTestReport(t, node1, node1.Address, 0, time.Hour, PostViews{"a", 10}, PostViews{"b", 5}),
+		engagementTestReport(t, node1, node1.Address, 30*time.Minute, 2*time.Hour, PostViews{"a", 100}),      // Overlaps the previous window.
+		engagementTestReport(t, node1, node1.Address, time.Hour, time.Hour+time.Minute, PostViews{"a", 100}), // Too short.
+		engagementTestReport(t, node2, node1.Address, 5*time.Hour, 6*time.Hour, PostViews{"a", 100}),         // Forged reporter.
+		engagementTestReport(t, node2, node2.Address, 0, time.Hour, PostViews{"a", 5}),
+	})
+	bc.AddBlock([]*ledger.Transaction{
+		engagementTestReport(t, node1, node1.Address, time.Hour, 2*time.Hour, PostViews{"a", 20}),
+	})
+	e := NewEngagementIndex()
+	if _, err := e.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if got, want := e.GetEngagement("a"), (PostEngagement{Views: 35, Reporters: 2}); got != want {
+		t.Errorf("GetEngagement(a) = %+v, want %+v", got, want)
+	}
+	if got, want := e.GetEngagement("b"), (PostEngagement{Views: 5, Reporters: 1}); got != want {
+		t.Errorf("GetEngagement(b) = %+v, want %+v", got, want)
+	}
+	if got := e.GetEngagement("unknown"); got != (PostEngagement{}) {
+		t.Errorf("GetEngagement(unknown) = %+v, want zero", got)
+	}
+
+	if _, err := e.Rebuild(bc); err != nil || e.GetEngagement("a").Views != 35 {
+		t.Errorf("Rebuild() = %v, views of a %d; want the same totals", err, e.GetEngagement("a").Views)
+	}
+}
@@ -0,0 +1,348 @@
+package social
+
+import (
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/migrations"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// ErrFeedIndexDiverged is returned by FeedService.Sync when the persisted
+// high-water mark no longer matches the chain (e.g. the index file is stale or
+// corrupted). Call Rebuild to recover.
+var ErrFeedIndexDiverged = errors.New("feed index high-water mark does not match the chain")
+
+// FeedEntry is a single post recorded in the feed index.
+type FeedEntry struct {
+	TxID            string   `json:"txId"`
+	BlockIndex      int64    `json:"blockIndex"`
+	AuthorPublicKey string   `json:"authorPublicKey"`
+	ContentCID      string   `json:"contentCID"`
+	Timestamp       int64    `json:"timestamp"`
+	Title           string   `json:"title,omitempty"`
+	Tags            []string `json:"tags,omitempty"`
+	GroupID         string   `json:"groupId,omitempty"`   // Set for group-only posts
+	CoAuthors       []string `json:"coAuthors,omitempty"` // Co-authors who co-signed the post
+	Media           []string `json:"media,omitempty"`
+	ContentWarning  string   `json:"contentWarning,omitempty"`
+	Sensitive       bool     `json:"sensitive,omitempty"`
+	Language        string   `json:"language,omitempty"` // BCP 47 tag, if the post has one
+}
+
+// clone returns a copy of e that shares no memory with the index.
+func (e FeedEntry) clone() FeedEntry {
+	if e.Tags != nil {
+		e.Tags = append([]string{}, e.Tags...)
+	}
+	if e.CoAuthors != nil {
+		e.CoAuthors = append([]string{}, e.CoAuthors...)
+	}
+	if e.Media != nil {
+		e.Media = append([]string{}, e.Media...)
+	}
+	return e
+}
+
+// Authors returns the post's author followed by its co-authors. A co-authored
+// post is attributed to, and appears in the feeds of, all of them.
+func (e FeedEntry) Authors() []string {
+	return append([]string{e.AuthorPublicKey}, e.CoAuthors...)
+}
+
+// FeedIndexState is the persisted form of the feed index.
+// HighWaterIndex/HighWaterHash identify the last block that has been processed;
+// HighWaterIndex is -1 for an index that has not processed any block yet.
+type FeedIndexState struct {
+	HighWaterIndex int64       `json:"highWaterIndex"`
+	HighWaterHash  string      `json:"highWaterHash"`
+	Entries        []FeedEntry `json:"entries"`
+}
+
+// FeedIndexStore persists FeedIndexState between restarts.
+type FeedIndexStore interface {
+	// LoadFeedIndex returns the saved state, or (nil, nil) if nothing has been saved yet.
+	LoadFeedIndex() (*FeedIndexState, error)
+	SaveFeedIndex(state *FeedIndexState) error
+}
+
+// FeedIndexSchema versions the FileFeedIndexStore file format. The index can
+// be rebuilt from the chain, so a migration may simply remove the file.
+var FeedIndexSchema = &migrations.Schema{Name: "social.feedindex", Current: 1}
+
+// FileFeedIndexStore is a FeedIndexStore backed by a single JSON file.
+type FileFeedIndexStore struct {
+	path string
+}
+
+// NewFileFeedIndexStore creates a FileFeedIndexStore writing to path. An
+// index file in an older format is migrated first (see FeedIndexSchema); one
+// in a newer format is refused with an error wrapping migrations.ErrNewerFormat.
+func NewFileFeedIndexStore(path string) (*FileFeedIndexStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("feed index path cannot be empty")
+	}
+	if err := migrations.Prepare(path, FeedIndexSchema); err != nil {
+		return nil, fmt.Errorf("failed to open feed index: %w", err)
+	}
+	return &FileFeedIndexStore{path: path}, nil
+}
+
+// LoadFeedIndex reads the feed index file. A missing file is not an error.
+func (fs *FileFeedIndexStore) LoadFeedIndex() (*FeedIndexState, error) {
+	data, err := os.ReadFile(fs.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed index %s: %w", fs.path, err)
+	}
+	var state FeedIndexState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode feed index %s: %w", fs.path, err)
+	}
+	return &state, nil
+}
+
+// SaveFeedIndex atomically replaces the feed index file.
+func (fs *FileFeedIndexStore) SaveFeedIndex(state *FeedIndexState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode feed index: %w", err)
+	}
+	tmp := fs.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write feed index %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, fs.path); err != nil {
+		return fmt.Errorf("failed to replace feed index %s: %w", fs.path, err)
+	}
+	return nil
+}
+
+// FeedService maintains an index of PostCreated transactions for building feeds.
+//
+// The index is updated incrementally: Sync only processes blocks above the
+// persisted high-water mark, so startup cost is proportional to the number of
+// new blocks rather than the length of the chain.
+//
+// A FeedService is safe for concurrent use, including Sync while the chain
+// grows. The entries it returns are copies the caller may modify.
+type FeedService struct {
+	mu       sync.RWMutex
+	store    FeedIndexStore // Optional; nil keeps the index in memory only
+	state    FeedIndexState
+	byAuthor map[string][]int // Author -> positions in state.Entries, in chain order
+	byTxID   map[string]int   // Post tx ID -> position in state.Entries
+	byCID    map[string][]int // Content or media CID -> positions in state.Entries
+}
+
+// NewFeedService creates a FeedService, restoring any state saved in store.
+// store may be nil for an in-memory index.
+func NewFeedService(store FeedIndexStore) (*FeedService, error) {
+	fs := &FeedService{store: store}
+	fs.reset()
+	if store == nil {
+		return fs, nil
+	}
+	saved, err := store.LoadFeedIndex()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load feed index: %w", err)
+	}
+	if saved != nil {
+		fs.state = *saved
+		for i := range fs.state.Entries {
+			fs.addToIndexes(i)
+		}
+	}
+	return fs, nil
+}
+
+// reset clears the in-memory index. The caller must hold fs.mu (or own fs exclusively).
+func (fs *FeedService) reset() {
+	fs.state = FeedIndexState{HighWaterIndex: -1}
+	fs.byAuthor = make(map[string][]int)
+	fs.byTxID = make(map[string]int)
+	fs.byCID = make(map[string][]int)
+}
+
+// addToIndexes indexes the entry at position i of state.Entries. The caller
+// must hold fs.mu (or own fs exclusively).
+func (fs *FeedService) addToIndexes(i int) {
+	entry := fs.state.Entries[i]
+	for _, author := range entry.Authors() {
+		fs.byAuthor[author] = append(fs.byAuthor[author], i)
+	}
+	fs.byTxID[entry.TxID] = i
+	for _, cid := range append([]string{entry.ContentCID}, entry.Media...) {
+		if cid != "" {
+			fs.byCID[cid] = append(fs.byCID[cid], i)
+		}
+	}
+}
+
+// HighWaterMark returns the index and hash of the last processed block.
+// The index is -1 if no block has been processed.
+func (fs *FeedService) HighWaterMark() (int64, string) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	return fs.state.HighWaterIndex, fs.state.HighWaterHash
+}
+
+// Sync processes every block above the high-water mark and persists the result.
+// It returns the number of blocks processed. ErrFeedIndexDiverged is returned
+// if the block at the high-water mark no longer matches the recorded hash.
+func (fs *FeedService) Sync(bc *ledger.Blockchain) (int, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.follow(bc)
+}
+
+// Rebuild discards the index and reprocesses the whole chain.
+// Use it to recover from ErrFeedIndexDiverged or a corrupted index file.
+func (fs *FeedService) Rebuild(bc *ledger.Blockchain) (int, error) {
+	if bc == nil {
+		return 0, fmt.Errorf("blockchain cannot be nil")
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.reset()
+	return fs.follow(bc)
+}
+
+// follow indexes the blocks above the high-water mark recorded in fs.state
+// and saves the new state. The caller must hold fs.mu.
+func (fs *FeedService) follow(bc *ledger.Blockchain) (int, error) {
+	follower := ledger.ChainFollower{HighWaterIndex: fs.state.HighWaterIndex, HighWaterHash: fs.state.HighWaterHash}
+	processed, err := follower.Sync(bc, "feed index", ErrFeedIndexDiverged, fs.indexBlock)
+	fs.state.HighWaterIndex, fs.state.HighWaterHash = follower.HighWaterMark()
+	if err != nil {
+		return processed, err
+	}
+	if processed > 0 && fs.store != nil {
+		if err := fs.store.SaveFeedIndex(&fs.state); err != nil {
+			return processed, fmt.Errorf("failed to persist feed index: %w", err)
+		}
+	}
+	return processed, nil
+}
+
+// indexBlock adds the block's PostCreated transactions to the index.
+// Payloads that are not valid Post metadata, or that name an author other
+// than the transaction's sender, are skipped.
+func (fs *FeedService) indexBlock(block *ledger.Block) {
+	for _, tx := range block.Transactions {
+		if tx == nil || tx.Type != ledger.PostCreated {
+			continue
+		}
+		post, err := PostFromPayload(tx.Payload)
+		if err != nil || post.AuthorPublicKey != tx.SenderPublicKey {
+			continue
+		}
+		entry := FeedEntry{
+			TxID:            tx.ID,
+			BlockIndex:      block.Index,
+			AuthorPublicKey: post.AuthorPublicKey,
+			ContentCID:      post.ContentCID,
+			Timestamp:       post.Timestamp,
+			Title:           post.Title,
+			Tags:            post.Tags,
+			GroupID:         post.GroupID,
+			CoAuthors:       post.CoAuthors,
+			Media:           post.Media,
+			ContentWarning:  post.ContentWarning,
+			Sensitive:       post.Sensitive,
+			Language:        post.Language,
+		}
+		fs.state.Entries = append(fs.state.Entries, entry)
+		fs.addToIndexes(len(fs.state.Entries) - 1)
+	}
+}
+
+// GetPost returns the post created by transaction txID.
+func (fs *FeedService) GetPost(txID string) (FeedEntry, bool) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	i, ok := fs.byTxID[txID]
+	if !ok {
+		return FeedEntry{}, false
+	}
+	return fs.state.Entries[i].clone(), true
+}
+
+// GetUserFeed returns up to limit posts by the given author, including posts
+// they co-authored, newest first. A limit <= 0 returns all posts.
+func (fs *FeedService) GetUserFeed(authorPublicKey string, limit int) []FeedEntry {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	positions := fs.byAuthor[authorPublicKey]
+	feed := make([]FeedEntry, 0, len(positions))
+	for i := len(positions) - 1; i >= 0; i-- {
+		if limit > 0 && len(feed) == limit {
+			break
+		}
+		feed = append(feed, fs.state.Entries[positions[i]].clone())
+	}
+	return feed
+}
+
+// GetAuthorsFeed returns up to limit posts by any of the given authors,
+// newest first as in GetGlobalFeed. A limit <= 0 returns all posts.
+func (fs *FeedService) GetAuthorsFeed(authors []string, limit int) []FeedEntry {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	var positions []int
+	included := make(map[int]bool)
+	for _, author := range authors {
+		for _, i := range fs.byAuthor[author] {
+			// Skips repeated authors and posts co-authored by several of them.
+			if !included[i] {
+				included[i] = true
+				positions = append(positions, i)
+			}
+		}
+	}
+	sort.Ints(positions) // Chain order, so ties sort as in GetGlobalFeed
+	feed := make([]FeedEntry, 0, len(positions))
+	for _, i := range positions {
+		feed = append(feed, fs.state.Entries[i])
+	}
+	sort.SliceStable(feed, func(i, j int) bool {
+		if feed[i].BlockIndex != feed[j].BlockIndex {
+			return feed[i].BlockIndex > feed[j].BlockIndex
+		}
+		return feed[i].Timestamp > feed[j].Timestamp
+	})
+	if limit > 0 && len(feed) > limit {
+		feed = feed[:limit]
+	}
+	for i := range feed {
+		feed[i] = feed[i].clone()
+	}
+	return feed
+}
+
+// GetGlobalFeed returns up to limit posts from all authors, newest first.
+// A limit <= 0 returns all posts.
+func (fs *FeedService) GetGlobalFeed(limit int) []FeedEntry {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	feed := make([]FeedEntry, 0, len(fs.state.Entries))
+	feed = append(feed, fs.state.Entries...)
+	sort.SliceStable(feed, func(i, j int) bool {
+		if feed[i].BlockIndex != feed[j].BlockIndex {
+			return feed[i].BlockIndex > feed[j].BlockIndex
+		}
+		return feed[i].Timestamp > feed[j].Timestamp
+	})
+	if limit > 0 && len(feed) > limit {
+		feed = feed[:limit]
+	}
+	for i := range feed {
+		feed[i] = feed[i].clone()
+	}
+	return feed
+}
@@ -0,0 +1,298 @@
+package social
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/migrations"
+	"digisocialblock/internal/testutil"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newSignedPostTx builds a signed PostCreated transaction carrying Post metadata.
+func newSignedPostTx(t *testing.T, wallet *identity.Wallet, contentCID, title string) *ledger.Transaction {
+	t.Helper()
+	payload, err := NewPost(wallet.Address, contentCID, title, nil).ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	tx, err := ledger.NewTransaction(wallet.Address, ledger.PostCreated, payload)
+	if err != nil {
+		t.Fatalf("NewTransaction() error = %v", err)
+	}
+	if err := wallet.SignTransaction(tx); err != nil {
+		t.Fatalf("SignTransaction() error = %v", err)
+	}
+	return tx
+}
+
+func TestFeedService_IncrementalSyncAcrossRestarts(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	bob, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+	store, _ := NewFileFeedIndexStore(filepath.Join(t.TempDir(), "feed.json"))
+
+	bc.AddBlock([]*ledger.Transaction{newSignedPostTx(t, alice, "cid-a1", "first")})
+	bc.AddBlock([]*ledger.Transaction{newSignedPostTx(t, bob, "cid-b1", "hello")})
+
+	fs, err := NewFeedService(store)
+	if err != nil {
+		t.Fatalf("NewFeedService() error = %v", err)
+	}
+	if n, err := fs.Sync(bc); err != nil || n != 3 {
+		t.Fatalf("initial Sync() = %d, %v; want 3 blocks (genesis + 2)", n, err)
+	}
+
+	// Simulate a restart with new blocks produced in the meantime.
+	bc.AddBlock([]*ledger.Transaction{newSignedPostTx(t, alice, "cid-a2", "second")})
+	restarted, err := NewFeedService(store)
+	if err != nil {
+		t.Fatalf("NewFeedService() after restart error = %v", err)
+	}
+	if hwm, _ := restarted.HighWaterMark(); hwm != 2 {
+		t.Fatalf("restored high-water mark = %d, want 2", hwm)
+	}
+	if n, err := restarted.Sync(bc); err != nil || n != 1 {
+		t.Fatalf("Sync() after restart = %d, %v; want only the 1 new block", n, err)
+	}
+
+	aliceFeed := restarted.GetUserFeed(alice.Address, 0)
+	if len(aliceFeed) != 2 || aliceFeed[0].ContentCID != "cid-a2" || aliceFeed[1].ContentCID != "cid-a1" {
+		t.Errorf("GetUserFeed(alice) = %+v, want cid-a2 then cid-a1", aliceFeed)
+	}
+	if global := restarted.GetGlobalFeed(2); len(global) != 2 || global[0].ContentCID != "cid-a2" {
+		t.Errorf("GetGlobalFeed(2) = %+v, want newest first", global)
+	}
+	// Posts restored from the store and synced since are both found by tx ID.
+	for _, entry := range aliceFeed {
+		if got, ok := restarted.GetPost(entry.TxID); !ok || got.ContentCID != entry.ContentCID {
+			t.Errorf("GetPost(%s) = %+v, %v; want %s", entry.TxID, got, ok, entry.ContentCID)
+		}
+	}
+	if _, ok := restarted.GetPost("missing"); ok {
+		t.Error("GetPost(missing) found a post")
+	}
+}
+
+func TestFeedService_DivergedIndexRequiresRebuild(t *testing.T) {
+	wallet, _ := identity.NewWallet()
+	store, _ := NewFileFeedIndexStore(filepath.Join(t.TempDir(), "feed.json"))
+
+	original, _ := ledger.NewBlockchain()
+	original.AddBlock([]*ledger.Transaction{newSignedPostTx(t, wallet, "cid-original", "")})
+	fs, _ := NewFeedService(store)
+	if _, err := fs.Sync(original); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	// A different chain of the same height no longer matches the high-water hash.
+	other, _ := ledger.NewBlockchain()
+	other.AddBlock([]*ledger.Transaction{newSignedPostTx(t, wallet, "cid-other", "")})
+	if _, err := fs.Sync(other); !errors.Is(err, ErrFeedIndexDiverged) {
+		t.Fatalf("Sync() on diverged chain error = %v, want ErrFeedIndexDiverged", err)
+	}
+
+	if _, err := fs.Rebuild(other); err != nil {
+		t.Fatalf("Rebuild() error = %v", err)
+	}
+	feed := fs.GetGlobalFeed(0)
+	if len(feed) != 1 || feed[0].ContentCID != "cid-other" {
+		t.Errorf("feed after Rebuild = %+v, want only cid-other", feed)
+	}
+}
+
+func TestFeedService_ConcurrentSyncAndQueries(t *testing.T) {
+	wallet, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+	bc.SetClock(testutil.NewClock(time.Millisecond))
+	fs, _ := NewFeedService(nil)
+
+	blocks := 40
+	if testing.Short() {
+		blocks = 10
+	}
+	txs := make([]*ledger.Transaction, blocks)
+	for i := range txs {
+		post := NewPost(wallet.Address, fmt.Sprintf("cid-%d", i), "", []string{"stress"})
+		payload, _ := post.ToJSON()
+		txs[i], _ = ledger.NewTransaction(wallet.Address, ledger.PostCreated, payload)
+		if err := wallet.SignTransaction(txs[i]); err != nil {
+			t.Fatalf("SignTransaction() error = %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	done, written := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(written)
+		for _, tx := range txs {
+			if _, err := bc.AddBlock([]*ledger.Transaction{tx}); err != nil {
+				t.Errorf("AddBlock() error = %v", err)
+				return
+			}
+		}
+	}()
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if r == 0 {
+					if _, err := fs.Sync(bc); err != nil {
+						t.Errorf("Sync() error = %v", err)
+						return
+					}
+					continue
+				}
+				// Callers own the entries they get back, Tags included.
+				for _, entry := range fs.GetUserFeed(wallet.Address, 5) {
+					entry.Tags[0] = "mutated"
+				}
+				for _, entry := range fs.GetGlobalFeed(5) {
+					if got, ok := fs.GetPost(entry.TxID); !ok || got.ContentCID != entry.ContentCID {
+						t.Errorf("GetPost(%s) = %+v, %v; want %s", entry.TxID, got, ok, entry.ContentCID)
+						return
+					}
+				}
+			}
+		}(r)
+	}
+	<-written
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if hwm, _ := fs.HighWaterMark(); hwm == bc.GetLatestBlock().Index {
+			break
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	feed := fs.GetUserFeed(wallet.Address, 0)
+	if len(feed) != blocks {
+		t.Fatalf("GetUserFeed() returned %d posts, want %d", len(feed), blocks)
+	}
+	for _, entry := range feed {
+		if len(entry.Tags) != 1 || entry.Tags[0] != "stress" {
+			t.Fatalf("entry %s has tags %v; the index was modified through a returned entry", entry.TxID, entry.Tags)
+		}
+	}
+}
+
+func TestNewFileFeedIndexStore_RefusesNewerFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feed.json")
+	if err := os.WriteFile(migrations.VersionPath(path), []byte(`{"schema":"social.feedindex","version":99}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileFeedIndexStore(path); !errors.Is(err, migrations.ErrNewerFormat) {
+		t.Errorf("NewFileFeedIndexStore() of a newer index error = %v, want ErrNewerFormat", err)
+	}
+}
+
+func TestFeedService_GetAuthorsFeed(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	bob, _ := identity.NewWallet()
+	carol, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+	a1, b1 := newSignedPostTx(t, alice, "cid-a1", ""), newSignedPostTx(t, bob, "cid-b1", "")
+	bc.AddBlock([]*ledger.Transaction{a1, b1, newSignedPostTx(t, carol, "cid-c1", "")})
+	a2 := newSignedPostTx(t, alice, "cid-a2", "")
+	bc.AddBlock([]*ledger.Transaction{a2})
+
+	fs, _ := NewFeedService(nil)
+	if _, err := fs.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	feed := fs.GetAuthorsFeed([]string{bob.Address, alice.Address, bob.Address}, 0)
+	if len(feed) != 3 || feed[0].TxID != a2.ID {
+		t.Fatalf("GetAuthorsFeed() = %+v, want alice's and bob's 3 posts, a2 first", feed)
+	}
+	if limited := fs.GetAuthorsFeed([]string{alice.Address, bob.Address}, 1); len(limited) != 1 || limited[0].TxID != a2.ID {
+		t.Errorf("GetAuthorsFeed(limit 1) = %+v, want a2", limited)
+	}
+	if none := fs.GetAuthorsFeed(nil, 0); len(none) != 0 {
+		t.Errorf("GetAuthorsFeed(nil) = %+v, want no posts", none)
+	}
+}
+
+func TestFeedService_CoAuthoredPostsAppearInEveryAuthorsFeed(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	bob, _ := identity.NewWallet()
+	carol, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+
+	post := NewPost(alice.Address, "cid-joint", "joint", nil)
+	post.CoAuthors = []string{bob.Address, carol.Address}
+	payload, _ := post.ToJSON()
+	tx, _ := ledger.NewTransaction(alice.Address, ledger.PostCreated, payload)
+	if err := alice.SignTransaction(tx); err != nil {
+		t.Fatalf("SignTransaction() error = %v", err)
+	}
+	if err := bob.CoSignTransaction(tx); err != nil {
+		t.Fatalf("CoSignTransaction(bob) error = %v", err)
+	}
+	if _, err := bc.AddBlock([]*ledger.Transaction{tx}); err == nil {
+		t.Fatal("AddBlock() accepted a co-authored post without carol's signature")
+	}
+	if err := carol.CoSignTransaction(tx); err != nil {
+		t.Fatalf("CoSignTransaction(carol) error = %v", err)
+	}
+	if _, err := bc.AddBlock([]*ledger.Transaction{tx}); err != nil {
+		t.Fatalf("AddBlock() with all co-signatures error = %v", err)
+	}
+	bc.AddBlock([]*ledger.Transaction{newSignedPostTx(t, bob, "cid-bob", "")})
+
+	fs, _ := NewFeedService(nil)
+	if _, err := fs.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	for name, w := range map[string]*identity.Wallet{"alice": alice, "bob": bob, "carol": carol} {
+		found := false
+		for _, e := range fs.GetUserFeed(w.Address, 0) {
+			found = found || e.TxID == tx.ID
+		}
+		if !found {
+			t.Errorf("GetUserFeed(%s) does not include the co-authored post", name)
+		}
+	}
+	if got, _ := fs.GetPost(tx.ID); len(got.Authors()) != 3 || got.Authors()[0] != alice.Address {
+		t.Errorf("Authors() = %v, want alice, bob and carol", got.Authors())
+	}
+	if feed := fs.GetAuthorsFeed([]string{alice.Address, bob.Address, carol.Address}, 0); len(feed) != 2 {
+		t.Errorf("GetAuthorsFeed() = %+v, want the co-authored post once and bob's post", feed)
+	}
+}
+
+func TestFeedService_SkipsPostsForgingAnotherAuthor(t *testing.T) {
+	victim, _ := identity.NewWallet()
+	attacker, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+
+	payload, _ := NewPost(victim.Address, "cid-forged", "not mine", nil).ToJSON()
+	forged, _ := ledger.NewTransaction(attacker.Address, ledger.PostCreated, payload)
+	if err := attacker.SignTransaction(forged); err != nil {
+		t.Fatalf("SignTransaction() error = %v", err)
+	}
+	if _, err := bc.AddBlock([]*ledger.Transaction{forged}); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+
+	fs, _ := NewFeedService(nil)
+	if _, err := fs.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if feed := fs.GetUserFeed(victim.Address, 0); len(feed) != 0 {
+		t.Errorf("GetUserFeed(victim) = %+v, want no posts signed by the attacker", feed)
+	}
+	if _, ok := fs.GetPost(forged.ID); ok {
+		t.Error("GetPost() found a post whose author is not its sender")
+	}
+}
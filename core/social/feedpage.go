@@ -0,0 +1,152 @@
+package social
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidFeedCursor is returned by GetFeedPage for a cursor it did not
+// issue, or one pinned to blocks the index no longer holds.
+var ErrInvalidFeedCursor = errors.New("feed cursor is invalid")
+
+// FeedQuery selects a page of a feed.
+type FeedQuery struct {
+	Authors []string // Posts by any of these authors; nil for the global feed
+	// Languages keeps only posts whose language falls under one of these
+	// BCP 47 ranges (see LanguageMatches), e.g. "en" for "en" and "en-GB"
+	// posts. Posts with no language are dropped. Nil keeps every post.
+	Languages []string
+	Limit     int    // Posts per page; <= 0 returns the rest of the feed
+	Cursor    string // NextCursor of the previous page; "" for the first page
+}
+
+// FeedPage is one page of a feed, newest first, as of a snapshot: the feed
+// as it stood at block Snapshot. Following NextCursor continues through the
+// same snapshot, so posts in blocks produced while paging neither shift
+// later pages nor appear twice. NewItems counts those posts; when it is
+// non-zero, a client can offer to reload from the first page.
+type FeedPage struct {
+	Entries    []FeedEntry `json:"entries"`
+	NextCursor string      `json:"nextCursor,omitempty"` // "" on the last page
+	Snapshot   int64       `json:"snapshot"`             // Height the page is pinned to
+	Total      int         `json:"total"`                // Posts in the feed as of Snapshot
+	NewItems   int         `json:"newItems"`             // Posts in the feed above Snapshot
+}
+
+// feedCursor is the decoded form of FeedPage.NextCursor: the snapshot height
+// and the last post of the page it follows.
+type feedCursor struct {
+	snapshot int64
+	txID     string
+}
+
+func (c feedCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.snapshot, 10) + ":" + c.txID))
+}
+
+func parseFeedCursor(s string) (feedCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return feedCursor{}, fmt.Errorf("%w: %q", ErrInvalidFeedCursor, s)
+	}
+	height, txID, ok := strings.Cut(string(raw), ":")
+	snapshot, err := strconv.ParseInt(height, 10, 64)
+	if !ok || err != nil || snapshot < 0 || txID == "" {
+		return feedCursor{}, fmt.Errorf("%w: %q", ErrInvalidFeedCursor, s)
+	}
+	return feedCursor{snapshot: snapshot, txID: txID}, nil
+}
+
+// GetFeedPage returns a page of the feed q selects, pinned to the index's
+// high-water mark for the first page and to the cursor's snapshot after
+// that. Ordering matches GetGlobalFeed and GetAuthorsFeed.
+func (fs *FeedService) GetFeedPage(q FeedQuery) (FeedPage, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	snapshot, after := fs.state.HighWaterIndex, -1
+	if q.Cursor != "" {
+		c, err := parseFeedCursor(q.Cursor)
+		if err != nil {
+			return FeedPage{}, err
+		}
+		i, ok := fs.byTxID[c.txID]
+		if !ok || c.snapshot > fs.state.HighWaterIndex || fs.state.Entries[i].BlockIndex > c.snapshot {
+			return FeedPage{}, fmt.Errorf("%w: %q", ErrInvalidFeedCursor, q.Cursor)
+		}
+		snapshot, after = c.snapshot, i
+	}
+
+	page := FeedPage{Snapshot: snapshot}
+	var view []int
+	for _, i := range fs.feedPositions(q.Authors) {
+		if q.Languages != nil && !LanguageMatches(fs.state.Entries[i].Language, q.Languages) {
+			continue
+		}
+		if fs.state.Entries[i].BlockIndex > snapshot {
+			page.NewItems++
+			continue
+		}
+		view = append(view, i)
+	}
+	sort.Slice(view, func(a, b int) bool { return fs.newer(view[a], view[b]) })
+	page.Total = len(view)
+
+	start := 0
+	if after >= 0 {
+		start = sort.Search(len(view), func(k int) bool { return fs.newer(after, view[k]) })
+	}
+	end := len(view)
+	if q.Limit > 0 && start+q.Limit < end {
+		end = start + q.Limit
+	}
+	page.Entries = make([]FeedEntry, 0, end-start)
+	for _, i := range view[start:end] {
+		page.Entries = append(page.Entries, fs.state.Entries[i].clone())
+	}
+	if end < len(view) {
+		page.NextCursor = feedCursor{snapshot: snapshot, txID: fs.state.Entries[view[end-1]].TxID}.String()
+	}
+	return page, nil
+}
+
+// feedPositions returns the positions of the posts by any of authors, or of
+// every post if authors is nil, each once. The caller must hold fs.mu.
+func (fs *FeedService) feedPositions(authors []string) []int {
+	if authors == nil {
+		positions := make([]int, len(fs.state.Entries))
+		for i := range positions {
+			positions[i] = i
+		}
+		return positions
+	}
+	var positions []int
+	included := make(map[int]bool)
+	for _, author := range authors {
+		for _, i := range fs.byAuthor[author] {
+			if !included[i] {
+				included[i] = true
+				positions = append(positions, i)
+			}
+		}
+	}
+	return positions
+}
+
+// newer reports whether the post at position i comes before the one at j in
+// a feed: by block, then timestamp, newest first, then in chain order. The
+// caller must hold fs.mu.
+func (fs *FeedService) newer(i, j int) bool {
+	a, b := &fs.state.Entries[i], &fs.state.Entries[j]
+	if a.BlockIndex != b.BlockIndex {
+		return a.BlockIndex > b.BlockIndex
+	}
+	if a.Timestamp != b.Timestamp {
+		return a.Timestamp > b.Timestamp
+	}
+	return i < j
+}
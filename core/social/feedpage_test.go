@@ -0,0 +1,78 @@
+package social
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// feedPageTestTitles returns the titles of entries, in order.
+func feedPageTestTitles(entries []FeedEntry) []string {
+	titles := make([]string, len(entries))
+	for i, e := range entries {
+		titles[i] = e.Title
+	}
+	return titles
+}
+
+func TestFeedService_GetFeedPageStaysOnItsSnapshot(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	bob, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+	for i := 1; i <= 5; i++ {
+		bc.AddBlock([]*ledger.Transaction{newSignedPostTx(t, alice, fmt.Sprintf("cid-%d", i), fmt.Sprintf("post %d", i))})
+	}
+	fs, _ := NewFeedService(nil)
+	fs.Sync(bc)
+
+	first, err := fs.GetFeedPage(FeedQuery{Limit: 2})
+	if err != nil || first.Snapshot != 5 || first.Total != 5 || first.NextCursor == "" {
+		t.Fatalf("GetFeedPage() = %+v, %v; want the first of 5 posts at height 5", first, err)
+	}
+	if got := fmt.Sprint(feedPageTestTitles(first.Entries)); got != "[post 5 post 4]" {
+		t.Errorf("first page = %s, want the two newest", got)
+	}
+
+	// Posts land between pages.
+	bc.AddBlock([]*ledger.Transaction{newSignedPostTx(t, alice, "cid-6", "post 6"), newSignedPostTx(t, bob, "cid-b", "bob's")})
+	fs.Sync(bc)
+
+	second, err := fs.GetFeedPage(FeedQuery{Limit: 2, Cursor: first.NextCursor})
+	if err != nil || second.Snapshot != 5 || second.Total != 5 || second.NewItems != 2 {
+		t.Fatalf("second page = %+v, %v; want it pinned to height 5 with 2 new items", second, err)
+	}
+	if got := fmt.Sprint(feedPageTestTitles(second.Entries)); got != "[post 3 post 2]" {
+		t.Errorf("second page = %s, want the next two of the snapshot", got)
+	}
+	last, _ := fs.GetFeedPage(FeedQuery{Limit: 2, Cursor: second.NextCursor})
+	if got := fmt.Sprint(feedPageTestTitles(last.Entries)); got != "[post 1]" || last.NextCursor != "" {
+		t.Errorf("last page = %s, cursor %q; want post 1 and no cursor", got, last.NextCursor)
+	}
+
+	fresh, _ := fs.GetFeedPage(FeedQuery{Authors: []string{alice.Address}})
+	if fresh.Snapshot != 6 || fresh.Total != 6 || fresh.NewItems != 0 || fresh.Entries[0].Title != "post 6" {
+		t.Errorf("reloaded author feed = %+v, want alice's 6 posts at height 6", fresh)
+	}
+}
+
+func TestFeedService_GetFeedPageRejectsForeignCursors(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+	bc.AddBlock([]*ledger.Transaction{newSignedPostTx(t, alice, "cid-1", "post 1")})
+	fs, _ := NewFeedService(nil)
+	fs.Sync(bc)
+
+	for name, cursor := range map[string]string{
+		"garbage":        "!!",
+		"unknown post":   feedCursor{snapshot: 1, txID: "nope"}.String(),
+		"future height":  feedCursor{snapshot: 9, txID: bc.GetLatestBlock().Transactions[0].ID}.String(),
+		"post past pin":  feedCursor{snapshot: 0, txID: bc.GetLatestBlock().Transactions[0].ID}.String(),
+		"missing height": "OnR4",
+	} {
+		if _, err := fs.GetFeedPage(FeedQuery{Cursor: cursor}); !errors.Is(err, ErrInvalidFeedCursor) {
+			t.Errorf("GetFeedPage() with %s cursor error = %v, want ErrInvalidFeedCursor", name, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package social
+
+import (
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil/golden"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// goldenPosts returns posts with fixed field values: a minimal one as
+// NewPost builds it, a public one with every other optional field set and a
+// group one, since group posts cannot carry a link preview.
+func goldenPosts() map[string]*Post {
+	author := "04" + strings.Repeat("ab", 64)
+	return map[string]*Post{
+		"post_minimal": {AuthorPublicKey: author, ContentCID: "bafy-content", Timestamp: 1700000000000000000, Version: 1},
+		"post_full": {
+			AuthorPublicKey: author,
+			ContentCID:      "bafy-content",
+			Timestamp:       1700000000000000000,
+			Version:         3,
+			Title:           "Golden",
+			Tags:            []string{"go", "dds"},
+			Media:           []string{"bafy-media-1", "bafy-media-2"},
+			Origin:          &PostOrigin{Source: "mastodon", URL: "https://example.social/@a/1", Timestamp: 1600000000000000000},
+			WebSource:       &WebSource{URL: "https://example.com/a", SHA256: strings.Repeat("0f", 32)},
+			PreviewCID:      "bafy-preview",
+		},
+		"post_group": {AuthorPublicKey: author, ContentCID: "bafy-envelope", Timestamp: 1700000000000000000, Version: 1, GroupID: "group-1", KeyEpoch: 2},
+	}
+}
+
+func TestGolden_Post(t *testing.T) {
+	for name, post := range goldenPosts() {
+		t.Run(name, func(t *testing.T) {
+			data, err := post.ToJSON()
+			if err != nil {
+				t.Fatalf("ToJSON() error = %v", err)
+			}
+			golden.Assert(t, name+".json", append(data, '\n'))
+
+			for _, tc := range []struct {
+				ext    string
+				format ledger.PayloadFormat
+			}{
+				{"payload_json.hex", ledger.PayloadFormatJSON},
+				{"payload_cbor.hex", ledger.PayloadFormatCBOR},
+			} {
+				payload, err := post.ToPayload(tc.format)
+				if err != nil {
+					t.Fatalf("ToPayload() error = %v", err)
+				}
+				golden.AssertBinary(t, name+"."+tc.ext, payload)
+				decoded, err := PostFromPayload(payload)
+				if err != nil || !reflect.DeepEqual(decoded, post) {
+					t.Errorf("PostFromPayload() = %+v, %v; want %+v", decoded, err, post)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package social
+
+import (
+	"digisocialblock/core/ledger"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrGraphIndexDiverged is returned by GraphIndex.Sync when the block recorded
+// as processed last is no longer on the chain. Call Rebuild to recover.
+var ErrGraphIndexDiverged = errors.New("graph index high-water mark does not match the chain")
+
+// Errors returned by GraphIndex.ValidateCommentChange. Changes failing them are
+// not indexed.
+var (
+	ErrCommentNotFound   = errors.New("comment not found")
+	ErrNotCommentAuthor  = errors.New("only the comment's author may change it")
+	ErrCommentDeleted    = errors.New("comment has been deleted")
+	ErrCommentVersionGap = errors.New("edit does not follow the comment's current version")
+)
+
+// CommentEntry is a comment recorded in the graph index, at its latest
+// version. A deleted comment stays in its thread as a tombstone, with Deleted
+// set and no ContentCID, so its replies keep their place.
+type CommentEntry struct {
+	TxID            string `json:"txId"`
+	BlockIndex      int64  `json:"blockIndex"`
+	AuthorPublicKey string `json:"authorPublicKey"`
+	PostTxID        string `json:"postTxId"`
+	ParentTxID      string `json:"parentTxId,omitempty"`
+	ContentCID      string `json:"contentCID"`
+	Timestamp       int64  `json:"timestamp"`          // When the comment was first made
+	Version         int    `json:"version"`            // 1, plus one per edit
+	EditedAt        int64  `json:"editedAt,omitempty"` // Timestamp of the latest edit
+	Deleted         bool   `json:"deleted,omitempty"`
+}
+
+// CommentRevision is one version of a comment's text, as returned by
+// GraphIndex.GetCommentHistory.
+type CommentRevision struct {
+	TxID       string `json:"txId"` // The CommentAdded or CommentEdited transaction
+	BlockIndex int64  `json:"blockIndex"`
+	Version    int    `json:"version"`
+	ContentCID string `json:"contentCID"`
+	Timestamp  int64  `json:"timestamp"`
+}
+
+// FollowEdge is a follow relationship recorded in the graph index.
+type FollowEdge struct {
+	TxID       string `json:"txId"`
+	BlockIndex int64  `json:"blockIndex"`
+	Follower   string `json:"follower"`
+	Followee   string `json:"followee"`
+	Timestamp  int64  `json:"timestamp"`
+}
+
+// GraphIndex indexes the social graph beyond posts: comment trees and follow
+// relationships. It is kept in memory and updated incrementally like the feed
+// index.
+//
+// A comment is indexed only if its author is the transaction sender; a reply
+// whose parent is not a comment on the same post is indexed as a top-level
+// comment. Edits and deletions are applied only if they pass
+// ValidateCommentChange when their block is indexed. Following someone twice
+// records the first follow only.
+type GraphIndex struct {
+	mu        sync.RWMutex
+	follower  ledger.ChainFollower
+	comments  map[string]CommentEntry      // Comment tx ID -> entry
+	revisions map[string][]CommentRevision // Comment tx ID -> versions, oldest first; dropped on deletion
+	topLevel  map[string][]string          // Post tx ID -> top-level comment tx IDs, in chain order
+	replies   map[string][]string          // Comment tx ID -> reply tx IDs, in chain order
+	followers map[string][]FollowEdge      // Followee -> edges, in chain order
+	following map[string][]FollowEdge      // Follower -> edges, in chain order
+	follows   map[[2]string]bool           // (follower, followee) pairs already recorded
+}
+
+// NewGraphIndex creates an empty GraphIndex. Call Sync to populate it.
+func NewGraphIndex() *GraphIndex {
+	g := &GraphIndex{}
+	g.reset()
+	return g
+}
+
+// reset clears the index. The caller must hold g.mu (or own g exclusively).
+func (g *GraphIndex) reset() {
+	g.follower = ledger.NewChainFollower()
+	g.comments = make(map[string]CommentEntry)
+	g.revisions = make(map[string][]CommentRevision)
+	g.topLevel = make(map[string][]string)
+	g.replies = make(map[string][]string)
+	g.followers = make(map[string][]FollowEdge)
+	g.following = make(map[string][]FollowEdge)
+	g.follows = make(map[[2]string]bool)
+}
+
+// HighWaterMark reports the last block whose comments and follows are in
+// the graph.
+func (g *GraphIndex) HighWaterMark() (int64, string) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.follower.HighWaterMark()
+}
+
+// Sync indexes the comments and follows in blocks added since the last call
+// and returns how many blocks it read. It fails with ErrGraphIndexDiverged if
+// the chain no longer holds the last block indexed.
+func (g *GraphIndex) Sync(bc *ledger.Blockchain) (int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.follower.Sync(bc, "graph index", ErrGraphIndexDiverged, g.indexBlock)
+}
+
+// Rebuild clears the graph and indexes the chain again from genesis.
+func (g *GraphIndex) Rebuild(bc *ledger.Blockchain) (int, error) {
+	if bc == nil {
+		return 0, fmt.Errorf("blockchain cannot be nil")
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.reset()
+	return g.follower.Sync(bc, "graph index", ErrGraphIndexDiverged, g.indexBlock)
+}
+
+// indexBlock adds the block's comments and follows to the index. Payloads that
+// do not decode are skipped.
+func (g *GraphIndex) indexBlock(block *ledger.Block) {
+	for _, tx := range block.Transactions {
+		if tx == nil {
+			continue
+		}
+		switch tx.Type {
+		case ledger.CommentAdded:
+			c, err := CommentFromPayload(tx.Payload)
+			if err != nil || c.AuthorPublicKey != tx.SenderPublicKey {
+				continue
+			}
+			if _, dup := g.comments[tx.ID]; dup {
+				continue
+			}
+			entry := CommentEntry{
+				TxID:            tx.ID,
+				BlockIndex:      block.Index,
+				AuthorPublicKey: c.AuthorPublicKey,
+				PostTxID:        c.PostTxID,
+				ContentCID:      c.ContentCID,
+				Timestamp:       c.Timestamp,
+				Version:         1,
+			}
+			if parent, ok := g.comments[c.ParentTxID]; ok && parent.PostTxID == c.PostTxID {
+				entry.ParentTxID = c.ParentTxID
+				g.replies[c.ParentTxID] = append(g.replies[c.ParentTxID], tx.ID)
+			} else {
+				g.topLevel[c.PostTxID] = append(g.topLevel[c.PostTxID], tx.ID)
+			}
+			g.comments[tx.ID] = entry
+			g.revisions[tx.ID] = []CommentRevision{{TxID: tx.ID, BlockIndex: block.Index, Version: 1, ContentCID: c.ContentCID, Timestamp: c.Timestamp}}
+		case ledger.CommentEdited, ledger.CommentDeleted:
+			target, edit, err := g.commentChangeLocked(tx)
+			if err != nil {
+				continue
+			}
+			entry := g.comments[target]
+			if edit == nil {
+				entry.Deleted, entry.ContentCID = true, ""
+				delete(g.revisions, target)
+			} else {
+				entry.ContentCID, entry.Version, entry.EditedAt = edit.ContentCID, edit.Version, edit.Timestamp
+				g.revisions[target] = append(g.revisions[target], CommentRevision{
+					TxID: tx.ID, BlockIndex: block.Index, Version: edit.Version, ContentCID: edit.ContentCID, Timestamp: edit.Timestamp,
+				})
+			}
+			g.comments[target] = entry
+		case ledger.UserFollowed:
+			f, err := FollowFromPayload(tx.Payload)
+			if err != nil || f.FolloweePublicKey == tx.SenderPublicKey {
+				continue
+			}
+			pair := [2]string{tx.SenderPublicKey, f.FolloweePublicKey}
+			if g.follows[pair] {
+				continue
+			}
+			g.follows[pair] = true
+			edge := FollowEdge{
+				TxID:       tx.ID,
+				BlockIndex: block.Index,
+				Follower:   tx.SenderPublicKey,
+				Followee:   f.FolloweePublicKey,
+				Timestamp:  f.Timestamp,
+			}
+			g.followers[edge.Followee] = append(g.followers[edge.Followee], edge)
+			g.following[edge.Follower] = append(g.following[edge.Follower], edge)
+		}
+	}
+}
+
+// ValidateCommentChange checks a CommentEdited or CommentDeleted transaction
+// against the indexed comment it changes: the sender must be the comment's
+// author, the comment must not be deleted, and an edit must carry the version
+// after the comment's current one. Clients and nodes can use it to reject a
+// change before submitting it; the index applies the same rules.
+func (g *GraphIndex) ValidateCommentChange(tx *ledger.Transaction) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	_, _, err := g.commentChangeLocked(tx)
+	return err
+}
+
+// commentChangeLocked validates a comment change and returns the tx ID of the
+// comment it changes and, for edits, the edit. The caller must hold g.mu.
+func (g *GraphIndex) commentChangeLocked(tx *ledger.Transaction) (string, *CommentEdit, error) {
+	var target, author string
+	var edit *CommentEdit
+	switch tx.Type {
+	case ledger.CommentEdited:
+		e, err := CommentEditFromPayload(tx.Payload)
+		if err != nil {
+			return "", nil, err
+		}
+		target, author, edit = e.CommentTxID, e.AuthorPublicKey, e
+	case ledger.CommentDeleted:
+		d, err := CommentDeletionFromPayload(tx.Payload)
+		if err != nil {
+			return "", nil, err
+		}
+		target, author = d.CommentTxID, d.AuthorPublicKey
+	default:
+		return "", nil, fmt.Errorf("transaction type %s is not a comment change", tx.Type)
+	}
+	entry, ok := g.comments[target]
+	switch {
+	case !ok:
+		return "", nil, fmt.Errorf("%w: %s", ErrCommentNotFound, target)
+	case author != tx.SenderPublicKey || author != entry.AuthorPublicKey:
+		return "", nil, fmt.Errorf("%w: comment %s", ErrNotCommentAuthor, target)
+	case entry.Deleted:
+		return "", nil, fmt.Errorf("%w: %s", ErrCommentDeleted, target)
+	case edit != nil && edit.Version != entry.Version+1:
+		return "", nil, fmt.Errorf("%w: comment %s is at version %d, edit is version %d", ErrCommentVersionGap, target, entry.Version, edit.Version)
+	}
+	return target, edit, nil
+}
+
+// GetComment returns the comment recorded under txID.
+func (g *GraphIndex) GetComment(txID string) (CommentEntry, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	entry, ok := g.comments[txID]
+	return entry, ok
+}
+
+// GetCommentHistory returns every version of the comment recorded under txID,
+// oldest first. It returns nil for unknown and deleted comments.
+func (g *GraphIndex) GetCommentHistory(txID string) []CommentRevision {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return append([]CommentRevision(nil), g.revisions[txID]...)
+}
+
+// GetComments returns the top-level comments on a post, oldest first.
+func (g *GraphIndex) GetComments(postTxID string) []CommentEntry {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.lookup(g.topLevel[postTxID])
+}
+
+// GetReplies returns the direct replies to a comment, oldest first.
+func (g *GraphIndex) GetReplies(commentTxID string) []CommentEntry {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.lookup(g.replies[commentTxID])
+}
+
+// lookup resolves comment tx IDs. The caller must hold g.mu.
+func (g *GraphIndex) lookup(txIDs []string) []CommentEntry {
+	entries := make([]CommentEntry, 0, len(txIDs))
+	for _, id := range txIDs {
+		entries = append(entries, g.comments[id])
+	}
+	return entries
+}
+
+// GetFollowers returns the follows of address, oldest first.
+func (g *GraphIndex) GetFollowers(address string) []FollowEdge {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return append([]FollowEdge(nil), g.followers[address]...)
+}
+
+// GetFollowing returns the follows made by address, oldest first.
+func (g *GraphIndex) GetFollowing(address string) []FollowEdge {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return append([]FollowEdge(nil), g.following[address]...)
+}
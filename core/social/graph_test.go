@@ -0,0 +1,195 @@
+package social
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"errors"
+	"testing"
+)
+
+func graphTestTx(t *testing.T, wallet *identity.Wallet, txType ledger.TransactionType, payload []byte) *ledger.Transaction {
+	t.Helper()
+	tx, err := ledger.NewTransaction(wallet.Address, txType, payload)
+	if err != nil {
+		t.Fatalf("NewTransaction() error = %v", err)
+	}
+	if err := wallet.SignTransaction(tx); err != nil {
+		t.Fatalf("SignTransaction() error = %v", err)
+	}
+	return tx
+}
+
+func graphTestComment(t *testing.T, wallet *identity.Wallet, postTxID, parentTxID, contentCID string) *ledger.Transaction {
+	t.Helper()
+	c := &Comment{AuthorPublicKey: wallet.Address, PostTxID: postTxID, ParentTxID: parentTxID, ContentCID: contentCID, Timestamp: 1}
+	payload, _ := c.ToPayload(ledger.PayloadFormatCBOR)
+	return graphTestTx(t, wallet, ledger.CommentAdded, payload)
+}
+
+func graphTestFollow(t *testing.T, follower *identity.Wallet, followee string) *ledger.Transaction {
+	t.Helper()
+	payload, _ := (&Follow{FolloweePublicKey: followee, Timestamp: 1}).ToPayload(ledger.PayloadFormatJSON)
+	return graphTestTx(t, follower, ledger.UserFollowed, payload)
+}
+
+func TestGraphIndex_CommentTreesAndFollows(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	bob, _ := identity.NewWallet()
+	carol, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+
+	post := newSignedPostTx(t, alice, "cid-post", "")
+	top := graphTestComment(t, bob, post.ID, "", "cid-c1")
+	bc.AddBlock([]*ledger.Transaction{post, top, graphTestFollow(t, bob, alice.Address)})
+
+	g := NewGraphIndex()
+	if n, err := g.Sync(bc); err != nil || n != 2 {
+		t.Fatalf("Sync() = %d, %v; want 2 blocks (genesis + 1)", n, err)
+	}
+
+	reply := graphTestComment(t, alice, post.ID, top.ID, "cid-c2")
+	// A reply to a comment on another post is indexed as top level.
+	stray := graphTestComment(t, carol, post.ID, "other-post-comment", "cid-c3")
+	bc.AddBlock([]*ledger.Transaction{
+		reply,
+		stray,
+		graphTestFollow(t, carol, alice.Address),
+		graphTestFollow(t, bob, alice.Address), // Repeat follow.
+	})
+	if n, err := g.Sync(bc); err != nil || n != 1 {
+		t.Fatalf("second Sync() = %d, %v; want 1 new block", n, err)
+	}
+
+	comments := g.GetComments(post.ID)
+	if len(comments) != 2 || comments[0].TxID != top.ID || comments[1].TxID != stray.ID {
+		t.Fatalf("GetComments() = %+v, want the comment then the stray reply", comments)
+	}
+	if comments[1].ParentTxID != "" {
+		t.Errorf("stray reply kept parent %q", comments[1].ParentTxID)
+	}
+	replies := g.GetReplies(top.ID)
+	if len(replies) != 1 || replies[0].TxID != reply.ID || replies[0].AuthorPublicKey != alice.Address {
+		t.Errorf("GetReplies() = %+v, want alice's reply", replies)
+	}
+	if got, ok := g.GetComment(reply.ID); !ok || got.ParentTxID != top.ID {
+		t.Errorf("GetComment(reply) = %+v, %v", got, ok)
+	}
+
+	followers := g.GetFollowers(alice.Address)
+	if len(followers) != 2 || followers[0].Follower != bob.Address || followers[1].Follower != carol.Address {
+		t.Errorf("GetFollowers(alice) = %+v, want bob then carol once each", followers)
+	}
+	if following := g.GetFollowing(bob.Address); len(following) != 1 || following[0].Followee != alice.Address {
+		t.Errorf("GetFollowing(bob) = %+v, want alice", following)
+	}
+
+	other, _ := ledger.NewBlockchain()
+	other.AddBlock(nil)
+	other.AddBlock(nil)
+	if _, err := g.Sync(other); !errors.Is(err, ErrGraphIndexDiverged) {
+		t.Errorf("Sync() of another chain error = %v, want ErrGraphIndexDiverged", err)
+	}
+	if _, err := g.Rebuild(other); err != nil || len(g.GetFollowers(alice.Address)) != 0 {
+		t.Errorf("Rebuild() = %v, want an empty graph", err)
+	}
+}
+
+func TestGraphIndex_SkipsForgedComments(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	mallory, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+	post := newSignedPostTx(t, alice, "cid-post", "")
+	forged := &Comment{AuthorPublicKey: alice.Address, PostTxID: post.ID, ContentCID: "cid-forged", Timestamp: 1}
+	payload, _ := forged.ToPayload(ledger.PayloadFormatJSON)
+	bc.AddBlock([]*ledger.Transaction{post, graphTestTx(t, mallory, ledger.CommentAdded, payload)})
+
+	g := NewGraphIndex()
+	if _, err := g.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if comments := g.GetComments(post.ID); len(comments) != 0 {
+		t.Errorf("GetComments() = %+v, want the forged comment skipped", comments)
+	}
+}
+
+func graphTestEdit(t *testing.T, wallet *identity.Wallet, author, commentTxID, contentCID string, version int) *ledger.Transaction {
+	t.Helper()
+	payload, _ := (&CommentEdit{AuthorPublicKey: author, CommentTxID: commentTxID, ContentCID: contentCID, Version: version, Timestamp: int64(version)}).ToPayload(ledger.PayloadFormatCBOR)
+	return graphTestTx(t, wallet, ledger.CommentEdited, payload)
+}
+
+func graphTestDeletion(t *testing.T, wallet *identity.Wallet, commentTxID string) *ledger.Transaction {
+	t.Helper()
+	payload, _ := (&CommentDeletion{AuthorPublicKey: wallet.Address, CommentTxID: commentTxID, Timestamp: 9}).ToPayload(ledger.PayloadFormatJSON)
+	return graphTestTx(t, wallet, ledger.CommentDeleted, payload)
+}
+
+func TestGraphIndex_CommentEditsAndDeletions(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	bob, _ := identity.NewWallet()
+	mallory, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+
+	post := newSignedPostTx(t, alice, "cid-post", "")
+	comment := graphTestComment(t, bob, post.ID, "", "cid-v1")
+	reply := graphTestComment(t, alice, post.ID, comment.ID, "cid-reply")
+	edit2 := graphTestEdit(t, bob, bob.Address, comment.ID, "cid-v2", 2)
+	bc.AddBlock([]*ledger.Transaction{
+		post, comment, reply, edit2,
+		graphTestEdit(t, bob, bob.Address, comment.ID, "cid-replayed", 2),   // Stale version.
+		graphTestEdit(t, mallory, bob.Address, comment.ID, "cid-forged", 3), // Sender is not the author.
+		graphTestEdit(t, mallory, mallory.Address, comment.ID, "cid-hijack", 3),
+		graphTestDeletion(t, mallory, comment.ID),
+	})
+	g := NewGraphIndex()
+	if _, err := g.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	got, _ := g.GetComment(comment.ID)
+	if got.ContentCID != "cid-v2" || got.Version != 2 || got.EditedAt != 2 || got.Deleted || got.Timestamp != 1 {
+		t.Fatalf("GetComment() = %+v, want bob's edit to version 2 only", got)
+	}
+	if comments := g.GetComments(post.ID); len(comments) != 1 || comments[0].ContentCID != "cid-v2" {
+		t.Errorf("GetComments() = %+v, want the latest version", comments)
+	}
+	history := g.GetCommentHistory(comment.ID)
+	if len(history) != 2 || history[0].TxID != comment.ID || history[0].ContentCID != "cid-v1" || history[1].TxID != edit2.ID || history[1].Version != 2 {
+		t.Errorf("GetCommentHistory() = %+v, want the original then edit 2", history)
+	}
+
+	for _, tt := range []struct {
+		tx   *ledger.Transaction
+		want error
+	}{
+		{graphTestEdit(t, bob, bob.Address, comment.ID, "cid-v4", 4), ErrCommentVersionGap},
+		{graphTestEdit(t, bob, bob.Address, "missing", "cid", 2), ErrCommentNotFound},
+		{graphTestDeletion(t, alice, comment.ID), ErrNotCommentAuthor},
+		{graphTestEdit(t, bob, bob.Address, comment.ID, "cid-v3", 3), nil},
+	} {
+		if err := g.ValidateCommentChange(tt.tx); !errors.Is(err, tt.want) {
+			t.Errorf("ValidateCommentChange() error = %v, want %v", err, tt.want)
+		}
+	}
+
+	bc.AddBlock([]*ledger.Transaction{
+		graphTestDeletion(t, bob, comment.ID),
+		graphTestEdit(t, bob, bob.Address, comment.ID, "cid-v3", 3), // After deletion.
+	})
+	if _, err := g.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	got, _ = g.GetComment(comment.ID)
+	if !got.Deleted || got.ContentCID != "" || got.Version != 2 {
+		t.Errorf("GetComment() after deletion = %+v, want a version 2 tombstone", got)
+	}
+	if history := g.GetCommentHistory(comment.ID); history != nil {
+		t.Errorf("GetCommentHistory() after deletion = %+v, want nil", history)
+	}
+	if replies := g.GetReplies(comment.ID); len(replies) != 1 || replies[0].TxID != reply.ID {
+		t.Errorf("GetReplies() of the deleted comment = %+v, want the reply kept", replies)
+	}
+	if err := g.ValidateCommentChange(graphTestEdit(t, bob, bob.Address, comment.ID, "cid-v3", 3)); !errors.Is(err, ErrCommentDeleted) {
+		t.Errorf("ValidateCommentChange() of a deleted comment error = %v, want ErrCommentDeleted", err)
+	}
+}
@@ -0,0 +1,247 @@
+package social
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// GroupEncryptionMethod identifies group-encrypted content.
+const GroupEncryptionMethod = "dsb-group-aes256gcm-v1"
+
+// ErrUnknownKeyEpoch is returned when content refers to a key epoch the
+// schedule does not contain.
+var ErrUnknownKeyEpoch = errors.New("unknown group key epoch")
+
+// GroupKeyEpoch is one generation of a group's content key: a fresh random key
+// sealed to each member at the time of the epoch.
+type GroupKeyEpoch struct {
+	Epoch      int                  `json:"epoch"`
+	CreatedAt  int64                `json:"createdAt"` // UnixNano
+	Members    []string             `json:"members"`   // Sorted member addresses
+	SealedKeys []identity.SealedKey `json:"sealedKeys"`
+}
+
+// GroupKeySchedule is the key history of a group. Every membership change
+// starts a new epoch with a new key sealed only to the new member set, so
+// removed members cannot read later posts and new members cannot read earlier
+// ones (unless an earlier epoch is explicitly re-sealed to them).
+//
+// The schedule holds no plaintext keys and can be stored or published as-is.
+type GroupKeySchedule struct {
+	GroupID string          `json:"groupId"`
+	Epochs  []GroupKeyEpoch `json:"epochs"`
+}
+
+// NewGroupKeySchedule creates a schedule for groupID with an initial epoch
+// sealed to members.
+func NewGroupKeySchedule(groupID string, members []string) (*GroupKeySchedule, error) {
+	if groupID == "" {
+		return nil, fmt.Errorf("group ID cannot be empty")
+	}
+	s := &GroupKeySchedule{GroupID: groupID}
+	if err := s.Rekey(members); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Current returns the newest epoch.
+func (s *GroupKeySchedule) Current() *GroupKeyEpoch {
+	if len(s.Epochs) == 0 {
+		return nil
+	}
+	return &s.Epochs[len(s.Epochs)-1]
+}
+
+// Epoch returns the epoch with the given number.
+func (s *GroupKeySchedule) Epoch(n int) (*GroupKeyEpoch, error) {
+	if n < 1 || n > len(s.Epochs) {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownKeyEpoch, n)
+	}
+	return &s.Epochs[n-1], nil
+}
+
+// Rekey starts a new epoch for members with a freshly generated key. Call it
+// whenever membership changes.
+func (s *GroupKeySchedule) Rekey(members []string) error {
+	if len(members) == 0 {
+		return fmt.Errorf("group must have at least one member")
+	}
+	sorted := append([]string(nil), members...)
+	sort.Strings(sorted)
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i] == sorted[i-1] {
+			return fmt.Errorf("duplicate group member %s", sorted[i])
+		}
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("failed to generate group key: %w", err)
+	}
+	defer wipe(key)
+	sealed, err := identity.SealKeyForRecipients(key, sorted)
+	if err != nil {
+		return fmt.Errorf("failed to seal group key: %w", err)
+	}
+	s.Epochs = append(s.Epochs, GroupKeyEpoch{
+		Epoch:      len(s.Epochs) + 1,
+		CreatedAt:  time.Now().UnixNano(),
+		Members:    sorted,
+		SealedKeys: sealed,
+	})
+	return nil
+}
+
+// GroupEnvelope is group-encrypted content as stored on DDS. It carries the
+// key epoch it was encrypted under, including that epoch's sealed keys, so any
+// member of the epoch can decrypt it from the content alone.
+type GroupEnvelope struct {
+	Method     string        `json:"method"`
+	GroupID    string        `json:"groupId"`
+	KeyEpoch   GroupKeyEpoch `json:"keyEpoch"`
+	Nonce      []byte        `json:"nonce"`
+	Ciphertext []byte        `json:"ciphertext"`
+}
+
+// Seal encrypts plaintext under the current epoch's key. wallet must belong to
+// a member of the current epoch, since it has to recover the key to use it.
+func (s *GroupKeySchedule) Seal(wallet *identity.Wallet, plaintext []byte) (*GroupEnvelope, error) {
+	epoch := s.Current()
+	if epoch == nil {
+		return nil, fmt.Errorf("group %s has no key epoch", s.GroupID)
+	}
+	aead, err := openEpochCipher(wallet, epoch)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return &GroupEnvelope{
+		Method:     GroupEncryptionMethod,
+		GroupID:    s.GroupID,
+		KeyEpoch:   *epoch,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, groupAAD(s.GroupID, epoch.Epoch)),
+	}, nil
+}
+
+// Open decrypts the envelope with wallet, which must be a member of the
+// envelope's key epoch.
+func (e *GroupEnvelope) Open(wallet *identity.Wallet) ([]byte, error) {
+	if e.Method != GroupEncryptionMethod {
+		return nil, fmt.Errorf("unsupported group encryption method %q", e.Method)
+	}
+	aead, err := openEpochCipher(wallet, &e.KeyEpoch)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, e.Nonce, e.Ciphertext, groupAAD(e.GroupID, e.KeyEpoch.Epoch))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt group content: %w", err)
+	}
+	return plaintext, nil
+}
+
+// ToJSON serializes the envelope.
+func (e *GroupEnvelope) ToJSON() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// GroupEnvelopeFromJSON deserializes an envelope.
+func GroupEnvelopeFromJSON(data []byte) (*GroupEnvelope, error) {
+	var e GroupEnvelope
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal group envelope: %w", err)
+	}
+	return &e, nil
+}
+
+// groupAAD binds ciphertext to its group and epoch.
+func groupAAD(groupID string, epoch int) []byte {
+	aad := make([]byte, 8, 8+len(groupID))
+	binary.BigEndian.PutUint64(aad, uint64(epoch))
+	return append(aad, groupID...)
+}
+
+func openEpochCipher(wallet *identity.Wallet, epoch *GroupKeyEpoch) (cipher.AEAD, error) {
+	if wallet == nil {
+		return nil, fmt.Errorf("wallet cannot be nil")
+	}
+	key, err := wallet.OpenSealedKey(epoch.SealedKeys)
+	if err != nil {
+		return nil, fmt.Errorf("cannot use key epoch %d: %w", epoch.Epoch, err)
+	}
+	defer wipe(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create group cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// CreateGroupPost is CreatePost for group-only content: the text is sealed to
+// the group's current key epoch before it is published to DDS, and the post
+// metadata records the group and epoch so readers know which key to use.
+func (pm *PostManager) CreateGroupPost(
+	wallet *identity.Wallet,
+	schedule *GroupKeySchedule,
+	rawTextContent string,
+	title string,
+	tags []string,
+) (*ledger.Transaction, error) {
+	if wallet == nil {
+		return nil, fmt.Errorf("wallet cannot be nil to create a post")
+	}
+	if schedule == nil {
+		return nil, fmt.Errorf("group key schedule cannot be nil")
+	}
+	if rawTextContent == "" {
+		return nil, fmt.Errorf("raw text content cannot be empty for a post")
+	}
+	envelope, err := schedule.Seal(wallet, []byte(rawTextContent))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt group post: %w", err)
+	}
+	envelopeJSON, err := envelope.ToJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize group envelope: %w", err)
+	}
+	contentCID, err := pm.publisher.PublishTextPostToDDS(string(envelopeJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish group post content to DDS: %w", err)
+	}
+
+	postMeta := NewPostWithClock(pm.clock, wallet.Address, contentCID, title, tags)
+	postMeta.GroupID = schedule.GroupID
+	postMeta.KeyEpoch = envelope.KeyEpoch.Epoch
+	payload, err := postMeta.ToPayload(pm.format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize post metadata: %w", err)
+	}
+	tx, err := ledger.NewTransactionWithClock(pm.clock, wallet.Address, ledger.PostCreated, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new ledger transaction for post: %w", err)
+	}
+	if err := wallet.SignTransaction(tx); err != nil {
+		return nil, fmt.Errorf("failed to sign post transaction: %w", err)
+	}
+	return tx, nil
+}
@@ -0,0 +1,118 @@
+package social
+
+import (
+	"digisocialblock/core/content"
+	"digisocialblock/core/identity"
+	"digisocialblock/internal/testutil"
+	"errors"
+	"testing"
+)
+
+func TestGroupKeySchedule_RekeyOnMembershipChange(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	defer alice.Close()
+	bob, _ := identity.NewWallet()
+	defer bob.Close()
+	carol, _ := identity.NewWallet()
+	defer carol.Close()
+
+	schedule, err := NewGroupKeySchedule("book-club", []string{alice.Address, bob.Address})
+	if err != nil {
+		t.Fatalf("NewGroupKeySchedule() error = %v", err)
+	}
+	before, err := schedule.Seal(alice, []byte("epoch one"))
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if got, err := before.Open(bob); err != nil || string(got) != "epoch one" {
+		t.Errorf("member Open() = %q, %v", got, err)
+	}
+	if _, err := before.Open(carol); !errors.Is(err, identity.ErrNotARecipient) {
+		t.Errorf("non-member Open() error = %v, want ErrNotARecipient", err)
+	}
+
+	// Bob leaves, Carol joins.
+	if err := schedule.Rekey([]string{alice.Address, carol.Address}); err != nil {
+		t.Fatalf("Rekey() error = %v", err)
+	}
+	if schedule.Current().Epoch != 2 {
+		t.Fatalf("Current().Epoch = %d, want 2", schedule.Current().Epoch)
+	}
+	if _, err := schedule.Seal(bob, []byte("x")); err == nil {
+		t.Error("a removed member should not be able to seal to the new epoch")
+	}
+	after, _ := schedule.Seal(carol, []byte("epoch two"))
+	if _, err := after.Open(bob); err == nil {
+		t.Error("a removed member should not read content from the new epoch")
+	}
+	if _, err := before.Open(carol); err == nil {
+		t.Error("a new member should not read content from an earlier epoch")
+	}
+
+	// The envelope survives serialization and is bound to its group.
+	data, _ := after.ToJSON()
+	decoded, err := GroupEnvelopeFromJSON(data)
+	if err != nil {
+		t.Fatalf("GroupEnvelopeFromJSON() error = %v", err)
+	}
+	if got, err := decoded.Open(alice); err != nil || string(got) != "epoch two" {
+		t.Errorf("Open() after round trip = %q, %v", got, err)
+	}
+	decoded.GroupID = "other-group"
+	if _, err := decoded.Open(alice); err == nil {
+		t.Error("Open() with a different group ID should fail")
+	}
+
+	if _, err := schedule.Epoch(3); !errors.Is(err, ErrUnknownKeyEpoch) {
+		t.Errorf("Epoch(3) error = %v, want ErrUnknownKeyEpoch", err)
+	}
+	if err := schedule.Rekey([]string{alice.Address, alice.Address}); err == nil {
+		t.Error("Rekey() with duplicate members should fail")
+	}
+}
+
+func TestPostManager_CreateGroupPost(t *testing.T) {
+	member, _ := identity.NewWallet()
+	defer member.Close()
+	dds := testutil.NewDDS(0)
+	publisher, _ := content.NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	retriever, _ := content.NewContentRetriever(dds.Manifests, dds.Storage)
+	pm, _ := NewPostManager(publisher)
+	schedule, _ := NewGroupKeySchedule("g1", []string{member.Address})
+
+	tx, err := pm.CreateGroupPost(member, schedule, "members only", "T", nil)
+	if err != nil {
+		t.Fatalf("CreateGroupPost() error = %v", err)
+	}
+	post, err := PostFromJSON(tx.Payload)
+	if err != nil || post.GroupID != "g1" || post.KeyEpoch != 1 {
+		t.Fatalf("group post metadata = %+v, %v", post, err)
+	}
+	stored, err := retriever.RetrieveAndVerifyTextPost(post.ContentCID)
+	if err != nil {
+		t.Fatalf("RetrieveAndVerifyTextPost() error = %v", err)
+	}
+	envelope, err := GroupEnvelopeFromJSON([]byte(stored))
+	if err != nil {
+		t.Fatalf("stored content is not a group envelope: %v", err)
+	}
+	if got, err := envelope.Open(member); err != nil || string(got) != "members only" {
+		t.Errorf("Open() of published content = %q, %v", got, err)
+	}
+}
+
+func TestPost_ValidateGroupFields(t *testing.T) {
+	p := NewPost("author", "cid", "", nil)
+	p.GroupID = "g"
+	if err := p.Validate(); err == nil {
+		t.Error("Validate() should reject a GroupID without a KeyEpoch")
+	}
+	p.KeyEpoch = 1
+	if err := p.Validate(); err != nil {
+		t.Errorf("Validate() of a group post error = %v", err)
+	}
+	p.GroupID = ""
+	if err := p.Validate(); err == nil {
+		t.Error("Validate() should reject a KeyEpoch without a GroupID")
+	}
+}
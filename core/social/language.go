@@ -0,0 +1,254 @@
+package social
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// MaxLanguageTagLength is the longest Post.Language accepted, in bytes. It
+// fits any tag of a language, script, region and a couple of variants.
+const MaxLanguageTagLength = 35
+
+// UndeterminedLanguage is the BCP 47 tag for content in no particular
+// language. Passed to PostManager.SetLanguage, it turns off detection.
+const UndeterminedLanguage = "und"
+
+// ValidateLanguageTag checks that tag is a well-formed BCP 47 language tag
+// (RFC 5646), such as "en", "pt-BR" or "zh-Hant-TW". It checks syntax only:
+// subtags are not looked up in the IANA registry, and the irregular
+// grandfathered tags are not accepted.
+func ValidateLanguageTag(tag string) error {
+	_, err := CanonicalLanguageTag(tag)
+	return err
+}
+
+// CanonicalLanguageTag validates tag like ValidateLanguageTag and returns it
+// in the conventional case: language lower, script title and region upper
+// case, as in "zh-Hant-TW". Tags compare equal ignoring case, so this is for
+// display and storage rather than matching.
+func CanonicalLanguageTag(tag string) (string, error) {
+	if tag == "" {
+		return "", fmt.Errorf("empty language tag")
+	}
+	if len(tag) > MaxLanguageTagLength {
+		return "", fmt.Errorf("language tag is %d bytes, limit %d", len(tag), MaxLanguageTagLength)
+	}
+	subtags := strings.Split(strings.ToLower(tag), "-")
+	for i, s := range subtags {
+		if s == "" || len(s) > 8 || !isAlphanumeric(s) {
+			return "", fmt.Errorf("language tag %q: malformed subtag %d", tag, i)
+		}
+	}
+
+	// The states follow RFC 5646's langtag production; each subtag may only
+	// move the state forward.
+	const (
+		stateLanguage = iota
+		stateExtlang
+		stateScript
+		stateRegion
+		stateVariant
+		stateExtension
+		statePrivate
+	)
+	state, extlangs := stateLanguage, 0
+	for i, s := range subtags {
+		switch {
+		case state == statePrivate:
+			// Any 1*8alphanum.
+		case len(s) == 1:
+			if s == "x" {
+				state = statePrivate
+			} else if i == 0 {
+				return "", fmt.Errorf("language tag %q: must start with a language", tag)
+			} else {
+				state = stateExtension
+			}
+			if i == len(subtags)-1 || state == stateExtension && len(subtags[i+1]) == 1 {
+				return "", fmt.Errorf("language tag %q: empty singleton %q", tag, s)
+			}
+		case state == stateExtension:
+			if len(s) < 2 {
+				return "", fmt.Errorf("language tag %q: malformed extension", tag)
+			}
+		case i == 0:
+			if len(s) < 2 || !isAlpha(s) {
+				return "", fmt.Errorf("language tag %q: malformed language %q", tag, s)
+			}
+			state = stateScript
+			if len(s) <= 3 {
+				state = stateExtlang
+			}
+		case state == stateExtlang && len(s) == 3 && isAlpha(s) && extlangs < 3:
+			extlangs++
+		case state <= stateScript && len(s) == 4 && isAlpha(s):
+			subtags[i] = strings.ToUpper(s[:1]) + s[1:]
+			state = stateRegion
+		case state <= stateRegion && (len(s) == 2 && isAlpha(s) || len(s) == 3 && isDigits(s)):
+			subtags[i] = strings.ToUpper(s)
+			state = stateVariant
+		case len(s) >= 5 || len(s) == 4 && s[0] >= '0' && s[0] <= '9':
+			state = stateVariant
+		default:
+			return "", fmt.Errorf("language tag %q: unexpected subtag %q", tag, s)
+		}
+	}
+	return strings.Join(subtags, "-"), nil
+}
+
+func isAlphanumeric(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 'a' || s[i] > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// LanguageMatches reports whether tag falls under any of ranges, using
+// RFC 4647 basic filtering: a range matches the tag itself and every more
+// specific tag, so "en" matches "en" and "en-GB" but not "eng". The range "*"
+// matches any tag. An empty tag matches nothing.
+func LanguageMatches(tag string, ranges []string) bool {
+	if tag == "" {
+		return false
+	}
+	for _, r := range ranges {
+		if r == "*" || strings.EqualFold(tag, r) {
+			return true
+		}
+		if len(tag) > len(r) && tag[len(r)] == '-' && strings.EqualFold(tag[:len(r)], r) {
+			return true
+		}
+	}
+	return false
+}
+
+// languageScripts maps writing systems used by essentially one language to
+// that language. Scripts shared by many languages, such as Latin, Cyrillic
+// and Arabic, are left to the word lists or left undetected.
+var languageScripts = []struct {
+	table *unicode.RangeTable
+	tag   string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Thai, "th"},
+	{unicode.Georgian, "ka"},
+	{unicode.Armenian, "hy"},
+}
+
+// languageWords are common short words that are frequent in text of each
+// language and rare in the others.
+var languageWords = map[string][]string{
+	"en": {"the", "and", "is", "are", "was", "of", "to", "that", "it", "with", "for", "this", "you", "have"},
+	"es": {"el", "los", "las", "que", "y", "es", "por", "con", "para", "una", "del", "muy", "pero", "está"},
+	"fr": {"le", "les", "des", "est", "et", "une", "pour", "dans", "pas", "avec", "sur", "du", "je", "c'est"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "ich", "mit", "auf", "den", "zu", "sich"},
+	"pt": {"os", "que", "é", "um", "uma", "não", "com", "para", "do", "da", "em", "mas", "você", "muito"},
+	"it": {"il", "gli", "che", "di", "è", "un", "una", "non", "per", "della", "sono", "anche", "questo", "molto"},
+	"nl": {"het", "een", "en", "van", "niet", "dat", "met", "op", "ik", "zijn", "voor", "ook", "maar", "wel"},
+}
+
+// languageWordIndex maps each word in languageWords to its languages.
+var languageWordIndex = func() map[string][]string {
+	index := make(map[string][]string)
+	for tag, words := range languageWords {
+		for _, w := range words {
+			index[w] = append(index[w], tag)
+		}
+	}
+	return index
+}()
+
+// Thresholds for DetectLanguage to name a Latin-script language.
+const (
+	minLanguageWordHits = 3 // Common words of the winning language
+	minLanguageLead     = 2 // More than the runner-up
+)
+
+// DetectLanguage guesses the language of text and returns its BCP 47 tag, or
+// "" when it cannot tell. It is a cheap heuristic for posts whose author did
+// not say: a script used by a single language decides directly, and Latin
+// text is judged by its most common words, which needs a sentence or two.
+// Authors should be able to correct the guess.
+func DetectLanguage(text string) string {
+	var letters, han, kana int
+	scripts := make([]int, len(languageScripts))
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+			continue
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+			continue
+		}
+		for i, s := range languageScripts {
+			if unicode.Is(s.table, r) {
+				scripts[i]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	// Japanese mixes kana into Han text; Chinese has none.
+	if 2*(han+kana) > letters {
+		if kana > 0 {
+			return "ja"
+		}
+		return "zh"
+	}
+	for i, n := range scripts {
+		if 2*n > letters {
+			return languageScripts[i].tag
+		}
+	}
+
+	hits := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	}) {
+		for _, tag := range languageWordIndex[word] {
+			hits[tag]++
+		}
+	}
+	best, bestHits, runnerUp := "", 0, 0
+	for tag, n := range hits {
+		if n > bestHits {
+			best, bestHits, runnerUp = tag, n, bestHits
+		} else if n > runnerUp {
+			runnerUp = n
+		}
+	}
+	if bestHits < minLanguageWordHits || bestHits-runnerUp < minLanguageLead {
+		return ""
+	}
+	return best
+}
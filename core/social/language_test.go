@@ -0,0 +1,154 @@
+package social
+
+import (
+	"digisocialblock/core/content"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalLanguageTag(t *testing.T) {
+	for tag, want := range map[string]string{
+		"en":                 "en",
+		"EN-gb":              "en-GB",
+		"zh-hant-tw":         "zh-Hant-TW",
+		"es-419":             "es-419",
+		"zh-yue-HK":          "zh-yue-HK",
+		"sl-rozaj-biske":     "sl-rozaj-biske",
+		"de-CH-1901":         "de-CH-1901",
+		"en-US-u-ca-gregory": "en-US-u-ca-gregory",
+		"x-whatever":         "x-whatever",
+		"en-x-a-b":           "en-x-a-b",
+	} {
+		if got, err := CanonicalLanguageTag(tag); err != nil || got != want {
+			t.Errorf("CanonicalLanguageTag(%q) = %q, %v; want %q", tag, got, err, want)
+		}
+	}
+	for _, tag := range []string{
+		"", "e", "en_US", "en-", "-en", "1en", "en-GB-Latn", "en-GB-US", "en-u", "en-u-x-a",
+		"en-a-b-cd", "en-abcdefghi", "én", strings.Repeat("a", 2) + strings.Repeat("-abcdefgh", 4),
+	} {
+		if err := ValidateLanguageTag(tag); err == nil {
+			t.Errorf("ValidateLanguageTag(%q) succeeded, want an error", tag)
+		}
+	}
+}
+
+func TestLanguageMatches(t *testing.T) {
+	for _, tt := range []struct {
+		tag    string
+		ranges []string
+		want   bool
+	}{
+		{"en", []string{"en"}, true},
+		{"en-GB", []string{"EN"}, true},
+		{"eng", []string{"en"}, false},
+		{"en", []string{"en-GB"}, false},
+		{"pt-BR", []string{"es", "pt"}, true},
+		{"fr", []string{"*"}, true},
+		{"", []string{"*"}, false},
+	} {
+		if got := LanguageMatches(tt.tag, tt.ranges); got != tt.want {
+			t.Errorf("LanguageMatches(%q, %v) = %v, want %v", tt.tag, tt.ranges, got, tt.want)
+		}
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	for text, want := range map[string]string{
+		"The weather is lovely and I have to say that it was worth the trip.":    "en",
+		"El tiempo es muy bueno y los niños están en la playa con sus padres.":   "es",
+		"Je pense que c'est une bonne idée pour les enfants et pour nous.":       "fr",
+		"Ich glaube, dass das Wetter heute nicht so gut ist und die Sonne fehlt": "de",
+		"今日はとても良い天気ですね。":                                                         "ja",
+		"今天天气很好，我们去公园吧。":                                                         "zh",
+		"오늘 날씨가 정말 좋네요":                                                          "ko",
+		"Καλημέρα σε όλους":                                                      "el",
+		"ok":                                                                     "",
+		"https://example.com #tag 12345":                                         "",
+		"":                                                                       "",
+	} {
+		if got := DetectLanguage(text); got != want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestFeedService_GetFeedPageFiltersByLanguage(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+	var txs []*ledger.Transaction
+	for i, language := range []string{"en", "en-GB", "pt-BR", ""} {
+		post := NewPost(alice.Address, fmt.Sprintf("cid-%d", i), language, nil)
+		post.Language = language
+		payload, _ := post.ToPayload(ledger.PayloadFormatJSON)
+		txs = append(txs, graphTestTx(t, alice, ledger.PostCreated, payload))
+	}
+	bc.AddBlock(txs)
+	fs, _ := NewFeedService(nil)
+	if _, err := fs.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		languages []string
+		want      string
+	}{
+		{nil, "[ pt-BR en-GB en]"},
+		{[]string{"en"}, "[en-GB en]"},
+		{[]string{"pt", "en-GB"}, "[pt-BR en-GB]"},
+		{[]string{"*"}, "[pt-BR en-GB en]"},
+		{[]string{"fr"}, "[]"},
+	} {
+		page, err := fs.GetFeedPage(FeedQuery{Languages: tt.languages})
+		if err != nil {
+			t.Fatalf("GetFeedPage() error = %v", err)
+		}
+		if got := fmt.Sprint(feedPageTestTitles(page.Entries)); got != tt.want || page.Total != len(page.Entries) {
+			t.Errorf("GetFeedPage(%v) = %s of %d, want %s", tt.languages, got, page.Total, tt.want)
+		}
+	}
+
+	bad := NewPost(alice.Address, "cid-bad", "", nil)
+	bad.Language = "en_US"
+	if err := bad.Validate(); err == nil {
+		t.Error("Validate() accepted a malformed language tag")
+	}
+}
+
+func TestPostManager_SetLanguage(t *testing.T) {
+	dds := testutil.NewDDS(0)
+	publisher, _ := content.NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	pm, _ := NewPostManager(publisher)
+	wallet, _ := identity.NewWallet()
+	language := func(text string) string {
+		t.Helper()
+		tx, err := pm.CreatePost(wallet, text, "", nil)
+		if err != nil {
+			t.Fatalf("CreatePost() error = %v", err)
+		}
+		post, _ := PostFromPayload(tx.Payload)
+		return post.Language
+	}
+	const english = "This is the post that I have written for you."
+
+	if got := language(english); got != "en" {
+		t.Errorf("detected language = %q, want en", got)
+	}
+	if err := pm.SetLanguage("EN-gb"); err != nil {
+		t.Fatalf("SetLanguage() error = %v", err)
+	}
+	if got := language("Bonjour"); got != "en-GB" {
+		t.Errorf("language = %q, want the canonical tag the author set", got)
+	}
+	if err := pm.SetLanguage("en_GB"); err == nil {
+		t.Error("SetLanguage() accepted a malformed tag")
+	}
+	pm.SetLanguage(UndeterminedLanguage)
+	if got := language(english); got != "" {
+		t.Errorf("language with detection off = %q, want none", got)
+	}
+}
@@ -0,0 +1,343 @@
+package social
+
+import (
+	"digisocialblock/core/ledger"
+	"errors"
+	"fmt"
+	"sync"
+	"unicode/utf8"
+)
+
+func init() {
+	ledger.RegisterPayloadValidator(ledger.ListCreated, ValidateListCreationPayload)
+	ledger.RegisterPayloadValidator(ledger.ListMemberAdded, ValidateListMembershipPayload)
+	ledger.RegisterPayloadDecoder(ledger.ListCreated, ledger.DecodeInto(func() interface{} { return &ListCreation{} }))
+	ledger.RegisterPayloadDecoder(ledger.ListMemberAdded, ledger.DecodeInto(func() interface{} { return &ListMembership{} }))
+}
+
+// Limits on lists.
+const (
+	MaxListNameLength        = 64  // Characters
+	MaxListDescriptionLength = 512 // Characters
+	MaxListMembers           = 500
+)
+
+// ErrListIndexDiverged is returned by ListIndex.Sync when the block recorded
+// as processed last is no longer on the chain. Call Rebuild to recover.
+var ErrListIndexDiverged = errors.New("list index high-water mark does not match the chain")
+
+// Errors returned by ListIndex.ValidateListChange. Changes failing them are
+// not indexed.
+var (
+	ErrListNotFound = errors.New("list not found")
+	ErrNotListOwner = errors.New("only the list's owner may change it")
+	ErrListFull     = errors.New("list has the maximum number of members")
+)
+
+// ListCreation is the ListCreated payload: a named collection of accounts
+// curated by its owner. The list is identified by the ListCreated
+// transaction ID.
+type ListCreation struct {
+	OwnerPublicKey string `json:"ownerPublicKey"`
+	Name           string `json:"name"`
+	Description    string `json:"description,omitempty"`
+	Timestamp      int64  `json:"timestamp"`
+}
+
+// Validate checks that required fields are set and within limits.
+func (l *ListCreation) Validate() error {
+	if l.OwnerPublicKey == "" {
+		return fmt.Errorf("empty OwnerPublicKey")
+	}
+	if n := utf8.RuneCountInString(l.Name); n == 0 || n > MaxListNameLength {
+		return fmt.Errorf("name is %d characters, want 1 to %d", n, MaxListNameLength)
+	}
+	if n := utf8.RuneCountInString(l.Description); n > MaxListDescriptionLength {
+		return fmt.Errorf("description is %d characters, limit %d", n, MaxListDescriptionLength)
+	}
+	if l.Timestamp == 0 {
+		return fmt.Errorf("zero timestamp")
+	}
+	return nil
+}
+
+// ToPayload serializes the ListCreation as a ListCreated transaction payload
+// in the given format.
+func (l *ListCreation) ToPayload(format ledger.PayloadFormat) ([]byte, error) {
+	payload, err := ledger.EncodePayload(format, l)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode list payload: %w", err)
+	}
+	return payload, nil
+}
+
+// ListCreationFromPayload deserializes a ListCreated payload in either
+// payload format. The result must pass Validate.
+func ListCreationFromPayload(payload []byte) (*ListCreation, error) {
+	var l ListCreation
+	if err := ledger.DecodePayload(payload, &l); err != nil {
+		return nil, fmt.Errorf("failed to decode list payload: %w", err)
+	}
+	if err := l.Validate(); err != nil {
+		return nil, fmt.Errorf("decoded list is invalid: %w", err)
+	}
+	return &l, nil
+}
+
+// ValidateListCreationPayload is the ledger schema validator for ListCreated payloads.
+func ValidateListCreationPayload(payload []byte) error {
+	_, err := ListCreationFromPayload(payload)
+	return err
+}
+
+// ListMembership is the ListMemberAdded payload: the list's owner adding an
+// account to the list or, with Removed set, taking it off. Only the owner may
+// change a list, which indexes check against the chain (see
+// ListIndex.ValidateListChange).
+type ListMembership struct {
+	OwnerPublicKey  string `json:"ownerPublicKey"`
+	ListTxID        string `json:"listTxId"` // ListCreated transaction
+	MemberPublicKey string `json:"memberPublicKey"`
+	Removed         bool   `json:"removed,omitempty"`
+	Timestamp       int64  `json:"timestamp"`
+}
+
+// Validate checks that required fields are set and within limits.
+func (m *ListMembership) Validate() error {
+	if m.OwnerPublicKey == "" {
+		return fmt.Errorf("empty OwnerPublicKey")
+	}
+	if m.ListTxID == "" || len(m.ListTxID) > MaxTxIDLength {
+		return fmt.Errorf("ListTxID is %d bytes, want 1 to %d", len(m.ListTxID), MaxTxIDLength)
+	}
+	if m.MemberPublicKey == "" {
+		return fmt.Errorf("empty MemberPublicKey")
+	}
+	if m.Timestamp == 0 {
+		return fmt.Errorf("zero timestamp")
+	}
+	return nil
+}
+
+// ToPayload serializes the ListMembership as a ListMemberAdded transaction
+// payload in the given format.
+func (m *ListMembership) ToPayload(format ledger.PayloadFormat) ([]byte, error) {
+	payload, err := ledger.EncodePayload(format, m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode list membership payload: %w", err)
+	}
+	return payload, nil
+}
+
+// ListMembershipFromPayload deserializes a ListMemberAdded payload in either
+// payload format. The result must pass Validate.
+func ListMembershipFromPayload(payload []byte) (*ListMembership, error) {
+	var m ListMembership
+	if err := ledger.DecodePayload(payload, &m); err != nil {
+		return nil, fmt.Errorf("failed to decode list membership payload: %w", err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("decoded list membership is invalid: %w", err)
+	}
+	return &m, nil
+}
+
+// ValidateListMembershipPayload is the ledger schema validator for ListMemberAdded payloads.
+func ValidateListMembershipPayload(payload []byte) error {
+	_, err := ListMembershipFromPayload(payload)
+	return err
+}
+
+// ListEntry is a list recorded in the list index.
+type ListEntry struct {
+	TxID            string   `json:"txId"`
+	BlockIndex      int64    `json:"blockIndex"`
+	OwnerPublicKey  string   `json:"ownerPublicKey"`
+	Name            string   `json:"name"`
+	Description     string   `json:"description,omitempty"`
+	Timestamp       int64    `json:"timestamp"`
+	MemberAddresses []string `json:"memberAddresses"` // In the order they were added
+}
+
+// clone returns a copy of e that shares no memory with the index.
+func (e ListEntry) clone() ListEntry {
+	e.MemberAddresses = append([]string{}, e.MemberAddresses...)
+	return e
+}
+
+// ListIndex indexes lists and their members. It is kept in memory and updated
+// incrementally like the graph index.
+//
+// A list is indexed only if its owner is the transaction sender. Membership
+// changes are applied only if they pass ValidateListChange when their block
+// is indexed; adding a member twice, or removing one who is not on the list,
+// changes nothing. A ListIndex is safe for concurrent use.
+type ListIndex struct {
+	mu       sync.RWMutex
+	follower ledger.ChainFollower
+	lists    map[string]*ListEntry // List tx ID -> list
+	byOwner  map[string][]string   // Owner -> list tx IDs, in chain order
+}
+
+// NewListIndex creates an empty ListIndex. Call Sync to populate it.
+func NewListIndex() *ListIndex {
+	l := &ListIndex{}
+	l.reset()
+	return l
+}
+
+// reset clears the index. The caller must hold l.mu (or own l exclusively).
+func (l *ListIndex) reset() {
+	l.follower = ledger.NewChainFollower()
+	l.lists = make(map[string]*ListEntry)
+	l.byOwner = make(map[string][]string)
+}
+
+// HighWaterMark reports the last block whose list transactions have been
+// applied.
+func (l *ListIndex) HighWaterMark() (int64, string) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.follower.HighWaterMark()
+}
+
+// Sync applies list transactions from blocks added since the last call and
+// returns how many blocks it read, or ErrListIndexDiverged if the chain was
+// replaced.
+func (l *ListIndex) Sync(bc *ledger.Blockchain) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.follower.Sync(bc, "list index", ErrListIndexDiverged, l.indexBlock)
+}
+
+// Rebuild drops every list and replays the list transactions from genesis.
+func (l *ListIndex) Rebuild(bc *ledger.Blockchain) (int, error) {
+	if bc == nil {
+		return 0, fmt.Errorf("blockchain cannot be nil")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.reset()
+	return l.follower.Sync(bc, "list index", ErrListIndexDiverged, l.indexBlock)
+}
+
+// indexBlock adds the block's lists and membership changes to the index.
+func (l *ListIndex) indexBlock(block *ledger.Block) {
+	for _, tx := range block.Transactions {
+		if tx == nil {
+			continue
+		}
+		switch tx.Type {
+		case ledger.ListCreated:
+			c, err := ListCreationFromPayload(tx.Payload)
+			if err != nil || c.OwnerPublicKey != tx.SenderPublicKey {
+				continue
+			}
+			if _, dup := l.lists[tx.ID]; dup {
+				continue
+			}
+			l.lists[tx.ID] = &ListEntry{
+				TxID:            tx.ID,
+				BlockIndex:      block.Index,
+				OwnerPublicKey:  c.OwnerPublicKey,
+				Name:            c.Name,
+				Description:     c.Description,
+				Timestamp:       c.Timestamp,
+				MemberAddresses: []string{},
+			}
+			l.byOwner[c.OwnerPublicKey] = append(l.byOwner[c.OwnerPublicKey], tx.ID)
+		case ledger.ListMemberAdded:
+			m, err := l.listChangeLocked(tx)
+			if err != nil {
+				continue
+			}
+			entry := l.lists[m.ListTxID]
+			i := indexOf(entry.MemberAddresses, m.MemberPublicKey)
+			switch {
+			case m.Removed && i >= 0:
+				entry.MemberAddresses = append(entry.MemberAddresses[:i:i], entry.MemberAddresses[i+1:]...)
+			case !m.Removed && i < 0:
+				entry.MemberAddresses = append(entry.MemberAddresses, m.MemberPublicKey)
+			}
+		}
+	}
+}
+
+// ValidateListChange checks a ListMemberAdded transaction against the indexed
+// list it changes: the list must exist, the sender must be its owner, and an
+// addition must not take it past MaxListMembers. Clients and nodes can use it
+// to reject a change before submitting it; the index applies the same rules.
+func (l *ListIndex) ValidateListChange(tx *ledger.Transaction) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, err := l.listChangeLocked(tx)
+	return err
+}
+
+// listChangeLocked validates a membership change and returns it. The caller
+// must hold l.mu.
+func (l *ListIndex) listChangeLocked(tx *ledger.Transaction) (*ListMembership, error) {
+	if tx.Type != ledger.ListMemberAdded {
+		return nil, fmt.Errorf("transaction type %s is not a list change", tx.Type)
+	}
+	m, err := ListMembershipFromPayload(tx.Payload)
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := l.lists[m.ListTxID]
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("%w: %s", ErrListNotFound, m.ListTxID)
+	case m.OwnerPublicKey != tx.SenderPublicKey || m.OwnerPublicKey != entry.OwnerPublicKey:
+		return nil, fmt.Errorf("%w: list %s", ErrNotListOwner, m.ListTxID)
+	case !m.Removed && len(entry.MemberAddresses) >= MaxListMembers && indexOf(entry.MemberAddresses, m.MemberPublicKey) < 0:
+		return nil, fmt.Errorf("%w: list %s has %d", ErrListFull, m.ListTxID, MaxListMembers)
+	}
+	return m, nil
+}
+
+func indexOf(values []string, v string) int {
+	for i, s := range values {
+		if s == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetList returns the list created by transaction txID.
+func (l *ListIndex) GetList(txID string) (ListEntry, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	entry, ok := l.lists[txID]
+	if !ok {
+		return ListEntry{}, false
+	}
+	return entry.clone(), true
+}
+
+// GetListsByOwner returns the lists owner created, oldest first.
+func (l *ListIndex) GetListsByOwner(owner string) []ListEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	ids := l.byOwner[owner]
+	lists := make([]ListEntry, 0, len(ids))
+	for _, id := range ids {
+		lists = append(lists, l.lists[id].clone())
+	}
+	return lists
+}
+
+// GetListFeed returns up to limit posts by the members of the list created
+// by listTxID, newest first (see FeedService.GetAuthorsFeed). A limit <= 0
+// returns all posts.
+func (l *ListIndex) GetListFeed(feed *FeedService, listTxID string, limit int) ([]FeedEntry, error) {
+	if feed == nil {
+		return nil, fmt.Errorf("feed service cannot be nil")
+	}
+	list, ok := l.GetList(listTxID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrListNotFound, listTxID)
+	}
+	return feed.GetAuthorsFeed(list.MemberAddresses, limit), nil
+}
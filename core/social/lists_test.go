@@ -0,0 +1,96 @@
+package social
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func listTestCreate(t *testing.T, owner *identity.Wallet, name string) *ledger.Transaction {
+	t.Helper()
+	payload, _ := (&ListCreation{OwnerPublicKey: owner.Address, Name: name, Timestamp: 1}).ToPayload(ledger.PayloadFormatJSON)
+	return graphTestTx(t, owner, ledger.ListCreated, payload)
+}
+
+func listTestMember(t *testing.T, sender *identity.Wallet, listTxID, member string, removed bool) *ledger.Transaction {
+	t.Helper()
+	m := &ListMembership{OwnerPublicKey: sender.Address, ListTxID: listTxID, MemberPublicKey: member, Removed: removed, Timestamp: 1}
+	payload, _ := m.ToPayload(ledger.PayloadFormatCBOR)
+	return graphTestTx(t, sender, ledger.ListMemberAdded, payload)
+}
+
+func TestListCreation_PayloadValidation(t *testing.T) {
+	for name, l := range map[string]ListCreation{
+		"no owner":   {Name: "go", Timestamp: 1},
+		"no name":    {OwnerPublicKey: "owner", Timestamp: 1},
+		"long name":  {OwnerPublicKey: "owner", Name: string(make([]rune, MaxListNameLength+1)), Timestamp: 1},
+		"no instant": {OwnerPublicKey: "owner", Name: "go"},
+	} {
+		if err := l.Validate(); err == nil {
+			t.Errorf("%s: Validate() expected error, got nil", name)
+		}
+	}
+	if err := (&ListMembership{OwnerPublicKey: "owner", ListTxID: "list", Timestamp: 1}).Validate(); err == nil {
+		t.Error("ListMembership without a member: Validate() expected error, got nil")
+	}
+}
+
+func TestListIndex_OwnerOnlyMembershipAndListFeed(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	bob, _ := identity.NewWallet()
+	carol, _ := identity.NewWallet()
+	mallory, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+
+	list := listTestCreate(t, alice, "Gophers")
+	bobPost, carolPost := newSignedPostTx(t, bob, "cid-bob", ""), newSignedPostTx(t, carol, "cid-carol", "")
+	bc.AddBlock([]*ledger.Transaction{
+		list, bobPost, carolPost, newSignedPostTx(t, mallory, "cid-mallory", ""),
+		listTestMember(t, alice, list.ID, bob.Address, false),
+		listTestMember(t, alice, list.ID, carol.Address, false),
+		listTestMember(t, alice, list.ID, bob.Address, false), // Already a member.
+		listTestMember(t, mallory, list.ID, mallory.Address, false),
+	})
+	bc.AddBlock([]*ledger.Transaction{listTestMember(t, alice, list.ID, carol.Address, true)})
+	bobLater := newSignedPostTx(t, bob, "cid-bob-2", "")
+	bc.AddBlock([]*ledger.Transaction{bobLater})
+
+	lists := NewListIndex()
+	if _, err := lists.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	got, ok := lists.GetList(list.ID)
+	if !ok || got.Name != "Gophers" || !reflect.DeepEqual(got.MemberAddresses, []string{bob.Address}) {
+		t.Fatalf("GetList() = %+v, %v; want Gophers with bob only", got, ok)
+	}
+	if owned := lists.GetListsByOwner(alice.Address); len(owned) != 1 || owned[0].TxID != list.ID {
+		t.Errorf("GetListsByOwner(alice) = %+v, want the one list", owned)
+	}
+
+	for _, tt := range []struct {
+		tx   *ledger.Transaction
+		want error
+	}{
+		{listTestMember(t, mallory, list.ID, mallory.Address, false), ErrNotListOwner},
+		{listTestMember(t, alice, "missing", carol.Address, false), ErrListNotFound},
+		{listTestMember(t, alice, list.ID, carol.Address, false), nil},
+	} {
+		if err := lists.ValidateListChange(tt.tx); !errors.Is(err, tt.want) {
+			t.Errorf("ValidateListChange() error = %v, want %v", err, tt.want)
+		}
+	}
+
+	feed, _ := NewFeedService(nil)
+	if _, err := feed.Sync(bc); err != nil {
+		t.Fatalf("FeedService.Sync() error = %v", err)
+	}
+	entries, err := lists.GetListFeed(feed, list.ID, 0)
+	if err != nil || len(entries) != 2 || entries[0].TxID != bobLater.ID || entries[1].TxID != bobPost.ID {
+		t.Errorf("GetListFeed() = %+v, %v; want bob's two posts, newest first", entries, err)
+	}
+	if _, err := lists.GetListFeed(feed, "missing", 0); !errors.Is(err, ErrListNotFound) {
+		t.Errorf("GetListFeed() of an unknown list error = %v, want ErrListNotFound", err)
+	}
+}
@@ -1,32 +1,95 @@
 package social
 
 import (
+	"crypto/sha256"
+	"digisocialblock/core/ledger"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"time"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+// Limits on Post metadata fields, enforced for PostCreated payloads.
+const (
+	MaxPostTitleLength      = 256 // Characters
+	MaxPostTags             = 16
+	MaxPostTagLength        = 64  // Characters
+	MaxCIDLength            = 128 // Bytes; generous for any CID encoding
+	MaxGroupIDLength        = 128 // Bytes
+	MaxPostMedia            = 4
+	MaxOriginSourceLen      = 32   // Bytes
+	MaxOriginURLLength      = 512  // Bytes
+	MaxWebURLLength         = 2048 // Bytes
+	MaxPostCoAuthors        = 8
+	MaxContentWarningLength = 256 // Characters
+)
+
+func init() {
+	ledger.RegisterPayloadValidator(ledger.PostCreated, ValidatePostPayload)
+	ledger.RegisterPayloadDecoder(ledger.PostCreated, ledger.DecodeInto(func() interface{} { return &Post{} }))
+	ledger.RegisterCoSigners(ledger.PostCreated, postCoSigners)
+	ledger.RegisterCIDResolver(ledger.PostCreated, postCIDs)
+	ledger.RegisterInviteResolver(ledger.PostCreated, postInvite)
+}
+
 // Post represents the metadata of a user's post.
-// The actual content of the post is stored on DDS and referenced by ContentCID.
+// The actual content of the post is stored on DDS and referenced by ContentCID,
+// or served over HTTPS and referenced by WebSource.
 type Post struct {
-	AuthorPublicKey string   `json:"authorPublicKey"` // Hex-encoded public key of the post author
-	ContentCID      string   `json:"contentCID"`      // CID of the post content stored on DDS
-	Timestamp       int64    `json:"timestamp"`       // UnixNano timestamp of when the post was created (or this version)
-	Version         int      `json:"version"`         // Version of the post (for edits)
-	Title           string   `json:"title,omitempty"` // Optional title for the post
-	Tags            []string `json:"tags,omitempty"`  // Optional tags
+	AuthorPublicKey string      `json:"authorPublicKey"`          // Hex-encoded public key of the post author
+	ContentCID      string      `json:"contentCID"`               // CID of the post content stored on DDS
+	Timestamp       int64       `json:"timestamp"`                // UnixNano timestamp of when the post was created (or this version)
+	Version         int         `json:"version"`                  // Version of the post (for edits)
+	Title           string      `json:"title,omitempty"`          // Optional title for the post
+	Tags            []string    `json:"tags,omitempty"`           // Optional tags
+	GroupID         string      `json:"groupId,omitempty"`        // Set for group-only posts; content is a GroupEnvelope
+	KeyEpoch        int         `json:"keyEpoch,omitempty"`       // Group key epoch the content is encrypted under
+	Media           []string    `json:"media,omitempty"`          // CIDs of attached media on DDS
+	Origin          *PostOrigin `json:"origin,omitempty"`         // Set for posts imported from another network
+	WebSource       *WebSource  `json:"webSource,omitempty"`      // External HTTPS content, for posts without a ContentCID
+	PreviewCID      string      `json:"previewCID,omitempty"`     // CID of a link preview card on DDS (see linkpreview.Card)
+	CoAuthors       []string    `json:"coAuthors,omitempty"`      // Addresses of co-authors, each of whom must co-sign the transaction
+	ContentWarning  string      `json:"contentWarning,omitempty"` // Shown in place of the content until the viewer opens it
+	Sensitive       bool        `json:"sensitive,omitempty"`      // The content or media is sensitive, e.g. graphic or adult
+	Language        string      `json:"language,omitempty"`       // BCP 47 tag of the content's language, e.g. "en" or "pt-BR"
+	Invite          string      `json:"invite,omitempty"`         // ID of the InviteIssued transaction a new author redeems (see ledger.InvitePolicy)
 	// ReplyToPostCID  string   `json:"replyToPostCID,omitempty"` // If this post is a reply to another
 	// RepostOfPostCID string   `json:"repostOfPostCID,omitempty"`// If this is a repost
 }
 
+// PostOrigin records where an imported post was originally published. The
+// post's own Timestamp is when it was imported; Timestamp here is when it was
+// first posted on the source network.
+type PostOrigin struct {
+	Source    string `json:"source"`        // Source network, e.g. "twitter" or "mastodon"
+	URL       string `json:"url,omitempty"` // Original post URL, if known
+	Timestamp int64  `json:"timestamp"`     // UnixNano time of original publication
+}
+
+// WebSource references content served over HTTPS rather than published to
+// DDS. The hash pins the exact bytes, so any node can fetch, verify and mirror
+// them into DDS (see content.WebMirror).
+type WebSource struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"` // Lowercase hex SHA-256 of the content
+}
+
 // NewPost creates a new Post metadata instance.
 // authorPublicKey is the hex-encoded public key string.
 // contentCID is the CID of the post's actual content on DDS.
 func NewPost(authorPublicKey, contentCID, title string, tags []string) *Post {
+	return NewPostWithClock(ledger.SystemClock, authorPublicKey, contentCID, title, tags)
+}
+
+// NewPostWithClock is NewPost with the post stamped by clock.
+func NewPostWithClock(clock ledger.Clock, authorPublicKey, contentCID, title string, tags []string) *Post {
 	return &Post{
 		AuthorPublicKey: authorPublicKey,
 		ContentCID:      contentCID,
-		Timestamp:       time.Now().UnixNano(),
+		Timestamp:       clock.Now().UnixNano(),
 		Version:         1, // Initial version
 		Title:           title,
 		Tags:            tags,
@@ -43,24 +106,189 @@ func (p *Post) ToJSON() ([]byte, error) {
 }
 
 // PostFromJSON deserializes a JSON byte slice into a Post struct.
+// Post JSON is read from the chain, so it is decoded strictly (see
+// ledger.DecodePayloadJSON) and the result must pass Validate.
 func PostFromJSON(jsonData []byte) (*Post, error) {
 	var p Post
-	err := json.Unmarshal(jsonData, &p)
-	if err != nil {
+	if err := ledger.DecodePayloadJSON(jsonData, &p); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal JSON to post: %w", err)
 	}
-	// Basic validation
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("unmarshaled post is invalid: %w", err)
+	}
+	return &p, nil
+}
+
+// ToPayload serializes the Post as a PostCreated transaction payload in the
+// given format.
+func (p *Post) ToPayload(format ledger.PayloadFormat) ([]byte, error) {
+	if format == ledger.PayloadFormatJSON {
+		return p.ToJSON()
+	}
+	payload, err := ledger.EncodePayload(format, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode post payload: %w", err)
+	}
+	return payload, nil
+}
+
+// PostFromPayload deserializes a PostCreated payload in either payload format
+// (see ledger.DecodePayload). The result must pass Validate.
+func PostFromPayload(payload []byte) (*Post, error) {
+	var p Post
+	if err := ledger.DecodePayload(payload, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode post payload: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("decoded post is invalid: %w", err)
+	}
+	return &p, nil
+}
+
+// Validate checks that required fields are set and all fields are within limits.
+func (p *Post) Validate() error {
 	if p.AuthorPublicKey == "" {
-		return nil, fmt.Errorf("unmarshaled post has empty AuthorPublicKey")
+		return fmt.Errorf("empty AuthorPublicKey")
+	}
+	if p.ContentCID == "" && p.WebSource == nil {
+		return fmt.Errorf("empty ContentCID")
 	}
-	if p.ContentCID == "" {
-		return nil, fmt.Errorf("unmarshaled post has empty ContentCID")
+	if len(p.ContentCID) > MaxCIDLength {
+		return fmt.Errorf("ContentCID is %d bytes, limit %d", len(p.ContentCID), MaxCIDLength)
 	}
 	if p.Timestamp == 0 {
-		return nil, fmt.Errorf("unmarshaled post has zero timestamp")
+		return fmt.Errorf("zero timestamp")
 	}
 	if p.Version <= 0 {
-		return nil, fmt.Errorf("unmarshaled post has invalid version: %d", p.Version)
+		return fmt.Errorf("invalid version: %d", p.Version)
 	}
-	return &p, nil
+	if n := utf8.RuneCountInString(p.Title); n > MaxPostTitleLength {
+		return fmt.Errorf("title is %d characters, limit %d", n, MaxPostTitleLength)
+	}
+	if len(p.Tags) > MaxPostTags {
+		return fmt.Errorf("post has %d tags, limit %d", len(p.Tags), MaxPostTags)
+	}
+	for i, tag := range p.Tags {
+		if tag == "" {
+			return fmt.Errorf("tag %d is empty", i)
+		}
+		if n := utf8.RuneCountInString(tag); n > MaxPostTagLength {
+			return fmt.Errorf("tag %d is %d characters, limit %d", i, n, MaxPostTagLength)
+		}
+	}
+	if len(p.GroupID) > MaxGroupIDLength {
+		return fmt.Errorf("GroupID is %d bytes, limit %d", len(p.GroupID), MaxGroupIDLength)
+	}
+	if (p.GroupID == "") != (p.KeyEpoch == 0) || p.KeyEpoch < 0 {
+		return fmt.Errorf("group posts need both GroupID and a positive KeyEpoch")
+	}
+	if len(p.Media) > MaxPostMedia {
+		return fmt.Errorf("post has %d media attachments, limit %d", len(p.Media), MaxPostMedia)
+	}
+	for i, cid := range p.Media {
+		if cid == "" || len(cid) > MaxCIDLength {
+			return fmt.Errorf("media CID %d is %d bytes, want 1 to %d", i, len(cid), MaxCIDLength)
+		}
+	}
+	if len(p.PreviewCID) > MaxCIDLength {
+		return fmt.Errorf("PreviewCID is %d bytes, limit %d", len(p.PreviewCID), MaxCIDLength)
+	}
+	if p.PreviewCID != "" && p.GroupID != "" {
+		// A clear-text preview would reveal the link in an encrypted post.
+		return fmt.Errorf("group posts cannot have a link preview")
+	}
+	if n := utf8.RuneCountInString(p.ContentWarning); n > MaxContentWarningLength {
+		return fmt.Errorf("content warning is %d characters, limit %d", n, MaxContentWarningLength)
+	}
+	if strings.IndexFunc(p.ContentWarning, unicode.IsControl) >= 0 {
+		return fmt.Errorf("content warning contains control characters")
+	}
+	if p.Language != "" {
+		if err := ValidateLanguageTag(p.Language); err != nil {
+			return fmt.Errorf("invalid language: %w", err)
+		}
+	}
+	if len(p.Invite) > MaxCIDLength {
+		return fmt.Errorf("Invite is %d bytes, limit %d", len(p.Invite), MaxCIDLength)
+	}
+	if len(p.CoAuthors) > MaxPostCoAuthors {
+		return fmt.Errorf("post has %d co-authors, limit %d", len(p.CoAuthors), MaxPostCoAuthors)
+	}
+	for i, coAuthor := range p.CoAuthors {
+		if coAuthor == "" {
+			return fmt.Errorf("co-author %d is empty", i)
+		}
+		if coAuthor == p.AuthorPublicKey {
+			return fmt.Errorf("co-author %d is the post author", i)
+		}
+		for _, earlier := range p.CoAuthors[:i] {
+			if earlier == coAuthor {
+				return fmt.Errorf("co-author %d is listed twice", i)
+			}
+		}
+	}
+	if o := p.Origin; o != nil {
+		if o.Source == "" || len(o.Source) > MaxOriginSourceLen {
+			return fmt.Errorf("origin source is %d bytes, want 1 to %d", len(o.Source), MaxOriginSourceLen)
+		}
+		if len(o.URL) > MaxOriginURLLength {
+			return fmt.Errorf("origin URL is %d bytes, limit %d", len(o.URL), MaxOriginURLLength)
+		}
+		if o.Timestamp <= 0 {
+			return fmt.Errorf("origin timestamp must be positive")
+		}
+	}
+	if w := p.WebSource; w != nil {
+		if len(w.URL) > MaxWebURLLength {
+			return fmt.Errorf("web source URL is %d bytes, limit %d", len(w.URL), MaxWebURLLength)
+		}
+		if u, err := url.Parse(w.URL); err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("web source URL %q is not an absolute https URL", w.URL)
+		}
+		if len(w.SHA256) != 2*sha256.Size || strings.ToLower(w.SHA256) != w.SHA256 {
+			return fmt.Errorf("web source hash must be %d lowercase hex characters", 2*sha256.Size)
+		}
+		if _, err := hex.DecodeString(w.SHA256); err != nil {
+			return fmt.Errorf("web source hash is not hex: %w", err)
+		}
+	}
+	return nil
+}
+
+// ValidatePostPayload is the ledger schema validator for PostCreated payloads:
+// the payload must be exactly a valid Post, with no unknown fields, in either
+// payload format.
+func ValidatePostPayload(payload []byte) error {
+	_, err := PostFromPayload(payload)
+	return err
+}
+
+// postCoSigners is the ledger co-signer resolver for PostCreated: every
+// co-author must sign the post.
+func postCoSigners(payload []byte) ([]string, error) {
+	p, err := PostFromPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	return p.CoAuthors, nil
+}
+
+// postCIDs is the ledger CID resolver for PostCreated: the post's content,
+// media and link preview.
+func postCIDs(payload []byte) ([]string, error) {
+	p, err := PostFromPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{p.ContentCID, p.PreviewCID}, p.Media...), nil
+}
+
+// postInvite is the ledger invite resolver for PostCreated: a new author's
+// first post may redeem an invite.
+func postInvite(payload []byte) (string, error) {
+	p, err := PostFromPayload(payload)
+	if err != nil {
+		return "", err
+	}
+	return p.Invite, nil
 }
@@ -0,0 +1,93 @@
+package social
+
+import (
+	"context"
+	"digisocialblock/core/content"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type postManagerTestPreviewer struct {
+	cid string
+	err error
+}
+
+func (p postManagerTestPreviewer) PreviewText(ctx context.Context, text string) (string, error) {
+	return p.cid, p.err
+}
+
+func TestPostManager_CreatePostAttachesLinkPreview(t *testing.T) {
+	dds := testutil.NewDDS(0)
+	publisher, _ := content.NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	pm, _ := NewPostManager(publisher)
+	wallet, _ := identity.NewWallet()
+
+	pm.SetLinkPreviewer(postManagerTestPreviewer{cid: "preview_cid"})
+	tx, err := pm.CreatePost(wallet, "see https://example.com/", "", nil)
+	if err != nil {
+		t.Fatalf("CreatePost() error = %v", err)
+	}
+	if post, err := PostFromPayload(tx.Payload); err != nil || post.PreviewCID != "preview_cid" {
+		t.Errorf("PostFromPayload() = %+v, %v; want PreviewCID preview_cid", post, err)
+	}
+
+	// A failed preview does not stop the post.
+	pm.SetLinkPreviewer(postManagerTestPreviewer{err: fmt.Errorf("site down")})
+	tx, err = pm.CreatePost(wallet, "see https://example.com/", "", nil)
+	if err != nil {
+		t.Fatalf("CreatePost() with a failing previewer error = %v", err)
+	}
+	if post, _ := PostFromPayload(tx.Payload); post.PreviewCID != "" {
+		t.Errorf("PreviewCID = %q after a failed preview, want empty", post.PreviewCID)
+	}
+}
+
+func TestPostManager_CreateCoAuthoredPost(t *testing.T) {
+	dds := testutil.NewDDS(0)
+	publisher, _ := content.NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	pm, _ := NewPostManager(publisher)
+	alice, _ := identity.NewWallet()
+	bob, _ := identity.NewWallet()
+
+	if _, err := pm.CreateCoAuthoredPost(alice, "hello", "", nil, []string{alice.Address}); err == nil {
+		t.Error("CreateCoAuthoredPost() listing the author as co-author: expected error, got nil")
+	}
+	tx, err := pm.CreateCoAuthoredPost(alice, "hello", "", nil, []string{bob.Address})
+	if err != nil {
+		t.Fatalf("CreateCoAuthoredPost() error = %v", err)
+	}
+	if _, err := tx.VerifySignature(); !errors.Is(err, ledger.ErrInvalidCoSignatures) {
+		t.Errorf("VerifySignature() before bob co-signs error = %v, want ErrInvalidCoSignatures", err)
+	}
+	if err := bob.CoSignTransaction(tx); err != nil {
+		t.Fatalf("CoSignTransaction() error = %v", err)
+	}
+	if err := tx.IsValid(); err != nil {
+		t.Errorf("IsValid() after bob co-signs error = %v", err)
+	}
+}
+
+func TestPostManager_SetClockStampsPostAndTransaction(t *testing.T) {
+	dds := testutil.NewDDS(0)
+	publisher, _ := content.NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	pm, _ := NewPostManager(publisher)
+	wallet, _ := identity.NewWallet()
+	pm.SetClock(testutil.NewClock(time.Second))
+
+	tx, err := pm.CreatePost(wallet, "hello", "", nil)
+	if err != nil {
+		t.Fatalf("CreatePost() error = %v", err)
+	}
+	post, err := PostFromPayload(tx.Payload)
+	if err != nil {
+		t.Fatalf("PostFromPayload() error = %v", err)
+	}
+	if post.Timestamp != testutil.ClockStart.UnixNano() || tx.Timestamp != testutil.ClockStart.Add(time.Second).UnixNano() {
+		t.Errorf("post and transaction stamped %d and %d, want the clock's first two times", post.Timestamp, tx.Timestamp)
+	}
+}
@@ -1,6 +1,7 @@
 package social
 
 import (
+	"context"
 	"digisocialblock/core/content"
 	"digisocialblock/core/identity"
 	"digisocialblock/core/ledger"
@@ -10,6 +11,10 @@ import (
 // PostManager handles the business logic for creating and managing posts.
 type PostManager struct {
 	publisher *content.ContentPublisher
+	format    ledger.PayloadFormat // Encoding of PostCreated payloads; JSON by default
+	clock     ledger.Clock         // Stamps posts and their transactions
+	previewer LinkPreviewer        // Optional
+	language  string               // Stamped on posts; "" detects it from the text
 	// Potentially a ContentRetriever if PostManager also handles fetching post content details
 	// For now, focusing on creation.
 }
@@ -21,13 +26,60 @@ func NewPostManager(publisher *content.ContentPublisher) (*PostManager, error) {
 	}
 	return &PostManager{
 		publisher: publisher,
+		clock:     ledger.SystemClock,
 	}, nil
 }
 
+// SetPayloadFormat selects the encoding of the PostCreated payloads this
+// manager creates. Validators and the feed accept either format.
+func (pm *PostManager) SetPayloadFormat(format ledger.PayloadFormat) {
+	pm.format = format
+}
+
+// SetClock sets the clock that stamps the posts and transactions this manager
+// creates. A nil clock restores ledger.SystemClock.
+func (pm *PostManager) SetClock(clock ledger.Clock) {
+	if clock == nil {
+		clock = ledger.SystemClock
+	}
+	pm.clock = clock
+}
+
+// SetLanguage sets the BCP 47 language tag stamped on the posts this manager
+// creates, typically the author's chosen posting language. By default, or
+// after SetLanguage(""), each post's language is guessed from its text with
+// DetectLanguage and left unset when the guess is unsure. UndeterminedLanguage
+// turns detection off, so posts carry no language.
+func (pm *PostManager) SetLanguage(tag string) error {
+	if tag != "" {
+		canonical, err := CanonicalLanguageTag(tag)
+		if err != nil {
+			return err
+		}
+		tag = canonical
+	}
+	pm.language = tag
+	return nil
+}
+
+// LinkPreviewer publishes a preview card for the first link in a post's text
+// and returns the card's CID, or "" if the text has no link.
+// linkpreview.Service implements it.
+type LinkPreviewer interface {
+	PreviewText(ctx context.Context, text string) (string, error)
+}
+
+// SetLinkPreviewer makes CreatePost attach link previews. Previews are best
+// effort: a post whose link cannot be previewed is created without one.
+func (pm *PostManager) SetLinkPreviewer(previewer LinkPreviewer) {
+	pm.previewer = previewer
+}
+
 // CreatePost handles the full process of creating a user post:
 // 1. Publishes the raw text content to DDS to get a ContentCID.
-// 2. Creates Post metadata (including AuthorPublicKey and ContentCID).
-// 3. Serializes the Post metadata to JSON to be used as transaction payload.
+// 2. Creates Post metadata (including AuthorPublicKey and ContentCID, the
+// language (see SetLanguage), and a link preview if SetLinkPreviewer was called).
+// 3. Serializes the Post metadata (JSON unless SetPayloadFormat says otherwise) to be used as transaction payload.
 // 4. Creates a new ledger.Transaction of type "PostCreated".
 // 5. Signs the transaction using the user's wallet.
 // Returns the signed ledger.Transaction, ready to be added to the blockchain.
@@ -36,6 +88,21 @@ func (pm *PostManager) CreatePost(
 	rawTextContent string,
 	title string, // Optional title
 	tags []string, // Optional tags
+) (*ledger.Transaction, error) {
+	return pm.CreateCoAuthoredPost(wallet, rawTextContent, title, tags, nil)
+}
+
+// CreateCoAuthoredPost is CreatePost for a post written together with the
+// wallets at the coAuthors addresses. The returned transaction is signed by
+// wallet only; each co-author must add their signature with
+// identity.Wallet.CoSignTransaction before it is submitted, as the ledger
+// rejects a co-authored post that is missing any of them.
+func (pm *PostManager) CreateCoAuthoredPost(
+	wallet *identity.Wallet,
+	rawTextContent string,
+	title string,
+	tags []string,
+	coAuthors []string,
 ) (*ledger.Transaction, error) {
 	if wallet == nil {
 		return nil, fmt.Errorf("wallet cannot be nil to create a post")
@@ -56,16 +123,48 @@ func (pm *PostManager) CreatePost(
 	}
 
 	// 2. Create Post metadata struct
-	postMeta := NewPost(wallet.Address, contentCID, title, tags)
+	postMeta, err := pm.newPostMeta(wallet, contentCID, rawTextContent, title, tags, coAuthors)
+	if err != nil {
+		return nil, err
+	}
+	return pm.signPost(wallet, postMeta, rawTextContent)
+}
+
+// newPostMeta returns the validated metadata of a post by wallet of the text
+// published under contentCID, with its language (see SetLanguage).
+func (pm *PostManager) newPostMeta(wallet *identity.Wallet, contentCID, rawTextContent, title string, tags, coAuthors []string) (*Post, error) {
+	postMeta := NewPostWithClock(pm.clock, wallet.Address, contentCID, title, tags)
+	postMeta.CoAuthors = coAuthors
+	switch pm.language {
+	case "":
+		postMeta.Language = DetectLanguage(rawTextContent)
+	case UndeterminedLanguage:
+	default:
+		postMeta.Language = pm.language
+	}
+	if err := postMeta.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid post metadata: %w", err)
+	}
+	return postMeta, nil
+}
+
+// signPost attaches a link preview to postMeta, if SetLinkPreviewer was
+// called, and returns it as a PostCreated transaction signed by wallet.
+func (pm *PostManager) signPost(wallet *identity.Wallet, postMeta *Post, rawTextContent string) (*ledger.Transaction, error) {
+	if pm.previewer != nil {
+		if previewCID, err := pm.previewer.PreviewText(context.Background(), rawTextContent); err == nil {
+			postMeta.PreviewCID = previewCID
+		}
+	}
 
-	// 3. Serialize Post metadata to JSON for the transaction payload
-	postPayloadJSON, err := postMeta.ToJSON()
+	// 3. Serialize Post metadata for the transaction payload
+	postPayload, err := postMeta.ToPayload(pm.format)
 	if err != nil {
-		return nil, fmt.Errorf("failed to serialize post metadata to JSON: %w", err)
+		return nil, fmt.Errorf("failed to serialize post metadata: %w", err)
 	}
 
 	// 4. Create a new ledger.Transaction
-	tx, err := ledger.NewTransaction(wallet.Address, ledger.PostCreated, postPayloadJSON)
+	tx, err := ledger.NewTransactionWithClock(pm.clock, wallet.Address, ledger.PostCreated, postPayload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create new ledger transaction for post: %w", err)
 	}
@@ -4,11 +4,9 @@ import (
 	"digisocialblock/core/content"
 	"digisocialblock/core/identity"
 	"digisocialblock/core/ledger"
-	// "digisocialblock/pkg/dds/chunking" // For direct mock if needed
-	// "digisocialblock/pkg/dds/storage"   // For direct mock if needed
+	"digisocialblock/internal/testutil"
 	"encoding/json"
 	"fmt"
-	"io"
 	"reflect"
 	"sync"
 	"testing"
@@ -46,31 +44,18 @@ func min(a, b int) int {
 }
 
 
-// --- Mock DDS components (needed if not mocking ContentPublisher directly) ---
-// These are needed to initialize a real ContentPublisher if we don't mock it.
-// For a more focused unit test of PostManager, mocking ContentPublisher is better.
-
-type MockPMDDSChunker struct{}
-func (m *MockPMDDSChunker) ChunkData(data io.Reader) (*chunking.ContentManifestV1, []chunking.DataChunk, error) {
-	return &chunking.ContentManifestV1{ManifestCID: "dummy_chunker_cid_for_pm_test"}, nil, nil
-}
-type MockPMDDSStorage struct{}
-func (m *MockPMDDSStorage) StoreChunk(chunkID string, data []byte) error { return nil }
-func (m *MockPMDDSStorage) RetrieveChunk(chunkID string) ([]byte, error) { return []byte("dummy_chunk_data"), nil }
-func (m *MockPMDDSStorage) ChunkExists(chunkID string) bool { return true }
-type MockPMOriginatorAdvertiser struct{}
-func (m *MockPMOriginatorAdvertiser) AdvertiseManifest(manifest *chunking.ContentManifestV1) error {return nil}
-
 
 func TestNewPostManager(t *testing.T) {
 	// Use the mock ContentPublisher for this test
 	mockPub := &MockPostManagerContentPublisher{}
+	_ = mockPub // Not injectable while NewPostManager takes a concrete publisher
 
 	// Cast the mock to the concrete type expected by NewPostManager's current signature.
 	// This is okay if MockPostManagerContentPublisher has the same method signature.
 	// Ideally, NewPostManager would take an interface.
 	// For now, we create a real ContentPublisher that uses deeper mocks.
-	realPublisher, _ := content.NewContentPublisher(&MockPMDDSChunker{}, &MockPMDDSStorage{}, &MockPMOriginatorAdvertiser{})
+	dds := testutil.NewDDS(0)
+	realPublisher, _ := content.NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
 
 
 	_, err := NewPostManager(nil)
@@ -92,6 +77,7 @@ func TestNewPostManager(t *testing.T) {
 func TestPostManager_CreatePost(t *testing.T) {
 	// Mock ContentPublisher directly for more focused testing of PostManager logic
 	mockPublisher := &MockPostManagerContentPublisher{}
+	_ = mockPublisher // See TestNewPostManager
 
 	// Since NewPostManager expects a concrete *content.ContentPublisher,
 	// we need to create a real one but ensure its *internal* behavior is controlled.
@@ -105,10 +91,8 @@ func TestPostManager_CreatePost(t *testing.T) {
 	// For now, we'll proceed with the assumption that the test setup can control ContentPublisher's behavior.
 
 	// Create a real ContentPublisher that uses further mocks for its dependencies
-	testDDSChunker := &MockPMDDSChunker{}
-	testDDSStorage := &MockPMDDSStorage{}
-	testDDSOriginator := &MockPMOriginatorAdvertiser{}
-	testContentPublisher, _ := content.NewContentPublisher(testDDSChunker, testDDSStorage, testDDSOriginator)
+	dds := testutil.NewDDS(0)
+	testContentPublisher, _ := content.NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
 
 
 	pm, _ := NewPostManager(testContentPublisher)
@@ -178,7 +162,7 @@ func TestPostManager_CreatePost(t *testing.T) {
 		t.Errorf("Post metadata AuthorPublicKey = %s, want %s", postMeta.AuthorPublicKey, wallet.Address)
 	}
 	// The CID check depends on what the mock chunker (used by testContentPublisher) returns.
-	// The mockChunker in test_content_posting/main.go generates CIDs like "test_content_manifest_..."
+	// testutil's chunker generates CIDs like "test_manifest_..."
 	// Let's check if it's non-empty for this unit test, as exact match is tricky without running the chunker here.
 	if postMeta.ContentCID == "" {
 		t.Errorf("Post metadata ContentCID is empty, expected a CID from publisher")
@@ -0,0 +1,72 @@
+package social
+
+import (
+	"context"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"fmt"
+	"strings"
+)
+
+// StagedPost is a post whose content is stored locally but not yet public
+// (see content.ContentPublisher.Stage). The author previews it by retrieving
+// Post.ContentCID from the local storage, then publishes it with
+// PostManager.CommitPost or drops it with PostManager.DiscardPost.
+type StagedPost struct {
+	Post *Post  // Metadata as it will be published; ContentCID is provisional until committed
+	Text string // The staged text, for previews and the link preview made on commit
+}
+
+// StagePost is the first phase of a two-phase CreatePost: it stores the
+// post's content without advertising it and returns the post as it would be
+// published. No transaction is made and the content is not public until
+// CommitPost, so an author can review a post, e.g. one written for a group
+// by mistake, before anyone can see it. Link previews, which publish a card
+// of their own, are made on commit.
+func (pm *PostManager) StagePost(wallet *identity.Wallet, rawTextContent, title string, tags []string) (*StagedPost, error) {
+	if wallet == nil {
+		return nil, fmt.Errorf("wallet cannot be nil to create a post")
+	}
+	if rawTextContent == "" {
+		return nil, fmt.Errorf("raw text content cannot be empty for a post")
+	}
+	result, err := pm.publisher.Stage(context.Background(), strings.NewReader(rawTextContent))
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage post content: %w", err)
+	}
+	postMeta, err := pm.newPostMeta(wallet, result.ManifestCID, rawTextContent, title, tags, nil)
+	if err != nil {
+		pm.publisher.Discard(result.ManifestCID)
+		return nil, err
+	}
+	return &StagedPost{Post: postMeta, Text: rawTextContent}, nil
+}
+
+// CommitPost is the second phase of a two-phase CreatePost: it advertises the
+// staged content and returns the post as a signed PostCreated transaction,
+// ready to be added to the blockchain, stamped with the time of the commit.
+// wallet must be the wallet the post was staged with. If the content cannot
+// be advertised it stays staged and CommitPost can be retried.
+func (pm *PostManager) CommitPost(wallet *identity.Wallet, staged *StagedPost) (*ledger.Transaction, error) {
+	if wallet == nil || staged == nil || staged.Post == nil {
+		return nil, fmt.Errorf("wallet and staged post cannot be nil")
+	}
+	if staged.Post.AuthorPublicKey != wallet.Address {
+		return nil, fmt.Errorf("post was staged by %s, not %s", staged.Post.AuthorPublicKey, wallet.Address)
+	}
+	if err := pm.publisher.Commit(context.Background(), staged.Post.ContentCID); err != nil {
+		return nil, fmt.Errorf("failed to commit post content: %w", err)
+	}
+	postMeta := *staged.Post
+	postMeta.Timestamp = pm.clock.Now().UnixNano()
+	return pm.signPost(wallet, &postMeta, staged.Text)
+}
+
+// DiscardPost drops a staged post without publishing it, and reports whether
+// it was still staged.
+func (pm *PostManager) DiscardPost(staged *StagedPost) bool {
+	if staged == nil || staged.Post == nil {
+		return false
+	}
+	return pm.publisher.Discard(staged.Post.ContentCID)
+}
@@ -0,0 +1,50 @@
+package social
+
+import (
+	"digisocialblock/core/content"
+	"digisocialblock/core/identity"
+	"digisocialblock/internal/testutil"
+	"testing"
+)
+
+func TestPostManager_StageCommitAndDiscard(t *testing.T) {
+	dds := testutil.NewDDS(16)
+	publisher, _ := content.NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	pm, _ := NewPostManager(publisher)
+	clock := testutil.NewClock(0)
+	pm.SetClock(clock)
+	alice, _ := identity.NewWallet()
+	bob, _ := identity.NewWallet()
+
+	staged, err := pm.StagePost(alice, "The weather is nice and the sun is out in the park", "Draft", []string{"go"})
+	if err != nil {
+		t.Fatalf("StagePost() error = %v", err)
+	}
+	if len(dds.Originator.Advertised()) != 0 || staged.Post.ContentCID == "" || staged.Post.Language != "en" {
+		t.Fatalf("StagePost() = %+v with %v advertised; want an unadvertised English post", staged.Post, dds.Originator.Advertised())
+	}
+	if _, err := pm.CommitPost(bob, staged); err == nil {
+		t.Error("CommitPost() by another wallet succeeded")
+	}
+
+	clock.Advance(1000)
+	tx, err := pm.CommitPost(alice, staged)
+	if err != nil {
+		t.Fatalf("CommitPost() error = %v", err)
+	}
+	post, err := PostFromPayload(tx.Payload)
+	if err != nil || post.ContentCID != staged.Post.ContentCID || post.Timestamp <= staged.Post.Timestamp {
+		t.Errorf("committed post = %+v, %v; want the staged content stamped at commit", post, err)
+	}
+	if advertised := dds.Originator.Advertised(); len(advertised) != 1 || advertised[0] != post.ContentCID {
+		t.Errorf("Advertised() = %v, want the committed content", advertised)
+	}
+
+	dropped, _ := pm.StagePost(alice, "on second thoughts", "", nil)
+	if !pm.DiscardPost(dropped) {
+		t.Error("DiscardPost() of a staged post = false")
+	}
+	if _, err := pm.CommitPost(alice, dropped); err == nil {
+		t.Error("CommitPost() of a discarded post succeeded")
+	}
+}
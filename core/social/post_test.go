@@ -1,8 +1,12 @@
 package social
 
 import (
+	"digisocialblock/core/clientkit"
+	"digisocialblock/core/ledger"
 	"encoding/json"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -92,3 +96,185 @@ func TestPost_ToJSON_And_PostFromJSON(t *testing.T) {
 		t.Error("PostFromJSON with invalid version (0): expected error, got nil")
 	}
 }
+
+func TestValidatePostPayload(t *testing.T) {
+	valid, _ := NewPost("author", "cid", "title", []string{"go"}).ToJSON()
+	if err := ValidatePostPayload(valid); err != nil {
+		t.Fatalf("ValidatePostPayload() on a valid post error = %v", err)
+	}
+
+	tooManyTags := make([]string, MaxPostTags+1)
+	for i := range tooManyTags {
+		tooManyTags[i] = "t"
+	}
+	longTitle, _ := NewPost("author", "cid", strings.Repeat("é", MaxPostTitleLength+1), nil).ToJSON()
+	manyTags, _ := NewPost("author", "cid", "", tooManyTags).ToJSON()
+	longTag, _ := NewPost("author", "cid", "", []string{strings.Repeat("t", MaxPostTagLength+1)}).ToJSON()
+	unknownField := []byte(`{"authorPublicKey":"a","contentCID":"c","timestamp":1,"version":1,"admin":true}`)
+	manyMediaPost := NewPost("author", "cid", "", nil)
+	manyMediaPost.Media = []string{"m1", "m2", "m3", "m4", "m5"}
+	manyMedia, _ := manyMediaPost.ToJSON()
+	undatedPost := NewPost("author", "cid", "", nil)
+	undatedPost.Origin = &PostOrigin{Source: "twitter"}
+	undated, _ := undatedPost.ToJSON()
+	webHash := strings.Repeat("ab", 32)
+	httpPost := NewPost("author", "", "", nil)
+	httpPost.WebSource = &WebSource{URL: "http://example.com/a.txt", SHA256: webHash}
+	httpSource, _ := httpPost.ToJSON()
+	badHashPost := NewPost("author", "", "", nil)
+	badHashPost.WebSource = &WebSource{URL: "https://example.com/a.txt", SHA256: strings.ToUpper(webHash)}
+	badHash, _ := badHashPost.ToJSON()
+	groupPreviewPost := NewPost("author", "cid", "", nil)
+	groupPreviewPost.GroupID, groupPreviewPost.KeyEpoch, groupPreviewPost.PreviewCID = "club", 1, "preview_cid"
+	groupPreview, _ := groupPreviewPost.ToJSON()
+	selfCoAuthorPost := NewPost("author", "cid", "", nil)
+	selfCoAuthorPost.CoAuthors = []string{"coauthor", "author"}
+	selfCoAuthor, _ := selfCoAuthorPost.ToJSON()
+	repeatedCoAuthorPost := NewPost("author", "cid", "", nil)
+	repeatedCoAuthorPost.CoAuthors = []string{"coauthor", "coauthor"}
+	repeatedCoAuthor, _ := repeatedCoAuthorPost.ToJSON()
+	longWarningPost := NewPost("author", "cid", "", nil)
+	longWarningPost.ContentWarning = strings.Repeat("w", MaxContentWarningLength+1)
+	longWarning, _ := longWarningPost.ToJSON()
+	controlWarningPost := NewPost("author", "cid", "", nil)
+	controlWarningPost.ContentWarning = "spoilers\r\nX-Injected: 1"
+	controlWarning, _ := controlWarningPost.ToJSON()
+	for name, payload := range map[string][]byte{
+		"long title":      longTitle,
+		"too many tags":   manyTags,
+		"long tag":        longTag,
+		"unknown field":   unknownField,
+		"too many media":  manyMedia,
+		"undated origin":  undated,
+		"http web URL":    httpSource,
+		"bad web hash":    badHash,
+		"group preview":   groupPreview,
+		"self co-author":  selfCoAuthor,
+		"co-author twice": repeatedCoAuthor,
+		"long warning":    longWarning,
+		"control warning": controlWarning,
+	} {
+		if err := ValidatePostPayload(payload); err == nil {
+			t.Errorf("ValidatePostPayload(%s): expected error, got nil", name)
+		}
+	}
+
+	// A web source stands in for the content CID.
+	webPost := NewPost("author", "", "", nil)
+	webPost.WebSource = &WebSource{URL: "https://example.com/a.txt", SHA256: webHash}
+	web, _ := webPost.ToJSON()
+	if err := ValidatePostPayload(web); err != nil {
+		t.Errorf("ValidatePostPayload() on a web-sourced post error = %v", err)
+	}
+
+	// The validator is registered with the ledger for PostCreated.
+	tx, _ := ledger.NewTransaction("author", ledger.PostCreated, unknownField)
+	if err := tx.IsValid(); !errors.Is(err, ledger.ErrInvalidPayload) {
+		t.Errorf("IsValid() on PostCreated with unknown field error = %v, want ErrInvalidPayload", err)
+	}
+}
+
+func TestPost_Payload_CBOR(t *testing.T) {
+	post := NewPost("author_pub_key_hex_cbor", "content_cid_cbor", "A title", []string{"go", "cbor"})
+	post.Media = []string{"media_cid_cbor"}
+	post.Origin = &PostOrigin{Source: "mastodon", URL: "https://example.social/@a/1", Timestamp: 1}
+
+	jsonPayload, err := post.ToPayload(ledger.PayloadFormatJSON)
+	if err != nil {
+		t.Fatalf("ToPayload(JSON) error = %v", err)
+	}
+	cborPayload, err := post.ToPayload(ledger.PayloadFormatCBOR)
+	if err != nil {
+		t.Fatalf("ToPayload(CBOR) error = %v", err)
+	}
+	if cborPayload[0] != ledger.PayloadVersionCBOR {
+		t.Errorf("CBOR payload starts with 0x%02x, want version byte 0x%02x", cborPayload[0], ledger.PayloadVersionCBOR)
+	}
+	if compact, _ := json.Marshal(post); len(cborPayload) >= len(compact) {
+		t.Errorf("CBOR payload is %d bytes, not smaller than compact JSON (%d bytes)", len(cborPayload), len(compact))
+	}
+
+	for name, payload := range map[string][]byte{"JSON": jsonPayload, "CBOR": cborPayload} {
+		got, err := PostFromPayload(payload)
+		if err != nil {
+			t.Fatalf("PostFromPayload(%s) error = %v", name, err)
+		}
+		if !reflect.DeepEqual(got, post) {
+			t.Errorf("PostFromPayload(%s) = %+v, want %+v", name, got, post)
+		}
+		if err := ValidatePostPayload(payload); err != nil {
+			t.Errorf("ValidatePostPayload(%s) error = %v", name, err)
+		}
+	}
+
+	// The same post always encodes to the same bytes.
+	again, _ := post.ToPayload(ledger.PayloadFormatCBOR)
+	if string(again) != string(cborPayload) {
+		t.Error("CBOR encoding of the same post is not deterministic")
+	}
+
+	// Limits apply to CBOR payloads as well.
+	invalid, _ := NewPost("author", "cid", strings.Repeat("é", MaxPostTitleLength+1), nil).ToPayload(ledger.PayloadFormatCBOR)
+	if err := ValidatePostPayload(invalid); err == nil {
+		t.Error("ValidatePostPayload() on a CBOR post with a long title: expected error, got nil")
+	}
+}
+
+func TestPost_MatchesClientkitPayload(t *testing.T) {
+	clientPost := clientkit.NewPost("author", "cid", "title", []string{"go"})
+	clientPost.Media = []string{"media_cid"}
+	clientPost.Origin = &clientkit.PostOrigin{Source: "twitter", URL: "https://twitter.com/i/web/status/1", Timestamp: 1}
+	clientPost.WebSource = &clientkit.WebSource{URL: "https://example.com/a.txt", SHA256: strings.Repeat("ab", 32)}
+	clientPost.Invite = "invite_tx"
+	post := &Post{
+		AuthorPublicKey: clientPost.AuthorPublicKey,
+		ContentCID:      clientPost.ContentCID,
+		Timestamp:       clientPost.Timestamp,
+		Version:         clientPost.Version,
+		Title:           clientPost.Title,
+		Tags:            clientPost.Tags,
+		Media:           clientPost.Media,
+		Origin:          &PostOrigin{Source: "twitter", URL: "https://twitter.com/i/web/status/1", Timestamp: 1},
+		WebSource:       &WebSource{URL: "https://example.com/a.txt", SHA256: strings.Repeat("ab", 32)},
+		Invite:          clientPost.Invite,
+	}
+	compact, _ := json.Marshal(post) // ToPayload indents JSON; the field encoding is what must match.
+	cbor, _ := post.ToPayload(ledger.PayloadFormatCBOR)
+	for format, want := range map[ledger.PayloadFormat][]byte{ledger.PayloadFormatJSON: compact, ledger.PayloadFormatCBOR: cbor} {
+		got, err := clientkit.EncodePayload(format, clientPost)
+		if err != nil {
+			t.Fatalf("clientkit EncodePayload(%d) error = %v", format, err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("clientkit payload in format %d differs from social.Post", format)
+		}
+		if err := ValidatePostPayload(got); err != nil {
+			t.Errorf("ValidatePostPayload() on a clientkit payload error = %v", err)
+		}
+	}
+}
+
+func FuzzPostFromJSON(f *testing.F) {
+	seed, _ := NewPost("author", "cid", "title", []string{"a", "b"}).ToJSON()
+	f.Add(seed)
+	f.Add([]byte(`{"authorPublicKey":"a","contentCID":"c","timestamp":1,"version":1,"title":"t"}`))
+	f.Add([]byte(`{"authorPublicKey":"a","authorPublicKey":"b"}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		post, err := PostFromJSON(data)
+		if err != nil {
+			return
+		}
+		// Anything accepted must re-encode to JSON that decodes to the same post.
+		encoded, err := post.ToJSON()
+		if err != nil {
+			t.Fatalf("ToJSON() of an accepted post error = %v", err)
+		}
+		again, err := PostFromJSON(encoded)
+		if err != nil {
+			t.Fatalf("PostFromJSON() rejected its own encoding: %v", err)
+		}
+		if !reflect.DeepEqual(post, again) {
+			t.Fatalf("round trip changed the post: %+v != %+v", post, again)
+		}
+	})
+}
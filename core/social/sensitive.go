@@ -0,0 +1,68 @@
+package social
+
+import (
+	"digisocialblock/core/content"
+	"strings"
+)
+
+// ViewerPreferences are a viewer's choices about posts carrying a content
+// warning or flagged as sensitive. The zero value shows everything.
+type ViewerPreferences struct {
+	HideSensitive bool     // Drop posts flagged Sensitive
+	HideWarned    bool     // Drop posts with any content warning
+	MutedWarnings []string // Drop posts whose warning contains any of these, ignoring case
+}
+
+// Allows reports whether a post should appear in the viewer's feeds.
+func (p ViewerPreferences) Allows(e FeedEntry) bool {
+	if p.HideSensitive && e.Sensitive {
+		return false
+	}
+	if e.ContentWarning == "" {
+		return true
+	}
+	if p.HideWarned {
+		return false
+	}
+	warning := strings.ToLower(e.ContentWarning)
+	for _, muted := range p.MutedWarnings {
+		if muted != "" && strings.Contains(warning, strings.ToLower(muted)) {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterFeed returns the entries prefs allows, in order. Feeds are filtered
+// after they are built, so a limited feed may come back shorter than its
+// limit; callers that page should ask for more.
+func FilterFeed(entries []FeedEntry, prefs ViewerPreferences) []FeedEntry {
+	out := make([]FeedEntry, 0, len(entries))
+	for _, e := range entries {
+		if prefs.Allows(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// ContentLabels implements content.LabelSource, so a gateway can return a
+// post's labels with its content or media. If several posts reference the
+// CID, it is sensitive if any of them says so, and carries the latest warning.
+func (fs *FeedService) ContentLabels(cid string) (content.ContentLabels, bool) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	positions, ok := fs.byCID[cid]
+	if !ok {
+		return content.ContentLabels{}, false
+	}
+	var labels content.ContentLabels
+	for _, i := range positions {
+		e := fs.state.Entries[i]
+		labels.Sensitive = labels.Sensitive || e.Sensitive
+		if e.ContentWarning != "" {
+			labels.ContentWarning = e.ContentWarning
+		}
+	}
+	return labels, true
+}
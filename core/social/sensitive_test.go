@@ -0,0 +1,69 @@
+package social
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"testing"
+)
+
+func TestViewerPreferences_FilterFeed(t *testing.T) {
+	entries := []FeedEntry{
+		{TxID: "plain"},
+		{TxID: "sensitive", Sensitive: true},
+		{TxID: "spoiler", ContentWarning: "Spoilers for the finale"},
+		{TxID: "gore", ContentWarning: "Gore", Sensitive: true},
+	}
+	ids := func(entries []FeedEntry) string {
+		var s string
+		for _, e := range entries {
+			s += e.TxID + " "
+		}
+		return s
+	}
+	for _, tt := range []struct {
+		prefs ViewerPreferences
+		want  string
+	}{
+		{ViewerPreferences{}, "plain sensitive spoiler gore "},
+		{ViewerPreferences{HideSensitive: true}, "plain spoiler "},
+		{ViewerPreferences{HideWarned: true}, "plain sensitive "},
+		{ViewerPreferences{MutedWarnings: []string{"SPOILER", ""}}, "plain sensitive gore "},
+	} {
+		if got := ids(FilterFeed(entries, tt.prefs)); got != tt.want {
+			t.Errorf("FilterFeed(%+v) = %q, want %q", tt.prefs, got, tt.want)
+		}
+	}
+}
+
+func TestFeedService_ContentLabels(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	bob, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+	labelled := func(w *identity.Wallet, cid, warning string, sensitive bool, media ...string) *ledger.Transaction {
+		post := NewPost(w.Address, cid, "", nil)
+		post.ContentWarning, post.Sensitive, post.Media = warning, sensitive, media
+		payload, _ := post.ToPayload(ledger.PayloadFormatCBOR)
+		return graphTestTx(t, w, ledger.PostCreated, payload)
+	}
+	bc.AddBlock([]*ledger.Transaction{
+		labelled(alice, "cid-a", "", true, "media-a"),
+		labelled(bob, "cid-a", "flashing lights", false), // A repost cannot clear the flag.
+		newSignedPostTx(t, bob, "cid-plain", ""),
+	})
+
+	fs, _ := NewFeedService(nil)
+	if _, err := fs.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	for cid, want := range map[string]string{"cid-a": "flashing lights", "media-a": ""} {
+		if labels, ok := fs.ContentLabels(cid); !ok || !labels.Sensitive || labels.ContentWarning != want {
+			t.Errorf("ContentLabels(%s) = %+v, %v; want sensitive with warning %q", cid, labels, ok, want)
+		}
+	}
+	if labels, ok := fs.ContentLabels("cid-plain"); !ok || labels.Sensitive || labels.ContentWarning != "" {
+		t.Errorf("ContentLabels(cid-plain) = %+v, %v; want no labels", labels, ok)
+	}
+	if _, ok := fs.ContentLabels("unknown"); ok {
+		t.Error("ContentLabels() of an unknown CID reported labels")
+	}
+}
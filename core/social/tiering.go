@@ -0,0 +1,23 @@
+package social
+
+import (
+	"digisocialblock/core/content"
+	"time"
+)
+
+// TieringCandidates lists the content of every indexed post, and each of its
+// media attachments, for content.TieredStorage.Archive, so an author's
+// archival policy applies to everything they have posted.
+func (fs *FeedService) TieringCandidates() []content.TieringCandidate {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	candidates := make([]content.TieringCandidate, 0, len(fs.state.Entries))
+	for _, e := range fs.state.Entries {
+		published := time.Unix(0, e.Timestamp)
+		candidates = append(candidates, content.TieringCandidate{ManifestCID: e.ContentCID, Author: e.AuthorPublicKey, Published: published})
+		for _, cid := range e.Media {
+			candidates = append(candidates, content.TieringCandidate{ManifestCID: cid, Author: e.AuthorPublicKey, Published: published})
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,31 @@
+package social
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"testing"
+	"time"
+)
+
+func TestFeedService_TieringCandidates(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+	post := NewPost(alice.Address, "cid-post", "", nil)
+	post.Media = []string{"cid-photo"}
+	payload, _ := post.ToPayload(ledger.PayloadFormatCBOR)
+	bc.AddBlock([]*ledger.Transaction{graphTestTx(t, alice, ledger.PostCreated, payload)})
+
+	fs, _ := NewFeedService(nil)
+	if _, err := fs.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	candidates := fs.TieringCandidates()
+	if len(candidates) != 2 || candidates[0].ManifestCID != "cid-post" || candidates[1].ManifestCID != "cid-photo" {
+		t.Fatalf("TieringCandidates() = %+v, want the post and its media", candidates)
+	}
+	for _, c := range candidates {
+		if c.Author != alice.Address || !c.Published.Equal(time.Unix(0, post.Timestamp)) {
+			t.Errorf("candidate %s = %+v, want alice's post at %d", c.ManifestCID, c, post.Timestamp)
+		}
+	}
+}
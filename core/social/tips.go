@@ -0,0 +1,30 @@
+package social
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"fmt"
+)
+
+// TipPost returns a signed Transfer of amount from wallet to the author of
+// post, linked to the post by its content CID. The caller submits it; the
+// chain rejects it if wallet's balance does not cover the tip.
+func TipPost(clock ledger.Clock, wallet *identity.Wallet, post FeedEntry, amount uint64, memo string) (*ledger.Transaction, error) {
+	if wallet == nil {
+		return nil, fmt.Errorf("wallet cannot be nil")
+	}
+	if post.ContentCID == "" {
+		return nil, fmt.Errorf("post %s has no content CID to tip", post.TxID)
+	}
+	if post.AuthorPublicKey == wallet.Address {
+		return nil, fmt.Errorf("cannot tip your own post")
+	}
+	tx, err := ledger.NewTransferTransaction(clock, wallet.Address, post.AuthorPublicKey, amount, post.ContentCID, memo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tip transaction: %w", err)
+	}
+	if err := wallet.SignTransaction(tx); err != nil {
+		return nil, fmt.Errorf("failed to sign tip transaction: %w", err)
+	}
+	return tx, nil
+}
@@ -0,0 +1,40 @@
+package social
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"errors"
+	"testing"
+)
+
+func TestTipPost_TransfersToTheAuthor(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	bob, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+	bc.SetGenesisAllocations(map[string]uint64{bob.Address: 10})
+	post := newSignedPostTx(t, alice, "cid-tipped", "")
+	bc.AddBlock([]*ledger.Transaction{post})
+	fs, _ := NewFeedService(nil)
+	fs.Sync(bc)
+	entry, _ := fs.GetPost(post.ID)
+
+	tip, err := TipPost(nil, bob, entry, 7, "great post")
+	if err != nil {
+		t.Fatalf("TipPost() error = %v", err)
+	}
+	if _, err := bc.AddBlock([]*ledger.Transaction{tip}); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+	transfer, _ := ledger.TransferFromPayload(tip.Payload)
+	if transfer.PostCID != "cid-tipped" || bc.Balance(alice.Address) != 7 || bc.Balance(bob.Address) != 3 {
+		t.Errorf("tip linked to %q, balances alice %d bob %d; want cid-tipped, 7 and 3", transfer.PostCID, bc.Balance(alice.Address), bc.Balance(bob.Address))
+	}
+
+	overdraft, _ := TipPost(nil, bob, entry, 4, "")
+	if _, err := bc.AddBlock([]*ledger.Transaction{overdraft}); !errors.Is(err, ledger.ErrInsufficientBalance) {
+		t.Errorf("AddBlock() of a tip over bob's balance error = %v, want ErrInsufficientBalance", err)
+	}
+	if _, err := TipPost(nil, alice, entry, 1, ""); err == nil {
+		t.Error("TipPost() on one's own post: expected error, got nil")
+	}
+}
@@ -0,0 +1,250 @@
+package social
+
+import (
+	"digisocialblock/core/ledger"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Trending windows and weights.
+const (
+	DefaultTrendingWindow = 24 * time.Hour
+	// MaxTrendingWindow is the longest window GetTrendingTags accepts; older
+	// activity is dropped from the index.
+	MaxTrendingWindow = 7 * 24 * time.Hour
+	// TrendingViewWeight is the score of one reported view of a post, relative
+	// to a new post, credited to each of the post's tags.
+	TrendingViewWeight = 0.1
+	// trendingBucket is the granularity at which activity is kept.
+	trendingBucket = time.Hour
+)
+
+// ErrTrendingIndexDiverged is returned by TrendingIndex.Sync when the block
+// recorded as processed last is no longer on the chain. Call Rebuild to
+// recover.
+var ErrTrendingIndexDiverged = errors.New("trending index high-water mark does not match the chain")
+
+// TrendingTag is a tag with its trending score, as returned by
+// TrendingIndex.GetTrendingTags.
+type TrendingTag struct {
+	Tag   string  `json:"tag"`
+	Score float64 `json:"score"`
+	Posts int     `json:"posts"` // New posts with the tag in the window
+}
+
+// trendingActivity is the activity on a tag in one bucket.
+type trendingActivity struct {
+	posts  int
+	weight float64
+}
+
+// TrendingIndex scores tags by recent activity: every public post counts 1
+// for each of its tags and every reported view of it (see EngagementReport)
+// counts TrendingViewWeight, as of the block that carries it. Scores decay
+// with a half-life of a quarter of the window asked for, so recent activity
+// outweighs older activity within the window. Engagement reports count under
+// the same rules as in EngagementIndex.
+//
+// The index is kept in memory and updated incrementally like the graph index;
+// only the last MaxTrendingWindow of activity is kept. A TrendingIndex is safe
+// for concurrent use.
+type TrendingIndex struct {
+	mu       sync.RWMutex
+	clock    ledger.Clock
+	follower ledger.ChainFollower
+	postTags map[string][]string                    // Public post tx ID -> distinct tags
+	activity map[string]map[int64]*trendingActivity // Tag -> bucket number -> activity
+	limiter  engagementLimiter
+}
+
+// NewTrendingIndex creates an empty TrendingIndex. Call Sync to populate it.
+func NewTrendingIndex() *TrendingIndex {
+	t := &TrendingIndex{clock: ledger.SystemClock}
+	t.reset()
+	return t
+}
+
+// SetClock sets the clock that scores are computed at. A nil clock restores
+// ledger.SystemClock.
+func (t *TrendingIndex) SetClock(clock ledger.Clock) {
+	if clock == nil {
+		clock = ledger.SystemClock
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.clock = clock
+}
+
+// reset clears the index. The caller must hold t.mu (or own t exclusively).
+func (t *TrendingIndex) reset() {
+	t.follower = ledger.NewChainFollower()
+	t.postTags = make(map[string][]string)
+	t.activity = make(map[string]map[int64]*trendingActivity)
+	t.limiter = make(engagementLimiter)
+}
+
+// HighWaterMark reports the last block whose tags have been scored.
+func (t *TrendingIndex) HighWaterMark() (int64, string) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.follower.HighWaterMark()
+}
+
+// Sync scores the tags of posts in blocks added since the last call and
+// returns how many blocks it read. ErrTrendingIndexDiverged means the chain
+// no longer matches the scores.
+func (t *TrendingIndex) Sync(bc *ledger.Blockchain) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.follow(bc)
+}
+
+// Rebuild zeroes the scores and recomputes them from genesis.
+func (t *TrendingIndex) Rebuild(bc *ledger.Blockchain) (int, error) {
+	if bc == nil {
+		return 0, fmt.Errorf("blockchain cannot be nil")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reset()
+	return t.follow(bc)
+}
+
+// follow indexes the blocks above the high-water mark, then drops activity
+// older than MaxTrendingWindow before the last of them. The caller must hold
+// t.mu.
+func (t *TrendingIndex) follow(bc *ledger.Blockchain) (int, error) {
+	var tip int64
+	processed, err := t.follower.Sync(bc, "trending index", ErrTrendingIndexDiverged, func(block *ledger.Block) {
+		t.indexBlock(block)
+		tip = block.Timestamp
+	})
+	if err == nil && processed > 0 {
+		t.prune(tip - int64(MaxTrendingWindow))
+	}
+	return processed, err
+}
+
+// indexBlock records the block's public posts and counted engagement reports
+// in the block's bucket.
+func (t *TrendingIndex) indexBlock(block *ledger.Block) {
+	bucket := block.Timestamp / int64(trendingBucket)
+	for _, tx := range block.Transactions {
+		if tx == nil {
+			continue
+		}
+		switch tx.Type {
+		case ledger.PostCreated:
+			post, err := PostFromPayload(tx.Payload)
+			if err != nil || post.GroupID != "" || len(post.Tags) == 0 {
+				continue
+			}
+			if _, dup := t.postTags[tx.ID]; dup {
+				continue
+			}
+			var tags []string
+			seen := make(map[string]bool, len(post.Tags))
+			for _, tag := range post.Tags {
+				if !seen[tag] {
+					seen[tag] = true
+					tags = append(tags, tag)
+					a := t.bucketLocked(tag, bucket)
+					a.posts++
+					a.weight++
+				}
+			}
+			t.postTags[tx.ID] = tags
+		case ledger.EngagementReport:
+			r, ok := t.limiter.accept(tx)
+			if !ok {
+				continue
+			}
+			for _, p := range r.Posts {
+				for _, tag := range t.postTags[p.PostTxID] {
+					t.bucketLocked(tag, bucket).weight += float64(p.Views) * TrendingViewWeight
+				}
+			}
+		}
+	}
+}
+
+// bucketLocked returns the activity of tag in bucket, creating it if needed.
+// The caller must hold t.mu.
+func (t *TrendingIndex) bucketLocked(tag string, bucket int64) *trendingActivity {
+	buckets := t.activity[tag]
+	if buckets == nil {
+		buckets = make(map[int64]*trendingActivity)
+		t.activity[tag] = buckets
+	}
+	a := buckets[bucket]
+	if a == nil {
+		a = &trendingActivity{}
+		buckets[bucket] = a
+	}
+	return a
+}
+
+// prune drops activity in buckets that end before cutoff (UnixNano). The
+// caller must hold t.mu.
+func (t *TrendingIndex) prune(cutoff int64) {
+	oldest := cutoff / int64(trendingBucket)
+	for tag, buckets := range t.activity {
+		for bucket := range buckets {
+			if bucket < oldest {
+				delete(buckets, bucket)
+			}
+		}
+		if len(buckets) == 0 {
+			delete(t.activity, tag)
+		}
+	}
+}
+
+// GetTrendingTags returns the tags with activity in the window before now,
+// highest score first. A window <= 0 means DefaultTrendingWindow; longer
+// windows than MaxTrendingWindow are shortened to it.
+func (t *TrendingIndex) GetTrendingTags(window time.Duration) []TrendingTag {
+	if window <= 0 {
+		window = DefaultTrendingWindow
+	}
+	if window > MaxTrendingWindow {
+		window = MaxTrendingWindow
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	now := t.clock.Now().UnixNano()
+	oldest := (now - int64(window)) / int64(trendingBucket)
+	halfLife := float64(window / 4)
+	var tags []TrendingTag
+	for tag, buckets := range t.activity {
+		trending := TrendingTag{Tag: tag}
+		for bucket, a := range buckets {
+			if bucket < oldest {
+				continue
+			}
+			// Age from the middle of the bucket; activity in the current
+			// bucket counts in full.
+			age := float64(now - (bucket*int64(trendingBucket) + int64(trendingBucket)/2))
+			if age < 0 {
+				age = 0
+			}
+			trending.Score += a.weight * math.Exp2(-age/halfLife)
+			trending.Posts += a.posts
+		}
+		if trending.Score > 0 {
+			tags = append(tags, trending)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Score != tags[j].Score {
+			return tags[i].Score > tags[j].Score
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags
+}
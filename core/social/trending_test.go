@@ -0,0 +1,70 @@
+package social
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil"
+	"testing"
+	"time"
+)
+
+func trendingTestPost(t *testing.T, wallet *identity.Wallet, groupID string, tags ...string) *ledger.Transaction {
+	t.Helper()
+	post := &Post{AuthorPublicKey: wallet.Address, ContentCID: "cid-" + tags[0], Timestamp: 1, Version: 1, Tags: tags, GroupID: groupID}
+	if groupID != "" {
+		post.KeyEpoch = 1
+	}
+	payload, err := post.ToPayload(ledger.PayloadFormatCBOR)
+	if err != nil {
+		t.Fatalf("ToPayload() error = %v", err)
+	}
+	return graphTestTx(t, wallet, ledger.PostCreated, payload)
+}
+
+func TestTrendingIndex_DecaysPostsAndViewsPerTag(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	node, _ := identity.NewWallet()
+	clock := testutil.NewClock(0)
+	bc, _ := ledger.NewBlockchain()
+	bc.SetClock(clock)
+	start := testutil.ClockStart
+
+	postA := trendingTestPost(t, alice, "", "go", "go")
+	clock.Set(start.Add(time.Hour))
+	bc.AddBlock([]*ledger.Transaction{postA, trendingTestPost(t, alice, "", "rust"), trendingTestPost(t, alice, "group", "secret")})
+	clock.Set(start.Add(21 * time.Hour))
+	report := &EngagementReport{ReporterPublicKey: node.Address, WindowStart: start.UnixNano(), WindowEnd: start.Add(time.Hour).UnixNano(),
+		Posts: []PostViews{{PostTxID: postA.ID, Views: 50}}}
+	payload, _ := report.ToPayload(ledger.PayloadFormatCBOR)
+	bc.AddBlock([]*ledger.Transaction{trendingTestPost(t, alice, "", "rust"), graphTestTx(t, node, ledger.EngagementReport, payload)})
+
+	trending := NewTrendingIndex()
+	trending.SetClock(clock)
+	if _, err := trending.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	// Over a day, go's views 20 hours later outweigh rust's two posts.
+	tags := trending.GetTrendingTags(24 * time.Hour)
+	if len(tags) != 2 || tags[0].Tag != "go" || tags[1].Tag != "rust" || tags[0].Posts != 1 || tags[1].Posts != 2 {
+		t.Fatalf("GetTrendingTags(24h) = %+v, want go (1 post) then rust (2 posts) and no group tags", tags)
+	}
+	if tags[0].Score < 5 || tags[0].Score > 6 || tags[1].Score < 1 || tags[1].Score > 1.5 {
+		t.Errorf("scores = %v and %v, want the older activity decayed", tags[0].Score, tags[1].Score)
+	}
+	// The last hour holds go's views and one rust post.
+	tags = trending.GetTrendingTags(time.Hour)
+	if len(tags) != 2 || tags[0].Tag != "go" || tags[0].Posts != 0 || tags[1].Posts != 1 {
+		t.Errorf("GetTrendingTags(1h) = %+v, want go's views then rust's latest post", tags)
+	}
+
+	// Activity older than MaxTrendingWindow is dropped once a later block arrives.
+	clock.Set(start.Add(MaxTrendingWindow + 30*time.Hour))
+	bc.AddBlock([]*ledger.Transaction{trendingTestPost(t, alice, "", "zig")})
+	if _, err := trending.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if tags := trending.GetTrendingTags(MaxTrendingWindow * 2); len(tags) != 1 || tags[0].Tag != "zig" {
+		t.Errorf("GetTrendingTags() after a week = %+v, want only zig", tags)
+	}
+}
@@ -0,0 +1,181 @@
+package social
+
+import (
+	"context"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"errors"
+	"fmt"
+)
+
+// TxSubmitter accepts transactions for inclusion in a block; *ledger.Mempool
+// implements it.
+type TxSubmitter interface {
+	Add(tx *ledger.Transaction) error
+}
+
+// TxBuilder builds, signs and submits one transaction from a wallet, in place
+// of the separate publish, payload, transaction, sign and submit steps:
+//
+//	tx, err := pm.NewTx(wallet).Post(text, title, tags).WithStamp(bits).SignAndSubmit(ctx, mempool)
+//
+// Each method returns the builder, and the first error is reported by Build
+// or SignAndSubmit. A TxBuilder builds a single transaction and is not safe
+// for concurrent use.
+type TxBuilder struct {
+	pm     *PostManager
+	wallet *identity.Wallet
+
+	txType    ledger.TransactionType
+	payload   []byte
+	text      string // Of a post, for its link preview
+	post      *Post
+	chainID   string
+	stampBits int
+	err       error
+}
+
+// NewTx starts a transaction from wallet, stamped by the manager's clock.
+// Posts are published with the manager's publisher and made like CreatePost.
+func (pm *PostManager) NewTx(wallet *identity.Wallet) *TxBuilder {
+	b := &TxBuilder{pm: pm, wallet: wallet, chainID: ledger.DefaultChainID}
+	if wallet == nil {
+		b.err = errors.New("wallet cannot be nil")
+	}
+	return b
+}
+
+// Post publishes rawTextContent to DDS and makes the transaction a
+// PostCreated transaction for it.
+func (b *TxBuilder) Post(rawTextContent, title string, tags []string) *TxBuilder {
+	if !b.setKind(ledger.PostCreated) {
+		return b
+	}
+	if rawTextContent == "" {
+		b.err = errors.New("raw text content cannot be empty for a post")
+		return b
+	}
+	contentCID, err := b.pm.publisher.PublishTextPostToDDS(rawTextContent)
+	if err != nil {
+		b.err = fmt.Errorf("failed to publish post content to DDS: %w", err)
+		return b
+	}
+	b.post, b.err = b.pm.newPostMeta(b.wallet, contentCID, rawTextContent, title, tags, nil)
+	b.text = rawTextContent
+	return b
+}
+
+// CoAuthors lists the co-authors of a post; see CreateCoAuthoredPost. Their
+// signatures must be added before the transaction is submitted, so a
+// co-authored post is made with Build rather than SignAndSubmit.
+func (b *TxBuilder) CoAuthors(addresses ...string) *TxBuilder {
+	if b.err != nil {
+		return b
+	}
+	if b.post == nil {
+		b.err = errors.New("co-authors can only be added to a post")
+		return b
+	}
+	b.post.CoAuthors = addresses
+	if err := b.post.Validate(); err != nil {
+		b.err = fmt.Errorf("invalid post metadata: %w", err)
+	}
+	return b
+}
+
+// Payload makes the transaction one of txType carrying an already encoded
+// payload, for transaction types without a method of their own.
+func (b *TxBuilder) Payload(txType ledger.TransactionType, payload []byte) *TxBuilder {
+	if b.setKind(txType) {
+		b.payload = payload
+	}
+	return b
+}
+
+// WithStamp attaches a proof-of-work stamp of at least bits, the price of
+// admission the chain asks in place of a fee (see ledger.StampPolicy).
+func (b *TxBuilder) WithStamp(bits int) *TxBuilder {
+	if b.err == nil && (bits < 0 || bits > ledger.MaxStampBits) {
+		b.err = fmt.Errorf("stamp bits %d out of range [0, %d]", bits, ledger.MaxStampBits)
+	}
+	b.stampBits = bits
+	return b
+}
+
+// WithChainID binds the signature to chainID instead of ledger.DefaultChainID.
+func (b *TxBuilder) WithChainID(chainID string) *TxBuilder {
+	if b.err == nil && chainID == "" {
+		b.err = errors.New("chain ID cannot be empty")
+	}
+	b.chainID = chainID
+	return b
+}
+
+// Build returns the transaction signed by the wallet and stamped, ready to be
+// co-signed or submitted. ctx bounds stamp minting.
+func (b *TxBuilder) Build(ctx context.Context) (*ledger.Transaction, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if b.txType == "" {
+		return nil, errors.New("transaction has no content: call Post or Payload")
+	}
+	payload := b.payload
+	if b.post != nil {
+		if b.pm.previewer != nil {
+			if previewCID, err := b.pm.previewer.PreviewText(ctx, b.text); err == nil {
+				b.post.PreviewCID = previewCID
+			}
+		}
+		var err error
+		if payload, err = b.post.ToPayload(b.pm.format); err != nil {
+			return nil, fmt.Errorf("failed to serialize post metadata: %w", err)
+		}
+	}
+	tx, err := ledger.NewTransactionWithClock(b.pm.clock, b.wallet.Address, b.txType, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s transaction: %w", b.txType, err)
+	}
+	tx.ChainID = b.chainID
+	if err := b.wallet.SignTransaction(tx); err != nil {
+		return nil, fmt.Errorf("failed to sign %s transaction: %w", b.txType, err)
+	}
+	// The stamp is bound to the ID, not covered by the signature.
+	if err := tx.MintStamp(ctx, b.stampBits); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// SignAndSubmit builds the transaction and submits it, returning it once
+// submitter has accepted it.
+func (b *TxBuilder) SignAndSubmit(ctx context.Context, submitter TxSubmitter) (*ledger.Transaction, error) {
+	if submitter == nil {
+		return nil, errors.New("submitter cannot be nil")
+	}
+	if b.post != nil && len(b.post.CoAuthors) > 0 {
+		return nil, errors.New("a co-authored post must be co-signed before it is submitted")
+	}
+	tx, err := b.Build(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := submitter.Add(tx); err != nil {
+		return nil, fmt.Errorf("failed to submit transaction %s: %w", tx.ID, err)
+	}
+	return tx, nil
+}
+
+// setKind sets the transaction type, reporting false if the builder has
+// failed or already has one.
+func (b *TxBuilder) setKind(txType ledger.TransactionType) bool {
+	if b.err != nil {
+		return false
+	}
+	if b.txType != "" {
+		b.err = fmt.Errorf("transaction is already a %s transaction", b.txType)
+		return false
+	}
+	b.txType = txType
+	return true
+}
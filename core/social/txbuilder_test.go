@@ -0,0 +1,66 @@
+package social
+
+import (
+	"context"
+	"digisocialblock/core/content"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTxBuilder_PostSignAndSubmit(t *testing.T) {
+	dds := testutil.NewDDS(0)
+	publisher, _ := content.NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	pm, _ := NewPostManager(publisher)
+	alice, _ := identity.NewWallet()
+	bob, _ := identity.NewWallet()
+	mempool := ledger.NewMempool(nil)
+	if err := mempool.SetStampPolicy(ledger.StampPolicy{DefaultBits: 8}); err != nil {
+		t.Fatalf("SetStampPolicy() error = %v", err)
+	}
+	mempool.SetChainID("testnet")
+	ctx := context.Background()
+
+	if _, err := pm.NewTx(alice).Post("hello", "", nil).WithChainID("testnet").SignAndSubmit(ctx, mempool); !errors.Is(err, ledger.ErrInsufficientStamp) {
+		t.Errorf("SignAndSubmit() without a stamp error = %v, want ErrInsufficientStamp", err)
+	}
+	tx, err := pm.NewTx(alice).Post("hello, world", "Greeting", []string{"hello"}).WithStamp(8).WithChainID("testnet").SignAndSubmit(ctx, mempool)
+	if err != nil {
+		t.Fatalf("SignAndSubmit() error = %v", err)
+	}
+	if tx.Type != ledger.PostCreated || tx.ChainID != "testnet" || tx.VerifyStamp(8) != nil || mempool.Size() != 1 {
+		t.Fatalf("SignAndSubmit() = %+v with %d pending, want a stamped testnet post in the mempool", tx, mempool.Size())
+	}
+	post, err := PostFromPayload(tx.Payload)
+	if err != nil {
+		t.Fatalf("PostFromPayload() error = %v", err)
+	}
+	manifest, chunks := testutil.Chunk([]byte("hello, world"), 0)
+	if post.AuthorPublicKey != alice.Address || post.Title != "Greeting" || !reflect.DeepEqual(post.Tags, []string{"hello"}) || post.ContentCID != manifest.ManifestCID || !dds.Storage.ChunkExists(chunks[0].ChunkCID) {
+		t.Errorf("post = %+v, want alice's published greeting", post)
+	}
+
+	// A co-authored post is built for co-signing, not submitted.
+	coAuthored := pm.NewTx(alice).Post("together", "", nil).CoAuthors(bob.Address)
+	if _, err := coAuthored.SignAndSubmit(ctx, mempool); err == nil {
+		t.Error("SignAndSubmit() of a co-authored post succeeded before it was co-signed")
+	}
+	if tx, err := coAuthored.Build(ctx); err != nil || bob.CoSignTransaction(tx) != nil {
+		t.Errorf("Build() of a co-authored post = %v, or bob could not co-sign it", err)
+	}
+
+	for name, b := range map[string]*TxBuilder{
+		"no wallet":     pm.NewTx(nil).Post("hello", "", nil),
+		"no content":    pm.NewTx(alice),
+		"two contents":  pm.NewTx(alice).Post("hello", "", nil).Payload(ledger.PostBookmarked, []byte("{}")),
+		"empty post":    pm.NewTx(alice).Post("", "", nil),
+		"stamp too big": pm.NewTx(alice).Post("hello", "", nil).WithStamp(ledger.MaxStampBits + 1),
+	} {
+		if _, err := b.Build(ctx); err == nil {
+			t.Errorf("Build() with %s succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package sqlmirror
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrations are applied in order, each in its own transaction. The schema
+// version (PRAGMA user_version) is the number applied so far. Never edit a
+// released migration: append a new one instead.
+var migrations = []string{
+	// 1: initial schema.
+	`CREATE TABLE sync_state (
+		id           INTEGER PRIMARY KEY CHECK (id = 1),
+		block_height INTEGER NOT NULL,
+		block_hash   TEXT NOT NULL
+	);
+	CREATE TABLE posts (
+		tx_id         TEXT PRIMARY KEY,
+		block_height  INTEGER NOT NULL,
+		author        TEXT NOT NULL,
+		content_cid   TEXT NOT NULL,
+		timestamp     INTEGER NOT NULL,
+		version       INTEGER NOT NULL,
+		title         TEXT,
+		group_id      TEXT,
+		key_epoch     INTEGER,
+		origin_source TEXT,
+		origin_url    TEXT,
+		web_url       TEXT,
+		web_sha256    TEXT
+	);
+	CREATE INDEX posts_author ON posts (author, timestamp);
+	CREATE TABLE post_tags (
+		tx_id TEXT NOT NULL REFERENCES posts (tx_id) ON DELETE CASCADE,
+		tag   TEXT NOT NULL,
+		PRIMARY KEY (tx_id, tag)
+	);
+	CREATE INDEX post_tags_tag ON post_tags (tag);
+	CREATE TABLE post_media (
+		tx_id    TEXT NOT NULL REFERENCES posts (tx_id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		cid      TEXT NOT NULL,
+		PRIMARY KEY (tx_id, position)
+	);
+	CREATE TABLE profiles (
+		owner        TEXT PRIMARY KEY,
+		tx_id        TEXT NOT NULL,
+		block_height INTEGER NOT NULL,
+		display_name TEXT NOT NULL,
+		bio          TEXT,
+		picture_cid  TEXT,
+		header_cid   TEXT,
+		timestamp    INTEGER NOT NULL,
+		version      INTEGER NOT NULL
+	);
+	CREATE TABLE follows (
+		tx_id        TEXT PRIMARY KEY,
+		block_height INTEGER NOT NULL,
+		follower     TEXT NOT NULL,
+		timestamp    INTEGER NOT NULL,
+		payload      TEXT
+	);
+	CREATE INDEX follows_follower ON follows (follower);
+	CREATE TABLE reactions (
+		tx_id        TEXT PRIMARY KEY,
+		block_height INTEGER NOT NULL,
+		actor        TEXT NOT NULL,
+		kind         TEXT NOT NULL,
+		timestamp    INTEGER NOT NULL,
+		payload      TEXT
+	);
+	CREATE INDEX reactions_actor ON reactions (actor);`,
+	// 2: UserFollowed payloads gained a schema (social.Follow).
+	`ALTER TABLE follows ADD COLUMN followee TEXT;
+	UPDATE follows SET followee = json_extract(payload, '$.followeePublicKey') WHERE json_valid(payload);
+	CREATE INDEX follows_followee ON follows (followee);`,
+}
+
+// SchemaVersion is the schema version a fully migrated database has.
+func SchemaVersion() int {
+	return len(migrations)
+}
+
+// migrate brings db up to SchemaVersion. It refuses a database created by a
+// newer version of this package.
+func migrate(ctx context.Context, db *sql.DB) error {
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", version, len(migrations))
+	}
+	for ; version < len(migrations); version++ {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %d: %w", version+1, err)
+		}
+		if _, err := tx.ExecContext(ctx, migrations[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", version+1, err)
+		}
+		// PRAGMA arguments cannot be bound parameters.
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", version+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", version+1, err)
+		}
+	}
+	return nil
+}
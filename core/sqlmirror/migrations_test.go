@@ -0,0 +1,43 @@
+package sqlmirror
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// openTestDB opens a fresh SQLite database for a test.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "mirror.db"))
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	for i := 0; i < 2; i++ { // Migrating an up-to-date database is a no-op.
+		if err := migrate(ctx, db); err != nil {
+			t.Fatalf("migrate() #%d error = %v", i+1, err)
+		}
+	}
+	var version int
+	db.QueryRow("PRAGMA user_version").Scan(&version)
+	if version != SchemaVersion() {
+		t.Errorf("user_version = %d, want %d", version, SchemaVersion())
+	}
+
+	if _, err := db.Exec("PRAGMA user_version = 99"); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate(ctx, db); err == nil {
+		t.Error("migrate() on a newer schema: expected error, got nil")
+	}
+}
@@ -0,0 +1,254 @@
+// Package sqlmirror mirrors chain-derived social data (posts, profiles,
+// follows and reactions) into a SQLite database, so applications can answer
+// questions the built-in indexes don't cover with plain SQL.
+//
+// The package uses only database/sql; the application opens the database
+// with a SQLite driver of its choice (cmd/dsb-sqlmirror uses the pure-Go
+// modernc.org/sqlite) and hands it to NewMirror, which applies any pending
+// schema migrations. The mirror is updated incrementally like the feed index:
+// Sync processes the blocks above the high-water mark recorded in the
+// database, one SQL transaction per block, so a crash never leaves a block
+// half mirrored.
+//
+// Like payloads have no schema fixed by the ledger, so JSON payloads are
+// stored verbatim for use with SQLite's JSON functions; follow payloads are
+// stored the same way alongside the decoded followee.
+package sqlmirror
+
+import (
+	"context"
+	"database/sql"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"digisocialblock/core/user"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrMirrorDiverged is returned by Mirror.Sync when the block recorded as
+// mirrored last is no longer on the chain. Call Rebuild to recover.
+var ErrMirrorDiverged = errors.New("SQL mirror high-water mark does not match the chain")
+
+// ReactionLike is the reactions.kind of a Like transaction.
+const ReactionLike = "like"
+
+// Mirror keeps a SQLite database in step with the chain.
+type Mirror struct {
+	mu sync.Mutex // Serializes Sync and Rebuild
+	db *sql.DB
+}
+
+// NewMirror migrates db to the current schema and returns a Mirror writing to it.
+func NewMirror(ctx context.Context, db *sql.DB) (*Mirror, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database cannot be nil")
+	}
+	if err := migrate(ctx, db); err != nil {
+		return nil, err
+	}
+	return &Mirror{db: db}, nil
+}
+
+// HighWaterMark returns the height and hash of the last mirrored block. The
+// height is -1 if no block has been mirrored.
+func (m *Mirror) HighWaterMark(ctx context.Context) (int64, string, error) {
+	var height int64
+	var hash string
+	err := m.db.QueryRowContext(ctx, "SELECT block_height, block_hash FROM sync_state WHERE id = 1").Scan(&height, &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return -1, "", nil
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read mirror high-water mark: %w", err)
+	}
+	return height, hash, nil
+}
+
+// Sync mirrors every block above the high-water mark and returns the number
+// of blocks processed. ErrMirrorDiverged is returned if the block at the
+// high-water mark no longer matches the recorded hash.
+func (m *Mirror) Sync(ctx context.Context, bc *ledger.Blockchain) (int, error) {
+	if bc == nil {
+		return 0, fmt.Errorf("blockchain cannot be nil")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	height, hash, err := m.HighWaterMark(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if height >= 0 {
+		block := bc.GetBlockByIndex(height)
+		if block == nil || block.Hash != hash {
+			return 0, fmt.Errorf("%w (block %d)", ErrMirrorDiverged, height)
+		}
+	}
+	return m.processFrom(ctx, bc, height+1)
+}
+
+// Rebuild empties the mirror and reprocesses the whole chain. Use it to
+// recover from ErrMirrorDiverged.
+func (m *Mirror) Rebuild(ctx context.Context, bc *ledger.Blockchain) (int, error) {
+	if bc == nil {
+		return 0, fmt.Errorf("blockchain cannot be nil")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin mirror reset: %w", err)
+	}
+	defer tx.Rollback()
+	for _, table := range []string{"post_tags", "post_media", "posts", "profiles", "follows", "reactions", "sync_state"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+			return 0, fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit mirror reset: %w", err)
+	}
+	return m.processFrom(ctx, bc, 0)
+}
+
+// processFrom mirrors blocks from height start up to the current tip. The
+// caller must hold m.mu.
+func (m *Mirror) processFrom(ctx context.Context, bc *ledger.Blockchain, start int64) (int, error) {
+	latest := bc.GetLatestBlock()
+	if latest == nil {
+		return 0, fmt.Errorf("blockchain has no blocks")
+	}
+	processed := 0
+	for i := start; i <= latest.Index; i++ {
+		block := bc.GetBlockByIndex(i)
+		if block == nil {
+			return processed, fmt.Errorf("block %d missing while syncing SQL mirror", i)
+		}
+		if err := m.applyBlock(ctx, block); err != nil {
+			return processed, err
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// applyBlock mirrors one block and advances the high-water mark in a single
+// SQL transaction. Payloads that do not decode are skipped, as in the feed index.
+func (m *Mirror) applyBlock(ctx context.Context, block *ledger.Block) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin block %d: %w", block.Index, err)
+	}
+	defer tx.Rollback()
+	for _, ltx := range block.Transactions {
+		if ltx == nil {
+			continue
+		}
+		if err := applyTransaction(ctx, tx, block.Index, ltx); err != nil {
+			return fmt.Errorf("failed to mirror transaction %s in block %d: %w", ltx.ID, block.Index, err)
+		}
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO sync_state (id, block_height, block_hash) VALUES (1, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET block_height = excluded.block_height, block_hash = excluded.block_hash`,
+		block.Index, block.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to advance mirror to block %d: %w", block.Index, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit block %d: %w", block.Index, err)
+	}
+	return nil
+}
+
+func applyTransaction(ctx context.Context, tx *sql.Tx, height int64, ltx *ledger.Transaction) error {
+	switch ltx.Type {
+	case ledger.PostCreated:
+		post, err := social.PostFromPayload(ltx.Payload)
+		if err != nil || post.AuthorPublicKey != ltx.SenderPublicKey {
+			return nil
+		}
+		return insertPost(ctx, tx, height, ltx.ID, post)
+	case ledger.ProfileUpdate:
+		profile, err := user.ProfileFromPayload(ltx.Payload)
+		if err != nil || profile.OwnerPublicKey != ltx.SenderPublicKey {
+			return nil
+		}
+		return upsertProfile(ctx, tx, height, ltx.ID, profile)
+	case ledger.UserFollowed:
+		follow, err := social.FollowFromPayload(ltx.Payload)
+		if err != nil {
+			return nil
+		}
+		_, err = tx.ExecContext(ctx, `INSERT OR IGNORE INTO follows (tx_id, block_height, follower, followee, timestamp, payload)
+			VALUES (?, ?, ?, ?, ?, ?)`, ltx.ID, height, ltx.SenderPublicKey, follow.FolloweePublicKey, ltx.Timestamp, jsonPayload(ltx.Payload))
+		return err
+	case ledger.Like:
+		_, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO reactions (tx_id, block_height, actor, kind, timestamp, payload)
+			VALUES (?, ?, ?, ?, ?, ?)`, ltx.ID, height, ltx.SenderPublicKey, ReactionLike, ltx.Timestamp, jsonPayload(ltx.Payload))
+		return err
+	}
+	return nil
+}
+
+func insertPost(ctx context.Context, tx *sql.Tx, height int64, txID string, post *social.Post) error {
+	var originSource, originURL, webURL, webSHA256 sql.NullString
+	if post.Origin != nil {
+		originSource = sql.NullString{String: post.Origin.Source, Valid: true}
+		originURL = sql.NullString{String: post.Origin.URL, Valid: post.Origin.URL != ""}
+	}
+	if post.WebSource != nil {
+		webURL = sql.NullString{String: post.WebSource.URL, Valid: true}
+		webSHA256 = sql.NullString{String: post.WebSource.SHA256, Valid: true}
+	}
+	res, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO posts (tx_id, block_height, author, content_cid,
+		timestamp, version, title, group_id, key_epoch, origin_source, origin_url, web_url, web_sha256)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		txID, height, post.AuthorPublicKey, post.ContentCID, post.Timestamp, post.Version,
+		nullIfEmpty(post.Title), nullIfEmpty(post.GroupID), sql.NullInt64{Int64: int64(post.KeyEpoch), Valid: post.KeyEpoch != 0},
+		originSource, originURL, webURL, webSHA256)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err // Already mirrored.
+	}
+	for _, tag := range post.Tags {
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO post_tags (tx_id, tag) VALUES (?, ?)", txID, tag); err != nil {
+			return err
+		}
+	}
+	for i, cid := range post.Media {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO post_media (tx_id, position, cid) VALUES (?, ?, ?)", txID, i, cid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upsertProfile keeps only the newest version of each owner's profile.
+func upsertProfile(ctx context.Context, tx *sql.Tx, height int64, txID string, p *user.Profile) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO profiles (owner, tx_id, block_height, display_name, bio,
+		picture_cid, header_cid, timestamp, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (owner) DO UPDATE SET tx_id = excluded.tx_id, block_height = excluded.block_height,
+			display_name = excluded.display_name, bio = excluded.bio, picture_cid = excluded.picture_cid,
+			header_cid = excluded.header_cid, timestamp = excluded.timestamp, version = excluded.version
+		WHERE excluded.version > profiles.version`,
+		p.OwnerPublicKey, txID, height, p.DisplayName, nullIfEmpty(p.Bio),
+		nullIfEmpty(p.ProfilePictureCID), nullIfEmpty(p.HeaderImageCID), p.Timestamp, p.Version)
+	return err
+}
+
+// jsonPayload returns a payload as TEXT if it is JSON, or NULL otherwise.
+func jsonPayload(payload []byte) sql.NullString {
+	if len(payload) == 0 || !json.Valid(payload) {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(payload), Valid: true}
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
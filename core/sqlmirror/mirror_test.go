@@ -0,0 +1,120 @@
+package sqlmirror
+
+import (
+	"context"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"digisocialblock/core/user"
+	"digisocialblock/internal/testutil/fixture"
+	"errors"
+	"testing"
+)
+
+func mirrorTestProfile(t *testing.T, wallet *identity.Wallet, name string, version int) *ledger.Transaction {
+	t.Helper()
+	profile := user.NewProfile(wallet.Address, name, "")
+	profile.Version = version
+	payload, _ := profile.ToJSON()
+	return fixture.RawTx(t, wallet, ledger.ProfileUpdate, payload)
+}
+
+func TestMirror_SyncAndQuery(t *testing.T) {
+	ctx := context.Background()
+	alice, _ := identity.NewWallet()
+	bob, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+	bc.AddBlock([]*ledger.Transaction{
+		fixture.PostTx(t, alice, "cid-a1", "go", "sql"),
+		mirrorTestProfile(t, alice, "Alice v2", 2),
+	})
+	db := openTestDB(t)
+	mirror, err := NewMirror(ctx, db)
+	if err != nil {
+		t.Fatalf("NewMirror() error = %v", err)
+	}
+	if n, err := mirror.Sync(ctx, bc); err != nil || n != 2 {
+		t.Fatalf("Sync() = %d, %v; want 2 blocks (genesis + 1)", n, err)
+	}
+
+	// An older profile version arriving later does not replace the newer one.
+	bc.AddBlock([]*ledger.Transaction{
+		mirrorTestProfile(t, alice, "Alice v1", 1),
+		fixture.PostTx(t, bob, "cid-b1", "go"),
+		fixture.RawTx(t, bob, ledger.UserFollowed, []byte(`{"followeePublicKey":"`+alice.Address+`","timestamp":1}`)),
+		fixture.RawTx(t, bob, ledger.Like, []byte(`{"postTxId":"x"}`)),
+	})
+	reopened, _ := NewMirror(ctx, db)
+	if n, err := reopened.Sync(ctx, bc); err != nil || n != 1 {
+		t.Fatalf("Sync() after reopening = %d, %v; want only the 1 new block", n, err)
+	}
+
+	var tagged int
+	db.QueryRow("SELECT COUNT(*) FROM post_tags WHERE tag = 'go'").Scan(&tagged)
+	if tagged != 2 {
+		t.Errorf("posts tagged go = %d, want 2", tagged)
+	}
+	var name string
+	db.QueryRow("SELECT display_name FROM profiles WHERE owner = ?", alice.Address).Scan(&name)
+	if name != "Alice v2" {
+		t.Errorf("display_name = %q, want the newest version's", name)
+	}
+	var followee, fromPayload string
+	db.QueryRow("SELECT followee, json_extract(payload, '$.followeePublicKey') FROM follows WHERE follower = ?",
+		bob.Address).Scan(&followee, &fromPayload)
+	if followee != alice.Address || fromPayload != alice.Address {
+		t.Errorf("followee = %q (payload %q), want alice", followee, fromPayload)
+	}
+	var likes int
+	db.QueryRow("SELECT COUNT(*) FROM reactions WHERE actor = ? AND kind = ?", bob.Address, ReactionLike).Scan(&likes)
+	if likes != 1 {
+		t.Errorf("likes = %d, want 1", likes)
+	}
+}
+
+func TestMirror_DivergedRequiresRebuild(t *testing.T) {
+	ctx := context.Background()
+	wallet, _ := identity.NewWallet()
+	original, _ := ledger.NewBlockchain()
+	original.AddBlock([]*ledger.Transaction{fixture.PostTx(t, wallet, "cid-original")})
+	mirror, _ := NewMirror(ctx, openTestDB(t))
+	if _, err := mirror.Sync(ctx, original); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	other, _ := ledger.NewBlockchain()
+	other.AddBlock([]*ledger.Transaction{fixture.PostTx(t, wallet, "cid-other")})
+	if _, err := mirror.Sync(ctx, other); !errors.Is(err, ErrMirrorDiverged) {
+		t.Fatalf("Sync() on diverged chain error = %v, want ErrMirrorDiverged", err)
+	}
+	if _, err := mirror.Rebuild(ctx, other); err != nil {
+		t.Fatalf("Rebuild() error = %v", err)
+	}
+	var cid string
+	var count int
+	mirror.db.QueryRow("SELECT content_cid, COUNT(*) FROM posts").Scan(&cid, &count)
+	if count != 1 || cid != "cid-other" {
+		t.Errorf("posts after Rebuild = %d (%s), want only cid-other", count, cid)
+	}
+}
+
+func TestMirror_SkipsPostsForgingAnotherAuthor(t *testing.T) {
+	ctx := context.Background()
+	victim, _ := identity.NewWallet()
+	attacker, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+	payload, _ := social.NewPost(victim.Address, "cid-forged", "not mine", nil).ToJSON()
+	if _, err := bc.AddBlock([]*ledger.Transaction{fixture.RawTx(t, attacker, ledger.PostCreated, payload)}); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+
+	mirror, _ := NewMirror(ctx, openTestDB(t))
+	if _, err := mirror.Sync(ctx, bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	var count int
+	mirror.db.QueryRow("SELECT COUNT(*) FROM posts").Scan(&count)
+	if count != 0 {
+		t.Errorf("posts = %d, want none for a post whose author is not its sender", count)
+	}
+}
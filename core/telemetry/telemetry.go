@@ -0,0 +1,54 @@
+// Package telemetry holds the shared pieces of the node's OpenTelemetry
+// tracing. Core packages create spans through the OpenTelemetry API, which
+// does nothing until the operator's binary installs an SDK tracer provider
+// (otel.SetTracerProvider) and, for cross-process traces, a propagator
+// (otel.SetTextMapPropagator with propagation.TraceContext{}).
+//
+// Span names are "<package>.<Operation>", e.g. "content.Publish" with child
+// spans "content.Chunk", "content.StoreChunks" and "content.Advertise", so a
+// slow publication shows which stage took the time. Network layers carry the
+// trace across processes with ExtractHTTP and InjectHTTP.
+package telemetry
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationPrefix prefixes the tracer name of every instrumented
+// package; the tracer for core/content is "digisocialblock/core/content".
+const InstrumentationPrefix = "digisocialblock/"
+
+// Tracer returns the tracer for the named package (e.g. "core/content") from
+// the global provider. A tracer obtained before the binary installs its
+// provider delegates to the first one passed to otel.SetTracerProvider and
+// ignores later calls, so binaries and tests install a single provider
+// before any spans are started.
+func Tracer(pkg string) trace.Tracer {
+	return otel.Tracer(InstrumentationPrefix + pkg)
+}
+
+// End ends span, first recording err on it if err is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// ExtractHTTP returns ctx carrying the remote span context in the request
+// headers h, if the caller sent one.
+func ExtractHTTP(ctx context.Context, h http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(h))
+}
+
+// InjectHTTP writes the span context in ctx into the request headers h.
+func InjectHTTP(ctx context.Context, h http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(h))
+}
@@ -0,0 +1,59 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestEnd_RecordsErrors(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)).Tracer("test")
+
+	_, ok := tracer.Start(context.Background(), "ok")
+	End(ok, nil)
+	_, failed := tracer.Start(context.Background(), "failed")
+	End(failed, errors.New("disk full"))
+
+	spans := sr.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("ended %d spans, want 2", len(spans))
+	}
+	if spans[0].Status().Code != codes.Unset || len(spans[0].Events()) != 0 {
+		t.Errorf("successful span status = %v with %d events, want unset and none", spans[0].Status(), len(spans[0].Events()))
+	}
+	if st := spans[1].Status(); st.Code != codes.Error || st.Description != "disk full" || len(spans[1].Events()) != 1 {
+		t.Errorf("failed span status = %v with %d events, want the error recorded", st, len(spans[1].Events()))
+	}
+}
+
+func TestHTTPPropagation(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator()) })
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3},
+		SpanID:     trace.SpanID{4, 5, 6},
+		TraceFlags: trace.FlagsSampled,
+	})
+	h := make(http.Header)
+	InjectHTTP(trace.ContextWithSpanContext(context.Background(), sc), h)
+	if h.Get("Traceparent") == "" {
+		t.Fatal("InjectHTTP() wrote no traceparent header")
+	}
+	got := trace.SpanContextFromContext(ExtractHTTP(context.Background(), h))
+	if got.TraceID() != sc.TraceID() || got.SpanID() != sc.SpanID() || !got.IsRemote() {
+		t.Errorf("ExtractHTTP() span context = %v, want the injected one, remote", got)
+	}
+	if empty := trace.SpanContextFromContext(ExtractHTTP(context.Background(), http.Header{})); empty.IsValid() {
+		t.Errorf("ExtractHTTP() without headers = %v, want no span context", empty)
+	}
+}
@@ -0,0 +1,82 @@
+package user
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil/golden"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// goldenProfile returns a profile with every field set to a fixed value.
+func goldenProfile() *Profile {
+	return &Profile{
+		OwnerPublicKey:    "04" + strings.Repeat("ab", 64),
+		DisplayName:       "Golden",
+		Bio:               "Keeps encodings stable.",
+		ProfilePictureCID: "bafy-avatar",
+		HeaderImageCID:    "bafy-header",
+		Timestamp:         1700000000000000000,
+		Version:           2,
+		Signature:         []byte{0x30, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02},
+		SigVersion:        1,
+	}
+}
+
+func TestGolden_Profile(t *testing.T) {
+	profile := goldenProfile()
+	data, err := profile.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	golden.Assert(t, "profile.json", append(data, '\n'))
+
+	signed, err := profile.signedBytes()
+	if err != nil {
+		t.Fatalf("signedBytes() error = %v", err)
+	}
+	golden.Assert(t, "profile.signed-bytes", append(signed, '\n'))
+
+	payload, err := profile.ToPayload(ledger.PayloadFormatCBOR)
+	if err != nil {
+		t.Fatalf("ToPayload() error = %v", err)
+	}
+	golden.AssertBinary(t, "profile.payload_cbor.hex", payload)
+	decoded, err := ProfileFromPayload(payload)
+	if err != nil || !reflect.DeepEqual(decoded, profile) {
+		t.Errorf("ProfileFromPayload() = %+v, %v; want %+v", decoded, err, profile)
+	}
+}
+
+// TestGolden_SignedProfile checks that a profile signed when the golden file
+// was written still verifies. Signatures are randomized, so the file is only
+// rewritten on -update.
+func TestGolden_SignedProfile(t *testing.T) {
+	const name = "profile_signed.json"
+	if golden.Updating() {
+		wallet, err := identity.NewWallet()
+		if err != nil {
+			t.Fatalf("NewWallet() error = %v", err)
+		}
+		defer wallet.Close()
+		profile := goldenProfile()
+		profile.OwnerPublicKey = wallet.Address
+		if err := profile.Sign(wallet, ledger.DefaultChainID); err != nil {
+			t.Fatalf("Sign() error = %v", err)
+		}
+		data, err := json.Marshal(profile)
+		if err != nil {
+			t.Fatalf("json.Marshal() error = %v", err)
+		}
+		golden.Write(t, name, append(data, '\n'))
+	}
+	profile, err := ProfileFromJSON(golden.Read(t, name))
+	if err != nil {
+		t.Fatalf("ProfileFromJSON() error = %v", err)
+	}
+	if err := profile.VerifySignature(ledger.DefaultChainID); err != nil {
+		t.Errorf("VerifySignature() error = %v", err)
+	}
+}
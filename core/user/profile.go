@@ -1,11 +1,28 @@
 package user
 
 import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
 	"encoding/json"
 	"fmt"
-	"time"
+	"unicode/utf8"
 )
 
+// Limits on Profile fields, enforced for ProfileUpdate payloads.
+const (
+	MaxDisplayNameLength = 64   // Characters
+	MaxBioLength         = 1024 // Characters
+	MaxProfileCIDLength  = 128  // Bytes
+)
+
+func init() {
+	ledger.RegisterPayloadValidator(ledger.ProfileUpdate, ValidateProfilePayload)
+	ledger.RegisterPayloadDecoder(ledger.ProfileUpdate, ledger.DecodeInto(func() interface{} { return &Profile{} }))
+	ledger.RegisterCIDResolver(ledger.ProfileUpdate, profileCIDs)
+	ledger.RegisterHandleResolver(ledger.ProfileUpdate, profileHandles)
+	ledger.RegisterInviteResolver(ledger.ProfileUpdate, profileInvite)
+}
+
 // Profile represents a user's profile data.
 type Profile struct {
 	OwnerPublicKey    string `json:"ownerPublicKey"`    // Hex-encoded public key of the profile owner (for association)
@@ -15,17 +32,26 @@ type Profile struct {
 	HeaderImageCID    string `json:"headerImageCID,omitempty"`    // CID of a header/banner image on DDS, optional
 	Timestamp         int64  `json:"timestamp"`         // UnixNano timestamp of when this profile version was created/updated
 	Version           int    `json:"version"`           // Version number of the profile, incremented on updates
+	BaseTxID          string `json:"baseTxId,omitempty"`          // ProfileUpdate this version was edited from (see ProfileIndex.Head), for conflict detection
+	Invite            string `json:"invite,omitempty"`            // InviteIssued transaction a new account redeems (see ledger.InvitePolicy)
 	// CustomFields map[string]string `json:"customFields,omitempty"` // For future extensibility
+	Signature  []byte `json:"signature,omitempty"`  // Owner's domain-separated signature over the other fields
+	SigVersion int    `json:"sigVersion,omitempty"` // Signing scheme version of Signature
 }
 
 // NewProfile creates a new Profile instance.
 // ownerPublicKey is the hex-encoded public key string of the user who owns this profile.
 func NewProfile(ownerPublicKey, displayName, bio string) *Profile {
+	return NewProfileWithClock(ledger.SystemClock, ownerPublicKey, displayName, bio)
+}
+
+// NewProfileWithClock is NewProfile with the profile stamped by clock.
+func NewProfileWithClock(clock ledger.Clock, ownerPublicKey, displayName, bio string) *Profile {
 	return &Profile{
 		OwnerPublicKey: ownerPublicKey,
 		DisplayName:    displayName,
 		Bio:            bio,
-		Timestamp:      time.Now().UnixNano(),
+		Timestamp:      clock.Now().UnixNano(),
 		Version:        1, // Initial version
 	}
 }
@@ -34,6 +60,11 @@ func NewProfile(ownerPublicKey, displayName, bio string) *Profile {
 // Fields that are empty in newProfileData are not updated, allowing partial updates.
 // Returns true if any field was actually changed.
 func (p *Profile) Update(newDisplayName, newBio, newProfilePicCID, newHeaderCID string) bool {
+	return p.UpdateWithClock(ledger.SystemClock, newDisplayName, newBio, newProfilePicCID, newHeaderCID)
+}
+
+// UpdateWithClock is Update with a changed profile stamped by clock.
+func (p *Profile) UpdateWithClock(clock ledger.Clock, newDisplayName, newBio, newProfilePicCID, newHeaderCID string) bool {
 	changed := false
 	if newDisplayName != "" && p.DisplayName != newDisplayName {
 		p.DisplayName = newDisplayName
@@ -57,12 +88,70 @@ func (p *Profile) Update(newDisplayName, newBio, newProfilePicCID, newHeaderCID
     }
 
 	if changed {
-		p.Timestamp = time.Now().UnixNano()
+		p.Timestamp = clock.Now().UnixNano()
 		p.Version++
+		// The old signature no longer covers the profile; it must be re-signed.
+		p.Signature = nil
+		p.SigVersion = 0
 	}
 	return changed
 }
 
+// signedBytes returns the canonical bytes covered by the profile signature:
+// the compact JSON encoding of the profile without its signature fields.
+func (p *Profile) signedBytes() ([]byte, error) {
+	unsigned := *p
+	unsigned.Signature = nil
+	unsigned.SigVersion = 0
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal profile for signing: %w", err)
+	}
+	return data, nil
+}
+
+// Sign signs the profile with the owner's wallet for the given chain.
+// The signature is domain-separated, so it cannot be reused as a transaction
+// or message signature.
+func (p *Profile) Sign(wallet *identity.Wallet, chainID string) error {
+	if wallet == nil {
+		return fmt.Errorf("wallet cannot be nil")
+	}
+	if wallet.Address != p.OwnerPublicKey {
+		return fmt.Errorf("wallet address %s does not own profile of %s", wallet.Address, p.OwnerPublicKey)
+	}
+	data, err := p.signedBytes()
+	if err != nil {
+		return err
+	}
+	signature, err := wallet.SignInDomain(identity.DomainProfile, chainID, data)
+	if err != nil {
+		return fmt.Errorf("failed to sign profile: %w", err)
+	}
+	p.Signature = signature
+	p.SigVersion = identity.CurrentSignatureVersion
+	return nil
+}
+
+// VerifySignature checks the profile signature against OwnerPublicKey.
+func (p *Profile) VerifySignature(chainID string) error {
+	if len(p.Signature) == 0 {
+		return fmt.Errorf("profile is not signed")
+	}
+	publicKey, err := identity.AddressToPublicKey(p.OwnerPublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to parse profile owner key: %w", err)
+	}
+	data, err := p.signedBytes()
+	if err != nil {
+		return err
+	}
+	if err := identity.VerifyInDomain(publicKey, identity.DomainProfile, p.SigVersion, chainID, data, p.Signature); err != nil {
+		return fmt.Errorf("invalid profile signature: %w", err)
+	}
+	return nil
+}
+
 // ToJSON serializes the Profile struct to a JSON byte slice.
 func (p *Profile) ToJSON() ([]byte, error) {
 	jsonData, err := json.MarshalIndent(p, "", "  ")
@@ -73,26 +162,114 @@ func (p *Profile) ToJSON() ([]byte, error) {
 }
 
 // FromJSON deserializes a JSON byte slice into a Profile struct.
+// Profile JSON comes from untrusted sources, so it is decoded strictly (see
+// ledger.DecodePayloadJSON) and the result must pass Validate.
 func ProfileFromJSON(jsonData []byte) (*Profile, error) {
 	var p Profile
-	err := json.Unmarshal(jsonData, &p)
-	if err != nil {
+	if err := ledger.DecodePayloadJSON(jsonData, &p); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal JSON to profile: %w", err)
 	}
-	// Basic validation after unmarshal
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("unmarshaled profile is invalid: %w", err)
+	}
+	return &p, nil
+}
+
+// ToPayload serializes the Profile as a ProfileUpdate transaction payload in
+// the given format.
+func (p *Profile) ToPayload(format ledger.PayloadFormat) ([]byte, error) {
+	if format == ledger.PayloadFormatJSON {
+		return p.ToJSON()
+	}
+	payload, err := ledger.EncodePayload(format, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode profile payload: %w", err)
+	}
+	return payload, nil
+}
+
+// ProfileFromPayload deserializes a ProfileUpdate payload in either payload
+// format (see ledger.DecodePayload). The result must pass Validate.
+func ProfileFromPayload(payload []byte) (*Profile, error) {
+	var p Profile
+	if err := ledger.DecodePayload(payload, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode profile payload: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("decoded profile is invalid: %w", err)
+	}
+	return &p, nil
+}
+
+// profileCIDs is the ledger CID resolver for ProfileUpdate: the profile's
+// images.
+func profileCIDs(payload []byte) ([]string, error) {
+	p, err := ProfileFromPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	return []string{p.ProfilePictureCID, p.HeaderImageCID}, nil
+}
+
+// profileHandles is the ledger handle resolver for ProfileUpdate: a profile
+// claims its display name, so no account can pass itself off under a name
+// reserved at genesis.
+func profileHandles(payload []byte) ([]string, error) {
+	p, err := ProfileFromPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	return []string{p.DisplayName}, nil
+}
+
+// profileInvite is the ledger invite resolver for ProfileUpdate: a new
+// account's first profile may redeem an invite.
+func profileInvite(payload []byte) (string, error) {
+	p, err := ProfileFromPayload(payload)
+	if err != nil {
+		return "", err
+	}
+	return p.Invite, nil
+}
+
+// Validate checks that required fields are set and all fields are within limits.
+func (p *Profile) Validate() error {
 	if p.OwnerPublicKey == "" {
-		return nil, fmt.Errorf("unmarshaled profile has empty OwnerPublicKey")
+		return fmt.Errorf("empty OwnerPublicKey")
 	}
 	if p.DisplayName == "" && p.Version > 0 { // Allow display name to be initially empty for a new profile perhaps, but not if versioned
 		// This rule might be too strict, depends on application logic.
 		// For now, let's say display name is required.
-		return nil, fmt.Errorf("unmarshaled profile has empty DisplayName")
+		return fmt.Errorf("empty DisplayName")
 	}
 	if p.Timestamp == 0 {
-		return nil, fmt.Errorf("unmarshaled profile has zero timestamp")
+		return fmt.Errorf("zero timestamp")
 	}
 	if p.Version <= 0 {
-		return nil, fmt.Errorf("unmarshaled profile has invalid version: %d", p.Version)
+		return fmt.Errorf("invalid version: %d", p.Version)
 	}
-	return &p, nil
+	if n := utf8.RuneCountInString(p.DisplayName); n > MaxDisplayNameLength {
+		return fmt.Errorf("display name is %d characters, limit %d", n, MaxDisplayNameLength)
+	}
+	if n := utf8.RuneCountInString(p.Bio); n > MaxBioLength {
+		return fmt.Errorf("bio is %d characters, limit %d", n, MaxBioLength)
+	}
+	if len(p.ProfilePictureCID) > MaxProfileCIDLength || len(p.HeaderImageCID) > MaxProfileCIDLength {
+		return fmt.Errorf("image CID exceeds %d bytes", MaxProfileCIDLength)
+	}
+	if len(p.BaseTxID) > MaxProfileCIDLength {
+		return fmt.Errorf("base transaction ID exceeds %d bytes", MaxProfileCIDLength)
+	}
+	if len(p.Invite) > MaxProfileCIDLength {
+		return fmt.Errorf("invite transaction ID exceeds %d bytes", MaxProfileCIDLength)
+	}
+	return nil
+}
+
+// ValidateProfilePayload is the ledger schema validator for ProfileUpdate
+// payloads: the payload must be exactly a valid Profile, with no unknown fields,
+// in either payload format.
+func ValidateProfilePayload(payload []byte) error {
+	_, err := ProfileFromPayload(payload)
+	return err
 }
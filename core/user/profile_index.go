@@ -0,0 +1,394 @@
+package user
+
+import (
+	"digisocialblock/core/ledger"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// MaxProfileHistory is the number of recent versions of each profile, and of
+// conflicts, a ProfileIndex keeps. An update based on an older version is
+// merged without a common base.
+const MaxProfileHistory = 32
+
+// ErrProfileIndexDiverged is returned by ProfileIndex.Sync when the block
+// recorded as processed last is no longer on the chain. Call Rebuild to
+// recover.
+var ErrProfileIndexDiverged = errors.New("profile index high-water mark does not match the chain")
+
+// ErrProfileNotFound is returned by ProfileIndex.GetProfile for an address
+// with no profile on chain.
+var ErrProfileNotFound = errors.New("profile not found")
+
+// ProfileConflict records a ProfileUpdate that was not based on the current
+// version of its profile, e.g. because two devices edited the profile at the
+// same time. The update is merged field by field: a field changed on only one
+// side keeps that change, and a field changed on both (Overlapping) takes the
+// value of the later update, by timestamp and then by transaction ID.
+type ProfileConflict struct {
+	Owner       string   `json:"owner"`
+	TxID        string   `json:"txId"`       // The conflicting update
+	BlockIndex  int64    `json:"blockIndex"` // Block of the conflicting update
+	BaseTxID    string   `json:"baseTxId,omitempty"`
+	HeadTxID    string   `json:"headTxId"` // The version it was concurrent with
+	Overlapping []string `json:"overlapping,omitempty"`
+	Merged      Profile  `json:"merged"` // The profile after the merge; unsigned
+}
+
+// ProfileChange records a change to an indexed profile, as handed to the
+// handler set with SetChangeHandler.
+type ProfileChange struct {
+	Owner      string
+	TxID       string   // The update that made the change
+	BlockIndex int64    // Block of the update
+	Previous   *Profile // The profile before the change; nil for a new profile
+	Current    Profile  // The profile after the change, merged if it conflicted
+}
+
+// ManifestInvalidator drops cached manifests. *content.ManifestCache
+// implements it.
+type ManifestInvalidator interface {
+	Invalidate(manifestCID string) bool
+}
+
+// InvalidateMedia returns a change handler that invalidates in cache the
+// picture and header image CIDs a profile change replaces and introduces: the
+// replaced ones so a retracted image is not served until its TTL runs out,
+// and the new ones so a failure cached before the image reached the network
+// does not hide it. Set it with SetChangeHandler to keep a ManifestCache in
+// step with the chain as each block is indexed.
+func InvalidateMedia(cache ManifestInvalidator) func(ProfileChange) {
+	return func(c ProfileChange) {
+		for _, f := range profileFields {
+			if f.name != "profilePictureCID" && f.name != "headerImageCID" {
+				continue
+			}
+			current := *f.field(&c.Current)
+			previous := ""
+			if c.Previous != nil {
+				previous = *f.field(c.Previous)
+			}
+			if current == previous {
+				continue
+			}
+			for _, cid := range []string{previous, current} {
+				if cid != "" {
+					cache.Invalidate(cid)
+				}
+			}
+		}
+	}
+}
+
+// profileVersion is a version of a profile as indexed.
+type profileVersion struct {
+	txID    string
+	profile Profile
+}
+
+// profileState is the indexed history of one profile, oldest first; the
+// last version is the current one.
+type profileState struct {
+	versions  []profileVersion
+	conflicts []ProfileConflict
+}
+
+// head returns the current version.
+func (s *profileState) head() *profileVersion {
+	return &s.versions[len(s.versions)-1]
+}
+
+// base returns the version an update was edited from: the one it names in
+// BaseTxID or, for an update without one, the latest with the previous
+// version number. It returns nil if that version is not in the history.
+func (s *profileState) base(update *Profile) *profileVersion {
+	for i := len(s.versions) - 1; i >= 0; i-- {
+		v := &s.versions[i]
+		if update.BaseTxID != "" && v.txID == update.BaseTxID {
+			return v
+		}
+		if update.BaseTxID == "" && v.profile.Version == update.Version-1 {
+			return v
+		}
+	}
+	return nil
+}
+
+// ProfileIndex keeps the current profile of every address from its
+// ProfileUpdate transactions, in memory, updated incrementally like the
+// social indexes. Only updates sent by the profile's owner are indexed.
+//
+// An update whose BaseTxID is the current version's transaction, or that
+// has no BaseTxID and a higher version number, replaces the profile. Any
+// other update is concurrent with the current version: it is merged with it
+// and recorded as a ProfileConflict (see SetConflictHandler). A merged
+// profile carries no signature, since its owner signed neither side as it
+// stands. A ProfileIndex is safe for concurrent use.
+type ProfileIndex struct {
+	mu         sync.RWMutex
+	follower   ledger.ChainFollower
+	profiles   map[string]*profileState // Owner -> history
+	onConflict func(ProfileConflict)
+	pending    []ProfileConflict // Found by the running Sync, not yet handed to onConflict
+	onChange   func(ProfileChange)
+	changes    []ProfileChange // Made by the running Sync, not yet handed to onChange
+}
+
+// NewProfileIndex creates an empty ProfileIndex. Call Sync to populate it.
+func NewProfileIndex() *ProfileIndex {
+	p := &ProfileIndex{}
+	p.reset()
+	return p
+}
+
+// reset clears the index. The caller must hold p.mu (or own p exclusively).
+func (p *ProfileIndex) reset() {
+	p.follower = ledger.NewChainFollower()
+	p.profiles = make(map[string]*profileState)
+	p.pending = nil
+	p.changes = nil
+}
+
+// SetConflictHandler sets a function called with each conflict found by
+// Sync or Rebuild, after the index is unlocked, e.g. to notify the profile's
+// owner. A nil fn stops the calls.
+func (p *ProfileIndex) SetConflictHandler(fn func(ProfileConflict)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onConflict = fn
+}
+
+// SetChangeHandler sets a function called with each change to a profile made
+// by Sync or Rebuild, after the index is unlocked and so once GetProfile
+// returns the changed profile, e.g. InvalidateMedia to invalidate cached
+// images. A nil fn stops the calls.
+func (p *ProfileIndex) SetChangeHandler(fn func(ProfileChange)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onChange = fn
+}
+
+// HighWaterMark reports the last block whose profile updates have been
+// applied.
+func (p *ProfileIndex) HighWaterMark() (int64, string) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.follower.HighWaterMark()
+}
+
+// Sync applies the profile updates in blocks added since the last call.
+// ErrProfileIndexDiverged is returned if the chain no longer holds the last
+// block applied.
+func (p *ProfileIndex) Sync(bc *ledger.Blockchain) (int, error) {
+	p.mu.Lock()
+	n, err := p.follower.Sync(bc, "profile index", ErrProfileIndexDiverged, p.indexBlock)
+	p.unlockAndNotify()
+	return n, err
+}
+
+// Rebuild forgets every profile and replays the updates from genesis.
+func (p *ProfileIndex) Rebuild(bc *ledger.Blockchain) (int, error) {
+	if bc == nil {
+		return 0, fmt.Errorf("blockchain cannot be nil")
+	}
+	p.mu.Lock()
+	p.reset()
+	n, err := p.follower.Sync(bc, "profile index", ErrProfileIndexDiverged, p.indexBlock)
+	p.unlockAndNotify()
+	return n, err
+}
+
+// unlockAndNotify releases p.mu, then hands the changes made and conflicts
+// found while it was held to their handlers.
+func (p *ProfileIndex) unlockAndNotify() {
+	changes, onChange := p.changes, p.onChange
+	conflicts, fn := p.pending, p.onConflict
+	p.pending, p.changes = nil, nil
+	p.mu.Unlock()
+	if onChange != nil {
+		for _, c := range changes {
+			onChange(c)
+		}
+	}
+	if fn == nil {
+		return
+	}
+	for _, c := range conflicts {
+		fn(c)
+	}
+}
+
+// indexBlock applies the block's profile updates.
+func (p *ProfileIndex) indexBlock(block *ledger.Block) {
+	for _, tx := range block.Transactions {
+		if tx == nil || tx.Type != ledger.ProfileUpdate {
+			continue
+		}
+		update, err := ProfileFromPayload(tx.Payload)
+		if err != nil || update.OwnerPublicKey != tx.SenderPublicKey {
+			continue
+		}
+		p.apply(block.Index, tx.ID, update)
+	}
+}
+
+// apply adds an update to its profile's history. The caller must hold p.mu.
+func (p *ProfileIndex) apply(blockIndex int64, txID string, update *Profile) {
+	state, ok := p.profiles[update.OwnerPublicKey]
+	if !ok {
+		p.profiles[update.OwnerPublicKey] = &profileState{versions: []profileVersion{{txID, *update}}}
+		p.changed(blockIndex, txID, nil, update)
+		return
+	}
+	head := state.head()
+	if head.txID == txID {
+		return
+	}
+	previous := head.profile
+	if update.BaseTxID == head.txID || (update.BaseTxID == "" && update.Version > head.profile.Version) {
+		state.versions = appendBounded(state.versions, profileVersion{txID, *update})
+		p.changed(blockIndex, txID, &previous, update)
+		return
+	}
+
+	var base *Profile
+	baseTxID := ""
+	if v := state.base(update); v != nil {
+		base, baseTxID = &v.profile, v.txID
+	}
+	later := update.Timestamp > head.profile.Timestamp || (update.Timestamp == head.profile.Timestamp && txID > head.txID)
+	merged, overlapping := mergeProfiles(base, &head.profile, update, later)
+	conflict := ProfileConflict{
+		Owner:       update.OwnerPublicKey,
+		TxID:        txID,
+		BlockIndex:  blockIndex,
+		BaseTxID:    baseTxID,
+		HeadTxID:    head.txID,
+		Overlapping: overlapping,
+		Merged:      merged,
+	}
+	state.versions = appendBounded(state.versions, profileVersion{txID, merged})
+	state.conflicts = append(state.conflicts, conflict)
+	if len(state.conflicts) > MaxProfileHistory {
+		state.conflicts = state.conflicts[len(state.conflicts)-MaxProfileHistory:]
+	}
+	p.pending = append(p.pending, conflict)
+	p.changed(blockIndex, txID, &previous, &merged)
+}
+
+// changed records a change for the change handler, if one is set. The caller
+// must hold p.mu.
+func (p *ProfileIndex) changed(blockIndex int64, txID string, previous, current *Profile) {
+	if p.onChange == nil {
+		return
+	}
+	p.changes = append(p.changes, ProfileChange{
+		Owner:      current.OwnerPublicKey,
+		TxID:       txID,
+		BlockIndex: blockIndex,
+		Previous:   previous,
+		Current:    *current,
+	})
+}
+
+// appendBounded appends v to versions, dropping the oldest beyond
+// MaxProfileHistory.
+func appendBounded(versions []profileVersion, v profileVersion) []profileVersion {
+	versions = append(versions, v)
+	if len(versions) > MaxProfileHistory {
+		versions = append([]profileVersion(nil), versions[len(versions)-MaxProfileHistory:]...)
+	}
+	return versions
+}
+
+// profileFields are the editable profile fields, in the order conflicts
+// list them.
+var profileFields = []struct {
+	name  string
+	field func(*Profile) *string
+}{
+	{"displayName", func(p *Profile) *string { return &p.DisplayName }},
+	{"bio", func(p *Profile) *string { return &p.Bio }},
+	{"profilePictureCID", func(p *Profile) *string { return &p.ProfilePictureCID }},
+	{"headerImageCID", func(p *Profile) *string { return &p.HeaderImageCID }},
+}
+
+// mergeProfiles merges update into head, both edited from base (nil if
+// unknown, when every differing field counts as changed on both sides), and
+// returns the result and the fields both changed. Those take update's value
+// if updateWins.
+func mergeProfiles(base, head, update *Profile, updateWins bool) (Profile, []string) {
+	merged := *head
+	var overlapping []string
+	for _, f := range profileFields {
+		h, u := *f.field(head), *f.field(update)
+		if h == u {
+			continue
+		}
+		if base != nil {
+			b := *f.field(base)
+			if u == b {
+				continue // Only head changed it
+			}
+			if h == b {
+				*f.field(&merged) = u // Only update changed it
+				continue
+			}
+		}
+		overlapping = append(overlapping, f.name)
+		if updateWins {
+			*f.field(&merged) = u
+		}
+	}
+	if update.Version > merged.Version {
+		merged.Version = update.Version
+	}
+	if update.Timestamp > merged.Timestamp {
+		merged.Timestamp = update.Timestamp
+	}
+	merged.BaseTxID = ""
+	merged.Signature = nil
+	merged.SigVersion = 0
+	return merged, overlapping
+}
+
+// GetProfile returns a copy of the current profile of address, or
+// ErrProfileNotFound. It implements the ProfileSource interfaces of the
+// graphql, digest and activitypub packages.
+func (p *ProfileIndex) GetProfile(address string) (*Profile, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	state, ok := p.profiles[address]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProfileNotFound, address)
+	}
+	profile := state.head().profile
+	profile.Signature = append([]byte(nil), profile.Signature...)
+	return &profile, nil
+}
+
+// Head returns the transaction ID of the current version of address's
+// profile. A device editing the profile sets the new version's BaseTxID to
+// it, so a concurrent edit from another device is detected.
+func (p *ProfileIndex) Head(address string) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	state, ok := p.profiles[address]
+	if !ok {
+		return "", false
+	}
+	return state.head().txID, true
+}
+
+// Conflicts returns the most recent conflicts on address's profile, oldest
+// first.
+func (p *ProfileIndex) Conflicts(address string) []ProfileConflict {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	state, ok := p.profiles[address]
+	if !ok {
+		return nil
+	}
+	return append([]ProfileConflict(nil), state.conflicts...)
+}
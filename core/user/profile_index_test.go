@@ -0,0 +1,191 @@
+package user
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil/fixture"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// profileIndexTestUpdate returns a signed ProfileUpdate of wallet's profile
+// edited from base, whose transaction ID is baseTxID.
+func profileIndexTestUpdate(t *testing.T, wallet *identity.Wallet, base Profile, baseTxID string, timestamp int64, edit func(*Profile)) (*ledger.Transaction, Profile) {
+	t.Helper()
+	p := base
+	edit(&p)
+	p.Version = base.Version + 1
+	p.Timestamp = timestamp
+	p.BaseTxID = baseTxID
+	return fixture.Tx(t, wallet, ledger.ProfileUpdate, &p), p
+}
+
+func TestProfileIndex_FastForwardsSequentialUpdates(t *testing.T) {
+	wallet := fixture.Wallet(t)
+	v1 := Profile{OwnerPublicKey: wallet.Address, DisplayName: "Alice", Timestamp: 1, Version: 1}
+	tx1 := fixture.Tx(t, wallet, ledger.ProfileUpdate, &v1)
+	tx2, v2 := profileIndexTestUpdate(t, wallet, v1, tx1.ID, 2, func(p *Profile) { p.Bio = "hello" })
+	legacy, v3 := profileIndexTestUpdate(t, wallet, v2, "", 3, func(p *Profile) { p.DisplayName = "Alice A." })
+
+	// Someone else's update of alice's profile is ignored.
+	mallory := fixture.Wallet(t)
+	forged := v3
+	forged.DisplayName, forged.Version, forged.Timestamp = "Mallory", 9, 9
+	bc := fixture.Chain(t, []*ledger.Transaction{tx1}, []*ledger.Transaction{tx2}, []*ledger.Transaction{legacy, fixture.Tx(t, mallory, ledger.ProfileUpdate, &forged)})
+
+	index := NewProfileIndex()
+	var conflicts []ProfileConflict
+	index.SetConflictHandler(func(c ProfileConflict) { conflicts = append(conflicts, c) })
+	if n, err := index.Sync(bc); n != 4 || err != nil {
+		t.Fatalf("Sync() = %d, %v; want 4 blocks", n, err)
+	}
+	got, err := index.GetProfile(wallet.Address)
+	if err != nil {
+		t.Fatalf("GetProfile() error = %v", err)
+	}
+	if !reflect.DeepEqual(*got, v3) || len(conflicts) != 0 {
+		t.Errorf("GetProfile() = %+v with %d conflicts, want %+v and none", got, len(conflicts), v3)
+	}
+	if head, ok := index.Head(wallet.Address); !ok || head != legacy.ID {
+		t.Errorf("Head() = %q, %v; want %q", head, ok, legacy.ID)
+	}
+	if _, err := index.GetProfile(mallory.Address); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("GetProfile() of a forged profile error = %v, want ErrProfileNotFound", err)
+	}
+
+	// A reorganized chain is detected.
+	other := fixture.Chain(t, []*ledger.Transaction{tx1})
+	if _, err := index.Sync(other); !errors.Is(err, ErrProfileIndexDiverged) {
+		t.Errorf("Sync() of another chain error = %v, want ErrProfileIndexDiverged", err)
+	}
+}
+
+func TestProfileIndex_MergesConcurrentUpdates(t *testing.T) {
+	wallet := fixture.Wallet(t)
+	v1 := Profile{OwnerPublicKey: wallet.Address, DisplayName: "Alice", Bio: "bio", Timestamp: 1, Version: 1}
+	tx1 := fixture.Tx(t, wallet, ledger.ProfileUpdate, &v1)
+	// A phone and a laptop both edit version 1.
+	phone, _ := profileIndexTestUpdate(t, wallet, v1, tx1.ID, 20, func(p *Profile) {
+		p.DisplayName = "Alice (phone)"
+		p.ProfilePictureCID = "cid-avatar"
+	})
+	laptop, _ := profileIndexTestUpdate(t, wallet, v1, tx1.ID, 10, func(p *Profile) {
+		p.DisplayName = "Alice (laptop)"
+		p.Bio = "new bio"
+	})
+	bc := fixture.Chain(t, []*ledger.Transaction{tx1}, []*ledger.Transaction{phone}, []*ledger.Transaction{laptop})
+
+	index := NewProfileIndex()
+	var handled []ProfileConflict
+	index.SetConflictHandler(func(c ProfileConflict) { handled = append(handled, c) })
+	if _, err := index.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	got, _ := index.GetProfile(wallet.Address)
+	want := Profile{
+		OwnerPublicKey:    wallet.Address,
+		DisplayName:       "Alice (phone)", // Changed on both; the phone's edit is later
+		Bio:               "new bio",
+		ProfilePictureCID: "cid-avatar",
+		Timestamp:         20,
+		Version:           2,
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("GetProfile() = %+v, want %+v", got, want)
+	}
+	conflicts := index.Conflicts(wallet.Address)
+	if len(conflicts) != 1 || !reflect.DeepEqual(handled, conflicts) {
+		t.Fatalf("Conflicts() = %+v, handled %+v; want one conflict, handled once", conflicts, handled)
+	}
+	c := conflicts[0]
+	if c.TxID != laptop.ID || c.HeadTxID != phone.ID || c.BaseTxID != tx1.ID || c.BlockIndex != 3 || !reflect.DeepEqual(c.Overlapping, []string{"displayName"}) {
+		t.Errorf("conflict = %+v, want the laptop's edit against the phone's, overlapping on displayName", c)
+	}
+	if head, _ := index.Head(wallet.Address); head != laptop.ID {
+		t.Errorf("Head() = %q, want the merge recorded under %q", head, laptop.ID)
+	}
+
+	// A rebuild reaches the same profile and reports the conflict again.
+	handled = nil
+	if _, err := index.Rebuild(bc); err != nil {
+		t.Fatalf("Rebuild() error = %v", err)
+	}
+	if rebuilt, _ := index.GetProfile(wallet.Address); !reflect.DeepEqual(*rebuilt, want) || len(handled) != 1 {
+		t.Errorf("after Rebuild() GetProfile() = %+v with %d conflicts handled, want %+v and 1", rebuilt, len(handled), want)
+	}
+}
+
+func TestMergeProfiles_WithoutBase(t *testing.T) {
+	head := &Profile{DisplayName: "a", Bio: "same", Version: 3, Timestamp: 5, Signature: []byte("sig"), SigVersion: 1}
+	update := &Profile{DisplayName: "b", Bio: "same", Version: 2, Timestamp: 4, BaseTxID: "gone"}
+
+	merged, overlapping := mergeProfiles(nil, head, update, false)
+	if merged.DisplayName != "a" || merged.Version != 3 || merged.Timestamp != 5 || merged.Signature != nil || merged.SigVersion != 0 {
+		t.Errorf("mergeProfiles() = %+v, want head's fields and no signature", merged)
+	}
+	if !reflect.DeepEqual(overlapping, []string{"displayName"}) {
+		t.Errorf("mergeProfiles() overlapping = %v, want every differing field", overlapping)
+	}
+	if merged, _ := mergeProfiles(nil, head, update, true); merged.DisplayName != "b" {
+		t.Errorf("mergeProfiles() when the update wins = %+v, want its display name", merged)
+	}
+}
+
+// profileIndexTestInvalidator records the CIDs invalidated and the picture
+// the index returned for owner at the time.
+type profileIndexTestInvalidator struct {
+	index *ProfileIndex
+	owner string
+	cids  []string
+	seen  []string
+}
+
+func (v *profileIndexTestInvalidator) Invalidate(cid string) bool {
+	v.cids = append(v.cids, cid)
+	if p, err := v.index.GetProfile(v.owner); err == nil {
+		v.seen = append(v.seen, p.ProfilePictureCID)
+	}
+	return true
+}
+
+func TestProfileIndex_InvalidatesReplacedMedia(t *testing.T) {
+	wallet := fixture.Wallet(t)
+	v1 := Profile{OwnerPublicKey: wallet.Address, DisplayName: "Alice", ProfilePictureCID: "old-avatar", Timestamp: 1, Version: 1}
+	tx1 := fixture.Tx(t, wallet, ledger.ProfileUpdate, &v1)
+	tx2, v2 := profileIndexTestUpdate(t, wallet, v1, tx1.ID, 2, func(p *Profile) { p.Bio = "unchanged images" })
+	tx3, _ := profileIndexTestUpdate(t, wallet, v2, tx2.ID, 3, func(p *Profile) { p.ProfilePictureCID = "new-avatar" })
+	bc := fixture.Chain(t, []*ledger.Transaction{tx1, tx2})
+
+	index := NewProfileIndex()
+	invalidator := &profileIndexTestInvalidator{index: index, owner: wallet.Address}
+	var changes []ProfileChange
+	handler := InvalidateMedia(invalidator)
+	index.SetChangeHandler(func(c ProfileChange) {
+		changes = append(changes, c)
+		handler(c)
+	})
+	if _, err := index.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if len(changes) != 2 || changes[0].Previous != nil || changes[1].Previous.Bio != "" || changes[1].TxID != tx2.ID {
+		t.Errorf("changes = %+v, want the new profile then the bio edit", changes)
+	}
+	if !reflect.DeepEqual(invalidator.cids, []string{"old-avatar"}) {
+		t.Errorf("invalidated %v, want only the first profile's picture", invalidator.cids)
+	}
+
+	// The replaced and new pictures are invalidated only once the index
+	// serves the new profile, so a refetch cannot repopulate the cache from
+	// the stale one.
+	if _, err := bc.AddBlock([]*ledger.Transaction{tx3}); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+	invalidator.cids, invalidator.seen = nil, nil
+	if _, err := index.Sync(bc); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if !reflect.DeepEqual(invalidator.cids, []string{"old-avatar", "new-avatar"}) || !reflect.DeepEqual(invalidator.seen, []string{"new-avatar", "new-avatar"}) {
+		t.Errorf("invalidated %v while the index served %v, want both pictures after the update", invalidator.cids, invalidator.seen)
+	}
+}
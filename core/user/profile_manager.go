@@ -2,6 +2,7 @@ package user
 
 import (
 	"digisocialblock/core/content" // Path to content publisher/retriever
+	"digisocialblock/core/ledger"
 	"fmt"
 	// "encoding/json" // Already used in profile.go, but here for clarity if needed directly
 )
@@ -11,6 +12,7 @@ import (
 type ProfileManager struct {
 	publisher  *content.ContentPublisher // Service to publish content to DDS
 	retriever  *content.ContentRetriever // Service to retrieve content from DDS
+	clock      ledger.Clock              // Stamps profiles published without a timestamp
 }
 
 // NewProfileManager creates a new ProfileManager.
@@ -25,9 +27,19 @@ func NewProfileManager(publisher *content.ContentPublisher, retriever *content.C
 	return &ProfileManager{
 		publisher:  publisher,
 		retriever:  retriever,
+		clock:      ledger.SystemClock,
 	}, nil
 }
 
+// SetClock sets the clock that stamps profiles published without a
+// timestamp. A nil clock restores ledger.SystemClock.
+func (pm *ProfileManager) SetClock(clock ledger.Clock) {
+	if clock == nil {
+		clock = ledger.SystemClock
+	}
+	pm.clock = clock
+}
+
 // PublishProfile serializes a Profile struct to JSON and publishes it to DDS.
 // It returns the DDS Content ID (CID) of the published profile data.
 func (pm *ProfileManager) PublishProfile(profileData *Profile) (string, error) {
@@ -38,7 +50,7 @@ func (pm *ProfileManager) PublishProfile(profileData *Profile) (string, error) {
 	// Ensure timestamp and version are set if it's a new profile being published this way
 	// (though NewProfile already does this). This is more of a safeguard.
 	if profileData.Timestamp == 0 {
-		profileData.Timestamp = time.Now().UnixNano()
+		profileData.Timestamp = pm.clock.Now().UnixNano()
 	}
 	if profileData.Version == 0 {
 		profileData.Version = 1
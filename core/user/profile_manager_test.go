@@ -2,10 +2,11 @@ package user
 
 import (
 	"digisocialblock/core/content"
-	"digisocialblock/pkg/dds/chunking" // For manifest struct in mock
+	"digisocialblock/internal/testutil"
+	"digisocialblock/pkg/dds/chunking"
 	"fmt"
-	"io"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -73,33 +74,12 @@ func (mcr *MockContentRetriever) Reset() {
 }
 
 
-// --- Mock interfaces for content.NewContentPublisher/Retriever if needed ---
-// These are the interfaces ContentPublisher/Retriever themselves expect.
-// For ProfileManager tests, we mock ContentPublisher and ContentRetriever directly.
-
-type MockDDSChunker struct{}
-func (m *MockDDSChunker) ChunkData(data io.Reader) (*chunking.ContentManifestV1, []chunking.DataChunk, error) {
-	// Simplified, not used directly by ProfileManager tests if ContentPublisher is mocked
-	return &chunking.ContentManifestV1{ManifestCID: "dummy_chunker_cid"}, nil, nil
-}
-type MockDDSStorage struct{}
-func (m *MockDDSStorage) StoreChunk(chunkID string, data []byte) error { return nil }
-func (m *MockDDSStorage) RetrieveChunk(chunkID string) ([]byte, error) { return []byte("dummy_chunk_data"), nil }
-func (m *MockDDSStorage) ChunkExists(chunkID string) bool { return true }
-
-type MockDDSManifestFetcher struct{}
-func (m *MockDDSManifestFetcher) FetchManifest(manifestCID string) (*chunking.ContentManifestV1, error) {
-	return &chunking.ContentManifestV1{ManifestCID: manifestCID, Chunks: []chunking.ChunkInfo{{ChunkCID: "dummy_chunk_cid"}}}, nil
-}
-type MockOriginatorAdvertiser struct{}
-func (m *MockOriginatorAdvertiser) AdvertiseManifest(manifest *chunking.ContentManifestV1) error {return nil}
-
-
 func TestNewProfileManager(t *testing.T) {
 	// Need actual instances of ContentPublisher/Retriever, or mocks that satisfy them.
 	// For this test, let's create real ones with their own (simple) mocks.
-	pub, _ := content.NewContentPublisher(&MockDDSChunker{}, &MockDDSStorage{}, &MockOriginatorAdvertiser{})
-	ret, _ := content.NewContentRetriever(&MockDDSManifestFetcher{}, &MockDDSStorage{})
+	dds := testutil.NewDDS(0)
+	pub, _ := content.NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	ret, _ := content.NewContentRetriever(dds.Manifests, dds.Storage)
 
 
 	_, err := NewProfileManager(nil, ret)
@@ -134,13 +114,9 @@ func TestProfileManager_PublishProfile(t *testing.T) {
 	// This means we need to pass real ContentPublisher/Retriever that are configured with mocks.
 
 	// Setup real ContentPublisher/Retriever with their own mocks for DDS layer
-	ddsChunker := &MockDDSChunker{}
-	ddsStorage := &MockDDSStorage{}
-	ddsOriginator := &MockOriginatorAdvertiser{}
-	realPublisher, _ := content.NewContentPublisher(ddsChunker, ddsStorage, ddsOriginator)
-
-	ddsManifestFetcher := &MockDDSManifestFetcher{}
-	realRetriever, _ := content.NewContentRetriever(ddsManifestFetcher, ddsStorage)
+	dds := testutil.NewDDS(0)
+	realPublisher, _ := content.NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	realRetriever, _ := content.NewContentRetriever(dds.Manifests, dds.Storage)
 
 	// This is the ProfileManager we are testing.
 	// It will use the *real* ContentPublisher, which in turn uses mocked DDS components.
@@ -153,22 +129,15 @@ func TestProfileManager_PublishProfile(t *testing.T) {
 	// to make mocking easier.
 	// For now, let's proceed by mocking the *underlying* behavior of the real publisher.
 
-	// Simpler approach: Directly use the MockContentPublisher/Retriever for this unit test
-	testablePM := &ProfileManager{
-		publisher: (*content.ContentPublisher)(mockPublisher), // This cast is problematic if interfaces don't match
-		retriever: (*content.ContentRetriever)(mockRetriever), // Same here
-	}
-	// The above casting won't work directly because MockContentPublisher is not a *content.ContentPublisher.
-	// We need to ensure ProfileManager's fields are interfaces or use the real ones with deeper mocks.
-	// Given current ProfileManager takes concrete types, we test its behavior by observing
-	// what its dependent (ContentPublisher) does. We can check ContentPublisher's internal mock.
+	// MockContentPublisher/Retriever cannot stand in for the concrete types
+	// ProfileManager takes, so the test observes the real ones over the fakes.
+	_, _ = mockPublisher, mockRetriever
 
 	// Let's re-initialize ProfileManager with real ContentPublisher/Retriever which use mocks.
 	pm, _ = NewProfileManager(realPublisher, realRetriever)
 
 
 	profileData := NewProfile("ownerTest", "Test Profile User", "Bio for test.")
-	expectedCID := "test_manifest_cid_from_publisher"
 
 	// Configure the mock behavior for the *actual* ContentPublisher's dependencies if needed,
 	// or trust ContentPublisher is tested elsewhere and just check ProfileManager's logic.
@@ -202,38 +171,23 @@ func TestProfileManager_PublishProfile(t *testing.T) {
 
 
 func TestProfileManager_RetrieveProfile(t *testing.T) {
-	// Similar setup challenge as above with concrete types.
-	mockPublisher := &MockContentPublisher{} // Not used by RetrieveProfile directly
-	mockRetriever := &MockContentRetriever{}
-
-	// We need ProfileManager to use our MockContentRetriever.
-	// Temporary direct assignment for testing (again, interfaces would be better for ProfileManager fields)
-	pm := &ProfileManager{
-		publisher: (*content.ContentPublisher)(mockPublisher),
-		retriever: (*content.ContentRetriever)(mockRetriever),
-	}
+	// ProfileManager takes the concrete retriever, so the profiles are
+	// published to the shared DDS fakes and retrieved through them.
+	dds := testutil.NewDDS(0)
+	publisher, _ := content.NewContentPublisher(dds.Chunker, dds.Storage, dds.Originator)
+	retriever, _ := content.NewContentRetriever(dds.Manifests, dds.Storage)
+	pm, _ := NewProfileManager(publisher, retriever)
 
 
 	expectedProfile := NewProfile("retrievedOwner", "Retrieved User", "Retrieved Bio")
 	expectedProfile.Version = 2
 	expectedProfileJSON, _ := expectedProfile.ToJSON()
-	targetCID := "cid_for_retrieve_test"
-
-	mockRetriever.RetrieveAndVerifyTextPostFunc = func(manifestCID string) (string, error) {
-		if manifestCID == targetCID {
-			return string(expectedProfileJSON), nil
-		}
-		if manifestCID == "error_cid" {
-			return "", fmt.Errorf("simulated retrieve error")
-		}
-		if manifestCID == "bad_json_cid" {
-			return "this is not json", nil
-		}
-		if manifestCID == "empty_json_cid" {
-			return "", nil // Simulates retriever returning empty string for content
-		}
-		return "", fmt.Errorf("unknown CID for mock retriever: %s", manifestCID)
+	targetCID, err := publisher.PublishTextPostToDDS(string(expectedProfileJSON))
+	if err != nil {
+		t.Fatalf("PublishTextPostToDDS() error = %v", err)
 	}
+	badJSONCID, _ := publisher.PublishTextPostToDDS("this is not json")
+	dds.Manifests.Add("empty_json_cid", &chunking.ContentManifestV1{ManifestCID: "empty_json_cid"})
 
 	// Test successful retrieval
 	retrieved, err := pm.RetrieveProfile(targetCID)
@@ -243,45 +197,38 @@ func TestProfileManager_RetrieveProfile(t *testing.T) {
 	if !reflect.DeepEqual(expectedProfile, retrieved) {
 		t.Errorf("RetrieveProfile() got = %+v, want %+v", retrieved, expectedProfile)
 	}
-	if mockRetriever.RetrieveCount != 1 || mockRetriever.LastRetrievedCID != targetCID {
-		t.Errorf("MockContentRetriever not called as expected")
-	}
-	mockRetriever.Reset()
 
 	// Test retriever error
-	_, err = pm.RetrieveProfile("error_cid")
+	dds.Manifests.Fail(fmt.Errorf("simulated retrieve error"))
+	_, err = pm.RetrieveProfile(targetCID)
 	if err == nil {
-		t.Error("RetrieveProfile(error_cid) expected error, got nil")
+		t.Error("RetrieveProfile() with a failing retriever expected error, got nil")
 	} else if !strings.Contains(err.Error(), "simulated retrieve error") {
-		t.Errorf("RetrieveProfile(error_cid) wrong error message: %v", err)
+		t.Errorf("RetrieveProfile() with a failing retriever wrong error message: %v", err)
 	}
-	mockRetriever.Reset()
+	dds.Manifests.Fail(nil)
 
 	// Test bad JSON error
-	_, err = pm.RetrieveProfile("bad_json_cid")
+	_, err = pm.RetrieveProfile(badJSONCID)
 	if err == nil {
-		t.Error("RetrieveProfile(bad_json_cid) expected error, got nil")
+		t.Error("RetrieveProfile(bad JSON) expected error, got nil")
 	} else if !strings.Contains(err.Error(), "failed to unmarshal JSON") {
-		t.Errorf("RetrieveProfile(bad_json_cid) wrong error message for JSON unmarshal: %v", err)
+		t.Errorf("RetrieveProfile(bad JSON) wrong error message for JSON unmarshal: %v", err)
 	}
-	mockRetriever.Reset()
 
 	// Test empty CID input
 	_, err = pm.RetrieveProfile("")
 	if err == nil {
 		t.Error("RetrieveProfile with empty CID: expected error, got nil")
 	}
-	mockRetriever.Reset()
 
 	// Test when retriever returns empty string for content
-    _, err = pm.RetrieveProfile("empty_json_cid")
-    if err == nil {
-        t.Error("RetrieveProfile(empty_json_cid) expected error for empty JSON data, got nil")
-    } else if !(strings.Contains(err.Error(), "retrieved empty profile data") || strings.Contains(err.Error(), "CID empty_json_cid points to empty content")) {
-        // The error message might slightly differ based on whether the CID is the special "empty_content_manifest_cid_v1"
-        t.Errorf("RetrieveProfile(empty_json_cid) wrong error message for empty JSON: %v", err)
-    }
-    mockRetriever.Reset()
+	_, err = pm.RetrieveProfile("empty_json_cid")
+	if err == nil {
+		t.Error("RetrieveProfile(empty_json_cid) expected error for empty JSON data, got nil")
+	} else if !strings.Contains(err.Error(), "retrieved empty profile data") {
+		t.Errorf("RetrieveProfile(empty_json_cid) wrong error message for empty JSON: %v", err)
+	}
 }
 
 func min(a, b int) int {
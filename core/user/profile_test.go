@@ -1,8 +1,13 @@
 package user
 
 import (
+	"digisocialblock/core/clientkit"
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/internal/testutil"
 	"encoding/json"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -40,12 +45,11 @@ func TestProfile_Update(t *testing.T) {
 	owner := "owner1"
 	initialDisplayName := "Initial Name"
 	initialBio := "Initial Bio"
-	profile := NewProfile(owner, initialDisplayName, initialBio)
+	clock := testutil.NewClock(time.Second) // Every update gets a later timestamp
+	profile := NewProfileWithClock(clock, owner, initialDisplayName, initialBio)
 	initialTimestamp := profile.Timestamp
 	initialVersion := profile.Version
 
-	time.Sleep(1 * time.Nanosecond) // Ensure timestamp can change
-
 	tests := []struct {
 		name               string
 		newDisplayName     string
@@ -106,11 +110,11 @@ func TestProfile_Update(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Reset profile for each test case to a known state
-			p := NewProfile(owner, initialDisplayName, initialBio)
+			p := NewProfileWithClock(clock, owner, initialDisplayName, initialBio)
 			p.Timestamp = initialTimestamp // Keep timestamp same for version check unless changed
 			p.Version = initialVersion
 
-			changed := p.Update(tt.newDisplayName, tt.newBio, tt.newProfilePicCID, tt.newHeaderCID)
+			changed := p.UpdateWithClock(clock, tt.newDisplayName, tt.newBio, tt.newProfilePicCID, tt.newHeaderCID)
 			if changed != tt.wantChanged {
 				t.Errorf("Profile.Update() changed = %v, want %v", changed, tt.wantChanged)
 			}
@@ -192,3 +196,142 @@ func TestProfile_ToJSON_And_ProfileFromJSON(t *testing.T) {
         t.Error("ProfileFromJSON with invalid version: expected error, got nil")
     }
 }
+
+func TestProfile_SignAndVerify(t *testing.T) {
+	wallet, _ := identity.NewWallet()
+	profile := NewProfile(wallet.Address, "Signed User", "bio")
+	if err := profile.Sign(wallet, "dsb-mainnet"); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if err := profile.VerifySignature("dsb-mainnet"); err != nil {
+		t.Fatalf("VerifySignature() error = %v", err)
+	}
+
+	// The signature survives a JSON round trip.
+	data, _ := profile.ToJSON()
+	decoded, err := ProfileFromJSON(data)
+	if err != nil {
+		t.Fatalf("ProfileFromJSON() error = %v", err)
+	}
+	if err := decoded.VerifySignature("dsb-mainnet"); err != nil {
+		t.Errorf("VerifySignature() after round trip error = %v", err)
+	}
+
+	if err := profile.VerifySignature("dsb-testnet"); err == nil {
+		t.Error("VerifySignature() for another chain: expected error, got nil")
+	}
+	decoded.Bio = "tampered"
+	if err := decoded.VerifySignature("dsb-mainnet"); err == nil {
+		t.Error("VerifySignature() on tampered profile: expected error, got nil")
+	}
+	if profile.Update("Renamed", "bio", "", ""); profile.Signature != nil {
+		t.Error("Update() kept a signature that no longer covers the profile")
+	}
+
+	other, _ := identity.NewWallet()
+	if err := NewProfile(wallet.Address, "x", "").Sign(other, "dsb-mainnet"); err == nil {
+		t.Error("Sign() with a non-owner wallet: expected error, got nil")
+	}
+}
+
+func TestProfile_VerifiesClientkitSignature(t *testing.T) {
+	priv, _ := clientkit.GenerateKey()
+	address, _ := clientkit.Address(&priv.PublicKey)
+	clientProfile := &clientkit.Profile{OwnerPublicKey: address, DisplayName: "Browser User", Bio: "bio", Timestamp: 1, Version: 1}
+	if err := clientProfile.Sign(priv, "dsb-mainnet"); err != nil {
+		t.Fatalf("clientkit Sign() error = %v", err)
+	}
+	data, _ := json.Marshal(clientProfile)
+	profile, err := ProfileFromJSON(data)
+	if err != nil {
+		t.Fatalf("ProfileFromJSON() error = %v", err)
+	}
+	if err := profile.VerifySignature("dsb-mainnet"); err != nil {
+		t.Errorf("VerifySignature() error = %v", err)
+	}
+}
+
+func TestValidateProfilePayload(t *testing.T) {
+	valid, _ := NewProfile("owner", "Name", "bio").ToJSON()
+	if err := ValidateProfilePayload(valid); err != nil {
+		t.Fatalf("ValidateProfilePayload() on a valid profile error = %v", err)
+	}
+	longName, _ := NewProfile("owner", strings.Repeat("n", MaxDisplayNameLength+1), "").ToJSON()
+	longBio, _ := NewProfile("owner", "Name", strings.Repeat("b", MaxBioLength+1)).ToJSON()
+	unknownField := []byte(`{"ownerPublicKey":"o","displayName":"n","timestamp":1,"version":1,"verified":true}`)
+	for name, payload := range map[string][]byte{"long display name": longName, "long bio": longBio, "unknown field": unknownField} {
+		if err := ValidateProfilePayload(payload); err == nil {
+			t.Errorf("ValidateProfilePayload(%s): expected error, got nil", name)
+		}
+	}
+}
+
+func TestProfile_Payload_CBOR(t *testing.T) {
+	profile := NewProfile("owner_pub_key_hex", "Name", "A short bio")
+	profile.ProfilePictureCID = "picture_cid"
+
+	payload, err := profile.ToPayload(ledger.PayloadFormatCBOR)
+	if err != nil {
+		t.Fatalf("ToPayload(CBOR) error = %v", err)
+	}
+	if compact, _ := json.Marshal(profile); len(payload) >= len(compact) {
+		t.Errorf("CBOR payload is %d bytes, not smaller than compact JSON (%d bytes)", len(payload), len(compact))
+	}
+	got, err := ProfileFromPayload(payload)
+	if err != nil {
+		t.Fatalf("ProfileFromPayload() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, profile) {
+		t.Errorf("ProfileFromPayload() = %+v, want %+v", got, profile)
+	}
+	if err := ValidateProfilePayload(payload); err != nil {
+		t.Errorf("ValidateProfilePayload() on a CBOR profile error = %v", err)
+	}
+
+	longBio, _ := NewProfile("owner", "Name", strings.Repeat("b", MaxBioLength+1)).ToPayload(ledger.PayloadFormatCBOR)
+	if err := ValidateProfilePayload(longBio); err == nil {
+		t.Error("ValidateProfilePayload() on a CBOR profile with a long bio: expected error, got nil")
+	}
+}
+
+func FuzzProfileFromJSON(f *testing.F) {
+	seed, _ := NewProfile("owner", "Name", "bio").ToJSON()
+	f.Add(seed)
+	f.Add([]byte(`{"ownerPublicKey":"o","displayName":"n","timestamp":1,"version":1,"signature":"AAEC"}`))
+	f.Add([]byte(`{"ownerPublicKey":"o","bio":{"nested":[1,2,3]}}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		profile, err := ProfileFromJSON(data)
+		if err != nil {
+			return
+		}
+		encoded, err := profile.ToJSON()
+		if err != nil {
+			t.Fatalf("ToJSON() of an accepted profile error = %v", err)
+		}
+		again, err := ProfileFromJSON(encoded)
+		if err != nil {
+			t.Fatalf("ProfileFromJSON() rejected its own encoding: %v", err)
+		}
+		if !reflect.DeepEqual(profile, again) {
+			t.Fatalf("round trip changed the profile: %+v != %+v", profile, again)
+		}
+	})
+}
+
+func TestProfile_ClaimsDisplayNameAsHandle(t *testing.T) {
+	payload, _ := NewProfile("owner", "Moderation Team", "").ToJSON()
+	tx, _ := ledger.NewTransaction("owner", ledger.ProfileUpdate, payload)
+	if handles, err := tx.ClaimedHandles(); err != nil || len(handles) != 1 || handles[0] != "Moderation Team" {
+		t.Errorf("ClaimedHandles() = %v, %v; want the display name", handles, err)
+	}
+}
+
+func TestProfile_RedeemsInvite(t *testing.T) {
+	p := NewProfile("owner", "Name", "")
+	p.Invite = "invite-tx"
+	payload, _ := p.ToPayload(ledger.PayloadFormatCBOR)
+	tx, _ := ledger.NewTransaction("owner", ledger.ProfileUpdate, payload)
+	if id, err := tx.RedeemedInvite(); err != nil || id != "invite-tx" {
+		t.Errorf("RedeemedInvite() = %q, %v; want the profile's invite", id, err)
+	}
+}
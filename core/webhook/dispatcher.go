@@ -0,0 +1,325 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Retry defaults applied when DispatcherOptions leaves them zero.
+const (
+	DefaultMaxAttempts  = 8
+	DefaultRetryBackoff = 10 * time.Second
+	DefaultMaxBackoff   = time.Hour
+	DefaultTimeout      = 10 * time.Second
+	DefaultMaxQueued    = 10000
+)
+
+// Delivery request headers.
+const (
+	HeaderEvent     = "X-DSB-Webhook-Event"
+	HeaderDelivery  = "X-DSB-Webhook-Delivery"
+	HeaderTimestamp = "X-DSB-Webhook-Timestamp"
+	HeaderSignature = "X-DSB-Webhook-Signature"
+)
+
+// maxResponseDrain bounds how much of a receiver's response body is read
+// before the connection is reused.
+const maxResponseDrain = 64 << 10
+
+// DispatcherOptions configures a Dispatcher.
+type DispatcherOptions struct {
+	// Store persists subscriptions. Nil keeps them in memory only.
+	Store SubscriptionStore
+	// Log records delivery attempts. Nil uses an in-memory log with
+	// DefaultLogSize entries.
+	Log *DeliveryLog
+	// Client sends deliveries. Nil uses a client with DefaultTimeout.
+	Client *http.Client
+	// AllowHTTP accepts plain http subscription URLs, for local receivers.
+	AllowHTTP bool
+
+	// MaxAttempts is the number of attempts made before a delivery is
+	// abandoned.
+	MaxAttempts int
+	// RetryBackoff is the delay before the first retry; it doubles with each
+	// further attempt up to MaxBackoff.
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+	// MaxQueued bounds the deliveries waiting to be sent. Publish drops
+	// deliveries beyond it and logs them as failed.
+	MaxQueued int
+
+	// Now returns the current time. Nil uses time.Now.
+	Now func() time.Time
+}
+
+// Dispatcher fans events out to matching subscriptions and delivers them.
+// Publish only queues deliveries; Run (or DeliverDue) sends them. Queued
+// deliveries live in memory and are lost on restart.
+type Dispatcher struct {
+	subs      registry
+	log       *DeliveryLog
+	client    *http.Client
+	allowHTTP bool
+	now       func() time.Time
+
+	maxAttempts  int
+	retryBackoff time.Duration
+	maxBackoff   time.Duration
+	maxQueued    int
+
+	mu    sync.Mutex
+	queue []*delivery // Sorted by due time
+	wake  chan struct{}
+}
+
+// delivery is one event queued for one subscription.
+type delivery struct {
+	id             string
+	subscriptionID string
+	event          Event
+	body           []byte
+	attempt        int // Attempts made so far
+	due            time.Time
+}
+
+// NewDispatcher creates a Dispatcher and loads any subscriptions saved in
+// opts.Store.
+func NewDispatcher(opts DispatcherOptions) (*Dispatcher, error) {
+	d := &Dispatcher{
+		subs:         registry{store: opts.Store, subs: make(map[string]Subscription)},
+		log:          opts.Log,
+		client:       opts.Client,
+		allowHTTP:    opts.AllowHTTP,
+		now:          opts.Now,
+		maxAttempts:  opts.MaxAttempts,
+		retryBackoff: opts.RetryBackoff,
+		maxBackoff:   opts.MaxBackoff,
+		maxQueued:    opts.MaxQueued,
+		wake:         make(chan struct{}, 1),
+	}
+	if d.log == nil {
+		d.log, _ = NewDeliveryLog(DefaultLogSize, "")
+	}
+	if d.client == nil {
+		d.client = &http.Client{Timeout: DefaultTimeout}
+	}
+	if d.now == nil {
+		d.now = time.Now
+	}
+	if d.maxAttempts <= 0 {
+		d.maxAttempts = DefaultMaxAttempts
+	}
+	if d.retryBackoff <= 0 {
+		d.retryBackoff = DefaultRetryBackoff
+	}
+	if d.maxBackoff <= 0 {
+		d.maxBackoff = DefaultMaxBackoff
+	}
+	if d.maxQueued <= 0 {
+		d.maxQueued = DefaultMaxQueued
+	}
+	if opts.Store != nil {
+		subs, err := opts.Store.LoadSubscriptions()
+		if err != nil {
+			return nil, err
+		}
+		for _, sub := range subs {
+			if err := sub.validate(d.allowHTTP); err != nil {
+				return nil, fmt.Errorf("saved subscription %s: %w", sub.ID, err)
+			}
+			d.subs.subs[sub.ID] = sub
+		}
+	}
+	return d, nil
+}
+
+// Log returns the dispatcher's delivery log.
+func (d *Dispatcher) Log() *DeliveryLog {
+	return d.log
+}
+
+// Publish queues ev for every subscription that matches it and returns the
+// number of deliveries queued. A zero Timestamp is set to the current time.
+func (d *Dispatcher) Publish(ev Event) int {
+	now := d.now()
+	if ev.Timestamp == 0 {
+		ev.Timestamp = now.UnixNano()
+	}
+	body, err := json.Marshal(ev)
+	if err != nil {
+		// Data is built by this package's callers from plain values, so this
+		// only fires on a programming error.
+		panic(fmt.Sprintf("webhook: failed to encode event %s: %v", ev.ID, err))
+	}
+
+	var matched []Subscription
+	for _, sub := range d.Subscriptions() {
+		if sub.Matches(&ev) {
+			matched = append(matched, sub)
+		}
+	}
+
+	d.mu.Lock()
+	queued := 0
+	for _, sub := range matched {
+		dl := &delivery{id: randomHex(8), subscriptionID: sub.ID, event: ev, body: body, due: now}
+		if len(d.queue) >= d.maxQueued {
+			d.log.record(dl, now, 0, fmt.Errorf("delivery queue is full (%d)", d.maxQueued), OutcomeDropped)
+			continue
+		}
+		d.enqueue(dl)
+		queued++
+	}
+	d.mu.Unlock()
+
+	if queued > 0 {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+	return queued
+}
+
+// Pending returns the number of queued deliveries.
+func (d *Dispatcher) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.queue)
+}
+
+// enqueue inserts dl in due order. The caller must hold d.mu.
+func (d *Dispatcher) enqueue(dl *delivery) {
+	i := sort.Search(len(d.queue), func(i int) bool { return d.queue[i].due.After(dl.due) })
+	d.queue = append(d.queue, nil)
+	copy(d.queue[i+1:], d.queue[i:])
+	d.queue[i] = dl
+}
+
+// DeliverDue sends every queued delivery whose due time has passed, one at a
+// time, and returns the number of attempts made. Failed deliveries are
+// requeued with backoff or abandoned after the last attempt.
+func (d *Dispatcher) DeliverDue(ctx context.Context) int {
+	attempts := 0
+	for ctx.Err() == nil {
+		d.mu.Lock()
+		if len(d.queue) == 0 || d.queue[0].due.After(d.now()) {
+			d.mu.Unlock()
+			break
+		}
+		dl := d.queue[0]
+		d.queue = d.queue[1:]
+		d.mu.Unlock()
+
+		sub, ok := d.Subscription(dl.subscriptionID)
+		if !ok {
+			continue // Unregistered since the event was published
+		}
+		attempts++
+		d.attempt(ctx, &sub, dl)
+	}
+	return attempts
+}
+
+// attempt sends dl once, records the outcome and requeues it if it should be
+// retried.
+func (d *Dispatcher) attempt(ctx context.Context, sub *Subscription, dl *delivery) {
+	dl.attempt++
+	status, err := d.send(ctx, sub, dl)
+	now := d.now()
+	switch {
+	case err == nil:
+		d.log.record(dl, now, status, nil, OutcomeDelivered)
+	case dl.attempt >= d.maxAttempts:
+		d.log.record(dl, now, status, err, OutcomeFailed)
+	default:
+		d.log.record(dl, now, status, err, OutcomeRetrying)
+		dl.due = now.Add(d.backoff(dl.attempt))
+		d.mu.Lock()
+		d.enqueue(dl)
+		d.mu.Unlock()
+	}
+}
+
+// backoff returns the delay after the given failed attempt.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.retryBackoff
+	for i := 1; i < attempt && delay < d.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.maxBackoff {
+		delay = d.maxBackoff
+	}
+	return delay
+}
+
+// send POSTs the delivery and returns the response status. Any status
+// outside 2xx is an error.
+func (d *Dispatcher) send(ctx context.Context, sub *Subscription, dl *delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(dl.body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	timestamp := strconv.FormatInt(d.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "digisocialblock-webhook/1")
+	req.Header.Set(HeaderEvent, string(dl.event.Type))
+	req.Header.Set(HeaderDelivery, dl.id)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, timestamp, dl.body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseDrain))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Run delivers queued events until ctx is cancelled, sleeping until the next
+// delivery is due or a new event is published. It returns ctx.Err().
+func (d *Dispatcher) Run(ctx context.Context) error {
+	for {
+		d.DeliverDue(ctx)
+		if err := d.waitNext(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// waitNext blocks until the earliest queued delivery is due, Publish queues
+// something new, or ctx is done.
+func (d *Dispatcher) waitNext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	var next <-chan time.Time
+	if len(d.queue) > 0 {
+		timer := time.NewTimer(d.queue[0].due.Sub(d.now()))
+		defer timer.Stop()
+		next = timer.C
+	}
+	d.mu.Unlock()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-d.wake:
+		return nil
+	case <-next:
+		return nil
+	}
+}
@@ -0,0 +1,191 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dispatcherTestClock is a settable clock for retry scheduling.
+type dispatcherTestClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *dispatcherTestClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *dispatcherTestClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// dispatcherTestReceiver answers deliveries with the queued statuses (200
+// once they run out) and records the events whose signature verified.
+type dispatcherTestReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	events   []Event
+	secret   string
+	clock    *dispatcherTestClock
+}
+
+func newDispatcherTestReceiver(t *testing.T, clock *dispatcherTestClock, statuses ...int) *dispatcherTestReceiver {
+	t.Helper()
+	rcv := &dispatcherTestReceiver{statuses: statuses, clock: clock}
+	rcv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rcv.mu.Lock()
+		defer rcv.mu.Unlock()
+		body, err := Verify(r, rcv.secret, 0, clock.Now())
+		if err != nil {
+			t.Errorf("receiver Verify() error = %v", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		status := http.StatusOK
+		if len(rcv.statuses) > 0 {
+			status, rcv.statuses = rcv.statuses[0], rcv.statuses[1:]
+		}
+		if status == http.StatusOK {
+			var ev Event
+			json.Unmarshal(body, &ev)
+			rcv.events = append(rcv.events, ev)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(rcv.Close)
+	return rcv
+}
+
+func (rcv *dispatcherTestReceiver) received() []Event {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return append([]Event(nil), rcv.events...)
+}
+
+func newDispatcherTest(t *testing.T, clock *dispatcherTestClock, rcv *dispatcherTestReceiver, sub Subscription) (*Dispatcher, Subscription) {
+	t.Helper()
+	d, err := NewDispatcher(DispatcherOptions{AllowHTTP: true, MaxAttempts: 3, RetryBackoff: time.Minute, Now: clock.Now})
+	if err != nil {
+		t.Fatalf("NewDispatcher() error = %v", err)
+	}
+	sub.URL = rcv.URL
+	if sub, err = d.Register(sub); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	rcv.secret = sub.Secret
+	return d, sub
+}
+
+func TestDispatcher_DeliversSignedEvents(t *testing.T) {
+	clock := &dispatcherTestClock{now: time.Unix(1700000000, 0)}
+	rcv := newDispatcherTestReceiver(t, clock)
+	d, sub := newDispatcherTest(t, clock, rcv, Subscription{Events: []EventType{EventPostCreated}, Addresses: []string{"alice"}})
+
+	if n := d.Publish(Event{ID: "tx1", Type: EventPostCreated, Address: "alice", Data: PostEventData{Author: "alice"}}); n != 1 {
+		t.Errorf("Publish(matching) queued %d, want 1", n)
+	}
+	if n := d.Publish(Event{ID: "tx2", Type: EventPostCreated, Address: "bob"}); n != 0 {
+		t.Errorf("Publish(other author) queued %d, want 0", n)
+	}
+	if n := d.DeliverDue(context.Background()); n != 1 {
+		t.Errorf("DeliverDue() attempts = %d, want 1", n)
+	}
+
+	got := rcv.received()
+	if len(got) != 1 || got[0].ID != "tx1" || got[0].Timestamp != clock.Now().UnixNano() {
+		t.Fatalf("received %+v, want tx1 stamped with the publish time", got)
+	}
+	recs := d.Log().Records(sub.ID, 0)
+	if len(recs) != 1 || recs[0].Outcome != OutcomeDelivered || recs[0].StatusCode != http.StatusOK || recs[0].EventID != "tx1" {
+		t.Errorf("delivery log = %+v, want one delivered record", recs)
+	}
+}
+
+func TestDispatcher_RetriesWithBackoff(t *testing.T) {
+	clock := &dispatcherTestClock{now: time.Unix(1700000000, 0)}
+	rcv := newDispatcherTestReceiver(t, clock, http.StatusInternalServerError, http.StatusBadGateway)
+	d, sub := newDispatcherTest(t, clock, rcv, Subscription{})
+	ctx := context.Background()
+
+	d.Publish(Event{ID: "tx1", Type: EventFollowCreated, Address: "alice"})
+	steps := []struct {
+		advance  time.Duration
+		attempts int
+	}{
+		{0, 1},                // Fails with 500
+		{59 * time.Second, 0}, // Not due yet
+		{time.Second, 1},      // Fails with 502; next backoff doubles
+		{time.Minute, 0},
+		{time.Minute, 1}, // Delivered
+	}
+	for i, step := range steps {
+		clock.Advance(step.advance)
+		if n := d.DeliverDue(ctx); n != step.attempts {
+			t.Fatalf("step %d: DeliverDue() attempts = %d, want %d", i, n, step.attempts)
+		}
+	}
+	if len(rcv.received()) != 1 || d.Pending() != 0 {
+		t.Errorf("received %d events with %d pending, want 1 and 0", len(rcv.received()), d.Pending())
+	}
+
+	var outcomes []Outcome
+	for _, rec := range d.Log().Records(sub.ID, 0) {
+		outcomes = append(outcomes, rec.Outcome)
+	}
+	want := []Outcome{OutcomeDelivered, OutcomeRetrying, OutcomeRetrying}
+	if len(outcomes) != len(want) || outcomes[0] != want[0] || outcomes[2] != want[2] {
+		t.Errorf("logged outcomes = %v, want %v", outcomes, want)
+	}
+}
+
+func TestDispatcher_AbandonsAfterMaxAttempts(t *testing.T) {
+	clock := &dispatcherTestClock{now: time.Unix(1700000000, 0)}
+	rcv := newDispatcherTestReceiver(t, clock, 500, 500, 500, 500)
+	d, sub := newDispatcherTest(t, clock, rcv, Subscription{})
+
+	d.Publish(Event{ID: "tx1", Type: EventPostCreated, Address: "alice"})
+	for i := 0; i < 5; i++ {
+		d.DeliverDue(context.Background())
+		clock.Advance(time.Hour)
+	}
+	recs := d.Log().Records(sub.ID, 0)
+	if len(recs) != 3 || recs[0].Outcome != OutcomeFailed || recs[0].Attempt != 3 || recs[0].StatusCode != 500 {
+		t.Errorf("delivery log = %+v, want three attempts ending in failed", recs)
+	}
+	if d.Pending() != 0 {
+		t.Errorf("Pending() = %d after abandoning, want 0", d.Pending())
+	}
+}
+
+func TestDispatcher_RunDeliversPublishedEvents(t *testing.T) {
+	clock := &dispatcherTestClock{now: time.Unix(1700000000, 0)}
+	rcv := newDispatcherTestReceiver(t, clock)
+	d, _ := newDispatcherTest(t, clock, rcv, Subscription{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- d.Run(ctx) }()
+	d.Publish(Event{ID: "tx1", Type: EventPostCreated, Address: "alice"})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(rcv.received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run() error = %v, want context.Canceled", err)
+	}
+	if len(rcv.received()) != 1 {
+		t.Errorf("received %d events, want 1", len(rcv.received()))
+	}
+}
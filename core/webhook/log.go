@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultLogSize is the number of delivery records kept in memory.
+const DefaultLogSize = 1000
+
+// Outcome is the result of a delivery attempt.
+type Outcome string
+
+const (
+	OutcomeDelivered Outcome = "delivered" // Receiver answered 2xx
+	OutcomeRetrying  Outcome = "retrying"  // Failed; another attempt is queued
+	OutcomeFailed    Outcome = "failed"    // Failed on the last attempt; abandoned
+	OutcomeDropped   Outcome = "dropped"   // Never attempted; the queue was full
+)
+
+// DeliveryRecord is one entry in the delivery log.
+type DeliveryRecord struct {
+	DeliveryID     string    `json:"deliveryId"`
+	SubscriptionID string    `json:"subscriptionId"`
+	EventID        string    `json:"eventId"`
+	EventType      EventType `json:"eventType"`
+	Attempt        int       `json:"attempt"`
+	Time           int64     `json:"time"`                 // UnixNano
+	StatusCode     int       `json:"statusCode,omitempty"` // Zero if no response was received
+	Error          string    `json:"error,omitempty"`
+	Outcome        Outcome   `json:"outcome"`
+}
+
+// DeliveryLog records delivery attempts. It keeps the most recent records in
+// memory for inspection and can append every record to a JSON-lines file for
+// auditing.
+type DeliveryLog struct {
+	mu      sync.Mutex
+	records []DeliveryRecord // Ring buffer
+	next    int              // Index of the next write once the ring is full
+	file    *os.File         // Optional
+}
+
+// NewDeliveryLog creates a log keeping the last size records in memory. If
+// path is not empty every record is also appended to that file.
+func NewDeliveryLog(size int, path string) (*DeliveryLog, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("delivery log size must be positive, got %d", size)
+	}
+	l := &DeliveryLog{records: make([]DeliveryRecord, 0, size)}
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open delivery log %s: %w", path, err)
+		}
+		l.file = f
+	}
+	return l, nil
+}
+
+// Close closes the log file, if any.
+func (l *DeliveryLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// record appends the outcome of an attempt at dl. A file write failure is
+// not fatal to delivery; the record is still kept in memory.
+func (l *DeliveryLog) record(dl *delivery, at time.Time, status int, err error, outcome Outcome) {
+	rec := DeliveryRecord{
+		DeliveryID:     dl.id,
+		SubscriptionID: dl.subscriptionID,
+		EventID:        dl.event.ID,
+		EventType:      dl.event.Type,
+		Attempt:        dl.attempt,
+		Time:           at.UnixNano(),
+		StatusCode:     status,
+		Outcome:        outcome,
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.records) < cap(l.records) {
+		l.records = append(l.records, rec)
+	} else {
+		l.records[l.next] = rec
+	}
+	l.next = (l.next + 1) % cap(l.records)
+	if l.file != nil {
+		if line, err := json.Marshal(rec); err == nil {
+			l.file.Write(append(line, '\n'))
+		}
+	}
+}
+
+// Records returns up to limit of the most recent records, newest first,
+// optionally only those for one subscription. A limit of zero or less
+// returns every record kept.
+func (l *DeliveryLog) Records(subscriptionID string, limit int) []DeliveryRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []DeliveryRecord
+	for i := 0; i < len(l.records); i++ {
+		// Walk backwards from the newest record, just before next.
+		rec := l.records[(l.next-1-i+2*len(l.records))%len(l.records)]
+		if subscriptionID != "" && rec.SubscriptionID != subscriptionID {
+			continue
+		}
+		out = append(out, rec)
+		if limit > 0 && len(out) == limit {
+			break
+		}
+	}
+	return out
+}
@@ -0,0 +1,50 @@
+package webhook
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDeliveryLog_KeepsNewest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deliveries.jsonl")
+	l, err := NewDeliveryLog(3, path)
+	if err != nil {
+		t.Fatalf("NewDeliveryLog() error = %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		dl := &delivery{id: fmt.Sprint(i), subscriptionID: []string{"a", "b"}[i%2], attempt: 1}
+		l.record(dl, time.Unix(int64(i), 0), 200, nil, OutcomeDelivered)
+	}
+
+	var ids []string
+	for _, rec := range l.Records("", 0) {
+		ids = append(ids, rec.DeliveryID)
+	}
+	if fmt.Sprint(ids) != "[4 3 2]" {
+		t.Errorf("Records() IDs = %v, want the newest three, newest first", ids)
+	}
+	if got := l.Records("a", 1); len(got) != 1 || got[0].DeliveryID != "4" {
+		t.Errorf(`Records("a", 1) = %+v, want delivery 4`, got)
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	f, _ := os.Open(path)
+	defer f.Close()
+	lines := 0
+	for sc := bufio.NewScanner(f); sc.Scan(); lines++ {
+		var rec DeliveryRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil || rec.Outcome != OutcomeDelivered {
+			t.Errorf("log line %d = %s, %v", lines, sc.Bytes(), err)
+		}
+	}
+	if lines != 5 {
+		t.Errorf("log file has %d lines, want every record", lines)
+	}
+}
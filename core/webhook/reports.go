@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ReportEventData is the Data of a report.threshold event.
+type ReportEventData struct {
+	Target    string `json:"target"`
+	Reports   int    `json:"reports"` // Distinct reporters so far
+	Threshold int    `json:"threshold"`
+}
+
+// ReportCounter counts distinct reporters per target (an address, post or
+// comment ID) and publishes a report.threshold event the first time a target
+// reaches the threshold. Moderation front ends call Report as reports come
+// in; counts are kept in memory.
+type ReportCounter struct {
+	mu         sync.Mutex
+	dispatcher *Dispatcher
+	threshold  int
+	reporters  map[string]map[string]bool // Target -> reporter set
+}
+
+// NewReportCounter creates a ReportCounter publishing to d.
+func NewReportCounter(d *Dispatcher, threshold int) (*ReportCounter, error) {
+	if d == nil {
+		return nil, fmt.Errorf("dispatcher cannot be nil")
+	}
+	if threshold <= 0 {
+		return nil, fmt.Errorf("report threshold must be positive, got %d", threshold)
+	}
+	return &ReportCounter{dispatcher: d, threshold: threshold, reporters: make(map[string]map[string]bool)}, nil
+}
+
+// Report records that reporter reported target and returns the number of
+// distinct reporters of target. Repeat reports by the same reporter are not
+// counted again.
+func (rc *ReportCounter) Report(target, reporter string) (int, error) {
+	if target == "" || reporter == "" {
+		return 0, fmt.Errorf("report target and reporter cannot be empty")
+	}
+	rc.mu.Lock()
+	set := rc.reporters[target]
+	if set == nil {
+		set = make(map[string]bool)
+		rc.reporters[target] = set
+	}
+	if set[reporter] {
+		n := len(set)
+		rc.mu.Unlock()
+		return n, nil
+	}
+	set[reporter] = true
+	n := len(set)
+	rc.mu.Unlock()
+
+	if n == rc.threshold {
+		rc.dispatcher.Publish(Event{
+			ID:      "report:" + target,
+			Type:    EventReportThreshold,
+			Address: target,
+			Data:    ReportEventData{Target: target, Reports: n, Threshold: rc.threshold},
+		})
+	}
+	return n, nil
+}
+
+// Count returns the number of distinct reporters of target.
+func (rc *ReportCounter) Count(target string) int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.reporters[target])
+}
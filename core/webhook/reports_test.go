@@ -0,0 +1,36 @@
+package webhook
+
+import "testing"
+
+func TestReportCounter_PublishesOnceAtThreshold(t *testing.T) {
+	d, _ := NewDispatcher(DispatcherOptions{})
+	d.Register(Subscription{URL: "https://example.com/hook", Events: []EventType{EventReportThreshold}})
+	rc, err := NewReportCounter(d, 2)
+	if err != nil {
+		t.Fatalf("NewReportCounter() error = %v", err)
+	}
+
+	steps := []struct {
+		reporter string
+		count    int
+		pending  int
+	}{
+		{"bob", 1, 0},
+		{"bob", 1, 0}, // Repeat reports do not count
+		{"carol", 2, 1},
+		{"dave", 3, 1}, // Already past the threshold
+	}
+	for _, step := range steps {
+		n, err := rc.Report("post-1", step.reporter)
+		if err != nil || n != step.count || d.Pending() != step.pending {
+			t.Errorf("Report(%s) = %d, %v with %d pending; want %d with %d pending",
+				step.reporter, n, err, d.Pending(), step.count, step.pending)
+		}
+	}
+	if ev := d.queue[0].event; ev.Type != EventReportThreshold || ev.Address != "post-1" {
+		t.Errorf("queued event = %+v, want report.threshold for post-1", ev)
+	}
+	if _, err := rc.Report("", "bob"); err == nil {
+		t.Error("Report() with an empty target expected error, got nil")
+	}
+}
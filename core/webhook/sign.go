@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMaxSkew is how far a delivery's timestamp may be from the
+// receiver's clock before Verify rejects it as a possible replay.
+const DefaultMaxSkew = 5 * time.Minute
+
+// signaturePrefix names the MAC in the signature header.
+const signaturePrefix = "sha256="
+
+// ErrBadSignature is returned by Verify for a delivery that was not signed
+// with the expected secret, or whose timestamp is out of range.
+var ErrBadSignature = errors.New("webhook signature is invalid")
+
+// Sign returns the signature header value for a delivery body: the hex
+// HMAC-SHA256, keyed by the subscription secret, of the timestamp header
+// value, a '.', and the body.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a received delivery against the subscription secret and
+// returns its body. The timestamp must be within maxSkew of now; zero means
+// DefaultMaxSkew. The request body is consumed.
+func Verify(r *http.Request, secret string, maxSkew time.Duration, now time.Time) ([]byte, error) {
+	if maxSkew <= 0 {
+		maxSkew = DefaultMaxSkew
+	}
+	timestamp := r.Header.Get(HeaderTimestamp)
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing or malformed %s", ErrBadSignature, HeaderTimestamp)
+	}
+	if skew := now.Sub(time.Unix(sent, 0)); skew > maxSkew || skew < -maxSkew {
+		return nil, fmt.Errorf("%w: timestamp is %v from now", ErrBadSignature, skew.Round(time.Second))
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook body: %w", err)
+	}
+	got := r.Header.Get(HeaderSignature)
+	if !strings.HasPrefix(got, signaturePrefix) || !hmac.Equal([]byte(got), []byte(Sign(secret, timestamp, body))) {
+		return nil, ErrBadSignature
+	}
+	return body, nil
+}
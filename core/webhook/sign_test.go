@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := `{"id":"tx1"}`
+	ts := strconv.FormatInt(now.Unix(), 10)
+	tests := []struct {
+		name      string
+		timestamp string
+		signature string
+		body      string
+		wantErr   bool
+	}{
+		{"valid", ts, Sign("secret", ts, []byte(body)), body, false},
+		{"wrong secret", ts, Sign("other", ts, []byte(body)), body, true},
+		{"tampered body", ts, Sign("secret", ts, []byte(body)), `{"id":"tx2"}`, true},
+		{"stale", "1699999000", Sign("secret", "1699999000", []byte(body)), body, true},
+		{"no timestamp", "", Sign("secret", "", []byte(body)), body, true},
+		{"no prefix", ts, strings.TrimPrefix(Sign("secret", ts, []byte(body)), signaturePrefix), body, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/hook", strings.NewReader(tt.body))
+			r.Header.Set(HeaderTimestamp, tt.timestamp)
+			r.Header.Set(HeaderSignature, tt.signature)
+			got, err := Verify(r, "secret", 0, now)
+			if tt.wantErr {
+				if !errors.Is(err, ErrBadSignature) {
+					t.Errorf("Verify() error = %v, want ErrBadSignature", err)
+				}
+				return
+			}
+			if err != nil || string(got) != body {
+				t.Errorf("Verify() = %q, %v; want the body", got, err)
+			}
+		})
+	}
+}
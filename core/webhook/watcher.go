@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrWatcherDiverged is returned by Watcher.Sync when the block recorded as
+// processed last is no longer on the chain. Call Reset to resume from the
+// new tip.
+var ErrWatcherDiverged = errors.New("webhook watcher high-water mark does not match the chain")
+
+// PostEventData is the Data of a post.created event.
+type PostEventData struct {
+	Author     string   `json:"author"`
+	ContentCID string   `json:"contentCID,omitempty"`
+	Title      string   `json:"title,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+}
+
+// FollowEventData is the Data of a follow.created event.
+type FollowEventData struct {
+	Follower string `json:"follower"`
+	Followee string `json:"followee"`
+}
+
+// Watcher turns new blocks into events for a Dispatcher: post.created for
+// public posts whose author signed the transaction, and follow.created for
+// follows of someone else. Group posts are never published.
+//
+// A Watcher starts at the chain tip, so historical blocks produce no events.
+type Watcher struct {
+	mu         sync.Mutex
+	dispatcher *Dispatcher
+	follower   ledger.ChainFollower
+}
+
+// NewWatcher creates a Watcher that publishes to d blocks added to bc from
+// now on.
+func NewWatcher(d *Dispatcher, bc *ledger.Blockchain) (*Watcher, error) {
+	if d == nil {
+		return nil, fmt.Errorf("dispatcher cannot be nil")
+	}
+	w := &Watcher{dispatcher: d}
+	if err := w.Reset(bc); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// HighWaterMark reports the last block whose events have been published.
+func (w *Watcher) HighWaterMark() (int64, string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.follower.HighWaterMark()
+}
+
+// Reset moves the high-water mark to the current tip without publishing
+// anything for the blocks skipped.
+func (w *Watcher) Reset(bc *ledger.Blockchain) error {
+	if bc == nil {
+		return fmt.Errorf("blockchain cannot be nil")
+	}
+	latest := bc.GetLatestBlock()
+	if latest == nil {
+		return fmt.Errorf("blockchain has no blocks")
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.follower = ledger.ChainFollower{HighWaterIndex: latest.Index, HighWaterHash: latest.Hash}
+	return nil
+}
+
+// Sync publishes events for every block above the high-water mark and returns
+// the number of blocks processed.
+func (w *Watcher) Sync(bc *ledger.Blockchain) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.follower.Sync(bc, "webhook watcher", ErrWatcherDiverged, w.publishBlock)
+}
+
+// publishBlock publishes the block's events. Payloads that do not decode are
+// skipped.
+func (w *Watcher) publishBlock(block *ledger.Block) {
+	for _, tx := range block.Transactions {
+		if tx == nil {
+			continue
+		}
+		switch tx.Type {
+		case ledger.PostCreated:
+			post, err := social.PostFromPayload(tx.Payload)
+			if err != nil || post.GroupID != "" || post.AuthorPublicKey != tx.SenderPublicKey {
+				continue
+			}
+			w.dispatcher.Publish(Event{
+				ID:         tx.ID,
+				Type:       EventPostCreated,
+				Address:    post.AuthorPublicKey,
+				Timestamp:  post.Timestamp,
+				BlockIndex: block.Index,
+				Data: PostEventData{
+					Author:     post.AuthorPublicKey,
+					ContentCID: post.ContentCID,
+					Title:      post.Title,
+					Tags:       post.Tags,
+				},
+			})
+		case ledger.UserFollowed:
+			f, err := social.FollowFromPayload(tx.Payload)
+			if err != nil || f.FolloweePublicKey == tx.SenderPublicKey {
+				continue
+			}
+			w.dispatcher.Publish(Event{
+				ID:         tx.ID,
+				Type:       EventFollowCreated,
+				Address:    f.FolloweePublicKey,
+				Timestamp:  f.Timestamp,
+				BlockIndex: block.Index,
+				Data:       FollowEventData{Follower: tx.SenderPublicKey, Followee: f.FolloweePublicKey},
+			})
+		}
+	}
+}
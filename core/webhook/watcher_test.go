@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"digisocialblock/internal/testutil/fixture"
+	"errors"
+	"testing"
+)
+
+func TestWatcher_PublishesNewBlocks(t *testing.T) {
+	alice, _ := identity.NewWallet()
+	bob, _ := identity.NewWallet()
+	bc, _ := ledger.NewBlockchain()
+	old := fixture.Tx(t, alice, ledger.PostCreated, social.NewPost(alice.Address, "cid-old", "", nil))
+	if _, err := bc.AddBlock([]*ledger.Transaction{old}); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+
+	d, _ := NewDispatcher(DispatcherOptions{})
+	sub, _ := d.Register(Subscription{URL: "https://example.com/hook"})
+	w, err := NewWatcher(d, bc)
+	if err != nil {
+		t.Fatalf("NewWatcher() error = %v", err)
+	}
+
+	post := fixture.Tx(t, alice, ledger.PostCreated, social.NewPost(alice.Address, "cid-new", "Hello", []string{"go"}))
+	groupPost := social.NewPost(alice.Address, "cid-secret", "", nil)
+	groupPost.GroupID, groupPost.KeyEpoch = "club", 1
+	forged := fixture.Tx(t, bob, ledger.PostCreated, social.NewPost(alice.Address, "cid-forged", "", nil))
+	follow := fixture.Tx(t, bob, ledger.UserFollowed, &social.Follow{FolloweePublicKey: alice.Address, Timestamp: 1})
+	selfFollow := fixture.Tx(t, bob, ledger.UserFollowed, &social.Follow{FolloweePublicKey: bob.Address, Timestamp: 2})
+	txs := []*ledger.Transaction{post, fixture.Tx(t, alice, ledger.PostCreated, groupPost), forged, follow, selfFollow}
+	if _, err := bc.AddBlock(txs); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+
+	if n, err := w.Sync(bc); err != nil || n != 1 {
+		t.Fatalf("Sync() = %d, %v; want 1 block", n, err)
+	}
+	d.mu.Lock()
+	var got []Event
+	for _, dl := range d.queue {
+		if dl.subscriptionID == sub.ID {
+			got = append(got, dl.event)
+		}
+	}
+	d.mu.Unlock()
+	if len(got) != 2 {
+		t.Fatalf("queued events = %+v, want the public post and the follow", got)
+	}
+	if ev := got[0]; ev.ID != post.ID || ev.Type != EventPostCreated || ev.Address != alice.Address || ev.BlockIndex != 2 {
+		t.Errorf("post event = %+v", ev)
+	}
+	if data, ok := got[1].Data.(FollowEventData); !ok || got[1].Address != alice.Address || data.Follower != bob.Address {
+		t.Errorf("follow event = %+v, want bob following alice", got[1])
+	}
+
+	if n, _ := w.Sync(bc); n != 0 {
+		t.Errorf("second Sync() = %d blocks, want 0", n)
+	}
+	fork, _ := ledger.NewBlockchain()
+	if _, err := w.Sync(fork); !errors.Is(err, ErrWatcherDiverged) {
+		t.Errorf("Sync(fork) error = %v, want ErrWatcherDiverged", err)
+	}
+}
@@ -0,0 +1,263 @@
+// Package webhook delivers chain and social events to operator-registered
+// HTTP endpoints.
+//
+// Operators register Subscriptions: a URL, the event types wanted and
+// optionally the addresses they care about (a post's author, a follow's
+// followee, a report's target). Events are published to a Dispatcher, by the
+// chain Watcher for posts and follows or by a ReportCounter when a report
+// threshold is reached, and each matching subscription gets a signed POST
+// (see Sign). Failed deliveries are retried with exponential backoff, and
+// every attempt is recorded in the DeliveryLog.
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+)
+
+// EventType names a kind of event.
+type EventType string
+
+const (
+	EventPostCreated     EventType = "post.created"     // Address is the post author
+	EventFollowCreated   EventType = "follow.created"   // Address is the followee
+	EventReportThreshold EventType = "report.threshold" // Address is the reported target
+	// EventInvariantViolation is published by the operator for a failed
+	// invariant check (see package invariant). Address is empty, so only
+	// subscriptions without an address filter receive it.
+	EventInvariantViolation EventType = "invariant.violation"
+)
+
+// Limits on subscriptions.
+const (
+	MaxSubscriptionURLLength = 2048 // Bytes
+	MaxSubscriptionAddresses = 256
+)
+
+// ErrSubscriptionNotFound is returned for an unknown subscription ID.
+var ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+
+// Event is the JSON body of a delivery.
+type Event struct {
+	ID         string      `json:"id"` // Transaction ID for chain events
+	Type       EventType   `json:"type"`
+	Address    string      `json:"address"`
+	Timestamp  int64       `json:"timestamp"`            // UnixNano
+	BlockIndex int64       `json:"blockIndex,omitempty"` // For chain events
+	Data       interface{} `json:"data,omitempty"`       // Type-specific details
+}
+
+// Subscription is an operator-registered endpoint and the events it wants.
+type Subscription struct {
+	ID     string      `json:"id"`
+	URL    string      `json:"url"`
+	Secret string      `json:"secret"`           // HMAC key for delivery signatures
+	Events []EventType `json:"events,omitempty"` // Empty means every event type
+	// Addresses restricts delivery to events about these addresses. Empty
+	// means any address.
+	Addresses []string `json:"addresses,omitempty"`
+	CreatedAt int64    `json:"createdAt"` // UnixNano
+}
+
+// Matches reports whether the subscription wants ev.
+func (s *Subscription) Matches(ev *Event) bool {
+	if len(s.Events) > 0 {
+		wanted := false
+		for _, typ := range s.Events {
+			wanted = wanted || typ == ev.Type
+		}
+		if !wanted {
+			return false
+		}
+	}
+	if len(s.Addresses) == 0 {
+		return true
+	}
+	for _, addr := range s.Addresses {
+		if addr == ev.Address {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the subscription's URL and filters. Plain http URLs are
+// accepted only if allowHTTP is set.
+func (s *Subscription) validate(allowHTTP bool) error {
+	if len(s.URL) > MaxSubscriptionURLLength {
+		return fmt.Errorf("webhook URL is %d bytes, limit %d", len(s.URL), MaxSubscriptionURLLength)
+	}
+	u, err := url.Parse(s.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && !(allowHTTP && u.Scheme == "http")) {
+		return fmt.Errorf("webhook URL %q is not an absolute https URL", s.URL)
+	}
+	for _, typ := range s.Events {
+		switch typ {
+		case EventPostCreated, EventFollowCreated, EventReportThreshold:
+		default:
+			return fmt.Errorf("unknown event type %q", typ)
+		}
+	}
+	if len(s.Addresses) > MaxSubscriptionAddresses {
+		return fmt.Errorf("subscription has %d addresses, limit %d", len(s.Addresses), MaxSubscriptionAddresses)
+	}
+	return nil
+}
+
+// SubscriptionStore persists subscriptions between restarts.
+type SubscriptionStore interface {
+	// LoadSubscriptions returns the saved subscriptions, or nil if nothing
+	// has been saved yet.
+	LoadSubscriptions() ([]Subscription, error)
+	SaveSubscriptions(subs []Subscription) error
+}
+
+// FileSubscriptionStore is a SubscriptionStore backed by a single JSON file.
+// The file holds delivery secrets, so it is written with mode 0600.
+type FileSubscriptionStore struct {
+	path string
+}
+
+// NewFileSubscriptionStore creates a FileSubscriptionStore writing to path.
+func NewFileSubscriptionStore(path string) (*FileSubscriptionStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("subscription store path cannot be empty")
+	}
+	return &FileSubscriptionStore{path: path}, nil
+}
+
+// LoadSubscriptions reads the subscription file. A missing file is not an error.
+func (fs *FileSubscriptionStore) LoadSubscriptions() ([]Subscription, error) {
+	data, err := os.ReadFile(fs.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subscriptions %s: %w", fs.path, err)
+	}
+	var subs []Subscription
+	if err := json.Unmarshal(data, &subs); err != nil {
+		return nil, fmt.Errorf("failed to decode subscriptions %s: %w", fs.path, err)
+	}
+	return subs, nil
+}
+
+// SaveSubscriptions atomically replaces the subscription file.
+func (fs *FileSubscriptionStore) SaveSubscriptions(subs []Subscription) error {
+	data, err := json.MarshalIndent(subs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode subscriptions: %w", err)
+	}
+	tmp := fs.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write subscriptions %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, fs.path); err != nil {
+		return fmt.Errorf("failed to replace subscriptions %s: %w", fs.path, err)
+	}
+	return nil
+}
+
+// registry holds the subscriptions of a Dispatcher.
+type registry struct {
+	mu    sync.RWMutex
+	store SubscriptionStore // Optional
+	subs  map[string]Subscription
+}
+
+// Register adds a subscription and returns it with its ID, secret and
+// creation time filled in. A secret is generated unless one is given.
+func (d *Dispatcher) Register(sub Subscription) (Subscription, error) {
+	if err := sub.validate(d.allowHTTP); err != nil {
+		return Subscription{}, err
+	}
+	sub.ID = randomHex(8)
+	if sub.Secret == "" {
+		sub.Secret = randomHex(32)
+	}
+	sub.CreatedAt = d.now().UnixNano()
+	sub.Events = append([]EventType(nil), sub.Events...)
+	sub.Addresses = append([]string(nil), sub.Addresses...)
+
+	d.subs.mu.Lock()
+	defer d.subs.mu.Unlock()
+	d.subs.subs[sub.ID] = sub
+	if err := d.subs.save(); err != nil {
+		delete(d.subs.subs, sub.ID)
+		return Subscription{}, err
+	}
+	return sub, nil
+}
+
+// Unregister removes a subscription. Deliveries already queued for it are
+// dropped when they come due.
+func (d *Dispatcher) Unregister(id string) error {
+	d.subs.mu.Lock()
+	defer d.subs.mu.Unlock()
+	sub, ok := d.subs.subs[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSubscriptionNotFound, id)
+	}
+	delete(d.subs.subs, id)
+	if err := d.subs.save(); err != nil {
+		d.subs.subs[id] = sub
+		return err
+	}
+	return nil
+}
+
+// Subscriptions returns the registered subscriptions, oldest first.
+func (d *Dispatcher) Subscriptions() []Subscription {
+	d.subs.mu.RLock()
+	defer d.subs.mu.RUnlock()
+	return d.subs.list()
+}
+
+// Subscription returns the subscription with the given ID.
+func (d *Dispatcher) Subscription(id string) (Subscription, bool) {
+	d.subs.mu.RLock()
+	defer d.subs.mu.RUnlock()
+	sub, ok := d.subs.subs[id]
+	return sub, ok
+}
+
+// list returns the subscriptions sorted by creation. The caller must hold r.mu.
+func (r *registry) list() []Subscription {
+	subs := make([]Subscription, 0, len(r.subs))
+	for _, sub := range r.subs {
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].CreatedAt != subs[j].CreatedAt {
+			return subs[i].CreatedAt < subs[j].CreatedAt
+		}
+		return subs[i].ID < subs[j].ID
+	})
+	return subs
+}
+
+// save persists the subscriptions. The caller must hold r.mu.
+func (r *registry) save() error {
+	if r.store == nil {
+		return nil
+	}
+	if err := r.store.SaveSubscriptions(r.list()); err != nil {
+		return fmt.Errorf("failed to persist webhook subscriptions: %w", err)
+	}
+	return nil
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSubscription_Matches(t *testing.T) {
+	post := &Event{Type: EventPostCreated, Address: "alice"}
+	tests := []struct {
+		name string
+		sub  Subscription
+		want bool
+	}{
+		{"everything", Subscription{}, true},
+		{"type", Subscription{Events: []EventType{EventPostCreated}}, true},
+		{"other type", Subscription{Events: []EventType{EventFollowCreated}}, false},
+		{"address", Subscription{Events: []EventType{EventPostCreated}, Addresses: []string{"bob", "alice"}}, true},
+		{"other address", Subscription{Addresses: []string{"bob"}}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.sub.Matches(post); got != tt.want {
+			t.Errorf("%s: Matches() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDispatcher_Register(t *testing.T) {
+	d, err := NewDispatcher(DispatcherOptions{})
+	if err != nil {
+		t.Fatalf("NewDispatcher() error = %v", err)
+	}
+	for _, bad := range []Subscription{
+		{URL: "http://example.com/hook"}, // Plain http not allowed
+		{URL: "/hook"},
+		{URL: "https://example.com/hook", Events: []EventType{"post.deleted"}},
+		{URL: "https://example.com/" + strings.Repeat("a", MaxSubscriptionURLLength)},
+	} {
+		if _, err := d.Register(bad); err == nil {
+			t.Errorf("Register(%+v) expected error, got nil", bad)
+		}
+	}
+
+	sub, err := d.Register(Subscription{URL: "https://example.com/hook", Events: []EventType{EventFollowCreated}})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if sub.ID == "" || len(sub.Secret) != 64 || sub.CreatedAt == 0 {
+		t.Errorf("Register() = %+v, want ID, generated secret and creation time", sub)
+	}
+	if got := d.Subscriptions(); len(got) != 1 || got[0].ID != sub.ID {
+		t.Errorf("Subscriptions() = %+v, want the registered subscription", got)
+	}
+	if err := d.Unregister(sub.ID); err != nil {
+		t.Fatalf("Unregister() error = %v", err)
+	}
+	if err := d.Unregister(sub.ID); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("second Unregister() error = %v, want ErrSubscriptionNotFound", err)
+	}
+}
+
+func TestFileSubscriptionStore_Persists(t *testing.T) {
+	store, err := NewFileSubscriptionStore(filepath.Join(t.TempDir(), "webhooks.json"))
+	if err != nil {
+		t.Fatalf("NewFileSubscriptionStore() error = %v", err)
+	}
+	d, _ := NewDispatcher(DispatcherOptions{Store: store})
+	first, _ := d.Register(Subscription{URL: "https://a.example/hook", Secret: "s3cret"})
+	second, _ := d.Register(Subscription{URL: "https://b.example/hook", Addresses: []string{"alice"}})
+	if err := d.Unregister(first.ID); err != nil {
+		t.Fatalf("Unregister() error = %v", err)
+	}
+
+	reloaded, err := NewDispatcher(DispatcherOptions{Store: store})
+	if err != nil {
+		t.Fatalf("NewDispatcher() after restart error = %v", err)
+	}
+	got := reloaded.Subscriptions()
+	if len(got) != 1 || got[0].ID != second.ID || got[0].Secret != second.Secret || got[0].Addresses[0] != "alice" {
+		t.Errorf("reloaded subscriptions = %+v, want only %+v", got, second)
+	}
+}
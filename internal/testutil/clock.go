@@ -0,0 +1,55 @@
+package testutil
+
+import (
+	"sync"
+	"time"
+)
+
+// ClockStart is the time a Clock from NewClock starts at.
+var ClockStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Clock is a fake ledger.Clock that only moves when told to. Each Now call
+// returns the current time and then advances it by Step, so a run of
+// constructor calls stamps distinct, increasing times without sleeping.
+// It is safe for concurrent use.
+type Clock struct {
+	mu   sync.Mutex
+	now  time.Time
+	step time.Duration
+}
+
+// NewClock returns a Clock at ClockStart that advances by step after each
+// Now call. A zero step keeps it still until Advance or Set.
+func NewClock(step time.Duration) *Clock {
+	return &Clock{now: ClockStart, step: step}
+}
+
+// Now returns the current fake time, then advances it by the clock's step.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now
+	c.now = c.now.Add(c.step)
+	return now
+}
+
+// Peek returns the time the next Now call will return, without advancing.
+func (c *Clock) Peek() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to t, which may be in its past.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
@@ -0,0 +1,29 @@
+package testutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock_StepsAdvanceAndSet(t *testing.T) {
+	c := NewClock(time.Second)
+	if got := c.Now(); !got.Equal(ClockStart) {
+		t.Fatalf("first Now() = %v, want %v", got, ClockStart)
+	}
+	if got := c.Now(); !got.Equal(ClockStart.Add(time.Second)) {
+		t.Errorf("second Now() = %v, want one step later", got)
+	}
+	c.Advance(time.Hour)
+	if got, want := c.Peek(), ClockStart.Add(2*time.Second+time.Hour); !got.Equal(want) {
+		t.Errorf("Peek() after Advance = %v, want %v", got, want)
+	}
+	c.Set(ClockStart)
+	if got := c.Now(); !got.Equal(ClockStart) {
+		t.Errorf("Now() after Set = %v, want %v", got, ClockStart)
+	}
+
+	still := NewClock(0)
+	if a, b := still.Now(), still.Now(); !a.Equal(b) {
+		t.Errorf("Now() on a zero-step clock moved from %v to %v", a, b)
+	}
+}
@@ -0,0 +1,357 @@
+// Package testutil provides in-memory fakes of the DDS interfaces, and a fake
+// Clock, for tests and the local test binaries. The fakes are deterministic:
+// the same input always chunks to the same chunk and manifest CIDs.
+//
+// Fixture builders for wallets, signed transactions and chains live in
+// testutil/fixture, which depends on the ledger and cannot be imported by the
+// ledger's own tests.
+package testutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"digisocialblock/pkg/dds/chunking"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// DefaultChunkSize is the chunk size of a Chunker with no ChunkSize set.
+const DefaultChunkSize = 1024
+
+// EmptyManifestCID is the manifest CID a Chunker gives empty content.
+const EmptyManifestCID = "empty_content_manifest_cid_v1"
+
+// Chunker splits content into fixed-size chunks. Each chunk CID is the hex
+// SHA-256 of its data; the manifest CID is "test_manifest_" followed by the
+// hex SHA-256 of the chunk CIDs in order. The zero value is usable. A
+// Chunker is safe for concurrent use as long as its fields are not changed
+// while it is in use.
+type Chunker struct {
+	ChunkSize int
+	// Err, if set, is returned by every ChunkData call.
+	Err error
+	// Manifests, if set, receives every manifest produced, so content
+	// chunked here can be fetched back by a retriever.
+	Manifests *ManifestFetcher
+}
+
+// NewChunker returns a Chunker with the given chunk size.
+func NewChunker(chunkSize int) *Chunker {
+	return &Chunker{ChunkSize: chunkSize}
+}
+
+// ChunkData reads all of data and splits it into chunks.
+func (c *Chunker) ChunkData(data io.Reader) (*chunking.ContentManifestV1, []chunking.DataChunk, error) {
+	if c.Err != nil {
+		return nil, nil, c.Err
+	}
+	raw, err := io.ReadAll(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("testutil: failed to read data: %w", err)
+	}
+	manifest, chunks := Chunk(raw, c.ChunkSize)
+	if c.Manifests != nil {
+		c.Manifests.Add(manifest.ManifestCID, manifest)
+	}
+	return manifest, chunks, nil
+}
+
+// ChunkSizeFor returns the size of the chunks content of any size is split
+// into.
+func (c *Chunker) ChunkSizeFor(int64) int {
+	if c.ChunkSize <= 0 {
+		return DefaultChunkSize
+	}
+	return c.ChunkSize
+}
+
+// Chunk splits raw the way a Chunker with the given chunk size does, for
+// tests that need the expected manifest without a publisher. A chunk size
+// of zero or less means DefaultChunkSize.
+func Chunk(raw []byte, chunkSize int) (*chunking.ContentManifestV1, []chunking.DataChunk) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	manifest := &chunking.ContentManifestV1{
+		Version:          1,
+		TotalSize:        int64(len(raw)),
+		Chunks:           []chunking.ChunkInfo{},
+		EncryptionMethod: "none",
+	}
+	chunks := []chunking.DataChunk{}
+	if len(raw) == 0 {
+		manifest.ManifestCID = EmptyManifestCID
+		return manifest, chunks
+	}
+
+	var cids bytes.Buffer
+	for i := 0; i < len(raw); i += chunkSize {
+		end := i + chunkSize
+		if end > len(raw) {
+			end = len(raw)
+		}
+		data := raw[i:end]
+		sum := sha256.Sum256(data)
+		cid := hex.EncodeToString(sum[:])
+		chunks = append(chunks, chunking.DataChunk{ChunkCID: cid, Data: data, Size: int64(len(data))})
+		manifest.Chunks = append(manifest.Chunks, chunking.ChunkInfo{ChunkCID: cid, Size: int64(len(data))})
+		cids.WriteString(cid)
+	}
+	sum := sha256.Sum256(cids.Bytes())
+	manifest.ManifestCID = "test_manifest_" + hex.EncodeToString(sum[:])
+	return manifest, chunks
+}
+
+// Storage is an in-memory chunk store. It satisfies the publisher's storage
+// interface, the retriever's chunk retriever interface and the zero-copy
+// storage interface. Stored data is copied, except by StoreChunkNoCopy. A
+// Storage is safe for concurrent use.
+type Storage struct {
+	mu           sync.Mutex
+	chunks       map[string][]byte
+	storeErr     error
+	retrieveErrs map[string]error
+	stores       int
+	noCopyStores int
+}
+
+// NewStorage returns an empty Storage.
+func NewStorage() *Storage {
+	return &Storage{chunks: make(map[string][]byte), retrieveErrs: make(map[string]error)}
+}
+
+// StoreChunk stores a copy of data under chunkID.
+func (s *Storage) StoreChunk(chunkID string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkStoreLocked(chunkID); err != nil {
+		return err
+	}
+	s.chunks[chunkID] = bytes.Clone(data)
+	s.stores++
+	return nil
+}
+
+// StoreChunkNoCopy stores data under chunkID without copying it.
+func (s *Storage) StoreChunkNoCopy(chunkID string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkStoreLocked(chunkID); err != nil {
+		return err
+	}
+	s.chunks[chunkID] = data
+	s.stores++
+	s.noCopyStores++
+	return nil
+}
+
+func (s *Storage) checkStoreLocked(chunkID string) error {
+	if s.storeErr != nil {
+		return s.storeErr
+	}
+	if chunkID == "" {
+		return fmt.Errorf("testutil: chunkID cannot be empty")
+	}
+	return nil
+}
+
+// RetrieveChunk returns a copy of the chunk stored under chunkID.
+func (s *Storage) RetrieveChunk(chunkID string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.retrieveErrs[chunkID]; err != nil {
+		return nil, err
+	}
+	data, ok := s.chunks[chunkID]
+	if !ok {
+		return nil, fmt.Errorf("testutil: chunk %s not found", chunkID)
+	}
+	return bytes.Clone(data), nil
+}
+
+// ChunkExists reports whether a chunk is stored under chunkID.
+func (s *Storage) ChunkExists(chunkID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.chunks[chunkID]
+	return ok
+}
+
+// Put stores data under chunkID as is, bypassing FailStores and the store
+// counters. Tests use it to seed chunks or to tamper with stored ones.
+func (s *Storage) Put(chunkID string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chunks[chunkID] = data
+}
+
+// Delete removes the chunk stored under chunkID.
+func (s *Storage) Delete(chunkID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.chunks, chunkID)
+}
+
+// Clear removes every stored chunk.
+func (s *Storage) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.chunks)
+}
+
+// FailStores makes every later store return err. A nil err stops the
+// failures.
+func (s *Storage) FailStores(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.storeErr = err
+}
+
+// FailRetrieve makes retrieving chunkID return err, whether or not the
+// chunk is stored. A nil err stops the failure.
+func (s *Storage) FailRetrieve(chunkID string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		delete(s.retrieveErrs, chunkID)
+		return
+	}
+	s.retrieveErrs[chunkID] = err
+}
+
+// Stores returns the number of successful stores, by either method.
+func (s *Storage) Stores() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stores
+}
+
+// NoCopyStores returns the number of successful StoreChunkNoCopy calls.
+func (s *Storage) NoCopyStores() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.noCopyStores
+}
+
+// Len returns the number of chunks stored.
+func (s *Storage) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.chunks)
+}
+
+// ManifestFetcher serves manifests added to it. It satisfies the
+// retriever's manifest fetcher interface. It is safe for concurrent use.
+type ManifestFetcher struct {
+	mu        sync.Mutex
+	manifests map[string]*chunking.ContentManifestV1
+	err       error
+}
+
+// NewManifestFetcher returns an empty ManifestFetcher.
+func NewManifestFetcher() *ManifestFetcher {
+	return &ManifestFetcher{manifests: make(map[string]*chunking.ContentManifestV1)}
+}
+
+// Add makes a copy of manifest fetchable under cid.
+func (f *ManifestFetcher) Add(cid string, manifest *chunking.ContentManifestV1) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.manifests[cid] = copyManifest(manifest)
+}
+
+// Fail makes every later fetch return err. A nil err stops the failures.
+func (f *ManifestFetcher) Fail(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+// FetchManifest returns a copy of the manifest added under manifestCID.
+func (f *ManifestFetcher) FetchManifest(manifestCID string) (*chunking.ContentManifestV1, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	manifest, ok := f.manifests[manifestCID]
+	if !ok {
+		return nil, fmt.Errorf("testutil: manifest CID %s not found", manifestCID)
+	}
+	return copyManifest(manifest), nil
+}
+
+func copyManifest(m *chunking.ContentManifestV1) *chunking.ContentManifestV1 {
+	c := *m
+	c.Chunks = append([]chunking.ChunkInfo{}, m.Chunks...)
+	return &c
+}
+
+// Originator records the manifests advertised to it. It satisfies the
+// publisher's originator advertiser interface. It is safe for concurrent use
+// as long as Manifests is not changed while it is in use.
+type Originator struct {
+	// Manifests, if set, receives every advertised manifest, as peers would
+	// once content is announced.
+	Manifests *ManifestFetcher
+
+	mu         sync.Mutex
+	err        error
+	advertised []string
+}
+
+// AdvertiseManifest records the manifest's CID and then returns the error
+// set by Fail, if any.
+func (o *Originator) AdvertiseManifest(manifest *chunking.ContentManifestV1) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if manifest == nil {
+		return fmt.Errorf("testutil: manifest cannot be nil")
+	}
+	o.advertised = append(o.advertised, manifest.ManifestCID)
+	if o.err != nil {
+		return o.err
+	}
+	if o.Manifests != nil {
+		o.Manifests.Add(manifest.ManifestCID, manifest)
+	}
+	return nil
+}
+
+// Fail makes every later advertisement return err. A nil err stops the
+// failures.
+func (o *Originator) Fail(err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.err = err
+}
+
+// Advertised returns the manifest CIDs advertised so far, in order.
+func (o *Originator) Advertised() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]string(nil), o.advertised...)
+}
+
+// DDS bundles a Chunker, Storage, ManifestFetcher and Originator wired
+// together: every manifest the Chunker produces is fetchable from
+// Manifests, so a publisher and retriever built from one DDS round-trip.
+type DDS struct {
+	Chunker    *Chunker
+	Storage    *Storage
+	Manifests  *ManifestFetcher
+	Originator *Originator
+}
+
+// NewDDS returns a DDS whose Chunker uses the given chunk size.
+func NewDDS(chunkSize int) *DDS {
+	manifests := NewManifestFetcher()
+	return &DDS{
+		Chunker:    &Chunker{ChunkSize: chunkSize, Manifests: manifests},
+		Storage:    NewStorage(),
+		Manifests:  manifests,
+		Originator: &Originator{},
+	}
+}
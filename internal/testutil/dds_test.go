@@ -0,0 +1,122 @@
+package testutil
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestChunker_DeterministicAndRecorded(t *testing.T) {
+	dds := NewDDS(4)
+	body := "ten bytes!"
+	manifest, chunks, err := dds.Chunker.ChunkData(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("ChunkData() error = %v", err)
+	}
+	if len(chunks) != 3 || manifest.TotalSize != int64(len(body)) {
+		t.Fatalf("ChunkData() = %d chunks of %d bytes, want 3 of %d", len(chunks), manifest.TotalSize, len(body))
+	}
+	again, _ := Chunk([]byte(body), 4)
+	if again.ManifestCID != manifest.ManifestCID || !strings.HasPrefix(manifest.ManifestCID, "test_manifest_") {
+		t.Errorf("manifest CIDs = %q and %q, want equal and prefixed", manifest.ManifestCID, again.ManifestCID)
+	}
+	fetched, err := dds.Manifests.FetchManifest(manifest.ManifestCID)
+	if err != nil || fetched.ManifestCID != manifest.ManifestCID {
+		t.Fatalf("FetchManifest() = %v, %v; want the chunked manifest", fetched, err)
+	}
+	fetched.Chunks[0].ChunkCID = "tampered"
+	if refetched, _ := dds.Manifests.FetchManifest(manifest.ManifestCID); refetched.Chunks[0].ChunkCID == "tampered" {
+		t.Error("FetchManifest() returned the stored manifest, want a copy")
+	}
+
+	empty, _ := Chunk(nil, 0)
+	if empty.ManifestCID != EmptyManifestCID || len(empty.Chunks) != 0 {
+		t.Errorf("Chunk(nil) = %+v, want the empty manifest", empty)
+	}
+
+	boom := errors.New("boom")
+	dds.Chunker.Err = boom
+	if _, _, err := dds.Chunker.ChunkData(strings.NewReader(body)); err != boom {
+		t.Errorf("ChunkData() with Err set error = %v, want %v", err, boom)
+	}
+}
+
+func TestStorage_FailuresAndCounters(t *testing.T) {
+	s := NewStorage()
+	data := []byte("chunk")
+	if err := s.StoreChunk("a", data); err != nil {
+		t.Fatalf("StoreChunk() error = %v", err)
+	}
+	data[0] = 'X'
+	if got, _ := s.RetrieveChunk("a"); string(got) != "chunk" {
+		t.Errorf("RetrieveChunk() = %q, want the data as stored", got)
+	}
+	if err := s.StoreChunk("", data); err == nil {
+		t.Error("StoreChunk(\"\") should fail")
+	}
+
+	boom := errors.New("boom")
+	s.FailStores(boom)
+	if err := s.StoreChunkNoCopy("b", data); err != boom {
+		t.Errorf("StoreChunkNoCopy() error = %v, want %v", err, boom)
+	}
+	s.FailStores(nil)
+	if err := s.StoreChunkNoCopy("b", data); err != nil {
+		t.Fatalf("StoreChunkNoCopy() error = %v", err)
+	}
+	if s.Stores() != 2 || s.NoCopyStores() != 1 || s.Len() != 2 {
+		t.Errorf("Stores, NoCopyStores, Len = %d, %d, %d; want 2, 1, 2", s.Stores(), s.NoCopyStores(), s.Len())
+	}
+
+	s.FailRetrieve("a", boom)
+	if _, err := s.RetrieveChunk("a"); err != boom {
+		t.Errorf("RetrieveChunk() error = %v, want %v", err, boom)
+	}
+	s.FailRetrieve("a", nil)
+	s.Put("a", []byte("tampered"))
+	if got, _ := s.RetrieveChunk("a"); !bytes.Equal(got, []byte("tampered")) || s.Stores() != 2 {
+		t.Errorf("after Put, RetrieveChunk() = %q with %d stores", got, s.Stores())
+	}
+	s.Delete("a")
+	if s.ChunkExists("a") {
+		t.Error("ChunkExists() after Delete = true")
+	}
+	s.Clear()
+	if s.Len() != 0 {
+		t.Errorf("Len() after Clear = %d, want 0", s.Len())
+	}
+	if _, err := s.RetrieveChunk("a"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("RetrieveChunk() of a missing chunk error = %v, want not found", err)
+	}
+}
+
+func TestOriginator_RecordsEvenOnFailure(t *testing.T) {
+	manifests := NewManifestFetcher()
+	o := &Originator{Manifests: manifests}
+	manifest, _ := Chunk([]byte("advertised"), 0)
+	if err := o.AdvertiseManifest(manifest); err != nil {
+		t.Fatalf("AdvertiseManifest() error = %v", err)
+	}
+	if _, err := manifests.FetchManifest(manifest.ManifestCID); err != nil {
+		t.Errorf("advertised manifest not fetchable: %v", err)
+	}
+
+	boom := errors.New("boom")
+	o.Fail(boom)
+	if err := o.AdvertiseManifest(manifest); err != boom {
+		t.Errorf("AdvertiseManifest() error = %v, want %v", err, boom)
+	}
+	if got := o.Advertised(); len(got) != 2 || got[1] != manifest.ManifestCID {
+		t.Errorf("Advertised() = %v, want the CID twice", got)
+	}
+
+	manifests.Fail(boom)
+	if _, err := manifests.FetchManifest(manifest.ManifestCID); err != boom {
+		t.Errorf("FetchManifest() error = %v, want %v", err, boom)
+	}
+	manifests.Fail(nil)
+	if _, err := manifests.FetchManifest("missing"); err == nil || !strings.Contains(err.Error(), "manifest CID missing not found") {
+		t.Errorf("FetchManifest(missing) error = %v", err)
+	}
+}
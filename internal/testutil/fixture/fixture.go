@@ -0,0 +1,93 @@
+// Package fixture builds wallets, signed transactions and chains for tests.
+// Every builder takes a testing.TB and fails the test on error, so fixtures
+// read as one line each.
+//
+// The package imports identity, ledger and social, so the internal tests of
+// those packages cannot use it.
+package fixture
+
+import (
+	"digisocialblock/core/identity"
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"testing"
+	"time"
+)
+
+// Payloader is implemented by the payload types that encode themselves,
+// such as social.Post, social.Comment, social.Follow and user.Profile.
+type Payloader interface {
+	ToPayload(format ledger.PayloadFormat) ([]byte, error)
+}
+
+// Wallet returns a new wallet, closed when the test ends.
+func Wallet(tb testing.TB) *identity.Wallet {
+	tb.Helper()
+	wallet, err := identity.NewWallet()
+	if err != nil {
+		tb.Fatalf("NewWallet() error = %v", err)
+	}
+	tb.Cleanup(func() { wallet.Close() })
+	return wallet
+}
+
+// Tx returns a transaction of txType carrying payload in CBOR, signed by
+// wallet.
+func Tx(tb testing.TB, wallet *identity.Wallet, txType ledger.TransactionType, payload Payloader) *ledger.Transaction {
+	tb.Helper()
+	return TxFormat(tb, wallet, txType, ledger.PayloadFormatCBOR, payload)
+}
+
+// TxFormat is like Tx but encodes payload in the given format.
+func TxFormat(tb testing.TB, wallet *identity.Wallet, txType ledger.TransactionType, format ledger.PayloadFormat, payload Payloader) *ledger.Transaction {
+	tb.Helper()
+	data, err := payload.ToPayload(format)
+	if err != nil {
+		tb.Fatalf("ToPayload() error = %v", err)
+	}
+	return RawTx(tb, wallet, txType, data)
+}
+
+// RawTx returns a transaction of txType carrying payload as is, signed by
+// wallet.
+func RawTx(tb testing.TB, wallet *identity.Wallet, txType ledger.TransactionType, payload []byte) *ledger.Transaction {
+	tb.Helper()
+	tx, err := ledger.NewTransaction(wallet.Address, txType, payload)
+	if err != nil {
+		tb.Fatalf("NewTransaction() error = %v", err)
+	}
+	if err := wallet.SignTransaction(tx); err != nil {
+		tb.Fatalf("SignTransaction() error = %v", err)
+	}
+	return tx
+}
+
+// PostTx returns a signed PostCreated transaction for a post by wallet of
+// the content at contentCID.
+func PostTx(tb testing.TB, wallet *identity.Wallet, contentCID string, tags ...string) *ledger.Transaction {
+	tb.Helper()
+	return Tx(tb, wallet, ledger.PostCreated, social.NewPost(wallet.Address, contentCID, "title", tags))
+}
+
+// FollowTx returns a signed UserFollowed transaction of wallet following
+// followee.
+func FollowTx(tb testing.TB, wallet *identity.Wallet, followee string) *ledger.Transaction {
+	tb.Helper()
+	return Tx(tb, wallet, ledger.UserFollowed, &social.Follow{FolloweePublicKey: followee, Timestamp: time.Now().UnixNano()})
+}
+
+// Chain returns a new blockchain with one block appended for each batch of
+// transactions, in order.
+func Chain(tb testing.TB, blocks ...[]*ledger.Transaction) *ledger.Blockchain {
+	tb.Helper()
+	bc, err := ledger.NewBlockchain()
+	if err != nil {
+		tb.Fatalf("NewBlockchain() error = %v", err)
+	}
+	for i, txs := range blocks {
+		if _, err := bc.AddBlock(txs); err != nil {
+			tb.Fatalf("AddBlock() of block %d error = %v", i+1, err)
+		}
+	}
+	return bc
+}
@@ -0,0 +1,29 @@
+package fixture
+
+import (
+	"digisocialblock/core/ledger"
+	"digisocialblock/core/social"
+	"testing"
+)
+
+func TestChain_AppendsSignedTransactions(t *testing.T) {
+	alice := Wallet(t)
+	bob := Wallet(t)
+	post := PostTx(t, alice, "cid-1", "go")
+	follow := FollowTx(t, bob, alice.Address)
+	repost := TxFormat(t, bob, ledger.PostCreated, ledger.PayloadFormatJSON, social.NewPost(bob.Address, "cid-2", "", nil))
+
+	bc := Chain(t, []*ledger.Transaction{post}, []*ledger.Transaction{follow, repost})
+	if got := bc.GetLatestBlock().Index; got != 2 {
+		t.Fatalf("latest block index = %d, want 2", got)
+	}
+	for _, tx := range []*ledger.Transaction{post, follow, repost} {
+		if ok, err := tx.VerifySignature(); !ok || err != nil {
+			t.Errorf("tx %s signature valid = %v, %v", tx.ID, ok, err)
+		}
+	}
+	decoded, err := social.PostFromPayload(post.Payload)
+	if err != nil || decoded.ContentCID != "cid-1" || decoded.AuthorPublicKey != alice.Address {
+		t.Errorf("PostFromPayload() = %+v, %v", decoded, err)
+	}
+}
@@ -0,0 +1,78 @@
+// Package golden compares serialized test output with golden files kept
+// under the calling package's testdata/golden directory. A golden test fails
+// when an encoding changes, so changes that would break hashes or
+// signatures of data already on the chain or in the DDS are never made by
+// accident.
+//
+// When an encoding change is intended, rewrite the files with
+//
+//	go test ./core/ledger -run Golden -update
+//
+// and review the diff of testdata/golden before committing. The -update flag
+// is only defined in the test binaries of packages that import golden, so
+// name those packages rather than ./... .
+package golden
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+// Dir is where golden files live, relative to the package under test.
+const Dir = "testdata/golden"
+
+// Updating reports whether the test run was asked to rewrite golden files.
+func Updating() bool { return *update }
+
+// Path returns the path of the golden file name.
+func Path(name string) string {
+	return filepath.Join(Dir, name)
+}
+
+// Read returns the contents of the golden file name, failing the test if
+// it is missing.
+func Read(tb testing.TB, name string) []byte {
+	tb.Helper()
+	data, err := os.ReadFile(Path(name))
+	if err != nil {
+		tb.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+	return data
+}
+
+// Write writes data to the golden file name.
+func Write(tb testing.TB, name string, data []byte) {
+	tb.Helper()
+	if err := os.MkdirAll(Dir, 0755); err != nil {
+		tb.Fatalf("failed to create %s: %v", Dir, err)
+	}
+	if err := os.WriteFile(Path(name), data, 0644); err != nil {
+		tb.Fatalf("failed to write golden file: %v", err)
+	}
+}
+
+// Assert checks got against the golden file name, or rewrites the file
+// when the run is updating.
+func Assert(tb testing.TB, name string, got []byte) {
+	tb.Helper()
+	if *update {
+		Write(tb, name, got)
+		return
+	}
+	if want := Read(tb, name); !bytes.Equal(got, want) {
+		tb.Errorf("encoding of %s changed; if that is intended, rerun with -update and review the diff\ngot:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+// AssertBinary is Assert for binary encodings. The golden file holds got as
+// one line of hex, so its diffs stay readable.
+func AssertBinary(tb testing.TB, name string, got []byte) {
+	tb.Helper()
+	Assert(tb, name, []byte(hex.EncodeToString(got)+"\n"))
+}
@@ -0,0 +1,39 @@
+package golden
+
+import (
+	"os"
+	"testing"
+)
+
+// recordingTB records failures instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) { r.failed = true }
+
+func TestAssert_ReadsAndRewrites(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	*update = true
+	AssertBinary(t, "bytes.hex", []byte{0x01, 0xab})
+	*update = false
+	if got := string(Read(t, "bytes.hex")); got != "01ab\n" {
+		t.Fatalf("golden file = %q, want hex and a newline", got)
+	}
+	AssertBinary(t, "bytes.hex", []byte{0x01, 0xab})
+
+	rec := &recordingTB{TB: t}
+	Assert(rec, "bytes.hex", []byte("changed\n"))
+	if !rec.failed {
+		t.Error("Assert() with changed output did not fail")
+	}
+}